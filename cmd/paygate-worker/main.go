@@ -0,0 +1,197 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+// paygate-worker runs only the pipeline consumer, file merging, uploads and inbound file
+// processing. It is deployed alongside PayGate instances running with mode=api.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/moov-io/base/admin"
+
+	"github.com/moov-io/paygate"
+	"github.com/moov-io/paygate/internal/worker"
+	"github.com/moov-io/paygate/pkg/config"
+	configadmin "github.com/moov-io/paygate/pkg/config/admin"
+	"github.com/moov-io/paygate/pkg/customers"
+	"github.com/moov-io/paygate/pkg/customers/accounts"
+	"github.com/moov-io/paygate/pkg/customers/ofac"
+	"github.com/moov-io/paygate/pkg/database"
+	"github.com/moov-io/paygate/pkg/organization"
+	"github.com/moov-io/paygate/pkg/transfers"
+	transferadmin "github.com/moov-io/paygate/pkg/transfers/admin"
+	"github.com/moov-io/paygate/pkg/transfers/fundflow"
+	"github.com/moov-io/paygate/pkg/transfers/killswitch"
+	"github.com/moov-io/paygate/pkg/transfers/limiter"
+	"github.com/moov-io/paygate/pkg/transfers/pipeline"
+	"github.com/moov-io/paygate/pkg/transfers/tracenumbers"
+	"github.com/moov-io/paygate/pkg/util"
+	"github.com/moov-io/paygate/pkg/validation/microdeposits"
+	"github.com/moov-io/paygate/pkg/validation/prenotes"
+	"github.com/moov-io/paygate/pkg/webhooks"
+	"github.com/moov-io/paygate/x/httpclient"
+	"github.com/moov-io/paygate/x/trace"
+)
+
+var (
+	flagConfigFile = flag.String("config", "", "Filepath for config file to load")
+)
+
+func main() {
+	flag.Parse()
+
+	// Read our config file
+	cfg := readConfig(os.Getenv("CONFIG_FILE"))
+	cfg.Logger = cfg.Logger.Set("package", "main")
+
+	_, traceCloser, err := trace.NewConstantTracer(cfg.Logger, "paygate-worker")
+	if err != nil {
+		panic(fmt.Sprintf("ERROR starting tracer: %v", err))
+	}
+	defer traceCloser.Close()
+
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	defer cancelFunc()
+
+	// migrate database
+	db, err := database.New(ctx, cfg.Logger, cfg.Database)
+	if err != nil {
+		panic(fmt.Sprintf("error creating database: %v", err))
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			cfg.Logger.LogErrorf("exit: %v", err)
+		}
+	}()
+
+	// Listen for application termination.
+	errs := make(chan error)
+	go func() {
+		c := make(chan os.Signal, 1)
+		signal.Notify(c, syscall.SIGINT, syscall.SIGTERM)
+		errs <- fmt.Errorf("%s", <-c)
+	}()
+
+	// Spin up admin HTTP server
+	adminServer := admin.NewServer(cfg.Admin.BindAddress)
+	adminServer.AddVersionHandler(paygate.Version) // Setup 'GET /version'
+	go func() {
+		cfg.Logger.Logf("admin: listening on %s", adminServer.BindAddr())
+		if err := adminServer.Listen(); err != nil {
+			err = cfg.Logger.LogErrorf("problem starting admin http: %v", err).Err()
+			errs <- err
+		}
+	}()
+	defer adminServer.Shutdown()
+
+	// Register admin route for config marshaling
+	configadmin.RegisterRoutes(adminServer, cfg)
+
+	// Customers
+	customersHTTP, err := httpclient.New(cfg.Customers.HTTPClient, customers.HttpClient.Timeout)
+	if err != nil {
+		panic(fmt.Sprintf("ERROR creating customers client: %v", err))
+	}
+	customersClient := customers.NewClient(cfg.Logger, cfg.Customers, customersHTTP)
+	adminServer.AddLivenessCheck("customers", customersClient.Ping)
+
+	// Webhooks
+	webhookSender, err := webhooks.NewDispatcher(cfg.Logger, cfg.Webhooks, webhooks.NewRepo(db))
+	if err != nil {
+		panic(fmt.Sprintf("ERROR creating webhook sender: %v", err))
+	}
+	defer func() {
+		// Send the webhooks still queued once the worker has stopped
+		ctx, cancelFunc := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancelFunc()
+		if err := webhooks.Close(ctx, webhookSender); err != nil {
+			cfg.Logger.LogErrorf("shutdown: %v", err)
+		}
+	}()
+
+	// Transfers
+	transfersRepo := transfers.NewRepo(db)
+	defer transfersRepo.Close()
+	transferPublisher, err := pipeline.NewPublisher(cfg.Pipeline)
+	if err != nil {
+		panic(fmt.Sprintf("ERROR setting up transfer publisher: %v", err))
+	}
+	defer transferPublisher.Shutdown(ctx)
+	transferadmin.RegisterRoutes(cfg, adminServer, transfersRepo, transferPublisher)
+
+	// Micro-Deposit returns
+	amountKeeper, err := microdeposits.OpenAmountKeeper(cfg)
+	if err != nil {
+		panic(fmt.Sprintf("ERROR setting up micro-deposit amount keeper: %v", err))
+	}
+	microDepositRepo := microdeposits.NewRepo(db, amountKeeper)
+	microDepositReturns := microdeposits.NewReturnHandler(cfg, microDepositRepo, customersClient, webhookSender)
+
+	// Prenote corrections
+	prenoteRepo := prenotes.NewRepo(db)
+	prenoteCorrections := prenotes.NewCorrectionHandler(cfg, prenoteRepo)
+
+	// Periodic jobs aren't started by API instances, so they run here
+	orgRepo := organization.NewRepo(db)
+	accountDecryptor, err := accounts.NewDecryptor(cfg.Customers.Accounts.Decryptor, customersClient)
+	if err != nil {
+		panic(fmt.Sprintf("ERROR creating account decryptor: %v", err))
+	}
+	fundflowStrategy := fundflow.NewStrategies(cfg.Logger, cfg.ODFI, tracenumbers.NewRepo(db))
+	debits := killswitch.NewChecker(cfg, killswitch.NewRepo(db))
+	limiterRepo := limiter.NewRepo(db)
+	limitOverrides := limiter.NewOverrides(limiterRepo)
+	exposure, err := limiter.NewExposure(cfg, limiterRepo, limitOverrides)
+	if err != nil {
+		panic(fmt.Sprintf("ERROR creating exposure limiter: %v", err))
+	}
+	scheduler, err := transfers.NewScheduler(cfg, transfersRepo, orgRepo, customersClient, accountDecryptor, fundflowStrategy, transferPublisher, debits, exposure, limitOverrides, webhookSender)
+	if err != nil {
+		panic(fmt.Sprintf("ERROR creating transfer scheduler: %v", err))
+	}
+	go scheduler.Start(ctx)
+	go transfers.NewIdempotencyPurger(cfg, transfersRepo).Start(ctx)
+	if cfg.ODFI.HasSettlement() {
+		go transfers.NewLegReleaser(cfg, transfersRepo, orgRepo, customersClient, accountDecryptor, fundflowStrategy, transferPublisher).Start(ctx)
+	}
+	go microdeposits.NewNotifier(cfg, microDepositRepo, webhookSender).Start(ctx)
+	go prenotes.NewVerifier(cfg, prenoteRepo, customersClient).Start(ctx)
+	go ofac.NewScreener(cfg, ofac.NewRepo(db), customersClient).Start(ctx)
+
+	w, err := worker.Start(ctx, cfg, db, adminServer, transfersRepo, microDepositReturns, prenoteCorrections, webhookSender)
+	if err != nil {
+		panic(fmt.Sprintf("ERROR starting worker: %v", err))
+	}
+	defer w.Shutdown()
+
+	if err := <-errs; err != nil {
+		cfg.Logger.LogErrorf("exit: %v", err)
+	}
+}
+
+var (
+	exampleConfigFilepath = filepath.Join("examples", "config.yaml")
+)
+
+func readConfig(path string) *config.Config {
+	path = util.Or(path, *flagConfigFile, exampleConfigFilepath)
+	cfg, err := config.FromFile(path)
+	if err != nil {
+		panic(fmt.Sprintf("failed to load config: %v", err))
+	}
+	cfg.Mode = config.ModeWorker
+	if err := cfg.Validate(); err != nil {
+		panic(fmt.Sprintf("invalid config: %v", err))
+	}
+	cfg.Logger.Logf("starting paygate worker version %s", paygate.Version)
+	return cfg
+}
@@ -0,0 +1,45 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/moov-io/paygate/pkg/config"
+)
+
+func TestWorker__readConfig(t *testing.T) {
+	bs, err := ioutil.ReadFile(filepath.Join("..", "..", "examples", "config.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf := strings.Replace(string(bs), `    inmem:
+      url: 'mem://paygate'`, `    kafka:
+      brokers: ["localhost:9092"]
+      group: "paygate"
+      topic: "transfers"`, 1)
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := ioutil.WriteFile(path, []byte(conf), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := readConfig(path)
+	if cfg.Mode != config.ModeWorker {
+		t.Errorf("unexpected mode: %q", cfg.Mode)
+	}
+}
+
+func TestWorker__readConfigInmem(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("expected panic with inmem pipeline")
+		}
+	}()
+	readConfig(filepath.Join("..", "..", "examples", "config.yaml"))
+}
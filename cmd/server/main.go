@@ -14,27 +14,43 @@ import (
 	"os"
 	"os/signal"
 	"path/filepath"
-	"strings"
 	"syscall"
 	"time"
 
 	"github.com/moov-io/base/admin"
 
 	"github.com/moov-io/paygate"
+	"github.com/moov-io/paygate/internal/startup"
+	"github.com/moov-io/paygate/internal/worker"
+	"github.com/moov-io/paygate/pkg/anonymize"
+	"github.com/moov-io/paygate/pkg/attachments"
+	"github.com/moov-io/paygate/pkg/audit"
 	"github.com/moov-io/paygate/pkg/config"
 	configadmin "github.com/moov-io/paygate/pkg/config/admin"
 	"github.com/moov-io/paygate/pkg/customers"
 	"github.com/moov-io/paygate/pkg/customers/accounts"
+	"github.com/moov-io/paygate/pkg/customers/ofac"
+	"github.com/moov-io/paygate/pkg/customers/privacy"
 	"github.com/moov-io/paygate/pkg/database"
 	"github.com/moov-io/paygate/pkg/organization"
+	"github.com/moov-io/paygate/pkg/seed"
+	"github.com/moov-io/paygate/pkg/tokens"
 	"github.com/moov-io/paygate/pkg/transfers"
 	transferadmin "github.com/moov-io/paygate/pkg/transfers/admin"
+	"github.com/moov-io/paygate/pkg/transfers/files"
 	"github.com/moov-io/paygate/pkg/transfers/fundflow"
-	"github.com/moov-io/paygate/pkg/transfers/inbound"
+	"github.com/moov-io/paygate/pkg/transfers/killswitch"
+	"github.com/moov-io/paygate/pkg/transfers/limiter"
+	"github.com/moov-io/paygate/pkg/transfers/periods"
 	"github.com/moov-io/paygate/pkg/transfers/pipeline"
+	"github.com/moov-io/paygate/pkg/transfers/tracenumbers"
 	"github.com/moov-io/paygate/pkg/upload"
 	"github.com/moov-io/paygate/pkg/util"
+	"github.com/moov-io/paygate/pkg/validation/emails"
 	"github.com/moov-io/paygate/pkg/validation/microdeposits"
+	"github.com/moov-io/paygate/pkg/validation/prenotes"
+	"github.com/moov-io/paygate/pkg/webhooks"
+	"github.com/moov-io/paygate/x/httpclient"
 	"github.com/moov-io/paygate/x/route"
 	"github.com/moov-io/paygate/x/schedule"
 	"github.com/moov-io/paygate/x/trace"
@@ -44,20 +60,39 @@ import (
 
 var (
 	flagConfigFile = flag.String("config", "", "Filepath for config file to load")
+	flagMode       = flag.String("mode", "", "Components to run: api, worker, or all (overrides config)")
 )
 
 func main() {
 	flag.Parse()
 
 	// Read our config file
-	cfg := readConfig(os.Getenv("CONFIG_FILE"))
+	cfg, err := readConfig(os.Getenv("CONFIG_FILE"))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		os.Exit(1)
+	}
 	cfg.Logger = cfg.Logger.Set("package", "main")
 
+	if err := run(cfg); err != nil {
+		cfg.Logger.LogErrorf("exit: %v", err)
+		os.Exit(1)
+	}
+}
+
+// run starts each subsystem and blocks until PayGate is shutdown. Errors are returned
+// for required subsystems which fail to start, while optional subsystems (see config.Startup)
+// are reported as degraded on 'GET /startup' of the admin server.
+func run(cfg *config.Config) error {
+	report := startup.NewReport(cfg.Logger, cfg.Startup)
+
 	_, traceCloser, err := trace.NewConstantTracer(cfg.Logger, "paygate")
-	if err != nil {
-		panic(fmt.Sprintf("ERROR starting tracer: %v", err))
+	if err := report.Setup(config.SubsystemTracing, err); err != nil {
+		return err
+	}
+	if traceCloser != nil {
+		defer traceCloser.Close()
 	}
-	defer traceCloser.Close()
 
 	ctx, cancelFunc := context.WithCancel(context.Background())
 	defer cancelFunc()
@@ -65,7 +100,7 @@ func main() {
 	// migrate database
 	db, err := database.New(ctx, cfg.Logger, cfg.Database)
 	if err != nil {
-		panic(fmt.Sprintf("error creating database: %v", err))
+		return fmt.Errorf("creating database: %v", err)
 	}
 	defer func() {
 		if err := db.Close(); err != nil {
@@ -84,6 +119,7 @@ func main() {
 	// Spin up admin HTTP server
 	adminServer := admin.NewServer(cfg.Admin.BindAddress)
 	adminServer.AddVersionHandler(paygate.Version) // Setup 'GET /version'
+	report.RegisterRoutes(adminServer)             // Setup 'GET /startup'
 	go func() {
 		cfg.Logger.Logf("admin: listening on %s", adminServer.BindAddr())
 		if err := adminServer.Listen(); err != nil {
@@ -102,59 +138,41 @@ func main() {
 	configadmin.RegisterRoutes(adminServer, cfg)
 
 	// Find our fundflow strategy
-	fundflowStrategy := fundflow.NewFirstPerson(cfg.Logger, cfg.ODFI)
+	fundflowStrategy := fundflow.NewStrategies(cfg.Logger, cfg.ODFI, tracenumbers.NewRepo(db))
 
 	// Setup our transfer publisher
 	transferPublisher, err := pipeline.NewPublisher(cfg.Pipeline)
 	if err != nil {
-		panic(fmt.Sprintf("ERROR setting up transfer publisher: %v", err))
+		return fmt.Errorf("setting up transfer publisher: %v", err)
 	}
 	defer transferPublisher.Shutdown(ctx)
 
-	transferSubscription, err := pipeline.NewSubscription(cfg)
-	if err != nil {
-		panic(fmt.Sprintf("ERROR setting up transfer subscription: %v", err))
-	}
-	defer transferSubscription.Shutdown(ctx)
-
-	agent, err := upload.New(cfg.Logger, cfg.ODFI)
-	if err != nil {
-		// We don't want to crash the system on this failure. It's an important
-		// connection, but not strictly required as the issue may be resolved
-		// without a restart of PayGate.
-		cfg.Logger.LogErrorf("problem with upload.Agent connection: %v", err)
-	}
-	defer agent.Close()
-	adminServer.AddLivenessCheck(upload.Type(cfg.ODFI), agent.Ping)
-
-	merger, err := pipeline.NewMerging(cfg.Logger, cfg.Pipeline)
-	if err != nil {
-		panic(fmt.Sprintf("ERROR setting up xfer merging: %v", err))
-	}
-
-	cutoffs, err := schedule.ForCutoffTimes(cfg.ODFI.Cutoffs.Timezone, cfg.ODFI.Cutoffs.Windows)
-	if err != nil {
-		panic(fmt.Sprintf("ERROR setting up cutoff times: %v", err))
-	} else {
-		cfg.Logger.Logf("registered %s cutoffs=%v", cfg.ODFI.Cutoffs.Timezone, strings.Join(cfg.ODFI.Cutoffs.Windows, ","))
-	}
-
-	pipelineRepo := pipeline.NewRepo(db)
-	xferAgg, err := pipeline.NewAggregator(cfg, agent, pipelineRepo, merger, transferSubscription, nil)
+	// Customers
+	customersHTTP, err := httpclient.New(cfg.Customers.HTTPClient, customers.HttpClient.Timeout)
 	if err != nil {
-		panic(fmt.Sprintf("ERROR creating transfer aggregator: %v", err))
+		return fmt.Errorf("setting up customers client: %v", err)
 	}
-	defer xferAgg.Shutdown()
-	go xferAgg.Start(ctx, cutoffs)
-	xferAgg.RegisterRoutes(adminServer)
-
-	// Customers
-	customersClient := customers.NewClient(cfg.Logger, cfg.Customers, customers.HttpClient)
+	customersClient := customers.NewClient(cfg.Logger, cfg.Customers, customersHTTP)
 	adminServer.AddLivenessCheck("customers", customersClient.Ping)
 
 	// Setup
 	registerMicroDepositHealth(cfg, customersClient, adminServer)
 
+	// Webhooks are dispatched by each organization's notification preferences
+	notificationsRepo := webhooks.NewRepo(db)
+	webhooks.NewRouter(cfg, notificationsRepo).RegisterRoutes(handler)
+	webhookSender, err := webhooks.NewDispatcher(cfg.Logger, cfg.Webhooks, notificationsRepo)
+	if cfg.Webhooks == nil {
+		report.Disabled(config.SubsystemWebhooks)
+	} else if err := report.Setup(config.SubsystemWebhooks, err); err != nil {
+		return err
+	}
+	if webhookSender == nil {
+		webhookSender, _ = webhooks.NewSender(nil) // discard events
+	}
+	// Deferred before anything which sends events, so queued webhooks are sent after they've stopped
+	defer closeWebhooks(cfg, webhookSender)
+
 	// Organization
 	orgRepo := organization.NewRepo(db)
 	organization.NewRouter(orgRepo).RegisterRoutes(handler)
@@ -162,91 +180,233 @@ func main() {
 	// Accounts
 	accountDecryptor, err := accounts.NewDecryptor(cfg.Customers.Accounts.Decryptor, customersClient)
 	if err != nil {
-		panic(fmt.Sprintf("ERROR creating account decryptor: %v", err))
+		return fmt.Errorf("creating account decryptor: %v", err)
 	}
 
 	// Transfers
 	transfersRepo := transfers.NewRepo(db)
 	defer transfersRepo.Close()
-	transfers.NewRouter(cfg, transfersRepo, orgRepo, customersClient, accountDecryptor, fundflowStrategy, transferPublisher).RegisterRoutes(handler)
-	transferadmin.RegisterRoutes(cfg, adminServer, transfersRepo)
+	debits := killswitch.NewChecker(cfg, killswitch.NewRepo(db))
+	killswitch.RegisterAdminRoutes(cfg, adminServer, debits)
+	limiterRepo := limiter.NewRepo(db)
+	limitOverrides := limiter.NewOverrides(limiterRepo)
+	limiter.RegisterAdminRoutes(cfg, adminServer, limiterRepo)
+	exposure, err := limiter.NewExposure(cfg, limiterRepo, limitOverrides)
+	if err != nil {
+		return fmt.Errorf("creating exposure limiter: %v", err)
+	}
+	quotas := limiter.NewQuotas(cfg, exposure, files.NewRepo(db))
+	transfers.NewRouter(cfg, transfersRepo, orgRepo, customersClient, accountDecryptor, fundflowStrategy, transferPublisher, debits, exposure, quotas, limitOverrides, webhookSender).RegisterRoutes(handler)
+	transferadmin.RegisterRoutes(cfg, adminServer, transfersRepo, transferPublisher)
+
+	// Recurring transfers are created from their schedules
+	scheduler, err := transfers.NewScheduler(cfg, transfersRepo, orgRepo, customersClient, accountDecryptor, fundflowStrategy, transferPublisher, debits, exposure, limitOverrides, webhookSender)
+	if err != nil {
+		return fmt.Errorf("creating transfer scheduler: %v", err)
+	}
+	jobs := []backgroundJob{scheduler, transfers.NewIdempotencyPurger(cfg, transfersRepo)}
+
+	// Closed accounting periods freeze transfer totals for reporting
+	periods.RegisterAdminRoutes(cfg, schedule.System, adminServer, periods.NewRepo(db))
+
+	// Two-leg transfers hold their credit leg until the debit leg settles
+	if cfg.ODFI.HasSettlement() {
+		releaser := transfers.NewLegReleaser(cfg, transfersRepo, orgRepo, customersClient, accountDecryptor, fundflowStrategy, transferPublisher)
+		transferadmin.RegisterLegRoutes(cfg, adminServer, releaser)
+		jobs = append(jobs, releaser)
+	}
 
 	// Micro-Deposit Validation
-	microDepositRepo := microdeposits.NewRepo(db)
-	microdeposits.NewRouter(cfg, microDepositRepo, transfersRepo, customersClient, accountDecryptor, fundflowStrategy, transferPublisher).RegisterRoutes(handler)
-
-	// Create main HTTP server
-	serve := &http.Server{
-		Addr:    cfg.Http.BindAddress,
-		Handler: handler,
-		TLSConfig: &tls.Config{
-			InsecureSkipVerify:       false,
-			PreferServerCipherSuites: true,
-			MinVersion:               tls.VersionTLS12,
-		},
-		ReadTimeout:  30 * time.Second,
-		WriteTimeout: 30 * time.Second,
-		IdleTimeout:  60 * time.Second,
-	}
-	shutdownServer := func() {
-		if err := serve.Shutdown(context.TODO()); err != nil {
-			cfg.Logger.LogErrorf("shutdown: %v", err)
-		}
+	amountKeeper, err := microdeposits.OpenAmountKeeper(cfg)
+	if err != nil {
+		return err
 	}
-	defer shutdownServer()
+	microDepositRepo := microdeposits.NewRepo(db, amountKeeper)
+	if n, err := microDepositRepo.EncryptAmounts(); err != nil {
+		return fmt.Errorf("encrypting micro-deposit amounts: %v", err)
+	} else if n > 0 {
+		cfg.Logger.Logf("encrypted %d plaintext micro-deposit amounts", n)
+	}
+	microdeposits.NewRouter(cfg, microDepositRepo, transfersRepo, customersClient, accountDecryptor, fundflowStrategy, transferPublisher, webhookSender).RegisterRoutes(handler)
+	microdeposits.RegisterAdminRoutes(cfg, adminServer, microDepositRepo, webhookSender)
+	jobs = append(jobs, microdeposits.NewNotifier(cfg, microDepositRepo, webhookSender))
 
-	// Start main HTTP server
-	go func() {
-		if certFile, keyFile := os.Getenv("HTTPS_CERT_FILE"), os.Getenv("HTTPS_KEY_FILE"); certFile != "" && keyFile != "" {
-			cfg.Logger.Logf("startup: binding to %s for secure HTTP server", cfg.Http.BindAddress)
-			if err := serve.ListenAndServeTLS(certFile, keyFile); err != nil {
-				cfg.Logger.LogErrorf("exit: %v", err)
+	// Prenote Validation
+	prenoteRepo := prenotes.NewRepo(db)
+	prenotes.NewRouter(cfg, prenoteRepo, transfersRepo, customersClient, accountDecryptor, fundflowStrategy, transferPublisher).RegisterRoutes(handler)
+	jobs = append(jobs, prenotes.NewVerifier(cfg, prenoteRepo, customersClient))
+
+	// Email Verification
+	emailSender, err := emails.NewSender(cfg.Validation.Emails)
+	if err != nil {
+		return err
+	}
+	emails.NewRouter(cfg, emails.NewRepo(db), customersClient, emailSender).RegisterRoutes(handler)
+
+	// OFAC re-screening
+	ofacRepo := ofac.NewRepo(db)
+	ofac.RegisterAdminRoutes(cfg, adminServer, ofacRepo)
+	jobs = append(jobs, ofac.NewScreener(cfg, ofacRepo, customersClient))
+
+	// Attachments
+	attachmentsBucket, err := attachments.OpenBucket(cfg.Attachments)
+	if cfg.Attachments == nil {
+		report.Disabled(config.SubsystemAttachments)
+	} else if err := report.Setup(config.SubsystemAttachments, err); err != nil {
+		return err
+	}
+	attachmentsRepo := attachments.NewRepo(db)
+	if report.IsDegraded(config.SubsystemAttachments) {
+		attachments.NewUnavailableRouter(startup.Unavailable(config.SubsystemAttachments)).RegisterRoutes(handler)
+	} else {
+		attachments.NewRouter(cfg, attachmentsRepo, attachmentsBucket, customersClient).RegisterRoutes(handler)
+	}
+
+	// Mutating requests are recorded for audits, including those rejected by API tokens
+	auditRepo := audit.NewRepo(db)
+	handler.Use(audit.Middleware(cfg, auditRepo))
+	audit.RegisterAdminRoutes(cfg, adminServer, auditRepo)
+
+	// API Tokens
+	tokensRepo := tokens.NewRepo(db)
+	tokens.NewRouter(cfg, tokensRepo, customersClient).RegisterRoutes(handler)
+	handler.Use(tokens.Middleware(cfg, tokensRepo))
+
+	// Seed data
+	if cfg.Seed != nil {
+		loader := seed.NewLoader(cfg, orgRepo, tokensRepo)
+		if cfg.Seed.File != "" {
+			_, err := loader.LoadFile(cfg.Seed.File)
+			if err := report.Setup(config.SubsystemSeed, err); err != nil {
+				return err
 			}
-		} else {
-			cfg.Logger.Logf("startup: binding to %s for HTTP server", cfg.Http.BindAddress)
-			if err := serve.ListenAndServe(); err != nil {
-				cfg.Logger.LogErrorf("exit: %v", err)
+		}
+		seed.RegisterAdminRoutes(cfg, adminServer, loader)
+	} else {
+		report.Disabled(config.SubsystemSeed)
+	}
+
+	// Anonymizing restored production snapshots
+	if cfg.Anonymize != nil {
+		anonymizer, err := anonymize.NewAnonymizer(cfg.Logger, cfg.Anonymize, db)
+		if err := report.Setup(config.SubsystemAnonymize, err); err != nil {
+			return err
+		}
+		if anonymizer != nil {
+			anonymize.RegisterAdminRoutes(cfg, adminServer, anonymizer)
+		}
+	} else {
+		report.Disabled(config.SubsystemAnonymize)
+	}
+
+	// Data subject requests (export and erasure)
+	privacyRepo := privacy.NewRepo(db)
+	privacy.RegisterAdminRoutes(cfg, adminServer, privacyRepo, privacy.NewEraser(cfg.Logger, privacyRepo, attachmentsBucket))
+
+	if cfg.Mode.API() {
+		// Create main HTTP server
+		serve := &http.Server{
+			Addr:    cfg.Http.BindAddress,
+			Handler: handler,
+			TLSConfig: &tls.Config{
+				InsecureSkipVerify:       false,
+				PreferServerCipherSuites: true,
+				MinVersion:               tls.VersionTLS12,
+			},
+			ReadTimeout:  30 * time.Second,
+			WriteTimeout: 30 * time.Second,
+			IdleTimeout:  60 * time.Second,
+		}
+		shutdownServer := func() {
+			if err := serve.Shutdown(context.TODO()); err != nil {
+				cfg.Logger.LogErrorf("shutdown: %v", err)
 			}
 		}
-	}()
+		defer shutdownServer()
+
+		// Start main HTTP server
+		go func() {
+			if certFile, keyFile := os.Getenv("HTTPS_CERT_FILE"), os.Getenv("HTTPS_KEY_FILE"); certFile != "" && keyFile != "" {
+				cfg.Logger.Logf("startup: binding to %s for secure HTTP server", cfg.Http.BindAddress)
+				if err := serve.ListenAndServeTLS(certFile, keyFile); err != nil {
+					cfg.Logger.LogErrorf("exit: %v", err)
+				}
+			} else {
+				cfg.Logger.Logf("startup: binding to %s for HTTP server", cfg.Http.BindAddress)
+				if err := serve.ListenAndServe(); err != nil {
+					cfg.Logger.LogErrorf("exit: %v", err)
+				}
+			}
+		}()
+	}
+	if !cfg.Mode.Worker() {
+		// Forward admin requests which trigger uploads to a worker
+		pipeline.RegisterPublisherRoutes(cfg, adminServer, transferPublisher)
+	}
 
-	// Setup our inbound file processor and scheduler
-	fileProcessors := inbound.SetupProcessors(
-		inbound.NewCorrectionProcessor(cfg.Logger),
-		inbound.NewPrenoteProcessor(cfg.Logger),
-		inbound.NewReturnProcessor(cfg.Logger, transfersRepo),
-	)
-	inboundProcessor := inbound.NewPeriodicScheduler(cfg, agent, fileProcessors)
-	go func() {
-		if err := inboundProcessor.Start(); err != nil {
-			panic(fmt.Sprintf("ERROR with inbound processor: %v", err))
+	startBackgroundJobs(ctx, cfg, jobs...)
+
+	if cfg.Mode.Worker() {
+		microDepositReturns := microdeposits.NewReturnHandler(cfg, microDepositRepo, customersClient, webhookSender)
+		prenoteCorrections := prenotes.NewCorrectionHandler(cfg, prenoteRepo)
+		w, err := worker.Start(ctx, cfg, db, adminServer, transfersRepo, microDepositReturns, prenoteCorrections, webhookSender)
+		if err != nil {
+			return fmt.Errorf("starting worker: %v", err)
 		}
-	}()
-	defer inboundProcessor.Shutdown()
+		defer w.Shutdown()
+	}
 
 	if err := <-errs; err != nil {
 		cfg.Logger.LogErrorf("exit: %v", err)
 	}
+	return nil
+}
+
+// backgroundJob is a periodic job which reads and updates shared state.
+type backgroundJob interface {
+	Start(ctx context.Context)
+}
+
+// startBackgroundJobs starts each job when running as a worker, so API replicas only serve
+// requests and can be scaled without running the jobs more than once. It returns how many
+// jobs were started.
+func startBackgroundJobs(ctx context.Context, cfg *config.Config, jobs ...backgroundJob) int {
+	if !cfg.Mode.Worker() {
+		return 0
+	}
+	for i := range jobs {
+		go jobs[i].Start(ctx)
+	}
+	return len(jobs)
 }
 
 var (
 	exampleConfigFilepath = filepath.Join("examples", "config.yaml")
 )
 
-func readConfig(path string) *config.Config {
+func readConfig(path string) (*config.Config, error) {
 	path = util.Or(path, *flagConfigFile, exampleConfigFilepath)
 	cfg, err := config.FromFile(path)
 	if err != nil {
-		panic(fmt.Sprintf("failed to load config: %v", err))
+		return nil, fmt.Errorf("failed to load config: %v", err)
 	}
-	cfg.Logger.Logf("starting paygate server version %s", paygate.Version)
+	if *flagMode != "" {
+		cfg.Mode = config.RunMode(*flagMode)
+		if err := cfg.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid config: %v", err)
+		}
+	}
+	cfg.Logger.Logf("starting paygate server version %s in mode=%s", paygate.Version, util.Or(string(cfg.Mode), string(config.ModeAll)))
 	if err := validateTemplate(cfg.ODFI); err != nil {
-		panic(fmt.Sprintf("ERROR %v", err))
+		return nil, err
 	}
-	return cfg
+	return cfg, nil
 }
 
 func validateTemplate(cfg config.ODFI) error {
+	if cfg.OutboundFilename.ProviderName() != config.FilenameProviderTemplate {
+		return nil
+	}
 	data := upload.FilenameData{
 		RoutingNumber: cfg.RoutingNumber,
 	}
@@ -260,6 +420,16 @@ func validateTemplate(cfg config.ODFI) error {
 	return nil
 }
 
+// closeWebhooks sends the webhooks still queued at shutdown.
+func closeWebhooks(cfg *config.Config, sender webhooks.Sender) {
+	ctx, cancelFunc := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancelFunc()
+
+	if err := webhooks.Close(ctx, sender); err != nil {
+		cfg.Logger.LogErrorf("shutdown: %v", err)
+	}
+}
+
 func registerMicroDepositHealth(cfg *config.Config, client customers.Client, svc *admin.Server) {
 	if micro := cfg.Validation.MicroDeposits; micro != nil {
 		check := customers.HealthChecker(client, micro.Source.Organization, micro.Source.CustomerID, micro.Source.AccountID)
@@ -5,15 +5,21 @@
 package main
 
 import (
+	"context"
 	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/moov-io/paygate/pkg/config"
 )
 
 func TestMain__readConfig(t *testing.T) {
-	cfg := readConfig(filepath.Join("..", "..", "examples", "config.yaml"))
+	cfg, err := readConfig(filepath.Join("..", "..", "examples", "config.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
 	if cfg == nil {
 		t.Fatal("expected Config, got nil")
 	}
@@ -45,3 +51,57 @@ func TestMain__validateTemplate(t *testing.T) {
 		}
 	}
 }
+
+type mockJob struct {
+	mu      sync.Mutex
+	started bool
+}
+
+func (j *mockJob) Start(ctx context.Context) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.started = true
+}
+
+func (j *mockJob) wasStarted() bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.started
+}
+
+func TestMain__startBackgroundJobs(t *testing.T) {
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	defer cancelFunc()
+
+	// API replicas don't start any jobs
+	cfg := config.Empty()
+	cfg.Mode = config.ModeAPI
+	jobs := []*mockJob{{}, {}}
+	if n := startBackgroundJobs(ctx, cfg, jobs[0], jobs[1]); n != 0 {
+		t.Errorf("started %d jobs in api mode", n)
+	}
+	time.Sleep(10 * time.Millisecond)
+	for i := range jobs {
+		if jobs[i].wasStarted() {
+			t.Errorf("job %d started in api mode", i)
+		}
+	}
+
+	// workers start every job
+	for _, mode := range []config.RunMode{"", config.ModeAll, config.ModeWorker} {
+		cfg.Mode = mode
+		jobs := []*mockJob{{}, {}}
+		if n := startBackgroundJobs(ctx, cfg, jobs[0], jobs[1]); n != 2 {
+			t.Errorf("mode=%q: started %d jobs", mode, n)
+		}
+		for i := range jobs {
+			deadline := time.Now().Add(time.Second)
+			for !jobs[i].wasStarted() && time.Now().Before(deadline) {
+				time.Sleep(time.Millisecond)
+			}
+			if !jobs[i].wasStarted() {
+				t.Errorf("mode=%q: job %d wasn't started", mode, i)
+			}
+		}
+	}
+}
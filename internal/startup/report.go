@@ -0,0 +1,121 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+// Package startup records which subsystems PayGate started with so optional dependencies
+// can fail without taking down the rest of PayGate.
+package startup
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/moov-io/base/admin"
+	moovhttp "github.com/moov-io/base/http"
+	"github.com/moov-io/base/log"
+
+	paygateadmin "github.com/moov-io/paygate/pkg/admin"
+	"github.com/moov-io/paygate/pkg/config"
+)
+
+const (
+	StatusOK       = "ok"
+	StatusDegraded = "degraded"
+	StatusDisabled = "disabled"
+)
+
+// Report collects the status of each subsystem as PayGate starts.
+type Report struct {
+	cfg    config.Startup
+	logger log.Logger
+
+	mu         sync.Mutex
+	subsystems []paygateadmin.Subsystem
+}
+
+func NewReport(logger log.Logger, cfg config.Startup) *Report {
+	return &Report{
+		cfg:    cfg,
+		logger: logger,
+	}
+}
+
+// Setup records the result of starting a subsystem. Errors from required subsystems are
+// returned so startup stops, but errors from optional subsystems are logged and recorded
+// as degraded and nil is returned.
+func (r *Report) Setup(name string, err error) error {
+	if err == nil {
+		r.record(name, StatusOK, nil)
+		return nil
+	}
+	if !r.cfg.IsOptional(name) {
+		return fmt.Errorf("%s: %v", name, err)
+	}
+	r.logger.Set("subsystem", name).LogErrorf("starting without optional subsystem: %v", err)
+	r.record(name, StatusDegraded, err)
+	return nil
+}
+
+// Disabled records a subsystem which isn't configured.
+func (r *Report) Disabled(name string) {
+	r.record(name, StatusDisabled, nil)
+}
+
+// IsDegraded returns true if the named subsystem failed to start.
+func (r *Report) IsDegraded(name string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.subsystems {
+		if r.subsystems[i].Name == name {
+			return r.subsystems[i].Status == StatusDegraded
+		}
+	}
+	return false
+}
+
+func (r *Report) record(name, status string, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	sub := paygateadmin.Subsystem{Name: name, Status: status}
+	if err != nil {
+		sub.Error = err.Error()
+	}
+	r.subsystems = append(r.subsystems, sub)
+}
+
+func (r *Report) report() paygateadmin.StartupReport {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	out := paygateadmin.StartupReport{
+		Subsystems: make([]paygateadmin.Subsystem, len(r.subsystems)),
+	}
+	copy(out.Subsystems, r.subsystems)
+	for i := range out.Subsystems {
+		if out.Subsystems[i].Status == StatusDegraded {
+			out.Degraded = true
+		}
+	}
+	return out
+}
+
+// RegisterRoutes adds 'GET /startup' to the admin server, which lists each subsystem's status.
+func (r *Report) RegisterRoutes(svc *admin.Server) {
+	svc.AddHandler("/startup", r.getReport())
+}
+
+func (r *Report) getReport() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		if req.Method != http.MethodGet {
+			moovhttp.Problem(w, fmt.Errorf("invalid method %s", req.Method))
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(r.report())
+	}
+}
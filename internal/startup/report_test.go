@@ -0,0 +1,85 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package startup
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/moov-io/base/log"
+
+	"github.com/moov-io/paygate/pkg/admin"
+	"github.com/moov-io/paygate/pkg/config"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReport__Setup(t *testing.T) {
+	report := NewReport(log.NewNopLogger(), config.Startup{
+		Optional: []string{config.SubsystemWebhooks},
+	})
+
+	require.NoError(t, report.Setup(config.SubsystemTracing, nil))
+	require.NoError(t, report.Setup(config.SubsystemWebhooks, errors.New("bad endpoint")))
+	require.True(t, report.IsDegraded(config.SubsystemWebhooks))
+	require.False(t, report.IsDegraded(config.SubsystemTracing))
+
+	// required subsystems return their error
+	err := report.Setup(config.SubsystemAttachments, errors.New("bad bucket"))
+	require.EqualError(t, err, "attachments: bad bucket")
+	require.False(t, report.IsDegraded(config.SubsystemAttachments))
+
+	report.Disabled(config.SubsystemSeed)
+
+	out := report.report()
+	require.True(t, out.Degraded)
+	require.Len(t, out.Subsystems, 3)
+	require.Equal(t, StatusOK, out.Subsystems[0].Status)
+	require.Equal(t, StatusDegraded, out.Subsystems[1].Status)
+	require.Equal(t, "bad endpoint", out.Subsystems[1].Error)
+	require.Equal(t, StatusDisabled, out.Subsystems[2].Status)
+}
+
+func TestReport__getReport(t *testing.T) {
+	report := NewReport(log.NewNopLogger(), config.Startup{})
+	report.Disabled(config.SubsystemAnonymize)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/startup", nil)
+	report.getReport()(w, req)
+	w.Flush()
+
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var out admin.StartupReport
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&out))
+	require.False(t, out.Degraded)
+	require.Len(t, out.Subsystems, 1)
+	require.Equal(t, config.SubsystemAnonymize, out.Subsystems[0].Name)
+
+	// invalid method
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest("POST", "/startup", nil)
+	report.getReport()(w, req)
+	w.Flush()
+
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestUnavailable(t *testing.T) {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/attachments", nil)
+	Unavailable(config.SubsystemAttachments)(w, req)
+	w.Flush()
+
+	require.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+	var out map[string]string
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&out))
+	require.Equal(t, "attachments_unavailable", out["code"])
+}
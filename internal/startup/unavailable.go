@@ -0,0 +1,24 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package startup
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// Unavailable responds to requests for a degraded subsystem with 503 Service Unavailable.
+// The response's code is "<name>_unavailable" so callers can tell it apart from other errors.
+func Unavailable(name string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(map[string]string{
+			"error": fmt.Sprintf("%s subsystem is unavailable", name),
+			"code":  fmt.Sprintf("%s_unavailable", name),
+		})
+	}
+}
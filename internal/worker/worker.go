@@ -0,0 +1,210 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package worker
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/moov-io/base/admin"
+
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/hooks"
+	"github.com/moov-io/paygate/pkg/transfers"
+	"github.com/moov-io/paygate/pkg/transfers/console"
+	"github.com/moov-io/paygate/pkg/transfers/files"
+	"github.com/moov-io/paygate/pkg/transfers/inbound"
+	"github.com/moov-io/paygate/pkg/transfers/killswitch"
+	"github.com/moov-io/paygate/pkg/transfers/pipeline"
+	"github.com/moov-io/paygate/pkg/transfers/pipeline/notify"
+	"github.com/moov-io/paygate/pkg/upload"
+	"github.com/moov-io/paygate/pkg/webhooks"
+	"github.com/moov-io/paygate/x/schedule"
+
+	"gocloud.dev/pubsub"
+)
+
+// Worker consumes Transfers from the pipeline, merges and uploads them at each cutoff,
+// and processes inbound files from the ODFI.
+type Worker struct {
+	subscription *pubsub.Subscription
+	agent        upload.Agent
+	mailboxes    []upload.Agent
+	aggregator   *pipeline.XferAggregator
+	inbound      inbound.Scheduler
+	retention    *pipeline.Retention
+	certifier    *pipeline.Certifier
+}
+
+// Start sets up and starts each component of the worker. Callers are expected to call
+// Shutdown once the worker should stop.
+func Start(
+	ctx context.Context,
+	cfg *config.Config,
+	db *sql.DB,
+	svc *admin.Server,
+	transfersRepo transfers.Repository,
+	microDeposits inbound.MicroDepositReturns,
+	prenotes inbound.PrenoteCorrections,
+	events webhooks.Sender,
+) (*Worker, error) {
+	w := &Worker{}
+
+	sub, err := pipeline.NewSubscription(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("setting up transfer subscription: %v", err)
+	}
+	w.subscription = sub
+
+	for _, conflict := range cfg.ODFI.ProtocolConflicts() {
+		cfg.Logger.Warn().Logf("odfi config: %s", conflict)
+	}
+	w.agent, err = upload.New(cfg.Logger, cfg.ODFI)
+	if err != nil {
+		// We don't want to crash the system on this failure. It's an important
+		// connection, but not strictly required as the issue may be resolved
+		// without a restart of PayGate.
+		cfg.Logger.LogErrorf("problem with upload.Agent connection: %v", err)
+	}
+	svc.AddLivenessCheck(upload.Type(cfg.ODFI), w.agent.Ping)
+
+	hookRunner, err := hooks.New(cfg)
+	if err != nil {
+		w.Shutdown()
+		return nil, fmt.Errorf("setting up lifecycle hooks: %v", err)
+	}
+
+	// Debits blocked by a kill switch, Transfers awaiting review and Transfers rejected by
+	// preMerge hooks are held out of merged files
+	debits := killswitch.NewChecker(cfg, killswitch.NewRepo(db))
+	merger, err := pipeline.NewMerging(cfg.Logger, cfg.Pipeline, debits, transfers.NewReviewHolder(transfersRepo), hookRunner)
+	if err != nil {
+		w.Shutdown()
+		return nil, fmt.Errorf("setting up xfer merging: %v", err)
+	}
+
+	// Every component reads the time and banking days from one clock
+	clock := schedule.System
+
+	cutoffs, err := schedule.ForDatedCutoffTimes(clock, cfg.ODFI.Cutoffs.Timezone, cfg.ODFI.Cutoffs.AllWindows(), cfg.ODFI.Cutoffs.WindowsOn)
+	if err != nil {
+		w.Shutdown()
+		return nil, fmt.Errorf("setting up cutoff times: %v", err)
+	}
+	cfg.Logger.Logf("registered %s cutoffs=%v", cfg.ODFI.Cutoffs.Timezone, strings.Join(cfg.ODFI.Cutoffs.AllWindows(), ","))
+
+	pipelineRepo := pipeline.NewRepo(db)
+	filesRepo := files.NewRepo(db)
+	files.RegisterAdminRoutes(svc, filesRepo)
+
+	// Skip looking up transfer statuses when webhooks aren't configured
+	var processedEvents webhooks.Sender
+	if cfg.Webhooks != nil {
+		processedEvents = events
+	}
+	w.aggregator, err = pipeline.NewAggregator(cfg, clock, w.agent, pipelineRepo, filesRepo, merger, sub, processedEvents, nil)
+	if err != nil {
+		w.Shutdown()
+		return nil, fmt.Errorf("creating transfer aggregator: %v", err)
+	}
+	go w.aggregator.Start(ctx, cutoffs)
+	w.aggregator.RegisterRoutes(svc)
+
+	// Setup our inbound file processor and scheduler
+	returns := inbound.NewReturnProcessor(cfg.Logger, cfg.ODFI.Inbound.Returns, transfersRepo, microDeposits, hookRunner, events)
+	returns.RegisterRoutes(cfg, svc)
+	fileProcessors := inbound.SetupProcessors(
+		inbound.NewCorrectionProcessor(cfg.Logger, cfg.ODFI.Inbound.Corrections, transfersRepo, prenotes, events),
+		inbound.NewPrenoteProcessor(cfg.Logger),
+		inbound.NewDeathNotificationProcessor(cfg.Logger, transfersRepo, merger, events),
+		returns,
+	)
+	notifier, err := notify.NewMultiSender(cfg.Logger, cfg.Pipeline.Notifications)
+	if err != nil {
+		w.Shutdown()
+		return nil, fmt.Errorf("setting up inbound notifications: %v", err)
+	}
+	// Warn when pending transfers might not be uploaded before the next cutoff
+	go pipeline.NewCutoffMonitor(cfg, clock, merger, notifier).Start(ctx)
+
+	// Archive or delete old directories of merged, uploaded and downloaded files
+	w.retention, err = pipeline.NewRetention(cfg)
+	if err != nil {
+		w.Shutdown()
+		return nil, fmt.Errorf("setting up file retention: %v", err)
+	}
+	go w.retention.Start(ctx)
+	w.retention.RegisterRoutes(cfg, svc)
+
+	// Upload files for ODFI certification to their test server
+	w.certifier, err = pipeline.NewCertifier(cfg)
+	if err != nil {
+		w.Shutdown()
+		return nil, fmt.Errorf("setting up certification: %v", err)
+	}
+	w.certifier.RegisterRoutes(svc)
+
+	quarantine, err := inbound.NewQuarantine(cfg.Logger, cfg.ODFI.Inbound.Quarantine, notifier)
+	if err != nil {
+		w.Shutdown()
+		return nil, fmt.Errorf("setting up inbound quarantine: %v", err)
+	}
+	quarantine.RegisterRoutes(cfg, svc, fileProcessors)
+
+	acks, err := inbound.NewAcknowledgements(cfg.Logger, cfg.ODFI.Inbound.Acknowledgements, filesRepo)
+	if err != nil {
+		w.Shutdown()
+		return nil, fmt.Errorf("setting up inbound acknowledgements: %v", err)
+	}
+
+	// ODFIs with an API acknowledge batches on callbacks rather than inbound files
+	inbound.NewAPICallbacks(cfg.Logger, cfg.ODFI.API, filesRepo, transfersRepo).RegisterRoutes(svc)
+
+	// Some banks push inbound files into a bucket or directory instead
+	w.mailboxes, err = upload.NewMailboxes(cfg.Logger, cfg.ODFI.Inbound.Mailboxes)
+	if err != nil {
+		w.Shutdown()
+		return nil, fmt.Errorf("setting up inbound mailboxes: %v", err)
+	}
+	for i := range w.mailboxes {
+		svc.AddLivenessCheck(fmt.Sprintf("mailbox-%s", cfg.ODFI.Inbound.Mailboxes[i].RoutingNumber), w.mailboxes[i].Ping)
+	}
+
+	w.inbound = inbound.NewPeriodicScheduler(cfg, w.agent, w.mailboxes, quarantine, acks, fileProcessors)
+	console.RegisterRoutes(cfg, clock, svc, w.aggregator, w.inbound)
+	go func() {
+		if err := w.inbound.Start(); err != nil {
+			cfg.Logger.LogErrorf("ERROR with inbound processor: %v", err)
+		}
+	}()
+
+	return w, nil
+}
+
+// Shutdown stops each component of the worker.
+func (w *Worker) Shutdown() {
+	if w == nil {
+		return
+	}
+	if w.inbound != nil {
+		w.inbound.Shutdown()
+	}
+	if w.aggregator != nil {
+		// The aggregator shuts down its subscription
+		w.aggregator.Shutdown()
+	} else if w.subscription != nil {
+		w.subscription.Shutdown(context.Background())
+	}
+	if w.agent != nil {
+		w.agent.Close()
+	}
+	for i := range w.mailboxes {
+		w.mailboxes[i].Close()
+	}
+	w.retention.Close()
+	w.certifier.Close()
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/moov-io/ach"
 	"github.com/moov-io/base"
 	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/util"
 )
 
 // makeBatchHeader creates an ach.BatchHeader from the given Transfer and source Account.
@@ -54,12 +55,28 @@ func makeBatchHeader(id string, options Options, xfer *client.Transfer, source S
 		batchHeader.CompanyDescriptiveDate = now.Format("060102")
 	}
 
-	batchHeader.EffectiveEntryDate = base.NewTime(now).AddBankingDay(1).Format("060102") // Date to be posted, YYMMDD
+	batchHeader.EffectiveEntryDate = effectiveEntryDate(now, xfer) // Date to be posted, YYMMDD
 	batchHeader.ODFIIdentification = ABA8(options.ODFIRoutingNumber)
 
 	return batchHeader
 }
 
+// effectiveEntryDate returns the YYMMDD date a Transfer's entries are posted on. Callers can
+// request a specific date, which was validated when the Transfer was created. Otherwise same-day
+// Transfers post today when it's a banking day and everything else on the next banking day.
+func effectiveEntryDate(now time.Time, xfer *client.Transfer) string {
+	if xfer.EffectiveDate != "" {
+		if when, err := time.Parse(util.YYMMDDTimeFormat, xfer.EffectiveDate); err == nil {
+			return when.Format("060102")
+		}
+	}
+	today := base.NewTime(now)
+	if xfer.SameDay && today.IsBankingDay() {
+		return today.Format("060102")
+	}
+	return today.AddBankingDay(1).Format("060102")
+}
+
 func createIdentificationNumber() string {
 	return base.ID()[:15]
 }
@@ -46,3 +46,42 @@ func TestBatch__SameDay(t *testing.T) {
 		t.Errorf("CompanyDescriptiveDate=%q", bh.CompanyDescriptiveDate)
 	}
 }
+
+func TestBatch__EffectiveDate(t *testing.T) {
+	opts := Options{
+		ODFIRoutingNumber:     "987654320",
+		CutoffTimezone:        time.UTC,
+		CompanyIdentification: "Moov",
+	}
+	xfer := &client.Transfer{
+		Description:   "PAYROLL",
+		EffectiveDate: "2020-11-19",
+	}
+	source := Source{
+		Account: customers.Account{
+			RoutingNumber: opts.ODFIRoutingNumber,
+		},
+	}
+	bh := makeBatchHeader("", opts, xfer, source)
+	if bh.EffectiveEntryDate != "201119" {
+		t.Errorf("EffectiveEntryDate=%q", bh.EffectiveEntryDate)
+	}
+}
+
+func TestBatch__effectiveEntryDate(t *testing.T) {
+	// Tuesday Nov 17th, 2020
+	now := time.Date(2020, time.November, 17, 10, 30, 0, 0, time.UTC)
+
+	if date := effectiveEntryDate(now, &client.Transfer{}); date != "201118" {
+		t.Errorf("EffectiveEntryDate=%q", date)
+	}
+	if date := effectiveEntryDate(now, &client.Transfer{SameDay: true}); date != "201117" {
+		t.Errorf("same-day EffectiveEntryDate=%q", date)
+	}
+
+	// Same-day Transfers created on a weekend post the next banking day
+	saturday := time.Date(2020, time.November, 21, 10, 30, 0, 0, time.UTC)
+	if date := effectiveEntryDate(saturday, &client.Transfer{SameDay: true}); date != "201123" {
+		t.Errorf("weekend same-day EffectiveEntryDate=%q", date)
+	}
+}
@@ -9,22 +9,105 @@ import (
 	customers "github.com/moov-io/customers/pkg/client"
 )
 
-func determineTransactionCode(options Options, srcAcct customers.Account) int {
+// determineTransactionCode returns the code of a Transfer's entry. Transfers from the ODFI push
+// funds into (credit) the destination account, otherwise funds are pulled from (debit) the source
+// account. The code matches the type of whichever account receives the entry.
+func determineTransactionCode(options Options, srcAcct customers.Account, dstAcct customers.Account) int {
 	if options.ODFIRoutingNumber == srcAcct.RoutingNumber {
-		// Credit
-		if srcAcct.Type == customers.ACCOUNTTYPE_CHECKING {
+		return TransactionCode(dstAcct.Type, true, false)
+	}
+	return TransactionCode(srcAcct.Type, false, false)
+}
+
+// TransactionCode returns the code of an entry to an account of the given type. Credits push funds
+// into the account and debits pull funds from it. Prenotes are the zero-dollar variants of each
+// code which verify the account before funds are moved.
+//
+// Zero is returned for account types which can't receive entries, representing a logic bug.
+func TransactionCode(accountType customers.AccountType, credit bool, prenote bool) int {
+	switch accountType {
+	case customers.ACCOUNTTYPE_CHECKING:
+		switch {
+		case credit && prenote:
+			return ach.CheckingPrenoteCredit
+		case credit:
 			return ach.CheckingCredit
+		case prenote:
+			return ach.CheckingPrenoteDebit
+		default:
+			return ach.CheckingDebit
 		}
-		if srcAcct.Type == customers.ACCOUNTTYPE_SAVINGS {
+
+	case customers.ACCOUNTTYPE_SAVINGS:
+		switch {
+		case credit && prenote:
+			return ach.SavingsPrenoteCredit
+		case credit:
 			return ach.SavingsCredit
+		case prenote:
+			return ach.SavingsPrenoteDebit
+		default:
+			return ach.SavingsDebit
 		}
 	}
-	// Debit
-	if srcAcct.Type == customers.ACCOUNTTYPE_CHECKING {
-		return ach.CheckingDebit
+	return 0
+}
+
+// Entry holds the fields PayGate reads from both standard and IAT entries.
+type Entry struct {
+	BatchNumber        int
+	TransactionCode    int
+	RDFIIdentification string
+	CheckDigit         string
+	DFIAccountNumber   string
+	Amount             int
+	TraceNumber        string
+}
+
+// CreditOrDebit returns "C" for credits and "D" for debits, like ach.EntryDetail.
+func (e Entry) CreditOrDebit() string {
+	// Credit transaction codes end in 0 through 4 and debits in 5 through 9
+	if e.TransactionCode%10 < 5 {
+		return "C"
+	}
+	return "D"
+}
+
+// Entries returns every entry in file including those of IAT batches.
+func Entries(file *ach.File) []Entry {
+	if file == nil {
+		return nil
 	}
-	if srcAcct.Type == customers.ACCOUNTTYPE_SAVINGS {
-		return ach.SavingsDebit
+	var out []Entry
+	for i := range file.Batches {
+		bh := file.Batches[i].GetHeader()
+		entries := file.Batches[i].GetEntries()
+		for j := range entries {
+			out = append(out, Entry{
+				BatchNumber:        bh.BatchNumber,
+				TransactionCode:    entries[j].TransactionCode,
+				RDFIIdentification: entries[j].RDFIIdentification,
+				CheckDigit:         entries[j].CheckDigit,
+				DFIAccountNumber:   entries[j].DFIAccountNumber,
+				Amount:             entries[j].Amount,
+				TraceNumber:        entries[j].TraceNumber,
+			})
+		}
+	}
+	for i := range file.IATBatches {
+		bh := file.IATBatches[i].GetHeader()
+		entries := file.IATBatches[i].GetEntries()
+		for j := range entries {
+			out = append(out, Entry{
+				BatchNumber:        bh.BatchNumber,
+				TransactionCode:    entries[j].TransactionCode,
+				RDFIIdentification: entries[j].RDFIIdentification,
+				CheckDigit:         entries[j].CheckDigit,
+				DFIAccountNumber:   entries[j].DFIAccountNumber,
+				Amount:             entries[j].Amount,
+				TraceNumber:        entries[j].TraceNumber,
+			})
+		}
 	}
-	return 0 // invalid, represents a logic bug
+	return out
 }
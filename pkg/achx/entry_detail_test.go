@@ -11,44 +11,77 @@ import (
 	customers "github.com/moov-io/customers/pkg/client"
 )
 
+func TestEntryDetail_TransactionCode(t *testing.T) {
+	cases := []struct {
+		accountType customers.AccountType
+		credit      bool
+		prenote     bool
+		expected    int
+	}{
+		{customers.ACCOUNTTYPE_CHECKING, true, false, ach.CheckingCredit},
+		{customers.ACCOUNTTYPE_CHECKING, true, true, ach.CheckingPrenoteCredit},
+		{customers.ACCOUNTTYPE_CHECKING, false, false, ach.CheckingDebit},
+		{customers.ACCOUNTTYPE_CHECKING, false, true, ach.CheckingPrenoteDebit},
+		{customers.ACCOUNTTYPE_SAVINGS, true, false, ach.SavingsCredit},
+		{customers.ACCOUNTTYPE_SAVINGS, true, true, ach.SavingsPrenoteCredit},
+		{customers.ACCOUNTTYPE_SAVINGS, false, false, ach.SavingsDebit},
+		{customers.ACCOUNTTYPE_SAVINGS, false, true, ach.SavingsPrenoteDebit},
+		{customers.AccountType(""), true, false, 0},
+		{customers.AccountType("loan"), false, false, 0},
+	}
+	for _, tc := range cases {
+		if n := TransactionCode(tc.accountType, tc.credit, tc.prenote); n != tc.expected {
+			t.Errorf("%s credit=%v prenote=%v: unexpected TransactionCode=%d", tc.accountType, tc.credit, tc.prenote, n)
+		}
+	}
+}
+
 func TestEntryDetail_TransactionCodeCredit(t *testing.T) {
 	opts := Options{}
-	destinationAccount := customers.Account{}
+	sourceAccount, destinationAccount := customers.Account{}, customers.Account{}
 
-	if n := determineTransactionCode(opts, destinationAccount); n != 0 {
+	if n := determineTransactionCode(opts, sourceAccount, destinationAccount); n != 0 {
 		t.Errorf("unexpected TransactionCode=%d", n)
 	}
 
+	// Transfers from the ODFI credit the destination's account type
 	opts.ODFIRoutingNumber = "987654320"
-	destinationAccount.RoutingNumber = "987654320"
+	sourceAccount.RoutingNumber = "987654320"
+	sourceAccount.Type = customers.ACCOUNTTYPE_SAVINGS
+	destinationAccount.RoutingNumber = "123456780"
 	destinationAccount.Type = customers.ACCOUNTTYPE_CHECKING
-	if n := determineTransactionCode(opts, destinationAccount); n != ach.CheckingCredit {
+	if n := determineTransactionCode(opts, sourceAccount, destinationAccount); n != ach.CheckingCredit {
 		t.Errorf("unexpected TransactionCode=%d", n)
 	}
 
+	sourceAccount.Type = customers.ACCOUNTTYPE_CHECKING
 	destinationAccount.Type = customers.ACCOUNTTYPE_SAVINGS
-	if n := determineTransactionCode(opts, destinationAccount); n != ach.SavingsCredit {
+	if n := determineTransactionCode(opts, sourceAccount, destinationAccount); n != ach.SavingsCredit {
 		t.Errorf("unexpected TransactionCode=%d", n)
 	}
 }
 
 func TestEntryDetail_TransactionCodeDebit(t *testing.T) {
 	opts := Options{}
-	destinationAccount := customers.Account{}
+	sourceAccount, destinationAccount := customers.Account{}, customers.Account{}
 
-	if n := determineTransactionCode(opts, destinationAccount); n != 0 {
+	if n := determineTransactionCode(opts, sourceAccount, destinationAccount); n != 0 {
 		t.Errorf("unexpected TransactionCode=%d", n)
 	}
 
+	// Transfers into the ODFI debit the source's account type
 	opts.ODFIRoutingNumber = "987654320"
-	destinationAccount.RoutingNumber = "123456780"
-	destinationAccount.Type = customers.ACCOUNTTYPE_CHECKING
-	if n := determineTransactionCode(opts, destinationAccount); n != ach.CheckingDebit {
+	sourceAccount.RoutingNumber = "123456780"
+	sourceAccount.Type = customers.ACCOUNTTYPE_CHECKING
+	destinationAccount.RoutingNumber = "987654320"
+	destinationAccount.Type = customers.ACCOUNTTYPE_SAVINGS
+	if n := determineTransactionCode(opts, sourceAccount, destinationAccount); n != ach.CheckingDebit {
 		t.Errorf("unexpected TransactionCode=%d", n)
 	}
 
-	destinationAccount.Type = customers.ACCOUNTTYPE_SAVINGS
-	if n := determineTransactionCode(opts, destinationAccount); n != ach.SavingsDebit {
+	sourceAccount.Type = customers.ACCOUNTTYPE_SAVINGS
+	destinationAccount.Type = customers.ACCOUNTTYPE_CHECKING
+	if n := determineTransactionCode(opts, sourceAccount, destinationAccount); n != ach.SavingsDebit {
 		t.Errorf("unexpected TransactionCode=%d", n)
 	}
 }
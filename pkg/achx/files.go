@@ -43,6 +43,10 @@ type Options struct {
 	// the file config.
 	// TODO(adam): Should this have another fallback of data from the Customer object?
 	CompanyIdentification string
+
+	// TraceNumbers hands out the trace number of each entry. Random trace numbers
+	// are used when it's nil.
+	TraceNumbers TraceNumbers
 }
 
 func ConstructFile(id string, options Options, xfer *client.Transfer, source Source, destination Destination) (*ach.File, error) {
@@ -65,6 +69,20 @@ func ConstructFile(id string, options Options, xfer *client.Transfer, source Sou
 	file.Header.FileCreationDate = now.Format("060102") // YYMMDD
 	file.Header.FileCreationTime = now.Format("1504")   // HHMM
 
+	// International transfers are originated as IAT rather than PPD
+	if xfer.IatDetail != nil {
+		batch, err := createIATBatch(id, options, xfer, source, destination)
+		if err != nil {
+			return nil, fmt.Errorf("createBatch: IAT: %v", err)
+		}
+		file.AddIATBatch(*batch)
+
+		if err := file.Create(); err != nil {
+			return file, err
+		}
+		return file, file.Validate()
+	}
+
 	b, err := createPPDBatch(id, options, xfer, source, destination)
 	if err != nil {
 		return nil, fmt.Errorf("createBatch: PPD: %v", err)
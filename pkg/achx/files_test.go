@@ -5,6 +5,7 @@
 package achx
 
 import (
+	"errors"
 	"testing"
 	"time"
 
@@ -92,7 +93,7 @@ func TestFiles__ConstructFile(t *testing.T) {
 	}
 
 	for i := range entries {
-		if entries[i].TransactionCode == ach.CheckingCredit {
+		if entries[i].TransactionCode == ach.SavingsCredit {
 			if entries[i].RDFIIdentification != "98765432" {
 				t.Errorf("RDFIIdentification=%s", entries[i].RDFIIdentification)
 			}
@@ -107,7 +108,7 @@ func TestFiles__ConstructFile(t *testing.T) {
 			}
 			continue
 		}
-		if entries[i].TransactionCode == ach.SavingsDebit {
+		if entries[i].TransactionCode == ach.CheckingDebit {
 			if entries[i].RDFIIdentification != "12345678" {
 				t.Errorf("RDFIIdentification=%s", entries[i].RDFIIdentification)
 			}
@@ -126,6 +127,59 @@ func TestFiles__ConstructFile(t *testing.T) {
 	}
 }
 
+func TestFiles__ConstructFileTraceNumbers(t *testing.T) {
+	opts := Options{
+		ODFIRoutingNumber: "123456780",
+		CutoffTimezone:    time.UTC,
+		FileConfig: config.FileConfig{
+			BalanceEntries: true,
+		},
+		CompanyIdentification: "MOOVZZZZZZ",
+		TraceNumbers:          &mockTraceNumbers{seq: 41},
+	}
+	xfer := &client.Transfer{
+		Amount: client.Amount{
+			Currency: "USD",
+			Value:    1247,
+		},
+		Description: "test payment",
+	}
+	source := Source{
+		Customer: customers.Customer{FirstName: "John", LastName: "Doe"},
+		Account: customers.Account{
+			RoutingNumber: opts.ODFIRoutingNumber,
+			Type:          customers.ACCOUNTTYPE_CHECKING,
+		},
+		AccountNumber: "7654321",
+	}
+	destination := Destination{
+		Customer: customers.Customer{FirstName: "Jane", LastName: "Doe"},
+		Account: customers.Account{
+			RoutingNumber: "987654320",
+			Type:          customers.ACCOUNTTYPE_SAVINGS,
+		},
+		AccountNumber: "1234567",
+	}
+
+	file, err := ConstructFile(base.ID(), opts, xfer, source, destination)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := file.Batches[0].GetEntries()
+	if len(entries) != 2 {
+		t.Fatalf("unexpected entries: %#v", entries)
+	}
+	// the offset takes the next trace number of the sequence
+	if entries[0].TraceNumber != "123456780000042" || entries[1].TraceNumber != "123456780000043" {
+		t.Errorf("unexpected trace numbers %s and %s", entries[0].TraceNumber, entries[1].TraceNumber)
+	}
+
+	opts.TraceNumbers = &mockTraceNumbers{err: errors.New("bad error")}
+	if _, err := ConstructFile(base.ID(), opts, xfer, source, destination); err == nil {
+		t.Error("expected error")
+	}
+}
+
 func TestFiles__determineOrigin(t *testing.T) {
 	opts := Options{
 		ODFIRoutingNumber: "987654320",
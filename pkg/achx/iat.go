@@ -0,0 +1,148 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package achx
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/moov-io/ach"
+	"github.com/moov-io/paygate/pkg/client"
+)
+
+// createIATBatch creates an International ACH Transaction (IAT) batch from the Transfer's IatDetail.
+//
+// IAT batches aren't balanced with offsetting entries as NACHA requires each IAT entry carry
+// the addenda of its own Originator and Receiver.
+func createIATBatch(id string, options Options, xfer *client.Transfer, source Source, destination Destination) (*ach.IATBatch, error) {
+	detail := xfer.IatDetail
+	if detail == nil {
+		return nil, errors.New("missing IAT detail")
+	}
+	now := time.Now().In(options.CutoffTimezone)
+
+	bh := ach.NewIATBatchHeader()
+	bh.ID = id
+	if options.ODFIRoutingNumber == source.Account.RoutingNumber {
+		bh.ServiceClassCode = ach.CreditsOnly
+	} else {
+		bh.ServiceClassCode = ach.DebitsOnly
+	}
+	bh.ForeignExchangeIndicator = detail.ForeignExchangeIndicator
+	bh.ForeignExchangeReferenceIndicator = int(detail.ForeignExchangeReferenceIndicator)
+	bh.ForeignExchangeReference = detail.ForeignExchangeReference
+	bh.ISODestinationCountryCode = detail.DestinationCountryCode
+	bh.OriginatorIdentification = options.CompanyIdentification
+	bh.StandardEntryClassCode = ach.IAT
+	bh.CompanyEntryDescription = xfer.Description // 10 character max
+	bh.ISOOriginatingCurrencyCode = detail.OriginatingCurrencyCode
+	bh.ISODestinationCurrencyCode = detail.DestinationCurrencyCode
+	bh.EffectiveEntryDate = effectiveEntryDate(now, xfer) // Date to be posted, YYMMDD
+	bh.ODFIIdentification = ABA8(options.ODFIRoutingNumber)
+
+	entry, err := createIATEntry(id, options, xfer, source, destination)
+	if err != nil {
+		return nil, err
+	}
+
+	batch := ach.NewIATBatch(bh)
+	batch.AddEntry(entry)
+	if err := batch.Create(); err != nil {
+		return nil, fmt.Errorf("failed to create IAT batch: %v", err)
+	}
+	return &batch, nil
+}
+
+func createIATEntry(id string, options Options, xfer *client.Transfer, src Source, dst Destination) (*ach.IATEntryDetail, error) {
+	detail := xfer.IatDetail
+
+	ed := ach.NewIATEntryDetail()
+	ed.ID = id
+	ed.Amount = int(xfer.Amount.Value)
+	traceNumber, err := options.traceNumber()
+	if err != nil {
+		return nil, err
+	}
+	ed.TraceNumber = traceNumber
+	ed.Category = ach.CategoryForward
+	ed.AddendaRecordIndicator = 1
+	ed.AddendaRecords = 7 // Addenda10 through Addenda16
+
+	// Set fields based on which FI is getting the funds
+	ed.TransactionCode = determineTransactionCode(options, src.Account, dst.Account)
+	if options.ODFIRoutingNumber == src.Account.RoutingNumber {
+		// Credit
+		ed.RDFIIdentification = ABA8(dst.Account.RoutingNumber)
+		ed.CheckDigit = ABACheckDigit(dst.Account.RoutingNumber)
+		ed.DFIAccountNumber = dst.AccountNumber
+	} else {
+		// Debit
+		ed.RDFIIdentification = ABA8(src.Account.RoutingNumber)
+		ed.CheckDigit = ABACheckDigit(src.Account.RoutingNumber)
+		ed.DFIAccountNumber = src.AccountNumber
+	}
+
+	// Each addenda record refers to its entry from the sequence at the end of the trace number
+	seq, err := strconv.Atoi(ed.TraceNumber[len(ed.TraceNumber)-7:])
+	if err != nil {
+		return nil, fmt.Errorf("invalid trace number %s: %v", ed.TraceNumber, err)
+	}
+
+	ed.Addenda10 = ach.NewAddenda10()
+	ed.Addenda10.TransactionTypeCode = detail.TransactionTypeCode
+	ed.Addenda10.ForeignPaymentAmount = ed.Amount
+	ed.Addenda10.Name = detail.Receiver.Name
+	ed.Addenda10.EntryDetailSequenceNumber = seq
+
+	ed.Addenda11 = ach.NewAddenda11()
+	ed.Addenda11.OriginatorName = detail.Originator.Name
+	ed.Addenda11.OriginatorStreetAddress = detail.Originator.StreetAddress
+	ed.Addenda11.EntryDetailSequenceNumber = seq
+
+	ed.Addenda12 = ach.NewAddenda12()
+	ed.Addenda12.OriginatorCityStateProvince = cityStateProvince(detail.Originator)
+	ed.Addenda12.OriginatorCountryPostalCode = countryPostalCode(detail.Originator)
+	ed.Addenda12.EntryDetailSequenceNumber = seq
+
+	ed.Addenda13 = ach.NewAddenda13()
+	ed.Addenda13.ODFIName = options.Gateway.OriginName
+	ed.Addenda13.ODFIIDNumberQualifier = "01" // national clearing system number
+	ed.Addenda13.ODFIIdentification = options.ODFIRoutingNumber
+	ed.Addenda13.ODFIBranchCountryCode = "US"
+	ed.Addenda13.EntryDetailSequenceNumber = seq
+
+	ed.Addenda14 = ach.NewAddenda14()
+	ed.Addenda14.RDFIName = detail.ReceivingBank.Name
+	ed.Addenda14.RDFIIDNumberQualifier = detail.ReceivingBank.IdNumberQualifier
+	ed.Addenda14.RDFIIdentification = detail.ReceivingBank.Identification
+	ed.Addenda14.RDFIBranchCountryCode = detail.ReceivingBank.BranchCountryCode
+	ed.Addenda14.EntryDetailSequenceNumber = seq
+
+	ed.Addenda15 = ach.NewAddenda15()
+	ed.Addenda15.ReceiverIDNumber = detail.Receiver.IdentificationNumber
+	ed.Addenda15.ReceiverStreetAddress = detail.Receiver.StreetAddress
+	ed.Addenda15.EntryDetailSequenceNumber = seq
+
+	ed.Addenda16 = ach.NewAddenda16()
+	ed.Addenda16.ReceiverCityStateProvince = cityStateProvince(detail.Receiver)
+	ed.Addenda16.ReceiverCountryPostalCode = countryPostalCode(detail.Receiver)
+	ed.Addenda16.EntryDetailSequenceNumber = seq
+
+	return ed, nil
+}
+
+// cityStateProvince formats a party's city and state or province as NACHA requires, with
+// an asterisk between them and a trailing backslash.
+func cityStateProvince(party client.IatParty) string {
+	return fmt.Sprintf("%s*%s\\", party.City, party.StateProvince)
+}
+
+// countryPostalCode formats a party's country and postal code as NACHA requires, with
+// an asterisk between them and a trailing backslash.
+func countryPostalCode(party client.IatParty) string {
+	return fmt.Sprintf("%s*%s\\", party.CountryCode, party.PostalCode)
+}
@@ -0,0 +1,119 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package achx
+
+import (
+	"testing"
+	"time"
+
+	"github.com/moov-io/ach"
+	"github.com/moov-io/base"
+	customers "github.com/moov-io/customers/pkg/client"
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+)
+
+func TestIAT__ConstructFile(t *testing.T) {
+	opts := Options{
+		ODFIRoutingNumber: "123456780",
+		CutoffTimezone:    time.UTC,
+		Gateway: config.Gateway{
+			OriginName:      "My Bank",
+			DestinationName: "Their Bank",
+		},
+		CompanyIdentification: "MOOVZZZZZZ",
+	}
+	xfer := &client.Transfer{
+		Amount: client.Amount{
+			Currency: "USD",
+			Value:    1247,
+		},
+		Description: "payroll",
+		IatDetail: &client.IatDetail{
+			ForeignExchangeIndicator:          "FF",
+			ForeignExchangeReferenceIndicator: 3,
+			DestinationCountryCode:            "DE",
+			OriginatingCurrencyCode:           "USD",
+			DestinationCurrencyCode:           "EUR",
+			TransactionTypeCode:               "SAL",
+			Originator: client.IatParty{
+				Name:          "John Doe",
+				StreetAddress: "123 Main St",
+				City:          "Anytown",
+				StateProvince: "IA",
+				CountryCode:   "US",
+				PostalCode:    "50401",
+			},
+			Receiver: client.IatParty{
+				Name:          "Jane Doe",
+				StreetAddress: "Hauptstrasse 1",
+				City:          "Berlin",
+				CountryCode:   "DE",
+				PostalCode:    "10115",
+			},
+			ReceivingBank: client.IatBank{
+				Name:              "Receiving Bank",
+				IdNumberQualifier: "01",
+				Identification:    "987654320",
+				BranchCountryCode: "DE",
+			},
+		},
+	}
+	source := Source{
+		Account: customers.Account{
+			RoutingNumber: opts.ODFIRoutingNumber,
+			Type:          customers.ACCOUNTTYPE_CHECKING,
+		},
+		AccountNumber: "7654321",
+	}
+	destination := Destination{
+		Account: customers.Account{
+			RoutingNumber: "987654320",
+			Type:          customers.ACCOUNTTYPE_CHECKING,
+		},
+		AccountNumber: "1234567",
+	}
+
+	file, err := ConstructFile(base.ID(), opts, xfer, source, destination)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(file.Batches) != 0 || len(file.IATBatches) != 1 {
+		t.Fatalf("unexpected batches=%d IAT batches=%d", len(file.Batches), len(file.IATBatches))
+	}
+
+	batch := file.IATBatches[0]
+	if sec := batch.GetHeader().StandardEntryClassCode; sec != ach.IAT {
+		t.Errorf("unexpected SEC code: %s", sec)
+	}
+	if code := batch.GetHeader().ISODestinationCountryCode; code != "DE" {
+		t.Errorf("unexpected destination country: %s", code)
+	}
+
+	entries := batch.GetEntries()
+	if len(entries) != 1 {
+		t.Fatalf("unexpected entries: %d", len(entries))
+	}
+	ed := entries[0]
+	if ed.TransactionCode != ach.CheckingCredit || ed.Amount != 1247 {
+		t.Errorf("unexpected entry: %#v", ed)
+	}
+	if ed.Addenda10 == nil || ed.Addenda11 == nil || ed.Addenda12 == nil || ed.Addenda13 == nil ||
+		ed.Addenda14 == nil || ed.Addenda15 == nil || ed.Addenda16 == nil {
+		t.Fatal("missing mandatory IAT addenda")
+	}
+	if ed.Addenda12.OriginatorCityStateProvince != `Anytown*IA\` {
+		t.Errorf("unexpected originator city: %q", ed.Addenda12.OriginatorCityStateProvince)
+	}
+	if ed.Addenda16.ReceiverCountryPostalCode != `DE*10115\` {
+		t.Errorf("unexpected receiver country: %q", ed.Addenda16.ReceiverCountryPostalCode)
+	}
+
+	// Entries reads IAT batches as well
+	found := Entries(file)
+	if len(found) != 1 || found[0].TraceNumber != ed.TraceNumber || found[0].CreditOrDebit() != "C" {
+		t.Errorf("unexpected entries: %#v", found)
+	}
+}
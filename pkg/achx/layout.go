@@ -0,0 +1,49 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package achx
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/moov-io/ach"
+
+	"github.com/moov-io/paygate/pkg/config"
+)
+
+var paddingRecord = strings.Repeat("9", ach.RecordLength)
+
+// WriteFile encodes file in NACHA format following layout. The output is read back
+// before being written to w so a layout never produces a file that doesn't parse.
+func WriteFile(w io.Writer, file *ach.File, layout config.FileLayout) error {
+	if err := layout.Validate(); err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := ach.NewWriter(&buf).Write(file); err != nil {
+		return err
+	}
+	if layout.Padded() && layout.Terminator() == "\n" {
+		_, err := w.Write(buf.Bytes())
+		return err
+	}
+
+	records := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if !layout.Padded() {
+		for len(records) > 0 && records[len(records)-1] == paddingRecord {
+			records = records[:len(records)-1]
+		}
+	}
+	terminator := layout.Terminator()
+	out := strings.Join(records, terminator) + terminator
+
+	if _, err := ach.NewReader(strings.NewReader(out)).Read(); err != nil {
+		return fmt.Errorf("file does not parse with layout: %v", err)
+	}
+	_, err := io.WriteString(w, out)
+	return err
+}
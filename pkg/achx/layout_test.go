@@ -0,0 +1,53 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package achx
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/moov-io/ach"
+
+	"github.com/moov-io/paygate/pkg/config"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteFile(t *testing.T) {
+	file, err := ach.ReadFile(filepath.Join("..", "..", "testdata", "ppd-debit.ach"))
+	require.NoError(t, err)
+
+	var expected bytes.Buffer
+	require.NoError(t, ach.NewWriter(&expected).Write(file))
+
+	// the default layout matches moov-io/ach
+	var buf bytes.Buffer
+	require.NoError(t, WriteFile(&buf, file, config.FileLayout{}))
+	require.Equal(t, expected.String(), buf.String())
+
+	// CRLF records without padding
+	buf.Reset()
+	require.NoError(t, WriteFile(&buf, file, config.FileLayout{BlockingFactor: 1, LineEnding: "crlf"}))
+
+	records := strings.Split(strings.TrimSuffix(buf.String(), "\r\n"), "\r\n")
+	require.Len(t, records, 5)
+	require.True(t, strings.HasPrefix(records[4], "9000001"))
+	for i := range records {
+		require.Len(t, records[i], ach.RecordLength)
+	}
+
+	parsed, err := ach.NewReader(&buf).Read()
+	require.NoError(t, err)
+	require.NoError(t, parsed.Validate())
+
+	// padded CRLF records fill the block
+	buf.Reset()
+	require.NoError(t, WriteFile(&buf, file, config.FileLayout{LineEnding: "CRLF"}))
+	require.Equal(t, 10, strings.Count(buf.String(), "\r\n"))
+
+	require.Error(t, WriteFile(&buf, file, config.FileLayout{BlockingFactor: 5}))
+}
@@ -22,7 +22,10 @@ func createPPDBatch(id string, options Options, xfer *client.Transfer, source So
 		return nil, fmt.Errorf("failed to create PPD batch: %v", err)
 	}
 
-	entry := createPPDEntry(id, options, xfer, source, destination)
+	entry, err := createPPDEntry(id, options, xfer, source, destination)
+	if err != nil {
+		return nil, err
+	}
 	batch.AddEntry(entry)
 
 	if options.FileConfig.BalanceEntries {
@@ -41,7 +44,12 @@ func createPPDBatch(id string, options Options, xfer *client.Transfer, source So
 	return batch, nil
 }
 
-func createPPDEntry(id string, options Options, xfer *client.Transfer, src Source, dst Destination) *ach.EntryDetail {
+func createPPDEntry(id string, options Options, xfer *client.Transfer, src Source, dst Destination) (*ach.EntryDetail, error) {
+	traceNumber, err := options.traceNumber()
+	if err != nil {
+		return nil, err
+	}
+
 	ed := ach.NewEntryDetail()
 	ed.ID = id
 
@@ -49,11 +57,11 @@ func createPPDEntry(id string, options Options, xfer *client.Transfer, src Sourc
 	ed.Amount = int(xfer.Amount.Value)
 	ed.IdentificationNumber = createIdentificationNumber()
 	ed.DiscretionaryData = xfer.Description
-	ed.TraceNumber = TraceNumber(options.ODFIRoutingNumber)
+	ed.TraceNumber = traceNumber
 	ed.Category = ach.CategoryForward
 
 	// Set fields based on which FI is getting the funds
-	ed.TransactionCode = determineTransactionCode(options, src.Account)
+	ed.TransactionCode = determineTransactionCode(options, src.Account, dst.Account)
 	if options.ODFIRoutingNumber == src.Account.RoutingNumber {
 		// Credit
 		ed.RDFIIdentification = ABA8(dst.Account.RoutingNumber)
@@ -81,7 +89,7 @@ func createPPDEntry(id string, options Options, xfer *client.Transfer, src Sourc
 		ed.AddAddenda05(addenda05)
 	}
 
-	return ed
+	return ed, nil
 }
 
 func balancePPDEntry(entry *ach.EntryDetail, options Options, src Source, dst Destination) (*ach.EntryDetail, error) {
@@ -94,23 +102,34 @@ func balancePPDEntry(entry *ach.EntryDetail, options Options, src Source, dst De
 	ed.DiscretionaryData = "OFFSET"
 	ed.Category = ach.CategoryForward
 
-	trace, err := strconv.ParseInt(entry.TraceNumber, 10, 64)
-	if err != nil {
-		return nil, err
+	// Offsets take the next trace number of the ODFI's sequence, or follow their entry
+	// when trace numbers are random.
+	if options.TraceNumbers != nil {
+		traceNumber, err := options.traceNumber()
+		if err != nil {
+			return nil, err
+		}
+		ed.TraceNumber = traceNumber
+	} else {
+		trace, err := strconv.ParseInt(entry.TraceNumber, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		ed.TraceNumber = fmt.Sprintf("%d", trace+1)
 	}
-	ed.TraceNumber = fmt.Sprintf("%d", trace+1)
-
-	// Set fields based on which FI is getting the funds
-	ed.TransactionCode = determineTransactionCode(options, dst.Account)
 
+	// Set fields based on which FI is getting the funds, the offset moves them
+	// the opposite way of the Transfer's entry.
 	if options.ODFIRoutingNumber == src.Account.RoutingNumber {
-		// Credit
+		// Debit the ODFI's account for the credit
+		ed.TransactionCode = TransactionCode(src.Account.Type, false, false)
 		ed.RDFIIdentification = ABA8(src.Account.RoutingNumber)
 		ed.CheckDigit = ABACheckDigit(src.Account.RoutingNumber)
 		ed.DFIAccountNumber = src.AccountNumber
 		ed.IndividualName = fmt.Sprintf("%s %s", src.Customer.FirstName, src.Customer.LastName)
 	} else {
-		// Debit
+		// Credit the ODFI's account for the debit
+		ed.TransactionCode = TransactionCode(dst.Account.Type, true, false)
 		ed.RDFIIdentification = ABA8(dst.Account.RoutingNumber)
 		ed.CheckDigit = ABACheckDigit(dst.Account.RoutingNumber)
 		ed.DFIAccountNumber = dst.AccountNumber
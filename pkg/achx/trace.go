@@ -26,6 +26,29 @@ func TraceNumber(routingNumber string) string {
 	return v
 }
 
+// TraceNumbers hands out the trace numbers of entries an ODFI originates. Trace numbers are
+// the ODFI's 8-digit routing number prefix followed by a 7-digit sequence.
+type TraceNumbers interface {
+	Next(routingNumber string) (string, error)
+}
+
+// FormatTraceNumber returns the trace number of an ODFI's entry with the given sequence.
+func FormatTraceNumber(routingNumber string, seq int) string {
+	return fmt.Sprintf("%s%07d", ABA8(routingNumber), seq)
+}
+
+// traceNumber returns the next trace number of the ODFI, which is random without TraceNumbers.
+func (o Options) traceNumber() (string, error) {
+	if o.TraceNumbers == nil {
+		return TraceNumber(o.ODFIRoutingNumber), nil
+	}
+	traceNumber, err := o.TraceNumbers.Next(o.ODFIRoutingNumber)
+	if err != nil {
+		return "", fmt.Errorf("problem with trace number: %v", err)
+	}
+	return traceNumber, nil
+}
+
 // ABA8 returns the first 8 digits of an ABA routing number.
 // If the input is invalid then an empty string is returned.
 func ABA8(rtn string) string {
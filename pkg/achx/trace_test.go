@@ -5,6 +5,7 @@
 package achx
 
 import (
+	"errors"
 	"testing"
 )
 
@@ -31,3 +32,42 @@ func TestTraceNumber(t *testing.T) {
 		t.Error("empty trace number")
 	}
 }
+
+type mockTraceNumbers struct {
+	seq int
+	err error
+}
+
+func (m *mockTraceNumbers) Next(routingNumber string) (string, error) {
+	if m.err != nil {
+		return "", m.err
+	}
+	m.seq++
+	return FormatTraceNumber(routingNumber, m.seq), nil
+}
+
+func TestFormatTraceNumber(t *testing.T) {
+	if v := FormatTraceNumber("121042882", 1); v != "121042880000001" {
+		t.Errorf("got %s", v)
+	}
+	if v := FormatTraceNumber("121042882", 9999999); v != "121042889999999" {
+		t.Errorf("got %s", v)
+	}
+}
+
+func TestTrace__OptionsTraceNumber(t *testing.T) {
+	opts := Options{ODFIRoutingNumber: "121042882"}
+	if v, err := opts.traceNumber(); err != nil || len(v) != 15 {
+		t.Errorf("got %s: %v", v, err)
+	}
+
+	opts.TraceNumbers = &mockTraceNumbers{}
+	if v, err := opts.traceNumber(); err != nil || v != "121042880000001" {
+		t.Errorf("got %s: %v", v, err)
+	}
+
+	opts.TraceNumbers = &mockTraceNumbers{err: errors.New("bad error")}
+	if _, err := opts.traceNumber(); err == nil {
+		t.Error("expected error")
+	}
+}
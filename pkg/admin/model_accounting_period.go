@@ -0,0 +1,28 @@
+/*
+ * Paygate Admin API
+ *
+ * PayGate is a RESTful API enabling first-party Automated Clearing House ([ACH](https://en.wikipedia.org/wiki/Automated_Clearing_House)) transfers to be created without a deep understanding of a full NACHA file specification. First-party transfers initiate at an Originating Depository Financial Institution (ODFI) and are sent off to other Financial Institutions.  Refer to the [client endpoints](https://moov-io.github.io/paygate/) for customr facing operations.
+ *
+ * API version: v1
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package admin
+
+import (
+	"time"
+)
+
+// AccountingPeriod struct for AccountingPeriod
+type AccountingPeriod struct {
+	// Business day (YYYY-MM-DD) or month (YYYY-MM) in the ODFI's cutoff timezone
+	Period string `json:"period"`
+	// Transfers created at or after this time are included
+	Start time.Time `json:"start"`
+	// Transfers created before this time are included
+	End time.Time `json:"end"`
+	// When the period was closed and its totals frozen
+	Closed time.Time `json:"closed"`
+	// Frozen totals for each originating organization and SEC code. Only included when reading a single period.
+	Totals []PeriodTotal `json:"totals,omitempty"`
+}
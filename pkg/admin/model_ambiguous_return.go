@@ -0,0 +1,26 @@
+/*
+ * Paygate Admin API
+ *
+ * PayGate is a RESTful API enabling first-party Automated Clearing House ([ACH](https://en.wikipedia.org/wiki/Automated_Clearing_House)) transfers to be created without a deep understanding of a full NACHA file specification. First-party transfers initiate at an Originating Depository Financial Institution (ODFI) and are sent off to other Financial Institutions.  Refer to the [client endpoints](https://moov-io.github.io/paygate/) for customr facing operations.
+ *
+ * API version: v1
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package admin
+
+import (
+	"time"
+)
+
+// AmbiguousReturn A return entry which matched more than one Transfer, or only matched one weakly, held for an admin to resolve.
+type AmbiguousReturn struct {
+	Record InboundRecord `json:"record"`
+	// Transfers the return could be for, most likely first
+	Candidates []ReturnCandidate `json:"candidates"`
+	// Options: pending, resolved, dismissed
+	Status string `json:"status"`
+	// Transfer the return was applied to once resolved
+	TransferID string     `json:"transferID,omitempty"`
+	Resolved   *time.Time `json:"resolved,omitempty"`
+}
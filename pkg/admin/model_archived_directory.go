@@ -0,0 +1,24 @@
+/*
+ * Paygate Admin API
+ *
+ * PayGate is a RESTful API enabling first-party Automated Clearing House ([ACH](https://en.wikipedia.org/wiki/Automated_Clearing_House)) transfers to be created without a deep understanding of a full NACHA file specification. First-party transfers initiate at an Originating Depository Financial Institution (ODFI) and are sent off to other Financial Institutions.  Refer to the [client endpoints](https://moov-io.github.io/paygate/) for customr facing operations.
+ *
+ * API version: v1
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package admin
+
+// ArchivedDirectory struct for ArchivedDirectory
+type ArchivedDirectory struct {
+	// Which files the directory held: merged or inbound
+	Kind string `json:"kind,omitempty"`
+	// Name of the directory on disk
+	Name string `json:"name,omitempty"`
+	// Size of the directory's files in bytes
+	Bytes int64 `json:"bytes,omitempty"`
+	// What happened to the directory: archived or deleted
+	Action string `json:"action,omitempty"`
+	// Key of the compressed archive in the bucket, empty for deleted directories
+	Archive string `json:"archive,omitempty"`
+}
@@ -0,0 +1,40 @@
+/*
+ * Paygate Admin API
+ *
+ * PayGate is a RESTful API enabling first-party Automated Clearing House ([ACH](https://en.wikipedia.org/wiki/Automated_Clearing_House)) transfers to be created without a deep understanding of a full NACHA file specification. First-party transfers initiate at an Originating Depository Financial Institution (ODFI) and are sent off to other Financial Institutions.  Refer to the [client endpoints](https://moov-io.github.io/paygate/) for customr facing operations.
+ *
+ * API version: v1
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package admin
+
+import (
+	"time"
+)
+
+// AuditRecord struct for AuditRecord
+type AuditRecord struct {
+	// Identifier of the audit record
+	RecordID     string `json:"recordID,omitempty"`
+	Organization string `json:"organization,omitempty"`
+	// X-User-ID of who made the request
+	UserID string `json:"userID,omitempty"`
+	// X-Request-ID of the request
+	RequestID string `json:"requestID,omitempty"`
+	// IP address the request came from
+	RemoteAddress string `json:"remoteAddress,omitempty"`
+	// HTTP method of the request
+	Method string `json:"method,omitempty"`
+	// Route the request matched, like /transfers/{transferID}
+	Endpoint string `json:"endpoint,omitempty"`
+	// Path of the request
+	Path string `json:"path,omitempty"`
+	// Type of resource changed, the first part of the endpoint
+	Resource string `json:"resource,omitempty"`
+	// HTTP status code of the response
+	Status int `json:"status,omitempty"`
+	// Fields sent in the request body with sensitive values redacted
+	Body    string    `json:"body,omitempty"`
+	Created time.Time `json:"created,omitempty"`
+}
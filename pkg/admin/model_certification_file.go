@@ -0,0 +1,27 @@
+/*
+ * Paygate Admin API
+ *
+ * PayGate is a RESTful API enabling first-party Automated Clearing House ([ACH](https://en.wikipedia.org/wiki/Automated_Clearing_House)) transfers to be created without a deep understanding of a full NACHA file specification. First-party transfers initiate at an Originating Depository Financial Institution (ODFI) and are sent off to other Financial Institutions.  Refer to the [client endpoints](https://moov-io.github.io/paygate/) for customr facing operations.
+ *
+ * API version: v1
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package admin
+
+import (
+	"time"
+)
+
+// CertificationFile struct for CertificationFile
+type CertificationFile struct {
+	// Name of the scenario the file was generated for
+	Scenario string `json:"scenario,omitempty"`
+	// Filename uploaded to the test server
+	Filename string `json:"filename,omitempty"`
+	// Routing number of the test ODFI
+	RoutingNumber string `json:"routingNumber,omitempty"`
+	// Hostname of the test server
+	Hostname string    `json:"hostname,omitempty"`
+	Uploaded time.Time `json:"uploaded,omitempty"`
+}
@@ -0,0 +1,18 @@
+/*
+ * Paygate Admin API
+ *
+ * PayGate is a RESTful API enabling first-party Automated Clearing House ([ACH](https://en.wikipedia.org/wiki/Automated_Clearing_House)) transfers to be created without a deep understanding of a full NACHA file specification. First-party transfers initiate at an Originating Depository Financial Institution (ODFI) and are sent off to other Financial Institutions.  Refer to the [client endpoints](https://moov-io.github.io/paygate/) for customr facing operations.
+ *
+ * API version: v1
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package admin
+
+// CertificationScenario struct for CertificationScenario
+type CertificationScenario struct {
+	// Name of the scenario, used to generate its file
+	Name string `json:"name,omitempty"`
+	// Which NACHA rule the generated file breaks
+	Description string `json:"description,omitempty"`
+}
@@ -0,0 +1,29 @@
+/*
+ * Paygate Admin API
+ *
+ * PayGate is a RESTful API enabling first-party Automated Clearing House ([ACH](https://en.wikipedia.org/wiki/Automated_Clearing_House)) transfers to be created without a deep understanding of a full NACHA file specification. First-party transfers initiate at an Originating Depository Financial Institution (ODFI) and are sent off to other Financial Institutions.  Refer to the [client endpoints](https://moov-io.github.io/paygate/) for customr facing operations.
+ *
+ * API version: v1
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package admin
+
+import (
+	"time"
+)
+
+// DeadLetter struct for DeadLetter
+type DeadLetter struct {
+	// Identifier of the dead-lettered message, used to replay it
+	LetterID string `json:"letterID,omitempty"`
+	// Type of pipeline message: xfer, cancel or unknown
+	Type string `json:"type,omitempty"`
+	// transferID of the message, empty for messages which couldn't be read
+	TransferID string `json:"transferID,omitempty"`
+	// Error from the last attempt at handling the message
+	Error string `json:"error,omitempty"`
+	// How many times the message was handled before it was dead-lettered
+	Attempts int32     `json:"attempts,omitempty"`
+	Created  time.Time `json:"created,omitempty"`
+}
@@ -0,0 +1,30 @@
+/*
+ * Paygate Admin API
+ *
+ * PayGate is a RESTful API enabling first-party Automated Clearing House ([ACH](https://en.wikipedia.org/wiki/Automated_Clearing_House)) transfers to be created without a deep understanding of a full NACHA file specification. First-party transfers initiate at an Originating Depository Financial Institution (ODFI) and are sent off to other Financial Institutions.  Refer to the [client endpoints](https://moov-io.github.io/paygate/) for customr facing operations.
+ *
+ * API version: v1
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package admin
+
+import (
+	"time"
+)
+
+// ErasureJob A right-to-erasure request for one Customer and the data it removed
+type ErasureJob struct {
+	JobID        string `json:"jobID"`
+	Organization string `json:"organization"`
+	CustomerID   string `json:"customerID"`
+	Status       string `json:"status"`
+	// Who asked for the Customer's data to be erased
+	RequestedBy string `json:"requestedBy"`
+	// Only included for failed jobs
+	Error     string     `json:"error,omitempty"`
+	Created   time.Time  `json:"created"`
+	Completed *time.Time `json:"completed,omitempty"`
+	// What was removed from each table, empty until the job completes
+	Items []ErasedData `json:"items,omitempty"`
+}
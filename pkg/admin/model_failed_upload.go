@@ -0,0 +1,26 @@
+/*
+ * Paygate Admin API
+ *
+ * PayGate is a RESTful API enabling first-party Automated Clearing House ([ACH](https://en.wikipedia.org/wiki/Automated_Clearing_House)) transfers to be created without a deep understanding of a full NACHA file specification. First-party transfers initiate at an Originating Depository Financial Institution (ODFI) and are sent off to other Financial Institutions.  Refer to the [client endpoints](https://moov-io.github.io/paygate/) for customr facing operations.
+ *
+ * API version: v1
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package admin
+
+import (
+	"time"
+)
+
+// FailedUpload struct for FailedUpload
+type FailedUpload struct {
+	// Identifies the merged file, as several files can share a filename
+	FileID string `json:"fileID,omitempty"`
+	// Filename the merged file would have been uploaded as
+	Filename string `json:"filename,omitempty"`
+	// Count of entries in the file
+	Entries int32     `json:"entries,omitempty"`
+	Error   string    `json:"error,omitempty"`
+	Created time.Time `json:"created,omitempty"`
+}
@@ -0,0 +1,38 @@
+/*
+ * Paygate Admin API
+ *
+ * PayGate is a RESTful API enabling first-party Automated Clearing House ([ACH](https://en.wikipedia.org/wiki/Automated_Clearing_House)) transfers to be created without a deep understanding of a full NACHA file specification. First-party transfers initiate at an Originating Depository Financial Institution (ODFI) and are sent off to other Financial Institutions.  Refer to the [client endpoints](https://moov-io.github.io/paygate/) for customr facing operations.
+ *
+ * API version: v1
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package admin
+
+import (
+	"time"
+)
+
+// InboundRecord A return or Notification of Change entry received from an RDFI, stored as it was sent.
+type InboundRecord struct {
+	RecordID string `json:"recordID"`
+	// Transfer the entry was matched to. Empty when no Transfer was found.
+	TransferID string `json:"transferID,omitempty"`
+	// Kind of entry. Options: return, correction
+	Type string `json:"type"`
+	// Return or change code from the addenda record. Example: R01, C01
+	Code string `json:"code"`
+	// Trace number of the returned or corrected entry.
+	TraceNumber string `json:"traceNumber"`
+	// Trace number of the original entry from the addenda record.
+	OriginalTrace string `json:"originalTrace"`
+	// ImmediateOrigin of the file the entry was received in.
+	ImmediateOrigin string `json:"immediateOrigin"`
+	// ImmediateDestination of the file the entry was received in.
+	ImmediateDestination string `json:"immediateDestination"`
+	// Raw NACHA formatted EntryDetail record.
+	EntryDetail string `json:"entryDetail"`
+	// Raw NACHA formatted Addenda99 (return) or Addenda98 (correction) record.
+	Addenda string    `json:"addenda"`
+	Created time.Time `json:"created"`
+}
@@ -0,0 +1,21 @@
+/*
+ * Paygate Admin API
+ *
+ * PayGate is a RESTful API enabling first-party Automated Clearing House ([ACH](https://en.wikipedia.org/wiki/Automated_Clearing_House)) transfers to be created without a deep understanding of a full NACHA file specification. First-party transfers initiate at an Originating Depository Financial Institution (ODFI) and are sent off to other Financial Institutions.  Refer to the [client endpoints](https://moov-io.github.io/paygate/) for customr facing operations.
+ *
+ * API version: v1
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package admin
+
+// IntegrityProblem struct for IntegrityProblem
+type IntegrityProblem struct {
+	// Database table of the unreadable row
+	Table string `json:"table,omitempty"`
+	// Primary key of the unreadable row
+	ID           string `json:"id,omitempty"`
+	Organization string `json:"organization,omitempty"`
+	// Error from reading the row
+	Error string `json:"error,omitempty"`
+}
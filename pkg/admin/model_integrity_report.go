@@ -0,0 +1,24 @@
+/*
+ * Paygate Admin API
+ *
+ * PayGate is a RESTful API enabling first-party Automated Clearing House ([ACH](https://en.wikipedia.org/wiki/Automated_Clearing_House)) transfers to be created without a deep understanding of a full NACHA file specification. First-party transfers initiate at an Originating Depository Financial Institution (ODFI) and are sent off to other Financial Institutions.  Refer to the [client endpoints](https://moov-io.github.io/paygate/) for customr facing operations.
+ *
+ * API version: v1
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package admin
+
+import (
+	"time"
+)
+
+// IntegrityReport struct for IntegrityReport
+type IntegrityReport struct {
+	// How many rows were read
+	Checked int32 `json:"checked"`
+	// Rows which couldn't be read and are omitted from API responses
+	Problems []IntegrityProblem `json:"problems"`
+	Started  time.Time          `json:"started,omitempty"`
+	Finished time.Time          `json:"finished,omitempty"`
+}
@@ -0,0 +1,26 @@
+/*
+ * Paygate Admin API
+ *
+ * PayGate is a RESTful API enabling first-party Automated Clearing House ([ACH](https://en.wikipedia.org/wiki/Automated_Clearing_House)) transfers to be created without a deep understanding of a full NACHA file specification. First-party transfers initiate at an Originating Depository Financial Institution (ODFI) and are sent off to other Financial Institutions.  Refer to the [client endpoints](https://moov-io.github.io/paygate/) for customr facing operations.
+ *
+ * API version: v1
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package admin
+
+import (
+	"time"
+)
+
+// KillSwitch struct for KillSwitch
+type KillSwitch struct {
+	// Either global or the organization whose debit Transfers are blocked
+	Scope string `json:"scope"`
+	// Where the switch was enabled, either config or admin
+	Source string `json:"source"`
+	// Why debits were blocked
+	Reason string `json:"reason,omitempty"`
+	// When the switch was enabled by an admin
+	Enabled *time.Time `json:"enabled,omitempty"`
+}
@@ -0,0 +1,31 @@
+/*
+ * Paygate Admin API
+ *
+ * PayGate is a RESTful API enabling first-party Automated Clearing House ([ACH](https://en.wikipedia.org/wiki/Automated_Clearing_House)) transfers to be created without a deep understanding of a full NACHA file specification. First-party transfers initiate at an Originating Depository Financial Institution (ODFI) and are sent off to other Financial Institutions.  Refer to the [client endpoints](https://moov-io.github.io/paygate/) for customr facing operations.
+ *
+ * API version: v1
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package admin
+
+import (
+	"time"
+)
+
+// LimitOverride Limits which replace the configured defaults for one organization or user. Zero values keep the default.
+type LimitOverride struct {
+	// Either organization or user
+	Scope string `json:"scope"`
+	// Organization or user ID the limits apply to
+	ID string `json:"id"`
+	// Transfers over this amount (in cents) are held for review.
+	SoftLimit int64 `json:"softLimit,omitempty"`
+	// Transfers over this amount (in cents) are rejected.
+	HardLimit int64 `json:"hardLimit,omitempty"`
+	// Total debits (in cents) allowed within transfers.limits.exposure.window.
+	DebitExposure int64 `json:"debitExposure,omitempty"`
+	// Total credits (in cents) allowed within transfers.limits.exposure.window.
+	CreditExposure int64      `json:"creditExposure,omitempty"`
+	UpdatedAt      *time.Time `json:"updatedAt,omitempty"`
+}
@@ -0,0 +1,33 @@
+/*
+ * Paygate Admin API
+ *
+ * PayGate is a RESTful API enabling first-party Automated Clearing House ([ACH](https://en.wikipedia.org/wiki/Automated_Clearing_House)) transfers to be created without a deep understanding of a full NACHA file specification. First-party transfers initiate at an Originating Depository Financial Institution (ODFI) and are sent off to other Financial Institutions.  Refer to the [client endpoints](https://moov-io.github.io/paygate/) for customr facing operations.
+ *
+ * API version: v1
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package admin
+
+import (
+	"time"
+)
+
+// OfacMatch struct for OfacMatch
+type OfacMatch struct {
+	Organization string `json:"organization"`
+	CustomerID   string `json:"customerID"`
+	// OFAC entity the Customer matched
+	EntityID string `json:"entityID"`
+	SdnName  string `json:"sdnName"`
+	// Match score between 0 and 1
+	Match float32 `json:"match"`
+	// Suspended Customers can't be used in new Transfers
+	Status string `json:"status"`
+	// When the Customer first matched the entity
+	Matched time.Time `json:"matched"`
+	// When the Customer was last screened
+	Screened   time.Time  `json:"screened"`
+	Reviewed   *time.Time `json:"reviewed,omitempty"`
+	ReviewedBy string     `json:"reviewedBy,omitempty"`
+}
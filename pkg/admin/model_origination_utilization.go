@@ -0,0 +1,28 @@
+/*
+ * Paygate Admin API
+ *
+ * PayGate is a RESTful API enabling first-party Automated Clearing House ([ACH](https://en.wikipedia.org/wiki/Automated_Clearing_House)) transfers to be created without a deep understanding of a full NACHA file specification. First-party transfers initiate at an Originating Depository Financial Institution (ODFI) and are sent off to other Financial Institutions.  Refer to the [client endpoints](https://moov-io.github.io/paygate/) for customr facing operations.
+ *
+ * API version: v1
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package admin
+
+// OriginationUtilization struct for OriginationUtilization
+type OriginationUtilization struct {
+	// ABA routing number files are uploaded for
+	RoutingNumber string `json:"routingNumber,omitempty"`
+	// Day (YYYY-MM-DD) of the utilization
+	Day string `json:"day,omitempty"`
+	// Sum of debits and credits in cents uploaded on the day
+	Originated int64 `json:"originated,omitempty"`
+	// Daily origination cap in cents, omitted when the routing number has no cap
+	Cap int64 `json:"cap,omitempty"`
+	// Cents left under the cap
+	Remaining int64 `json:"remaining,omitempty"`
+	// Percent of the cap used
+	Percent float32 `json:"percent,omitempty"`
+	// Files over the cap are uploaded after logging a warning rather than blocked
+	WarnOnly bool `json:"warnOnly,omitempty"`
+}
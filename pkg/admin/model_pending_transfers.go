@@ -0,0 +1,20 @@
+/*
+ * Paygate Admin API
+ *
+ * PayGate is a RESTful API enabling first-party Automated Clearing House ([ACH](https://en.wikipedia.org/wiki/Automated_Clearing_House)) transfers to be created without a deep understanding of a full NACHA file specification. First-party transfers initiate at an Originating Depository Financial Institution (ODFI) and are sent off to other Financial Institutions.  Refer to the [client endpoints](https://moov-io.github.io/paygate/) for customr facing operations.
+ *
+ * API version: v1
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package admin
+
+// PendingTransfers struct for PendingTransfers
+type PendingTransfers struct {
+	// ABA routing number of the receiving financial institution
+	RoutingNumber string `json:"routingNumber,omitempty"`
+	// Count of transfers waiting to be merged and uploaded
+	Transfers int32 `json:"transfers,omitempty"`
+	// Sum of entry amounts in the smallest currency unit (cents)
+	TotalAmount int64 `json:"totalAmount,omitempty"`
+}
@@ -0,0 +1,29 @@
+/*
+ * Paygate Admin API
+ *
+ * PayGate is a RESTful API enabling first-party Automated Clearing House ([ACH](https://en.wikipedia.org/wiki/Automated_Clearing_House)) transfers to be created without a deep understanding of a full NACHA file specification. First-party transfers initiate at an Originating Depository Financial Institution (ODFI) and are sent off to other Financial Institutions.  Refer to the [client endpoints](https://moov-io.github.io/paygate/) for customr facing operations.
+ *
+ * API version: v1
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package admin
+
+import (
+	"time"
+)
+
+// PendingVerification Micro-deposits which are waiting for the Customer to confirm their amounts
+type PendingVerification struct {
+	MicroDepositID string `json:"microDepositID"`
+	Organization   string `json:"organization"`
+	CustomerID     string `json:"customerID"`
+	AccountID      string `json:"accountID"`
+	// Status of the account verification, either initiated or processed
+	Status    string     `json:"status"`
+	Attempt   int32      `json:"attempt"`
+	Created   time.Time  `json:"created"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+	// How long ago the micro-deposits were initiated
+	Age string `json:"age"`
+}
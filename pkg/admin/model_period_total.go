@@ -0,0 +1,32 @@
+/*
+ * Paygate Admin API
+ *
+ * PayGate is a RESTful API enabling first-party Automated Clearing House ([ACH](https://en.wikipedia.org/wiki/Automated_Clearing_House)) transfers to be created without a deep understanding of a full NACHA file specification. First-party transfers initiate at an Originating Depository Financial Institution (ODFI) and are sent off to other Financial Institutions.  Refer to the [client endpoints](https://moov-io.github.io/paygate/) for customr facing operations.
+ *
+ * API version: v1
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package admin
+
+// PeriodTotal struct for PeriodTotal
+type PeriodTotal struct {
+	// Organization which originated the Transfers
+	Organization string `json:"organization"`
+	// Standard Entry Class code of the Transfers' batches
+	SecCode string `json:"secCode"`
+	// Count of Transfers created in the period, excluding canceled Transfers
+	Transfers int64 `json:"transfers"`
+	// Count of Transfers which debited an account at another financial institution
+	DebitCount int64 `json:"debitCount"`
+	// Total debited in cents
+	DebitAmount int64 `json:"debitAmount"`
+	// Count of Transfers which credited an account at another financial institution
+	CreditCount int64 `json:"creditCount"`
+	// Total credited in cents
+	CreditAmount int64 `json:"creditAmount"`
+	// Count of Transfers returned when the period was closed
+	ReturnCount int64 `json:"returnCount"`
+	// Total amount of returned Transfers in cents
+	ReturnAmount int64 `json:"returnAmount"`
+}
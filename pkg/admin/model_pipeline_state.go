@@ -0,0 +1,21 @@
+/*
+ * Paygate Admin API
+ *
+ * PayGate is a RESTful API enabling first-party Automated Clearing House ([ACH](https://en.wikipedia.org/wiki/Automated_Clearing_House)) transfers to be created without a deep understanding of a full NACHA file specification. First-party transfers initiate at an Originating Depository Financial Institution (ODFI) and are sent off to other Financial Institutions.  Refer to the [client endpoints](https://moov-io.github.io/paygate/) for customr facing operations.
+ *
+ * API version: v1
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package admin
+
+// PipelineState struct for PipelineState
+type PipelineState struct {
+	// Transfers waiting for the next cutoff grouped by the receiving financial institution
+	PendingTransfers []PendingTransfers `json:"pendingTransfers"`
+	// Merged files which failed to upload since the worker started
+	FailedUploads []FailedUpload   `json:"failedUploads"`
+	Cutoffs       []UpcomingCutoff `json:"cutoffs"`
+	Inbound       *InboundSync     `json:"inbound,omitempty"`
+	RecentErrors  []PipelineError  `json:"recentErrors"`
+}
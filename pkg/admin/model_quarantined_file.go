@@ -0,0 +1,27 @@
+/*
+ * Paygate Admin API
+ *
+ * PayGate is a RESTful API enabling first-party Automated Clearing House ([ACH](https://en.wikipedia.org/wiki/Automated_Clearing_House)) transfers to be created without a deep understanding of a full NACHA file specification. First-party transfers initiate at an Originating Depository Financial Institution (ODFI) and are sent off to other Financial Institutions.  Refer to the [client endpoints](https://moov-io.github.io/paygate/) for customr facing operations.
+ *
+ * API version: v1
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package admin
+
+import (
+	"time"
+)
+
+// QuarantinedFile struct for QuarantinedFile
+type QuarantinedFile struct {
+	// Unique identifier of the quarantined file
+	QuarantineID string `json:"quarantineID,omitempty"`
+	// Filename as downloaded from the ODFI
+	Filename string `json:"filename,omitempty"`
+	// Why the file was quarantined rather than processed
+	Reason string `json:"reason,omitempty"`
+	// ImmediateOrigin from the file's FileHeader, if it could be read
+	Origin  string    `json:"origin,omitempty"`
+	Created time.Time `json:"created,omitempty"`
+}
@@ -0,0 +1,26 @@
+/*
+ * Paygate Admin API
+ *
+ * PayGate is a RESTful API enabling first-party Automated Clearing House ([ACH](https://en.wikipedia.org/wiki/Automated_Clearing_House)) transfers to be created without a deep understanding of a full NACHA file specification. First-party transfers initiate at an Originating Depository Financial Institution (ODFI) and are sent off to other Financial Institutions.  Refer to the [client endpoints](https://moov-io.github.io/paygate/) for customr facing operations.
+ *
+ * API version: v1
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package admin
+
+import (
+	"time"
+)
+
+// UnprocessedTransfer struct for UnprocessedTransfer
+type UnprocessedTransfer struct {
+	// transferID which was uploaded but couldn't be marked as processed
+	TransferID string `json:"transferID,omitempty"`
+	// Error from the latest attempt to mark the transfer as processed
+	Error string `json:"error,omitempty"`
+	// How many times marking the transfer as processed has failed
+	Attempts    int32     `json:"attempts,omitempty"`
+	Created     time.Time `json:"created,omitempty"`
+	LastAttempt time.Time `json:"lastAttempt,omitempty"`
+}
@@ -0,0 +1,23 @@
+/*
+ * Paygate Admin API
+ *
+ * PayGate is a RESTful API enabling first-party Automated Clearing House ([ACH](https://en.wikipedia.org/wiki/Automated_Clearing_House)) transfers to be created without a deep understanding of a full NACHA file specification. First-party transfers initiate at an Originating Depository Financial Institution (ODFI) and are sent off to other Financial Institutions.  Refer to the [client endpoints](https://moov-io.github.io/paygate/) for customr facing operations.
+ *
+ * API version: v1
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package admin
+
+import (
+	"time"
+)
+
+// UpcomingCutoff struct for UpcomingCutoff
+type UpcomingCutoff struct {
+	// ABA routing number of the ODFI files are uploaded to
+	RoutingNumber string    `json:"routingNumber,omitempty"`
+	Cutoff        time.Time `json:"cutoff,omitempty"`
+	// Seconds remaining until the cutoff
+	SecondsRemaining int64 `json:"secondsRemaining,omitempty"`
+}
@@ -0,0 +1,28 @@
+/*
+ * Paygate Admin API
+ *
+ * PayGate is a RESTful API enabling first-party Automated Clearing House ([ACH](https://en.wikipedia.org/wiki/Automated_Clearing_House)) transfers to be created without a deep understanding of a full NACHA file specification. First-party transfers initiate at an Originating Depository Financial Institution (ODFI) and are sent off to other Financial Institutions.  Refer to the [client endpoints](https://moov-io.github.io/paygate/) for customr facing operations.
+ *
+ * API version: v1
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package admin
+
+// UploadRetry struct for UploadRetry
+type UploadRetry struct {
+	// Identifies the merged file, as several files can share a filename
+	FileID string `json:"fileID"`
+	// Filename the merged file was uploaded as
+	Filename string `json:"filename"`
+	// Routing number the file is sent to
+	RoutingNumber string `json:"routingNumber"`
+	// Hostname of the ODFI's server
+	RemoteServer string `json:"remoteServer"`
+	// Directory on the ODFI's server the file is uploaded into
+	RemotePath string `json:"remotePath"`
+	// If the file was uploaded
+	Uploaded bool `json:"uploaded"`
+	// Error from uploading the file, such as the SFTP or FTP agent's error
+	Error string `json:"error,omitempty"`
+}
@@ -0,0 +1,38 @@
+/*
+ * Paygate Admin API
+ *
+ * PayGate is a RESTful API enabling first-party Automated Clearing House ([ACH](https://en.wikipedia.org/wiki/Automated_Clearing_House)) transfers to be created without a deep understanding of a full NACHA file specification. First-party transfers initiate at an Originating Depository Financial Institution (ODFI) and are sent off to other Financial Institutions.  Refer to the [client endpoints](https://moov-io.github.io/paygate/) for customr facing operations.
+ *
+ * API version: v1
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package admin
+
+import (
+	"time"
+)
+
+// UploadedFile struct for UploadedFile
+type UploadedFile struct {
+	// Identifies the upload, as several uploads can share a filename
+	FileID string `json:"fileID,omitempty"`
+	// Filename as uploaded to the ODFI
+	Filename string `json:"filename,omitempty"`
+	// ImmediateDestination of the uploaded file
+	RoutingNumber string `json:"routingNumber,omitempty"`
+	// Hostname of the ODFI server the file was uploaded to
+	RemoteServer string `json:"remoteServer,omitempty"`
+	// Directory on the ODFI server the file was uploaded into
+	RemotePath string `json:"remotePath,omitempty"`
+	// Size in bytes of the uploaded contents
+	Size int64 `json:"size,omitempty"`
+	// Hex encoded SHA-256 checksum of the uploaded contents
+	Checksum string `json:"checksum,omitempty"`
+	// Transfers with an entry in the file
+	TransferIDs []string        `json:"transferIDs,omitempty"`
+	Batches     []UploadedBatch `json:"batches,omitempty"`
+	Uploaded    time.Time       `json:"uploaded,omitempty"`
+	// When the ODFI's acknowledgement of this file was processed
+	Acknowledged *time.Time `json:"acknowledged,omitempty"`
+}
@@ -0,0 +1,48 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package anonymize
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/moov-io/base/admin"
+	moovhttp "github.com/moov-io/base/http"
+
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/x/adminauth"
+)
+
+// RegisterAdminRoutes adds 'POST /anonymize' which rewrites personal data in the database.
+func RegisterAdminRoutes(cfg *config.Config, svc *admin.Server, anonymizer *Anonymizer) {
+	if cfg.Anonymize == nil || anonymizer == nil {
+		return
+	}
+	svc.AddHandler("/anonymize", adminauth.Protect(cfg.Admin.Signing, anonymizeDatabase(anonymizer)))
+}
+
+func problem(w http.ResponseWriter, err error) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	moovhttp.Problem(w, err)
+}
+
+func anonymizeDatabase(anonymizer *Anonymizer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			problem(w, fmt.Errorf("invalid method %s", r.Method))
+			return
+		}
+		result, err := anonymizer.Run()
+		if err != nil {
+			problem(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(result)
+	}
+}
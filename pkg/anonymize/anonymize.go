@@ -0,0 +1,247 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+// Package anonymize rewrites personal data stored by PayGate with fake values so production
+// snapshots can be restored into staging. Each value is replaced by a keyed hash of itself,
+// so a customer or account ID becomes the same fake ID in every table which references it.
+// Statuses, amounts and timestamps are left as-is.
+package anonymize
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/moov-io/base/log"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+)
+
+type kind int
+
+const (
+	// kindID replaces a value with hex characters of the same length
+	kindID kind = iota
+	// kindDigits replaces a value with digits of the same length
+	kindDigits
+	// kindText replaces a value with fake words no longer than the original
+	kindText
+	// kindFilename replaces a filename and keeps its extension
+	kindFilename
+	// kindIP replaces an IP address with one in 10.0.0.0/8
+	kindIP
+	// kindEmail replaces an email address with one at example.com
+	kindEmail
+	// kindTransferTemplate replaces the IDs and description of a JSON encoded CreateTransfer
+	kindTransferTemplate
+)
+
+type column struct {
+	table string
+	name  string
+	kind  kind
+}
+
+// columns lists every column holding personal data. Customer and account details (names,
+// emails and account numbers) live in the Customers service and aren't stored here.
+var columns = []column{
+	{"organization_configs", "organization", kindID},
+	{"organization_configs", "company_identification", kindDigits},
+
+	{"transfers", "organization", kindID},
+	{"transfers", "source_customer_id", kindID},
+	{"transfers", "source_account_id", kindID},
+	{"transfers", "destination_customer_id", kindID},
+	{"transfers", "destination_account_id", kindID},
+	{"transfers", "description", kindText},
+	{"transfers", "remote_address", kindIP},
+
+	{"micro_deposits", "destination_customer_id", kindID},
+	{"micro_deposits", "destination_account_id", kindID},
+
+	{"attachments", "organization", kindID},
+	{"attachments", "customer_id", kindID},
+	{"attachments", "account_id", kindID},
+	{"attachments", "note", kindText},
+	{"attachments", "filename", kindFilename},
+
+	{"api_tokens", "organization", kindID},
+	{"api_tokens", "token_hash", kindID},
+	{"api_tokens", "source_customer_id", kindID},
+	{"api_tokens", "source_account_id", kindID},
+	{"api_token_receivers", "customer_id", kindID},
+	{"api_token_receivers", "account_id", kindID},
+
+	{"scheduled_transfers", "organization", kindID},
+	{"scheduled_transfers", "user_id", kindID},
+	{"scheduled_transfers", "template", kindTransferTemplate},
+
+	{"prenotes", "organization", kindID},
+	{"prenotes", "destination_customer_id", kindID},
+	{"prenotes", "destination_account_id", kindID},
+
+	{"notification_preferences", "organization", kindID},
+	{"notification_preferences", "email", kindEmail},
+
+	{"audit_records", "organization", kindID},
+	{"audit_records", "user_id", kindID},
+	{"audit_records", "remote_address", kindIP},
+	{"audit_records", "body", kindText},
+}
+
+// Result counts the rows rewritten in each table.
+type Result struct {
+	Tables map[string]int64 `json:"tables"`
+}
+
+type Anonymizer struct {
+	logger log.Logger
+	db     *sql.DB
+	key    []byte
+}
+
+func NewAnonymizer(logger log.Logger, cfg *config.Anonymize, db *sql.DB) (*Anonymizer, error) {
+	if cfg == nil {
+		return nil, errors.New("anonymize is not configured")
+	}
+	return &Anonymizer{
+		logger: logger,
+		db:     db,
+		key:    []byte(cfg.Secret),
+	}, nil
+}
+
+// Run rewrites every personal data column in one transaction.
+func (a *Anonymizer) Run() (*Result, error) {
+	tx, err := a.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{Tables: make(map[string]int64)}
+	for i := range columns {
+		n, err := a.rewrite(tx, columns[i])
+		if err != nil {
+			tx.Rollback()
+			return nil, fmt.Errorf("%s.%s: %v", columns[i].table, columns[i].name, err)
+		}
+		result.Tables[columns[i].table] += n
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	a.logger.Log("anonymized database")
+	return result, nil
+}
+
+// rewrite updates each distinct value of a column so rows sharing a value still do afterwards.
+func (a *Anonymizer) rewrite(tx *sql.Tx, col column) (int64, error) {
+	query := fmt.Sprintf(`select distinct %s from %s where %s is not null and %s <> '';`, col.name, col.table, col.name, col.name)
+	rows, err := tx.Query(query)
+	if err != nil {
+		return 0, err
+	}
+	var values []string
+	for rows.Next() {
+		var v string
+		if err := rows.Scan(&v); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		values = append(values, v)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	query = fmt.Sprintf(`update %s set %s = ? where %s = ?;`, col.table, col.name, col.name)
+	stmt, err := tx.Prepare(query)
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	var total int64
+	for i := range values {
+		res, err := stmt.Exec(a.fake(col.kind, values[i]), values[i])
+		if err != nil {
+			return total, err
+		}
+		n, _ := res.RowsAffected()
+		total += n
+	}
+	return total, nil
+}
+
+func (a *Anonymizer) fake(k kind, value string) string {
+	mac := hmac.New(sha256.New, a.key)
+	mac.Write([]byte(value))
+	sum := mac.Sum(nil)
+
+	switch k {
+	case kindDigits:
+		var buf strings.Builder
+		for i := 0; buf.Len() < len(value); i++ {
+			buf.WriteByte('0' + sum[i%len(sum)]%10)
+		}
+		return buf.String()
+
+	case kindText:
+		var buf strings.Builder
+		for i := 0; buf.Len() < len(value); i++ {
+			if i > 0 {
+				buf.WriteByte(' ')
+			}
+			buf.WriteString(words[int(sum[i%len(sum)])%len(words)])
+		}
+		return strings.TrimSpace(buf.String()[:len(value)])
+
+	case kindFilename:
+		ext := filepath.Ext(value)
+		return fakeHex(sum, len(value)-len(ext)) + ext
+
+	case kindIP:
+		return fmt.Sprintf("10.%d.%d.%d", sum[0], sum[1], sum[2])
+
+	case kindEmail:
+		return fakeHex(sum, 12) + "@example.com"
+
+	case kindTransferTemplate:
+		var template client.CreateTransfer
+		if err := json.Unmarshal([]byte(value), &template); err != nil {
+			break // unreadable templates are replaced entirely
+		}
+		template.Source.CustomerID = a.fake(kindID, template.Source.CustomerID)
+		template.Source.AccountID = a.fake(kindID, template.Source.AccountID)
+		template.Destination.CustomerID = a.fake(kindID, template.Destination.CustomerID)
+		template.Destination.AccountID = a.fake(kindID, template.Destination.AccountID)
+		template.Description = a.fake(kindText, template.Description)
+		bs, _ := json.Marshal(template)
+		return string(bs)
+	}
+	return fakeHex(sum, len(value))
+}
+
+// fakeHex returns n hex characters, repeating the hash for long values
+func fakeHex(sum []byte, n int) string {
+	encoded := hex.EncodeToString(sum)
+	for len(encoded) < n {
+		encoded += encoded
+	}
+	return encoded[:n]
+}
+
+var words = []string{
+	"account", "bill", "credit", "debit", "deposit", "fee", "goods", "invoice",
+	"loan", "order", "pay", "payroll", "refund", "rent", "service", "transfer",
+}
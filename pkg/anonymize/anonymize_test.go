@@ -0,0 +1,145 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package anonymize
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/moov-io/base"
+	"github.com/moov-io/base/log"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/database"
+	"github.com/moov-io/paygate/pkg/organization"
+	"github.com/moov-io/paygate/pkg/transfers"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnonymizer(t *testing.T) {
+	t.Parallel()
+
+	check := func(t *testing.T, db *sql.DB) {
+		orgID, customerID, accountID := base.ID(), base.ID(), base.ID()
+
+		_, err := organization.NewRepo(db).UpdateConfig(orgID, &client.OrganizationConfiguration{
+			CompanyIdentification: "1234567890",
+		})
+		require.NoError(t, err)
+
+		xfer := &client.Transfer{
+			TransferID:  base.ID(),
+			Amount:      client.Amount{Currency: "USD", Value: 1245},
+			Source:      client.Source{CustomerID: base.ID(), AccountID: base.ID()},
+			Destination: client.Destination{CustomerID: customerID, AccountID: accountID},
+			Description: "payroll",
+			Status:      client.PROCESSED,
+			Created:     time.Now(),
+		}
+		require.NoError(t, transfers.NewRepo(db).WriteUserTransfer(orgID, xfer))
+
+		query := `insert into micro_deposits (micro_deposit_id, destination_customer_id, destination_account_id, status, created_at) values (?, ?, ?, ?, ?);`
+		_, err = db.Exec(query, base.ID(), customerID, accountID, client.PENDING, time.Now())
+		require.NoError(t, err)
+
+		anonymizer, err := NewAnonymizer(log.NewNopLogger(), &config.Anonymize{Secret: "0123456789abcdef"}, db)
+		require.NoError(t, err)
+
+		result, err := anonymizer.Run()
+		require.NoError(t, err)
+		require.Equal(t, int64(6), result.Tables["transfers"])
+		require.Equal(t, int64(2), result.Tables["micro_deposits"])
+
+		var organization, company string
+		require.NoError(t, db.QueryRow(`select organization, company_identification from organization_configs where organization = ?;`, anonymizer.fake(kindID, orgID)).Scan(&organization, &company))
+		require.Len(t, company, 10)
+		require.NotEqual(t, "1234567890", company)
+
+		var destCustomerID, description, status string
+		query = `select destination_customer_id, description, status from transfers where transfer_id = ?;`
+		require.NoError(t, db.QueryRow(query, xfer.TransferID).Scan(&destCustomerID, &description, &status))
+		require.NotEqual(t, customerID, destCustomerID)
+		require.NotEqual(t, "payroll", description)
+		require.LessOrEqual(t, len(description), len("payroll"))
+		require.Equal(t, string(client.PROCESSED), status)
+
+		// IDs shared across tables still match
+		var microDepositCustomerID string
+		query = `select destination_customer_id from micro_deposits where destination_account_id = ?;`
+		require.NoError(t, db.QueryRow(query, anonymizer.fake(kindID, accountID)).Scan(&microDepositCustomerID))
+		require.Equal(t, destCustomerID, microDepositCustomerID)
+	}
+
+	sqliteDB := database.CreateTestSqliteDB(t)
+	t.Cleanup(func() { sqliteDB.Close() })
+	check(t, sqliteDB.DB)
+
+	mysqlDB := database.CreateTestMySQLDB(t)
+	t.Cleanup(func() { mysqlDB.Close() })
+	check(t, mysqlDB.DB)
+}
+
+func TestAnonymizer__fake(t *testing.T) {
+	anonymizer := &Anonymizer{key: []byte("0123456789abcdef")}
+
+	// values are deterministic
+	require.Equal(t, anonymizer.fake(kindID, "customer"), anonymizer.fake(kindID, "customer"))
+	require.NotEqual(t, anonymizer.fake(kindID, "customer"), anonymizer.fake(kindID, "account"))
+
+	id := base.ID()
+	require.Len(t, anonymizer.fake(kindID, id), len(id))
+	require.Len(t, anonymizer.fake(kindID, id+id), 2*len(id))
+
+	require.Regexp(t, `^[0-9]{9}$`, anonymizer.fake(kindDigits, "123456789"))
+	require.Regexp(t, `^[0-9a-f]{7}\.pdf$`, anonymizer.fake(kindFilename, "invoice.pdf"))
+	require.Regexp(t, `^10\.\d+\.\d+\.\d+$`, anonymizer.fake(kindIP, "192.168.1.10"))
+	require.Regexp(t, `^[0-9a-f]{12}@example\.com$`, anonymizer.fake(kindEmail, "ops@bank.com"))
+
+	template := client.CreateTransfer{
+		Source:      client.Source{CustomerID: "customer", AccountID: "account"},
+		Description: "Payroll for Jane Doe",
+	}
+	bs, _ := json.Marshal(template)
+	require.NoError(t, json.Unmarshal([]byte(anonymizer.fake(kindTransferTemplate, string(bs))), &template))
+	require.Equal(t, anonymizer.fake(kindID, "customer"), template.Source.CustomerID)
+	require.Equal(t, anonymizer.fake(kindID, "account"), template.Source.AccountID)
+	require.NotContains(t, template.Description, "Jane")
+
+	text := anonymizer.fake(kindText, "Rent for Jane Doe at 123 Main St")
+	require.LessOrEqual(t, len(text), len("Rent for Jane Doe at 123 Main St"))
+	require.NotContains(t, text, "Jane")
+
+	_, err := NewAnonymizer(log.NewNopLogger(), nil, nil)
+	require.Error(t, err)
+}
+
+func TestAnonymizer__admin(t *testing.T) {
+	db := database.CreateTestSqliteDB(t)
+	t.Cleanup(func() { db.Close() })
+
+	anonymizer, err := NewAnonymizer(log.NewNopLogger(), &config.Anonymize{Secret: "0123456789abcdef"}, db.DB)
+	require.NoError(t, err)
+	handler := anonymizeDatabase(anonymizer)
+
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest("POST", "/anonymize", nil))
+	w.Flush()
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var result Result
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&result))
+	require.NotNil(t, result.Tables)
+
+	// wrong method
+	w = httptest.NewRecorder()
+	handler(w, httptest.NewRequest("GET", "/anonymize", nil))
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,41 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package attachments
+
+import (
+	"github.com/moov-io/paygate/pkg/client"
+)
+
+type MockRepository struct {
+	Attachments []*client.Attachment
+	Err         error
+}
+
+func (r *MockRepository) getAttachments(organization string, customerID string, accountID string) ([]*client.Attachment, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	return r.Attachments, nil
+}
+
+func (r *MockRepository) getAttachment(organization string, attachmentID string) (*client.Attachment, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	for i := range r.Attachments {
+		if r.Attachments[i].AttachmentID == attachmentID {
+			return r.Attachments[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *MockRepository) writeAttachment(organization string, attachment *client.Attachment) error {
+	if r.Err != nil {
+		return r.Err
+	}
+	r.Attachments = append(r.Attachments, attachment)
+	return nil
+}
@@ -0,0 +1,145 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package attachments
+
+import (
+	"database/sql"
+
+	"github.com/moov-io/paygate/pkg/client"
+)
+
+type Repository interface {
+	getAttachments(organization string, customerID string, accountID string) ([]*client.Attachment, error)
+	getAttachment(organization string, attachmentID string) (*client.Attachment, error)
+	writeAttachment(organization string, attachment *client.Attachment) error
+}
+
+func NewRepo(db *sql.DB) *sqlRepo {
+	return &sqlRepo{db: db}
+}
+
+type sqlRepo struct {
+	db *sql.DB
+}
+
+func (r *sqlRepo) Close() error {
+	if r == nil || r.db == nil {
+		return nil
+	}
+	return r.db.Close()
+}
+
+// getAttachments returns the attachments for a customer, or for one of their accounts when
+// accountID is non-empty.
+func (r *sqlRepo) getAttachments(organization string, customerID string, accountID string) ([]*client.Attachment, error) {
+	query := `select attachment_id from attachments where organization = ? and customer_id = ? and deleted_at is null`
+	args := []interface{}{organization, customerID}
+	if accountID != "" {
+		query += " and account_id = ?"
+		args = append(args, accountID)
+	}
+	query += " order by created_at asc;"
+
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.Query(args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var attachmentIDs []string
+	for rows.Next() {
+		var attachmentID string
+		if err := rows.Scan(&attachmentID); err != nil {
+			return nil, err
+		}
+		attachmentIDs = append(attachmentIDs, attachmentID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var out []*client.Attachment
+	for i := range attachmentIDs {
+		a, err := r.getAttachment(organization, attachmentIDs[i])
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, a)
+	}
+	return out, nil
+}
+
+func (r *sqlRepo) getAttachment(organization string, attachmentID string) (*client.Attachment, error) {
+	query := `select attachment_id, customer_id, account_id, kind, note, filename, content_type, size, created_at from attachments
+where attachment_id = ? and organization = ? and deleted_at is null limit 1;`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	var accountID, note, filename, contentType *string
+	var size *int64
+	var a client.Attachment
+	err = stmt.QueryRow(attachmentID, organization).Scan(
+		&a.AttachmentID,
+		&a.CustomerID,
+		&accountID,
+		&a.Kind,
+		&note,
+		&filename,
+		&contentType,
+		&size,
+		&a.Created,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if accountID != nil {
+		a.AccountID = *accountID
+	}
+	if note != nil {
+		a.Note = *note
+	}
+	if filename != nil {
+		a.Filename = *filename
+	}
+	if contentType != nil {
+		a.ContentType = *contentType
+	}
+	if size != nil {
+		a.Size = *size
+	}
+	return &a, nil
+}
+
+func (r *sqlRepo) writeAttachment(organization string, a *client.Attachment) error {
+	query := `insert into attachments (attachment_id, organization, customer_id, account_id, kind, note, filename, content_type, size, created_at) values (?, ?, ?, ?, ?, ?, ?, ?, ?, ?);`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(
+		a.AttachmentID,
+		organization,
+		a.CustomerID,
+		a.AccountID,
+		a.Kind,
+		a.Note,
+		a.Filename,
+		a.ContentType,
+		a.Size,
+		a.Created,
+	)
+	return err
+}
@@ -0,0 +1,95 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package attachments
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/moov-io/base"
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/database"
+
+	"github.com/stretchr/testify/require"
+)
+
+func setupSQLiteDB(t *testing.T) *sqlRepo {
+	db := database.CreateTestSqliteDB(t)
+	t.Cleanup(func() { db.Close() })
+
+	repo := NewRepo(db.DB)
+	t.Cleanup(func() { repo.Close() })
+
+	return repo
+}
+
+func setupMySQLeDB(t *testing.T) *sqlRepo {
+	db := database.CreateTestMySQLDB(t)
+	t.Cleanup(func() { db.Close() })
+
+	repo := NewRepo(db.DB)
+	t.Cleanup(func() { repo.Close() })
+
+	return repo
+}
+
+func TestRepository__Attachments(t *testing.T) {
+	t.Parallel()
+
+	check := func(t *testing.T, repo *sqlRepo) {
+		orgID, customerID, accountID := base.ID(), base.ID(), base.ID()
+
+		note := &client.Attachment{
+			AttachmentID: base.ID(),
+			CustomerID:   customerID,
+			Kind:         client.ATTACHMENTKIND_NOTE,
+			Note:         "called customer about returns",
+			Created:      time.Now(),
+		}
+		require.NoError(t, repo.writeAttachment(orgID, note))
+
+		doc := &client.Attachment{
+			AttachmentID: base.ID(),
+			CustomerID:   customerID,
+			AccountID:    accountID,
+			Kind:         client.ATTACHMENTKIND_DOCUMENT,
+			Filename:     "statement.pdf",
+			ContentType:  "application/pdf",
+			Size:         1024,
+			Created:      time.Now().Add(time.Second),
+		}
+		require.NoError(t, repo.writeAttachment(orgID, doc))
+
+		found, err := repo.getAttachments(orgID, customerID, "")
+		require.NoError(t, err)
+		require.Len(t, found, 2)
+		require.Equal(t, note.AttachmentID, found[0].AttachmentID)
+		require.Equal(t, note.Note, found[0].Note)
+
+		found, err = repo.getAttachments(orgID, customerID, accountID)
+		require.NoError(t, err)
+		require.Len(t, found, 1)
+		require.Equal(t, doc.AttachmentID, found[0].AttachmentID)
+		require.Equal(t, "statement.pdf", found[0].Filename)
+		require.Equal(t, int64(1024), found[0].Size)
+
+		// other organizations can't read these
+		found, err = repo.getAttachments(base.ID(), customerID, "")
+		require.NoError(t, err)
+		require.Empty(t, found)
+
+		_, err = repo.getAttachment(base.ID(), doc.AttachmentID)
+		require.Equal(t, sql.ErrNoRows, err)
+	}
+
+	t.Run("SQLite", func(t *testing.T) {
+		check(t, setupSQLiteDB(t))
+	})
+
+	t.Run("MySQL", func(t *testing.T) {
+		check(t, setupMySQLeDB(t))
+	})
+}
@@ -0,0 +1,358 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package attachments
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/moov-io/base"
+	"gocloud.dev/blob"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/customers"
+	"github.com/moov-io/paygate/x/route"
+)
+
+const maxNoteLength = 4096
+
+type Router struct {
+	GetCustomerAttachments http.HandlerFunc
+	CreateCustomerNote     http.HandlerFunc
+	UploadCustomerDocument http.HandlerFunc
+
+	GetAttachment         http.HandlerFunc
+	GetAttachmentContents http.HandlerFunc
+}
+
+func NewRouter(
+	cfg *config.Config,
+	repo Repository,
+	bucket *blob.Bucket,
+	customersClient customers.Client,
+) *Router {
+	if cfg.Attachments == nil || bucket == nil {
+		return &Router{
+			GetCustomerAttachments: NotImplemented(cfg),
+			CreateCustomerNote:     NotImplemented(cfg),
+			UploadCustomerDocument: NotImplemented(cfg),
+			GetAttachment:          NotImplemented(cfg),
+			GetAttachmentContents:  NotImplemented(cfg),
+		}
+	}
+	return &Router{
+		GetCustomerAttachments: GetCustomerAttachments(cfg, repo),
+		CreateCustomerNote:     CreateCustomerNote(cfg, repo, customersClient),
+		UploadCustomerDocument: UploadCustomerDocument(cfg, repo, bucket, customersClient),
+		GetAttachment:          GetAttachment(cfg, repo),
+		GetAttachmentContents:  GetAttachmentContents(cfg, repo, bucket),
+	}
+}
+
+// NewUnavailableRouter returns a Router which responds to every attachment route with h,
+// used when the attachments bucket couldn't be opened.
+func NewUnavailableRouter(h http.HandlerFunc) *Router {
+	return &Router{
+		GetCustomerAttachments: h,
+		CreateCustomerNote:     h,
+		UploadCustomerDocument: h,
+		GetAttachment:          h,
+		GetAttachmentContents:  h,
+	}
+}
+
+func (c *Router) RegisterRoutes(r *mux.Router) {
+	r.Methods("GET").Path("/customers/{customerID}/attachments").HandlerFunc(c.GetCustomerAttachments)
+	r.Methods("POST").Path("/customers/{customerID}/notes").HandlerFunc(c.CreateCustomerNote)
+	r.Methods("POST").Path("/customers/{customerID}/documents").HandlerFunc(c.UploadCustomerDocument)
+
+	r.Methods("GET").Path("/customers/{customerID}/accounts/{accountID}/attachments").HandlerFunc(c.GetCustomerAttachments)
+	r.Methods("POST").Path("/customers/{customerID}/accounts/{accountID}/notes").HandlerFunc(c.CreateCustomerNote)
+	r.Methods("POST").Path("/customers/{customerID}/accounts/{accountID}/documents").HandlerFunc(c.UploadCustomerDocument)
+
+	r.Methods("GET").Path("/attachments/{attachmentID}").HandlerFunc(c.GetAttachment)
+	r.Methods("GET").Path("/attachments/{attachmentID}/contents").HandlerFunc(c.GetAttachmentContents)
+}
+
+// readOwner returns the customerID and optional accountID from the request path.
+func readOwner(r *http.Request) (string, string, error) {
+	customerID, accountID := route.ReadPathID("customerID", r), route.ReadPathID("accountID", r)
+	if customerID == "" {
+		return "", "", errors.New("missing customerID")
+	}
+	return customerID, accountID, nil
+}
+
+// verifyOwner confirms the Customer (and Account) exist within the organization.
+func verifyOwner(customersClient customers.Client, organization, requestID, customerID, accountID string) error {
+	if customersClient == nil {
+		return nil
+	}
+	cust, err := customersClient.Lookup(organization, customerID, requestID)
+	if err != nil {
+		return err
+	}
+	if cust == nil || cust.CustomerID == "" {
+		return fmt.Errorf("customerID=%s is not found", customerID)
+	}
+	if accountID != "" {
+		acct, err := customersClient.FindAccount(organization, customerID, accountID)
+		if err != nil {
+			return err
+		}
+		if acct == nil || acct.AccountID == "" {
+			return fmt.Errorf("accountID=%s not found for customerID=%s", accountID, customerID)
+		}
+	}
+	return nil
+}
+
+func GetCustomerAttachments(cfg *config.Config, repo Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(cfg, w, r)
+		responder.Respond(func(w http.ResponseWriter) {
+			customerID, accountID, err := readOwner(r)
+			if err != nil {
+				responder.Problem(err)
+				return
+			}
+
+			attachments, err := repo.getAttachments(responder.OrganizationID, customerID, accountID)
+			if err != nil {
+				cfg.Logger.LogErrorf("ERROR getting customerID=%s attachments: %v", customerID, err)
+				responder.Problem(err)
+				return
+			}
+			if attachments == nil {
+				attachments = make([]*client.Attachment, 0)
+			}
+
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(attachments)
+		})
+	}
+}
+
+func CreateCustomerNote(cfg *config.Config, repo Repository, customersClient customers.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(cfg, w, r)
+		responder.Respond(func(w http.ResponseWriter) {
+			customerID, accountID, err := readOwner(r)
+			if err != nil {
+				responder.Problem(err)
+				return
+			}
+
+			var req client.CreateNote
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				responder.Problem(fmt.Errorf("problem reading request body: %v", err))
+				return
+			}
+			req.Note = strings.TrimSpace(req.Note)
+			if req.Note == "" {
+				responder.Problem(errors.New("missing note"))
+				return
+			}
+			if len(req.Note) > maxNoteLength {
+				responder.Problem(fmt.Errorf("note exceeds %d characters", maxNoteLength))
+				return
+			}
+
+			if err := verifyOwner(customersClient, responder.OrganizationID, responder.XRequestID, customerID, accountID); err != nil {
+				responder.Problem(err)
+				return
+			}
+
+			attachment := &client.Attachment{
+				AttachmentID: base.ID(),
+				CustomerID:   customerID,
+				AccountID:    accountID,
+				Kind:         client.ATTACHMENTKIND_NOTE,
+				Note:         req.Note,
+				Created:      time.Now(),
+			}
+			if err := repo.writeAttachment(responder.OrganizationID, attachment); err != nil {
+				cfg.Logger.LogErrorf("ERROR writing customerID=%s note: %v", customerID, err)
+				responder.Problem(err)
+				return
+			}
+
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(attachment)
+		})
+	}
+}
+
+func UploadCustomerDocument(cfg *config.Config, repo Repository, bucket *blob.Bucket, customersClient customers.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(cfg, w, r)
+		responder.Respond(func(w http.ResponseWriter) {
+			customerID, accountID, err := readOwner(r)
+			if err != nil {
+				responder.Problem(err)
+				return
+			}
+
+			maxBytes := cfg.Attachments.MaxBytes()
+			r.Body = http.MaxBytesReader(w, r.Body, maxBytes+(64*1024)) // allow for multipart overhead
+
+			file, header, err := r.FormFile("file")
+			if err != nil {
+				responder.Problem(fmt.Errorf("problem reading file: %v", err))
+				return
+			}
+			defer file.Close()
+
+			var buf bytes.Buffer
+			n, err := io.Copy(&buf, io.LimitReader(file, maxBytes+1))
+			if err != nil {
+				responder.Problem(fmt.Errorf("problem reading file: %v", err))
+				return
+			}
+			if n > maxBytes {
+				responder.Problem(fmt.Errorf("document exceeds %d bytes", maxBytes))
+				return
+			}
+			if n == 0 {
+				responder.Problem(errors.New("empty document"))
+				return
+			}
+
+			contentType, err := allowedContentType(cfg.Attachments, buf.Bytes())
+			if err != nil {
+				responder.Problem(err)
+				return
+			}
+
+			if err := verifyOwner(customersClient, responder.OrganizationID, responder.XRequestID, customerID, accountID); err != nil {
+				responder.Problem(err)
+				return
+			}
+
+			attachment := &client.Attachment{
+				AttachmentID: base.ID(),
+				CustomerID:   customerID,
+				AccountID:    accountID,
+				Kind:         client.ATTACHMENTKIND_DOCUMENT,
+				Filename:     filepath.Base(header.Filename),
+				ContentType:  contentType,
+				Size:         n,
+				Created:      time.Now(),
+			}
+
+			opts := &blob.WriterOptions{ContentType: contentType}
+			if err := bucket.WriteAll(r.Context(), ContentsPath(responder.OrganizationID, attachment.AttachmentID), buf.Bytes(), opts); err != nil {
+				cfg.Logger.LogErrorf("ERROR storing customerID=%s document: %v", customerID, err)
+				responder.Problem(err)
+				return
+			}
+			if err := repo.writeAttachment(responder.OrganizationID, attachment); err != nil {
+				cfg.Logger.LogErrorf("ERROR writing customerID=%s document: %v", customerID, err)
+				responder.Problem(err)
+				return
+			}
+
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(attachment)
+		})
+	}
+}
+
+// allowedContentType detects the MIME type of data rather than trusting the client and
+// returns an error if it's not allowed by the config.
+func allowedContentType(cfg *config.Attachments, data []byte) (string, error) {
+	detected, _, err := mime.ParseMediaType(http.DetectContentType(data))
+	if err != nil {
+		return "", fmt.Errorf("unable to detect content type: %v", err)
+	}
+	allowed := cfg.AllowedContentTypes()
+	for i := range allowed {
+		if strings.EqualFold(allowed[i], detected) {
+			return detected, nil
+		}
+	}
+	return "", fmt.Errorf("content type %s is not allowed", detected)
+}
+
+func GetAttachment(cfg *config.Config, repo Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(cfg, w, r)
+		responder.Respond(func(w http.ResponseWriter) {
+			attachment, err := lookupAttachment(repo, responder.OrganizationID, r)
+			if err != nil {
+				responder.Problem(err)
+				return
+			}
+
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(attachment)
+		})
+	}
+}
+
+func GetAttachmentContents(cfg *config.Config, repo Repository, bucket *blob.Bucket) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(cfg, w, r)
+		responder.Respond(func(w http.ResponseWriter) {
+			attachment, err := lookupAttachment(repo, responder.OrganizationID, r)
+			if err != nil {
+				responder.Problem(err)
+				return
+			}
+			if attachment.Kind != client.ATTACHMENTKIND_DOCUMENT {
+				responder.Problem(fmt.Errorf("attachmentID=%s is not a document", attachment.AttachmentID))
+				return
+			}
+
+			rdr, err := bucket.NewReader(r.Context(), ContentsPath(responder.OrganizationID, attachment.AttachmentID), nil)
+			if err != nil {
+				cfg.Logger.LogErrorf("ERROR reading attachmentID=%s contents: %v", attachment.AttachmentID, err)
+				responder.Problem(err)
+				return
+			}
+			defer rdr.Close()
+
+			w.Header().Set("Content-Type", attachment.ContentType)
+			w.Header().Set("Content-Length", strconv.FormatInt(rdr.Size(), 10))
+			w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Filename}))
+			w.WriteHeader(http.StatusOK)
+			io.Copy(w, rdr)
+		})
+	}
+}
+
+func lookupAttachment(repo Repository, organization string, r *http.Request) (*client.Attachment, error) {
+	attachmentID := route.ReadPathID("attachmentID", r)
+	if attachmentID == "" {
+		return nil, errors.New("missing attachmentID")
+	}
+	attachment, err := repo.getAttachment(organization, attachmentID)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	if attachment == nil {
+		return nil, fmt.Errorf("attachmentID=%s not found", attachmentID)
+	}
+	return attachment, nil
+}
+
+func NotImplemented(cfg *config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(cfg, w, r)
+		responder.Problem(errors.New("attachments are disabled via config"))
+	}
+}
@@ -0,0 +1,239 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package attachments
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/moov-io/base"
+	moovcustomers "github.com/moov-io/customers/pkg/client"
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/customers"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+	"gocloud.dev/blob"
+)
+
+var (
+	customerID = base.ID()
+	accountID  = base.ID()
+)
+
+func mockCustomersClient() *customers.MockClient {
+	return &customers.MockClient{
+		Customers: []*moovcustomers.Customer{
+			{CustomerID: customerID},
+		},
+		Accounts: map[string]*moovcustomers.Account{
+			accountID: {AccountID: accountID},
+		},
+	}
+}
+
+func setupRouter(t *testing.T, repo Repository) (*mux.Router, *blob.Bucket) {
+	cfg := config.Empty()
+	cfg.Attachments = &config.Attachments{
+		BucketURI: "mem://",
+		MaxSize:   1024,
+	}
+	bucket, err := OpenBucket(cfg.Attachments)
+	require.NoError(t, err)
+	t.Cleanup(func() { bucket.Close() })
+
+	r := mux.NewRouter()
+	NewRouter(cfg, repo, bucket, mockCustomersClient()).RegisterRoutes(r)
+	return r, bucket
+}
+
+func TestRouter__CreateNote(t *testing.T) {
+	repo := &MockRepository{}
+	r, _ := setupRouter(t, repo)
+
+	body := strings.NewReader(`{"note": "customer requested a call back"}`)
+	req := httptest.NewRequest("POST", fmt.Sprintf("/customers/%s/accounts/%s/notes", customerID, accountID), body)
+	req.Header.Set("X-Organization", "moov")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	w.Flush()
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var resp client.Attachment
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	require.Equal(t, client.ATTACHMENTKIND_NOTE, resp.Kind)
+	require.Equal(t, accountID, resp.AccountID)
+	require.Len(t, repo.Attachments, 1)
+
+	// empty note
+	req = httptest.NewRequest("POST", fmt.Sprintf("/customers/%s/notes", customerID), strings.NewReader(`{"note": " "}`))
+	req.Header.Set("X-Organization", "moov")
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	w.Flush()
+
+	require.Equal(t, http.StatusBadRequest, w.Code)
+
+	// unknown customer
+	req = httptest.NewRequest("POST", fmt.Sprintf("/customers/%s/notes", base.ID()), strings.NewReader(`{"note": "hello"}`))
+	req.Header.Set("X-Organization", "moov")
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	w.Flush()
+
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func multipartFile(t *testing.T, filename string, contents []byte) (*bytes.Buffer, string) {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	part, err := mw.CreateFormFile("file", filename)
+	require.NoError(t, err)
+	part.Write(contents)
+	require.NoError(t, mw.Close())
+	return &buf, mw.FormDataContentType()
+}
+
+func TestRouter__UploadDocument(t *testing.T) {
+	repo := &MockRepository{}
+	r, bucket := setupRouter(t, repo)
+
+	contents := []byte("%PDF-1.4 fake statement")
+	body, contentType := multipartFile(t, "statement.pdf", contents)
+
+	req := httptest.NewRequest("POST", fmt.Sprintf("/customers/%s/documents", customerID), body)
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("X-Organization", "moov")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	w.Flush()
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var resp client.Attachment
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	require.Equal(t, client.ATTACHMENTKIND_DOCUMENT, resp.Kind)
+	require.Equal(t, "application/pdf", resp.ContentType)
+	require.Equal(t, int64(len(contents)), resp.Size)
+
+	stored, err := bucket.ReadAll(context.Background(), ContentsPath("moov", resp.AttachmentID))
+	require.NoError(t, err)
+	require.Equal(t, contents, stored)
+
+	// download the contents
+	req = httptest.NewRequest("GET", fmt.Sprintf("/attachments/%s/contents", resp.AttachmentID), nil)
+	req.Header.Set("X-Organization", "moov")
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	w.Flush()
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.Equal(t, "application/pdf", w.Header().Get("Content-Type"))
+	bs, _ := ioutil.ReadAll(w.Body)
+	require.Equal(t, contents, bs)
+}
+
+func TestRouter__UploadDocumentRejected(t *testing.T) {
+	repo := &MockRepository{}
+	r, _ := setupRouter(t, repo)
+
+	upload := func(contents []byte) *httptest.ResponseRecorder {
+		body, contentType := multipartFile(t, "file.bin", contents)
+		req := httptest.NewRequest("POST", fmt.Sprintf("/customers/%s/documents", customerID), body)
+		req.Header.Set("Content-Type", contentType)
+		req.Header.Set("X-Organization", "moov")
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		w.Flush()
+		return w
+	}
+
+	// disallowed content type
+	w := upload([]byte{0x7f, 'E', 'L', 'F', 0x02, 0x01, 0x01, 0x00})
+	require.Equal(t, http.StatusBadRequest, w.Code)
+
+	// too large
+	w = upload(bytes.Repeat([]byte("a"), 2048))
+	require.Equal(t, http.StatusBadRequest, w.Code)
+
+	require.Empty(t, repo.Attachments)
+}
+
+func TestRouter__GetAttachments(t *testing.T) {
+	repo := &MockRepository{
+		Attachments: []*client.Attachment{
+			{
+				AttachmentID: base.ID(),
+				CustomerID:   customerID,
+				Kind:         client.ATTACHMENTKIND_NOTE,
+				Note:         "hello",
+			},
+		},
+	}
+	r, _ := setupRouter(t, repo)
+
+	req := httptest.NewRequest("GET", fmt.Sprintf("/customers/%s/attachments", customerID), nil)
+	req.Header.Set("X-Organization", "moov")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	w.Flush()
+
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var resp []client.Attachment
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	require.Len(t, resp, 1)
+
+	// single attachment
+	req = httptest.NewRequest("GET", fmt.Sprintf("/attachments/%s", repo.Attachments[0].AttachmentID), nil)
+	req.Header.Set("X-Organization", "moov")
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	w.Flush()
+
+	require.Equal(t, http.StatusOK, w.Code)
+
+	// notes have no contents
+	req = httptest.NewRequest("GET", fmt.Sprintf("/attachments/%s/contents", repo.Attachments[0].AttachmentID), nil)
+	req.Header.Set("X-Organization", "moov")
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	w.Flush()
+
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestRouter__AttachmentsDisabled(t *testing.T) {
+	r := mux.NewRouter()
+	NewRouter(config.Empty(), &MockRepository{}, nil, nil).RegisterRoutes(r)
+
+	req := httptest.NewRequest("GET", fmt.Sprintf("/customers/%s/attachments", customerID), nil)
+	req.Header.Set("X-Organization", "moov")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	w.Flush()
+
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
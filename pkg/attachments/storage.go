@@ -0,0 +1,32 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package attachments
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/moov-io/paygate/pkg/config"
+
+	"gocloud.dev/blob"
+	_ "gocloud.dev/blob/azureblob"
+	_ "gocloud.dev/blob/fileblob"
+	_ "gocloud.dev/blob/gcsblob"
+	_ "gocloud.dev/blob/memblob"
+	_ "gocloud.dev/blob/s3blob"
+)
+
+// OpenBucket returns the blob storage for document contents, or nil if attachments are disabled.
+func OpenBucket(cfg *config.Attachments) (*blob.Bucket, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	return blob.OpenBucket(context.Background(), cfg.BucketURI)
+}
+
+// ContentsPath returns where an attachment's contents are stored in the bucket.
+func ContentsPath(organization string, attachmentID string) string {
+	return fmt.Sprintf("attachments/%s/%s", organization, attachmentID)
+}
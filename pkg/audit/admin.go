@@ -0,0 +1,119 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package audit
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/moov-io/base"
+	"github.com/moov-io/base/admin"
+	moovhttp "github.com/moov-io/base/http"
+
+	paygateadmin "github.com/moov-io/paygate/pkg/admin"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/util"
+	"github.com/moov-io/paygate/x/route"
+)
+
+// defaultRecordCount is how many records are returned without a limit
+const defaultRecordCount = 200
+
+// RegisterAdminRoutes adds an endpoint to search and export audit records.
+func RegisterAdminRoutes(cfg *config.Config, svc *admin.Server, repo Repository) {
+	svc.AddHandler("/audit", getRecords(repo))
+}
+
+var csvColumns = []string{
+	"recordID", "created", "organization", "userID", "requestID", "remoteAddress",
+	"method", "endpoint", "path", "resource", "status", "body",
+}
+
+func readRecordFilterParams(r *http.Request) (recordFilterParams, error) {
+	q := r.URL.Query()
+	params := recordFilterParams{
+		Organization: q.Get("organization"),
+		UserID:       q.Get("userID"),
+		Resource:     q.Get("resource"),
+		Skip:         route.ReadOffset(r),
+		Count:        route.ReadLimit(r),
+	}
+	if params.Count == 0 {
+		params.Count = defaultRecordCount
+	}
+	if v := q.Get("startDate"); v != "" {
+		params.StartDate = util.FirstParsedTime(v, base.ISO8601Format, util.YYMMDDTimeFormat)
+		if params.StartDate.IsZero() {
+			return params, fmt.Errorf("invalid startDate %q", v)
+		}
+	}
+	if v := q.Get("endDate"); v != "" {
+		params.EndDate = util.FirstParsedTime(v, base.ISO8601Format, util.YYMMDDTimeFormat)
+		if params.EndDate.IsZero() {
+			return params, fmt.Errorf("invalid endDate %q", v)
+		}
+	}
+	return params, nil
+}
+
+func getRecords(repo Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			moovhttp.Problem(w, fmt.Errorf("invalid method %s", r.Method))
+			return
+		}
+		params, err := readRecordFilterParams(r)
+		if err != nil {
+			moovhttp.Problem(w, err)
+			return
+		}
+		records, err := repo.getRecords(params)
+		if err != nil {
+			moovhttp.Problem(w, err)
+			return
+		}
+		if records == nil {
+			records = make([]*paygateadmin.AuditRecord, 0)
+		}
+
+		switch format := r.URL.Query().Get("format"); format {
+		case "", "json":
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(records)
+
+		case "csv":
+			w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+			w.Header().Set("Content-Disposition", `attachment; filename="audit.csv"`)
+			w.WriteHeader(http.StatusOK)
+			writeRecordsCSV(w, records)
+
+		default:
+			moovhttp.Problem(w, fmt.Errorf("unknown format %q", format))
+		}
+	}
+}
+
+func writeRecordsCSV(w http.ResponseWriter, records []*paygateadmin.AuditRecord) error {
+	out := csv.NewWriter(w)
+	if err := out.Write(csvColumns); err != nil {
+		return err
+	}
+	for _, rec := range records {
+		err := out.Write([]string{
+			rec.RecordID, rec.Created.Format(time.RFC3339), rec.Organization, rec.UserID, rec.RequestID, rec.RemoteAddress,
+			rec.Method, rec.Endpoint, rec.Path, rec.Resource, strconv.Itoa(rec.Status), rec.Body,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
@@ -0,0 +1,65 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package audit
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/moov-io/paygate/pkg/admin"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdmin__getRecords(t *testing.T) {
+	repo := &MockRepository{
+		Records: []*admin.AuditRecord{
+			{
+				RecordID: "rec1",
+				UserID:   "jane",
+				Method:   "POST",
+				Endpoint: "/transfers",
+				Resource: "transfers",
+				Status:   200,
+				Body:     `{"description":"rent"}`,
+				Created:  time.Now(),
+			},
+		},
+	}
+
+	w := httptest.NewRecorder()
+	getRecords(repo)(w, httptest.NewRequest("GET", "/audit?userID=jane&startDate=2020-06-01", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var records []admin.AuditRecord
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&records))
+	require.Len(t, records, 1)
+	require.Equal(t, "rec1", records[0].RecordID)
+
+	w = httptest.NewRecorder()
+	getRecords(repo)(w, httptest.NewRequest("GET", "/audit?format=csv", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+
+	rows, err := csv.NewReader(w.Body).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+	require.Equal(t, csvColumns, rows[0])
+	require.Equal(t, `{"description":"rent"}`, rows[1][11])
+
+	// invalid requests
+	for _, target := range []string{"/audit?format=xml", "/audit?startDate=yesterday"} {
+		w = httptest.NewRecorder()
+		getRecords(repo)(w, httptest.NewRequest("GET", target, nil))
+		require.Equal(t, http.StatusBadRequest, w.Code, target)
+	}
+	w = httptest.NewRecorder()
+	getRecords(repo)(w, httptest.NewRequest("POST", "/audit", nil))
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,157 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/moov-io/base"
+	moovhttp "github.com/moov-io/base/http"
+	"github.com/moov-io/base/log"
+
+	"github.com/moov-io/paygate/pkg/admin"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/util"
+	"github.com/moov-io/paygate/x/route"
+
+	"github.com/gorilla/mux"
+)
+
+// maxRecordedBody is the largest request body saved with a record, larger bodies are
+// only recorded by their size.
+const maxRecordedBody = 16 * 1024
+
+// redactedFields are JSON fields whose values are never saved, compared case-insensitively.
+var redactedFields = map[string]bool{
+	"accountnumber":    true,
+	"password":         true,
+	"secret":           true,
+	"token":            true,
+	"clientprivatekey": true,
+}
+
+// Middleware records every POST, PUT, PATCH and DELETE request once it's been handled.
+// Failing to save a record is logged and doesn't change the response.
+func Middleware(cfg *config.Config, repo Repository) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !mutating(r.Method) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			var bs []byte
+			if r.Body != nil {
+				bs, _ = ioutil.ReadAll(r.Body)
+				r.Body = ioutil.NopCloser(bytes.NewReader(bs))
+			}
+			rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(rec, r)
+
+			record := newRecord(cfg, r, bs, rec.status, time.Now())
+			if err := repo.writeRecord(record); err != nil {
+				cfg.Logger.With(log.Fields{
+					"requestID": record.RequestID,
+					"endpoint":  record.Endpoint,
+				}).LogErrorf("ERROR saving audit record: %v", err)
+			}
+		})
+	}
+}
+
+func mutating(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusRecorder) WriteHeader(code int) {
+	w.status = code
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func newRecord(cfg *config.Config, r *http.Request, body []byte, status int, when time.Time) *admin.AuditRecord {
+	record := &admin.AuditRecord{
+		RecordID:      base.ID(),
+		Organization:  util.Or(r.Header.Get(util.Or(cfg.Organization.Header, "X-Organization")), cfg.Organization.Default),
+		UserID:        route.GetHeaderValue("X-User-ID", r),
+		RequestID:     moovhttp.GetRequestID(r),
+		RemoteAddress: remoteAddress(r),
+		Method:        r.Method,
+		Path:          r.URL.Path,
+		Status:        status,
+		Body:          recordedBody(r.Header.Get("Content-Type"), body),
+		Created:       when,
+	}
+	record.Endpoint = record.Path
+	if current := mux.CurrentRoute(r); current != nil {
+		if tpl, err := current.GetPathTemplate(); err == nil {
+			record.Endpoint = tpl
+		}
+	}
+	record.Resource = strings.SplitN(strings.TrimPrefix(record.Endpoint, "/"), "/", 2)[0]
+	return record
+}
+
+// remoteAddress prefers the first X-Forwarded-For address set by a load balancer.
+func remoteAddress(r *http.Request) string {
+	if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+		return strings.TrimSpace(strings.Split(forwarded, ",")[0])
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// recordedBody returns JSON bodies with sensitive fields redacted. Other bodies, like
+// uploaded attachments, are described by their type and size.
+func recordedBody(contentType string, body []byte) string {
+	if len(body) == 0 {
+		return ""
+	}
+	var v interface{}
+	if err := json.Unmarshal(body, &v); err != nil {
+		return fmt.Sprintf("<%s, %d bytes>", util.Or(contentType, "unknown"), len(body))
+	}
+	bs, err := json.Marshal(redact(v))
+	if err != nil || len(bs) > maxRecordedBody {
+		return fmt.Sprintf("<%s, %d bytes>", util.Or(contentType, "application/json"), len(body))
+	}
+	return string(bs)
+}
+
+func redact(v interface{}) interface{} {
+	switch vv := v.(type) {
+	case map[string]interface{}:
+		for k := range vv {
+			if redactedFields[strings.ToLower(k)] {
+				vv[k] = "REDACTED"
+			} else {
+				vv[k] = redact(vv[k])
+			}
+		}
+	case []interface{}:
+		for i := range vv {
+			vv[i] = redact(vv[i])
+		}
+	}
+	return v
+}
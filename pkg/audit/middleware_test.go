@@ -0,0 +1,57 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package audit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/moov-io/paygate/pkg/config"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMiddleware(t *testing.T) {
+	repo := &MockRepository{}
+
+	router := mux.NewRouter()
+	router.Use(Middleware(config.Empty(), repo))
+	router.Methods("GET", "PUT").Path("/transfers/{transferID}").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	req := httptest.NewRequest("GET", "/transfers/abc", nil)
+	router.ServeHTTP(httptest.NewRecorder(), req)
+	require.Len(t, repo.Records, 0)
+
+	body := `{"description":"rent","destination":{"accountNumber":"123456"}}`
+	req = httptest.NewRequest("PUT", "/transfers/abc", strings.NewReader(body))
+	req.Header.Set("X-Organization", "moov")
+	req.Header.Set("X-User-ID", "jane")
+	req.Header.Set("X-Request-ID", "req1")
+	req.Header.Set("X-Forwarded-For", "10.1.2.3, 172.16.0.1")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	require.Len(t, repo.Records, 1)
+	record := repo.Records[0]
+	require.Equal(t, "moov", record.Organization)
+	require.Equal(t, "jane", record.UserID)
+	require.Equal(t, "req1", record.RequestID)
+	require.Equal(t, "10.1.2.3", record.RemoteAddress)
+	require.Equal(t, "/transfers/{transferID}", record.Endpoint)
+	require.Equal(t, "/transfers/abc", record.Path)
+	require.Equal(t, "transfers", record.Resource)
+	require.Equal(t, http.StatusNotFound, record.Status)
+	require.Equal(t, `{"description":"rent","destination":{"accountNumber":"REDACTED"}}`, record.Body)
+}
+
+func TestRecordedBody(t *testing.T) {
+	require.Equal(t, "", recordedBody("", nil))
+	require.Equal(t, `[{"token":"REDACTED"}]`, recordedBody("application/json", []byte(`[{"token":"abc"}]`)))
+	require.Equal(t, "<multipart/form-data, 5 bytes>", recordedBody("multipart/form-data", []byte("hello")))
+}
@@ -0,0 +1,30 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package audit
+
+import (
+	"github.com/moov-io/paygate/pkg/admin"
+)
+
+type MockRepository struct {
+	Records []*admin.AuditRecord
+
+	Err error
+}
+
+func (r *MockRepository) writeRecord(record *admin.AuditRecord) error {
+	if r.Err != nil {
+		return r.Err
+	}
+	r.Records = append(r.Records, record)
+	return nil
+}
+
+func (r *MockRepository) getRecords(params recordFilterParams) ([]*admin.AuditRecord, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	return r.Records, nil
+}
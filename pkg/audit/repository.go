@@ -0,0 +1,99 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package audit
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/moov-io/paygate/pkg/admin"
+)
+
+// Repository saves audit records. Records are only ever inserted, there's no way to
+// update or delete them.
+type Repository interface {
+	writeRecord(record *admin.AuditRecord) error
+	getRecords(params recordFilterParams) ([]*admin.AuditRecord, error)
+}
+
+type recordFilterParams struct {
+	Organization string
+	UserID       string
+	Resource     string
+	StartDate    time.Time
+	EndDate      time.Time
+
+	Skip  int64
+	Count int64
+}
+
+func NewRepo(db *sql.DB) *sqlRepo {
+	return &sqlRepo{db: db}
+}
+
+type sqlRepo struct {
+	db *sql.DB
+}
+
+func (r *sqlRepo) Close() error {
+	return r.db.Close()
+}
+
+func (r *sqlRepo) writeRecord(record *admin.AuditRecord) error {
+	query := `insert into audit_records (record_id, organization, user_id, request_id, remote_address, method, endpoint, path, resource, status_code, body, created_at)
+values (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);`
+	_, err := r.db.Exec(query, record.RecordID, record.Organization, record.UserID, record.RequestID, record.RemoteAddress,
+		record.Method, record.Endpoint, record.Path, record.Resource, record.Status, record.Body, record.Created)
+	return err
+}
+
+func (r *sqlRepo) getRecords(params recordFilterParams) ([]*admin.AuditRecord, error) {
+	query := `select record_id, organization, user_id, request_id, remote_address, method, endpoint, path, resource, status_code, body, created_at
+from audit_records where 1 = 1`
+	var args []interface{}
+	if params.Organization != "" {
+		query += ` and organization = ?`
+		args = append(args, params.Organization)
+	}
+	if params.UserID != "" {
+		query += ` and user_id = ?`
+		args = append(args, params.UserID)
+	}
+	if params.Resource != "" {
+		query += ` and resource = ?`
+		args = append(args, params.Resource)
+	}
+	if !params.StartDate.IsZero() {
+		query += ` and created_at >= ?`
+		args = append(args, params.StartDate)
+	}
+	if !params.EndDate.IsZero() {
+		query += ` and created_at < ?`
+		args = append(args, params.EndDate)
+	}
+	query += ` order by created_at asc limit ? offset ?;`
+	args = append(args, params.Count, params.Skip)
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []*admin.AuditRecord
+	for rows.Next() {
+		var record admin.AuditRecord
+		var body *string
+		if err := rows.Scan(&record.RecordID, &record.Organization, &record.UserID, &record.RequestID, &record.RemoteAddress,
+			&record.Method, &record.Endpoint, &record.Path, &record.Resource, &record.Status, &body, &record.Created); err != nil {
+			return nil, err
+		}
+		if body != nil {
+			record.Body = *body
+		}
+		out = append(out, &record)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,83 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package audit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/moov-io/base"
+
+	"github.com/moov-io/paygate/pkg/admin"
+	"github.com/moov-io/paygate/pkg/database"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepository(t *testing.T) {
+	t.Parallel()
+
+	check := func(t *testing.T, repo *sqlRepo) {
+		orgID := base.ID()
+		now := time.Now().Truncate(time.Second)
+
+		for i, resource := range []string{"transfers", "transfers", "tokens"} {
+			require.NoError(t, repo.writeRecord(&admin.AuditRecord{
+				RecordID:      base.ID(),
+				Organization:  orgID,
+				UserID:        "jane",
+				RequestID:     base.ID(),
+				RemoteAddress: "10.0.0.1",
+				Method:        "POST",
+				Endpoint:      "/" + resource,
+				Path:          "/" + resource,
+				Resource:      resource,
+				Status:        200,
+				Body:          `{"amount":"USD 1.00"}`,
+				Created:       now.Add(time.Duration(i) * time.Minute),
+			}))
+		}
+
+		records, err := repo.getRecords(recordFilterParams{Organization: orgID, Count: 10})
+		require.NoError(t, err)
+		require.Len(t, records, 3)
+		require.Equal(t, "jane", records[0].UserID)
+		require.Equal(t, `{"amount":"USD 1.00"}`, records[0].Body)
+
+		records, err = repo.getRecords(recordFilterParams{Organization: orgID, Resource: "transfers", Count: 10})
+		require.NoError(t, err)
+		require.Len(t, records, 2)
+
+		records, err = repo.getRecords(recordFilterParams{Organization: orgID, StartDate: now.Add(30 * time.Second), Count: 10})
+		require.NoError(t, err)
+		require.Len(t, records, 2)
+
+		records, err = repo.getRecords(recordFilterParams{Organization: orgID, UserID: "john", Count: 10})
+		require.NoError(t, err)
+		require.Len(t, records, 0)
+
+		records, err = repo.getRecords(recordFilterParams{Organization: orgID, Count: 1, Skip: 2})
+		require.NoError(t, err)
+		require.Len(t, records, 1)
+		require.Equal(t, "tokens", records[0].Resource)
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+}
+
+func setupSQLiteDB(t *testing.T) *sqlRepo {
+	db := database.CreateTestSqliteDB(t)
+	t.Cleanup(func() { db.Close() })
+
+	return NewRepo(db.DB)
+}
+
+func setupMySQLeDB(t *testing.T) *sqlRepo {
+	db := database.CreateTestMySQLDB(t)
+	t.Cleanup(func() { db.Close() })
+
+	return NewRepo(db.DB)
+}
@@ -142,17 +142,19 @@ func (a *TransfersApiService) AddTransfer(ctx _context.Context, xOrganization st
 
 // DeleteTransferByIDOpts Optional parameters for the method 'DeleteTransferByID'
 type DeleteTransferByIDOpts struct {
-	XRequestID optional.String
+	XRequestID     optional.String
+	CancelTransfer optional.Interface
 }
 
 /*
 DeleteTransferByID Delete Transfer
-Remove a transfer for the specified organization. Its status will be updated as transfer is processed. It is only possible to delete (recall) a Transfer before it has been released from the financial institution.
+Cancel a transfer for the specified organization. Its status will be updated to canceled along with the reason given. It is only possible to delete (recall) a Transfer before it has been released from the financial institution. Deleting a canceled Transfer again succeeds and keeps the original reason.
  * @param ctx _context.Context - for authentication, logging, cancellation, deadlines, tracing, etc. Passed from http.Request or context.Background().
  * @param transferID transferID to delete
  * @param xOrganization Value used to separate and identify models
  * @param optional nil or *DeleteTransferByIDOpts - Optional Parameters:
  * @param "XRequestID" (optional.String) -  Optional requestID allows application developer to trace requests through the systems logs
+ * @param "CancelTransfer" (optional.Interface of CancelTransfer) -
 */
 func (a *TransfersApiService) DeleteTransferByID(ctx _context.Context, transferID string, xOrganization string, localVarOptionals *DeleteTransferByIDOpts) (*_nethttp.Response, error) {
 	var (
@@ -172,7 +174,7 @@ func (a *TransfersApiService) DeleteTransferByID(ctx _context.Context, transferI
 	localVarFormParams := _neturl.Values{}
 
 	// to determine the Content-Type header
-	localVarHTTPContentTypes := []string{}
+	localVarHTTPContentTypes := []string{"application/json"}
 
 	// set Content-Type header
 	localVarHTTPContentType := selectHeaderContentType(localVarHTTPContentTypes)
@@ -192,6 +194,14 @@ func (a *TransfersApiService) DeleteTransferByID(ctx _context.Context, transferI
 		localVarHeaderParams["X-Request-ID"] = parameterToString(localVarOptionals.XRequestID.Value(), "")
 	}
 	localVarHeaderParams["X-Organization"] = parameterToString(xOrganization, "")
+	// body params
+	if localVarOptionals != nil && localVarOptionals.CancelTransfer.IsSet() {
+		localVarOptionalCancelTransfer, localVarOptionalCancelTransferok := localVarOptionals.CancelTransfer.Value().(CancelTransfer)
+		if !localVarOptionalCancelTransferok {
+			return nil, reportError("cancelTransfer should be CancelTransfer")
+		}
+		localVarPostBody = &localVarOptionalCancelTransfer
+	}
 	r, err := a.client.prepareRequest(ctx, localVarPath, localVarHTTPMethod, localVarPostBody, localVarHeaderParams, localVarQueryParams, localVarFormParams, localVarFormFileName, localVarFileName, localVarFileBytes)
 	if err != nil {
 		return nil, err
@@ -327,6 +337,8 @@ type GetTransfersOpts struct {
 	EndDate         optional.Time
 	OrganizationIDs optional.String
 	CustomerIDs     optional.String
+	Tags            optional.String
+	View            optional.String
 	XRequestID      optional.String
 }
 
@@ -343,6 +355,8 @@ List all Transfers created for the given organization.
  * @param "EndDate" (optional.Time) -  Return Transfers that are scheduled for this date or earlier in ISO-8601 format YYYY-MM-DD. Can optionally be used with startDate to specify a date range.
  * @param "OrganizationIDs" (optional.String) -  Comma separated list of organizationID values to return Transfer objects for.
  * @param "CustomerIDs" (optional.String) -  Comma separated list of customerID values to return Transfer objects for. A maximum of 25 IDs is allowed.
+ * @param "Tags" (optional.String) -  Comma separated list of tags, Transfers with any of these tags are returned.
+ * @param "View" (optional.String) -  viewID of a saved TransferView whose filters are applied. Filters set on the request take precedence.
  * @param "XRequestID" (optional.String) -  Optional requestID allows application developer to trace requests through the systems logs
 @return []Transfer
 */
@@ -383,6 +397,12 @@ func (a *TransfersApiService) GetTransfers(ctx _context.Context, xOrganization s
 	if localVarOptionals != nil && localVarOptionals.CustomerIDs.IsSet() {
 		localVarQueryParams.Add("customerIDs", parameterToString(localVarOptionals.CustomerIDs.Value(), ""))
 	}
+	if localVarOptionals != nil && localVarOptionals.Tags.IsSet() {
+		localVarQueryParams.Add("tags", parameterToString(localVarOptionals.Tags.Value(), ""))
+	}
+	if localVarOptionals != nil && localVarOptionals.View.IsSet() {
+		localVarQueryParams.Add("view", parameterToString(localVarOptionals.View.Value(), ""))
+	}
 	// to determine the Content-Type header
 	localVarHTTPContentTypes := []string{}
 
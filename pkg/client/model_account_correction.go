@@ -0,0 +1,36 @@
+/*
+ * Paygate API
+ *
+ * PayGate is a RESTful API enabling first-party Automated Clearing House ([ACH](https://en.wikipedia.org/wiki/Automated_Clearing_House)) transfers to be created without a deep understanding of a full NACHA file specification. First-party transfers initiate at an Originating Depository Financial Institution (ODFI) and are sent off to other Financial Institutions.  An organization is a value used to isolate models from each other. This can be set to a \"user ID\" from your authentication service or any value your system has to identify.  There are also [admin endpoints](https://moov-io.github.io/paygate/admin/) for back-office operations.
+ *
+ * API version: v1
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package client
+
+import (
+	"time"
+)
+
+// AccountCorrection A Notification of Change an RDFI sent for one of a Customer's Accounts and the values it corrects.
+type AccountCorrection struct {
+	// Unique identifier of the correction
+	CorrectionID string `json:"correctionID"`
+	// Customer who owns the corrected Account
+	CustomerID string `json:"customerID"`
+	// Account the correction was received for
+	AccountID string `json:"accountID"`
+	// Transfer whose entry was corrected
+	TransferID string `json:"transferID"`
+	// Change code from the Addenda98 record
+	ChangeCode string `json:"changeCode"`
+	// Values the RDFI corrected. Empty when the change code doesn't correct Account details.
+	Changes []AccountCorrectionChange `json:"changes"`
+	Status  AccountCorrectionStatus   `json:"status"`
+	Created time.Time                 `json:"created"`
+	// When an operator applied or dismissed the correction
+	Reviewed *time.Time `json:"reviewed,omitempty"`
+	// X-User-ID of the operator who applied or dismissed the correction
+	ReviewedBy string `json:"reviewedBy,omitempty"`
+}
@@ -0,0 +1,32 @@
+/*
+ * Paygate API
+ *
+ * PayGate is a RESTful API enabling first-party Automated Clearing House ([ACH](https://en.wikipedia.org/wiki/Automated_Clearing_House)) transfers to be created without a deep understanding of a full NACHA file specification. First-party transfers initiate at an Originating Depository Financial Institution (ODFI) and are sent off to other Financial Institutions.  An organization is a value used to isolate models from each other. This can be set to a \"user ID\" from your authentication service or any value your system has to identify.  There are also [admin endpoints](https://moov-io.github.io/paygate/admin/) for back-office operations.
+ *
+ * API version: v1
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package client
+
+import (
+	"time"
+)
+
+// AccountVerification struct for AccountVerification
+type AccountVerification struct {
+	// accountID identifier from Customers service
+	AccountID string `json:"accountID"`
+	// Method used to verify the account
+	Method string `json:"method"`
+	// A microDepositID used to verify this account
+	MicroDepositID string             `json:"microDepositID"`
+	Status         VerificationStatus `json:"status"`
+	// How many more times the amounts can be confirmed before the verification fails
+	AttemptsRemaining int32       `json:"attemptsRemaining"`
+	Initiated         time.Time   `json:"initiated"`
+	ProcessedAt       *time.Time  `json:"processedAt,omitempty"`
+	ExpiresAt         *time.Time  `json:"expiresAt,omitempty"`
+	VerifiedAt        *time.Time  `json:"verifiedAt,omitempty"`
+	ReturnCode        *ReturnCode `json:"returnCode,omitempty"`
+}
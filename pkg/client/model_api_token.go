@@ -0,0 +1,27 @@
+/*
+ * Paygate API
+ *
+ * PayGate is a RESTful API enabling first-party Automated Clearing House ([ACH](https://en.wikipedia.org/wiki/Automated_Clearing_House)) transfers to be created without a deep understanding of a full NACHA file specification. First-party transfers initiate at an Originating Depository Financial Institution (ODFI) and are sent off to other Financial Institutions.  An organization is a value used to isolate models from each other. This can be set to a \"user ID\" from your authentication service or any value your system has to identify.  There are also [admin endpoints](https://moov-io.github.io/paygate/admin/) for back-office operations.
+ *
+ * API version: v1
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package client
+
+import (
+	"time"
+)
+
+// APIToken A token scoped to a single source Customer and Account which can only create Transfers to the registered receivers.
+type APIToken struct {
+	// Unique identifier for this APIToken
+	TokenID string `json:"tokenID"`
+	// Secret value used as a Bearer token in the Authorization header. Only returned when the token is created.
+	Token     string        `json:"token,omitempty"`
+	Source    Source        `json:"source"`
+	Receivers []Destination `json:"receivers"`
+	// Timestamp after which the token is no longer valid. Empty if the token does not expire.
+	Expires *time.Time `json:"expires,omitempty"`
+	Created time.Time  `json:"created"`
+}
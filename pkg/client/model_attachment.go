@@ -0,0 +1,34 @@
+/*
+ * Paygate API
+ *
+ * PayGate is a RESTful API enabling first-party Automated Clearing House ([ACH](https://en.wikipedia.org/wiki/Automated_Clearing_House)) transfers to be created without a deep understanding of a full NACHA file specification. First-party transfers initiate at an Originating Depository Financial Institution (ODFI) and are sent off to other Financial Institutions.  An organization is a value used to isolate models from each other. This can be set to a \"user ID\" from your authentication service or any value your system has to identify.  There are also [admin endpoints](https://moov-io.github.io/paygate/admin/) for back-office operations.
+ *
+ * API version: v1
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package client
+
+import (
+	"time"
+)
+
+// Attachment struct for Attachment
+type Attachment struct {
+	// Unique identifier for this attachment
+	AttachmentID string `json:"attachmentID"`
+	// customerID identifier from Customers service
+	CustomerID string `json:"customerID"`
+	// Optional accountID identifier from Customers service
+	AccountID string         `json:"accountID,omitempty"`
+	Kind      AttachmentKind `json:"kind"`
+	// Text of a note
+	Note string `json:"note,omitempty"`
+	// Original filename of an uploaded document
+	Filename string `json:"filename,omitempty"`
+	// MIME type of an uploaded document
+	ContentType string `json:"contentType,omitempty"`
+	// Size in bytes of an uploaded document
+	Size    int64     `json:"size,omitempty"`
+	Created time.Time `json:"created"`
+}
@@ -0,0 +1,26 @@
+/*
+ * Paygate API
+ *
+ * PayGate is a RESTful API enabling first-party Automated Clearing House ([ACH](https://en.wikipedia.org/wiki/Automated_Clearing_House)) transfers to be created without a deep understanding of a full NACHA file specification. First-party transfers initiate at an Originating Depository Financial Institution (ODFI) and are sent off to other Financial Institutions.  An organization is a value used to isolate models from each other. This can be set to a \"user ID\" from your authentication service or any value your system has to identify.  There are also [admin endpoints](https://moov-io.github.io/paygate/admin/) for back-office operations.
+ *
+ * API version: v1
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package client
+
+import (
+	"time"
+)
+
+// CreateDebitAuthorization Evidence a Customer authorized debits from one of their Accounts.
+type CreateDebitAuthorization struct {
+	// When the Customer gave the authorization
+	AuthorizedAt time.Time                `json:"authorizedAt"`
+	Method       DebitAuthorizationMethod `json:"method"`
+	// Largest amount one debit can be for, any amount when missing.
+	Amount    *Amount                     `json:"amount,omitempty"`
+	Frequency DebitAuthorizationFrequency `json:"frequency"`
+	// Terms the Customer agreed to, such as the wording they were shown.
+	Terms string `json:"terms,omitempty"`
+}
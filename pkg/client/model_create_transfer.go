@@ -18,4 +18,9 @@ type CreateTransfer struct {
 	Description string `json:"description"`
 	// When set to true this indicates the transfer should be processed the same day if possible.
 	SameDay bool `json:"sameDay,omitempty"`
+	// Date (YYYY-MM-DD) the transfer should settle on. This must be a banking day and defaults to the next banking day.
+	EffectiveDate string `json:"effectiveDate,omitempty"`
+	// Tags used to organize Transfers, each must be in the organization's transferTags.
+	Tags      []string   `json:"tags,omitempty"`
+	IatDetail *IatDetail `json:"iatDetail,omitempty"`
 }
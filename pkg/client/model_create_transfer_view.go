@@ -0,0 +1,27 @@
+/*
+ * Paygate API
+ *
+ * PayGate is a RESTful API enabling first-party Automated Clearing House ([ACH](https://en.wikipedia.org/wiki/Automated_Clearing_House)) transfers to be created without a deep understanding of a full NACHA file specification. First-party transfers initiate at an Originating Depository Financial Institution (ODFI) and are sent off to other Financial Institutions.  An organization is a value used to isolate models from each other. This can be set to a \"user ID\" from your authentication service or any value your system has to identify.  There are also [admin endpoints](https://moov-io.github.io/paygate/admin/) for back-office operations.
+ *
+ * API version: v1
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package client
+
+import (
+	"time"
+)
+
+// CreateTransferView Filters to save as a named view of Transfers.
+type CreateTransferView struct {
+	// Human readable name of the view, e.g. June payroll
+	Name   string         `json:"name"`
+	Status TransferStatus `json:"status,omitempty"`
+	// Only include Transfers created on or after this time
+	StartDate time.Time `json:"startDate,omitempty"`
+	// Only include Transfers created on or before this time
+	EndDate time.Time `json:"endDate,omitempty"`
+	// Only include Transfers with any of these tags
+	Tags []string `json:"tags,omitempty"`
+}
@@ -0,0 +1,21 @@
+/*
+ * Paygate API
+ *
+ * PayGate is a RESTful API enabling first-party Automated Clearing House ([ACH](https://en.wikipedia.org/wiki/Automated_Clearing_House)) transfers to be created without a deep understanding of a full NACHA file specification. First-party transfers initiate at an Originating Depository Financial Institution (ODFI) and are sent off to other Financial Institutions.  An organization is a value used to isolate models from each other. This can be set to a \"user ID\" from your authentication service or any value your system has to identify.  There are also [admin endpoints](https://moov-io.github.io/paygate/admin/) for back-office operations.
+ *
+ * API version: v1
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package client
+
+// IatBank The Receiver's financial institution of an IAT entry.
+type IatBank struct {
+	Name string `json:"name"`
+	// 01 for a national clearing system number, 02 for a BIC code and 03 for an IBAN
+	IdNumberQualifier string `json:"idNumberQualifier"`
+	// Identifier of the financial institution using idNumberQualifier
+	Identification string `json:"identification"`
+	// ISO 3166 country code of the financial institution's branch
+	BranchCountryCode string `json:"branchCountryCode"`
+}
@@ -0,0 +1,31 @@
+/*
+ * Paygate API
+ *
+ * PayGate is a RESTful API enabling first-party Automated Clearing House ([ACH](https://en.wikipedia.org/wiki/Automated_Clearing_House)) transfers to be created without a deep understanding of a full NACHA file specification. First-party transfers initiate at an Originating Depository Financial Institution (ODFI) and are sent off to other Financial Institutions.  An organization is a value used to isolate models from each other. This can be set to a \"user ID\" from your authentication service or any value your system has to identify.  There are also [admin endpoints](https://moov-io.github.io/paygate/admin/) for back-office operations.
+ *
+ * API version: v1
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package client
+
+// IatDetail International ACH Transaction (IAT) details. Transfers with these are originated as IAT entries rather than PPD.
+type IatDetail struct {
+	// FF (fixed-to-fixed), FV (fixed-to-variable) or VF (variable-to-fixed) foreign exchange conversion.
+	ForeignExchangeIndicator string `json:"foreignExchangeIndicator"`
+	// 1 when foreignExchangeReference is an exchange rate, 2 when it's a reference number and 3 when it's empty.
+	ForeignExchangeReferenceIndicator int32 `json:"foreignExchangeReferenceIndicator"`
+	// Exchange rate or reference number, required unless foreignExchangeReferenceIndicator is 3.
+	ForeignExchangeReference string `json:"foreignExchangeReference,omitempty"`
+	// ISO 3166 country code of the Receiver's financial institution
+	DestinationCountryCode string `json:"destinationCountryCode"`
+	// ISO 4217 currency code of the Transfer's amount
+	OriginatingCurrencyCode string `json:"originatingCurrencyCode"`
+	// ISO 4217 currency code the Receiver's account is held in
+	DestinationCurrencyCode string `json:"destinationCurrencyCode"`
+	// NACHA transaction type code of the payment, e.g. BUS (business), SAL (salary) or MIS (miscellaneous)
+	TransactionTypeCode string   `json:"transactionTypeCode"`
+	Originator          IatParty `json:"originator"`
+	Receiver            IatParty `json:"receiver"`
+	ReceivingBank       IatBank  `json:"receivingBank"`
+}
@@ -0,0 +1,22 @@
+/*
+ * Paygate API
+ *
+ * PayGate is a RESTful API enabling first-party Automated Clearing House ([ACH](https://en.wikipedia.org/wiki/Automated_Clearing_House)) transfers to be created without a deep understanding of a full NACHA file specification. First-party transfers initiate at an Originating Depository Financial Institution (ODFI) and are sent off to other Financial Institutions.  An organization is a value used to isolate models from each other. This can be set to a \"user ID\" from your authentication service or any value your system has to identify.  There are also [admin endpoints](https://moov-io.github.io/paygate/admin/) for back-office operations.
+ *
+ * API version: v1
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package client
+
+// InsufficientFunds struct for InsufficientFunds
+type InsufficientFunds struct {
+	// Human readable description of the error
+	Error string `json:"error"`
+	// Available balance of the source Account in the smallest currency unit (cents)
+	Available int64 `json:"available"`
+	// Total of the source Account's pending Transfers which are held against its balance
+	Holds int64 `json:"holds"`
+	// Amount of the rejected Transfer
+	Required int64 `json:"required"`
+}
@@ -22,6 +22,14 @@ type MicroDeposits struct {
 	Destination Destination    `json:"destination"`
 	Amounts     []Amount       `json:"amounts"`
 	Status      TransferStatus `json:"status"`
-	ProcessedAt *time.Time     `json:"processedAt,omitempty"`
-	Created     time.Time      `json:"created"`
+	// Sequence number of this verification attempt for the destination account, starting at 1
+	Attempt int32 `json:"attempt"`
+	// Incorrect confirmations of the amounts made for this attempt
+	ConfirmationAttempts int32      `json:"confirmationAttempts,omitempty"`
+	ExpiresAt            *time.Time `json:"expiresAt,omitempty"`
+	ProcessedAt          *time.Time `json:"processedAt,omitempty"`
+	// Timestamp when the Receiver confirmed the amounts
+	VerifiedAt *time.Time  `json:"verifiedAt,omitempty"`
+	ReturnCode *ReturnCode `json:"returnCode,omitempty"`
+	Created    time.Time   `json:"created"`
 }
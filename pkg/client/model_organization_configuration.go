@@ -13,4 +13,10 @@ package client
 type OrganizationConfiguration struct {
 	// This field corresponds to the CompanyIdentification value in an ACH BatchHeader record.
 	CompanyIdentification string `json:"companyIdentification"`
+	// How funds move for this organization's Transfers. One of firstParty (default), passThrough or twoLeg.
+	FundingFlow string `json:"fundingFlow,omitempty"`
+	// Send Transfers same-day when they're created before the same-day cutoff and within the same-day amount limit.
+	PreferSameDay bool `json:"preferSameDay,omitempty"`
+	// Tags which can be set on this organization's Transfers.
+	TransferTags []string `json:"transferTags,omitempty"`
 }
@@ -0,0 +1,31 @@
+/*
+ * Paygate API
+ *
+ * PayGate is a RESTful API enabling first-party Automated Clearing House ([ACH](https://en.wikipedia.org/wiki/Automated_Clearing_House)) transfers to be created without a deep understanding of a full NACHA file specification. First-party transfers initiate at an Originating Depository Financial Institution (ODFI) and are sent off to other Financial Institutions.  An organization is a value used to isolate models from each other. This can be set to a \"user ID\" from your authentication service or any value your system has to identify.  There are also [admin endpoints](https://moov-io.github.io/paygate/admin/) for back-office operations.
+ *
+ * API version: v1
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package client
+
+import (
+	"time"
+)
+
+// Prenote struct for Prenote
+type Prenote struct {
+	// A prenoteID to identify this zero-dollar entry to an external account
+	PrenoteID string `json:"prenoteID"`
+	// The transferID created for this prenote
+	TransferID  string             `json:"transferID"`
+	Destination Destination        `json:"destination"`
+	Status      VerificationStatus `json:"status"`
+	// Return code from the RDFI if the prenote was returned
+	ReturnCode string `json:"returnCode,omitempty"`
+	// Change code from the RDFI if a Notification of Change was received for the prenote
+	ChangeCode string `json:"changeCode,omitempty"`
+	// Timestamp when the account was verified after the waiting period passed
+	VerifiedAt *time.Time `json:"verifiedAt,omitempty"`
+	Created    time.Time  `json:"created"`
+}
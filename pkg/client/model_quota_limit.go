@@ -0,0 +1,28 @@
+/*
+ * Paygate API
+ *
+ * PayGate is a RESTful API enabling first-party Automated Clearing House ([ACH](https://en.wikipedia.org/wiki/Automated_Clearing_House)) transfers to be created without a deep understanding of a full NACHA file specification. First-party transfers initiate at an Originating Depository Financial Institution (ODFI) and are sent off to other Financial Institutions.  An organization is a value used to isolate models from each other. This can be set to a \"user ID\" from your authentication service or any value your system has to identify.  There are also [admin endpoints](https://moov-io.github.io/paygate/admin/) for back-office operations.
+ *
+ * API version: v1
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package client
+
+// QuotaLimit struct for QuotaLimit
+type QuotaLimit struct {
+	// What the limit applies to (organization, customer, user or odfi)
+	Scope string `json:"scope"`
+	// ID of the organization, customer or user, or the routing number of an ODFI cap
+	ID string `json:"id"`
+	// Which entries count towards the limit (debit, credit or total)
+	Direction string `json:"direction"`
+	// How long entries count towards the limit, or day for caps which reset each day
+	Window string `json:"window"`
+	// Value of the limit in the smallest currency unit (cents)
+	Limit int64 `json:"limit"`
+	// Amount already counted towards the limit
+	Used int64 `json:"used"`
+	// Amount which can still be originated under the limit
+	Remaining int64 `json:"remaining"`
+}
@@ -0,0 +1,24 @@
+/*
+ * Paygate API
+ *
+ * PayGate is a RESTful API enabling first-party Automated Clearing House ([ACH](https://en.wikipedia.org/wiki/Automated_Clearing_House)) transfers to be created without a deep understanding of a full NACHA file specification. First-party transfers initiate at an Originating Depository Financial Institution (ODFI) and are sent off to other Financial Institutions.  An organization is a value used to isolate models from each other. This can be set to a \"user ID\" from your authentication service or any value your system has to identify.  There are also [admin endpoints](https://moov-io.github.io/paygate/admin/) for back-office operations.
+ *
+ * API version: v1
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package client
+
+import (
+	"time"
+)
+
+// Representment struct for Representment
+type Representment struct {
+	// Attempts of this entry returned for R01 or R09, including this Transfer.
+	Attempts int32 `json:"attempts"`
+	// Re-presentments still allowed under NACHA rules.
+	Remaining int32 `json:"remaining"`
+	// Last day the entry can be re-presented, 180 days after the original attempt was processed.
+	Deadline time.Time `json:"deadline"`
+}
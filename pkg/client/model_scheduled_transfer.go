@@ -0,0 +1,36 @@
+/*
+ * Paygate API
+ *
+ * PayGate is a RESTful API enabling first-party Automated Clearing House ([ACH](https://en.wikipedia.org/wiki/Automated_Clearing_House)) transfers to be created without a deep understanding of a full NACHA file specification. First-party transfers initiate at an Originating Depository Financial Institution (ODFI) and are sent off to other Financial Institutions.  An organization is a value used to isolate models from each other. This can be set to a \"user ID\" from your authentication service or any value your system has to identify.  There are also [admin endpoints](https://moov-io.github.io/paygate/admin/) for back-office operations.
+ *
+ * API version: v1
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package client
+
+import (
+	"time"
+)
+
+// ScheduledTransfer A template which Transfers are created from on a recurring schedule.
+type ScheduledTransfer struct {
+	// scheduleID to uniquely identify this schedule
+	ScheduleID string            `json:"scheduleID"`
+	Transfer   CreateTransfer    `json:"transfer"`
+	Frequency  ScheduleFrequency `json:"frequency"`
+	Status     ScheduleStatus    `json:"status"`
+	// When the first Transfer is created. Later Transfers are created at the same time of day.
+	StartDate time.Time `json:"startDate"`
+	// Optional time after which no more Transfers are created
+	EndDate time.Time `json:"endDate,omitempty"`
+	// When the next Transfer will be created
+	NextRun time.Time `json:"nextRun,omitempty"`
+	// When a Transfer was last attempted from this schedule
+	LastRun time.Time `json:"lastRun,omitempty"`
+	// transferID of the last Transfer created from this schedule
+	LastTransferID string `json:"lastTransferID,omitempty"`
+	// Why the last Transfer couldn't be created, empty when it was created
+	LastError string    `json:"lastError,omitempty"`
+	Created   time.Time `json:"created"`
+}
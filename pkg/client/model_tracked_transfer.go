@@ -0,0 +1,29 @@
+/*
+ * Paygate API
+ *
+ * PayGate is a RESTful API enabling first-party Automated Clearing House ([ACH](https://en.wikipedia.org/wiki/Automated_Clearing_House)) transfers to be created without a deep understanding of a full NACHA file specification. First-party transfers initiate at an Originating Depository Financial Institution (ODFI) and are sent off to other Financial Institutions.  An organization is a value used to isolate models from each other. This can be set to a \"user ID\" from your authentication service or any value your system has to identify.  There are also [admin endpoints](https://moov-io.github.io/paygate/admin/) for back-office operations.
+ *
+ * API version: v1
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package client
+
+import (
+	"time"
+)
+
+// TrackedTransfer struct for TrackedTransfer
+type TrackedTransfer struct {
+	// transferID to uniquely identify this Transfer
+	TransferID string `json:"transferID"`
+	Amount     Amount `json:"amount"`
+	// Brief description of the transaction, this will appear on the receiving entity’s financial statement.
+	Description string         `json:"description"`
+	Status      TransferStatus `json:"status"`
+	// Date (YYYY-MM-DD) the transfer is expected to settle on.
+	EffectiveDate string      `json:"effectiveDate,omitempty"`
+	ReturnCode    *ReturnCode `json:"returnCode,omitempty"`
+	ProcessedAt   *time.Time  `json:"processedAt,omitempty"`
+	Created       time.Time   `json:"created"`
+}
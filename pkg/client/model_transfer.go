@@ -24,9 +24,32 @@ type Transfer struct {
 	Description string         `json:"description"`
 	Status      TransferStatus `json:"status"`
 	// When set to true this indicates the transfer should be processed the same day if possible.
-	SameDay      bool        `json:"sameDay"`
-	ReturnCode   *ReturnCode `json:"returnCode,omitempty"`
-	ProcessedAt  *time.Time  `json:"processedAt,omitempty"`
-	Created      time.Time   `json:"created"`
-	TraceNumbers []string    `json:"traceNumbers"`
+	SameDay bool `json:"sameDay"`
+	// Only included for organizations which prefer same-day processing and didn't request it.
+	SameDayDecision *SameDayDecision `json:"sameDayDecision,omitempty"`
+	// Date (YYYY-MM-DD) the transfer is expected to settle on.
+	EffectiveDate string      `json:"effectiveDate,omitempty"`
+	ReturnCode    *ReturnCode `json:"returnCode,omitempty"`
+	// Only included when listing Transfers with representable=true.
+	Representment *Representment `json:"representment,omitempty"`
+	ProcessedAt   *time.Time     `json:"processedAt,omitempty"`
+	Created       time.Time      `json:"created"`
+	TraceNumbers  []string       `json:"traceNumbers"`
+	// Funds movement for each side of the Transfer. Only included for passThrough and twoLeg funding flows.
+	Legs []TransferLeg `json:"legs,omitempty"`
+	// Only included for canceled Transfers.
+	Cancellation *Cancellation `json:"cancellation,omitempty"`
+	// Tags used to organize Transfers, each must be in the organization's transferTags.
+	Tags []string `json:"tags,omitempty"`
+	// transferID of the Transfer this one reverses, only included for reversals.
+	ReversalOf string `json:"reversalOf,omitempty"`
+	// transferID of the reversal created for this Transfer, only included once it's been reversed.
+	ReversedBy string `json:"reversedBy,omitempty"`
+	// Limits the Transfer came close to exceeding, only included when the Transfer is created.
+	Warnings  []LimitWarning `json:"warnings,omitempty"`
+	IatDetail *IatDetail     `json:"iatDetail,omitempty"`
+	// Identifies the Transfer in logs and notifications. This is the X-Request-ID of the request which created the Transfer, or its transferID when none was sent.
+	CorrelationID string `json:"correlationID,omitempty"`
+	// Entries of the Transfer in uploaded files, only included when creating a Transfer waited for it to be merged.
+	Files []TransferFile `json:"files,omitempty"`
 }
@@ -0,0 +1,26 @@
+/*
+ * Paygate API
+ *
+ * PayGate is a RESTful API enabling first-party Automated Clearing House ([ACH](https://en.wikipedia.org/wiki/Automated_Clearing_House)) transfers to be created without a deep understanding of a full NACHA file specification. First-party transfers initiate at an Originating Depository Financial Institution (ODFI) and are sent off to other Financial Institutions.  An organization is a value used to isolate models from each other. This can be set to a \"user ID\" from your authentication service or any value your system has to identify.  There are also [admin endpoints](https://moov-io.github.io/paygate/admin/) for back-office operations.
+ *
+ * API version: v1
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package client
+
+import (
+	"time"
+)
+
+// TransferChange A field-level change made to a Transfer
+type TransferChange struct {
+	// Name of the Transfer field which changed. Examples: status, returnCode, traceNumbers, deleted
+	Field string `json:"field"`
+	// Value prior to the change. Empty when the field was first set.
+	OldValue string `json:"oldValue,omitempty"`
+	NewValue string `json:"newValue,omitempty"`
+	// Which part of PayGate made the change. Options: api, admin, pipeline, inbound
+	Actor   string    `json:"actor"`
+	Created time.Time `json:"created"`
+}
@@ -0,0 +1,32 @@
+/*
+ * Paygate API
+ *
+ * PayGate is a RESTful API enabling first-party Automated Clearing House ([ACH](https://en.wikipedia.org/wiki/Automated_Clearing_House)) transfers to be created without a deep understanding of a full NACHA file specification. First-party transfers initiate at an Originating Depository Financial Institution (ODFI) and are sent off to other Financial Institutions.  An organization is a value used to isolate models from each other. This can be set to a \"user ID\" from your authentication service or any value your system has to identify.  There are also [admin endpoints](https://moov-io.github.io/paygate/admin/) for back-office operations.
+ *
+ * API version: v1
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package client
+
+import (
+	"time"
+)
+
+// TransferEvent A step in the lifecycle of a Transfer
+type TransferEvent struct {
+	// What happened. Options: created, changed, uploaded, acknowledged, return, correction
+	Type string `json:"type"`
+	// Human readable summary of the event
+	Description string `json:"description"`
+	// Which part of PayGate made a change, only for changed events
+	Actor string `json:"actor,omitempty"`
+	// Merged file the Transfer was uploaded in
+	Filename string `json:"filename,omitempty"`
+	// ImmediateDestination of the uploaded file
+	RoutingNumber string `json:"routingNumber,omitempty"`
+	TraceNumber   string `json:"traceNumber,omitempty"`
+	// Return or change code received from the RDFI
+	Code    string    `json:"code,omitempty"`
+	Created time.Time `json:"created"`
+}
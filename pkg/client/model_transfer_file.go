@@ -0,0 +1,36 @@
+/*
+ * Paygate API
+ *
+ * PayGate is a RESTful API enabling first-party Automated Clearing House ([ACH](https://en.wikipedia.org/wiki/Automated_Clearing_House)) transfers to be created without a deep understanding of a full NACHA file specification. First-party transfers initiate at an Originating Depository Financial Institution (ODFI) and are sent off to other Financial Institutions.  An organization is a value used to isolate models from each other. This can be set to a \"user ID\" from your authentication service or any value your system has to identify.  There are also [admin endpoints](https://moov-io.github.io/paygate/admin/) for back-office operations.
+ *
+ * API version: v1
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package client
+
+import (
+	"time"
+)
+
+// TransferFile An entry of the Transfer as it was uploaded to the ODFI within a merged file
+type TransferFile struct {
+	// Filename as uploaded to the ODFI
+	Filename string `json:"filename"`
+	// Hostname of the ODFI server the file was uploaded to
+	RemoteServer string `json:"remoteServer,omitempty"`
+	// ImmediateDestination of the uploaded file
+	RoutingNumber string `json:"routingNumber"`
+	// Batch the entry was merged into
+	BatchNumber int32  `json:"batchNumber"`
+	TraceNumber string `json:"traceNumber"`
+	// EntryDetail record as written in the file
+	EntryDetail string `json:"entryDetail"`
+	// The ODFI's acknowledgement of the batch, one of pending, accepted or rejected
+	Status string `json:"status"`
+	// Why the ODFI rejected the batch
+	Reason   string    `json:"reason,omitempty"`
+	Uploaded time.Time `json:"uploaded"`
+	// When the ODFI's acknowledgement of the file was processed
+	Acknowledged *time.Time `json:"acknowledged,omitempty"`
+}
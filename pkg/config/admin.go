@@ -4,7 +4,53 @@
 
 package config
 
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
 type Admin struct {
 	BindAddress           string
 	DisableConfigEndpoint bool
+
+	// Signing requires HMAC signed requests on admin routes which change state.
+	Signing *AdminSigning
+}
+
+func (cfg Admin) Validate() error {
+	if err := cfg.Signing.Validate(); err != nil {
+		return fmt.Errorf("signing: %v", err)
+	}
+	return nil
+}
+
+type AdminSigning struct {
+	// Secret is the shared key used to compute request signatures. It's excluded from
+	// the /config admin endpoint.
+	Secret string `json:"-"`
+
+	// MaxSkew is how far a request's timestamp can differ from our clock.
+	// Nonces are remembered for this long to reject replayed requests.
+	MaxSkew time.Duration
+}
+
+func (cfg *AdminSigning) Validate() error {
+	if cfg == nil {
+		return nil
+	}
+	if len(cfg.Secret) < 32 {
+		return errors.New("secret must be at least 32 characters")
+	}
+	if cfg.MaxSkew < 0 {
+		return fmt.Errorf("negative MaxSkew=%v", cfg.MaxSkew)
+	}
+	return nil
+}
+
+func (cfg *AdminSigning) Skew() time.Duration {
+	if cfg == nil || cfg.MaxSkew == 0 {
+		return 5 * time.Minute
+	}
+	return cfg.MaxSkew
 }
@@ -0,0 +1,79 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package admin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	moovhttp "github.com/moov-io/base/http"
+	"github.com/moov-io/paygate/pkg/config"
+)
+
+// fileTransfer is the part of the ODFI config which decides how and when files are
+// exchanged with the ODFI. Passwords, keys and tokens are left out so the document
+// can be version controlled.
+type fileTransfer struct {
+	RoutingNumber string
+	Protocol      string
+	Cutoffs       config.Cutoffs
+
+	InboundPath  string
+	OutboundPath string
+	ReturnPath   string
+	AllowedIPs   string
+
+	OutboundFilenameTemplate string
+
+	FTP  *config.FTP
+	SFTP *config.SFTP
+	API  *config.API
+	Blob *config.Blob
+}
+
+func exportFileTransfer(odfi config.ODFI) fileTransfer {
+	doc := fileTransfer{
+		RoutingNumber:            odfi.RoutingNumber,
+		Protocol:                 odfi.Protocol,
+		Cutoffs:                  odfi.Cutoffs,
+		InboundPath:              odfi.InboundPath,
+		OutboundPath:             odfi.OutboundPath,
+		ReturnPath:               odfi.ReturnPath,
+		AllowedIPs:               odfi.AllowedIPs,
+		OutboundFilenameTemplate: odfi.OutboundFilenameTemplate,
+		Blob:                     odfi.Blob,
+	}
+	if odfi.FTP != nil {
+		ftp := *odfi.FTP
+		ftp.Password = ""
+		doc.FTP = &ftp
+	}
+	if odfi.SFTP != nil {
+		sftp := *odfi.SFTP
+		sftp.Password, sftp.ClientPrivateKey = "", ""
+		doc.SFTP = &sftp
+	}
+	if odfi.API != nil {
+		api := *odfi.API
+		api.Token, api.CallbackToken = "", ""
+		doc.API = &api
+	}
+	return doc
+}
+
+// fileTransferConfig exports the running file transfer config. Documents aren't imported
+// as upload agents and cutoffs are setup from the config file when PayGate starts.
+func fileTransferConfig(cfg *config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			moovhttp.Problem(w, fmt.Errorf("unsupported HTTP verb %s", r.Method))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(exportFileTransfer(cfg.ODFI))
+	}
+}
@@ -6,10 +6,14 @@ package admin
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/moov-io/base/admin"
+	moovhttp "github.com/moov-io/base/http"
 	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/x/adminauth"
 )
 
 // RegisterRoutes will add HTTP handlers for PayGate's admin HTTP server
@@ -19,6 +23,9 @@ func RegisterRoutes(svc *admin.Server, cfg *config.Config) {
 	}
 
 	svc.AddHandler("/config", marshalConfig(cfg))
+	svc.AddHandler("/config/effective", effectiveConfig(cfg))
+	svc.AddHandler("/config/upload", adminauth.Protect(cfg.Admin.Signing, uploadConfig(cfg)))
+	svc.AddHandler("/config/file-transfer", adminauth.Protect(cfg.Admin.Signing, fileTransferConfig(cfg)))
 }
 
 func marshalConfig(cfg *config.Config) http.HandlerFunc {
@@ -28,3 +35,68 @@ func marshalConfig(cfg *config.Config) http.HandlerFunc {
 		json.NewEncoder(w).Encode(cfg)
 	}
 }
+
+// effective holds the cutoffs and limits which apply on one day
+type effective struct {
+	Date    string
+	Cutoffs effectiveCutoffs
+	Limits  config.Limits
+}
+
+type effectiveCutoffs struct {
+	Timezone string
+	Windows  []string
+}
+
+// effectiveConfig previews the cutoffs and limits in effect on the requested date
+// (YYYY-MM-DD), which defaults to today in the cutoff timezone.
+func effectiveConfig(cfg *config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+
+		loc := cfg.ODFI.Cutoffs.Location()
+		if loc == nil {
+			loc = time.UTC
+		}
+		day := time.Now().In(loc)
+		if v := r.URL.Query().Get("date"); v != "" {
+			when, err := time.ParseInLocation("2006-01-02", v, loc)
+			if err != nil {
+				moovhttp.Problem(w, fmt.Errorf("invalid date=%q: %v", v, err))
+				return
+			}
+			day = when
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(effective{
+			Date: day.Format("2006-01-02"),
+			Cutoffs: effectiveCutoffs{
+				Timezone: cfg.ODFI.Cutoffs.Timezone,
+				Windows:  cfg.ODFI.Cutoffs.WindowsOn(day),
+			},
+			Limits: cfg.Transfers.Limits.On(day),
+		})
+	}
+}
+
+// upload describes which protocol files are sent to the ODFI with
+type upload struct {
+	Protocol   string
+	Configured []string
+	Conflicts  []string
+}
+
+// uploadConfig shows the protocol used for uploads along with credentials configured
+// for other protocols, which are ignored.
+func uploadConfig(cfg *config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(upload{
+			Protocol:   cfg.ODFI.UploadProtocol(),
+			Configured: cfg.ODFI.ConfiguredProtocols(),
+			Conflicts:  cfg.ODFI.ProtocolConflicts(),
+		})
+	}
+}
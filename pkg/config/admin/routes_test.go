@@ -5,9 +5,11 @@
 package admin
 
 import (
+	"encoding/json"
 	"io/ioutil"
 	"net/http"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/moov-io/paygate/pkg/config"
@@ -40,3 +42,116 @@ func TestConfigRoute(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestConfigRoute__effective(t *testing.T) {
+	cfg, err := config.FromFile(filepath.Join("..", "testdata", "valid.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.ODFI.Cutoffs.Changes = []config.CutoffChange{
+		{ValidFrom: "2021-03-19", Windows: []string{"12:30", "16:20"}},
+	}
+	cfg.Transfers.Limits.Changes = []config.LimitsChange{
+		{ValidFrom: "2021-03-19", Fixed: &config.FixedLimits{SoftLimit: 1000, HardLimit: 2000}},
+	}
+
+	svc, _ := testclient.Admin(t)
+	RegisterRoutes(svc, cfg)
+
+	read := func(date string) (*effective, int) {
+		resp, err := http.DefaultClient.Get("http://" + svc.BindAddr() + "/config/effective?date=" + date)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+
+		var out effective
+		if resp.StatusCode == http.StatusOK {
+			if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return &out, resp.StatusCode
+	}
+
+	before, _ := read("2021-03-18")
+	if len(before.Cutoffs.Windows) != 1 || before.Limits.Fixed != nil {
+		t.Errorf("unexpected config: %#v", before)
+	}
+
+	after, _ := read("2021-03-19")
+	if after.Date != "2021-03-19" || len(after.Cutoffs.Windows) != 2 || after.Limits.Fixed.SoftLimit != 1000 {
+		t.Errorf("unexpected config: %#v", after)
+	}
+
+	if _, status := read("03-19-2021"); status != http.StatusBadRequest {
+		t.Errorf("unexpected HTTP status: %d", status)
+	}
+}
+
+func TestConfigRoute__upload(t *testing.T) {
+	cfg, err := config.FromFile(filepath.Join("..", "testdata", "valid.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.ODFI.Protocol = "ftp"
+	cfg.ODFI.SFTP = &config.SFTP{Hostname: "sftp.bank.com"}
+
+	svc, _ := testclient.Admin(t)
+	RegisterRoutes(svc, cfg)
+
+	resp, err := http.DefaultClient.Get("http://" + svc.BindAddr() + "/config/upload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var out upload
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Protocol != "ftp" || len(out.Configured) != 2 {
+		t.Errorf("unexpected upload config: %#v", out)
+	}
+	if len(out.Conflicts) != 1 || out.Conflicts[0] != "sftp is configured but protocol=ftp" {
+		t.Errorf("unexpected conflicts: %#v", out.Conflicts)
+	}
+}
+
+func TestConfigRoute__fileTransfer(t *testing.T) {
+	cfg, err := config.FromFile(filepath.Join("..", "testdata", "valid.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	svc, _ := testclient.Admin(t)
+	RegisterRoutes(svc, cfg)
+
+	address := "http://" + svc.BindAddr() + "/config/file-transfer"
+	resp, err := http.DefaultClient.Get(address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var doc fileTransfer
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.RoutingNumber != "987654320" || doc.FTP == nil || doc.FTP.Hostname != "sftp.moov.io" {
+		t.Errorf("unexpected file transfer config: %#v", doc)
+	}
+	if doc.FTP.Password != "" {
+		t.Error("exported FTP password")
+	}
+
+	// documents aren't imported
+	resp, err = http.DefaultClient.Post(address, "application/json", strings.NewReader("{}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("unexpected HTTP status: %d", resp.StatusCode)
+	}
+}
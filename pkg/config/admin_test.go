@@ -0,0 +1,34 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdminSigning(t *testing.T) {
+	var cfg *AdminSigning
+	require.NoError(t, cfg.Validate())
+	require.Equal(t, 5*time.Minute, cfg.Skew())
+
+	cfg = &AdminSigning{Secret: "short"}
+	require.Error(t, cfg.Validate())
+
+	cfg.Secret = strings.Repeat("a", 32)
+	require.NoError(t, cfg.Validate())
+
+	cfg.MaxSkew = -1 * time.Second
+	require.Error(t, cfg.Validate())
+
+	// the secret isn't exposed on the /config endpoint
+	bs, err := json.Marshal(Admin{Signing: cfg})
+	require.NoError(t, err)
+	require.NotContains(t, string(bs), cfg.Secret)
+}
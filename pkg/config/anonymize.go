@@ -0,0 +1,27 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"errors"
+)
+
+// Anonymize enables rewriting personal data in the database with fake values so production
+// snapshots can be restored into other environments. It must not be enabled in production.
+type Anonymize struct {
+	// Secret keys the fake values. The same Secret always produces the same value for an
+	// input, so IDs referenced across tables (or snapshots) stay consistent.
+	Secret string `json:"-"`
+}
+
+func (cfg *Anonymize) Validate() error {
+	if cfg == nil {
+		return nil
+	}
+	if len(cfg.Secret) < 16 {
+		return errors.New("secret must be at least 16 characters")
+	}
+	return nil
+}
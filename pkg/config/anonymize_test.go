@@ -0,0 +1,22 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnonymize(t *testing.T) {
+	var cfg *Anonymize
+	require.NoError(t, cfg.Validate())
+
+	cfg = &Anonymize{Secret: "short"}
+	require.Error(t, cfg.Validate())
+
+	cfg.Secret = "0123456789abcdef"
+	require.NoError(t, cfg.Validate())
+}
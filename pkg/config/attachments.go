@@ -0,0 +1,49 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"errors"
+	"fmt"
+)
+
+type Attachments struct {
+	// BucketURI is a gocloud.dev/blob URI where document contents are stored.
+	// Example: s3://my-bucket?region=us-west-1 or file:///opt/moov/attachments/
+	BucketURI string
+
+	// MaxSize is the largest document in bytes which can be uploaded.
+	MaxSize int64
+
+	// ContentTypes are the MIME types allowed for uploaded documents.
+	ContentTypes []string
+}
+
+func (cfg *Attachments) Validate() error {
+	if cfg == nil {
+		return nil
+	}
+	if cfg.BucketURI == "" {
+		return errors.New("missing bucketURI")
+	}
+	if cfg.MaxSize < 0 {
+		return fmt.Errorf("negative MaxSize=%d", cfg.MaxSize)
+	}
+	return nil
+}
+
+func (cfg *Attachments) MaxBytes() int64 {
+	if cfg == nil || cfg.MaxSize == 0 {
+		return 5 * 1024 * 1024 // 5MB
+	}
+	return cfg.MaxSize
+}
+
+func (cfg *Attachments) AllowedContentTypes() []string {
+	if cfg == nil || len(cfg.ContentTypes) == 0 {
+		return []string{"application/pdf", "image/jpeg", "image/png", "text/plain"}
+	}
+	return cfg.ContentTypes
+}
@@ -21,6 +21,8 @@ type Config struct {
 	Logger  log.Logger `yaml:"-" json:"-"`
 	Logging Logging
 
+	Mode RunMode
+
 	Http  HTTP
 	Admin Admin
 
@@ -32,7 +34,19 @@ type Config struct {
 	Transfers  Transfers
 	Validation Validation
 
-	Customers Customers
+	Customers   Customers
+	Attachments *Attachments
+	Tokens      *Tokens
+
+	Webhooks *Webhooks
+
+	// Hooks are called at points in a Transfer's lifecycle, in the order they're listed.
+	Hooks []Hook
+
+	Seed      *Seed
+	Anonymize *Anonymize
+
+	Startup Startup
 }
 
 type Logging struct {
@@ -110,6 +124,21 @@ func (cfg *Config) Validate() error {
 		return errors.New("missing Config")
 	}
 
+	if err := cfg.Mode.Validate(); err != nil {
+		return fmt.Errorf("mode: %v", err)
+	}
+	if err := cfg.Admin.Validate(); err != nil {
+		return fmt.Errorf("admin: %v", err)
+	}
+	if !cfg.Mode.API() || !cfg.Mode.Worker() {
+		if cfg.Pipeline.Stream != nil && cfg.Pipeline.Stream.InMem != nil {
+			return fmt.Errorf("mode: %s requires a pipeline shared between processes, not inmem", cfg.Mode)
+		}
+	}
+
+	if err := cfg.Database.Validate(); err != nil {
+		return fmt.Errorf("database: %v", err)
+	}
 	if err := cfg.ODFI.Validate(); err != nil {
 		return fmt.Errorf("odfi: %v", err)
 	}
@@ -126,6 +155,29 @@ func (cfg *Config) Validate() error {
 	if err := cfg.Customers.Validate(); err != nil {
 		return fmt.Errorf("customers: %v", err)
 	}
+	if err := cfg.Attachments.Validate(); err != nil {
+		return fmt.Errorf("attachments: %v", err)
+	}
+	if err := cfg.Tokens.Validate(); err != nil {
+		return fmt.Errorf("tokens: %v", err)
+	}
+	if err := cfg.Webhooks.Validate(); err != nil {
+		return fmt.Errorf("webhooks: %v", err)
+	}
+	for i := range cfg.Hooks {
+		if err := cfg.Hooks[i].Validate(); err != nil {
+			return fmt.Errorf("hooks[%d]: %v", i, err)
+		}
+	}
+	if err := cfg.Seed.Validate(); err != nil {
+		return fmt.Errorf("seed: %v", err)
+	}
+	if err := cfg.Anonymize.Validate(); err != nil {
+		return fmt.Errorf("anonymize: %v", err)
+	}
+	if err := cfg.Startup.Validate(); err != nil {
+		return fmt.Errorf("startup: %v", err)
+	}
 
 	return nil
 }
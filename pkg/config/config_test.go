@@ -59,6 +59,9 @@ odfi:
   cutoffs:
     timezone: "America/New_York"
     windows: ["17:00"]
+    changes:
+      - validFrom: 2021-03-19
+        windows: ["12:30", "17:00"]
   ftp:
     hostname: sftp.moov.io
     username: moov
@@ -82,6 +85,14 @@ pipeline:
   stream:
     inmem:
       url: "mem://paygate"
+transfers:
+  limits:
+    changes:
+      - validFrom: 2021-03-19
+        validTo: 2021-03-31
+        fixed:
+          softLimit: 2000
+          hardLimit: 8000
 `)
 	cfg, err := Read(conf)
 	if err != nil {
@@ -109,6 +120,13 @@ pipeline:
 	if cfg.Pipeline.Stream.InMem.URL != "mem://paygate" {
 		t.Errorf("missing pipeline stream config: %#v", cfg.Pipeline.Stream)
 	}
+
+	if changes := cfg.ODFI.Cutoffs.Changes; len(changes) != 1 || changes[0].ValidFrom != "2021-03-19" {
+		t.Errorf("unexpected cutoff changes: %#v", changes)
+	}
+	if changes := cfg.Transfers.Limits.Changes; len(changes) != 1 || changes[0].ValidTo != "2021-03-31" || changes[0].Fixed.HardLimit != 8000 {
+		t.Errorf("unexpected limits changes: %#v", changes)
+	}
 }
 
 func TestConfig__FTP(t *testing.T) {
@@ -145,6 +163,32 @@ func TestConfig__SFTP(t *testing.T) {
 	}
 }
 
+func TestConfig__API(t *testing.T) {
+	cfg := Empty().ODFI.API
+	if cfg != nil {
+		t.Fatalf("unexpected %#v", cfg)
+	}
+
+	if v := cfg.Timeout(); v != 10*time.Second {
+		t.Errorf("dialTimeout=%v", v)
+	}
+	if v := cfg.MappingName(); v != "json-batches" {
+		t.Errorf("mapping=%s", v)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+
+	cfg = &API{Endpoint: "https://odfi.example.com/v1"}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+	cfg.CallbackToken = "secret"
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+}
+
 func TestConfig__Uppercase(t *testing.T) {
 	// What can be read out for a config if the case of each key varies from lowercase
 	data := []byte(`Customers:
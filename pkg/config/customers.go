@@ -6,21 +6,65 @@ package config
 
 import (
 	"errors"
+	"fmt"
+	"time"
 )
 
 type Customers struct {
 	Endpoint string
 	Accounts Accounts
 	Debug    bool
+
+	// OFAC enables periodic re-screening of Customers PayGate has transferred with
+	OFAC *OFAC
+
+	HTTPClient *HTTPClient
 }
 
 func (cfg Customers) Validate() error {
 	if err := cfg.Accounts.Decryptor.Validate(); err != nil {
 		return err
 	}
+	if err := cfg.OFAC.Validate(); err != nil {
+		return fmt.Errorf("ofac: %v", err)
+	}
+	if err := cfg.HTTPClient.Validate(); err != nil {
+		return fmt.Errorf("http client: %v", err)
+	}
 	return nil
 }
 
+const DefaultOFACMatchThreshold = 0.99
+
+type OFAC struct {
+	// Interval is how often every Customer is searched against OFAC again
+	Interval time.Duration
+
+	// MatchThreshold is the lowest match score which suspends a Customer.
+	// A zero value uses DefaultOFACMatchThreshold.
+	MatchThreshold float32
+}
+
+func (cfg *OFAC) Validate() error {
+	if cfg == nil {
+		return nil
+	}
+	if cfg.Interval <= 0 {
+		return errors.New("missing interval")
+	}
+	if cfg.MatchThreshold < 0 || cfg.MatchThreshold > 1 {
+		return fmt.Errorf("matchThreshold of %v must be between 0 and 1", cfg.MatchThreshold)
+	}
+	return nil
+}
+
+func (cfg *OFAC) Threshold() float32 {
+	if cfg == nil || cfg.MatchThreshold == 0 {
+		return DefaultOFACMatchThreshold
+	}
+	return cfg.MatchThreshold
+}
+
 type Accounts struct {
 	Decryptor Decryptor
 }
@@ -6,6 +6,7 @@ package config
 
 import (
 	"testing"
+	"time"
 )
 
 func TestCustomers_validate(t *testing.T) {
@@ -22,3 +23,31 @@ func TestCustomers_validate(t *testing.T) {
 		t.Error("expected error")
 	}
 }
+
+func TestCustomers__OFAC(t *testing.T) {
+	var cfg *OFAC
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+	if n := cfg.Threshold(); n != DefaultOFACMatchThreshold {
+		t.Errorf("unexpected threshold: %v", n)
+	}
+
+	cfg = &OFAC{}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+	cfg.Interval = 24 * time.Hour
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+
+	cfg.MatchThreshold = 1.5
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+	cfg.MatchThreshold = 0.9
+	if n := cfg.Threshold(); n != 0.9 {
+		t.Errorf("unexpected threshold: %v", n)
+	}
+}
@@ -5,6 +5,8 @@
 package config
 
 import (
+	"errors"
+	"fmt"
 	"os"
 
 	"github.com/moov-io/paygate/pkg/util"
@@ -15,6 +17,15 @@ type Database struct {
 	MySQL  *MySQL
 }
 
+func (cfg Database) Validate() error {
+	if cfg.MySQL != nil {
+		if err := cfg.MySQL.Migrations.Validate(); err != nil {
+			return fmt.Errorf("mysql: %v", err)
+		}
+	}
+	return nil
+}
+
 type SQLite struct {
 	Path string
 }
@@ -24,6 +35,8 @@ type MySQL struct {
 	Username string
 	Password string
 	Database string
+
+	Migrations *MySQLMigrations
 }
 
 func (cfg *MySQL) GetPassword() string {
@@ -33,3 +46,56 @@ func (cfg *MySQL) GetPassword() string {
 	}
 	return util.Or(pass, cfg.Password)
 }
+
+// MySQLMigrations controls how schema migrations which would lock large tables are run.
+type MySQLMigrations struct {
+	// LargeTableRows is the estimated row count at which a table is considered large.
+	// ALTERs on large tables lock them for minutes.
+	LargeTableRows int64
+
+	// Production refuses to run locking migrations on large tables unless they're
+	// run online or Force is set.
+	Production bool
+	Force      bool
+
+	// Online runs migrations on large tables with gh-ost, which copies the table
+	// in the background and swaps it in once caught up.
+	Online *OnlineMigrations
+}
+
+func (cfg *MySQLMigrations) Validate() error {
+	if cfg == nil {
+		return nil
+	}
+	if cfg.LargeTableRows < 0 {
+		return errors.New("migrations: negative largeTableRows")
+	}
+	return nil
+}
+
+// LargeTable returns true if a table with the estimated rows is large.
+func (cfg *MySQLMigrations) LargeTable(rows int64) bool {
+	if cfg == nil {
+		return false
+	}
+	threshold := cfg.LargeTableRows
+	if threshold == 0 {
+		threshold = 1000000
+	}
+	return rows >= threshold
+}
+
+type OnlineMigrations struct {
+	// Path to the gh-ost binary, defaults to gh-ost on the $PATH.
+	Path string
+
+	// Args are added to each gh-ost invocation, e.g. --max-load=Threads_running=25
+	Args []string
+}
+
+func (cfg *OnlineMigrations) Command() string {
+	if cfg == nil || cfg.Path == "" {
+		return "gh-ost"
+	}
+	return cfg.Path
+}
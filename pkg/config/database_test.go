@@ -0,0 +1,32 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMySQLMigrations(t *testing.T) {
+	var cfg *MySQLMigrations
+	require.NoError(t, cfg.Validate())
+	require.False(t, cfg.LargeTable(50000000))
+
+	cfg = &MySQLMigrations{}
+	require.False(t, cfg.LargeTable(999999))
+	require.True(t, cfg.LargeTable(1000000))
+
+	cfg.LargeTableRows = 100
+	require.True(t, cfg.LargeTable(100))
+
+	cfg.LargeTableRows = -1
+	require.Error(t, Database{MySQL: &MySQL{Migrations: cfg}}.Validate())
+
+	var online *OnlineMigrations
+	require.Equal(t, "gh-ost", online.Command())
+	online = &OnlineMigrations{Path: "/usr/local/bin/gh-ost"}
+	require.Equal(t, "/usr/local/bin/gh-ost", online.Command())
+}
@@ -0,0 +1,89 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// effectiveDateFormat is the YYYY-MM-DD layout of ValidFrom and ValidTo
+const effectiveDateFormat = "2006-01-02"
+
+// validatePeriod checks the ValidFrom and ValidTo dates of a staged config change.
+// Either date can be empty to leave that end of the period open.
+func validatePeriod(validFrom, validTo string) error {
+	if validFrom == "" && validTo == "" {
+		return errors.New("missing ValidFrom and ValidTo")
+	}
+	if validFrom != "" {
+		if _, err := time.Parse(effectiveDateFormat, validFrom); err != nil {
+			return fmt.Errorf("invalid ValidFrom=%q: %v", validFrom, err)
+		}
+	}
+	if validTo != "" {
+		if _, err := time.Parse(effectiveDateFormat, validTo); err != nil {
+			return fmt.Errorf("invalid ValidTo=%q: %v", validTo, err)
+		}
+	}
+	if validFrom != "" && validTo != "" && validTo < validFrom {
+		return fmt.Errorf("ValidTo=%s is before ValidFrom=%s", validTo, validFrom)
+	}
+	return nil
+}
+
+// effectiveOn returns true if day falls between validFrom and validTo, inclusive.
+func effectiveOn(validFrom, validTo string, day time.Time) bool {
+	d := day.Format(effectiveDateFormat)
+	if validFrom != "" && d < validFrom {
+		return false
+	}
+	if validTo != "" && d > validTo {
+		return false
+	}
+	return true
+}
+
+// CutoffChange replaces the cutoff windows on the days between ValidFrom and ValidTo
+// (YYYY-MM-DD, inclusive) so new windows can be staged before they take effect.
+type CutoffChange struct {
+	ValidFrom string
+	ValidTo   string
+	Windows   []string
+}
+
+func (cfg CutoffChange) Validate() error {
+	if err := validatePeriod(cfg.ValidFrom, cfg.ValidTo); err != nil {
+		return err
+	}
+	if len(cfg.Windows) == 0 {
+		return errors.New("no cutoff windows")
+	}
+	for i := range cfg.Windows {
+		if _, err := time.Parse("15:04", cfg.Windows[i]); err != nil {
+			return fmt.Errorf("invalid window %q: %v", cfg.Windows[i], err)
+		}
+	}
+	return nil
+}
+
+// LimitsChange replaces the fixed limits on the days between ValidFrom and ValidTo
+// (YYYY-MM-DD, inclusive) so new limits can be staged before they take effect.
+type LimitsChange struct {
+	ValidFrom string
+	ValidTo   string
+	Fixed     *FixedLimits
+}
+
+func (cfg LimitsChange) Validate() error {
+	if err := validatePeriod(cfg.ValidFrom, cfg.ValidTo); err != nil {
+		return err
+	}
+	if err := cfg.Fixed.Validate(); err != nil {
+		return fmt.Errorf("fixed limits: %v", err)
+	}
+	return nil
+}
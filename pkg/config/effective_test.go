@@ -0,0 +1,110 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestEffective__validatePeriod(t *testing.T) {
+	if err := validatePeriod("2021-03-19", ""); err != nil {
+		t.Error(err)
+	}
+	if err := validatePeriod("", "2021-03-19"); err != nil {
+		t.Error(err)
+	}
+	if err := validatePeriod("2021-03-19", "2021-03-19"); err != nil {
+		t.Error(err)
+	}
+
+	cases := [][2]string{
+		{"", ""},
+		{"03/19/2021", ""},
+		{"", "tomorrow"},
+		{"2021-03-19", "2021-03-18"},
+	}
+	for i := range cases {
+		if err := validatePeriod(cases[i][0], cases[i][1]); err == nil {
+			t.Errorf("case %d: expected error", i)
+		}
+	}
+}
+
+func TestEffective__effectiveOn(t *testing.T) {
+	day := time.Date(2021, time.March, 19, 10, 0, 0, 0, time.UTC)
+
+	if !effectiveOn("2021-03-19", "", day) || !effectiveOn("", "2021-03-19", day) {
+		t.Error("expected inclusive period")
+	}
+	if effectiveOn("2021-03-20", "", day) || effectiveOn("", "2021-03-18", day) {
+		t.Error("expected day outside period")
+	}
+}
+
+func TestCutoffs__WindowsOn(t *testing.T) {
+	cfg := Cutoffs{
+		Timezone: "America/New_York",
+		Windows:  []string{"16:15"},
+		Changes: []CutoffChange{
+			{ValidFrom: "2021-03-19", Windows: []string{"12:30", "16:15"}},
+		},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	before := time.Date(2021, time.March, 18, 10, 0, 0, 0, time.UTC)
+	if windows := cfg.WindowsOn(before); !reflect.DeepEqual(windows, []string{"16:15"}) {
+		t.Errorf("unexpected windows: %v", windows)
+	}
+	after := time.Date(2021, time.March, 19, 10, 0, 0, 0, time.UTC)
+	if windows := cfg.WindowsOn(after); !reflect.DeepEqual(windows, []string{"12:30", "16:15"}) {
+		t.Errorf("unexpected windows: %v", windows)
+	}
+	if windows := cfg.AllWindows(); !reflect.DeepEqual(windows, []string{"16:15", "12:30"}) {
+		t.Errorf("unexpected windows: %v", windows)
+	}
+
+	cfg.Changes[0].Windows = []string{"noon"}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+	cfg.Changes[0].Windows = nil
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestLimits__On(t *testing.T) {
+	cfg := Limits{
+		Fixed: &FixedLimits{SoftLimit: 1000, HardLimit: 5000},
+		Changes: []LimitsChange{
+			{ValidFrom: "2021-03-19", ValidTo: "2021-03-31", Fixed: &FixedLimits{SoftLimit: 2000, HardLimit: 8000}},
+		},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	limits := cfg.On(time.Date(2021, time.March, 18, 10, 0, 0, 0, time.UTC))
+	if limits.Fixed.SoftLimit != 1000 || len(limits.Changes) != 0 {
+		t.Errorf("unexpected limits: %#v", limits)
+	}
+	limits = cfg.On(time.Date(2021, time.March, 25, 10, 0, 0, 0, time.UTC))
+	if limits.Fixed.SoftLimit != 2000 {
+		t.Errorf("unexpected limits: %#v", limits.Fixed)
+	}
+	limits = cfg.On(time.Date(2021, time.April, 1, 10, 0, 0, 0, time.UTC))
+	if limits.Fixed.SoftLimit != 1000 {
+		t.Errorf("unexpected limits: %#v", limits.Fixed)
+	}
+
+	cfg.Changes[0].Fixed = &FixedLimits{SoftLimit: -1}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+}
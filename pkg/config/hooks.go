@@ -0,0 +1,78 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+const (
+	HookPreTransferCreate = "preTransferCreate"
+	HookPreMerge          = "preMerge"
+	HookPreUpload         = "preUpload"
+	HookPostReturn        = "postReturn"
+
+	// HookFailIgnore logs hook errors and timeouts then continues as if the hook accepted.
+	HookFailIgnore = "ignore"
+
+	// HookFailReject treats hook errors and timeouts as if the hook rejected.
+	HookFailReject = "reject"
+)
+
+// Hook is an HTTP endpoint PayGate calls at a point in a Transfer's lifecycle so
+// deployments can add their own rules and enrichment.
+type Hook struct {
+	Name     string
+	Point    string
+	Endpoint string
+
+	Timeout time.Duration
+
+	// FailurePolicy decides what happens when the hook can't be reached, times out or
+	// responds with an error status. Options are "ignore" (default) and "reject".
+	FailurePolicy string
+
+	HTTPClient *HTTPClient
+}
+
+func (cfg Hook) Validate() error {
+	if cfg.Name == "" {
+		return errors.New("missing name")
+	}
+	switch cfg.Point {
+	case HookPreTransferCreate, HookPreMerge, HookPreUpload, HookPostReturn:
+	default:
+		return fmt.Errorf("unknown point %q", cfg.Point)
+	}
+	if u, err := url.Parse(cfg.Endpoint); err != nil || u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("invalid endpoint %q", cfg.Endpoint)
+	}
+	if cfg.Timeout < 0 {
+		return fmt.Errorf("negative Timeout=%v", cfg.Timeout)
+	}
+	switch cfg.FailurePolicy {
+	case "", HookFailIgnore, HookFailReject:
+	default:
+		return fmt.Errorf("unknown failurePolicy %q", cfg.FailurePolicy)
+	}
+	if err := cfg.HTTPClient.Validate(); err != nil {
+		return fmt.Errorf("http client: %v", err)
+	}
+	return nil
+}
+
+func (cfg Hook) RequestTimeout() time.Duration {
+	if cfg.Timeout == 0 {
+		return 5 * time.Second
+	}
+	return cfg.Timeout
+}
+
+func (cfg Hook) RejectOnFailure() bool {
+	return cfg.FailurePolicy == HookFailReject
+}
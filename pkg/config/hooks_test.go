@@ -0,0 +1,44 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHook(t *testing.T) {
+	cfg := Hook{
+		Name:     "fraud-score",
+		Point:    HookPreTransferCreate,
+		Endpoint: "https://rules.example.com/transfers",
+	}
+	require.NoError(t, cfg.Validate())
+	require.Equal(t, 5*time.Second, cfg.RequestTimeout())
+	require.False(t, cfg.RejectOnFailure())
+
+	cfg.FailurePolicy = HookFailReject
+	require.NoError(t, cfg.Validate())
+	require.True(t, cfg.RejectOnFailure())
+
+	cfg.FailurePolicy = "retry"
+	require.Error(t, cfg.Validate())
+	cfg.FailurePolicy = ""
+
+	cfg.Point = "postCreate"
+	require.Error(t, cfg.Validate())
+	cfg.Point = HookPostReturn
+
+	cfg.Endpoint = "/transfers"
+	require.Error(t, cfg.Validate())
+}
+
+func TestConfig__Hooks(t *testing.T) {
+	cfg := Empty()
+	cfg.Hooks = []Hook{{Name: "enrich"}}
+	require.Error(t, cfg.Validate())
+}
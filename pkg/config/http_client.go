@@ -0,0 +1,64 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+)
+
+// ProxyDirect skips the environment's proxy for a client.
+const ProxyDirect = "direct"
+
+// HTTPClient configures how PayGate connects to another service over HTTP. Without it requests
+// are proxied according to the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables and
+// the system's certificates are trusted.
+type HTTPClient struct {
+	// TLS sets the certificates used to verify the service and an optional client
+	// certificate for mutual TLS.
+	TLS *ClientTLS
+
+	// Proxy is the URL of a proxy requests are sent through instead of the one from the
+	// environment, e.g. http://proxy.example.com:3128. Set it to "direct" to skip proxying.
+	Proxy string
+}
+
+func (cfg *HTTPClient) Validate() error {
+	if cfg == nil {
+		return nil
+	}
+	if err := cfg.TLS.Validate(); err != nil {
+		return fmt.Errorf("tls: %v", err)
+	}
+	if cfg.Proxy != "" && cfg.Proxy != ProxyDirect {
+		if u, err := url.Parse(cfg.Proxy); err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("invalid proxy %q", cfg.Proxy)
+		}
+	}
+	return nil
+}
+
+// ClientTLS holds certificates for connecting to a service. Without a CAFile the system's
+// certificate pool is used.
+type ClientTLS struct {
+	CAFile string
+
+	// CertFile and KeyFile are an optional client certificate for mutual TLS
+	CertFile string
+	KeyFile  string
+
+	InsecureSkipVerify bool
+}
+
+func (cfg *ClientTLS) Validate() error {
+	if cfg == nil {
+		return nil
+	}
+	if (cfg.CertFile == "") != (cfg.KeyFile == "") {
+		return errors.New("both certFile and keyFile are required")
+	}
+	return nil
+}
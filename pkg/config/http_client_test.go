@@ -0,0 +1,44 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"testing"
+)
+
+func TestHTTPClient(t *testing.T) {
+	var cfg *HTTPClient
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg = &HTTPClient{
+		TLS: &ClientTLS{
+			CAFile:   "/opt/certs/ca.pem",
+			CertFile: "/opt/certs/client.pem",
+			KeyFile:  "/opt/certs/client.key",
+		},
+		Proxy: "http://proxy.example.com:3128",
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg.Proxy = ProxyDirect
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg.Proxy = "proxy.example.com"
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+
+	cfg.Proxy = ""
+	cfg.TLS.KeyFile = ""
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+}
@@ -0,0 +1,47 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RunMode controls which components of PayGate are started.
+//
+// API instances serve HTTP requests and publish Transfers onto the pipeline. Worker instances
+// consume the pipeline, merge and upload files, and process inbound files. The default runs both.
+type RunMode string
+
+const (
+	ModeAll    RunMode = "all"
+	ModeAPI    RunMode = "api"
+	ModeWorker RunMode = "worker"
+)
+
+func (m RunMode) Validate() error {
+	switch RunMode(strings.ToLower(string(m))) {
+	case "", ModeAll, ModeAPI, ModeWorker:
+		return nil
+	}
+	return fmt.Errorf("unknown mode %q", m)
+}
+
+// API returns true if HTTP routes should be served.
+func (m RunMode) API() bool {
+	return m.normalize() != ModeWorker
+}
+
+// Worker returns true if the pipeline consumer and file operations should be started.
+func (m RunMode) Worker() bool {
+	return m.normalize() != ModeAPI
+}
+
+func (m RunMode) normalize() RunMode {
+	if m == "" {
+		return ModeAll
+	}
+	return RunMode(strings.ToLower(string(m)))
+}
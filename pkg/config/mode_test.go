@@ -0,0 +1,42 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunMode(t *testing.T) {
+	var mode RunMode
+	require.NoError(t, mode.Validate())
+	require.True(t, mode.API())
+	require.True(t, mode.Worker())
+
+	mode = ModeAPI
+	require.NoError(t, mode.Validate())
+	require.True(t, mode.API())
+	require.False(t, mode.Worker())
+
+	mode = "WORKER"
+	require.NoError(t, mode.Validate())
+	require.False(t, mode.API())
+	require.True(t, mode.Worker())
+
+	mode = "other"
+	require.Error(t, mode.Validate())
+}
+
+func TestRunMode__inmemPipeline(t *testing.T) {
+	cfg := Empty()
+	cfg.Mode = ModeAPI
+	cfg.Pipeline.Stream = &StreamPipeline{
+		InMem: &InMemPipeline{URL: "mem://paygate"},
+	}
+	err := cfg.Validate()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "mode: api requires")
+}
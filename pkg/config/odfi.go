@@ -8,6 +8,8 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"net/url"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -23,7 +25,8 @@ var (
 	// Examples:
 	//  - 20191010-987654320-1.ach
 	//  - 20191010-987654320-1.ach.gpg (GPG encrypted)
-	DefaultFilenameTemplate = `{{ date "20060102" }}-{{ .RoutingNumber }}.ach{{ if .GPG }}.gpg{{ end }}`
+	//  - 20191010-987654320-2-IAT.ach (international batches)
+	DefaultFilenameTemplate = `{{ date "20060102" }}-{{ .RoutingNumber }}-{{ .Sequence }}{{ if .IAT }}-IAT{{ end }}.ach{{ if .GPG }}.gpg{{ end }}`
 )
 
 // ODFI holds all the configuration for sending and retrieving ACH files with
@@ -49,13 +52,32 @@ type ODFI struct {
 
 	OutboundFilenameTemplate string
 
+	// OutboundFilename names uploaded files with something other than OutboundFilenameTemplate.
+	OutboundFilename *OutboundFilename
+
+	// Protocol picks which of FTP, SFTP, API or Blob is used to send files to the ODFI.
+	// It can be left empty when only one of them is configured.
+	Protocol string
+
 	FTP  *FTP
 	SFTP *SFTP
+	API  *API
+	Blob *Blob
 
 	Inbound Inbound
 
 	FileConfig FileConfig
 
+	// Settlement is the ODFI's own account which funds move through for
+	// pass-through and two-leg fund flows.
+	Settlement *Settlement
+
+	// Tenants override the values above for some organizations.
+	Tenants []Tenant
+
+	// OriginationCaps are contractual limits on the dollar amount uploaded each day.
+	OriginationCaps []OriginationCap
+
 	Storage *Storage
 }
 
@@ -66,6 +88,57 @@ func (cfg *ODFI) FilenameTemplate() string {
 	return cfg.OutboundFilenameTemplate
 }
 
+const (
+	FilenameProviderTemplate = "template"
+	FilenameProviderHTTP     = "http"
+)
+
+// OutboundFilename picks how files are named before they're uploaded to the ODFI.
+type OutboundFilename struct {
+	// Provider is "template" (default), which renders OutboundFilenameTemplate, or
+	// "http" which asks Endpoint for each filename.
+	Provider string
+
+	Endpoint string
+	Timeout  time.Duration
+
+	HTTPClient *HTTPClient
+}
+
+// ProviderName returns the configured provider, a nil config uses the template.
+func (cfg *OutboundFilename) ProviderName() string {
+	if cfg == nil || cfg.Provider == "" {
+		return FilenameProviderTemplate
+	}
+	return cfg.Provider
+}
+
+func (cfg *OutboundFilename) RequestTimeout() time.Duration {
+	if cfg == nil || cfg.Timeout == 0 {
+		return 10 * time.Second
+	}
+	return cfg.Timeout
+}
+
+func (cfg *OutboundFilename) Validate() error {
+	switch cfg.ProviderName() {
+	case FilenameProviderTemplate:
+		return nil
+	case FilenameProviderHTTP:
+		if u, err := url.Parse(cfg.Endpoint); err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("outboundFilename: invalid endpoint %q", cfg.Endpoint)
+		}
+		if cfg.Timeout < 0 {
+			return fmt.Errorf("outboundFilename: negative timeout=%v", cfg.Timeout)
+		}
+		if err := cfg.HTTPClient.Validate(); err != nil {
+			return fmt.Errorf("outboundFilename: http client: %v", err)
+		}
+		return nil
+	}
+	return fmt.Errorf("outboundFilename: unknown provider %q", cfg.Provider)
+}
+
 func (cfg *ODFI) SplitAllowedIPs() []string {
 	if cfg.AllowedIPs != "" {
 		return strings.Split(cfg.AllowedIPs, ",")
@@ -86,9 +159,108 @@ func (cfg *ODFI) Validate() error {
 	if err := cfg.FileConfig.Validate(); err != nil {
 		return fmt.Errorf("odfi config: %v", err)
 	}
+	if err := cfg.Inbound.Validate(); err != nil {
+		return fmt.Errorf("odfi config: %v", err)
+	}
+	if err := cfg.Settlement.Validate(); err != nil {
+		return fmt.Errorf("odfi config: %v", err)
+	}
+	if err := validateTenants(cfg.Tenants); err != nil {
+		return fmt.Errorf("odfi config: %v", err)
+	}
+	if err := cfg.API.Validate(); err != nil {
+		return fmt.Errorf("odfi config: %v", err)
+	}
+	if err := cfg.Blob.Validate(); err != nil {
+		return fmt.Errorf("odfi config: %v", err)
+	}
+	if err := cfg.OutboundFilename.Validate(); err != nil {
+		return fmt.Errorf("odfi config: %v", err)
+	}
+	if err := cfg.validateProtocol(); err != nil {
+		return fmt.Errorf("odfi config: %v", err)
+	}
+	if err := validateOriginationCaps(cfg.OriginationCaps); err != nil {
+		return fmt.Errorf("odfi config: %v", err)
+	}
 	return nil
 }
 
+const (
+	ProtocolFTP  = "ftp"
+	ProtocolSFTP = "sftp"
+	ProtocolAPI  = "api"
+	ProtocolBlob = "blob"
+)
+
+// ConfiguredProtocols returns each protocol which has its credentials set.
+func (cfg *ODFI) ConfiguredProtocols() []string {
+	var out []string
+	if cfg.FTP != nil {
+		out = append(out, ProtocolFTP)
+	}
+	if cfg.SFTP != nil {
+		out = append(out, ProtocolSFTP)
+	}
+	if cfg.API != nil {
+		out = append(out, ProtocolAPI)
+	}
+	if cfg.Blob != nil {
+		out = append(out, ProtocolBlob)
+	}
+	return out
+}
+
+// UploadProtocol returns the protocol files are sent to the ODFI with. An empty
+// Protocol falls back to the only configured protocol, otherwise "" is returned.
+func (cfg *ODFI) UploadProtocol() string {
+	if cfg.Protocol != "" {
+		return strings.ToLower(cfg.Protocol)
+	}
+	if configured := cfg.ConfiguredProtocols(); len(configured) == 1 {
+		return configured[0]
+	}
+	return ""
+}
+
+// ProtocolConflicts describes credentials which are configured for protocols other
+// than the one in use. They're ignored, but often point to a mistaken config.
+func (cfg *ODFI) ProtocolConflicts() []string {
+	protocol := cfg.UploadProtocol()
+	if protocol == "" {
+		return nil
+	}
+	var out []string
+	for _, other := range cfg.ConfiguredProtocols() {
+		if other != protocol {
+			out = append(out, fmt.Sprintf("%s is configured but protocol=%s", other, protocol))
+		}
+	}
+	return out
+}
+
+func (cfg *ODFI) validateProtocol() error {
+	configured := cfg.ConfiguredProtocols()
+	if cfg.Protocol == "" {
+		if len(configured) > 1 {
+			return fmt.Errorf("protocol is required when %s are configured", strings.Join(configured, ", "))
+		}
+		return nil
+	}
+
+	protocol := cfg.UploadProtocol()
+	switch protocol {
+	case ProtocolFTP, ProtocolSFTP, ProtocolAPI, ProtocolBlob:
+		for i := range configured {
+			if configured[i] == protocol {
+				return nil
+			}
+		}
+		return fmt.Errorf("protocol=%s is missing its %s config", protocol, protocol)
+	}
+	return fmt.Errorf("unknown protocol=%q", cfg.Protocol)
+}
+
 type Gateway struct {
 	Origin          string
 	OriginName      string
@@ -99,6 +271,9 @@ type Gateway struct {
 type Cutoffs struct {
 	Timezone string
 	Windows  []string
+
+	// Changes replace Windows on the days they're effective. The first matching change is used.
+	Changes []CutoffChange
 }
 
 func (cfg Cutoffs) Location() *time.Location {
@@ -116,9 +291,43 @@ func (cfg Cutoffs) Validate() error {
 	if len(cfg.Windows) == 0 {
 		return errors.New("no cutoff windows")
 	}
+	for i := range cfg.Changes {
+		if err := cfg.Changes[i].Validate(); err != nil {
+			return fmt.Errorf("cutoff change %d: %v", i, err)
+		}
+	}
 	return nil
 }
 
+// WindowsOn returns the cutoff windows in effect on day.
+func (cfg Cutoffs) WindowsOn(day time.Time) []string {
+	for i := range cfg.Changes {
+		if effectiveOn(cfg.Changes[i].ValidFrom, cfg.Changes[i].ValidTo, day) {
+			return cfg.Changes[i].Windows
+		}
+	}
+	return cfg.Windows
+}
+
+// AllWindows returns every cutoff window, including those from Changes, without duplicates.
+func (cfg Cutoffs) AllWindows() []string {
+	seen := make(map[string]bool)
+	var out []string
+	add := func(windows []string) {
+		for i := range windows {
+			if !seen[windows[i]] {
+				seen[windows[i]] = true
+				out = append(out, windows[i])
+			}
+		}
+	}
+	add(cfg.Windows)
+	for i := range cfg.Changes {
+		add(cfg.Changes[i].Windows)
+	}
+	return out
+}
+
 type FTP struct {
 	Hostname string
 	Username string
@@ -202,8 +411,337 @@ func (cfg *SFTP) String() string {
 	return buf.String()
 }
 
+// API is an ODFI which accepts originations through a REST API rather than file uploads.
+// Each batch of a merged file is sent as its own request and the ODFI acknowledges them
+// later on a callback to PayGate's admin server.
+type API struct {
+	// Endpoint is the base URL of the ODFI's API, batches are sent to Endpoint + "/batches"
+	Endpoint string
+
+	// Token is sent on each request as a Bearer Authorization header
+	Token string
+
+	// Mapping is the name of the upload.BatchMapper which converts files into requests
+	Mapping string
+
+	// CallbackToken is required as a Bearer Authorization header on acknowledgement callbacks
+	CallbackToken string
+
+	DialTimeout time.Duration
+
+	HTTPClient *HTTPClient
+}
+
+func (cfg *API) Timeout() time.Duration {
+	if cfg == nil || cfg.DialTimeout == 0*time.Second {
+		return 10 * time.Second
+	}
+	return cfg.DialTimeout
+}
+
+func (cfg *API) MappingName() string {
+	if cfg == nil || cfg.Mapping == "" {
+		return "json-batches"
+	}
+	return cfg.Mapping
+}
+
+func (cfg *API) Validate() error {
+	if cfg == nil {
+		return nil
+	}
+	if cfg.Endpoint == "" {
+		return errors.New("api: missing endpoint")
+	}
+	if cfg.CallbackToken == "" {
+		return errors.New("api: missing callbackToken")
+	}
+	if err := cfg.HTTPClient.Validate(); err != nil {
+		return fmt.Errorf("api: http client: %v", err)
+	}
+	return nil
+}
+
+func (cfg *API) String() string {
+	var buf bytes.Buffer
+	buf.WriteString(fmt.Sprintf("API{Endpoint=%s, ", cfg.Endpoint))
+	buf.WriteString(fmt.Sprintf("Token=%s, ", mask.Password(cfg.Token)))
+	buf.WriteString(fmt.Sprintf("Mapping=%s, ", cfg.MappingName()))
+	buf.WriteString(fmt.Sprintf("CallbackToken=%s}", mask.Password(cfg.CallbackToken)))
+	return buf.String()
+}
+
+// Blob is an ODFI which polls a bucket for files rather than running an FTP or SFTP
+// server. Files are written under OutboundPath and inbound and return files are read
+// from InboundPath and ReturnPath of the bucket.
+type Blob struct {
+	// BucketURI is a gocloud.dev/blob URI, e.g. s3://bucket?region=us-east-2,
+	// gs://bucket or azblob://container
+	BucketURI string
+
+	// Destinations upload files for some routing numbers into another bucket
+	Destinations []BlobDestination
+}
+
+type BlobDestination struct {
+	// RoutingNumber is matched against the ImmediateDestination of each file
+	RoutingNumber string
+	BucketURI     string
+}
+
+// Bucket returns the BucketURI files for routingNumber are uploaded into.
+func (cfg *Blob) Bucket(routingNumber string) string {
+	for i := range cfg.Destinations {
+		if cfg.Destinations[i].RoutingNumber == routingNumber {
+			return cfg.Destinations[i].BucketURI
+		}
+	}
+	return cfg.BucketURI
+}
+
+func (cfg *Blob) Validate() error {
+	if cfg == nil {
+		return nil
+	}
+	if cfg.BucketURI == "" {
+		return errors.New("blob: missing bucketURI")
+	}
+	seen := make(map[string]bool)
+	for i, dest := range cfg.Destinations {
+		if dest.RoutingNumber == "" || dest.BucketURI == "" {
+			return fmt.Errorf("blob: destinations[%d]: routingNumber and bucketURI are required", i)
+		}
+		if seen[dest.RoutingNumber] {
+			return fmt.Errorf("blob: destinations[%d]: duplicate routingNumber %s", i, dest.RoutingNumber)
+		}
+		seen[dest.RoutingNumber] = true
+	}
+	return nil
+}
+
 type Inbound struct {
 	Interval time.Duration
+
+	// Quarantine holds suspicious inbound files for manual review rather than
+	// processing them. Leaving this nil disables quarantining.
+	Quarantine *Quarantine
+
+	// Acknowledgements are parsers for non-NACHA files the ODFI sends in response
+	// to our uploads, picked by matching the inbound filename.
+	Acknowledgements []AckFormat
+
+	// Mailboxes are buckets or directories banks push inbound files into. They're
+	// read on each Interval alongside the FTP or SFTP server.
+	Mailboxes []Mailbox
+
+	// Corrections decides which Notifications of Change are applied to Accounts
+	// without an operator reviewing them first.
+	Corrections Corrections
+
+	// Returns configures how return entries are matched to Transfers.
+	Returns Returns
+}
+
+func (cfg Inbound) Validate() error {
+	if err := cfg.Quarantine.Validate(); err != nil {
+		return fmt.Errorf("quarantine: %v", err)
+	}
+	for i := range cfg.Mailboxes {
+		if err := cfg.Mailboxes[i].Validate(); err != nil {
+			return fmt.Errorf("mailboxes: %v", err)
+		}
+	}
+	for i := range cfg.Acknowledgements {
+		if err := cfg.Acknowledgements[i].Validate(); err != nil {
+			return fmt.Errorf("acknowledgements: %v", err)
+		}
+	}
+	if err := cfg.Corrections.Validate(); err != nil {
+		return fmt.Errorf("corrections: %v", err)
+	}
+	if err := cfg.Returns.Validate(); err != nil {
+		return fmt.Errorf("returns: %v", err)
+	}
+	return nil
+}
+
+// applicableChangeCodes are the Notification of Change codes which correct an Account's
+// routing number, account number or type. Other codes correct Customer details which
+// are kept in the Customers service.
+var applicableChangeCodes = map[string]bool{
+	"C01": true, // Incorrect DFI account number
+	"C02": true, // Incorrect routing number
+	"C03": true, // Incorrect routing number and DFI account number
+	"C05": true, // Incorrect transaction code
+	"C06": true, // Incorrect DFI account number and transaction code
+	"C07": true, // Incorrect routing number, DFI account number and transaction code
+}
+
+// Corrections are policies for Notifications of Change (COR entries) received from RDFIs.
+type Corrections struct {
+	// AutoApply lists change codes whose corrected data is used for later Transfers as
+	// soon as the Notification of Change arrives, e.g. C01 and C02. Corrections with
+	// other codes are recorded and wait for an operator to apply or dismiss them.
+	AutoApply []string
+}
+
+func (cfg Corrections) Validate() error {
+	for _, code := range cfg.AutoApply {
+		if !applicableChangeCodes[code] {
+			return fmt.Errorf("change code %q can't be applied to accounts", code)
+		}
+	}
+	return nil
+}
+
+// Applies returns true when corrections with changeCode are applied without review.
+func (cfg Corrections) Applies(changeCode string) bool {
+	for _, code := range cfg.AutoApply {
+		if code == changeCode {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns are policies for matching return entries to the Transfers they were for.
+type Returns struct {
+	// Fuzzy matches return entries whose trace number doesn't match a Transfer on
+	// the original routing and account number, amount and date. Leaving this nil
+	// disables fuzzy matching and returns without a trace number match are unmatched.
+	Fuzzy *FuzzyReturnMatching
+}
+
+func (cfg Returns) Validate() error {
+	if err := cfg.Fuzzy.Validate(); err != nil {
+		return fmt.Errorf("fuzzy: %v", err)
+	}
+	return nil
+}
+
+const (
+	DefaultFuzzyReturnWindow     = 5 * 24 * time.Hour
+	DefaultFuzzyReturnConfidence = 0.9
+)
+
+type FuzzyReturnMatching struct {
+	// Window is how long before or after the return's effective date the original
+	// entry could have been uploaded. Defaults to DefaultFuzzyReturnWindow.
+	Window time.Duration
+
+	// Confidence is the lowest score, between 0 and 1, a lone candidate needs to be
+	// matched without review. Returns with a weaker or more than one strong candidate
+	// are held for an admin to resolve. Defaults to DefaultFuzzyReturnConfidence.
+	Confidence float64
+}
+
+func (cfg *FuzzyReturnMatching) Validate() error {
+	if cfg == nil {
+		return nil
+	}
+	if cfg.Window < 0 {
+		return fmt.Errorf("negative window: %v", cfg.Window)
+	}
+	if cfg.Confidence < 0 || cfg.Confidence > 1 {
+		return fmt.Errorf("confidence of %v must be between 0 and 1", cfg.Confidence)
+	}
+	return nil
+}
+
+func (cfg *FuzzyReturnMatching) MatchWindow() time.Duration {
+	if cfg == nil || cfg.Window == 0 {
+		return DefaultFuzzyReturnWindow
+	}
+	return cfg.Window
+}
+
+func (cfg *FuzzyReturnMatching) MinConfidence() float64 {
+	if cfg == nil || cfg.Confidence == 0 {
+		return DefaultFuzzyReturnConfidence
+	}
+	return cfg.Confidence
+}
+
+// Mailbox is a location a bank pushes files into rather than PayGate polling
+// their FTP or SFTP server. Exactly one of BucketURI or Directory is set.
+type Mailbox struct {
+	// RoutingNumber is the ABA routing number of the bank pushing files
+	RoutingNumber string
+
+	// BucketURI is a gocloud.dev/blob URI, e.g. s3://bucket?region=us-east-2
+	BucketURI string
+
+	// Directory is a local or NFS mounted filesystem path
+	Directory string
+
+	// InboundPath and ReturnPath are prefixes inside the mailbox, they default
+	// to "inbound/" and "returned/"
+	InboundPath string
+	ReturnPath  string
+}
+
+func (cfg Mailbox) Inbound() string {
+	if cfg.InboundPath == "" {
+		return "inbound/"
+	}
+	return cfg.InboundPath
+}
+
+func (cfg Mailbox) Return() string {
+	if cfg.ReturnPath == "" {
+		return "returned/"
+	}
+	return cfg.ReturnPath
+}
+
+func (cfg Mailbox) Validate() error {
+	if cfg.RoutingNumber == "" {
+		return errors.New("missing routingNumber")
+	}
+	if (cfg.BucketURI == "") == (cfg.Directory == "") {
+		return fmt.Errorf("%s: one of bucketURI or directory is required", cfg.RoutingNumber)
+	}
+	return nil
+}
+
+type AckFormat struct {
+	// Pattern is a filepath.Match pattern for inbound filenames, e.g. "*.ack"
+	Pattern string
+
+	// Format names the parser used for matching files, e.g. "batch-sequence"
+	Format string
+}
+
+func (cfg AckFormat) Validate() error {
+	if cfg.Pattern == "" {
+		return errors.New("missing pattern")
+	}
+	if _, err := filepath.Match(cfg.Pattern, ""); err != nil {
+		return fmt.Errorf("pattern %q: %v", cfg.Pattern, err)
+	}
+	if cfg.Format == "" {
+		return fmt.Errorf("pattern %q: missing format", cfg.Pattern)
+	}
+	return nil
+}
+
+type Quarantine struct {
+	// Directory is where quarantined files are kept until they're approved or discarded.
+	Directory string
+
+	// AllowedOrigins are the ImmediateOrigin values expected on inbound files.
+	// Files from any other origin are quarantined. An empty list allows every origin.
+	AllowedOrigins []string
+}
+
+func (cfg *Quarantine) Validate() error {
+	if cfg == nil {
+		return nil
+	}
+	if cfg.Directory == "" {
+		return errors.New("missing directory")
+	}
+	return nil
 }
 
 type FileConfig struct {
@@ -235,6 +773,110 @@ type Addendum struct {
 	Create05 bool
 }
 
+type Settlement struct {
+	AccountNumber string
+
+	// AccountType is either checking or savings
+	AccountType string
+
+	// Name is written as the IndividualName on entries for the settlement account
+	Name string
+
+	// HoldDays is how many banking days the credit leg of a two-leg transfer is held
+	// after its debit leg is uploaded. This should cover the return window of the debit.
+	HoldDays int
+}
+
+func (cfg *Settlement) HoldBankingDays() int {
+	if cfg == nil || cfg.HoldDays <= 0 {
+		return 2
+	}
+	return cfg.HoldDays
+}
+
+func (cfg *Settlement) Validate() error {
+	if cfg == nil {
+		return nil
+	}
+	if cfg.AccountNumber == "" {
+		return errors.New("settlement: missing accountNumber")
+	}
+	switch strings.ToLower(cfg.AccountType) {
+	case "checking", "savings":
+	default:
+		return fmt.Errorf("settlement: unknown accountType %q", cfg.AccountType)
+	}
+	if cfg.HoldDays < 0 {
+		return fmt.Errorf("settlement: negative holdDays=%d", cfg.HoldDays)
+	}
+	return nil
+}
+
+// OriginationCap limits the total of debits and credits in files uploaded for a routing
+// number (their ImmediateDestination) each day.
+type OriginationCap struct {
+	RoutingNumber string
+
+	// Daily is the cap in cents.
+	Daily int64
+
+	// WarnPercent is a percentage (1-100) of Daily. Uploads which leave more than this
+	// share of the cap used log a warning.
+	WarnPercent int
+
+	// WarnOnly uploads files which exceed the cap after logging a warning. Otherwise
+	// they aren't uploaded and are listed with the failed uploads.
+	WarnOnly bool
+}
+
+func (cfg OriginationCap) Validate() error {
+	if err := ach.CheckRoutingNumber(cfg.RoutingNumber); err != nil {
+		return err
+	}
+	if cfg.Daily <= 0 {
+		return fmt.Errorf("%s: unexpected daily=%d", cfg.RoutingNumber, cfg.Daily)
+	}
+	if cfg.WarnPercent < 0 || cfg.WarnPercent > 100 {
+		return fmt.Errorf("%s: unexpected warnPercent=%d", cfg.RoutingNumber, cfg.WarnPercent)
+	}
+	return nil
+}
+
+// NearCap returns true when originated uses more than WarnPercent of the cap without exceeding it.
+func (cfg OriginationCap) NearCap(originated int64) bool {
+	if cfg.WarnPercent <= 0 || originated > cfg.Daily {
+		return false
+	}
+	return originated*100 > cfg.Daily*int64(cfg.WarnPercent)
+}
+
+func validateOriginationCaps(caps []OriginationCap) error {
+	seen := make(map[string]bool)
+	for i := range caps {
+		if err := caps[i].Validate(); err != nil {
+			return fmt.Errorf("originationCaps[%d]: %v", i, err)
+		}
+		if seen[caps[i].RoutingNumber] {
+			return fmt.Errorf("originationCaps[%d]: duplicate routingNumber %s", i, caps[i].RoutingNumber)
+		}
+		seen[caps[i].RoutingNumber] = true
+	}
+	return nil
+}
+
+// OriginationCapFor returns the cap of files uploaded for routingNumber, or nil.
+func (cfg *ODFI) OriginationCapFor(routingNumber string) *OriginationCap {
+	if cfg == nil {
+		return nil
+	}
+	for i := range cfg.OriginationCaps {
+		if cfg.OriginationCaps[i].RoutingNumber == routingNumber {
+			return &cfg.OriginationCaps[i]
+		}
+	}
+	return nil
+}
+
 type Storage struct {
 	// CleanupLocalDirectory determines if we delete the local directory after
 	// processing is finished. Leaving these files around helps debugging, but
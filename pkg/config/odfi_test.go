@@ -6,6 +6,7 @@ package config
 
 import (
 	"testing"
+	"time"
 )
 
 func TestCutoffs_Location(t *testing.T) {
@@ -34,3 +35,262 @@ func TestODFI__Validate(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestODFI__Protocol(t *testing.T) {
+	cfg := &ODFI{}
+	if err := cfg.validateProtocol(); err != nil {
+		t.Fatal(err)
+	}
+	if p := cfg.UploadProtocol(); p != "" {
+		t.Errorf("unexpected protocol=%q", p)
+	}
+
+	// inferred from the only credentials
+	cfg.SFTP = &SFTP{Hostname: "sftp.bank.com"}
+	if err := cfg.validateProtocol(); err != nil {
+		t.Fatal(err)
+	}
+	if p := cfg.UploadProtocol(); p != ProtocolSFTP {
+		t.Errorf("unexpected protocol=%q", p)
+	}
+
+	// ambiguous without a protocol
+	cfg.FTP = &FTP{Hostname: "ftp.bank.com"}
+	if err := cfg.validateProtocol(); err == nil {
+		t.Error("expected error")
+	}
+	cfg.Protocol = "SFTP"
+	if err := cfg.validateProtocol(); err != nil {
+		t.Fatal(err)
+	}
+	if p := cfg.UploadProtocol(); p != ProtocolSFTP {
+		t.Errorf("unexpected protocol=%q", p)
+	}
+	if conflicts := cfg.ProtocolConflicts(); len(conflicts) != 1 || conflicts[0] != "ftp is configured but protocol=sftp" {
+		t.Errorf("unexpected conflicts: %#v", conflicts)
+	}
+
+	// missing credentials
+	cfg.Protocol = "api"
+	if err := cfg.validateProtocol(); err == nil {
+		t.Error("expected error")
+	}
+	cfg.Protocol = "as2"
+	if err := cfg.validateProtocol(); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestBlob__Validate(t *testing.T) {
+	var cfg *Blob
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg = &Blob{
+		BucketURI: "s3://odfi?region=us-east-2",
+		Destinations: []BlobDestination{
+			{RoutingNumber: "231380104", BucketURI: "gs://other"},
+		},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if uri := cfg.Bucket("231380104"); uri != "gs://other" {
+		t.Errorf("unexpected bucket: %s", uri)
+	}
+	if uri := cfg.Bucket("987654320"); uri != "s3://odfi?region=us-east-2" {
+		t.Errorf("unexpected bucket: %s", uri)
+	}
+
+	cfg.Destinations = append(cfg.Destinations, BlobDestination{RoutingNumber: "231380104", BucketURI: "gs://third"})
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+	cfg.Destinations = nil
+	cfg.BucketURI = ""
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestOutboundFilename__Validate(t *testing.T) {
+	var cfg *OutboundFilename
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if name := cfg.ProviderName(); name != FilenameProviderTemplate {
+		t.Errorf("unexpected provider: %s", name)
+	}
+
+	cfg = &OutboundFilename{Provider: "http", Endpoint: "http://filenames.local/name"}
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	cfg.Endpoint = ""
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+	cfg.Provider = "other"
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestSettlement__Validate(t *testing.T) {
+	var cfg *Settlement
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg = &Settlement{AccountNumber: "1234567", AccountType: "Checking"}
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := cfg.HoldBankingDays(); n != 2 {
+		t.Errorf("unexpected default of %d days", n)
+	}
+	cfg.HoldDays = -1
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+	cfg.HoldDays = 0
+
+	cfg.AccountType = "loan"
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+	cfg.AccountNumber = ""
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestInbound__Acknowledgements(t *testing.T) {
+	cfg := Inbound{
+		Acknowledgements: []AckFormat{
+			{Pattern: "*.ack", Format: "batch-sequence"},
+		},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg.Acknowledgements[0].Format = ""
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+
+	cfg.Acknowledgements[0] = AckFormat{Pattern: "[*.ack", Format: "batch-sequence"}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestInbound__Corrections(t *testing.T) {
+	cfg := Inbound{
+		Corrections: Corrections{
+			AutoApply: []string{"C01", "C02"},
+		},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if !cfg.Corrections.Applies("C02") {
+		t.Error("expected C02 to be applied")
+	}
+	if cfg.Corrections.Applies("C05") {
+		t.Error("expected C05 to be reviewed")
+	}
+
+	// name changes are made in the Customers service
+	cfg.Corrections.AutoApply = append(cfg.Corrections.AutoApply, "C04")
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestInbound__Returns(t *testing.T) {
+	cfg := Inbound{}
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if w := cfg.Returns.Fuzzy.MatchWindow(); w != DefaultFuzzyReturnWindow {
+		t.Errorf("unexpected window: %v", w)
+	}
+
+	cfg.Returns.Fuzzy = &FuzzyReturnMatching{}
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if n := cfg.Returns.Fuzzy.MinConfidence(); n != DefaultFuzzyReturnConfidence {
+		t.Errorf("unexpected confidence: %v", n)
+	}
+
+	cfg.Returns.Fuzzy.Confidence = 1.2
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+	cfg.Returns.Fuzzy.Confidence = 0.75
+	cfg.Returns.Fuzzy.Window = -1 * time.Hour
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestInbound__Mailboxes(t *testing.T) {
+	cfg := Inbound{
+		Mailboxes: []Mailbox{
+			{RoutingNumber: "987654320", Directory: "/mnt/mailbox"},
+		},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if path := cfg.Mailboxes[0].Inbound(); path != "inbound/" {
+		t.Errorf("unexpected inbound path: %q", path)
+	}
+
+	cfg.Mailboxes[0].BucketURI = "s3://bucket"
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+
+	cfg.Mailboxes[0] = Mailbox{RoutingNumber: "987654320"}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestODFI__OriginationCaps(t *testing.T) {
+	cfg := &ODFI{
+		OriginationCaps: []OriginationCap{
+			{RoutingNumber: "987654320", Daily: 100000, WarnPercent: 80},
+		},
+	}
+	if err := validateOriginationCaps(cfg.OriginationCaps); err != nil {
+		t.Fatal(err)
+	}
+
+	limit := cfg.OriginationCapFor("987654320")
+	if limit == nil || limit.Daily != 100000 {
+		t.Fatalf("unexpected cap: %#v", limit)
+	}
+	if cfg.OriginationCapFor("231380104") != nil {
+		t.Error("expected no cap")
+	}
+	if limit.NearCap(50000) || !limit.NearCap(90000) || limit.NearCap(100001) {
+		t.Error("unexpected NearCap")
+	}
+
+	cfg.OriginationCaps = append(cfg.OriginationCaps, OriginationCap{RoutingNumber: "987654320", Daily: 5})
+	if err := validateOriginationCaps(cfg.OriginationCaps); err == nil {
+		t.Error("expected error")
+	}
+
+	cfg.OriginationCaps = []OriginationCap{{RoutingNumber: "987654320"}}
+	if err := validateOriginationCaps(cfg.OriginationCaps); err == nil {
+		t.Error("expected error")
+	}
+}
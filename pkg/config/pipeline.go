@@ -8,8 +8,11 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"strings"
 	"text/template"
+	"time"
 
+	"github.com/moov-io/ach"
 	"github.com/moov-io/paygate/pkg/util"
 )
 
@@ -22,7 +25,8 @@ Credits: ${{ .CreditTotal | printf "%.2f" }}
 
 Batches: {{ .BatchCount }}
 Total Entries: {{ .EntryCount }}
-`))
+{{ if .CorrelationIDs }}Correlation IDs: {{ range $i, $id := .CorrelationIDs }}{{ if $i }}, {{ end }}{{ $id }}{{ end }}
+{{ end }}`))
 )
 
 type Pipeline struct {
@@ -32,6 +36,10 @@ type Pipeline struct {
 	AuditTrail    *AuditTrail
 	Stream        *StreamPipeline
 	Notifications *PipelineNotifications
+	CutoffMonitor *CutoffMonitor
+	DeadLetters   *DeadLetters
+	Retention     *Retention
+	Certification *Certification
 }
 
 func (cfg Pipeline) Validate() error {
@@ -53,6 +61,18 @@ func (cfg Pipeline) Validate() error {
 	if err := cfg.Notifications.Validate(); err != nil {
 		return fmt.Errorf("notifications: %v", err)
 	}
+	if err := cfg.CutoffMonitor.Validate(); err != nil {
+		return fmt.Errorf("cutoff-monitor: %v", err)
+	}
+	if err := cfg.DeadLetters.Validate(); err != nil {
+		return fmt.Errorf("dead-letters: %v", err)
+	}
+	if err := cfg.Retention.Validate(); err != nil {
+		return fmt.Errorf("retention: %v", err)
+	}
+	if err := cfg.Certification.Validate(); err != nil {
+		return fmt.Errorf("certification: %v", err)
+	}
 	return nil
 }
 
@@ -86,20 +106,320 @@ func (cfg *Signer) Password() string {
 
 type Output struct {
 	Format string
+
+	// ZIP configures the "zip" format, which bundles each file with a control file
+	// in a password-protected ZIP archive.
+	ZIP *ZipOutput
+
+	// Layouts overrides how files sent to each ImmediateDestination are written, for
+	// receiving banks which require CRLF line endings or unpadded files.
+	Layouts map[string]FileLayout
 }
 
 func (cfg *Output) Validate() error {
+	if cfg == nil {
+		return nil
+	}
+	if strings.EqualFold(cfg.Format, "zip") && cfg.ZIP == nil {
+		return errors.New("zip format: missing zip config")
+	}
+	if err := cfg.ZIP.Validate(); err != nil {
+		return fmt.Errorf("zip: %v", err)
+	}
+	for routingNumber, layout := range cfg.Layouts {
+		if err := layout.Validate(); err != nil {
+			return fmt.Errorf("layout for routing number %s: %v", routingNumber, err)
+		}
+	}
 	return nil
 }
 
+// Layout returns how files sent to routingNumber are written.
+func (cfg *Output) Layout(routingNumber string) FileLayout {
+	if cfg == nil {
+		return FileLayout{}
+	}
+	return cfg.Layouts[strings.TrimSpace(routingNumber)]
+}
+
+// FileLayout holds the formatting of written ACH files. The zero value is the
+// NACHA default of LF line endings and records padded into blocks of 10.
+type FileLayout struct {
+	// BlockingFactor is how many records make up a block, the last block is filled with
+	// records of 9s. Only 10 (default) or 1 for unpadded files are allowed because the
+	// File Header and Control always describe blocks of 10.
+	BlockingFactor int
+
+	// LineEnding terminates each record, either "lf" (default) or "crlf".
+	LineEnding string
+}
+
+func (cfg FileLayout) Validate() error {
+	switch cfg.BlockingFactor {
+	case 0, 1, 10:
+	default:
+		return fmt.Errorf("unsupported BlockingFactor=%d", cfg.BlockingFactor)
+	}
+	switch strings.ToLower(cfg.LineEnding) {
+	case "", "lf", "crlf":
+	default:
+		return fmt.Errorf("unknown LineEnding=%q", cfg.LineEnding)
+	}
+	return nil
+}
+
+// Padded returns true when the last block of a file is filled with records of 9s.
+func (cfg FileLayout) Padded() bool {
+	return cfg.BlockingFactor != 1
+}
+
+// Terminator returns the characters written after each record.
+func (cfg FileLayout) Terminator() string {
+	if strings.EqualFold(cfg.LineEnding, "crlf") {
+		return "\r\n"
+	}
+	return "\n"
+}
+
+type ZipOutput struct {
+	// Password encrypts the archive's contents
+	Password string `json:"-"`
+
+	// ControlLayout is how the control file's totals are written, either "fixed" (default)
+	// for one fixed-width record or "text" for key=value lines.
+	ControlLayout string
+
+	// RoutingNumbers overrides the default bundle settings for files sent to
+	// each ImmediateDestination.
+	RoutingNumbers map[string]ZipBundle
+}
+
+func (cfg *ZipOutput) Validate() error {
+	if cfg == nil {
+		return nil
+	}
+	if err := cfg.defaults().Validate(); err != nil {
+		return err
+	}
+	for routingNumber, bundle := range cfg.RoutingNumbers {
+		if err := bundle.Validate(); err != nil {
+			return fmt.Errorf("routing number %s: %v", routingNumber, err)
+		}
+	}
+	return nil
+}
+
+// Bundle returns the settings used for files sent to routingNumber.
+func (cfg *ZipOutput) Bundle(routingNumber string) ZipBundle {
+	bundle, ok := cfg.RoutingNumbers[strings.TrimSpace(routingNumber)]
+	if !ok {
+		return cfg.defaults()
+	}
+	if bundle.Password == "" {
+		bundle.Password = cfg.Password
+	}
+	if bundle.ControlLayout == "" {
+		bundle.ControlLayout = cfg.ControlLayout
+	}
+	return bundle
+}
+
+func (cfg *ZipOutput) defaults() ZipBundle {
+	return ZipBundle{
+		Password:      cfg.Password,
+		ControlLayout: cfg.ControlLayout,
+	}
+}
+
+// ZipBundle holds the settings of one ZIP archive, see ZipOutput for each field.
+type ZipBundle struct {
+	Password      string `json:"-"`
+	ControlLayout string
+}
+
+func (cfg ZipBundle) Validate() error {
+	switch strings.ToLower(cfg.ControlLayout) {
+	case "", "fixed", "text":
+		return nil
+	}
+	return fmt.Errorf("unknown control layout %q", cfg.ControlLayout)
+}
+
 type Merging struct {
 	Directory string
+
+	// KeyURI is a base64 encoded key used to encrypt transfers and merged files
+	// written under Directory. It's excluded from the /config admin endpoint.
+	KeyURI string `json:"-"`
 }
 
 func (cfg *Merging) Validate() error {
 	return nil
 }
 
+// CutoffMonitor periodically projects how long merging and uploading the pending transfers
+// will take and warns when that would run past the next cutoff.
+type CutoffMonitor struct {
+	// Interval is how often pending transfers are checked
+	Interval time.Duration
+
+	// MergeDuration is the time merging and uploading takes regardless of how many
+	// transfers are pending.
+	MergeDuration time.Duration
+
+	// PerTransfer is the time each pending transfer adds to merging and uploading
+	PerTransfer time.Duration
+
+	// Margin is how much time should be left before the cutoff once files are uploaded
+	Margin time.Duration
+}
+
+func (cfg *CutoffMonitor) CheckInterval() time.Duration {
+	if cfg == nil || cfg.Interval == 0 {
+		return time.Minute
+	}
+	return cfg.Interval
+}
+
+// Projected returns how long merging and uploading count transfers is expected to take.
+func (cfg *CutoffMonitor) Projected(count int) time.Duration {
+	base, per := time.Minute, 50*time.Millisecond
+	if cfg != nil && cfg.MergeDuration > 0 {
+		base = cfg.MergeDuration
+	}
+	if cfg != nil && cfg.PerTransfer > 0 {
+		per = cfg.PerTransfer
+	}
+	return base + time.Duration(count)*per
+}
+
+func (cfg *CutoffMonitor) SafetyMargin() time.Duration {
+	if cfg == nil || cfg.Margin == 0 {
+		return 10 * time.Minute
+	}
+	return cfg.Margin
+}
+
+func (cfg *CutoffMonitor) Validate() error {
+	if cfg == nil {
+		return nil
+	}
+	if cfg.Interval < 0 || cfg.MergeDuration < 0 || cfg.PerTransfer < 0 || cfg.Margin < 0 {
+		return errors.New("durations cannot be negative")
+	}
+	return nil
+}
+
+// DeadLetters holds pipeline messages which fail to be handled after several attempts
+// so they stop being redelivered.
+type DeadLetters struct {
+	BucketURI string
+
+	// MaxAttempts is how many times a message is handled before it's dead-lettered.
+	MaxAttempts int
+}
+
+func (cfg *DeadLetters) Attempts() int {
+	if cfg == nil || cfg.MaxAttempts <= 0 {
+		return 5
+	}
+	return cfg.MaxAttempts
+}
+
+func (cfg *DeadLetters) Validate() error {
+	if cfg == nil {
+		return nil
+	}
+	if cfg.BucketURI == "" {
+		return errors.New("missing bucket_uri")
+	}
+	if cfg.MaxAttempts < 0 {
+		return fmt.Errorf("unexpected maxAttempts=%d", cfg.MaxAttempts)
+	}
+	return nil
+}
+
+// Retention removes directories of merged and uploaded files, and files downloaded from
+// the ODFI, once they're older than MaxAge. Directories are archived first when a
+// BucketURI is set, otherwise they're deleted.
+type Retention struct {
+	MaxAge time.Duration
+
+	// Interval is how often directories are checked
+	Interval time.Duration
+
+	// BucketURI is where compressed archives of each directory are written
+	BucketURI string
+}
+
+func (cfg *Retention) CheckInterval() time.Duration {
+	if cfg == nil || cfg.Interval == 0 {
+		return time.Hour
+	}
+	return cfg.Interval
+}
+
+func (cfg *Retention) Validate() error {
+	if cfg == nil {
+		return nil
+	}
+	if cfg.MaxAge <= 0 {
+		return errors.New("missing max age")
+	}
+	if cfg.Interval < 0 {
+		return errors.New("interval cannot be negative")
+	}
+	return nil
+}
+
+// Certification generates the malformed files an ODFI asks for while certifying an
+// originator and uploads them to a test server rather than the production ODFI.
+type Certification struct {
+	// RoutingNumber is the test ODFI, files are addressed to it.
+	RoutingNumber string
+
+	// Protocol and one of FTP, SFTP, API or Blob are where files are uploaded. They're
+	// set the same way as for the ODFI.
+	Protocol string
+
+	FTP  *FTP
+	SFTP *SFTP
+	API  *API
+	Blob *Blob
+}
+
+// Agent returns odfi with its routing number and upload settings replaced by the test server's.
+func (cfg *Certification) Agent(odfi ODFI) ODFI {
+	odfi.RoutingNumber = cfg.RoutingNumber
+	odfi.Protocol = cfg.Protocol
+	odfi.FTP, odfi.SFTP, odfi.API, odfi.Blob = cfg.FTP, cfg.SFTP, cfg.API, cfg.Blob
+	odfi.Tenants = nil
+	return odfi
+}
+
+func (cfg *Certification) Validate() error {
+	if cfg == nil {
+		return nil
+	}
+	if err := ach.CheckRoutingNumber(cfg.RoutingNumber); err != nil {
+		return err
+	}
+	agent := cfg.Agent(ODFI{})
+	if err := agent.API.Validate(); err != nil {
+		return err
+	}
+	if err := agent.Blob.Validate(); err != nil {
+		return err
+	}
+	if err := agent.validateProtocol(); err != nil {
+		return err
+	}
+	if agent.UploadProtocol() == "" {
+		return errors.New("missing test server")
+	}
+	return nil
+}
+
 type AuditTrail struct {
 	BucketURI string
 	GPG       *GPG
@@ -127,10 +447,8 @@ func (cfg *StreamPipeline) Validate() error {
 	if cfg.InMem != nil && cfg.InMem.URL == "" {
 		return errors.New("inmem: missing stream url")
 	}
-	if k := cfg.Kafka; k != nil {
-		if len(k.Brokers) == 0 || k.Group == "" || k.Topic == "" {
-			return errors.New("kafka: missing brokers, group, or topic")
-		}
+	if err := cfg.Kafka.Validate(); err != nil {
+		return fmt.Errorf("kafka: %v", err)
 	}
 	return nil
 }
@@ -143,6 +461,117 @@ type KafkaPipeline struct {
 	Brokers []string
 	Group   string
 	Topic   string
+
+	// Topics overrides Topic for each type of message, so transfers, cancellations
+	// and cutoff triggers can be published onto separate topics of an existing event bus.
+	Topics *KafkaTopics
+
+	TLS  *KafkaTLS
+	SASL *KafkaSASL
+}
+
+func (cfg *KafkaPipeline) Validate() error {
+	if cfg == nil {
+		return nil
+	}
+	if len(cfg.Brokers) == 0 || cfg.Group == "" {
+		return errors.New("missing brokers or group")
+	}
+	if cfg.TransfersTopic() == "" || cfg.CancellationsTopic() == "" || cfg.CutoffsTopic() == "" {
+		return errors.New("missing topic")
+	}
+	if err := cfg.TLS.Validate(); err != nil {
+		return fmt.Errorf("tls: %v", err)
+	}
+	if err := cfg.SASL.Validate(); err != nil {
+		return fmt.Errorf("sasl: %v", err)
+	}
+	return nil
+}
+
+// TransfersTopic returns the topic Transfers and their ACH files are published onto.
+func (cfg *KafkaPipeline) TransfersTopic() string {
+	if cfg.Topics != nil && cfg.Topics.Transfers != "" {
+		return cfg.Topics.Transfers
+	}
+	return cfg.Topic
+}
+
+// CancellationsTopic returns the topic canceled Transfers are published onto.
+func (cfg *KafkaPipeline) CancellationsTopic() string {
+	if cfg.Topics != nil && cfg.Topics.Cancellations != "" {
+		return cfg.Topics.Cancellations
+	}
+	return cfg.Topic
+}
+
+// CutoffsTopic returns the topic manually triggered cutoffs are published onto.
+func (cfg *KafkaPipeline) CutoffsTopic() string {
+	if cfg.Topics != nil && cfg.Topics.Cutoffs != "" {
+		return cfg.Topics.Cutoffs
+	}
+	return cfg.Topic
+}
+
+// AllTopics returns each distinct topic messages are published onto.
+func (cfg *KafkaPipeline) AllTopics() []string {
+	var out []string
+	seen := make(map[string]bool)
+	for _, topic := range []string{cfg.TransfersTopic(), cfg.CancellationsTopic(), cfg.CutoffsTopic()} {
+		if topic != "" && !seen[topic] {
+			seen[topic] = true
+			out = append(out, topic)
+		}
+	}
+	return out
+}
+
+type KafkaTopics struct {
+	Transfers     string
+	Cancellations string
+	Cutoffs       string
+}
+
+// KafkaTLS enables TLS when connecting to brokers. Without a CAFile the system's
+// certificate pool is used.
+type KafkaTLS struct {
+	CAFile string
+
+	// CertFile and KeyFile are an optional client certificate for mutual TLS
+	CertFile string
+	KeyFile  string
+
+	InsecureSkipVerify bool
+}
+
+func (cfg *KafkaTLS) Validate() error {
+	if cfg == nil {
+		return nil
+	}
+	if (cfg.CertFile == "") != (cfg.KeyFile == "") {
+		return errors.New("both certFile and keyFile are required")
+	}
+	return nil
+}
+
+// KafkaSASL authenticates with brokers using SASL/PLAIN.
+type KafkaSASL struct {
+	Username string
+	Password string `json:"-"`
+}
+
+func (cfg *KafkaSASL) Validate() error {
+	if cfg == nil {
+		return nil
+	}
+	if cfg.Username == "" || cfg.GetPassword() == "" {
+		return errors.New("missing username or password")
+	}
+	return nil
+}
+
+func (cfg *KafkaSASL) GetPassword() string {
+	return util.Or(os.Getenv("PIPELINE_KAFKA_SASL_PASSWORD"), cfg.Password)
 }
 
 type PipelineNotifications struct {
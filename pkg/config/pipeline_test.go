@@ -6,6 +6,7 @@ package config
 
 import (
 	"testing"
+	"time"
 )
 
 func TestPipeline(t *testing.T) {
@@ -52,6 +53,52 @@ func TestStreamPipeline(t *testing.T) {
 	}
 }
 
+func TestKafkaPipeline(t *testing.T) {
+	cfg := &KafkaPipeline{
+		Brokers: []string{"localhost:9092"},
+		Group:   "paygate",
+		Topic:   "paygate",
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if topics := cfg.AllTopics(); len(topics) != 1 || topics[0] != "paygate" {
+		t.Errorf("unexpected topics: %v", topics)
+	}
+
+	cfg.Topics = &KafkaTopics{Cancellations: "canceled-transfers"}
+	if v := cfg.TransfersTopic(); v != "paygate" {
+		t.Errorf("TransfersTopic=%s", v)
+	}
+	if v := cfg.CancellationsTopic(); v != "canceled-transfers" {
+		t.Errorf("CancellationsTopic=%s", v)
+	}
+	if topics := cfg.AllTopics(); len(topics) != 2 {
+		t.Errorf("unexpected topics: %v", topics)
+	}
+
+	cfg.Topic = "" // cutoffs and transfers have no topic
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+	cfg.Topic = "paygate"
+
+	cfg.TLS = &KafkaTLS{CertFile: "client.pem"}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+	cfg.TLS = nil
+
+	cfg.SASL = &KafkaSASL{Username: "paygate"}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+	cfg.SASL.Password = "secret"
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+}
+
 func TestPipelineNotifications(t *testing.T) {
 	cfg := &PipelineNotifications{
 		Email: &Email{
@@ -74,3 +121,145 @@ func TestPipelineNotifications(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestOutputZip(t *testing.T) {
+	cfg := &Output{Format: "zip"}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+
+	cfg.ZIP = &ZipOutput{
+		Password: "default",
+		RoutingNumbers: map[string]ZipBundle{
+			"987654320": {ControlLayout: "text"},
+		},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+
+	bundle := cfg.ZIP.Bundle(" 987654320")
+	if bundle.Password != "default" || bundle.ControlLayout != "text" {
+		t.Errorf("unexpected bundle: %#v", bundle)
+	}
+	if bundle := cfg.ZIP.Bundle("123456780"); bundle.ControlLayout != "" {
+		t.Errorf("unexpected bundle: %#v", bundle)
+	}
+
+	cfg.ZIP.RoutingNumbers["987654320"] = ZipBundle{ControlLayout: "csv"}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestOutputLayouts(t *testing.T) {
+	var cfg *Output
+	if layout := cfg.Layout("987654320"); !layout.Padded() || layout.Terminator() != "\n" {
+		t.Errorf("unexpected layout: %#v", layout)
+	}
+
+	cfg = &Output{
+		Layouts: map[string]FileLayout{
+			"987654320": {BlockingFactor: 1, LineEnding: "crlf"},
+		},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+	layout := cfg.Layout(" 987654320")
+	if layout.Padded() || layout.Terminator() != "\r\n" {
+		t.Errorf("unexpected layout: %#v", layout)
+	}
+
+	cfg.Layouts["987654320"] = FileLayout{BlockingFactor: 20}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+	cfg.Layouts["987654320"] = FileLayout{LineEnding: "cr"}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestDeadLetters(t *testing.T) {
+	var cfg *DeadLetters
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+	if n := cfg.Attempts(); n != 5 {
+		t.Errorf("unexpected attempts: %d", n)
+	}
+
+	cfg = &DeadLetters{BucketURI: "mem://", MaxAttempts: 3}
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+	if n := cfg.Attempts(); n != 3 {
+		t.Errorf("unexpected attempts: %d", n)
+	}
+
+	cfg.BucketURI = ""
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestRetention(t *testing.T) {
+	var cfg *Retention
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+	if d := cfg.CheckInterval(); d != time.Hour {
+		t.Errorf("unexpected interval: %v", d)
+	}
+
+	cfg = &Retention{MaxAge: 30 * 24 * time.Hour, Interval: time.Minute}
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+	if d := cfg.CheckInterval(); d != time.Minute {
+		t.Errorf("unexpected interval: %v", d)
+	}
+
+	cfg.MaxAge = 0
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestCertification(t *testing.T) {
+	var cfg *Certification
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+
+	cfg = &Certification{
+		RoutingNumber: "121042882",
+		Blob:          &Blob{BucketURI: "mem://"},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+
+	odfi := cfg.Agent(ODFI{
+		RoutingNumber: "987654320",
+		OutboundPath:  "outbound/",
+		SFTP:          &SFTP{Hostname: "ach.bank.com:22"},
+	})
+	if odfi.RoutingNumber != "121042882" || odfi.SFTP != nil || odfi.OutboundPath != "outbound/" {
+		t.Errorf("unexpected ODFI: %#v", odfi)
+	}
+	if protocol := odfi.UploadProtocol(); protocol != ProtocolBlob {
+		t.Errorf("unexpected protocol=%q", protocol)
+	}
+
+	cfg.Blob = nil
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+	cfg.Blob = &Blob{BucketURI: "mem://"}
+	cfg.RoutingNumber = ""
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+}
@@ -0,0 +1,28 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"fmt"
+	"os"
+)
+
+// Seed enables loading fixture data (organization configs and API tokens) for demo
+// and test environments. It should not be enabled in production.
+type Seed struct {
+	// File is a YAML fixture file loaded on startup. Leave empty to only
+	// enable the admin endpoint.
+	File string
+}
+
+func (cfg *Seed) Validate() error {
+	if cfg == nil || cfg.File == "" {
+		return nil
+	}
+	if _, err := os.Stat(cfg.File); err != nil {
+		return fmt.Errorf("file: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,26 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSeed(t *testing.T) {
+	var cfg *Seed
+	require.NoError(t, cfg.Validate())
+
+	cfg = &Seed{}
+	require.NoError(t, cfg.Validate())
+
+	cfg.File = filepath.Join("..", "..", "testdata", "seed.yaml")
+	require.NoError(t, cfg.Validate())
+
+	cfg.File = filepath.Join("..", "..", "testdata", "missing.yaml")
+	require.Error(t, cfg.Validate())
+}
@@ -0,0 +1,45 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"fmt"
+)
+
+// Subsystems PayGate can start without when they're listed in Startup.Optional
+const (
+	SubsystemTracing     = "tracing"
+	SubsystemWebhooks    = "webhooks"
+	SubsystemAttachments = "attachments"
+	SubsystemSeed        = "seed"
+	SubsystemAnonymize   = "anonymize"
+)
+
+// Startup controls which subsystems PayGate can run without. An optional subsystem which
+// fails to start is reported as degraded on the admin server instead of stopping PayGate.
+type Startup struct {
+	Optional []string
+}
+
+func (cfg Startup) Validate() error {
+	for i := range cfg.Optional {
+		switch cfg.Optional[i] {
+		case SubsystemTracing, SubsystemWebhooks, SubsystemAttachments, SubsystemSeed, SubsystemAnonymize:
+		default:
+			return fmt.Errorf("unknown optional subsystem %q", cfg.Optional[i])
+		}
+	}
+	return nil
+}
+
+// IsOptional returns true if PayGate can start without the named subsystem.
+func (cfg Startup) IsOptional(name string) bool {
+	for i := range cfg.Optional {
+		if cfg.Optional[i] == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,25 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStartup(t *testing.T) {
+	cfg := Startup{}
+	require.NoError(t, cfg.Validate())
+	require.False(t, cfg.IsOptional(SubsystemWebhooks))
+
+	cfg.Optional = []string{SubsystemWebhooks, SubsystemAttachments}
+	require.NoError(t, cfg.Validate())
+	require.True(t, cfg.IsOptional(SubsystemWebhooks))
+	require.False(t, cfg.IsOptional(SubsystemSeed))
+
+	cfg.Optional = []string{"database"}
+	require.Error(t, cfg.Validate())
+}
@@ -0,0 +1,109 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Tenant overrides the ODFI's file values and settlement account for a set of organizations.
+// Files for each tenant are merged separately, but are still uploaded to the same ODFI.
+type Tenant struct {
+	Name          string
+	Organizations []string
+
+	// Gateway replaces the ODFI's FileHeader values when set.
+	Gateway *Gateway
+
+	// CompanyIdentification is used in BatchHeaders for organizations without their own.
+	CompanyIdentification string
+
+	// Settlement replaces the ODFI's settlement account for pass-through and two-leg flows.
+	Settlement *Settlement
+}
+
+func (cfg Tenant) Validate() error {
+	if cfg.Name == "" {
+		return errors.New("missing name")
+	}
+	if len(cfg.Organizations) == 0 {
+		return fmt.Errorf("%s: missing organizations", cfg.Name)
+	}
+	if err := cfg.Settlement.Validate(); err != nil {
+		return fmt.Errorf("%s: %v", cfg.Name, err)
+	}
+	return nil
+}
+
+func validateTenants(tenants []Tenant) error {
+	names := make(map[string]bool)
+	organizations := make(map[string]string)
+	for i := range tenants {
+		if err := tenants[i].Validate(); err != nil {
+			return fmt.Errorf("tenants[%d]: %v", i, err)
+		}
+		if names[tenants[i].Name] {
+			return fmt.Errorf("tenants[%d]: duplicate name %s", i, tenants[i].Name)
+		}
+		names[tenants[i].Name] = true
+
+		for _, org := range tenants[i].Organizations {
+			if other, exists := organizations[org]; exists {
+				return fmt.Errorf("tenants[%d]: organization %s is already part of %s", i, org, other)
+			}
+			organizations[org] = tenants[i].Name
+		}
+	}
+	return nil
+}
+
+// TenantFor returns the Tenant an organization is part of, or nil.
+func (cfg ODFI) TenantFor(orgID string) *Tenant {
+	for i := range cfg.Tenants {
+		for _, org := range cfg.Tenants[i].Organizations {
+			if org == orgID {
+				return &cfg.Tenants[i]
+			}
+		}
+	}
+	return nil
+}
+
+// ForTenant returns a copy of the ODFI config with the tenant's values applied.
+func (cfg ODFI) ForTenant(tenant *Tenant) ODFI {
+	if tenant == nil {
+		return cfg
+	}
+	if tenant.Gateway != nil {
+		cfg.Gateway = *tenant.Gateway
+	}
+	if tenant.CompanyIdentification != "" {
+		cfg.FileConfig.BatchHeader.CompanyIdentification = tenant.CompanyIdentification
+	}
+	if tenant.Settlement != nil {
+		cfg.Settlement = tenant.Settlement
+	}
+	cfg.Tenants = nil
+	return cfg
+}
+
+// ForOrganization returns the ODFI config with the values of the organization's Tenant applied.
+func (cfg ODFI) ForOrganization(orgID string) ODFI {
+	return cfg.ForTenant(cfg.TenantFor(orgID))
+}
+
+// HasSettlement returns true if the ODFI or any Tenant has a settlement account.
+func (cfg ODFI) HasSettlement() bool {
+	if cfg.Settlement != nil {
+		return true
+	}
+	for i := range cfg.Tenants {
+		if cfg.Tenants[i].Settlement != nil {
+			return true
+		}
+	}
+	return false
+}
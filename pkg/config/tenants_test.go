@@ -0,0 +1,72 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTenants(t *testing.T) {
+	cfg := ODFI{
+		Gateway: Gateway{
+			Origin: "987654320",
+		},
+		FileConfig: FileConfig{
+			BatchHeader: BatchHeader{
+				CompanyIdentification: "MOOV",
+			},
+		},
+		Tenants: []Tenant{
+			{
+				Name:          "acme",
+				Organizations: []string{"acme", "acme-labs"},
+				Gateway: &Gateway{
+					Origin:     "231380104",
+					OriginName: "Acme Payments",
+				},
+				CompanyIdentification: "ACME",
+				Settlement: &Settlement{
+					AccountNumber: "123",
+					AccountType:   "checking",
+				},
+			},
+		},
+	}
+	require.NoError(t, validateTenants(cfg.Tenants))
+	require.True(t, cfg.HasSettlement())
+
+	require.Nil(t, cfg.TenantFor("moov"))
+	other := cfg.ForOrganization("moov")
+	require.Equal(t, "987654320", other.Gateway.Origin)
+	require.Equal(t, "MOOV", other.FileConfig.BatchHeader.CompanyIdentification)
+	require.Nil(t, other.Settlement)
+
+	acme := cfg.ForOrganization("acme-labs")
+	require.Equal(t, "231380104", acme.Gateway.Origin)
+	require.Equal(t, "Acme Payments", acme.Gateway.OriginName)
+	require.Equal(t, "ACME", acme.FileConfig.BatchHeader.CompanyIdentification)
+	require.Equal(t, "123", acme.Settlement.AccountNumber)
+	require.Empty(t, acme.Tenants)
+
+	// the original config isn't modified
+	require.Equal(t, "MOOV", cfg.FileConfig.BatchHeader.CompanyIdentification)
+}
+
+func TestTenants__Validate(t *testing.T) {
+	require.Error(t, validateTenants([]Tenant{{Organizations: []string{"acme"}}}))
+	require.Error(t, validateTenants([]Tenant{{Name: "acme"}}))
+	require.Error(t, validateTenants([]Tenant{{Name: "acme", Organizations: []string{"acme"}, Settlement: &Settlement{}}}))
+
+	require.Error(t, validateTenants([]Tenant{
+		{Name: "acme", Organizations: []string{"acme"}},
+		{Name: "acme", Organizations: []string{"globex"}},
+	}))
+	require.Error(t, validateTenants([]Tenant{
+		{Name: "acme", Organizations: []string{"acme"}},
+		{Name: "globex", Organizations: []string{"acme"}},
+	}))
+}
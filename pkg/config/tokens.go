@@ -0,0 +1,35 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// Tokens configures API tokens which are scoped to a single source Customer and Account.
+// Token holders can only create Transfers to Receivers registered on the token.
+type Tokens struct {
+	// MaxAmount is the exclusive upper bound, in the smallest currency unit, for
+	// Transfers created with a token.
+	MaxAmount int64
+
+	// Expiration is how long tokens are valid for after creation.
+	// Leaving this empty means tokens never expire.
+	Expiration time.Duration
+}
+
+func (cfg *Tokens) Validate() error {
+	if cfg == nil {
+		return nil
+	}
+	if cfg.MaxAmount <= 0 {
+		return fmt.Errorf("unexpected MaxAmount=%d", cfg.MaxAmount)
+	}
+	if cfg.Expiration < 0 {
+		return fmt.Errorf("negative Expiration=%v", cfg.Expiration)
+	}
+	return nil
+}
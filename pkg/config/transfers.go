@@ -5,30 +5,445 @@
 package config
 
 import (
+	"errors"
 	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/moov-io/ach"
 
 	"github.com/moov-io/paygate/pkg/client"
 )
 
 type Transfers struct {
-	Limits Limits
+	Limits         Limits
+	EffectiveDates EffectiveDates
+
+	// SameDay enables automatically sending Transfers same-day for organizations
+	// which prefer it. Leaving this nil disables automatic selection.
+	SameDay *SameDay
+
+	// KillSwitch blocks debit (pull) Transfers from being created or merged. Admins can
+	// also block debits at runtime without changing the config.
+	KillSwitch KillSwitch
+
+	// DebitAuthorizations controls whether debit (pull) Transfers need an authorization
+	// on file from the Customer whose Account is debited.
+	DebitAuthorizations DebitAuthorizations
+
+	Export Export
+
+	Scheduled ScheduledTransfers
+
+	// StatusLinks enables signed, expiring links which show a single Transfer's status
+	// without credentials. Leaving this nil disables them.
+	StatusLinks *StatusLinks
+
+	// WaitForMerge lets POST /transfers block until the Transfer is merged and uploaded.
+	// Leaving this nil disables waiting.
+	WaitForMerge *WaitForMerge
+
+	// Balances checks the source Account has enough funds before a Transfer is created.
+	// Leaving this nil disables balance checks.
+	Balances *Balances
 }
 
 func (cfg Transfers) Validate() error {
 	if err := cfg.Limits.Validate(); err != nil {
 		return fmt.Errorf("limits: %v", err)
 	}
+	if err := cfg.EffectiveDates.Validate(); err != nil {
+		return fmt.Errorf("effective dates: %v", err)
+	}
+	if err := cfg.SameDay.Validate(); err != nil {
+		return fmt.Errorf("same day: %v", err)
+	}
+	if err := cfg.KillSwitch.Validate(); err != nil {
+		return fmt.Errorf("kill switch: %v", err)
+	}
+	if err := cfg.Export.Validate(); err != nil {
+		return fmt.Errorf("export: %v", err)
+	}
+	if err := cfg.Scheduled.Validate(); err != nil {
+		return fmt.Errorf("scheduled: %v", err)
+	}
+	if err := cfg.StatusLinks.Validate(); err != nil {
+		return fmt.Errorf("status links: %v", err)
+	}
+	if err := cfg.WaitForMerge.Validate(); err != nil {
+		return fmt.Errorf("wait for merge: %v", err)
+	}
+	if err := cfg.Balances.Validate(); err != nil {
+		return fmt.Errorf("balances: %v", err)
+	}
+	return nil
+}
+
+// Balances reads the available balance of a Transfer's source Account from an HTTP service,
+// such as the core banking system holding the Account.
+type Balances struct {
+	// Endpoint is called with GET and the organization, customerID and accountID query
+	// parameters. It responds with the available balance in cents, e.g. {"available": 12500}
+	Endpoint string
+
+	Timeout time.Duration
+
+	// Strict rejects Transfers over the available balance, less holds, along with those whose
+	// balance couldn't be read. Otherwise shortfalls and errors are only logged.
+	Strict bool
+
+	HTTPClient *HTTPClient
+}
+
+func (cfg *Balances) Validate() error {
+	if cfg == nil {
+		return nil
+	}
+	if u, err := url.Parse(cfg.Endpoint); err != nil || u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("invalid endpoint %q", cfg.Endpoint)
+	}
+	if cfg.Timeout < 0 {
+		return fmt.Errorf("negative Timeout=%v", cfg.Timeout)
+	}
+	if err := cfg.HTTPClient.Validate(); err != nil {
+		return fmt.Errorf("http client: %v", err)
+	}
+	return nil
+}
+
+func (cfg *Balances) RequestTimeout() time.Duration {
+	if cfg == nil || cfg.Timeout == 0 {
+		return 5 * time.Second
+	}
+	return cfg.Timeout
+}
+
+// WaitForMerge bounds how long a request creating a Transfer can wait for it to be merged
+// into a file and uploaded. Transfers are only merged at cutoffs, so this suits
+// low-volume integrations close to a cutoff.
+type WaitForMerge struct {
+	// MaxTimeout caps the wait requested by each request
+	MaxTimeout time.Duration
+
+	// PollInterval is how often the upload history is checked
+	PollInterval time.Duration
+}
+
+func (cfg *WaitForMerge) Timeout(requested time.Duration) time.Duration {
+	if max := cfg.MaxWait(); requested <= 0 || requested > max {
+		return max
+	}
+	return requested
+}
+
+func (cfg *WaitForMerge) MaxWait() time.Duration {
+	if cfg == nil || cfg.MaxTimeout == 0 {
+		return time.Minute
+	}
+	return cfg.MaxTimeout
+}
+
+func (cfg *WaitForMerge) Interval() time.Duration {
+	if cfg == nil || cfg.PollInterval == 0 {
+		return time.Second
+	}
+	return cfg.PollInterval
+}
+
+func (cfg *WaitForMerge) Validate() error {
+	if cfg == nil {
+		return nil
+	}
+	if cfg.MaxTimeout < 0 || cfg.PollInterval < 0 {
+		return errors.New("durations cannot be negative")
+	}
+	return nil
+}
+
+type StatusLinks struct {
+	// Secret is the key used to sign link tokens. It's excluded from
+	// the /config admin endpoint.
+	Secret string `json:"-"`
+
+	// BaseURL is where receivers can reach PayGate's /track routes,
+	// for example https://pay.example.com
+	BaseURL string
+
+	// Expiration is how long links are valid for after creation.
+	Expiration time.Duration
+}
+
+func (cfg *StatusLinks) Validate() error {
+	if cfg == nil {
+		return nil
+	}
+	if len(cfg.Secret) < 32 {
+		return errors.New("secret must be at least 32 characters")
+	}
+	if cfg.Expiration < 0 {
+		return fmt.Errorf("negative Expiration=%v", cfg.Expiration)
+	}
+	return nil
+}
+
+func (cfg *StatusLinks) LinkExpiration() time.Duration {
+	if cfg == nil || cfg.Expiration == 0 {
+		return 30 * 24 * time.Hour
+	}
+	return cfg.Expiration
+}
+
+type DebitAuthorizations struct {
+	// Required rejects debit Transfers unless the source Customer and Account have an
+	// active authorization covering the amount.
+	Required bool
+}
+
+// Export controls CSV downloads of Transfers from GET /transfers.csv
+type Export struct {
+	// MaxRows is the most rows written by one request, callers page through
+	// larger exports with skip. Defaults to 250,000.
+	MaxRows int64
+}
+
+func (cfg Export) Validate() error {
+	if cfg.MaxRows < 0 {
+		return fmt.Errorf("negative MaxRows=%d", cfg.MaxRows)
+	}
+	return nil
+}
+
+func (cfg Export) RowLimit() int64 {
+	if cfg.MaxRows == 0 {
+		return 250000
+	}
+	return cfg.MaxRows
+}
+
+// ScheduledTransfers controls how often schedules are checked for Transfers to create.
+type ScheduledTransfers struct {
+	// Interval is how often due schedules are checked. Defaults to one minute.
+	Interval time.Duration
+}
+
+func (cfg ScheduledTransfers) Validate() error {
+	if cfg.Interval < 0 {
+		return fmt.Errorf("negative Interval=%v", cfg.Interval)
+	}
+	return nil
+}
+
+func (cfg ScheduledTransfers) CheckInterval() time.Duration {
+	if cfg.Interval == 0 {
+		return time.Minute
+	}
+	return cfg.Interval
+}
+
+type EffectiveDates struct {
+	// MaxForwardDays is how many banking days into the future a caller supplied
+	// EffectiveDate is allowed to be.
+	MaxForwardDays int
+}
+
+func (cfg EffectiveDates) Validate() error {
+	if cfg.MaxForwardDays < 0 {
+		return fmt.Errorf("negative MaxForwardDays=%d", cfg.MaxForwardDays)
+	}
+	return nil
+}
+
+func (cfg EffectiveDates) ForwardDays() int {
+	if cfg.MaxForwardDays == 0 {
+		return 5
+	}
+	return cfg.MaxForwardDays
+}
+
+// sameDayMaxAmount is NACHA's per-entry limit of $1,000,000 for Same Day ACH
+const sameDayMaxAmount int64 = 100000000
+
+type SameDay struct {
+	// Cutoff is the latest time of day (HH:MM in the ODFI cutoff timezone) a Transfer
+	// can be created and still be sent same-day.
+	Cutoff string
+
+	// RoutingNumbers override Cutoff for Transfers sent to specific RDFIs.
+	RoutingNumbers []SameDayRoutingNumber
+
+	// MaxAmount is the largest Transfer amount (in cents) sent same-day. NACHA's limit
+	// of $1,000,000 is used when empty.
+	MaxAmount int64
+}
+
+// SameDayRoutingNumber is the same-day cutoff for Transfers sent to one RDFI.
+type SameDayRoutingNumber struct {
+	RoutingNumber string
+	Cutoff        string
+}
+
+func (cfg *SameDay) Validate() error {
+	if cfg == nil {
+		return nil
+	}
+	if _, err := cfg.CutoffOn(time.Now()); err != nil {
+		return err
+	}
+	seen := make(map[string]bool)
+	for _, rn := range cfg.RoutingNumbers {
+		if err := ach.CheckRoutingNumber(rn.RoutingNumber); err != nil {
+			return fmt.Errorf("routing number %q: %v", rn.RoutingNumber, err)
+		}
+		if seen[rn.RoutingNumber] {
+			return fmt.Errorf("duplicate routing number %s", rn.RoutingNumber)
+		}
+		seen[rn.RoutingNumber] = true
+		if _, err := parseCutoff(rn.Cutoff, time.Now()); err != nil {
+			return fmt.Errorf("routing number %s: %v", rn.RoutingNumber, err)
+		}
+	}
+	if cfg.MaxAmount < 0 {
+		return fmt.Errorf("negative MaxAmount=%d", cfg.MaxAmount)
+	}
+	return nil
+}
+
+// CutoffOn returns the same-day cutoff on the day of when, in when's location.
+func (cfg *SameDay) CutoffOn(when time.Time) (time.Time, error) {
+	return parseCutoff(cfg.Cutoff, when)
+}
+
+// CutoffFor returns the same-day cutoff for Transfers sent to routingNumber on the day
+// of when, in when's location. Routing numbers without an override use Cutoff.
+func (cfg *SameDay) CutoffFor(routingNumber string, when time.Time) (time.Time, error) {
+	for i := range cfg.RoutingNumbers {
+		if cfg.RoutingNumbers[i].RoutingNumber == routingNumber {
+			return parseCutoff(cfg.RoutingNumbers[i].Cutoff, when)
+		}
+	}
+	return cfg.CutoffOn(when)
+}
+
+func parseCutoff(value string, when time.Time) (time.Time, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid Cutoff=%q: %v", value, err)
+	}
+	return time.Date(when.Year(), when.Month(), when.Day(), t.Hour(), t.Minute(), 0, 0, when.Location()), nil
+}
+
+func (cfg *SameDay) Limit() int64 {
+	if cfg.MaxAmount == 0 {
+		return sameDayMaxAmount
+	}
+	return cfg.MaxAmount
+}
+
+type KillSwitch struct {
+	// Debits blocks every debit Transfer when set to true.
+	Debits bool
+
+	// Organizations whose debit Transfers are blocked.
+	Organizations []string
+}
+
+func (cfg KillSwitch) Validate() error {
+	for i := range cfg.Organizations {
+		if cfg.Organizations[i] == "" {
+			return errors.New("empty organization")
+		}
+	}
 	return nil
 }
 
 type Limits struct {
 	Fixed *FixedLimits
+
+	// Exposure limits the rolling total of debits and credits, which carry different
+	// risks, that can be originated for each organization, customer or user.
+	Exposure *ExposureLimits
+
+	// Changes replace Fixed on the days they're effective. The first matching change is used.
+	Changes []LimitsChange
 }
 
 func (cfg Limits) Validate() error {
 	if err := cfg.Fixed.Validate(); err != nil {
 		return fmt.Errorf("fixed limits: %v", err)
 	}
+	if err := cfg.Exposure.Validate(); err != nil {
+		return fmt.Errorf("exposure limits: %v", err)
+	}
+	for i := range cfg.Changes {
+		if err := cfg.Changes[i].Validate(); err != nil {
+			return fmt.Errorf("limits change %d: %v", i, err)
+		}
+	}
+	return nil
+}
+
+// On returns the limits in effect on day, without any Changes.
+func (cfg Limits) On(day time.Time) Limits {
+	out := Limits{
+		Fixed:    cfg.Fixed,
+		Exposure: cfg.Exposure,
+	}
+	for i := range cfg.Changes {
+		if effectiveOn(cfg.Changes[i].ValidFrom, cfg.Changes[i].ValidTo, day) {
+			out.Fixed = cfg.Changes[i].Fixed
+			break
+		}
+	}
+	return out
+}
+
+type ExposureLimits struct {
+	// Window is how far back Transfers count towards exposure. Defaults to 24 hours.
+	Window time.Duration
+
+	// Debits limits the total pulled from remote accounts, which the ODFI might
+	// not collect if the debits are returned.
+	Debits ExposureLimit
+
+	// Credits limits the total pushed to remote accounts, which leaves the ODFI's account.
+	Credits ExposureLimit
+}
+
+func (cfg *ExposureLimits) Validate() error {
+	if cfg == nil {
+		return nil
+	}
+	if cfg.Window < 0 {
+		return fmt.Errorf("negative Window=%v", cfg.Window)
+	}
+	if err := cfg.Debits.Validate(); err != nil {
+		return fmt.Errorf("debits: %v", err)
+	}
+	if err := cfg.Credits.Validate(); err != nil {
+		return fmt.Errorf("credits: %v", err)
+	}
+	return nil
+}
+
+func (cfg *ExposureLimits) RollingWindow() time.Duration {
+	if cfg.Window == 0 {
+		return 24 * time.Hour
+	}
+	return cfg.Window
+}
+
+// ExposureLimit holds the most (in cents) each scope can originate within the window.
+// A zero value leaves that scope unlimited.
+type ExposureLimit struct {
+	Organization int64
+	Customer     int64
+	User         int64
+}
+
+func (cfg ExposureLimit) Validate() error {
+	if cfg.Organization < 0 || cfg.Customer < 0 || cfg.User < 0 {
+		return fmt.Errorf("unexpected limits: Organization=%d Customer=%d User=%d", cfg.Organization, cfg.Customer, cfg.User)
+	}
 	return nil
 }
 
@@ -40,6 +455,10 @@ type FixedLimits struct {
 	// HardLimit is a numerical value. No Transfer amount is allowed to exceed this value
 	// when specified.
 	HardLimit int64
+
+	// WarnPercent is a percentage (1-100) of the soft or hard limit. Accepted Transfers
+	// using more than this share of a limit include a warning in their create response.
+	WarnPercent int
 }
 
 func (cfg *FixedLimits) Validate() error {
@@ -49,6 +468,9 @@ func (cfg *FixedLimits) Validate() error {
 	if cfg.SoftLimit <= 0 || cfg.HardLimit < 0 {
 		return fmt.Errorf("unexpected limits: SoftLimit=%d HardLimit=%d", cfg.SoftLimit, cfg.HardLimit)
 	}
+	if cfg.WarnPercent < 0 || cfg.WarnPercent > 100 {
+		return fmt.Errorf("unexpected WarnPercent=%d", cfg.WarnPercent)
+	}
 	return nil
 }
 
@@ -63,3 +485,11 @@ func (cfg *FixedLimits) OverHardLimit(amt client.Amount) bool {
 func (cfg *FixedLimits) overLimit(limit int64, amt client.Amount) bool {
 	return int64(amt.Value) > limit
 }
+
+// NearLimit returns true when amt uses more than WarnPercent of limit without exceeding it.
+func (cfg *FixedLimits) NearLimit(limit int64, amt client.Amount) bool {
+	if cfg.WarnPercent <= 0 || limit <= 0 || cfg.overLimit(limit, amt) {
+		return false
+	}
+	return int64(amt.Value)*100 > limit*int64(cfg.WarnPercent)
+}
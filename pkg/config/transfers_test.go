@@ -6,6 +6,7 @@ package config
 
 import (
 	"testing"
+	"time"
 
 	"github.com/moov-io/paygate/pkg/client"
 )
@@ -49,6 +50,32 @@ func TestFixedLimits__Hard(t *testing.T) {
 	}
 }
 
+func TestFixedLimits__NearLimit(t *testing.T) {
+	cfg := &FixedLimits{
+		SoftLimit:   1000,
+		WarnPercent: 80,
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if cfg.NearLimit(cfg.SoftLimit, client.Amount{Value: 800}) {
+		t.Error("expected not near limit")
+	}
+	if !cfg.NearLimit(cfg.SoftLimit, client.Amount{Value: 801}) {
+		t.Error("expected near limit")
+	}
+	if cfg.NearLimit(cfg.SoftLimit, client.Amount{Value: 1001}) {
+		t.Error("over the limit isn't near it")
+	}
+
+	// invalid
+	cfg.WarnPercent = 101
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+}
+
 func TestFixedLimits__Validate(t *testing.T) {
 	cfg := &Transfers{
 		Limits: Limits{
@@ -62,3 +89,202 @@ func TestFixedLimits__Validate(t *testing.T) {
 	}
 
 }
+
+func TestExposureLimits(t *testing.T) {
+	var cfg *ExposureLimits
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+
+	cfg = &ExposureLimits{
+		Debits: ExposureLimit{Organization: 1000000, Customer: 50000},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+	if d := cfg.RollingWindow(); d != 24*time.Hour {
+		t.Errorf("unexpected default window of %v", d)
+	}
+
+	cfg.Credits.User = -1
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+	cfg.Credits.User = 0
+	cfg.Window = -time.Hour
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestEffectiveDates(t *testing.T) {
+	cfg := EffectiveDates{}
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+	if n := cfg.ForwardDays(); n != 5 {
+		t.Errorf("unexpected default of %d days", n)
+	}
+
+	cfg.MaxForwardDays = -1
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestExport(t *testing.T) {
+	cfg := Export{}
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+	if n := cfg.RowLimit(); n != 250000 {
+		t.Errorf("unexpected default of %d rows", n)
+	}
+
+	cfg.MaxRows = -1
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestScheduledTransfers(t *testing.T) {
+	cfg := ScheduledTransfers{}
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+	if d := cfg.CheckInterval(); d != time.Minute {
+		t.Errorf("unexpected default interval of %v", d)
+	}
+
+	cfg.Interval = -1 * time.Second
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestWaitForMerge(t *testing.T) {
+	var cfg *WaitForMerge
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+	if d := cfg.Interval(); d != time.Second {
+		t.Errorf("unexpected default interval of %v", d)
+	}
+
+	cfg = &WaitForMerge{MaxTimeout: 30 * time.Second}
+	if d := cfg.Timeout(10 * time.Second); d != 10*time.Second {
+		t.Errorf("unexpected timeout of %v", d)
+	}
+	if d := cfg.Timeout(time.Hour); d != 30*time.Second {
+		t.Errorf("unexpected timeout of %v", d)
+	}
+	if d := cfg.Timeout(0); d != 30*time.Second {
+		t.Errorf("unexpected timeout of %v", d)
+	}
+
+	cfg.PollInterval = -1 * time.Second
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestSameDay(t *testing.T) {
+	var cfg *SameDay
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+
+	cfg = &SameDay{Cutoff: "14:45"}
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+	if n := cfg.Limit(); n != 100000000 {
+		t.Errorf("unexpected default limit of %d", n)
+	}
+
+	when := time.Date(2020, time.June, 1, 9, 30, 0, 0, time.UTC)
+	cutoff, err := cfg.CutoffOn(when)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := time.Date(2020, time.June, 1, 14, 45, 0, 0, time.UTC); !cutoff.Equal(expected) {
+		t.Errorf("unexpected cutoff: %v", cutoff)
+	}
+
+	cfg.MaxAmount = -1
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+
+	cfg = &SameDay{Cutoff: "2pm"}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestSameDay__RoutingNumbers(t *testing.T) {
+	cfg := &SameDay{
+		Cutoff: "14:45",
+		RoutingNumbers: []SameDayRoutingNumber{
+			{RoutingNumber: "987654320", Cutoff: "13:00"},
+		},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	when := time.Date(2020, time.June, 1, 9, 30, 0, 0, time.UTC)
+	cutoff, err := cfg.CutoffFor("987654320", when)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := time.Date(2020, time.June, 1, 13, 0, 0, 0, time.UTC); !cutoff.Equal(expected) {
+		t.Errorf("unexpected cutoff: %v", cutoff)
+	}
+	cutoff, err = cfg.CutoffFor("123456780", when)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := time.Date(2020, time.June, 1, 14, 45, 0, 0, time.UTC); !cutoff.Equal(expected) {
+		t.Errorf("unexpected cutoff: %v", cutoff)
+	}
+
+	cfg.RoutingNumbers = append(cfg.RoutingNumbers, SameDayRoutingNumber{RoutingNumber: "987654320", Cutoff: "12:00"})
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+	cfg.RoutingNumbers = []SameDayRoutingNumber{{RoutingNumber: "12345", Cutoff: "13:00"}}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+	cfg.RoutingNumbers = []SameDayRoutingNumber{{RoutingNumber: "987654320", Cutoff: "1pm"}}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestBalances(t *testing.T) {
+	var cfg *Balances
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+	if d := cfg.RequestTimeout(); d != 5*time.Second {
+		t.Errorf("unexpected default timeout of %v", d)
+	}
+
+	cfg = &Balances{Endpoint: "http://core.bank.internal/balances"}
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+
+	cfg.Endpoint = "core.bank.internal"
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+
+	cfg.Endpoint = "http://core.bank.internal/balances"
+	cfg.Timeout = -1 * time.Second
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+}
@@ -6,16 +6,26 @@ package config
 
 import (
 	"errors"
+	"fmt"
+	"time"
 )
 
 type Validation struct {
 	MicroDeposits *MicroDeposits
+	Prenotes      *Prenotes
+	Emails        *EmailVerification
 }
 
 func (cfg Validation) Validate() error {
 	if err := cfg.MicroDeposits.Validate(); err != nil {
 		return err
 	}
+	if err := cfg.Prenotes.Validate(); err != nil {
+		return err
+	}
+	if err := cfg.Emails.Validate(); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -28,6 +38,19 @@ type MicroDeposits struct {
 	Description string
 
 	SameDay bool
+
+	// Expiration is how long after initiation micro-deposits are considered
+	// valid for verification. A zero value means they never expire.
+	Expiration time.Duration
+
+	// Links enables signed, expiring links which Receivers can use to
+	// confirm their micro-deposit amounts with PayGate directly.
+	Links *VerificationLinks
+
+	// KeyURI is the base64 encoded key used to encrypt amounts stored in the
+	// database. When empty the key for decrypting account numbers is used.
+	// It's excluded from the /config admin endpoint.
+	KeyURI string `json:"-"`
 }
 
 func (cfg *MicroDeposits) Validate() error {
@@ -35,11 +58,147 @@ func (cfg *MicroDeposits) Validate() error {
 		return nil
 	}
 	if err := cfg.Source.Validate(); err != nil {
-		return err
+		return fmt.Errorf("micro-deposits: %v", err)
+	}
+	if err := cfg.Links.Validate(); err != nil {
+		return fmt.Errorf("micro-deposits: links: %v", err)
+	}
+	return nil
+}
+
+type VerificationLinks struct {
+	// Secret is the key used to sign link tokens. It's excluded from
+	// the /config admin endpoint.
+	Secret string `json:"-"`
+
+	// BaseURL is where Receivers can reach PayGate's /verify routes,
+	// for example https://pay.example.com
+	BaseURL string
+
+	// Expiration is how long links are valid for after creation. Links never
+	// outlive the micro-deposits they confirm.
+	Expiration time.Duration
+
+	// MaxGuesses is how many incorrect confirmations are accepted before the
+	// micro-deposits are failed.
+	MaxGuesses int
+}
+
+func (cfg *VerificationLinks) Validate() error {
+	if cfg == nil {
+		return nil
+	}
+	if len(cfg.Secret) < 32 {
+		return errors.New("secret must be at least 32 characters")
+	}
+	if cfg.Expiration < 0 {
+		return fmt.Errorf("negative Expiration=%v", cfg.Expiration)
+	}
+	if cfg.MaxGuesses < 0 {
+		return fmt.Errorf("negative MaxGuesses=%d", cfg.MaxGuesses)
+	}
+	return nil
+}
+
+func (cfg *VerificationLinks) LinkExpiration() time.Duration {
+	if cfg == nil || cfg.Expiration == 0 {
+		return 72 * time.Hour
+	}
+	return cfg.Expiration
+}
+
+func (cfg *VerificationLinks) Guesses() int {
+	if cfg == nil || cfg.MaxGuesses == 0 {
+		return 3
+	}
+	return cfg.MaxGuesses
+}
+
+type Prenotes struct {
+	Source Source
+
+	// Description is the default for what appears in the Online Banking
+	// system for end-users of PayGate. Per NACHA limits this is restricted
+	// to 10 characters.
+	Description string
+
+	// WaitDays is how many banking days after a prenote is uploaded without a
+	// return or correction before the account is verified. NACHA requires at
+	// least three.
+	WaitDays int
+}
+
+func (cfg *Prenotes) Validate() error {
+	if cfg == nil {
+		return nil
+	}
+	if err := cfg.Source.Validate(); err != nil {
+		return fmt.Errorf("prenotes: %v", err)
+	}
+	if cfg.WaitDays != 0 && cfg.WaitDays < 3 {
+		return fmt.Errorf("prenotes: WaitDays=%d is less than three banking days", cfg.WaitDays)
 	}
 	return nil
 }
 
+func (cfg *Prenotes) WaitBankingDays() int {
+	if cfg == nil || cfg.WaitDays == 0 {
+		return 3
+	}
+	return cfg.WaitDays
+}
+
+type EmailVerification struct {
+	// Email sends verification links to each Customer's email address. Any provider
+	// accepting SMTP, like Amazon SES, can be used. To is unused.
+	Email *Email
+
+	// Secret is the key used to sign verification tokens. It's excluded from
+	// the /config admin endpoint.
+	Secret string `json:"-"`
+
+	// BaseURL is the page Customers land on from the email, for example
+	// https://app.example.com/verify-email which should POST the token query
+	// parameter to PayGate's /customers/{customerID}/verify-email route.
+	BaseURL string
+
+	// Expiration is how long links are valid for after they're sent.
+	Expiration time.Duration
+
+	// RequiredForDebits rejects debit (pull) Transfers from Customers whose
+	// current email address hasn't been verified.
+	RequiredForDebits bool
+}
+
+func (cfg *EmailVerification) Validate() error {
+	if cfg == nil {
+		return nil
+	}
+	if cfg.Email == nil || cfg.Email.ConnectionURI == "" {
+		return errors.New("emails: missing Email ConnectionURI")
+	}
+	if cfg.Email.From == "" {
+		return errors.New("emails: missing Email From")
+	}
+	if len(cfg.Secret) < 32 {
+		return errors.New("emails: secret must be at least 32 characters")
+	}
+	if cfg.BaseURL == "" {
+		return errors.New("emails: missing BaseURL")
+	}
+	if cfg.Expiration < 0 {
+		return fmt.Errorf("emails: negative Expiration=%v", cfg.Expiration)
+	}
+	return nil
+}
+
+func (cfg *EmailVerification) LinkExpiration() time.Duration {
+	if cfg == nil || cfg.Expiration == 0 {
+		return 72 * time.Hour
+	}
+	return cfg.Expiration
+}
+
 type Source struct {
 	CustomerID   string
 	AccountID    string
@@ -48,13 +207,13 @@ type Source struct {
 
 func (cfg Source) Validate() error {
 	if cfg.CustomerID == "" {
-		return errors.New("micro-deposits: missing Source CustomerID")
+		return errors.New("missing Source CustomerID")
 	}
 	if cfg.AccountID == "" {
-		return errors.New("micro-deposits: missing Source AccountID")
+		return errors.New("missing Source AccountID")
 	}
 	if cfg.Organization == "" {
-		return errors.New("micro-deposits: missing Source Organization")
+		return errors.New("missing Source Organization")
 	}
 	return nil
 }
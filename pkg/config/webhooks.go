@@ -0,0 +1,83 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+type Webhooks struct {
+	// Endpoint is a URL which receives a POST of each event as JSON.
+	Endpoint string
+
+	// Secret signs each request with the same HMAC headers as admin requests so
+	// receivers can verify events came from PayGate. It's excluded from the /config
+	// admin endpoint.
+	Secret string `json:"-"`
+
+	Timeout time.Duration
+
+	// MaxAttempts is how many times an event is sent before giving up. Network errors
+	// along with 429 and 5xx responses are retried, waiting Backoff (doubled after each
+	// attempt) in between.
+	MaxAttempts int
+	Backoff     time.Duration
+
+	// Email delivers events to organizations which prefer an email channel. The
+	// recipient comes from each organization's notification preferences, so To is unused.
+	Email *Email
+
+	HTTPClient *HTTPClient
+}
+
+func (cfg *Webhooks) Validate() error {
+	if cfg == nil {
+		return nil
+	}
+	if cfg.Endpoint == "" {
+		return errors.New("missing endpoint")
+	}
+	if cfg.Secret != "" && len(cfg.Secret) < 32 {
+		return errors.New("secret must be at least 32 characters")
+	}
+	if cfg.MaxAttempts < 0 {
+		return fmt.Errorf("negative MaxAttempts=%d", cfg.MaxAttempts)
+	}
+	if cfg.Backoff < 0 {
+		return fmt.Errorf("negative Backoff=%v", cfg.Backoff)
+	}
+	if e := cfg.Email; e != nil {
+		if e.From == "" || e.ConnectionURI == "" {
+			return errors.New("email: missing configs")
+		}
+	}
+	if err := cfg.HTTPClient.Validate(); err != nil {
+		return fmt.Errorf("http client: %v", err)
+	}
+	return nil
+}
+
+func (cfg *Webhooks) RequestTimeout() time.Duration {
+	if cfg == nil || cfg.Timeout == 0*time.Second {
+		return 5 * time.Second
+	}
+	return cfg.Timeout
+}
+
+func (cfg *Webhooks) Attempts() int {
+	if cfg == nil || cfg.MaxAttempts == 0 {
+		return 3
+	}
+	return cfg.MaxAttempts
+}
+
+func (cfg *Webhooks) RetryBackoff() time.Duration {
+	if cfg == nil || cfg.Backoff == 0 {
+		return time.Second
+	}
+	return cfg.Backoff
+}
@@ -26,6 +26,7 @@ type Client interface {
 	Lookup(organization string, customerID string, requestID string) (*moovcustomers.Customer, error)
 	FindAccount(organization, customerID, accountID string) (*moovcustomers.Account, error)
 	DecryptAccount(organization, customerID, accountID string) (*moovcustomers.TransitAccountNumber, error)
+	UpdateAccountStatus(customerID, accountID string, status moovcustomers.AccountStatus) (*moovcustomers.Account, error)
 
 	LatestOFACSearch(organization, customerID, requestID string) (*OfacSearch, error)
 	RefreshOFACSearch(organization, customerID, requestID string) (*OfacSearch, error)
@@ -119,6 +120,26 @@ func (c *moovClient) DecryptAccount(organization, customerID, accountID string)
 	return &transit, nil
 }
 
+func (c *moovClient) UpdateAccountStatus(customerID, accountID string, status moovcustomers.AccountStatus) (*moovcustomers.Account, error) {
+	ctx, cancelFn := context.WithTimeout(context.TODO(), 10*time.Second)
+	defer cancelFn()
+
+	req := moovcustomers.UpdateAccountStatus{
+		Status: status,
+	}
+	account, resp, err := c.underlying.CustomersApi.UpdateAccountStatus(ctx, customerID, accountID, req)
+	if resp != nil && resp.Body != nil {
+		resp.Body.Close()
+	}
+	if resp == nil || err != nil {
+		return nil, fmt.Errorf("update account status: failed: %v", err)
+	}
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return nil, fmt.Errorf("update account status: status=%s", resp.Status)
+	}
+	return &account, nil
+}
+
 func (c *moovClient) LatestOFACSearch(organization, customerID, requestID string) (*OfacSearch, error) {
 	ctx, cancelFn := context.WithTimeout(context.TODO(), 10*time.Second)
 	defer cancelFn()
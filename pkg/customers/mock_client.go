@@ -50,6 +50,17 @@ func (c *MockClient) DecryptAccount(organization, customerID, accountID string)
 	return c.Transit, nil
 }
 
+func (c *MockClient) UpdateAccountStatus(customerID, accountID string, status moovcustomers.AccountStatus) (*moovcustomers.Account, error) {
+	if c.Err != nil {
+		return nil, c.Err
+	}
+	if acct, exists := c.Accounts[accountID]; exists {
+		acct.Status = status
+		return acct, nil
+	}
+	return nil, nil
+}
+
 func (c *MockClient) LatestOFACSearch(organization, customerID, requestID string) (*OfacSearch, error) {
 	if c.Err != nil {
 		return nil, c.Err
@@ -0,0 +1,112 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package ofac
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/moov-io/base/admin"
+	moovhttp "github.com/moov-io/base/http"
+	"github.com/moov-io/base/log"
+
+	paygateadmin "github.com/moov-io/paygate/pkg/admin"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/x/adminauth"
+	"github.com/moov-io/paygate/x/route"
+)
+
+// RegisterAdminRoutes adds endpoints to list and review Customers suspended after matching OFAC.
+func RegisterAdminRoutes(cfg *config.Config, svc *admin.Server, repo Repository) {
+	svc.AddHandler("/ofac/matches", listMatches(repo))
+	svc.AddHandler("/ofac/matches/{organization}/{customerID}", adminauth.Protect(cfg.Admin.Signing, updateMatch(cfg, repo)))
+}
+
+func problem(w http.ResponseWriter, err error) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	moovhttp.Problem(w, err)
+}
+
+func validStatus(status string) bool {
+	return status == StatusSuspended || status == StatusCleared
+}
+
+func listMatches(repo Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			problem(w, fmt.Errorf("invalid method %s", r.Method))
+			return
+		}
+		status := r.URL.Query().Get("status")
+		if status != "" && !validStatus(status) {
+			problem(w, fmt.Errorf("unknown status %q", status))
+			return
+		}
+		matches, err := repo.getMatches(status)
+		if err != nil {
+			problem(w, err)
+			return
+		}
+		if matches == nil {
+			matches = make([]*paygateadmin.OfacMatch, 0)
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(matches)
+	}
+}
+
+func updateMatch(cfg *config.Config, repo Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(cfg, w, r)
+
+		if r.Method != http.MethodPut {
+			responder.Problem(fmt.Errorf("unsupported HTTP verb %s", r.Method))
+			return
+		}
+		var req paygateadmin.UpdateOfacMatch
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			responder.Problem(err)
+			return
+		}
+		if !validStatus(req.Status) {
+			responder.Problem(fmt.Errorf("unknown status %q", req.Status))
+			return
+		}
+
+		organization, customerID := route.ReadPathID("organization", r), route.ReadPathID("customerID", r)
+		if organization == "" || customerID == "" {
+			responder.Problem(errors.New("missing organization or customerID"))
+			return
+		}
+		if err := repo.reviewMatch(organization, customerID, req.Status, req.ReviewedBy, time.Now()); err != nil {
+			responder.Problem(err)
+			return
+		}
+		match, err := repo.getMatch(organization, customerID)
+		if err != nil {
+			responder.Problem(err)
+			return
+		}
+		if n, err := repo.suspendedCount(); err == nil {
+			suspendedCustomers.Set(float64(n))
+		}
+
+		cfg.Logger.With(log.Fields{
+			"requestID":    responder.XRequestID,
+			"organization": organization,
+			"customerID":   customerID,
+			"status":       req.Status,
+		}).Log("Reviewed OFAC match")
+
+		responder.Respond(func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(match)
+		})
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package ofac
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/moov-io/paygate/pkg/admin"
+	"github.com/moov-io/paygate/pkg/config"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdmin__matches(t *testing.T) {
+	repo := &MockRepository{
+		Matches: []*admin.OfacMatch{
+			{
+				Organization: "moov",
+				CustomerID:   "jane",
+				EntityID:     "1231",
+				SdnName:      "Jane Doe",
+				Match:        0.99,
+				Status:       StatusSuspended,
+				Matched:      time.Now(),
+				Screened:     time.Now(),
+			},
+		},
+	}
+
+	router := mux.NewRouter()
+	router.Handle("/ofac/matches", listMatches(repo))
+	router.Handle("/ofac/matches/{organization}/{customerID}", updateMatch(config.Empty(), repo))
+
+	req := httptest.NewRequest("GET", "/ofac/matches?status=suspended", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	w.Flush()
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var matches []admin.OfacMatch
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&matches))
+	require.Len(t, matches, 1)
+	require.Equal(t, "jane", matches[0].CustomerID)
+
+	// clear
+	req = httptest.NewRequest("PUT", "/ofac/matches/moov/jane", strings.NewReader(`{"status": "cleared", "reviewedBy": "compliance"}`))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	w.Flush()
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var match admin.OfacMatch
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&match))
+	require.Equal(t, StatusCleared, match.Status)
+	require.Equal(t, "compliance", match.ReviewedBy)
+
+	req = httptest.NewRequest("GET", "/ofac/matches?status=suspended", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	w.Flush()
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "[]", strings.TrimSpace(w.Body.String()))
+
+	// unknown status and Customers
+	req = httptest.NewRequest("PUT", "/ofac/matches/moov/jane", strings.NewReader(`{"status": "ignored"}`))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+
+	req = httptest.NewRequest("PUT", "/ofac/matches/moov/john", strings.NewReader(`{"status": "cleared"}`))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,32 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package ofac
+
+import (
+	"github.com/go-kit/kit/metrics/prometheus"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	screenings = prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+		Name: "ofac_customer_screenings",
+		Help: "Counter of Customers searched against OFAC again by result",
+	}, []string{"result"})
+
+	suspendedCustomers = prometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+		Name: "ofac_suspended_customers",
+		Help: "Gauge of Customers suspended after matching an OFAC entity",
+	}, nil)
+)
+
+const (
+	resultClear = "clear"
+	resultMatch = "match"
+	resultError = "error"
+)
+
+func recordScreening(result string) {
+	screenings.With("result", result).Add(1)
+}
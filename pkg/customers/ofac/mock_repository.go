@@ -0,0 +1,99 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package ofac
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/moov-io/paygate/pkg/admin"
+	"github.com/moov-io/paygate/pkg/customers"
+)
+
+type MockRepository struct {
+	Customers []customer
+	Matches   []*admin.OfacMatch
+
+	Err error
+}
+
+func (r *MockRepository) listCustomers() ([]customer, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	return r.Customers, nil
+}
+
+func (r *MockRepository) recordMatch(organization, customerID string, search *customers.OfacSearch, when time.Time) (bool, error) {
+	if r.Err != nil {
+		return false, r.Err
+	}
+	existing, _ := r.getMatch(organization, customerID)
+	if existing != nil && existing.EntityID == search.EntityId {
+		existing.Match = search.Match
+		existing.Screened = when
+		return false, nil
+	}
+	match := &admin.OfacMatch{
+		Organization: organization,
+		CustomerID:   customerID,
+		EntityID:     search.EntityId,
+		SdnName:      search.SdnName,
+		Match:        search.Match,
+		Status:       StatusSuspended,
+		Matched:      when,
+		Screened:     when,
+	}
+	if existing != nil {
+		*existing = *match
+	} else {
+		r.Matches = append(r.Matches, match)
+	}
+	return true, nil
+}
+
+func (r *MockRepository) getMatches(status string) ([]*admin.OfacMatch, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	var out []*admin.OfacMatch
+	for i := range r.Matches {
+		if status == "" || r.Matches[i].Status == status {
+			out = append(out, r.Matches[i])
+		}
+	}
+	return out, nil
+}
+
+func (r *MockRepository) getMatch(organization, customerID string) (*admin.OfacMatch, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	for i := range r.Matches {
+		if r.Matches[i].Organization == organization && r.Matches[i].CustomerID == customerID {
+			return r.Matches[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *MockRepository) reviewMatch(organization, customerID string, status string, reviewedBy string, when time.Time) error {
+	match, err := r.getMatch(organization, customerID)
+	if err != nil {
+		return err
+	}
+	if match == nil {
+		return fmt.Errorf("no OFAC match for customerID=%s", customerID)
+	}
+	match.Status = status
+	match.Reviewed = &when
+	match.ReviewedBy = reviewedBy
+	return nil
+}
+
+func (r *MockRepository) suspendedCount() (int, error) {
+	matches, err := r.getMatches(StatusSuspended)
+	return len(matches), err
+}
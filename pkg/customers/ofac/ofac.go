@@ -0,0 +1,122 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+// Package ofac periodically searches every Customer PayGate has transferred with against
+// OFAC again. Customers are only searched by the Customers service when they're created,
+// so entities added to the sanctions lists afterwards would otherwise go unnoticed.
+//
+// Customers which match above the configured threshold are suspended from new Transfers
+// until an admin reviews and clears the match.
+package ofac
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/moov-io/base"
+	"github.com/moov-io/base/log"
+
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/customers"
+)
+
+const (
+	StatusSuspended = "suspended"
+	StatusCleared   = "cleared"
+)
+
+// Screener searches stored Customers against OFAC on an interval.
+type Screener struct {
+	cfg    config.OFAC
+	logger log.Logger
+
+	repo            Repository
+	customersClient customers.Client
+}
+
+// NewScreener returns a Screener or nil if re-screening is disabled.
+func NewScreener(cfg *config.Config, repo Repository, customersClient customers.Client) *Screener {
+	if cfg.Customers.OFAC == nil {
+		return nil
+	}
+	return &Screener{
+		cfg:             *cfg.Customers.OFAC,
+		logger:          cfg.Logger.Set("service", "ofac"),
+		repo:            repo,
+		customersClient: customersClient,
+	}
+}
+
+func (s *Screener) Start(ctx context.Context) {
+	if s == nil {
+		return
+	}
+
+	ticker := time.NewTicker(s.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case now := <-ticker.C:
+			if err := s.tick(now); err != nil {
+				s.logger.LogErrorf("ERROR re-screening customers: %v", err)
+			}
+
+		case <-ctx.Done():
+			s.logger.Log("ofac screener shutdown")
+			return
+		}
+	}
+}
+
+func (s *Screener) tick(now time.Time) error {
+	custs, err := s.repo.listCustomers()
+	if err != nil {
+		return err
+	}
+
+	var el base.ErrorList
+	for i := range custs {
+		if err := s.screen(custs[i], now); err != nil {
+			recordScreening(resultError)
+			el.Add(fmt.Errorf("customerID=%s: %v", custs[i].customerID, err))
+		}
+	}
+	if n, err := s.repo.suspendedCount(); err == nil {
+		suspendedCustomers.Set(float64(n))
+	} else {
+		el.Add(fmt.Errorf("counting suspended customers: %v", err))
+	}
+	if el.Empty() {
+		return nil
+	}
+	return el
+}
+
+func (s *Screener) screen(cust customer, now time.Time) error {
+	search, err := s.customersClient.RefreshOFACSearch(cust.organization, cust.customerID, base.ID())
+	if err != nil {
+		return err
+	}
+	if search == nil || search.Match < s.cfg.Threshold() {
+		recordScreening(resultClear)
+		return nil
+	}
+
+	suspended, err := s.repo.recordMatch(cust.organization, cust.customerID, search, now)
+	if err != nil {
+		return err
+	}
+	recordScreening(resultMatch)
+	if suspended {
+		s.logger.With(log.Fields{
+			"organization": cust.organization,
+			"customerID":   cust.customerID,
+			"entityID":     search.EntityId,
+			"match":        fmt.Sprintf("%.2f", search.Match),
+		}).Log("suspended customer after OFAC match")
+	}
+	return nil
+}
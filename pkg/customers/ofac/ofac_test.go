@@ -0,0 +1,50 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package ofac
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/customers"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestScreener__disabled(t *testing.T) {
+	s := NewScreener(config.Empty(), &MockRepository{}, &customers.MockClient{})
+	require.Nil(t, s)
+}
+
+func TestScreener__tick(t *testing.T) {
+	cfg := config.Empty()
+	cfg.Customers.OFAC = &config.OFAC{Interval: time.Hour}
+
+	repo := &MockRepository{
+		Customers: []customer{
+			{organization: "moov", customerID: "jane"},
+		},
+	}
+	client := &customers.MockClient{
+		Result: &customers.OfacSearch{EntityId: "1231", SdnName: "Jane Doe", Match: 0.5},
+	}
+	s := NewScreener(cfg, repo, client)
+	require.NotNil(t, s)
+
+	// below the threshold
+	require.NoError(t, s.tick(time.Now()))
+	require.Empty(t, repo.Matches)
+
+	client.Result.Match = 0.99
+	require.NoError(t, s.tick(time.Now()))
+	require.Len(t, repo.Matches, 1)
+	require.Equal(t, "jane", repo.Matches[0].CustomerID)
+	require.Equal(t, StatusSuspended, repo.Matches[0].Status)
+
+	client.Err = errors.New("bad error")
+	require.Error(t, s.tick(time.Now()))
+}
@@ -0,0 +1,170 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package ofac
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/moov-io/paygate/pkg/admin"
+	"github.com/moov-io/paygate/pkg/customers"
+)
+
+type Repository interface {
+	// listCustomers returns every Customer used as the source or destination of a Transfer
+	listCustomers() ([]customer, error)
+
+	// recordMatch saves an OFAC match for the Customer and returns true if they were suspended by it.
+	// Customers cleared by an admin stay cleared until they match a different entity.
+	recordMatch(organization, customerID string, search *customers.OfacSearch, when time.Time) (bool, error)
+
+	getMatches(status string) ([]*admin.OfacMatch, error)
+	getMatch(organization, customerID string) (*admin.OfacMatch, error)
+	reviewMatch(organization, customerID string, status string, reviewedBy string, when time.Time) error
+	suspendedCount() (int, error)
+}
+
+type customer struct {
+	organization string
+	customerID   string
+}
+
+func NewRepo(db *sql.DB) *sqlRepo {
+	return &sqlRepo{db: db}
+}
+
+type sqlRepo struct {
+	db *sql.DB
+}
+
+func (r *sqlRepo) Close() error {
+	return r.db.Close()
+}
+
+func (r *sqlRepo) listCustomers() ([]customer, error) {
+	query := `select organization, source_customer_id from transfers where deleted_at is null
+union select organization, destination_customer_id from transfers where deleted_at is null;`
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []customer
+	for rows.Next() {
+		var cust customer
+		if err := rows.Scan(&cust.organization, &cust.customerID); err != nil {
+			return nil, err
+		}
+		out = append(out, cust)
+	}
+	return out, rows.Err()
+}
+
+func (r *sqlRepo) recordMatch(organization, customerID string, search *customers.OfacSearch, when time.Time) (bool, error) {
+	existing, err := r.getMatch(organization, customerID)
+	if err != nil {
+		return false, err
+	}
+	if existing != nil && existing.EntityID == search.EntityId {
+		query := `update ofac_matches set sdn_name = ?, match_score = ?, screened_at = ? where organization = ? and customer_id = ?;`
+		_, err := r.db.Exec(query, search.SdnName, search.Match, when, organization, customerID)
+		return false, err
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	if _, err := tx.Exec(`delete from ofac_matches where organization = ? and customer_id = ?;`, organization, customerID); err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	query := `insert into ofac_matches (organization, customer_id, entity_id, sdn_name, match_score, status, matched_at, screened_at) values (?, ?, ?, ?, ?, ?, ?, ?);`
+	if _, err := tx.Exec(query, organization, customerID, search.EntityId, search.SdnName, search.Match, StatusSuspended, when, when); err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+	return existing == nil || existing.Status != StatusSuspended, nil
+}
+
+const matchColumns = `organization, customer_id, entity_id, sdn_name, match_score, status, matched_at, screened_at, reviewed_at, reviewed_by`
+
+func (r *sqlRepo) getMatches(status string) ([]*admin.OfacMatch, error) {
+	query := `select ` + matchColumns + ` from ofac_matches`
+	var args []interface{}
+	if status != "" {
+		query += ` where status = ?`
+		args = append(args, status)
+	}
+	rows, err := r.db.Query(query+` order by matched_at desc;`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []*admin.OfacMatch
+	for rows.Next() {
+		match, err := scanMatch(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, match)
+	}
+	return out, rows.Err()
+}
+
+func (r *sqlRepo) getMatch(organization, customerID string) (*admin.OfacMatch, error) {
+	query := `select ` + matchColumns + ` from ofac_matches where organization = ? and customer_id = ? limit 1;`
+	match, err := scanMatch(r.db.QueryRow(query, organization, customerID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return match, nil
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanMatch(row scanner) (*admin.OfacMatch, error) {
+	var match admin.OfacMatch
+	var reviewedAt *time.Time
+	var reviewedBy *string
+	err := row.Scan(&match.Organization, &match.CustomerID, &match.EntityID, &match.SdnName, &match.Match, &match.Status, &match.Matched, &match.Screened, &reviewedAt, &reviewedBy)
+	if err != nil {
+		return nil, err
+	}
+	match.Reviewed = reviewedAt
+	if reviewedBy != nil {
+		match.ReviewedBy = *reviewedBy
+	}
+	return &match, nil
+}
+
+func (r *sqlRepo) reviewMatch(organization, customerID string, status string, reviewedBy string, when time.Time) error {
+	query := `update ofac_matches set status = ?, reviewed_at = ?, reviewed_by = ? where organization = ? and customer_id = ?;`
+	res, err := r.db.Exec(query, status, when, reviewedBy, organization, customerID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("no OFAC match for customerID=%s", customerID)
+	}
+	return nil
+}
+
+func (r *sqlRepo) suspendedCount() (int, error) {
+	var n int
+	err := r.db.QueryRow(`select count(*) from ofac_matches where status = ?;`, StatusSuspended).Scan(&n)
+	return n, err
+}
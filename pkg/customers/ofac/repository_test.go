@@ -0,0 +1,117 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package ofac
+
+import (
+	"testing"
+	"time"
+
+	"github.com/moov-io/base"
+
+	"github.com/moov-io/paygate/pkg/customers"
+	"github.com/moov-io/paygate/pkg/database"
+
+	"github.com/stretchr/testify/require"
+)
+
+func writeTransfer(t *testing.T, repo *sqlRepo, organization, source, destination string) {
+	t.Helper()
+
+	query := `insert into transfers (transfer_id, organization, amount_currency, amount_value, source_customer_id, source_account_id, destination_customer_id, destination_account_id, description, status, same_day, created_at, last_updated_at)
+values (?, ?, 'USD', 100, ?, 'source', ?, 'destination', 'test', 'pending', false, ?, ?);`
+	_, err := repo.db.Exec(query, base.ID(), organization, source, destination, time.Now(), time.Now())
+	require.NoError(t, err)
+}
+
+func TestRepository__listCustomers(t *testing.T) {
+	t.Parallel()
+
+	check := func(t *testing.T, repo *sqlRepo) {
+		writeTransfer(t, repo, "moov", "jane", "john")
+		writeTransfer(t, repo, "moov", "john", "jane")
+		writeTransfer(t, repo, "other", "jane", "acme")
+
+		custs, err := repo.listCustomers()
+		require.NoError(t, err)
+		require.ElementsMatch(t, []customer{
+			{organization: "moov", customerID: "jane"},
+			{organization: "moov", customerID: "john"},
+			{organization: "other", customerID: "jane"},
+			{organization: "other", customerID: "acme"},
+		}, custs)
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+}
+
+func TestRepository__matches(t *testing.T) {
+	t.Parallel()
+
+	check := func(t *testing.T, repo *sqlRepo) {
+		customerID := base.ID()
+		now := time.Now().Truncate(time.Second)
+		search := &customers.OfacSearch{EntityId: "1231", SdnName: "Jane Doe", Match: 0.99}
+
+		suspended, err := repo.recordMatch("moov", customerID, search, now)
+		require.NoError(t, err)
+		require.True(t, suspended)
+
+		n, err := repo.suspendedCount()
+		require.NoError(t, err)
+		require.Equal(t, 1, n)
+
+		require.NoError(t, repo.reviewMatch("moov", customerID, StatusCleared, "compliance", now))
+		match, err := repo.getMatch("moov", customerID)
+		require.NoError(t, err)
+		require.Equal(t, StatusCleared, match.Status)
+		require.Equal(t, "compliance", match.ReviewedBy)
+		require.NotNil(t, match.Reviewed)
+
+		// cleared Customers stay cleared for the same entity
+		suspended, err = repo.recordMatch("moov", customerID, search, now.Add(time.Hour))
+		require.NoError(t, err)
+		require.False(t, suspended)
+
+		matches, err := repo.getMatches(StatusSuspended)
+		require.NoError(t, err)
+		require.Empty(t, matches)
+
+		// but are suspended again by another one
+		suspended, err = repo.recordMatch("moov", customerID, &customers.OfacSearch{EntityId: "4562", SdnName: "Jane Doe", Match: 1.0}, now.Add(2*time.Hour))
+		require.NoError(t, err)
+		require.True(t, suspended)
+
+		matches, err = repo.getMatches("")
+		require.NoError(t, err)
+		require.Len(t, matches, 1)
+		require.Equal(t, "4562", matches[0].EntityID)
+		require.Equal(t, StatusSuspended, matches[0].Status)
+		require.Nil(t, matches[0].Reviewed)
+
+		// other organizations have no match
+		match, err = repo.getMatch("other", customerID)
+		require.NoError(t, err)
+		require.Nil(t, match)
+		require.Error(t, repo.reviewMatch("other", customerID, StatusCleared, "", now))
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+}
+
+func setupSQLiteDB(t *testing.T) *sqlRepo {
+	db := database.CreateTestSqliteDB(t)
+	t.Cleanup(func() { db.Close() })
+
+	return NewRepo(db.DB)
+}
+
+func setupMySQLeDB(t *testing.T) *sqlRepo {
+	db := database.CreateTestMySQLDB(t)
+	t.Cleanup(func() { db.Close() })
+
+	return NewRepo(db.DB)
+}
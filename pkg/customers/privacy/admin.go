@@ -0,0 +1,159 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package privacy
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/moov-io/base/admin"
+	moovhttp "github.com/moov-io/base/http"
+	"github.com/moov-io/base/log"
+
+	paygateadmin "github.com/moov-io/paygate/pkg/admin"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/x/adminauth"
+	"github.com/moov-io/paygate/x/route"
+)
+
+// RegisterAdminRoutes adds endpoints to export a Customer's data and to erase it.
+func RegisterAdminRoutes(cfg *config.Config, svc *admin.Server, repo Repository, eraser *Eraser) {
+	svc.AddHandler("/customers/{organization}/{customerID}/export", adminauth.Protect(cfg.Admin.Signing, exportCustomer(cfg, repo)))
+	svc.AddHandler("/customers/{organization}/{customerID}/erasures", adminauth.Protect(cfg.Admin.Signing, eraseCustomer(cfg, eraser)))
+	svc.AddHandler("/erasures", listJobs(repo))
+	svc.AddHandler("/erasures/{jobID}", getJob(repo))
+}
+
+func problem(w http.ResponseWriter, err error) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	moovhttp.Problem(w, err)
+}
+
+func exportCustomer(cfg *config.Config, repo Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(cfg, w, r)
+
+		if r.Method != http.MethodGet {
+			responder.Problem(fmt.Errorf("unsupported HTTP verb %s", r.Method))
+			return
+		}
+		organization, customerID := route.ReadPathID("organization", r), route.ReadPathID("customerID", r)
+		if organization == "" || customerID == "" {
+			responder.Problem(errors.New("missing organization or customerID"))
+			return
+		}
+
+		data, err := repo.exportCustomer(organization, customerID)
+		if err != nil {
+			responder.Problem(err)
+			return
+		}
+		var buf bytes.Buffer
+		if err := writeArchive(&buf, data); err != nil {
+			responder.Problem(err)
+			return
+		}
+
+		cfg.Logger.With(log.Fields{
+			"requestID":    responder.XRequestID,
+			"organization": organization,
+			"customerID":   customerID,
+		}).Log("Exported customer data")
+
+		filename := fmt.Sprintf("%s-%s.zip", organization, customerID)
+		w.Header().Set("Content-Type", "application/zip")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+		w.WriteHeader(http.StatusOK)
+		w.Write(buf.Bytes())
+	}
+}
+
+func eraseCustomer(cfg *config.Config, eraser *Eraser) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(cfg, w, r)
+
+		if r.Method != http.MethodPost {
+			responder.Problem(fmt.Errorf("unsupported HTTP verb %s", r.Method))
+			return
+		}
+		var req paygateadmin.CreateErasure
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			responder.Problem(err)
+			return
+		}
+		if req.RequestedBy == "" {
+			responder.Problem(errors.New("missing requestedBy"))
+			return
+		}
+		organization, customerID := route.ReadPathID("organization", r), route.ReadPathID("customerID", r)
+		if organization == "" || customerID == "" {
+			responder.Problem(errors.New("missing organization or customerID"))
+			return
+		}
+
+		job, err := eraser.Start(organization, customerID, req.RequestedBy)
+		if err != nil {
+			responder.Problem(err)
+			return
+		}
+
+		cfg.Logger.With(log.Fields{
+			"requestID":    responder.XRequestID,
+			"organization": organization,
+			"customerID":   customerID,
+			"jobID":        job.JobID,
+		}).Log("Started customer erasure")
+
+		responder.Respond(func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusAccepted)
+			json.NewEncoder(w).Encode(job)
+		})
+	}
+}
+
+func listJobs(repo Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			problem(w, fmt.Errorf("invalid method %s", r.Method))
+			return
+		}
+		jobs, err := repo.getJobs()
+		if err != nil {
+			problem(w, err)
+			return
+		}
+		if jobs == nil {
+			jobs = make([]*paygateadmin.ErasureJob, 0)
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(jobs)
+	}
+}
+
+func getJob(repo Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			problem(w, fmt.Errorf("invalid method %s", r.Method))
+			return
+		}
+		jobID := route.ReadPathID("jobID", r)
+		job, err := repo.getJob(jobID)
+		if err != nil {
+			problem(w, err)
+			return
+		}
+		if job == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(job)
+	}
+}
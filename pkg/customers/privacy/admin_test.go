@@ -0,0 +1,102 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package privacy
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/moov-io/base/log"
+
+	"github.com/moov-io/paygate/pkg/admin"
+	"github.com/moov-io/paygate/pkg/config"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdmin__export(t *testing.T) {
+	repo := &MockRepository{
+		Data: map[string][]map[string]interface{}{
+			"transfers":   {{"transfer_id": "abc", "description": "rent"}},
+			"attachments": {},
+		},
+	}
+
+	router := mux.NewRouter()
+	router.Handle("/customers/{organization}/{customerID}/export", exportCustomer(config.Empty(), repo))
+
+	req := httptest.NewRequest("GET", "/customers/moov/jane/export", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	w.Flush()
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "application/zip", w.Header().Get("Content-Type"))
+
+	zr, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+	require.NoError(t, err)
+	require.Len(t, zr.File, 2)
+	require.Equal(t, "attachments.json", zr.File[0].Name)
+	require.Equal(t, "transfers.json", zr.File[1].Name)
+
+	f, err := zr.File[1].Open()
+	require.NoError(t, err)
+	bs, err := ioutil.ReadAll(f)
+	require.NoError(t, err)
+	require.Contains(t, string(bs), `"description": "rent"`)
+}
+
+func TestAdmin__erasures(t *testing.T) {
+	repo := &MockRepository{}
+	eraser := NewEraser(log.NewNopLogger(), repo, nil)
+
+	router := mux.NewRouter()
+	router.Handle("/customers/{organization}/{customerID}/erasures", eraseCustomer(config.Empty(), eraser))
+	router.Handle("/erasures", listJobs(repo))
+	router.Handle("/erasures/{jobID}", getJob(repo))
+
+	// requestedBy is required
+	req := httptest.NewRequest("POST", "/customers/moov/jane/erasures", strings.NewReader(`{}`))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+
+	req = httptest.NewRequest("POST", "/customers/moov/jane/erasures", strings.NewReader(`{"requestedBy": "privacy"}`))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	w.Flush()
+	require.Equal(t, http.StatusAccepted, w.Code)
+
+	var job admin.ErasureJob
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&job))
+	require.Equal(t, "jane", job.CustomerID)
+	require.Equal(t, StatusPending, job.Status)
+
+	req = httptest.NewRequest("GET", "/erasures", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	w.Flush()
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var jobs []admin.ErasureJob
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&jobs))
+	require.Len(t, jobs, 1)
+
+	req = httptest.NewRequest("GET", "/erasures/"+job.JobID, nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	req = httptest.NewRequest("GET", "/erasures/missing", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusNotFound, w.Code)
+}
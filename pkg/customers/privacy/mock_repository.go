@@ -0,0 +1,65 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package privacy
+
+import (
+	"time"
+
+	"github.com/moov-io/paygate/pkg/admin"
+)
+
+type MockRepository struct {
+	Data          map[string][]map[string]interface{}
+	Items         []admin.ErasedData
+	AttachmentIDs []string
+	Jobs          []*admin.ErasureJob
+
+	Err error
+}
+
+func (r *MockRepository) exportCustomer(organization, customerID string) (map[string][]map[string]interface{}, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	return r.Data, nil
+}
+
+func (r *MockRepository) eraseCustomer(organization, customerID string, when time.Time) ([]admin.ErasedData, []string, error) {
+	if r.Err != nil {
+		return nil, nil, r.Err
+	}
+	return r.Items, r.AttachmentIDs, nil
+}
+
+func (r *MockRepository) createJob(job *admin.ErasureJob) error {
+	if r.Err != nil {
+		return r.Err
+	}
+	r.Jobs = append(r.Jobs, job)
+	return nil
+}
+
+func (r *MockRepository) completeJob(jobID string, items []admin.ErasedData, failure error, when time.Time) error {
+	return r.Err
+}
+
+func (r *MockRepository) getJobs() ([]*admin.ErasureJob, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	return r.Jobs, nil
+}
+
+func (r *MockRepository) getJob(jobID string) (*admin.ErasureJob, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	for i := range r.Jobs {
+		if r.Jobs[i].JobID == jobID {
+			return r.Jobs[i], nil
+		}
+	}
+	return nil, nil
+}
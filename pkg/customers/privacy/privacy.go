@@ -0,0 +1,115 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+// Package privacy handles data subject requests (GDPR and CCPA) for a Customer. An export
+// collects every row PayGate stores about the Customer into a zip archive of JSON files.
+// An erasure job clears their personal data but keeps the payment records which must be
+// retained, and records what it removed as an audit trail.
+package privacy
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/moov-io/base"
+	"github.com/moov-io/base/log"
+	"gocloud.dev/blob"
+
+	"github.com/moov-io/paygate/pkg/admin"
+	"github.com/moov-io/paygate/pkg/attachments"
+)
+
+const (
+	StatusPending   = "pending"
+	StatusCompleted = "completed"
+	StatusFailed    = "failed"
+)
+
+// writeArchive writes a zip archive with one JSON file per table.
+func writeArchive(w io.Writer, data map[string][]map[string]interface{}) error {
+	var tables []string
+	for table := range data {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+
+	zw := zip.NewWriter(w)
+	for _, table := range tables {
+		f, err := zw.Create(table + ".json")
+		if err != nil {
+			return err
+		}
+		enc := json.NewEncoder(f)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(data[table]); err != nil {
+			return fmt.Errorf("%s: %v", table, err)
+		}
+	}
+	return zw.Close()
+}
+
+type Eraser struct {
+	logger log.Logger
+	repo   Repository
+
+	// bucket holds attachment contents, it's nil when attachments are disabled
+	bucket *blob.Bucket
+}
+
+func NewEraser(logger log.Logger, repo Repository, bucket *blob.Bucket) *Eraser {
+	return &Eraser{
+		logger: logger,
+		repo:   repo,
+		bucket: bucket,
+	}
+}
+
+// Start records a pending erasure job for the Customer and runs it in the background.
+func (e *Eraser) Start(organization, customerID, requestedBy string) (*admin.ErasureJob, error) {
+	job := &admin.ErasureJob{
+		JobID:        base.ID(),
+		Organization: organization,
+		CustomerID:   customerID,
+		Status:       StatusPending,
+		RequestedBy:  requestedBy,
+		Created:      time.Now(),
+	}
+	if err := e.repo.createJob(job); err != nil {
+		return nil, err
+	}
+	go e.run(job)
+	return job, nil
+}
+
+func (e *Eraser) run(job *admin.ErasureJob) {
+	logger := e.logger.With(log.Fields{
+		"jobID":        job.JobID,
+		"organization": job.Organization,
+		"customerID":   job.CustomerID,
+	})
+
+	items, attachmentIDs, err := e.repo.eraseCustomer(job.Organization, job.CustomerID, time.Now())
+	if err == nil && e.bucket != nil {
+		for i := range attachmentIDs {
+			path := attachments.ContentsPath(job.Organization, attachmentIDs[i])
+			if err = e.bucket.Delete(context.Background(), path); err != nil {
+				err = fmt.Errorf("deleting attachmentID=%s contents: %v", attachmentIDs[i], err)
+				break
+			}
+		}
+	}
+	if err := e.repo.completeJob(job.JobID, items, err, time.Now()); err != nil {
+		logger.LogErrorf("saving erasure job: %v", err)
+	}
+	if err != nil {
+		logger.LogErrorf("erasing customer: %v", err)
+		return
+	}
+	logger.Log("erased customer")
+}
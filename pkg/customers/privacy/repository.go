@@ -0,0 +1,339 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package privacy
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/moov-io/paygate/pkg/admin"
+)
+
+type Repository interface {
+	// exportCustomer reads every row stored about a Customer, keyed by table
+	exportCustomer(organization, customerID string) (map[string][]map[string]interface{}, error)
+
+	// eraseCustomer clears the Customer's personal data in one transaction. It returns what was
+	// removed from each table and the IDs of deleted attachments so their contents can be removed.
+	eraseCustomer(organization, customerID string, when time.Time) ([]admin.ErasedData, []string, error)
+
+	createJob(job *admin.ErasureJob) error
+	completeJob(jobID string, items []admin.ErasedData, failure error, when time.Time) error
+	getJobs() ([]*admin.ErasureJob, error)
+	getJob(jobID string) (*admin.ErasureJob, error)
+}
+
+func NewRepo(db *sql.DB) *sqlRepo {
+	return &sqlRepo{db: db}
+}
+
+type sqlRepo struct {
+	db *sql.DB
+}
+
+func (r *sqlRepo) Close() error {
+	return r.db.Close()
+}
+
+// placeholders in table filters which are bound to the organization and customerID
+var placeholders = regexp.MustCompile(`:org|:customer`)
+
+// bind replaces the placeholders in a filter with ? and returns the matching arguments.
+func bind(where, organization, customerID string) (string, []interface{}) {
+	var args []interface{}
+	where = placeholders.ReplaceAllStringFunc(where, func(name string) string {
+		if name == ":org" {
+			args = append(args, organization)
+		} else {
+			args = append(args, customerID)
+		}
+		return "?"
+	})
+	return where, args
+}
+
+const (
+	transfersFilter   = `organization = :org and (source_customer_id = :customer or destination_customer_id = :customer)`
+	attachmentsFilter = `organization = :org and customer_id = :customer`
+	apiTokensFilter   = `organization = :org and source_customer_id = :customer`
+	receiversFilter   = `customer_id = :customer and token_id in (select token_id from api_tokens where organization = :org)`
+)
+
+type exportTable struct {
+	name  string
+	where string
+	// omit lists columns which aren't included, like secrets
+	omit []string
+}
+
+var exportTables = []exportTable{
+	{name: "transfers", where: transfersFilter},
+	{name: "transfer_history", where: `transfer_id in (select transfer_id from transfers where ` + transfersFilter + `)`},
+	{name: "micro_deposits", where: `destination_customer_id = :customer`},
+	{name: "attachments", where: attachmentsFilter},
+	{name: "prenotes", where: `organization = :org and destination_customer_id = :customer`},
+	{name: "debit_authorizations", where: `organization = :org and customer_id = :customer`},
+	{name: "account_corrections", where: `organization = :org and customer_id = :customer`},
+	{name: "account_correction_changes", where: `correction_id in (select correction_id from account_corrections where organization = :org and customer_id = :customer)`},
+	{name: "ofac_matches", where: `organization = :org and customer_id = :customer`},
+	{name: "api_tokens", where: apiTokensFilter, omit: []string{"token_hash"}},
+	{name: "api_token_receivers", where: receiversFilter},
+	{name: "transfer_exposures", where: `organization = :org and customer_id = :customer`},
+}
+
+func (r *sqlRepo) exportCustomer(organization, customerID string) (map[string][]map[string]interface{}, error) {
+	out := make(map[string][]map[string]interface{})
+	for _, table := range exportTables {
+		where, args := bind(table.where, organization, customerID)
+		rows, err := r.db.Query(fmt.Sprintf(`select * from %s where %s;`, table.name, where), args...)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", table.name, err)
+		}
+		found, err := scanRows(rows, table.omit)
+		rows.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", table.name, err)
+		}
+		out[table.name] = found
+	}
+	return out, nil
+}
+
+func scanRows(rows *sql.Rows, omit []string) ([]map[string]interface{}, error) {
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]map[string]interface{}, 0)
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		pointers := make([]interface{}, len(columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			return nil, err
+		}
+		row := make(map[string]interface{})
+		for i := range columns {
+			if bs, ok := values[i].([]byte); ok {
+				values[i] = string(bs)
+			}
+			row[columns[i]] = values[i]
+		}
+		for i := range omit {
+			delete(row, omit[i])
+		}
+		out = append(out, row)
+	}
+	return out, rows.Err()
+}
+
+const (
+	ActionCleared = "cleared"
+	ActionDeleted = "deleted"
+)
+
+type erasure struct {
+	table string
+	where string
+	// fields are cleared on each row, rows are deleted when there are none
+	fields []string
+	// softDelete sets deleted_at rather than removing rows which other tables reference
+	softDelete bool
+}
+
+// erasures lists the personal data removed for a Customer. Transfers keep their amounts,
+// statuses, dates and trace numbers, and debit authorizations, OFAC matches and uploaded
+// entries are kept as-is, as they're payment records which must be retained.
+var erasures = []erasure{
+	{table: "transfers", where: transfersFilter, fields: []string{"description", "remote_address", "iat_detail"}},
+	{table: "attachments", where: attachmentsFilter + ` and deleted_at is null`, fields: []string{"note", "filename"}, softDelete: true},
+	{table: "api_token_receivers", where: receiversFilter},
+	{table: "api_tokens", where: apiTokensFilter + ` and deleted_at is null`, softDelete: true},
+}
+
+func (r *sqlRepo) eraseCustomer(organization, customerID string, when time.Time) ([]admin.ErasedData, []string, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// read attachments before they're deleted
+	where, args := bind(attachmentsFilter+` and deleted_at is null`, organization, customerID)
+	rows, err := tx.Query(`select attachment_id from attachments where `+where+`;`, args...)
+	if err != nil {
+		tx.Rollback()
+		return nil, nil, err
+	}
+	var attachmentIDs []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			tx.Rollback()
+			return nil, nil, err
+		}
+		attachmentIDs = append(attachmentIDs, id)
+	}
+	rows.Close()
+
+	var items []admin.ErasedData
+	for _, e := range erasures {
+		n, err := e.exec(tx, organization, customerID, when)
+		if err != nil {
+			tx.Rollback()
+			return nil, nil, fmt.Errorf("%s: %v", e.table, err)
+		}
+		item := admin.ErasedData{Table: e.table, Fields: e.fields, Action: ActionDeleted, Rows: n}
+		if len(e.fields) > 0 && !e.softDelete {
+			item.Action = ActionCleared
+		}
+		items = append(items, item)
+	}
+	return items, attachmentIDs, tx.Commit()
+}
+
+func (e erasure) exec(tx *sql.Tx, organization, customerID string, when time.Time) (int64, error) {
+	where, args := bind(e.where, organization, customerID)
+
+	var query string
+	if len(e.fields) == 0 && !e.softDelete {
+		query = fmt.Sprintf(`delete from %s where %s;`, e.table, where)
+	} else {
+		var set []string
+		for i := range e.fields {
+			set = append(set, e.fields[i]+` = ''`)
+		}
+		if e.softDelete {
+			set = append(set, `deleted_at = ?`)
+			args = append([]interface{}{when}, args...)
+		}
+		query = fmt.Sprintf(`update %s set %s where %s;`, e.table, strings.Join(set, ", "), where)
+	}
+
+	res, err := tx.Exec(query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func (r *sqlRepo) createJob(job *admin.ErasureJob) error {
+	query := `insert into erasure_jobs (job_id, organization, customer_id, status, requested_by, created_at) values (?, ?, ?, ?, ?, ?);`
+	_, err := r.db.Exec(query, job.JobID, job.Organization, job.CustomerID, job.Status, job.RequestedBy, job.Created)
+	return err
+}
+
+func (r *sqlRepo) completeJob(jobID string, items []admin.ErasedData, failure error, when time.Time) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	status, message := StatusCompleted, ""
+	if failure != nil {
+		status, message = StatusFailed, failure.Error()
+	}
+	query := `update erasure_jobs set status = ?, error = ?, completed_at = ? where job_id = ? and status = ?;`
+	if _, err := tx.Exec(query, status, message, when, jobID, StatusPending); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	query = `insert into erasure_job_items (job_id, table_name, fields, action, row_count) values (?, ?, ?, ?, ?);`
+	for i := range items {
+		fields := strings.Join(items[i].Fields, ",")
+		if _, err := tx.Exec(query, jobID, items[i].Table, fields, items[i].Action, items[i].Rows); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+const jobColumns = `job_id, organization, customer_id, status, requested_by, error, created_at, completed_at`
+
+func (r *sqlRepo) getJobs() ([]*admin.ErasureJob, error) {
+	rows, err := r.db.Query(`select ` + jobColumns + ` from erasure_jobs order by created_at desc;`)
+	if err != nil {
+		return nil, err
+	}
+	var out []*admin.ErasureJob
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		out = append(out, job)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	for i := range out {
+		if out[i].Items, err = r.getJobItems(out[i].JobID); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+func (r *sqlRepo) getJob(jobID string) (*admin.ErasureJob, error) {
+	job, err := scanJob(r.db.QueryRow(`select `+jobColumns+` from erasure_jobs where job_id = ? limit 1;`, jobID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	job.Items, err = r.getJobItems(jobID)
+	return job, err
+}
+
+func (r *sqlRepo) getJobItems(jobID string) ([]admin.ErasedData, error) {
+	rows, err := r.db.Query(`select table_name, fields, action, row_count from erasure_job_items where job_id = ?;`, jobID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []admin.ErasedData
+	for rows.Next() {
+		var item admin.ErasedData
+		var fields string
+		if err := rows.Scan(&item.Table, &fields, &item.Action, &item.Rows); err != nil {
+			return nil, err
+		}
+		if fields != "" {
+			item.Fields = strings.Split(fields, ",")
+		}
+		out = append(out, item)
+	}
+	return out, rows.Err()
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanJob(row scanner) (*admin.ErasureJob, error) {
+	var job admin.ErasureJob
+	var failure *string
+	var completed *time.Time
+	err := row.Scan(&job.JobID, &job.Organization, &job.CustomerID, &job.Status, &job.RequestedBy, &failure, &job.Created, &completed)
+	if err != nil {
+		return nil, err
+	}
+	if failure != nil {
+		job.Error = *failure
+	}
+	job.Completed = completed
+	return &job, nil
+}
@@ -0,0 +1,160 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package privacy
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/moov-io/base"
+
+	"github.com/moov-io/paygate/pkg/admin"
+	"github.com/moov-io/paygate/pkg/database"
+
+	"github.com/stretchr/testify/require"
+)
+
+func writeCustomerData(t *testing.T, repo *sqlRepo, organization, customerID string) {
+	t.Helper()
+
+	query := `insert into transfers (transfer_id, organization, amount_currency, amount_value, source_customer_id, source_account_id, destination_customer_id, destination_account_id, description, status, same_day, remote_address, created_at, last_updated_at)
+values (?, ?, 'USD', 100, ?, 'source', 'acme', 'destination', 'rent for jane', 'pending', false, '1.2.3.4', ?, ?);`
+	_, err := repo.db.Exec(query, base.ID(), organization, customerID, time.Now(), time.Now())
+	require.NoError(t, err)
+
+	query = `insert into attachments (attachment_id, organization, customer_id, kind, note, filename, created_at) values (?, ?, ?, 'document', 'passport', 'passport.pdf', ?);`
+	_, err = repo.db.Exec(query, base.ID(), organization, customerID, time.Now())
+	require.NoError(t, err)
+
+	query = `insert into api_tokens (token_id, organization, token_hash, source_customer_id, source_account_id, created_at) values (?, ?, 'secret', ?, 'source', ?);`
+	_, err = repo.db.Exec(query, base.ID(), organization, customerID, time.Now())
+	require.NoError(t, err)
+}
+
+func TestRepository__exportCustomer(t *testing.T) {
+	t.Parallel()
+
+	check := func(t *testing.T, repo *sqlRepo) {
+		writeCustomerData(t, repo, "moov", "jane")
+		writeCustomerData(t, repo, "other", "jane")
+
+		data, err := repo.exportCustomer("moov", "jane")
+		require.NoError(t, err)
+		require.Len(t, data, len(exportTables))
+		require.Len(t, data["transfers"], 1)
+		require.Equal(t, "rent for jane", data["transfers"][0]["description"])
+		require.Len(t, data["attachments"], 1)
+		require.Len(t, data["api_tokens"], 1)
+		require.NotContains(t, data["api_tokens"][0], "token_hash")
+		require.Empty(t, data["prenotes"])
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+}
+
+func TestRepository__eraseCustomer(t *testing.T) {
+	t.Parallel()
+
+	check := func(t *testing.T, repo *sqlRepo) {
+		writeCustomerData(t, repo, "moov", "jane")
+		writeCustomerData(t, repo, "other", "jane")
+
+		items, attachmentIDs, err := repo.eraseCustomer("moov", "jane", time.Now())
+		require.NoError(t, err)
+		require.Len(t, attachmentIDs, 1)
+		require.Len(t, items, len(erasures))
+		require.Equal(t, admin.ErasedData{
+			Table:  "transfers",
+			Fields: []string{"description", "remote_address", "iat_detail"},
+			Action: ActionCleared,
+			Rows:   1,
+		}, items[0])
+
+		data, err := repo.exportCustomer("moov", "jane")
+		require.NoError(t, err)
+		require.Len(t, data["transfers"], 1)
+		require.Equal(t, "", data["transfers"][0]["description"])
+		require.Equal(t, "", data["attachments"][0]["filename"])
+		require.NotNil(t, data["attachments"][0]["deleted_at"])
+
+		// other organizations are untouched
+		data, err = repo.exportCustomer("other", "jane")
+		require.NoError(t, err)
+		require.Equal(t, "rent for jane", data["transfers"][0]["description"])
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+}
+
+func TestRepository__jobs(t *testing.T) {
+	t.Parallel()
+
+	check := func(t *testing.T, repo *sqlRepo) {
+		job := &admin.ErasureJob{
+			JobID:        base.ID(),
+			Organization: "moov",
+			CustomerID:   "jane",
+			Status:       StatusPending,
+			RequestedBy:  "privacy",
+			Created:      time.Now().Truncate(time.Second),
+		}
+		require.NoError(t, repo.createJob(job))
+
+		found, err := repo.getJob(job.JobID)
+		require.NoError(t, err)
+		require.Equal(t, StatusPending, found.Status)
+		require.Nil(t, found.Completed)
+
+		items := []admin.ErasedData{
+			{Table: "transfers", Fields: []string{"description"}, Action: ActionCleared, Rows: 2},
+			{Table: "api_token_receivers", Action: ActionDeleted, Rows: 1},
+		}
+		require.NoError(t, repo.completeJob(job.JobID, items, nil, time.Now()))
+
+		found, err = repo.getJob(job.JobID)
+		require.NoError(t, err)
+		require.Equal(t, StatusCompleted, found.Status)
+		require.NotNil(t, found.Completed)
+		require.Equal(t, items, found.Items)
+
+		failed := *job
+		failed.JobID = base.ID()
+		require.NoError(t, repo.createJob(&failed))
+		require.NoError(t, repo.completeJob(failed.JobID, nil, errors.New("bad thing"), time.Now()))
+
+		jobs, err := repo.getJobs()
+		require.NoError(t, err)
+		require.Len(t, jobs, 2)
+
+		found, err = repo.getJob(failed.JobID)
+		require.NoError(t, err)
+		require.Equal(t, StatusFailed, found.Status)
+		require.Equal(t, "bad thing", found.Error)
+
+		found, err = repo.getJob("missing")
+		require.NoError(t, err)
+		require.Nil(t, found)
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+}
+
+func setupSQLiteDB(t *testing.T) *sqlRepo {
+	db := database.CreateTestSqliteDB(t)
+	t.Cleanup(func() { db.Close() })
+
+	return NewRepo(db.DB)
+}
+
+func setupMySQLeDB(t *testing.T) *sqlRepo {
+	db := database.CreateTestMySQLDB(t)
+	t.Cleanup(func() { db.Close() })
+
+	return NewRepo(db.DB)
+}
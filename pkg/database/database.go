@@ -19,7 +19,7 @@ import (
 func New(ctx context.Context, logger log.Logger, cfg config.Database) (*sql.DB, error) {
 	if cfg.MySQL != nil {
 		logger.Log("setting up mysql database provider")
-		return mysqlConnection(logger, cfg.MySQL.Username, cfg.MySQL.GetPassword(), cfg.MySQL.Address, cfg.MySQL.Database).Connect(ctx)
+		return mysqlConnection(logger, cfg.MySQL.Username, cfg.MySQL.GetPassword(), cfg.MySQL.Address, cfg.MySQL.Database, cfg.MySQL.Migrations).Connect(ctx)
 	}
 
 	logger.Log("setting up sqlite database provider")
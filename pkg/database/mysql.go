@@ -15,6 +15,7 @@ import (
 	"time"
 
 	"github.com/moov-io/base/docker"
+	"github.com/moov-io/paygate/pkg/config"
 
 	kitprom "github.com/go-kit/kit/metrics/prometheus"
 	gomysql "github.com/go-sql-driver/mysql"
@@ -34,6 +35,10 @@ var (
 	// https://dev.mysql.com/doc/refman/8.0/en/server-error-reference.html#error_er_dup_entry
 	mySQLErrDuplicateKey uint16 = 1062
 
+	// mySQLErrDeadlock is the error code for transactions rolled back after a deadlock
+	// https://dev.mysql.com/doc/refman/8.0/en/server-error-reference.html#error_er_lock_deadlock
+	mySQLErrDeadlock uint16 = 1213
+
 	maxActiveMySQLConnections = func() int {
 		if v := os.Getenv("MYSQL_MAX_CONNECTIONS"); v != "" {
 			if n, _ := strconv.ParseInt(v, 10, 32); n > 0 {
@@ -42,8 +47,11 @@ var (
 		}
 		return 16
 	}()
+)
 
-	mysqlMigrations = migrator.Migrations(
+// mysqlMigrations returns PayGate's schema migrations with each statement run by execsql.
+func mysqlMigrations(execsql func(name, raw string) *migrator.MigrationNoTx) migrator.Option {
+	return migrator.Migrations(
 		execsql(
 			"create_namespace_configs",
 			`create table namespace_configs(namespace varchar(40) primary key not null, company_identification varchar(40) not null)`,
@@ -105,8 +113,368 @@ var (
 			"rename_transfers_namespace_to_organization",
 			`alter table transfers rename column namespace to organization;`,
 		),
+		execsql(
+			"add_effective_date__to__transfers",
+			`alter table transfers add column effective_date varchar(10);`,
+		),
+		execsql(
+			"add_return_code__to__micro_deposits",
+			`alter table micro_deposits add column return_code varchar(10);`,
+		),
+		execsql(
+			"create_attachments",
+			`create table attachments(attachment_id varchar(40) primary key not null, organization varchar(40) not null, customer_id varchar(40) not null, account_id varchar(40), kind varchar(10) not null, note text, filename varchar(200), content_type varchar(100), size bigint, created_at datetime not null, deleted_at datetime);`,
+		),
+		execsql(
+			"create_attachments__customer_id_idx",
+			`create index attachments_customer_id on attachments (organization, customer_id);`,
+		),
+		execsql(
+			"create_api_tokens",
+			`create table api_tokens(token_id varchar(40) primary key not null, organization varchar(40) not null, token_hash varchar(64) not null, source_customer_id varchar(40) not null, source_account_id varchar(40) not null, created_at datetime not null, expires_at datetime, deleted_at datetime);`,
+		),
+		execsql(
+			"create_api_tokens__token_hash_idx",
+			`create unique index api_tokens_token_hash on api_tokens (token_hash);`,
+		),
+		execsql(
+			"create_api_token_receivers",
+			`create table api_token_receivers(token_id varchar(40) not null, customer_id varchar(40) not null, account_id varchar(40) not null);`,
+		),
+		execsql(
+			"create_transfer_history",
+			`create table transfer_history(history_id varchar(40) primary key not null, transfer_id varchar(40) not null, field varchar(40) not null, old_value varchar(200), new_value varchar(200), actor varchar(20) not null, created_at datetime(3) not null);`,
+		),
+		execsql(
+			"create_transfer_history__transfer_id_idx",
+			`create index transfer_history_transfer_id on transfer_history (transfer_id);`,
+		),
+		execsql(
+			"add_attempt__to__micro_deposits",
+			`alter table micro_deposits add column attempt integer not null default 1;`,
+		),
+		execsql(
+			"add_expires_at__to__micro_deposits",
+			`alter table micro_deposits add column expires_at datetime;`,
+		),
+		execsql(
+			"drop_micro_deposits__unique_account_id_idx",
+			`drop index micro_deposits_account_id on micro_deposits;`,
+		),
+		execsql(
+			"create_micro_deposits__account_id_created_at_idx",
+			`create index micro_deposits_account_id_created_at on micro_deposits (destination_account_id, created_at);`,
+		),
+		execsql(
+			"create_unprocessed_transfers",
+			`create table unprocessed_transfers(transfer_id varchar(40) primary key not null, error varchar(500), attempts integer not null, created_at datetime(3) not null, last_attempt_at datetime(3) not null);`,
+		),
+		execsql(
+			"add_funding_flow__to__organization_configs",
+			`alter table organization_configs add column funding_flow varchar(20) not null default '';`,
+		),
+		execsql(
+			"create_transfer_legs",
+			`create table transfer_legs(transfer_id varchar(40) not null, leg varchar(10) not null, status varchar(10) not null, updated_at datetime(3) not null, primary key (transfer_id, leg));`,
+		),
+		execsql(
+			"add_verified_at__to__micro_deposits",
+			`alter table micro_deposits add column verified_at datetime;`,
+		),
+		execsql(
+			"add_confirmation_attempts__to__micro_deposits",
+			`alter table micro_deposits add column confirmation_attempts integer not null default 0;`,
+		),
+		execsql(
+			"create_uploaded_files",
+			`create table uploaded_files(filename varchar(100) primary key not null, routing_number varchar(10) not null, uploaded_at datetime not null, acknowledged_at datetime);`,
+		),
+		execsql(
+			"create_uploaded_file_batches",
+			`create table uploaded_file_batches(filename varchar(100) not null, batch_number integer not null, entries integer not null, status varchar(10) not null, reason varchar(100), primary key (filename, batch_number));`,
+		),
+		execsql(
+			"add_prefer_same_day__to__organization_configs",
+			`alter table organization_configs add column prefer_same_day boolean not null default false;`,
+		),
+		execsql(
+			"add_same_day_preferred__to__transfers",
+			`alter table transfers add column same_day_preferred boolean not null default false;`,
+		),
+		execsql(
+			"add_same_day_reason__to__transfers",
+			`alter table transfers add column same_day_reason varchar(200);`,
+		),
+		execsql(
+			"add_cancel_reason__to__transfers",
+			`alter table transfers add column cancel_reason varchar(20);`,
+		),
+		execsql(
+			"add_cancel_note__to__transfers",
+			`alter table transfers add column cancel_note varchar(200);`,
+		),
+		execsql(
+			"add_canceled_at__to__transfers",
+			`alter table transfers add column canceled_at datetime;`,
+		),
+		execsql(
+			"create_debit_kill_switches",
+			`create table debit_kill_switches(scope varchar(40) primary key not null, reason varchar(200), enabled_at datetime not null);`,
+		),
+		execsql(
+			"create_outbound_file_sequences",
+			`create table outbound_file_sequences(routing_number varchar(10) not null, day varchar(8) not null, sequence integer not null, primary key (routing_number, day));`,
+		),
+		execsql(
+			"add_transfer_tags__to__organization_configs",
+			`alter table organization_configs add column transfer_tags varchar(1000) not null default '';`,
+		),
+		execsql(
+			"create_transfer_tags",
+			`create table transfer_tags(transfer_id varchar(40) not null, tag varchar(40) not null, primary key (transfer_id, tag));`,
+		),
+		execsql(
+			"create_transfer_views",
+			`create table transfer_views(view_id varchar(40) primary key not null, organization varchar(40) not null, user_id varchar(40) not null, name varchar(100) not null, status varchar(10) not null default '', start_date datetime, end_date datetime, tags varchar(1000) not null default '', created_at datetime not null, deleted_at datetime);`,
+		),
+		execsql(
+			"create_transfer_exposures",
+			`create table transfer_exposures(transfer_id varchar(40) not null, direction varchar(6) not null, organization varchar(40) not null, customer_id varchar(40) not null, user_id varchar(40) not null, amount bigint not null, created_at datetime not null, primary key (transfer_id, direction));`,
+		),
+		execsql(
+			"add_sec_code__to__transfers",
+			`alter table transfers add column sec_code varchar(3) not null default '';`,
+		),
+		execsql(
+			"add_debit_amount__to__transfers",
+			`alter table transfers add column debit_amount bigint not null default 0;`,
+		),
+		execsql(
+			"add_credit_amount__to__transfers",
+			`alter table transfers add column credit_amount bigint not null default 0;`,
+		),
+		execsql(
+			"create_accounting_periods",
+			`create table accounting_periods(period varchar(10) primary key not null, starts_at datetime not null, ends_at datetime not null, closed_at datetime not null);`,
+		),
+		execsql(
+			"create_accounting_period_totals",
+			`create table accounting_period_totals(period varchar(10) not null, organization varchar(40) not null, sec_code varchar(3) not null, transfers bigint not null, debit_count bigint not null, debit_amount bigint not null, credit_count bigint not null, credit_amount bigint not null, return_count bigint not null, return_amount bigint not null, primary key (period, organization, sec_code));`,
+		),
+		execsql(
+			"create_scheduled_transfers",
+			`create table scheduled_transfers(schedule_id varchar(40) primary key not null, organization varchar(40) not null, user_id varchar(40) not null, template text not null, frequency varchar(10) not null, status varchar(10) not null, start_date datetime not null, end_date datetime, next_run_at datetime, run_count integer not null default 0, last_run_at datetime, last_transfer_id varchar(40), last_error varchar(200), created_at datetime not null);`,
+		),
+		execsql(
+			"create_prenotes",
+			`create table prenotes(prenote_id varchar(40) primary key not null, organization varchar(40) not null, destination_customer_id varchar(40) not null, destination_account_id varchar(40) not null, transfer_id varchar(40) not null, status varchar(10) not null, return_code varchar(10), change_code varchar(10), verified_at datetime, created_at datetime not null, deleted_at datetime);`,
+		),
+		execsql(
+			"create_notification_preferences",
+			`create table notification_preferences(organization varchar(40) not null, event_type varchar(40) not null, channel varchar(10) not null, email varchar(200), updated_at datetime not null, primary key (organization, event_type));`,
+		),
+		execsql(
+			"add_amount_value_encrypted__to__micro_deposit_amounts",
+			`alter table micro_deposit_amounts add column amount_value_encrypted varchar(255);`,
+		),
+		execsql(
+			"allow_null__micro_deposit_amounts__amount_value",
+			`alter table micro_deposit_amounts modify amount_value integer;`,
+		),
+		execsql(
+			"create_inbound_records",
+			`create table inbound_records(record_id varchar(40) primary key not null, transfer_id varchar(40), record_type varchar(10) not null, code varchar(3) not null, trace_number varchar(15) not null, original_trace varchar(15) not null, immediate_origin varchar(10) not null, immediate_destination varchar(10) not null, entry_detail varchar(94) not null, addenda varchar(94) not null, created_at datetime not null);`,
+		),
+		execsql(
+			"create_inbound_records__transfer_id_idx",
+			`create index inbound_records_transfer_id on inbound_records (transfer_id);`,
+		),
+		execsql(
+			"create_limit_overrides",
+			`create table limit_overrides(scope varchar(20) not null, scope_id varchar(40) not null, soft_limit bigint not null, hard_limit bigint not null, debit_exposure bigint not null, credit_exposure bigint not null, updated_at datetime not null, primary key (scope, scope_id));`,
+		),
+		execsql(
+			"add_remote_server__to__uploaded_files",
+			`alter table uploaded_files add column remote_server varchar(100) not null default '';`,
+		),
+		execsql(
+			"create_uploaded_file_entries",
+			`create table uploaded_file_entries(filename varchar(100) not null, batch_number integer not null, trace_number varchar(15) not null, entry_detail varchar(94) not null, primary key (filename, trace_number));`,
+		),
+		execsql(
+			"create_uploaded_file_entries__trace_number_idx",
+			`create index uploaded_file_entries_trace_number on uploaded_file_entries (trace_number);`,
+		),
+		execsql(
+			"create_transfer_reversals",
+			`create table transfer_reversals(transfer_id varchar(40) primary key not null, reversal_id varchar(40) not null, created_at datetime not null);`,
+		),
+		execsql(
+			"create_transfer_reversals__reversal_id_idx",
+			`create unique index transfer_reversals_reversal_id on transfer_reversals (reversal_id);`,
+		),
+		execsql(
+			"create_daily_originations",
+			`create table daily_originations(routing_number varchar(10) not null, day varchar(8) not null, amount bigint not null, primary key (routing_number, day));`,
+		),
+		execsql(
+			"create_transfer_batches",
+			`create table transfer_batches(batch_id varchar(40) primary key not null, organization varchar(40) not null, idempotency_key varchar(255), created_at datetime not null);`,
+		),
+		execsql(
+			"create_transfer_batches__idempotency_key_idx",
+			`create unique index transfer_batches_idempotency_key on transfer_batches (organization, idempotency_key);`,
+		),
+		execsql(
+			"create_debit_authorizations",
+			`create table debit_authorizations(authorization_id varchar(40) primary key not null, organization varchar(40) not null, customer_id varchar(40) not null, account_id varchar(40) not null, authorized_at datetime not null, method varchar(10) not null, amount_currency varchar(3), amount_value bigint, frequency varchar(10) not null, terms text, status varchar(10) not null, revoked_at datetime, revocation_reason varchar(200), created_at datetime not null);`,
+		),
+		execsql(
+			"create_debit_authorizations__account_idx",
+			`create index debit_authorizations_account on debit_authorizations (organization, customer_id, account_id);`,
+		),
+		execsql(
+			"create_account_corrections",
+			`create table account_corrections(correction_id varchar(40) primary key not null, organization varchar(40) not null, customer_id varchar(40) not null, account_id varchar(40) not null, transfer_id varchar(40) not null, change_code varchar(3) not null, status varchar(10) not null, created_at datetime not null, reviewed_at datetime, reviewed_by varchar(40));`,
+		),
+		execsql(
+			"create_account_corrections__account_idx",
+			`create index account_corrections_account on account_corrections (organization, customer_id, account_id);`,
+		),
+		execsql(
+			"create_account_correction_changes",
+			`create table account_correction_changes(correction_id varchar(40) not null, field varchar(20) not null, old_value varchar(40) not null, new_value varchar(40) not null);`,
+		),
+		execsql(
+			"create_account_correction_changes__correction_id_idx",
+			`create index account_correction_changes_correction_id on account_correction_changes (correction_id);`,
+		),
+		execsql(
+			"create_ofac_matches",
+			`create table ofac_matches(organization varchar(40) not null, customer_id varchar(40) not null, entity_id varchar(40) not null, sdn_name varchar(200) not null, match_score float not null, status varchar(10) not null, matched_at datetime not null, screened_at datetime not null, reviewed_at datetime, reviewed_by varchar(40), primary key (organization, customer_id));`,
+		),
+		execsql(
+			"create_ambiguous_returns",
+			`create table ambiguous_returns(record_id varchar(40) primary key not null, status varchar(10) not null, transfer_id varchar(40), resolved_at datetime, created_at datetime not null);`,
+		),
+		execsql(
+			"create_ambiguous_return_candidates",
+			`create table ambiguous_return_candidates(record_id varchar(40) not null, transfer_id varchar(40) not null, confidence double not null, primary key (record_id, transfer_id));`,
+		),
+		execsql(
+			"add_correlation_id__to__transfers",
+			`alter table transfers add column correlation_id varchar(40) not null default '';`,
+		),
+		execsql(
+			"add_iat_detail__to__transfers",
+			`alter table transfers add column iat_detail text;`,
+		),
+		execsql(
+			"create_erasure_jobs",
+			`create table erasure_jobs(job_id varchar(40) primary key not null, organization varchar(40) not null, customer_id varchar(40) not null, status varchar(10) not null, requested_by varchar(40) not null, error text, created_at datetime not null, completed_at datetime);`,
+		),
+		execsql(
+			"create_erasure_job_items",
+			`create table erasure_job_items(job_id varchar(40) not null, table_name varchar(40) not null, fields varchar(200) not null, action varchar(10) not null, row_count bigint not null);`,
+		),
+		execsql(
+			"create_micro_deposit_confirmations",
+			`create table micro_deposit_confirmations(micro_deposit_id varchar(40) not null, matched boolean not null, created_at datetime not null);`,
+		),
+		execsql(
+			"create_audit_records",
+			`create table audit_records(record_id varchar(40) primary key not null, organization varchar(40) not null, user_id varchar(40) not null, request_id varchar(40) not null, remote_address varchar(45) not null, method varchar(10) not null, endpoint varchar(200) not null, path varchar(200) not null, resource varchar(40) not null, status_code integer not null, body text, created_at datetime not null);`,
+		),
+		execsql(
+			"add_size_bytes__to__uploaded_files",
+			`alter table uploaded_files add column size_bytes bigint not null default 0;`,
+		),
+		execsql(
+			"add_checksum__to__uploaded_files",
+			`alter table uploaded_files add column checksum varchar(64) not null default '';`,
+		),
+		execsql(
+			"add_remote_path__to__uploaded_files",
+			`alter table uploaded_files add column remote_path varchar(200) not null default '';`,
+		),
+		execsql(
+			"create_trace_number_sequences",
+			`create table trace_number_sequences(routing_number varchar(10) primary key not null, sequence integer not null);`,
+		),
+		execsql(
+			"create_email_verifications",
+			`create table email_verifications(organization varchar(40) not null, customer_id varchar(40) not null, email varchar(254) not null, verified_at datetime not null, primary key (organization, customer_id));`,
+		),
+		execsql(
+			"create_death_notifications",
+			`create table death_notifications(organization varchar(40) not null, customer_id varchar(40) not null, account_id varchar(40) not null, trace_number varchar(20) not null, date_of_death datetime, received_at datetime not null, primary key (organization, customer_id, account_id));`,
+		),
+		execsql(
+			"create_transfer_idempotency_keys",
+			`create table transfer_idempotency_keys(organization varchar(40) not null, user_id varchar(40) not null, idempotency_key varchar(255) not null, transfer_id varchar(40), response text, created_at datetime not null, primary key (organization, user_id, idempotency_key));`,
+		),
+		execsql(
+			"add_processed_event_at__to__micro_deposits",
+			`alter table micro_deposits add column processed_event_at datetime;`,
+		),
+		execsql(
+			"add_expired_event_at__to__micro_deposits",
+			`alter table micro_deposits add column expired_event_at datetime;`,
+		),
+		execsql(
+			"backfill_processed_event_at__on__micro_deposits",
+			`update micro_deposits set processed_event_at = processed_at where processed_at is not null;`,
+		),
+		execsql(
+			"backfill_expired_event_at__on__micro_deposits",
+			`update micro_deposits set expired_event_at = expires_at where expires_at < current_timestamp;`,
+		),
+		execsql(
+			"add_file_id__to__uploaded_files",
+			`alter table uploaded_files add column file_id varchar(100) not null default '';`,
+		),
+		execsql(
+			"backfill_file_id__on__uploaded_files",
+			`update uploaded_files set file_id = filename;`,
+		),
+		execsql(
+			"key_uploaded_files__by__file_id",
+			`alter table uploaded_files drop primary key, add primary key (file_id);`,
+		),
+		execsql(
+			"create_uploaded_files__filename_idx",
+			`create index uploaded_files_filename on uploaded_files (filename);`,
+		),
+		execsql(
+			"add_file_id__to__uploaded_file_batches",
+			`alter table uploaded_file_batches add column file_id varchar(100) not null default '';`,
+		),
+		execsql(
+			"backfill_file_id__on__uploaded_file_batches",
+			`update uploaded_file_batches set file_id = filename;`,
+		),
+		execsql(
+			"key_uploaded_file_batches__by__file_id",
+			`alter table uploaded_file_batches drop primary key, drop column filename, add primary key (file_id, batch_number);`,
+		),
+		execsql(
+			"add_file_id__to__uploaded_file_entries",
+			`alter table uploaded_file_entries add column file_id varchar(100) not null default '';`,
+		),
+		execsql(
+			"backfill_file_id__on__uploaded_file_entries",
+			`update uploaded_file_entries set file_id = filename;`,
+		),
+		execsql(
+			"key_uploaded_file_entries__by__file_id",
+			`alter table uploaded_file_entries drop primary key, drop column filename, add primary key (file_id, trace_number);`,
+		),
+		execsql(
+			"add_response__to__transfer_batches",
+			`alter table transfer_batches add column response mediumtext;`,
+		),
 	)
-)
+}
 
 type discardLogger struct{}
 
@@ -120,6 +488,8 @@ type mysql struct {
 	dsn    string
 	logger log.Logger
 
+	migrations *mysqlMigrationRunner
+
 	connections *kitprom.Gauge
 }
 
@@ -140,7 +510,7 @@ func (my *mysql) Connect(ctx context.Context) (*sql.DB, error) {
 	}
 
 	// Migrate our database
-	if m, err := migrator.New(mysqlMigrations); err != nil {
+	if m, err := migrator.New(mysqlMigrations(my.migrations.execsql)); err != nil {
 		return nil, err
 	} else {
 		if err := m.Migrate(db); err != nil {
@@ -167,7 +537,7 @@ func (my *mysql) Connect(ctx context.Context) (*sql.DB, error) {
 	return db, nil
 }
 
-func mysqlConnection(logger log.Logger, user, pass string, address string, database string) *mysql {
+func mysqlConnection(logger log.Logger, user, pass string, address string, database string, migrations *config.MySQLMigrations) *mysql {
 	timeout := "30s"
 	if v := os.Getenv("MYSQL_TIMEOUT"); v != "" {
 		timeout = v
@@ -177,6 +547,7 @@ func mysqlConnection(logger log.Logger, user, pass string, address string, datab
 	return &mysql{
 		dsn:         dsn,
 		logger:      logger,
+		migrations:  newMySQLMigrationRunner(logger, migrations, address, user, pass, database),
 		connections: mysqlConnections,
 	}
 }
@@ -251,7 +622,7 @@ func CreateTestMySQLDB(t *testing.T) *TestMySQLDB {
 
 	ctx, cancelFunc := context.WithCancel(context.Background())
 
-	db, err := mysqlConnection(logger, "moov", "secret", address, "paygate").Connect(ctx)
+	db, err := mysqlConnection(logger, "moov", "secret", address, "paygate", nil).Connect(ctx)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -275,3 +646,13 @@ func MySQLUniqueViolation(err error) bool {
 	}
 	return match
 }
+
+// MySQLDeadlock returns true when the provided error matches the MySQL code for
+// transactions rolled back after a deadlock, which can be retried.
+func MySQLDeadlock(err error) bool {
+	match := strings.Contains(err.Error(), fmt.Sprintf("Error %d: Deadlock found", mySQLErrDeadlock))
+	if e, ok := err.(*gomysql.MySQLError); ok {
+		return match || e.Number == mySQLErrDeadlock
+	}
+	return match
+}
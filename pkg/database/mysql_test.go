@@ -21,7 +21,7 @@ func TestMySQL__basic(t *testing.T) {
 	}
 
 	// create a phony MySQL
-	m := mysqlConnection(log.NewNopLogger(), "user", "pass", "127.0.0.1:3006", "db")
+	m := mysqlConnection(log.NewNopLogger(), "user", "pass", "127.0.0.1:3006", "db", nil)
 
 	ctx, cancelFunc := context.WithCancel(context.Background())
 
@@ -44,3 +44,13 @@ func TestMySQLUniqueViolation(t *testing.T) {
 		t.Error("should have matched unique violation")
 	}
 }
+
+func TestMySQLDeadlock(t *testing.T) {
+	err := errors.New(`incrementing sequence: Error 1213: Deadlock found when trying to get lock; try restarting transaction`)
+	if !MySQLDeadlock(err) {
+		t.Error("should have matched deadlock")
+	}
+	if MySQLDeadlock(errors.New("Error 1062: Duplicate entry")) {
+		t.Error("unexpected deadlock")
+	}
+}
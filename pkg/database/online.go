@@ -0,0 +1,199 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+
+	"github.com/moov-io/paygate/pkg/config"
+
+	"github.com/lopezator/migrator"
+	"github.com/moov-io/base/log"
+)
+
+var (
+	alterTableRegex  = regexp.MustCompile("(?is)^\\s*alter\\s+table\\s+`?(\\w+)`?\\s+(.+?);?\\s*$")
+	createIndexRegex = regexp.MustCompile("(?is)^\\s*create\\s+(unique\\s+)?index\\s+`?(\\w+)`?\\s+on\\s+`?(\\w+)`?\\s*(\\(.+\\))\\s*;?\\s*$")
+)
+
+// parseAlter returns the table and ALTER clause of statements which rebuild or lock a table.
+func parseAlter(raw string) (string, string, bool) {
+	if m := alterTableRegex.FindStringSubmatch(raw); len(m) == 3 {
+		return m[1], m[2], true
+	}
+	if m := createIndexRegex.FindStringSubmatch(raw); len(m) == 5 {
+		return m[3], fmt.Sprintf("add %sindex %s %s", strings.ToLower(m[1]), m[2], m[4]), true
+	}
+	return "", "", false
+}
+
+// mysqlMigrationRunner runs schema migrations on MySQL, moving ALTERs of large tables online
+// with gh-ost or refusing them in production.
+type mysqlMigrationRunner struct {
+	cfg    *config.MySQLMigrations
+	logger log.Logger
+
+	address  string
+	user     string
+	pass     string
+	database string
+
+	tableRows func(db *sql.DB, table string) (int64, error)
+	command   func(name string, args ...string) error
+}
+
+func newMySQLMigrationRunner(logger log.Logger, cfg *config.MySQLMigrations, address, user, pass, database string) *mysqlMigrationRunner {
+	return &mysqlMigrationRunner{
+		cfg:       cfg,
+		logger:    logger,
+		address:   address,
+		user:      user,
+		pass:      pass,
+		database:  database,
+		tableRows: estimatedTableRows,
+		command:   runCommand,
+	}
+}
+
+func (r *mysqlMigrationRunner) execsql(name, raw string) *migrator.MigrationNoTx {
+	return &migrator.MigrationNoTx{
+		Name: name,
+		Func: func(db *sql.DB) error {
+			return r.exec(db, name, raw)
+		},
+	}
+}
+
+func (r *mysqlMigrationRunner) exec(db *sql.DB, name, raw string) error {
+	table, alter, ok := parseAlter(raw)
+	if !ok || r.cfg == nil {
+		_, err := db.Exec(raw)
+		return err
+	}
+
+	rows, err := r.tableRows(db, table)
+	if err != nil {
+		return fmt.Errorf("%s: estimating %s rows: %v", name, table, err)
+	}
+	if !r.cfg.LargeTable(rows) {
+		_, err := db.Exec(raw)
+		return err
+	}
+
+	switch {
+	case r.cfg.Online != nil:
+		r.logger.Logf("migrations: running %s on %s (~%d rows) online", name, table, rows)
+		if err := r.runOnline(table, alter); err != nil {
+			return fmt.Errorf("%s: online migration of %s: %v", name, table, err)
+		}
+		return nil
+
+	case r.cfg.Production && !r.cfg.Force:
+		return fmt.Errorf("%s: refusing to lock %s (~%d rows) in production, run it online or set force", name, table, rows)
+	}
+
+	r.logger.Logf("migrations: %s locks %s (~%d rows) until it completes", name, table, rows)
+	_, err = db.Exec(raw)
+	return err
+}
+
+func (r *mysqlMigrationRunner) runOnline(table, alter string) error {
+	host, port, err := splitMySQLAddress(r.address)
+	if err != nil {
+		return err
+	}
+
+	// Credentials are read from a config file rather than arguments, which any local
+	// user can read from ps or /proc.
+	conf, err := writeCredentials(r.user, r.pass)
+	if err != nil {
+		return fmt.Errorf("writing gh-ost credentials: %v", err)
+	}
+	defer os.Remove(conf)
+
+	args := []string{
+		"--host=" + host,
+		"--port=" + port,
+		"--conf=" + conf,
+		"--database=" + r.database,
+		"--table=" + table,
+		"--alter=" + alter,
+		"--execute",
+	}
+	args = append(args, r.cfg.Online.Args...)
+	return r.command(r.cfg.Online.Command(), args...)
+}
+
+// writeCredentials writes the user and password into a temporary file only readable by
+// us, in the [client] format gh-ost reads with --conf. Callers remove the file.
+func writeCredentials(user, pass string) (string, error) {
+	fd, err := ioutil.TempFile("", "paygate-gh-ost-*.cnf")
+	if err != nil {
+		return "", err
+	}
+	// TempFile creates files with 0600, but make sure before writing the password
+	if err := fd.Chmod(0600); err != nil {
+		fd.Close()
+		os.Remove(fd.Name())
+		return "", err
+	}
+	_, err = fmt.Fprintf(fd, "[client]\nuser = %s\npassword = %s\n", quoteConfValue(user), quoteConfValue(pass))
+	if closeErr := fd.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(fd.Name())
+		return "", err
+	}
+	return fd.Name(), nil
+}
+
+// quoteConfValue quotes a value so characters like ; and # aren't read as comments.
+func quoteConfValue(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `"`, `\"`)
+	return `"` + value + `"`
+}
+
+// splitMySQLAddress returns the host and port from a DSN address like tcp(localhost:3306)
+func splitMySQLAddress(address string) (string, string, error) {
+	address = strings.TrimSuffix(strings.TrimPrefix(address, "tcp("), ")")
+	if address == "" {
+		return "", "", errors.New("missing mysql address")
+	}
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return address, "3306", nil
+	}
+	return host, port, nil
+}
+
+func estimatedTableRows(db *sql.DB, table string) (int64, error) {
+	query := `select coalesce(table_rows, 0) from information_schema.tables where table_schema = database() and table_name = ? limit 1;`
+	var rows int64
+	if err := db.QueryRow(query, table).Scan(&rows); err != nil {
+		if err == sql.ErrNoRows {
+			return 0, nil
+		}
+		return 0, err
+	}
+	return rows, nil
+}
+
+func runCommand(name string, args ...string) error {
+	out, err := exec.Command(name, args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%v: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package database
+
+import (
+	"database/sql"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/moov-io/paygate/pkg/config"
+
+	"github.com/moov-io/base/log"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAlter(t *testing.T) {
+	table, alter, ok := parseAlter("alter table transfers add column processed_at datetime;")
+	require.True(t, ok)
+	require.Equal(t, "transfers", table)
+	require.Equal(t, "add column processed_at datetime", alter)
+
+	table, alter, ok = parseAlter("create unique index transfers_idempotency_key on transfers (organization, idempotency_key);")
+	require.True(t, ok)
+	require.Equal(t, "transfers", table)
+	require.Equal(t, "add unique index transfers_idempotency_key (organization, idempotency_key)", alter)
+
+	table, alter, ok = parseAlter("CREATE INDEX transfers_created_at ON transfers (created_at)")
+	require.True(t, ok)
+	require.Equal(t, "transfers", table)
+	require.Equal(t, "add index transfers_created_at (created_at)", alter)
+
+	_, _, ok = parseAlter("create table periods(period varchar(10) primary key not null);")
+	require.False(t, ok)
+}
+
+func TestSplitMySQLAddress(t *testing.T) {
+	host, port, err := splitMySQLAddress("tcp(db.example.com:3307)")
+	require.NoError(t, err)
+	require.Equal(t, "db.example.com", host)
+	require.Equal(t, "3307", port)
+
+	host, port, err = splitMySQLAddress("localhost")
+	require.NoError(t, err)
+	require.Equal(t, "localhost", host)
+	require.Equal(t, "3306", port)
+
+	_, _, err = splitMySQLAddress("")
+	require.Error(t, err)
+}
+
+func TestMySQLMigrationRunner(t *testing.T) {
+	db := CreateTestSqliteDB(t)
+	defer db.Close()
+
+	var commands [][]string
+	var confPath, credentials string
+	var confMode os.FileMode
+	setup := func(cfg *config.MySQLMigrations, rows int64) *mysqlMigrationRunner {
+		commands = nil
+		r := newMySQLMigrationRunner(log.NewNopLogger(), cfg, "tcp(localhost:3306)", "moov", `se"cr;et`, "paygate")
+		r.tableRows = func(_ *sql.DB, _ string) (int64, error) {
+			return rows, nil
+		}
+		r.command = func(name string, args ...string) error {
+			commands = append(commands, append([]string{name}, args...))
+
+			// the credentials file only exists while the command runs
+			for i := range args {
+				if strings.HasPrefix(args[i], "--conf=") {
+					confPath = strings.TrimPrefix(args[i], "--conf=")
+					info, err := os.Stat(confPath)
+					require.NoError(t, err)
+					confMode = info.Mode().Perm()
+					bs, err := ioutil.ReadFile(confPath)
+					require.NoError(t, err)
+					credentials = string(bs)
+				}
+			}
+			return nil
+		}
+		return r
+	}
+
+	_, err := db.DB.Exec("create table large(id primary key);")
+	require.NoError(t, err)
+
+	// small tables are altered directly
+	r := setup(&config.MySQLMigrations{Production: true}, 10)
+	require.NoError(t, r.exec(db.DB, "small", "alter table large add column a;"))
+	require.Empty(t, commands)
+
+	// large tables are refused in production
+	r = setup(&config.MySQLMigrations{Production: true}, 5000000)
+	err = r.exec(db.DB, "refused", "alter table large add column b;")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "refusing to lock large")
+
+	// ...unless forced
+	r = setup(&config.MySQLMigrations{Production: true, Force: true}, 5000000)
+	require.NoError(t, r.exec(db.DB, "forced", "alter table large add column c;"))
+	require.Empty(t, commands)
+
+	// or run online
+	r = setup(&config.MySQLMigrations{
+		Production: true,
+		Online: &config.OnlineMigrations{
+			Args: []string{"--max-load=Threads_running=25"},
+		},
+	}, 5000000)
+	require.NoError(t, r.exec(db.DB, "online", "alter table large add column d varchar(10);"))
+	require.Len(t, commands, 1)
+	require.Equal(t, []string{
+		"gh-ost",
+		"--host=localhost",
+		"--port=3306",
+		"--conf=" + confPath,
+		"--database=paygate",
+		"--table=large",
+		"--alter=add column d varchar(10)",
+		"--execute",
+		"--max-load=Threads_running=25",
+	}, commands[0])
+	for i := range commands[0] {
+		require.NotContains(t, commands[0][i], "se\"cr;et")
+	}
+	require.Equal(t, os.FileMode(0600), confMode)
+	require.Equal(t, "[client]\nuser = \"moov\"\npassword = \"se\\\"cr;et\"\n", credentials)
+	_, err = os.Stat(confPath)
+	require.True(t, os.IsNotExist(err))
+
+	// without config every statement is run directly
+	r = setup(nil, 5000000)
+	require.NoError(t, r.exec(db.DB, "unguarded", "alter table large add column e;"))
+	require.Empty(t, commands)
+}
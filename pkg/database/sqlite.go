@@ -84,6 +84,374 @@ var (
 			"rename_transfers_namespace_to_organization",
 			`alter table transfers rename column namespace to organization;`,
 		),
+		execsql(
+			"add_effective_date__to__transfers",
+			`alter table transfers add column effective_date;`,
+		),
+		execsql(
+			"create_attachments",
+			`create table attachments(attachment_id primary key, organization, customer_id, account_id, kind, note, filename, content_type, size integer, created_at datetime, deleted_at datetime);`,
+		),
+		execsql(
+			"create_attachments__customer_id_idx",
+			`create index attachments_customer_id on attachments (organization, customer_id);`,
+		),
+		execsql(
+			"create_api_tokens",
+			`create table api_tokens(token_id primary key, organization, token_hash, source_customer_id, source_account_id, created_at datetime, expires_at datetime, deleted_at datetime);`,
+		),
+		execsql(
+			"create_api_tokens__token_hash_idx",
+			`create unique index api_tokens_token_hash on api_tokens (token_hash);`,
+		),
+		execsql(
+			"create_api_token_receivers",
+			`create table api_token_receivers(token_id, customer_id, account_id);`,
+		),
+		execsql(
+			"create_transfer_history",
+			`create table transfer_history(history_id primary key, transfer_id, field, old_value, new_value, actor, created_at datetime);`,
+		),
+		execsql(
+			"create_transfer_history__transfer_id_idx",
+			`create index transfer_history_transfer_id on transfer_history (transfer_id);`,
+		),
+		execsql(
+			"add_attempt__to__micro_deposits",
+			`alter table micro_deposits add column attempt integer default 1;`,
+		),
+		execsql(
+			"add_expires_at__to__micro_deposits",
+			`alter table micro_deposits add column expires_at datetime;`,
+		),
+		execsql(
+			"drop_micro_deposits__unique_account_id_idx",
+			`drop index micro_deposits_account_id;`,
+		),
+		execsql(
+			"create_micro_deposits__account_id_created_at_idx",
+			`create index micro_deposits_account_id_created_at on micro_deposits (destination_account_id, created_at);`,
+		),
+		execsql(
+			"create_unprocessed_transfers",
+			`create table unprocessed_transfers(transfer_id primary key, error, attempts integer, created_at datetime, last_attempt_at datetime);`,
+		),
+		execsql(
+			"add_funding_flow__to__organization_configs",
+			`alter table organization_configs add column funding_flow default '';`,
+		),
+		execsql(
+			"create_transfer_legs",
+			`create table transfer_legs(transfer_id, leg, status, updated_at datetime, unique(transfer_id, leg));`,
+		),
+		execsql(
+			"add_verified_at__to__micro_deposits",
+			`alter table micro_deposits add column verified_at datetime;`,
+		),
+		execsql(
+			"add_confirmation_attempts__to__micro_deposits",
+			`alter table micro_deposits add column confirmation_attempts integer default 0;`,
+		),
+		execsql(
+			"create_uploaded_files",
+			`create table uploaded_files(filename primary key, routing_number, uploaded_at datetime, acknowledged_at datetime);`,
+		),
+		execsql(
+			"create_uploaded_file_batches",
+			`create table uploaded_file_batches(filename, batch_number integer, entries integer, status, reason, unique(filename, batch_number));`,
+		),
+		execsql(
+			"add_prefer_same_day__to__organization_configs",
+			`alter table organization_configs add column prefer_same_day integer default 0;`,
+		),
+		execsql(
+			"add_same_day_preferred__to__transfers",
+			`alter table transfers add column same_day_preferred integer default 0;`,
+		),
+		execsql(
+			"add_same_day_reason__to__transfers",
+			`alter table transfers add column same_day_reason;`,
+		),
+		execsql(
+			"add_cancel_reason__to__transfers",
+			`alter table transfers add column cancel_reason;`,
+		),
+		execsql(
+			"add_cancel_note__to__transfers",
+			`alter table transfers add column cancel_note;`,
+		),
+		execsql(
+			"add_canceled_at__to__transfers",
+			`alter table transfers add column canceled_at datetime;`,
+		),
+		execsql(
+			"create_debit_kill_switches",
+			`create table debit_kill_switches(scope primary key, reason, enabled_at datetime);`,
+		),
+		execsql(
+			"create_outbound_file_sequences",
+			`create table outbound_file_sequences(routing_number, day, sequence integer, primary key (routing_number, day));`,
+		),
+		execsql(
+			"add_transfer_tags__to__organization_configs",
+			`alter table organization_configs add column transfer_tags default '';`,
+		),
+		execsql(
+			"create_transfer_tags",
+			`create table transfer_tags(transfer_id, tag, primary key (transfer_id, tag));`,
+		),
+		execsql(
+			"create_transfer_views",
+			`create table transfer_views(view_id primary key, organization, user_id, name, status default '', start_date datetime, end_date datetime, tags default '', created_at datetime, deleted_at datetime);`,
+		),
+		execsql(
+			"create_transfer_exposures",
+			`create table transfer_exposures(transfer_id, direction, organization, customer_id, user_id, amount integer, created_at datetime, primary key (transfer_id, direction));`,
+		),
+		execsql(
+			"add_sec_code__to__transfers",
+			`alter table transfers add column sec_code default '';`,
+		),
+		execsql(
+			"add_debit_amount__to__transfers",
+			`alter table transfers add column debit_amount integer default 0;`,
+		),
+		execsql(
+			"add_credit_amount__to__transfers",
+			`alter table transfers add column credit_amount integer default 0;`,
+		),
+		execsql(
+			"create_accounting_periods",
+			`create table accounting_periods(period primary key, starts_at datetime, ends_at datetime, closed_at datetime);`,
+		),
+		execsql(
+			"create_accounting_period_totals",
+			`create table accounting_period_totals(period, organization, sec_code, transfers integer, debit_count integer, debit_amount integer, credit_count integer, credit_amount integer, return_count integer, return_amount integer, primary key (period, organization, sec_code));`,
+		),
+		execsql(
+			"create_scheduled_transfers",
+			`create table scheduled_transfers(schedule_id primary key, organization, user_id, template, frequency, status, start_date datetime, end_date datetime, next_run_at datetime, run_count integer default 0, last_run_at datetime, last_transfer_id, last_error, created_at datetime);`,
+		),
+		execsql(
+			"create_prenotes",
+			`create table prenotes(prenote_id primary key, organization, destination_customer_id, destination_account_id, transfer_id, status, return_code, change_code, verified_at datetime, created_at datetime, deleted_at datetime);`,
+		),
+		execsql(
+			"create_notification_preferences",
+			`create table notification_preferences(organization, event_type, channel, email, updated_at datetime, primary key (organization, event_type));`,
+		),
+		execsql(
+			"add_amount_value_encrypted__to__micro_deposit_amounts",
+			`alter table micro_deposit_amounts add column amount_value_encrypted;`,
+		),
+		execsql(
+			"create_inbound_records",
+			`create table inbound_records(record_id primary key, transfer_id, record_type, code, trace_number, original_trace, immediate_origin, immediate_destination, entry_detail, addenda, created_at datetime);`,
+		),
+		execsql(
+			"create_inbound_records__transfer_id_idx",
+			`create index inbound_records_transfer_id on inbound_records (transfer_id);`,
+		),
+		execsql(
+			"create_limit_overrides",
+			`create table limit_overrides(scope, scope_id, soft_limit integer, hard_limit integer, debit_exposure integer, credit_exposure integer, updated_at datetime, primary key (scope, scope_id));`,
+		),
+		execsql(
+			"add_remote_server__to__uploaded_files",
+			`alter table uploaded_files add column remote_server;`,
+		),
+		execsql(
+			"create_uploaded_file_entries",
+			`create table uploaded_file_entries(filename, batch_number integer, trace_number, entry_detail, unique(filename, trace_number));`,
+		),
+		execsql(
+			"create_uploaded_file_entries__trace_number_idx",
+			`create index uploaded_file_entries_trace_number on uploaded_file_entries (trace_number);`,
+		),
+		execsql(
+			"create_transfer_reversals",
+			`create table transfer_reversals(transfer_id primary key, reversal_id, created_at datetime);`,
+		),
+		execsql(
+			"create_transfer_reversals__reversal_id_idx",
+			`create unique index transfer_reversals_reversal_id on transfer_reversals (reversal_id);`,
+		),
+		execsql(
+			"create_daily_originations",
+			`create table daily_originations(routing_number, day, amount integer, primary key (routing_number, day));`,
+		),
+		execsql(
+			"create_transfer_batches",
+			`create table transfer_batches(batch_id primary key, organization, idempotency_key, created_at datetime);`,
+		),
+		execsql(
+			"create_transfer_batches__idempotency_key_idx",
+			`create unique index transfer_batches_idempotency_key on transfer_batches (organization, idempotency_key);`,
+		),
+		execsql(
+			"create_debit_authorizations",
+			`create table debit_authorizations(authorization_id primary key, organization, customer_id, account_id, authorized_at datetime, method, amount_currency, amount_value integer, frequency, terms, status, revoked_at datetime, revocation_reason, created_at datetime);`,
+		),
+		execsql(
+			"create_debit_authorizations__account_idx",
+			`create index debit_authorizations_account on debit_authorizations (organization, customer_id, account_id);`,
+		),
+		execsql(
+			"create_account_corrections",
+			`create table account_corrections(correction_id primary key, organization, customer_id, account_id, transfer_id, change_code, status, created_at datetime, reviewed_at datetime, reviewed_by);`,
+		),
+		execsql(
+			"create_account_corrections__account_idx",
+			`create index account_corrections_account on account_corrections (organization, customer_id, account_id);`,
+		),
+		execsql(
+			"create_account_correction_changes",
+			`create table account_correction_changes(correction_id, field, old_value, new_value);`,
+		),
+		execsql(
+			"create_account_correction_changes__correction_id_idx",
+			`create index account_correction_changes_correction_id on account_correction_changes (correction_id);`,
+		),
+		execsql(
+			"create_ofac_matches",
+			`create table ofac_matches(organization, customer_id, entity_id, sdn_name, match_score real, status, matched_at datetime, screened_at datetime, reviewed_at datetime, reviewed_by, primary key (organization, customer_id));`,
+		),
+		execsql(
+			"create_ambiguous_returns",
+			`create table ambiguous_returns(record_id primary key, status, transfer_id, resolved_at datetime, created_at datetime);`,
+		),
+		execsql(
+			"create_ambiguous_return_candidates",
+			`create table ambiguous_return_candidates(record_id, transfer_id, confidence real, primary key (record_id, transfer_id));`,
+		),
+		execsql(
+			"add_correlation_id__to__transfers",
+			`alter table transfers add column correlation_id default '';`,
+		),
+		execsql(
+			"add_iat_detail__to__transfers",
+			`alter table transfers add column iat_detail default '';`,
+		),
+		execsql(
+			"create_erasure_jobs",
+			`create table erasure_jobs(job_id primary key, organization, customer_id, status, requested_by, error, created_at datetime, completed_at datetime);`,
+		),
+		execsql(
+			"create_erasure_job_items",
+			`create table erasure_job_items(job_id, table_name, fields, action, row_count integer);`,
+		),
+		execsql(
+			"create_micro_deposit_confirmations",
+			`create table micro_deposit_confirmations(micro_deposit_id, matched boolean, created_at datetime);`,
+		),
+		execsql(
+			"create_audit_records",
+			`create table audit_records(record_id primary key, organization, user_id, request_id, remote_address, method, endpoint, path, resource, status_code integer, body, created_at datetime);`,
+		),
+		execsql(
+			"add_size_bytes__to__uploaded_files",
+			`alter table uploaded_files add column size_bytes integer;`,
+		),
+		execsql(
+			"add_checksum__to__uploaded_files",
+			`alter table uploaded_files add column checksum;`,
+		),
+		execsql(
+			"add_remote_path__to__uploaded_files",
+			`alter table uploaded_files add column remote_path;`,
+		),
+		execsql(
+			"create_trace_number_sequences",
+			`create table trace_number_sequences(routing_number primary key, sequence integer);`,
+		),
+		execsql(
+			"create_email_verifications",
+			`create table email_verifications(organization, customer_id, email, verified_at datetime, primary key (organization, customer_id));`,
+		),
+		execsql(
+			"create_death_notifications",
+			`create table death_notifications(organization, customer_id, account_id, trace_number, date_of_death datetime, received_at datetime, primary key (organization, customer_id, account_id));`,
+		),
+		execsql(
+			"create_transfer_idempotency_keys",
+			`create table transfer_idempotency_keys(organization, user_id, idempotency_key, transfer_id, response, created_at datetime, primary key (organization, user_id, idempotency_key));`,
+		),
+		execsql(
+			"add_processed_event_at__to__micro_deposits",
+			`alter table micro_deposits add column processed_event_at datetime;`,
+		),
+		execsql(
+			"add_expired_event_at__to__micro_deposits",
+			`alter table micro_deposits add column expired_event_at datetime;`,
+		),
+		execsql(
+			"backfill_processed_event_at__on__micro_deposits",
+			`update micro_deposits set processed_event_at = processed_at where processed_at is not null;`,
+		),
+		execsql(
+			"backfill_expired_event_at__on__micro_deposits",
+			`update micro_deposits set expired_event_at = expires_at where expires_at < current_timestamp;`,
+		),
+		execsql(
+			"create_uploaded_files_by_id",
+			`create table uploaded_files_by_id(file_id primary key, filename, routing_number, uploaded_at datetime, acknowledged_at datetime, remote_server, size_bytes integer, checksum, remote_path);`,
+		),
+		execsql(
+			"copy_uploaded_files__to__uploaded_files_by_id",
+			`insert into uploaded_files_by_id(file_id, filename, routing_number, uploaded_at, acknowledged_at, remote_server, size_bytes, checksum, remote_path) select filename, filename, routing_number, uploaded_at, acknowledged_at, remote_server, size_bytes, checksum, remote_path from uploaded_files;`,
+		),
+		execsql(
+			"drop_uploaded_files",
+			`drop table uploaded_files;`,
+		),
+		execsql(
+			"rename_uploaded_files_by_id__to__uploaded_files",
+			`alter table uploaded_files_by_id rename to uploaded_files;`,
+		),
+		execsql(
+			"create_uploaded_files__filename_idx",
+			`create index uploaded_files_filename on uploaded_files (filename);`,
+		),
+		execsql(
+			"create_uploaded_file_batches_by_id",
+			`create table uploaded_file_batches_by_id(file_id, batch_number integer, entries integer, status, reason, unique(file_id, batch_number));`,
+		),
+		execsql(
+			"copy_uploaded_file_batches__to__uploaded_file_batches_by_id",
+			`insert into uploaded_file_batches_by_id(file_id, batch_number, entries, status, reason) select filename, batch_number, entries, status, reason from uploaded_file_batches;`,
+		),
+		execsql(
+			"drop_uploaded_file_batches",
+			`drop table uploaded_file_batches;`,
+		),
+		execsql(
+			"rename_uploaded_file_batches_by_id__to__uploaded_file_batches",
+			`alter table uploaded_file_batches_by_id rename to uploaded_file_batches;`,
+		),
+		execsql(
+			"create_uploaded_file_entries_by_id",
+			`create table uploaded_file_entries_by_id(file_id, batch_number integer, trace_number, entry_detail, unique(file_id, trace_number));`,
+		),
+		execsql(
+			"copy_uploaded_file_entries__to__uploaded_file_entries_by_id",
+			`insert into uploaded_file_entries_by_id(file_id, batch_number, trace_number, entry_detail) select filename, batch_number, trace_number, entry_detail from uploaded_file_entries;`,
+		),
+		execsql(
+			"drop_uploaded_file_entries",
+			`drop table uploaded_file_entries;`,
+		),
+		execsql(
+			"rename_uploaded_file_entries_by_id__to__uploaded_file_entries",
+			`alter table uploaded_file_entries_by_id rename to uploaded_file_entries;`,
+		),
+		execsql(
+			"create_uploaded_file_entries__trace_number_idx__by_id",
+			`create index uploaded_file_entries_trace_number on uploaded_file_entries (trace_number);`,
+		),
+		execsql(
+			"add_response__to__transfer_batches",
+			`alter table transfer_batches add column response;`,
+		),
 	)
 )
 
@@ -0,0 +1,149 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+// Package hooks calls HTTP endpoints at points in a Transfer's lifecycle so deployments
+// can add their own business rules and enrichment without changing PayGate.
+//
+// Each hook receives a Request as JSON and responds with a Response. Hooks for a point
+// are called in the order they're configured and the first to reject stops the rest.
+package hooks
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/moov-io/ach"
+	"github.com/moov-io/base/log"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+)
+
+var ErrRejected = errors.New("rejected by hook")
+
+// Request is sent to each hook. Which fields are set depends on the point.
+type Request struct {
+	Point        string `json:"point"`
+	Organization string `json:"organization,omitempty"`
+
+	// Transfer is set for preTransferCreate
+	Transfer *client.Transfer `json:"transfer,omitempty"`
+
+	// TransferID is set for preMerge and postReturn
+	TransferID string `json:"transferID,omitempty"`
+
+	// Filename is set for preUpload
+	Filename string `json:"filename,omitempty"`
+
+	// File is set for preMerge and preUpload
+	File *ach.File `json:"file,omitempty"`
+
+	// ReturnCode is set for postReturn
+	ReturnCode string `json:"returnCode,omitempty"`
+}
+
+// Response is read from each hook. An empty response accepts the Request.
+type Response struct {
+	Reject bool   `json:"reject"`
+	Reason string `json:"reason,omitempty"`
+
+	// Description and Tags replace those of the Transfer at preTransferCreate when set.
+	Description string   `json:"description,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+}
+
+// Runner calls the hooks configured for each point.
+//
+// A nil *Runner accepts everything.
+type Runner struct {
+	logger log.Logger
+	hooks  map[string][]*httpHook
+}
+
+// New returns nil when no hooks are configured.
+func New(cfg *config.Config) (*Runner, error) {
+	if len(cfg.Hooks) == 0 {
+		return nil, nil
+	}
+	r := &Runner{
+		logger: cfg.Logger.Set("service", "hooks"),
+		hooks:  make(map[string][]*httpHook),
+	}
+	for i := range cfg.Hooks {
+		if err := cfg.Hooks[i].Validate(); err != nil {
+			return nil, fmt.Errorf("hook %s: %v", cfg.Hooks[i].Name, err)
+		}
+		hook, err := newHTTPHook(cfg.Hooks[i])
+		if err != nil {
+			return nil, fmt.Errorf("hook %s: %v", cfg.Hooks[i].Name, err)
+		}
+		point := cfg.Hooks[i].Point
+		r.hooks[point] = append(r.hooks[point], hook)
+	}
+	return r, nil
+}
+
+// Run calls each hook for req.Point and combines their enrichment. An error wrapping
+// ErrRejected is returned when a hook rejects, or fails with the reject failure policy.
+func (r *Runner) Run(req Request) (*Response, error) {
+	out := &Response{}
+	if r == nil {
+		return out, nil
+	}
+	for _, hook := range r.hooks[req.Point] {
+		start := time.Now()
+		resp, err := hook.call(req)
+		hookDuration.With("hook", hook.cfg.Name, "point", req.Point).Observe(time.Since(start).Seconds())
+
+		if err != nil {
+			recordOutcome(hook.cfg.Name, req.Point, outcomeFailed)
+			logger := r.logger.With(log.Fields{
+				"hook":  hook.cfg.Name,
+				"point": req.Point,
+			})
+			if hook.cfg.RejectOnFailure() {
+				logger.LogErrorf("rejecting after hook failure: %v", err)
+				return nil, fmt.Errorf("hook %s: %w: %v", hook.cfg.Name, ErrRejected, err)
+			}
+			logger.LogErrorf("ignoring hook failure: %v", err)
+			continue
+		}
+		if resp.Reject {
+			recordOutcome(hook.cfg.Name, req.Point, outcomeRejected)
+			reason := resp.Reason
+			if reason == "" {
+				reason = "no reason given"
+			}
+			return nil, fmt.Errorf("hook %s: %w: %s", hook.cfg.Name, ErrRejected, reason)
+		}
+		recordOutcome(hook.cfg.Name, req.Point, outcomeAccepted)
+
+		if resp.Description != "" {
+			out.Description = resp.Description
+		}
+		if len(resp.Tags) > 0 {
+			out.Tags = resp.Tags
+		}
+	}
+	return out, nil
+}
+
+// HoldTransfer keeps Transfers rejected by a preMerge hook out of the merged files
+// until the next cutoff, where they're offered to the hooks again.
+func (r *Runner) HoldTransfer(transferID string, file *ach.File) (bool, error) {
+	if r == nil || len(r.hooks[config.HookPreMerge]) == 0 {
+		return false, nil
+	}
+	_, err := r.Run(Request{
+		Point:      config.HookPreMerge,
+		TransferID: transferID,
+		File:       file,
+	})
+	if err != nil {
+		r.logger.Set("transferID", transferID).Logf("holding transfer: %v", err)
+		return true, nil
+	}
+	return false, nil
+}
@@ -0,0 +1,165 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package hooks
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/moov-io/ach"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+
+	"github.com/stretchr/testify/require"
+)
+
+func hookServer(t *testing.T, fn func(req Request) (int, *Response)) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req Request
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		status, resp := fn(req)
+		w.WriteHeader(status)
+		if resp != nil {
+			json.NewEncoder(w).Encode(resp)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func setupRunner(t *testing.T, hooks ...config.Hook) *Runner {
+	t.Helper()
+
+	cfg := config.Empty()
+	cfg.Hooks = hooks
+	runner, err := New(cfg)
+	require.NoError(t, err)
+	return runner
+}
+
+func TestRunner__nil(t *testing.T) {
+	runner, err := New(config.Empty())
+	require.NoError(t, err)
+	require.Nil(t, runner)
+
+	resp, err := runner.Run(Request{Point: config.HookPreTransferCreate})
+	require.NoError(t, err)
+	require.False(t, resp.Reject)
+
+	held, err := runner.HoldTransfer("transferID", nil)
+	require.NoError(t, err)
+	require.False(t, held)
+
+	cfg := config.Empty()
+	cfg.Hooks = []config.Hook{{Name: "other", Point: "unknown"}}
+	_, err = New(cfg)
+	require.Error(t, err)
+}
+
+func TestRunner__enrichment(t *testing.T) {
+	enrich := hookServer(t, func(req Request) (int, *Response) {
+		if req.Point != config.HookPreTransferCreate || req.Transfer == nil || req.Organization != "moov" {
+			return http.StatusBadRequest, nil
+		}
+		return http.StatusOK, &Response{Description: "payroll: " + req.Transfer.Description}
+	})
+	tags := hookServer(t, func(req Request) (int, *Response) {
+		return http.StatusOK, &Response{Tags: []string{"payroll"}}
+	})
+	runner := setupRunner(t,
+		config.Hook{Name: "enrich", Point: config.HookPreTransferCreate, Endpoint: enrich.URL},
+		config.Hook{Name: "tags", Point: config.HookPreTransferCreate, Endpoint: tags.URL},
+		config.Hook{Name: "unused", Point: config.HookPreUpload, Endpoint: "http://localhost:1"},
+	)
+
+	resp, err := runner.Run(Request{
+		Point:        config.HookPreTransferCreate,
+		Organization: "moov",
+		Transfer:     &client.Transfer{Description: "june"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "payroll: june", resp.Description)
+	require.Equal(t, []string{"payroll"}, resp.Tags)
+}
+
+func TestRunner__rejected(t *testing.T) {
+	called := false
+	reject := hookServer(t, func(req Request) (int, *Response) {
+		return http.StatusOK, &Response{Reject: true, Reason: "velocity check failed"}
+	})
+	after := hookServer(t, func(req Request) (int, *Response) {
+		called = true
+		return http.StatusOK, nil
+	})
+	runner := setupRunner(t,
+		config.Hook{Name: "velocity", Point: config.HookPreTransferCreate, Endpoint: reject.URL},
+		config.Hook{Name: "after", Point: config.HookPreTransferCreate, Endpoint: after.URL},
+	)
+
+	_, err := runner.Run(Request{Point: config.HookPreTransferCreate})
+	require.True(t, errors.Is(err, ErrRejected))
+	require.Contains(t, err.Error(), "velocity check failed")
+	require.False(t, called)
+}
+
+func TestRunner__failurePolicy(t *testing.T) {
+	broken := hookServer(t, func(req Request) (int, *Response) {
+		return http.StatusInternalServerError, nil
+	})
+	slow := hookServer(t, func(req Request) (int, *Response) {
+		time.Sleep(100 * time.Millisecond)
+		return http.StatusOK, nil
+	})
+
+	// failures are ignored by default
+	runner := setupRunner(t,
+		config.Hook{Name: "broken", Point: config.HookPostReturn, Endpoint: broken.URL},
+		config.Hook{Name: "slow", Point: config.HookPostReturn, Endpoint: slow.URL, Timeout: 10 * time.Millisecond},
+	)
+	_, err := runner.Run(Request{Point: config.HookPostReturn, TransferID: "xfer", ReturnCode: "R01"})
+	require.NoError(t, err)
+
+	runner = setupRunner(t, config.Hook{
+		Name:          "slow",
+		Point:         config.HookPostReturn,
+		Endpoint:      slow.URL,
+		Timeout:       10 * time.Millisecond,
+		FailurePolicy: config.HookFailReject,
+	})
+	_, err = runner.Run(Request{Point: config.HookPostReturn})
+	require.True(t, errors.Is(err, ErrRejected))
+}
+
+func TestRunner__HoldTransfer(t *testing.T) {
+	file, err := ach.ReadFile(filepath.Join("..", "..", "testdata", "ppd-debit.ach"))
+	require.NoError(t, err)
+
+	server := hookServer(t, func(req Request) (int, *Response) {
+		if req.File == nil || len(req.File.Batches) != 1 {
+			return http.StatusBadRequest, nil
+		}
+		return http.StatusOK, &Response{Reject: req.TransferID == "held"}
+	})
+	runner := setupRunner(t, config.Hook{Name: "merge", Point: config.HookPreMerge, Endpoint: server.URL})
+
+	held, err := runner.HoldTransfer("held", file)
+	require.NoError(t, err)
+	require.True(t, held)
+
+	held, err = runner.HoldTransfer("sent", file)
+	require.NoError(t, err)
+	require.False(t, held)
+}
@@ -0,0 +1,69 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package hooks
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/moov-io/paygate"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/x/httpclient"
+)
+
+// maxResponseSize limits how much of a hook's response is read
+const maxResponseSize = 1024 * 1024
+
+type httpHook struct {
+	cfg      config.Hook
+	client   *http.Client
+	endpoint string
+}
+
+func newHTTPHook(cfg config.Hook) (*httpHook, error) {
+	client, err := httpclient.New(cfg.HTTPClient, cfg.RequestTimeout())
+	if err != nil {
+		return nil, err
+	}
+	return &httpHook{
+		cfg:      cfg,
+		client:   client,
+		endpoint: strings.TrimSpace(cfg.Endpoint),
+	}, nil
+}
+
+func (h *httpHook) call(request Request) (*Response, error) {
+	var body bytes.Buffer
+	if err := json.NewEncoder(&body).Encode(request); err != nil {
+		return nil, fmt.Errorf("encode: %v", err)
+	}
+
+	req, err := http.NewRequest("POST", h.endpoint, &body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", fmt.Sprintf("moov/paygate %v hooks", paygate.Version))
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	var out Response
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize)).Decode(&out); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("decode: %v", err)
+	}
+	return &out, nil
+}
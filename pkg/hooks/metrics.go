@@ -0,0 +1,33 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package hooks
+
+import (
+	"github.com/go-kit/kit/metrics/prometheus"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	hookCalls = prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+		Name: "hook_calls",
+		Help: "Counter of lifecycle hook calls by their outcome",
+	}, []string{"hook", "point", "outcome"})
+
+	hookDuration = prometheus.NewHistogramFrom(stdprometheus.HistogramOpts{
+		Name:    "hook_duration_seconds",
+		Help:    "Histogram of how long lifecycle hooks took to respond",
+		Buckets: []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10},
+	}, []string{"hook", "point"})
+)
+
+const (
+	outcomeAccepted = "accepted"
+	outcomeRejected = "rejected"
+	outcomeFailed   = "failed"
+)
+
+func recordOutcome(hook, point, outcome string) {
+	hookCalls.With("hook", hook, "point", point, "outcome", outcome).Add(1)
+}
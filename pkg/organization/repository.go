@@ -7,6 +7,7 @@ package organization
 import (
 	"database/sql"
 	"fmt"
+	"strings"
 
 	"github.com/moov-io/paygate/pkg/client"
 )
@@ -32,7 +33,7 @@ func (r *sqlRepo) Close() error {
 }
 
 func (r *sqlRepo) GetConfig(orgID string) (*client.OrganizationConfiguration, error) {
-	query := `select company_identification from organization_configs where organization = ? limit 1;`
+	query := `select company_identification, funding_flow, prefer_same_day, transfer_tags from organization_configs where organization = ? limit 1;`
 	stmt, err := r.db.Prepare(query)
 	if err != nil {
 		return nil, err
@@ -40,24 +41,28 @@ func (r *sqlRepo) GetConfig(orgID string) (*client.OrganizationConfiguration, er
 	defer stmt.Close()
 
 	var cfg client.OrganizationConfiguration
-	if err := stmt.QueryRow(orgID).Scan(&cfg.CompanyIdentification); err != nil {
+	var tags string
+	if err := stmt.QueryRow(orgID).Scan(&cfg.CompanyIdentification, &cfg.FundingFlow, &cfg.PreferSameDay, &tags); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
 		}
 		return nil, err
 	}
+	if tags != "" {
+		cfg.TransferTags = strings.Split(tags, ",")
+	}
 	return &cfg, nil
 }
 
 func (r *sqlRepo) UpdateConfig(orgID string, cfg *client.OrganizationConfiguration) (*client.OrganizationConfiguration, error) {
-	query := `replace into organization_configs (organization, company_identification) values (?, ?);`
+	query := `replace into organization_configs (organization, company_identification, funding_flow, prefer_same_day, transfer_tags) values (?, ?, ?, ?, ?);`
 	stmt, err := r.db.Prepare(query)
 	if err != nil {
 		return nil, fmt.Errorf("config: organization does not belong: %v", err)
 	}
 	defer stmt.Close()
 
-	_, err = stmt.Exec(orgID, cfg.CompanyIdentification)
+	_, err = stmt.Exec(orgID, cfg.CompanyIdentification, cfg.FundingFlow, cfg.PreferSameDay, strings.Join(cfg.TransferTags, ","))
 	if err != nil {
 		return nil, fmt.Errorf("config: issue updating config: %v", err)
 	}
@@ -8,6 +8,7 @@ import (
 	"testing"
 
 	"github.com/moov-io/base"
+	"github.com/moov-io/paygate/pkg/client"
 	"github.com/moov-io/paygate/pkg/database"
 )
 
@@ -73,3 +74,34 @@ func TestRepository__GetConfig(t *testing.T) {
 	check(t, setupSQLiteDB(t))
 	check(t, setupMySQLeDB(t))
 }
+
+func TestRepository__UpdateConfig(t *testing.T) {
+	t.Parallel()
+
+	check := func(t *testing.T, repo *sqlRepo) {
+		orgID := base.ID()
+
+		_, err := repo.UpdateConfig(orgID, &client.OrganizationConfiguration{
+			CompanyIdentification: "foo",
+			PreferSameDay:         true,
+			TransferTags:          []string{"June payroll", "chargeback-retry"},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		cfg, err := repo.GetConfig(orgID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg == nil || !cfg.PreferSameDay {
+			t.Fatalf("unexpected config: %#v", cfg)
+		}
+		if len(cfg.TransferTags) != 2 || cfg.TransferTags[1] != "chargeback-retry" {
+			t.Fatalf("unexpected tags: %#v", cfg.TransferTags)
+		}
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+}
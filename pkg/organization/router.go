@@ -13,6 +13,7 @@ import (
 	"github.com/gorilla/mux"
 	moovhttp "github.com/moov-io/base/http"
 	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/transfers/fundflow"
 	"github.com/moov-io/paygate/x/route"
 )
 
@@ -63,6 +64,14 @@ func updateConfig(repo Repository) http.HandlerFunc {
 			moovhttp.Problem(w, err)
 			return
 		}
+		if _, err := fundflow.ParseFlow(body.FundingFlow); err != nil {
+			moovhttp.Problem(w, err)
+			return
+		}
+		if err := ValidateTags(body.TransferTags); err != nil {
+			moovhttp.Problem(w, fmt.Errorf("transferTags: %v", err))
+			return
+		}
 
 		cfg, err := repo.UpdateConfig(organization, &body)
 		if err != nil {
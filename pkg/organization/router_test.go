@@ -142,3 +142,39 @@ func TestUpdateConfigMissingCompany(t *testing.T) {
 
 	require.Equal(t, w.Code, http.StatusBadRequest)
 }
+
+func TestUpdateConfigUnknownFundingFlow(t *testing.T) {
+	var body bytes.Buffer
+	json.NewEncoder(&body).Encode(&client.OrganizationConfiguration{
+		CompanyIdentification: base.ID(),
+		FundingFlow:           "other",
+	})
+	req := httptest.NewRequest("PUT", "/configuration/transfers", &body)
+	req.Header.Set("X-Organization", "moov")
+	w := httptest.NewRecorder()
+
+	router := mux.NewRouter()
+	NewRouter(orgRepo).RegisterRoutes(router)
+	router.ServeHTTP(w, req)
+	w.Flush()
+
+	require.Equal(t, w.Code, http.StatusBadRequest)
+}
+
+func TestUpdateConfigInvalidTags(t *testing.T) {
+	var body bytes.Buffer
+	json.NewEncoder(&body).Encode(&client.OrganizationConfiguration{
+		CompanyIdentification: base.ID(),
+		TransferTags:          []string{"payroll", "a,b"},
+	})
+	req := httptest.NewRequest("PUT", "/configuration/transfers", &body)
+	req.Header.Set("X-Organization", "moov")
+	w := httptest.NewRecorder()
+
+	router := mux.NewRouter()
+	NewRouter(orgRepo).RegisterRoutes(router)
+	router.ServeHTTP(w, req)
+	w.Flush()
+
+	require.Equal(t, w.Code, http.StatusBadRequest)
+}
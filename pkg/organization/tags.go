@@ -0,0 +1,33 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package organization
+
+import (
+	"fmt"
+	"regexp"
+)
+
+const maxTagLength = 40
+
+var tagPattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9 _.-]*$`)
+
+// ValidateTags checks each tag is unique and only uses letters, numbers, spaces,
+// underscores, dashes or periods.
+func ValidateTags(tags []string) error {
+	seen := make(map[string]bool)
+	for i := range tags {
+		if len(tags[i]) > maxTagLength {
+			return fmt.Errorf("tag %q is longer than %d characters", tags[i], maxTagLength)
+		}
+		if !tagPattern.MatchString(tags[i]) {
+			return fmt.Errorf("invalid tag %q", tags[i])
+		}
+		if seen[tags[i]] {
+			return fmt.Errorf("duplicate tag %q", tags[i])
+		}
+		seen[tags[i]] = true
+	}
+	return nil
+}
@@ -0,0 +1,23 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package organization
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateTags(t *testing.T) {
+	require.NoError(t, ValidateTags(nil))
+	require.NoError(t, ValidateTags([]string{"June payroll", "chargeback-retry", "q2.2020"}))
+
+	require.Error(t, ValidateTags([]string{""}))
+	require.Error(t, ValidateTags([]string{"a,b"}))
+	require.Error(t, ValidateTags([]string{" leading"}))
+	require.Error(t, ValidateTags([]string{"payroll", "payroll"}))
+	require.Error(t, ValidateTags([]string{strings.Repeat("a", 41)}))
+}
@@ -0,0 +1,62 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package seed
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/moov-io/base/admin"
+	moovhttp "github.com/moov-io/base/http"
+
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/x/adminauth"
+)
+
+const maxFixturesSize = 1024 * 1024
+
+// RegisterAdminRoutes adds 'POST /seed' which loads YAML fixtures from the request body.
+func RegisterAdminRoutes(cfg *config.Config, svc *admin.Server, loader *Loader) {
+	if cfg.Seed == nil || loader == nil {
+		return
+	}
+	svc.AddHandler("/seed", adminauth.Protect(cfg.Admin.Signing, loadFixtures(loader)))
+}
+
+func problem(w http.ResponseWriter, err error) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	moovhttp.Problem(w, err)
+}
+
+func loadFixtures(loader *Loader) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			problem(w, fmt.Errorf("invalid method %s", r.Method))
+			return
+		}
+
+		bs, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxFixturesSize))
+		if err != nil {
+			problem(w, err)
+			return
+		}
+		fx, err := Read(bs)
+		if err != nil {
+			problem(w, err)
+			return
+		}
+		result, err := loader.Load(fx)
+		if err != nil {
+			problem(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(result)
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package seed
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/spf13/viper"
+
+	"github.com/moov-io/paygate/pkg/client"
+)
+
+// Fixtures are the records loaded into PayGate for demo and test environments.
+//
+// Customers and their Accounts are owned by the Customers service and need to be
+// created there first. Fixtures only contain what PayGate stores itself.
+type Fixtures struct {
+	Organizations []Organization
+}
+
+type Organization struct {
+	// ID is the value sent in the organization header (X-Organization by default)
+	ID string
+
+	// CompanyIdentification is saved as the organization's configuration when non-empty
+	CompanyIdentification string
+
+	Tokens []Token
+}
+
+type Token struct {
+	// Secret is the Bearer token, which must start with "pgt_". Using a fixed value
+	// lets scripts and test suites authenticate without reading it back.
+	Secret string
+
+	Source    client.Source
+	Receivers []client.Destination
+}
+
+func (fx *Fixtures) Validate() error {
+	if fx == nil {
+		return errors.New("missing Fixtures")
+	}
+	for i := range fx.Organizations {
+		org := fx.Organizations[i]
+		if org.ID == "" {
+			return fmt.Errorf("organizations[%d]: missing id", i)
+		}
+		for j := range org.Tokens {
+			if org.Tokens[j].Secret == "" {
+				return fmt.Errorf("organizations[%d].tokens[%d]: missing secret", i, j)
+			}
+		}
+	}
+	return nil
+}
+
+// Read parses YAML fixtures in the same way as PayGate's config file.
+func Read(data []byte) (*Fixtures, error) {
+	vip := viper.New()
+	vip.SetConfigType("yaml")
+	if err := vip.ReadConfig(bytes.NewReader(data)); err != nil {
+		return nil, fmt.Errorf("problem reading fixtures: %v", err)
+	}
+
+	var fx Fixtures
+	if err := vip.Unmarshal(&fx); err != nil {
+		return nil, fmt.Errorf("problem unmarshaling fixtures: %v", err)
+	}
+	if err := fx.Validate(); err != nil {
+		return nil, err
+	}
+	return &fx, nil
+}
+
+func ReadFile(path string) (*Fixtures, error) {
+	bs, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("seed: read %s: %v", path, err)
+	}
+	return Read(bs)
+}
@@ -0,0 +1,41 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package seed
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFixtures__ReadFile(t *testing.T) {
+	fx, err := ReadFile(filepath.Join("..", "..", "testdata", "seed.yaml"))
+	require.NoError(t, err)
+	require.Len(t, fx.Organizations, 2)
+
+	org := fx.Organizations[0]
+	require.Equal(t, "moov", org.ID)
+	require.Equal(t, "MoovZZZZZZ", org.CompanyIdentification)
+	require.Len(t, org.Tokens, 1)
+	require.Equal(t, "source-customer", org.Tokens[0].Source.CustomerID)
+	require.Equal(t, "source-account", org.Tokens[0].Source.AccountID)
+	require.Len(t, org.Tokens[0].Receivers, 1)
+	require.Equal(t, "receiver-account", org.Tokens[0].Receivers[0].AccountID)
+
+	_, err = ReadFile(filepath.Join("..", "..", "testdata", "missing.yaml"))
+	require.Error(t, err)
+}
+
+func TestFixtures__Validate(t *testing.T) {
+	var fx *Fixtures
+	require.Error(t, fx.Validate())
+
+	_, err := Read([]byte("organizations:\n  - companyIdentification: foo\n"))
+	require.Error(t, err)
+
+	_, err = Read([]byte("organizations:\n  - id: moov\n    tokens:\n      - source:\n          customerID: foo\n"))
+	require.Error(t, err)
+}
@@ -0,0 +1,85 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package seed
+
+import (
+	"fmt"
+
+	"github.com/moov-io/base/log"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/organization"
+	"github.com/moov-io/paygate/pkg/tokens"
+)
+
+// Loader writes Fixtures into PayGate's database. Loading the same fixtures
+// multiple times is safe as existing records are left as-is.
+type Loader struct {
+	logger    log.Logger
+	tokensCfg *config.Tokens
+
+	orgRepo   organization.Repository
+	tokenRepo tokens.Repository
+}
+
+func NewLoader(cfg *config.Config, orgRepo organization.Repository, tokenRepo tokens.Repository) *Loader {
+	return &Loader{
+		logger:    cfg.Logger.Set("package", "seed"),
+		tokensCfg: cfg.Tokens,
+		orgRepo:   orgRepo,
+		tokenRepo: tokenRepo,
+	}
+}
+
+// Result summarizes what was loaded from a set of Fixtures.
+type Result struct {
+	Organizations int `json:"organizations"`
+	Tokens        int `json:"tokens"`
+}
+
+func (l *Loader) Load(fx *Fixtures) (*Result, error) {
+	if err := fx.Validate(); err != nil {
+		return nil, err
+	}
+
+	result := &Result{}
+	for i := range fx.Organizations {
+		org := fx.Organizations[i]
+		logger := l.logger.Set("organization", org.ID)
+
+		if org.CompanyIdentification != "" {
+			_, err := l.orgRepo.UpdateConfig(org.ID, &client.OrganizationConfiguration{
+				CompanyIdentification: org.CompanyIdentification,
+			})
+			if err != nil {
+				return result, fmt.Errorf("organization %s: %v", org.ID, err)
+			}
+		}
+		result.Organizations++
+
+		for j := range org.Tokens {
+			token, err := tokens.Seed(l.tokensCfg, l.tokenRepo, org.ID, org.Tokens[j].Secret, client.CreateAPIToken{
+				Source:    org.Tokens[j].Source,
+				Receivers: org.Tokens[j].Receivers,
+			})
+			if err != nil {
+				return result, fmt.Errorf("organization %s tokens[%d]: %v", org.ID, j, err)
+			}
+			logger.Set("tokenID", token.TokenID).Log("seeded token")
+			result.Tokens++
+		}
+	}
+	l.logger.Logf("loaded %d organizations and %d tokens", result.Organizations, result.Tokens)
+	return result, nil
+}
+
+func (l *Loader) LoadFile(path string) (*Result, error) {
+	fx, err := ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return l.Load(fx)
+}
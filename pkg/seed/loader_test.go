@@ -0,0 +1,90 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package seed
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/database"
+	"github.com/moov-io/paygate/pkg/organization"
+	"github.com/moov-io/paygate/pkg/tokens"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testLoader(t *testing.T) (*Loader, organization.Repository) {
+	db := database.CreateTestSqliteDB(t)
+	t.Cleanup(func() { db.Close() })
+
+	cfg := config.Empty()
+	cfg.Tokens = &config.Tokens{MaxAmount: 1000}
+
+	orgRepo := organization.NewRepo(db.DB)
+	return NewLoader(cfg, orgRepo, tokens.NewRepo(db.DB)), orgRepo
+}
+
+func TestLoader(t *testing.T) {
+	loader, orgRepo := testLoader(t)
+
+	path := filepath.Join("..", "..", "testdata", "seed.yaml")
+	result, err := loader.LoadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, 2, result.Organizations)
+	require.Equal(t, 1, result.Tokens)
+
+	orgCfg, err := orgRepo.GetConfig("acme")
+	require.NoError(t, err)
+	require.Equal(t, "AcmeCorp01", orgCfg.CompanyIdentification)
+
+	// loading again doesn't fail or duplicate tokens
+	result, err = loader.LoadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, 1, result.Tokens)
+}
+
+func TestLoader__tokensDisabled(t *testing.T) {
+	loader, _ := testLoader(t)
+	loader.tokensCfg = nil
+
+	_, err := loader.LoadFile(filepath.Join("..", "..", "testdata", "seed.yaml"))
+	require.Error(t, err)
+}
+
+func TestLoader__admin(t *testing.T) {
+	loader, orgRepo := testLoader(t)
+	handler := loadFixtures(loader)
+
+	body := []byte("organizations:\n  - id: demo\n    companyIdentification: DemoCo\n")
+	req := httptest.NewRequest("POST", "/seed", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+	handler(w, req)
+	w.Flush()
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var result Result
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&result))
+	require.Equal(t, 1, result.Organizations)
+
+	orgCfg, err := orgRepo.GetConfig("demo")
+	require.NoError(t, err)
+	require.Equal(t, "DemoCo", orgCfg.CompanyIdentification)
+
+	// wrong method
+	w = httptest.NewRecorder()
+	handler(w, httptest.NewRequest("GET", "/seed", nil))
+	require.Equal(t, http.StatusBadRequest, w.Code)
+
+	// invalid fixtures
+	w = httptest.NewRecorder()
+	handler(w, httptest.NewRequest("POST", "/seed", bytes.NewReader([]byte("organizations: [{}]"))))
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,111 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package tokens
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/util"
+)
+
+const maxTransferBodySize = 1024 * 1024
+
+// Middleware restricts requests carrying a PayGate token to creating Transfers from the token's
+// source to one of its receivers below the configured amount. The token's organization replaces
+// any organization header on the request. Requests without a token are unchanged.
+func Middleware(cfg *config.Config, repo Repository) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			secret := readBearerToken(r)
+			if cfg.Tokens == nil || secret == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			token, organization, err := repo.lookupToken(hashSecret(secret))
+			if err != nil {
+				cfg.Logger.LogErrorf("ERROR looking up token: %v", err)
+				forbidden(w, errors.New("invalid token"))
+				return
+			}
+			if token == nil || (token.Expires != nil && time.Now().After(*token.Expires)) {
+				forbidden(w, errors.New("invalid token"))
+				return
+			}
+			if r.Method != http.MethodPost || r.URL.Path != "/transfers" {
+				forbidden(w, errors.New("token can only create transfers"))
+				return
+			}
+
+			bs, err := ioutil.ReadAll(io.LimitReader(r.Body, maxTransferBodySize))
+			if err != nil {
+				forbidden(w, err)
+				return
+			}
+			var req client.CreateTransfer
+			if err := json.Unmarshal(bs, &req); err != nil {
+				forbidden(w, fmt.Errorf("problem reading request body: %v", err))
+				return
+			}
+			if err := checkTransfer(cfg.Tokens, token, req); err != nil {
+				cfg.Logger.Set("tokenID", token.TokenID).Logf("rejected transfer: %v", err)
+				forbidden(w, err)
+				return
+			}
+
+			r.Body = ioutil.NopCloser(bytes.NewReader(bs))
+			r.Header.Set(util.Or(cfg.Organization.Header, "X-Organization"), organization)
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func readBearerToken(r *http.Request) string {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "Bearer ") {
+		return ""
+	}
+	secret := strings.TrimSpace(strings.TrimPrefix(auth, "Bearer "))
+	if !strings.HasPrefix(secret, tokenPrefix) {
+		return ""
+	}
+	return secret
+}
+
+func checkTransfer(cfg *config.Tokens, token *client.APIToken, req client.CreateTransfer) error {
+	if req.Source.CustomerID != token.Source.CustomerID || req.Source.AccountID != token.Source.AccountID {
+		return errors.New("source does not match token")
+	}
+	if int64(req.Amount.Value) >= cfg.MaxAmount {
+		return fmt.Errorf("amount must be below %d", cfg.MaxAmount)
+	}
+	for i := range token.Receivers {
+		if req.Destination.CustomerID == token.Receivers[i].CustomerID && req.Destination.AccountID == token.Receivers[i].AccountID {
+			return nil
+		}
+	}
+	return errors.New("destination is not a registered receiver")
+}
+
+func forbidden(w http.ResponseWriter, err error) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusForbidden)
+	json.NewEncoder(w).Encode(map[string]string{
+		"error": err.Error(),
+	})
+}
@@ -0,0 +1,150 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package tokens
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/moov-io/base"
+	"github.com/moov-io/paygate/pkg/client"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+)
+
+func setupMiddleware(t *testing.T) (*mux.Router, *MockRepository, string) {
+	secret := "pgt_" + base.ID()
+	repo := &MockRepository{
+		Tokens: []*client.APIToken{
+			{
+				TokenID:   base.ID(),
+				Source:    source,
+				Receivers: []client.Destination{receiver},
+			},
+		},
+		Organization: "partner",
+	}
+	repo.Hashes = map[string]string{
+		hashSecret(secret): repo.Tokens[0].TokenID,
+	}
+
+	r := mux.NewRouter()
+	r.Methods("POST").Path("/transfers").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req client.CreateTransfer
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		w.WriteHeader(http.StatusOK)
+	})
+	r.Methods("GET").Path("/transfers").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	r.Use(Middleware(mockConfig(), repo))
+
+	return r, repo, secret
+}
+
+func transferRequest(t *testing.T, secret string, xfer client.CreateTransfer) *http.Request {
+	var body bytes.Buffer
+	require.NoError(t, json.NewEncoder(&body).Encode(xfer))
+
+	req := httptest.NewRequest("POST", "/transfers", &body)
+	req.Header.Set("X-Organization", "other")
+	if secret != "" {
+		req.Header.Set("Authorization", "Bearer "+secret)
+	}
+	return req
+}
+
+func serve(r *mux.Router, req *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	w.Flush()
+	return w
+}
+
+func TestMiddleware(t *testing.T) {
+	r, repo, secret := setupMiddleware(t)
+
+	xfer := client.CreateTransfer{
+		Amount:      client.Amount{Currency: "USD", Value: 1250},
+		Source:      source,
+		Destination: receiver,
+		Description: "payout",
+	}
+
+	// allowed transfer
+	w := serve(r, transferRequest(t, secret, xfer))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	// requests without a token are unchanged
+	w = serve(r, transferRequest(t, "", client.CreateTransfer{}))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	// amount at the limit
+	over := xfer
+	over.Amount.Value = 10000
+	w = serve(r, transferRequest(t, secret, over))
+	require.Equal(t, http.StatusForbidden, w.Code)
+
+	// unregistered receiver
+	other := xfer
+	other.Destination.AccountID = base.ID()
+	w = serve(r, transferRequest(t, secret, other))
+	require.Equal(t, http.StatusForbidden, w.Code)
+
+	// different source
+	other = xfer
+	other.Source.AccountID = base.ID()
+	w = serve(r, transferRequest(t, secret, other))
+	require.Equal(t, http.StatusForbidden, w.Code)
+
+	// other routes are forbidden
+	req := httptest.NewRequest("GET", "/transfers", nil)
+	req.Header.Set("Authorization", "Bearer "+secret)
+	w = serve(r, req)
+	require.Equal(t, http.StatusForbidden, w.Code)
+
+	// unknown token
+	w = serve(r, transferRequest(t, "pgt_"+base.ID(), xfer))
+	require.Equal(t, http.StatusForbidden, w.Code)
+
+	// expired token
+	expired := time.Now().Add(-1 * time.Minute)
+	repo.Tokens[0].Expires = &expired
+	w = serve(r, transferRequest(t, secret, xfer))
+	require.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestMiddleware__organization(t *testing.T) {
+	var seen string
+	r := mux.NewRouter()
+	r.Methods("POST").Path("/transfers").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = r.Header.Get("X-Organization")
+		w.WriteHeader(http.StatusOK)
+	})
+
+	secret := "pgt_" + base.ID()
+	repo := &MockRepository{
+		Tokens: []*client.APIToken{
+			{TokenID: base.ID(), Source: source, Receivers: []client.Destination{receiver}},
+		},
+		Organization: "partner",
+	}
+	repo.Hashes = map[string]string{hashSecret(secret): repo.Tokens[0].TokenID}
+	r.Use(Middleware(mockConfig(), repo))
+
+	xfer := client.CreateTransfer{
+		Amount:      client.Amount{Currency: "USD", Value: 100},
+		Source:      source,
+		Destination: receiver,
+	}
+	w := serve(r, transferRequest(t, secret, xfer))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "partner", seen)
+}
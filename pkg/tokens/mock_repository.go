@@ -0,0 +1,73 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package tokens
+
+import (
+	"github.com/moov-io/paygate/pkg/client"
+)
+
+type MockRepository struct {
+	Tokens       []*client.APIToken
+	Hashes       map[string]string // tokenHash to tokenID
+	Organization string
+
+	Err error
+}
+
+func (r *MockRepository) getTokens(organization string) ([]*client.APIToken, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	return r.Tokens, nil
+}
+
+func (r *MockRepository) getToken(organization string, tokenID string) (*client.APIToken, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	for i := range r.Tokens {
+		if r.Tokens[i].TokenID == tokenID {
+			return r.Tokens[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *MockRepository) writeToken(organization string, token *client.APIToken, tokenHash string) error {
+	if r.Err != nil {
+		return r.Err
+	}
+	if r.Hashes == nil {
+		r.Hashes = make(map[string]string)
+	}
+	r.Hashes[tokenHash] = token.TokenID
+	r.Organization = organization
+	r.Tokens = append(r.Tokens, token)
+	return nil
+}
+
+func (r *MockRepository) deleteToken(organization string, tokenID string) error {
+	if r.Err != nil {
+		return r.Err
+	}
+	for i := range r.Tokens {
+		if r.Tokens[i].TokenID == tokenID {
+			r.Tokens = append(r.Tokens[:i], r.Tokens[i+1:]...)
+			return nil
+		}
+	}
+	return nil
+}
+
+func (r *MockRepository) lookupToken(tokenHash string) (*client.APIToken, string, error) {
+	if r.Err != nil {
+		return nil, "", r.Err
+	}
+	if tokenID, exists := r.Hashes[tokenHash]; exists {
+		token, err := r.getToken(r.Organization, tokenID)
+		return token, r.Organization, err
+	}
+	return nil, "", nil
+}
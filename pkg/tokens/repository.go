@@ -0,0 +1,204 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package tokens
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/moov-io/paygate/pkg/client"
+)
+
+type Repository interface {
+	getTokens(organization string) ([]*client.APIToken, error)
+	getToken(organization string, tokenID string) (*client.APIToken, error)
+	writeToken(organization string, token *client.APIToken, tokenHash string) error
+	deleteToken(organization string, tokenID string) error
+
+	// lookupToken returns the non-deleted token and its organization for a hashed secret
+	lookupToken(tokenHash string) (*client.APIToken, string, error)
+}
+
+func NewRepo(db *sql.DB) *sqlRepo {
+	return &sqlRepo{db: db}
+}
+
+type sqlRepo struct {
+	db *sql.DB
+}
+
+func (r *sqlRepo) Close() error {
+	if r == nil || r.db == nil {
+		return nil
+	}
+	return r.db.Close()
+}
+
+func (r *sqlRepo) getTokens(organization string) ([]*client.APIToken, error) {
+	query := `select token_id from api_tokens where organization = ? and deleted_at is null order by created_at asc;`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.Query(organization)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tokenIDs []string
+	for rows.Next() {
+		var tokenID string
+		if err := rows.Scan(&tokenID); err != nil {
+			return nil, err
+		}
+		tokenIDs = append(tokenIDs, tokenID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var out []*client.APIToken
+	for i := range tokenIDs {
+		token, err := r.getToken(organization, tokenIDs[i])
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, token)
+	}
+	return out, nil
+}
+
+func (r *sqlRepo) getToken(organization string, tokenID string) (*client.APIToken, error) {
+	query := `select token_id, source_customer_id, source_account_id, created_at, expires_at from api_tokens
+where token_id = ? and organization = ? and deleted_at is null limit 1;`
+	return r.readToken(query, tokenID, organization)
+}
+
+func (r *sqlRepo) lookupToken(tokenHash string) (*client.APIToken, string, error) {
+	query := `select organization from api_tokens where token_hash = ? and deleted_at is null limit 1;`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return nil, "", err
+	}
+	defer stmt.Close()
+
+	var organization string
+	if err := stmt.QueryRow(tokenHash).Scan(&organization); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, "", nil
+		}
+		return nil, "", err
+	}
+
+	query = `select token_id, source_customer_id, source_account_id, created_at, expires_at from api_tokens
+where token_hash = ? and organization = ? and deleted_at is null limit 1;`
+	token, err := r.readToken(query, tokenHash, organization)
+	return token, organization, err
+}
+
+func (r *sqlRepo) readToken(query string, args ...interface{}) (*client.APIToken, error) {
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	var token client.APIToken
+	var expires *time.Time
+	err = stmt.QueryRow(args...).Scan(&token.TokenID, &token.Source.CustomerID, &token.Source.AccountID, &token.Created, &expires)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	token.Expires = expires
+
+	receivers, err := r.getReceivers(token.TokenID)
+	if err != nil {
+		return nil, err
+	}
+	token.Receivers = receivers
+
+	return &token, nil
+}
+
+func (r *sqlRepo) getReceivers(tokenID string) ([]client.Destination, error) {
+	query := `select customer_id, account_id from api_token_receivers where token_id = ?;`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.Query(tokenID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []client.Destination
+	for rows.Next() {
+		var dest client.Destination
+		if err := rows.Scan(&dest.CustomerID, &dest.AccountID); err != nil {
+			return nil, err
+		}
+		out = append(out, dest)
+	}
+	return out, rows.Err()
+}
+
+func (r *sqlRepo) writeToken(organization string, token *client.APIToken, tokenHash string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	query := `insert into api_tokens (token_id, organization, token_hash, source_customer_id, source_account_id, created_at, expires_at) values (?, ?, ?, ?, ?, ?, ?);`
+	stmt, err := tx.Prepare(query)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(token.TokenID, organization, tokenHash, token.Source.CustomerID, token.Source.AccountID, token.Created, token.Expires)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	query = `insert into api_token_receivers (token_id, customer_id, account_id) values (?, ?, ?);`
+	stmt, err = tx.Prepare(query)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	for i := range token.Receivers {
+		if _, err := stmt.Exec(token.TokenID, token.Receivers[i].CustomerID, token.Receivers[i].AccountID); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r *sqlRepo) deleteToken(organization string, tokenID string) error {
+	query := `update api_tokens set deleted_at = ? where token_id = ? and organization = ? and deleted_at is null;`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(time.Now(), tokenID, organization)
+	return err
+}
@@ -0,0 +1,95 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package tokens
+
+import (
+	"testing"
+	"time"
+
+	"github.com/moov-io/base"
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/database"
+
+	"github.com/stretchr/testify/require"
+)
+
+func setupSQLiteDB(t *testing.T) *sqlRepo {
+	db := database.CreateTestSqliteDB(t)
+	t.Cleanup(func() { db.Close() })
+
+	repo := NewRepo(db.DB)
+	t.Cleanup(func() { repo.Close() })
+
+	return repo
+}
+
+func setupMySQLeDB(t *testing.T) *sqlRepo {
+	db := database.CreateTestMySQLDB(t)
+	t.Cleanup(func() { db.Close() })
+
+	repo := NewRepo(db.DB)
+	t.Cleanup(func() { repo.Close() })
+
+	return repo
+}
+
+func TestRepository__Tokens(t *testing.T) {
+	t.Parallel()
+
+	check := func(t *testing.T, repo *sqlRepo) {
+		orgID := base.ID()
+		expires := time.Now().Add(time.Hour).Truncate(time.Second)
+
+		token := &client.APIToken{
+			TokenID: base.ID(),
+			Source: client.Source{
+				CustomerID: base.ID(),
+				AccountID:  base.ID(),
+			},
+			Receivers: []client.Destination{
+				{CustomerID: base.ID(), AccountID: base.ID()},
+				{CustomerID: base.ID(), AccountID: base.ID()},
+			},
+			Expires: &expires,
+			Created: time.Now(),
+		}
+		hash := hashSecret("pgt_secret")
+		require.NoError(t, repo.writeToken(orgID, token, hash))
+
+		tokens, err := repo.getTokens(orgID)
+		require.NoError(t, err)
+		require.Len(t, tokens, 1)
+		require.Equal(t, token.Source, tokens[0].Source)
+		require.ElementsMatch(t, token.Receivers, tokens[0].Receivers)
+		require.NotNil(t, tokens[0].Expires)
+
+		found, org, err := repo.lookupToken(hash)
+		require.NoError(t, err)
+		require.Equal(t, orgID, org)
+		require.Equal(t, token.TokenID, found.TokenID)
+
+		found, _, err = repo.lookupToken(hashSecret("other"))
+		require.NoError(t, err)
+		require.Nil(t, found)
+
+		// other organizations can't see or delete it
+		found, err = repo.getToken(base.ID(), token.TokenID)
+		require.NoError(t, err)
+		require.Nil(t, found)
+
+		require.NoError(t, repo.deleteToken(orgID, token.TokenID))
+		found, _, err = repo.lookupToken(hash)
+		require.NoError(t, err)
+		require.Nil(t, found)
+	}
+
+	t.Run("SQLite", func(t *testing.T) {
+		check(t, setupSQLiteDB(t))
+	})
+
+	t.Run("MySQL", func(t *testing.T) {
+		check(t, setupMySQLeDB(t))
+	})
+}
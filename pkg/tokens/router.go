@@ -0,0 +1,202 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package tokens
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/moov-io/base"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/customers"
+	"github.com/moov-io/paygate/x/route"
+)
+
+// tokenPrefix marks secrets issued by PayGate so they're not confused with
+// other Bearer tokens an upstream auth proxy might use.
+const tokenPrefix = "pgt_"
+
+type Router struct {
+	GetTokens   http.HandlerFunc
+	CreateToken http.HandlerFunc
+	DeleteToken http.HandlerFunc
+}
+
+func NewRouter(cfg *config.Config, repo Repository, customersClient customers.Client) *Router {
+	if cfg.Tokens == nil {
+		return &Router{
+			GetTokens:   NotImplemented(cfg),
+			CreateToken: NotImplemented(cfg),
+			DeleteToken: NotImplemented(cfg),
+		}
+	}
+	return &Router{
+		GetTokens:   GetTokens(cfg, repo),
+		CreateToken: CreateToken(cfg, repo, customersClient),
+		DeleteToken: DeleteToken(cfg, repo),
+	}
+}
+
+func (c *Router) RegisterRoutes(r *mux.Router) {
+	r.Methods("GET").Path("/tokens").HandlerFunc(c.GetTokens)
+	r.Methods("POST").Path("/tokens").HandlerFunc(c.CreateToken)
+	r.Methods("DELETE").Path("/tokens/{tokenID}").HandlerFunc(c.DeleteToken)
+}
+
+func GetTokens(cfg *config.Config, repo Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(cfg, w, r)
+		responder.Respond(func(w http.ResponseWriter) {
+			tokens, err := repo.getTokens(responder.OrganizationID)
+			if err != nil {
+				cfg.Logger.LogErrorf("ERROR getting tokens: %v", err)
+				responder.Problem(err)
+				return
+			}
+			if tokens == nil {
+				tokens = make([]*client.APIToken, 0)
+			}
+
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(tokens)
+		})
+	}
+}
+
+func CreateToken(cfg *config.Config, repo Repository, customersClient customers.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(cfg, w, r)
+		responder.Respond(func(w http.ResponseWriter) {
+			var req client.CreateAPIToken
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				responder.Problem(fmt.Errorf("problem reading request body: %v", err))
+				return
+			}
+			if err := validateTokenRequest(cfg, customersClient, responder.OrganizationID, responder.XRequestID, req); err != nil {
+				responder.Problem(fmt.Errorf("creating token: %v", err))
+				return
+			}
+
+			secret, err := generateSecret()
+			if err != nil {
+				responder.Problem(err)
+				return
+			}
+			token := &client.APIToken{
+				TokenID:   base.ID(),
+				Source:    req.Source,
+				Receivers: req.Receivers,
+				Created:   time.Now(),
+			}
+			if cfg.Tokens.Expiration > 0 {
+				expires := token.Created.Add(cfg.Tokens.Expiration)
+				token.Expires = &expires
+			}
+			if err := repo.writeToken(responder.OrganizationID, token, hashSecret(secret)); err != nil {
+				cfg.Logger.LogErrorf("ERROR writing token: %v", err)
+				responder.Problem(err)
+				return
+			}
+			cfg.Logger.Set("tokenID", token.TokenID).Logf("created token for customerID=%s", token.Source.CustomerID)
+
+			// The secret is only returned when it's created
+			token.Token = secret
+
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(token)
+		})
+	}
+}
+
+// validateTokenRequest checks the source and every receiver exist. Token holders can only push
+// funds out of the source account, so it must be held at our ODFI.
+func validateTokenRequest(cfg *config.Config, customersClient customers.Client, organization, requestID string, req client.CreateAPIToken) error {
+	if req.Source.CustomerID == "" || req.Source.AccountID == "" {
+		return errors.New("missing source customerID or accountID")
+	}
+	if len(req.Receivers) == 0 {
+		return errors.New("missing receivers")
+	}
+
+	cust, err := customersClient.Lookup(organization, req.Source.CustomerID, requestID)
+	if err != nil {
+		return err
+	}
+	if cust == nil {
+		return fmt.Errorf("source customerID=%s not found", req.Source.CustomerID)
+	}
+	acct, err := customersClient.FindAccount(organization, req.Source.CustomerID, req.Source.AccountID)
+	if err != nil {
+		return err
+	}
+	if acct == nil {
+		return fmt.Errorf("source accountID=%s not found", req.Source.AccountID)
+	}
+	if acct.RoutingNumber != cfg.ODFI.RoutingNumber {
+		return fmt.Errorf("source accountID=%s is not held at %s", req.Source.AccountID, cfg.ODFI.RoutingNumber)
+	}
+
+	for i := range req.Receivers {
+		dest := req.Receivers[i]
+		if dest.CustomerID == "" || dest.AccountID == "" {
+			return errors.New("missing receiver customerID or accountID")
+		}
+		acct, err := customersClient.FindAccount(organization, dest.CustomerID, dest.AccountID)
+		if err != nil {
+			return err
+		}
+		if acct == nil {
+			return fmt.Errorf("receiver accountID=%s not found", dest.AccountID)
+		}
+	}
+	return nil
+}
+
+func DeleteToken(cfg *config.Config, repo Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(cfg, w, r)
+
+		tokenID := route.ReadPathID("tokenID", r)
+		if err := repo.deleteToken(responder.OrganizationID, tokenID); err != nil {
+			cfg.Logger.LogErrorf("ERROR deleting tokenID=%s: %v", tokenID, err)
+			responder.Problem(err)
+			return
+		}
+
+		responder.Respond(func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusOK)
+		})
+	}
+}
+
+func NotImplemented(cfg *config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(cfg, w, r)
+		responder.Problem(errors.New("tokens are disabled via config"))
+	}
+}
+
+func generateSecret() (string, error) {
+	bs := make([]byte, 32)
+	if _, err := rand.Read(bs); err != nil {
+		return "", fmt.Errorf("generating token: %v", err)
+	}
+	return tokenPrefix + hex.EncodeToString(bs), nil
+}
+
+// hashSecret is what's stored so tokens can't be recovered from the database
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,125 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package tokens
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/moov-io/base"
+	moovcustomers "github.com/moov-io/customers/pkg/client"
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/customers"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	source = client.Source{
+		CustomerID: base.ID(),
+		AccountID:  base.ID(),
+	}
+	receiver = client.Destination{
+		CustomerID: base.ID(),
+		AccountID:  base.ID(),
+	}
+)
+
+func mockConfig() *config.Config {
+	cfg := config.Empty()
+	cfg.ODFI.RoutingNumber = "987654320"
+	cfg.Tokens = &config.Tokens{
+		MaxAmount: 10000,
+	}
+	return cfg
+}
+
+func mockCustomersClient() *customers.MockClient {
+	return &customers.MockClient{
+		Customers: []*moovcustomers.Customer{
+			{CustomerID: source.CustomerID},
+		},
+		Accounts: map[string]*moovcustomers.Account{
+			source.AccountID: {
+				AccountID:     source.AccountID,
+				RoutingNumber: "987654320",
+			},
+			receiver.AccountID: {
+				AccountID:     receiver.AccountID,
+				RoutingNumber: "123456780",
+			},
+		},
+	}
+}
+
+func createToken(t *testing.T, r *mux.Router, req client.CreateAPIToken) *httptest.ResponseRecorder {
+	t.Helper()
+
+	var body bytes.Buffer
+	require.NoError(t, json.NewEncoder(&body).Encode(req))
+
+	httpReq := httptest.NewRequest("POST", "/tokens", &body)
+	httpReq.Header.Set("X-Organization", "moov")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httpReq)
+	w.Flush()
+	return w
+}
+
+func TestRouter__CreateToken(t *testing.T) {
+	repo := &MockRepository{}
+
+	r := mux.NewRouter()
+	NewRouter(mockConfig(), repo, mockCustomersClient()).RegisterRoutes(r)
+
+	w := createToken(t, r, client.CreateAPIToken{
+		Source:    source,
+		Receivers: []client.Destination{receiver},
+	})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var resp client.APIToken
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	require.Contains(t, resp.Token, tokenPrefix)
+	require.Equal(t, "moov", repo.Organization)
+	require.Len(t, repo.Tokens, 1)
+
+	// the secret is only stored as a hash
+	require.Contains(t, repo.Hashes, hashSecret(resp.Token))
+
+	// receivers are required
+	w = createToken(t, r, client.CreateAPIToken{
+		Source: source,
+	})
+	require.Equal(t, http.StatusBadRequest, w.Code)
+
+	// source must be at our ODFI
+	w = createToken(t, r, client.CreateAPIToken{
+		Source: client.Source{
+			CustomerID: source.CustomerID,
+			AccountID:  receiver.AccountID,
+		},
+		Receivers: []client.Destination{receiver},
+	})
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestRouter__TokensDisabled(t *testing.T) {
+	r := mux.NewRouter()
+	NewRouter(config.Empty(), &MockRepository{}, nil).RegisterRoutes(r)
+
+	req := httptest.NewRequest("GET", "/tokens", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	w.Flush()
+
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,59 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package tokens
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/moov-io/base"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+)
+
+// Seed writes a token with a known secret so fixtures can hand out predictable credentials.
+// Seeding is idempotent: an existing token with the same secret is returned instead of
+// being written again. The source and receivers are not checked against the Customers service.
+func Seed(cfg *config.Tokens, repo Repository, organization string, secret string, req client.CreateAPIToken) (*client.APIToken, error) {
+	if cfg == nil {
+		return nil, errors.New("tokens are disabled via config")
+	}
+	if !strings.HasPrefix(secret, tokenPrefix) {
+		return nil, fmt.Errorf("secret must start with %s", tokenPrefix)
+	}
+	if req.Source.CustomerID == "" || req.Source.AccountID == "" {
+		return nil, errors.New("missing source customerID or accountID")
+	}
+
+	hash := hashSecret(secret)
+	existing, org, err := repo.lookupToken(hash)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		if org != organization {
+			return nil, errors.New("secret is used by another organization")
+		}
+		return existing, nil
+	}
+
+	token := &client.APIToken{
+		TokenID:   base.ID(),
+		Source:    req.Source,
+		Receivers: req.Receivers,
+		Created:   time.Now(),
+	}
+	if cfg.Expiration > 0 {
+		expires := token.Created.Add(cfg.Expiration)
+		token.Expires = &expires
+	}
+	if err := repo.writeToken(organization, token, hash); err != nil {
+		return nil, err
+	}
+	return token, nil
+}
@@ -0,0 +1,49 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package tokens
+
+import (
+	"testing"
+	"time"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSeed(t *testing.T) {
+	cfg := &config.Tokens{MaxAmount: 1000, Expiration: time.Hour}
+	repo := &MockRepository{}
+
+	req := client.CreateAPIToken{
+		Source: client.Source{CustomerID: "foo", AccountID: "bar"},
+	}
+	secret := tokenPrefix + "demo"
+
+	token, err := Seed(cfg, repo, "moov", secret, req)
+	require.NoError(t, err)
+	require.NotNil(t, token.Expires)
+	require.Equal(t, "", token.Token)
+
+	// seeding again returns the existing token
+	again, err := Seed(cfg, repo, "moov", secret, req)
+	require.NoError(t, err)
+	require.Equal(t, token.TokenID, again.TokenID)
+	require.Len(t, repo.Tokens, 1)
+
+	// the same secret in another organization is rejected
+	_, err = Seed(cfg, repo, "other", secret, req)
+	require.Error(t, err)
+
+	_, err = Seed(cfg, repo, "moov", "demo", req)
+	require.Error(t, err)
+
+	_, err = Seed(cfg, repo, "moov", secret, client.CreateAPIToken{})
+	require.Error(t, err)
+
+	_, err = Seed(nil, repo, "moov", secret, req)
+	require.Error(t, err)
+}
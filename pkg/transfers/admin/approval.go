@@ -15,6 +15,8 @@ import (
 	"github.com/moov-io/paygate/pkg/client"
 	"github.com/moov-io/paygate/pkg/config"
 	"github.com/moov-io/paygate/pkg/transfers"
+	"github.com/moov-io/paygate/pkg/transfers/history"
+	"github.com/moov-io/paygate/pkg/transfers/pipeline"
 	"github.com/moov-io/paygate/x/route"
 )
 
@@ -22,7 +24,7 @@ func getTransferID(r *http.Request) string {
 	return route.ReadPathID("transferID", r)
 }
 
-func updateTransferStatus(cfg *config.Config, repo transfers.Repository) http.HandlerFunc {
+func updateTransferStatus(cfg *config.Config, repo transfers.Repository, pub pipeline.XferPublisher) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		responder := route.NewResponder(cfg, w, r)
 
@@ -50,7 +52,7 @@ func updateTransferStatus(cfg *config.Config, repo transfers.Repository) http.Ha
 		}
 
 		// Perform the DB update since it's an allowed transition
-		if err := repo.UpdateTransferStatus(transferID, request.Status); err != nil {
+		if err := repo.UpdateTransferStatus(transferID, request.Status, history.Admin); err != nil {
 			responder.Problem(err)
 			return
 		}
@@ -61,6 +63,14 @@ func updateTransferStatus(cfg *config.Config, repo transfers.Repository) http.Ha
 			"status":       string(request.Status),
 		}).Log("Updated transfer status")
 
+		// Drop the canceled Transfer's files from the pipeline so they aren't merged
+		if request.Status == client.CANCELED && pub != nil {
+			if err := pub.Cancel(pipeline.CanceledTransfer{TransferID: transferID}); err != nil {
+				responder.Problem(fmt.Errorf("canceling transfer in pipeline: %v", err))
+				return
+			}
+		}
+
 		responder.Respond(func(w http.ResponseWriter) {
 			w.WriteHeader(http.StatusOK)
 		})
@@ -85,6 +95,12 @@ func validStatusTransistion(transferID string, current client.TransferStatus, pr
 		case client.CANCELED, client.REVIEWABLE:
 			return nil
 		}
+	case client.FAILED:
+		// Failed transfers can be reopened when they were failed by mistake. Their files
+		// aren't published again.
+		if proposed == client.PENDING {
+			return nil
+		}
 	}
 	return fmt.Errorf("unable to move transfer=%s from status=%s to status=%s", transferID, current, proposed)
 }
@@ -16,6 +16,7 @@ import (
 	"github.com/moov-io/paygate/pkg/config"
 	"github.com/moov-io/paygate/pkg/testclient"
 	"github.com/moov-io/paygate/pkg/transfers"
+	"github.com/moov-io/paygate/pkg/transfers/pipeline"
 )
 
 func TestAdmin__updateTransferStatus(t *testing.T) {
@@ -43,19 +44,25 @@ func TestAdmin__updateTransferStatus(t *testing.T) {
 	}
 
 	cfg := config.Empty()
+	pub := pipeline.NewMockPublisher()
 	svc, c := testclient.Admin(t)
-	RegisterRoutes(cfg, svc, repo)
+	RegisterRoutes(cfg, svc, repo, pub)
 
+	transferID := repo.Transfers[0].TransferID
 	req := admin.UpdateTransferStatus{
 		Status: admin.CANCELED,
 	}
-	resp, err := c.TransfersApi.UpdateTransferStatus(context.TODO(), "transferID", "organization", req, nil)
+	resp, err := c.TransfersApi.UpdateTransferStatus(context.TODO(), transferID, "organization", req, nil)
 	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK || err != nil {
 		t.Errorf("bogus HTTP status: %d", resp.StatusCode)
 		t.Fatal(err)
 	}
 
+	// canceled Transfers are dropped from the pipeline
+	if _, ok := pub.Cancels[transferID]; !ok {
+		t.Errorf("expected %s to be canceled in the pipeline: %#v", transferID, pub.Cancels)
+	}
 }
 
 func TestAdmin__validStatusTransistion(t *testing.T) {
@@ -85,4 +92,15 @@ func TestAdmin__validStatusTransistion(t *testing.T) {
 	if err := validStatusTransistion(transferID, client.PENDING, client.REVIEWABLE); err != nil {
 		t.Error(err)
 	}
+
+	// Failed can only be reopened as Pending
+	if err := validStatusTransistion(transferID, client.FAILED, client.PENDING); err != nil {
+		t.Error(err)
+	}
+	if err := validStatusTransistion(transferID, client.FAILED, client.CANCELED); err == nil {
+		t.Error("expected error")
+	}
+	if err := validStatusTransistion(transferID, client.PROCESSED, client.PENDING); err == nil {
+		t.Error("expected error")
+	}
 }
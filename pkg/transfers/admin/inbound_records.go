@@ -0,0 +1,60 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package admin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	moovhttp "github.com/moov-io/base/http"
+
+	"github.com/moov-io/paygate/pkg/transfers"
+)
+
+// listInboundRecords returns the raw return and correction entries received from RDFIs,
+// including those which weren't matched to a Transfer.
+func listInboundRecords(repo transfers.Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+
+		if r.Method != http.MethodGet {
+			moovhttp.Problem(w, fmt.Errorf("invalid method %s", r.Method))
+			return
+		}
+
+		q := r.URL.Query()
+		filter := transfers.InboundRecordFilter{
+			TransferID: q.Get("transferID"),
+			Type:       q.Get("type"),
+		}
+		if v := q.Get("unmatched"); v != "" {
+			unmatched, err := strconv.ParseBool(v)
+			if err != nil {
+				moovhttp.Problem(w, fmt.Errorf("invalid unmatched: %v", err))
+				return
+			}
+			filter.Unmatched = unmatched
+		}
+		if v := q.Get("count"); v != "" {
+			count, err := strconv.Atoi(v)
+			if err != nil {
+				moovhttp.Problem(w, fmt.Errorf("invalid count: %v", err))
+				return
+			}
+			filter.Limit = count
+		}
+
+		records, err := repo.ListInboundRecords(filter)
+		if err != nil {
+			moovhttp.Problem(w, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(records)
+	}
+}
@@ -0,0 +1,50 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/moov-io/paygate/pkg/admin"
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/transfers"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdmin__listInboundRecords(t *testing.T) {
+	repo := &transfers.MockRepository{
+		InboundRecords: []*client.InboundRecord{
+			{RecordID: "record", Type: "return", Code: "R01"},
+		},
+	}
+	handler := listInboundRecords(repo)
+
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest("GET", "/inbound/records?unmatched=true&count=10", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var records []admin.InboundRecord
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&records))
+	require.Len(t, records, 1)
+	require.Equal(t, "R01", records[0].Code)
+
+	w = httptest.NewRecorder()
+	handler(w, httptest.NewRequest("GET", "/inbound/records?count=many", nil))
+	require.Equal(t, http.StatusBadRequest, w.Code)
+
+	repo.Err = errors.New("bad error")
+	w = httptest.NewRecorder()
+	handler(w, httptest.NewRequest("GET", "/inbound/records", nil))
+	require.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = httptest.NewRecorder()
+	handler(w, httptest.NewRequest("POST", "/inbound/records", nil))
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,41 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package admin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	moovhttp "github.com/moov-io/base/http"
+
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/transfers"
+)
+
+// checkIntegrity reads every Transfer and lists those which are omitted from API
+// responses because they can't be read.
+func checkIntegrity(cfg *config.Config, repo transfers.Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+
+		if r.Method != http.MethodGet {
+			moovhttp.Problem(w, fmt.Errorf("invalid method %s", r.Method))
+			return
+		}
+
+		report, err := repo.CheckIntegrity()
+		if err != nil {
+			moovhttp.Problem(w, err)
+			return
+		}
+		if n := len(report.Problems); n > 0 {
+			cfg.Logger.Logf("integrity check found %d unreadable transfers of %d", n, report.Checked)
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(report)
+	}
+}
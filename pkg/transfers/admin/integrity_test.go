@@ -0,0 +1,49 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/moov-io/paygate/pkg/admin"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/transfers"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdmin__checkIntegrity(t *testing.T) {
+	repo := &transfers.MockRepository{
+		Integrity: &admin.IntegrityReport{
+			Checked: 2,
+			Problems: []admin.IntegrityProblem{
+				{Table: "transfers", ID: "abc", Error: "bad scan"},
+			},
+		},
+	}
+	handler := checkIntegrity(config.Empty(), repo)
+
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest("GET", "/transfers/integrity", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var report admin.IntegrityReport
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&report))
+	require.Equal(t, int32(2), report.Checked)
+	require.Len(t, report.Problems, 1)
+
+	repo.Err = errors.New("bad error")
+	w = httptest.NewRecorder()
+	handler(w, httptest.NewRequest("GET", "/transfers/integrity", nil))
+	require.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = httptest.NewRecorder()
+	handler(w, httptest.NewRequest("POST", "/transfers/integrity", nil))
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,74 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package admin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/moov-io/base/admin"
+	"github.com/moov-io/base/log"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/x/adminauth"
+	"github.com/moov-io/paygate/x/route"
+)
+
+// LegReleaser is the part of a *transfers.LegReleaser which admin routes override.
+type LegReleaser interface {
+	Release(transferID string) error
+	Cancel(transferID string) error
+}
+
+// RegisterLegRoutes adds admin routes to release or cancel the held credit leg of two-leg transfers.
+func RegisterLegRoutes(cfg *config.Config, svc *admin.Server, releaser LegReleaser) {
+	svc.AddHandler("/transfers/{transferID}/legs/credit/status", adminauth.Protect(cfg.Admin.Signing, updateCreditLegStatus(cfg, releaser)))
+}
+
+func updateCreditLegStatus(cfg *config.Config, releaser LegReleaser) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(cfg, w, r)
+
+		if r.Method != http.MethodPut {
+			responder.Problem(fmt.Errorf("unsupported HTTP verb %s", r.Method))
+			return
+		}
+
+		var request struct {
+			Status client.TransferLegStatus `json:"status"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			responder.Problem(err)
+			return
+		}
+
+		transferID := getTransferID(r)
+		var err error
+		switch request.Status {
+		case client.TRANSFERLEGSTATUS_PENDING:
+			err = releaser.Release(transferID)
+		case client.TRANSFERLEGSTATUS_CANCELED:
+			err = releaser.Cancel(transferID)
+		default:
+			err = fmt.Errorf("unable to move credit leg of transfer=%s into status=%s", transferID, request.Status)
+		}
+		if err != nil {
+			responder.Problem(err)
+			return
+		}
+
+		cfg.Logger.With(log.Fields{
+			"requestID":  responder.XRequestID,
+			"transferID": transferID,
+			"status":     string(request.Status),
+		}).Log("Updated credit leg status")
+
+		responder.Respond(func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusOK)
+		})
+	}
+}
@@ -0,0 +1,71 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package admin
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/moov-io/paygate/pkg/config"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+)
+
+type mockLegReleaser struct {
+	Released []string
+	Canceled []string
+	Err      error
+}
+
+func (r *mockLegReleaser) Release(transferID string) error {
+	r.Released = append(r.Released, transferID)
+	return r.Err
+}
+
+func (r *mockLegReleaser) Cancel(transferID string) error {
+	r.Canceled = append(r.Canceled, transferID)
+	return r.Err
+}
+
+func TestAdmin__updateCreditLegStatus(t *testing.T) {
+	releaser := &mockLegReleaser{}
+
+	r := mux.NewRouter()
+	r.Path("/transfers/{transferID}/legs/credit/status").HandlerFunc(updateCreditLegStatus(config.Empty(), releaser))
+
+	put := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("PUT", "/transfers/xfer/legs/credit/status", strings.NewReader(body))
+		r.ServeHTTP(w, req)
+		w.Flush()
+		return w
+	}
+
+	w := put(`{"status": "pending"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, []string{"xfer"}, releaser.Released)
+
+	w = put(`{"status": "canceled"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, []string{"xfer"}, releaser.Canceled)
+
+	// unsupported status
+	w = put(`{"status": "processed"}`)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+
+	// releaser error
+	releaser.Err = errors.New("bad error")
+	w = put(`{"status": "pending"}`)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+
+	// wrong method
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/transfers/xfer/legs/credit/status", nil))
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -8,9 +8,13 @@ import (
 	"github.com/moov-io/base/admin"
 	"github.com/moov-io/paygate/pkg/config"
 	"github.com/moov-io/paygate/pkg/transfers"
+	"github.com/moov-io/paygate/pkg/transfers/pipeline"
+	"github.com/moov-io/paygate/x/adminauth"
 )
 
 // RegisterRoutes will add HTTP handlers for paygate's admin HTTP server
-func RegisterRoutes(cfg *config.Config, svc *admin.Server, repo transfers.Repository) {
-	svc.AddHandler("/transfers/{transferId}/status", updateTransferStatus(cfg, repo))
+func RegisterRoutes(cfg *config.Config, svc *admin.Server, repo transfers.Repository, pub pipeline.XferPublisher) {
+	svc.AddHandler("/transfers/{transferID}/status", adminauth.Protect(cfg.Admin.Signing, updateTransferStatus(cfg, repo, pub)))
+	svc.AddHandler("/transfers/integrity", checkIntegrity(cfg, repo))
+	svc.AddHandler("/inbound/records", listInboundRecords(repo))
 }
@@ -0,0 +1,447 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package transfers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/moov-io/base"
+	"github.com/moov-io/base/log"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/customers"
+	"github.com/moov-io/paygate/pkg/transfers/history"
+	"github.com/moov-io/paygate/pkg/transfers/limiter"
+	"github.com/moov-io/paygate/x/route"
+)
+
+const (
+	maxAuthorizationTermsLength = 4096
+	maxRevocationReasonLength   = 200
+)
+
+// ErrUnauthorizedDebit is returned for debit Transfers which aren't covered by an active
+// authorization from the Customer being debited.
+var ErrUnauthorizedDebit = errors.New("debit is not authorized")
+
+func validateDebitAuthorization(req client.CreateDebitAuthorization, now time.Time) error {
+	if req.AuthorizedAt.IsZero() {
+		return errors.New("missing authorizedAt")
+	}
+	if req.AuthorizedAt.After(now) {
+		return errors.New("authorizedAt is in the future")
+	}
+	switch req.Method {
+	case client.DEBITAUTHORIZATIONMETHOD_WRITTEN, client.DEBITAUTHORIZATIONMETHOD_ORAL, client.DEBITAUTHORIZATIONMETHOD_ELECTRONIC:
+	default:
+		return fmt.Errorf("unknown authorization method %q", req.Method)
+	}
+	switch req.Frequency {
+	case client.DEBITAUTHORIZATIONFREQUENCY_SINGLE, client.DEBITAUTHORIZATIONFREQUENCY_RECURRING:
+	default:
+		return fmt.Errorf("unknown authorization frequency %q", req.Frequency)
+	}
+	if req.Amount != nil {
+		if err := validateAmount(*req.Amount); err != nil {
+			return err
+		}
+	}
+	if len(req.Terms) > maxAuthorizationTermsLength {
+		return fmt.Errorf("terms are longer than %d characters", maxAuthorizationTermsLength)
+	}
+	return nil
+}
+
+// authorizationCovers returns true when auth allows debiting amount at when.
+func authorizationCovers(auth *client.DebitAuthorization, amount client.Amount, when time.Time) bool {
+	if auth == nil || auth.Status != client.DEBITAUTHORIZATIONSTATUS_ACTIVE {
+		return false
+	}
+	if auth.AuthorizedAt.After(when) {
+		return false
+	}
+	if auth.Amount != nil {
+		return auth.Amount.Currency == amount.Currency && amount.Value <= auth.Amount.Value
+	}
+	return true
+}
+
+// checkDebitAuthorization rejects debit Transfers when the source Customer and Account have
+// authorizations on file but none of the active ones cover the Transfer, which is how
+// revocations block future debits. When authorizations are required an Account without any
+// is rejected as well.
+func (c *creator) checkDebitAuthorization(orgID string, pending *pendingTransfer) error {
+	debits, _ := limiter.RemoteAmounts(c.cfg.ODFI.RoutingNumber, pending.files)
+	if debits == 0 {
+		return nil
+	}
+
+	src := pending.transfer.Source
+	auths, err := c.repo.getDebitAuthorizations(orgID, src.CustomerID, src.AccountID)
+	if err != nil {
+		return fmt.Errorf("reading debit authorizations: %v", err)
+	}
+	if len(auths) == 0 && !c.cfg.Transfers.DebitAuthorizations.Required {
+		return nil
+	}
+	now := time.Now()
+	for i := range auths {
+		if authorizationCovers(auths[i], pending.transfer.Amount, now) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: no active authorization from customerID=%s for accountID=%s covers %d cents", ErrUnauthorizedDebit, src.CustomerID, src.AccountID, pending.transfer.Amount.Value)
+}
+
+func (r *sqlRepo) getDebitAuthorizations(orgID string, customerID string, accountID string) ([]*client.DebitAuthorization, error) {
+	query := `select authorization_id from debit_authorizations where organization = ? and customer_id = ? and account_id = ? order by created_at asc;`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.Query(orgID, customerID, accountID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var authorizationIDs []string
+	for rows.Next() {
+		var authorizationID string
+		if err := rows.Scan(&authorizationID); err != nil {
+			return nil, err
+		}
+		authorizationIDs = append(authorizationIDs, authorizationID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var out []*client.DebitAuthorization
+	for i := range authorizationIDs {
+		auth, err := r.getDebitAuthorization(orgID, authorizationIDs[i])
+		if err != nil {
+			return nil, err
+		}
+		if auth != nil {
+			out = append(out, auth)
+		}
+	}
+	return out, nil
+}
+
+func (r *sqlRepo) getDebitAuthorization(orgID string, authorizationID string) (*client.DebitAuthorization, error) {
+	query := `select authorization_id, customer_id, account_id, authorized_at, method, amount_currency, amount_value, frequency, terms, status, revoked_at, revocation_reason, created_at
+from debit_authorizations where authorization_id = ? and organization = ? limit 1;`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	var currency, terms, reason *string
+	var value *int64
+	var auth client.DebitAuthorization
+	err = stmt.QueryRow(authorizationID, orgID).Scan(
+		&auth.AuthorizationID,
+		&auth.CustomerID,
+		&auth.AccountID,
+		&auth.AuthorizedAt,
+		&auth.Method,
+		&currency,
+		&value,
+		&auth.Frequency,
+		&terms,
+		&auth.Status,
+		&auth.Revoked,
+		&reason,
+		&auth.Created,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if currency != nil && value != nil {
+		auth.Amount = &client.Amount{Currency: *currency, Value: *value}
+	}
+	if terms != nil {
+		auth.Terms = *terms
+	}
+	if reason != nil {
+		auth.RevocationReason = *reason
+	}
+	return &auth, nil
+}
+
+func (r *sqlRepo) createDebitAuthorization(orgID string, auth *client.DebitAuthorization) error {
+	query := `insert into debit_authorizations (authorization_id, organization, customer_id, account_id, authorized_at, method, amount_currency, amount_value, frequency, terms, status, created_at) values (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	var currency, value interface{}
+	if auth.Amount != nil {
+		currency, value = auth.Amount.Currency, auth.Amount.Value
+	}
+	_, err = stmt.Exec(
+		auth.AuthorizationID,
+		orgID,
+		auth.CustomerID,
+		auth.AccountID,
+		auth.AuthorizedAt,
+		auth.Method,
+		currency,
+		value,
+		auth.Frequency,
+		auth.Terms,
+		auth.Status,
+		auth.Created,
+	)
+	return err
+}
+
+// revokeDebitAuthorization revokes an active authorization and moves pending debits from its
+// Customer and Account into review, which holds them out of merged files. It returns the
+// transferIDs moved into review. Revoking an authorization again is a no-op.
+func (r *sqlRepo) revokeDebitAuthorization(orgID string, authorizationID string, reason string, when time.Time) ([]string, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+
+	var customerID, accountID string
+	query := `select customer_id, account_id from debit_authorizations where authorization_id = ? and organization = ? and status = ? limit 1;`
+	err = tx.QueryRow(query, authorizationID, orgID, client.DEBITAUTHORIZATIONSTATUS_ACTIVE).Scan(&customerID, &accountID)
+	if err != nil {
+		tx.Rollback()
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	query = `update debit_authorizations set status = ?, revoked_at = ?, revocation_reason = ? where authorization_id = ? and organization = ?;`
+	if _, err := tx.Exec(query, client.DEBITAUTHORIZATIONSTATUS_REVOKED, when, reason, authorizationID, orgID); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	transferIDs, err := pendingDebits(tx, orgID, customerID, accountID)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	query = `update transfers set status = ? where transfer_id = ? and status = ? and deleted_at is null;`
+	for i := range transferIDs {
+		if _, err := tx.Exec(query, client.REVIEWABLE, transferIDs[i], client.PENDING); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		change := history.Change{Field: "status", OldValue: string(client.PENDING), NewValue: string(client.REVIEWABLE)}
+		if err := history.Record(tx, transferIDs[i], history.API, change); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
+	return transferIDs, tx.Commit()
+}
+
+// pendingDebits returns the PENDING Transfers which debit a Customer's Account.
+func pendingDebits(tx *sql.Tx, orgID string, customerID string, accountID string) ([]string, error) {
+	query := `select transfer_id from transfers where organization = ? and source_customer_id = ? and source_account_id = ? and status = ? and debit_amount > 0 and deleted_at is null;`
+	rows, err := tx.Query(query, orgID, customerID, accountID, client.PENDING)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []string
+	for rows.Next() {
+		var transferID string
+		if err := rows.Scan(&transferID); err != nil {
+			return nil, err
+		}
+		out = append(out, transferID)
+	}
+	return out, rows.Err()
+}
+
+func getAuthorizationID(r *http.Request) string {
+	return route.ReadPathID("authorizationID", r)
+}
+
+// readCustomerAccount returns the customerID and accountID from the request path.
+func readCustomerAccount(r *http.Request) (string, string, error) {
+	customerID, accountID := route.ReadPathID("customerID", r), route.ReadPathID("accountID", r)
+	if customerID == "" || accountID == "" {
+		return "", "", errors.New("missing customerID or accountID")
+	}
+	return customerID, accountID, nil
+}
+
+func GetDebitAuthorizations(cfg *config.Config, repo Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(cfg, w, r)
+
+		customerID, accountID, err := readCustomerAccount(r)
+		if err != nil {
+			responder.Problem(err)
+			return
+		}
+		auths, err := repo.getDebitAuthorizations(responder.OrganizationID, customerID, accountID)
+		if err != nil {
+			responder.Problem(err)
+			return
+		}
+		if auths == nil {
+			auths = make([]*client.DebitAuthorization, 0)
+		}
+
+		responder.Respond(func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(auths)
+		})
+	}
+}
+
+func CreateDebitAuthorization(cfg *config.Config, repo Repository, customersClient customers.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(cfg, w, r)
+
+		customerID, accountID, err := readCustomerAccount(r)
+		if err != nil {
+			responder.Problem(err)
+			return
+		}
+		var req client.CreateDebitAuthorization
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			responder.Problem(fmt.Errorf("reading debit authorization: %v", err))
+			return
+		}
+		if err := validateDebitAuthorization(req, time.Now()); err != nil {
+			responder.Problem(err)
+			return
+		}
+
+		// Authorizations are only recorded for Accounts the organization can see
+		if customersClient != nil {
+			acct, err := customersClient.FindAccount(responder.OrganizationID, customerID, accountID)
+			if err != nil {
+				responder.Problem(err)
+				return
+			}
+			if acct == nil || acct.AccountID == "" {
+				responder.Problem(fmt.Errorf("accountID=%s not found for customerID=%s", accountID, customerID))
+				return
+			}
+		}
+
+		auth := &client.DebitAuthorization{
+			AuthorizationID: base.ID(),
+			CustomerID:      customerID,
+			AccountID:       accountID,
+			AuthorizedAt:    req.AuthorizedAt,
+			Method:          req.Method,
+			Amount:          req.Amount,
+			Frequency:       req.Frequency,
+			Terms:           req.Terms,
+			Status:          client.DEBITAUTHORIZATIONSTATUS_ACTIVE,
+			Created:         time.Now(),
+		}
+		if err := repo.createDebitAuthorization(responder.OrganizationID, auth); err != nil {
+			responder.Problem(err)
+			return
+		}
+
+		responder.Respond(func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(auth)
+		})
+	}
+}
+
+func GetDebitAuthorization(cfg *config.Config, repo Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(cfg, w, r)
+
+		authorizationID := getAuthorizationID(r)
+		auth, err := repo.getDebitAuthorization(responder.OrganizationID, authorizationID)
+		if err != nil {
+			responder.Problem(err)
+			return
+		}
+		if auth == nil {
+			responder.Problem(fmt.Errorf("authorizationID=%s not found", authorizationID))
+			return
+		}
+
+		responder.Respond(func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(auth)
+		})
+	}
+}
+
+// RevokeDebitAuthorization revokes an authorization so later debits from its Account are
+// rejected, and moves pending debits into review.
+func RevokeDebitAuthorization(cfg *config.Config, repo Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(cfg, w, r)
+
+		var req client.RevokeDebitAuthorization
+		if r.Body != nil {
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+				responder.Problem(fmt.Errorf("reading revocation: %v", err))
+				return
+			}
+		}
+		if len(req.Reason) > maxRevocationReasonLength {
+			responder.Problem(fmt.Errorf("revocation reason is longer than %d characters", maxRevocationReasonLength))
+			return
+		}
+
+		authorizationID := getAuthorizationID(r)
+		reviewed, err := repo.revokeDebitAuthorization(responder.OrganizationID, authorizationID, req.Reason, time.Now())
+		if err != nil {
+			responder.Problem(err)
+			return
+		}
+		auth, err := repo.getDebitAuthorization(responder.OrganizationID, authorizationID)
+		if err != nil {
+			responder.Problem(err)
+			return
+		}
+		if auth == nil {
+			responder.Problem(fmt.Errorf("authorizationID=%s not found", authorizationID))
+			return
+		}
+		logger := cfg.Logger.With(log.Fields{
+			"authorizationID": authorizationID,
+			"customerID":      auth.CustomerID,
+		})
+		for i := range reviewed {
+			logger.Set("transferID", reviewed[i]).Log("moved pending debit into review after revocation")
+		}
+
+		responder.Respond(func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(auth)
+		})
+	}
+}
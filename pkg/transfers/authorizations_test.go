@@ -0,0 +1,247 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package transfers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/moov-io/ach"
+	"github.com/moov-io/base"
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/transfers/history"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDebitAuthorizations__validate(t *testing.T) {
+	now := time.Now()
+	req := client.CreateDebitAuthorization{
+		AuthorizedAt: now.Add(-1 * time.Hour),
+		Method:       client.DEBITAUTHORIZATIONMETHOD_WRITTEN,
+		Frequency:    client.DEBITAUTHORIZATIONFREQUENCY_RECURRING,
+		Amount:       &client.Amount{Currency: "USD", Value: 5000},
+	}
+	require.NoError(t, validateDebitAuthorization(req, now))
+
+	bad := req
+	bad.AuthorizedAt = time.Time{}
+	require.Error(t, validateDebitAuthorization(bad, now))
+
+	bad = req
+	bad.AuthorizedAt = now.Add(time.Hour)
+	require.Error(t, validateDebitAuthorization(bad, now))
+
+	bad = req
+	bad.Method = "fax"
+	require.Error(t, validateDebitAuthorization(bad, now))
+
+	bad = req
+	bad.Frequency = "daily"
+	require.Error(t, validateDebitAuthorization(bad, now))
+
+	bad = req
+	bad.Amount = &client.Amount{Currency: "USD", Value: -1}
+	require.Error(t, validateDebitAuthorization(bad, now))
+}
+
+func TestDebitAuthorizations__covers(t *testing.T) {
+	now := time.Now()
+	auth := &client.DebitAuthorization{
+		AuthorizedAt: now.Add(-1 * time.Hour),
+		Amount:       &client.Amount{Currency: "USD", Value: 5000},
+		Status:       client.DEBITAUTHORIZATIONSTATUS_ACTIVE,
+	}
+	require.True(t, authorizationCovers(auth, client.Amount{Currency: "USD", Value: 5000}, now))
+	require.False(t, authorizationCovers(auth, client.Amount{Currency: "USD", Value: 5001}, now))
+	require.False(t, authorizationCovers(auth, client.Amount{Currency: "CAD", Value: 100}, now))
+
+	// any amount
+	auth.Amount = nil
+	require.True(t, authorizationCovers(auth, client.Amount{Currency: "USD", Value: 500000}, now))
+
+	auth.Status = client.DEBITAUTHORIZATIONSTATUS_REVOKED
+	require.False(t, authorizationCovers(auth, client.Amount{Currency: "USD", Value: 100}, now))
+}
+
+func TestCreator__checkDebitAuthorization(t *testing.T) {
+	file, err := ach.ReadFile(filepath.Join("..", "..", "testdata", "ppd-debit.ach"))
+	require.NoError(t, err)
+
+	cfg := config.Empty()
+	cfg.ODFI.RoutingNumber = "076401251"
+	repo := &MockRepository{}
+	c := &creator{cfg: cfg, repo: repo}
+
+	pending := &pendingTransfer{
+		transfer: &client.Transfer{
+			Amount: client.Amount{Currency: "USD", Value: 10500},
+			Source: client.Source{CustomerID: sourceCustomerID, AccountID: sourceAccountID},
+		},
+		files: []*ach.File{file},
+	}
+
+	// Accounts without authorizations are only rejected when they're required
+	require.NoError(t, c.checkDebitAuthorization("moov", pending))
+	cfg.Transfers.DebitAuthorizations.Required = true
+	err = c.checkDebitAuthorization("moov", pending)
+	require.True(t, errors.Is(err, ErrUnauthorizedDebit))
+
+	repo.Authorizations = []*client.DebitAuthorization{
+		{
+			AuthorizationID: base.ID(),
+			CustomerID:      sourceCustomerID,
+			AccountID:       sourceAccountID,
+			AuthorizedAt:    time.Now().Add(-1 * time.Hour),
+			Status:          client.DEBITAUTHORIZATIONSTATUS_ACTIVE,
+		},
+	}
+	require.NoError(t, c.checkDebitAuthorization("moov", pending))
+
+	// revoked authorizations block debits even when they aren't required
+	cfg.Transfers.DebitAuthorizations.Required = false
+	repo.Authorizations[0].Status = client.DEBITAUTHORIZATIONSTATUS_REVOKED
+	err = c.checkDebitAuthorization("moov", pending)
+	require.True(t, errors.Is(err, ErrUnauthorizedDebit))
+
+	// Transfers without debits don't need an authorization
+	pending.files = nil
+	require.NoError(t, c.checkDebitAuthorization("moov", pending))
+}
+
+func TestRepository__debitAuthorizations(t *testing.T) {
+	t.Parallel()
+
+	check := func(t *testing.T, repo *sqlRepo) {
+		xfer := writeTransfer(t, "moov", repo)
+		require.NoError(t, repo.saveEntryTotals(xfer.TransferID, "PPD", xfer.Amount.Value, 0))
+
+		auth := &client.DebitAuthorization{
+			AuthorizationID: base.ID(),
+			CustomerID:      xfer.Source.CustomerID,
+			AccountID:       xfer.Source.AccountID,
+			AuthorizedAt:    time.Now().Add(-1 * time.Hour).Truncate(time.Second),
+			Method:          client.DEBITAUTHORIZATIONMETHOD_ELECTRONIC,
+			Amount:          &client.Amount{Currency: "USD", Value: 5000},
+			Frequency:       client.DEBITAUTHORIZATIONFREQUENCY_RECURRING,
+			Terms:           "I authorize monthly debits",
+			Status:          client.DEBITAUTHORIZATIONSTATUS_ACTIVE,
+			Created:         time.Now(),
+		}
+		require.NoError(t, repo.createDebitAuthorization("moov", auth))
+
+		auths, err := repo.getDebitAuthorizations("moov", xfer.Source.CustomerID, xfer.Source.AccountID)
+		require.NoError(t, err)
+		require.Len(t, auths, 1)
+		require.Equal(t, auth.AuthorizationID, auths[0].AuthorizationID)
+		require.Equal(t, int64(5000), auths[0].Amount.Value)
+		require.Equal(t, "I authorize monthly debits", auths[0].Terms)
+
+		// other organizations can't read it
+		found, err := repo.getDebitAuthorization("other", auth.AuthorizationID)
+		require.NoError(t, err)
+		require.Nil(t, found)
+
+		reviewed, err := repo.revokeDebitAuthorization("moov", auth.AuthorizationID, "customer called", time.Now())
+		require.NoError(t, err)
+		require.Equal(t, []string{xfer.TransferID}, reviewed)
+
+		found, err = repo.getDebitAuthorization("moov", auth.AuthorizationID)
+		require.NoError(t, err)
+		require.Equal(t, client.DEBITAUTHORIZATIONSTATUS_REVOKED, found.Status)
+		require.NotNil(t, found.Revoked)
+		require.Equal(t, "customer called", found.RevocationReason)
+
+		xfer, err = repo.GetTransfer(xfer.TransferID)
+		require.NoError(t, err)
+		require.Equal(t, client.REVIEWABLE, xfer.Status)
+
+		changes, err := repo.getTransferHistory("moov", xfer.TransferID)
+		require.NoError(t, err)
+		var reviewable bool
+		for i := range changes {
+			if changes[i].Field == "status" && changes[i].NewValue == string(client.REVIEWABLE) {
+				reviewable = true
+				require.Equal(t, string(client.PENDING), changes[i].OldValue)
+				require.Equal(t, string(history.API), changes[i].Actor)
+			}
+		}
+		require.True(t, reviewable)
+
+		// revoking again is a no-op
+		reviewed, err = repo.revokeDebitAuthorization("moov", auth.AuthorizationID, "again", time.Now())
+		require.NoError(t, err)
+		require.Empty(t, reviewed)
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+}
+
+func TestRouter__DebitAuthorizations(t *testing.T) {
+	repo := &MockRepository{}
+
+	r := mux.NewRouter()
+	NewRouter(config.Empty(), repo, orgRepo, mockCustomersClient(), mockDecryptor, mockStrategy, fakePublisher, nil, nil, nil, nil, nil).RegisterRoutes(r)
+
+	send := func(method, path string, body interface{}) *httptest.ResponseRecorder {
+		var buf bytes.Buffer
+		if body != nil {
+			require.NoError(t, json.NewEncoder(&buf).Encode(body))
+		}
+		req := httptest.NewRequest(method, path, &buf)
+		req.Header.Set("X-Organization", "moov")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	path := "/customers/" + sourceCustomerID + "/accounts/" + sourceAccountID + "/authorizations"
+	w := send("POST", path, client.CreateDebitAuthorization{
+		AuthorizedAt: time.Now().Add(-1 * time.Hour),
+		Method:       client.DEBITAUTHORIZATIONMETHOD_ORAL,
+		Frequency:    client.DEBITAUTHORIZATIONFREQUENCY_SINGLE,
+	})
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var auth client.DebitAuthorization
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&auth))
+	require.NotEmpty(t, auth.AuthorizationID)
+	require.Equal(t, client.DEBITAUTHORIZATIONSTATUS_ACTIVE, auth.Status)
+
+	w = send("GET", path, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var auths []client.DebitAuthorization
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&auths))
+	require.Len(t, auths, 1)
+
+	w = send("POST", "/authorizations/"+auth.AuthorizationID+"/revoke", client.RevokeDebitAuthorization{Reason: "customer called"})
+	require.Equal(t, http.StatusOK, w.Code)
+
+	auth = client.DebitAuthorization{}
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&auth))
+	require.Equal(t, client.DEBITAUTHORIZATIONSTATUS_REVOKED, auth.Status)
+	require.Equal(t, "customer called", auth.RevocationReason)
+
+	// unknown accounts
+	w = send("POST", "/customers/"+sourceCustomerID+"/accounts/"+base.ID()+"/authorizations", client.CreateDebitAuthorization{
+		AuthorizedAt: time.Now().Add(-1 * time.Hour),
+		Method:       client.DEBITAUTHORIZATIONMETHOD_ORAL,
+		Frequency:    client.DEBITAUTHORIZATIONFREQUENCY_SINGLE,
+	})
+	require.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = send("GET", "/authorizations/"+base.ID(), nil)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,167 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+// Package balances checks the source Account of a Transfer has enough funds before it's
+// created. Balances are read from an HTTP service, like the core banking system holding
+// the Account, and Transfers which are still pending are held against them.
+package balances
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/moov-io/base/log"
+
+	"github.com/moov-io/paygate"
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/x/httpclient"
+)
+
+// maxResponseSize limits how much of a balance response is read
+const maxResponseSize = 1024 * 1024
+
+var ErrInsufficientFunds = errors.New("insufficient funds")
+
+// InsufficientFundsError is returned for Transfers larger than their source Account's
+// available balance less holds.
+type InsufficientFundsError struct {
+	Available int64
+	Holds     int64
+	Required  int64
+}
+
+func (e *InsufficientFundsError) Error() string {
+	return fmt.Sprintf("%v: transfer of %d exceeds available balance of %d less %d held", ErrInsufficientFunds, e.Required, e.Available, e.Holds)
+}
+
+func (e *InsufficientFundsError) Unwrap() error {
+	return ErrInsufficientFunds
+}
+
+// Problem describes the error for API responses.
+func (e *InsufficientFundsError) Problem() client.InsufficientFunds {
+	return client.InsufficientFunds{
+		Error:     e.Error(),
+		Available: e.Available,
+		Holds:     e.Holds,
+		Required:  e.Required,
+	}
+}
+
+// Checker compares Transfers against their source Account's balance.
+//
+// A nil *Checker accepts every Transfer.
+type Checker struct {
+	cfg    *config.Balances
+	logger log.Logger
+
+	client   *http.Client
+	endpoint string
+}
+
+// NewChecker returns nil when balance checks aren't configured.
+func NewChecker(cfg *config.Config) (*Checker, error) {
+	if cfg.Transfers.Balances == nil {
+		return nil, nil
+	}
+	if err := cfg.Transfers.Balances.Validate(); err != nil {
+		return nil, err
+	}
+	client, err := httpclient.New(cfg.Transfers.Balances.HTTPClient, cfg.Transfers.Balances.RequestTimeout())
+	if err != nil {
+		return nil, err
+	}
+	return &Checker{
+		cfg:      cfg.Transfers.Balances,
+		logger:   cfg.Logger,
+		client:   client,
+		endpoint: strings.TrimSpace(cfg.Transfers.Balances.Endpoint),
+	}, nil
+}
+
+// Check returns an *InsufficientFundsError when the Transfer is over its source Account's
+// available balance less holds. Unless the Checker is strict shortfalls and problems reading
+// the balance are logged and the Transfer is accepted.
+func (c *Checker) Check(organization string, xfer *client.Transfer, holds int64) error {
+	if c == nil || xfer == nil {
+		return nil
+	}
+	logger := c.logger.With(log.Fields{
+		"organization": organization,
+		"transferID":   xfer.TransferID,
+	})
+
+	available, err := c.available(organization, xfer.Source.CustomerID, xfer.Source.AccountID)
+	if err != nil {
+		balanceChecks.With("result", "error").Add(1)
+		if c.cfg.Strict {
+			return fmt.Errorf("reading balance: %v", err)
+		}
+		logger.LogErrorf("problem reading balance: %v", err)
+		return nil
+	}
+	if xfer.Amount.Value <= available-holds {
+		balanceChecks.With("result", "sufficient").Add(1)
+		return nil
+	}
+
+	balanceChecks.With("result", "insufficient").Add(1)
+	err = &InsufficientFundsError{
+		Available: available,
+		Holds:     holds,
+		Required:  xfer.Amount.Value,
+	}
+	if c.cfg.Strict {
+		return err
+	}
+	logger.Warn().Logf("accepting transfer: %v", err)
+	return nil
+}
+
+type balanceResponse struct {
+	Available *int64 `json:"available"`
+}
+
+func (c *Checker) available(organization, customerID, accountID string) (int64, error) {
+	u, err := url.Parse(c.endpoint)
+	if err != nil {
+		return 0, err
+	}
+	q := u.Query()
+	q.Set("organization", organization)
+	q.Set("customerID", customerID)
+	q.Set("accountID", accountID)
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("User-Agent", fmt.Sprintf("moov/paygate %v balances", paygate.Version))
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return 0, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	var out balanceResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize)).Decode(&out); err != nil {
+		return 0, fmt.Errorf("decode: %v", err)
+	}
+	if out.Available == nil {
+		return 0, errors.New("missing available balance")
+	}
+	return *out.Available, nil
+}
@@ -0,0 +1,102 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package balances
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+
+	"github.com/stretchr/testify/require"
+)
+
+func balanceServer(t *testing.T, body string) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("accountID") != "account" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func setupChecker(t *testing.T, endpoint string, strict bool) *Checker {
+	t.Helper()
+
+	cfg := config.Empty()
+	cfg.Transfers.Balances = &config.Balances{
+		Endpoint: endpoint,
+		Strict:   strict,
+	}
+	checker, err := NewChecker(cfg)
+	require.NoError(t, err)
+	require.NotNil(t, checker)
+	return checker
+}
+
+func transfer(amount int64) *client.Transfer {
+	return &client.Transfer{
+		TransferID: "transfer",
+		Amount:     client.Amount{Currency: "USD", Value: amount},
+		Source:     client.Source{CustomerID: "customer", AccountID: "account"},
+	}
+}
+
+func TestChecker__nil(t *testing.T) {
+	checker, err := NewChecker(config.Empty())
+	require.NoError(t, err)
+	require.Nil(t, checker)
+	require.NoError(t, checker.Check("moov", transfer(1000), 0))
+
+	cfg := config.Empty()
+	cfg.Transfers.Balances = &config.Balances{Endpoint: "::invalid"}
+	_, err = NewChecker(cfg)
+	require.Error(t, err)
+}
+
+func TestChecker__Check(t *testing.T) {
+	server := balanceServer(t, `{"available": 2000}`)
+	checker := setupChecker(t, server.URL, true)
+
+	require.NoError(t, checker.Check("moov", transfer(1500), 500))
+
+	err := checker.Check("moov", transfer(1500), 501)
+	require.True(t, errors.Is(err, ErrInsufficientFunds))
+
+	var insufficient *InsufficientFundsError
+	require.True(t, errors.As(err, &insufficient))
+	require.Equal(t, client.InsufficientFunds{
+		Error:     err.Error(),
+		Available: 2000,
+		Holds:     501,
+		Required:  1500,
+	}, insufficient.Problem())
+
+	// without strict checks the transfer is accepted
+	checker = setupChecker(t, server.URL, false)
+	require.NoError(t, checker.Check("moov", transfer(1500), 501))
+}
+
+func TestChecker__Errors(t *testing.T) {
+	missing := balanceServer(t, `{}`)
+	checker := setupChecker(t, missing.URL, true)
+	require.Error(t, checker.Check("moov", transfer(1), 0))
+
+	xfer := transfer(1)
+	xfer.Source.AccountID = "other"
+	require.Error(t, checker.Check("moov", xfer, 0))
+
+	// problems reading the balance are only logged
+	checker = setupChecker(t, missing.URL, false)
+	require.NoError(t, checker.Check("moov", transfer(1), 0))
+}
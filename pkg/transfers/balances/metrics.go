@@ -0,0 +1,17 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package balances
+
+import (
+	"github.com/go-kit/kit/metrics/prometheus"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	balanceChecks = prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+		Name: "transfer_balance_checks",
+		Help: "Counter of source account balance checks by their result",
+	}, []string{"result"})
+)
@@ -0,0 +1,304 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package transfers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/moov-io/base"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/customers"
+	"github.com/moov-io/paygate/pkg/customers/accounts"
+	"github.com/moov-io/paygate/pkg/database"
+	"github.com/moov-io/paygate/pkg/hooks"
+	"github.com/moov-io/paygate/pkg/organization"
+	"github.com/moov-io/paygate/pkg/transfers/balances"
+	"github.com/moov-io/paygate/pkg/transfers/fundflow"
+	"github.com/moov-io/paygate/pkg/transfers/history"
+	"github.com/moov-io/paygate/pkg/transfers/killswitch"
+	"github.com/moov-io/paygate/pkg/transfers/limiter"
+	"github.com/moov-io/paygate/pkg/transfers/pipeline"
+	"github.com/moov-io/paygate/pkg/webhooks"
+	"github.com/moov-io/paygate/x/route"
+)
+
+const (
+	maxTransferBatchSize = 100
+
+	// batchKeyExpiration is how long an idempotency key covers the batch it was sent with.
+	batchKeyExpiration = 24 * time.Hour
+)
+
+// batchedTransfer is an originated Transfer and what's saved alongside it.
+type batchedTransfer struct {
+	transfer     *client.Transfer
+	traceNumbers []string
+	secCode      string
+	debits       int64
+	credits      int64
+}
+
+// createBatch creates each requested Transfer and reports how each one went. Transfers which
+// fail validation, limits or origination are left out while the rest are saved in a single
+// transaction, so a batch is never partially written.
+func (c *creator) createBatch(orgID, userID, requestID string, batchID string, reqs []client.CreateTransfer) *client.TransferBatch {
+	out := &client.TransferBatch{
+		BatchID: batchID,
+		Results: make([]client.TransferBatchResult, len(reqs)),
+	}
+	failed := func(idx int, err error) {
+		out.Results[idx].Status = client.TRANSFERBATCHRESULTSTATUS_FAILED
+		out.Results[idx].Error = err.Error()
+	}
+
+	var accepted []*pendingTransfer
+	var indexes []int
+	for i := range reqs {
+		out.Results[i].Index = int32(i)
+
+		pending, err := c.prepare(orgID, userID, requestID, reqs[i], "")
+		if err == nil {
+			err = c.originate(orgID, pending)
+		}
+		if err == nil {
+			err = c.checkFiles(orgID, userID, pending)
+		}
+		if err != nil {
+			failed(i, err)
+			continue
+		}
+		accepted = append(accepted, pending)
+		indexes = append(indexes, i)
+	}
+	if len(accepted) == 0 {
+		return out
+	}
+
+	batch := make([]batchedTransfer, len(accepted))
+	for i := range accepted {
+		secCode, debits, credits := entryTotals(c.cfg.ODFI.RoutingNumber, accepted[i].files)
+		accepted[i].transfer.TraceNumbers = traceNumbers(accepted[i].files)
+		batch[i] = batchedTransfer{
+			transfer:     accepted[i].transfer,
+			traceNumbers: accepted[i].transfer.TraceNumbers,
+			secCode:      secCode,
+			debits:       debits,
+			credits:      credits,
+		}
+	}
+	if err := c.repo.writeTransferBatch(orgID, batch); err != nil {
+		err = fmt.Errorf("creating transfer: error writing batch: %v", err)
+		for _, idx := range indexes {
+			failed(idx, err)
+		}
+		return out
+	}
+
+	for i := range accepted {
+		idx, transfer := indexes[i], accepted[i].transfer
+		if err := pipeline.PublishFiles(c.pub, transfer, accepted[i].files); err != nil {
+			// The Transfer was saved, so fail it rather than leave it pending without files
+			if err := c.repo.UpdateTransferStatus(transfer.TransferID, client.FAILED, history.API); err != nil {
+				c.cfg.Logger.Set("transferID", transfer.TransferID).LogErrorf("problem failing unpublished transfer: %v", err)
+			}
+			transfer.Status = client.FAILED
+			out.Results[idx].Transfer = transfer
+			failed(idx, fmt.Errorf("creating transfer: error publishing files: %v", err))
+			continue
+		}
+		c.notifyLimitsNearing(orgID, transfer)
+
+		out.Results[idx].Status = client.TRANSFERBATCHRESULTSTATUS_CREATED
+		out.Results[idx].Transfer = transfer
+	}
+	return out
+}
+
+func (r *sqlRepo) writeTransferBatch(orgID string, batch []batchedTransfer) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	for i := range batch {
+		if err := writeBatchedTransfer(tx, orgID, batch[i]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("transferID=%s: %v", batch[i].transfer.TransferID, err)
+		}
+	}
+	return tx.Commit()
+}
+
+func writeBatchedTransfer(tx *sql.Tx, orgID string, xfer batchedTransfer) error {
+	transferID := xfer.transfer.TransferID
+	if err := insertTransfer(tx, orgID, xfer.transfer); err != nil {
+		return err
+	}
+	if err := insertTraceNumbers(tx, transferID, xfer.traceNumbers); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(saveEntryTotalsQuery, xfer.secCode, xfer.debits, xfer.credits, transferID); err != nil {
+		return err
+	}
+	return insertTransferLegs(tx, transferID, xfer.transfer.Legs)
+}
+
+// saveTransferBatch records a batch and claims its idempotency key. When the organization
+// already sent the key with another batch in the last 24 hours false is returned along with
+// that batch's response, which is empty while it's still being created. Like single Transfers,
+// keys without a saved response are released once their lease is over.
+func (r *sqlRepo) saveTransferBatch(orgID string, batchID string, idempotencyKey string, now time.Time) (bool, []byte, error) {
+	var key interface{}
+	if idempotencyKey != "" {
+		key = idempotencyKey
+	}
+
+	query := `insert into transfer_batches (batch_id, organization, idempotency_key, created_at) values (?, ?, ?, ?);`
+	_, err := r.db.Exec(query, batchID, orgID, key, now)
+	if err == nil {
+		return true, nil, nil
+	}
+	if key == nil || !database.UniqueViolation(err) {
+		return false, nil, err
+	}
+
+	// Release an expired key from its old batch, or one whose response was never saved, so
+	// it can be used again
+	release := `update transfer_batches set idempotency_key = null where organization = ? and idempotency_key = ? and (created_at < ? or (response is null and created_at < ?));`
+	res, err := r.db.Exec(release, orgID, idempotencyKey, now.Add(-batchKeyExpiration), now.Add(-idempotencyKeyLease))
+	if err != nil {
+		return false, nil, err
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		_, err := r.db.Exec(query, batchID, orgID, key, now)
+		if err == nil {
+			return true, nil, nil
+		}
+		if !database.UniqueViolation(err) {
+			return false, nil, err
+		}
+	}
+
+	var response sql.NullString
+	query = `select response from transfer_batches where organization = ? and idempotency_key = ? limit 1;`
+	if err := r.db.QueryRow(query, orgID, idempotencyKey).Scan(&response); err != nil && err != sql.ErrNoRows {
+		return false, nil, err
+	}
+	if response.String == "" {
+		return false, nil, nil
+	}
+	return false, []byte(response.String), nil
+}
+
+// saveTransferBatchResponse stores the response of a batch so it's returned when the batch's
+// idempotency key is sent again.
+func (r *sqlRepo) saveTransferBatchResponse(batchID string, batch *client.TransferBatch) error {
+	bs, err := json.Marshal(batch)
+	if err != nil {
+		return err
+	}
+	query := `update transfer_batches set response = ? where batch_id = ?;`
+	_, err = r.db.Exec(query, string(bs), batchID)
+	return err
+}
+
+// releaseTransferBatch removes a batch which didn't create any Transfers, so the request can
+// be retried with the same idempotency key.
+func (r *sqlRepo) releaseTransferBatch(batchID string) error {
+	query := `delete from transfer_batches where batch_id = ? and response is null;`
+	_, err := r.db.Exec(query, batchID)
+	return err
+}
+
+// CreateTransferBatch creates up to 100 Transfers in one request. Each Transfer succeeds or
+// fails on its own and the response has a result for each one. An X-Idempotency-Key header
+// covers the whole batch.
+func CreateTransferBatch(
+	cfg *config.Config,
+	repo Repository,
+	orgRepo organization.Repository,
+	customersClient customers.Client,
+	accountDecryptor accounts.Decryptor,
+	fundStrategy fundflow.Strategy,
+	pub pipeline.XferPublisher,
+	limitChecker limiter.Checker,
+	debits *killswitch.Checker,
+	exposure *limiter.Exposure,
+	balanceChecker *balances.Checker,
+	hookRunner *hooks.Runner,
+	events webhooks.Sender,
+) http.HandlerFunc {
+	c := &creator{
+		cfg:              cfg,
+		repo:             repo,
+		orgRepo:          orgRepo,
+		customersClient:  customersClient,
+		accountDecryptor: accountDecryptor,
+		fundStrategy:     fundStrategy,
+		pub:              pub,
+		limitChecker:     limitChecker,
+		debits:           debits,
+		exposure:         exposure,
+		balances:         balanceChecker,
+		hookRunner:       hookRunner,
+		events:           events,
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Idempotency keys are kept in the database so a replay returns the original batch
+		responder := route.NewResponderWithoutIdempotency(cfg, w, r)
+
+		var req client.CreateTransferBatch
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			responder.Problem(fmt.Errorf("creating transfer batch: problem reading request body: %v", err))
+			return
+		}
+		if n := len(req.Transfers); n == 0 || n > maxTransferBatchSize {
+			responder.Problem(fmt.Errorf("creating transfer batch: found %d transfers, expected between 1 and %d", n, maxTransferBatchSize))
+			return
+		}
+
+		// Claim the idempotency key before anything is created, as only the database is
+		// shared between instances.
+		batchID := base.ID()
+		claimed, response, err := repo.saveTransferBatch(responder.OrganizationID, batchID, r.Header.Get("X-Idempotency-Key"), time.Now())
+		if err != nil {
+			responder.Problem(fmt.Errorf("creating transfer batch: %v", err))
+			return
+		}
+		if !claimed {
+			replayIdempotentResponse(responder, response)
+			return
+		}
+
+		batch := c.createBatch(responder.OrganizationID, getUserID(r), responder.XRequestID, batchID, req.Transfers)
+
+		var created int
+		for i := range batch.Results {
+			if batch.Results[i].Status == client.TRANSFERBATCHRESULTSTATUS_CREATED {
+				created++
+			}
+		}
+		logger := cfg.Logger.Set("batchID", batchID)
+		logger.Logf("created %d of %d transfers in batch", created, len(batch.Results))
+
+		if created == 0 {
+			if err := repo.releaseTransferBatch(batchID); err != nil {
+				logger.LogErrorf("problem releasing idempotency key: %v", err)
+			}
+		} else if err := repo.saveTransferBatchResponse(batchID, batch); err != nil {
+			logger.LogErrorf("problem saving idempotency key: %v", err)
+		}
+
+		responder.Respond(func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(batch)
+		})
+	}
+}
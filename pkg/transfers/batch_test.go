@@ -0,0 +1,234 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package transfers
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/moov-io/base"
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+)
+
+func batchTransfer(t *testing.T) *client.Transfer {
+	t.Helper()
+
+	return &client.Transfer{
+		TransferID:  base.ID(),
+		Amount:      client.Amount{Currency: "USD", Value: 1245},
+		Source:      client.Source{CustomerID: base.ID(), AccountID: base.ID()},
+		Destination: client.Destination{CustomerID: base.ID(), AccountID: base.ID()},
+		Description: "payroll",
+		Status:      client.PENDING,
+		Created:     time.Now(),
+		Tags:        []string{"batch"},
+	}
+}
+
+func TestRepository__writeTransferBatch(t *testing.T) {
+	t.Parallel()
+
+	check := func(t *testing.T, repo *sqlRepo) {
+		first, second := batchTransfer(t), batchTransfer(t)
+		err := repo.writeTransferBatch("moov", []batchedTransfer{
+			{transfer: first, traceNumbers: []string{"123456780000001"}, secCode: "PPD", debits: 1245},
+			{transfer: second, secCode: "PPD", credits: 1245},
+		})
+		require.NoError(t, err)
+
+		found, err := repo.GetTransfer(first.TransferID)
+		require.NoError(t, err)
+		require.Equal(t, first.TransferID, found.TransferID)
+		require.Equal(t, []string{"batch"}, found.Tags)
+
+		traceNumbers, err := repo.getTraceNumbers(first.TransferID)
+		require.NoError(t, err)
+		require.Equal(t, []string{"123456780000001"}, traceNumbers)
+
+		found, err = repo.GetTransfer(second.TransferID)
+		require.NoError(t, err)
+		require.Equal(t, second.TransferID, found.TransferID)
+
+		// a failed write saves none of the batch
+		third := batchTransfer(t)
+		err = repo.writeTransferBatch("moov", []batchedTransfer{
+			{transfer: third},
+			{transfer: first},
+		})
+		require.Error(t, err)
+
+		_, err = repo.GetTransfer(third.TransferID)
+		require.Equal(t, sql.ErrNoRows, err)
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+}
+
+func TestRepository__saveTransferBatch(t *testing.T) {
+	t.Parallel()
+
+	check := func(t *testing.T, repo *sqlRepo) {
+		now := time.Now()
+
+		// batches without a key are always saved
+		claimed, _, err := repo.saveTransferBatch("moov", base.ID(), "", now)
+		require.NoError(t, err)
+		require.True(t, claimed)
+		claimed, _, err = repo.saveTransferBatch("moov", base.ID(), "", now)
+		require.NoError(t, err)
+		require.True(t, claimed)
+
+		key, batchID := base.ID(), base.ID()
+		claimed, response, err := repo.saveTransferBatch("moov", batchID, key, now)
+		require.NoError(t, err)
+		require.True(t, claimed)
+		require.Empty(t, response)
+
+		// the key is claimed while its batch is created
+		claimed, response, err = repo.saveTransferBatch("moov", base.ID(), key, now.Add(time.Minute))
+		require.NoError(t, err)
+		require.False(t, claimed)
+		require.Empty(t, response)
+
+		batch := &client.TransferBatch{BatchID: batchID}
+		require.NoError(t, repo.saveTransferBatchResponse(batchID, batch))
+
+		claimed, response, err = repo.saveTransferBatch("moov", base.ID(), key, now.Add(time.Hour))
+		require.NoError(t, err)
+		require.False(t, claimed)
+
+		var replay client.TransferBatch
+		require.NoError(t, json.Unmarshal(response, &replay))
+		require.Equal(t, batchID, replay.BatchID)
+
+		// batches with a response aren't released
+		require.NoError(t, repo.releaseTransferBatch(batchID))
+		claimed, _, err = repo.saveTransferBatch("moov", base.ID(), key, now.Add(time.Hour))
+		require.NoError(t, err)
+		require.False(t, claimed)
+
+		// keys are per organization
+		otherID := base.ID()
+		claimed, _, err = repo.saveTransferBatch("other", otherID, key, now)
+		require.NoError(t, err)
+		require.True(t, claimed)
+
+		require.NoError(t, repo.releaseTransferBatch(otherID))
+		claimed, _, err = repo.saveTransferBatch("other", base.ID(), key, now)
+		require.NoError(t, err)
+		require.True(t, claimed)
+
+		// and expire after a day
+		claimed, response, err = repo.saveTransferBatch("moov", base.ID(), key, now.Add(25*time.Hour))
+		require.NoError(t, err)
+		require.True(t, claimed)
+		require.Empty(t, response)
+
+		// batches which never saved a response release their key after the lease
+		leased := base.ID()
+		claimed, _, err = repo.saveTransferBatch("moov", base.ID(), leased, now)
+		require.NoError(t, err)
+		require.True(t, claimed)
+		claimed, _, err = repo.saveTransferBatch("moov", base.ID(), leased, now.Add(idempotencyKeyLease+time.Minute))
+		require.NoError(t, err)
+		require.True(t, claimed)
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+}
+
+func TestRouter__CreateTransferBatch(t *testing.T) {
+	repo := &MockRepository{}
+
+	r := mux.NewRouter()
+	NewRouter(config.Empty(), repo, orgRepo, mockCustomersClient(), mockDecryptor, mockStrategy, fakePublisher, nil, nil, nil, nil, nil).RegisterRoutes(r)
+
+	batch := client.CreateTransferBatch{
+		Transfers: []client.CreateTransfer{
+			{
+				Amount:      client.Amount{Currency: "USD", Value: 1244},
+				Source:      client.Source{CustomerID: sourceCustomerID, AccountID: sourceAccountID},
+				Destination: client.Destination{CustomerID: destinationCustomerID, AccountID: destinationAccountID},
+				Description: "test transfer",
+			},
+			{
+				Amount:      client.Amount{Currency: "USD", Value: 1244},
+				Source:      client.Source{CustomerID: sourceCustomerID, AccountID: sourceAccountID},
+				Destination: client.Destination{CustomerID: destinationCustomerID, AccountID: destinationAccountID},
+			},
+		},
+	}
+	send := func(body interface{}, key string) *httptest.ResponseRecorder {
+		var buf bytes.Buffer
+		require.NoError(t, json.NewEncoder(&buf).Encode(body))
+
+		req := httptest.NewRequest("POST", "/transfers/batch", &buf)
+		req.Header.Set("X-Organization", "moov")
+		if key != "" {
+			req.Header.Set("X-Idempotency-Key", key)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	key := base.ID()
+	w := send(batch, key)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var resp client.TransferBatch
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	require.NotEmpty(t, resp.BatchID)
+	require.Len(t, resp.Results, 2)
+
+	require.Equal(t, int32(0), resp.Results[0].Index)
+	require.Equal(t, client.TRANSFERBATCHRESULTSTATUS_CREATED, resp.Results[0].Status)
+	require.NotNil(t, resp.Results[0].Transfer)
+	require.NotEmpty(t, resp.Results[0].Transfer.TransferID)
+
+	require.Equal(t, int32(1), resp.Results[1].Index)
+	require.Equal(t, client.TRANSFERBATCHRESULTSTATUS_FAILED, resp.Results[1].Status)
+	require.Nil(t, resp.Results[1].Transfer)
+	require.Contains(t, resp.Results[1].Error, "missing description")
+
+	require.Equal(t, resp.BatchID, repo.IdempotencyKeys[key])
+
+	// the key covers the whole batch and returns its original response
+	w = send(batch, key)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var replay client.TransferBatch
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&replay))
+	require.Equal(t, resp.BatchID, replay.BatchID)
+	require.Len(t, replay.Results, 2)
+	require.Equal(t, resp.Results[0].Transfer.TransferID, replay.Results[0].Transfer.TransferID)
+
+	// keys of batches which didn't create any Transfers can be used again
+	failing := client.CreateTransferBatch{
+		Transfers: batch.Transfers[1:],
+	}
+	key = base.ID()
+	w = send(failing, key)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NotContains(t, repo.IdempotencyKeys, key)
+
+	w = send(batch, key)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Contains(t, repo.IdempotencyKeys, key)
+
+	w = send(client.CreateTransferBatch{}, "")
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
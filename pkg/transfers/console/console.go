@@ -0,0 +1,110 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+// Package console offers a single admin endpoint summarizing the state of the transfer
+// pipeline: what's waiting for the next cutoff, what failed to upload, when the next cutoff
+// is, the latest inbound sync and recent errors.
+package console
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/moov-io/base/admin"
+	moovhttp "github.com/moov-io/base/http"
+
+	paygateadmin "github.com/moov-io/paygate/pkg/admin"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/transfers/inbound"
+	"github.com/moov-io/paygate/x/errorlog"
+	"github.com/moov-io/paygate/x/schedule"
+)
+
+const maxRecentErrors = 50
+
+// Aggregator is the part of a *pipeline.XferAggregator which the console reads.
+type Aggregator interface {
+	PendingTransfers() ([]paygateadmin.PendingTransfers, error)
+	FailedUploads() []paygateadmin.FailedUpload
+	RecentErrors() []errorlog.Entry
+}
+
+// RegisterRoutes adds 'GET /pipeline' to the admin server.
+func RegisterRoutes(cfg *config.Config, clock schedule.Clock, svc *admin.Server, agg Aggregator, scheduler inbound.Scheduler) {
+	svc.AddHandler("/pipeline", getPipelineState(cfg, clock, agg, scheduler))
+}
+
+func getPipelineState(cfg *config.Config, clock schedule.Clock, agg Aggregator, scheduler inbound.Scheduler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			moovhttp.Problem(w, fmt.Errorf("invalid method %s", r.Method))
+			return
+		}
+
+		state := pipelineState(cfg, clock, agg, scheduler)
+
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(state)
+	}
+}
+
+// pipelineState collects each part of the pipeline. Problems reading one part are
+// included as errors rather than failing the whole response.
+func pipelineState(cfg *config.Config, clock schedule.Clock, agg Aggregator, scheduler inbound.Scheduler) *paygateadmin.PipelineState {
+	now := clock.Now()
+	var entries []errorlog.Entry
+
+	state := &paygateadmin.PipelineState{
+		PendingTransfers: make([]paygateadmin.PendingTransfers, 0),
+		FailedUploads:    make([]paygateadmin.FailedUpload, 0),
+		Cutoffs:          make([]paygateadmin.UpcomingCutoff, 0),
+		RecentErrors:     make([]paygateadmin.PipelineError, 0),
+	}
+
+	if agg != nil {
+		pending, err := agg.PendingTransfers()
+		if err != nil {
+			entries = append(entries, errorlog.Entry{Component: "console", Message: fmt.Sprintf("reading pending transfers: %v", err), Created: now})
+		}
+		state.PendingTransfers = append(state.PendingTransfers, pending...)
+		state.FailedUploads = append(state.FailedUploads, agg.FailedUploads()...)
+		entries = append(entries, agg.RecentErrors()...)
+	}
+
+	cutoffs := cfg.ODFI.Cutoffs
+	if next, err := schedule.NextDatedCutoff(clock, cutoffs.Timezone, cutoffs.WindowsOn); err != nil {
+		entries = append(entries, errorlog.Entry{Component: "console", Message: fmt.Sprintf("finding next cutoff: %v", err), Created: now})
+	} else {
+		state.Cutoffs = append(state.Cutoffs, paygateadmin.UpcomingCutoff{
+			RoutingNumber:    cfg.ODFI.RoutingNumber,
+			Cutoff:           next,
+			SecondsRemaining: int64(next.Sub(now).Seconds()),
+		})
+	}
+
+	if scheduler != nil {
+		state.Inbound = scheduler.LastSync()
+		entries = append(entries, scheduler.RecentErrors()...)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Created.After(entries[j].Created)
+	})
+	if len(entries) > maxRecentErrors {
+		entries = entries[:maxRecentErrors]
+	}
+	for i := range entries {
+		state.RecentErrors = append(state.RecentErrors, paygateadmin.PipelineError{
+			Component: entries[i].Component,
+			Message:   entries[i].Message,
+			Created:   entries[i].Created,
+		})
+	}
+
+	return state
+}
@@ -0,0 +1,122 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package console
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	paygateadmin "github.com/moov-io/paygate/pkg/admin"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/transfers/inbound"
+	"github.com/moov-io/paygate/x/errorlog"
+	"github.com/moov-io/paygate/x/schedule"
+
+	"github.com/stretchr/testify/require"
+)
+
+type mockAggregator struct {
+	Pending []paygateadmin.PendingTransfers
+	Failed  []paygateadmin.FailedUpload
+	Errors  []errorlog.Entry
+	Err     error
+}
+
+func (agg *mockAggregator) PendingTransfers() ([]paygateadmin.PendingTransfers, error) {
+	return agg.Pending, agg.Err
+}
+
+func (agg *mockAggregator) FailedUploads() []paygateadmin.FailedUpload {
+	return agg.Failed
+}
+
+func (agg *mockAggregator) RecentErrors() []errorlog.Entry {
+	return agg.Errors
+}
+
+func testConfig() *config.Config {
+	cfg := config.Empty()
+	cfg.ODFI.RoutingNumber = "987654320"
+	cfg.ODFI.Cutoffs = config.Cutoffs{
+		Timezone: "America/New_York",
+		Windows:  []string{"16:20"},
+	}
+	return cfg
+}
+
+func TestConsole__pipelineState(t *testing.T) {
+	loc, _ := time.LoadLocation("America/New_York")
+	now := time.Date(2020, time.October, 14, 10, 0, 0, 0, loc) // Wednesday
+	clock := schedule.NewMockClock(now)
+	agg := &mockAggregator{
+		Pending: []paygateadmin.PendingTransfers{
+			{RoutingNumber: "273976369", Transfers: 2, TotalAmount: 1250},
+		},
+		Failed: []paygateadmin.FailedUpload{
+			{Filename: "20201014-987654320-1.ach", Error: "connection refused"},
+		},
+		Errors: []errorlog.Entry{
+			{Component: "upload", Message: "connection refused", Created: now.Add(-time.Minute)},
+		},
+	}
+	scheduler := &inbound.MockScheduler{
+		Sync: &paygateadmin.InboundSync{Started: now.Add(-time.Hour), Finished: now.Add(-59 * time.Minute)},
+		Errors: []errorlog.Entry{
+			{Component: "inbound", Message: "timeout", Created: now},
+		},
+	}
+
+	state := pipelineState(testConfig(), clock, agg, scheduler)
+	require.Len(t, state.PendingTransfers, 1)
+	require.Len(t, state.FailedUploads, 1)
+	require.NotNil(t, state.Inbound)
+
+	require.Len(t, state.Cutoffs, 1)
+	require.Equal(t, "987654320", state.Cutoffs[0].RoutingNumber)
+	require.Equal(t, time.Date(2020, time.October, 14, 16, 20, 0, 0, loc), state.Cutoffs[0].Cutoff)
+	require.Equal(t, int64((6*time.Hour + 20*time.Minute).Seconds()), state.Cutoffs[0].SecondsRemaining)
+
+	// newest errors first
+	require.Len(t, state.RecentErrors, 2)
+	require.Equal(t, "inbound", state.RecentErrors[0].Component)
+	require.Equal(t, "upload", state.RecentErrors[1].Component)
+}
+
+func TestConsole__pipelineStateErrors(t *testing.T) {
+	cfg := testConfig()
+	cfg.ODFI.Cutoffs.Windows = nil
+
+	agg := &mockAggregator{Err: errors.New("bad error")}
+
+	state := pipelineState(cfg, schedule.System, agg, nil)
+	require.Len(t, state.PendingTransfers, 0)
+	require.Len(t, state.Cutoffs, 0)
+	require.Nil(t, state.Inbound)
+	require.Len(t, state.RecentErrors, 2)
+	require.Equal(t, "console", state.RecentErrors[0].Component)
+}
+
+func TestConsole__getPipelineState(t *testing.T) {
+	handler := getPipelineState(testConfig(), schedule.System, &mockAggregator{}, &inbound.MockScheduler{})
+
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest("GET", "/pipeline", nil))
+	w.Flush()
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var state paygateadmin.PipelineState
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&state))
+	require.Len(t, state.Cutoffs, 1)
+	require.NotNil(t, state.PendingTransfers)
+
+	w = httptest.NewRecorder()
+	handler(w, httptest.NewRequest("PUT", "/pipeline", nil))
+	w.Flush()
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
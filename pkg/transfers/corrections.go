@@ -0,0 +1,426 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package transfers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/moov-io/ach"
+	"github.com/moov-io/base"
+	moovcustomers "github.com/moov-io/customers/pkg/client"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/x/route"
+)
+
+// Fields of an Account or Customer which a Notification of Change can correct.
+const (
+	correctedAccountNumber  = "accountNumber"
+	correctedRoutingNumber  = "routingNumber"
+	correctedAccountType    = "accountType"
+	correctedName           = "name"
+	correctedIdentification = "identification"
+)
+
+// NewAccountCorrection reads what a Notification of Change corrects about the Account transfer
+// was sent to. Corrections of debit entries are for the source Account and corrections of credit
+// entries are for the destination. The correction is pending until it's applied or dismissed.
+func NewAccountCorrection(transfer *client.Transfer, entry *ach.EntryDetail) *client.AccountCorrection {
+	if transfer == nil || entry == nil || entry.Addenda98 == nil {
+		return nil
+	}
+	correction := &client.AccountCorrection{
+		CorrectionID: base.ID(),
+		CustomerID:   transfer.Destination.CustomerID,
+		AccountID:    transfer.Destination.AccountID,
+		TransferID:   transfer.TransferID,
+		ChangeCode:   entry.Addenda98.ChangeCode,
+		Changes:      correctionChanges(entry),
+		Status:       client.ACCOUNTCORRECTIONSTATUS_PENDING,
+		Created:      time.Now(),
+	}
+	if entry.CreditOrDebit() == "D" {
+		correction.CustomerID = transfer.Source.CustomerID
+		correction.AccountID = transfer.Source.AccountID
+	}
+	return correction
+}
+
+// correctionChanges compares the corrected data of an Addenda98 against the values in the
+// entry. NOC entries repeat the original account number and name, but carry our routing
+// number so the original one is read from the addenda.
+func correctionChanges(entry *ach.EntryDetail) []client.AccountCorrectionChange {
+	addenda98 := entry.Addenda98
+	data := addenda98.CorrectedData
+
+	accountNumber := func(start, end int) client.AccountCorrectionChange {
+		return client.AccountCorrectionChange{
+			Field:    correctedAccountNumber,
+			OldValue: strings.TrimSpace(entry.DFIAccountNumber),
+			NewValue: correctedField(data, start, end),
+		}
+	}
+	routingNumber := func(start, end int) client.AccountCorrectionChange {
+		return client.AccountCorrectionChange{
+			Field:    correctedRoutingNumber,
+			OldValue: originalRoutingNumber(addenda98.OriginalDFI),
+			NewValue: correctedField(data, start, end),
+		}
+	}
+	accountType := func(start, end int) client.AccountCorrectionChange {
+		code, _ := strconv.Atoi(correctedField(data, start, end))
+		return client.AccountCorrectionChange{
+			Field:    correctedAccountType,
+			OldValue: transactionCodeAccountType(entry.TransactionCode),
+			NewValue: transactionCodeAccountType(code),
+		}
+	}
+
+	var changes []client.AccountCorrectionChange
+	switch addenda98.ChangeCode {
+	case "C01":
+		changes = append(changes, accountNumber(0, 17))
+	case "C02":
+		changes = append(changes, routingNumber(0, 9))
+	case "C03":
+		changes = append(changes, routingNumber(0, 9), accountNumber(13, 30))
+	case "C04":
+		changes = append(changes, client.AccountCorrectionChange{
+			Field:    correctedName,
+			OldValue: strings.TrimSpace(entry.IndividualName),
+			NewValue: correctedField(data, 0, 22),
+		})
+	case "C05":
+		changes = append(changes, accountType(0, 2))
+	case "C06":
+		changes = append(changes, accountNumber(0, 17), accountType(20, 22))
+	case "C07":
+		changes = append(changes, routingNumber(0, 9), accountNumber(9, 26), accountType(26, 28))
+	case "C09":
+		changes = append(changes, client.AccountCorrectionChange{
+			Field:    correctedIdentification,
+			OldValue: strings.TrimSpace(entry.IdentificationNumber),
+			NewValue: correctedField(data, 0, 22),
+		})
+	}
+
+	out := make([]client.AccountCorrectionChange, 0, len(changes))
+	for i := range changes {
+		if changes[i].NewValue != "" && changes[i].NewValue != changes[i].OldValue {
+			out = append(out, changes[i])
+		}
+	}
+	return out
+}
+
+func correctedField(data string, start, end int) string {
+	if start >= len(data) {
+		return ""
+	}
+	if end > len(data) {
+		end = len(data)
+	}
+	return strings.TrimSpace(data[start:end])
+}
+
+// originalRoutingNumber adds the check digit to the eight digit Original Receiving DFI
+// Identification of an Addenda98.
+func originalRoutingNumber(dfi string) string {
+	dfi = strings.TrimSpace(dfi)
+	if len(dfi) != 8 {
+		return dfi
+	}
+	if digit := ach.CalculateCheckDigit(dfi); digit >= 0 {
+		return dfi + strconv.Itoa(digit)
+	}
+	return dfi
+}
+
+// transactionCodeAccountType returns the Account type of an entry's transaction code,
+// or an empty string for general ledger and loan accounts.
+func transactionCodeAccountType(code int) string {
+	switch code / 10 {
+	case 2:
+		return string(moovcustomers.ACCOUNTTYPE_CHECKING)
+	case 3:
+		return string(moovcustomers.ACCOUNTTYPE_SAVINGS)
+	}
+	return ""
+}
+
+// correctionApplies returns true when a correction has changes PayGate can use for later
+// Transfers. Names and identification numbers are kept in the Customers service instead.
+func correctionApplies(correction *client.AccountCorrection) bool {
+	for i := range correction.Changes {
+		switch correction.Changes[i].Field {
+		case correctedAccountNumber, correctedRoutingNumber, correctedAccountType:
+			return true
+		}
+	}
+	return false
+}
+
+// applyCorrections uses the values from applied corrections in place of what the Customers
+// service returned for an Account. Each change is only used while the Account still has the
+// corrected value, so updating the Account in the Customers service takes precedence.
+func (c *creator) applyCorrections(orgID string, customerID string, account *moovcustomers.Account, accountNumber *string) error {
+	corrections, err := c.repo.getAccountCorrections(orgID, customerID, account.AccountID)
+	if err != nil {
+		return fmt.Errorf("reading corrections for accountID=%s: %v", account.AccountID, err)
+	}
+	for i := range corrections {
+		if corrections[i].Status != client.ACCOUNTCORRECTIONSTATUS_APPLIED {
+			continue
+		}
+		for _, change := range corrections[i].Changes {
+			switch change.Field {
+			case correctedAccountNumber:
+				if *accountNumber == change.OldValue {
+					*accountNumber = change.NewValue
+				}
+			case correctedRoutingNumber:
+				if account.RoutingNumber == change.OldValue {
+					account.RoutingNumber = change.NewValue
+				}
+			case correctedAccountType:
+				if string(account.Type) == change.OldValue {
+					account.Type = moovcustomers.AccountType(change.NewValue)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// SaveAccountCorrection records a Notification of Change along with each value it corrects.
+func (r *sqlRepo) SaveAccountCorrection(orgID string, correction *client.AccountCorrection) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	query := `insert into account_corrections (correction_id, organization, customer_id, account_id, transfer_id, change_code, status, created_at) values (?, ?, ?, ?, ?, ?, ?, ?);`
+	_, err = tx.Exec(
+		query,
+		correction.CorrectionID,
+		orgID,
+		correction.CustomerID,
+		correction.AccountID,
+		correction.TransferID,
+		correction.ChangeCode,
+		correction.Status,
+		correction.Created,
+	)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	query = `insert into account_correction_changes (correction_id, field, old_value, new_value) values (?, ?, ?, ?);`
+	for _, change := range correction.Changes {
+		if _, err := tx.Exec(query, correction.CorrectionID, change.Field, change.OldValue, change.NewValue); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (r *sqlRepo) getAccountCorrections(orgID string, customerID string, accountID string) ([]*client.AccountCorrection, error) {
+	query := `select correction_id from account_corrections where organization = ? and customer_id = ? and account_id = ? order by created_at asc;`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.Query(orgID, customerID, accountID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var correctionIDs []string
+	for rows.Next() {
+		var correctionID string
+		if err := rows.Scan(&correctionID); err != nil {
+			return nil, err
+		}
+		correctionIDs = append(correctionIDs, correctionID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var out []*client.AccountCorrection
+	for i := range correctionIDs {
+		correction, err := r.getAccountCorrection(orgID, correctionIDs[i])
+		if err != nil {
+			return nil, err
+		}
+		if correction != nil {
+			out = append(out, correction)
+		}
+	}
+	return out, nil
+}
+
+func (r *sqlRepo) getAccountCorrection(orgID string, correctionID string) (*client.AccountCorrection, error) {
+	query := `select correction_id, customer_id, account_id, transfer_id, change_code, status, created_at, reviewed_at, reviewed_by
+from account_corrections where correction_id = ? and organization = ? limit 1;`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	var reviewedBy *string
+	var correction client.AccountCorrection
+	err = stmt.QueryRow(correctionID, orgID).Scan(
+		&correction.CorrectionID,
+		&correction.CustomerID,
+		&correction.AccountID,
+		&correction.TransferID,
+		&correction.ChangeCode,
+		&correction.Status,
+		&correction.Created,
+		&correction.Reviewed,
+		&reviewedBy,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if reviewedBy != nil {
+		correction.ReviewedBy = *reviewedBy
+	}
+
+	query = `select field, old_value, new_value from account_correction_changes where correction_id = ?;`
+	rows, err := r.db.Query(query, correctionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	correction.Changes = make([]client.AccountCorrectionChange, 0)
+	for rows.Next() {
+		var change client.AccountCorrectionChange
+		if err := rows.Scan(&change.Field, &change.OldValue, &change.NewValue); err != nil {
+			return nil, err
+		}
+		correction.Changes = append(correction.Changes, change)
+	}
+	return &correction, rows.Err()
+}
+
+// reviewAccountCorrection applies or dismisses a pending correction.
+func (r *sqlRepo) reviewAccountCorrection(orgID string, correctionID string, status client.AccountCorrectionStatus, userID string, when time.Time) error {
+	query := `update account_corrections set status = ?, reviewed_at = ?, reviewed_by = ? where correction_id = ? and organization = ? and status = ?;`
+	res, err := r.db.Exec(query, status, when, userID, correctionID, orgID, client.ACCOUNTCORRECTIONSTATUS_PENDING)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("correctionID=%s is not pending", correctionID)
+	}
+	return nil
+}
+
+func getCorrectionID(r *http.Request) string {
+	return route.ReadPathID("correctionID", r)
+}
+
+func GetAccountCorrections(cfg *config.Config, repo Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(cfg, w, r)
+
+		customerID, accountID, err := readCustomerAccount(r)
+		if err != nil {
+			responder.Problem(err)
+			return
+		}
+		corrections, err := repo.getAccountCorrections(responder.OrganizationID, customerID, accountID)
+		if err != nil {
+			responder.Problem(err)
+			return
+		}
+		if corrections == nil {
+			corrections = make([]*client.AccountCorrection, 0)
+		}
+
+		responder.Respond(func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(corrections)
+		})
+	}
+}
+
+// UpdateAccountCorrection lets an operator apply or dismiss a correction which was held
+// for review.
+func UpdateAccountCorrection(cfg *config.Config, repo Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(cfg, w, r)
+
+		customerID, accountID, err := readCustomerAccount(r)
+		if err != nil {
+			responder.Problem(err)
+			return
+		}
+		var req client.UpdateAccountCorrection
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			responder.Problem(fmt.Errorf("reading correction update: %v", err))
+			return
+		}
+
+		correctionID := getCorrectionID(r)
+		correction, err := repo.getAccountCorrection(responder.OrganizationID, correctionID)
+		if err != nil {
+			responder.Problem(err)
+			return
+		}
+		if correction == nil || correction.CustomerID != customerID || correction.AccountID != accountID {
+			responder.Problem(fmt.Errorf("correctionID=%s not found", correctionID))
+			return
+		}
+		switch req.Status {
+		case client.ACCOUNTCORRECTIONSTATUS_APPLIED:
+			if !correctionApplies(correction) {
+				responder.Problem(fmt.Errorf("change code %s can't be applied to accounts, update the customer instead", correction.ChangeCode))
+				return
+			}
+		case client.ACCOUNTCORRECTIONSTATUS_DISMISSED:
+		default:
+			responder.Problem(errors.New("corrections can only be applied or dismissed"))
+			return
+		}
+
+		userID := getUserID(r)
+		if err := repo.reviewAccountCorrection(responder.OrganizationID, correctionID, req.Status, userID, time.Now()); err != nil {
+			responder.Problem(err)
+			return
+		}
+		correction, err = repo.getAccountCorrection(responder.OrganizationID, correctionID)
+		if err != nil {
+			responder.Problem(err)
+			return
+		}
+		cfg.Logger.Set("correctionID", correctionID).Set("userID", userID).Logf("account correction %s", req.Status)
+
+		responder.Respond(func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(correction)
+		})
+	}
+}
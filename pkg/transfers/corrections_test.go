@@ -0,0 +1,255 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package transfers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/moov-io/ach"
+	"github.com/moov-io/base"
+	moovcustomers "github.com/moov-io/customers/pkg/client"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+)
+
+func correctionEntry(changeCode, correctedData string) *ach.EntryDetail {
+	entry := ach.NewEntryDetail()
+	entry.TransactionCode = ach.CheckingReturnNOCDebit
+	entry.DFIAccountNumber = "1234567          "
+	entry.IndividualName = "Jane Doe"
+	entry.Addenda98 = ach.NewAddenda98()
+	entry.Addenda98.ChangeCode = changeCode
+	entry.Addenda98.OriginalDFI = "12104288"
+	entry.Addenda98.CorrectedData = correctedData
+	return entry
+}
+
+func TestAccountCorrections__changes(t *testing.T) {
+	changes := correctionChanges(correctionEntry("C01", "7654321"))
+	require.Equal(t, []client.AccountCorrectionChange{
+		{Field: "accountNumber", OldValue: "1234567", NewValue: "7654321"},
+	}, changes)
+
+	changes = correctionChanges(correctionEntry("C03", "273976369    7654321"))
+	require.Equal(t, []client.AccountCorrectionChange{
+		{Field: "routingNumber", OldValue: "121042882", NewValue: "273976369"},
+		{Field: "accountNumber", OldValue: "1234567", NewValue: "7654321"},
+	}, changes)
+
+	changes = correctionChanges(correctionEntry("C07", "2739763697654321          32"))
+	require.Equal(t, []client.AccountCorrectionChange{
+		{Field: "routingNumber", OldValue: "121042882", NewValue: "273976369"},
+		{Field: "accountNumber", OldValue: "1234567", NewValue: "7654321"},
+		{Field: "accountType", OldValue: "checking", NewValue: "savings"},
+	}, changes)
+
+	changes = correctionChanges(correctionEntry("C04", "John Doe"))
+	require.Equal(t, []client.AccountCorrectionChange{
+		{Field: "name", OldValue: "Jane Doe", NewValue: "John Doe"},
+	}, changes)
+
+	// unchanged or missing values are skipped
+	require.Empty(t, correctionChanges(correctionEntry("C01", "1234567")))
+	require.Empty(t, correctionChanges(correctionEntry("C06", "")))
+	require.Empty(t, correctionChanges(correctionEntry("C13", "")))
+}
+
+func TestAccountCorrections__NewAccountCorrection(t *testing.T) {
+	transfer := &client.Transfer{
+		TransferID:  base.ID(),
+		Source:      client.Source{CustomerID: base.ID(), AccountID: base.ID()},
+		Destination: client.Destination{CustomerID: base.ID(), AccountID: base.ID()},
+	}
+
+	// debits are corrected for the source Account
+	entry := correctionEntry("C01", "7654321")
+	correction := NewAccountCorrection(transfer, entry)
+	require.Equal(t, transfer.Source.CustomerID, correction.CustomerID)
+	require.Equal(t, transfer.Source.AccountID, correction.AccountID)
+	require.Equal(t, transfer.TransferID, correction.TransferID)
+	require.Equal(t, client.ACCOUNTCORRECTIONSTATUS_PENDING, correction.Status)
+
+	entry.TransactionCode = ach.SavingsReturnNOCCredit
+	correction = NewAccountCorrection(transfer, entry)
+	require.Equal(t, transfer.Destination.AccountID, correction.AccountID)
+
+	require.Nil(t, NewAccountCorrection(transfer, ach.NewEntryDetail()))
+}
+
+func TestCreator__applyCorrections(t *testing.T) {
+	customerID, accountID := base.ID(), base.ID()
+	repo := &MockRepository{
+		Corrections: []*client.AccountCorrection{
+			{
+				CustomerID: customerID,
+				AccountID:  accountID,
+				ChangeCode: "C03",
+				Status:     client.ACCOUNTCORRECTIONSTATUS_APPLIED,
+				Changes: []client.AccountCorrectionChange{
+					{Field: "routingNumber", OldValue: "121042882", NewValue: "273976369"},
+					{Field: "accountNumber", OldValue: "1234567", NewValue: "7654321"},
+				},
+			},
+			{
+				CustomerID: customerID,
+				AccountID:  accountID,
+				ChangeCode: "C05",
+				Status:     client.ACCOUNTCORRECTIONSTATUS_PENDING,
+				Changes: []client.AccountCorrectionChange{
+					{Field: "accountType", OldValue: "checking", NewValue: "savings"},
+				},
+			},
+		},
+	}
+	c := &creator{cfg: config.Empty(), repo: repo}
+
+	account := moovcustomers.Account{
+		AccountID:     accountID,
+		RoutingNumber: "121042882",
+		Type:          moovcustomers.ACCOUNTTYPE_CHECKING,
+	}
+	accountNumber := "1234567"
+	require.NoError(t, c.applyCorrections("moov", customerID, &account, &accountNumber))
+	require.Equal(t, "273976369", account.RoutingNumber)
+	require.Equal(t, "7654321", accountNumber)
+	require.Equal(t, moovcustomers.ACCOUNTTYPE_CHECKING, account.Type)
+
+	// Accounts updated in the Customers service are left as-is
+	account.RoutingNumber = "987654320"
+	accountNumber = "5555"
+	require.NoError(t, c.applyCorrections("moov", customerID, &account, &accountNumber))
+	require.Equal(t, "987654320", account.RoutingNumber)
+	require.Equal(t, "5555", accountNumber)
+}
+
+func TestRepository__accountCorrections(t *testing.T) {
+	t.Parallel()
+
+	check := func(t *testing.T, repo *sqlRepo) {
+		correction := &client.AccountCorrection{
+			CorrectionID: base.ID(),
+			CustomerID:   base.ID(),
+			AccountID:    base.ID(),
+			TransferID:   base.ID(),
+			ChangeCode:   "C02",
+			Changes: []client.AccountCorrectionChange{
+				{Field: "routingNumber", OldValue: "121042882", NewValue: "273976369"},
+			},
+			Status:  client.ACCOUNTCORRECTIONSTATUS_PENDING,
+			Created: time.Now(),
+		}
+		require.NoError(t, repo.SaveAccountCorrection("moov", correction))
+
+		corrections, err := repo.getAccountCorrections("moov", correction.CustomerID, correction.AccountID)
+		require.NoError(t, err)
+		require.Len(t, corrections, 1)
+		require.Equal(t, correction.CorrectionID, corrections[0].CorrectionID)
+		require.Equal(t, correction.Changes, corrections[0].Changes)
+		require.Nil(t, corrections[0].Reviewed)
+
+		// other organizations can't read it
+		found, err := repo.getAccountCorrection("other", correction.CorrectionID)
+		require.NoError(t, err)
+		require.Nil(t, found)
+
+		require.NoError(t, repo.reviewAccountCorrection("moov", correction.CorrectionID, client.ACCOUNTCORRECTIONSTATUS_APPLIED, "operator", time.Now()))
+		found, err = repo.getAccountCorrection("moov", correction.CorrectionID)
+		require.NoError(t, err)
+		require.Equal(t, client.ACCOUNTCORRECTIONSTATUS_APPLIED, found.Status)
+		require.NotNil(t, found.Reviewed)
+		require.Equal(t, "operator", found.ReviewedBy)
+
+		// corrections are only reviewed once
+		err = repo.reviewAccountCorrection("moov", correction.CorrectionID, client.ACCOUNTCORRECTIONSTATUS_DISMISSED, "operator", time.Now())
+		require.Error(t, err)
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+}
+
+func TestRouter__AccountCorrections(t *testing.T) {
+	customerID, accountID := base.ID(), base.ID()
+	repo := &MockRepository{
+		Corrections: []*client.AccountCorrection{
+			{
+				CorrectionID: base.ID(),
+				CustomerID:   customerID,
+				AccountID:    accountID,
+				ChangeCode:   "C05",
+				Status:       client.ACCOUNTCORRECTIONSTATUS_PENDING,
+				Changes: []client.AccountCorrectionChange{
+					{Field: "accountType", OldValue: "checking", NewValue: "savings"},
+				},
+			},
+			{
+				CorrectionID: base.ID(),
+				CustomerID:   customerID,
+				AccountID:    accountID,
+				ChangeCode:   "C04",
+				Status:       client.ACCOUNTCORRECTIONSTATUS_PENDING,
+				Changes: []client.AccountCorrectionChange{
+					{Field: "name", OldValue: "Jane Doe", NewValue: "John Doe"},
+				},
+			},
+		},
+	}
+
+	r := mux.NewRouter()
+	NewRouter(config.Empty(), repo, orgRepo, mockCustomersClient(), mockDecryptor, mockStrategy, fakePublisher, nil, nil, nil, nil, nil).RegisterRoutes(r)
+
+	send := func(method, path string, body interface{}) *httptest.ResponseRecorder {
+		var buf bytes.Buffer
+		if body != nil {
+			require.NoError(t, json.NewEncoder(&buf).Encode(body))
+		}
+		req := httptest.NewRequest(method, path, &buf)
+		req.Header.Set("X-Organization", "moov")
+		req.Header.Set("X-User-ID", "operator")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	path := "/customers/" + customerID + "/accounts/" + accountID + "/corrections"
+	w := send("GET", path, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var corrections []client.AccountCorrection
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&corrections))
+	require.Len(t, corrections, 2)
+
+	applied := client.UpdateAccountCorrection{Status: client.ACCOUNTCORRECTIONSTATUS_APPLIED}
+	w = send("PUT", path+"/"+corrections[0].CorrectionID, applied)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var correction client.AccountCorrection
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&correction))
+	require.Equal(t, client.ACCOUNTCORRECTIONSTATUS_APPLIED, correction.Status)
+	require.Equal(t, "operator", correction.ReviewedBy)
+
+	// already reviewed
+	w = send("PUT", path+"/"+corrections[0].CorrectionID, applied)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+
+	// name changes can only be dismissed
+	w = send("PUT", path+"/"+corrections[1].CorrectionID, applied)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	w = send("PUT", path+"/"+corrections[1].CorrectionID, client.UpdateAccountCorrection{Status: client.ACCOUNTCORRECTIONSTATUS_DISMISSED})
+	require.Equal(t, http.StatusOK, w.Code)
+
+	// corrections from other Accounts
+	w = send("PUT", "/customers/"+customerID+"/accounts/"+base.ID()+"/corrections/"+corrections[0].CorrectionID, applied)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
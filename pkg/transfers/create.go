@@ -0,0 +1,397 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package transfers
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/moov-io/ach"
+	"github.com/moov-io/base"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/customers"
+	"github.com/moov-io/paygate/pkg/customers/accounts"
+	"github.com/moov-io/paygate/pkg/hooks"
+	"github.com/moov-io/paygate/pkg/organization"
+	"github.com/moov-io/paygate/pkg/transfers/balances"
+	"github.com/moov-io/paygate/pkg/transfers/fundflow"
+	"github.com/moov-io/paygate/pkg/transfers/history"
+	"github.com/moov-io/paygate/pkg/transfers/killswitch"
+	"github.com/moov-io/paygate/pkg/transfers/limiter"
+	"github.com/moov-io/paygate/pkg/transfers/pipeline"
+	"github.com/moov-io/paygate/pkg/util"
+	"github.com/moov-io/paygate/pkg/webhooks"
+	"github.com/moov-io/paygate/x/trace"
+
+	opentracing "github.com/opentracing/opentracing-go"
+)
+
+// creator validates, saves and originates Transfers. Transfers created over HTTP and from
+// schedules both go through it so limits, kill switches and hooks apply the same way.
+type creator struct {
+	cfg *config.Config
+
+	repo             Repository
+	orgRepo          organization.Repository
+	customersClient  customers.Client
+	accountDecryptor accounts.Decryptor
+	fundStrategy     fundflow.Strategy
+	pub              pipeline.XferPublisher
+	limitChecker     limiter.Checker
+	debits           *killswitch.Checker
+	exposure         *limiter.Exposure
+	balances         *balances.Checker
+	hookRunner       *hooks.Runner
+	events           webhooks.Sender
+}
+
+func (c *creator) create(parent opentracing.Span, orgID, userID, requestID string, req client.CreateTransfer) (*client.Transfer, error) {
+	return c.createTransfer(parent, orgID, userID, requestID, req, "")
+}
+
+// createTransfer saves and originates a Transfer. When reversalOf is set the Transfer is
+// linked to the Transfer it reverses before any files are published.
+//
+// Each step is traced as a child of parent, which is the request's span for Transfers created
+// over HTTP. The trace is carried with the published files so merging continues it.
+func (c *creator) createTransfer(parent opentracing.Span, orgID, userID, requestID string, req client.CreateTransfer, reversalOf string) (transfer *client.Transfer, err error) {
+	span := trace.StartSpan("create-transfer", parent)
+	span.SetTag("organization", orgID)
+	defer func() {
+		trace.Finish(span, err)
+	}()
+
+	stage := trace.StartSpan("prepare-transfer", span)
+	pending, err := c.prepare(orgID, userID, requestID, req, reversalOf)
+	trace.Finish(stage, err)
+	if err != nil {
+		return nil, err
+	}
+	transfer = pending.transfer
+	span.SetTag("transferID", transfer.TransferID)
+
+	// Save our Transfer to the database
+	stage = trace.StartSpan("sql-write-transfer", span)
+	err = c.repo.WriteUserTransfer(orgID, transfer)
+	trace.Finish(stage, err)
+	if err != nil {
+		return nil, fmt.Errorf("creating transfer: error writing user transfr: %v", err)
+	}
+	if reversalOf != "" {
+		if err := c.repo.saveReversal(reversalOf, transfer.TransferID); err != nil {
+			if err := c.repo.UpdateTransferStatus(transfer.TransferID, client.FAILED, history.API); err != nil {
+				c.cfg.Logger.Set("transferID", transfer.TransferID).LogErrorf("problem failing unlinked reversal: %v", err)
+			}
+			return nil, fmt.Errorf("creating transfer: error linking reversal: %v", err)
+		}
+		transfer.ReversalOf = reversalOf
+	}
+
+	stage = trace.StartSpan("originate-transfer", span)
+	err = c.originate(orgID, pending)
+	trace.Finish(stage, err)
+	if err != nil {
+		return nil, err
+	}
+	stage = trace.StartSpan("check-files", span)
+	err = c.checkFiles(orgID, userID, pending)
+	trace.Finish(stage, err)
+	if err != nil {
+		if blockedTransfer(err) {
+			if err := c.repo.UpdateTransferStatus(transfer.TransferID, client.FAILED, history.API); err != nil {
+				c.cfg.Logger.Set("transferID", transfer.TransferID).LogErrorf("problem failing blocked transfer: %v", err)
+			}
+		}
+		return nil, err
+	}
+
+	stage = trace.StartSpan("sql-save-entries", span)
+	err = c.saveEntries(transfer, pending.files)
+	trace.Finish(stage, err)
+	if err != nil {
+		return nil, err
+	}
+
+	stage = trace.StartSpan("publish-files", span)
+	err = pipeline.PublishTracedFiles(c.pub, span, transfer, pending.files)
+	trace.Finish(stage, err)
+	if err != nil {
+		return nil, fmt.Errorf("creating transfer: error publishing files: %v", err)
+	}
+	c.notifyLimitsNearing(orgID, transfer)
+	return transfer, nil
+}
+
+// saveEntries records the trace numbers, entry totals and legs of an originated Transfer.
+func (c *creator) saveEntries(transfer *client.Transfer, files []*ach.File) error {
+	if err := SaveTraceNumbers(c.repo, transfer, files); err != nil {
+		return fmt.Errorf("creating transfer: error saving trace numbers: %v", err)
+	}
+	if err := SaveEntryTotals(c.repo, c.cfg.ODFI.RoutingNumber, transfer, files); err != nil {
+		return fmt.Errorf("creating transfer: error saving entry totals: %v", err)
+	}
+	if err := c.repo.saveTransferLegs(transfer.TransferID, transfer.Legs); err != nil {
+		return fmt.Errorf("creating transfer: error saving legs: %v", err)
+	}
+	return nil
+}
+
+// pendingTransfer is a Transfer which passed validation, limits and hooks but isn't saved yet.
+type pendingTransfer struct {
+	transfer    *client.Transfer
+	orgConfig   *client.OrganizationConfiguration
+	source      fundflow.Source
+	destination fundflow.Destination
+
+	// files are set once the Transfer is originated
+	files []*ach.File
+}
+
+// prepare validates a request and builds its Transfer without saving anything.
+func (c *creator) prepare(orgID, userID, requestID string, req client.CreateTransfer, reversalOf string) (*pendingTransfer, error) {
+	if err := validateTransferRequest(req); err != nil {
+		return nil, fmt.Errorf("creating transfer: invalid transfer request: %v", err)
+	}
+	effectiveDate, err := normalizeEffectiveDate(c.cfg.Transfers.EffectiveDates, c.cfg.ODFI.Cutoffs.Location(), time.Now(), req.EffectiveDate, req.SameDay)
+	if err != nil {
+		return nil, fmt.Errorf("creating transfer: %v", err)
+	}
+
+	orgConfig, err := c.orgRepo.GetConfig(orgID)
+	if err != nil {
+		return nil, fmt.Errorf("getting org config: error getting config: %v", err)
+	}
+	if err := validateTransferTags(orgConfig, req.Tags); err != nil {
+		return nil, fmt.Errorf("creating transfer: %v", err)
+	}
+
+	source, err := GetFundflowSource(c.customersClient, c.accountDecryptor, req.Source, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("creating transfer: error getting fundflow source: %v", err)
+	}
+	destination, err := GetFundflowDestination(c.customersClient, c.accountDecryptor, req.Destination, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("creating transfer: error getting destination: %v", err)
+	}
+	if err := customers.AcceptableAccountStatus(&destination.Account); err != nil {
+		return nil, fmt.Errorf("creating transfer: unaccepted account status: %v", err)
+	}
+	if err := c.checkSuspended(orgID, req.Source.CustomerID, req.Destination.CustomerID); err != nil {
+		return nil, fmt.Errorf("creating transfer: %w", err)
+	}
+
+	// Use the values RDFIs corrected with Notifications of Change
+	if err := c.applyCorrections(orgID, req.Source.CustomerID, &source.Account, &source.AccountNumber); err != nil {
+		return nil, fmt.Errorf("creating transfer: %v", err)
+	}
+	if err := c.applyCorrections(orgID, req.Destination.CustomerID, &destination.Account, &destination.AccountNumber); err != nil {
+		return nil, fmt.Errorf("creating transfer: %v", err)
+	}
+
+	transferID := base.ID()
+	transfer := &client.Transfer{
+		TransferID:    transferID,
+		CorrelationID: correlationID(requestID, transferID),
+		Amount:        req.Amount,
+		Source:        req.Source,
+		Destination:   req.Destination,
+		Description:   req.Description,
+		Status:        client.PENDING,
+		SameDay:       req.SameDay,
+		EffectiveDate: effectiveDate,
+		Created:       time.Now(),
+		Tags:          req.Tags,
+		IatDetail:     req.IatDetail,
+	}
+	if req.SameDay {
+		if err := checkSameDay(c.cfg.Transfers.SameDay, c.cfg.ODFI.Cutoffs.Location(), transfer.Created, destination.Account.RoutingNumber, req.Amount); err != nil {
+			return nil, fmt.Errorf("creating transfer: same-day: %v", err)
+		}
+	}
+	applySameDayPreference(c.cfg, orgConfig, transfer.Created, destination.Account.RoutingNumber, req, transfer)
+
+	// Check transfer limits
+	if c.limitChecker != nil {
+		if err := c.limitChecker.Accept(orgID, userID, transfer); err != nil {
+			if !errors.Is(err, limiter.ErrReviewableTransfer) {
+				return nil, err
+			}
+			// Transfers over the soft limit are held out of merged files until an admin approves them
+			transfer.Status = client.REVIEWABLE
+		}
+		if warner, ok := c.limitChecker.(limiter.Warner); ok {
+			transfer.Warnings = warner.Warnings(orgID, userID, transfer)
+		}
+	}
+	if err := c.checkBalance(orgID, transfer); err != nil {
+		return nil, fmt.Errorf("creating transfer: %w", err)
+	}
+
+	// Apply the deployment's own rules and enrichment
+	enrichment, err := c.hookRunner.Run(hooks.Request{
+		Point:        config.HookPreTransferCreate,
+		Organization: orgID,
+		Transfer:     transfer,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("creating transfer: %v", err)
+	}
+	if enrichment.Description != "" && reversalOf == "" {
+		// Reversals must keep "REVERSAL" as their company entry description
+		transfer.Description = enrichment.Description
+	}
+	if len(enrichment.Tags) > 0 {
+		if err := validateTransferTags(orgConfig, enrichment.Tags); err != nil {
+			return nil, fmt.Errorf("creating transfer: tags from hook: %v", err)
+		}
+		transfer.Tags = enrichment.Tags
+	}
+
+	return &pendingTransfer{
+		transfer:    transfer,
+		orgConfig:   orgConfig,
+		source:      source,
+		destination: destination,
+	}, nil
+}
+
+// correlationID identifies a Transfer across log streams, the pipeline and notifications.
+// Transfers created without an X-Request-ID are identified by their transferID.
+func correlationID(requestID, transferID string) string {
+	if requestID != "" {
+		return requestID
+	}
+	return transferID
+}
+
+// originate creates the ACH files for a pending Transfer according to our strategy.
+func (c *creator) originate(orgID string, pending *pendingTransfer) error {
+	if c.fundStrategy == nil {
+		return errors.New("no fundflow strategy configured, unable to originate ACH files")
+	}
+
+	companyID := c.cfg.ODFI.ForOrganization(orgID).FileConfig.BatchHeader.CompanyIdentification
+	var flow string
+	if pending.orgConfig != nil {
+		companyID = util.Or(pending.orgConfig.CompanyIdentification, companyID)
+		flow = pending.orgConfig.FundingFlow
+	}
+	strategy, err := selectStrategy(c.fundStrategy, orgID, flow)
+	if err != nil {
+		return fmt.Errorf("creating transfer: %v", err)
+	}
+
+	files, err := strategy.Originate(companyID, pending.transfer, pending.source, pending.destination)
+	if err != nil {
+		return fmt.Errorf("creating transfer: error originating file: %v", err)
+	}
+	pending.files = files
+	return nil
+}
+
+// checkFiles applies the debit kill switch, debit authorizations, email verification and
+// exposure limits to originated files.
+func (c *creator) checkFiles(orgID, userID string, pending *pendingTransfer) error {
+	if err := c.debits.CheckFiles(orgID, pending.files); err != nil {
+		return fmt.Errorf("creating transfer: %w", err)
+	}
+	if err := c.checkDebitAuthorization(orgID, pending); err != nil {
+		return fmt.Errorf("creating transfer: %w", err)
+	}
+	if err := c.checkVerifiedEmail(orgID, pending); err != nil {
+		return fmt.Errorf("creating transfer: %w", err)
+	}
+	if err := c.exposure.CheckFiles(orgID, userID, pending.transfer, pending.files); err != nil {
+		return fmt.Errorf("creating transfer: %w", err)
+	}
+	return nil
+}
+
+// ErrCustomerSuspended is returned for Transfers with a Customer who matched OFAC when
+// they were screened again and hasn't been cleared.
+var ErrCustomerSuspended = errors.New("customer is suspended after an OFAC match")
+
+func (c *creator) checkSuspended(orgID string, customerIDs ...string) error {
+	for i := range customerIDs {
+		suspended, err := c.repo.customerSuspended(orgID, customerIDs[i])
+		if err != nil {
+			return fmt.Errorf("checking OFAC suspension: %v", err)
+		}
+		if suspended {
+			return fmt.Errorf("%w: customerID=%s", ErrCustomerSuspended, customerIDs[i])
+		}
+		deceased, err := c.repo.customerDeceased(orgID, customerIDs[i])
+		if err != nil {
+			return fmt.Errorf("checking death notifications: %v", err)
+		}
+		if deceased {
+			return fmt.Errorf("%w: customerID=%s", ErrCustomerDeceased, customerIDs[i])
+		}
+	}
+	return nil
+}
+
+// ErrUnverifiedEmail is returned for debit Transfers from a Customer who hasn't verified
+// their current email address when validation.emails.requiredForDebits is set.
+var ErrUnverifiedEmail = errors.New("customer has not verified their email")
+
+func (c *creator) checkVerifiedEmail(orgID string, pending *pendingTransfer) error {
+	if c.cfg.Validation.Emails == nil || !c.cfg.Validation.Emails.RequiredForDebits {
+		return nil
+	}
+	if debits, _ := limiter.RemoteAmounts(c.cfg.ODFI.RoutingNumber, pending.files); debits == 0 {
+		return nil
+	}
+	customerID := pending.transfer.Source.CustomerID
+	verified, err := c.repo.emailVerified(orgID, customerID, pending.source.Customer.Email)
+	if err != nil {
+		return fmt.Errorf("reading email verification: %v", err)
+	}
+	if !verified {
+		return fmt.Errorf("%w: customerID=%s", ErrUnverifiedEmail, customerID)
+	}
+	return nil
+}
+
+// checkBalance compares a Transfer against its source Account's balance. Pending Transfers
+// from the same Account are held against it as they haven't been sent yet.
+func (c *creator) checkBalance(orgID string, transfer *client.Transfer) error {
+	if c.balances == nil {
+		return nil
+	}
+	holds, err := c.repo.sourceHolds(orgID, transfer.Source.CustomerID, transfer.Source.AccountID)
+	if err != nil {
+		return fmt.Errorf("reading balance holds: %v", err)
+	}
+	return c.balances.Check(orgID, transfer, holds)
+}
+
+// blockedTransfer returns true for errors from checkFiles which reject the Transfer itself
+// rather than a problem reading limits.
+func blockedTransfer(err error) bool {
+	return errors.Is(err, killswitch.ErrDebitsBlocked) ||
+		errors.Is(err, ErrUnauthorizedDebit) ||
+		errors.Is(err, ErrUnverifiedEmail) ||
+		errors.Is(err, limiter.ErrDebitExposure) ||
+		errors.Is(err, limiter.ErrCreditExposure)
+}
+
+func (c *creator) notifyLimitsNearing(orgID string, transfer *client.Transfer) {
+	if c.events == nil || len(transfer.Warnings) == 0 {
+		return
+	}
+	event := webhooks.TransferEvent(webhooks.EventTransferLimitsNearing, orgID, webhooks.TransferUpdate{
+		TransferID:    transfer.TransferID,
+		Status:        transfer.Status,
+		CorrelationID: transfer.CorrelationID,
+		Warnings:      transfer.Warnings,
+	})
+	if err := c.events.Send(event); err != nil {
+		c.cfg.Logger.Set("transferID", transfer.TransferID).LogErrorf("problem sending limits nearing event: %v", err)
+	}
+}
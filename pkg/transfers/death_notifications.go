@@ -0,0 +1,154 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package transfers
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/moov-io/ach"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/transfers/history"
+)
+
+// ErrCustomerDeceased is returned for Transfers with a Customer whose Account a Death
+// Notification Entry (DNE) was received for.
+var ErrCustomerDeceased = errors.New("customer is deceased after a death notification")
+
+// EntryAccount is the Customer and Account a Transfer's uploaded entry was sent to.
+type EntryAccount struct {
+	Organization string
+	CustomerID   string
+	AccountID    string
+}
+
+// DeathNotification records a Death Notification Entry (DNE) received for an Account.
+type DeathNotification struct {
+	EntryAccount
+
+	TraceNumber string
+	DateOfDeath *time.Time
+	Received    time.Time
+}
+
+// LookupEntryAccounts returns each Customer and Account we've uploaded an entry to routingNumber
+// (the first eight digits) and accountNumber for. Debits are from a Transfer's source and credits
+// are to its destination.
+func (r *sqlRepo) LookupEntryAccounts(routingNumber string, accountNumber string) ([]EntryAccount, error) {
+	query := `select xf.organization, xf.source_customer_id, xf.source_account_id, xf.destination_customer_id, xf.destination_account_id, e.entry_detail
+from transfers as xf
+inner join transfer_trace_numbers as tn on xf.transfer_id = tn.transfer_id
+inner join uploaded_file_entries as e on tn.trace_number = e.trace_number
+where substr(e.entry_detail, 4, 8) = ? and trim(substr(e.entry_detail, 13, 17)) = ? and xf.deleted_at is null;`
+	rows, err := r.db.Query(query, routingNumber, accountNumber)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	seen := make(map[EntryAccount]bool)
+	var out []EntryAccount
+	for rows.Next() {
+		var src, dst EntryAccount
+		var line string
+		if err := rows.Scan(&src.Organization, &src.CustomerID, &src.AccountID, &dst.CustomerID, &dst.AccountID, &line); err != nil {
+			return nil, err
+		}
+		dst.Organization = src.Organization
+
+		entry := ach.NewEntryDetail()
+		entry.Parse(line)
+
+		acct := dst
+		if entry.CreditOrDebit() == "D" {
+			acct = src
+		}
+		if !seen[acct] {
+			seen[acct] = true
+			out = append(out, acct)
+		}
+	}
+	return out, rows.Err()
+}
+
+// SaveDeathNotification records the notification and cancels every PENDING or REVIEWABLE
+// Transfer to or from the Account. The canceled transferIDs are returned.
+func (r *sqlRepo) SaveDeathNotification(notification *DeathNotification) ([]string, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+
+	acct := notification.EntryAccount
+	query := `replace into death_notifications (organization, customer_id, account_id, trace_number, date_of_death, received_at) values (?, ?, ?, ?, ?, ?);`
+	_, err = tx.Exec(query, acct.Organization, acct.CustomerID, acct.AccountID, notification.TraceNumber, notification.DateOfDeath, notification.Received)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	unsent, err := unsentAccountTransfers(tx, acct)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	query = `update transfers set status = ?, cancel_reason = ?, cancel_note = ?, canceled_at = ? where transfer_id = ? and status = ? and deleted_at is null;`
+	var transferIDs []string
+	for i := range unsent {
+		transferID, status := unsent[i][0], unsent[i][1]
+		_, err := tx.Exec(query, client.CANCELED, client.CANCELLATIONREASON_COMPLIANCE, "death notification", notification.Received, transferID, status)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		changes := []history.Change{
+			{Field: "status", OldValue: status, NewValue: string(client.CANCELED)},
+			{Field: "cancelReason", NewValue: string(client.CANCELLATIONREASON_COMPLIANCE)},
+		}
+		if err := history.Record(tx, transferID, history.Inbound, changes...); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		transferIDs = append(transferIDs, transferID)
+	}
+
+	return transferIDs, tx.Commit()
+}
+
+// unsentAccountTransfers returns the transferID and status of each PENDING and REVIEWABLE
+// Transfer to or from an Account.
+func unsentAccountTransfers(tx *sql.Tx, acct EntryAccount) ([][2]string, error) {
+	query := `select transfer_id, status from transfers where organization = ? and status in (?, ?) and deleted_at is null
+and ((source_customer_id = ? and source_account_id = ?) or (destination_customer_id = ? and destination_account_id = ?))
+order by created_at asc;`
+	rows, err := tx.Query(query, acct.Organization, client.PENDING, client.REVIEWABLE, acct.CustomerID, acct.AccountID, acct.CustomerID, acct.AccountID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out [][2]string
+	for rows.Next() {
+		var row [2]string
+		if err := rows.Scan(&row[0], &row[1]); err != nil {
+			return nil, err
+		}
+		out = append(out, row)
+	}
+	return out, rows.Err()
+}
+
+// customerDeceased returns true when a Death Notification Entry was received for any of the
+// Customer's Accounts.
+func (r *sqlRepo) customerDeceased(orgID string, customerID string) (bool, error) {
+	query := `select count(*) from death_notifications where organization = ? and customer_id = ?;`
+	var n int
+	if err := r.db.QueryRow(query, orgID, customerID).Scan(&n); err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
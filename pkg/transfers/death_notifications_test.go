@@ -0,0 +1,106 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package transfers
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/moov-io/ach"
+	"github.com/moov-io/base"
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/transfers/files"
+	"github.com/moov-io/paygate/pkg/transfers/history"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepository__LookupEntryAccounts(t *testing.T) {
+	t.Parallel()
+
+	file, err := ach.ReadFile(filepath.Join("..", "..", "testdata", "ppd-debit.ach"))
+	require.NoError(t, err)
+	entry := file.Batches[0].GetEntries()[0]
+	accountNumber := strings.TrimSpace(entry.DFIAccountNumber)
+
+	check := func(t *testing.T, repo *sqlRepo) {
+		xfer := writeTransfer(t, "moov", repo)
+		require.NoError(t, repo.saveTraceNumbers(xfer.TransferID, []string{entry.TraceNumber}))
+		require.NoError(t, files.NewRepo(repo.db).RecordUpload(files.Upload{Filename: base.ID() + ".ach", RemoteServer: "sftp.bank.com:22", Uploaded: time.Now()}, file))
+
+		// debits are from the Transfer's source
+		accounts, err := repo.LookupEntryAccounts(entry.RDFIIdentification, accountNumber)
+		require.NoError(t, err)
+		require.Equal(t, []EntryAccount{
+			{Organization: "moov", CustomerID: xfer.Source.CustomerID, AccountID: xfer.Source.AccountID},
+		}, accounts)
+
+		accounts, err = repo.LookupEntryAccounts(entry.RDFIIdentification, "987654321")
+		require.NoError(t, err)
+		require.Empty(t, accounts)
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+}
+
+func TestRepository__SaveDeathNotification(t *testing.T) {
+	t.Parallel()
+
+	check := func(t *testing.T, repo *sqlRepo) {
+		pending, processed := writeTransfer(t, "moov", repo), writeTransfer(t, "moov", repo)
+		require.NoError(t, repo.UpdateTransferStatus(processed.TransferID, client.PROCESSED, history.API))
+
+		acct := EntryAccount{
+			Organization: "moov",
+			CustomerID:   pending.Source.CustomerID,
+			AccountID:    pending.Source.AccountID,
+		}
+		// point the processed Transfer at the same Account
+		_, err := repo.db.Exec(`update transfers set source_customer_id = ?, source_account_id = ? where transfer_id = ?;`, acct.CustomerID, acct.AccountID, processed.TransferID)
+		require.NoError(t, err)
+
+		deceased, err := repo.customerDeceased("moov", acct.CustomerID)
+		require.NoError(t, err)
+		require.False(t, deceased)
+
+		when := time.Date(2020, time.January, 2, 0, 0, 0, 0, time.UTC)
+		canceled, err := repo.SaveDeathNotification(&DeathNotification{
+			EntryAccount: acct,
+			TraceNumber:  "076401250000009",
+			DateOfDeath:  &when,
+			Received:     time.Now(),
+		})
+		require.NoError(t, err)
+		require.Equal(t, []string{pending.TransferID}, canceled)
+
+		xfer, err := repo.getUserTransfer(pending.TransferID, "moov")
+		require.NoError(t, err)
+		require.Equal(t, client.CANCELED, xfer.Status)
+
+		xfer, err = repo.getUserTransfer(processed.TransferID, "moov")
+		require.NoError(t, err)
+		require.Equal(t, client.PROCESSED, xfer.Status)
+
+		deceased, err = repo.customerDeceased("moov", acct.CustomerID)
+		require.NoError(t, err)
+		require.True(t, deceased)
+
+		// other organizations aren't affected
+		deceased, err = repo.customerDeceased("other", acct.CustomerID)
+		require.NoError(t, err)
+		require.False(t, deceased)
+
+		// notifications can be received again
+		canceled, err = repo.SaveDeathNotification(&DeathNotification{EntryAccount: acct, TraceNumber: "076401250000010", Received: time.Now()})
+		require.NoError(t, err)
+		require.Empty(t, canceled)
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+}
@@ -0,0 +1,57 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package transfers
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/moov-io/base"
+
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/util"
+)
+
+// normalizeEffectiveDate validates a caller supplied EffectiveDate and returns it in
+// YYYY-MM-DD format. When no date is supplied an empty string is returned and the
+// batch header is given its default EffectiveEntryDate.
+//
+// The earliest allowed date is today for same-day transfers and otherwise the next
+// banking day. Dates must fall on a banking day and be within the configured forward window.
+func normalizeEffectiveDate(cfg config.EffectiveDates, loc *time.Location, now time.Time, requested string, sameDay bool) (string, error) {
+	if requested == "" {
+		return "", nil
+	}
+	if loc == nil {
+		loc = time.UTC
+	}
+	today := base.NewTime(now.In(loc))
+
+	earliest := today.AddBankingDay(1)
+	if sameDay && today.IsBankingDay() {
+		earliest = today
+	}
+
+	when, err := time.ParseInLocation(util.YYMMDDTimeFormat, requested, loc)
+	if err != nil {
+		return "", fmt.Errorf("invalid effectiveDate=%q: %v", requested, err)
+	}
+	effective := base.NewTime(when)
+	if !effective.IsBankingDay() {
+		return "", fmt.Errorf("effectiveDate=%s is not a banking day", requested)
+	}
+	if sameDay && requested != today.Format(util.YYMMDDTimeFormat) {
+		return "", errors.New("same-day transfers must have an effectiveDate of today")
+	}
+	if requested < earliest.Format(util.YYMMDDTimeFormat) {
+		return "", fmt.Errorf("effectiveDate=%s is before the earliest allowed date of %s", requested, earliest.Format(util.YYMMDDTimeFormat))
+	}
+	latest := today.AddBankingDay(cfg.ForwardDays())
+	if requested > latest.Format(util.YYMMDDTimeFormat) {
+		return "", fmt.Errorf("effectiveDate=%s is after the latest allowed date of %s", requested, latest.Format(util.YYMMDDTimeFormat))
+	}
+	return requested, nil
+}
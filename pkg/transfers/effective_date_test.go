@@ -0,0 +1,54 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package transfers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/moov-io/paygate/pkg/config"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransfers__normalizeEffectiveDate(t *testing.T) {
+	cfg := config.EffectiveDates{MaxForwardDays: 3}
+	loc, _ := time.LoadLocation("America/New_York")
+
+	// Tuesday Nov 17th, 2020
+	now := time.Date(2020, time.November, 17, 10, 30, 0, 0, loc)
+
+	date, err := normalizeEffectiveDate(cfg, loc, now, "", false)
+	require.NoError(t, err)
+	require.Equal(t, "", date)
+
+	date, err = normalizeEffectiveDate(cfg, loc, now, "2020-11-17", true)
+	require.NoError(t, err)
+	require.Equal(t, "2020-11-17", date)
+
+	date, err = normalizeEffectiveDate(cfg, loc, now, "2020-11-19", false)
+	require.NoError(t, err)
+	require.Equal(t, "2020-11-19", date)
+
+	// same-day must be today
+	_, err = normalizeEffectiveDate(cfg, loc, now, "2020-11-19", true)
+	require.Error(t, err)
+
+	// too early
+	_, err = normalizeEffectiveDate(cfg, loc, now, "2020-11-17", false)
+	require.Error(t, err)
+
+	// weekend
+	_, err = normalizeEffectiveDate(cfg, loc, now, "2020-11-21", false)
+	require.Error(t, err)
+
+	// outside of forward window
+	_, err = normalizeEffectiveDate(cfg, loc, now, "2020-11-24", false)
+	require.Error(t, err)
+
+	// invalid format
+	_, err = normalizeEffectiveDate(cfg, loc, now, "11/19/2020", false)
+	require.Error(t, err)
+}
@@ -0,0 +1,156 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package transfers
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/moov-io/base/log"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/x/route"
+)
+
+// exportFlushRows is how many rows are buffered before being flushed to the client
+const exportFlushRows = 1000
+
+var exportColumns = map[string]func(*client.Transfer) string{
+	"transferID": func(t *client.Transfer) string { return t.TransferID },
+	"created":    func(t *client.Transfer) string { return t.Created.Format(time.RFC3339) },
+	"status":     func(t *client.Transfer) string { return string(t.Status) },
+	"currency":   func(t *client.Transfer) string { return t.Amount.Currency },
+	"amount":     func(t *client.Transfer) string { return strconv.FormatInt(int64(t.Amount.Value), 10) },
+	"description": func(t *client.Transfer) string {
+		return t.Description
+	},
+	"sourceCustomerID":      func(t *client.Transfer) string { return t.Source.CustomerID },
+	"sourceAccountID":       func(t *client.Transfer) string { return t.Source.AccountID },
+	"destinationCustomerID": func(t *client.Transfer) string { return t.Destination.CustomerID },
+	"destinationAccountID":  func(t *client.Transfer) string { return t.Destination.AccountID },
+	"sameDay":               func(t *client.Transfer) string { return strconv.FormatBool(t.SameDay) },
+	"effectiveDate":         func(t *client.Transfer) string { return t.EffectiveDate },
+	"returnCode": func(t *client.Transfer) string {
+		if t.ReturnCode != nil {
+			return t.ReturnCode.Code
+		}
+		return ""
+	},
+	"processedAt": func(t *client.Transfer) string {
+		if t.ProcessedAt != nil {
+			return t.ProcessedAt.Format(time.RFC3339)
+		}
+		return ""
+	},
+}
+
+var defaultExportColumns = []string{
+	"transferID", "created", "status", "currency", "amount", "description",
+	"sourceCustomerID", "sourceAccountID", "destinationCustomerID", "destinationAccountID",
+	"effectiveDate", "returnCode",
+}
+
+func readExportColumns(raw string) ([]string, error) {
+	if raw == "" {
+		return defaultExportColumns, nil
+	}
+	columns := strings.Split(raw, ",")
+	for i := range columns {
+		if _, exists := exportColumns[columns[i]]; !exists {
+			return nil, fmt.Errorf("unknown column %q", columns[i])
+		}
+	}
+	return columns, nil
+}
+
+// ExportTransfers streams Transfers matching the request's filters as CSV. Rows are read
+// from a cursored query and written as they're read, so large exports aren't held in memory.
+func ExportTransfers(cfg *config.Config, repo Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(cfg, w, r)
+
+		params, err := readRequestedView(repo, responder.OrganizationID, r, readTransferFilterParams(r))
+		if err != nil {
+			responder.Problem(err)
+			return
+		}
+		q := r.URL.Query()
+		columns, err := readExportColumns(q.Get("columns"))
+		if err != nil {
+			responder.Problem(err)
+			return
+		}
+		params.Count = cfg.Transfers.Export.RowLimit()
+		if v := q.Get("limit"); v != "" {
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil || n <= 0 {
+				responder.Problem(fmt.Errorf("invalid limit %q", v))
+				return
+			}
+			if n < params.Count {
+				params.Count = n
+			}
+		}
+
+		flusher, _ := w.(http.Flusher)
+		responder.Respond(func(w http.ResponseWriter) {
+			w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+			w.Header().Set("Content-Disposition", `attachment; filename="transfers.csv"`)
+			w.Header().Set("X-Row-Limit", strconv.FormatInt(params.Count, 10))
+			w.WriteHeader(http.StatusOK)
+
+			logger := cfg.Logger.With(log.Fields{
+				"organization": responder.OrganizationID,
+				"requestID":    responder.XRequestID,
+			})
+			rows, skipped, err := writeTransfersCSV(w, flusher, repo, responder.OrganizationID, params, columns)
+			for i := range skipped {
+				logger.Set("transferID", skipped[i].ID).LogErrorf("omitted unreadable transfer from export: %v", skipped[i].Err)
+			}
+			if err != nil {
+				// The status is already sent, so the client sees a truncated file
+				logger.LogErrorf("problem exporting transfers after %d rows: %v", rows, err)
+				return
+			}
+			logger.Logf("exported %d transfers", rows)
+		})
+	}
+}
+
+func writeTransfersCSV(w http.ResponseWriter, flusher http.Flusher, repo Repository, orgID string, params transferFilterParams, columns []string) (int, []skippedRow, error) {
+	out := csv.NewWriter(w)
+	if err := out.Write(columns); err != nil {
+		return 0, nil, err
+	}
+
+	rows := 0
+	record := make([]string, len(columns))
+	skipped, err := repo.streamTransfers(orgID, params, func(xfer *client.Transfer) error {
+		for i := range columns {
+			record[i] = exportColumns[columns[i]](xfer)
+		}
+		if err := out.Write(record); err != nil {
+			return err
+		}
+		rows++
+		if rows%exportFlushRows == 0 {
+			out.Flush()
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+		return out.Error()
+	})
+	out.Flush()
+	if err == nil {
+		err = out.Error()
+	}
+	return rows, skipped, err
+}
@@ -0,0 +1,152 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package transfers
+
+import (
+	"encoding/csv"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/moov-io/base"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepository__streamTransfers(t *testing.T) {
+	t.Parallel()
+
+	check := func(t *testing.T, repo *sqlRepo) {
+		orgID := base.ID()
+		first := writeTransfer(t, orgID, repo)
+		second := writeTransfer(t, orgID, repo)
+		writeTransfer(t, base.ID(), repo) // other organization
+
+		params := readTransferFilterParams(&http.Request{})
+		params.Count = 10
+
+		var found []*client.Transfer
+		skipped, err := repo.streamTransfers(orgID, params, func(xfer *client.Transfer) error {
+			found = append(found, xfer)
+			return nil
+		})
+		require.NoError(t, err)
+		require.Len(t, skipped, 0)
+		require.Len(t, found, 2)
+
+		ids := []string{found[0].TransferID, found[1].TransferID}
+		require.ElementsMatch(t, []string{first.TransferID, second.TransferID}, ids)
+		require.Equal(t, first.Amount.Value, found[0].Amount.Value)
+
+		// the row cap is applied to the query
+		params.Count = 1
+		found = nil
+		_, err = repo.streamTransfers(orgID, params, func(xfer *client.Transfer) error {
+			found = append(found, xfer)
+			return nil
+		})
+		require.NoError(t, err)
+		require.Len(t, found, 1)
+
+		// errors from fn stop reading
+		params.Count = 10
+		calls := 0
+		_, err = repo.streamTransfers(orgID, params, func(xfer *client.Transfer) error {
+			calls++
+			return errors.New("client went away")
+		})
+		require.Error(t, err)
+		require.Equal(t, 1, calls)
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+}
+
+func TestRepository__streamTransfersSkipped(t *testing.T) {
+	orgID := base.ID()
+	repo := setupSQLiteDB(t)
+
+	writeTransfer(t, orgID, repo)
+	corrupt := writeTransfer(t, orgID, repo)
+
+	_, err := repo.db.Exec(`update transfers set amount_value = 'bad' where transfer_id = ?;`, corrupt.TransferID)
+	require.NoError(t, err)
+
+	params := readTransferFilterParams(&http.Request{})
+	rows := 0
+	skipped, err := repo.streamTransfers(orgID, params, func(xfer *client.Transfer) error {
+		rows++
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, rows)
+	require.Len(t, skipped, 1)
+	require.Equal(t, corrupt.TransferID, skipped[0].ID)
+}
+
+func TestTransfers__readExportColumns(t *testing.T) {
+	columns, err := readExportColumns("")
+	require.NoError(t, err)
+	require.Equal(t, defaultExportColumns, columns)
+
+	columns, err = readExportColumns("transferID,amount,processedAt")
+	require.NoError(t, err)
+	require.Equal(t, []string{"transferID", "amount", "processedAt"}, columns)
+
+	_, err = readExportColumns("transferID,password")
+	require.Error(t, err)
+}
+
+func TestRouter__ExportTransfers(t *testing.T) {
+	cfg := config.Empty()
+	cfg.Transfers.Export.MaxRows = 100
+
+	r := mux.NewRouter()
+	router := NewRouter(cfg, repoWithTransfer, orgRepo, mockCustomersClient(), mockDecryptor, mockStrategy, fakePublisher, nil, nil, nil, nil, nil)
+	router.RegisterRoutes(r)
+
+	req := httptest.NewRequest("GET", "/transfers.csv?columns=transferID,amount,status&limit=5000", nil)
+	req.Header.Set("X-Organization", "moov")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	w.Flush()
+
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+	require.Equal(t, "100", w.Header().Get("X-Row-Limit"))
+
+	records, err := csv.NewReader(w.Body).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 1+len(repoWithTransfer.Transfers))
+	require.Equal(t, []string{"transferID", "amount", "status"}, records[0])
+
+	xfer := repoWithTransfer.Transfers[0]
+	require.Equal(t, xfer.TransferID, records[1][0])
+	require.Equal(t, string(xfer.Status), records[1][2])
+}
+
+func TestRouter__ExportTransfersErr(t *testing.T) {
+	r := mux.NewRouter()
+	router := NewRouter(config.Empty(), repoWithTransfer, orgRepo, mockCustomersClient(), mockDecryptor, mockStrategy, fakePublisher, nil, nil, nil, nil, nil)
+	router.RegisterRoutes(r)
+
+	for _, u := range []string{"/transfers.csv?columns=other", "/transfers.csv?limit=-1"} {
+		req := httptest.NewRequest("GET", u, nil)
+		req.Header.Set("X-Organization", "moov")
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		w.Flush()
+
+		require.Equal(t, http.StatusBadRequest, w.Code, u)
+	}
+}
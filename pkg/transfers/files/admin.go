@@ -0,0 +1,73 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package files
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/moov-io/base/admin"
+	moovhttp "github.com/moov-io/base/http"
+
+	paygateadmin "github.com/moov-io/paygate/pkg/admin"
+	"github.com/moov-io/paygate/x/route"
+)
+
+// RegisterAdminRoutes adds endpoints to inspect uploaded files and their acknowledgements.
+func RegisterAdminRoutes(svc *admin.Server, repo Repository) {
+	svc.AddHandler("/files", listFiles(repo))
+	svc.AddHandler("/files/{fileID}", getFile(repo))
+}
+
+func problem(w http.ResponseWriter, err error) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	moovhttp.Problem(w, err)
+}
+
+func listFiles(repo Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			problem(w, fmt.Errorf("invalid method %s", r.Method))
+			return
+		}
+		limit := int(route.ReadLimit(r))
+		if limit <= 0 {
+			limit = 100
+		}
+		files, err := repo.listFiles(limit)
+		if err != nil {
+			problem(w, err)
+			return
+		}
+		if files == nil {
+			files = make([]*paygateadmin.UploadedFile, 0)
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(files)
+	}
+}
+
+func getFile(repo Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			problem(w, fmt.Errorf("invalid method %s", r.Method))
+			return
+		}
+		file, err := repo.getFile(route.ReadPathID("fileID", r))
+		if err != nil {
+			problem(w, err)
+			return
+		}
+		if file == nil {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(file)
+	}
+}
@@ -0,0 +1,76 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package files
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/moov-io/paygate/pkg/admin"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdmin__listFiles(t *testing.T) {
+	repo := &MockRepository{
+		Files: []*admin.UploadedFile{
+			{Filename: "20200601-987654320.ach"},
+		},
+	}
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/files?limit=5", nil)
+	listFiles(repo).ServeHTTP(w, req)
+	w.Flush()
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var files []admin.UploadedFile
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&files))
+	require.Len(t, files, 1)
+
+	repo.Err = errors.New("bad error")
+	w = httptest.NewRecorder()
+	listFiles(repo).ServeHTTP(w, req)
+	w.Flush()
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestAdmin__getFile(t *testing.T) {
+	repo := &MockRepository{
+		Files: []*admin.UploadedFile{
+			{
+				FileID:   "7e2ac0cc",
+				Filename: "20200601-987654320.ach",
+				Batches: []admin.UploadedBatch{
+					{BatchNumber: 1, Entries: 2, Status: StatusAccepted},
+				},
+			},
+		},
+	}
+	router := mux.NewRouter()
+	router.Handle("/files/{fileID}", getFile(repo))
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/files/7e2ac0cc", nil)
+	router.ServeHTTP(w, req)
+	w.Flush()
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var file admin.UploadedFile
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&file))
+	require.Equal(t, "20200601-987654320.ach", file.Filename)
+	require.Len(t, file.Batches, 1)
+	require.Equal(t, StatusAccepted, file.Batches[0].Status)
+
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest("GET", "/files/missing.ach", nil)
+	router.ServeHTTP(w, req)
+	w.Flush()
+	require.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,84 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package files
+
+import (
+	"time"
+
+	"github.com/moov-io/ach"
+
+	"github.com/moov-io/paygate/pkg/admin"
+)
+
+type MockRepository struct {
+	Files           []*admin.UploadedFile
+	Uploaded        []string
+	Acknowledgement *Acknowledgement
+	Sequence        int
+	SequenceDay     time.Time
+	Origination     int64
+	Err             error
+}
+
+func (r *MockRepository) RecordUpload(upload Upload, file *ach.File) error {
+	if r.Err != nil {
+		return r.Err
+	}
+	r.Uploaded = append(r.Uploaded, upload.Filename)
+	return nil
+}
+
+func (r *MockRepository) SaveAcknowledgement(ack Acknowledgement, received time.Time) error {
+	if r.Err != nil {
+		return r.Err
+	}
+	r.Acknowledgement = &ack
+	return nil
+}
+
+func (r *MockRepository) NextSequence(routingNumber string, day time.Time) (int, error) {
+	if r.Err != nil {
+		return 0, r.Err
+	}
+	r.SequenceDay = day
+	r.Sequence++
+	return r.Sequence, nil
+}
+
+func (r *MockRepository) ReleaseSequence(routingNumber string, day time.Time, seq int) error {
+	if r.Err != nil {
+		return r.Err
+	}
+	if r.Sequence == seq {
+		r.Sequence--
+	}
+	return nil
+}
+
+func (r *MockRepository) Originated(routingNumber string, day time.Time) (int64, error) {
+	if r.Err != nil {
+		return 0, r.Err
+	}
+	return r.Origination, nil
+}
+
+func (r *MockRepository) listFiles(limit int) ([]*admin.UploadedFile, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	return r.Files, nil
+}
+
+func (r *MockRepository) getFile(fileID string) (*admin.UploadedFile, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	for i := range r.Files {
+		if r.Files[i].FileID == fileID {
+			return r.Files[i], nil
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,452 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+// Package files keeps a history of ACH files uploaded to the ODFI along with
+// the ODFI's acknowledgement of each batch.
+package files
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/moov-io/ach"
+	"github.com/moov-io/base"
+
+	"github.com/moov-io/paygate/pkg/admin"
+	"github.com/moov-io/paygate/pkg/database"
+)
+
+const (
+	StatusPending  = "pending"
+	StatusAccepted = "accepted"
+	StatusRejected = "rejected"
+)
+
+var ErrUnknownFile = errors.New("file was not found in upload history")
+
+// Acknowledgement is an ODFI's response to one uploaded file.
+type Acknowledgement struct {
+	Filename string
+	Batches  []BatchAcknowledgement
+}
+
+type BatchAcknowledgement struct {
+	BatchNumber int
+	Accepted    bool
+	Reason      string
+}
+
+// Upload describes where a file was uploaded along with the exact bytes which were sent.
+// FileID identifies the upload, as several uploads can share a Filename.
+type Upload struct {
+	FileID       string
+	Filename     string
+	RemoteServer string
+	RemotePath   string
+	Contents     []byte
+	Uploaded     time.Time
+}
+
+// Checksum returns the hex encoded SHA-256 of the uploaded contents.
+func (u Upload) Checksum() string {
+	sum := sha256.Sum256(u.Contents)
+	return hex.EncodeToString(sum[:])
+}
+
+type Repository interface {
+	// RecordUpload saves a file into the upload history with each batch pending. Each
+	// entry's trace number is kept so Transfers can be traced to the files they were sent in.
+	RecordUpload(upload Upload, file *ach.File) error
+
+	// SaveAcknowledgement updates the batches of the latest file uploaded with the
+	// acknowledged filename. ErrUnknownFile is returned for files which aren't in the
+	// upload history.
+	SaveAcknowledgement(ack Acknowledgement, received time.Time) error
+
+	// NextSequence reserves the next sequence for files sent to a routing number on the
+	// given day. Sequences start at 1 and are never handed out twice, even when several
+	// instances merge files at once.
+	NextSequence(routingNumber string, day time.Time) (int, error)
+
+	// ReleaseSequence returns a sequence for a file which wasn't sent. It's only handed
+	// out again when no later sequence was reserved for the routing number and day.
+	ReleaseSequence(routingNumber string, day time.Time, seq int) error
+
+	// Originated returns the total in cents of debits and credits uploaded for a
+	// routing number on the given day.
+	Originated(routingNumber string, day time.Time) (int64, error)
+
+	listFiles(limit int) ([]*admin.UploadedFile, error)
+	getFile(fileID string) (*admin.UploadedFile, error)
+}
+
+func NewRepo(db *sql.DB) *sqlRepo {
+	return &sqlRepo{db: db}
+}
+
+type sqlRepo struct {
+	db *sql.DB
+}
+
+func (r *sqlRepo) Close() error {
+	if r == nil || r.db == nil {
+		return nil
+	}
+	return r.db.Close()
+}
+
+func (r *sqlRepo) RecordUpload(upload Upload, file *ach.File) error {
+	if file == nil {
+		return errors.New("nil ach.File")
+	}
+	if upload.FileID == "" {
+		upload.FileID = base.ID()
+	}
+	fileID, uploaded := upload.FileID, upload.Uploaded
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	query := `insert into uploaded_files(file_id, filename, routing_number, remote_server, remote_path, size_bytes, checksum, uploaded_at) values (?, ?, ?, ?, ?, ?, ?, ?);`
+	_, err = tx.Exec(query, fileID, upload.Filename, file.Header.ImmediateDestination, upload.RemoteServer, upload.RemotePath, len(upload.Contents), upload.Checksum(), uploaded)
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("saving file: %v", err)
+	}
+
+	query = `insert into uploaded_file_batches(file_id, batch_number, entries, status) values (?, ?, ?, ?);`
+	for i := range file.Batches {
+		bh := file.Batches[i].GetHeader()
+		entries := len(file.Batches[i].GetEntries())
+		if _, err := tx.Exec(query, fileID, bh.BatchNumber, entries, StatusPending); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("saving batch %d: %v", bh.BatchNumber, err)
+		}
+	}
+	for i := range file.IATBatches {
+		bh := file.IATBatches[i].GetHeader()
+		entries := len(file.IATBatches[i].GetEntries())
+		if _, err := tx.Exec(query, fileID, bh.BatchNumber, entries, StatusPending); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("saving IAT batch %d: %v", bh.BatchNumber, err)
+		}
+	}
+
+	query = `insert into uploaded_file_entries(file_id, batch_number, trace_number, entry_detail) values (?, ?, ?, ?);`
+	for i := range file.Batches {
+		bh := file.Batches[i].GetHeader()
+		entries := file.Batches[i].GetEntries()
+		for j := range entries {
+			if _, err := tx.Exec(query, fileID, bh.BatchNumber, entries[j].TraceNumber, entries[j].String()); err != nil {
+				tx.Rollback()
+				return fmt.Errorf("saving entry %s: %v", entries[j].TraceNumber, err)
+			}
+		}
+	}
+	for i := range file.IATBatches {
+		bh := file.IATBatches[i].GetHeader()
+		entries := file.IATBatches[i].GetEntries()
+		for j := range entries {
+			if _, err := tx.Exec(query, fileID, bh.BatchNumber, entries[j].TraceNumber, entries[j].String()); err != nil {
+				tx.Rollback()
+				return fmt.Errorf("saving IAT entry %s: %v", entries[j].TraceNumber, err)
+			}
+		}
+	}
+
+	amount := int64(file.Control.TotalDebitEntryDollarAmountInFile + file.Control.TotalCreditEntryDollarAmountInFile)
+	if err := recordOrigination(tx, file.Header.ImmediateDestination, uploaded, amount); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("saving origination: %v", err)
+	}
+
+	return tx.Commit()
+}
+
+// recordOrigination adds amount to the day's total for a routing number.
+func recordOrigination(tx *sql.Tx, routingNumber string, day time.Time, amount int64) error {
+	date := day.Format("20060102")
+
+	query := `update daily_originations set amount = amount + ? where routing_number = ? and day = ?;`
+	res, err := tx.Exec(query, amount, routingNumber, date)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		return nil
+	}
+	query = `insert into daily_originations(routing_number, day, amount) values (?, ?, ?);`
+	if _, err := tx.Exec(query, routingNumber, date, amount); err != nil {
+		if database.UniqueViolation(err) {
+			// another instance recorded the day's first upload
+			query = `update daily_originations set amount = amount + ? where routing_number = ? and day = ?;`
+			_, err = tx.Exec(query, amount, routingNumber, date)
+		}
+		return err
+	}
+	return nil
+}
+
+func (r *sqlRepo) Originated(routingNumber string, day time.Time) (int64, error) {
+	query := `select amount from daily_originations where routing_number = ? and day = ? limit 1;`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	var amount int64
+	if err := stmt.QueryRow(routingNumber, day.Format("20060102")).Scan(&amount); err != nil {
+		if err == sql.ErrNoRows {
+			return 0, nil
+		}
+		return 0, err
+	}
+	return amount, nil
+}
+
+func (r *sqlRepo) SaveAcknowledgement(ack Acknowledgement, received time.Time) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	// Acknowledgements only name the file, so they're for the latest upload with that filename.
+	var fileID string
+	query := `select file_id from uploaded_files where filename = ? order by uploaded_at desc limit 1;`
+	if err := tx.QueryRow(query, ack.Filename).Scan(&fileID); err != nil {
+		tx.Rollback()
+		if err == sql.ErrNoRows {
+			return ErrUnknownFile
+		}
+		return fmt.Errorf("reading file: %v", err)
+	}
+
+	query = `update uploaded_files set acknowledged_at = ? where file_id = ?;`
+	if _, err := tx.Exec(query, received, fileID); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("updating file: %v", err)
+	}
+
+	for i := range ack.Batches {
+		status := StatusRejected
+		if ack.Batches[i].Accepted {
+			status = StatusAccepted
+		}
+		batchNumber := ack.Batches[i].BatchNumber
+
+		// MySQL reports no affected rows for updates which don't change anything, so check
+		// for each row before updating it.
+		var n int
+		query = `select count(*) from uploaded_file_batches where file_id = ? and batch_number = ?;`
+		if err := tx.QueryRow(query, fileID, batchNumber).Scan(&n); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("reading batch %d: %v", batchNumber, err)
+		}
+		if n == 0 {
+			// keep batches we didn't record so nothing the ODFI reported is lost
+			query = `insert into uploaded_file_batches(file_id, batch_number, entries, status, reason) values (?, ?, 0, ?, ?);`
+			_, err = tx.Exec(query, fileID, batchNumber, status, ack.Batches[i].Reason)
+		} else {
+			query = `update uploaded_file_batches set status = ?, reason = ? where file_id = ? and batch_number = ?;`
+			_, err = tx.Exec(query, status, ack.Batches[i].Reason, fileID, batchNumber)
+		}
+		if err != nil {
+			tx.Rollback()
+			return fmt.Errorf("saving batch %d: %v", batchNumber, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// maxSequenceAttempts is how many times reserving a sequence is tried when it conflicts
+// with other instances.
+const maxSequenceAttempts = 3
+
+func (r *sqlRepo) NextSequence(routingNumber string, day time.Time) (int, error) {
+	var seq int
+	var err error
+	for i := 0; i < maxSequenceAttempts; i++ {
+		seq, err = r.nextSequence(routingNumber, day)
+		// Another instance created the day's first sequence, or on MySQL our first-of-day
+		// update and insert deadlocked with theirs. Either way try again to increment theirs.
+		if err == nil || !(database.UniqueViolation(err) || database.MySQLDeadlock(err)) {
+			break
+		}
+	}
+	return seq, err
+}
+
+func (r *sqlRepo) ReleaseSequence(routingNumber string, day time.Time, seq int) error {
+	query := `update outbound_file_sequences set sequence = sequence - 1 where routing_number = ? and day = ? and sequence = ?;`
+	_, err := r.db.Exec(query, routingNumber, day.Format("20060102"), seq)
+	return err
+}
+
+func (r *sqlRepo) nextSequence(routingNumber string, day time.Time) (int, error) {
+	date := day.Format("20060102")
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+
+	// The update locks the row until our transaction commits, so concurrent callers wait
+	// for us rather than reading the same sequence.
+	query := `update outbound_file_sequences set sequence = sequence + 1 where routing_number = ? and day = ?;`
+	res, err := tx.Exec(query, routingNumber, date)
+	if err != nil {
+		tx.Rollback()
+		return 0, fmt.Errorf("incrementing sequence: %v", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		query = `insert into outbound_file_sequences(routing_number, day, sequence) values (?, ?, 1);`
+		if _, err := tx.Exec(query, routingNumber, date); err != nil {
+			tx.Rollback()
+			return 0, err
+		}
+	}
+
+	var seq int
+	query = `select sequence from outbound_file_sequences where routing_number = ? and day = ?;`
+	if err := tx.QueryRow(query, routingNumber, date).Scan(&seq); err != nil {
+		tx.Rollback()
+		return 0, fmt.Errorf("reading sequence: %v", err)
+	}
+	return seq, tx.Commit()
+}
+
+func (r *sqlRepo) listFiles(limit int) ([]*admin.UploadedFile, error) {
+	query := `select file_id from uploaded_files order by uploaded_at desc limit ?;`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.Query(limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var fileIDs []string
+	for rows.Next() {
+		var fileID string
+		if err := rows.Scan(&fileID); err != nil {
+			return nil, err
+		}
+		fileIDs = append(fileIDs, fileID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var out []*admin.UploadedFile
+	for i := range fileIDs {
+		file, err := r.getFile(fileIDs[i])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", fileIDs[i], err)
+		}
+		out = append(out, file)
+	}
+	return out, nil
+}
+
+// getFile returns the file uploaded with fileID. Files uploaded before they were given IDs
+// use their filename as their ID.
+func (r *sqlRepo) getFile(fileID string) (*admin.UploadedFile, error) {
+	query := `select file_id, filename, routing_number, remote_server, remote_path, size_bytes, checksum, uploaded_at, acknowledged_at from uploaded_files where file_id = ? limit 1;`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	var file admin.UploadedFile
+	var remoteServer, remotePath, checksum *string
+	var size *int64
+	err = stmt.QueryRow(fileID).Scan(&file.FileID, &file.Filename, &file.RoutingNumber, &remoteServer, &remotePath, &size, &checksum, &file.Uploaded, &file.Acknowledged)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if remoteServer != nil {
+		file.RemoteServer = *remoteServer
+	}
+	if remotePath != nil {
+		file.RemotePath = *remotePath
+	}
+	if size != nil {
+		file.Size = *size
+	}
+	if checksum != nil {
+		file.Checksum = *checksum
+	}
+
+	query = `select batch_number, entries, status, reason from uploaded_file_batches where file_id = ? order by batch_number asc;`
+	stmt, err = r.db.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.Query(fileID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var batch admin.UploadedBatch
+		var reason *string
+		if err := rows.Scan(&batch.BatchNumber, &batch.Entries, &batch.Status, &reason); err != nil {
+			return nil, err
+		}
+		if reason != nil {
+			batch.Reason = *reason
+		}
+		file.Batches = append(file.Batches, batch)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	file.TransferIDs, err = r.getFileTransferIDs(fileID)
+	if err != nil {
+		return nil, fmt.Errorf("reading transferIDs: %v", err)
+	}
+	return &file, nil
+}
+
+// getFileTransferIDs returns each Transfer with an entry in the file, matched by trace number.
+func (r *sqlRepo) getFileTransferIDs(fileID string) ([]string, error) {
+	query := `select distinct trace.transfer_id from uploaded_file_entries entry
+inner join transfer_trace_numbers trace on entry.trace_number = trace.trace_number
+where entry.file_id = ? order by trace.transfer_id asc;`
+	rows, err := r.db.Query(query, fileID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []string
+	for rows.Next() {
+		var transferID string
+		if err := rows.Scan(&transferID); err != nil {
+			return nil, err
+		}
+		out = append(out, transferID)
+	}
+	return out, rows.Err()
+}
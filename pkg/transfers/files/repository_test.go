@@ -0,0 +1,244 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package files
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/moov-io/ach"
+	"github.com/moov-io/base"
+
+	"github.com/moov-io/paygate/pkg/database"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepository__Acknowledgements(t *testing.T) {
+	t.Parallel()
+
+	file, err := ach.ReadFile(filepath.Join("..", "..", "..", "testdata", "ppd-debit.ach"))
+	require.NoError(t, err)
+
+	check := func(t *testing.T, repo *sqlRepo) {
+		filename := base.ID() + ".ach"
+		transferID := base.ID()
+		_, err := repo.db.Exec(`insert into transfer_trace_numbers(transfer_id, trace_number) values (?, ?);`, transferID, file.Batches[0].GetEntries()[0].TraceNumber)
+		require.NoError(t, err)
+
+		upload := Upload{
+			FileID:       base.ID(),
+			Filename:     filename,
+			RemoteServer: "sftp.bank.com",
+			RemotePath:   "outbound/",
+			Contents:     []byte("file contents"),
+			Uploaded:     time.Now(),
+		}
+		require.NoError(t, repo.RecordUpload(upload, file))
+
+		uploaded, err := repo.getFile(upload.FileID)
+		require.NoError(t, err)
+		require.Equal(t, upload.FileID, uploaded.FileID)
+		require.Equal(t, filename, uploaded.Filename)
+		require.Equal(t, file.Header.ImmediateDestination, uploaded.RoutingNumber)
+		require.Equal(t, "sftp.bank.com", uploaded.RemoteServer)
+		require.Equal(t, "outbound/", uploaded.RemotePath)
+		require.Equal(t, int64(13), uploaded.Size)
+		require.Equal(t, "7bb6f9f7a47a63e684925af3608c059edcc371eb81188c48c9714896fb1091fd", uploaded.Checksum)
+		require.Equal(t, []string{transferID}, uploaded.TransferIDs)
+		require.Nil(t, uploaded.Acknowledged)
+		require.Len(t, uploaded.Batches, 1)
+		require.Equal(t, StatusPending, uploaded.Batches[0].Status)
+		require.Equal(t, int32(1), uploaded.Batches[0].Entries)
+
+		batchNumber := file.Batches[0].GetHeader().BatchNumber
+		ack := Acknowledgement{
+			Filename: filename,
+			Batches: []BatchAcknowledgement{
+				{BatchNumber: batchNumber, Accepted: false, Reason: "R01 invalid company id"},
+				{BatchNumber: batchNumber + 1, Accepted: true},
+			},
+		}
+		require.NoError(t, repo.SaveAcknowledgement(ack, time.Now()))
+		require.NoError(t, repo.SaveAcknowledgement(ack, time.Now())) // duplicate delivery
+
+		uploaded, err = repo.getFile(upload.FileID)
+		require.NoError(t, err)
+		require.NotNil(t, uploaded.Acknowledged)
+		require.Len(t, uploaded.Batches, 2)
+		require.Equal(t, StatusRejected, uploaded.Batches[0].Status)
+		require.Equal(t, "R01 invalid company id", uploaded.Batches[0].Reason)
+		require.Equal(t, StatusAccepted, uploaded.Batches[1].Status)
+
+		files, err := repo.listFiles(10)
+		require.NoError(t, err)
+		require.Len(t, files, 1)
+
+		// files we didn't upload
+		ack.Filename = "other.ach"
+		require.Equal(t, ErrUnknownFile, repo.SaveAcknowledgement(ack, time.Now()))
+
+		uploaded, err = repo.getFile("other.ach")
+		require.NoError(t, err)
+		require.Nil(t, uploaded)
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+}
+
+func TestRepository__sameFilename(t *testing.T) {
+	t.Parallel()
+
+	file, err := ach.ReadFile(filepath.Join("..", "..", "..", "testdata", "ppd-debit.ach"))
+	require.NoError(t, err)
+
+	check := func(t *testing.T, repo *sqlRepo) {
+		filename := base.ID() + ".ach"
+		now := time.Date(2020, time.June, 1, 12, 0, 0, 0, time.UTC)
+
+		// files with the same name are kept separately
+		older := Upload{FileID: base.ID(), Filename: filename, Contents: []byte("first"), Uploaded: now.Add(-time.Minute)}
+		require.NoError(t, repo.RecordUpload(older, file))
+		newer := Upload{Filename: filename, Contents: []byte("second"), Uploaded: now}
+		require.NoError(t, repo.RecordUpload(newer, file))
+
+		files, err := repo.listFiles(10)
+		require.NoError(t, err)
+		require.Len(t, files, 2)
+		require.NotEqual(t, files[0].FileID, files[1].FileID)
+		require.Equal(t, older.FileID, files[1].FileID)
+
+		originated, err := repo.Originated(file.Header.ImmediateDestination, now)
+		require.NoError(t, err)
+		require.Equal(t, 2*int64(file.Control.TotalDebitEntryDollarAmountInFile+file.Control.TotalCreditEntryDollarAmountInFile), originated)
+
+		// acknowledgements are for the latest upload
+		ack := Acknowledgement{
+			Filename: filename,
+			Batches: []BatchAcknowledgement{
+				{BatchNumber: file.Batches[0].GetHeader().BatchNumber, Accepted: true},
+			},
+		}
+		require.NoError(t, repo.SaveAcknowledgement(ack, now))
+
+		uploaded, err := repo.getFile(files[0].FileID)
+		require.NoError(t, err)
+		require.NotNil(t, uploaded.Acknowledged)
+		require.Equal(t, StatusAccepted, uploaded.Batches[0].Status)
+
+		uploaded, err = repo.getFile(older.FileID)
+		require.NoError(t, err)
+		require.Nil(t, uploaded.Acknowledged)
+		require.Equal(t, StatusPending, uploaded.Batches[0].Status)
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+}
+
+func TestRepository__NextSequence(t *testing.T) {
+	t.Parallel()
+
+	check := func(t *testing.T, repo *sqlRepo) {
+		now := time.Now()
+
+		seq, err := repo.NextSequence("987654320", now)
+		require.NoError(t, err)
+		require.Equal(t, 1, seq)
+
+		// other destinations and days have their own sequence
+		seq, err = repo.NextSequence("123456780", now)
+		require.NoError(t, err)
+		require.Equal(t, 1, seq)
+
+		seq, err = repo.NextSequence("987654320", now.Add(24*time.Hour))
+		require.NoError(t, err)
+		require.Equal(t, 1, seq)
+
+		// concurrent merges never share a sequence
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		seen := make(map[int]bool)
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				seq, err := repo.NextSequence("987654320", now)
+				require.NoError(t, err)
+
+				mu.Lock()
+				defer mu.Unlock()
+				require.False(t, seen[seq], "duplicate sequence %d", seq)
+				seen[seq] = true
+			}()
+		}
+		wg.Wait()
+		require.Len(t, seen, 10)
+		require.False(t, seen[1])
+
+		// only the latest sequence is released
+		require.NoError(t, repo.ReleaseSequence("987654320", now, 5))
+		seq, err = repo.NextSequence("987654320", now)
+		require.NoError(t, err)
+		require.Equal(t, 12, seq)
+
+		require.NoError(t, repo.ReleaseSequence("987654320", now, 12))
+		seq, err = repo.NextSequence("987654320", now)
+		require.NoError(t, err)
+		require.Equal(t, 12, seq)
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+}
+
+func setupSQLiteDB(t *testing.T) *sqlRepo {
+	db := database.CreateTestSqliteDB(t)
+	t.Cleanup(func() { db.Close() })
+
+	return NewRepo(db.DB)
+}
+
+func setupMySQLeDB(t *testing.T) *sqlRepo {
+	db := database.CreateTestMySQLDB(t)
+	t.Cleanup(func() { db.Close() })
+
+	return NewRepo(db.DB)
+}
+
+func TestRepository__Originated(t *testing.T) {
+	t.Parallel()
+
+	file, err := ach.ReadFile(filepath.Join("..", "..", "..", "testdata", "ppd-debit.ach"))
+	require.NoError(t, err)
+	amount := int64(file.Control.TotalDebitEntryDollarAmountInFile + file.Control.TotalCreditEntryDollarAmountInFile)
+	routingNumber := file.Header.ImmediateDestination
+
+	check := func(t *testing.T, repo *sqlRepo) {
+		day := time.Date(2020, time.June, 1, 14, 0, 0, 0, time.UTC)
+
+		originated, err := repo.Originated(routingNumber, day)
+		require.NoError(t, err)
+		require.Equal(t, int64(0), originated)
+
+		require.NoError(t, repo.RecordUpload(Upload{Filename: base.ID() + ".ach", RemoteServer: "sftp.bank.com", Uploaded: day}, file))
+		require.NoError(t, repo.RecordUpload(Upload{Filename: base.ID() + ".ach", RemoteServer: "sftp.bank.com", Uploaded: day.Add(time.Hour)}, file))
+
+		originated, err = repo.Originated(routingNumber, day)
+		require.NoError(t, err)
+		require.Equal(t, 2*amount, originated)
+
+		// other days are tracked separately
+		originated, err = repo.Originated(routingNumber, day.Add(24*time.Hour))
+		require.NoError(t, err)
+		require.Equal(t, int64(0), originated)
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+}
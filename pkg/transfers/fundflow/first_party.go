@@ -32,6 +32,8 @@ import (
 type FirstParty struct {
 	cfg    config.ODFI
 	logger log.Logger
+
+	traceNumbers achx.TraceNumbers
 }
 
 func NewFirstPerson(logger log.Logger, cfg config.ODFI) Strategy {
@@ -75,6 +77,7 @@ func (fp *FirstParty) Originate(companyID string, xfer *client.Transfer, src Sou
 		FileConfig:            fp.cfg.FileConfig,
 		CutoffTimezone:        fp.cfg.Cutoffs.Location(),
 		CompanyIdentification: companyID,
+		TraceNumbers:          fp.traceNumbers,
 	}
 	// Balance entries from transfers which appear to not be "account validation" (aka micro-deposits).
 	// Right now we're doing this by checking the amount which obviously isn't ideal.
@@ -5,6 +5,9 @@
 package fundflow
 
 import (
+	"fmt"
+	"time"
+
 	"github.com/moov-io/ach"
 	customers "github.com/moov-io/customers/pkg/client"
 	"github.com/moov-io/paygate/pkg/client"
@@ -30,3 +33,61 @@ type Destination struct {
 	// AccountNumber contains the decrypted account number from the customers service
 	AccountNumber string
 }
+
+// Flow names a funding Strategy an organization can select for its Transfers.
+type Flow string
+
+const (
+	// FirstPartyFlow moves funds directly between the ODFI and one remote account.
+	FirstPartyFlow Flow = "firstParty"
+
+	// PassThroughFlow debits the source and credits the destination in one file,
+	// with both entries settling against the ODFI's settlement account.
+	PassThroughFlow Flow = "passThrough"
+
+	// TwoLegFlow collects funds from the source first and only pays out to the
+	// destination once the debit has settled.
+	TwoLegFlow Flow = "twoLeg"
+)
+
+// ParseFlow returns the Flow named by v, which defaults to FirstPartyFlow when empty.
+func ParseFlow(v string) (Flow, error) {
+	switch Flow(v) {
+	case "", FirstPartyFlow:
+		return FirstPartyFlow, nil
+	case PassThroughFlow, TwoLegFlow:
+		return Flow(v), nil
+	}
+	return "", fmt.Errorf("unknown funding flow %q", v)
+}
+
+// Selector is implemented by Strategies which can delegate to the Strategy
+// an organization has configured.
+type Selector interface {
+	Select(flow Flow) (Strategy, error)
+}
+
+// Tenants is implemented by Strategies which originate files with the values of the
+// Tenant an organization is part of.
+type Tenants interface {
+	ForOrganization(orgID string) Strategy
+}
+
+// CreditOriginator is implemented by Strategies which hold the credit leg of a Transfer
+// until it's released.
+type CreditOriginator interface {
+	OriginateCredit(companyID string, xfer *client.Transfer, dst Destination) ([]*ach.File, error)
+}
+
+const (
+	DebitLeg  = "debit"
+	CreditLeg = "credit"
+)
+
+func newLeg(leg string, status client.TransferLegStatus) client.TransferLeg {
+	return client.TransferLeg{
+		Leg:     leg,
+		Status:  status,
+		Updated: time.Now(),
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package fundflow
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/moov-io/ach"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+
+	"github.com/moov-io/base/log"
+)
+
+// PassThrough returns a Strategy where PayGate moves funds between two accounts which are
+// both outside of the ODFI. The source is debited and the destination credited in the same
+// file, so the ODFI's settlement account nets to zero once both entries settle.
+//
+// Returned debits leave the settlement account short as the credit has already been sent.
+type PassThrough struct {
+	settlement
+	logger log.Logger
+}
+
+func NewPassThrough(logger log.Logger, cfg config.ODFI) Strategy {
+	return &PassThrough{
+		settlement: settlement{cfg: cfg},
+		logger:     logger,
+	}
+}
+
+func (pt *PassThrough) Originate(companyID string, xfer *client.Transfer, src Source, dst Destination) ([]*ach.File, error) {
+	if xfer.IatDetail != nil {
+		return nil, errors.New("IAT transfers are only supported with the first party fundflow")
+	}
+	if err := pt.validate(src, dst); err != nil {
+		return nil, err
+	}
+
+	file, err := pt.debit(companyID, xfer, src)
+	if err != nil {
+		return nil, err
+	}
+	credit, err := pt.credit(companyID, xfer, dst)
+	if err != nil {
+		return nil, err
+	}
+
+	// Move the credit batches into the debit file
+	for i := range credit.Batches {
+		credit.Batches[i].GetHeader().BatchNumber = len(file.Batches) + 1
+		if err := credit.Batches[i].Create(); err != nil {
+			return nil, fmt.Errorf("transferID=%s: %v", xfer.TransferID, err)
+		}
+		file.AddBatch(credit.Batches[i])
+	}
+	if err := file.Create(); err != nil {
+		return nil, fmt.Errorf("transferID=%s: %v", xfer.TransferID, err)
+	}
+	if err := file.Validate(); err != nil {
+		return nil, fmt.Errorf("transferID=%s: %v", xfer.TransferID, err)
+	}
+
+	xfer.Legs = []client.TransferLeg{
+		newLeg(DebitLeg, client.TRANSFERLEGSTATUS_PENDING),
+		newLeg(CreditLeg, client.TRANSFERLEGSTATUS_PENDING),
+	}
+	return []*ach.File{file}, nil
+}
+
+func (pt *PassThrough) HandleReturn(returned *ach.File, xfer *client.Transfer) ([]*ach.File, error) {
+	return nil, nil
+}
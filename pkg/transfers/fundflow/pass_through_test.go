@@ -0,0 +1,118 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package fundflow
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/moov-io/ach"
+	customers "github.com/moov-io/customers/pkg/client"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+)
+
+func settlementConfig() *config.Config {
+	cfg := config.Empty()
+	cfg.ODFI.RoutingNumber = "987654320"
+	cfg.ODFI.Settlement = &config.Settlement{
+		AccountNumber: "55512345",
+		AccountType:   "checking",
+		Name:          "Moov Settlement",
+	}
+	return cfg
+}
+
+func outsideAccounts() (Source, Destination) {
+	src := Source{
+		Customer: customers.Customer{
+			FirstName: "John",
+			LastName:  "Doe",
+			Status:    customers.CUSTOMERSTATUS_VERIFIED,
+		},
+		Account: customers.Account{
+			Type:          customers.ACCOUNTTYPE_CHECKING,
+			RoutingNumber: "123456780",
+		},
+		AccountNumber: "123456",
+	}
+	dst := Destination{
+		Customer: customers.Customer{
+			FirstName: "Jane",
+			LastName:  "Doe",
+		},
+		Account: customers.Account{
+			Type:          customers.ACCOUNTTYPE_SAVINGS,
+			RoutingNumber: "231380104",
+		},
+		AccountNumber: "654321",
+	}
+	return src, dst
+}
+
+func TestPassThrough__Originate(t *testing.T) {
+	cfg := settlementConfig()
+	pt := NewPassThrough(cfg.Logger, cfg.ODFI)
+
+	xfer := &client.Transfer{
+		TransferID: "xfer",
+		Amount: client.Amount{
+			Currency: "USD",
+			Value:    1253,
+		},
+		Description: "test",
+	}
+	src, dst := outsideAccounts()
+
+	files, err := pt.Originate("MOOV", xfer, src, dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("unexpected %d ACH files", len(files))
+	}
+	if n := len(files[0].Batches); n != 2 {
+		t.Fatalf("unexpected %d batches", n)
+	}
+
+	debit := files[0].Batches[0].GetEntries()[0]
+	if debit.TransactionCode != ach.CheckingDebit || debit.RDFIIdentification != "12345678" {
+		t.Errorf("unexpected debit: %#v", debit)
+	}
+	credit := files[0].Batches[1].GetEntries()[0]
+	if credit.TransactionCode != ach.SavingsCredit || credit.RDFIIdentification != "23138010" || credit.DFIAccountNumber != "654321" {
+		t.Errorf("unexpected credit: %#v", credit)
+	}
+
+	if len(xfer.Legs) != 2 {
+		t.Fatalf("unexpected legs: %#v", xfer.Legs)
+	}
+	for i := range xfer.Legs {
+		if xfer.Legs[i].Status != client.TRANSFERLEGSTATUS_PENDING {
+			t.Errorf("unexpected leg: %#v", xfer.Legs[i])
+		}
+	}
+}
+
+func TestPassThrough__OriginateErr(t *testing.T) {
+	cfg := settlementConfig()
+	src, dst := outsideAccounts()
+	xfer := &client.Transfer{TransferID: "xfer"}
+
+	// accounts at the ODFI
+	dst.Account.RoutingNumber = cfg.ODFI.RoutingNumber
+	pt := NewPassThrough(cfg.Logger, cfg.ODFI)
+	if _, err := pt.Originate("MOOV", xfer, src, dst); err == nil || !strings.Contains(err.Error(), "with an account within") {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// missing settlement account
+	cfg.ODFI.Settlement = nil
+	pt = NewPassThrough(cfg.Logger, cfg.ODFI)
+	if _, err := pt.Originate("MOOV", xfer, src, dst); err == nil || !strings.Contains(err.Error(), "no settlement account") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package fundflow
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/moov-io/ach"
+	customers "github.com/moov-io/customers/pkg/client"
+
+	"github.com/moov-io/paygate/pkg/achx"
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+)
+
+// settlement builds files where the ODFI's settlement account sits between a Transfer's
+// source and destination. Debits from the source are paid into the settlement account and
+// credits to the destination are paid out of it.
+type settlement struct {
+	cfg config.ODFI
+
+	traceNumbers achx.TraceNumbers
+}
+
+func (s settlement) validate(src Source, dst Destination) error {
+	if s.cfg.Settlement == nil {
+		return errors.New("no settlement account configured")
+	}
+	if src.Account.RoutingNumber == s.cfg.RoutingNumber || dst.Account.RoutingNumber == s.cfg.RoutingNumber {
+		// Accounts at our ODFI should use first-party transfers instead
+		return fmt.Errorf("rejecting transfer with an account within %s", s.cfg.RoutingNumber)
+	}
+	if !strings.EqualFold(string(src.Customer.Status), string(customers.CUSTOMERSTATUS_VERIFIED)) {
+		return fmt.Errorf("source customerID=%s does not support debit with status %s", src.Customer.CustomerID, src.Customer.Status)
+	}
+	return nil
+}
+
+func (s settlement) options(companyID string, xfer *client.Transfer) achx.Options {
+	opts := achx.Options{
+		ODFIRoutingNumber:     s.cfg.RoutingNumber,
+		Gateway:               s.cfg.Gateway,
+		FileConfig:            s.cfg.FileConfig,
+		CutoffTimezone:        s.cfg.Cutoffs.Location(),
+		CompanyIdentification: companyID,
+		TraceNumbers:          s.traceNumbers,
+	}
+	opts.FileConfig.BalanceEntries = s.cfg.FileConfig.BalanceEntries && (xfer.Amount.Value >= 50)
+	return opts
+}
+
+func (s settlement) account() (customers.Customer, customers.Account) {
+	cust := customers.Customer{
+		NickName: s.cfg.Settlement.Name,
+		Status:   customers.CUSTOMERSTATUS_VERIFIED,
+	}
+	acct := customers.Account{
+		RoutingNumber: s.cfg.RoutingNumber,
+		Type:          customers.ACCOUNTTYPE_CHECKING,
+	}
+	if strings.EqualFold(s.cfg.Settlement.AccountType, "savings") {
+		acct.Type = customers.ACCOUNTTYPE_SAVINGS
+	}
+	return cust, acct
+}
+
+// debit creates a file which debits the source and pays into the settlement account.
+func (s settlement) debit(companyID string, xfer *client.Transfer, src Source) (*ach.File, error) {
+	cust, acct := s.account()
+	source := achx.Source{
+		Customer:      src.Customer,
+		Account:       src.Account,
+		AccountNumber: src.AccountNumber,
+	}
+	destination := achx.Destination{
+		Customer:      cust,
+		Account:       acct,
+		AccountNumber: s.cfg.Settlement.AccountNumber,
+	}
+	file, err := achx.ConstructFile(xfer.TransferID, s.options(companyID, xfer), xfer, source, destination)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create debit file: transferID=%s: %v", xfer.TransferID, err)
+	}
+	return file, nil
+}
+
+// credit creates a file which pays the destination out of the settlement account.
+func (s settlement) credit(companyID string, xfer *client.Transfer, dst Destination) (*ach.File, error) {
+	cust, acct := s.account()
+	source := achx.Source{
+		Customer:      cust,
+		Account:       acct,
+		AccountNumber: s.cfg.Settlement.AccountNumber,
+	}
+	destination := achx.Destination{
+		Customer:      dst.Customer,
+		Account:       dst.Account,
+		AccountNumber: dst.AccountNumber,
+	}
+	file, err := achx.ConstructFile(xfer.TransferID, s.options(companyID, xfer), xfer, source, destination)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create credit file: transferID=%s: %v", xfer.TransferID, err)
+	}
+	return file, nil
+}
@@ -0,0 +1,91 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package fundflow
+
+import (
+	"fmt"
+
+	"github.com/moov-io/ach"
+
+	"github.com/moov-io/paygate/pkg/achx"
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+
+	"github.com/moov-io/base/log"
+)
+
+// Strategies holds each funding flow an organization can select. It's used as a Strategy
+// directly for first-party transfers, which organizations without a configured flow use.
+//
+// Pass-through and two-leg flows are only available when the ODFI has a settlement account.
+// Organizations which are part of a Tenant use Strategies built from the Tenant's values.
+type Strategies struct {
+	flows map[Flow]Strategy
+
+	// tenants holds the Strategies of each organization which is part of a Tenant
+	tenants map[string]*Strategies
+}
+
+// NewStrategies returns the funding flows of the ODFI and each Tenant. Entries take their trace
+// numbers from traceNumbers, or random trace numbers are used when it's nil.
+func NewStrategies(logger log.Logger, cfg config.ODFI, traceNumbers achx.TraceNumbers) *Strategies {
+	strategies := newStrategies(logger, cfg, traceNumbers)
+	for i := range cfg.Tenants {
+		tenant := newStrategies(logger, cfg.ForTenant(&cfg.Tenants[i]), traceNumbers)
+		for _, org := range cfg.Tenants[i].Organizations {
+			if strategies.tenants == nil {
+				strategies.tenants = make(map[string]*Strategies)
+			}
+			strategies.tenants[org] = tenant
+		}
+	}
+	return strategies
+}
+
+func newStrategies(logger log.Logger, cfg config.ODFI, traceNumbers achx.TraceNumbers) *Strategies {
+	flows := map[Flow]Strategy{
+		FirstPartyFlow: &FirstParty{cfg: cfg, logger: logger, traceNumbers: traceNumbers},
+	}
+	if cfg.Settlement != nil {
+		settlement := settlement{cfg: cfg, traceNumbers: traceNumbers}
+		flows[PassThroughFlow] = &PassThrough{settlement: settlement, logger: logger}
+		flows[TwoLegFlow] = &TwoLeg{settlement: settlement, logger: logger}
+	}
+	return &Strategies{flows: flows}
+}
+
+// ForOrganization returns the Strategies used for an organization's Transfers.
+func (s *Strategies) ForOrganization(orgID string) Strategy {
+	if tenant, exists := s.tenants[orgID]; exists {
+		return tenant
+	}
+	return s
+}
+
+// OriginateCredit creates the held credit leg of a two-leg transfer.
+func (s *Strategies) OriginateCredit(companyID string, xfer *client.Transfer, dst Destination) ([]*ach.File, error) {
+	if originator, ok := s.flows[TwoLegFlow].(CreditOriginator); ok {
+		return originator.OriginateCredit(companyID, xfer, dst)
+	}
+	return nil, fmt.Errorf("funding flow %s is not available", TwoLegFlow)
+}
+
+func (s *Strategies) Select(flow Flow) (Strategy, error) {
+	if flow == "" {
+		flow = FirstPartyFlow
+	}
+	if strategy, exists := s.flows[flow]; exists {
+		return strategy, nil
+	}
+	return nil, fmt.Errorf("funding flow %s is not available", flow)
+}
+
+func (s *Strategies) Originate(companyID string, xfer *client.Transfer, source Source, destination Destination) ([]*ach.File, error) {
+	return s.flows[FirstPartyFlow].Originate(companyID, xfer, source, destination)
+}
+
+func (s *Strategies) HandleReturn(returned *ach.File, xfer *client.Transfer) ([]*ach.File, error) {
+	return s.flows[FirstPartyFlow].HandleReturn(returned, xfer)
+}
@@ -0,0 +1,104 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package fundflow
+
+import (
+	"testing"
+
+	customers "github.com/moov-io/customers/pkg/client"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+)
+
+func TestStrategies__Select(t *testing.T) {
+	cfg := config.Empty()
+	strategies := NewStrategies(cfg.Logger, cfg.ODFI, nil)
+
+	if s, err := strategies.Select(""); err != nil {
+		t.Fatal(err)
+	} else if _, ok := s.(*FirstParty); !ok {
+		t.Errorf("unexpected %T", s)
+	}
+	if _, err := strategies.Select(TwoLegFlow); err == nil {
+		t.Error("expected error without settlement account")
+	}
+
+	cfg = settlementConfig()
+	strategies = NewStrategies(cfg.Logger, cfg.ODFI, nil)
+	if s, err := strategies.Select(PassThroughFlow); err != nil {
+		t.Fatal(err)
+	} else if _, ok := s.(*PassThrough); !ok {
+		t.Errorf("unexpected %T", s)
+	}
+	if s, err := strategies.Select(TwoLegFlow); err != nil {
+		t.Fatal(err)
+	} else if _, ok := s.(*TwoLeg); !ok {
+		t.Errorf("unexpected %T", s)
+	}
+}
+
+func TestStrategies__ForOrganization(t *testing.T) {
+	cfg := config.Empty()
+	cfg.ODFI.RoutingNumber = "987654320"
+	cfg.ODFI.Tenants = []config.Tenant{
+		{
+			Name:          "acme",
+			Organizations: []string{"acme"},
+			Gateway: &config.Gateway{
+				Origin:     "231380104",
+				OriginName: "Acme Payments",
+			},
+		},
+	}
+	strategies := NewStrategies(cfg.Logger, cfg.ODFI, nil)
+
+	if s := strategies.ForOrganization("moov"); s != strategies {
+		t.Errorf("unexpected %#v", s)
+	}
+
+	xfer := &client.Transfer{
+		Amount:      client.Amount{Currency: "USD", Value: 153},
+		Description: "test payment",
+	}
+	src := Source{
+		Customer: customers.Customer{Status: customers.CUSTOMERSTATUS_VERIFIED},
+		Account: customers.Account{
+			Type:          customers.ACCOUNTTYPE_SAVINGS,
+			RoutingNumber: "123456780",
+		},
+		AccountNumber: "123456",
+	}
+	dest := Destination{
+		Account: customers.Account{
+			Type:          customers.ACCOUNTTYPE_SAVINGS,
+			RoutingNumber: "987654320",
+		},
+		AccountNumber: "654321",
+	}
+
+	files, err := strategies.ForOrganization("acme").Originate("ACME", xfer, src, dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("unexpected %d ACH files", len(files))
+	}
+	if files[0].Header.ImmediateOrigin != "231380104" || files[0].Header.ImmediateOriginName != "Acme Payments" {
+		t.Errorf("unexpected file header: %#v", files[0].Header)
+	}
+}
+
+func TestParseFlow(t *testing.T) {
+	if flow, err := ParseFlow(""); err != nil || flow != FirstPartyFlow {
+		t.Errorf("flow=%q error=%v", flow, err)
+	}
+	if flow, err := ParseFlow("twoLeg"); err != nil || flow != TwoLegFlow {
+		t.Errorf("flow=%q error=%v", flow, err)
+	}
+	if _, err := ParseFlow("other"); err == nil {
+		t.Error("expected error")
+	}
+}
@@ -0,0 +1,71 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package fundflow
+
+import (
+	"errors"
+
+	"github.com/moov-io/ach"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+
+	"github.com/moov-io/base/log"
+)
+
+// TwoLeg returns a Strategy where the ODFI funds a Transfer between two outside accounts.
+// Funds are first collected from the source into the ODFI's settlement account and the
+// destination is only credited after that debit has settled.
+//
+// Originate only creates the debit leg. The credit leg is held until OriginateCredit is
+// called for the Transfer.
+type TwoLeg struct {
+	settlement
+	logger log.Logger
+}
+
+func NewTwoLeg(logger log.Logger, cfg config.ODFI) *TwoLeg {
+	return &TwoLeg{
+		settlement: settlement{cfg: cfg},
+		logger:     logger,
+	}
+}
+
+func (tl *TwoLeg) Originate(companyID string, xfer *client.Transfer, src Source, dst Destination) ([]*ach.File, error) {
+	if xfer.IatDetail != nil {
+		return nil, errors.New("IAT transfers are only supported with the first party fundflow")
+	}
+	if err := tl.validate(src, dst); err != nil {
+		return nil, err
+	}
+
+	file, err := tl.debit(companyID, xfer, src)
+	if err != nil {
+		return nil, err
+	}
+
+	xfer.Legs = []client.TransferLeg{
+		newLeg(DebitLeg, client.TRANSFERLEGSTATUS_PENDING),
+		newLeg(CreditLeg, client.TRANSFERLEGSTATUS_HELD),
+	}
+	return []*ach.File{file}, nil
+}
+
+// OriginateCredit creates the held credit leg which pays the destination out of the
+// settlement account.
+func (tl *TwoLeg) OriginateCredit(companyID string, xfer *client.Transfer, dst Destination) ([]*ach.File, error) {
+	if tl.cfg.Settlement == nil {
+		return nil, errors.New("no settlement account configured")
+	}
+	file, err := tl.credit(companyID, xfer, dst)
+	if err != nil {
+		return nil, err
+	}
+	return []*ach.File{file}, nil
+}
+
+func (tl *TwoLeg) HandleReturn(returned *ach.File, xfer *client.Transfer) ([]*ach.File, error) {
+	return nil, nil
+}
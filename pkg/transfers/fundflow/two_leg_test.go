@@ -0,0 +1,54 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package fundflow
+
+import (
+	"testing"
+
+	"github.com/moov-io/ach"
+
+	"github.com/moov-io/paygate/pkg/client"
+)
+
+func TestTwoLeg__Originate(t *testing.T) {
+	cfg := settlementConfig()
+	tl := NewTwoLeg(cfg.Logger, cfg.ODFI)
+
+	xfer := &client.Transfer{
+		TransferID: "xfer",
+		Amount: client.Amount{
+			Currency: "USD",
+			Value:    1253,
+		},
+		Description: "test",
+	}
+	src, dst := outsideAccounts()
+
+	files, err := tl.Originate("MOOV", xfer, src, dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || len(files[0].Batches) != 1 {
+		t.Fatalf("unexpected files: %#v", files)
+	}
+	if entry := files[0].Batches[0].GetEntries()[0]; entry.TransactionCode != ach.CheckingDebit {
+		t.Errorf("unexpected entry: %#v", entry)
+	}
+	if len(xfer.Legs) != 2 || xfer.Legs[1].Leg != CreditLeg || xfer.Legs[1].Status != client.TRANSFERLEGSTATUS_HELD {
+		t.Fatalf("unexpected legs: %#v", xfer.Legs)
+	}
+
+	// release the credit leg
+	files, err = tl.OriginateCredit("MOOV", xfer, dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("unexpected %d files", len(files))
+	}
+	if entry := files[0].Batches[0].GetEntries()[0]; entry.RDFIIdentification != "23138010" || entry.DFIAccountNumber != "654321" {
+		t.Errorf("unexpected entry: %#v", entry)
+	}
+}
@@ -0,0 +1,74 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+// Package history records field-level changes of Transfers into the append-only
+// transfer_history table. Changes are written in the same transaction as the update
+// they describe so the history can't drift from the transfers table.
+package history
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/moov-io/base"
+)
+
+// Actor identifies which part of PayGate made a change.
+type Actor string
+
+const (
+	API      Actor = "api"
+	Admin    Actor = "admin"
+	Pipeline Actor = "pipeline"
+	Inbound  Actor = "inbound"
+)
+
+// Change is a single field being modified on a Transfer
+type Change struct {
+	Field    string
+	OldValue string
+	NewValue string
+}
+
+// Record writes each change for a Transfer inside of tx.
+func Record(tx *sql.Tx, transferID string, actor Actor, changes ...Change) error {
+	if len(changes) == 0 {
+		return nil
+	}
+
+	query := `insert into transfer_history (history_id, transfer_id, field, old_value, new_value, actor, created_at) values (?, ?, ?, ?, ?, ?, ?);`
+	stmt, err := tx.Prepare(query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	now := time.Now()
+	for i := range changes {
+		_, err := stmt.Exec(base.ID(), transferID, changes[i].Field, changes[i].OldValue, changes[i].NewValue, actor, now)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CurrentStatus reads the status of a Transfer inside of tx so it can be recorded as the old value.
+func CurrentStatus(tx *sql.Tx, transferID string) (string, error) {
+	query := `select status from transfers where transfer_id = ? and deleted_at is null limit 1;`
+	stmt, err := tx.Prepare(query)
+	if err != nil {
+		return "", err
+	}
+	defer stmt.Close()
+
+	var status string
+	if err := stmt.QueryRow(transferID).Scan(&status); err != nil {
+		if err == sql.ErrNoRows {
+			return "", nil
+		}
+		return "", err
+	}
+	return status, nil
+}
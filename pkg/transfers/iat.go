@@ -0,0 +1,93 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package transfers
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/moov-io/paygate/pkg/client"
+)
+
+var (
+	countryCodeRegex  = regexp.MustCompile(`^[A-Z]{2}$`)
+	currencyCodeRegex = regexp.MustCompile(`^[A-Z]{3}$`)
+)
+
+// validateIATDetail checks the fields NACHA requires on International ACH Transactions (IAT)
+// are present and well formed.
+func validateIATDetail(detail *client.IatDetail, sameDay bool) error {
+	if detail == nil {
+		return nil
+	}
+	if sameDay {
+		return errors.New("IAT transfers can not be same-day")
+	}
+
+	switch detail.ForeignExchangeIndicator {
+	case "FF", "FV", "VF":
+	default:
+		return fmt.Errorf("invalid foreignExchangeIndicator %q", detail.ForeignExchangeIndicator)
+	}
+	switch detail.ForeignExchangeReferenceIndicator {
+	case 1, 2:
+		if detail.ForeignExchangeReference == "" {
+			return errors.New("missing foreignExchangeReference")
+		}
+	case 3:
+		if detail.ForeignExchangeReference != "" {
+			return errors.New("foreignExchangeReference must be empty with foreignExchangeReferenceIndicator 3")
+		}
+	default:
+		return fmt.Errorf("invalid foreignExchangeReferenceIndicator %d", detail.ForeignExchangeReferenceIndicator)
+	}
+
+	if !countryCodeRegex.MatchString(detail.DestinationCountryCode) {
+		return fmt.Errorf("invalid destinationCountryCode %q", detail.DestinationCountryCode)
+	}
+	if !currencyCodeRegex.MatchString(detail.OriginatingCurrencyCode) {
+		return fmt.Errorf("invalid originatingCurrencyCode %q", detail.OriginatingCurrencyCode)
+	}
+	if !currencyCodeRegex.MatchString(detail.DestinationCurrencyCode) {
+		return fmt.Errorf("invalid destinationCurrencyCode %q", detail.DestinationCurrencyCode)
+	}
+	if len(detail.TransactionTypeCode) != 3 {
+		return fmt.Errorf("invalid transactionTypeCode %q", detail.TransactionTypeCode)
+	}
+
+	if err := validateIATParty(detail.Originator); err != nil {
+		return fmt.Errorf("originator: %v", err)
+	}
+	if err := validateIATParty(detail.Receiver); err != nil {
+		return fmt.Errorf("receiver: %v", err)
+	}
+	return validateIATBank(detail.ReceivingBank)
+}
+
+func validateIATParty(party client.IatParty) error {
+	if party.Name == "" || party.StreetAddress == "" || party.City == "" || party.PostalCode == "" {
+		return errors.New("name, streetAddress, city and postalCode are required")
+	}
+	if !countryCodeRegex.MatchString(party.CountryCode) {
+		return fmt.Errorf("invalid countryCode %q", party.CountryCode)
+	}
+	return nil
+}
+
+func validateIATBank(bank client.IatBank) error {
+	if bank.Name == "" || bank.Identification == "" {
+		return errors.New("receivingBank: name and identification are required")
+	}
+	switch bank.IdNumberQualifier {
+	case "01", "02", "03":
+	default:
+		return fmt.Errorf("receivingBank: invalid idNumberQualifier %q", bank.IdNumberQualifier)
+	}
+	if !countryCodeRegex.MatchString(bank.BranchCountryCode) {
+		return fmt.Errorf("receivingBank: invalid branchCountryCode %q", bank.BranchCountryCode)
+	}
+	return nil
+}
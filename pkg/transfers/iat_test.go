@@ -0,0 +1,74 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package transfers
+
+import (
+	"testing"
+
+	"github.com/moov-io/paygate/pkg/client"
+)
+
+func iatDetail() *client.IatDetail {
+	return &client.IatDetail{
+		ForeignExchangeIndicator:          "FF",
+		ForeignExchangeReferenceIndicator: 3,
+		DestinationCountryCode:            "DE",
+		OriginatingCurrencyCode:           "USD",
+		DestinationCurrencyCode:           "EUR",
+		TransactionTypeCode:               "SAL",
+		Originator: client.IatParty{
+			Name:          "John Doe",
+			StreetAddress: "123 Main St",
+			City:          "Anytown",
+			StateProvince: "IA",
+			CountryCode:   "US",
+			PostalCode:    "50401",
+		},
+		Receiver: client.IatParty{
+			Name:          "Jane Doe",
+			StreetAddress: "Hauptstrasse 1",
+			City:          "Berlin",
+			CountryCode:   "DE",
+			PostalCode:    "10115",
+		},
+		ReceivingBank: client.IatBank{
+			Name:              "Receiving Bank",
+			IdNumberQualifier: "01",
+			Identification:    "987654320",
+			BranchCountryCode: "DE",
+		},
+	}
+}
+
+func TestIAT__validateIATDetail(t *testing.T) {
+	if err := validateIATDetail(nil, true); err != nil {
+		t.Error(err)
+	}
+	if err := validateIATDetail(iatDetail(), false); err != nil {
+		t.Error(err)
+	}
+	if err := validateIATDetail(iatDetail(), true); err == nil {
+		t.Error("expected error for same-day IAT")
+	}
+
+	cases := []func(detail *client.IatDetail){
+		func(detail *client.IatDetail) { detail.ForeignExchangeIndicator = "XX" },
+		func(detail *client.IatDetail) { detail.ForeignExchangeReferenceIndicator = 1 },
+		func(detail *client.IatDetail) { detail.ForeignExchangeReference = "1.08" },
+		func(detail *client.IatDetail) { detail.DestinationCountryCode = "DEU" },
+		func(detail *client.IatDetail) { detail.DestinationCurrencyCode = "eur" },
+		func(detail *client.IatDetail) { detail.TransactionTypeCode = "" },
+		func(detail *client.IatDetail) { detail.Originator.StreetAddress = "" },
+		func(detail *client.IatDetail) { detail.Receiver.CountryCode = "" },
+		func(detail *client.IatDetail) { detail.ReceivingBank.IdNumberQualifier = "04" },
+	}
+	for i := range cases {
+		detail := iatDetail()
+		cases[i](detail)
+		if err := validateIATDetail(detail, false); err == nil {
+			t.Errorf("#%d: expected error", i)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package transfers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/moov-io/base/log"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/database"
+)
+
+const (
+	// idempotencyKeyExpiration is how long an X-Idempotency-Key covers the Transfer created with it.
+	idempotencyKeyExpiration = 24 * time.Hour
+
+	// idempotencyKeyLease is how long a claimed key is held without a saved response. Keys of
+	// requests which stopped before saving one, like after a crash, can be claimed again after it.
+	idempotencyKeyLease = 5 * time.Minute
+)
+
+// claimIdempotencyKey claims an X-Idempotency-Key for the Transfer a user is about to create.
+// Keys are stored in the database so they're shared by every instance and kept across restarts.
+//
+// When the user already sent the key in the last 24 hours false is returned along with the
+// response body of the Transfer created with it, which is empty while it's still being created.
+// Claims without a Transfer are released once their lease is over.
+func (r *sqlRepo) claimIdempotencyKey(orgID, userID, key string, now time.Time) (bool, []byte, error) {
+	query := `insert into transfer_idempotency_keys (organization, user_id, idempotency_key, created_at) values (?, ?, ?, ?);`
+	_, err := r.db.Exec(query, orgID, userID, key, now)
+	if err == nil {
+		return true, nil, nil
+	}
+	if !database.UniqueViolation(err) {
+		return false, nil, err
+	}
+
+	// Release an expired key, or one whose Transfer was never saved, so it can be used again
+	release := `delete from transfer_idempotency_keys where organization = ? and user_id = ? and idempotency_key = ? and (created_at < ? or (transfer_id is null and created_at < ?));`
+	res, err := r.db.Exec(release, orgID, userID, key, now.Add(-idempotencyKeyExpiration), now.Add(-idempotencyKeyLease))
+	if err != nil {
+		return false, nil, err
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		_, err := r.db.Exec(query, orgID, userID, key, now)
+		if err == nil {
+			return true, nil, nil
+		}
+		if !database.UniqueViolation(err) {
+			return false, nil, err
+		}
+	}
+
+	var response sql.NullString
+	query = `select response from transfer_idempotency_keys where organization = ? and user_id = ? and idempotency_key = ? limit 1;`
+	if err := r.db.QueryRow(query, orgID, userID, key).Scan(&response); err != nil && err != sql.ErrNoRows {
+		return false, nil, err
+	}
+	if response.String == "" {
+		return false, nil, nil
+	}
+	return false, []byte(response.String), nil
+}
+
+// saveIdempotentTransfer stores the Transfer created with a claimed key so it's returned when
+// the key is sent again.
+func (r *sqlRepo) saveIdempotentTransfer(orgID, userID, key string, transfer *client.Transfer) error {
+	bs, err := json.Marshal(transfer)
+	if err != nil {
+		return err
+	}
+	query := `update transfer_idempotency_keys set transfer_id = ?, response = ? where organization = ? and user_id = ? and idempotency_key = ?;`
+	_, err = r.db.Exec(query, transfer.TransferID, string(bs), orgID, userID, key)
+	return err
+}
+
+// releaseIdempotencyKey removes a claimed key when its Transfer wasn't created, so the request
+// can be retried with the same key.
+func (r *sqlRepo) releaseIdempotencyKey(orgID, userID, key string) error {
+	query := `delete from transfer_idempotency_keys where organization = ? and user_id = ? and idempotency_key = ? and transfer_id is null;`
+	_, err := r.db.Exec(query, orgID, userID, key)
+	return err
+}
+
+// purgeIdempotencyKeys deletes the keys of single Transfers claimed before the cutoff and
+// clears the keys and saved responses of batches created before it.
+func (r *sqlRepo) purgeIdempotencyKeys(before time.Time) (int64, error) {
+	res, err := r.db.Exec(`delete from transfer_idempotency_keys where created_at < ?;`, before)
+	if err != nil {
+		return 0, err
+	}
+	deleted, _ := res.RowsAffected()
+
+	query := `update transfer_batches set idempotency_key = null, response = null where created_at < ? and (idempotency_key is not null or response is not null);`
+	res, err = r.db.Exec(query, before)
+	if err != nil {
+		return deleted, err
+	}
+	cleared, _ := res.RowsAffected()
+	return deleted + cleared, nil
+}
+
+// IdempotencyPurger removes expired idempotency keys, which are otherwise only replaced when
+// the same key is sent again.
+type IdempotencyPurger struct {
+	logger log.Logger
+	repo   Repository
+
+	interval time.Duration
+}
+
+func NewIdempotencyPurger(cfg *config.Config, repo Repository) *IdempotencyPurger {
+	return &IdempotencyPurger{
+		logger:   cfg.Logger.Set("service", "idempotency-keys"),
+		repo:     repo,
+		interval: time.Hour,
+	}
+}
+
+func (p *IdempotencyPurger) Start(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case now := <-ticker.C:
+			if err := p.tick(now); err != nil {
+				p.logger.LogErrorf("ERROR purging idempotency keys: %v", err)
+			}
+
+		case <-ctx.Done():
+			p.logger.Log("idempotency key purger shutdown")
+			return
+		}
+	}
+}
+
+func (p *IdempotencyPurger) tick(now time.Time) error {
+	n, err := p.repo.purgeIdempotencyKeys(now.Add(-idempotencyKeyExpiration))
+	if err != nil {
+		return fmt.Errorf("purging expired keys: %v", err)
+	}
+	if n > 0 {
+		p.logger.Logf("purged %d expired idempotency keys", n)
+	}
+	return nil
+}
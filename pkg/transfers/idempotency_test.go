@@ -0,0 +1,164 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package transfers
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/moov-io/base"
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepository__IdempotencyKeys(t *testing.T) {
+	t.Parallel()
+
+	check := func(t *testing.T, repo *sqlRepo) {
+		key, now := base.ID(), time.Now()
+
+		claimed, response, err := repo.claimIdempotencyKey("moov", "jane", key, now)
+		require.NoError(t, err)
+		require.True(t, claimed)
+		require.Empty(t, response)
+
+		// the key is claimed while its Transfer is created
+		claimed, response, err = repo.claimIdempotencyKey("moov", "jane", key, now)
+		require.NoError(t, err)
+		require.False(t, claimed)
+		require.Empty(t, response)
+
+		// other users and organizations have their own keys
+		claimed, _, err = repo.claimIdempotencyKey("moov", "john", key, now)
+		require.NoError(t, err)
+		require.True(t, claimed)
+		claimed, _, err = repo.claimIdempotencyKey("other", "jane", key, now)
+		require.NoError(t, err)
+		require.True(t, claimed)
+
+		xfer := &client.Transfer{TransferID: base.ID(), Status: client.PENDING}
+		require.NoError(t, repo.saveIdempotentTransfer("moov", "jane", key, xfer))
+
+		claimed, response, err = repo.claimIdempotencyKey("moov", "jane", key, now)
+		require.NoError(t, err)
+		require.False(t, claimed)
+
+		var replay client.Transfer
+		require.NoError(t, json.Unmarshal(response, &replay))
+		require.Equal(t, xfer.TransferID, replay.TransferID)
+
+		// keys with a Transfer aren't released
+		require.NoError(t, repo.releaseIdempotencyKey("moov", "jane", key))
+		claimed, _, err = repo.claimIdempotencyKey("moov", "jane", key, now)
+		require.NoError(t, err)
+		require.False(t, claimed)
+
+		require.NoError(t, repo.releaseIdempotencyKey("moov", "john", key))
+		claimed, _, err = repo.claimIdempotencyKey("moov", "john", key, now)
+		require.NoError(t, err)
+		require.True(t, claimed)
+
+		// expired keys can be used again
+		claimed, response, err = repo.claimIdempotencyKey("moov", "jane", key, now.Add(idempotencyKeyExpiration+time.Minute))
+		require.NoError(t, err)
+		require.True(t, claimed)
+		require.Empty(t, response)
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+}
+
+func TestRepository__IdempotencyKeysLease(t *testing.T) {
+	t.Parallel()
+
+	check := func(t *testing.T, repo *sqlRepo) {
+		key, now := base.ID(), time.Now()
+
+		claimed, _, err := repo.claimIdempotencyKey("moov", "jane", key, now)
+		require.NoError(t, err)
+		require.True(t, claimed)
+
+		// a claim without a Transfer is held for its lease
+		claimed, _, err = repo.claimIdempotencyKey("moov", "jane", key, now.Add(idempotencyKeyLease-time.Minute))
+		require.NoError(t, err)
+		require.False(t, claimed)
+
+		// and released after it, like when the instance crashed before saving the Transfer
+		later := now.Add(idempotencyKeyLease + time.Minute)
+		claimed, _, err = repo.claimIdempotencyKey("moov", "jane", key, later)
+		require.NoError(t, err)
+		require.True(t, claimed)
+
+		// saved Transfers are kept past the lease
+		xfer := &client.Transfer{TransferID: base.ID(), Status: client.PENDING}
+		require.NoError(t, repo.saveIdempotentTransfer("moov", "jane", key, xfer))
+
+		claimed, response, err := repo.claimIdempotencyKey("moov", "jane", key, later.Add(idempotencyKeyLease+time.Minute))
+		require.NoError(t, err)
+		require.False(t, claimed)
+		require.NotEmpty(t, response)
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+}
+
+func TestRepository__purgeIdempotencyKeys(t *testing.T) {
+	t.Parallel()
+
+	check := func(t *testing.T, repo *sqlRepo) {
+		now := time.Now()
+		expired, current := base.ID(), base.ID()
+
+		_, _, err := repo.claimIdempotencyKey("moov", "jane", expired, now.Add(-25*time.Hour))
+		require.NoError(t, err)
+		_, _, err = repo.claimIdempotencyKey("moov", "jane", current, now)
+		require.NoError(t, err)
+
+		oldBatch := base.ID()
+		_, _, err = repo.saveTransferBatch("moov", oldBatch, expired, now.Add(-25*time.Hour))
+		require.NoError(t, err)
+		require.NoError(t, repo.saveTransferBatchResponse(oldBatch, &client.TransferBatch{BatchID: oldBatch}))
+		_, _, err = repo.saveTransferBatch("moov", base.ID(), current, now)
+		require.NoError(t, err)
+
+		n, err := repo.purgeIdempotencyKeys(now.Add(-idempotencyKeyExpiration))
+		require.NoError(t, err)
+		require.Equal(t, int64(2), n)
+
+		var keys int
+		require.NoError(t, repo.db.QueryRow(`select count(*) from transfer_idempotency_keys;`).Scan(&keys))
+		require.Equal(t, 1, keys)
+
+		var batches int
+		require.NoError(t, repo.db.QueryRow(`select count(*) from transfer_batches where idempotency_key is not null or response is not null;`).Scan(&batches))
+		require.Equal(t, 1, batches)
+
+		// nothing is left to purge
+		n, err = repo.purgeIdempotencyKeys(now.Add(-idempotencyKeyExpiration))
+		require.NoError(t, err)
+		require.Equal(t, int64(0), n)
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+}
+
+func TestIdempotencyPurger__tick(t *testing.T) {
+	repo := &MockRepository{}
+	purger := NewIdempotencyPurger(config.Empty(), repo)
+
+	now := time.Now()
+	require.NoError(t, purger.tick(now))
+	require.Equal(t, now.Add(-idempotencyKeyExpiration), repo.PurgedBefore)
+
+	repo.Err = errors.New("bad error")
+	require.Error(t, purger.tick(now))
+}
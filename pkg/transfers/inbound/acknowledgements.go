@@ -0,0 +1,126 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package inbound
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/moov-io/base/log"
+
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/transfers/files"
+)
+
+// AckParser reads a non-NACHA acknowledgement file from the ODFI which lists the
+// batches accepted or rejected from our uploaded files.
+type AckParser interface {
+	Parse(r io.Reader) ([]files.Acknowledgement, error)
+}
+
+var (
+	ackFormatsMu sync.RWMutex
+	ackFormats   = map[string]AckParser{
+		"batch-sequence": &batchSequenceParser{},
+	}
+)
+
+// RegisterAckFormat makes an AckParser available to config under name. Registering
+// a name twice replaces the earlier parser.
+func RegisterAckFormat(name string, parser AckParser) {
+	ackFormatsMu.Lock()
+	defer ackFormatsMu.Unlock()
+	ackFormats[name] = parser
+}
+
+func lookupAckFormat(name string) AckParser {
+	ackFormatsMu.RLock()
+	defer ackFormatsMu.RUnlock()
+	return ackFormats[name]
+}
+
+type ackPattern struct {
+	pattern string
+	parser  AckParser
+}
+
+// Acknowledgements parses inbound files matching configured filename patterns and saves
+// each acknowledgement against our upload history. Matching files aren't read as ACH files.
+//
+// A nil *Acknowledgements is valid and matches no files.
+type Acknowledgements struct {
+	logger   log.Logger
+	repo     files.Repository
+	patterns []ackPattern
+}
+
+func NewAcknowledgements(logger log.Logger, cfgs []config.AckFormat, repo files.Repository) (*Acknowledgements, error) {
+	if len(cfgs) == 0 {
+		return nil, nil
+	}
+	acks := &Acknowledgements{
+		logger: logger,
+		repo:   repo,
+	}
+	for i := range cfgs {
+		parser := lookupAckFormat(cfgs[i].Format)
+		if parser == nil {
+			return nil, fmt.Errorf("unknown acknowledgement format %q", cfgs[i].Format)
+		}
+		acks.patterns = append(acks.patterns, ackPattern{
+			pattern: cfgs[i].Pattern,
+			parser:  parser,
+		})
+	}
+	return acks, nil
+}
+
+// match returns the parser for the first pattern filename matches, or nil.
+func (acks *Acknowledgements) match(filename string) AckParser {
+	if acks == nil {
+		return nil
+	}
+	for i := range acks.patterns {
+		if ok, _ := filepath.Match(acks.patterns[i].pattern, filename); ok {
+			return acks.patterns[i].parser
+		}
+	}
+	return nil
+}
+
+func (acks *Acknowledgements) handle(path string, parser AckParser) error {
+	fd, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer fd.Close()
+
+	parsed, err := parser.Parse(fd)
+	if err != nil {
+		return fmt.Errorf("parsing acknowledgement: %v", err)
+	}
+
+	received := time.Now()
+	for i := range parsed {
+		logger := acks.logger.Set("filename", parsed[i].Filename)
+
+		err := acks.repo.SaveAcknowledgement(parsed[i], received)
+		if err == files.ErrUnknownFile {
+			// Acknowledgements can cover files uploaded by another system or from before
+			// upload history was recorded, so they're skipped rather than failing the file.
+			logger.Log("skipping acknowledgement for unknown file")
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("saving acknowledgement for %s: %v", parsed[i].Filename, err)
+		}
+		logger.Logf("saved acknowledgement of %d batches", len(parsed[i].Batches))
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package inbound
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/moov-io/base/log"
+
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/transfers/files"
+
+	"github.com/stretchr/testify/require"
+)
+
+type mockAckParser struct{}
+
+func (p *mockAckParser) Parse(r io.Reader) ([]files.Acknowledgement, error) {
+	return []files.Acknowledgement{{Filename: "custom.ach"}}, nil
+}
+
+func TestAcknowledgements(t *testing.T) {
+	acks, err := NewAcknowledgements(log.NewNopLogger(), nil, &files.MockRepository{})
+	require.NoError(t, err)
+	require.Nil(t, acks)
+	require.Nil(t, acks.match("file.ack"))
+
+	_, err = NewAcknowledgements(log.NewNopLogger(), []config.AckFormat{
+		{Pattern: "*.ack", Format: "missing"},
+	}, &files.MockRepository{})
+	require.Error(t, err)
+
+	RegisterAckFormat("mock", &mockAckParser{})
+	acks, err = NewAcknowledgements(log.NewNopLogger(), []config.AckFormat{
+		{Pattern: "*.ack", Format: "batch-sequence"},
+		{Pattern: "ACK_*.txt", Format: "mock"},
+	}, &files.MockRepository{})
+	require.NoError(t, err)
+
+	require.IsType(t, &batchSequenceParser{}, acks.match("20200601.ack"))
+	require.IsType(t, &mockAckParser{}, acks.match("ACK_20200601.txt"))
+	require.Nil(t, acks.match("20200601.ach"))
+}
+
+func TestAcknowledgements__process(t *testing.T) {
+	repo := &files.MockRepository{}
+	acks, err := NewAcknowledgements(log.NewNopLogger(), []config.AckFormat{
+		{Pattern: "*.ack", Format: "batch-sequence"},
+	}, repo)
+	require.NoError(t, err)
+
+	dir := testDir(t)
+	bs, err := ioutil.ReadFile(filepath.Join("testdata", "batch-sequence.ack"))
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "20200601.ack"), bs, 0600))
+
+	// acknowledgements aren't handed to the ACH file processors
+	processor := &MockProcessor{Err: errors.New("unexpected ACH file")}
+	require.NoError(t, process(dir, nil, acks, SetupProcessors(processor)))
+
+	require.NotNil(t, repo.Acknowledgement)
+	require.Equal(t, "20200601-987654320.ach", repo.Acknowledgement.Filename)
+	require.Len(t, repo.Acknowledgement.Batches, 2)
+
+	// acknowledgements for files we didn't upload are skipped
+	repo.Err = files.ErrUnknownFile
+	require.NoError(t, process(dir, nil, acks, SetupProcessors(processor)))
+}
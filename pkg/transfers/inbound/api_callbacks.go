@@ -0,0 +1,140 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package inbound
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/moov-io/base/admin"
+	"github.com/moov-io/base/log"
+
+	paygateadmin "github.com/moov-io/paygate/pkg/admin"
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/transfers"
+	"github.com/moov-io/paygate/pkg/transfers/files"
+	"github.com/moov-io/paygate/pkg/transfers/history"
+)
+
+// APICallbacks receives acknowledgements from ODFIs which accept batches through their API
+// (see upload.APITransferAgent). Batch results are saved against our upload history and
+// rejected entries mark their Transfer as failed.
+//
+// A nil *APICallbacks is valid and registers no routes.
+type APICallbacks struct {
+	logger       log.Logger
+	token        string
+	filesRepo    files.Repository
+	transferRepo transfers.Repository
+}
+
+func NewAPICallbacks(logger log.Logger, cfg *config.API, filesRepo files.Repository, transferRepo transfers.Repository) *APICallbacks {
+	if cfg == nil {
+		return nil
+	}
+	return &APICallbacks{
+		logger:       logger,
+		token:        cfg.CallbackToken,
+		filesRepo:    filesRepo,
+		transferRepo: transferRepo,
+	}
+}
+
+// RegisterRoutes adds 'POST /odfi/acknowledgements' to the admin server.
+func (cb *APICallbacks) RegisterRoutes(svc *admin.Server) {
+	if cb == nil {
+		return
+	}
+	svc.AddHandler("/odfi/acknowledgements", cb.receiveAcknowledgement())
+}
+
+func (cb *APICallbacks) authorized(r *http.Request) bool {
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return cb.token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(cb.token)) == 1
+}
+
+func (cb *APICallbacks) receiveAcknowledgement() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			problem(w, fmt.Errorf("invalid method %s", r.Method))
+			return
+		}
+		if !cb.authorized(r) {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		var ack paygateadmin.OdfiAcknowledgement
+		if err := json.NewDecoder(r.Body).Decode(&ack); err != nil {
+			problem(w, err)
+			return
+		}
+		if ack.FileName == "" {
+			problem(w, errors.New("missing fileName"))
+			return
+		}
+		if err := cb.handle(ack, time.Now()); err != nil {
+			cb.logger.Set("filename", ack.FileName).LogErrorf("problem handling ODFI acknowledgement: %v", err)
+			problem(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+func (cb *APICallbacks) handle(ack paygateadmin.OdfiAcknowledgement, received time.Time) error {
+	logger := cb.logger.Set("filename", ack.FileName)
+
+	saved := files.Acknowledgement{Filename: ack.FileName}
+	for i := range ack.Batches {
+		saved.Batches = append(saved.Batches, files.BatchAcknowledgement{
+			BatchNumber: int(ack.Batches[i].BatchNumber),
+			Accepted:    ack.Batches[i].Accepted,
+			Reason:      ack.Batches[i].Reason,
+		})
+	}
+	err := cb.filesRepo.SaveAcknowledgement(saved, received)
+	if err == files.ErrUnknownFile {
+		logger.Log("acknowledgement for unknown file")
+	} else if err != nil {
+		return fmt.Errorf("saving acknowledgement: %v", err)
+	}
+
+	for i := range ack.Batches {
+		for _, entry := range ack.Batches[i].Entries {
+			if entry.Accepted {
+				continue
+			}
+			if err := cb.rejectEntry(logger, entry); err != nil {
+				return err
+			}
+		}
+	}
+	logger.Logf("saved ODFI acknowledgement of %d batches", len(ack.Batches))
+	return nil
+}
+
+func (cb *APICallbacks) rejectEntry(logger log.Logger, entry paygateadmin.OdfiEntryAcknowledgement) error {
+	logger = logger.Set("traceNumber", entry.TraceNumber)
+
+	transfer, err := cb.transferRepo.LookupTransferFromTraceNumber(entry.TraceNumber)
+	if err != nil {
+		return fmt.Errorf("finding transfer for traceNumber=%s: %v", entry.TraceNumber, err)
+	}
+	if transfer == nil {
+		logger.Log("transfer not found from rejected entry")
+		return nil
+	}
+	if err := cb.transferRepo.UpdateTransferStatus(transfer.TransferID, client.FAILED, history.Inbound); err != nil {
+		return fmt.Errorf("problem marking transferID=%s as %s: %v", transfer.TransferID, client.FAILED, err)
+	}
+	logger.Set("transferID", transfer.TransferID).Logf("ODFI rejected entry: %s", entry.Reason)
+	return nil
+}
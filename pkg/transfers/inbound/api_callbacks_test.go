@@ -0,0 +1,84 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package inbound
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/moov-io/base/log"
+
+	"github.com/moov-io/paygate/pkg/admin"
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/transfers"
+	"github.com/moov-io/paygate/pkg/transfers/files"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPICallbacks(t *testing.T) {
+	require.Nil(t, NewAPICallbacks(log.NewNopLogger(), nil, nil, nil))
+
+	filesRepo := &files.MockRepository{}
+	transferRepo := &transfers.MockRepository{
+		Transfers: []*client.Transfer{{TransferID: "xfer"}},
+	}
+	cb := NewAPICallbacks(log.NewNopLogger(), &config.API{CallbackToken: "secret"}, filesRepo, transferRepo)
+
+	ack := admin.OdfiAcknowledgement{
+		FileName: "20200601-987654320-1.ach",
+		Batches: []admin.OdfiBatchAcknowledgement{
+			{
+				BatchNumber: 1,
+				Accepted:    true,
+				Entries: []admin.OdfiEntryAcknowledgement{
+					{TraceNumber: "987654320000001", Accepted: true},
+					{TraceNumber: "987654320000002", Accepted: false, Reason: "R03"},
+				},
+			},
+		},
+	}
+	send := func(token string, body interface{}) *httptest.ResponseRecorder {
+		var buf bytes.Buffer
+		json.NewEncoder(&buf).Encode(body)
+
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/odfi/acknowledgements", &buf)
+		req.Header.Set("Authorization", "Bearer "+token)
+		cb.receiveAcknowledgement()(w, req)
+		w.Flush()
+		return w
+	}
+
+	w := send("other", ack)
+	require.Equal(t, http.StatusForbidden, w.Code)
+	require.Nil(t, filesRepo.Acknowledgement)
+
+	w = send("secret", ack)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NotNil(t, filesRepo.Acknowledgement)
+	require.Equal(t, ack.FileName, filesRepo.Acknowledgement.Filename)
+	require.Len(t, filesRepo.Acknowledgement.Batches, 1)
+	require.True(t, filesRepo.Acknowledgement.Batches[0].Accepted)
+
+	// missing fileName
+	w = send("secret", admin.OdfiAcknowledgement{})
+	require.Equal(t, http.StatusBadRequest, w.Code)
+
+	// acknowledgements for files we didn't upload still update transfers
+	filesRepo.Err = files.ErrUnknownFile
+	w = send("secret", ack)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	filesRepo.Err = nil
+	transferRepo.Err = errors.New("bad error")
+	w = send("secret", ack)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
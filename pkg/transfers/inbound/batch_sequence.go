@@ -0,0 +1,96 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package inbound
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/moov-io/paygate/pkg/transfers/files"
+)
+
+// batchSequenceParser reads fixed-width acknowledgements where each record's first
+// character is its type:
+//
+//	F  positions 2-51   filename of the uploaded file
+//	B  positions 2-8    batch number
+//	   position  9      A (accepted) or R (rejected)
+//	   positions 10-12  reason code
+//	   positions 13-    reason description
+//
+// Batch records belong to the most recent file record. Other record types (such as
+// headers and trailers) are ignored.
+type batchSequenceParser struct{}
+
+func (p *batchSequenceParser) Parse(r io.Reader) ([]files.Acknowledgement, error) {
+	var out []files.Acknowledgement
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		record := strings.TrimRight(scanner.Text(), "\r ")
+		if record == "" {
+			continue
+		}
+		switch record[0] {
+		case 'F':
+			filename := strings.TrimSpace(fixedField(record, 1, 51))
+			if filename == "" {
+				return nil, fmt.Errorf("line %d: missing filename", line)
+			}
+			out = append(out, files.Acknowledgement{Filename: filename})
+
+		case 'B':
+			if len(out) == 0 {
+				return nil, fmt.Errorf("line %d: batch record before file record", line)
+			}
+			batch, err := parseBatchSequenceRecord(record)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", line, err)
+			}
+			out[len(out)-1].Batches = append(out[len(out)-1].Batches, batch)
+		}
+	}
+	return out, scanner.Err()
+}
+
+func parseBatchSequenceRecord(record string) (files.BatchAcknowledgement, error) {
+	var batch files.BatchAcknowledgement
+	if len(record) < 9 {
+		return batch, errors.New("short batch record")
+	}
+
+	n, err := strconv.Atoi(strings.TrimSpace(fixedField(record, 1, 8)))
+	if err != nil {
+		return batch, fmt.Errorf("invalid batch number: %v", err)
+	}
+	batch.BatchNumber = n
+
+	switch record[8] {
+	case 'A':
+		batch.Accepted = true
+	case 'R':
+		code := strings.TrimSpace(fixedField(record, 9, 12))
+		desc := strings.TrimSpace(fixedField(record, 12, len(record)))
+		batch.Reason = strings.TrimSpace(code + " " + desc)
+	default:
+		return batch, fmt.Errorf("unknown batch status %q", record[8])
+	}
+	return batch, nil
+}
+
+// fixedField returns record[start:end] or whatever part of it exists
+func fixedField(record string, start, end int) string {
+	if start >= len(record) {
+		return ""
+	}
+	if end > len(record) {
+		end = len(record)
+	}
+	return record[start:end]
+}
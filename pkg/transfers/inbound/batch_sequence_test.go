@@ -0,0 +1,48 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package inbound
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBatchSequence__Parse(t *testing.T) {
+	fd, err := os.Open(filepath.Join("testdata", "batch-sequence.ack"))
+	require.NoError(t, err)
+	defer fd.Close()
+
+	acks, err := (&batchSequenceParser{}).Parse(fd)
+	require.NoError(t, err)
+	require.Len(t, acks, 1)
+
+	require.Equal(t, "20200601-987654320.ach", acks[0].Filename)
+	require.Len(t, acks[0].Batches, 2)
+	require.Equal(t, 1, acks[0].Batches[0].BatchNumber)
+	require.True(t, acks[0].Batches[0].Accepted)
+	require.Equal(t, 2, acks[0].Batches[1].BatchNumber)
+	require.False(t, acks[0].Batches[1].Accepted)
+	require.Equal(t, "R01 INVALID COMPANY ID", acks[0].Batches[1].Reason)
+}
+
+func TestBatchSequence__ParseErr(t *testing.T) {
+	parser := &batchSequenceParser{}
+
+	cases := []string{
+		"B0000001A\n",            // batch before file
+		"F\n",                    // missing filename
+		"Ffile.ach\nB00000XXA\n", // invalid batch number
+		"Ffile.ach\nB0000001X\n", // unknown status
+		"Ffile.ach\nB000001\n",   // short record
+	}
+	for i := range cases {
+		_, err := parser.Parse(strings.NewReader(cases[i]))
+		require.Error(t, err, "case %d: %q", i, cases[i])
+	}
+}
@@ -5,8 +5,16 @@
 package inbound
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/moov-io/ach"
 
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/transfers"
+	"github.com/moov-io/paygate/pkg/webhooks"
+
 	"github.com/go-kit/kit/metrics/prometheus"
 	"github.com/moov-io/base/log"
 	stdprometheus "github.com/prometheus/client_golang/prometheus"
@@ -19,13 +27,26 @@ var (
 	}, []string{"origin", "destination", "code"})
 )
 
+// PrenoteCorrections handles Notifications of Change for Transfers which were created as prenotes.
+type PrenoteCorrections interface {
+	HandleCorrection(transferID string, changeCode string) error
+}
+
 type correctionProcessor struct {
-	logger log.Logger
+	logger       log.Logger
+	policies     config.Corrections
+	transferRepo transfers.Repository
+	prenotes     PrenoteCorrections
+	events       webhooks.Sender
 }
 
-func NewCorrectionProcessor(logger log.Logger) *correctionProcessor {
+func NewCorrectionProcessor(logger log.Logger, policies config.Corrections, transferRepo transfers.Repository, prenotes PrenoteCorrections, events webhooks.Sender) *correctionProcessor {
 	return &correctionProcessor{
-		logger: logger,
+		logger:       logger,
+		policies:     policies,
+		transferRepo: transferRepo,
+		prenotes:     prenotes,
+		events:       events,
 	}
 }
 
@@ -56,8 +77,77 @@ func (pc *correctionProcessor) Handle(file *ach.File) error {
 				"destination", file.Header.ImmediateDestination,
 				"code", changeCode.Code,
 			).Add(1)
+
+			if err := pc.handleCorrection(file.Header, entries[j]); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// handleCorrection stores the Notification of Change, records what it corrects about the
+// Account, fails any prenote and sends a webhook for the Transfer it was received for.
+func (pc *correctionProcessor) handleCorrection(fh ach.FileHeader, entry *ach.EntryDetail) error {
+	if pc.transferRepo == nil {
+		return nil
+	}
+	addenda98 := entry.Addenda98
+	traceNumber := strings.TrimSpace(addenda98.OriginalTrace)
+	transfer, err := pc.transferRepo.LookupTransferFromTraceNumber(traceNumber)
+	if err != nil {
+		return fmt.Errorf("problem with corrected Transfer: %v", err)
+	}
+	if transfer == nil {
+		pc.logger.Set("traceNumber", traceNumber).Log("transfer not found from correction entry")
+		return saveInboundRecord(pc.transferRepo, fh, "correction", "", entry)
+	}
+	if err := saveInboundRecord(pc.transferRepo, fh, "correction", transfer.TransferID, entry); err != nil {
+		return err
+	}
+	if err := pc.saveCorrection(transfer, entry); err != nil {
+		return err
+	}
+	if pc.prenotes != nil {
+		if err := pc.prenotes.HandleCorrection(transfer.TransferID, addenda98.ChangeCode); err != nil {
+			return fmt.Errorf("problem handling prenote correction for transferID=%s: %v", transfer.TransferID, err)
 		}
 	}
+	if pc.events == nil {
+		return nil
+	}
+	sendTransferEvent(pc.logger, pc.transferRepo, pc.events, webhooks.EventTransferCorrected, transfer, webhooks.TransferUpdate{
+		TransferID:    transfer.TransferID,
+		Status:        transfer.Status,
+		ChangeCode:    addenda98.ChangeCode,
+		CorrectedData: strings.TrimSpace(addenda98.CorrectedData),
+	})
+	return nil
+}
+
+// saveCorrection records the values a Notification of Change corrects for an Account. Corrections
+// are applied right away when their change code is configured to, otherwise they wait for review.
+func (pc *correctionProcessor) saveCorrection(transfer *client.Transfer, entry *ach.EntryDetail) error {
+	correction := transfers.NewAccountCorrection(transfer, entry)
+	if correction == nil {
+		return nil
+	}
+	if len(correction.Changes) > 0 && pc.policies.Applies(correction.ChangeCode) {
+		correction.Status = client.ACCOUNTCORRECTIONSTATUS_APPLIED
+	}
 
+	organization, err := pc.transferRepo.GetTransferOrganization(transfer.TransferID)
+	if err != nil {
+		return fmt.Errorf("problem finding organization for transferID=%s: %v", transfer.TransferID, err)
+	}
+	if err := pc.transferRepo.SaveAccountCorrection(organization, correction); err != nil {
+		return fmt.Errorf("problem saving correction for transferID=%s: %v", transfer.TransferID, err)
+	}
+	pc.logger.With(log.Fields{
+		"transferID": transfer.TransferID,
+		"accountID":  correction.AccountID,
+		"changeCode": correction.ChangeCode,
+	}).Logf("inbound: account correction %s", correction.Status)
 	return nil
 }
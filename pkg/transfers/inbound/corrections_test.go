@@ -0,0 +1,151 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package inbound
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/moov-io/ach"
+	"github.com/moov-io/base"
+	"github.com/moov-io/base/log"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/transfers"
+	"github.com/moov-io/paygate/pkg/webhooks"
+
+	"github.com/stretchr/testify/require"
+)
+
+func correctionFile(t *testing.T) *ach.File {
+	t.Helper()
+
+	bh := ach.NewBatchHeader()
+	bh.StandardEntryClassCode = ach.COR
+	batch, err := ach.NewBatch(bh)
+	require.NoError(t, err)
+
+	entry := ach.NewEntryDetail()
+	entry.Addenda98 = ach.NewAddenda98()
+	entry.Addenda98.ChangeCode = "C01"
+	entry.Addenda98.OriginalTrace = "121042880000001"
+	entry.Addenda98.CorrectedData = "1918171614"
+	batch.AddEntry(entry)
+
+	file := ach.NewFile()
+	file.NotificationOfChange = append(file.NotificationOfChange, batch)
+	return file
+}
+
+func TestCorrections__Handle(t *testing.T) {
+	transferID := base.ID()
+	repo := &transfers.MockRepository{
+		Transfers: []*client.Transfer{
+			{TransferID: transferID, Status: client.PROCESSED},
+		},
+		Organization: "moov",
+	}
+	events := &webhooks.MockSender{}
+	processor := NewCorrectionProcessor(log.NewNopLogger(), config.Corrections{}, repo, nil, events)
+
+	require.NoError(t, processor.Handle(correctionFile(t)))
+	require.Len(t, events.Events, 1)
+
+	event := events.Events[0]
+	require.Equal(t, webhooks.EventTransferCorrected, event.Type)
+	require.Equal(t, "moov", event.Organization)
+	require.Equal(t, webhooks.TransferUpdate{
+		TransferID:    transferID,
+		Status:        client.PROCESSED,
+		ChangeCode:    "C01",
+		CorrectedData: "1918171614",
+	}, event.Data)
+
+	require.Len(t, repo.InboundRecords, 1)
+	require.Equal(t, transferID, repo.InboundRecords[0].TransferID)
+	require.Equal(t, "correction", repo.InboundRecords[0].Type)
+	require.Equal(t, "C01", repo.InboundRecords[0].Code)
+	require.Len(t, repo.InboundRecords[0].Addenda, 94)
+
+	// no Transfer found
+	repo.Transfers = nil
+	require.NoError(t, processor.Handle(correctionFile(t)))
+	require.Len(t, events.Events, 1)
+	require.Len(t, repo.InboundRecords, 2)
+	require.Empty(t, repo.InboundRecords[1].TransferID)
+
+	// error from the repository
+	repo.Err = errors.New("bad error")
+	require.Error(t, processor.Handle(correctionFile(t)))
+}
+
+func TestCorrections__AccountCorrections(t *testing.T) {
+	transfer := &client.Transfer{
+		TransferID:  base.ID(),
+		Source:      client.Source{CustomerID: base.ID(), AccountID: base.ID()},
+		Destination: client.Destination{CustomerID: base.ID(), AccountID: base.ID()},
+		Status:      client.PROCESSED,
+	}
+	repo := &transfers.MockRepository{
+		Transfers:    []*client.Transfer{transfer},
+		Organization: "moov",
+	}
+	processor := NewCorrectionProcessor(log.NewNopLogger(), config.Corrections{AutoApply: []string{"C01"}}, repo, nil, nil)
+
+	file := correctionFile(t)
+	entry := file.NotificationOfChange[0].GetEntries()[0]
+	entry.TransactionCode = ach.CheckingReturnNOCCredit
+	entry.DFIAccountNumber = "1234567"
+	require.NoError(t, processor.Handle(file))
+
+	require.Len(t, repo.Corrections, 1)
+	correction := repo.Corrections[0]
+	require.Equal(t, transfer.Destination.AccountID, correction.AccountID)
+	require.Equal(t, "C01", correction.ChangeCode)
+	require.Equal(t, client.ACCOUNTCORRECTIONSTATUS_APPLIED, correction.Status)
+	require.Equal(t, []client.AccountCorrectionChange{
+		{Field: "accountNumber", OldValue: "1234567", NewValue: "1918171614"},
+	}, correction.Changes)
+
+	// other change codes wait for review
+	entry.Addenda98.ChangeCode = "C05"
+	entry.Addenda98.CorrectedData = "32"
+	require.NoError(t, processor.Handle(file))
+
+	require.Len(t, repo.Corrections, 2)
+	correction = repo.Corrections[1]
+	require.Equal(t, client.ACCOUNTCORRECTIONSTATUS_PENDING, correction.Status)
+	require.Equal(t, []client.AccountCorrectionChange{
+		{Field: "accountType", OldValue: "checking", NewValue: "savings"},
+	}, correction.Changes)
+}
+
+type mockPrenoteCorrections struct {
+	transferIDs []string
+	err         error
+}
+
+func (m *mockPrenoteCorrections) HandleCorrection(transferID string, changeCode string) error {
+	m.transferIDs = append(m.transferIDs, transferID)
+	return m.err
+}
+
+func TestCorrections__Prenotes(t *testing.T) {
+	transferID := base.ID()
+	repo := &transfers.MockRepository{
+		Transfers: []*client.Transfer{
+			{TransferID: transferID, Status: client.PROCESSED},
+		},
+	}
+	prenotes := &mockPrenoteCorrections{}
+	processor := NewCorrectionProcessor(log.NewNopLogger(), config.Corrections{}, repo, prenotes, nil)
+
+	require.NoError(t, processor.Handle(correctionFile(t)))
+	require.Equal(t, []string{transferID}, prenotes.transferIDs)
+
+	prenotes.err = errors.New("bad error")
+	require.Error(t, processor.Handle(correctionFile(t)))
+}
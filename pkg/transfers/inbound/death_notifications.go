@@ -0,0 +1,184 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package inbound
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/moov-io/ach"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/transfers"
+	"github.com/moov-io/paygate/pkg/transfers/pipeline"
+	"github.com/moov-io/paygate/pkg/webhooks"
+
+	"github.com/go-kit/kit/metrics/prometheus"
+	"github.com/moov-io/base/log"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	deathNotificationsProcessed = prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+		Name: "death_notifications_processed",
+		Help: "Counter of Death Notification Entry (DNE) records processed",
+	}, []string{"origin", "destination", "result"})
+)
+
+const (
+	deathNotificationMatched   = "matched"
+	deathNotificationUnmatched = "unmatched"
+	deathNotificationError     = "error"
+)
+
+// TransferCanceler removes canceled Transfers from files waiting to be merged.
+type TransferCanceler interface {
+	HandleCancel(cancel pipeline.CanceledTransfer) error
+}
+
+// deathNotificationProcessor handles Death Notification Entries (DNE) sent by federal agencies.
+// Each Customer and Account we've sent an entry to the same routing and account number is
+// marked deceased, which cancels their pending Transfers and rejects new ones.
+type deathNotificationProcessor struct {
+	logger       log.Logger
+	transferRepo transfers.Repository
+	canceler     TransferCanceler
+	events       webhooks.Sender
+}
+
+func NewDeathNotificationProcessor(logger log.Logger, transferRepo transfers.Repository, canceler TransferCanceler, events webhooks.Sender) *deathNotificationProcessor {
+	return &deathNotificationProcessor{
+		logger:       logger,
+		transferRepo: transferRepo,
+		canceler:     canceler,
+		events:       events,
+	}
+}
+
+func (pc *deathNotificationProcessor) Type() string {
+	return "death_notification"
+}
+
+func (pc *deathNotificationProcessor) Handle(file *ach.File) error {
+	for i := range file.Batches {
+		if file.Batches[i].GetHeader().StandardEntryClassCode != ach.DNE {
+			continue
+		}
+		entries := file.Batches[i].GetEntries()
+		for j := range entries {
+			pc.logger.With(log.Fields{
+				"origin":      file.Header.ImmediateOrigin,
+				"destination": file.Header.ImmediateDestination,
+				"traceNumber": entries[j].TraceNumber,
+			}).Log("inbound: death notification")
+
+			result, err := pc.handleEntry(file.Header, entries[j])
+			if err != nil {
+				result = deathNotificationError
+			}
+			deathNotificationsProcessed.With(
+				"origin", file.Header.ImmediateOrigin,
+				"destination", file.Header.ImmediateDestination,
+				"result", result,
+			).Add(1)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// handleEntry marks each Account the entry is for as deceased and returns whether any were found.
+func (pc *deathNotificationProcessor) handleEntry(fh ach.FileHeader, entry *ach.EntryDetail) (string, error) {
+	if pc.transferRepo == nil {
+		return deathNotificationUnmatched, nil
+	}
+	if err := saveInboundRecord(pc.transferRepo, fh, "death_notification", "", entry); err != nil {
+		return "", err
+	}
+
+	accountNumber := strings.TrimSpace(entry.DFIAccountNumber)
+	accounts, err := pc.transferRepo.LookupEntryAccounts(entry.RDFIIdentification, accountNumber)
+	if err != nil {
+		return "", fmt.Errorf("problem finding accounts for death notification traceNumber=%s: %v", entry.TraceNumber, err)
+	}
+	if len(accounts) == 0 {
+		pc.logger.Set("traceNumber", entry.TraceNumber).Log("no accounts found for death notification")
+		return deathNotificationUnmatched, nil
+	}
+
+	dateOfDeath := parseDateOfDeath(entry)
+	for i := range accounts {
+		notification := &transfers.DeathNotification{
+			EntryAccount: accounts[i],
+			TraceNumber:  entry.TraceNumber,
+			DateOfDeath:  dateOfDeath,
+			Received:     time.Now(),
+		}
+		canceled, err := pc.transferRepo.SaveDeathNotification(notification)
+		if err != nil {
+			return "", fmt.Errorf("problem saving death notification for customerID=%s: %v", accounts[i].CustomerID, err)
+		}
+		logger := pc.logger.With(log.Fields{
+			"organization": accounts[i].Organization,
+			"customerID":   accounts[i].CustomerID,
+			"accountID":    accounts[i].AccountID,
+		})
+		logger.Logf("marked customer deceased and canceled %d transfers", len(canceled))
+
+		for j := range canceled {
+			if pc.canceler == nil {
+				break
+			}
+			cancel := pipeline.CanceledTransfer{
+				TransferID: canceled[j],
+				Reason:     string(client.CANCELLATIONREASON_COMPLIANCE),
+				Note:       "death notification",
+			}
+			if err := pc.canceler.HandleCancel(cancel); err != nil {
+				logger.LogErrorf("problem canceling transferID=%s in pipeline: %v", canceled[j], err)
+			}
+		}
+		pc.sendEvent(logger, accounts[i], dateOfDeath, canceled)
+	}
+	return deathNotificationMatched, nil
+}
+
+// sendEvent notifies external systems a Customer was marked deceased. Failures are logged as
+// webhooks are not allowed to block inbound file processing.
+func (pc *deathNotificationProcessor) sendEvent(logger log.Logger, acct transfers.EntryAccount, dateOfDeath *time.Time, canceled []string) {
+	if pc.events == nil {
+		return
+	}
+	event := webhooks.CustomerEvent(webhooks.EventCustomerDeceased, acct.Organization, webhooks.CustomerUpdate{
+		CustomerID:          acct.CustomerID,
+		AccountID:           acct.AccountID,
+		DateOfDeath:         dateOfDeath,
+		CanceledTransferIDs: canceled,
+	})
+	if err := pc.events.Send(event); err != nil {
+		logger.LogErrorf("problem sending %s webhook: %v", webhooks.EventCustomerDeceased, err)
+	}
+}
+
+// dateOfDeathPattern matches the date in the Addenda05 of DNE entries, which NACHA formats as
+// "DATE OF DEATH*MMDDYY*CUSTOMERSSN*#########*AMOUNT*$$$$.cc\"
+var dateOfDeathPattern = regexp.MustCompile(`DATE OF DEATH\*(\d{6})\*`)
+
+func parseDateOfDeath(entry *ach.EntryDetail) *time.Time {
+	for i := range entry.Addenda05 {
+		m := dateOfDeathPattern.FindStringSubmatch(entry.Addenda05[i].PaymentRelatedInformation)
+		if len(m) != 2 {
+			continue
+		}
+		if when, err := time.Parse("010206", m[1]); err == nil {
+			return &when
+		}
+	}
+	return nil
+}
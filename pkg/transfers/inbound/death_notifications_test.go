@@ -0,0 +1,98 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package inbound
+
+import (
+	"testing"
+	"time"
+
+	"github.com/moov-io/ach"
+	"github.com/moov-io/base/log"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/transfers"
+	"github.com/moov-io/paygate/pkg/transfers/pipeline"
+	"github.com/moov-io/paygate/pkg/webhooks"
+
+	"github.com/stretchr/testify/require"
+)
+
+func deathNotificationFile(t *testing.T) *ach.File {
+	t.Helper()
+
+	bh := ach.NewBatchHeader()
+	bh.StandardEntryClassCode = ach.DNE
+	batch, err := ach.NewBatch(bh)
+	require.NoError(t, err)
+
+	entry := ach.NewEntryDetail()
+	entry.TransactionCode = ach.CheckingReturnNOCCredit
+	entry.RDFIIdentification = "23138010"
+	entry.DFIAccountNumber = "744-5678-99      "
+	entry.TraceNumber = "121042880000001"
+
+	addenda := ach.NewAddenda05()
+	addenda.PaymentRelatedInformation = `DATE OF DEATH*010218*CUSTOMERSSN*#########*AMOUNT*$$$$.cc\`
+	entry.AddAddenda05(addenda)
+	batch.AddEntry(entry)
+
+	file := ach.NewFile()
+	file.AddBatch(batch)
+	return file
+}
+
+func TestDeathNotifications__parseDateOfDeath(t *testing.T) {
+	entry := deathNotificationFile(t).Batches[0].GetEntries()[0]
+
+	when := parseDateOfDeath(entry)
+	require.NotNil(t, when)
+	require.Equal(t, time.Date(2018, time.January, 2, 0, 0, 0, 0, time.UTC), *when)
+
+	entry.Addenda05[0].PaymentRelatedInformation = "DATE OF DEATH*MMDDYY*"
+	require.Nil(t, parseDateOfDeath(entry))
+}
+
+func TestDeathNotifications__Handle(t *testing.T) {
+	acct := transfers.EntryAccount{Organization: "moov", CustomerID: "jane", AccountID: "checking"}
+	repo := &transfers.MockRepository{
+		EntryAccounts:     []transfers.EntryAccount{acct},
+		CanceledTransfers: []string{"xfer"},
+	}
+	merger := &pipeline.MockXferMerging{}
+	events := &webhooks.MockSender{}
+	processor := NewDeathNotificationProcessor(log.NewNopLogger(), repo, merger, events)
+
+	require.NoError(t, processor.Handle(deathNotificationFile(t)))
+
+	require.Len(t, repo.InboundRecords, 1)
+	require.Equal(t, "death_notification", repo.InboundRecords[0].Type)
+
+	require.Len(t, repo.DeathNotifications, 1)
+	require.Equal(t, acct, repo.DeathNotifications[0].EntryAccount)
+	require.Equal(t, "121042880000001", repo.DeathNotifications[0].TraceNumber)
+	require.NotNil(t, repo.DeathNotifications[0].DateOfDeath)
+
+	require.NotNil(t, merger.LatestCancel)
+	require.Equal(t, "xfer", merger.LatestCancel.TransferID)
+	require.Equal(t, string(client.CANCELLATIONREASON_COMPLIANCE), merger.LatestCancel.Reason)
+
+	require.Len(t, events.Events, 1)
+	event := events.Events[0]
+	require.Equal(t, webhooks.EventCustomerDeceased, event.Type)
+	require.Equal(t, "moov", event.Organization)
+	require.Equal(t, []string{"xfer"}, event.Data.(webhooks.CustomerUpdate).CanceledTransferIDs)
+	require.Contains(t, event.Links, webhooks.Link{Type: webhooks.LinkCustomer, ID: "jane"})
+}
+
+func TestDeathNotifications__HandleUnmatched(t *testing.T) {
+	repo := &transfers.MockRepository{}
+	events := &webhooks.MockSender{}
+	processor := NewDeathNotificationProcessor(log.NewNopLogger(), repo, nil, events)
+
+	require.NoError(t, processor.Handle(deathNotificationFile(t)))
+	require.Len(t, repo.InboundRecords, 1)
+	require.Empty(t, repo.DeathNotifications)
+	require.Empty(t, events.Events)
+}
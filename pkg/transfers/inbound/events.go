@@ -0,0 +1,52 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package inbound
+
+import (
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/transfers"
+	"github.com/moov-io/paygate/pkg/webhooks"
+
+	"github.com/moov-io/base/log"
+)
+
+// sendTransferEvent notifies external systems about an inbound file changing a Transfer.
+// Failures are logged as webhooks are not allowed to block inbound file processing.
+func sendTransferEvent(
+	logger log.Logger,
+	repo transfers.Repository,
+	events webhooks.Sender,
+	eventType string,
+	transfer *client.Transfer,
+	update webhooks.TransferUpdate,
+) {
+	if events == nil || transfer == nil {
+		return
+	}
+	logger = logger.With(log.Fields{
+		"transferID":    transfer.TransferID,
+		"correlationID": transfer.CorrelationID,
+	})
+	if update.CorrelationID == "" {
+		update.CorrelationID = transfer.CorrelationID
+	}
+
+	organization, err := repo.GetTransferOrganization(transfer.TransferID)
+	if err != nil {
+		logger.LogErrorf("problem finding organization for %s webhook: %v", eventType, err)
+		return
+	}
+
+	event := webhooks.TransferEvent(eventType, organization, update)
+	event.Links = append(event.Links, webhooks.Links(
+		webhooks.LinkCustomer, transfer.Source.CustomerID,
+		webhooks.LinkAccount, transfer.Source.AccountID,
+		webhooks.LinkCustomer, transfer.Destination.CustomerID,
+		webhooks.LinkAccount, transfer.Destination.AccountID,
+	)...)
+	if err := events.Send(event); err != nil {
+		logger.LogErrorf("problem sending %s webhook: %v", eventType, err)
+	}
+}
@@ -4,7 +4,15 @@
 
 package inbound
 
+import (
+	"github.com/moov-io/paygate/pkg/admin"
+	"github.com/moov-io/paygate/x/errorlog"
+)
+
 type MockScheduler struct {
+	Sync   *admin.InboundSync
+	Errors []errorlog.Entry
+
 	Err error
 }
 
@@ -13,3 +21,11 @@ func (s *MockScheduler) Start() error {
 }
 
 func (s *MockScheduler) Shutdown() {}
+
+func (s *MockScheduler) LastSync() *admin.InboundSync {
+	return s.Sync
+}
+
+func (s *MockScheduler) RecentErrors() []errorlog.Entry {
+	return s.Errors
+}
@@ -39,14 +39,17 @@ func (pcs Processors) HandleAll(file *ach.File) error {
 	return el
 }
 
-func ProcessFiles(dl *downloadedFiles, fileProcessors Processors) error {
+// ProcessFiles handles each downloaded file with fileProcessors. Files which quarantine
+// rejects are held for review instead of being processed, and acknowledgement files are
+// saved against our upload history.
+func ProcessFiles(dl *downloadedFiles, quarantine *Quarantine, acks *Acknowledgements, fileProcessors Processors) error {
 	var el base.ErrorList
 	dirs, err := ioutil.ReadDir(dl.dir)
 	if err != nil {
 		return fmt.Errorf("reading %s: %v", dl.dir, err)
 	}
 	for i := range dirs {
-		if err := process(filepath.Join(dl.dir, dirs[i].Name()), fileProcessors); err != nil {
+		if err := process(filepath.Join(dl.dir, dirs[i].Name()), quarantine, acks, fileProcessors); err != nil {
 			el.Add(fmt.Errorf("%s: %v", dirs[i], err))
 		}
 	}
@@ -56,7 +59,7 @@ func ProcessFiles(dl *downloadedFiles, fileProcessors Processors) error {
 	return el
 }
 
-func process(dir string, fileProcessors Processors) error {
+func process(dir string, quarantine *Quarantine, acks *Acknowledgements, fileProcessors Processors) error {
 	infos, err := ioutil.ReadDir(dir)
 	if err != nil {
 		return fmt.Errorf("reading %s: %v", dir, err)
@@ -64,7 +67,20 @@ func process(dir string, fileProcessors Processors) error {
 
 	var el base.ErrorList
 	for i := range infos {
-		file, err := ach.ReadFile(filepath.Join(dir, infos[i].Name()))
+		path := filepath.Join(dir, infos[i].Name())
+		if parser := acks.match(infos[i].Name()); parser != nil {
+			if err := acks.handle(path, parser); err != nil {
+				el.Add(fmt.Errorf("processing acknowledgement %s: %v", infos[i].Name(), err))
+			}
+			continue
+		}
+		file, err := ach.ReadFile(path)
+		if reason := quarantine.inspect(file, err); reason != "" {
+			if _, err := quarantine.hold(path, file, reason); err != nil {
+				el.Add(fmt.Errorf("problem quarantining %s: %v", infos[i].Name(), err))
+			}
+			continue
+		}
 		if err != nil {
 			// Some return files don't contain FileHeader info, but can be processed as there
 			// are batches with entries. Let's continue to process those, but skip other errors.
@@ -21,7 +21,7 @@ func TestProcessor__process(t *testing.T) {
 	// By reading a file without ACH FileHeaders we still want to try and process
 	// Batches inside of it if any are found, so reading this kind of file shouldn't
 	// return an error from reading the file.
-	if err := process(dir, processors); err != nil {
+	if err := process(dir, nil, nil, processors); err != nil {
 		t.Error(err)
 	}
 }
@@ -0,0 +1,219 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package inbound
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/moov-io/ach"
+	"github.com/moov-io/base"
+	"github.com/moov-io/base/log"
+
+	paygateadmin "github.com/moov-io/paygate/pkg/admin"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/transfers/pipeline/notify"
+
+	"github.com/go-kit/kit/metrics/prometheus"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	filesQuarantined = prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+		Name: "files_quarantined",
+		Help: "Counter of inbound files quarantined instead of processed",
+	}, nil)
+)
+
+const quarantineMetadataFilename = "quarantine.json"
+
+// Quarantine holds inbound files which fail structural validation or come from an
+// unexpected origin so they can be inspected, then approved or discarded, by an operator.
+//
+// A nil *Quarantine is valid and never holds any files.
+type Quarantine struct {
+	cfg      *config.Quarantine
+	logger   log.Logger
+	notifier notify.Sender
+}
+
+func NewQuarantine(logger log.Logger, cfg *config.Quarantine, notifier notify.Sender) (*Quarantine, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	if err := os.MkdirAll(cfg.Directory, 0777); err != nil {
+		return nil, fmt.Errorf("creating quarantine directory: %v", err)
+	}
+	return &Quarantine{
+		cfg:      cfg,
+		logger:   logger,
+		notifier: notifier,
+	}, nil
+}
+
+// inspect returns why a file should be quarantined, or an empty string if it can be processed.
+func (q *Quarantine) inspect(file *ach.File, readErr error) string {
+	if q == nil {
+		return ""
+	}
+	if readErr != nil && !base.Has(readErr, ach.ErrFileHeader) {
+		return fmt.Sprintf("invalid file: %v", readErr)
+	}
+	if file == nil {
+		return "empty file"
+	}
+	origin := strings.TrimSpace(file.Header.ImmediateOrigin)
+	if origin == "" || len(q.cfg.AllowedOrigins) == 0 {
+		return ""
+	}
+	for i := range q.cfg.AllowedOrigins {
+		if strings.TrimSpace(q.cfg.AllowedOrigins[i]) == origin {
+			return ""
+		}
+	}
+	return fmt.Sprintf("unexpected origin %s", origin)
+}
+
+// hold copies the file at path into the quarantine directory and notifies operators.
+func (q *Quarantine) hold(path string, file *ach.File, reason string) (*paygateadmin.QuarantinedFile, error) {
+	qf := &paygateadmin.QuarantinedFile{
+		QuarantineID: base.ID(),
+		Filename:     filepath.Base(path),
+		Reason:       reason,
+		Created:      time.Now(),
+	}
+	if file != nil {
+		qf.Origin = strings.TrimSpace(file.Header.ImmediateOrigin)
+	}
+
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	dir := filepath.Join(q.cfg.Directory, qf.QuarantineID)
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return nil, err
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, qf.Filename), contents, 0600); err != nil {
+		return nil, err
+	}
+	bs, err := json.Marshal(qf)
+	if err != nil {
+		return nil, err
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, quarantineMetadataFilename), bs, 0600); err != nil {
+		return nil, err
+	}
+
+	filesQuarantined.Add(1)
+	q.logger.With(log.Fields{
+		"quarantineID": qf.QuarantineID,
+		"filename":     qf.Filename,
+	}).Logf("quarantined inbound file: %s", reason)
+
+	msg := &notify.Message{
+		Direction: notify.Quarantine,
+		Filename:  qf.Filename,
+		File:      file,
+	}
+	if err := q.notifier.Critical(msg); err != nil {
+		q.logger.LogErrorf("problem sending quarantine notification for %s: %v", qf.Filename, err)
+	}
+
+	return qf, nil
+}
+
+func (q *Quarantine) dir(quarantineID string) (string, error) {
+	if quarantineID == "" || strings.ContainsAny(quarantineID, `./\`) {
+		return "", errors.New("invalid quarantineID")
+	}
+	dir := filepath.Join(q.cfg.Directory, quarantineID)
+	if _, err := os.Stat(dir); err != nil {
+		if os.IsNotExist(err) {
+			return "", fmt.Errorf("quarantineID=%s not found", quarantineID)
+		}
+		return "", err
+	}
+	return dir, nil
+}
+
+func (q *Quarantine) list() ([]*paygateadmin.QuarantinedFile, error) {
+	infos, err := ioutil.ReadDir(q.cfg.Directory)
+	if err != nil {
+		return nil, err
+	}
+	var out []*paygateadmin.QuarantinedFile
+	for i := range infos {
+		if !infos[i].IsDir() {
+			continue
+		}
+		qf, err := q.get(infos[i].Name())
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, qf)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Created.Before(out[j].Created)
+	})
+	return out, nil
+}
+
+func (q *Quarantine) get(quarantineID string) (*paygateadmin.QuarantinedFile, error) {
+	dir, err := q.dir(quarantineID)
+	if err != nil {
+		return nil, err
+	}
+	bs, err := ioutil.ReadFile(filepath.Join(dir, quarantineMetadataFilename))
+	if err != nil {
+		return nil, err
+	}
+	var qf paygateadmin.QuarantinedFile
+	if err := json.Unmarshal(bs, &qf); err != nil {
+		return nil, fmt.Errorf("reading quarantineID=%s: %v", quarantineID, err)
+	}
+	return &qf, nil
+}
+
+func (q *Quarantine) contents(quarantineID string) ([]byte, error) {
+	qf, err := q.get(quarantineID)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.ReadFile(filepath.Join(q.cfg.Directory, qf.QuarantineID, qf.Filename))
+}
+
+// approve processes a quarantined file and removes it from quarantine. Files which
+// can't be parsed remain quarantined.
+func (q *Quarantine) approve(quarantineID string, fileProcessors Processors) error {
+	qf, err := q.get(quarantineID)
+	if err != nil {
+		return err
+	}
+	file, err := ach.ReadFile(filepath.Join(q.cfg.Directory, qf.QuarantineID, qf.Filename))
+	if err != nil && !base.Has(err, ach.ErrFileHeader) {
+		return fmt.Errorf("unable to process quarantineID=%s: %v", quarantineID, err)
+	}
+	if err := fileProcessors.HandleAll(file); err != nil {
+		return fmt.Errorf("processing quarantineID=%s: %v", quarantineID, err)
+	}
+	q.logger.Set("quarantineID", quarantineID).Logf("approved and processed %s", qf.Filename)
+	return q.discard(quarantineID)
+}
+
+func (q *Quarantine) discard(quarantineID string) error {
+	dir, err := q.dir(quarantineID)
+	if err != nil {
+		return err
+	}
+	return os.RemoveAll(dir)
+}
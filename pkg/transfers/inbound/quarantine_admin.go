@@ -0,0 +1,116 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package inbound
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/moov-io/base/admin"
+	moovhttp "github.com/moov-io/base/http"
+
+	paygateadmin "github.com/moov-io/paygate/pkg/admin"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/x/adminauth"
+	"github.com/moov-io/paygate/x/route"
+)
+
+// RegisterRoutes adds admin endpoints to inspect, approve or discard quarantined files.
+// Approved files are handled by fileProcessors.
+func (q *Quarantine) RegisterRoutes(cfg *config.Config, svc *admin.Server, fileProcessors Processors) {
+	if q == nil {
+		return
+	}
+	svc.AddHandler("/inbound/quarantine", q.listQuarantinedFiles())
+	svc.AddHandler("/inbound/quarantine/{quarantineID}", adminauth.Protect(cfg.Admin.Signing, q.quarantinedFile()))
+	svc.AddHandler("/inbound/quarantine/{quarantineID}/contents", q.quarantinedFileContents())
+	svc.AddHandler("/inbound/quarantine/{quarantineID}/approve", adminauth.Protect(cfg.Admin.Signing, q.approveQuarantinedFile(fileProcessors)))
+}
+
+func problem(w http.ResponseWriter, err error) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	moovhttp.Problem(w, err)
+}
+
+func (q *Quarantine) listQuarantinedFiles() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			problem(w, fmt.Errorf("invalid method %s", r.Method))
+			return
+		}
+		files, err := q.list()
+		if err != nil {
+			problem(w, err)
+			return
+		}
+		if files == nil {
+			files = make([]*paygateadmin.QuarantinedFile, 0)
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(files)
+	}
+}
+
+func (q *Quarantine) quarantinedFile() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		quarantineID := route.ReadPathID("quarantineID", r)
+
+		switch r.Method {
+		case http.MethodGet:
+			qf, err := q.get(quarantineID)
+			if err != nil {
+				problem(w, err)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(qf)
+
+		case http.MethodDelete:
+			if err := q.discard(quarantineID); err != nil {
+				problem(w, err)
+				return
+			}
+			q.logger.Set("quarantineID", quarantineID).Log("discarded quarantined file")
+			w.WriteHeader(http.StatusOK)
+
+		default:
+			problem(w, fmt.Errorf("invalid method %s", r.Method))
+		}
+	}
+}
+
+func (q *Quarantine) quarantinedFileContents() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			problem(w, fmt.Errorf("invalid method %s", r.Method))
+			return
+		}
+		bs, err := q.contents(route.ReadPathID("quarantineID", r))
+		if err != nil {
+			problem(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(http.StatusOK)
+		w.Write(bs)
+	}
+}
+
+func (q *Quarantine) approveQuarantinedFile(fileProcessors Processors) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			problem(w, fmt.Errorf("invalid method %s", r.Method))
+			return
+		}
+		if err := q.approve(route.ReadPathID("quarantineID", r), fileProcessors); err != nil {
+			problem(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+}
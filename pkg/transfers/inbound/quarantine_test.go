@@ -0,0 +1,149 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package inbound
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/moov-io/ach"
+	"github.com/moov-io/base"
+	"github.com/moov-io/base/log"
+
+	paygateadmin "github.com/moov-io/paygate/pkg/admin"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/testclient"
+	"github.com/moov-io/paygate/pkg/transfers/pipeline/notify"
+
+	"github.com/stretchr/testify/require"
+)
+
+func setupQuarantine(t *testing.T, allowedOrigins ...string) (*Quarantine, *notify.MockSender) {
+	t.Helper()
+
+	sender := &notify.MockSender{}
+	q, err := NewQuarantine(log.NewNopLogger(), &config.Quarantine{
+		Directory:      testDir(t),
+		AllowedOrigins: allowedOrigins,
+	}, sender)
+	require.NoError(t, err)
+	return q, sender
+}
+
+func copyTestFile(t *testing.T, dir, filename string) string {
+	t.Helper()
+
+	bs, err := ioutil.ReadFile(filepath.Join("testdata", filename))
+	require.NoError(t, err)
+
+	path := filepath.Join(dir, filename)
+	require.NoError(t, ioutil.WriteFile(path, bs, 0600))
+	return path
+}
+
+func TestQuarantine__inspect(t *testing.T) {
+	var q *Quarantine
+	require.Empty(t, q.inspect(nil, errors.New("bad file")))
+
+	file, err := ach.ReadFile(filepath.Join("testdata", "prenote-ppd-debit.ach"))
+	require.NoError(t, err)
+
+	q, _ = setupQuarantine(t)
+	require.Empty(t, q.inspect(file, nil))
+	require.Contains(t, q.inspect(file, errors.New("bad record")), "invalid file")
+	require.Empty(t, q.inspect(file, base.ErrorList{ach.ErrFileHeader}))
+
+	q, _ = setupQuarantine(t, file.Header.ImmediateOrigin)
+	require.Empty(t, q.inspect(file, nil))
+
+	q, _ = setupQuarantine(t, "123456780")
+	require.Contains(t, q.inspect(file, nil), "unexpected origin")
+}
+
+func TestQuarantine__process(t *testing.T) {
+	q, sender := setupQuarantine(t, "123456780")
+
+	dir := testDir(t)
+	copyTestFile(t, dir, "prenote-ppd-debit.ach")
+
+	// The file is quarantined rather than erroring
+	processors := SetupProcessors(&MockProcessor{Err: errors.New("bad")})
+	require.NoError(t, process(dir, q, nil, processors))
+	require.True(t, sender.CriticalWasCalled())
+	require.Equal(t, notify.Quarantine, sender.CapturedMessage().Direction)
+
+	files, err := q.list()
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	require.Equal(t, "prenote-ppd-debit.ach", files[0].Filename)
+	require.Contains(t, files[0].Reason, "unexpected origin")
+
+	bs, err := q.contents(files[0].QuarantineID)
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(string(bs), "101"))
+
+	// approval fails while processors error
+	require.Error(t, q.approve(files[0].QuarantineID, processors))
+
+	processors = SetupProcessors(&MockProcessor{})
+	require.NoError(t, q.approve(files[0].QuarantineID, processors))
+
+	files, err = q.list()
+	require.NoError(t, err)
+	require.Empty(t, files)
+}
+
+func TestQuarantine__invalidID(t *testing.T) {
+	q, _ := setupQuarantine(t)
+
+	_, err := q.get("../etc")
+	require.Error(t, err)
+
+	require.Error(t, q.discard("missing"))
+}
+
+func TestQuarantine__admin(t *testing.T) {
+	q, _ := setupQuarantine(t)
+
+	dir := testDir(t)
+	qf, err := q.hold(copyTestFile(t, dir, "prenote-ppd-debit.ach"), nil, "invalid file: testing")
+	require.NoError(t, err)
+
+	svc, _ := testclient.Admin(t)
+	q.RegisterRoutes(config.Empty(), svc, SetupProcessors(&MockProcessor{}))
+
+	address := fmt.Sprintf("http://%s/inbound/quarantine", svc.BindAddr())
+
+	resp, err := http.Get(address)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var files []paygateadmin.QuarantinedFile
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&files))
+	require.Len(t, files, 1)
+	require.Equal(t, qf.QuarantineID, files[0].QuarantineID)
+
+	resp, err = http.Get(address + "/" + qf.QuarantineID + "/contents")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	req, _ := http.NewRequest("DELETE", address+"/"+qf.QuarantineID, nil)
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	files2, err := q.list()
+	require.NoError(t, err)
+	require.Empty(t, files2)
+}
@@ -0,0 +1,54 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package inbound
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/moov-io/ach"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/transfers"
+)
+
+// saveInboundRecord keeps the raw return, correction or death notification entry sent to us
+// so disputes can reference the exact record. transferID is empty when no Transfer was found.
+func saveInboundRecord(repo transfers.Repository, fh ach.FileHeader, recordType string, transferID string, entry *ach.EntryDetail) error {
+	if repo == nil || entry == nil {
+		return nil
+	}
+	record := newInboundRecord(fh, recordType, transferID, entry)
+	if err := repo.SaveInboundRecord(record); err != nil {
+		return fmt.Errorf("problem saving %s record for traceNumber=%s: %v", recordType, entry.TraceNumber, err)
+	}
+	return nil
+}
+
+func newInboundRecord(fh ach.FileHeader, recordType string, transferID string, entry *ach.EntryDetail) *client.InboundRecord {
+	record := &client.InboundRecord{
+		TransferID:           transferID,
+		Type:                 recordType,
+		TraceNumber:          entry.TraceNumber,
+		ImmediateOrigin:      strings.TrimSpace(fh.ImmediateOrigin),
+		ImmediateDestination: strings.TrimSpace(fh.ImmediateDestination),
+		EntryDetail:          entry.String(),
+		Created:              time.Now(),
+	}
+	switch {
+	case entry.Addenda99 != nil:
+		record.Code = entry.Addenda99.ReturnCode
+		record.OriginalTrace = strings.TrimSpace(entry.Addenda99.OriginalTrace)
+		record.Addenda = entry.Addenda99.String()
+	case entry.Addenda98 != nil:
+		record.Code = entry.Addenda98.ChangeCode
+		record.OriginalTrace = strings.TrimSpace(entry.Addenda98.OriginalTrace)
+		record.Addenda = entry.Addenda98.String()
+	case len(entry.Addenda05) > 0:
+		record.Addenda = entry.Addenda05[0].String()
+	}
+	return record
+}
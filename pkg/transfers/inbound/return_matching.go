@@ -0,0 +1,155 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package inbound
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/moov-io/ach"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/transfers"
+)
+
+// ReturnMatch is a Transfer a return entry could be for and how confident the matcher is,
+// between 0 and 1.
+type ReturnMatch struct {
+	Transfer   *client.Transfer
+	Confidence float64
+}
+
+// ReturnMatcher finds the Transfers a return entry could be for. Matchers are tried in order
+// and the first to return any matches decides the outcome, so a matcher returns nothing
+// when the next one should be tried.
+type ReturnMatcher interface {
+	Name() string
+	Match(bh *ach.BatchHeader, entry *ach.EntryDetail) ([]ReturnMatch, error)
+}
+
+// NewReturnMatchers returns the exact trace number matcher followed by the fuzzy matcher
+// when it's enabled.
+func NewReturnMatchers(cfg config.Returns, repo transfers.Repository) []ReturnMatcher {
+	matchers := []ReturnMatcher{
+		&traceMatcher{repo: repo},
+	}
+	if cfg.Fuzzy != nil {
+		matchers = append(matchers, &fuzzyMatcher{
+			window: cfg.Fuzzy.MatchWindow(),
+			repo:   repo,
+		})
+	}
+	return matchers
+}
+
+// traceMatcher matches returns on the trace number and amount of the original entry.
+type traceMatcher struct {
+	repo transfers.Repository
+}
+
+func (m *traceMatcher) Name() string {
+	return "trace"
+}
+
+func (m *traceMatcher) Match(bh *ach.BatchHeader, entry *ach.EntryDetail) ([]ReturnMatch, error) {
+	effectiveEntryDate, err := returnEffectiveDate(bh)
+	if err != nil {
+		return nil, err
+	}
+	amount := client.Amount{
+		Currency: "USD",
+		Value:    int32(entry.Amount),
+	}
+	traceNumbers := []string{entry.TraceNumber}
+	if entry.Addenda99 != nil {
+		if original := strings.TrimSpace(entry.Addenda99.OriginalTrace); original != "" && original != entry.TraceNumber {
+			traceNumbers = append(traceNumbers, original)
+		}
+	}
+	for i := range traceNumbers {
+		transfer, err := m.repo.LookupTransferFromReturn(amount, traceNumbers[i], effectiveEntryDate)
+		if err != nil && err != sql.ErrNoRows {
+			return nil, err
+		}
+		if transfer != nil {
+			return []ReturnMatch{{Transfer: transfer, Confidence: 1.0}}, nil
+		}
+	}
+	return nil, nil
+}
+
+// fuzzyMatcher matches returns whose trace number or amount were re-keyed by the ODFI to
+// Transfers uploaded for the same routing and account number around the same date.
+type fuzzyMatcher struct {
+	window time.Duration
+	repo   transfers.Repository
+}
+
+func (m *fuzzyMatcher) Name() string {
+	return "fuzzy"
+}
+
+func (m *fuzzyMatcher) Match(bh *ach.BatchHeader, entry *ach.EntryDetail) ([]ReturnMatch, error) {
+	if entry.Addenda99 == nil || len(entry.Addenda99.OriginalDFI) < 8 {
+		return nil, nil
+	}
+	accountNumber := strings.TrimSpace(entry.DFIAccountNumber)
+	if accountNumber == "" {
+		return nil, nil
+	}
+	effectiveEntryDate, err := returnEffectiveDate(bh)
+	if err != nil {
+		return nil, err
+	}
+
+	start, end := effectiveEntryDate.Add(-1*m.window), effectiveEntryDate.Add(m.window)
+	candidates, err := m.repo.LookupReturnCandidates(entry.Addenda99.OriginalDFI[:8], accountNumber, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []ReturnMatch
+	for i := range candidates {
+		out = append(out, ReturnMatch{
+			Transfer:   candidates[i].Transfer,
+			Confidence: m.score(candidates[i], entry, effectiveEntryDate),
+		})
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		return out[i].Confidence > out[j].Confidence
+	})
+	return out, nil
+}
+
+// score weighs a candidate which already matches the routing and account number. Matching
+// amounts count the most, followed by how close the upload was to the return's date.
+func (m *fuzzyMatcher) score(candidate *transfers.ReturnCandidate, entry *ach.EntryDetail, effectiveEntryDate time.Time) float64 {
+	score := 0.3
+
+	original := ach.NewEntryDetail()
+	original.Parse(candidate.EntryDetail)
+	if original.Amount == entry.Amount {
+		score += 0.6
+	}
+
+	if m.window > 0 {
+		distance := math.Abs(effectiveEntryDate.Sub(candidate.Uploaded).Hours())
+		score += 0.1 * math.Max(0, 1-distance/m.window.Hours())
+	}
+	return math.Round(score*100) / 100
+}
+
+func returnEffectiveDate(bh *ach.BatchHeader) (time.Time, error) {
+	when, err := time.Parse("060102", bh.EffectiveEntryDate) // YYMMDD
+	if err != nil {
+		return when, fmt.Errorf("invalid EffectiveEntryDate=%q: %v", bh.EffectiveEntryDate, err)
+	}
+	return when, nil
+}
@@ -0,0 +1,178 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package inbound
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/moov-io/ach"
+	"github.com/moov-io/base"
+	"github.com/moov-io/base/log"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/transfers"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+)
+
+func returnEntry(amount int) (*ach.BatchHeader, *ach.EntryDetail) {
+	bh := ach.NewBatchHeader()
+	bh.EffectiveEntryDate = time.Now().Format("060102")
+
+	entry := ach.NewEntryDetail()
+	entry.TransactionCode = ach.CheckingReturnNOCCredit
+	entry.RDFIIdentification = "07640125"
+	entry.CheckDigit = "1"
+	entry.DFIAccountNumber = "1234567"
+	entry.Amount = amount
+	entry.TraceNumber = "076401250000009"
+	entry.Addenda99 = ach.NewAddenda99()
+	entry.Addenda99.ReturnCode = "R01"
+	entry.Addenda99.OriginalTrace = "076401250000009"
+	entry.Addenda99.OriginalDFI = "12104288"
+	return bh, entry
+}
+
+func returnCandidate(amount int, uploaded time.Time) *transfers.ReturnCandidate {
+	original := ach.NewEntryDetail()
+	original.TransactionCode = ach.CheckingCredit
+	original.RDFIIdentification = "12104288"
+	original.CheckDigit = "2"
+	original.DFIAccountNumber = "1234567"
+	original.Amount = amount
+	original.TraceNumber = "076401250000001"
+	return &transfers.ReturnCandidate{
+		Transfer:    &client.Transfer{TransferID: base.ID()},
+		EntryDetail: original.String(),
+		Uploaded:    uploaded,
+	}
+}
+
+func TestReturnMatching__fuzzy(t *testing.T) {
+	bh, entry := returnEntry(1250)
+	now, _ := returnEffectiveDate(bh)
+
+	repo := &transfers.MockRepository{
+		Candidates: []*transfers.ReturnCandidate{
+			returnCandidate(9999, now.Add(-24*time.Hour)),
+			returnCandidate(1250, now.Add(-24*time.Hour)),
+		},
+	}
+	matcher := &fuzzyMatcher{window: config.DefaultFuzzyReturnWindow, repo: repo}
+
+	matches, err := matcher.Match(bh, entry)
+	require.NoError(t, err)
+	require.Len(t, matches, 2)
+
+	// matching amounts are sorted first
+	require.Equal(t, repo.Candidates[1].Transfer.TransferID, matches[0].Transfer.TransferID)
+	require.Equal(t, 0.98, matches[0].Confidence)
+	require.Equal(t, 0.38, matches[1].Confidence)
+
+	// returns without the original routing number aren't matched
+	entry.Addenda99.OriginalDFI = ""
+	matches, err = matcher.Match(bh, entry)
+	require.NoError(t, err)
+	require.Empty(t, matches)
+}
+
+func TestReturnMatching__confident(t *testing.T) {
+	pc := NewReturnProcessor(log.NewNopLogger(), config.Returns{}, &transfers.MockRepository{}, nil, nil, nil)
+	require.Len(t, pc.matchers, 1)
+
+	require.False(t, pc.confident(nil))
+	require.True(t, pc.confident([]ReturnMatch{{Confidence: 1.0}}))
+	require.True(t, pc.confident([]ReturnMatch{{Confidence: 0.95}, {Confidence: 0.4}}))
+	require.False(t, pc.confident([]ReturnMatch{{Confidence: 0.95}, {Confidence: 0.92}}))
+	require.False(t, pc.confident([]ReturnMatch{{Confidence: 0.7}}))
+}
+
+func TestReturnMatching__processReturnEntry(t *testing.T) {
+	bh, entry := returnEntry(1250)
+	now, _ := returnEffectiveDate(bh)
+
+	cfg := config.Returns{
+		Fuzzy: &config.FuzzyReturnMatching{},
+	}
+	repo := &transfers.MockRepository{
+		Candidates: []*transfers.ReturnCandidate{
+			returnCandidate(1250, now),
+		},
+	}
+	pc := NewReturnProcessor(log.NewNopLogger(), cfg, repo, nil, nil, nil)
+	require.Len(t, pc.matchers, 2)
+
+	// a lone strong candidate is applied
+	require.NoError(t, pc.processReturnEntry(ach.FileHeader{}, bh, entry))
+	require.Len(t, repo.InboundRecords, 1)
+	require.Equal(t, repo.Candidates[0].Transfer.TransferID, repo.InboundRecords[0].TransferID)
+	require.Empty(t, repo.Ambiguous)
+
+	// two strong candidates are held for review
+	repo.Candidates = append(repo.Candidates, returnCandidate(1250, now))
+	require.NoError(t, pc.processReturnEntry(ach.FileHeader{}, bh, entry))
+	require.Len(t, repo.Ambiguous, 1)
+
+	ret := repo.Ambiguous[0]
+	require.Equal(t, transfers.AmbiguousReturnPending, ret.Status)
+	require.Len(t, ret.Candidates, 2)
+	require.Equal(t, "R01", ret.Record.Code)
+	require.Empty(t, ret.Record.TransferID)
+}
+
+func TestReturnMatching__admin(t *testing.T) {
+	bh, entry := returnEntry(1250)
+	now, _ := returnEffectiveDate(bh)
+
+	repo := &transfers.MockRepository{
+		Candidates: []*transfers.ReturnCandidate{
+			returnCandidate(1250, now),
+			returnCandidate(1250, now),
+		},
+	}
+	pc := NewReturnProcessor(log.NewNopLogger(), config.Returns{Fuzzy: &config.FuzzyReturnMatching{}}, repo, nil, nil, nil)
+	require.NoError(t, pc.processReturnEntry(ach.FileHeader{}, bh, entry))
+	require.NoError(t, pc.processReturnEntry(ach.FileHeader{}, bh, entry))
+	require.Len(t, repo.Ambiguous, 2)
+
+	router := mux.NewRouter()
+	router.Handle("/inbound/returns/ambiguous", pc.listAmbiguousReturns())
+	router.Handle("/inbound/returns/ambiguous/{recordID}", pc.ambiguousReturn(config.Empty()))
+
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		w.Flush()
+		return w
+	}
+
+	w := send("GET", "/inbound/returns/ambiguous?status=pending", "")
+	require.Equal(t, http.StatusOK, w.Code)
+
+	// resolve the first return
+	transfer := repo.Candidates[1].Transfer
+	repo.Transfers = []*client.Transfer{transfer}
+	first := repo.Ambiguous[0].Record.RecordID
+	w = send("PUT", "/inbound/returns/ambiguous/"+first, `{"transferID": "`+transfer.TransferID+`"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, transfers.AmbiguousReturnResolved, repo.Ambiguous[0].Status)
+	require.Equal(t, transfer.TransferID, repo.Ambiguous[0].TransferID)
+
+	// returns are only resolved once
+	w = send("PUT", "/inbound/returns/ambiguous/"+first, `{"transferID": "`+transfer.TransferID+`"}`)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+
+	// dismiss the second
+	w = send("DELETE", "/inbound/returns/ambiguous/"+repo.Ambiguous[1].Record.RecordID, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, transfers.AmbiguousReturnDismissed, repo.Ambiguous[1].Status)
+}
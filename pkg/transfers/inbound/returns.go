@@ -5,15 +5,19 @@
 package inbound
 
 import (
-	"database/sql"
 	"errors"
 	"fmt"
-	"time"
 
 	"github.com/moov-io/ach"
+	"github.com/moov-io/base"
 
+	"github.com/moov-io/paygate/pkg/admin"
 	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/hooks"
 	"github.com/moov-io/paygate/pkg/transfers"
+	"github.com/moov-io/paygate/pkg/transfers/history"
+	"github.com/moov-io/paygate/pkg/webhooks"
 
 	"github.com/go-kit/kit/metrics/prometheus"
 	"github.com/moov-io/base/log"
@@ -30,17 +34,50 @@ var (
 		Name: "missing_return_transfers",
 		Help: "Counter of return EntryDetail records handled without a found transfer",
 	}, []string{"origin", "destination", "code"})
+
+	matchedReturnEntries = prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+		Name: "matched_return_entries",
+		Help: "Counter of return EntryDetail records applied to a transfer by how they were matched",
+	}, []string{"matcher"})
+
+	ambiguousReturnEntries = prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+		Name: "ambiguous_return_entries",
+		Help: "Counter of return EntryDetail records held for an admin to match",
+	}, []string{"origin", "destination", "code"})
 )
 
+// MicroDepositReturns handles returns for Transfers which were created from micro-deposits.
+type MicroDepositReturns interface {
+	HandleReturn(transferID string, returnCode *ach.ReturnCode) error
+}
+
 type returnProcessor struct {
-	logger       log.Logger
-	transferRepo transfers.Repository
+	logger        log.Logger
+	transferRepo  transfers.Repository
+	microDeposits MicroDepositReturns
+	hooks         *hooks.Runner
+	events        webhooks.Sender
+
+	matchers      []ReturnMatcher
+	minConfidence float64
 }
 
-func NewReturnProcessor(logger log.Logger, transferRepo transfers.Repository) *returnProcessor {
+func NewReturnProcessor(
+	logger log.Logger,
+	cfg config.Returns,
+	transferRepo transfers.Repository,
+	microDeposits MicroDepositReturns,
+	hookRunner *hooks.Runner,
+	events webhooks.Sender,
+) *returnProcessor {
 	return &returnProcessor{
-		logger:       logger,
-		transferRepo: transferRepo,
+		logger:        logger,
+		transferRepo:  transferRepo,
+		microDeposits: microDeposits,
+		hooks:         hookRunner,
+		events:        events,
+		matchers:      NewReturnMatchers(cfg, transferRepo),
+		minConfidence: cfg.Fuzzy.MinConfidence(),
 	}
 }
 
@@ -81,36 +118,52 @@ func (pc *returnProcessor) Handle(file *ach.File) error {
 }
 
 func (pc *returnProcessor) processReturnEntry(fh ach.FileHeader, bh *ach.BatchHeader, entry *ach.EntryDetail) error {
-	amount := client.Amount{
-		Currency: "USD",
-		Value:    int32(entry.Amount),
-	}
-	effectiveEntryDate, err := time.Parse("060102", bh.EffectiveEntryDate) // YYMMDD
+	// Do we find a Transfer related to the ach.EntryDetail?
+	matcher, matches, err := pc.matchReturn(bh, entry)
 	if err != nil {
-		return fmt.Errorf("invalid EffectiveEntryDate=%q: %v", bh.EffectiveEntryDate, err)
+		return fmt.Errorf("problem with returned Transfer: %v", err)
 	}
-
-	// Do we find a Transfer related to the ach.EntryDetail?
-	transfer, err := pc.transferRepo.LookupTransferFromReturn(amount, entry.TraceNumber, effectiveEntryDate)
-	if transfer != nil {
-		pc.logger.Set("transferID", transfer.TransferID).Log("handling return for transfer")
-		if err := SaveReturnCode(pc.transferRepo, transfer.TransferID, entry); err != nil {
+	switch {
+	case pc.confident(matches):
+		transfer := matches[0].Transfer
+		pc.logger.With(log.Fields{
+			"transferID":    transfer.TransferID,
+			"correlationID": transfer.CorrelationID,
+			"matcher":       matcher,
+		}).Log("handling return for transfer")
+		if err := saveInboundRecord(pc.transferRepo, fh, "return", transfer.TransferID, entry); err != nil {
 			return err
 		}
-		if err := pc.transferRepo.UpdateTransferStatus(transfer.TransferID, client.FAILED); err != nil {
-			return fmt.Errorf("problem marking transferID=%s as %s: %v", transfer.TransferID, client.FAILED, err)
+		matchedReturnEntries.With("matcher", matcher).Add(1)
+		return pc.applyReturn(transfer, entry)
+
+	case len(matches) > 0:
+		// Hold the return for an admin rather than guessing which Transfer it's for
+		candidates := make([]admin.ReturnCandidate, len(matches))
+		for i := range matches {
+			candidates[i] = admin.ReturnCandidate{
+				TransferID: matches[i].Transfer.TransferID,
+				Confidence: matches[i].Confidence,
+			}
 		}
-		// TODO(adam): We need to update the Customer/Account from return codes
-		// R02 (Account Closed) -- mark account Disabled / Rejected / (new status)
-		// R03 (No Account)
-		// R04 (Invalid Account Number)
-		// R07 (Authorization Revoked by Customer)
-		// R10 (Customer Advises Not Authorized)
-		// R14 (Representative payee deceased)
-		// R16 (Bank account frozen)
-	} else {
-		if err != nil && err != sql.ErrNoRows {
-			return fmt.Errorf("problem with returned Transfer: %v", err)
+		record := newInboundRecord(fh, "return", "", entry)
+		record.RecordID = base.ID()
+		if err := pc.transferRepo.SaveAmbiguousReturn(record, candidates); err != nil {
+			return fmt.Errorf("problem saving ambiguous return for traceNumber=%s: %v", entry.TraceNumber, err)
+		}
+		pc.logger.With(log.Fields{
+			"traceNumber": entry.TraceNumber,
+			"recordID":    record.RecordID,
+			"candidates":  fmt.Sprintf("%d", len(candidates)),
+		}).Log("holding ambiguous return for review")
+		ambiguousReturnEntries.With(
+			"origin", fh.ImmediateOrigin,
+			"destination", fh.ImmediateDestination,
+			"code", entry.Addenda99.ReturnCodeField().Code).Add(1)
+
+	default:
+		if err := saveInboundRecord(pc.transferRepo, fh, "return", "", entry); err != nil {
+			return err
 		}
 		pc.logger.Set("traceNumber", entry.TraceNumber).Log("transfer not found from return entry")
 		missingReturnTransfers.With(
@@ -119,8 +172,70 @@ func (pc *returnProcessor) processReturnEntry(fh ach.FileHeader, bh *ach.BatchHe
 			"code", entry.Addenda99.ReturnCodeField().Code).Add(1)
 	}
 
-	// TODO(adam): lookup any micro-deposits from the transferID
+	return nil
+}
+
+// matchReturn returns the matches from the first matcher which found any.
+func (pc *returnProcessor) matchReturn(bh *ach.BatchHeader, entry *ach.EntryDetail) (string, []ReturnMatch, error) {
+	for i := range pc.matchers {
+		matches, err := pc.matchers[i].Match(bh, entry)
+		if err != nil {
+			return "", nil, fmt.Errorf("%s matcher: %v", pc.matchers[i].Name(), err)
+		}
+		if len(matches) > 0 {
+			return pc.matchers[i].Name(), matches, nil
+		}
+	}
+	return "", nil, nil
+}
+
+// confident returns true when the best match is strong enough to apply without review and
+// no other match is.
+func (pc *returnProcessor) confident(matches []ReturnMatch) bool {
+	if len(matches) == 0 || matches[0].Confidence < pc.minConfidence {
+		return false
+	}
+	return len(matches) == 1 || matches[1].Confidence < pc.minConfidence
+}
+
+// applyReturn fails the Transfer with the entry's return code and notifies everything
+// interested in returns.
+func (pc *returnProcessor) applyReturn(transfer *client.Transfer, entry *ach.EntryDetail) error {
+	if err := SaveReturnCode(pc.transferRepo, transfer.TransferID, entry); err != nil {
+		return err
+	}
+	if err := pc.transferRepo.UpdateTransferStatus(transfer.TransferID, client.FAILED, history.Inbound); err != nil {
+		return fmt.Errorf("problem marking transferID=%s as %s: %v", transfer.TransferID, client.FAILED, err)
+	}
+	// TODO(adam): We need to update the Customer/Account from return codes
+	// R02 (Account Closed) -- mark account Disabled / Rejected / (new status)
+	// R03 (No Account)
+	// R04 (Invalid Account Number)
+	// R07 (Authorization Revoked by Customer)
+	// R10 (Customer Advises Not Authorized)
+	// R14 (Representative payee deceased)
+	// R16 (Bank account frozen)
+
+	if pc.microDeposits != nil {
+		if err := pc.microDeposits.HandleReturn(transfer.TransferID, entry.Addenda99.ReturnCodeField()); err != nil {
+			return fmt.Errorf("problem handling micro-deposit return for transferID=%s: %v", transfer.TransferID, err)
+		}
+	}
+
+	// Returns can't be rejected, but hooks with the reject failure policy stop processing this file
+	if _, err := pc.hooks.Run(hooks.Request{
+		Point:      config.HookPostReturn,
+		TransferID: transfer.TransferID,
+		ReturnCode: entry.Addenda99.ReturnCodeField().Code,
+	}); err != nil {
+		return fmt.Errorf("problem with post-return hooks for transferID=%s: %v", transfer.TransferID, err)
+	}
 
+	sendTransferEvent(pc.logger, pc.transferRepo, pc.events, webhooks.EventTransferReturned, transfer, webhooks.TransferUpdate{
+		TransferID: transfer.TransferID,
+		Status:     client.FAILED,
+		ReturnCode: entry.Addenda99.ReturnCodeField().Code,
+	})
 	return nil
 }
 
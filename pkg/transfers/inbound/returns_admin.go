@@ -0,0 +1,121 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package inbound
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/moov-io/ach"
+	"github.com/moov-io/base/admin"
+	"github.com/moov-io/base/log"
+
+	paygateadmin "github.com/moov-io/paygate/pkg/admin"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/transfers"
+	"github.com/moov-io/paygate/x/adminauth"
+	"github.com/moov-io/paygate/x/route"
+)
+
+// RegisterRoutes adds admin endpoints to list, resolve and dismiss ambiguous returns.
+func (pc *returnProcessor) RegisterRoutes(cfg *config.Config, svc *admin.Server) {
+	svc.AddHandler("/inbound/returns/ambiguous", pc.listAmbiguousReturns())
+	svc.AddHandler("/inbound/returns/ambiguous/{recordID}", adminauth.Protect(cfg.Admin.Signing, pc.ambiguousReturn(cfg)))
+}
+
+func (pc *returnProcessor) listAmbiguousReturns() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			problem(w, fmt.Errorf("invalid method %s", r.Method))
+			return
+		}
+		returns, err := pc.transferRepo.ListAmbiguousReturns(r.URL.Query().Get("status"))
+		if err != nil {
+			problem(w, err)
+			return
+		}
+		if returns == nil {
+			returns = make([]*paygateadmin.AmbiguousReturn, 0)
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(returns)
+	}
+}
+
+func (pc *returnProcessor) ambiguousReturn(cfg *config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(cfg, w, r)
+
+		recordID := route.ReadPathID("recordID", r)
+		var err error
+		switch r.Method {
+		case http.MethodPut:
+			var req paygateadmin.ResolveAmbiguousReturn
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				responder.Problem(err)
+				return
+			}
+			err = pc.resolveReturn(recordID, req.TransferID)
+
+		case http.MethodDelete:
+			err = pc.transferRepo.ResolveAmbiguousReturn(recordID, "", time.Now())
+
+		default:
+			err = fmt.Errorf("unsupported HTTP verb %s", r.Method)
+		}
+		if err != nil {
+			responder.Problem(err)
+			return
+		}
+
+		pc.logger.With(log.Fields{
+			"requestID": responder.XRequestID,
+			"recordID":  recordID,
+			"method":    r.Method,
+		}).Log("reviewed ambiguous return")
+
+		responder.Respond(func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusOK)
+		})
+	}
+}
+
+// resolveReturn applies a pending ambiguous return to the Transfer an admin picked.
+func (pc *returnProcessor) resolveReturn(recordID string, transferID string) error {
+	if transferID == "" {
+		return errors.New("missing transferID")
+	}
+	ret, err := pc.transferRepo.GetAmbiguousReturn(recordID)
+	if err != nil {
+		return err
+	}
+	if ret == nil || ret.Status != transfers.AmbiguousReturnPending {
+		return fmt.Errorf("recordID=%s is not a pending ambiguous return", recordID)
+	}
+	transfer, err := pc.transferRepo.GetTransfer(transferID)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	if transfer == nil {
+		return fmt.Errorf("transferID=%s not found", transferID)
+	}
+
+	entry := ach.NewEntryDetail()
+	entry.Parse(ret.Record.EntryDetail)
+	entry.Addenda99 = ach.NewAddenda99()
+	entry.Addenda99.Parse(ret.Record.Addenda)
+
+	// Claim the return first so it's only applied once
+	if err := pc.transferRepo.ResolveAmbiguousReturn(recordID, transfer.TransferID, time.Now()); err != nil {
+		return err
+	}
+	matchedReturnEntries.With("matcher", "admin").Add(1)
+	return pc.applyReturn(transfer, entry)
+}
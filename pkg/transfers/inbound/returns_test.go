@@ -13,7 +13,12 @@ import (
 	"github.com/moov-io/base"
 	"github.com/moov-io/base/log"
 
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
 	"github.com/moov-io/paygate/pkg/transfers"
+	"github.com/moov-io/paygate/pkg/webhooks"
+
+	"github.com/stretchr/testify/require"
 )
 
 func TestReturns__SetReturnCode(t *testing.T) {
@@ -49,7 +54,7 @@ func TestReturns__Handle(t *testing.T) {
 	}
 
 	repo := &transfers.MockRepository{}
-	processor := NewReturnProcessor(log.NewNopLogger(), repo)
+	processor := NewReturnProcessor(log.NewNopLogger(), config.Returns{}, repo, nil, nil, nil)
 
 	if err := processor.Handle(file); err != nil {
 		t.Fatal(err)
@@ -73,7 +78,7 @@ func TestReturns__processReturnEntry(t *testing.T) {
 	entry := file.Batches[0].GetEntries()[0]
 
 	repo := &transfers.MockRepository{}
-	processor := NewReturnProcessor(log.NewNopLogger(), repo)
+	processor := NewReturnProcessor(log.NewNopLogger(), config.Returns{}, repo, nil, nil, nil)
 
 	if err := processor.processReturnEntry(fh, bh, entry); err != nil {
 		t.Fatal(err)
@@ -85,3 +90,94 @@ func TestReturns__processReturnEntry(t *testing.T) {
 		t.Fatal("expected error")
 	}
 }
+
+type mockMicroDepositReturns struct {
+	transferIDs []string
+	err         error
+}
+
+func (m *mockMicroDepositReturns) HandleReturn(transferID string, returnCode *ach.ReturnCode) error {
+	m.transferIDs = append(m.transferIDs, transferID)
+	return m.err
+}
+
+func TestReturns__processReturnEntryMicroDeposits(t *testing.T) {
+	file, _ := ach.ReadFile(filepath.Join("testdata", "bh-ed-ad-bh-ed-ad-ed-ad.ach"))
+	if len(file.Batches) != 1 {
+		t.Fatalf("batches: %#v", file.Batches)
+	}
+
+	fh := ach.NewFileHeader()
+	bh := file.Batches[0].GetHeader()
+	entry := file.Batches[0].GetEntries()[0]
+
+	transferID := base.ID()
+	repo := &transfers.MockRepository{
+		Transfers: []*client.Transfer{
+			{TransferID: transferID},
+		},
+	}
+	micro := &mockMicroDepositReturns{}
+	processor := NewReturnProcessor(log.NewNopLogger(), config.Returns{}, repo, micro, nil, nil)
+
+	if err := processor.processReturnEntry(fh, bh, entry); err != nil {
+		t.Fatal(err)
+	}
+	if len(micro.transferIDs) != 1 || micro.transferIDs[0] != transferID {
+		t.Errorf("unexpected transferIDs: %v", micro.transferIDs)
+	}
+	if len(repo.InboundRecords) != 1 || repo.InboundRecords[0].TransferID != transferID {
+		t.Fatalf("unexpected inbound records: %#v", repo.InboundRecords)
+	}
+	if record := repo.InboundRecords[0]; record.Type != "return" || record.EntryDetail != entry.String() || record.Addenda != entry.Addenda99.String() {
+		t.Errorf("unexpected inbound record: %#v", record)
+	}
+
+	micro.err = errors.New("bad error")
+	if err := processor.processReturnEntry(fh, bh, entry); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestReturns__processReturnEntryWebhook(t *testing.T) {
+	file, _ := ach.ReadFile(filepath.Join("testdata", "bh-ed-ad-bh-ed-ad-ed-ad.ach"))
+	if len(file.Batches) != 1 {
+		t.Fatalf("batches: %#v", file.Batches)
+	}
+
+	fh := ach.NewFileHeader()
+	bh := file.Batches[0].GetHeader()
+	entry := file.Batches[0].GetEntries()[0]
+
+	transferID := base.ID()
+	repo := &transfers.MockRepository{
+		Transfers: []*client.Transfer{
+			{
+				TransferID:  transferID,
+				Source:      client.Source{CustomerID: "source"},
+				Destination: client.Destination{CustomerID: "destination"},
+			},
+		},
+		Organization: "moov",
+	}
+	events := &webhooks.MockSender{}
+	processor := NewReturnProcessor(log.NewNopLogger(), config.Returns{}, repo, nil, nil, events)
+
+	require.NoError(t, processor.processReturnEntry(fh, bh, entry))
+	require.Len(t, events.Events, 1)
+
+	event := events.Events[0]
+	require.Equal(t, webhooks.EventTransferReturned, event.Type)
+	require.Equal(t, "moov", event.Organization)
+	require.Equal(t, webhooks.TransferUpdate{
+		TransferID: transferID,
+		Status:     client.FAILED,
+		ReturnCode: entry.Addenda99.ReturnCodeField().Code,
+	}, event.Data)
+	require.Contains(t, event.Links, webhooks.Link{Type: webhooks.LinkTransfer, ID: transferID})
+	require.Contains(t, event.Links, webhooks.Link{Type: webhooks.LinkCustomer, ID: "destination"})
+
+	// webhook failures don't stop processing returns
+	events.Err = errors.New("bad error")
+	require.NoError(t, processor.processReturnEntry(fh, bh, entry))
+}
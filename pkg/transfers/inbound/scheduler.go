@@ -7,17 +7,24 @@ package inbound
 import (
 	"context"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/moov-io/base/log"
 
+	"github.com/moov-io/paygate/pkg/admin"
 	"github.com/moov-io/paygate/pkg/config"
 	"github.com/moov-io/paygate/pkg/upload"
+	"github.com/moov-io/paygate/x/errorlog"
 )
 
 type Scheduler interface {
 	Start() error
 	Shutdown()
+
+	// LastSync returns the result of the most recent sync, or nil if one hasn't finished.
+	LastSync() *admin.InboundSync
+	RecentErrors() []errorlog.Entry
 }
 
 type PeriodicScheduler struct {
@@ -29,13 +36,23 @@ type PeriodicScheduler struct {
 	shutdownFunc context.CancelFunc
 
 	agent      upload.Agent
+	mailboxes  []upload.Agent
 	downloader Downloader
+	quarantine *Quarantine
+	acks       *Acknowledgements
 	processors Processors
+
+	mu       sync.Mutex
+	lastSync *admin.InboundSync
+	errors   *errorlog.Recent
 }
 
 func NewPeriodicScheduler(
 	cfg *config.Config,
 	agent upload.Agent,
+	mailboxes []upload.Agent,
+	quarantine *Quarantine,
+	acks *Acknowledgements,
 	processors Processors,
 ) Scheduler {
 	if cfg.ODFI.Inbound.Interval == 0*time.Second {
@@ -56,8 +73,13 @@ func NewPeriodicScheduler(
 		shutdownFunc: cancelFunc,
 
 		agent:      agent,
+		mailboxes:  mailboxes,
 		downloader: NewDownloader(cfg.Logger, cfg.ODFI.Storage),
+		quarantine: quarantine,
+		acks:       acks,
 		processors: processors,
+
+		errors: errorlog.New(20),
 	}
 }
 
@@ -72,9 +94,13 @@ func (s *PeriodicScheduler) Start() error {
 	for {
 		select {
 		case <-s.ticker.C:
-			if err := s.tick(); err != nil {
+			started := time.Now()
+			err := s.tick()
+			if err != nil {
 				s.logger.LogErrorf("ERROR with inbound file processor: %v", err)
+				s.errors.Add("inbound", err)
 			}
+			s.recordSync(started, err)
 
 		case <-s.shutdown.Done():
 			s.logger.Log("scheduler shutdown")
@@ -83,10 +109,51 @@ func (s *PeriodicScheduler) Start() error {
 	}
 }
 
+func (s *PeriodicScheduler) recordSync(started time.Time, err error) {
+	result := &admin.InboundSync{
+		Started:  started,
+		Finished: time.Now(),
+	}
+	if err != nil {
+		result.Error = err.Error()
+	}
+
+	s.mu.Lock()
+	s.lastSync = result
+	s.mu.Unlock()
+}
+
+func (s *PeriodicScheduler) LastSync() *admin.InboundSync {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.lastSync == nil {
+		return nil
+	}
+	result := *s.lastSync
+	return &result
+}
+
+func (s *PeriodicScheduler) RecentErrors() []errorlog.Entry {
+	return s.errors.Entries()
+}
+
 func (s *PeriodicScheduler) tick() error {
 	s.logger.Log("start retrieving and processing of inbound files")
 
-	dl, err := s.downloader.CopyFilesFromRemote(s.agent)
+	if err := s.sync(s.agent); err != nil {
+		return err
+	}
+	for i := range s.mailboxes {
+		if err := s.sync(s.mailboxes[i]); err != nil {
+			return fmt.Errorf("mailbox %s: %v", s.mailboxes[i].Hostname(), err)
+		}
+	}
+	return nil
+}
+
+func (s *PeriodicScheduler) sync(agent upload.Agent) error {
+	dl, err := s.downloader.CopyFilesFromRemote(agent)
 	if err != nil {
 		return fmt.Errorf("ERROR: problem moving files: %v", err)
 	}
@@ -95,16 +162,16 @@ func (s *PeriodicScheduler) tick() error {
 		if s.cfg.Storage.CleanupLocalDirectory {
 			defer dl.deleteFiles()
 		} else {
-			defer dl.deleteEmptyDirs(s.agent)
+			defer dl.deleteEmptyDirs(agent)
 		}
 	}
 
-	if err := ProcessFiles(dl, s.processors); err != nil {
+	if err := ProcessFiles(dl, s.quarantine, s.acks, s.processors); err != nil {
 		return fmt.Errorf("ERROR: processing files: %v", err)
 	}
 
 	if s.cfg.Storage != nil && !s.cfg.Storage.KeepRemoteFiles {
-		if err := Cleanup(s.logger, s.agent, dl); err != nil {
+		if err := Cleanup(s.logger, agent, dl); err != nil {
 			return fmt.Errorf("ERROR: deleting remote files: %v", err)
 		}
 	}
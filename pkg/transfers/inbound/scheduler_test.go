@@ -23,7 +23,7 @@ func TestScheduler(t *testing.T) {
 	agent := &upload.MockAgent{}
 	processors := SetupProcessors(&MockProcessor{})
 
-	schd := NewPeriodicScheduler(cfg, agent, processors)
+	schd := NewPeriodicScheduler(cfg, agent, nil, nil, nil, processors)
 	if schd == nil {
 		t.Fatal("nil Scheduler")
 	}
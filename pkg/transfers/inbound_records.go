@@ -0,0 +1,175 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package transfers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/moov-io/paygate/pkg/admin"
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/x/route"
+
+	"github.com/moov-io/base"
+)
+
+// InboundRecordFilter narrows the records listed for admins.
+type InboundRecordFilter struct {
+	TransferID string
+	Type       string
+
+	// Unmatched only lists records which weren't matched to a Transfer.
+	Unmatched bool
+
+	Limit int
+}
+
+// SaveInboundRecord stores a return or correction entry as it was received. Records are
+// written even when no Transfer is found so they can be reconciled later.
+func (r *sqlRepo) SaveInboundRecord(record *client.InboundRecord) error {
+	if record.RecordID == "" {
+		record.RecordID = base.ID()
+	}
+	query := `insert into inbound_records (record_id, transfer_id, record_type, code, trace_number, original_trace, immediate_origin, immediate_destination, entry_detail, addenda, created_at) values (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	var transferID *string
+	if record.TransferID != "" {
+		transferID = &record.TransferID
+	}
+	_, err = stmt.Exec(
+		record.RecordID, transferID, record.Type, record.Code, record.TraceNumber, record.OriginalTrace,
+		record.ImmediateOrigin, record.ImmediateDestination, record.EntryDetail, record.Addenda, record.Created,
+	)
+	return err
+}
+
+const inboundRecordColumns = `r.record_id, r.transfer_id, r.record_type, r.code, r.trace_number, r.original_trace, r.immediate_origin, r.immediate_destination, r.entry_detail, r.addenda, r.created_at`
+
+func (r *sqlRepo) getInboundRecords(orgID string, transferID string) ([]*client.InboundRecord, error) {
+	query := `select ` + inboundRecordColumns + ` from inbound_records as r
+inner join transfers as t on r.transfer_id = t.transfer_id
+where r.transfer_id = ? and t.organization = ? order by r.created_at asc;`
+	var out []*client.InboundRecord
+	err := r.queryInboundRecords(query, []interface{}{transferID, orgID}, func(record client.InboundRecord) {
+		out = append(out, &record)
+	})
+	return out, err
+}
+
+// ListInboundRecords returns the newest return and correction records across every organization.
+func (r *sqlRepo) ListInboundRecords(filter InboundRecordFilter) ([]*admin.InboundRecord, error) {
+	var conditions []string
+	var args []interface{}
+	if filter.TransferID != "" {
+		conditions = append(conditions, "r.transfer_id = ?")
+		args = append(args, filter.TransferID)
+	}
+	if filter.Type != "" {
+		conditions = append(conditions, "r.record_type = ?")
+		args = append(args, filter.Type)
+	}
+	if filter.Unmatched {
+		conditions = append(conditions, "r.transfer_id is null")
+	}
+
+	query := `select ` + inboundRecordColumns + ` from inbound_records as r`
+	if len(conditions) > 0 {
+		query += " where " + strings.Join(conditions, " and ")
+	}
+	query += " order by r.created_at desc limit ?;"
+	if filter.Limit <= 0 {
+		filter.Limit = 100
+	}
+	args = append(args, filter.Limit)
+
+	out := make([]*admin.InboundRecord, 0)
+	err := r.queryInboundRecords(query, args, func(record client.InboundRecord) {
+		out = append(out, &admin.InboundRecord{
+			RecordID:             record.RecordID,
+			TransferID:           record.TransferID,
+			Type:                 record.Type,
+			Code:                 record.Code,
+			TraceNumber:          record.TraceNumber,
+			OriginalTrace:        record.OriginalTrace,
+			ImmediateOrigin:      record.ImmediateOrigin,
+			ImmediateDestination: record.ImmediateDestination,
+			EntryDetail:          record.EntryDetail,
+			Addenda:              record.Addenda,
+			Created:              record.Created,
+		})
+	})
+	return out, err
+}
+
+func (r *sqlRepo) queryInboundRecords(query string, args []interface{}, fn func(client.InboundRecord)) error {
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.Query(args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var record client.InboundRecord
+		var transferID *string
+		if err := rows.Scan(
+			&record.RecordID, &transferID, &record.Type, &record.Code, &record.TraceNumber, &record.OriginalTrace,
+			&record.ImmediateOrigin, &record.ImmediateDestination, &record.EntryDetail, &record.Addenda, &record.Created,
+		); err != nil {
+			return err
+		}
+		if transferID != nil {
+			record.TransferID = *transferID
+		}
+		fn(record)
+	}
+	return rows.Err()
+}
+
+// GetTransferReturns returns the return and correction entries received for a Transfer
+// exactly as the RDFI sent them.
+func GetTransferReturns(cfg *config.Config, repo Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(cfg, w, r)
+
+		transferID := getTransferID(r)
+		orgID, err := repo.GetTransferOrganization(transferID)
+		if err != nil {
+			responder.Problem(err)
+			return
+		}
+		if orgID == "" || orgID != responder.OrganizationID {
+			responder.Problem(fmt.Errorf("transferID=%s not found", transferID))
+			return
+		}
+
+		records, err := repo.getInboundRecords(responder.OrganizationID, transferID)
+		if err != nil {
+			responder.Problem(err)
+			return
+		}
+		if records == nil {
+			records = make([]*client.InboundRecord, 0)
+		}
+
+		responder.Respond(func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(records)
+		})
+	}
+}
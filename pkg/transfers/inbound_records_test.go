@@ -0,0 +1,121 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package transfers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/moov-io/base"
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+)
+
+func inboundRecord(transferID string, recordType string, code string) *client.InboundRecord {
+	return &client.InboundRecord{
+		TransferID:           transferID,
+		Type:                 recordType,
+		Code:                 code,
+		TraceNumber:          "121042880000001",
+		OriginalTrace:        "091400600000001",
+		ImmediateOrigin:      "121042882",
+		ImmediateDestination: "231380104",
+		EntryDetail:          "626231380104744-5678-99      0000000500c-1            Customer Name           1121042880000001",
+		Addenda:              "799" + code + strings.Repeat(" ", 88),
+		Created:              time.Now().Truncate(time.Second),
+	}
+}
+
+func TestRepository__InboundRecords(t *testing.T) {
+	t.Parallel()
+
+	check := func(t *testing.T, repo *sqlRepo) {
+		xfer := writeTransfer(t, "moov", repo)
+
+		returned := inboundRecord(xfer.TransferID, "return", "R01")
+		require.NoError(t, repo.SaveInboundRecord(returned))
+		require.NotEmpty(t, returned.RecordID)
+
+		corrected := inboundRecord(xfer.TransferID, "correction", "C01")
+		corrected.Created = corrected.Created.Add(time.Second)
+		require.NoError(t, repo.SaveInboundRecord(corrected))
+
+		unmatched := inboundRecord("", "return", "R03")
+		require.NoError(t, repo.SaveInboundRecord(unmatched))
+
+		records, err := repo.getInboundRecords("moov", xfer.TransferID)
+		require.NoError(t, err)
+		require.Len(t, records, 2)
+		require.Equal(t, "R01", records[0].Code)
+		require.Equal(t, returned.EntryDetail, records[0].EntryDetail)
+		require.Equal(t, returned.Addenda, records[0].Addenda)
+		require.Equal(t, "C01", records[1].Code)
+
+		// other organizations can't read them
+		records, err = repo.getInboundRecords("other", xfer.TransferID)
+		require.NoError(t, err)
+		require.Empty(t, records)
+
+		listed, err := repo.ListInboundRecords(InboundRecordFilter{})
+		require.NoError(t, err)
+		require.Len(t, listed, 3)
+
+		listed, err = repo.ListInboundRecords(InboundRecordFilter{Unmatched: true})
+		require.NoError(t, err)
+		require.Len(t, listed, 1)
+		require.Equal(t, "R03", listed[0].Code)
+		require.Empty(t, listed[0].TransferID)
+
+		listed, err = repo.ListInboundRecords(InboundRecordFilter{TransferID: xfer.TransferID, Type: "correction"})
+		require.NoError(t, err)
+		require.Len(t, listed, 1)
+		require.Equal(t, "C01", listed[0].Code)
+
+		listed, err = repo.ListInboundRecords(InboundRecordFilter{Limit: 1})
+		require.NoError(t, err)
+		require.Len(t, listed, 1)
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+}
+
+func TestRouter__GetTransferReturns(t *testing.T) {
+	transferID := base.ID()
+	repo := &MockRepository{
+		Organization: "moov",
+		InboundRecords: []*client.InboundRecord{
+			inboundRecord(transferID, "return", "R01"),
+		},
+	}
+
+	r := mux.NewRouter()
+	NewRouter(config.Empty(), repo, nil, mockCustomersClient(), mockDecryptor, mockStrategy, fakePublisher, nil, nil, nil, nil, nil).RegisterRoutes(r)
+
+	req := httptest.NewRequest("GET", "/transfers/"+transferID+"/returns", nil)
+	req.Header.Set("X-Organization", "moov")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var records []*client.InboundRecord
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&records))
+	require.Len(t, records, 1)
+	require.Equal(t, "R01", records[0].Code)
+
+	// other organization
+	req = httptest.NewRequest("GET", "/transfers/"+transferID+"/returns", nil)
+	req.Header.Set("X-Organization", "other")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
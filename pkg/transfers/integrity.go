@@ -0,0 +1,101 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package transfers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/moov-io/paygate/pkg/admin"
+
+	"github.com/go-kit/kit/metrics/prometheus"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	rowsSkipped = prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+		Name: "repository_rows_skipped",
+		Help: "Counter of database rows omitted from responses because they couldn't be read",
+	}, []string{"table"})
+)
+
+// skippedRow is a row left out of a list because it couldn't be read, which
+// usually means its data is corrupt.
+type skippedRow struct {
+	Table string
+	ID    string
+	Err   error
+}
+
+func skipRow(table, id string, err error) skippedRow {
+	if err == nil {
+		err = errors.New("row not found")
+	}
+	rowsSkipped.With("table", table).Add(1)
+	return skippedRow{Table: table, ID: id, Err: err}
+}
+
+// omittedWarning sets a Warning header (RFC 7234) on list responses which left out rows.
+// The body stays an array so existing clients are unaffected.
+func omittedWarning(w http.ResponseWriter, skipped []skippedRow) {
+	if len(skipped) == 0 {
+		return
+	}
+	w.Header().Set("Warning", fmt.Sprintf(`199 paygate "%d %s omitted, they could not be read"`, len(skipped), skipped[0].Table))
+}
+
+// CheckIntegrity reads every Transfer and reports each which fails, for example from
+// a column which can't be scanned.
+func (r *sqlRepo) CheckIntegrity() (*admin.IntegrityReport, error) {
+	report := &admin.IntegrityReport{
+		Problems: make([]admin.IntegrityProblem, 0),
+		Started:  time.Now(),
+	}
+
+	query := `select transfer_id, organization from transfers where deleted_at is null;`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.Query()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	type key struct {
+		transferID, organization string
+	}
+	var keys []key
+	for rows.Next() {
+		var k key
+		if err := rows.Scan(&k.transferID, &k.organization); err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range keys {
+		report.Checked++
+		if xfer, err := r.getUserTransfer(keys[i].transferID, keys[i].organization); err != nil || xfer == nil {
+			skipped := skipRow("transfers", keys[i].transferID, err)
+			report.Problems = append(report.Problems, admin.IntegrityProblem{
+				Table:        skipped.Table,
+				ID:           skipped.ID,
+				Organization: keys[i].organization,
+				Error:        skipped.Err.Error(),
+			})
+		}
+	}
+	report.Finished = time.Now()
+	return report, nil
+}
@@ -0,0 +1,68 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package transfers
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/moov-io/base"
+
+	"github.com/moov-io/paygate/pkg/config"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepository__skippedRows(t *testing.T) {
+	orgID := base.ID()
+	repo := setupSQLiteDB(t)
+
+	writeTransfer(t, orgID, repo)
+	corrupt := writeTransfer(t, orgID, repo)
+
+	_, err := repo.db.Exec(`update transfers set amount_value = 'bad' where transfer_id = ?;`, corrupt.TransferID)
+	require.NoError(t, err)
+
+	xfers, skipped, err := repo.getTransfers(orgID, readTransferFilterParams(&http.Request{}))
+	require.NoError(t, err)
+	require.Len(t, xfers, 1)
+	require.Len(t, skipped, 1)
+	require.Equal(t, corrupt.TransferID, skipped[0].ID)
+	require.Error(t, skipped[0].Err)
+
+	report, err := repo.CheckIntegrity()
+	require.NoError(t, err)
+	require.Equal(t, int32(2), report.Checked)
+	require.Len(t, report.Problems, 1)
+	require.Equal(t, "transfers", report.Problems[0].Table)
+	require.Equal(t, corrupt.TransferID, report.Problems[0].ID)
+	require.Equal(t, orgID, report.Problems[0].Organization)
+}
+
+func TestRouter__getTransfersOmitted(t *testing.T) {
+	repo := &MockRepository{
+		Transfers: repoWithTransfer.Transfers,
+		Skipped: []skippedRow{
+			{Table: "transfers", ID: base.ID(), Err: errors.New("bad scan")},
+		},
+	}
+
+	r := mux.NewRouter()
+	router := NewRouter(config.Empty(), repo, orgRepo, mockCustomersClient(), mockDecryptor, mockStrategy, fakePublisher, nil, nil, nil, nil, nil)
+	router.RegisterRoutes(r)
+
+	req := httptest.NewRequest("GET", "/transfers", nil)
+	req.Header.Set("X-Organization", "moov")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	w.Flush()
+
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, `199 paygate "1 transfers omitted, they could not be read"`, w.Header().Get("Warning"))
+}
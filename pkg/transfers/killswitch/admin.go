@@ -0,0 +1,91 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package killswitch
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/moov-io/base/admin"
+	moovhttp "github.com/moov-io/base/http"
+	"github.com/moov-io/base/log"
+
+	paygateadmin "github.com/moov-io/paygate/pkg/admin"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/x/adminauth"
+	"github.com/moov-io/paygate/x/route"
+)
+
+// RegisterAdminRoutes adds endpoints to inspect, enable and disable debit kill switches.
+func RegisterAdminRoutes(cfg *config.Config, svc *admin.Server, checker *Checker) {
+	svc.AddHandler("/debits/kill-switches", listSwitches(checker))
+	svc.AddHandler("/debits/kill-switches/{scope}", adminauth.Protect(cfg.Admin.Signing, updateSwitch(cfg, checker)))
+}
+
+func problem(w http.ResponseWriter, err error) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	moovhttp.Problem(w, err)
+}
+
+func listSwitches(checker *Checker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			problem(w, fmt.Errorf("invalid method %s", r.Method))
+			return
+		}
+		switches, err := checker.Switches()
+		if err != nil {
+			problem(w, err)
+			return
+		}
+		if switches == nil {
+			switches = make([]paygateadmin.KillSwitch, 0)
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(switches)
+	}
+}
+
+func updateSwitch(cfg *config.Config, checker *Checker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(cfg, w, r)
+
+		scope := route.ReadPathID("scope", r)
+		var err error
+		switch r.Method {
+		case http.MethodPut:
+			var request struct {
+				Reason string `json:"reason"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+				responder.Problem(err)
+				return
+			}
+			err = checker.Enable(scope, request.Reason)
+
+		case http.MethodDelete:
+			err = checker.Disable(scope)
+
+		default:
+			err = fmt.Errorf("unsupported HTTP verb %s", r.Method)
+		}
+		if err != nil {
+			responder.Problem(err)
+			return
+		}
+
+		cfg.Logger.With(log.Fields{
+			"requestID": responder.XRequestID,
+			"scope":     scope,
+			"method":    r.Method,
+		}).Log("Updated debit kill switch")
+
+		responder.Respond(func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusOK)
+		})
+	}
+}
@@ -0,0 +1,71 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package killswitch
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/moov-io/paygate/pkg/admin"
+	"github.com/moov-io/paygate/pkg/config"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdmin__switches(t *testing.T) {
+	repo := &MockRepository{}
+	checker := testChecker(repo)
+
+	router := mux.NewRouter()
+	router.Handle("/debits/kill-switches", listSwitches(checker))
+	router.Handle("/debits/kill-switches/{scope}", updateSwitch(config.Empty(), checker))
+
+	// enable
+	req := httptest.NewRequest("PUT", "/debits/kill-switches/moov", strings.NewReader(`{"reason": "compromised login"}`))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	w.Flush()
+	require.Equal(t, http.StatusOK, w.Code)
+
+	// list
+	req = httptest.NewRequest("GET", "/debits/kill-switches", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	w.Flush()
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var switches []admin.KillSwitch
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&switches))
+	require.Len(t, switches, 1)
+	require.Equal(t, "moov", switches[0].Scope)
+	require.Equal(t, "compromised login", switches[0].Reason)
+
+	// disable
+	req = httptest.NewRequest("DELETE", "/debits/kill-switches/moov", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	w.Flush()
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Len(t, repo.Switches, 0)
+
+	// errors
+	req = httptest.NewRequest("POST", "/debits/kill-switches/moov", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	w.Flush()
+	require.Equal(t, http.StatusBadRequest, w.Code)
+
+	repo.Err = errors.New("bad error")
+	req = httptest.NewRequest("GET", "/debits/kill-switches", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	w.Flush()
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
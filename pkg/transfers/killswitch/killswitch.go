@@ -0,0 +1,178 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+// Package killswitch blocks debit (pull) Transfers from being created or merged, either
+// for every organization or just one. It's meant for incident response, such as when an
+// organization's authentication has been compromised, and leaves credits unaffected.
+package killswitch
+
+import (
+	"errors"
+	"time"
+
+	"github.com/moov-io/ach"
+	"github.com/moov-io/base/log"
+
+	"github.com/moov-io/paygate/pkg/achx"
+	"github.com/moov-io/paygate/pkg/admin"
+	"github.com/moov-io/paygate/pkg/config"
+)
+
+const (
+	// Global is the scope of a switch which blocks debits for every organization.
+	Global = "global"
+
+	SourceConfig = "config"
+	SourceAdmin  = "admin"
+)
+
+var ErrDebitsBlocked = errors.New("debit transfers are blocked")
+
+// Checker combines the switches from config with those enabled by admins. Admin switches
+// are read from the database on each check so they apply to every PayGate instance at once.
+//
+// A nil *Checker never blocks debits.
+type Checker struct {
+	cfg               config.KillSwitch
+	odfiRoutingNumber string
+	logger            log.Logger
+
+	repo Repository
+}
+
+func NewChecker(cfg *config.Config, repo Repository) *Checker {
+	c := &Checker{
+		cfg:               cfg.Transfers.KillSwitch,
+		odfiRoutingNumber: cfg.ODFI.RoutingNumber,
+		logger:            cfg.Logger,
+		repo:              repo,
+	}
+	if _, err := c.Switches(); err != nil {
+		c.logger.LogErrorf("problem reading debit kill switches: %v", err)
+	}
+	return c
+}
+
+// DebitsBlocked returns true when debits are blocked for every organization or the given one.
+func (c *Checker) DebitsBlocked(organization string) (bool, error) {
+	if c == nil {
+		return false, nil
+	}
+	if c.cfg.Debits {
+		return true, nil
+	}
+	scopes := []string{Global}
+	if organization != "" {
+		for i := range c.cfg.Organizations {
+			if c.cfg.Organizations[i] == organization {
+				return true, nil
+			}
+		}
+		scopes = append(scopes, organization)
+	}
+	return c.repo.anyEnabled(scopes...)
+}
+
+// CheckFiles returns ErrDebitsBlocked when any of the files debit a remote account and
+// debits are blocked for the organization.
+func (c *Checker) CheckFiles(organization string, files []*ach.File) error {
+	if c == nil {
+		return nil
+	}
+	debits := false
+	for i := range files {
+		if HasDebits(c.odfiRoutingNumber, files[i]) {
+			debits = true
+			break
+		}
+	}
+	if !debits {
+		return nil
+	}
+	blocked, err := c.DebitsBlocked(organization)
+	if err != nil {
+		return err
+	}
+	if blocked {
+		recordBlocked(stageCreate)
+		return ErrDebitsBlocked
+	}
+	return nil
+}
+
+// HoldTransfer returns true for debit Transfers which are blocked. The pipeline keeps these
+// out of merged files until their switch is disabled.
+func (c *Checker) HoldTransfer(transferID string, file *ach.File) (bool, error) {
+	if c == nil || !HasDebits(c.odfiRoutingNumber, file) {
+		return false, nil
+	}
+	organization, err := c.repo.getOrganization(transferID)
+	if err != nil {
+		return false, err
+	}
+	blocked, err := c.DebitsBlocked(organization)
+	if blocked {
+		recordBlocked(stageMerge)
+	}
+	return blocked, err
+}
+
+// Enable blocks debits for the scope, which is Global or an organization.
+func (c *Checker) Enable(scope string, reason string) error {
+	if scope == "" {
+		return errors.New("missing scope")
+	}
+	if err := c.repo.enable(scope, reason, time.Now()); err != nil {
+		return err
+	}
+	c.Switches()
+	return nil
+}
+
+// Disable removes the switch an admin enabled for scope. Switches from config can't be disabled.
+func (c *Checker) Disable(scope string) error {
+	if err := c.repo.disable(scope); err != nil {
+		return err
+	}
+	c.Switches()
+	return nil
+}
+
+// Switches returns every enabled switch, starting with those from config.
+func (c *Checker) Switches() ([]admin.KillSwitch, error) {
+	var out []admin.KillSwitch
+	if c.cfg.Debits {
+		out = append(out, admin.KillSwitch{Scope: Global, Source: SourceConfig})
+	}
+	for i := range c.cfg.Organizations {
+		out = append(out, admin.KillSwitch{Scope: c.cfg.Organizations[i], Source: SourceConfig})
+	}
+	enabled, err := c.repo.enabled()
+	if err != nil {
+		return nil, err
+	}
+	out = append(out, enabled...)
+
+	recordEnabled(out)
+	return out, nil
+}
+
+// HasDebits returns true if file debits any account outside of the ODFI. Offsetting debits
+// to the ODFI's own accounts and zero-dollar prenotes aren't considered.
+func HasDebits(odfiRoutingNumber string, file *ach.File) bool {
+	if file == nil {
+		return false
+	}
+	odfi := achx.ABA8(odfiRoutingNumber)
+	entries := achx.Entries(file)
+	for i := range entries {
+		if entries[i].CreditOrDebit() != "D" || entries[i].Amount == 0 {
+			continue
+		}
+		if entries[i].RDFIIdentification != odfi {
+			return true
+		}
+	}
+	return false
+}
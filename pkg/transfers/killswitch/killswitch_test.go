@@ -0,0 +1,119 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package killswitch
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/moov-io/ach"
+
+	"github.com/moov-io/paygate/pkg/config"
+
+	"github.com/stretchr/testify/require"
+)
+
+func readDebitFile(t *testing.T) *ach.File {
+	t.Helper()
+
+	file, err := ach.ReadFile(filepath.Join("..", "..", "..", "testdata", "ppd-debit.ach"))
+	require.NoError(t, err)
+	return file
+}
+
+func testChecker(repo Repository) *Checker {
+	cfg := config.Empty()
+	cfg.ODFI.RoutingNumber = "076401251"
+	return NewChecker(cfg, repo)
+}
+
+func TestHasDebits(t *testing.T) {
+	file := readDebitFile(t)
+
+	require.True(t, HasDebits("076401251", file))
+	require.False(t, HasDebits("053200019", file)) // debits to the ODFI are offsets
+	require.False(t, HasDebits("076401251", nil))
+}
+
+func TestChecker__DebitsBlocked(t *testing.T) {
+	var checker *Checker
+	blocked, err := checker.DebitsBlocked("moov")
+	require.NoError(t, err)
+	require.False(t, blocked)
+
+	repo := &MockRepository{}
+	checker = testChecker(repo)
+
+	blocked, err = checker.DebitsBlocked("moov")
+	require.NoError(t, err)
+	require.False(t, blocked)
+
+	// from config
+	checker.cfg.Organizations = []string{"moov"}
+	blocked, err = checker.DebitsBlocked("moov")
+	require.NoError(t, err)
+	require.True(t, blocked)
+
+	blocked, err = checker.DebitsBlocked("other")
+	require.NoError(t, err)
+	require.False(t, blocked)
+
+	// from an admin
+	require.NoError(t, checker.Enable(Global, "incident"))
+	blocked, err = checker.DebitsBlocked("other")
+	require.NoError(t, err)
+	require.True(t, blocked)
+
+	switches, err := checker.Switches()
+	require.NoError(t, err)
+	require.Len(t, switches, 2)
+	require.Equal(t, SourceConfig, switches[0].Source)
+	require.Equal(t, SourceAdmin, switches[1].Source)
+
+	require.NoError(t, checker.Disable(Global))
+	blocked, err = checker.DebitsBlocked("other")
+	require.NoError(t, err)
+	require.False(t, blocked)
+
+	require.Error(t, checker.Enable("", "missing scope"))
+}
+
+func TestChecker__CheckFiles(t *testing.T) {
+	file := readDebitFile(t)
+
+	repo := &MockRepository{}
+	checker := testChecker(repo)
+	require.NoError(t, checker.CheckFiles("moov", []*ach.File{file}))
+
+	require.NoError(t, checker.Enable("moov", "incident"))
+	require.Equal(t, ErrDebitsBlocked, checker.CheckFiles("moov", []*ach.File{file}))
+	require.NoError(t, checker.CheckFiles("other", []*ach.File{file}))
+
+	// credits are unaffected
+	checker.odfiRoutingNumber = "053200019"
+	require.NoError(t, checker.CheckFiles("moov", []*ach.File{file}))
+}
+
+func TestChecker__HoldTransfer(t *testing.T) {
+	file := readDebitFile(t)
+
+	repo := &MockRepository{
+		Organizations: map[string]string{"xfer": "moov"},
+	}
+	checker := testChecker(repo)
+
+	held, err := checker.HoldTransfer("xfer", file)
+	require.NoError(t, err)
+	require.False(t, held)
+
+	require.NoError(t, checker.Enable("moov", "incident"))
+	held, err = checker.HoldTransfer("xfer", file)
+	require.NoError(t, err)
+	require.True(t, held)
+
+	held, err = checker.HoldTransfer("other", file)
+	require.NoError(t, err)
+	require.False(t, held)
+}
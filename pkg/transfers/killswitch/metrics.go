@@ -0,0 +1,46 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package killswitch
+
+import (
+	"github.com/go-kit/kit/metrics/prometheus"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+
+	"github.com/moov-io/paygate/pkg/admin"
+)
+
+var (
+	switchesEnabled = prometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+		Name: "debit_kill_switches_enabled",
+		Help: "Gauge of debit kill switches which are enabled",
+	}, []string{"source"})
+
+	debitsBlocked = prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+		Name: "debit_kill_switch_blocked_transfers",
+		Help: "Counter of debit transfers blocked by a kill switch",
+	}, []string{"stage"})
+)
+
+const (
+	stageCreate = "create"
+	stageMerge  = "merge"
+)
+
+func recordEnabled(switches []admin.KillSwitch) {
+	counts := map[string]int{
+		SourceConfig: 0,
+		SourceAdmin:  0,
+	}
+	for i := range switches {
+		counts[switches[i].Source]++
+	}
+	for source, n := range counts {
+		switchesEnabled.With("source", source).Set(float64(n))
+	}
+}
+
+func recordBlocked(stage string) {
+	debitsBlocked.With("stage", stage).Add(1)
+}
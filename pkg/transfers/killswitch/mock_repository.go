@@ -0,0 +1,64 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package killswitch
+
+import (
+	"time"
+
+	"github.com/moov-io/paygate/pkg/admin"
+)
+
+type MockRepository struct {
+	Switches      map[string]admin.KillSwitch
+	Organizations map[string]string
+
+	Err error
+}
+
+func (r *MockRepository) enable(scope string, reason string, when time.Time) error {
+	if r.Err != nil {
+		return r.Err
+	}
+	if r.Switches == nil {
+		r.Switches = make(map[string]admin.KillSwitch)
+	}
+	r.Switches[scope] = admin.KillSwitch{Scope: scope, Source: SourceAdmin, Reason: reason, Enabled: &when}
+	return nil
+}
+
+func (r *MockRepository) disable(scope string) error {
+	if r.Err != nil {
+		return r.Err
+	}
+	delete(r.Switches, scope)
+	return nil
+}
+
+func (r *MockRepository) enabled() ([]admin.KillSwitch, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	var out []admin.KillSwitch
+	for _, sw := range r.Switches {
+		out = append(out, sw)
+	}
+	return out, nil
+}
+
+func (r *MockRepository) anyEnabled(scopes ...string) (bool, error) {
+	if r.Err != nil {
+		return false, r.Err
+	}
+	for i := range scopes {
+		if _, exists := r.Switches[scopes[i]]; exists {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *MockRepository) getOrganization(transferID string) (string, error) {
+	return r.Organizations[transferID], r.Err
+}
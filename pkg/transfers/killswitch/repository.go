@@ -0,0 +1,109 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package killswitch
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/moov-io/paygate/pkg/admin"
+)
+
+type Repository interface {
+	enable(scope string, reason string, when time.Time) error
+	disable(scope string) error
+
+	// enabled returns the switches enabled by admins
+	enabled() ([]admin.KillSwitch, error)
+	anyEnabled(scopes ...string) (bool, error)
+
+	getOrganization(transferID string) (string, error)
+}
+
+func NewRepo(db *sql.DB) *sqlRepo {
+	return &sqlRepo{db: db}
+}
+
+type sqlRepo struct {
+	db *sql.DB
+}
+
+func (r *sqlRepo) Close() error {
+	return r.db.Close()
+}
+
+func (r *sqlRepo) enable(scope string, reason string, when time.Time) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	// Enabling an existing switch replaces its reason
+	if _, err := tx.Exec(`delete from debit_kill_switches where scope = ?;`, scope); err != nil {
+		tx.Rollback()
+		return err
+	}
+	query := `insert into debit_kill_switches (scope, reason, enabled_at) values (?, ?, ?);`
+	if _, err := tx.Exec(query, scope, reason, when); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *sqlRepo) disable(scope string) error {
+	_, err := r.db.Exec(`delete from debit_kill_switches where scope = ?;`, scope)
+	return err
+}
+
+func (r *sqlRepo) enabled() ([]admin.KillSwitch, error) {
+	rows, err := r.db.Query(`select scope, reason, enabled_at from debit_kill_switches order by enabled_at asc;`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []admin.KillSwitch
+	for rows.Next() {
+		var reason *string
+		var enabled time.Time
+		sw := admin.KillSwitch{Source: SourceAdmin}
+		if err := rows.Scan(&sw.Scope, &reason, &enabled); err != nil {
+			return nil, err
+		}
+		if reason != nil {
+			sw.Reason = *reason
+		}
+		sw.Enabled = &enabled
+		out = append(out, sw)
+	}
+	return out, rows.Err()
+}
+
+func (r *sqlRepo) anyEnabled(scopes ...string) (bool, error) {
+	query := `select count(*) from debit_kill_switches where scope = ?;`
+	for i := range scopes {
+		var n int
+		if err := r.db.QueryRow(query, scopes[i]).Scan(&n); err != nil {
+			return false, err
+		}
+		if n > 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *sqlRepo) getOrganization(transferID string) (string, error) {
+	query := `select organization from transfers where transfer_id = ? and deleted_at is null limit 1;`
+	var organization string
+	if err := r.db.QueryRow(query, transferID).Scan(&organization); err != nil {
+		if err == sql.ErrNoRows {
+			return "", nil
+		}
+		return "", err
+	}
+	return organization, nil
+}
@@ -0,0 +1,70 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package killswitch
+
+import (
+	"testing"
+	"time"
+
+	"github.com/moov-io/base"
+
+	"github.com/moov-io/paygate/pkg/database"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepository(t *testing.T) {
+	t.Parallel()
+
+	check := func(t *testing.T, repo *sqlRepo) {
+		orgID := base.ID()
+
+		enabled, err := repo.anyEnabled(Global, orgID)
+		require.NoError(t, err)
+		require.False(t, enabled)
+
+		require.NoError(t, repo.enable(orgID, "compromised login", time.Now()))
+		require.NoError(t, repo.enable(orgID, "compromised password reset", time.Now()))
+
+		enabled, err = repo.anyEnabled(Global, orgID)
+		require.NoError(t, err)
+		require.True(t, enabled)
+
+		switches, err := repo.enabled()
+		require.NoError(t, err)
+		require.Len(t, switches, 1)
+		require.Equal(t, orgID, switches[0].Scope)
+		require.Equal(t, SourceAdmin, switches[0].Source)
+		require.Equal(t, "compromised password reset", switches[0].Reason)
+		require.NotNil(t, switches[0].Enabled)
+
+		require.NoError(t, repo.disable(orgID))
+		enabled, err = repo.anyEnabled(Global, orgID)
+		require.NoError(t, err)
+		require.False(t, enabled)
+
+		// unknown transfers have no organization
+		organization, err := repo.getOrganization(base.ID())
+		require.NoError(t, err)
+		require.Equal(t, "", organization)
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+}
+
+func setupSQLiteDB(t *testing.T) *sqlRepo {
+	db := database.CreateTestSqliteDB(t)
+	t.Cleanup(func() { db.Close() })
+
+	return NewRepo(db.DB)
+}
+
+func setupMySQLeDB(t *testing.T) *sqlRepo {
+	db := database.CreateTestMySQLDB(t)
+	t.Cleanup(func() { db.Close() })
+
+	return NewRepo(db.DB)
+}
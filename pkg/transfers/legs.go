@@ -0,0 +1,220 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package transfers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/moov-io/base"
+	"github.com/moov-io/base/log"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/customers"
+	"github.com/moov-io/paygate/pkg/customers/accounts"
+	"github.com/moov-io/paygate/pkg/organization"
+	"github.com/moov-io/paygate/pkg/transfers/fundflow"
+	"github.com/moov-io/paygate/pkg/transfers/history"
+	"github.com/moov-io/paygate/pkg/transfers/pipeline"
+	"github.com/moov-io/paygate/pkg/util"
+)
+
+// LegReleaser sends the held credit leg of two-leg transfers once their debit leg has been
+// uploaded for the configured number of banking days without a return. Credit legs whose
+// debit was returned are canceled instead.
+//
+// Releasing first moves the credit leg from held to pending, so only one releaser can send
+// the leg even with several PayGate instances running.
+type LegReleaser struct {
+	cfg    *config.Config
+	logger log.Logger
+
+	repo             Repository
+	orgRepo          organization.Repository
+	customersClient  customers.Client
+	accountDecryptor accounts.Decryptor
+	originator       fundflow.CreditOriginator
+	pub              pipeline.XferPublisher
+
+	interval time.Duration
+}
+
+func NewLegReleaser(
+	cfg *config.Config,
+	repo Repository,
+	orgRepo organization.Repository,
+	customersClient customers.Client,
+	accountDecryptor accounts.Decryptor,
+	originator fundflow.CreditOriginator,
+	pub pipeline.XferPublisher,
+) *LegReleaser {
+	return &LegReleaser{
+		cfg:              cfg,
+		logger:           cfg.Logger,
+		repo:             repo,
+		orgRepo:          orgRepo,
+		customersClient:  customersClient,
+		accountDecryptor: accountDecryptor,
+		originator:       originator,
+		pub:              pub,
+		interval:         15 * time.Minute,
+	}
+}
+
+func (lr *LegReleaser) Start(ctx context.Context) {
+	ticker := time.NewTicker(lr.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case now := <-ticker.C:
+			if err := lr.tick(now); err != nil {
+				lr.logger.LogErrorf("ERROR releasing transfer legs: %v", err)
+			}
+
+		case <-ctx.Done():
+			lr.logger.Log("leg releaser shutdown")
+			return
+		}
+	}
+}
+
+func (lr *LegReleaser) tick(now time.Time) error {
+	held, err := lr.repo.getHeldCreditLegs()
+	if err != nil {
+		return err
+	}
+
+	var el base.ErrorList
+	for i := range held {
+		if held[i].ReturnCode != "" {
+			if err := lr.cancel(held[i].TransferID, history.Pipeline); err != nil {
+				el.Add(fmt.Errorf("transferID=%s: %v", held[i].TransferID, err))
+			}
+			continue
+		}
+		if now.Before(lr.releaseAt(held[i])) {
+			continue
+		}
+		if err := lr.release(held[i], history.Pipeline); err != nil {
+			el.Add(fmt.Errorf("transferID=%s: %v", held[i].TransferID, err))
+		}
+	}
+	if el.Empty() {
+		return nil
+	}
+	return el
+}
+
+// releaseAt returns when the debit leg's return window has passed
+func (lr *LegReleaser) releaseAt(leg heldLeg) time.Time {
+	days := lr.cfg.ODFI.ForOrganization(leg.OrganizationID).Settlement.HoldBankingDays()
+	return base.NewTime(leg.DebitProcessed).AddBankingDay(days).Time
+}
+
+// Release sends the held credit leg of a Transfer without waiting for the hold to pass.
+func (lr *LegReleaser) Release(transferID string) error {
+	leg, err := lr.repo.getHeldCreditLeg(transferID)
+	if err != nil {
+		return err
+	}
+	if leg == nil {
+		return fmt.Errorf("transferID=%s has no held credit leg", transferID)
+	}
+	if leg.ReturnCode != "" {
+		return fmt.Errorf("transferID=%s debit was returned with %s", transferID, leg.ReturnCode)
+	}
+	return lr.release(*leg, history.Admin)
+}
+
+// Cancel stops the held credit leg of a Transfer from being sent.
+func (lr *LegReleaser) Cancel(transferID string) error {
+	return lr.cancel(transferID, history.Admin)
+}
+
+func (lr *LegReleaser) cancel(transferID string, actor history.Actor) error {
+	err := lr.repo.updateTransferLeg(transferID, fundflow.CreditLeg, client.TRANSFERLEGSTATUS_HELD, client.TRANSFERLEGSTATUS_CANCELED, actor)
+	if err == errLegNotUpdated {
+		return fmt.Errorf("transferID=%s has no held credit leg", transferID)
+	}
+	if err == nil {
+		lr.logger.Set("transferID", transferID).Log("canceled credit leg")
+	}
+	return err
+}
+
+func (lr *LegReleaser) release(leg heldLeg, actor history.Actor) error {
+	// Claim the leg so no other releaser sends it
+	err := lr.repo.updateTransferLeg(leg.TransferID, fundflow.CreditLeg, client.TRANSFERLEGSTATUS_HELD, client.TRANSFERLEGSTATUS_PENDING, actor)
+	if err != nil {
+		if err == errLegNotUpdated {
+			return nil // released or canceled elsewhere
+		}
+		return err
+	}
+
+	if err := lr.originate(leg); err != nil {
+		// Put the leg back so it's retried
+		if revertErr := lr.repo.updateTransferLeg(leg.TransferID, fundflow.CreditLeg, client.TRANSFERLEGSTATUS_PENDING, client.TRANSFERLEGSTATUS_HELD, actor); revertErr != nil {
+			return fmt.Errorf("%v: problem holding credit leg again: %v", err, revertErr)
+		}
+		return err
+	}
+
+	lr.logger.Set("transferID", leg.TransferID).Log("released credit leg")
+	return nil
+}
+
+func (lr *LegReleaser) originate(leg heldLeg) error {
+	if lr.originator == nil {
+		return errors.New("no credit originator configured")
+	}
+
+	xfer, err := lr.repo.GetTransfer(leg.TransferID)
+	if err != nil {
+		return fmt.Errorf("reading transfer: %v", err)
+	}
+	if xfer == nil {
+		return errors.New("transfer not found")
+	}
+
+	destination, err := GetFundflowDestination(lr.customersClient, lr.accountDecryptor, xfer.Destination, leg.OrganizationID)
+	if err != nil {
+		return fmt.Errorf("getting destination: %v", err)
+	}
+	if err := customers.AcceptableAccountStatus(&destination.Account); err != nil {
+		return fmt.Errorf("unaccepted account status: %v", err)
+	}
+
+	companyID := lr.cfg.ODFI.ForOrganization(leg.OrganizationID).FileConfig.BatchHeader.CompanyIdentification
+	orgConfig, err := lr.orgRepo.GetConfig(leg.OrganizationID)
+	if err != nil {
+		return fmt.Errorf("getting org config: %v", err)
+	}
+	if orgConfig != nil {
+		companyID = util.Or(orgConfig.CompanyIdentification, companyID)
+	}
+
+	originator := lr.originator
+	if tenants, ok := originator.(fundflow.Tenants); ok {
+		if originator, ok = tenants.ForOrganization(leg.OrganizationID).(fundflow.CreditOriginator); !ok {
+			return errors.New("tenant strategy can't originate credits")
+		}
+	}
+	files, err := originator.OriginateCredit(companyID, xfer, destination)
+	if err != nil {
+		return fmt.Errorf("originating credit: %v", err)
+	}
+	if err := SaveTraceNumbers(lr.repo, xfer, files); err != nil {
+		return fmt.Errorf("saving trace numbers: %v", err)
+	}
+	if err := pipeline.PublishFiles(lr.pub, xfer, files); err != nil {
+		return fmt.Errorf("publishing files: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package transfers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/moov-io/base"
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/transfers/fundflow"
+	"github.com/moov-io/paygate/pkg/transfers/history"
+	"github.com/moov-io/paygate/pkg/transfers/pipeline"
+
+	"github.com/stretchr/testify/require"
+)
+
+func setupLegReleaser(t *testing.T) (*LegReleaser, *sqlRepo, *pipeline.MockPublisher) {
+	t.Helper()
+
+	cfg := config.Empty()
+	cfg.ODFI.RoutingNumber = "987654320"
+	cfg.ODFI.FileConfig.BatchHeader.CompanyIdentification = "MOOV"
+	cfg.ODFI.Settlement = &config.Settlement{
+		AccountNumber: "55512345",
+		AccountType:   "checking",
+		Name:          "Moov Settlement",
+	}
+
+	repo := setupSQLiteDB(t)
+	pub := pipeline.NewMockPublisher()
+	originator := fundflow.NewTwoLeg(cfg.Logger, cfg.ODFI)
+
+	lr := NewLegReleaser(cfg, repo, orgRepo, mockCustomersClient(), mockDecryptor, originator, pub)
+	return lr, repo, pub
+}
+
+func writeTwoLegTransfer(t *testing.T, orgID string, repo *sqlRepo) *client.Transfer {
+	t.Helper()
+
+	xfer := &client.Transfer{
+		TransferID: base.ID(),
+		Amount: client.Amount{
+			Currency: "USD",
+			Value:    1245,
+		},
+		Source: client.Source{
+			CustomerID: sourceCustomerID,
+			AccountID:  sourceAccountID,
+		},
+		Destination: client.Destination{
+			CustomerID: destinationCustomerID,
+			AccountID:  destinationAccountID,
+		},
+		Description: "payroll",
+		Status:      client.PROCESSED,
+		Created:     time.Now(),
+	}
+	require.NoError(t, repo.WriteUserTransfer(orgID, xfer))
+
+	legs := []client.TransferLeg{
+		{Leg: fundflow.DebitLeg, Status: client.TRANSFERLEGSTATUS_PENDING, Updated: time.Now()},
+		{Leg: fundflow.CreditLeg, Status: client.TRANSFERLEGSTATUS_HELD, Updated: time.Now()},
+	}
+	require.NoError(t, repo.saveTransferLegs(xfer.TransferID, legs))
+	return xfer
+}
+
+func legStatus(t *testing.T, repo *sqlRepo, transferID string, leg string) client.TransferLegStatus {
+	t.Helper()
+
+	xfer, err := repo.GetTransfer(transferID)
+	require.NoError(t, err)
+	for i := range xfer.Legs {
+		if xfer.Legs[i].Leg == leg {
+			return xfer.Legs[i].Status
+		}
+	}
+	t.Fatalf("transferID=%s has no %s leg", transferID, leg)
+	return ""
+}
+
+func TestLegReleaser__tick(t *testing.T) {
+	lr, repo, pub := setupLegReleaser(t)
+	xfer := writeTwoLegTransfer(t, base.ID(), repo)
+
+	// debit leg hasn't been uploaded
+	require.NoError(t, lr.tick(time.Now().Add(30*24*time.Hour)))
+	require.Equal(t, client.TRANSFERLEGSTATUS_HELD, legStatus(t, repo, xfer.TransferID, fundflow.CreditLeg))
+
+	err := repo.updateTransferLeg(xfer.TransferID, fundflow.DebitLeg, client.TRANSFERLEGSTATUS_PENDING, client.TRANSFERLEGSTATUS_PROCESSED, history.Pipeline)
+	require.NoError(t, err)
+
+	// still within the hold
+	require.NoError(t, lr.tick(time.Now()))
+	require.Equal(t, client.TRANSFERLEGSTATUS_HELD, legStatus(t, repo, xfer.TransferID, fundflow.CreditLeg))
+	require.Len(t, pub.Xfers, 0)
+
+	// after the hold
+	require.NoError(t, lr.tick(time.Now().Add(30*24*time.Hour)))
+	require.Equal(t, client.TRANSFERLEGSTATUS_PENDING, legStatus(t, repo, xfer.TransferID, fundflow.CreditLeg))
+	require.Len(t, pub.Xfers, 1)
+
+	traceNumbers, err := repo.getTraceNumbers(xfer.TransferID)
+	require.NoError(t, err)
+	require.Len(t, traceNumbers, 1)
+}
+
+func TestLegReleaser__tickReturned(t *testing.T) {
+	lr, repo, pub := setupLegReleaser(t)
+	xfer := writeTwoLegTransfer(t, base.ID(), repo)
+
+	err := repo.updateTransferLeg(xfer.TransferID, fundflow.DebitLeg, client.TRANSFERLEGSTATUS_PENDING, client.TRANSFERLEGSTATUS_PROCESSED, history.Pipeline)
+	require.NoError(t, err)
+	require.NoError(t, repo.SaveReturnCode(xfer.TransferID, "R01"))
+
+	require.NoError(t, lr.tick(time.Now()))
+	require.Equal(t, client.TRANSFERLEGSTATUS_CANCELED, legStatus(t, repo, xfer.TransferID, fundflow.CreditLeg))
+	require.Len(t, pub.Xfers, 0)
+
+	// releasing a canceled leg fails
+	require.Error(t, lr.Release(xfer.TransferID))
+}
+
+func TestLegReleaser__Release(t *testing.T) {
+	lr, repo, pub := setupLegReleaser(t)
+	xfer := writeTwoLegTransfer(t, base.ID(), repo)
+
+	// debit leg hasn't been uploaded
+	require.Error(t, lr.Release(xfer.TransferID))
+
+	err := repo.updateTransferLeg(xfer.TransferID, fundflow.DebitLeg, client.TRANSFERLEGSTATUS_PENDING, client.TRANSFERLEGSTATUS_PROCESSED, history.Pipeline)
+	require.NoError(t, err)
+
+	require.NoError(t, lr.Release(xfer.TransferID))
+	require.Equal(t, client.TRANSFERLEGSTATUS_PENDING, legStatus(t, repo, xfer.TransferID, fundflow.CreditLeg))
+	require.Len(t, pub.Xfers, 1)
+
+	// already released
+	require.Error(t, lr.Release(xfer.TransferID))
+	require.Error(t, lr.Cancel(xfer.TransferID))
+}
+
+func TestLegReleaser__Cancel(t *testing.T) {
+	lr, repo, pub := setupLegReleaser(t)
+	orgID := base.ID()
+	xfer := writeTwoLegTransfer(t, orgID, repo)
+
+	require.NoError(t, lr.Cancel(xfer.TransferID))
+	require.Equal(t, client.TRANSFERLEGSTATUS_CANCELED, legStatus(t, repo, xfer.TransferID, fundflow.CreditLeg))
+	require.Len(t, pub.Xfers, 0)
+
+	changes, err := repo.getTransferHistory(orgID, xfer.TransferID)
+	require.NoError(t, err)
+	require.Equal(t, "creditLeg", changes[len(changes)-1].Field)
+	require.Equal(t, string(history.Admin), changes[len(changes)-1].Actor)
+}
@@ -0,0 +1,98 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package limiter
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/moov-io/base/admin"
+	moovhttp "github.com/moov-io/base/http"
+	"github.com/moov-io/base/log"
+
+	paygateadmin "github.com/moov-io/paygate/pkg/admin"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/x/adminauth"
+	"github.com/moov-io/paygate/x/route"
+)
+
+// RegisterAdminRoutes adds endpoints to view and adjust the limits of organizations and users.
+func RegisterAdminRoutes(cfg *config.Config, svc *admin.Server, repo OverrideRepository) {
+	svc.AddHandler("/limits/organizations/{id}", adminauth.Protect(cfg.Admin.Signing, limitOverride(cfg, repo, scopeOrganization)))
+	svc.AddHandler("/limits/users/{id}", adminauth.Protect(cfg.Admin.Signing, limitOverride(cfg, repo, scopeUser)))
+}
+
+func limitOverride(cfg *config.Config, repo OverrideRepository, scope string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(cfg, w, r)
+
+		id := route.ReadPathID("id", r)
+		if id == "" {
+			responder.Problem(errors.New("missing id"))
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			override, err := repo.getLimitOverride(scope, id)
+			if err != nil {
+				responder.Problem(err)
+				return
+			}
+			if override == nil {
+				override = &paygateadmin.LimitOverride{Scope: scope, ID: id}
+			}
+			responder.Respond(func(w http.ResponseWriter) {
+				w.WriteHeader(http.StatusOK)
+				json.NewEncoder(w).Encode(override)
+			})
+
+		case http.MethodPut:
+			var override paygateadmin.LimitOverride
+			if err := json.NewDecoder(r.Body).Decode(&override); err != nil {
+				responder.Problem(err)
+				return
+			}
+			override.Scope, override.ID = scope, id
+			if err := validateOverride(override); err != nil {
+				responder.Problem(err)
+				return
+			}
+			now := time.Now()
+			if err := repo.saveLimitOverride(override, now); err != nil {
+				responder.Problem(err)
+				return
+			}
+			override.UpdatedAt = &now
+
+			cfg.Logger.With(log.Fields{
+				"requestID": responder.XRequestID,
+				"scope":     scope,
+				"id":        id,
+			}).Log("Updated limit override")
+
+			responder.Respond(func(w http.ResponseWriter) {
+				w.WriteHeader(http.StatusOK)
+				json.NewEncoder(w).Encode(override)
+			})
+
+		default:
+			moovhttp.Problem(w, fmt.Errorf("unsupported HTTP verb %s", r.Method))
+		}
+	}
+}
+
+func validateOverride(override paygateadmin.LimitOverride) error {
+	if override.SoftLimit < 0 || override.HardLimit < 0 || override.DebitExposure < 0 || override.CreditExposure < 0 {
+		return errors.New("limits can't be negative")
+	}
+	if override.HardLimit > 0 && override.SoftLimit > override.HardLimit {
+		return fmt.Errorf("softLimit=%d is over hardLimit=%d", override.SoftLimit, override.HardLimit)
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package limiter
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/moov-io/paygate/pkg/admin"
+	"github.com/moov-io/paygate/pkg/config"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdmin__limitOverride(t *testing.T) {
+	repo := &MockRepository{}
+
+	router := mux.NewRouter()
+	router.Handle("/limits/organizations/{id}", limitOverride(config.Empty(), repo, scopeOrganization))
+	router.Handle("/limits/users/{id}", limitOverride(config.Empty(), repo, scopeUser))
+
+	// nothing set
+	req := httptest.NewRequest("GET", "/limits/users/jane", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var override admin.LimitOverride
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&override))
+	require.Equal(t, admin.LimitOverride{Scope: "user", ID: "jane"}, override)
+
+	// update
+	req = httptest.NewRequest("PUT", "/limits/users/jane", strings.NewReader(`{"softLimit": 1000, "hardLimit": 5000, "debitExposure": 20000}`))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, int64(5000), repo.Overrides["user-jane"].HardLimit)
+	require.Nil(t, repo.Overrides["organization-jane"])
+
+	req = httptest.NewRequest("GET", "/limits/users/jane", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&override))
+	require.Equal(t, int64(20000), override.DebitExposure)
+	require.NotNil(t, override.UpdatedAt)
+
+	// invalid
+	for _, body := range []string{`{"softLimit": -1}`, `{"softLimit": 6000, "hardLimit": 5000}`, `{`} {
+		req = httptest.NewRequest("PUT", "/limits/organizations/moov", strings.NewReader(body))
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusBadRequest, w.Code, body)
+	}
+
+	req = httptest.NewRequest("DELETE", "/limits/organizations/moov", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+
+	repo.Err = errors.New("bad error")
+	req = httptest.NewRequest("GET", "/limits/organizations/moov", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,52 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package limiter
+
+import (
+	"time"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+)
+
+// datedLimiter checks each Transfer against the limits in effect on the day it was created,
+// so limit changes can be staged in the config before they apply.
+type datedLimiter struct {
+	cfg config.Limits
+}
+
+func newDatedLimiter(cfg config.Limits) (Checker, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return &datedLimiter{cfg: cfg}, nil
+}
+
+func (l *datedLimiter) checker(xfer *client.Transfer) (Checker, error) {
+	day := xfer.Created
+	if day.IsZero() {
+		day = time.Now()
+	}
+	return New(l.cfg.On(day), nil)
+}
+
+func (l *datedLimiter) Accept(organization, userID string, xfer *client.Transfer) error {
+	checker, err := l.checker(xfer)
+	if err != nil {
+		return err
+	}
+	return checker.Accept(organization, userID, xfer)
+}
+
+func (l *datedLimiter) Warnings(organization, userID string, xfer *client.Transfer) []client.LimitWarning {
+	checker, err := l.checker(xfer)
+	if err != nil {
+		return nil
+	}
+	if warner, ok := checker.(Warner); ok {
+		return warner.Warnings(organization, userID, xfer)
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package limiter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/moov-io/base"
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+)
+
+func TestDatedLimiter(t *testing.T) {
+	limit, err := New(config.Limits{
+		Fixed: &config.FixedLimits{
+			SoftLimit: 111,
+			HardLimit: 222,
+		},
+		Changes: []config.LimitsChange{
+			{
+				ValidFrom: "2021-03-19",
+				Fixed: &config.FixedLimits{
+					SoftLimit:   1000,
+					HardLimit:   2000,
+					WarnPercent: 90,
+				},
+			},
+		},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	organization := base.ID()
+	xfer := &client.Transfer{
+		Amount: client.Amount{
+			Currency: "USD",
+			Value:    950,
+		},
+		Created: time.Date(2021, time.March, 18, 10, 0, 0, 0, time.UTC),
+	}
+	// rejected under the current limits
+	if err := limit.Accept(organization, "", xfer); err == nil {
+		t.Error("expected error")
+	}
+
+	// accepted once the staged limits are effective
+	xfer.Created = time.Date(2021, time.March, 19, 10, 0, 0, 0, time.UTC)
+	if err := limit.Accept(organization, "", xfer); err != nil {
+		t.Fatal(err)
+	}
+	warner, ok := limit.(Warner)
+	if !ok {
+		t.Fatalf("unexpected %T", limit)
+	}
+	if warnings := warner.Warnings(organization, "", xfer); len(warnings) != 1 || warnings[0].LimitAmount != 1000 {
+		t.Errorf("unexpected warnings: %#v", warnings)
+	}
+}
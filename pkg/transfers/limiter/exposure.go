@@ -0,0 +1,168 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package limiter
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/moov-io/ach"
+
+	"github.com/moov-io/paygate/pkg/achx"
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+)
+
+const (
+	directionDebit  = "debit"
+	directionCredit = "credit"
+
+	scopeOrganization = "organization"
+	scopeCustomer     = "customer"
+	scopeUser         = "user"
+)
+
+// Exposure enforces rolling limits on the debits and credits originated for each
+// organization, customer and user. Whether a Transfer debits or credits a remote
+// account is only known once its files are originated, so those are checked.
+//
+// A nil *Exposure never rejects Transfers.
+type Exposure struct {
+	cfg               *config.ExposureLimits
+	odfiRoutingNumber string
+
+	repo      ExposureRepository
+	overrides *Overrides
+}
+
+// NewExposure returns nil when no exposure limits are configured. Organization and user
+// limits are replaced by their overrides when overrides is non-nil.
+func NewExposure(cfg *config.Config, repo ExposureRepository, overrides *Overrides) (*Exposure, error) {
+	limits := cfg.Transfers.Limits.Exposure
+	if limits == nil {
+		return nil, nil
+	}
+	if err := limits.Validate(); err != nil {
+		return nil, err
+	}
+	return &Exposure{
+		cfg:               limits,
+		odfiRoutingNumber: cfg.ODFI.RoutingNumber,
+		repo:              repo,
+		overrides:         overrides,
+	}, nil
+}
+
+// CheckFiles returns an error wrapping ErrDebitExposure or ErrCreditExposure when the files
+// would put any scope over its limit. Accepted Transfers are recorded towards each scope.
+func (e *Exposure) CheckFiles(organization, userID string, xfer *client.Transfer, files []*ach.File) error {
+	if e == nil || xfer == nil {
+		return nil
+	}
+	debits, credits := RemoteAmounts(e.odfiRoutingNumber, files)
+	since := time.Now().Add(-1 * e.cfg.RollingWindow())
+
+	var pending []exposure
+	if debits > 0 {
+		exp := exposure{
+			transferID:   xfer.TransferID,
+			direction:    directionDebit,
+			organization: organization,
+			customerID:   xfer.Source.CustomerID,
+			userID:       userID,
+			amount:       debits,
+		}
+		if err := e.check(exp, e.cfg.Debits, since, ErrDebitExposure); err != nil {
+			return err
+		}
+		pending = append(pending, exp)
+	}
+	if credits > 0 {
+		exp := exposure{
+			transferID:   xfer.TransferID,
+			direction:    directionCredit,
+			organization: organization,
+			customerID:   xfer.Destination.CustomerID,
+			userID:       userID,
+			amount:       credits,
+		}
+		if err := e.check(exp, e.cfg.Credits, since, ErrCreditExposure); err != nil {
+			return err
+		}
+		pending = append(pending, exp)
+	}
+	for i := range pending {
+		if err := e.repo.recordExposure(pending[i], time.Now()); err != nil {
+			return fmt.Errorf("exposureLimiter: recording %s: %v", pending[i].direction, err)
+		}
+	}
+	return nil
+}
+
+func (e *Exposure) check(exp exposure, limits config.ExposureLimit, since time.Time, reject error) error {
+	scopes := []struct {
+		name, value string
+		limit       int64
+	}{
+		{scopeOrganization, exp.organization, limits.Organization},
+		{scopeCustomer, exp.customerID, limits.Customer},
+		{scopeUser, exp.userID, limits.User},
+	}
+	for _, scope := range scopes {
+		limit, err := e.limitFor(scope.name, scope.value, exp.direction, scope.limit)
+		if err != nil {
+			return fmt.Errorf("exposureLimiter: reading %s limit override: %v", scope.name, err)
+		}
+		scope.limit = limit
+		if scope.limit <= 0 || scope.value == "" {
+			continue
+		}
+		current, err := e.repo.exposure(exp.direction, scope.name, scope.value, since)
+		if err != nil {
+			return fmt.Errorf("exposureLimiter: reading %s %s exposure: %v", scope.name, exp.direction, err)
+		}
+		rule := exp.direction + "_" + scope.name
+		total := current + exp.amount
+		recordUsage(rule, scope.limit, total)
+
+		if total > scope.limit {
+			recordDecision(rule, decisionReject)
+			return fmt.Errorf("exposureLimiter: %w: %s %s exposure of %d would exceed %d", reject, scope.name, exp.direction, total, scope.limit)
+		}
+	}
+	return nil
+}
+
+// limitFor returns a scope's limit in one direction. Customers can't have overrides.
+func (e *Exposure) limitFor(scope, value, direction string, def int64) (int64, error) {
+	if scope == scopeCustomer {
+		return def, nil
+	}
+	return e.overrides.exposure(scope, value, direction, def)
+}
+
+// RemoteAmounts sums the debits and credits files make to accounts outside of the ODFI.
+// Offsetting entries to the ODFI's own accounts and prenotes aren't counted.
+func RemoteAmounts(odfiRoutingNumber string, files []*ach.File) (debits int64, credits int64) {
+	odfi := achx.ABA8(odfiRoutingNumber)
+	for i := range files {
+		if files[i] == nil {
+			continue
+		}
+		entries := achx.Entries(files[i])
+		for k := range entries {
+			if entries[k].RDFIIdentification == odfi {
+				continue
+			}
+			switch entries[k].CreditOrDebit() {
+			case "D":
+				debits += int64(entries[k].Amount)
+			case "C":
+				credits += int64(entries[k].Amount)
+			}
+		}
+	}
+	return debits, credits
+}
@@ -0,0 +1,177 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package limiter
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/moov-io/ach"
+	"github.com/moov-io/base"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/database"
+
+	"github.com/stretchr/testify/require"
+)
+
+func readFile(t *testing.T, credit bool) *ach.File {
+	t.Helper()
+
+	file, err := ach.ReadFile(filepath.Join("..", "..", "..", "testdata", "ppd-debit.ach"))
+	require.NoError(t, err)
+	if credit {
+		file.Batches[0].GetEntries()[0].TransactionCode = ach.CheckingCredit
+	}
+	return file
+}
+
+func exposureConfig(limits *config.ExposureLimits) *config.Config {
+	cfg := config.Empty()
+	cfg.ODFI.RoutingNumber = "076401251"
+	cfg.Transfers.Limits.Exposure = limits
+	return cfg
+}
+
+func TestExposure__nil(t *testing.T) {
+	exp, err := NewExposure(config.Empty(), &MockRepository{}, nil)
+	require.NoError(t, err)
+	require.Nil(t, exp)
+	require.NoError(t, exp.CheckFiles("org", "", &client.Transfer{}, nil))
+
+	_, err = NewExposure(exposureConfig(&config.ExposureLimits{Window: -time.Hour}), &MockRepository{}, nil)
+	require.Error(t, err)
+}
+
+func TestExposure__CheckFiles(t *testing.T) {
+	repo := &MockRepository{}
+	exp, err := NewExposure(exposureConfig(&config.ExposureLimits{
+		Debits: config.ExposureLimit{
+			Customer: 20000,
+		},
+		Credits: config.ExposureLimit{
+			Organization: 5000,
+		},
+	}), repo, nil)
+	require.NoError(t, err)
+
+	xfer := &client.Transfer{
+		TransferID: base.ID(),
+		Source:     client.Source{CustomerID: base.ID()},
+	}
+
+	// first debit of $105 is accepted and recorded
+	require.NoError(t, exp.CheckFiles("org", "", xfer, []*ach.File{readFile(t, false)}))
+	require.Equal(t, int64(10500), repo.Totals["debit-customer"])
+
+	// the second would put the customer over $200
+	err = exp.CheckFiles("org", "", xfer, []*ach.File{readFile(t, false)})
+	require.True(t, errors.Is(err, ErrDebitExposure))
+	require.Contains(t, err.Error(), "customer debit exposure of 21000 would exceed 20000")
+
+	// credits are limited separately
+	err = exp.CheckFiles("org", "", xfer, []*ach.File{readFile(t, true)})
+	require.True(t, errors.Is(err, ErrCreditExposure))
+	require.False(t, errors.Is(err, ErrDebitExposure))
+
+	repo.Err = errors.New("bad error")
+	require.Error(t, exp.CheckFiles("org", "", xfer, []*ach.File{readFile(t, false)}))
+}
+
+func TestRemoteAmounts(t *testing.T) {
+	debits, credits := RemoteAmounts("076401251", []*ach.File{readFile(t, false), readFile(t, true), nil})
+	require.Equal(t, int64(10500), debits)
+	require.Equal(t, int64(10500), credits)
+
+	// entries to the ODFI aren't counted
+	debits, credits = RemoteAmounts("053200019", []*ach.File{readFile(t, false)})
+	require.Equal(t, int64(0), debits)
+	require.Equal(t, int64(0), credits)
+}
+
+func TestRepository__exposure(t *testing.T) {
+	t.Parallel()
+
+	check := func(t *testing.T, repo *sqlRepo) {
+		customerID, userID := base.ID(), base.ID()
+		since := time.Now().Add(-1 * time.Hour)
+
+		total, err := repo.exposure(directionDebit, scopeCustomer, customerID, since)
+		require.NoError(t, err)
+		require.Equal(t, int64(0), total)
+
+		for _, amount := range []int64{100, 250} {
+			require.NoError(t, repo.recordExposure(exposure{
+				transferID:   base.ID(),
+				direction:    directionDebit,
+				organization: "org",
+				customerID:   customerID,
+				userID:       userID,
+				amount:       amount,
+			}, time.Now()))
+		}
+		// outside the window
+		require.NoError(t, repo.recordExposure(exposure{
+			transferID: base.ID(),
+			direction:  directionDebit,
+			customerID: customerID,
+			amount:     1000,
+		}, time.Now().Add(-2*time.Hour)))
+
+		total, err = repo.exposure(directionDebit, scopeCustomer, customerID, since)
+		require.NoError(t, err)
+		require.Equal(t, int64(350), total)
+
+		total, err = repo.exposure(directionDebit, scopeUser, userID, since)
+		require.NoError(t, err)
+		require.Equal(t, int64(350), total)
+
+		total, err = repo.exposure(directionCredit, scopeCustomer, customerID, since)
+		require.NoError(t, err)
+		require.Equal(t, int64(0), total)
+
+		_, err = repo.exposure(directionDebit, "other", customerID, since)
+		require.Error(t, err)
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+}
+
+func TestRepository__exposureCanceled(t *testing.T) {
+	repo := setupSQLiteDB(t)
+	transferID, customerID := base.ID(), base.ID()
+
+	require.NoError(t, repo.recordExposure(exposure{
+		transferID: transferID,
+		direction:  directionCredit,
+		customerID: customerID,
+		amount:     500,
+	}, time.Now()))
+
+	_, err := repo.db.Exec(`insert into transfers (transfer_id, status) values (?, ?);`, transferID, client.CANCELED)
+	require.NoError(t, err)
+
+	total, err := repo.exposure(directionCredit, scopeCustomer, customerID, time.Now().Add(-1*time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, int64(0), total)
+}
+
+func setupSQLiteDB(t *testing.T) *sqlRepo {
+	db := database.CreateTestSqliteDB(t)
+	t.Cleanup(func() { db.Close() })
+
+	return NewRepo(db.DB)
+}
+
+func setupMySQLeDB(t *testing.T) *sqlRepo {
+	db := database.CreateTestMySQLDB(t)
+	t.Cleanup(func() { db.Close() })
+
+	return NewRepo(db.DB)
+}
@@ -22,12 +22,43 @@ func newFixedLimiter(cfg *config.FixedLimits) (Checker, error) {
 	return &fixedLimiter{cfg: cfg}, nil
 }
 
-func (l *fixedLimiter) Accept(organization string, xfer *client.Transfer) error {
+func (l *fixedLimiter) Accept(organization, userID string, xfer *client.Transfer) error {
+	recordUtilization(ruleSoft, l.cfg.SoftLimit, xfer.Amount)
+	recordUtilization(ruleHard, l.cfg.HardLimit, xfer.Amount)
+
 	if l.cfg.OverHardLimit(xfer.Amount) {
-		return fmt.Errorf("fixedLimiter: %v", ErrOverLimits)
+		recordDecision(ruleHard, decisionReject)
+		return fmt.Errorf("fixedLimiter: %w", ErrOverLimits)
 	}
 	if l.cfg.OverSoftLimit(xfer.Amount) {
-		return fmt.Errorf("fixedLimiter: %v", ErrReviewableTransfer)
+		recordDecision(ruleSoft, decisionReview)
+		return fmt.Errorf("fixedLimiter: %w", ErrReviewableTransfer)
 	}
+	recordDecision(ruleNone, decisionAccept)
 	return nil
 }
+
+// Warnings returns a warning for each limit the Transfer uses more than WarnPercent of.
+func (l *fixedLimiter) Warnings(organization, userID string, xfer *client.Transfer) []client.LimitWarning {
+	var out []client.LimitWarning
+	if w := l.warning("soft", l.cfg.SoftLimit, xfer.Amount); w != nil {
+		out = append(out, *w)
+	}
+	if w := l.warning("hard", l.cfg.HardLimit, xfer.Amount); w != nil {
+		out = append(out, *w)
+	}
+	return out
+}
+
+func (l *fixedLimiter) warning(name string, limit int64, amt client.Amount) *client.LimitWarning {
+	if !l.cfg.NearLimit(limit, amt) {
+		return nil
+	}
+	remaining := limit - int64(amt.Value)
+	return &client.LimitWarning{
+		Limit:       name,
+		LimitAmount: limit,
+		Remaining:   remaining,
+		Message:     fmt.Sprintf("transfer uses %d%% of the %s limit, %d remaining", int64(amt.Value)*100/limit, name, remaining),
+	}
+}
@@ -30,7 +30,7 @@ func TestFixedLimiter(t *testing.T) {
 		},
 	}
 	// successful transfer
-	if err := limit.Accept(organization, xfer); err != nil {
+	if err := limit.Accept(organization, "", xfer); err != nil {
 		t.Fatal(err)
 	}
 
@@ -39,7 +39,7 @@ func TestFixedLimiter(t *testing.T) {
 		Currency: "USD",
 		Value:    133,
 	}
-	if err := limit.Accept(organization, xfer); err != nil {
+	if err := limit.Accept(organization, "", xfer); err != nil {
 		if !strings.Contains(err.Error(), ErrReviewableTransfer.Error()) {
 			t.Fatalf("unexpected error: %q", err)
 		}
@@ -50,13 +50,50 @@ func TestFixedLimiter(t *testing.T) {
 		Currency: "USD",
 		Value:    456,
 	}
-	if err := limit.Accept(organization, xfer); err != nil {
+	if err := limit.Accept(organization, "", xfer); err != nil {
 		if !strings.Contains(err.Error(), ErrOverLimits.Error()) {
 			t.Fatalf("unexpected error: %q", err)
 		}
 	}
 }
 
+func TestFixedLimiter__Warnings(t *testing.T) {
+	limit, err := newFixedLimiter(&config.FixedLimits{
+		SoftLimit:   1000,
+		HardLimit:   5000,
+		WarnPercent: 90,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	warner, ok := limit.(Warner)
+	if !ok {
+		t.Fatalf("unexpected %T", limit)
+	}
+
+	xfer := &client.Transfer{
+		Amount: client.Amount{
+			Currency: "USD",
+			Value:    500,
+		},
+	}
+	if warnings := warner.Warnings("moov", "", xfer); len(warnings) != 0 {
+		t.Errorf("unexpected warnings: %#v", warnings)
+	}
+
+	xfer.Amount.Value = 950
+	warnings := warner.Warnings("moov", "", xfer)
+	if len(warnings) != 1 {
+		t.Fatalf("unexpected warnings: %#v", warnings)
+	}
+	if w := warnings[0]; w.Limit != "soft" || w.LimitAmount != 1000 || w.Remaining != 50 {
+		t.Errorf("unexpected warning: %#v", w)
+	}
+	if !strings.Contains(warnings[0].Message, "95%") {
+		t.Errorf("unexpected message: %q", warnings[0].Message)
+	}
+}
+
 func TestFixedLimiterErr(t *testing.T) {
 	if _, err := newFixedLimiter(&config.FixedLimits{}); err == nil {
 		t.Error("expected error")
@@ -14,13 +14,33 @@ import (
 var (
 	ErrReviewableTransfer = errors.New("require manual review")
 	ErrOverLimits         = errors.New("rejected transfer - over all limits")
+
+	ErrDebitExposure  = errors.New("rejected transfer - over debit exposure limit")
+	ErrCreditExposure = errors.New("rejected transfer - over credit exposure limit")
 )
 
 type Checker interface {
-	Accept(organization string, xfer *client.Transfer) error
+	Accept(organization, userID string, xfer *client.Transfer) error
+}
+
+// Warner is implemented by Checkers which can describe how close an accepted
+// Transfer came to their limits.
+type Warner interface {
+	Warnings(organization, userID string, xfer *client.Transfer) []client.LimitWarning
 }
 
-func New(cfg config.Limits) (Checker, error) {
+// New returns a Checker for the configured limits. Overrides stored for an organization or
+// user are applied when overrides is non-nil.
+func New(cfg config.Limits, overrides *Overrides) (Checker, error) {
+	if overrides != nil {
+		if err := cfg.Validate(); err != nil {
+			return nil, err
+		}
+		return &overridingLimiter{cfg: cfg, overrides: overrides}, nil
+	}
+	if len(cfg.Changes) > 0 {
+		return newDatedLimiter(cfg)
+	}
 	if cfg.Fixed != nil {
 		return newFixedLimiter(cfg.Fixed)
 	}
@@ -30,6 +50,6 @@ func New(cfg config.Limits) (Checker, error) {
 type passingLimiter struct{}
 
 // Accept always returns no error for the passingLimiter
-func (l *passingLimiter) Accept(organization string, xfer *client.Transfer) error {
+func (l *passingLimiter) Accept(organization, userID string, xfer *client.Transfer) error {
 	return nil
 }
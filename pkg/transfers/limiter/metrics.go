@@ -0,0 +1,50 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package limiter
+
+import (
+	"github.com/go-kit/kit/metrics/prometheus"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+
+	"github.com/moov-io/paygate/pkg/client"
+)
+
+var (
+	limiterDecisions = prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+		Name: "limiter_decisions",
+		Help: "Counter of limiter decisions on created transfers",
+	}, []string{"rule", "decision"})
+
+	limiterUtilization = prometheus.NewHistogramFrom(stdprometheus.HistogramOpts{
+		Name:    "limiter_utilization_ratio",
+		Help:    "Histogram of the share of each limit used by created transfers",
+		Buckets: []float64{0.1, 0.25, 0.5, 0.75, 0.9, 1, 1.5, 2},
+	}, []string{"rule"})
+)
+
+const (
+	decisionAccept = "accept"
+	decisionReview = "review"
+	decisionReject = "reject"
+
+	ruleNone = "none"
+	ruleSoft = "soft"
+	ruleHard = "hard"
+)
+
+func recordDecision(rule, decision string) {
+	limiterDecisions.With("rule", rule, "decision", decision).Add(1)
+}
+
+func recordUtilization(rule string, limit int64, amt client.Amount) {
+	recordUsage(rule, limit, int64(amt.Value))
+}
+
+func recordUsage(rule string, limit int64, used int64) {
+	if limit <= 0 {
+		return
+	}
+	limiterUtilization.With("rule", rule).Observe(float64(used) / float64(limit))
+}
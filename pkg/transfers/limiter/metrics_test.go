@@ -0,0 +1,66 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package limiter
+
+import (
+	"testing"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+)
+
+func decisionCount(t *testing.T, rule, decision string) float64 {
+	t.Helper()
+
+	families, err := stdprometheus.DefaultGatherer.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, family := range families {
+		if family.GetName() != "limiter_decisions" {
+			continue
+		}
+		for _, m := range family.GetMetric() {
+			labels := make(map[string]string)
+			for _, l := range m.GetLabel() {
+				labels[l.GetName()] = l.GetValue()
+			}
+			if labels["rule"] == rule && labels["decision"] == decision {
+				return m.GetCounter().GetValue()
+			}
+		}
+	}
+	return 0
+}
+
+func TestMetrics__decisions(t *testing.T) {
+	limit, err := newFixedLimiter(&config.FixedLimits{
+		SoftLimit: 100,
+		HardLimit: 200,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	check := func(value int32, rule, decision string) {
+		t.Helper()
+
+		before := decisionCount(t, rule, decision)
+		limit.Accept("moov", "", &client.Transfer{
+			Amount: client.Amount{Currency: "USD", Value: value},
+		})
+		if after := decisionCount(t, rule, decision); after != before+1 {
+			t.Errorf("%s/%s: before=%v after=%v", rule, decision, before, after)
+		}
+	}
+	check(50, ruleNone, decisionAccept)
+	check(150, ruleSoft, decisionReview)
+	check(250, ruleHard, decisionReject)
+
+	// limits of zero aren't observed
+	recordUtilization(ruleHard, 0, client.Amount{Value: 10})
+}
@@ -0,0 +1,60 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package limiter
+
+import (
+	"time"
+
+	"github.com/moov-io/paygate/pkg/admin"
+)
+
+type MockRepository struct {
+	// Totals holds exposure by direction and scope, such as "debit-customer"
+	Totals map[string]int64
+
+	// Overrides holds limit overrides by scope and ID, such as "user-jane"
+	Overrides map[string]*admin.LimitOverride
+
+	Err error
+}
+
+func (r *MockRepository) exposure(direction, scope, value string, since time.Time) (int64, error) {
+	if r.Err != nil {
+		return 0, r.Err
+	}
+	return r.Totals[direction+"-"+scope], nil
+}
+
+func (r *MockRepository) recordExposure(exp exposure, when time.Time) error {
+	if r.Err != nil {
+		return r.Err
+	}
+	if r.Totals == nil {
+		r.Totals = make(map[string]int64)
+	}
+	for _, scope := range []string{scopeOrganization, scopeCustomer, scopeUser} {
+		r.Totals[exp.direction+"-"+scope] += exp.amount
+	}
+	return nil
+}
+
+func (r *MockRepository) getLimitOverride(scope, id string) (*admin.LimitOverride, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	return r.Overrides[scope+"-"+id], nil
+}
+
+func (r *MockRepository) saveLimitOverride(override admin.LimitOverride, when time.Time) error {
+	if r.Err != nil {
+		return r.Err
+	}
+	if r.Overrides == nil {
+		r.Overrides = make(map[string]*admin.LimitOverride)
+	}
+	override.UpdatedAt = &when
+	r.Overrides[override.Scope+"-"+override.ID] = &override
+	return nil
+}
@@ -0,0 +1,139 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package limiter
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/moov-io/paygate/pkg/admin"
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+)
+
+// Overrides replaces the configured limits for organizations (Originators) and users with
+// values admins store in the database.
+//
+// Fixed limits use the user's override, then their organization's, before the config.
+// Exposure limits of each scope use that scope's own override.
+//
+// A nil *Overrides applies no overrides.
+type Overrides struct {
+	repo OverrideRepository
+}
+
+func NewOverrides(repo OverrideRepository) *Overrides {
+	if repo == nil {
+		return nil
+	}
+	return &Overrides{repo: repo}
+}
+
+func (o *Overrides) get(scope, id string) (*admin.LimitOverride, error) {
+	if o == nil || id == "" {
+		return nil, nil
+	}
+	return o.repo.getLimitOverride(scope, id)
+}
+
+// fixed returns the per-Transfer limits for an organization and user.
+func (o *Overrides) fixed(defaults *config.FixedLimits, organization, userID string) (*config.FixedLimits, error) {
+	org, err := o.get(scopeOrganization, organization)
+	if err != nil {
+		return nil, fmt.Errorf("reading organization limit override: %v", err)
+	}
+	user, err := o.get(scopeUser, userID)
+	if err != nil {
+		return nil, fmt.Errorf("reading user limit override: %v", err)
+	}
+	return overrideFixed(overrideFixed(defaults, org), user), nil
+}
+
+func overrideFixed(limits *config.FixedLimits, override *admin.LimitOverride) *config.FixedLimits {
+	if override == nil || (override.SoftLimit <= 0 && override.HardLimit <= 0) {
+		return limits
+	}
+	var out config.FixedLimits
+	if limits != nil {
+		out = *limits
+	}
+	if override.SoftLimit > 0 {
+		out.SoftLimit = override.SoftLimit
+	}
+	if override.HardLimit > 0 {
+		out.HardLimit = override.HardLimit
+	}
+	// Overriding only the soft limit doesn't reject every Transfer when no hard limit is configured
+	if out.HardLimit <= 0 {
+		out.HardLimit = maxEntryAmount
+	}
+	// Without a soft limit nothing under the hard limit is held for review, and a lower
+	// hard limit than the configured soft limit lowers both.
+	if out.SoftLimit <= 0 || out.SoftLimit > out.HardLimit {
+		out.SoftLimit = out.HardLimit
+	}
+	return &out
+}
+
+// maxEntryAmount is the largest amount (ten digits of cents) an ACH entry can carry, which
+// leaves a hard limit unbounded.
+const maxEntryAmount = 9999999999
+
+// exposure returns the limit for a scope and direction, or def when it isn't overridden.
+func (o *Overrides) exposure(scope, id, direction string, def int64) (int64, error) {
+	override, err := o.get(scope, id)
+	if err != nil || override == nil {
+		return def, err
+	}
+	limit := override.CreditExposure
+	if direction == directionDebit {
+		limit = override.DebitExposure
+	}
+	if limit > 0 {
+		return limit, nil
+	}
+	return def, nil
+}
+
+// overridingLimiter checks each Transfer against the fixed limits in effect on the day it
+// was created after applying overrides.
+type overridingLimiter struct {
+	cfg       config.Limits
+	overrides *Overrides
+}
+
+func (l *overridingLimiter) checker(organization, userID string, xfer *client.Transfer) (Checker, error) {
+	day := xfer.Created
+	if day.IsZero() {
+		day = time.Now()
+	}
+	fixed, err := l.overrides.fixed(l.cfg.On(day).Fixed, organization, userID)
+	if err != nil {
+		return nil, err
+	}
+	if fixed == nil {
+		return &passingLimiter{}, nil
+	}
+	return newFixedLimiter(fixed)
+}
+
+func (l *overridingLimiter) Accept(organization, userID string, xfer *client.Transfer) error {
+	checker, err := l.checker(organization, userID, xfer)
+	if err != nil {
+		return err
+	}
+	return checker.Accept(organization, userID, xfer)
+}
+
+func (l *overridingLimiter) Warnings(organization, userID string, xfer *client.Transfer) []client.LimitWarning {
+	checker, err := l.checker(organization, userID, xfer)
+	if err != nil {
+		return nil
+	}
+	if warner, ok := checker.(Warner); ok {
+		return warner.Warnings(organization, userID, xfer)
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package limiter
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/moov-io/ach"
+	"github.com/moov-io/base"
+	"github.com/moov-io/paygate/pkg/admin"
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOverrides__fixed(t *testing.T) {
+	defaults := &config.FixedLimits{SoftLimit: 1000, HardLimit: 5000, WarnPercent: 90}
+
+	require.Equal(t, defaults, overrideFixed(defaults, nil))
+	require.Equal(t, defaults, overrideFixed(defaults, &admin.LimitOverride{DebitExposure: 100}))
+
+	out := overrideFixed(defaults, &admin.LimitOverride{HardLimit: 9000})
+	require.Equal(t, &config.FixedLimits{SoftLimit: 1000, HardLimit: 9000, WarnPercent: 90}, out)
+	require.Equal(t, int64(5000), defaults.HardLimit)
+
+	// without configured limits nothing under the hard limit is reviewed
+	out = overrideFixed(nil, &admin.LimitOverride{HardLimit: 9000})
+	require.Equal(t, &config.FixedLimits{SoftLimit: 9000, HardLimit: 9000}, out)
+
+	// soft limits alone don't reject Transfers without a configured hard limit
+	out = overrideFixed(nil, &admin.LimitOverride{SoftLimit: 2000})
+	require.Equal(t, &config.FixedLimits{SoftLimit: 2000, HardLimit: maxEntryAmount}, out)
+	checker, err := newFixedLimiter(out)
+	require.NoError(t, err)
+	require.True(t, errors.Is(checker.Accept("moov", "jane", &client.Transfer{Amount: client.Amount{Value: 5000}}), ErrReviewableTransfer))
+	require.NoError(t, checker.Accept("moov", "jane", &client.Transfer{Amount: client.Amount{Value: 1000}}))
+
+	// hard limits under the configured soft limit lower it as well
+	out = overrideFixed(defaults, &admin.LimitOverride{HardLimit: 500})
+	require.Equal(t, &config.FixedLimits{SoftLimit: 500, HardLimit: 500, WarnPercent: 90}, out)
+
+	// users take precedence over their organization
+	repo := &MockRepository{}
+	require.NoError(t, repo.saveLimitOverride(admin.LimitOverride{Scope: scopeOrganization, ID: "moov", SoftLimit: 2000, HardLimit: 8000}, time.Now()))
+	require.NoError(t, repo.saveLimitOverride(admin.LimitOverride{Scope: scopeUser, ID: "jane", SoftLimit: 3000}, time.Now()))
+	overrides := NewOverrides(repo)
+
+	out, err = overrides.fixed(defaults, "moov", "jane")
+	require.NoError(t, err)
+	require.Equal(t, &config.FixedLimits{SoftLimit: 3000, HardLimit: 8000, WarnPercent: 90}, out)
+
+	out, err = overrides.fixed(defaults, "other", "")
+	require.NoError(t, err)
+	require.Equal(t, defaults, out)
+
+	repo.Err = errors.New("bad error")
+	_, err = overrides.fixed(defaults, "moov", "jane")
+	require.Error(t, err)
+
+	// nil overrides keep the defaults
+	var none *Overrides
+	out, err = none.fixed(defaults, "moov", "jane")
+	require.NoError(t, err)
+	require.Equal(t, defaults, out)
+}
+
+func TestOverrides__Accept(t *testing.T) {
+	repo := &MockRepository{}
+	limit, err := New(config.Limits{
+		Fixed: &config.FixedLimits{SoftLimit: 100, HardLimit: 200},
+	}, NewOverrides(repo))
+	require.NoError(t, err)
+
+	xfer := &client.Transfer{
+		Amount: client.Amount{Currency: "USD", Value: 500},
+	}
+	require.True(t, errors.Is(limit.Accept("moov", "jane", xfer), ErrOverLimits))
+
+	require.NoError(t, repo.saveLimitOverride(admin.LimitOverride{Scope: scopeOrganization, ID: "moov", HardLimit: 1000}, time.Now()))
+	require.True(t, errors.Is(limit.Accept("moov", "jane", xfer), ErrReviewableTransfer))
+	require.True(t, errors.Is(limit.Accept("other", "jane", xfer), ErrOverLimits))
+
+	require.NoError(t, repo.saveLimitOverride(admin.LimitOverride{Scope: scopeUser, ID: "jane", SoftLimit: 600}, time.Now()))
+	require.NoError(t, limit.Accept("moov", "jane", xfer))
+	require.True(t, errors.Is(limit.Accept("moov", "john", xfer), ErrReviewableTransfer))
+
+	repo.Err = errors.New("bad error")
+	require.Error(t, limit.Accept("moov", "jane", xfer))
+
+	// without configured limits only overrides are checked
+	repo = &MockRepository{}
+	limit, err = New(config.Limits{}, NewOverrides(repo))
+	require.NoError(t, err)
+	require.NoError(t, limit.Accept("moov", "", xfer))
+
+	require.NoError(t, repo.saveLimitOverride(admin.LimitOverride{Scope: scopeOrganization, ID: "moov", HardLimit: 400}, time.Now()))
+	require.True(t, errors.Is(limit.Accept("moov", "", xfer), ErrOverLimits))
+}
+
+func TestOverrides__exposure(t *testing.T) {
+	repo := &MockRepository{}
+	exp, err := NewExposure(exposureConfig(&config.ExposureLimits{
+		Credits: config.ExposureLimit{
+			Organization: 5000,
+		},
+	}), repo, NewOverrides(repo))
+	require.NoError(t, err)
+
+	xfer := &client.Transfer{
+		TransferID:  base.ID(),
+		Destination: client.Destination{CustomerID: base.ID()},
+	}
+	err = exp.CheckFiles("moov", "jane", xfer, []*ach.File{readFile(t, true)})
+	require.True(t, errors.Is(err, ErrCreditExposure))
+
+	// organizations can be allowed more
+	require.NoError(t, repo.saveLimitOverride(admin.LimitOverride{Scope: scopeOrganization, ID: "moov", CreditExposure: 1000000}, time.Now()))
+	require.NoError(t, exp.CheckFiles("moov", "jane", xfer, []*ach.File{readFile(t, true)}))
+
+	// and users limited to less
+	require.NoError(t, repo.saveLimitOverride(admin.LimitOverride{Scope: scopeUser, ID: "jane", CreditExposure: 100}, time.Now()))
+	err = exp.CheckFiles("moov", "jane", xfer, []*ach.File{readFile(t, true)})
+	require.True(t, errors.Is(err, ErrCreditExposure))
+	require.Contains(t, err.Error(), "user credit exposure")
+}
+
+func TestRepository__limitOverrides(t *testing.T) {
+	t.Parallel()
+
+	check := func(t *testing.T, repo *sqlRepo) {
+		override, err := repo.getLimitOverride(scopeUser, "jane")
+		require.NoError(t, err)
+		require.Nil(t, override)
+
+		require.NoError(t, repo.saveLimitOverride(admin.LimitOverride{Scope: scopeUser, ID: "jane", SoftLimit: 100, HardLimit: 200}, time.Now()))
+		require.NoError(t, repo.saveLimitOverride(admin.LimitOverride{Scope: scopeUser, ID: "jane", DebitExposure: 300}, time.Now()))
+
+		override, err = repo.getLimitOverride(scopeUser, "jane")
+		require.NoError(t, err)
+		require.Equal(t, int64(0), override.SoftLimit)
+		require.Equal(t, int64(300), override.DebitExposure)
+		require.NotNil(t, override.UpdatedAt)
+
+		override, err = repo.getLimitOverride(scopeOrganization, "jane")
+		require.NoError(t, err)
+		require.Nil(t, override)
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+}
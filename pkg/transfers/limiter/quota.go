@@ -0,0 +1,188 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package limiter
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+)
+
+const (
+	scopeODFI      = "odfi"
+	directionTotal = "total"
+)
+
+// OriginationRepository reads how much was uploaded for a routing number each day.
+type OriginationRepository interface {
+	Originated(routingNumber string, day time.Time) (int64, error)
+}
+
+// Quotas reports how much more can be originated before Transfers are rejected by
+// exposure limits or the ODFI's daily origination caps.
+//
+// A nil *Quotas reports no limits.
+type Quotas struct {
+	odfi         config.ODFI
+	exposure     *Exposure
+	originations OriginationRepository
+}
+
+// NewQuotas returns nil when neither exposure limits nor origination caps are configured.
+func NewQuotas(cfg *config.Config, exposure *Exposure, originations OriginationRepository) *Quotas {
+	if exposure == nil && (len(cfg.ODFI.OriginationCaps) == 0 || originations == nil) {
+		return nil
+	}
+	return &Quotas{
+		odfi:         cfg.ODFI,
+		exposure:     exposure,
+		originations: originations,
+	}
+}
+
+// Get returns each limit which applies to the organization, customer and user along with the
+// smallest remaining amount of debits and credits. The customer is optional.
+func (q *Quotas) Get(organization, customerID, userID string, now time.Time) (*client.Quota, error) {
+	out := &client.Quota{
+		Debits:  -1,
+		Credits: -1,
+		Limits:  make([]client.QuotaLimit, 0),
+	}
+	if q == nil {
+		return out, nil
+	}
+	exposure, err := q.exposure.quotaLimits(organization, customerID, userID, now)
+	if err != nil {
+		return nil, err
+	}
+	caps, err := q.originationLimits(organization, now)
+	if err != nil {
+		return nil, err
+	}
+	out.Limits = append(append(out.Limits, exposure...), caps...)
+
+	for i := range out.Limits {
+		limit := out.Limits[i]
+		if limit.Direction != directionCredit {
+			out.Debits = smallerRemaining(out.Debits, limit.Remaining)
+		}
+		if limit.Direction != directionDebit {
+			out.Credits = smallerRemaining(out.Credits, limit.Remaining)
+		}
+	}
+	return out, nil
+}
+
+func smallerRemaining(current, remaining int64) int64 {
+	if current < 0 || remaining < current {
+		return remaining
+	}
+	return current
+}
+
+func newQuotaLimit(scope, id, direction, window string, limit, used int64) client.QuotaLimit {
+	out := client.QuotaLimit{
+		Scope:     scope,
+		ID:        id,
+		Direction: direction,
+		Window:    window,
+		Limit:     limit,
+		Used:      used,
+		Remaining: limit - used,
+	}
+	if out.Remaining < 0 {
+		out.Remaining = 0
+	}
+	return out
+}
+
+// quotaLimits returns the exposure limits of each scope with their usage inside the rolling window.
+func (e *Exposure) quotaLimits(organization, customerID, userID string, now time.Time) ([]client.QuotaLimit, error) {
+	if e == nil {
+		return nil, nil
+	}
+	window := e.cfg.RollingWindow()
+	since := now.Add(-1 * window)
+
+	var out []client.QuotaLimit
+	directions := []struct {
+		name   string
+		limits config.ExposureLimit
+	}{
+		{directionDebit, e.cfg.Debits},
+		{directionCredit, e.cfg.Credits},
+	}
+	for _, direction := range directions {
+		scopes := []struct {
+			name, value string
+			limit       int64
+		}{
+			{scopeOrganization, organization, direction.limits.Organization},
+			{scopeCustomer, customerID, direction.limits.Customer},
+			{scopeUser, userID, direction.limits.User},
+		}
+		for _, scope := range scopes {
+			if scope.value == "" {
+				continue
+			}
+			limit, err := e.limitFor(scope.name, scope.value, direction.name, scope.limit)
+			if err != nil {
+				return nil, fmt.Errorf("reading %s limit override: %v", scope.name, err)
+			}
+			if limit <= 0 {
+				continue
+			}
+			used, err := e.repo.exposure(direction.name, scope.name, scope.value, since)
+			if err != nil {
+				return nil, fmt.Errorf("reading %s %s exposure: %v", scope.name, direction.name, err)
+			}
+			out = append(out, newQuotaLimit(scope.name, scope.value, direction.name, window.String(), limit, used))
+		}
+	}
+	return out, nil
+}
+
+// originationLimits returns the ODFI's daily caps with what was uploaded today. Organizations
+// in a Tenant with its own gateway destination only count against that routing number's cap.
+func (q *Quotas) originationLimits(organization string, now time.Time) ([]client.QuotaLimit, error) {
+	if q.originations == nil {
+		return nil, nil
+	}
+	caps := q.odfi.OriginationCaps
+	if destination := q.odfi.ForOrganization(organization).Gateway.Destination; destination != "" {
+		caps = nil
+		if limit := q.odfi.OriginationCapFor(destination); limit != nil {
+			caps = append(caps, *limit)
+		}
+	}
+	var out []client.QuotaLimit
+	for i := range caps {
+		used, err := q.originations.Originated(caps[i].RoutingNumber, now)
+		if err != nil {
+			return nil, fmt.Errorf("reading origination for %s: %v", caps[i].RoutingNumber, err)
+		}
+		out = append(out, newQuotaLimit(scopeODFI, caps[i].RoutingNumber, directionTotal, "day", caps[i].Daily, used))
+	}
+	return out, nil
+}
+
+// QuotaHeader summarizes the remaining debits and credits of a quota, such as "debit=5000; credit=12000".
+// Directions without limits are left out and an empty string is returned when nothing is limited.
+func QuotaHeader(quota *client.Quota) string {
+	if quota == nil {
+		return ""
+	}
+	var parts []string
+	if quota.Debits >= 0 {
+		parts = append(parts, fmt.Sprintf("%s=%d", directionDebit, quota.Debits))
+	}
+	if quota.Credits >= 0 {
+		parts = append(parts, fmt.Sprintf("%s=%d", directionCredit, quota.Credits))
+	}
+	return strings.Join(parts, "; ")
+}
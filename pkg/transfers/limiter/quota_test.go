@@ -0,0 +1,126 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package limiter
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/moov-io/paygate/pkg/admin"
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+
+	"github.com/stretchr/testify/require"
+)
+
+type mockOriginations struct {
+	Totals map[string]int64
+	Err    error
+}
+
+func (r *mockOriginations) Originated(routingNumber string, day time.Time) (int64, error) {
+	return r.Totals[routingNumber], r.Err
+}
+
+func TestQuotas__nil(t *testing.T) {
+	quotas := NewQuotas(config.Empty(), nil, &mockOriginations{})
+	require.Nil(t, quotas)
+
+	quota, err := quotas.Get("org", "", "jane", time.Now())
+	require.NoError(t, err)
+	require.Equal(t, int64(-1), quota.Debits)
+	require.Equal(t, int64(-1), quota.Credits)
+	require.Empty(t, quota.Limits)
+	require.Equal(t, "", QuotaHeader(quota))
+}
+
+func TestQuotas__Get(t *testing.T) {
+	repo := &MockRepository{
+		Totals: map[string]int64{
+			"debit-organization": 4000,
+			"debit-customer":     1000,
+			"credit-user":        7500,
+		},
+		Overrides: map[string]*admin.LimitOverride{
+			"user-jane": {CreditExposure: 8000},
+		},
+	}
+	cfg := exposureConfig(&config.ExposureLimits{
+		Debits: config.ExposureLimit{
+			Organization: 10000,
+			Customer:     2000,
+		},
+		Credits: config.ExposureLimit{
+			User: 50000,
+		},
+	})
+	cfg.ODFI.OriginationCaps = []config.OriginationCap{
+		{RoutingNumber: "987654320", Daily: 100000},
+	}
+	exp, err := NewExposure(cfg, repo, NewOverrides(repo))
+	require.NoError(t, err)
+
+	originations := &mockOriginations{Totals: map[string]int64{"987654320": 99000}}
+	quotas := NewQuotas(cfg, exp, originations)
+
+	// without a customer only organization, user and ODFI limits apply
+	quota, err := quotas.Get("org", "", "jane", time.Now())
+	require.NoError(t, err)
+	require.Len(t, quota.Limits, 3)
+	require.Equal(t, client.QuotaLimit{
+		Scope:     "organization",
+		ID:        "org",
+		Direction: "debit",
+		Window:    "24h0m0s",
+		Limit:     10000,
+		Used:      4000,
+		Remaining: 6000,
+	}, quota.Limits[0])
+	require.Equal(t, int64(500), quota.Limits[1].Remaining) // user override
+	require.Equal(t, "odfi", quota.Limits[2].Scope)
+	require.Equal(t, int64(1000), quota.Limits[2].Remaining)
+
+	require.Equal(t, int64(1000), quota.Debits)
+	require.Equal(t, int64(500), quota.Credits)
+	require.Equal(t, "debit=1000; credit=500", QuotaHeader(quota))
+
+	// the customer's limit is tighter
+	quota, err = quotas.Get("org", "customer", "jane", time.Now())
+	require.NoError(t, err)
+	require.Len(t, quota.Limits, 4)
+	require.Equal(t, int64(1000), quota.Debits)
+
+	originations.Err = errors.New("bad error")
+	_, err = quotas.Get("org", "", "jane", time.Now())
+	require.Error(t, err)
+}
+
+func TestQuotas__tenantDestination(t *testing.T) {
+	cfg := config.Empty()
+	cfg.ODFI.OriginationCaps = []config.OriginationCap{
+		{RoutingNumber: "987654320", Daily: 100000},
+		{RoutingNumber: "121042882", Daily: 5000},
+	}
+	cfg.ODFI.Tenants = []config.Tenant{
+		{
+			Name:          "acme",
+			Organizations: []string{"acme"},
+			Gateway:       &config.Gateway{Destination: "121042882"},
+		},
+	}
+	quotas := NewQuotas(cfg, nil, &mockOriginations{})
+
+	quota, err := quotas.Get("moov", "", "", time.Now())
+	require.NoError(t, err)
+	require.Len(t, quota.Limits, 2)
+
+	quota, err = quotas.Get("acme", "", "", time.Now())
+	require.NoError(t, err)
+	require.Len(t, quota.Limits, 1)
+	require.Equal(t, "121042882", quota.Limits[0].ID)
+	require.Equal(t, int64(5000), quota.Debits)
+	require.Equal(t, int64(5000), quota.Credits)
+}
@@ -0,0 +1,112 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package limiter
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/moov-io/paygate/pkg/admin"
+	"github.com/moov-io/paygate/pkg/client"
+)
+
+type ExposureRepository interface {
+	// exposure returns the total of direction recorded for the scope since the given time
+	exposure(direction, scope, value string, since time.Time) (int64, error)
+	recordExposure(exp exposure, when time.Time) error
+}
+
+// OverrideRepository stores the limits admins set for organizations and users.
+type OverrideRepository interface {
+	// getLimitOverride returns nil when the organization or user has no override
+	getLimitOverride(scope, id string) (*admin.LimitOverride, error)
+	saveLimitOverride(override admin.LimitOverride, when time.Time) error
+}
+
+type exposure struct {
+	transferID   string
+	direction    string
+	organization string
+	customerID   string
+	userID       string
+	amount       int64
+}
+
+func NewRepo(db *sql.DB) *sqlRepo {
+	return &sqlRepo{db: db}
+}
+
+type sqlRepo struct {
+	db *sql.DB
+}
+
+func (r *sqlRepo) Close() error {
+	return r.db.Close()
+}
+
+var exposureColumns = map[string]string{
+	scopeOrganization: "organization",
+	scopeCustomer:     "customer_id",
+	scopeUser:         "user_id",
+}
+
+// exposure skips Transfers which were canceled, failed or deleted as they won't be sent.
+func (r *sqlRepo) exposure(direction, scope, value string, since time.Time) (int64, error) {
+	column, exists := exposureColumns[scope]
+	if !exists {
+		return 0, fmt.Errorf("unknown scope %q", scope)
+	}
+	query := fmt.Sprintf(`select coalesce(sum(e.amount), 0) from transfer_exposures as e
+where e.direction = ? and e.%s = ? and e.created_at > ? and not exists (
+  select 1 from transfers as xf where xf.transfer_id = e.transfer_id and (xf.status in (?, ?) or xf.deleted_at is not null)
+);`, column)
+
+	var total int64
+	err := r.db.QueryRow(query, direction, value, since, client.CANCELED, client.FAILED).Scan(&total)
+	return total, err
+}
+
+func (r *sqlRepo) recordExposure(exp exposure, when time.Time) error {
+	query := `insert into transfer_exposures (transfer_id, direction, organization, customer_id, user_id, amount, created_at) values (?, ?, ?, ?, ?, ?, ?);`
+	_, err := r.db.Exec(query, exp.transferID, exp.direction, exp.organization, exp.customerID, exp.userID, exp.amount, when)
+	return err
+}
+
+func (r *sqlRepo) getLimitOverride(scope, id string) (*admin.LimitOverride, error) {
+	query := `select soft_limit, hard_limit, debit_exposure, credit_exposure, updated_at from limit_overrides where scope = ? and scope_id = ? limit 1;`
+	override := admin.LimitOverride{
+		Scope: scope,
+		ID:    id,
+	}
+	var updatedAt time.Time
+	err := r.db.QueryRow(query, scope, id).Scan(&override.SoftLimit, &override.HardLimit, &override.DebitExposure, &override.CreditExposure, &updatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	override.UpdatedAt = &updatedAt
+	return &override, nil
+}
+
+// saveLimitOverride replaces any existing override for the scope
+func (r *sqlRepo) saveLimitOverride(override admin.LimitOverride, when time.Time) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`delete from limit_overrides where scope = ? and scope_id = ?;`, override.Scope, override.ID); err != nil {
+		tx.Rollback()
+		return err
+	}
+	query := `insert into limit_overrides (scope, scope_id, soft_limit, hard_limit, debit_exposure, credit_exposure, updated_at) values (?, ?, ?, ?, ?, ?, ?);`
+	if _, err := tx.Exec(query, override.Scope, override.ID, override.SoftLimit, override.HardLimit, override.DebitExposure, override.CreditExposure, when); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
@@ -5,21 +5,80 @@
 package transfers
 
 import (
+	"encoding/json"
+	"fmt"
 	"time"
 
+	"github.com/moov-io/paygate/pkg/admin"
 	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/transfers/history"
 )
 
 type MockRepository struct {
 	Transfers []*client.Transfer
-	Err       error
+	History   []*client.TransferChange
+	Held      []heldLeg
+	Views     []*client.TransferView
+	Schedules []*client.ScheduledTransfer
+	Skipped   []skippedRow
+	Integrity *admin.IntegrityReport
+
+	Representment *client.Representment
+
+	InboundRecords []*client.InboundRecord
+	Candidates     []*ReturnCandidate
+	Ambiguous      []*admin.AmbiguousReturn
+	Files          []*client.TransferFile
+
+	Reversals map[string]string // transferID to reversalID
+
+	IdempotencyKeys map[string]string // idempotency key to batchID
+	BatchResponses  map[string][]byte // batchID to the batch's response
+	TransferKeys    map[string][]byte // idempotency key to the created Transfer's response
+	PurgedBefore    time.Time
+
+	Authorizations []*client.DebitAuthorization
+	Reviewed       []string // transferIDs moved into review by revocations
+
+	Corrections []*client.AccountCorrection
+
+	Suspended []string // customerIDs suspended after an OFAC match
+	Verified  []string // customerIDs who verified their email
+	Deceased  []string // customerIDs with a death notification
+
+	EntryAccounts      []EntryAccount
+	DeathNotifications []*DeathNotification
+	CanceledTransfers  []string // transferIDs canceled by death notifications
+
+	Organization string
+
+	Err error
+}
+
+func (r *MockRepository) getTransfers(organization string, params transferFilterParams) ([]*client.Transfer, []skippedRow, error) {
+	if r.Err != nil {
+		return nil, nil, r.Err
+	}
+	return r.Transfers, r.Skipped, nil
+}
+
+func (r *MockRepository) getRepresentment(organization string, xfer *client.Transfer) (*client.Representment, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	return r.Representment, nil
 }
 
-func (r *MockRepository) getTransfers(organization string, params transferFilterParams) ([]*client.Transfer, error) {
+func (r *MockRepository) streamTransfers(organization string, params transferFilterParams, fn func(*client.Transfer) error) ([]skippedRow, error) {
 	if r.Err != nil {
 		return nil, r.Err
 	}
-	return r.Transfers, nil
+	for i := range r.Transfers {
+		if err := fn(r.Transfers[i]); err != nil {
+			return r.Skipped, err
+		}
+	}
+	return r.Skipped, nil
 }
 
 func (r *MockRepository) GetTransfer(id string) (*client.Transfer, error) {
@@ -32,7 +91,14 @@ func (r *MockRepository) GetTransfer(id string) (*client.Transfer, error) {
 	return nil, nil
 }
 
-func (r *MockRepository) UpdateTransferStatus(transferID string, status client.TransferStatus) error {
+func (r *MockRepository) GetTransferOrganization(transferID string) (string, error) {
+	if r.Err != nil {
+		return "", r.Err
+	}
+	return r.Organization, nil
+}
+
+func (r *MockRepository) UpdateTransferStatus(transferID string, status client.TransferStatus, actor history.Actor) error {
 	return r.Err
 }
 
@@ -40,7 +106,7 @@ func (r *MockRepository) WriteUserTransfer(organization string, transfer *client
 	return r.Err
 }
 
-func (r *MockRepository) deleteUserTransfer(organization string, transferID string) error {
+func (r *MockRepository) deleteUserTransfer(organization string, transferID string, cancel client.CancelTransfer) error {
 	return r.Err
 }
 
@@ -52,6 +118,37 @@ func (r *MockRepository) saveTraceNumbers(transferID string, traceNumbers []stri
 	return r.Err
 }
 
+func (r *MockRepository) saveEntryTotals(transferID string, secCode string, debits, credits int64) error {
+	return r.Err
+}
+
+func (r *MockRepository) saveTransferLegs(transferID string, legs []client.TransferLeg) error {
+	return r.Err
+}
+
+func (r *MockRepository) getHeldCreditLegs() ([]heldLeg, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	return r.Held, nil
+}
+
+func (r *MockRepository) getHeldCreditLeg(transferID string) (*heldLeg, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	for i := range r.Held {
+		if r.Held[i].TransferID == transferID {
+			return &r.Held[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *MockRepository) updateTransferLeg(transferID string, leg string, from, to client.TransferLegStatus, actor history.Actor) error {
+	return r.Err
+}
+
 func (r *MockRepository) LookupTransferFromReturn(amount client.Amount, traceNumber string, effectiveEntryDate time.Time) (*client.Transfer, error) {
 	if r.Err != nil {
 		return nil, r.Err
@@ -62,9 +159,504 @@ func (r *MockRepository) LookupTransferFromReturn(amount client.Amount, traceNum
 	return nil, nil
 }
 
+func (r *MockRepository) LookupTransferFromTraceNumber(traceNumber string) (*client.Transfer, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	if len(r.Transfers) > 0 {
+		return r.Transfers[0], nil
+	}
+	return nil, nil
+}
+
+func (r *MockRepository) LookupReturnCandidates(routingNumber string, accountNumber string, start, end time.Time) ([]*ReturnCandidate, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	return r.Candidates, nil
+}
+
+func (r *MockRepository) SaveAmbiguousReturn(record *client.InboundRecord, candidates []admin.ReturnCandidate) error {
+	if err := r.SaveInboundRecord(record); err != nil {
+		return err
+	}
+	r.Ambiguous = append(r.Ambiguous, &admin.AmbiguousReturn{
+		Record: admin.InboundRecord{
+			RecordID:    record.RecordID,
+			Type:        record.Type,
+			Code:        record.Code,
+			TraceNumber: record.TraceNumber,
+			EntryDetail: record.EntryDetail,
+			Addenda:     record.Addenda,
+			Created:     record.Created,
+		},
+		Candidates: candidates,
+		Status:     AmbiguousReturnPending,
+	})
+	return nil
+}
+
+func (r *MockRepository) ListAmbiguousReturns(status string) ([]*admin.AmbiguousReturn, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	var out []*admin.AmbiguousReturn
+	for i := range r.Ambiguous {
+		if status == "" || r.Ambiguous[i].Status == status {
+			out = append(out, r.Ambiguous[i])
+		}
+	}
+	return out, nil
+}
+
+func (r *MockRepository) GetAmbiguousReturn(recordID string) (*admin.AmbiguousReturn, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	for i := range r.Ambiguous {
+		if r.Ambiguous[i].Record.RecordID == recordID {
+			return r.Ambiguous[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *MockRepository) ResolveAmbiguousReturn(recordID string, transferID string, when time.Time) error {
+	ret, err := r.GetAmbiguousReturn(recordID)
+	if err != nil {
+		return err
+	}
+	if ret == nil || ret.Status != AmbiguousReturnPending {
+		return fmt.Errorf("recordID=%s is not a pending ambiguous return", recordID)
+	}
+	ret.Status = AmbiguousReturnDismissed
+	if transferID != "" {
+		ret.Status = AmbiguousReturnResolved
+		ret.TransferID = transferID
+		ret.Record.TransferID = transferID
+	}
+	ret.Resolved = &when
+	return nil
+}
+
 func (r *MockRepository) getTraceNumbers(transferID string) ([]string, error) {
 	return []string{
 		"123",
 		"245",
 	}, nil
 }
+
+func (r *MockRepository) getTransferHistory(orgID string, transferID string) ([]*client.TransferChange, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	return r.History, nil
+}
+
+func (r *MockRepository) saveTransferTags(orgID string, transferID string, tags []string) error {
+	return r.Err
+}
+
+func (r *MockRepository) getTransferViews(orgID string, userID string) ([]*client.TransferView, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	return r.Views, nil
+}
+
+func (r *MockRepository) getTransferView(orgID string, userID string, viewID string) (*client.TransferView, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	for i := range r.Views {
+		if r.Views[i].ViewID == viewID {
+			return r.Views[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *MockRepository) createTransferView(orgID string, userID string, view *client.TransferView) error {
+	return r.Err
+}
+
+func (r *MockRepository) deleteTransferView(orgID string, userID string, viewID string) error {
+	return r.Err
+}
+
+func (r *MockRepository) getScheduledTransfers(orgID string) ([]*client.ScheduledTransfer, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	return r.Schedules, nil
+}
+
+func (r *MockRepository) getScheduledTransfer(orgID string, scheduleID string) (*client.ScheduledTransfer, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	for i := range r.Schedules {
+		if r.Schedules[i].ScheduleID == scheduleID {
+			return r.Schedules[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *MockRepository) createScheduledTransfer(orgID string, userID string, schedule *client.ScheduledTransfer) error {
+	return r.Err
+}
+
+func (r *MockRepository) updateScheduledTransferStatus(orgID string, scheduleID string, from client.ScheduleStatus, run scheduledRun) error {
+	return r.Err
+}
+
+func (r *MockRepository) getDueScheduledTransfers(now time.Time) ([]dueSchedule, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	var out []dueSchedule
+	for i := range r.Schedules {
+		if r.Schedules[i].Status == client.SCHEDULESTATUS_ACTIVE && !r.Schedules[i].NextRun.After(now) {
+			out = append(out, dueSchedule{schedule: r.Schedules[i]})
+		}
+	}
+	return out, nil
+}
+
+func (r *MockRepository) claimScheduledRun(scheduleID string, runCount int, run scheduledRun) (bool, error) {
+	return r.Err == nil, r.Err
+}
+
+func (r *MockRepository) recordScheduledRun(scheduleID string, transferID string, lastError string, when time.Time) error {
+	return r.Err
+}
+
+func (r *MockRepository) CheckIntegrity() (*admin.IntegrityReport, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	return r.Integrity, nil
+}
+
+func (r *MockRepository) SaveInboundRecord(record *client.InboundRecord) error {
+	if r.Err != nil {
+		return r.Err
+	}
+	r.InboundRecords = append(r.InboundRecords, record)
+	return nil
+}
+
+func (r *MockRepository) getInboundRecords(orgID string, transferID string) ([]*client.InboundRecord, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	return r.InboundRecords, nil
+}
+
+func (r *MockRepository) getTransferFiles(orgID string, transferID string) ([]*client.TransferFile, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	return r.Files, nil
+}
+
+func (r *MockRepository) saveReversal(transferID string, reversalID string) error {
+	if r.Err != nil {
+		return r.Err
+	}
+	if r.Reversals == nil {
+		r.Reversals = make(map[string]string)
+	}
+	r.Reversals[transferID] = reversalID
+	return nil
+}
+
+func (r *MockRepository) getReversal(transferID string) (string, string, error) {
+	if r.Err != nil {
+		return "", "", r.Err
+	}
+	for original, reversal := range r.Reversals {
+		if original == transferID {
+			return "", reversal, nil
+		}
+		if reversal == transferID {
+			return original, "", nil
+		}
+	}
+	return "", "", nil
+}
+
+func (r *MockRepository) writeTransferBatch(orgID string, batch []batchedTransfer) error {
+	return r.Err
+}
+
+func (r *MockRepository) saveTransferBatch(orgID string, batchID string, idempotencyKey string, now time.Time) (bool, []byte, error) {
+	if r.Err != nil {
+		return false, nil, r.Err
+	}
+	if idempotencyKey == "" {
+		return true, nil, nil
+	}
+	if existing, exists := r.IdempotencyKeys[idempotencyKey]; exists {
+		return false, r.BatchResponses[existing], nil
+	}
+	if r.IdempotencyKeys == nil {
+		r.IdempotencyKeys = make(map[string]string)
+	}
+	r.IdempotencyKeys[idempotencyKey] = batchID
+	return true, nil, nil
+}
+
+func (r *MockRepository) saveTransferBatchResponse(batchID string, batch *client.TransferBatch) error {
+	if r.Err != nil {
+		return r.Err
+	}
+	bs, err := json.Marshal(batch)
+	if err != nil {
+		return err
+	}
+	if r.BatchResponses == nil {
+		r.BatchResponses = make(map[string][]byte)
+	}
+	r.BatchResponses[batchID] = bs
+	return nil
+}
+
+func (r *MockRepository) releaseTransferBatch(batchID string) error {
+	if r.Err != nil {
+		return r.Err
+	}
+	if len(r.BatchResponses[batchID]) > 0 {
+		return nil
+	}
+	for key, id := range r.IdempotencyKeys {
+		if id == batchID {
+			delete(r.IdempotencyKeys, key)
+		}
+	}
+	return nil
+}
+
+func (r *MockRepository) claimIdempotencyKey(orgID, userID, key string, now time.Time) (bool, []byte, error) {
+	if r.Err != nil {
+		return false, nil, r.Err
+	}
+	if response, exists := r.TransferKeys[key]; exists {
+		return false, response, nil
+	}
+	if r.TransferKeys == nil {
+		r.TransferKeys = make(map[string][]byte)
+	}
+	r.TransferKeys[key] = nil
+	return true, nil, nil
+}
+
+func (r *MockRepository) saveIdempotentTransfer(orgID, userID, key string, transfer *client.Transfer) error {
+	if r.Err != nil {
+		return r.Err
+	}
+	bs, err := json.Marshal(transfer)
+	if err != nil {
+		return err
+	}
+	r.TransferKeys[key] = bs
+	return nil
+}
+
+func (r *MockRepository) releaseIdempotencyKey(orgID, userID, key string) error {
+	if r.Err != nil {
+		return r.Err
+	}
+	if len(r.TransferKeys[key]) == 0 {
+		delete(r.TransferKeys, key)
+	}
+	return nil
+}
+
+func (r *MockRepository) purgeIdempotencyKeys(before time.Time) (int64, error) {
+	if r.Err != nil {
+		return 0, r.Err
+	}
+	r.PurgedBefore = before
+	return 0, nil
+}
+
+func (r *MockRepository) getDebitAuthorizations(orgID string, customerID string, accountID string) ([]*client.DebitAuthorization, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	var out []*client.DebitAuthorization
+	for i := range r.Authorizations {
+		if r.Authorizations[i].CustomerID == customerID && r.Authorizations[i].AccountID == accountID {
+			out = append(out, r.Authorizations[i])
+		}
+	}
+	return out, nil
+}
+
+func (r *MockRepository) getDebitAuthorization(orgID string, authorizationID string) (*client.DebitAuthorization, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	for i := range r.Authorizations {
+		if r.Authorizations[i].AuthorizationID == authorizationID {
+			return r.Authorizations[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *MockRepository) createDebitAuthorization(orgID string, auth *client.DebitAuthorization) error {
+	if r.Err != nil {
+		return r.Err
+	}
+	r.Authorizations = append(r.Authorizations, auth)
+	return nil
+}
+
+func (r *MockRepository) revokeDebitAuthorization(orgID string, authorizationID string, reason string, when time.Time) ([]string, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	for i := range r.Authorizations {
+		auth := r.Authorizations[i]
+		if auth.AuthorizationID == authorizationID && auth.Status == client.DEBITAUTHORIZATIONSTATUS_ACTIVE {
+			auth.Status = client.DEBITAUTHORIZATIONSTATUS_REVOKED
+			auth.Revoked = &when
+			auth.RevocationReason = reason
+			return r.Reviewed, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *MockRepository) SaveAccountCorrection(orgID string, correction *client.AccountCorrection) error {
+	if r.Err != nil {
+		return r.Err
+	}
+	r.Corrections = append(r.Corrections, correction)
+	return nil
+}
+
+func (r *MockRepository) getAccountCorrections(orgID string, customerID string, accountID string) ([]*client.AccountCorrection, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	var out []*client.AccountCorrection
+	for i := range r.Corrections {
+		if r.Corrections[i].CustomerID == customerID && r.Corrections[i].AccountID == accountID {
+			out = append(out, r.Corrections[i])
+		}
+	}
+	return out, nil
+}
+
+func (r *MockRepository) getAccountCorrection(orgID string, correctionID string) (*client.AccountCorrection, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	for i := range r.Corrections {
+		if r.Corrections[i].CorrectionID == correctionID {
+			return r.Corrections[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *MockRepository) reviewAccountCorrection(orgID string, correctionID string, status client.AccountCorrectionStatus, userID string, when time.Time) error {
+	if r.Err != nil {
+		return r.Err
+	}
+	for i := range r.Corrections {
+		correction := r.Corrections[i]
+		if correction.CorrectionID == correctionID && correction.Status == client.ACCOUNTCORRECTIONSTATUS_PENDING {
+			correction.Status = status
+			correction.Reviewed = &when
+			correction.ReviewedBy = userID
+			return nil
+		}
+	}
+	return fmt.Errorf("correctionID=%s is not pending", correctionID)
+}
+
+func (r *MockRepository) customerSuspended(orgID string, customerID string) (bool, error) {
+	if r.Err != nil {
+		return false, r.Err
+	}
+	for i := range r.Suspended {
+		if r.Suspended[i] == customerID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *MockRepository) customerDeceased(orgID string, customerID string) (bool, error) {
+	if r.Err != nil {
+		return false, r.Err
+	}
+	for i := range r.Deceased {
+		if r.Deceased[i] == customerID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *MockRepository) LookupEntryAccounts(routingNumber string, accountNumber string) ([]EntryAccount, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	return r.EntryAccounts, nil
+}
+
+func (r *MockRepository) SaveDeathNotification(notification *DeathNotification) ([]string, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	r.DeathNotifications = append(r.DeathNotifications, notification)
+	return r.CanceledTransfers, nil
+}
+
+func (r *MockRepository) emailVerified(orgID string, customerID string, email string) (bool, error) {
+	if r.Err != nil {
+		return false, r.Err
+	}
+	for i := range r.Verified {
+		if r.Verified[i] == customerID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *MockRepository) sourceHolds(orgID string, customerID string, accountID string) (int64, error) {
+	if r.Err != nil {
+		return 0, r.Err
+	}
+	var total int64
+	for i := range r.Transfers {
+		xfer := r.Transfers[i]
+		if xfer.Source.CustomerID == customerID && xfer.Source.AccountID == accountID && (xfer.Status == client.PENDING || xfer.Status == client.REVIEWABLE) {
+			total += xfer.Amount.Value
+		}
+	}
+	return total, nil
+}
+
+func (r *MockRepository) ListInboundRecords(filter InboundRecordFilter) ([]*admin.InboundRecord, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	var out []*admin.InboundRecord
+	for i := range r.InboundRecords {
+		out = append(out, &admin.InboundRecord{
+			RecordID:   r.InboundRecords[i].RecordID,
+			TransferID: r.InboundRecords[i].TransferID,
+			Type:       r.InboundRecords[i].Type,
+			Code:       r.InboundRecords[i].Code,
+		})
+	}
+	return out, nil
+}
@@ -0,0 +1,87 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package periods
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/moov-io/base/admin"
+	moovhttp "github.com/moov-io/base/http"
+	"github.com/moov-io/base/log"
+
+	paygateadmin "github.com/moov-io/paygate/pkg/admin"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/x/adminauth"
+	"github.com/moov-io/paygate/x/route"
+	"github.com/moov-io/paygate/x/schedule"
+)
+
+// RegisterAdminRoutes adds endpoints to close accounting periods and read their frozen totals.
+func RegisterAdminRoutes(cfg *config.Config, clock schedule.Clock, svc *admin.Server, repo Repository) {
+	svc.AddHandler("/periods", listPeriods(repo))
+	svc.AddHandler("/periods/{period}", adminauth.Protect(cfg.Admin.Signing, accountingPeriod(cfg, clock, repo)))
+}
+
+func problem(w http.ResponseWriter, err error) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	moovhttp.Problem(w, err)
+}
+
+func listPeriods(repo Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			problem(w, fmt.Errorf("invalid method %s", r.Method))
+			return
+		}
+		periods, err := repo.listPeriods()
+		if err != nil {
+			problem(w, err)
+			return
+		}
+		if periods == nil {
+			periods = make([]paygateadmin.AccountingPeriod, 0)
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(periods)
+	}
+}
+
+func accountingPeriod(cfg *config.Config, clock schedule.Clock, repo Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(cfg, w, r)
+
+		period := route.ReadPathID("period", r)
+		var snapshot *paygateadmin.AccountingPeriod
+		var err error
+		switch r.Method {
+		case http.MethodGet:
+			snapshot, err = repo.getPeriod(period)
+
+		case http.MethodPut:
+			snapshot, err = Close(repo, cfg.ODFI.Cutoffs.Location(), period, clock.Now())
+			if err == nil {
+				cfg.Logger.With(log.Fields{
+					"requestID": responder.XRequestID,
+					"period":    period,
+				}).Log("closed accounting period")
+			}
+
+		default:
+			err = fmt.Errorf("unsupported HTTP verb %s", r.Method)
+		}
+		if err != nil {
+			responder.Problem(err)
+			return
+		}
+
+		responder.Respond(func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(snapshot)
+		})
+	}
+}
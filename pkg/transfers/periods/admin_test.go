@@ -0,0 +1,101 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package periods
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/moov-io/paygate/pkg/admin"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/x/schedule"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdmin__periods(t *testing.T) {
+	repo := &MockRepository{
+		Totals: []admin.PeriodTotal{
+			{Organization: "moov", SecCode: "PPD", Transfers: 2, DebitCount: 2, DebitAmount: 1250},
+		},
+	}
+	cfg := config.Empty()
+	clock := schedule.NewMockClock(time.Date(2020, time.June, 1, 18, 0, 0, 0, time.UTC))
+
+	router := mux.NewRouter()
+	router.Handle("/periods", listPeriods(repo))
+	router.Handle("/periods/{period}", accountingPeriod(cfg, clock, repo))
+
+	// the period hasn't ended yet
+	req := httptest.NewRequest("PUT", "/periods/2020-06-01", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	w.Flush()
+	require.Equal(t, http.StatusBadRequest, w.Code)
+
+	// close
+	clock.Advance(12 * time.Hour)
+	req = httptest.NewRequest("PUT", "/periods/2020-06-01", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	w.Flush()
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var period admin.AccountingPeriod
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&period))
+	require.Equal(t, "2020-06-01", period.Period)
+	require.Equal(t, repo.Totals, period.Totals)
+
+	// closing again fails
+	req = httptest.NewRequest("PUT", "/periods/2020-06-01", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	w.Flush()
+	require.Equal(t, http.StatusBadRequest, w.Code)
+
+	// read
+	req = httptest.NewRequest("GET", "/periods/2020-06-01", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	w.Flush()
+	require.Equal(t, http.StatusOK, w.Code)
+
+	// list
+	req = httptest.NewRequest("GET", "/periods", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	w.Flush()
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var periods []admin.AccountingPeriod
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&periods))
+	require.Len(t, periods, 1)
+	require.Empty(t, periods[0].Totals)
+
+	// errors
+	req = httptest.NewRequest("GET", "/periods/2020-06-02", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	w.Flush()
+	require.Equal(t, http.StatusBadRequest, w.Code)
+
+	req = httptest.NewRequest("POST", "/periods/2020-06-01", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	w.Flush()
+	require.Equal(t, http.StatusBadRequest, w.Code)
+
+	repo.Err = errors.New("bad error")
+	req = httptest.NewRequest("GET", "/periods", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	w.Flush()
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
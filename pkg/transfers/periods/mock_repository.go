@@ -0,0 +1,62 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package periods
+
+import (
+	"sort"
+	"time"
+
+	"github.com/moov-io/paygate/pkg/admin"
+)
+
+type MockRepository struct {
+	Periods map[string]*admin.AccountingPeriod
+
+	// Totals are frozen into each period when it's closed
+	Totals []admin.PeriodTotal
+
+	Err error
+}
+
+func (r *MockRepository) closePeriod(period string, start, end, closed time.Time) (*admin.AccountingPeriod, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	if _, exists := r.Periods[period]; exists {
+		return nil, ErrAlreadyClosed
+	}
+	if r.Periods == nil {
+		r.Periods = make(map[string]*admin.AccountingPeriod)
+	}
+	totals := append([]admin.PeriodTotal{}, r.Totals...)
+	r.Periods[period] = &admin.AccountingPeriod{Period: period, Start: start, End: end, Closed: closed, Totals: totals}
+	return r.Periods[period], nil
+}
+
+func (r *MockRepository) getPeriod(period string) (*admin.AccountingPeriod, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	if p, exists := r.Periods[period]; exists {
+		return p, nil
+	}
+	return nil, ErrNotClosed
+}
+
+func (r *MockRepository) listPeriods() ([]admin.AccountingPeriod, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	var out []admin.AccountingPeriod
+	for _, p := range r.Periods {
+		period := *p
+		period.Totals = nil
+		out = append(out, period)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Period > out[j].Period
+	})
+	return out, nil
+}
@@ -0,0 +1,54 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+// Package periods closes accounting periods by freezing the totals of Transfers created
+// in a business day or month. Closed periods are never updated, so reported numbers don't
+// shift when late returns are applied to historical Transfers.
+package periods
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/moov-io/paygate/pkg/admin"
+)
+
+var (
+	ErrAlreadyClosed = errors.New("period is already closed")
+	ErrNotClosed     = errors.New("period is not closed")
+)
+
+const (
+	dayFormat   = "2006-01-02"
+	monthFormat = "2006-01"
+)
+
+// bounds returns the start (inclusive) and end (exclusive) of a business day (YYYY-MM-DD)
+// or month (YYYY-MM) in loc.
+func bounds(period string, loc *time.Location) (time.Time, time.Time, error) {
+	if loc == nil {
+		loc = time.UTC
+	}
+	if start, err := time.ParseInLocation(dayFormat, period, loc); err == nil {
+		return start, start.AddDate(0, 0, 1), nil
+	}
+	if start, err := time.ParseInLocation(monthFormat, period, loc); err == nil {
+		return start, start.AddDate(0, 1, 0), nil
+	}
+	return time.Time{}, time.Time{}, fmt.Errorf("invalid period %q, expected YYYY-MM-DD or YYYY-MM", period)
+}
+
+// Close freezes the totals of Transfers created during period. Periods can only be closed
+// once they've ended and only once.
+func Close(repo Repository, loc *time.Location, period string, now time.Time) (*admin.AccountingPeriod, error) {
+	start, end, err := bounds(period, loc)
+	if err != nil {
+		return nil, err
+	}
+	if now.Before(end) {
+		return nil, fmt.Errorf("period %s hasn't ended", period)
+	}
+	return repo.closePeriod(period, start, end, now)
+}
@@ -0,0 +1,50 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package periods
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPeriods__bounds(t *testing.T) {
+	loc, _ := time.LoadLocation("America/New_York")
+
+	start, end, err := bounds("2020-06-01", loc)
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2020, time.June, 1, 0, 0, 0, 0, loc), start)
+	require.Equal(t, time.Date(2020, time.June, 2, 0, 0, 0, 0, loc), end)
+
+	start, end, err = bounds("2020-12", loc)
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2020, time.December, 1, 0, 0, 0, 0, loc), start)
+	require.Equal(t, time.Date(2021, time.January, 1, 0, 0, 0, 0, loc), end)
+
+	_, _, err = bounds("2020/06/01", loc)
+	require.Error(t, err)
+}
+
+func TestPeriods__Close(t *testing.T) {
+	repo := &MockRepository{}
+	now := time.Date(2020, time.June, 2, 9, 0, 0, 0, time.UTC)
+
+	period, err := Close(repo, time.UTC, "2020-06-01", now)
+	require.NoError(t, err)
+	require.Equal(t, now, period.Closed)
+
+	_, err = Close(repo, time.UTC, "2020-06-01", now)
+	require.Equal(t, ErrAlreadyClosed, err)
+
+	// periods can't be closed before they end
+	_, err = Close(repo, time.UTC, "2020-06-02", now)
+	require.Error(t, err)
+	_, err = Close(repo, time.UTC, "2020-06", now)
+	require.Error(t, err)
+
+	_, err = Close(repo, time.UTC, "june", now)
+	require.Error(t, err)
+}
@@ -0,0 +1,138 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package periods
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/moov-io/paygate/pkg/admin"
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/database"
+)
+
+type Repository interface {
+	// closePeriod snapshots the totals of transfers created in [start, end)
+	closePeriod(period string, start, end, closed time.Time) (*admin.AccountingPeriod, error)
+
+	// getPeriod returns a closed period with its totals
+	getPeriod(period string) (*admin.AccountingPeriod, error)
+	listPeriods() ([]admin.AccountingPeriod, error)
+}
+
+func NewRepo(db *sql.DB) *sqlRepo {
+	return &sqlRepo{db: db}
+}
+
+type sqlRepo struct {
+	db *sql.DB
+}
+
+func (r *sqlRepo) Close() error {
+	return r.db.Close()
+}
+
+// totaledTransfers filters transfers to those counted in a period's totals. Canceled transfers
+// and those which failed without a return were never uploaded.
+const totaledTransfers = `created_at >= ? and created_at < ? and deleted_at is null
+  and status <> ? and (status <> ? or coalesce(return_code, '') <> '')`
+
+func (r *sqlRepo) closePeriod(period string, start, end, closed time.Time) (*admin.AccountingPeriod, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+
+	query := `insert into accounting_periods (period, starts_at, ends_at, closed_at) values (?, ?, ?, ?);`
+	if _, err := tx.Exec(query, period, start, end, closed); err != nil {
+		tx.Rollback()
+		if database.UniqueViolation(err) {
+			return nil, ErrAlreadyClosed
+		}
+		return nil, err
+	}
+
+	// Transfers created before entry totals were saved have no SEC code or amounts. Closing the
+	// period would freeze them as zeros, so it's refused until they're filled in.
+	var missing int
+	query = `select count(*) from transfers where ` + totaledTransfers + ` and coalesce(sec_code, '') = '';`
+	if err := tx.QueryRow(query, start, end, client.CANCELED, client.FAILED).Scan(&missing); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if missing > 0 {
+		tx.Rollback()
+		return nil, fmt.Errorf("period %s has %d transfers without entry totals", period, missing)
+	}
+
+	query = `insert into accounting_period_totals (period, organization, sec_code, transfers, debit_count, debit_amount, credit_count, credit_amount, return_count, return_amount)
+select ?, organization, coalesce(sec_code, ''), count(*),
+  sum(case when debit_amount > 0 then 1 else 0 end), coalesce(sum(debit_amount), 0),
+  sum(case when credit_amount > 0 then 1 else 0 end), coalesce(sum(credit_amount), 0),
+  sum(case when coalesce(return_code, '') <> '' then 1 else 0 end),
+  sum(case when coalesce(return_code, '') <> '' then amount_value else 0 end)
+from transfers
+where ` + totaledTransfers + `
+group by organization, coalesce(sec_code, '');`
+	if _, err := tx.Exec(query, period, start, end, client.CANCELED, client.FAILED); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return r.getPeriod(period)
+}
+
+func (r *sqlRepo) getPeriod(period string) (*admin.AccountingPeriod, error) {
+	query := `select period, starts_at, ends_at, closed_at from accounting_periods where period = ? limit 1;`
+	var out admin.AccountingPeriod
+	if err := r.db.QueryRow(query, period).Scan(&out.Period, &out.Start, &out.End, &out.Closed); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotClosed
+		}
+		return nil, err
+	}
+
+	query = `select organization, sec_code, transfers, debit_count, debit_amount, credit_count, credit_amount, return_count, return_amount
+from accounting_period_totals where period = ? order by organization, sec_code;`
+	rows, err := r.db.Query(query, period)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out.Totals = make([]admin.PeriodTotal, 0)
+	for rows.Next() {
+		var total admin.PeriodTotal
+		err := rows.Scan(&total.Organization, &total.SecCode, &total.Transfers,
+			&total.DebitCount, &total.DebitAmount, &total.CreditCount, &total.CreditAmount,
+			&total.ReturnCount, &total.ReturnAmount)
+		if err != nil {
+			return nil, err
+		}
+		out.Totals = append(out.Totals, total)
+	}
+	return &out, rows.Err()
+}
+
+func (r *sqlRepo) listPeriods() ([]admin.AccountingPeriod, error) {
+	rows, err := r.db.Query(`select period, starts_at, ends_at, closed_at from accounting_periods order by period desc;`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []admin.AccountingPeriod
+	for rows.Next() {
+		var period admin.AccountingPeriod
+		if err := rows.Scan(&period.Period, &period.Start, &period.End, &period.Closed); err != nil {
+			return nil, err
+		}
+		out = append(out, period)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,165 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package periods
+
+import (
+	"testing"
+	"time"
+
+	"github.com/moov-io/base"
+
+	"github.com/moov-io/paygate/pkg/admin"
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/database"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testTransfer struct {
+	organization string
+	secCode      string
+	amount       int64
+	debit        bool
+	status       client.TransferStatus
+	returnCode   string
+	created      time.Time
+}
+
+func writeTransfer(t *testing.T, repo *sqlRepo, xfer testTransfer) string {
+	t.Helper()
+
+	var debits, credits int64
+	if xfer.debit {
+		debits = xfer.amount
+	} else {
+		credits = xfer.amount
+	}
+	var returnCode *string
+	if xfer.returnCode != "" {
+		returnCode = &xfer.returnCode
+	}
+
+	transferID := base.ID()
+	query := `insert into transfers (transfer_id, organization, amount_currency, amount_value, source_customer_id, source_account_id, destination_customer_id, destination_account_id, description, status, same_day, return_code, sec_code, debit_amount, credit_amount, created_at, last_updated_at)
+values (?, ?, 'USD', ?, 'source', 'source', 'destination', 'destination', 'test', ?, false, ?, ?, ?, ?, ?, ?);`
+	_, err := repo.db.Exec(query, transferID, xfer.organization, xfer.amount, xfer.status, returnCode, xfer.secCode, debits, credits, xfer.created, xfer.created)
+	require.NoError(t, err)
+	return transferID
+}
+
+func TestRepository__closePeriod(t *testing.T) {
+	t.Parallel()
+
+	check := func(t *testing.T, repo *sqlRepo) {
+		start := time.Date(2020, time.June, 1, 0, 0, 0, 0, time.UTC)
+		end := start.AddDate(0, 0, 1)
+		during := start.Add(10 * time.Hour)
+
+		writeTransfer(t, repo, testTransfer{organization: "moov", secCode: "PPD", amount: 1000, debit: true, status: client.PROCESSED, created: during})
+		writeTransfer(t, repo, testTransfer{organization: "moov", secCode: "PPD", amount: 250, status: client.PENDING, created: during})
+		writeTransfer(t, repo, testTransfer{organization: "moov", secCode: "PPD", amount: 500, debit: true, status: client.FAILED, returnCode: "R01", created: during})
+		writeTransfer(t, repo, testTransfer{organization: "other", secCode: "CCD", amount: 75, status: client.PROCESSED, created: during})
+
+		// excluded from the totals
+		writeTransfer(t, repo, testTransfer{organization: "moov", secCode: "PPD", amount: 99, status: client.CANCELED, created: during})
+		writeTransfer(t, repo, testTransfer{organization: "moov", secCode: "PPD", amount: 99, debit: true, status: client.FAILED, created: during})
+		writeTransfer(t, repo, testTransfer{organization: "moov", secCode: "PPD", amount: 99, status: client.PROCESSED, created: end})
+
+		period, err := repo.closePeriod("2020-06-01", start, end, end.Add(time.Hour))
+		require.NoError(t, err)
+		require.Equal(t, "2020-06-01", period.Period)
+		require.Equal(t, []admin.PeriodTotal{
+			{
+				Organization: "moov",
+				SecCode:      "PPD",
+				Transfers:    3,
+				DebitCount:   2,
+				DebitAmount:  1500,
+				CreditCount:  1,
+				CreditAmount: 250,
+				ReturnCount:  1,
+				ReturnAmount: 500,
+			},
+			{
+				Organization: "other",
+				SecCode:      "CCD",
+				Transfers:    1,
+				CreditCount:  1,
+				CreditAmount: 75,
+			},
+		}, period.Totals)
+
+		// late returns don't change closed periods
+		_, err = repo.db.Exec(`update transfers set status = ?, return_code = ? where organization = ?;`, client.FAILED, "R02", "other")
+		require.NoError(t, err)
+
+		_, err = repo.closePeriod("2020-06-01", start, end, end.Add(2*time.Hour))
+		require.Equal(t, ErrAlreadyClosed, err)
+
+		again, err := repo.getPeriod("2020-06-01")
+		require.NoError(t, err)
+		require.Equal(t, period.Totals, again.Totals)
+
+		periods, err := repo.listPeriods()
+		require.NoError(t, err)
+		require.Len(t, periods, 1)
+		require.Empty(t, periods[0].Totals)
+
+		_, err = repo.getPeriod("2020-06-02")
+		require.Equal(t, ErrNotClosed, err)
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+}
+
+func TestRepository__closePeriodMissingTotals(t *testing.T) {
+	t.Parallel()
+
+	check := func(t *testing.T, repo *sqlRepo) {
+		start := time.Date(2020, time.June, 1, 0, 0, 0, 0, time.UTC)
+		end := start.AddDate(0, 0, 1)
+		during := start.Add(10 * time.Hour)
+
+		writeTransfer(t, repo, testTransfer{organization: "moov", secCode: "PPD", amount: 1000, debit: true, status: client.PROCESSED, created: during})
+		transferID := writeTransfer(t, repo, testTransfer{organization: "moov", amount: 250, status: client.PROCESSED, created: during})
+
+		// canceled transfers aren't totaled, so they don't need entry totals
+		writeTransfer(t, repo, testTransfer{organization: "moov", amount: 99, status: client.CANCELED, created: during})
+
+		_, err := repo.closePeriod("2020-06-01", start, end, end.Add(time.Hour))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "1 transfers without entry totals")
+
+		_, err = repo.getPeriod("2020-06-01")
+		require.Equal(t, ErrNotClosed, err)
+
+		// the period can be closed once totals are saved
+		_, err = repo.db.Exec(`update transfers set sec_code = ?, credit_amount = ? where transfer_id = ?;`, "PPD", 250, transferID)
+		require.NoError(t, err)
+
+		period, err := repo.closePeriod("2020-06-01", start, end, end.Add(time.Hour))
+		require.NoError(t, err)
+		require.Len(t, period.Totals, 1)
+		require.Equal(t, int64(2), period.Totals[0].Transfers)
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+}
+
+func setupSQLiteDB(t *testing.T) *sqlRepo {
+	db := database.CreateTestSqliteDB(t)
+	t.Cleanup(func() { db.Close() })
+
+	return NewRepo(db.DB)
+}
+
+func setupMySQLeDB(t *testing.T) *sqlRepo {
+	db := database.CreateTestMySQLDB(t)
+	t.Cleanup(func() { db.Close() })
+
+	return NewRepo(db.DB)
+}
@@ -12,18 +12,25 @@ import (
 	"fmt"
 	"io/ioutil"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/moov-io/ach"
 	"github.com/moov-io/base"
 
+	"github.com/moov-io/paygate/pkg/client"
 	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/hooks"
+	"github.com/moov-io/paygate/pkg/transfers/files"
 	"github.com/moov-io/paygate/pkg/transfers/pipeline/audittrail"
 	"github.com/moov-io/paygate/pkg/transfers/pipeline/notify"
 	"github.com/moov-io/paygate/pkg/transfers/pipeline/output"
 	"github.com/moov-io/paygate/pkg/transfers/pipeline/transform"
 	"github.com/moov-io/paygate/pkg/upload"
+	"github.com/moov-io/paygate/pkg/webhooks"
+	"github.com/moov-io/paygate/x/errorlog"
 	"github.com/moov-io/paygate/x/schedule"
+	"github.com/moov-io/paygate/x/trace"
 
 	"github.com/moov-io/base/log"
 	"gocloud.dev/pubsub"
@@ -37,14 +44,17 @@ import (
 type XferAggregator struct {
 	cfg    *config.Config
 	logger log.Logger
+	clock  schedule.Clock
 
 	agent    upload.Agent
 	notifier notify.Sender
 
-	repo Repository
+	repo  Repository
+	files files.Repository
 
 	merger       XferMerging
 	subscription *pubsub.Subscription
+	topic        string // label for metrics
 
 	cutoffCallbacks []CutoffCallback
 	cutoffTrigger   chan manuallyTriggeredCutoff
@@ -52,14 +62,26 @@ type XferAggregator struct {
 	auditStorage          audittrail.Storage
 	preuploadTransformers []transform.PreUpload
 	outputFormatter       output.Formatter
+	filenames             upload.FilenameProvider
+	deadLetters           *deadLetters
+	hooks                 *hooks.Runner
+	events                webhooks.Sender
+
+	// state exposed for operators, see aggregate_state.go
+	errors        *errorlog.Recent
+	uploadsMu     sync.Mutex
+	failedUploads []failedUpload
 }
 
 func NewAggregator(
 	cfg *config.Config,
+	clock schedule.Clock,
 	agent upload.Agent,
 	repo Repository,
+	filesRepo files.Repository,
 	merger XferMerging,
 	sub *pubsub.Subscription,
+	events webhooks.Sender,
 	cutoffCallbacks []CutoffCallback,
 ) (*XferAggregator, error) {
 	cfg.Logger = cfg.Logger.Set("service", "XferAggregator")
@@ -86,20 +108,51 @@ func NewAggregator(
 	}
 	cfg.Logger.Logf("setup %T output formatter", outputFormatter)
 
-	return &XferAggregator{
+	filenames, err := upload.NewFilenameProvider(cfg.ODFI)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Logger.Logf("setup %T filename provider", filenames)
+
+	hookRunner, err := hooks.New(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	deadLetters, err := newDeadLetters(cfg.Pipeline.DeadLetters)
+	if err != nil {
+		return nil, err
+	}
+
+	xfagg := &XferAggregator{
 		cfg:                   cfg,
 		logger:                cfg.Logger,
+		clock:                 clock,
 		agent:                 agent,
 		notifier:              notifier,
 		repo:                  repo,
+		files:                 filesRepo,
 		merger:                merger,
 		subscription:          sub,
+		topic:                 topicName(cfg.Pipeline.Stream),
 		cutoffCallbacks:       cutoffCallbacks,
 		cutoffTrigger:         make(chan manuallyTriggeredCutoff, 1),
 		auditStorage:          auditStorage,
 		preuploadTransformers: preuploadTransformers,
 		outputFormatter:       outputFormatter,
-	}, nil
+		filenames:             filenames,
+		deadLetters:           deadLetters,
+		hooks:                 hookRunner,
+		events:                events,
+		errors:                errorlog.New(maxRecentErrors),
+	}
+	if err := xfagg.loadFailedUploads(); err != nil {
+		return nil, fmt.Errorf("problem reading failed uploads: %v", err)
+	}
+	if n := len(xfagg.failedUploads); n > 0 {
+		cfg.Logger.Logf("found %d failed uploads to retry", n)
+	}
+	return xfagg, nil
 }
 
 // CutoffCallback is a function called before cutoff processing is performed.
@@ -138,18 +191,21 @@ func (xfagg *XferAggregator) Start(ctx context.Context, cutoffs *schedule.Cutoff
 		case tt := <-cutoffs.C:
 			if err := xfagg.processCutoffCallbacks(); err != nil {
 				xfagg.logger.LogErrorf("ERROR with cutoff callbacks: %v", err)
+				xfagg.errors.Add("cutoff", err)
 			}
 			xfagg.withEachFile(tt)
 
 		case waiter := <-xfagg.cutoffTrigger:
 			if err := xfagg.processCutoffCallbacks(); err != nil {
 				xfagg.logger.LogErrorf("ERROR with manual cutoff callbacks: %v", err)
+				xfagg.errors.Add("cutoff", err)
 			}
 			xfagg.manualCutoff(waiter)
 
 		case err := <-xfagg.await():
 			if err != nil {
 				xfagg.logger.LogErrorf("ERROR handling message: %v", err)
+				xfagg.errors.Add("pipeline", err)
 			}
 
 		case <-ctx.Done():
@@ -165,33 +221,78 @@ func (xfagg *XferAggregator) Shutdown() {
 	if xfagg.auditStorage != nil {
 		xfagg.auditStorage.Close()
 	}
+	if err := xfagg.deadLetters.Close(); err != nil {
+		xfagg.logger.LogErrorf("problem closing dead letters: %v", err)
+	}
 	if err := xfagg.subscription.Shutdown(context.Background()); err != nil {
 		xfagg.logger.LogErrorf("problem shutting down transfer aggregator: %v", err)
 	}
 }
 
-func (xfagg *XferAggregator) runTransformers(outgoing *ach.File) error {
-	result, err := transform.ForUpload(outgoing, xfagg.preuploadTransformers)
+func (xfagg *XferAggregator) runTransformers(outgoing *ach.File, correlationIDs []string) error {
+	day := xfagg.clock.Now()
+	seq, err := xfagg.assignSequence(outgoing, day)
 	if err != nil {
 		return err
 	}
-	return xfagg.uploadFile(result)
+	layout := xfagg.cfg.Pipeline.Output.Layout(outgoing.Header.ImmediateDestination)
+	result, err := transform.ForUpload(outgoing, layout, xfagg.preuploadTransformers)
+	if err != nil {
+		xfagg.releaseSequence(outgoing, day, seq)
+		return err
+	}
+	filename, contents, err := xfagg.renderFile(result, seq)
+	if err != nil {
+		xfagg.releaseSequence(outgoing, day, seq)
+		return err
+	}
+	// Files which fail to upload keep their sequence, as they're retried with the same contents.
+	// Each file gets its own ID, as filename templates don't have to render unique filenames.
+	return xfagg.upload(base.ID(), filename, contents, result.File, correlationIDs)
+}
+
+// assignSequence reserves the file's sequence for its destination on day and sets the
+// FileIDModifier from it, so files merged at the same time (or by other instances)
+// never share a FileIDModifier or filename.
+func (xfagg *XferAggregator) assignSequence(file *ach.File, day time.Time) (int, error) {
+	if xfagg.files == nil || file == nil {
+		return 0, nil
+	}
+	seq, err := xfagg.files.NextSequence(file.Header.ImmediateDestination, day)
+	if err != nil {
+		return 0, fmt.Errorf("problem reserving file sequence: %v", err)
+	}
+	modifier, err := upload.FileIDModifier(seq)
+	if err != nil {
+		return 0, fmt.Errorf("destination %s: %v", file.Header.ImmediateDestination, err)
+	}
+	file.Header.FileIDModifier = modifier
+	return seq, nil
+}
+
+// releaseSequence returns a reserved sequence for a file which was never sent, so the
+// destination's 36 daily FileIDModifiers aren't used up by files which failed to be built.
+func (xfagg *XferAggregator) releaseSequence(file *ach.File, day time.Time, seq int) {
+	if xfagg.files == nil || file == nil || seq <= 0 {
+		return
+	}
+	if err := xfagg.files.ReleaseSequence(file.Header.ImmediateDestination, day, seq); err != nil {
+		xfagg.logger.LogErrorf("problem releasing file sequence %d: %v", seq, err)
+	}
 }
 
 func (xfagg *XferAggregator) manualCutoff(waiter manuallyTriggeredCutoff) {
 	xfagg.logger.Log("starting manual cutoff window processing")
 
-	if processed, err := xfagg.merger.WithEachMerged(xfagg.runTransformers); err != nil {
+	processed, err := xfagg.merger.WithEachMerged(xfagg.runTransformers)
+	if err != nil {
 		xfagg.logger.LogErrorf("ERROR inside manual WithEachMerged: %v", err)
-		waiter.C <- err
-	} else {
-		if err := xfagg.repo.MarkTransfersAsProcessed(processed.transferIDs); err != nil {
-			xfagg.logger.LogErrorf("ERROR marking %d transfers as processed: %v", len(processed.transferIDs), err)
-			waiter.C <- err
-		} else {
-			waiter.C <- nil
-		}
+		xfagg.errors.Add("merge", err)
+	}
+	if markErr := xfagg.markTransfersAsProcessed(processed); markErr != nil && err == nil {
+		err = markErr
 	}
+	waiter.C <- err
 
 	xfagg.logger.Log("ended manual cutoff window processing")
 }
@@ -200,59 +301,196 @@ func (xfagg *XferAggregator) withEachFile(when time.Time) {
 	window := when.Format("15:04")
 	xfagg.logger.Logf("starting %s cutoff window processing", window)
 
-	if processed, err := xfagg.merger.WithEachMerged(xfagg.runTransformers); err != nil {
+	processed, err := xfagg.merger.WithEachMerged(xfagg.runTransformers)
+	if err != nil {
 		xfagg.logger.LogErrorf("ERROR inside WithEachMerged: %v", err)
-	} else {
-		if err := xfagg.repo.MarkTransfersAsProcessed(processed.transferIDs); err != nil {
-			xfagg.logger.LogErrorf("ERROR marking %d transfers as processed: %v", len(processed.transferIDs), err)
-		}
+		xfagg.errors.Add("merge", err)
 	}
+	xfagg.markTransfersAsProcessed(processed)
 
 	xfagg.logger.Logf("ended %s cutoff window processing", window)
 }
 
-func (xfagg *XferAggregator) uploadFile(res *transform.Result) error {
-	if res == nil || res.File == nil {
-		return errors.New("uploadFile: nil Result / File")
+// markTransfersAsProcessed updates transfers from the uploaded files along with any which
+// failed to be marked after a previous cutoff. Errors are logged and returned.
+func (xfagg *XferAggregator) markTransfersAsProcessed(processed *processedTransfers) error {
+	var transferIDs []string
+	if processed != nil {
+		transferIDs = append(transferIDs, processed.transferIDs...)
 	}
 
-	data := upload.FilenameData{
-		RoutingNumber: res.File.Header.ImmediateDestination,
-		GPG:           len(res.Encrypted) > 0,
+	unprocessed, err := xfagg.repo.getUnprocessedTransfers()
+	if err != nil {
+		xfagg.logger.LogErrorf("ERROR reading unprocessed transfers: %v", err)
+	}
+	if len(unprocessed) > 0 {
+		xfagg.logger.Logf("retrying %d unprocessed transfers", len(unprocessed))
 	}
-	filename, err := upload.RenderACHFilename(xfagg.cfg.ODFI.FilenameTemplate(), data)
+	for i := range unprocessed {
+		transferIDs = append(transferIDs, unprocessed[i].TransferID)
+	}
+	if len(transferIDs) == 0 {
+		return nil
+	}
+
+	// Transfers can be partially marked, so send events for any which changed
+	before := xfagg.transferStatuses(transferIDs)
+	defer xfagg.sendProcessedEvents(transferIDs, before)
+
+	if err := xfagg.repo.MarkTransfersAsProcessed(transferIDs); err != nil {
+		xfagg.errors.Add("bookkeeping", err)
+		return xfagg.logger.LogErrorf("ERROR marking %d transfers as processed: %v", len(transferIDs), err).Err()
+	}
+	return nil
+}
+
+func (xfagg *XferAggregator) transferStatuses(transferIDs []string) map[string]transferStatus {
+	if xfagg.events == nil {
+		return nil
+	}
+	statuses, err := xfagg.repo.getTransferStatuses(transferIDs)
 	if err != nil {
-		return fmt.Errorf("problem rendering filename template: %v", err)
+		xfagg.logger.LogErrorf("ERROR reading transfer statuses for webhooks: %v", err)
+	}
+	return statuses
+}
+
+// sendProcessedEvents notifies external systems of transfers which became PROCESSED.
+// Failures are logged as webhooks are not allowed to block uploads.
+func (xfagg *XferAggregator) sendProcessedEvents(transferIDs []string, before map[string]transferStatus) {
+	if xfagg.events == nil || before == nil {
+		return
+	}
+	after := xfagg.transferStatuses(transferIDs)
+	for transferID, status := range after {
+		if status.status != client.PROCESSED || before[transferID].status == client.PROCESSED {
+			continue
+		}
+		event := webhooks.TransferEvent(webhooks.EventTransferProcessed, status.organization, webhooks.TransferUpdate{
+			TransferID: transferID,
+			Status:     client.PROCESSED,
+		})
+		if err := xfagg.events.Send(event); err != nil {
+			xfagg.logger.Set("transferID", transferID).LogErrorf("problem sending %s webhook: %v", event.Type, err)
+		}
+	}
+}
+
+// renderFile formats a transformed file and returns its filename along with the contents to upload.
+func (xfagg *XferAggregator) renderFile(res *transform.Result, seq int) (string, []byte, error) {
+	if res == nil || res.File == nil {
+		return "", nil, errors.New("renderFile: nil Result / File")
 	}
 
 	var buf bytes.Buffer
 	if err := xfagg.outputFormatter.Format(&buf, res); err != nil {
-		return fmt.Errorf("problem formatting output: %v", err)
+		return "", nil, fmt.Errorf("problem formatting output: %v", err)
+	}
+
+	// Files are named from their formatted contents, so providers can include checksums
+	filename, err := xfagg.filenames.Filename(upload.FilenameData{
+		RoutingNumber: res.File.Header.ImmediateDestination,
+		GPG:           len(res.Encrypted) > 0,
+		Sequence:      seq,
+		IAT:           len(res.File.IATBatches) > 0,
+		File:          res.File,
+		Contents:      buf.Bytes(),
+	})
+	if err != nil {
+		return "", nil, fmt.Errorf("problem naming file: %v", err)
+	}
+	return filename, buf.Bytes(), nil
+}
+
+// upload sends a named file to the ODFI. Files which fail are kept with the failed uploads
+// so they can be retried, see retryUploads.
+func (xfagg *XferAggregator) upload(fileID, filename string, contents []byte, file *ach.File, correlationIDs []string) (err error) {
+	span := trace.StartSpan("upload-file", nil)
+	span.SetTag("fileID", fileID)
+	span.SetTag("filename", filename)
+	span.SetTag("destination", file.Header.ImmediateDestination)
+	span.SetTag("correlationIDs", strings.Join(correlationIDs, ","))
+	defer func() {
+		if err != nil {
+			xfagg.recordFailedUpload(fileID, filename, contents, file, correlationIDs, err)
+		}
+		trace.Finish(span, err)
+	}()
+
+	if err := xfagg.checkOriginationCap(file); err != nil {
+		return err
+	}
+
+	// Hooks can stop a file from being uploaded, it's then listed with the failed uploads
+	if _, err := xfagg.hooks.Run(hooks.Request{
+		Point:    config.HookPreUpload,
+		Filename: filename,
+		File:     file,
+	}); err != nil {
+		return fmt.Errorf("problem with pre-upload hooks: %v", err)
 	}
 
 	// Record the file in our audit trail
-	if err := xfagg.auditStorage.SaveFile(filename, res.File); err != nil {
+	if err := xfagg.auditStorage.SaveFile(filename, file); err != nil {
 		return fmt.Errorf("problem saving file in audit record: %v", err)
 	}
 
-	// Upload our file
+	// Upload our file, keeping its exact contents for the upload history
+	agentSpan := trace.StartSpan("agent-upload-file", span)
+	agentSpan.SetTag("hostname", xfagg.agent.Hostname())
 	err = xfagg.agent.UploadFile(upload.File{
-		Filename: filename,
-		Contents: ioutil.NopCloser(&buf),
+		Filename:      filename,
+		Contents:      ioutil.NopCloser(bytes.NewReader(contents)),
+		RoutingNumber: file.Header.ImmediateDestination,
 	})
+	trace.Finish(agentSpan, err)
 
 	// Send Slack/PD or whatever notifications after the file is uploaded
-	xfagg.notifyAfterUpload(filename, res.File, err)
+	xfagg.notifyAfterUpload(filename, file, correlationIDs, err)
+
+	logger := xfagg.logger.With(log.Fields{
+		"fileID":         fileID,
+		"filename":       filename,
+		"correlationIDs": strings.Join(correlationIDs, ","),
+	})
+	if err == nil {
+		logger.Log("uploaded file")
+		xfagg.recordUpload(fileID, filename, contents, file)
+	} else {
+		logger.LogErrorf("problem uploading file: %v", err)
+	}
 
 	return err
 }
 
-func (xfagg *XferAggregator) notifyAfterUpload(filename string, file *ach.File, err error) {
+// recordUpload saves the file into our upload history so the ODFI's acknowledgement
+// can be matched against it. The file is already uploaded, so failures are only logged.
+func (xfagg *XferAggregator) recordUpload(fileID, filename string, contents []byte, file *ach.File) {
+	if xfagg.files == nil {
+		return
+	}
+	uploaded := files.Upload{
+		FileID:       fileID,
+		Filename:     filename,
+		RemoteServer: xfagg.agent.Hostname(),
+		RemotePath:   xfagg.agent.OutboundPath(),
+		Contents:     contents,
+		Uploaded:     xfagg.clock.Now(),
+	}
+	if err := xfagg.files.RecordUpload(uploaded, file); err != nil {
+		xfagg.logger.Set("fileID", fileID).LogErrorf("problem recording upload history of %s: %v", filename, err)
+		return
+	}
+	xfagg.updateOriginationMetrics(file.Header.ImmediateDestination)
+}
+
+func (xfagg *XferAggregator) notifyAfterUpload(filename string, file *ach.File, correlationIDs []string, err error) {
 	msg := &notify.Message{
-		Direction: notify.Upload,
-		Filename:  filename,
-		File:      file,
-		Hostname:  xfagg.agent.Hostname(),
+		Direction:      notify.Upload,
+		Filename:       filename,
+		File:           file,
+		Hostname:       xfagg.agent.Hostname(),
+		CorrelationIDs: correlationIDs,
 	}
 
 	if err != nil {
@@ -278,50 +516,101 @@ func (xfagg *XferAggregator) await() chan error {
 				return
 			}
 		}
-		out <- handleMessage(xfagg.merger, msg)
+		if trigger, ok := readTriggeredCutoff(msg); ok {
+			out <- xfagg.handleTriggeredCutoff(msg, trigger)
+			return
+		}
+		out <- handleMessage(xfagg.topic, xfagg.merger, xfagg.deadLetters, msg)
 	}()
 	return out
 }
 
+// readTriggeredCutoff returns a TriggeredCutoff if msg was published as one.
+func readTriggeredCutoff(msg *pubsub.Message) (TriggeredCutoff, bool) {
+	var trigger TriggeredCutoff
+	if msg == nil {
+		return trigger, false
+	}
+	if err := json.NewDecoder(bytes.NewReader(msg.Body)).Decode(&trigger); err != nil || trigger.TriggerID == "" {
+		return trigger, false
+	}
+	return trigger, true
+}
+
+// handleTriggeredCutoff performs cutoff processing requested by another instance through the pipeline.
+func (xfagg *XferAggregator) handleTriggeredCutoff(msg *pubsub.Message, trigger TriggeredCutoff) error {
+	started := time.Now()
+	recordReceived(xfagg.topic, msg, started)
+	msg.Ack()
+
+	xfagg.logger.Set("triggerID", trigger.TriggerID).Log("received cutoff trigger from pipeline")
+
+	waiter := manuallyTriggeredCutoff{
+		C: make(chan error, 1),
+	}
+	xfagg.cutoffTrigger <- waiter
+	err := <-waiter.C
+
+	recordHandled(xfagg.topic, messageTypeCutoff, true, started)
+
+	// The aggregator has already moved on to receiving the next message, so log the result here.
+	if err != nil {
+		xfagg.logger.Set("triggerID", trigger.TriggerID).LogErrorf("problem with triggered cutoff: %v", err)
+	}
+	return nil
+}
+
 // handleMessage attempts to parse a pubsub.Message into a strongly typed message
-// which an XferMerging instance can handle.
-func handleMessage(merger XferMerging, msg *pubsub.Message) error {
+// which an XferMerging instance can handle. Messages which fail are Nack'd until
+// they're dead-lettered.
+func handleMessage(topic string, merger XferMerging, letters *deadLetters, msg *pubsub.Message) error {
 	if msg == nil {
 		return errors.New("nil pubsub.Message")
 	}
+	started := time.Now()
+	recordReceived(topic, msg, started)
+
+	messageType, err := handleBody(merger, msg.Body)
+	if err != nil {
+		deadLettered, dlErr := letters.failed(msg, err)
+		if deadLettered {
+			messagesDeadLettered.With("topic", topic, "type", messageType).Add(1)
+		}
+		if dlErr != nil {
+			err = fmt.Errorf("%v: %v", err, dlErr)
+		}
+		recordHandled(topic, messageType, false, started)
+		return err
+	}
+	msg.Ack()
+	letters.handled(msg)
+	recordHandled(topic, messageType, true, started)
+	return nil
+}
 
+// handleBody decodes a pipeline message and passes it to merger. The message type is
+// returned for metrics.
+func handleBody(merger XferMerging, body []byte) (string, error) {
 	var xfer Xfer
-	err := json.NewDecoder(bytes.NewReader(msg.Body)).Decode(&xfer)
+	err := json.NewDecoder(bytes.NewReader(body)).Decode(&xfer)
 	if err == nil && xfer.Transfer != nil && xfer.File != nil {
-		// Handle the Xfer after decoding it.
-		if err := merger.HandleXfer(xfer); err != nil {
-			if msg.Nackable() {
-				msg.Nack()
-			}
-			return fmt.Errorf("HandleXfer problem with transferID=%s: %v", xfer.Transfer.TransferID, err)
-		} else {
-			msg.Ack()
+		span := trace.FollowsFrom("merge-transfer", xfer.Trace)
+		span.SetTag("transferID", xfer.Transfer.TransferID)
+		err := merger.HandleXfer(xfer)
+		trace.Finish(span, err)
+		if err != nil {
+			return messageTypeXfer, fmt.Errorf("HandleXfer problem with transferID=%s correlationID=%s: %v", xfer.Transfer.TransferID, xfer.Transfer.CorrelationID, err)
 		}
-		return nil
+		return messageTypeXfer, nil
 	}
 
 	var cancel CanceledTransfer
-	if err := json.NewDecoder(bytes.NewReader(msg.Body)).Decode(&cancel); err == nil && cancel.TransferID != "" {
-		// Cancel the given transfer
+	if err := json.NewDecoder(bytes.NewReader(body)).Decode(&cancel); err == nil && cancel.TransferID != "" {
 		if err := merger.HandleCancel(cancel); err != nil {
-			if msg.Nackable() {
-				msg.Nack()
-			}
-			return fmt.Errorf("CanceledTransfer problem with transferID=%s: %v", cancel.TransferID, err)
-		} else {
-			msg.Ack()
+			return messageTypeCancel, fmt.Errorf("CanceledTransfer problem with transferID=%s: %v", cancel.TransferID, err)
 		}
-		return nil
-	}
-
-	if msg.Nackable() {
-		msg.Nack()
+		return messageTypeCancel, nil
 	}
 
-	return fmt.Errorf("unexpected message: %v", string(msg.Body))
+	return messageTypeOther, fmt.Errorf("unexpected message: %v", string(body))
 }
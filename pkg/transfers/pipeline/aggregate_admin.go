@@ -5,15 +5,30 @@
 package pipeline
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"strings"
 
+	"github.com/moov-io/base"
 	"github.com/moov-io/base/admin"
 	moovhttp "github.com/moov-io/base/http"
+
+	paygateadmin "github.com/moov-io/paygate/pkg/admin"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/x/adminauth"
+	"github.com/moov-io/paygate/x/route"
 )
 
 func (xfagg *XferAggregator) RegisterRoutes(svc *admin.Server) {
-	svc.AddHandler("/trigger-cutoff", xfagg.triggerManualCutoff())
+	svc.AddHandler("/trigger-cutoff", adminauth.Protect(xfagg.cfg.Admin.Signing, xfagg.triggerManualCutoff()))
+	svc.AddHandler("/pipeline/unprocessed-transfers", xfagg.listUnprocessedTransfers())
+	svc.AddHandler("/pipeline/unprocessed-transfers/{transferID}", adminauth.Protect(xfagg.cfg.Admin.Signing, xfagg.dismissUnprocessedTransfer()))
+	svc.AddHandler("/odfi/{routingNumber}/utilization", xfagg.getOriginationUtilization())
+	svc.AddHandler("/pipeline/dead-letters", xfagg.listDeadLetters())
+	svc.AddHandler("/pipeline/dead-letters/{letterID}/replay", adminauth.Protect(xfagg.cfg.Admin.Signing, xfagg.replayDeadLetter()))
+	svc.AddHandler("/files/uploads/retry", adminauth.Protect(xfagg.cfg.Admin.Signing, xfagg.retryFailedUploads()))
 }
 
 type manuallyTriggeredCutoff struct {
@@ -41,3 +56,165 @@ func (xfagg *XferAggregator) triggerManualCutoff() http.HandlerFunc {
 		}
 	}
 }
+
+func (xfagg *XferAggregator) listUnprocessedTransfers() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			moovhttp.Problem(w, fmt.Errorf("invalid method %s", r.Method))
+			return
+		}
+
+		xfers, err := xfagg.repo.getUnprocessedTransfers()
+		if err != nil {
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			moovhttp.Problem(w, err)
+			return
+		}
+		if xfers == nil {
+			xfers = make([]*paygateadmin.UnprocessedTransfer, 0)
+		}
+
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(xfers)
+	}
+}
+
+// dismissUnprocessedTransfer stops retrying a transfer, which is used after it's been
+// resolved manually (e.g. the transfer was deleted).
+func (xfagg *XferAggregator) dismissUnprocessedTransfer() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			moovhttp.Problem(w, fmt.Errorf("invalid method %s", r.Method))
+			return
+		}
+
+		transferID := route.ReadPathID("transferID", r)
+		if err := xfagg.repo.deleteUnprocessedTransfer(transferID); err != nil {
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			moovhttp.Problem(w, err)
+			return
+		}
+		xfagg.logger.Set("transferID", transferID).Log("dismissed unprocessed transfer")
+
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+func (xfagg *XferAggregator) listDeadLetters() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			moovhttp.Problem(w, fmt.Errorf("invalid method %s", r.Method))
+			return
+		}
+
+		letters, err := xfagg.deadLetters.list()
+		if err != nil {
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			moovhttp.Problem(w, err)
+			return
+		}
+		if letters == nil {
+			letters = make([]paygateadmin.DeadLetter, 0)
+		}
+
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(letters)
+	}
+}
+
+// replayDeadLetter handles a dead-lettered message again, which is used once whatever
+// made it fail has been fixed. The dead letter is removed after it's handled.
+func (xfagg *XferAggregator) replayDeadLetter() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			moovhttp.Problem(w, fmt.Errorf("invalid method %s", r.Method))
+			return
+		}
+
+		letterID := route.ReadPathID("letterID", r)
+		letter, err := xfagg.deadLetters.get(letterID)
+		if err != nil {
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			moovhttp.Problem(w, err)
+			return
+		}
+		if letter == nil {
+			http.NotFound(w, r)
+			return
+		}
+		if _, err := handleBody(xfagg.merger, letter.Body); err != nil {
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			moovhttp.Problem(w, err)
+			return
+		}
+		if err := xfagg.deadLetters.remove(letterID); err != nil {
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			moovhttp.Problem(w, err)
+			return
+		}
+		xfagg.logger.Set("letterID", letterID).Log("replayed dead letter")
+
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// retryFailedUploads uploads failed files again by filename or routing number. Each file is
+// uploaded before responding so the agent's errors can be read when uploads keep failing.
+func (xfagg *XferAggregator) retryFailedUploads() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			moovhttp.Problem(w, fmt.Errorf("invalid method %s", r.Method))
+			return
+		}
+
+		var req paygateadmin.RetryUploads
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			moovhttp.Problem(w, fmt.Errorf("invalid request: %v", err))
+			return
+		}
+		req.Filename = strings.TrimSpace(req.Filename)
+		req.RoutingNumber = strings.TrimSpace(req.RoutingNumber)
+		if req.Filename == "" && req.RoutingNumber == "" {
+			moovhttp.Problem(w, errors.New("missing filename or routingNumber"))
+			return
+		}
+
+		results := xfagg.retryUploads(req.Filename, req.RoutingNumber)
+		if len(results) == 0 {
+			http.NotFound(w, r)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(results)
+	}
+}
+
+// RegisterPublisherRoutes adds admin routes for instances which publish Transfers but do not
+// run an XferAggregator. Requests are forwarded through the pipeline to a worker.
+func RegisterPublisherRoutes(cfg *config.Config, svc *admin.Server, pub XferPublisher) {
+	svc.AddHandler("/trigger-cutoff", adminauth.Protect(cfg.Admin.Signing, triggerCutoffThroughPipeline(pub)))
+}
+
+func triggerCutoffThroughPipeline(pub XferPublisher) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			moovhttp.Problem(w, fmt.Errorf("invalid method %s", r.Method))
+			return
+		}
+
+		msg := TriggeredCutoff{
+			TriggerID: base.ID(),
+		}
+		if err := pub.TriggerCutoff(msg); err != nil {
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			moovhttp.Problem(w, err)
+		} else {
+			// Processing happens asynchronously on a worker
+			w.WriteHeader(http.StatusAccepted)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/moov-io/base/log"
+
+	"github.com/moov-io/paygate/pkg/admin"
+	"github.com/moov-io/paygate/pkg/upload"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAggregateAdmin__triggerCutoffThroughPipeline(t *testing.T) {
+	pub := NewMockPublisher()
+	handler := triggerCutoffThroughPipeline(pub)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("PUT", "/trigger-cutoff", nil)
+	handler.ServeHTTP(w, req)
+	w.Flush()
+
+	require.Equal(t, http.StatusAccepted, w.Code)
+	require.Len(t, pub.Cutoffs, 1)
+	require.NotEmpty(t, pub.Cutoffs[0].TriggerID)
+
+	// wrong method
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest("GET", "/trigger-cutoff", nil)
+	handler.ServeHTTP(w, req)
+	w.Flush()
+	require.Equal(t, http.StatusBadRequest, w.Code)
+
+	// publisher error
+	pub.Err = errors.New("bad error")
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest("PUT", "/trigger-cutoff", nil)
+	handler.ServeHTTP(w, req)
+	w.Flush()
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestAggregateAdmin__readTriggeredCutoff(t *testing.T) {
+	pub := testingPublisher(t)
+	sub := testingSubscriber(t, pub)
+
+	require.NoError(t, pub.TriggerCutoff(TriggeredCutoff{TriggerID: "trigger-id"}))
+
+	msg, err := sub.Receive(context.Background())
+	require.NoError(t, err)
+
+	trigger, ok := readTriggeredCutoff(msg)
+	require.True(t, ok)
+	require.Equal(t, "trigger-id", trigger.TriggerID)
+	msg.Ack()
+
+	// Cancels are not triggers
+	require.NoError(t, pub.Cancel(CanceledTransfer{TransferID: "transfer-id"}))
+	msg, err = sub.Receive(context.Background())
+	require.NoError(t, err)
+
+	_, ok = readTriggeredCutoff(msg)
+	require.False(t, ok)
+	msg.Ack()
+}
+
+func TestAggregateAdmin__unprocessedTransfers(t *testing.T) {
+	repo := &MockRepository{
+		Unprocessed: []*admin.UnprocessedTransfer{
+			{TransferID: "transfer-id", Error: "bad error", Attempts: 1},
+		},
+	}
+	xfagg := &XferAggregator{
+		logger: log.NewNopLogger(),
+		repo:   repo,
+	}
+
+	r := mux.NewRouter()
+	r.Path("/pipeline/unprocessed-transfers").HandlerFunc(xfagg.listUnprocessedTransfers())
+	r.Path("/pipeline/unprocessed-transfers/{transferID}").HandlerFunc(xfagg.dismissUnprocessedTransfer())
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/pipeline/unprocessed-transfers", nil))
+	w.Flush()
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var xfers []*admin.UnprocessedTransfer
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&xfers))
+	require.Len(t, xfers, 1)
+	require.Equal(t, "transfer-id", xfers[0].TransferID)
+
+	// dismiss the transfer
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("DELETE", "/pipeline/unprocessed-transfers/transfer-id", nil))
+	w.Flush()
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Len(t, repo.Unprocessed, 0)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/pipeline/unprocessed-transfers", nil))
+	w.Flush()
+	require.Equal(t, "[]", strings.TrimSpace(w.Body.String()))
+
+	// wrong methods
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("PUT", "/pipeline/unprocessed-transfers", nil))
+	require.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/pipeline/unprocessed-transfers/transfer-id", nil))
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestAggregateAdmin__retryFailedUploads(t *testing.T) {
+	agent := &upload.MockAgent{}
+	xfagg := setupRetryAggregator(t, agent, &MockRepository{}, &MockXferMerging{})
+
+	r := mux.NewRouter()
+	r.Path("/files/uploads/retry").HandlerFunc(xfagg.retryFailedUploads())
+
+	// missing filename and routing number
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("POST", "/files/uploads/retry", strings.NewReader(`{}`)))
+	require.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("POST", "/files/uploads/retry", strings.NewReader(`{"filename": "other.ach"}`)))
+	require.Equal(t, http.StatusNotFound, w.Code)
+
+	// the agent's error is returned
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("POST", "/files/uploads/retry", strings.NewReader(`{"routingNumber": "076401251"}`)))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var results []admin.UploadRetry
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&results))
+	require.Len(t, results, 1)
+	require.Equal(t, "20200601-076401251.ach", results[0].Filename)
+	require.False(t, results[0].Uploaded)
+	require.Equal(t, "connection refused", results[0].Error)
+
+	agent.Err = nil
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("POST", "/files/uploads/retry", strings.NewReader(`{"filename": "20200601-076401251.ach"}`)))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&results))
+	require.True(t, results[0].Uploaded)
+
+	// wrong method
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/files/uploads/retry", nil))
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
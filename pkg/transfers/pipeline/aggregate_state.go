@@ -0,0 +1,123 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package pipeline
+
+import (
+	"time"
+
+	"github.com/moov-io/ach"
+
+	"github.com/moov-io/paygate/pkg/achx"
+	"github.com/moov-io/paygate/pkg/admin"
+	"github.com/moov-io/paygate/x/errorlog"
+)
+
+const maxRecentErrors = 50
+
+// PendingTransfers returns transfers waiting for the next cutoff grouped by their RDFI.
+func (xfagg *XferAggregator) PendingTransfers() ([]admin.PendingTransfers, error) {
+	return xfagg.merger.pendingTransfers()
+}
+
+// FailedUploads returns merged files which failed to upload, newest first.
+func (xfagg *XferAggregator) FailedUploads() []admin.FailedUpload {
+	xfagg.uploadsMu.Lock()
+	defer xfagg.uploadsMu.Unlock()
+
+	out := make([]admin.FailedUpload, len(xfagg.failedUploads))
+	for i := range xfagg.failedUploads {
+		out[len(xfagg.failedUploads)-1-i] = xfagg.failedUploads[i].FailedUpload
+	}
+	return out
+}
+
+// RecentErrors returns errors from merging, uploading and marking transfers, newest first.
+func (xfagg *XferAggregator) RecentErrors() []errorlog.Entry {
+	return xfagg.errors.Entries()
+}
+
+// failedUpload is a merged file which failed to upload along with what's needed to retry it.
+type failedUpload struct {
+	admin.FailedUpload
+
+	contents       []byte
+	file           *ach.File
+	correlationIDs []string
+}
+
+func newFailedUpload(fileID, filename string, contents []byte, file *ach.File, correlationIDs []string, message string, created time.Time) failedUpload {
+	failed := failedUpload{
+		FailedUpload: admin.FailedUpload{
+			FileID:   fileID,
+			Filename: filename,
+			Error:    message,
+			Created:  created,
+		},
+		contents:       contents,
+		file:           file,
+		correlationIDs: correlationIDs,
+	}
+	if file != nil {
+		failed.Entries = int32(len(achx.Entries(file)))
+	}
+	return failed
+}
+
+// recordFailedUpload lists a file with the failed uploads and saves it with the merger, so
+// it can still be retried after a restart. A file which fails again replaces its earlier failure.
+func (xfagg *XferAggregator) recordFailedUpload(fileID, filename string, contents []byte, file *ach.File, correlationIDs []string, err error) {
+	xfagg.errors.Add("upload", err)
+
+	failed := newFailedUpload(fileID, filename, contents, file, correlationIDs, err.Error(), xfagg.clock.Now())
+	if xfagg.merger != nil && file != nil {
+		if err := xfagg.merger.saveFailedUpload(failed); err != nil {
+			xfagg.errors.Add("upload", err)
+			xfagg.logger.Set("fileID", fileID).LogErrorf("ERROR saving failed upload of %s: %v", filename, err)
+		}
+	}
+
+	xfagg.uploadsMu.Lock()
+	defer xfagg.uploadsMu.Unlock()
+
+	kept := xfagg.failedUploads[:0]
+	for i := range xfagg.failedUploads {
+		if xfagg.failedUploads[i].FileID != fileID {
+			kept = append(kept, xfagg.failedUploads[i])
+		}
+	}
+	xfagg.failedUploads = append(kept, failed)
+}
+
+// takeFailedUploads removes and returns the failed uploads with the filename or sent to the
+// routing number, oldest first. Files which fail again are recorded as new failed uploads.
+func (xfagg *XferAggregator) takeFailedUploads(filename, routingNumber string) []failedUpload {
+	xfagg.uploadsMu.Lock()
+	defer xfagg.uploadsMu.Unlock()
+
+	var taken, kept []failedUpload
+	for i := range xfagg.failedUploads {
+		failed := xfagg.failedUploads[i]
+		if failed.matches(filename, routingNumber) {
+			taken = append(taken, failed)
+		} else {
+			kept = append(kept, failed)
+		}
+	}
+	xfagg.failedUploads = kept
+	return taken
+}
+
+func (f failedUpload) matches(filename, routingNumber string) bool {
+	if f.file == nil {
+		return false
+	}
+	if filename != "" && f.Filename != filename {
+		return false
+	}
+	if routingNumber != "" && f.file.Header.ImmediateDestination != routingNumber {
+		return false
+	}
+	return filename != "" || routingNumber != ""
+}
@@ -7,8 +7,12 @@ package pipeline
 import (
 	"context"
 	"errors"
+	"fmt"
+	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 
@@ -22,7 +26,16 @@ import (
 	"github.com/moov-io/base"
 	"gocloud.dev/pubsub"
 
+	"github.com/moov-io/paygate/internal"
+	"github.com/moov-io/paygate/pkg/admin"
 	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/transfers/files"
+	"github.com/moov-io/paygate/pkg/transfers/pipeline/audittrail"
+	"github.com/moov-io/paygate/pkg/transfers/pipeline/output"
+	"github.com/moov-io/paygate/pkg/webhooks"
+	"github.com/moov-io/paygate/x/errorlog"
+	"github.com/moov-io/paygate/x/schedule"
 )
 
 func TestAggregate__handleMessageXfer(t *testing.T) {
@@ -47,7 +60,7 @@ func TestAggregate__handleMessageXfer(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	if err := handleMessage(merge, msg); err != nil {
+	if err := handleMessage("test", merge, nil, msg); err != nil {
 		t.Fatal(err)
 	}
 
@@ -77,7 +90,7 @@ func TestAggregate__handleMessageCancel(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	if err := handleMessage(merge, msg); err != nil {
+	if err := handleMessage("test", merge, nil, msg); err != nil {
 		t.Fatal(err)
 	}
 
@@ -92,7 +105,7 @@ func TestAggregate__handleMessageErr(t *testing.T) {
 		Body: []byte("unexpected message"),
 	}
 
-	if err := handleMessage(merge, msg); err == nil {
+	if err := handleMessage("test", merge, nil, msg); err == nil {
 		t.Error("expected error")
 	}
 }
@@ -106,7 +119,7 @@ func TestAggregate_notifyAfterUpload(t *testing.T) {
 	}
 
 	require.NotPanics(t, func() {
-		xferAggregator.notifyAfterUpload("filename.txt", nil, nil)
+		xferAggregator.notifyAfterUpload("filename.txt", nil, nil, nil)
 	})
 	require.True(t, mockNotifier.InfoWasCalled())
 	require.False(t, mockNotifier.CriticalWasCalled())
@@ -123,10 +136,270 @@ func TestAggregate_notifyAfterUploadErr(t *testing.T) {
 	}
 
 	require.NotPanics(t, func() {
-		xferAggregator.notifyAfterUpload("filename.txt", nil, errors.New("upload failed"))
+		xferAggregator.notifyAfterUpload("filename.txt", nil, nil, errors.New("upload failed"))
 	})
 	require.False(t, mockNotifier.InfoWasCalled())
 	require.True(t, mockNotifier.CriticalWasCalled())
 	require.NotEmpty(t, mockNotifier.CapturedMessage())
 	require.NotEmpty(t, mockNotifier.CapturedMessage().Hostname)
 }
+
+func TestAggregate_markTransfersAsProcessed(t *testing.T) {
+	repo := &MockRepository{
+		Unprocessed: []*admin.UnprocessedTransfer{
+			{TransferID: "previous"},
+		},
+	}
+	xferAggregator := &XferAggregator{
+		logger: log.NewNopLogger(),
+		repo:   repo,
+	}
+
+	// unprocessed transfers from earlier cutoffs are retried
+	err := xferAggregator.markTransfersAsProcessed(newProcessedTransfers([]string{"transfer-id.ach"}))
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"transfer-id", "previous"}, repo.Processed)
+
+	// retries happen even if nothing was uploaded
+	repo.Processed = nil
+	repo.Unprocessed = []*admin.UnprocessedTransfer{{TransferID: "previous"}}
+	require.NoError(t, xferAggregator.markTransfersAsProcessed(nil))
+	require.Equal(t, []string{"previous"}, repo.Processed)
+
+	repo.Err = errors.New("bad error")
+	require.Error(t, xferAggregator.markTransfersAsProcessed(newProcessedTransfers([]string{"transfer-id.ach"})))
+}
+
+func TestAggregate_markTransfersAsProcessedWebhooks(t *testing.T) {
+	repo := &MockRepository{
+		Processed: []string{"already"},
+		Organizations: map[string]string{
+			"transfer-id": "moov",
+			"already":     "moov",
+		},
+	}
+	events := &webhooks.MockSender{}
+	xferAggregator := &XferAggregator{
+		logger: log.NewNopLogger(),
+		repo:   repo,
+		events: events,
+	}
+
+	// transfers which were already PROCESSED aren't sent again
+	err := xferAggregator.markTransfersAsProcessed(newProcessedTransfers([]string{"transfer-id.ach", "already.ach"}))
+	require.NoError(t, err)
+	require.Len(t, events.Events, 1)
+
+	event := events.Events[0]
+	require.Equal(t, webhooks.EventTransferProcessed, event.Type)
+	require.Equal(t, "moov", event.Organization)
+	require.Equal(t, webhooks.TransferUpdate{TransferID: "transfer-id", Status: client.PROCESSED}, event.Data)
+
+	// webhook failures don't fail marking transfers
+	events.Err = errors.New("bad error")
+	repo.Organizations["other"] = "moov"
+	require.NoError(t, xferAggregator.markTransfersAsProcessed(newProcessedTransfers([]string{"other.ach"})))
+	require.Len(t, events.Events, 2)
+}
+
+func TestAggregate_recordFailedUpload(t *testing.T) {
+	now := time.Date(2020, time.June, 1, 16, 20, 0, 0, time.UTC)
+	xferAggregator := &XferAggregator{
+		logger: log.NewNopLogger(),
+		clock:  schedule.NewMockClock(now),
+		errors: errorlog.New(maxRecentErrors),
+	}
+	require.Len(t, xferAggregator.FailedUploads(), 0)
+
+	file, err := ach.ReadFile(filepath.Join("..", "..", "..", "testdata", "ppd-debit.ach"))
+	require.NoError(t, err)
+
+	for i := 0; i < 25; i++ {
+		xferAggregator.recordFailedUpload(fmt.Sprintf("id-%d", i), fmt.Sprintf("file-%d.ach", i), []byte("contents"), file, nil, errors.New("connection refused"))
+	}
+
+	failed := xferAggregator.FailedUploads()
+	require.Len(t, failed, 25)
+	require.Equal(t, "id-24", failed[0].FileID)
+	require.Equal(t, "file-24.ach", failed[0].Filename)
+	require.Equal(t, int32(1), failed[0].Entries)
+	require.Equal(t, "connection refused", failed[0].Error)
+	require.Equal(t, now, failed[0].Created)
+
+	errs := xferAggregator.RecentErrors()
+	require.Len(t, errs, 25)
+	require.Equal(t, "upload", errs[0].Component)
+
+	// files which fail again replace their earlier failure
+	xferAggregator.recordFailedUpload("id-3", "file-3.ach", []byte("contents"), file, nil, errors.New("timeout"))
+	failed = xferAggregator.FailedUploads()
+	require.Len(t, failed, 25)
+	require.Equal(t, "file-3.ach", failed[0].Filename)
+	require.Equal(t, "timeout", failed[0].Error)
+
+	// other files with the same filename are kept
+	xferAggregator.recordFailedUpload("id-25", "file-3.ach", []byte("contents"), file, nil, errors.New("timeout"))
+	failed = xferAggregator.FailedUploads()
+	require.Len(t, failed, 26)
+	require.Equal(t, "id-25", failed[0].FileID)
+	require.Equal(t, "id-3", failed[1].FileID)
+}
+
+func TestAggregate_recordUpload(t *testing.T) {
+	file, err := ach.ReadFile(filepath.Join("..", "..", "..", "testdata", "ppd-debit.ach"))
+	require.NoError(t, err)
+
+	repo := &files.MockRepository{}
+	xferAggregator := &XferAggregator{
+		logger: log.NewNopLogger(),
+		clock:  schedule.System,
+		agent:  &upload.MockAgent{},
+		files:  repo,
+	}
+	xferAggregator.recordUpload("id-1", "20200601-987654320.ach", []byte("contents"), file)
+	require.Equal(t, []string{"20200601-987654320.ach"}, repo.Uploaded)
+
+	// failures are only logged as the file was uploaded
+	repo.Err = errors.New("bad error")
+	require.NotPanics(t, func() {
+		xferAggregator.recordUpload("id-2", "20200602-987654320.ach", []byte("contents"), file)
+	})
+
+	// upload history is optional
+	xferAggregator.files = nil
+	require.NotPanics(t, func() {
+		xferAggregator.recordUpload("id-3", "20200603-987654320.ach", []byte("contents"), file)
+	})
+}
+
+func TestAggregate_assignSequence(t *testing.T) {
+	file, err := ach.ReadFile(filepath.Join("..", "..", "..", "testdata", "ppd-debit.ach"))
+	require.NoError(t, err)
+
+	repo := &files.MockRepository{}
+	clock := schedule.NewMockClock(time.Date(2020, time.June, 1, 16, 20, 0, 0, time.UTC))
+	xferAggregator := &XferAggregator{
+		logger: log.NewNopLogger(),
+		clock:  clock,
+		files:  repo,
+	}
+
+	seq, err := xferAggregator.assignSequence(file, clock.Now())
+	require.NoError(t, err)
+	require.Equal(t, 1, seq)
+	require.Equal(t, "A", file.Header.FileIDModifier)
+	require.Equal(t, clock.Now(), repo.SequenceDay)
+
+	seq, err = xferAggregator.assignSequence(file, clock.Now())
+	require.NoError(t, err)
+	require.Equal(t, 2, seq)
+	require.Equal(t, "B", file.Header.FileIDModifier)
+
+	// the day's modifiers are used up
+	repo.Sequence = 36
+	_, err = xferAggregator.assignSequence(file, clock.Now())
+	require.Error(t, err)
+
+	// files which weren't sent give their sequence back
+	repo.Sequence = 2
+	xferAggregator.releaseSequence(file, clock.Now(), 2)
+	seq, err = xferAggregator.assignSequence(file, clock.Now())
+	require.NoError(t, err)
+	require.Equal(t, 2, seq)
+
+	repo.Err = errors.New("bad error")
+	_, err = xferAggregator.assignSequence(file, clock.Now())
+	require.Error(t, err)
+
+	// upload history is optional
+	xferAggregator.files = nil
+	seq, err = xferAggregator.assignSequence(file, clock.Now())
+	require.NoError(t, err)
+	require.Equal(t, 0, seq)
+}
+
+// setupCutoffAggregator returns an aggregator which merges transfers from a directory and
+// uploads them with the default filename template.
+func setupCutoffAggregator(t *testing.T) (*XferAggregator, *filesystemMerging, *files.MockRepository) {
+	t.Helper()
+
+	merger := &filesystemMerging{
+		baseDir: filepath.Join(internal.TestDir(t), "mergable"),
+		logger:  log.NewNopLogger(),
+	}
+	require.NoError(t, os.MkdirAll(merger.baseDir, 0777))
+
+	cfg := config.Empty()
+	filenames, err := upload.NewFilenameProvider(cfg.ODFI)
+	require.NoError(t, err)
+
+	filesRepo := &files.MockRepository{}
+	xfagg := &XferAggregator{
+		cfg:             cfg,
+		logger:          log.NewNopLogger(),
+		clock:           schedule.System,
+		agent:           &upload.MockAgent{},
+		notifier:        &notify.MockSender{},
+		repo:            &MockRepository{},
+		files:           filesRepo,
+		merger:          merger,
+		auditStorage:    &audittrail.MockStorage{},
+		outputFormatter: &output.NACHA{},
+		filenames:       filenames,
+		errors:          errorlog.New(maxRecentErrors),
+	}
+	return xfagg, merger, filesRepo
+}
+
+func TestAggregate__sameDayFilenames(t *testing.T) {
+	xfagg, merger, filesRepo := setupCutoffAggregator(t)
+
+	read := func() *ach.File {
+		file, err := ach.ReadFile(filepath.Join("..", "..", "..", "testdata", "ppd-debit.ach"))
+		require.NoError(t, err)
+		return file
+	}
+	nextDay, sameDay := read(), read()
+	sameDay.Batches[0].GetHeader().CompanyDescriptiveDate = "SD1300"
+
+	require.NoError(t, merger.HandleXfer(Xfer{Transfer: &client.Transfer{TransferID: base.ID()}, File: nextDay}))
+	require.NoError(t, merger.HandleXfer(Xfer{Transfer: &client.Transfer{TransferID: base.ID()}, File: sameDay}))
+
+	processed, err := merger.WithEachMerged(xfagg.runTransformers)
+	require.NoError(t, err)
+	require.Len(t, processed.transferIDs, 2)
+
+	// the same-day and next-day files are uploaded under their own names
+	require.Len(t, filesRepo.Uploaded, 2)
+	require.NotEqual(t, filesRepo.Uploaded[0], filesRepo.Uploaded[1])
+	require.Empty(t, xfagg.FailedUploads())
+}
+
+func TestAggregate__iatFilenames(t *testing.T) {
+	xfagg, merger, filesRepo := setupCutoffAggregator(t)
+
+	international := iatFile(t)
+	domestic, err := ach.ReadFile(filepath.Join("..", "..", "..", "testdata", "ppd-debit.ach"))
+	require.NoError(t, err)
+	domestic.Header.ImmediateDestination = international.Header.ImmediateDestination
+
+	require.NoError(t, merger.HandleXfer(Xfer{Transfer: &client.Transfer{TransferID: base.ID()}, File: international}))
+	require.NoError(t, merger.HandleXfer(Xfer{Transfer: &client.Transfer{TransferID: base.ID()}, File: domestic}))
+
+	processed, err := merger.WithEachMerged(xfagg.runTransformers)
+	require.NoError(t, err)
+	require.Len(t, processed.transferIDs, 2)
+
+	// the IAT and domestic files for a destination are uploaded under their own names
+	require.Len(t, filesRepo.Uploaded, 2)
+	require.NotEqual(t, filesRepo.Uploaded[0], filesRepo.Uploaded[1])
+
+	var iat []string
+	for _, filename := range filesRepo.Uploaded {
+		if strings.Contains(filename, "-IAT") {
+			iat = append(iat, filename)
+		}
+	}
+	require.Len(t, iat, 1)
+	require.Empty(t, xfagg.FailedUploads())
+}
@@ -0,0 +1,322 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package pipeline
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/moov-io/ach"
+	baseadmin "github.com/moov-io/base/admin"
+	moovhttp "github.com/moov-io/base/http"
+	"github.com/moov-io/base/log"
+
+	"github.com/moov-io/paygate/pkg/achx"
+	"github.com/moov-io/paygate/pkg/admin"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/upload"
+	"github.com/moov-io/paygate/pkg/util"
+	"github.com/moov-io/paygate/x/adminauth"
+	"github.com/moov-io/paygate/x/route"
+)
+
+// certificationScenario breaks one NACHA rule in an otherwise valid file.
+type certificationScenario struct {
+	name        string
+	description string
+	apply       func(file *ach.File, now time.Time)
+}
+
+// certificationScenarios are the files ODFIs commonly ask originators to send so they can
+// check each one is rejected.
+var certificationScenarios = []certificationScenario{
+	{
+		name:        "unbalanced-batch",
+		description: "The batch control's total credit amount is one cent more than its entries",
+		apply: func(file *ach.File, now time.Time) {
+			file.Batches[0].GetControl().TotalCreditEntryDollarAmount += 1
+		},
+	},
+	{
+		name:        "unbalanced-file",
+		description: "The file control's total credit amount is one cent more than its batches",
+		apply: func(file *ach.File, now time.Time) {
+			file.Control.TotalCreditEntryDollarAmountInFile += 1
+		},
+	},
+	{
+		name:        "invalid-dfi",
+		description: "An entry's receiving DFI routing number has an incorrect check digit",
+		apply: func(file *ach.File, now time.Time) {
+			entry := file.Batches[0].GetEntries()[0]
+			digit, _ := strconv.Atoi(entry.CheckDigit)
+			entry.CheckDigit = strconv.Itoa((digit + 1) % 10)
+		},
+	},
+	{
+		name:        "stale-effective-date",
+		description: "The batch's effective entry date is 30 days in the past",
+		apply: func(file *ach.File, now time.Time) {
+			file.Batches[0].GetHeader().EffectiveEntryDate = now.AddDate(0, 0, -30).Format("060102")
+		},
+	},
+	{
+		name:        "duplicate-trace-numbers",
+		description: "Two entries in the batch share a trace number",
+		apply: func(file *ach.File, now time.Time) {
+			entries := file.Batches[0].GetEntries()
+			entries[1].TraceNumber = entries[0].TraceNumber
+		},
+	},
+}
+
+func findCertificationScenario(name string) *certificationScenario {
+	for i := range certificationScenarios {
+		if certificationScenarios[i].name == name {
+			return &certificationScenarios[i]
+		}
+	}
+	return nil
+}
+
+// Certifier uploads files from a catalog of certification scenarios to the ODFI's test server.
+// Files are named like any other upload but are never sent to the production ODFI, merged with
+// Transfers or recorded in the upload history.
+//
+// A nil *Certifier is valid and does nothing.
+type Certifier struct {
+	odfi   config.ODFI
+	cfg    *config.Config
+	logger log.Logger
+
+	agent     upload.Agent
+	filenames upload.FilenameProvider
+
+	mu       sync.Mutex
+	day      string
+	sequence int
+}
+
+func NewCertifier(cfg *config.Config) (*Certifier, error) {
+	if cfg.Pipeline.Certification == nil {
+		return nil, nil
+	}
+	odfi := cfg.Pipeline.Certification.Agent(cfg.ODFI)
+	logger := cfg.Logger.Set("service", "Certifier")
+
+	agent, err := upload.New(logger, odfi)
+	if err != nil {
+		return nil, fmt.Errorf("certification: %v", err)
+	}
+	filenames, err := upload.NewFilenameProvider(odfi)
+	if err != nil {
+		agent.Close()
+		return nil, fmt.Errorf("certification: %v", err)
+	}
+	return &Certifier{
+		odfi:      odfi,
+		cfg:       cfg,
+		logger:    logger,
+		agent:     agent,
+		filenames: filenames,
+	}, nil
+}
+
+func (c *Certifier) Close() error {
+	if c == nil {
+		return nil
+	}
+	return c.agent.Close()
+}
+
+// RegisterRoutes adds admin endpoints to list the scenarios and upload their files.
+func (c *Certifier) RegisterRoutes(svc *baseadmin.Server) {
+	if c == nil {
+		return
+	}
+	svc.AddHandler("/certification/scenarios", c.listScenarios())
+	svc.AddHandler("/certification/scenarios/{scenario}", adminauth.Protect(c.cfg.Admin.Signing, c.uploadScenario()))
+}
+
+func (c *Certifier) listScenarios() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			moovhttp.Problem(w, fmt.Errorf("invalid method %s", r.Method))
+			return
+		}
+		out := make([]admin.CertificationScenario, 0, len(certificationScenarios))
+		for i := range certificationScenarios {
+			out = append(out, admin.CertificationScenario{
+				Name:        certificationScenarios[i].name,
+				Description: certificationScenarios[i].description,
+			})
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(out)
+	}
+}
+
+func (c *Certifier) uploadScenario() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+
+		if r.Method != http.MethodPost {
+			moovhttp.Problem(w, fmt.Errorf("invalid method %s", r.Method))
+			return
+		}
+		scenario := findCertificationScenario(route.ReadPathID("scenario", r))
+		if scenario == nil {
+			moovhttp.Problem(w, fmt.Errorf("unknown scenario %q", route.ReadPathID("scenario", r)))
+			return
+		}
+		uploaded, err := c.upload(scenario, time.Now())
+		if err != nil {
+			moovhttp.Problem(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(uploaded)
+	}
+}
+
+// upload generates the scenario's file and uploads it to the test server.
+func (c *Certifier) upload(scenario *certificationScenario, now time.Time) (*admin.CertificationFile, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// files sent on the same day need their own FileIDModifier
+	if day := now.Format("20060102"); day != c.day {
+		c.day, c.sequence = day, 0
+	}
+	c.sequence++
+
+	file, err := certificationFile(c.odfi, c.sequence, now)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", scenario.name, err)
+	}
+	scenario.apply(file, now)
+	contents := encodeCertificationFile(file)
+
+	filename, err := c.filenames.Filename(upload.FilenameData{
+		RoutingNumber: c.odfi.RoutingNumber,
+		Sequence:      c.sequence,
+		File:          file,
+		Contents:      contents,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("problem naming file: %v", err)
+	}
+	err = c.agent.UploadFile(upload.File{
+		Filename:      filename,
+		Contents:      ioutil.NopCloser(bytes.NewReader(contents)),
+		RoutingNumber: c.odfi.RoutingNumber,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("problem uploading %s: %v", filename, err)
+	}
+
+	c.logger.With(log.Fields{
+		"scenario": scenario.name,
+		"filename": filename,
+	}).Log("uploaded certification file")
+
+	return &admin.CertificationFile{
+		Scenario:      scenario.name,
+		Filename:      filename,
+		RoutingNumber: c.odfi.RoutingNumber,
+		Hostname:      c.agent.Hostname(),
+		Uploaded:      now,
+	}, nil
+}
+
+// certificationFile returns a valid file with one batch of a credit and debit to the test ODFI,
+// which each scenario then breaks.
+func certificationFile(odfi config.ODFI, sequence int, now time.Time) (*ach.File, error) {
+	file := ach.NewFile()
+	file.Header.ImmediateOrigin = util.Or(odfi.Gateway.Origin, odfi.RoutingNumber)
+	file.Header.ImmediateOriginName = odfi.Gateway.OriginName
+	file.Header.ImmediateDestination = util.Or(odfi.Gateway.Destination, odfi.RoutingNumber)
+	file.Header.ImmediateDestinationName = odfi.Gateway.DestinationName
+	file.Header.FileCreationDate = now.Format("060102")
+	file.Header.FileCreationTime = now.Format("1504")
+
+	modifier, err := upload.FileIDModifier(sequence)
+	if err != nil {
+		return nil, err
+	}
+	file.Header.FileIDModifier = modifier
+
+	bh := ach.NewBatchHeader()
+	bh.ServiceClassCode = ach.MixedDebitsAndCredits
+	bh.StandardEntryClassCode = ach.PPD
+	bh.CompanyName = util.Or(odfi.Gateway.OriginName, "PayGate")
+	bh.CompanyIdentification = odfi.FileConfig.BatchHeader.CompanyIdentification
+	bh.CompanyEntryDescription = "CERTIFY"
+	bh.CompanyDescriptiveDate = now.Format("060102")
+	bh.EffectiveEntryDate = now.AddDate(0, 0, 1).Format("060102")
+	bh.ODFIIdentification = achx.ABA8(odfi.RoutingNumber)
+
+	batch, err := ach.NewBatch(bh)
+	if err != nil {
+		return nil, err
+	}
+	// trace numbers must be ascending within the batch
+	trace, err := strconv.ParseInt(achx.TraceNumber(odfi.RoutingNumber), 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	for i, code := range []int{ach.CheckingCredit, ach.CheckingDebit} {
+		ed := ach.NewEntryDetail()
+		ed.TransactionCode = code
+		ed.RDFIIdentification = achx.ABA8(odfi.RoutingNumber)
+		ed.CheckDigit = achx.ABACheckDigit(odfi.RoutingNumber)
+		ed.DFIAccountNumber = fmt.Sprintf("12345678%d", i)
+		ed.Amount = 100
+		ed.IdentificationNumber = fmt.Sprintf("CERT%d", i)
+		ed.IndividualName = "Certification Test"
+		ed.TraceNumber = fmt.Sprintf("%d", trace+int64(i))
+		ed.Category = ach.CategoryForward
+		batch.AddEntry(ed)
+	}
+	batch.SetControl(ach.NewBatchControl())
+	if err := batch.Create(); err != nil {
+		return nil, err
+	}
+	file.AddBatch(batch)
+
+	if err := file.Create(); err != nil {
+		return nil, err
+	}
+	if err := file.Validate(); err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+// encodeCertificationFile writes each record of file in NACHA format. ach.Writer validates
+// files first, which would reject every scenario.
+func encodeCertificationFile(file *ach.File) []byte {
+	records := []string{file.Header.String()}
+	for _, batch := range file.Batches {
+		records = append(records, batch.GetHeader().String())
+		for _, entry := range batch.GetEntries() {
+			records = append(records, entry.String())
+		}
+		records = append(records, batch.GetControl().String())
+	}
+	records = append(records, file.Control.String())
+	for len(records)%10 != 0 {
+		records = append(records, strings.Repeat("9", ach.RecordLength))
+	}
+	return []byte(strings.Join(records, "\n") + "\n")
+}
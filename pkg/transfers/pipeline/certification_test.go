@@ -0,0 +1,121 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package pipeline
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/moov-io/ach"
+	"github.com/moov-io/paygate/pkg/admin"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/upload"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+)
+
+func testingCertifier(t *testing.T) (*Certifier, *upload.MockAgent) {
+	t.Helper()
+
+	cfg := config.Empty()
+	cfg.ODFI.RoutingNumber = "987654320"
+	cfg.ODFI.FileConfig.BatchHeader.CompanyIdentification = "MOOVZZZZZZ"
+	cfg.Pipeline.Certification = &config.Certification{
+		RoutingNumber: "121042882",
+		Blob:          &config.Blob{BucketURI: "mem://"},
+	}
+	odfi := cfg.Pipeline.Certification.Agent(cfg.ODFI)
+
+	filenames, err := upload.NewFilenameProvider(odfi)
+	require.NoError(t, err)
+
+	agent := &upload.MockAgent{}
+	return &Certifier{
+		odfi:      odfi,
+		cfg:       cfg,
+		logger:    cfg.Logger,
+		agent:     agent,
+		filenames: filenames,
+	}, agent
+}
+
+func TestCertifier__nil(t *testing.T) {
+	certifier, err := NewCertifier(config.Empty())
+	require.NoError(t, err)
+	require.Nil(t, certifier)
+	require.NoError(t, certifier.Close())
+}
+
+func TestCertifier__scenarios(t *testing.T) {
+	certifier, agent := testingCertifier(t)
+	now := time.Now()
+
+	// the file before any scenario is applied is valid
+	file, err := certificationFile(certifier.odfi, 1, now)
+	require.NoError(t, err)
+	_, err = ach.NewReader(bytes.NewReader(encodeCertificationFile(file))).Read()
+	require.NoError(t, err)
+
+	for i := range certificationScenarios {
+		scenario := &certificationScenarios[i]
+
+		uploaded, err := certifier.upload(scenario, now)
+		require.NoError(t, err, scenario.name)
+		require.Equal(t, scenario.name, uploaded.Scenario)
+		require.Equal(t, "121042882", uploaded.RoutingNumber)
+		require.NotNil(t, agent.UploadedFile)
+		require.Equal(t, uploaded.Filename, agent.UploadedFile.Filename)
+
+		bs, err := ioutil.ReadAll(agent.UploadedFile.Contents)
+		require.NoError(t, err)
+		require.Equal(t, 0, len(bs)%(ach.RecordLength+1), scenario.name)
+
+		parsed, err := ach.NewReader(bytes.NewReader(bs)).Read()
+		if scenario.name == "stale-effective-date" {
+			require.NoError(t, err)
+			require.Equal(t, now.AddDate(0, 0, -30).Format("060102"), parsed.Batches[0].GetHeader().EffectiveEntryDate)
+		} else {
+			require.Error(t, err, scenario.name)
+		}
+	}
+}
+
+func TestCertifier__routes(t *testing.T) {
+	certifier, agent := testingCertifier(t)
+
+	router := mux.NewRouter()
+	router.Handle("/certification/scenarios", certifier.listScenarios())
+	router.Handle("/certification/scenarios/{scenario}", certifier.uploadScenario())
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/certification/scenarios", nil))
+	w.Flush()
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var scenarios []admin.CertificationScenario
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&scenarios))
+	require.Len(t, scenarios, len(certificationScenarios))
+	require.Equal(t, "unbalanced-batch", scenarios[0].Name)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/certification/scenarios/invalid-dfi", nil))
+	w.Flush()
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var uploaded admin.CertificationFile
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&uploaded))
+	require.Equal(t, "invalid-dfi", uploaded.Scenario)
+	require.Equal(t, agent.UploadedFile.Filename, uploaded.Filename)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/certification/scenarios/other", nil))
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
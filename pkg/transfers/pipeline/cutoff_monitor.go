@@ -0,0 +1,179 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/moov-io/base/log"
+
+	"github.com/moov-io/paygate/pkg/admin"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/transfers/pipeline/notify"
+	"github.com/moov-io/paygate/x/schedule"
+)
+
+// CutoffMonitor periodically compares the time left before the next cutoff (skipping
+// weekends and holidays) with how long merging and uploading the pending transfers is
+// projected to take. Metrics are updated on each check and a notification is sent once
+// per cutoff when the upload might be late, so operators can trigger a cutoff early.
+//
+// A nil *CutoffMonitor is valid and does nothing.
+type CutoffMonitor struct {
+	cfg      *config.CutoffMonitor
+	odfi     config.ODFI
+	clock    schedule.Clock
+	logger   log.Logger
+	merger   XferMerging
+	notifier notify.Sender
+
+	warnedCutoff time.Time
+	lastRDFIs    map[string]bool
+}
+
+func NewCutoffMonitor(cfg *config.Config, clock schedule.Clock, merger XferMerging, notifier notify.Sender) *CutoffMonitor {
+	if cfg.Pipeline.CutoffMonitor == nil {
+		return nil
+	}
+	return &CutoffMonitor{
+		cfg:      cfg.Pipeline.CutoffMonitor,
+		odfi:     cfg.ODFI,
+		clock:    clock,
+		logger:   cfg.Logger.Set("service", "CutoffMonitor"),
+		merger:   merger,
+		notifier: notifier,
+	}
+}
+
+func (m *CutoffMonitor) Start(ctx context.Context) {
+	if m == nil {
+		return
+	}
+	ticker := time.NewTicker(m.cfg.CheckInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if _, err := m.check(); err != nil {
+				m.logger.LogErrorf("ERROR checking upcoming cutoff: %v", err)
+			}
+
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+type cutoffForecast struct {
+	Cutoff    time.Time
+	Remaining time.Duration
+	Projected time.Duration
+	Pending   []admin.PendingTransfers
+
+	// Breach is true when the pending transfers might not be uploaded with the
+	// configured margin left before the cutoff.
+	Breach bool
+}
+
+func (fc *cutoffForecast) transfers() (count int, amount int64) {
+	for i := range fc.Pending {
+		count += int(fc.Pending[i].Transfers)
+		amount += fc.Pending[i].TotalAmount
+	}
+	return count, amount
+}
+
+func (m *CutoffMonitor) check() (*cutoffForecast, error) {
+	now := m.clock.Now()
+	next, err := schedule.NextDatedCutoff(m.clock, m.odfi.Cutoffs.Timezone, m.odfi.Cutoffs.WindowsOn)
+	if err != nil {
+		return nil, fmt.Errorf("finding next cutoff: %v", err)
+	}
+	pending, err := m.merger.pendingTransfers()
+	if err != nil {
+		return nil, fmt.Errorf("reading pending transfers: %v", err)
+	}
+	sort.Slice(pending, func(i, j int) bool {
+		return pending[i].RoutingNumber < pending[j].RoutingNumber
+	})
+
+	forecast := &cutoffForecast{
+		Cutoff:    next,
+		Remaining: next.Sub(now),
+		Pending:   pending,
+	}
+	count, _ := forecast.transfers()
+	forecast.Projected = m.cfg.Projected(count)
+	forecast.Breach = count > 0 && forecast.Remaining-forecast.Projected < m.cfg.SafetyMargin()
+
+	m.recordMetrics(forecast)
+
+	if forecast.Breach && !forecast.Cutoff.Equal(m.warnedCutoff) {
+		m.warn(forecast)
+		m.warnedCutoff = forecast.Cutoff
+	}
+	return forecast, nil
+}
+
+func (m *CutoffMonitor) recordMetrics(forecast *cutoffForecast) {
+	odfi := m.odfi.RoutingNumber
+	cutoffSecondsRemaining.With("routing_number", odfi).Set(forecast.Remaining.Seconds())
+	cutoffProjectedSeconds.With("routing_number", odfi).Set(forecast.Projected.Seconds())
+
+	current := make(map[string]bool)
+	for i := range forecast.Pending {
+		rdfi := forecast.Pending[i].RoutingNumber
+		cutoffPendingTransfers.With("routing_number", rdfi).Set(float64(forecast.Pending[i].Transfers))
+		cutoffPendingAmount.With("routing_number", rdfi).Set(float64(forecast.Pending[i].TotalAmount))
+		current[rdfi] = true
+	}
+	// Reset routing numbers which were merged since our last check
+	for rdfi := range m.lastRDFIs {
+		if !current[rdfi] {
+			cutoffPendingTransfers.With("routing_number", rdfi).Set(0)
+			cutoffPendingAmount.With("routing_number", rdfi).Set(0)
+		}
+	}
+	m.lastRDFIs = current
+}
+
+func (m *CutoffMonitor) warn(forecast *cutoffForecast) {
+	cutoffBreachWarnings.With("routing_number", m.odfi.RoutingNumber).Add(1)
+
+	detail := marshalCutoffWarning(forecast)
+	m.logger.Log(detail)
+
+	if m.notifier == nil {
+		return
+	}
+	msg := &notify.Message{
+		Direction: notify.CutoffWarning,
+		Detail:    detail,
+	}
+	if err := m.notifier.Critical(msg); err != nil {
+		m.logger.LogErrorf("problem sending cutoff warning: %v", err)
+	}
+}
+
+func marshalCutoffWarning(forecast *cutoffForecast) string {
+	count, amount := forecast.transfers()
+
+	var buf strings.Builder
+	buf.WriteString(fmt.Sprintf("%d transfers ($%.2f) might not be uploaded before the %s cutoff in %v, ",
+		count, float64(amount)/100.0, forecast.Cutoff.Format("2006-01-02 15:04 MST"), forecast.Remaining.Round(time.Second)))
+	buf.WriteString(fmt.Sprintf("merging and uploading is projected to take %v. ", forecast.Projected.Round(time.Second)))
+	buf.WriteString("Trigger the cutoff early with 'PUT /trigger-cutoff' on the admin server.")
+
+	for i := range forecast.Pending {
+		p := forecast.Pending[i]
+		buf.WriteString(fmt.Sprintf("\n  %s: %d transfers ($%.2f)", p.RoutingNumber, p.Transfers, float64(p.TotalAmount)/100.0))
+	}
+	return buf.String()
+}
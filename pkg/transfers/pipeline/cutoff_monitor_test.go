@@ -0,0 +1,113 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package pipeline
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/moov-io/paygate/pkg/admin"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/transfers/pipeline/notify"
+	"github.com/moov-io/paygate/x/schedule"
+
+	"github.com/stretchr/testify/require"
+)
+
+func setupCutoffMonitor(t *testing.T, clock schedule.Clock, merger XferMerging, notifier notify.Sender) *CutoffMonitor {
+	t.Helper()
+
+	cfg := config.Empty()
+	cfg.ODFI.RoutingNumber = "987654320"
+	cfg.ODFI.Cutoffs = config.Cutoffs{
+		Timezone: "America/New_York",
+		Windows:  []string{"16:20"},
+	}
+	cfg.Pipeline.CutoffMonitor = &config.CutoffMonitor{
+		MergeDuration: 2 * time.Minute,
+		PerTransfer:   time.Second,
+		Margin:        5 * time.Minute,
+	}
+	return NewCutoffMonitor(cfg, clock, merger, notifier)
+}
+
+func TestCutoffMonitor(t *testing.T) {
+	require.Nil(t, NewCutoffMonitor(config.Empty(), schedule.System, nil, nil))
+
+	merger := &MockXferMerging{
+		Pending: []admin.PendingTransfers{
+			{RoutingNumber: "273976369", Transfers: 50, TotalAmount: 125000},
+			{RoutingNumber: "121042882", Transfers: 10, TotalAmount: 4000},
+		},
+	}
+	notifier := &notify.MockSender{}
+	clock := schedule.NewMockClock(time.Now())
+	monitor := setupCutoffMonitor(t, clock, merger, notifier)
+
+	loc, _ := time.LoadLocation("America/New_York")
+
+	// plenty of time on a Monday morning
+	clock.Set(time.Date(2020, time.June, 1, 10, 0, 0, 0, loc))
+	forecast, err := monitor.check()
+	require.NoError(t, err)
+	require.Equal(t, 6*time.Hour+20*time.Minute, forecast.Remaining)
+	require.Equal(t, 3*time.Minute, forecast.Projected) // 2m + 60 transfers * 1s
+	require.False(t, forecast.Breach)
+	require.False(t, notifier.CriticalWasCalled())
+
+	// 3m of uploading leaves less than the 5m margin
+	clock.Set(time.Date(2020, time.June, 1, 16, 13, 0, 0, loc))
+	forecast, err = monitor.check()
+	require.NoError(t, err)
+	require.True(t, forecast.Breach)
+	require.True(t, notifier.CriticalWasCalled())
+
+	msg := notifier.CapturedMessage()
+	require.Equal(t, notify.CutoffWarning, msg.Direction)
+	require.Contains(t, msg.Detail, "60 transfers ($1290.00)")
+	require.Contains(t, msg.Detail, "121042882: 10 transfers ($40.00)")
+
+	// only one warning is sent per cutoff
+	notifier = &notify.MockSender{}
+	monitor.notifier = notifier
+	clock.Set(time.Date(2020, time.June, 1, 16, 15, 0, 0, loc))
+	_, err = monitor.check()
+	require.NoError(t, err)
+	require.False(t, notifier.CriticalWasCalled())
+
+	// nothing pending
+	merger.Pending = nil
+	clock.Set(time.Date(2020, time.June, 2, 16, 19, 0, 0, loc))
+	forecast, err = monitor.check()
+	require.NoError(t, err)
+	require.False(t, forecast.Breach)
+}
+
+func TestCutoffMonitor__holidays(t *testing.T) {
+	loc, _ := time.LoadLocation("America/New_York")
+	clock := schedule.NewMockClock(time.Now())
+	clock.Holidays = []time.Time{time.Date(2020, time.May, 25, 0, 0, 0, 0, loc)}
+	monitor := setupCutoffMonitor(t, clock, &MockXferMerging{}, nil)
+
+	// After Friday's cutoff the next is on Monday
+	clock.Set(time.Date(2020, time.June, 5, 17, 0, 0, 0, loc))
+	forecast, err := monitor.check()
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2020, time.June, 8, 16, 20, 0, 0, loc), forecast.Cutoff)
+
+	// Memorial Day was Monday May 25th in 2020
+	clock.Set(time.Date(2020, time.May, 22, 17, 0, 0, 0, loc))
+	forecast, err = monitor.check()
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2020, time.May, 26, 16, 20, 0, 0, loc), forecast.Cutoff)
+}
+
+func TestCutoffMonitor__err(t *testing.T) {
+	monitor := setupCutoffMonitor(t, schedule.System, &MockXferMerging{Err: errors.New("bad error")}, nil)
+
+	_, err := monitor.check()
+	require.Error(t, err)
+}
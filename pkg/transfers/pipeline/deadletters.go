@@ -0,0 +1,215 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package pipeline
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/moov-io/paygate/pkg/admin"
+	"github.com/moov-io/paygate/pkg/config"
+
+	"gocloud.dev/blob"
+	"gocloud.dev/gcerrors"
+	"gocloud.dev/pubsub"
+)
+
+const deadLetterPrefix = "dead-letters/"
+
+// deadLetters saves messages which fail to be handled after several attempts and
+// acknowledges them, so drivers which redeliver Nack'd messages right away don't loop on them.
+//
+// Attempts are counted by each instance from the message body.
+type deadLetters struct {
+	bucket      *blob.Bucket
+	maxAttempts int
+
+	mu       sync.Mutex
+	attempts map[string]int
+}
+
+type deadLetter struct {
+	ID       string    `json:"id"`
+	Body     []byte    `json:"body"`
+	Error    string    `json:"error"`
+	Attempts int       `json:"attempts"`
+	Created  time.Time `json:"created"`
+}
+
+func newDeadLetters(cfg *config.DeadLetters) (*deadLetters, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	bucket, err := blob.OpenBucket(context.Background(), cfg.BucketURI)
+	if err != nil {
+		return nil, fmt.Errorf("dead letters: %v", err)
+	}
+	return &deadLetters{
+		bucket:      bucket,
+		maxAttempts: cfg.Attempts(),
+		attempts:    make(map[string]int),
+	}, nil
+}
+
+func (dl *deadLetters) Close() error {
+	if dl == nil {
+		return nil
+	}
+	return dl.bucket.Close()
+}
+
+func deadLetterID(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+// failed records an unsuccessful attempt at handling msg. Once msg has used up its attempts
+// it's saved and acknowledged, otherwise it's Nack'd to be redelivered.
+func (dl *deadLetters) failed(msg *pubsub.Message, cause error) (bool, error) {
+	if dl == nil {
+		if msg.Nackable() {
+			msg.Nack()
+		}
+		return false, nil
+	}
+
+	id := deadLetterID(msg.Body)
+
+	dl.mu.Lock()
+	dl.attempts[id]++
+	attempts := dl.attempts[id]
+	dl.mu.Unlock()
+
+	if attempts < dl.maxAttempts {
+		if msg.Nackable() {
+			msg.Nack()
+		}
+		return false, nil
+	}
+
+	err := dl.save(deadLetter{
+		ID:       id,
+		Body:     msg.Body,
+		Error:    cause.Error(),
+		Attempts: attempts,
+		Created:  time.Now(),
+	})
+	if err != nil {
+		// keep redelivering until the dead letter is saved
+		if msg.Nackable() {
+			msg.Nack()
+		}
+		return false, err
+	}
+	msg.Ack()
+	dl.handled(msg)
+	return true, nil
+}
+
+// handled forgets the failed attempts of msg.
+func (dl *deadLetters) handled(msg *pubsub.Message) {
+	if dl == nil {
+		return
+	}
+	dl.mu.Lock()
+	defer dl.mu.Unlock()
+	delete(dl.attempts, deadLetterID(msg.Body))
+}
+
+func (dl *deadLetters) save(letter deadLetter) error {
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(letter); err != nil {
+		return err
+	}
+	key := deadLetterPrefix + letter.ID + ".json"
+	if err := dl.bucket.WriteAll(context.Background(), key, buf.Bytes(), nil); err != nil {
+		return fmt.Errorf("dead letters: writing %s: %v", key, err)
+	}
+	return nil
+}
+
+func (dl *deadLetters) get(id string) (*deadLetter, error) {
+	if dl == nil || id == "" || strings.ContainsAny(id, `/\`) {
+		return nil, nil
+	}
+	bs, err := dl.bucket.ReadAll(context.Background(), deadLetterPrefix+id+".json")
+	if err != nil {
+		if gcerrors.Code(err) == gcerrors.NotFound {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("dead letters: reading %s: %v", id, err)
+	}
+	var letter deadLetter
+	if err := json.Unmarshal(bs, &letter); err != nil {
+		return nil, fmt.Errorf("dead letters: reading %s: %v", id, err)
+	}
+	return &letter, nil
+}
+
+func (dl *deadLetters) remove(id string) error {
+	return dl.bucket.Delete(context.Background(), deadLetterPrefix+id+".json")
+}
+
+// list returns each dead letter, oldest first.
+func (dl *deadLetters) list() ([]admin.DeadLetter, error) {
+	if dl == nil {
+		return nil, nil
+	}
+	ctx := context.Background()
+
+	var out []admin.DeadLetter
+	iter := dl.bucket.List(&blob.ListOptions{Prefix: deadLetterPrefix})
+	for {
+		obj, err := iter.Next(ctx)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("dead letters: listing: %v", err)
+		}
+		letter, err := dl.get(strings.TrimSuffix(strings.TrimPrefix(obj.Key, deadLetterPrefix), ".json"))
+		if err != nil {
+			return nil, err
+		}
+		if letter == nil {
+			continue
+		}
+		messageType, transferID := describeBody(letter.Body)
+		out = append(out, admin.DeadLetter{
+			LetterID:   letter.ID,
+			Type:       messageType,
+			TransferID: transferID,
+			Error:      letter.Error,
+			Attempts:   int32(letter.Attempts),
+			Created:    letter.Created,
+		})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Created.Before(out[j].Created)
+	})
+	return out, nil
+}
+
+// describeBody returns the message type and transferID of a pipeline message.
+func describeBody(body []byte) (string, string) {
+	var xfer Xfer
+	if err := json.Unmarshal(body, &xfer); err == nil && xfer.Transfer != nil {
+		return messageTypeXfer, xfer.Transfer.TransferID
+	}
+	var cancel CanceledTransfer
+	if err := json.Unmarshal(body, &cancel); err == nil && cancel.TransferID != "" {
+		return messageTypeCancel, cancel.TransferID
+	}
+	return messageTypeOther, ""
+}
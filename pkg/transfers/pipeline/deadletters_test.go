@@ -0,0 +1,120 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/moov-io/ach"
+	"github.com/moov-io/base/log"
+
+	"github.com/moov-io/paygate/pkg/admin"
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+	"gocloud.dev/pubsub"
+)
+
+func testingDeadLetters(t *testing.T, attempts int) *deadLetters {
+	t.Helper()
+
+	letters, err := newDeadLetters(&config.DeadLetters{
+		BucketURI:   "mem://",
+		MaxAttempts: attempts,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { letters.Close() })
+	return letters
+}
+
+func TestDeadLetters__handleMessage(t *testing.T) {
+	pub := testingPublisher(t)
+	sub := testingSubscriber(t, pub)
+	letters := testingDeadLetters(t, 2)
+	merge := &MockXferMerging{}
+
+	require.NoError(t, pub.topic.Send(context.Background(), &pubsub.Message{
+		Body: []byte("unexpected message"),
+	}))
+
+	// the first attempt is Nack'd and redelivered
+	msg, err := sub.Receive(context.Background())
+	require.NoError(t, err)
+	require.Error(t, handleMessage("test", merge, letters, msg))
+
+	list, err := letters.list()
+	require.NoError(t, err)
+	require.Len(t, list, 0)
+
+	msg, err = sub.Receive(context.Background())
+	require.NoError(t, err)
+	require.Error(t, handleMessage("test", merge, letters, msg))
+
+	list, err = letters.list()
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	require.Equal(t, messageTypeOther, list[0].Type)
+	require.Equal(t, int32(2), list[0].Attempts)
+	require.Contains(t, list[0].Error, "unexpected message")
+	require.Len(t, letters.attempts, 0)
+}
+
+func TestDeadLetters__replay(t *testing.T) {
+	letters := testingDeadLetters(t, 1)
+	merge := &MockXferMerging{}
+	xfagg := &XferAggregator{
+		logger:      log.NewNopLogger(),
+		merger:      merge,
+		deadLetters: letters,
+	}
+
+	file, err := ach.ReadFile(filepath.Join("..", "..", "..", "testdata", "ppd-debit.ach"))
+	require.NoError(t, err)
+	body, err := json.Marshal(Xfer{
+		Transfer: &client.Transfer{TransferID: "transfer-id"},
+		File:     file,
+	})
+	require.NoError(t, err)
+
+	letterID := deadLetterID(body)
+	require.NoError(t, letters.save(deadLetter{ID: letterID, Body: body, Error: "bad error", Attempts: 5}))
+
+	r := mux.NewRouter()
+	r.Path("/pipeline/dead-letters").HandlerFunc(xfagg.listDeadLetters())
+	r.Path("/pipeline/dead-letters/{letterID}/replay").HandlerFunc(xfagg.replayDeadLetter())
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/pipeline/dead-letters", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var found []admin.DeadLetter
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&found))
+	require.Len(t, found, 1)
+	require.Equal(t, letterID, found[0].LetterID)
+	require.Equal(t, "transfer-id", found[0].TransferID)
+	require.Equal(t, messageTypeXfer, found[0].Type)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("POST", "/pipeline/dead-letters/"+letterID+"/replay", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NotNil(t, merge.LatestXfer)
+	require.Equal(t, "transfer-id", merge.LatestXfer.Transfer.TransferID)
+
+	// replayed letters are removed
+	list, err := letters.list()
+	require.NoError(t, err)
+	require.Len(t, list, 0)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("POST", "/pipeline/dead-letters/"+letterID+"/replay", nil))
+	require.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -6,6 +6,7 @@ package pipeline
 
 import (
 	"bytes"
+	"context"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
@@ -13,12 +14,17 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/moov-io/ach"
 	"github.com/moov-io/base"
+	"github.com/moov-io/customers/pkg/secrets"
 
+	"github.com/moov-io/paygate/pkg/achx"
+	"github.com/moov-io/paygate/pkg/admin"
 	"github.com/moov-io/paygate/pkg/client"
 	"github.com/moov-io/paygate/pkg/config"
 
@@ -36,28 +42,69 @@ type XferMerging interface {
 	HandleXfer(xfer Xfer) error
 	HandleCancel(cancel CanceledTransfer) error
 
-	WithEachMerged(func(*ach.File) error) (*processedTransfers, error)
+	WithEachMerged(func(file *ach.File, correlationIDs []string) error) (*processedTransfers, error)
+
+	// pendingTransfers summarizes transfers waiting for the next cutoff by their RDFI.
+	pendingTransfers() ([]admin.PendingTransfers, error)
+
+	// saveFailedUpload keeps a file which failed to upload so it can be retried after a
+	// restart. removeFailedUpload deletes it once it's been uploaded.
+	saveFailedUpload(failed failedUpload) error
+	removeFailedUpload(fileID string) error
+	// failedUploads returns every saved failed upload, oldest first.
+	failedUploads() ([]failedUpload, error)
+}
+
+// Holder decides which Transfers are kept out of merged files. Held Transfers stay in the
+// mergable directory and are offered again at the next cutoff.
+type Holder interface {
+	HoldTransfer(transferID string, file *ach.File) (bool, error)
 }
 
-func NewMerging(logger log.Logger, cfg config.Pipeline) (XferMerging, error) {
-	dir := filepath.Join("storage", "mergable") // default directory
+// mergingDirectory returns the directory which holds the mergable directory and
+// the directories isolated at each cutoff.
+func mergingDirectory(cfg config.Pipeline) string {
 	if cfg.Merging != nil {
-		dir = filepath.Join(cfg.Merging.Directory, "mergable")
+		return cfg.Merging.Directory
 	}
+	return "storage" // default directory
+}
+
+func NewMerging(logger log.Logger, cfg config.Pipeline, holders ...Holder) (XferMerging, error) {
+	dir := filepath.Join(mergingDirectory(cfg), "mergable")
 
 	if err := os.MkdirAll(dir, 0777); err != nil {
 		return nil, err
 	}
 
+	var keeper *secrets.Keeper
+	if cfg.Merging != nil && cfg.Merging.KeyURI != "" {
+		k, err := secrets.OpenLocal(cfg.Merging.KeyURI)
+		if err != nil {
+			return nil, fmt.Errorf("opening merging keeper: %v", err)
+		}
+		keeper = k
+	}
+
 	return &filesystemMerging{
 		baseDir: dir,
 		logger:  logger,
+		holders: holders,
+		keeper:  keeper,
 	}, nil
 }
 
 type filesystemMerging struct {
 	logger  log.Logger
 	baseDir string
+	holders []Holder
+
+	// keeper encrypts the files we write when merging.keyURI is set, see writeData
+	keeper *secrets.Keeper
+
+	// mergeMu serializes WithEachMerged so concurrent cutoffs can't isolate the
+	// same directory or upload files with duplicate sequences.
+	mergeMu sync.Mutex
 }
 
 func (m *filesystemMerging) HandleXfer(xfer Xfer) error {
@@ -68,6 +115,11 @@ func (m *filesystemMerging) HandleXfer(xfer Xfer) error {
 		return fmt.Errorf("problem writing transfer: %v\n problem writing ACH file: %v", err1, err2)
 	}
 
+	m.logger.With(log.Fields{
+		"transferID":    xfer.Transfer.TransferID,
+		"correlationID": xfer.Transfer.CorrelationID,
+	}).Log("wrote transfer for merging")
+
 	return nil
 }
 
@@ -78,7 +130,7 @@ func (m *filesystemMerging) writeTransfer(transfer *client.Transfer) error {
 	}
 
 	path := filepath.Join(m.baseDir, fmt.Sprintf("%s.json", transfer.TransferID))
-	if err := ioutil.WriteFile(path, buf.Bytes(), 0644); err != nil {
+	if err := m.writeData(path, buf.Bytes()); err != nil {
 		return err
 	}
 
@@ -92,13 +144,56 @@ func (m *filesystemMerging) writeACHFile(transferID string, file *ach.File) erro
 	}
 
 	path := filepath.Join(m.baseDir, fmt.Sprintf("%s.ach", transferID))
-	if err := ioutil.WriteFile(path, buf.Bytes(), 0644); err != nil {
+	if err := m.writeData(path, buf.Bytes()); err != nil {
 		return err
 	}
 
 	return nil
 }
 
+// encryptedPrefix starts each file written while merging.keyURI is set. Files without it are
+// read as plaintext, so transfers written before encryption was enabled are still merged.
+var encryptedPrefix = []byte("paygate-encrypted-v1\n")
+
+// writeData writes a file under the merging directory. Transfers and merged files contain
+// full account numbers, so they're encrypted when we have a keeper and only decrypted in
+// memory when they're merged and uploaded.
+func (m *filesystemMerging) writeData(path string, data []byte) error {
+	if m.keeper != nil {
+		encrypted, err := m.keeper.Encrypt(context.Background(), data)
+		if err != nil {
+			return fmt.Errorf("problem encrypting %s: %v", filepath.Base(path), err)
+		}
+		data = append(append([]byte{}, encryptedPrefix...), encrypted...)
+	}
+	return ioutil.WriteFile(path, data, 0644)
+}
+
+// readData reads a file written with writeData.
+func (m *filesystemMerging) readData(path string) ([]byte, error) {
+	bs, err := ioutil.ReadFile(path)
+	if err != nil || !bytes.HasPrefix(bs, encryptedPrefix) {
+		return bs, err
+	}
+	if m.keeper == nil {
+		return nil, fmt.Errorf("%s is encrypted but merging.keyURI isn't set", filepath.Base(path))
+	}
+	bs, err = m.keeper.Decrypt(context.Background(), bytes.TrimPrefix(bs, encryptedPrefix))
+	if err != nil {
+		return nil, fmt.Errorf("problem decrypting %s: %v", filepath.Base(path), err)
+	}
+	return bs, nil
+}
+
+func (m *filesystemMerging) readACHFile(path string) (*ach.File, error) {
+	bs, err := m.readData(path)
+	if err != nil {
+		return nil, err
+	}
+	file, err := ach.NewReader(bytes.NewReader(bs)).Read()
+	return &file, err
+}
+
 func (m *filesystemMerging) HandleCancel(cancel CanceledTransfer) error {
 	path := filepath.Join(m.baseDir, fmt.Sprintf("%s.ach", cancel.TransferID))
 
@@ -111,10 +206,13 @@ func (m *filesystemMerging) HandleCancel(cancel CanceledTransfer) error {
 	}
 }
 
+// isolatedDirFormat names each directory isolated for a cutoff after when it was isolated
+const isolatedDirFormat = "20060102-150405"
+
 func (m *filesystemMerging) isolateMergableDir() (string, error) {
 	// rename m.baseDir so we're the only accessor for it, then recreate m.baseDir
 	parent, _ := filepath.Split(m.baseDir)
-	newdir := filepath.Join(parent, time.Now().Format("20060102-150405"))
+	newdir := filepath.Join(parent, time.Now().Format(isolatedDirFormat))
 	if err := os.Rename(m.baseDir, newdir); err != nil {
 		return newdir, err
 	}
@@ -149,6 +247,60 @@ func getNonCanceledMatches(path string) ([]string, error) {
 	return out, nil
 }
 
+func (m *filesystemMerging) pendingTransfers() ([]admin.PendingTransfers, error) {
+	matches, err := getNonCanceledMatches(filepath.Join(m.baseDir, "*.ach"))
+	if err != nil {
+		return nil, err
+	}
+
+	byRoutingNumber := make(map[string]*admin.PendingTransfers)
+	var routingNumbers []string
+	for i := range matches {
+		// Files can be moved during a cutoff, so skip any that are gone
+		file, err := m.readACHFile(matches[i])
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, fmt.Errorf("problem reading %s: %v", filepath.Base(matches[i]), err)
+		}
+		entries := firstBatchEntries(file)
+		if len(entries) == 0 {
+			continue
+		}
+		routingNumber := entries[0].RDFIIdentification + entries[0].CheckDigit
+		pending, exists := byRoutingNumber[routingNumber]
+		if !exists {
+			pending = &admin.PendingTransfers{RoutingNumber: routingNumber}
+			byRoutingNumber[routingNumber] = pending
+			routingNumbers = append(routingNumbers, routingNumber)
+		}
+		pending.Transfers++ // each file is one transfer
+		for j := range entries {
+			pending.TotalAmount += int64(entries[j].Amount)
+		}
+	}
+
+	sort.Strings(routingNumbers)
+	out := make([]admin.PendingTransfers, 0, len(routingNumbers))
+	for i := range routingNumbers {
+		out = append(out, *byRoutingNumber[routingNumbers[i]])
+	}
+	return out, nil
+}
+
+// firstBatchEntries returns the entries of the first batch in file with any entries, which
+// can be an IAT batch.
+func firstBatchEntries(file *ach.File) []achx.Entry {
+	entries := achx.Entries(file)
+	for i := range entries {
+		if entries[i].BatchNumber != entries[0].BatchNumber {
+			return entries[:i]
+		}
+	}
+	return entries
+}
+
 type processedTransfers struct {
 	transferIDs []string
 }
@@ -166,7 +318,10 @@ func newProcessedTransfers(matches []string) *processedTransfers {
 	return processed
 }
 
-func (m *filesystemMerging) WithEachMerged(f func(*ach.File) error) (*processedTransfers, error) {
+func (m *filesystemMerging) WithEachMerged(f func(file *ach.File, correlationIDs []string) error) (*processedTransfers, error) {
+	m.mergeMu.Lock()
+	defer m.mergeMu.Unlock()
+
 	// move the current directory so it's isolated and easier to debug later on
 	dir, err := m.isolateMergableDir()
 	if err != nil {
@@ -180,24 +335,42 @@ func (m *filesystemMerging) WithEachMerged(f func(*ach.File) error) (*processedT
 	}
 
 	var files []*ach.File
+	var merged []string
 	var el base.ErrorList
+	ids := make(correlationIDs)
 	for i := range matches {
-		file, err := ach.ReadFile(matches[i])
+		file, err := m.readACHFile(matches[i])
 		if err != nil {
 			el.Add(fmt.Errorf("problem reading %s: %v", matches[i], err))
 			continue
 		}
-		if file != nil {
+		if file == nil {
+			continue
+		}
+		held, err := m.holdTransfer(matches[i], file)
+		if err != nil {
+			el.Add(fmt.Errorf("problem holding %s: %v", matches[i], err))
+			continue
+		}
+		if !held {
 			files = append(files, file)
+			merged = append(merged, matches[i])
+
+			correlationID := m.readCorrelationID(matches[i])
+			ids.add(file, correlationID)
+			m.logger.With(log.Fields{
+				"transferID":    strings.TrimSuffix(filepath.Base(matches[i]), ".ach"),
+				"correlationID": correlationID,
+			}).Log("merging transfer")
 		}
 	}
-	files, err = ach.MergeFiles(files)
+	files, err = mergeFiles(files)
 	if err != nil {
 		el.Add(fmt.Errorf("unable to merge files: %v", err))
 	}
 
-	if len(matches) > 0 {
-		m.logger.Logf("merged %d transfers into %d files", len(matches), len(files))
+	if len(merged) > 0 {
+		m.logger.Logf("merged %d transfers into %d files", len(merged), len(files))
 	}
 
 	// Remove the directory if there are no files, otherwise setup an inner dir for the uploaded file.
@@ -213,10 +386,10 @@ func (m *filesystemMerging) WithEachMerged(f func(*ach.File) error) (*processedT
 
 	// Write each file to our storage
 	for i := range files {
-		if err := writeFile(dir, files[i]); err != nil {
+		if err := m.writeFile(dir, files[i]); err != nil {
 			el.Add(fmt.Errorf("problem writing merged file: %v", err))
 		}
-		if err := f(files[i]); err != nil {
+		if err := f(files[i], ids.forFile(files[i])); err != nil {
 			el.Add(fmt.Errorf("problem from callback: %v", err))
 		}
 	}
@@ -227,16 +400,169 @@ func (m *filesystemMerging) WithEachMerged(f func(*ach.File) error) (*processedT
 		return nil, el
 	}
 
-	return newProcessedTransfers(matches), nil
+	return newProcessedTransfers(merged), nil
+}
+
+// correlationIDs maps the trace numbers of each merged Transfer's entries to its correlation ID
+// so merged files can be traced back to the requests which created their Transfers.
+type correlationIDs map[string]string
+
+func (ids correlationIDs) add(file *ach.File, correlationID string) {
+	entries := achx.Entries(file)
+	for i := range entries {
+		ids[entries[i].TraceNumber] = correlationID
+	}
+}
+
+// forFile returns the sorted correlation IDs of Transfers with entries in file.
+func (ids correlationIDs) forFile(file *ach.File) []string {
+	seen := make(map[string]bool)
+	var out []string
+	entries := achx.Entries(file)
+	for i := range entries {
+		id, ok := ids[entries[i].TraceNumber]
+		if ok && !seen[id] {
+			seen[id] = true
+			out = append(out, id)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// readCorrelationID returns the correlation ID from the Transfer's JSON written next to the
+// ACH file at path. Transfers without one are identified by their transferID.
+func (m *filesystemMerging) readCorrelationID(path string) string {
+	transferID := strings.TrimSuffix(filepath.Base(path), ".ach")
+
+	bs, err := m.readData(strings.TrimSuffix(path, ".ach") + ".json")
+	if err != nil {
+		return transferID
+	}
+	var transfer client.Transfer
+	if err := json.Unmarshal(bs, &transfer); err != nil || transfer.CorrelationID == "" {
+		return transferID
+	}
+	return transfer.CorrelationID
+}
+
+// holdTransfer moves the Transfer at path back into the mergable directory if any of our
+// Holders want it kept out of this cutoff's files.
+func (m *filesystemMerging) holdTransfer(path string, file *ach.File) (bool, error) {
+	transferID := strings.TrimSuffix(filepath.Base(path), ".ach")
+	held := false
+	for i := range m.holders {
+		if m.holders[i] == nil {
+			continue
+		}
+		h, err := m.holders[i].HoldTransfer(transferID, file)
+		if err != nil {
+			return false, err
+		}
+		if h {
+			held = true
+			break
+		}
+	}
+	if !held {
+		return false, nil
+	}
+	if err := os.Rename(path, filepath.Join(m.baseDir, filepath.Base(path))); err != nil {
+		return false, err
+	}
+	// The Transfer's JSON is optional
+	jsonPath := strings.TrimSuffix(path, ".ach") + ".json"
+	if _, err := os.Stat(jsonPath); err == nil {
+		os.Rename(jsonPath, filepath.Join(m.baseDir, filepath.Base(jsonPath)))
+	}
+	m.logger.Set("transferID", transferID).Log("holding transfer until next cutoff")
+	return true, nil
+}
+
+// mergeFiles merges Same Day ACH files separately from every other file so same-day
+// entries are uploaded in their own files rather than mixed into next-day files.
+// International (IAT) files are merged on their own as well.
+func mergeFiles(files []*ach.File) ([]*ach.File, error) {
+	var sameDay, nextDay, international []*ach.File
+	for i := range files {
+		switch {
+		case len(files[i].IATBatches) > 0:
+			international = append(international, files[i])
+		case isSameDay(files[i]):
+			sameDay = append(sameDay, files[i])
+		default:
+			nextDay = append(nextDay, files[i])
+		}
+	}
+	var out []*ach.File
+	for _, group := range [][]*ach.File{sameDay, nextDay} {
+		if len(group) == 0 {
+			continue
+		}
+		merged, err := ach.MergeFiles(group)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, merged...)
+	}
+	merged, err := mergeIATFiles(international)
+	if err != nil {
+		return nil, err
+	}
+	return append(out, merged...), nil
+}
+
+// mergeIATFiles combines the IAT batches of files sent between the same origin and
+// destination into one file. ach.MergeFiles only considers non-IAT batches.
+func mergeIATFiles(files []*ach.File) ([]*ach.File, error) {
+	var out []*ach.File
+	byRoute := make(map[string]*ach.File)
+	for i := range files {
+		key := files[i].Header.ImmediateOrigin + files[i].Header.ImmediateDestination
+		merged, exists := byRoute[key]
+		if !exists {
+			merged = ach.NewFile()
+			merged.ID = files[i].ID
+			merged.Header = files[i].Header
+			merged.Control = ach.NewFileControl()
+			byRoute[key] = merged
+			out = append(out, merged)
+		}
+		for j := range files[i].IATBatches {
+			batch := files[i].IATBatches[j]
+			batch.GetHeader().BatchNumber = len(merged.IATBatches) + 1
+			if err := batch.Create(); err != nil {
+				return nil, fmt.Errorf("IAT batch %s: %v", batch.ID, err)
+			}
+			merged.AddIATBatch(batch)
+		}
+	}
+	for i := range out {
+		if err := out[i].Create(); err != nil {
+			return nil, fmt.Errorf("merging IAT files: %v", err)
+		}
+	}
+	return out, nil
+}
+
+// isSameDay returns true if any batch in file is Same Day ACH, which achx marks with an
+// "SDHHMM" CompanyDescriptiveDate.
+func isSameDay(file *ach.File) bool {
+	for i := range file.Batches {
+		if strings.HasPrefix(file.Batches[i].GetHeader().CompanyDescriptiveDate, "SD") {
+			return true
+		}
+	}
+	return false
 }
 
-func writeFile(dir string, file *ach.File) error {
+func (m *filesystemMerging) writeFile(dir string, file *ach.File) error {
 	var buf bytes.Buffer
 	if err := ach.NewWriter(&buf).Write(file); err != nil {
 		return fmt.Errorf("unable to buffer ACH file: %v", err)
 	}
 	filename := filepath.Join(dir, fmt.Sprintf("%s.ach", hash(buf.Bytes())))
-	return ioutil.WriteFile(filename, buf.Bytes(), 0644)
+	return m.writeData(filename, buf.Bytes())
 }
 
 func hash(data []byte) string {
@@ -5,14 +5,23 @@
 package pipeline
 
 import (
+	"bytes"
 	"fmt"
 	"io/ioutil"
+	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/moov-io/ach"
 	"github.com/moov-io/base"
+	"github.com/moov-io/base/log"
+	customers "github.com/moov-io/customers/pkg/client"
+	"github.com/moov-io/customers/pkg/secrets"
 	"github.com/moov-io/paygate/internal"
+	"github.com/moov-io/paygate/pkg/achx"
+	"github.com/moov-io/paygate/pkg/client"
 )
 
 func TestMerging__getNonCanceledMatches(t *testing.T) {
@@ -45,3 +54,296 @@ func TestMerging__getNonCanceledMatches(t *testing.T) {
 		t.Errorf("unexpected match: %v", matches[0])
 	}
 }
+
+func TestMerging__pendingTransfers(t *testing.T) {
+	dir := internal.TestDir(t)
+	m := &filesystemMerging{baseDir: dir}
+
+	bs, err := ioutil.ReadFile(filepath.Join("..", "..", "..", "testdata", "ppd-debit.ach"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a.ach", "b.ach", "c.ach"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), bs, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// canceled transfers aren't pending
+	if err := ioutil.WriteFile(filepath.Join(dir, "c.ach.canceled"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	pending, err := m.pendingTransfers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 1 {
+		t.Fatalf("unexpected pending: %#v", pending)
+	}
+	if pending[0].RoutingNumber != "053200019" || pending[0].Transfers != 2 || pending[0].TotalAmount != 21000 {
+		t.Errorf("unexpected pending: %#v", pending[0])
+	}
+}
+
+type holdTransfers map[string]bool
+
+func (h holdTransfers) HoldTransfer(transferID string, file *ach.File) (bool, error) {
+	return h[transferID], nil
+}
+
+func TestMerging__WithEachMergedHeld(t *testing.T) {
+	parent := internal.TestDir(t)
+	dir := filepath.Join(parent, "mergable")
+	if err := os.Mkdir(dir, 0777); err != nil {
+		t.Fatal(err)
+	}
+	m := &filesystemMerging{
+		baseDir: dir,
+		logger:  log.NewNopLogger(),
+		holders: []Holder{nil, holdTransfers{}, holdTransfers{"held": true}},
+	}
+
+	bs, err := ioutil.ReadFile(filepath.Join("..", "..", "..", "testdata", "ppd-debit.ach"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"held.ach", "held.json", "sent.ach"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), bs, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var merged int
+	var ids []string
+	processed, err := m.WithEachMerged(func(file *ach.File, correlationIDs []string) error {
+		merged++
+		ids = append(ids, correlationIDs...)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if merged != 1 || len(processed.transferIDs) != 1 || processed.transferIDs[0] != "sent" {
+		t.Errorf("merged=%d processed=%#v", merged, processed)
+	}
+	// Transfers without JSON are identified by their transferID
+	if len(ids) != 1 || ids[0] != "sent" {
+		t.Errorf("unexpected correlation IDs: %v", ids)
+	}
+
+	// the held transfer waits for the next cutoff
+	for _, name := range []string{"held.ach", "held.json"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+}
+
+func TestMerging__encrypted(t *testing.T) {
+	parent := internal.TestDir(t)
+	dir := filepath.Join(parent, "mergable")
+	if err := os.Mkdir(dir, 0777); err != nil {
+		t.Fatal(err)
+	}
+	keeper, err := secrets.OpenLocal("MTIzNDU2Nzg5MDEyMzQ1Njc4OTAxMjM0NTY3ODkwMTI=")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &filesystemMerging{
+		baseDir: dir,
+		logger:  log.NewNopLogger(),
+		keeper:  keeper,
+	}
+
+	bs, err := ioutil.ReadFile(filepath.Join("..", "..", "..", "testdata", "ppd-debit.ach"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.writeData(filepath.Join(dir, "a.ach"), bs); err != nil {
+		t.Fatal(err)
+	}
+	// files written before encryption was enabled are still read
+	if err := ioutil.WriteFile(filepath.Join(dir, "b.ach"), bs, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	encrypted, err := ioutil.ReadFile(filepath.Join(dir, "a.ach"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(encrypted, []byte("Bachman")) {
+		t.Errorf("plaintext found in encrypted file:\n%s", string(encrypted))
+	}
+
+	// without the key files can't be read
+	if _, err := (&filesystemMerging{baseDir: dir}).pendingTransfers(); err == nil {
+		t.Error("expected error")
+	}
+
+	pending, err := m.pendingTransfers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 1 || pending[0].Transfers != 2 {
+		t.Errorf("unexpected pending: %#v", pending)
+	}
+
+	var merged int
+	processed, err := m.WithEachMerged(func(file *ach.File, correlationIDs []string) error {
+		merged++
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if merged == 0 || len(processed.transferIDs) != 2 {
+		t.Errorf("merged=%d processed=%#v", merged, processed)
+	}
+
+	// merged files are encrypted as well
+	matches, err := filepath.Glob(filepath.Join(parent, "*", "uploaded", "*.ach"))
+	if err != nil || len(matches) != merged {
+		t.Fatalf("matches=%v error=%v", matches, err)
+	}
+	for i := range matches {
+		uploaded, err := ioutil.ReadFile(matches[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.HasPrefix(uploaded, encryptedPrefix) {
+			t.Errorf("merged file isn't encrypted:\n%s", string(uploaded))
+		}
+		if file, err := m.readACHFile(matches[i]); err != nil || len(file.Batches) == 0 {
+			t.Errorf("file=%#v error=%v", file, err)
+		}
+	}
+}
+
+func TestMerging__mergeFilesSameDay(t *testing.T) {
+	read := func() *ach.File {
+		file, err := ach.ReadFile(filepath.Join("..", "..", "..", "testdata", "ppd-debit.ach"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return file
+	}
+	nextDay, sameDay := read(), read()
+	sameDay.Batches[0].GetHeader().CompanyDescriptiveDate = "SD1300"
+
+	if isSameDay(nextDay) || !isSameDay(sameDay) {
+		t.Fatal("unexpected same-day detection")
+	}
+
+	files, err := mergeFiles([]*ach.File{nextDay, sameDay})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("got %d files", len(files))
+	}
+	if !isSameDay(files[0]) || isSameDay(files[1]) {
+		t.Error("same-day entries were mixed with next-day entries")
+	}
+}
+
+func TestMerging__correlationIDs(t *testing.T) {
+	dir := internal.TestDir(t)
+	m := &filesystemMerging{
+		baseDir: dir,
+		logger:  log.NewNopLogger(),
+	}
+
+	file, err := ach.ReadFile(filepath.Join("..", "..", "..", "testdata", "ppd-debit.ach"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	xfer := &client.Transfer{TransferID: "abc", CorrelationID: "request-id"}
+	if err := m.HandleXfer(Xfer{Transfer: xfer, File: file}); err != nil {
+		t.Fatal(err)
+	}
+
+	if id := m.readCorrelationID(filepath.Join(dir, "abc.ach")); id != "request-id" {
+		t.Errorf("unexpected correlationID=%q", id)
+	}
+	if id := m.readCorrelationID(filepath.Join(dir, "missing.ach")); id != "missing" {
+		t.Errorf("unexpected correlationID=%q", id)
+	}
+
+	ids := make(correlationIDs)
+	ids.add(file, "request-id")
+	if found := ids.forFile(file); len(found) != 1 || found[0] != "request-id" {
+		t.Errorf("unexpected correlation IDs: %v", found)
+	}
+	if found := ids.forFile(ach.NewFile()); len(found) != 0 {
+		t.Errorf("unexpected correlation IDs: %v", found)
+	}
+}
+
+// iatFile returns a file with one IAT batch from 123456780 to 987654320.
+func iatFile(t *testing.T) *ach.File {
+	t.Helper()
+
+	opts := achx.Options{
+		ODFIRoutingNumber:     "123456780",
+		CutoffTimezone:        time.UTC,
+		CompanyIdentification: "MOOVZZZZZZ",
+	}
+	xfer := &client.Transfer{
+		Amount:      client.Amount{Currency: "USD", Value: 1247},
+		Description: "payroll",
+		IatDetail: &client.IatDetail{
+			ForeignExchangeIndicator:          "FF",
+			ForeignExchangeReferenceIndicator: 3,
+			DestinationCountryCode:            "DE",
+			OriginatingCurrencyCode:           "USD",
+			DestinationCurrencyCode:           "EUR",
+			TransactionTypeCode:               "SAL",
+			Originator: client.IatParty{
+				Name: "John Doe", StreetAddress: "123 Main St", City: "Anytown", StateProvince: "IA", CountryCode: "US", PostalCode: "50401",
+			},
+			Receiver: client.IatParty{
+				Name: "Jane Doe", StreetAddress: "Hauptstrasse 1", City: "Berlin", CountryCode: "DE", PostalCode: "10115",
+			},
+			ReceivingBank: client.IatBank{
+				Name: "Receiving Bank", IdNumberQualifier: "01", Identification: "987654320", BranchCountryCode: "DE",
+			},
+		},
+	}
+	source := achx.Source{
+		Account:       customers.Account{RoutingNumber: "123456780", Type: customers.ACCOUNTTYPE_CHECKING},
+		AccountNumber: "7654321",
+	}
+	destination := achx.Destination{
+		Account:       customers.Account{RoutingNumber: "987654320", Type: customers.ACCOUNTTYPE_CHECKING},
+		AccountNumber: "1234567",
+	}
+	file, err := achx.ConstructFile(base.ID(), opts, xfer, source, destination)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func TestMerging__mergeFilesIAT(t *testing.T) {
+	domestic, err := ach.ReadFile(filepath.Join("..", "..", "..", "testdata", "ppd-debit.ach"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := mergeFiles([]*ach.File{iatFile(t), domestic, iatFile(t)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("got %d files", len(files))
+	}
+	if len(files[0].IATBatches) != 0 || len(files[1].IATBatches) != 2 {
+		t.Fatal("IAT entries were mixed with domestic entries")
+	}
+	if n := files[1].IATBatches[1].GetHeader().BatchNumber; n != 2 {
+		t.Errorf("unexpected BatchNumber=%d", n)
+	}
+	if err := files[1].Validate(); err != nil {
+		t.Error(err)
+	}
+}
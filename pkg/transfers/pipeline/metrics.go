@@ -0,0 +1,174 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package pipeline
+
+import (
+	"net/url"
+	"time"
+
+	"github.com/moov-io/paygate/pkg/config"
+
+	"github.com/go-kit/kit/metrics/prometheus"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+	"gocloud.dev/pubsub"
+)
+
+var (
+	messagesPublished = prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+		Name: "pipeline_messages_published",
+		Help: "Counter of messages published onto the transfer pipeline",
+	}, []string{"topic", "type", "status"})
+
+	messagesReceived = prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+		Name: "pipeline_messages_received",
+		Help: "Counter of messages received from the transfer pipeline",
+	}, []string{"topic"})
+
+	messagesHandled = prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+		Name: "pipeline_messages_handled",
+		Help: "Counter of pipeline messages acknowledged or negatively acknowledged",
+	}, []string{"topic", "type", "result"})
+
+	messagesDeadLettered = prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+		Name: "pipeline_messages_dead_lettered",
+		Help: "Counter of pipeline messages saved as dead letters after failing every attempt",
+	}, []string{"topic", "type"})
+
+	messageProcessingDuration = prometheus.NewHistogramFrom(stdprometheus.HistogramOpts{
+		Name: "pipeline_message_processing_seconds",
+		Help: "Histogram of how long each pipeline message took to be handled",
+	}, []string{"topic"})
+
+	messageLag = prometheus.NewHistogramFrom(stdprometheus.HistogramOpts{
+		Name:    "pipeline_message_lag_seconds",
+		Help:    "Histogram of the time between a message being published and received",
+		Buckets: []float64{0.1, 1, 10, 30, 60, 300, 900, 1800, 3600},
+	}, []string{"topic"})
+
+	transfersUnprocessed = prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+		Name: "pipeline_transfers_unprocessed",
+		Help: "Counter of uploaded transfers which failed to be marked as processed",
+	}, nil)
+
+	retentionDirectories = prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+		Name: "retention_directories",
+		Help: "Counter of old directories of merged or inbound files archived or deleted",
+	}, []string{"kind", "action"})
+
+	retentionDiskUsage = prometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+		Name: "retention_disk_usage_bytes",
+		Help: "Bytes used on disk by directories of merged or inbound files",
+	}, []string{"kind"})
+
+	subscriptionBacklog = prometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+		Name: "pipeline_subscription_backlog",
+		Help: "Estimated count of messages published but not yet handled",
+	}, []string{"topic"})
+
+	cutoffSecondsRemaining = prometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+		Name: "cutoff_seconds_remaining",
+		Help: "Seconds until the next cutoff of an ODFI",
+	}, []string{"routing_number"})
+
+	cutoffProjectedSeconds = prometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+		Name: "cutoff_projected_upload_seconds",
+		Help: "Projected seconds to merge and upload the transfers pending for an ODFI",
+	}, []string{"routing_number"})
+
+	cutoffPendingTransfers = prometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+		Name: "cutoff_pending_transfers",
+		Help: "Count of transfers waiting to be merged for a receiving routing number",
+	}, []string{"routing_number"})
+
+	cutoffPendingAmount = prometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+		Name: "cutoff_pending_amount_cents",
+		Help: "Sum of transfers waiting to be merged for a receiving routing number",
+	}, []string{"routing_number"})
+
+	cutoffBreachWarnings = prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+		Name: "cutoff_breach_warnings",
+		Help: "Counter of warnings that pending transfers might miss a cutoff",
+	}, []string{"routing_number"})
+
+	originationAmount = prometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+		Name: "odfi_origination_cents",
+		Help: "Sum of debits and credits uploaded today for a routing number",
+	}, []string{"routing_number"})
+
+	originationUtilization = prometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+		Name: "odfi_origination_cap_utilization",
+		Help: "Percent of a routing number's daily origination cap used today",
+	}, []string{"routing_number"})
+
+	originationCapExceeded = prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+		Name: "odfi_origination_cap_exceeded",
+		Help: "Counter of files which would exceed a daily origination cap",
+	}, []string{"routing_number"})
+)
+
+const (
+	// publishedAtKey is a message metadata key holding the RFC3339 time a message was published
+	publishedAtKey = "publishedAt"
+
+	messageTypeXfer   = "xfer"
+	messageTypeCancel = "cancel"
+	messageTypeCutoff = "cutoff"
+	messageTypeOther  = "unknown"
+)
+
+// topicName returns the label used in metrics for a stream pipeline. Brokers (like our
+// inmem or kafka setups) don't expose a backlog so it's estimated from published and handled messages.
+func topicName(cfg *config.StreamPipeline) string {
+	if cfg == nil {
+		return ""
+	}
+	if cfg.InMem != nil {
+		if u, err := url.Parse(cfg.InMem.URL); err == nil && u.Host != "" {
+			return u.Host
+		}
+		return cfg.InMem.URL
+	}
+	if cfg.Kafka != nil {
+		return cfg.Kafka.TransfersTopic()
+	}
+	return ""
+}
+
+func recordPublished(topic, messageType string, err error) {
+	status := "success"
+	if err != nil {
+		status = "failure"
+	} else {
+		subscriptionBacklog.With("topic", topic).Add(1)
+	}
+	messagesPublished.With("topic", topic, "type", messageType, "status", status).Add(1)
+}
+
+func recordReceived(topic string, msg *pubsub.Message, now time.Time) {
+	messagesReceived.With("topic", topic).Add(1)
+	if msg == nil || msg.Metadata == nil {
+		return
+	}
+	if when, err := time.Parse(time.RFC3339Nano, msg.Metadata[publishedAtKey]); err == nil {
+		messageLag.With("topic", topic).Observe(now.Sub(when).Seconds())
+	}
+}
+
+func recordHandled(topic, messageType string, acked bool, started time.Time) {
+	result := "ack"
+	if !acked {
+		result = "nack"
+	}
+	messagesHandled.With("topic", topic, "type", messageType, "result", result).Add(1)
+	messageProcessingDuration.With("topic", topic).Observe(time.Since(started).Seconds())
+	subscriptionBacklog.With("topic", topic).Add(-1)
+}
+
+func recordOrigination(routingNumber string, originated, daily int64) {
+	originationAmount.With("routing_number", routingNumber).Set(float64(originated))
+	if daily > 0 {
+		originationUtilization.With("routing_number", routingNumber).Set(float64(originated) * 100 / float64(daily))
+	}
+}
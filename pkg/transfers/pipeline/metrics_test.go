@@ -0,0 +1,46 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package pipeline
+
+import (
+	"testing"
+	"time"
+
+	"github.com/moov-io/paygate/pkg/config"
+
+	"gocloud.dev/pubsub"
+)
+
+func TestMetrics__topicName(t *testing.T) {
+	if name := topicName(nil); name != "" {
+		t.Errorf("unexpected topic: %q", name)
+	}
+
+	cfg := &config.StreamPipeline{
+		InMem: &config.InMemPipeline{URL: "mem://paygate"},
+	}
+	if name := topicName(cfg); name != "paygate" {
+		t.Errorf("unexpected topic: %q", name)
+	}
+
+	cfg = &config.StreamPipeline{
+		Kafka: &config.KafkaPipeline{Topic: "transfers"},
+	}
+	if name := topicName(cfg); name != "transfers" {
+		t.Errorf("unexpected topic: %q", name)
+	}
+}
+
+func TestMetrics__recordReceived(t *testing.T) {
+	msg := &pubsub.Message{
+		Metadata: map[string]string{
+			publishedAtKey: time.Now().Add(-1 * time.Minute).Format(time.RFC3339Nano),
+		},
+	}
+	// nothing to check besides not panicing
+	recordReceived("test", msg, time.Now())
+	recordReceived("test", nil, time.Now())
+	recordReceived("test", &pubsub.Message{}, time.Now())
+}
@@ -0,0 +1,86 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package pipeline
+
+import (
+	"github.com/moov-io/paygate/pkg/admin"
+	"github.com/moov-io/paygate/pkg/client"
+)
+
+type MockRepository struct {
+	Processed   []string
+	Unprocessed []*admin.UnprocessedTransfer
+
+	// Organizations of transfers, which are PROCESSED once included in Processed
+	Organizations map[string]string
+
+	// TraceNumbers maps trace numbers to their transfer
+	TraceNumbers map[string]string
+
+	Err error
+}
+
+func (r *MockRepository) MarkTransfersAsProcessed(transferIDs []string) error {
+	if r.Err != nil {
+		return r.Err
+	}
+	r.Processed = append(r.Processed, transferIDs...)
+	r.Unprocessed = nil
+	return nil
+}
+
+func (r *MockRepository) getUnprocessedTransfers() ([]*admin.UnprocessedTransfer, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	return r.Unprocessed, nil
+}
+
+func (r *MockRepository) deleteUnprocessedTransfer(transferID string) error {
+	if r.Err != nil {
+		return r.Err
+	}
+	for i := range r.Unprocessed {
+		if r.Unprocessed[i].TransferID == transferID {
+			r.Unprocessed = append(r.Unprocessed[:i], r.Unprocessed[i+1:]...)
+			return nil
+		}
+	}
+	return nil
+}
+
+func (r *MockRepository) getTransferStatuses(transferIDs []string) (map[string]transferStatus, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	out := make(map[string]transferStatus)
+	for i := range transferIDs {
+		org, exists := r.Organizations[transferIDs[i]]
+		if !exists {
+			continue
+		}
+		status := transferStatus{organization: org, status: client.PENDING}
+		for j := range r.Processed {
+			if r.Processed[j] == transferIDs[i] {
+				status.status = client.PROCESSED
+			}
+		}
+		out[transferIDs[i]] = status
+	}
+	return out, nil
+}
+
+func (r *MockRepository) getTraceTransferIDs(traceNumbers []string) ([]string, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	var out []string
+	for i := range traceNumbers {
+		if transferID, exists := r.TraceNumbers[traceNumbers[i]]; exists {
+			out = append(out, transferID)
+		}
+	}
+	return out, nil
+}
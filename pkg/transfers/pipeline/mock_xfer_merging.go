@@ -6,12 +6,16 @@ package pipeline
 
 import (
 	"github.com/moov-io/ach"
+
+	"github.com/moov-io/paygate/pkg/admin"
 )
 
 type MockXferMerging struct {
 	LatestXfer   *Xfer
 	LatestCancel *CanceledTransfer
 	processed    *processedTransfers
+	Pending      []admin.PendingTransfers
+	Failed       []failedUpload
 
 	Err error
 }
@@ -26,9 +30,45 @@ func (merge *MockXferMerging) HandleCancel(cancel CanceledTransfer) error {
 	return merge.Err
 }
 
-func (merge *MockXferMerging) WithEachMerged(func(*ach.File) error) (*processedTransfers, error) {
+func (merge *MockXferMerging) WithEachMerged(func(*ach.File, []string) error) (*processedTransfers, error) {
 	if merge.Err != nil {
 		return nil, merge.Err
 	}
 	return merge.processed, nil
 }
+
+func (merge *MockXferMerging) pendingTransfers() ([]admin.PendingTransfers, error) {
+	if merge.Err != nil {
+		return nil, merge.Err
+	}
+	return merge.Pending, nil
+}
+
+func (merge *MockXferMerging) saveFailedUpload(failed failedUpload) error {
+	if merge.Err != nil {
+		return merge.Err
+	}
+	merge.removeFailedUpload(failed.FileID)
+	merge.Failed = append(merge.Failed, failed)
+	return nil
+}
+
+func (merge *MockXferMerging) removeFailedUpload(fileID string) error {
+	if merge.Err != nil {
+		return merge.Err
+	}
+	for i := range merge.Failed {
+		if merge.Failed[i].FileID == fileID {
+			merge.Failed = append(merge.Failed[:i], merge.Failed[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+func (merge *MockXferMerging) failedUploads() ([]failedUpload, error) {
+	if merge.Err != nil {
+		return nil, merge.Err
+	}
+	return merge.Failed, nil
+}
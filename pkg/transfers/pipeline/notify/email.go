@@ -38,6 +38,9 @@ type EmailTemplateData struct {
 
 	BatchCount int
 	EntryCount int
+
+	// CorrelationIDs identify the Transfers in the file
+	CorrelationIDs []string
 }
 
 var (
@@ -95,7 +98,7 @@ func (mailer *Email) Info(msg *Message) error {
 	if err != nil {
 		return err
 	}
-	return sendEmail(mailer.cfg, mailer.dialer, msg.Filename, contents)
+	return sendEmail(mailer.cfg, mailer.dialer, emailSubject(mailer.cfg, msg), contents)
 }
 
 func (mailer *Email) Critical(msg *Message) error {
@@ -103,15 +106,34 @@ func (mailer *Email) Critical(msg *Message) error {
 	if err != nil {
 		return err
 	}
-	return sendEmail(mailer.cfg, mailer.dialer, msg.Filename, contents)
+	return sendEmail(mailer.cfg, mailer.dialer, emailSubject(mailer.cfg, msg), contents)
+}
+
+// SendTo delivers a plain text email to the given recipients rather than those configured.
+func (mailer *Email) SendTo(to []string, subject, body string) error {
+	cfg := *mailer.cfg
+	cfg.To = to
+	return sendEmail(&cfg, mailer.dialer, subject, body)
+}
+
+func emailSubject(cfg *config.Email, msg *Message) string {
+	if msg.Detail != "" {
+		return fmt.Sprintf("%s for %s", msg.Direction, cfg.CompanyName)
+	}
+	return fmt.Sprintf("%s uploaded by %s", msg.Filename, cfg.CompanyName)
 }
 
 func marshalEmail(cfg *config.Email, msg *Message) (string, error) {
+	if msg.Detail != "" {
+		return fmt.Sprintf("Name: %s\n\n%s\n", cfg.CompanyName, msg.Detail), nil
+	}
 	data := EmailTemplateData{
 		CompanyName: cfg.CompanyName,
 		Verb:        string(msg.Direction),
 		Filename:    msg.Filename,
 		Hostname:    msg.Hostname,
+
+		CorrelationIDs: msg.CorrelationIDs,
 	}
 	if msg.File != nil {
 		data.BatchCount = msg.File.Control.BatchCount
@@ -132,11 +154,11 @@ func convertDollar(in int) float64 {
 	return float64(in) / 100.0
 }
 
-func sendEmail(cfg *config.Email, dialer *gomail.Dialer, filename, body string) error {
+func sendEmail(cfg *config.Email, dialer *gomail.Dialer, subject, body string) error {
 	m := gomail.NewMessage()
 	m.SetHeader("From", cfg.From)
 	m.SetHeader("To", cfg.To...)
-	m.SetHeader("Subject", fmt.Sprintf("%s uploaded by %s", filename, cfg.CompanyName))
+	m.SetHeader("Subject", subject)
 	m.SetBody("text/plain", body)
 
 	if err := dialer.DialAndSend(context.Background(), m); err != nil {
@@ -153,5 +175,8 @@ func countEntries(file *ach.File) int {
 	for i := range file.Batches {
 		total += len(file.Batches[i].GetEntries())
 	}
+	for i := range file.IATBatches {
+		total += len(file.IATBatches[i].GetEntries())
+	}
 	return total
 }
@@ -47,7 +47,7 @@ func TestEmailSend(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	if err := sendEmail(cfg, dialer, msg.Filename, body); err != nil {
+	if err := sendEmail(cfg, dialer, emailSubject(cfg, msg), body); err != nil {
 		t.Fatal(err)
 	}
 
@@ -96,5 +96,25 @@ func TestEmail__marshalDefaultTemplate(t *testing.T) {
 		require.Contains(t, contents, `Credits: $0.00`, "Test: "+test.desc)
 		require.Contains(t, contents, `Batches: 1`, "Test: "+test.desc)
 		require.Contains(t, contents, `Total Entries: 1`, "Test: "+test.desc)
+		require.NotContains(t, contents, "Correlation IDs", "Test: "+test.desc)
 	}
+
+	contents, err := marshalEmail(cfg, &Message{Direction: Upload, File: f, CorrelationIDs: []string{"abc", "def"}})
+	require.NoError(t, err)
+	require.Contains(t, contents, "Correlation IDs: abc, def\n")
+}
+
+func TestEmail__marshalDetail(t *testing.T) {
+	cfg := &config.Email{
+		CompanyName: "Moov",
+	}
+	msg := &Message{Direction: CutoffWarning, Detail: "12 transfers might miss the 16:20 cutoff"}
+
+	contents, err := marshalEmail(cfg, msg)
+	require.NoError(t, err)
+	require.Contains(t, contents, "Name: Moov")
+	require.Contains(t, contents, msg.Detail)
+
+	require.Equal(t, "cutoff warning for Moov", emailSubject(cfg, msg))
+	require.Equal(t, "20200529-131400.ach uploaded by Moov", emailSubject(cfg, &Message{Filename: "20200529-131400.ach"}))
 }
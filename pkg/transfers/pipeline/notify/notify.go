@@ -5,6 +5,9 @@
 package notify
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/moov-io/ach"
 )
 
@@ -13,6 +16,12 @@ type Direction string
 const (
 	Upload   Direction = "upload"
 	Download Direction = "download"
+
+	// Quarantine is used for inbound files held back from processing
+	Quarantine Direction = "quarantine"
+
+	// CutoffWarning is used when pending transfers might miss the next cutoff
+	CutoffWarning Direction = "cutoff warning"
 )
 
 type Message struct {
@@ -20,9 +29,23 @@ type Message struct {
 	Filename  string
 	File      *ach.File
 	Hostname  string
+
+	// Detail describes messages which aren't about one file, such as cutoff warnings
+	Detail string
+
+	// CorrelationIDs identify the Transfers with entries in File
+	CorrelationIDs []string
 }
 
 type Sender interface {
 	Info(msg *Message) error
 	Critical(msg *Message) error
 }
+
+// correlationSuffix lists the Transfers in msg so a notification can be found in logs.
+func correlationSuffix(msg *Message) string {
+	if len(msg.CorrelationIDs) == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" (correlation IDs: %s)", strings.Join(msg.CorrelationIDs, ", "))
+}
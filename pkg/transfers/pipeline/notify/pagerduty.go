@@ -55,7 +55,7 @@ func (pd *PagerDuty) Info(msg *Message) error {
 		Urgency: "low",
 		Body: &pagerduty.APIDetails{
 			Type:    "incident_body",
-			Details: fmt.Sprintf("SUCCESSFUL %s of %s", msg.Direction, msg.Filename),
+			Details: fmt.Sprintf("SUCCESSFUL %s of %s%s", msg.Direction, msg.Filename, correlationSuffix(msg)),
 		},
 		Service: &pagerduty.APIReference{
 			Type: "service_reference",
@@ -70,13 +70,17 @@ func (pd *PagerDuty) Critical(msg *Message) error {
 		Title: fmt.Sprintf("ERROR during file %s", msg.Direction),
 		Body: &pagerduty.APIDetails{
 			Type:    "incident_body",
-			Details: fmt.Sprintf("FAILURE on %s of %s", msg.Direction, msg.Filename),
+			Details: fmt.Sprintf("FAILURE on %s of %s%s", msg.Direction, msg.Filename, correlationSuffix(msg)),
 		},
 		Service: &pagerduty.APIReference{
 			Type: "service_reference",
 			ID:   pd.serviceKey,
 		},
 	}
+	if msg.Detail != "" {
+		opts.Title = fmt.Sprintf("WARNING: %s", msg.Direction)
+		opts.Body.Details = msg.Detail
+	}
 	if msg.Direction == Download {
 		// Downloads don't have to such a high priority
 		opts.Urgency = "low"
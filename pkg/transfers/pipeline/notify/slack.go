@@ -51,6 +51,9 @@ func (s *Slack) Critical(msg *Message) error {
 }
 
 func marshalSlackMessage(status uploadStatus, msg *Message) string {
+	if msg.Detail != "" {
+		return fmt.Sprintf("%s %s: %s", status, msg.Direction, msg.Detail)
+	}
 	slackMsg := fmt.Sprintf("%s %s of %s", status, msg.Direction, msg.Filename)
 	if msg.Hostname != "" {
 		if msg.Direction == Upload {
@@ -59,7 +62,7 @@ func marshalSlackMessage(status uploadStatus, msg *Message) string {
 			slackMsg += fmt.Sprintf(" from %s", msg.Hostname)
 		}
 	}
-	slackMsg += " with ODFI server"
+	slackMsg += " with ODFI server" + correlationSuffix(msg)
 
 	return slackMsg
 }
@@ -68,6 +68,10 @@ func TestSlack__marshal(t *testing.T) {
 			"SUCCESSFUL download of myfile.txt from ftp.mybank.com:1234 with ODFI server"},
 		{"failed download", failed, &Message{Direction: Download, Filename: "myfile.txt", Hostname: "ftp.mybank.com:1234"},
 			"FAILED download of myfile.txt from ftp.mybank.com:1234 with ODFI server"},
+		{"upload with correlation IDs", success, &Message{Direction: Upload, Filename: "myfile.txt", CorrelationIDs: []string{"abc", "def"}},
+			"SUCCESSFUL upload of myfile.txt with ODFI server (correlation IDs: abc, def)"},
+		{"cutoff warning", failed, &Message{Direction: CutoffWarning, Detail: "12 transfers might miss the 16:20 cutoff"},
+			"FAILED cutoff warning: 12 transfers might miss the 16:20 cutoff"},
 	}
 
 	for _, test := range tests {
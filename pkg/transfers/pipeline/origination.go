@@ -0,0 +1,119 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package pipeline
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/moov-io/ach"
+	moovhttp "github.com/moov-io/base/http"
+
+	"github.com/moov-io/paygate/pkg/admin"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/x/route"
+)
+
+func fileAmount(file *ach.File) int64 {
+	return int64(file.Control.TotalDebitEntryDollarAmountInFile + file.Control.TotalCreditEntryDollarAmountInFile)
+}
+
+func (xfagg *XferAggregator) originationCap(routingNumber string) *config.OriginationCap {
+	if xfagg.cfg == nil {
+		return nil
+	}
+	return xfagg.cfg.ODFI.OriginationCapFor(routingNumber)
+}
+
+// checkOriginationCap returns an error when uploading file would put its routing number
+// over the ODFI's daily origination cap. Caps which only warn are logged instead.
+func (xfagg *XferAggregator) checkOriginationCap(file *ach.File) error {
+	limit := xfagg.originationCap(file.Header.ImmediateDestination)
+	if limit == nil || xfagg.files == nil {
+		return nil
+	}
+	originated, err := xfagg.files.Originated(limit.RoutingNumber, xfagg.clock.Now())
+	if err != nil {
+		return fmt.Errorf("problem reading origination for %s: %v", limit.RoutingNumber, err)
+	}
+	recordOrigination(limit.RoutingNumber, originated, limit.Daily)
+
+	logger := xfagg.logger.Set("routingNumber", limit.RoutingNumber)
+	total := originated + fileAmount(file)
+	if total > limit.Daily {
+		originationCapExceeded.With("routing_number", limit.RoutingNumber).Add(1)
+		err := fmt.Errorf("file would bring %s to %d cents originated today, over its daily cap of %d", limit.RoutingNumber, total, limit.Daily)
+		if limit.WarnOnly {
+			logger.Warn().Logf("uploading over origination cap: %v", err)
+			return nil
+		}
+		return err
+	}
+	if limit.NearCap(total) {
+		logger.Warn().Logf("file brings %s to %d cents originated today, near its daily cap of %d", limit.RoutingNumber, total, limit.Daily)
+	}
+	return nil
+}
+
+// originationUtilization reads how much of a routing number's daily cap was used today.
+func (xfagg *XferAggregator) originationUtilization(routingNumber string) (*admin.OriginationUtilization, error) {
+	now := xfagg.clock.Now()
+	out := &admin.OriginationUtilization{
+		RoutingNumber: routingNumber,
+		Day:           now.Format("2006-01-02"),
+	}
+	if xfagg.files != nil {
+		originated, err := xfagg.files.Originated(routingNumber, now)
+		if err != nil {
+			return nil, err
+		}
+		out.Originated = originated
+	}
+	if limit := xfagg.originationCap(routingNumber); limit != nil {
+		out.Cap = limit.Daily
+		out.Remaining = limit.Daily - out.Originated
+		if out.Remaining < 0 {
+			out.Remaining = 0
+		}
+		out.Percent = float32(out.Originated) * 100 / float32(limit.Daily)
+		out.WarnOnly = limit.WarnOnly
+	}
+	recordOrigination(routingNumber, out.Originated, out.Cap)
+	return out, nil
+}
+
+// updateOriginationMetrics refreshes the utilization gauges after a file is uploaded.
+func (xfagg *XferAggregator) updateOriginationMetrics(routingNumber string) {
+	if _, err := xfagg.originationUtilization(routingNumber); err != nil {
+		xfagg.logger.Set("routingNumber", routingNumber).LogErrorf("problem reading origination: %v", err)
+	}
+}
+
+func (xfagg *XferAggregator) getOriginationUtilization() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			moovhttp.Problem(w, fmt.Errorf("invalid method %s", r.Method))
+			return
+		}
+
+		routingNumber := route.ReadPathID("routingNumber", r)
+		if err := ach.CheckRoutingNumber(routingNumber); err != nil {
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			moovhttp.Problem(w, err)
+			return
+		}
+		out, err := xfagg.originationUtilization(routingNumber)
+		if err != nil {
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			moovhttp.Problem(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(out)
+	}
+}
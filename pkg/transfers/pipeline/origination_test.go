@@ -0,0 +1,105 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package pipeline
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/moov-io/ach"
+	"github.com/moov-io/base/log"
+
+	"github.com/moov-io/paygate/pkg/admin"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/transfers/files"
+	"github.com/moov-io/paygate/x/schedule"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrigination__checkOriginationCap(t *testing.T) {
+	file, err := ach.ReadFile(filepath.Join("..", "..", "..", "testdata", "ppd-debit.ach"))
+	require.NoError(t, err)
+	amount := fileAmount(file)
+
+	cfg := config.Empty()
+	cfg.ODFI.OriginationCaps = []config.OriginationCap{
+		{RoutingNumber: file.Header.ImmediateDestination, Daily: amount * 2, WarnPercent: 75},
+	}
+	repo := &files.MockRepository{}
+	xfagg := &XferAggregator{
+		cfg:    cfg,
+		logger: log.NewNopLogger(),
+		clock:  schedule.NewMockClock(time.Date(2020, time.June, 1, 16, 20, 0, 0, time.UTC)),
+		files:  repo,
+	}
+	require.NoError(t, xfagg.checkOriginationCap(file))
+
+	// exactly at the cap
+	repo.Origination = amount
+	require.NoError(t, xfagg.checkOriginationCap(file))
+
+	repo.Origination = amount + 1
+	require.Error(t, xfagg.checkOriginationCap(file))
+
+	// warnings don't block uploads
+	cfg.ODFI.OriginationCaps[0].WarnOnly = true
+	require.NoError(t, xfagg.checkOriginationCap(file))
+
+	repo.Err = errors.New("bad error")
+	require.Error(t, xfagg.checkOriginationCap(file))
+
+	// other routing numbers have no cap
+	cfg.ODFI.OriginationCaps[0].RoutingNumber = "987654320"
+	require.NoError(t, xfagg.checkOriginationCap(file))
+}
+
+func TestOrigination__getOriginationUtilization(t *testing.T) {
+	cfg := config.Empty()
+	cfg.ODFI.OriginationCaps = []config.OriginationCap{
+		{RoutingNumber: "987654320", Daily: 100000},
+	}
+	xfagg := &XferAggregator{
+		cfg:    cfg,
+		logger: log.NewNopLogger(),
+		clock:  schedule.NewMockClock(time.Date(2020, time.June, 1, 16, 20, 0, 0, time.UTC)),
+		files:  &files.MockRepository{Origination: 25000},
+	}
+
+	r := mux.NewRouter()
+	r.Path("/odfi/{routingNumber}/utilization").HandlerFunc(xfagg.getOriginationUtilization())
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/odfi/987654320/utilization", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var util admin.OriginationUtilization
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&util))
+	require.Equal(t, "987654320", util.RoutingNumber)
+	require.Equal(t, "2020-06-01", util.Day)
+	require.Equal(t, int64(25000), util.Originated)
+	require.Equal(t, int64(100000), util.Cap)
+	require.Equal(t, int64(75000), util.Remaining)
+	require.Equal(t, float32(25), util.Percent)
+
+	// routing numbers without a cap
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/odfi/231380104/utilization", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	util = admin.OriginationUtilization{}
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&util))
+	require.Equal(t, int64(0), util.Cap)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/odfi/12345/utilization", nil))
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -31,6 +31,9 @@ func NewFormatter(cfg *config.Output) (Formatter, error) {
 
 	case strings.EqualFold(cfg.Format, "nacha"):
 		return &NACHA{}, nil
+
+	case strings.EqualFold(cfg.Format, "zip"):
+		return newZipBundle(cfg.ZIP)
 	}
 	return nil, errors.New("unknown output format")
 }
@@ -8,14 +8,14 @@ import (
 	"bytes"
 	"fmt"
 
-	"github.com/moov-io/ach"
+	"github.com/moov-io/paygate/pkg/achx"
 	"github.com/moov-io/paygate/pkg/transfers/pipeline/transform"
 )
 
 type NACHA struct{}
 
 func (*NACHA) Format(buf *bytes.Buffer, res *transform.Result) error {
-	if err := ach.NewWriter(buf).Write(res.File); err != nil {
+	if err := achx.WriteFile(buf, res.File, res.Layout); err != nil {
 		return fmt.Errorf("unable to buffer ACH file: %v", err)
 	}
 	return nil
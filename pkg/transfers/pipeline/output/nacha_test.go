@@ -11,6 +11,7 @@ import (
 	"testing"
 
 	"github.com/moov-io/ach"
+	"github.com/moov-io/paygate/pkg/config"
 	"github.com/moov-io/paygate/pkg/transfers/pipeline/transform"
 )
 
@@ -43,3 +44,18 @@ func TestNACHA(t *testing.T) {
 		t.Errorf("unexpected output:\n%v", s)
 	}
 }
+
+func TestNACHA__layout(t *testing.T) {
+	enc := &NACHA{}
+
+	var buf bytes.Buffer
+	res := testResult(t)
+	res.Layout = config.FileLayout{BlockingFactor: 1, LineEnding: "crlf"}
+
+	if err := enc.Format(&buf, res); err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(buf.String(), "\r\n"); n != 5 {
+		t.Errorf("unexpected %d records:\n%v", n, buf.String())
+	}
+}
@@ -0,0 +1,140 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package output
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"strings"
+	"time"
+
+	"github.com/moov-io/ach"
+
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/transfers/pipeline/transform"
+)
+
+// ZipBundle writes a password-protected ZIP archive holding the ACH file (or its encrypted
+// bytes) and a control file with the file's totals. Settings are picked by the file's
+// ImmediateDestination.
+type ZipBundle struct {
+	cfg *config.ZipOutput
+}
+
+func newZipBundle(cfg *config.ZipOutput) (*ZipBundle, error) {
+	if cfg == nil {
+		return nil, errors.New("missing zip config")
+	}
+	return &ZipBundle{cfg: cfg}, nil
+}
+
+func (z *ZipBundle) Format(buf *bytes.Buffer, res *transform.Result) error {
+	if res == nil || res.File == nil {
+		return errors.New("nil Result / File")
+	}
+	routingNumber := strings.TrimSpace(res.File.Header.ImmediateDestination)
+	bundle := z.cfg.Bundle(routingNumber)
+	if bundle.Password == "" {
+		return fmt.Errorf("no zip password for routing number %s", routingNumber)
+	}
+
+	contents := res.Encrypted
+	if len(contents) == 0 {
+		var nacha bytes.Buffer
+		if err := (&NACHA{}).Format(&nacha, res); err != nil {
+			return err
+		}
+		contents = nacha.Bytes()
+	}
+	control := controlFile(bundle.ControlLayout, res.File)
+
+	name := fmt.Sprintf("%s-%s", routingNumber, res.File.Header.FileCreationDate)
+	now := time.Now()
+
+	w := zip.NewWriter(buf)
+	if err := writeEncrypted(w, bundle.Password, name+".ach", now, contents); err != nil {
+		return fmt.Errorf("writing ACH file: %v", err)
+	}
+	if err := writeEncrypted(w, bundle.Password, name+".ctl", now, control); err != nil {
+		return fmt.Errorf("writing control file: %v", err)
+	}
+	return w.Close()
+}
+
+func writeEncrypted(w *zip.Writer, password, name string, modified time.Time, data []byte) error {
+	var compressed bytes.Buffer
+	fw, err := flate.NewWriter(&compressed, flate.DefaultCompression)
+	if err != nil {
+		return err
+	}
+	if _, err := fw.Write(data); err != nil {
+		return err
+	}
+	if err := fw.Close(); err != nil {
+		return err
+	}
+
+	crc := crc32.ChecksumIEEE(data)
+	encrypted, err := encryptEntry(password, crc, compressed.Bytes())
+	if err != nil {
+		return err
+	}
+
+	hdr := &zip.FileHeader{
+		Name:               name,
+		Method:             zip.Deflate,
+		Flags:              0x1, // encrypted
+		Modified:           modified,
+		CRC32:              crc,
+		CompressedSize64:   uint64(len(encrypted)),
+		UncompressedSize64: uint64(len(data)),
+	}
+	raw, err := w.CreateRaw(hdr)
+	if err != nil {
+		return err
+	}
+	_, err = raw.Write(encrypted)
+	return err
+}
+
+// controlFile summarizes an ACH file's totals in the given layout.
+func controlFile(layout string, file *ach.File) []byte {
+	routingNumber := strings.TrimSpace(file.Header.ImmediateDestination)
+	fc := file.Control
+
+	if strings.EqualFold(layout, "text") {
+		var buf bytes.Buffer
+		fmt.Fprintf(&buf, "routingNumber=%s\n", routingNumber)
+		fmt.Fprintf(&buf, "fileCreationDate=%s\n", file.Header.FileCreationDate)
+		fmt.Fprintf(&buf, "batchCount=%d\n", fc.BatchCount)
+		fmt.Fprintf(&buf, "entryAddendaCount=%d\n", fc.EntryAddendaCount)
+		fmt.Fprintf(&buf, "entryHash=%d\n", fc.EntryHash)
+		fmt.Fprintf(&buf, "totalDebit=%d\n", fc.TotalDebitEntryDollarAmountInFile)
+		fmt.Fprintf(&buf, "totalCredit=%d\n", fc.TotalCreditEntryDollarAmountInFile)
+		return buf.Bytes()
+	}
+
+	// Fixed-width record:
+	//  1-9    routing number
+	//  10-15  file creation date (YYMMDD)
+	//  16-21  batch count
+	//  22-29  entry and addenda count
+	//  30-39  entry hash
+	//  40-51  total debits (cents)
+	//  52-63  total credits (cents)
+	return []byte(fmt.Sprintf("%-9.9s%-6.6s%06d%08d%010d%012d%012d\n",
+		routingNumber,
+		file.Header.FileCreationDate,
+		fc.BatchCount,
+		fc.EntryAddendaCount,
+		fc.EntryHash%10000000000,
+		fc.TotalDebitEntryDollarAmountInFile,
+		fc.TotalCreditEntryDollarAmountInFile,
+	))
+}
@@ -0,0 +1,109 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package output
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/moov-io/paygate/pkg/config"
+
+	"github.com/stretchr/testify/require"
+)
+
+// decryptEntry reverses encryptEntry and checks the password against the header
+func decryptEntry(t *testing.T, f *zip.File, password string) []byte {
+	t.Helper()
+
+	require.Equal(t, uint16(0x1), f.Flags&0x1)
+	r, err := f.OpenRaw()
+	require.NoError(t, err)
+	encrypted, err := ioutil.ReadAll(r)
+	require.NoError(t, err)
+
+	z := newZipCrypto(password)
+	plain := make([]byte, len(encrypted))
+	for i := range encrypted {
+		plain[i] = encrypted[i] ^ z.stream()
+		z.update(plain[i])
+	}
+	require.Equal(t, byte(f.CRC32>>24), plain[11], "password check")
+
+	bs, err := ioutil.ReadAll(flate.NewReader(bytes.NewReader(plain[12:])))
+	require.NoError(t, err)
+	return bs
+}
+
+func TestZipBundle(t *testing.T) {
+	res := testResult(t)
+	routingNumber := strings.TrimSpace(res.File.Header.ImmediateDestination)
+
+	cfg := &config.Output{
+		Format: "zip",
+		ZIP: &config.ZipOutput{
+			Password: "default",
+			RoutingNumbers: map[string]config.ZipBundle{
+				routingNumber: {Password: "secret", ControlLayout: "text"},
+			},
+		},
+	}
+	enc, err := NewFormatter(cfg)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, enc.Format(&buf, res))
+
+	r, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	require.Len(t, r.File, 2)
+
+	prefix := routingNumber + "-" + res.File.Header.FileCreationDate
+	require.Equal(t, prefix+".ach", r.File[0].Name)
+	require.Equal(t, prefix+".ctl", r.File[1].Name)
+
+	var nacha bytes.Buffer
+	require.NoError(t, (&NACHA{}).Format(&nacha, res))
+	require.Equal(t, nacha.String(), string(decryptEntry(t, r.File[0], "secret")))
+
+	control := string(decryptEntry(t, r.File[1], "secret"))
+	require.Contains(t, control, "routingNumber="+routingNumber+"\n")
+	require.Contains(t, control, "batchCount=1\n")
+}
+
+func TestZipBundle__fixedControl(t *testing.T) {
+	res := testResult(t)
+	res.Encrypted = []byte("gpg encrypted bytes")
+
+	enc, err := newZipBundle(&config.ZipOutput{Password: "default"})
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, enc.Format(&buf, res))
+
+	r, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	require.Equal(t, "gpg encrypted bytes", string(decryptEntry(t, r.File[0], "default")))
+
+	control := decryptEntry(t, r.File[1], "default")
+	require.Len(t, control, 64)
+	require.Equal(t, strings.TrimSpace(res.File.Header.ImmediateDestination), string(control[:9]))
+	require.Equal(t, "000001", string(control[15:21]))
+}
+
+func TestZipBundle__errors(t *testing.T) {
+	_, err := NewFormatter(&config.Output{Format: "zip"})
+	require.Error(t, err)
+
+	enc, err := newZipBundle(&config.ZipOutput{})
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.Error(t, enc.Format(&buf, testResult(t)))
+	require.Error(t, enc.Format(&buf, nil))
+}
@@ -0,0 +1,61 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package output
+
+import (
+	"crypto/rand"
+	"hash/crc32"
+)
+
+// zipCrypto implements the traditional PKWARE encryption from section 6.1 of the ZIP
+// specification (APPNOTE.TXT), which is what most ODFIs mean by a password-protected ZIP.
+type zipCrypto struct {
+	keys [3]uint32
+}
+
+func newZipCrypto(password string) *zipCrypto {
+	z := &zipCrypto{keys: [3]uint32{0x12345678, 0x23456789, 0x34567890}}
+	for i := 0; i < len(password); i++ {
+		z.update(password[i])
+	}
+	return z
+}
+
+func crc32Update(crc uint32, b byte) uint32 {
+	return crc32.IEEETable[byte(crc)^b] ^ (crc >> 8)
+}
+
+func (z *zipCrypto) update(b byte) {
+	z.keys[0] = crc32Update(z.keys[0], b)
+	z.keys[1] = (z.keys[1]+(z.keys[0]&0xff))*134775813 + 1
+	z.keys[2] = crc32Update(z.keys[2], byte(z.keys[1]>>24))
+}
+
+func (z *zipCrypto) stream() byte {
+	temp := uint16(z.keys[2] | 2)
+	return byte((temp * (temp ^ 1)) >> 8)
+}
+
+func (z *zipCrypto) encrypt(data []byte) []byte {
+	out := make([]byte, len(data))
+	for i := range data {
+		out[i] = data[i] ^ z.stream()
+		z.update(data[i])
+	}
+	return out
+}
+
+// encryptEntry returns the 12 byte encryption header followed by the encrypted data.
+// The header's last byte is the high byte of crc so readers can check the password.
+func encryptEntry(password string, crc uint32, data []byte) ([]byte, error) {
+	header := make([]byte, 12)
+	if _, err := rand.Read(header[:11]); err != nil {
+		return nil, err
+	}
+	header[11] = byte(crc >> 24)
+
+	z := newZipCrypto(password)
+	return append(z.encrypt(header), z.encrypt(data)...), nil
+}
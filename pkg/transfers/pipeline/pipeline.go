@@ -12,8 +12,22 @@ import (
 type Xfer struct {
 	Transfer *client.Transfer `json:"transfer"`
 	File     *ach.File        `json:"file"`
+
+	// Trace is the span context of the Transfer's creation so merging continues its trace.
+	Trace map[string]string `json:"trace,omitempty"`
 }
 
 type CanceledTransfer struct {
 	TransferID string `json:"transferID"`
+
+	// Reason and Note are copied from the request which canceled the Transfer so consumers
+	// can tell customer cancellations apart from compliance or fraud holds.
+	Reason string `json:"reason,omitempty"`
+	Note   string `json:"note,omitempty"`
+}
+
+// TriggeredCutoff is a request for any XferAggregator consuming the pipeline to perform
+// cutoff processing immediately. This is published from instances not running an aggregator.
+type TriggeredCutoff struct {
+	TriggerID string `json:"triggerID"`
 }
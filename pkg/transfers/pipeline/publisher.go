@@ -12,6 +12,9 @@ import (
 	"github.com/moov-io/base"
 	"github.com/moov-io/paygate/pkg/client"
 	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/x/trace"
+
+	opentracing "github.com/opentracing/opentracing-go"
 )
 
 // XferPublisher is an interface for pushing Transfers (and their ACH files) to be
@@ -20,6 +23,7 @@ import (
 type XferPublisher interface {
 	Upload(xfer Xfer) error
 	Cancel(msg CanceledTransfer) error
+	TriggerCutoff(msg TriggeredCutoff) error
 	Shutdown(ctx context.Context)
 }
 
@@ -36,15 +40,24 @@ func NewPublisher(cfg config.Pipeline) (XferPublisher, error) {
 // All files are attempted to be published as downstream processors
 // are expected to de-duplicate files.
 func PublishFiles(pub XferPublisher, xfer *client.Transfer, files []*ach.File) error {
+	return PublishTracedFiles(pub, nil, xfer, files)
+}
+
+// PublishTracedFiles publishes files like PublishFiles along with the context of span, so
+// merging and uploading the files are traced with the Transfer's creation.
+func PublishTracedFiles(pub XferPublisher, span opentracing.Span, xfer *client.Transfer, files []*ach.File) error {
 	if pub == nil {
 		return nil
 	}
 
+	carrier := trace.Inject(span)
+
 	var el base.ErrorList
 	for i := range files {
 		xf := Xfer{
 			File:     files[i],
 			Transfer: xfer,
+			Trace:    carrier,
 		}
 		if err := pub.Upload(xf); err != nil {
 			el.Add(err)
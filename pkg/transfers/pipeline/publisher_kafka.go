@@ -5,12 +5,17 @@
 package pipeline
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	"errors"
+	"fmt"
+	"io/ioutil"
 
 	"github.com/moov-io/paygate/pkg/config"
 	"github.com/moov-io/paygate/pkg/stream"
 
 	"github.com/Shopify/sarama"
+	"gocloud.dev/pubsub"
 )
 
 func createKafkaPublisher(cfg *config.KafkaPipeline) (*streamPublisher, error) {
@@ -18,11 +23,97 @@ func createKafkaPublisher(cfg *config.KafkaPipeline) (*streamPublisher, error) {
 		return nil, errors.New("nil Kafka config")
 	}
 
-	pub := &streamPublisher{}
+	pub := &streamPublisher{
+		topics: make(map[string]*pubsub.Topic),
+	}
+
+	// Open one topic per distinct name and share it between message types publishing onto it.
+	opened := make(map[string]*pubsub.Topic)
+	open := func(name string) (*pubsub.Topic, error) {
+		if topic, exists := opened[name]; exists {
+			return topic, nil
+		}
+		config, err := saramaConfig(cfg)
+		if err != nil {
+			return nil, err
+		}
+		topic, err := stream.KafkaTopic(cfg.Brokers, config, name, nil)
+		if err != nil {
+			return nil, fmt.Errorf("kafka topic %s: %v", name, err)
+		}
+		opened[name] = topic
+		return topic, nil
+	}
+
 	var err error
+	if pub.topic, err = open(cfg.TransfersTopic()); err != nil {
+		return nil, err
+	}
+	if pub.topics[messageTypeCancel], err = open(cfg.CancellationsTopic()); err != nil {
+		return nil, err
+	}
+	if pub.topics[messageTypeCutoff], err = open(cfg.CutoffsTopic()); err != nil {
+		return nil, err
+	}
+	return pub, nil
+}
+
+func createKafkaSubscription(cfg *config.KafkaPipeline) (*pubsub.Subscription, error) {
+	if cfg == nil {
+		return nil, errors.New("nil Kafka config")
+	}
+	config, err := saramaConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return stream.KafkaSubscription(cfg.Brokers, config, cfg.Group, cfg.AllTopics(), nil)
+}
 
+// saramaConfig returns the client config for connecting to cfg.Brokers with optional
+// TLS and SASL/PLAIN authentication.
+func saramaConfig(cfg *config.KafkaPipeline) (*sarama.Config, error) {
 	config := sarama.NewConfig()
-	pub.topic, err = stream.KafkaTopic(cfg.Brokers, config, cfg.Topic, nil)
+	if cfg.TLS != nil {
+		tlsConfig, err := kafkaTLSConfig(cfg.TLS)
+		if err != nil {
+			return nil, fmt.Errorf("kafka tls: %v", err)
+		}
+		config.Net.TLS.Enable = true
+		config.Net.TLS.Config = tlsConfig
+	}
+	if cfg.SASL != nil {
+		config.Net.SASL.Enable = true
+		config.Net.SASL.Mechanism = sarama.SASLTypePlaintext
+		config.Net.SASL.User = cfg.SASL.Username
+		config.Net.SASL.Password = cfg.SASL.GetPassword()
+	}
+	return config, nil
+}
 
-	return pub, err
+func kafkaTLSConfig(cfg *config.KafkaTLS) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: cfg.InsecureSkipVerify,
+	}
+	if cfg.CAFile != "" {
+		bs, err := ioutil.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %v", cfg.CAFile, err)
+		}
+		pool, err := x509.SystemCertPool()
+		if pool == nil || err != nil {
+			pool = x509.NewCertPool()
+		}
+		if ok := pool.AppendCertsFromPEM(bs); !ok {
+			return nil, fmt.Errorf("problem with AppendCertsFromPEM from %s", cfg.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if cfg.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("client certificate: %v", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
 }
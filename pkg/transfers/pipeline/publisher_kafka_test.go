@@ -0,0 +1,72 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package pipeline
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/moov-io/paygate/pkg/config"
+
+	"github.com/Shopify/sarama"
+	"github.com/stretchr/testify/require"
+	"gocloud.dev/pubsub"
+	"gocloud.dev/pubsub/mempubsub"
+)
+
+func TestKafka__saramaConfig(t *testing.T) {
+	cfg := &config.KafkaPipeline{
+		Brokers: []string{"localhost:9092"},
+		Group:   "paygate",
+		Topic:   "transfers",
+	}
+	conf, err := saramaConfig(cfg)
+	require.NoError(t, err)
+	require.False(t, conf.Net.TLS.Enable)
+	require.False(t, conf.Net.SASL.Enable)
+
+	cfg.TLS = &config.KafkaTLS{InsecureSkipVerify: true}
+	cfg.SASL = &config.KafkaSASL{Username: "paygate", Password: "secret"}
+	conf, err = saramaConfig(cfg)
+	require.NoError(t, err)
+	require.True(t, conf.Net.TLS.Enable)
+	require.True(t, conf.Net.TLS.Config.InsecureSkipVerify)
+	require.True(t, conf.Net.SASL.Enable)
+	require.Equal(t, sarama.SASLMechanism(sarama.SASLTypePlaintext), conf.Net.SASL.Mechanism)
+	require.Equal(t, "paygate", conf.Net.SASL.User)
+	require.Equal(t, "secret", conf.Net.SASL.Password)
+
+	cfg.TLS.CAFile = "/does/not/exist.pem"
+	_, err = saramaConfig(cfg)
+	require.Error(t, err)
+}
+
+func TestStreamPublisher__topicPerMessageType(t *testing.T) {
+	transfers, cancels := mempubsub.NewTopic(), mempubsub.NewTopic()
+	pub := &streamPublisher{
+		topic: transfers,
+		topics: map[string]*pubsub.Topic{
+			messageTypeCancel: cancels,
+		},
+	}
+	defer pub.Shutdown(context.Background())
+
+	require.Equal(t, transfers, pub.topicFor(messageTypeXfer))
+	require.Equal(t, cancels, pub.topicFor(messageTypeCancel))
+	require.Equal(t, transfers, pub.topicFor(messageTypeCutoff))
+
+	sub := mempubsub.NewSubscription(cancels, time.Second)
+	defer sub.Shutdown(context.Background())
+
+	require.NoError(t, pub.Cancel(CanceledTransfer{TransferID: "xfer"}))
+
+	ctx, cancelFn := context.WithTimeout(context.Background(), time.Second)
+	defer cancelFn()
+	msg, err := sub.Receive(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "xfer", msg.Metadata["transferID"])
+	msg.Ack()
+}
@@ -11,6 +11,7 @@ import (
 type MockPublisher struct {
 	Xfers   map[string]Xfer
 	Cancels map[string]CanceledTransfer
+	Cutoffs []TriggeredCutoff
 
 	Err error
 }
@@ -32,4 +33,9 @@ func (p *MockPublisher) Cancel(msg CanceledTransfer) error {
 	return p.Err
 }
 
+func (p *MockPublisher) TriggerCutoff(msg TriggeredCutoff) error {
+	p.Cutoffs = append(p.Cutoffs, msg)
+	return p.Err
+}
+
 func (p *MockPublisher) Shutdown(ctx context.Context) {}
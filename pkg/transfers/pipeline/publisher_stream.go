@@ -10,6 +10,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/moov-io/paygate/pkg/config"
 
@@ -18,6 +19,17 @@ import (
 
 type streamPublisher struct {
 	topic *pubsub.Topic
+	name  string // label for metrics
+
+	// topics overrides topic for a message type (e.g. cancel, cutoff)
+	topics map[string]*pubsub.Topic
+}
+
+func (pub *streamPublisher) topicFor(messageType string) *pubsub.Topic {
+	if topic, exists := pub.topics[messageType]; exists && topic != nil {
+		return topic
+	}
+	return pub.topic
 }
 
 func (pub *streamPublisher) Upload(xfer Xfer) error {
@@ -25,6 +37,7 @@ func (pub *streamPublisher) Upload(xfer Xfer) error {
 		Metadata: make(map[string]string),
 	}
 	msg.Metadata["transferID"] = xfer.Transfer.TransferID
+	msg.Metadata[publishedAtKey] = time.Now().Format(time.RFC3339Nano)
 
 	var buf bytes.Buffer
 	if err := json.NewEncoder(&buf).Encode(xfer); err != nil {
@@ -32,7 +45,9 @@ func (pub *streamPublisher) Upload(xfer Xfer) error {
 	}
 	msg.Body = buf.Bytes()
 
-	return pub.topic.Send(context.TODO(), msg)
+	err := pub.topicFor(messageTypeXfer).Send(context.TODO(), msg)
+	recordPublished(pub.name, messageTypeXfer, err)
+	return err
 }
 
 func (pub *streamPublisher) Cancel(cancel CanceledTransfer) error {
@@ -40,6 +55,7 @@ func (pub *streamPublisher) Cancel(cancel CanceledTransfer) error {
 		Metadata: make(map[string]string),
 	}
 	msg.Metadata["transferID"] = cancel.TransferID
+	msg.Metadata[publishedAtKey] = time.Now().Format(time.RFC3339Nano)
 
 	var buf bytes.Buffer
 	if err := json.NewEncoder(&buf).Encode(cancel); err != nil {
@@ -47,7 +63,27 @@ func (pub *streamPublisher) Cancel(cancel CanceledTransfer) error {
 	}
 	msg.Body = buf.Bytes()
 
-	return pub.topic.Send(context.TODO(), msg)
+	err := pub.topicFor(messageTypeCancel).Send(context.TODO(), msg)
+	recordPublished(pub.name, messageTypeCancel, err)
+	return err
+}
+
+func (pub *streamPublisher) TriggerCutoff(trigger TriggeredCutoff) error {
+	msg := &pubsub.Message{
+		Metadata: make(map[string]string),
+	}
+	msg.Metadata["triggerID"] = trigger.TriggerID
+	msg.Metadata[publishedAtKey] = time.Now().Format(time.RFC3339Nano)
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(trigger); err != nil {
+		return fmt.Errorf("triggerID=%s json encode: %v", trigger.TriggerID, err)
+	}
+	msg.Body = buf.Bytes()
+
+	err := pub.topicFor(messageTypeCutoff).Send(context.TODO(), msg)
+	recordPublished(pub.name, messageTypeCutoff, err)
+	return err
 }
 
 func (pub *streamPublisher) Shutdown(ctx context.Context) {
@@ -55,6 +91,14 @@ func (pub *streamPublisher) Shutdown(ctx context.Context) {
 		return
 	}
 	pub.topic.Shutdown(ctx)
+
+	closed := map[*pubsub.Topic]bool{pub.topic: true}
+	for _, topic := range pub.topics {
+		if !closed[topic] {
+			closed[topic] = true
+			topic.Shutdown(ctx)
+		}
+	}
 }
 
 func createStreamPublisher(cfg *config.StreamPipeline) (XferPublisher, error) {
@@ -62,10 +106,20 @@ func createStreamPublisher(cfg *config.StreamPipeline) (XferPublisher, error) {
 		return nil, errors.New("missing config: StreamPipeline")
 	}
 	if cfg.InMem != nil {
-		return inmemPublisher(cfg.InMem.URL)
+		pub, err := inmemPublisher(cfg.InMem.URL)
+		if err != nil {
+			return nil, err
+		}
+		pub.name = topicName(cfg)
+		return pub, nil
 	}
 	if cfg.Kafka != nil {
-		return createKafkaPublisher(cfg.Kafka)
+		pub, err := createKafkaPublisher(cfg.Kafka)
+		if err != nil {
+			return nil, err
+		}
+		pub.name = topicName(cfg)
+		return pub, nil
 	}
 	return nil, errors.New("unknown StreamPipeline config")
 }
@@ -9,11 +9,30 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/moov-io/base"
+
+	"github.com/moov-io/paygate/pkg/admin"
 	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/transfers/history"
 )
 
 type Repository interface {
 	MarkTransfersAsProcessed(transferIDs []string) error
+
+	// getUnprocessedTransfers returns transfers which were uploaded but failed to be marked as processed.
+	getUnprocessedTransfers() ([]*admin.UnprocessedTransfer, error)
+	deleteUnprocessedTransfer(transferID string) error
+
+	// getTransferStatuses returns the organization and status of each transfer found
+	getTransferStatuses(transferIDs []string) (map[string]transferStatus, error)
+
+	// getTraceTransferIDs returns the transfers whose entries have any of the trace numbers
+	getTraceTransferIDs(traceNumbers []string) ([]string, error)
+}
+
+type transferStatus struct {
+	organization string
+	status       client.TransferStatus
 }
 
 func NewRepo(db *sql.DB) *sqlRepo {
@@ -27,52 +46,240 @@ type sqlRepo struct {
 // MarkTransfersAsProcessed updates the status for transfers and micro-deposits to PROCESSED.
 // This signals that the underlying ACH file has been uploaded to the ODFI.
 //
+// Each transfer is updated in its own transaction so one failure doesn't leave every other
+// transfer from the file unmarked. Failed transfers are saved as unprocessed and retried on the
+// next call, and transfers which are already PROCESSED are skipped so retries are safe.
+// The returned error is a base.ErrorList of each transfer which failed.
+//
 // It would be nicer to share this repository between ./pkg/transfers/ and
 // ./pkg/validation/microdeposits/, but there are cyclic dependencies if it's put into either
 // package.
 func (r *sqlRepo) MarkTransfersAsProcessed(transferIDs []string) error {
+	now := time.Now()
+
+	var el base.ErrorList
+	for i := range transferIDs {
+		if err := r.markTransferAsProcessed(transferIDs[i], now); err != nil {
+			transfersUnprocessed.Add(1)
+			el.Add(fmt.Errorf("transferID=%s: %v", transferIDs[i], err))
+
+			if err := r.saveUnprocessedTransfer(transferIDs[i], err, now); err != nil {
+				el.Add(fmt.Errorf("transferID=%s: saving as unprocessed: %v", transferIDs[i], err))
+			}
+		}
+	}
+	if el.Empty() {
+		return nil
+	}
+	return el
+}
+
+func (r *sqlRepo) markTransferAsProcessed(transferID string, now time.Time) error {
 	tx, err := r.db.Begin()
 	if err != nil {
 		return err
 	}
 
-	now := time.Now()
-
-	transferQuery := `update transfers set status = ?, processed_at = ? where transfer_id = ? and deleted_at is null`
-	transferStmt, err := tx.Prepare(transferQuery)
+	existing, err := history.CurrentStatus(tx, transferID)
 	if err != nil {
 		tx.Rollback()
 		return err
 	}
-	defer transferStmt.Close()
-
-	microQuery := `update micro_deposits set status = ?, processed_at = ? where micro_deposit_id = (
-  select micro_deposit_id from micro_deposit_transfers where transfer_id = ?);`
-	microStmt, err := tx.Prepare(microQuery)
-	if err != nil {
+	// Legs of pass-through and two-leg transfers are uploaded in this file, but held legs
+	// are uploaded later and marked then.
+	legQuery := `update transfer_legs set status = ?, updated_at = ? where transfer_id = ? and status = ?;`
+	if _, err := tx.Exec(legQuery, client.TRANSFERLEGSTATUS_PROCESSED, now, transferID, client.TRANSFERLEGSTATUS_PENDING); err != nil {
 		tx.Rollback()
 		return err
 	}
-	defer microStmt.Close()
 
-	for i := range transferIDs {
-		row, err := transferStmt.Exec(client.PROCESSED, now, transferIDs[i])
+	if existing != string(client.PROCESSED) {
+		transferQuery := `update transfers set status = ?, processed_at = ? where transfer_id = ? and deleted_at is null`
+		transferStmt, err := tx.Prepare(transferQuery)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+		defer transferStmt.Close()
+
+		row, err := transferStmt.Exec(client.PROCESSED, now, transferID)
 		if err != nil && err != sql.ErrNoRows {
 			tx.Rollback()
 			return err
 		}
 		if n, _ := row.RowsAffected(); n == 0 {
 			tx.Rollback()
-			return fmt.Errorf("transferID=%s not found / updated: %v", transferIDs[i], err)
+			return fmt.Errorf("transferID=%s not found / updated: %v", transferID, err)
+		}
+		change := history.Change{Field: "status", OldValue: existing, NewValue: string(client.PROCESSED)}
+		if err := history.Record(tx, transferID, history.Pipeline, change); err != nil {
+			tx.Rollback()
+			return err
 		}
 
 		// not every transfer is used in micro-deposits so we can ignore a zero row update
-		_, err = microStmt.Exec(client.PROCESSED, now, transferIDs[i])
+		microQuery := `update micro_deposits set status = ?, processed_at = ? where micro_deposit_id = (
+  select micro_deposit_id from micro_deposit_transfers where transfer_id = ?);`
+		microStmt, err := tx.Prepare(microQuery)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+		defer microStmt.Close()
+
+		_, err = microStmt.Exec(client.PROCESSED, now, transferID)
 		if err != nil && err != sql.ErrNoRows {
 			tx.Rollback()
 			return err
 		}
 	}
 
+	// clear out any previous failure
+	if _, err := tx.Exec(`delete from unprocessed_transfers where transfer_id = ?;`, transferID); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *sqlRepo) getTransferStatuses(transferIDs []string) (map[string]transferStatus, error) {
+	query := `select organization, status from transfers where transfer_id = ? and deleted_at is null limit 1;`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	out := make(map[string]transferStatus)
+	for i := range transferIDs {
+		var status transferStatus
+		if err := stmt.QueryRow(transferIDs[i]).Scan(&status.organization, &status.status); err != nil {
+			if err == sql.ErrNoRows {
+				continue
+			}
+			return nil, err
+		}
+		out[transferIDs[i]] = status
+	}
+	return out, nil
+}
+
+func (r *sqlRepo) getTraceTransferIDs(traceNumbers []string) ([]string, error) {
+	query := `select transfer_id from transfer_trace_numbers where trace_number = ?;`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	var out []string
+	seen := make(map[string]bool)
+	for i := range traceNumbers {
+		rows, err := stmt.Query(traceNumbers[i])
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var transferID string
+			if err := rows.Scan(&transferID); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			if !seen[transferID] {
+				seen[transferID] = true
+				out = append(out, transferID)
+			}
+		}
+		if err := rows.Err(); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		rows.Close()
+	}
+	return out, nil
+}
+
+// maxUnprocessedErrorLength matches the column size in MySQL
+const maxUnprocessedErrorLength = 500
+
+func (r *sqlRepo) saveUnprocessedTransfer(transferID string, failure error, now time.Time) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	query := `select attempts, created_at from unprocessed_transfers where transfer_id = ? limit 1;`
+	stmt, err := tx.Prepare(query)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	attempts, created := 0, now
+	if err := stmt.QueryRow(transferID).Scan(&attempts, &created); err != nil && err != sql.ErrNoRows {
+		tx.Rollback()
+		return err
+	}
+
+	msg := failure.Error()
+	if len(msg) > maxUnprocessedErrorLength {
+		msg = msg[:maxUnprocessedErrorLength]
+	}
+
+	query = `replace into unprocessed_transfers (transfer_id, error, attempts, created_at, last_attempt_at) values (?, ?, ?, ?, ?);`
+	stmt, err = tx.Prepare(query)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	if _, err := stmt.Exec(transferID, msg, attempts+1, created, now); err != nil {
+		tx.Rollback()
+		return err
+	}
 	return tx.Commit()
 }
+
+func (r *sqlRepo) getUnprocessedTransfers() ([]*admin.UnprocessedTransfer, error) {
+	query := `select transfer_id, error, attempts, created_at, last_attempt_at from unprocessed_transfers order by created_at asc;`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.Query()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []*admin.UnprocessedTransfer
+	for rows.Next() {
+		var xfer admin.UnprocessedTransfer
+		var msg *string
+		if err := rows.Scan(&xfer.TransferID, &msg, &xfer.Attempts, &xfer.Created, &xfer.LastAttempt); err != nil {
+			return nil, err
+		}
+		if msg != nil {
+			xfer.Error = *msg
+		}
+		out = append(out, &xfer)
+	}
+	return out, rows.Err()
+}
+
+func (r *sqlRepo) deleteUnprocessedTransfer(transferID string) error {
+	query := `delete from unprocessed_transfers where transfer_id = ?;`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(transferID)
+	return err
+}
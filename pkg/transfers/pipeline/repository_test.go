@@ -7,10 +7,13 @@ package pipeline
 import (
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/moov-io/base"
 	"github.com/moov-io/paygate/pkg/client"
 	"github.com/moov-io/paygate/pkg/database"
+
+	"github.com/stretchr/testify/require"
 )
 
 func TestRepository__MarkMicroDepositsAsProcessed(t *testing.T) {
@@ -78,6 +81,173 @@ func TestRepository__MarkTransfersProcessed(t *testing.T) {
 	check(t, setupMySQLeDB(t))
 }
 
+func TestRepository__MarkTransferLegsProcessed(t *testing.T) {
+	t.Parallel()
+
+	check := func(t *testing.T, repo *sqlRepo) {
+		transferID := base.ID()
+		writeTransfer(t, repo, transferID)
+
+		query := `insert into transfer_legs (transfer_id, leg, status, updated_at) values (?, ?, ?, ?);`
+		if _, err := repo.db.Exec(query, transferID, "debit", client.TRANSFERLEGSTATUS_PENDING, time.Now()); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := repo.db.Exec(query, transferID, "credit", client.TRANSFERLEGSTATUS_HELD, time.Now()); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := repo.MarkTransfersAsProcessed([]string{transferID}); err != nil {
+			t.Fatal(err)
+		}
+
+		statuses := make(map[string]string)
+		rows, err := repo.db.Query(`select leg, status from transfer_legs where transfer_id = ?;`, transferID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var leg, status string
+			if err := rows.Scan(&leg, &status); err != nil {
+				t.Fatal(err)
+			}
+			statuses[leg] = status
+		}
+		if statuses["debit"] != string(client.TRANSFERLEGSTATUS_PROCESSED) || statuses["credit"] != string(client.TRANSFERLEGSTATUS_HELD) {
+			t.Errorf("unexpected legs: %#v", statuses)
+		}
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+}
+
+func TestRepository__MarkTransfersProcessedPartialFailure(t *testing.T) {
+	t.Parallel()
+
+	check := func(t *testing.T, repo *sqlRepo) {
+		transferID, missingID := base.ID(), base.ID()
+		writeTransfer(t, repo, transferID)
+
+		// one transfer is marked even though the other fails
+		err := repo.MarkTransfersAsProcessed([]string{transferID, missingID})
+		if err == nil || !strings.Contains(err.Error(), missingID) {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if xfer := getPartialTransferModel(t, repo, transferID); xfer.Status != client.PROCESSED {
+			t.Errorf("unexpected transfer status: %s", xfer.Status)
+		}
+
+		unprocessed, err := repo.getUnprocessedTransfers()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(unprocessed) != 1 || unprocessed[0].TransferID != missingID || unprocessed[0].Attempts != 1 {
+			t.Fatalf("unexpected unprocessed transfers: %#v", unprocessed)
+		}
+		if !strings.Contains(unprocessed[0].Error, "not found / updated") {
+			t.Errorf("unexpected error: %v", unprocessed[0].Error)
+		}
+
+		// marking again is safe and counts another attempt
+		if err := repo.MarkTransfersAsProcessed([]string{transferID, missingID}); err == nil {
+			t.Error("expected error")
+		}
+		unprocessed, _ = repo.getUnprocessedTransfers()
+		if len(unprocessed) != 1 || unprocessed[0].Attempts != 2 {
+			t.Fatalf("unexpected unprocessed transfers: %#v", unprocessed)
+		}
+
+		// once the transfer exists a retry succeeds and clears it
+		writeTransfer(t, repo, missingID)
+		if err := repo.MarkTransfersAsProcessed([]string{missingID}); err != nil {
+			t.Fatal(err)
+		}
+		unprocessed, _ = repo.getUnprocessedTransfers()
+		if len(unprocessed) != 0 {
+			t.Errorf("unexpected unprocessed transfers: %#v", unprocessed)
+		}
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+}
+
+func TestRepository__getTransferStatuses(t *testing.T) {
+	t.Parallel()
+
+	check := func(t *testing.T, repo *sqlRepo) {
+		transferID, missingID := base.ID(), base.ID()
+		writeTransfer(t, repo, transferID)
+
+		_, err := repo.db.Exec(`update transfers set organization = ? where transfer_id = ?;`, "moov", transferID)
+		require.NoError(t, err)
+
+		statuses, err := repo.getTransferStatuses([]string{transferID, missingID})
+		require.NoError(t, err)
+		require.Equal(t, map[string]transferStatus{
+			transferID: {organization: "moov", status: client.PENDING},
+		}, statuses)
+
+		require.NoError(t, repo.MarkTransfersAsProcessed([]string{transferID}))
+
+		statuses, err = repo.getTransferStatuses([]string{transferID})
+		require.NoError(t, err)
+		require.Equal(t, client.PROCESSED, statuses[transferID].status)
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+}
+
+func TestRepository__getTraceTransferIDs(t *testing.T) {
+	t.Parallel()
+
+	check := func(t *testing.T, repo *sqlRepo) {
+		transferID := base.ID()
+		query := `insert into transfer_trace_numbers (transfer_id, trace_number) values (?, ?);`
+		for _, traceNumber := range []string{"121042880000001", "121042880000002"} {
+			_, err := repo.db.Exec(query, transferID, traceNumber)
+			require.NoError(t, err)
+		}
+
+		transferIDs, err := repo.getTraceTransferIDs([]string{"121042880000001", "121042880000002", "121042880000003"})
+		require.NoError(t, err)
+		require.Equal(t, []string{transferID}, transferIDs)
+
+		transferIDs, err = repo.getTraceTransferIDs([]string{"121042880000003"})
+		require.NoError(t, err)
+		require.Empty(t, transferIDs)
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+}
+
+func TestRepository__deleteUnprocessedTransfer(t *testing.T) {
+	t.Parallel()
+
+	check := func(t *testing.T, repo *sqlRepo) {
+		transferID := base.ID()
+		if err := repo.MarkTransfersAsProcessed([]string{transferID}); err == nil {
+			t.Fatal("expected error")
+		}
+		if err := repo.deleteUnprocessedTransfer(transferID); err != nil {
+			t.Fatal(err)
+		}
+		unprocessed, err := repo.getUnprocessedTransfers()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(unprocessed) != 0 {
+			t.Errorf("unexpected unprocessed transfers: %#v", unprocessed)
+		}
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+}
+
 func setupSQLiteDB(t *testing.T) *sqlRepo {
 	db := database.CreateTestSqliteDB(t)
 	t.Cleanup(func() { db.Close() })
@@ -0,0 +1,296 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package pipeline
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	baseadmin "github.com/moov-io/base/admin"
+	moovhttp "github.com/moov-io/base/http"
+	"github.com/moov-io/base/log"
+
+	"github.com/moov-io/paygate/pkg/admin"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/x/adminauth"
+
+	"gocloud.dev/blob"
+	_ "gocloud.dev/blob/azureblob"
+	_ "gocloud.dev/blob/fileblob"
+	_ "gocloud.dev/blob/gcsblob"
+	_ "gocloud.dev/blob/memblob"
+	_ "gocloud.dev/blob/s3blob"
+)
+
+const (
+	retainedMerged  = "merged"
+	retainedInbound = "inbound"
+
+	retentionArchived = "archived"
+	retentionDeleted  = "deleted"
+)
+
+// Retention removes old directories of files from disk. Merged directories are isolated at each
+// cutoff and hold the merged Transfers along with the files uploaded to the ODFI. Inbound
+// directories hold files downloaded from the ODFI which are kept when local storage isn't
+// cleaned up. Each directory is written to a bucket as a compressed tarball before it's
+// removed, or just deleted when there's no bucket.
+//
+// A nil *Retention is valid and does nothing.
+type Retention struct {
+	cfg    *config.Retention
+	logger log.Logger
+
+	mergingDir string
+	inboundDir string
+
+	// bucket is nil when old directories are deleted rather than archived
+	bucket *blob.Bucket
+
+	mu sync.Mutex
+}
+
+func NewRetention(cfg *config.Config) (*Retention, error) {
+	if cfg.Pipeline.Retention == nil {
+		return nil, nil
+	}
+	r := &Retention{
+		cfg:        cfg.Pipeline.Retention,
+		logger:     cfg.Logger.Set("service", "Retention"),
+		mergingDir: mergingDirectory(cfg.Pipeline),
+	}
+	if cfg.ODFI.Storage != nil && cfg.ODFI.Storage.Local != nil {
+		r.inboundDir = cfg.ODFI.Storage.Local.Directory
+	}
+	if cfg.Pipeline.Retention.BucketURI != "" {
+		bucket, err := blob.OpenBucket(context.Background(), cfg.Pipeline.Retention.BucketURI)
+		if err != nil {
+			return nil, fmt.Errorf("retention: %v", err)
+		}
+		r.bucket = bucket
+	}
+	return r, nil
+}
+
+func (r *Retention) Start(ctx context.Context) {
+	if r == nil {
+		return
+	}
+	ticker := time.NewTicker(r.cfg.CheckInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if _, err := r.run(time.Now()); err != nil {
+				r.logger.LogErrorf("ERROR archiving old files: %v", err)
+			}
+
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (r *Retention) Close() error {
+	if r == nil || r.bucket == nil {
+		return nil
+	}
+	return r.bucket.Close()
+}
+
+// RegisterRoutes adds an admin endpoint to archive old directories right away.
+func (r *Retention) RegisterRoutes(cfg *config.Config, svc *baseadmin.Server) {
+	if r == nil {
+		return
+	}
+	svc.AddHandler("/pipeline/archive", adminauth.Protect(cfg.Admin.Signing, r.triggerArchival()))
+}
+
+func (r *Retention) triggerArchival() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPut {
+			moovhttp.Problem(w, fmt.Errorf("invalid method %s", req.Method))
+			return
+		}
+		dirs, err := r.run(time.Now())
+		if err != nil {
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			moovhttp.Problem(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(dirs)
+	}
+}
+
+type retainedDir struct {
+	kind string
+	path string
+}
+
+// run archives or deletes each directory older than the max age and updates the disk usage metrics.
+func (r *Retention) run(now time.Time) ([]admin.ArchivedDirectory, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	dirs, err := r.expired(now)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]admin.ArchivedDirectory, 0, len(dirs))
+	for i := range dirs {
+		dir := admin.ArchivedDirectory{
+			Kind:   dirs[i].kind,
+			Name:   filepath.Base(dirs[i].path),
+			Bytes:  dirSize(dirs[i].path),
+			Action: retentionDeleted,
+		}
+		if r.bucket != nil {
+			dir.Action = retentionArchived
+			dir.Archive = fmt.Sprintf("%s/%s.tar.gz", dir.Kind, dir.Name)
+			if err := r.archive(dir.Archive, dirs[i].path); err != nil {
+				return out, fmt.Errorf("archiving %s: %v", dirs[i].path, err)
+			}
+		}
+		if err := os.RemoveAll(dirs[i].path); err != nil {
+			return out, fmt.Errorf("removing %s: %v", dirs[i].path, err)
+		}
+		retentionDirectories.With("kind", dir.Kind, "action", dir.Action).Add(1)
+
+		r.logger.With(log.Fields{
+			"kind":    dir.Kind,
+			"name":    dir.Name,
+			"archive": dir.Archive,
+		}).Logf("%s directory", dir.Action)
+
+		out = append(out, dir)
+	}
+
+	retentionDiskUsage.With("kind", retainedMerged).Set(float64(dirSize(r.mergingDir)))
+	if r.inboundDir != "" {
+		retentionDiskUsage.With("kind", retainedInbound).Set(float64(dirSize(r.inboundDir)))
+	}
+	return out, nil
+}
+
+// expired returns the directories which are older than the max age. Merged directories are
+// aged from their name and inbound directories from when they were last modified.
+func (r *Retention) expired(now time.Time) ([]retainedDir, error) {
+	var out []retainedDir
+
+	infos, err := ioutil.ReadDir(r.mergingDir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for i := range infos {
+		if !infos[i].IsDir() {
+			continue
+		}
+		isolated, err := time.ParseInLocation(isolatedDirFormat, infos[i].Name(), time.Local)
+		if err != nil {
+			continue // the mergable directory or one we didn't create
+		}
+		if now.Sub(isolated) > r.cfg.MaxAge {
+			out = append(out, retainedDir{kind: retainedMerged, path: filepath.Join(r.mergingDir, infos[i].Name())})
+		}
+	}
+
+	if r.inboundDir == "" {
+		return out, nil
+	}
+	infos, err = ioutil.ReadDir(r.inboundDir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for i := range infos {
+		if !infos[i].IsDir() || !strings.HasPrefix(infos[i].Name(), "download") {
+			continue
+		}
+		if now.Sub(infos[i].ModTime()) > r.cfg.MaxAge {
+			out = append(out, retainedDir{kind: retainedInbound, path: filepath.Join(r.inboundDir, infos[i].Name())})
+		}
+	}
+	return out, nil
+}
+
+// archive writes every file under dir into a gzipped tarball at key.
+func (r *Retention) archive(key string, dir string) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	w, err := r.bucket.NewWriter(ctx, key, nil)
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	parent := filepath.Dir(dir)
+	err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		name, err := filepath.Rel(parent, path)
+		if err != nil {
+			return err
+		}
+		hdr, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		hdr.Name = filepath.ToSlash(name)
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		fd, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer fd.Close()
+		_, err = io.Copy(tw, fd)
+		return err
+	})
+	if err == nil {
+		err = tw.Close()
+	}
+	if err == nil {
+		err = gz.Close()
+	}
+	if err != nil {
+		cancel() // discard the partial archive
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+// dirSize returns the bytes used by files under dir, or zero if it doesn't exist.
+func dirSize(dir string) int64 {
+	var size int64
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && info.Mode().IsRegular() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
@@ -0,0 +1,175 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package pipeline
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/moov-io/paygate/pkg/admin"
+	"github.com/moov-io/paygate/pkg/config"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testingRetention(t *testing.T, bucketURI string) (*Retention, string, string) {
+	t.Helper()
+
+	mergingDir, inboundDir := t.TempDir(), t.TempDir()
+
+	cfg := config.Empty()
+	cfg.Pipeline.Merging = &config.Merging{Directory: mergingDir}
+	cfg.Pipeline.Retention = &config.Retention{
+		MaxAge:    7 * 24 * time.Hour,
+		BucketURI: bucketURI,
+	}
+	cfg.ODFI.Storage = &config.Storage{
+		Local: &config.Local{Directory: inboundDir},
+	}
+	retention, err := NewRetention(cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() { retention.Close() })
+	return retention, mergingDir, inboundDir
+}
+
+func writeRetainedFile(t *testing.T, path string) {
+	t.Helper()
+
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0777))
+	require.NoError(t, ioutil.WriteFile(path, []byte("101 ..."), 0600))
+}
+
+func TestRetention__nil(t *testing.T) {
+	retention, err := NewRetention(config.Empty())
+	require.NoError(t, err)
+	require.Nil(t, retention)
+
+	retention.Start(context.Background())
+	require.NoError(t, retention.Close())
+}
+
+func TestRetention__archive(t *testing.T) {
+	retention, mergingDir, inboundDir := testingRetention(t, "mem://")
+	now := time.Now()
+
+	old := now.Add(-10 * 24 * time.Hour).Format(isolatedDirFormat)
+	recent := now.Add(-time.Hour).Format(isolatedDirFormat)
+
+	writeRetainedFile(t, filepath.Join(mergingDir, "mergable", "pending.ach"))
+	writeRetainedFile(t, filepath.Join(mergingDir, old, "transfer.ach"))
+	writeRetainedFile(t, filepath.Join(mergingDir, old, "uploaded", "merged.ach"))
+	writeRetainedFile(t, filepath.Join(mergingDir, recent, "transfer.ach"))
+
+	writeRetainedFile(t, filepath.Join(inboundDir, "download123", "inbound", "ret.ach"))
+	writeRetainedFile(t, filepath.Join(inboundDir, "download456", "inbound", "cor.ach"))
+	when := now.Add(-8 * 24 * time.Hour)
+	require.NoError(t, os.Chtimes(filepath.Join(inboundDir, "download123"), when, when))
+
+	dirs, err := retention.run(now)
+	require.NoError(t, err)
+	require.Len(t, dirs, 2)
+
+	require.Equal(t, admin.ArchivedDirectory{
+		Kind:    "merged",
+		Name:    old,
+		Bytes:   14,
+		Action:  "archived",
+		Archive: "merged/" + old + ".tar.gz",
+	}, dirs[0])
+	require.Equal(t, "inbound", dirs[1].Kind)
+	require.Equal(t, "inbound/download123.tar.gz", dirs[1].Archive)
+
+	// the archive holds each file under the directory's name
+	r, err := retention.bucket.NewReader(context.Background(), dirs[0].Archive, nil)
+	require.NoError(t, err)
+	defer r.Close()
+	gz, err := gzip.NewReader(r)
+	require.NoError(t, err)
+
+	var names []string
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		if hdr.Typeflag == tar.TypeReg {
+			names = append(names, hdr.Name)
+		}
+	}
+	sort.Strings(names)
+	require.Equal(t, []string{old + "/transfer.ach", old + "/uploaded/merged.ach"}, names)
+
+	// expired directories are removed and the others are kept
+	for _, path := range []string{
+		filepath.Join(mergingDir, old),
+		filepath.Join(inboundDir, "download123"),
+	} {
+		_, err := os.Stat(path)
+		require.True(t, os.IsNotExist(err), path)
+	}
+	for _, path := range []string{
+		filepath.Join(mergingDir, "mergable", "pending.ach"),
+		filepath.Join(mergingDir, recent, "transfer.ach"),
+		filepath.Join(inboundDir, "download456", "inbound", "cor.ach"),
+	} {
+		_, err := os.Stat(path)
+		require.NoError(t, err)
+	}
+
+	// nothing is left to archive
+	dirs, err = retention.run(now)
+	require.NoError(t, err)
+	require.Len(t, dirs, 0)
+}
+
+func TestRetention__delete(t *testing.T) {
+	retention, mergingDir, _ := testingRetention(t, "")
+	now := time.Now()
+
+	old := now.Add(-10 * 24 * time.Hour).Format(isolatedDirFormat)
+	writeRetainedFile(t, filepath.Join(mergingDir, old, "uploaded", "merged.ach"))
+
+	dirs, err := retention.run(now)
+	require.NoError(t, err)
+	require.Len(t, dirs, 1)
+	require.Equal(t, "deleted", dirs[0].Action)
+	require.Empty(t, dirs[0].Archive)
+
+	_, err = os.Stat(filepath.Join(mergingDir, old))
+	require.True(t, os.IsNotExist(err))
+}
+
+func TestRetention__triggerArchival(t *testing.T) {
+	retention, mergingDir, _ := testingRetention(t, "mem://")
+
+	old := time.Now().Add(-10 * 24 * time.Hour).Format(isolatedDirFormat)
+	writeRetainedFile(t, filepath.Join(mergingDir, old, "transfer.ach"))
+
+	w := httptest.NewRecorder()
+	retention.triggerArchival()(w, httptest.NewRequest("GET", "/pipeline/archive", nil))
+	require.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = httptest.NewRecorder()
+	retention.triggerArchival()(w, httptest.NewRequest("PUT", "/pipeline/archive", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var dirs []admin.ArchivedDirectory
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&dirs))
+	require.Len(t, dirs, 1)
+	require.Equal(t, old, dirs[0].Name)
+}
@@ -0,0 +1,209 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package pipeline
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/moov-io/ach"
+
+	"github.com/moov-io/paygate/pkg/achx"
+	"github.com/moov-io/paygate/pkg/admin"
+)
+
+// retryUploads uploads the failed files with the filename or sent to the routing number again.
+// The Transfers of each file which is uploaded are marked as processed, as the cutoff which
+// merged them didn't once the file failed.
+func (xfagg *XferAggregator) retryUploads(filename, routingNumber string) []admin.UploadRetry {
+	failed := xfagg.takeFailedUploads(filename, routingNumber)
+
+	out := make([]admin.UploadRetry, 0, len(failed))
+	for i := range failed {
+		result := admin.UploadRetry{
+			FileID:        failed[i].FileID,
+			Filename:      failed[i].Filename,
+			RoutingNumber: failed[i].file.Header.ImmediateDestination,
+			RemoteServer:  xfagg.agent.Hostname(),
+			RemotePath:    xfagg.agent.OutboundPath(),
+		}
+		xfagg.logger.Set("fileID", result.FileID).Logf("retrying upload of %s", result.Filename)
+
+		if err := xfagg.upload(failed[i].FileID, failed[i].Filename, failed[i].contents, failed[i].file, failed[i].correlationIDs); err != nil {
+			result.Error = err.Error()
+		} else {
+			result.Uploaded = true
+			xfagg.removeFailedUpload(failed[i].FileID)
+			xfagg.markRetriedTransfers(failed[i].file)
+		}
+		out = append(out, result)
+	}
+	return out
+}
+
+// markRetriedTransfers marks the Transfers with entries in an uploaded file as processed. The
+// file is already uploaded, so failures are logged and saved like any other unprocessed Transfer.
+func (xfagg *XferAggregator) markRetriedTransfers(file *ach.File) {
+	var traceNumbers []string
+	for _, entry := range achx.Entries(file) {
+		traceNumbers = append(traceNumbers, entry.TraceNumber)
+	}
+	transferIDs, err := xfagg.repo.getTraceTransferIDs(traceNumbers)
+	if err != nil {
+		xfagg.errors.Add("bookkeeping", err)
+		xfagg.logger.LogErrorf("ERROR reading transfers of retried upload: %v", err)
+		return
+	}
+	xfagg.markTransfersAsProcessed(&processedTransfers{transferIDs: transferIDs})
+}
+
+// loadFailedUploads rebuilds the failed uploads saved by the merger, so files which failed
+// before a restart can still be retried. Saved files which can't be read are skipped.
+func (xfagg *XferAggregator) loadFailedUploads() error {
+	if xfagg.merger == nil {
+		return nil
+	}
+	failed, err := xfagg.merger.failedUploads()
+	if err != nil {
+		return err
+	}
+
+	xfagg.uploadsMu.Lock()
+	defer xfagg.uploadsMu.Unlock()
+
+	xfagg.failedUploads = failed
+	return nil
+}
+
+// removeFailedUpload deletes the saved copy of a file which has been uploaded. The file is
+// already uploaded, so failures are only logged.
+func (xfagg *XferAggregator) removeFailedUpload(fileID string) {
+	if xfagg.merger == nil {
+		return
+	}
+	if err := xfagg.merger.removeFailedUpload(fileID); err != nil {
+		xfagg.errors.Add("upload", err)
+		xfagg.logger.Set("fileID", fileID).LogErrorf("ERROR removing failed upload: %v", err)
+	}
+}
+
+// retryDirectory holds the files which failed to upload. It's next to the mergable
+// directory so it isn't isolated at cutoffs or removed by retention.
+func (m *filesystemMerging) retryDirectory() string {
+	return filepath.Join(filepath.Dir(m.baseDir), "retry")
+}
+
+// failedUploadPath is where a failed upload is saved. Files are saved by their ID, as several
+// files can be uploaded with the same filename.
+func (m *filesystemMerging) failedUploadPath(fileID string) string {
+	return filepath.Join(m.retryDirectory(), filepath.Base(fileID)+".json")
+}
+
+// savedUpload is how a failed upload is written into the retry directory.
+type savedUpload struct {
+	FileID         string    `json:"fileID"`
+	Filename       string    `json:"filename"`
+	Error          string    `json:"error"`
+	Created        time.Time `json:"created"`
+	CorrelationIDs []string  `json:"correlationIDs"`
+
+	// Contents are the exact bytes to upload and File is the merged file in the Nacha format.
+	Contents []byte `json:"contents"`
+	File     []byte `json:"file"`
+}
+
+func (m *filesystemMerging) saveFailedUpload(failed failedUpload) error {
+	var buf bytes.Buffer
+	if err := ach.NewWriter(&buf).Write(failed.file); err != nil {
+		return fmt.Errorf("unable to buffer ACH file: %v", err)
+	}
+	bs, err := json.Marshal(savedUpload{
+		FileID:         failed.FileID,
+		Filename:       failed.Filename,
+		Error:          failed.Error,
+		Created:        failed.Created,
+		CorrelationIDs: failed.correlationIDs,
+		Contents:       failed.contents,
+		File:           buf.Bytes(),
+	})
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(m.retryDirectory(), 0777); err != nil {
+		return err
+	}
+	return m.writeData(m.failedUploadPath(failed.FileID), bs)
+}
+
+func (m *filesystemMerging) removeFailedUpload(fileID string) error {
+	if err := os.Remove(m.failedUploadPath(fileID)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// quarantineDirectory holds saved failed uploads which can't be read, so they're kept for
+// an operator to inspect without stopping the rest from being retried.
+func (m *filesystemMerging) quarantineDirectory() string {
+	return filepath.Join(m.retryDirectory(), "quarantine")
+}
+
+// failedUploads reads every saved failed upload. Files which can't be read, decrypted or
+// parsed are logged and moved into the quarantine directory.
+func (m *filesystemMerging) failedUploads() ([]failedUpload, error) {
+	matches, err := filepath.Glob(filepath.Join(m.retryDirectory(), "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	var out []failedUpload
+	for i := range matches {
+		failed, err := m.readFailedUpload(matches[i])
+		if err != nil {
+			m.logger.Set("path", matches[i]).LogErrorf("ERROR skipping failed upload: %v", err)
+			m.quarantineFailedUpload(matches[i])
+			continue
+		}
+		out = append(out, failed)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Created.Before(out[j].Created)
+	})
+	return out, nil
+}
+
+func (m *filesystemMerging) readFailedUpload(path string) (failedUpload, error) {
+	bs, err := m.readData(path)
+	if err != nil {
+		return failedUpload{}, err
+	}
+	var saved savedUpload
+	if err := json.Unmarshal(bs, &saved); err != nil {
+		return failedUpload{}, fmt.Errorf("problem reading %s: %v", filepath.Base(path), err)
+	}
+	file, err := ach.NewReader(bytes.NewReader(saved.File)).Read()
+	if err != nil {
+		return failedUpload{}, fmt.Errorf("problem reading %s: %v", filepath.Base(path), err)
+	}
+	return newFailedUpload(saved.FileID, saved.Filename, saved.Contents, &file, saved.CorrelationIDs, saved.Error, saved.Created), nil
+}
+
+// quarantineFailedUpload moves a saved failed upload which can't be read out of the retry
+// directory. Files which can't be moved are left in place and skipped again next time.
+func (m *filesystemMerging) quarantineFailedUpload(path string) {
+	dir := m.quarantineDirectory()
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		m.logger.Set("path", path).LogErrorf("ERROR quarantining failed upload: %v", err)
+		return
+	}
+	if err := os.Rename(path, filepath.Join(dir, filepath.Base(path))); err != nil {
+		m.logger.Set("path", path).LogErrorf("ERROR quarantining failed upload: %v", err)
+	}
+}
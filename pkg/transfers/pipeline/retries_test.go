@@ -0,0 +1,194 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package pipeline
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/moov-io/ach"
+	"github.com/moov-io/base/log"
+
+	"github.com/moov-io/paygate/internal"
+	"github.com/moov-io/paygate/pkg/transfers/pipeline/audittrail"
+	"github.com/moov-io/paygate/pkg/transfers/pipeline/notify"
+	"github.com/moov-io/paygate/pkg/upload"
+	"github.com/moov-io/paygate/x/errorlog"
+	"github.com/moov-io/paygate/x/schedule"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newRetryAggregator(agent *upload.MockAgent, repo *MockRepository, merger XferMerging) *XferAggregator {
+	return &XferAggregator{
+		logger:       log.NewNopLogger(),
+		clock:        schedule.System,
+		agent:        agent,
+		notifier:     &notify.MockSender{},
+		repo:         repo,
+		merger:       merger,
+		auditStorage: &audittrail.MockStorage{},
+		errors:       errorlog.New(maxRecentErrors),
+	}
+}
+
+func setupRetryAggregator(t *testing.T, agent *upload.MockAgent, repo *MockRepository, merger XferMerging) *XferAggregator {
+	t.Helper()
+
+	file, err := ach.ReadFile(filepath.Join("..", "..", "..", "testdata", "ppd-debit.ach"))
+	require.NoError(t, err)
+
+	xfagg := newRetryAggregator(agent, repo, merger)
+
+	// fail the first upload
+	agent.Err = errors.New("connection refused")
+	require.Error(t, xfagg.upload("file-id", "20200601-076401251.ach", []byte("contents"), file, []string{"correlation"}))
+	require.Len(t, xfagg.FailedUploads(), 1)
+
+	return xfagg
+}
+
+func TestRetries__retryUploads(t *testing.T) {
+	agent := &upload.MockAgent{}
+	repo := &MockRepository{
+		TraceNumbers: map[string]string{"076401255655291": "transfer-id"},
+	}
+	merger := &MockXferMerging{}
+	xfagg := setupRetryAggregator(t, agent, repo, merger)
+	require.Len(t, merger.Failed, 1)
+
+	// other files aren't retried
+	require.Empty(t, xfagg.retryUploads("other.ach", ""))
+	require.Empty(t, xfagg.retryUploads("", "987654320"))
+	require.Empty(t, xfagg.retryUploads("20200601-076401251.ach", "987654320"))
+
+	// the upload fails again
+	results := xfagg.retryUploads("", "076401251")
+	require.Len(t, results, 1)
+	require.Equal(t, "file-id", results[0].FileID)
+	require.False(t, results[0].Uploaded)
+	require.Equal(t, "connection refused", results[0].Error)
+	require.Equal(t, "hostname", results[0].RemoteServer)
+	require.Equal(t, "outbound/", results[0].RemotePath)
+	require.Len(t, xfagg.FailedUploads(), 1)
+	require.Len(t, merger.Failed, 1)
+	require.Empty(t, repo.Processed)
+
+	agent.Err = nil
+	results = xfagg.retryUploads("20200601-076401251.ach", "")
+	require.Len(t, results, 1)
+	require.True(t, results[0].Uploaded)
+	require.Empty(t, results[0].Error)
+	require.Empty(t, xfagg.FailedUploads())
+	require.Empty(t, merger.Failed)
+	require.Equal(t, []string{"transfer-id"}, repo.Processed)
+
+	require.Equal(t, "20200601-076401251.ach", agent.UploadedFile.Filename)
+	bs, err := ioutil.ReadAll(agent.UploadedFile.Contents)
+	require.NoError(t, err)
+	require.Equal(t, "contents", string(bs))
+
+	// uploaded files are no longer retried
+	require.Empty(t, xfagg.retryUploads("20200601-076401251.ach", ""))
+}
+
+func TestRetries__afterRestart(t *testing.T) {
+	dir := internal.TestDir(t)
+	merger := &filesystemMerging{
+		baseDir: filepath.Join(dir, "mergable"),
+		logger:  log.NewNopLogger(),
+	}
+	agent := &upload.MockAgent{}
+	repo := &MockRepository{
+		TraceNumbers: map[string]string{"076401255655291": "transfer-id"},
+	}
+	setupRetryAggregator(t, agent, repo, merger)
+
+	matches, err := filepath.Glob(filepath.Join(dir, "retry", "*.json"))
+	require.NoError(t, err)
+	require.Len(t, matches, 1)
+
+	// a new aggregator reads the failed uploads saved by the first
+	xfagg := newRetryAggregator(agent, repo, merger)
+	require.NoError(t, xfagg.loadFailedUploads())
+
+	failed := xfagg.FailedUploads()
+	require.Len(t, failed, 1)
+	require.Equal(t, "20200601-076401251.ach", failed[0].Filename)
+	require.Equal(t, "connection refused", failed[0].Error)
+	require.Equal(t, int32(1), failed[0].Entries)
+
+	agent.Err = nil
+	results := xfagg.retryUploads("", "076401251")
+	require.Len(t, results, 1)
+	require.True(t, results[0].Uploaded)
+	require.Equal(t, []string{"transfer-id"}, repo.Processed)
+
+	bs, err := ioutil.ReadAll(agent.UploadedFile.Contents)
+	require.NoError(t, err)
+	require.Equal(t, "contents", string(bs))
+
+	// uploaded files are removed from the retry directory
+	failed, err = merger.failedUploads()
+	require.NoError(t, err)
+	require.Empty(t, failed)
+}
+
+func TestRetries__corruptFile(t *testing.T) {
+	dir := internal.TestDir(t)
+	merger := &filesystemMerging{
+		baseDir: filepath.Join(dir, "mergable"),
+		logger:  log.NewNopLogger(),
+	}
+	agent := &upload.MockAgent{}
+	setupRetryAggregator(t, agent, &MockRepository{}, merger)
+
+	// one file isn't JSON and the other doesn't hold an ACH file
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "retry", "corrupt.json"), []byte("{not json"), 0600))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "retry", "invalid.json"), []byte(`{"fileID":"invalid","file":"YWJj"}`), 0600))
+
+	// the readable file is still loaded
+	xfagg := newRetryAggregator(agent, &MockRepository{}, merger)
+	require.NoError(t, xfagg.loadFailedUploads())
+
+	failed := xfagg.FailedUploads()
+	require.Len(t, failed, 1)
+	require.Equal(t, "file-id", failed[0].FileID)
+
+	// and the others are moved aside
+	matches, err := filepath.Glob(filepath.Join(dir, "retry", "*.json"))
+	require.NoError(t, err)
+	require.Len(t, matches, 1)
+
+	quarantined, err := filepath.Glob(filepath.Join(dir, "retry", "quarantine", "*.json"))
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{
+		filepath.Join(dir, "retry", "quarantine", "corrupt.json"),
+		filepath.Join(dir, "retry", "quarantine", "invalid.json"),
+	}, quarantined)
+}
+
+func TestRetries__sameFilename(t *testing.T) {
+	dir := internal.TestDir(t)
+	merger := &filesystemMerging{
+		baseDir: filepath.Join(dir, "mergable"),
+		logger:  log.NewNopLogger(),
+	}
+	agent := &upload.MockAgent{}
+	xfagg := setupRetryAggregator(t, agent, &MockRepository{}, merger)
+
+	file, err := ach.ReadFile(filepath.Join("..", "..", "..", "testdata", "ppd-debit.ach"))
+	require.NoError(t, err)
+	require.Error(t, xfagg.upload("other-id", "20200601-076401251.ach", []byte("other contents"), file, nil))
+
+	// both files are kept for retries
+	require.Len(t, xfagg.FailedUploads(), 2)
+	failed, err := merger.failedUploads()
+	require.NoError(t, err)
+	require.Len(t, failed, 2)
+	require.ElementsMatch(t, []string{"file-id", "other-id"}, []string{failed[0].FileID, failed[1].FileID})
+}
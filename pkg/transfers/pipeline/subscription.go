@@ -28,6 +28,9 @@ func createStreamSubscription(cfg *config.StreamPipeline) (*pubsub.Subscription,
 	if cfg.InMem != nil {
 		return createInmemSubscription(cfg.InMem.URL)
 	}
+	if cfg.Kafka != nil {
+		return createKafkaSubscription(cfg.Kafka)
+	}
 	return nil, fmt.Errorf("unknown %#v", cfg)
 }
 
@@ -11,9 +11,8 @@ import (
 	"fmt"
 	"strings"
 
-	"github.com/moov-io/ach"
-
 	"github.com/moov-io/paygate/internal/gpgx"
+	"github.com/moov-io/paygate/pkg/achx"
 	"github.com/moov-io/paygate/pkg/config"
 
 	"github.com/moov-io/base/log"
@@ -76,7 +75,7 @@ func fingerprint(key openpgp.EntityList) string {
 
 func (morph *GPGEncryption) Transform(res *Result) (*Result, error) {
 	var buf bytes.Buffer
-	if err := ach.NewWriter(&buf).Write(res.File); err != nil {
+	if err := achx.WriteFile(&buf, res.File, res.Layout); err != nil {
 		return res, err
 	}
 
@@ -15,6 +15,9 @@ import (
 type Result struct {
 	File      *ach.File
 	Encrypted []byte
+
+	// Layout is how File is written for its destination
+	Layout config.FileLayout
 }
 
 type PreUpload interface {
@@ -22,8 +25,8 @@ type PreUpload interface {
 }
 
 // ForUpload iterates each Transformer over an ACH file and mutates it along the way
-func ForUpload(file *ach.File, funcs []PreUpload) (*Result, error) {
-	res := &Result{File: file}
+func ForUpload(file *ach.File, layout config.FileLayout, funcs []PreUpload) (*Result, error) {
+	res := &Result{File: file, Layout: layout}
 
 	var err error
 	for i := range funcs {
@@ -0,0 +1,59 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package transfers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/moov-io/base/log"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/transfers/limiter"
+	"github.com/moov-io/paygate/x/route"
+)
+
+// quotaHeader is set on created Transfers with the debits and credits which can still be
+// originated for the organization and user, such as "debit=5000; credit=12000".
+const quotaHeader = "X-Quota-Remaining"
+
+// GetQuota returns the remaining capacity under every limit which applies to the organization
+// and user. Customer limits are included when the customerID query parameter is set.
+func GetQuota(cfg *config.Config, quotas *limiter.Quotas) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(cfg, w, r)
+
+		customerID := r.URL.Query().Get("customerID")
+		quota, err := quotas.Get(responder.OrganizationID, customerID, getUserID(r), time.Now())
+		if err != nil {
+			responder.Problem(err)
+			return
+		}
+
+		responder.Respond(func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(quota)
+		})
+	}
+}
+
+// quotaSnapshot returns the value of quotaHeader after a Transfer is created. Problems reading
+// the quota are logged rather than failing the already created Transfer.
+func quotaSnapshot(cfg *config.Config, quotas *limiter.Quotas, orgID, userID string, xfer *client.Transfer) string {
+	if quotas == nil {
+		return ""
+	}
+	quota, err := quotas.Get(orgID, "", userID, time.Now())
+	if err != nil {
+		cfg.Logger.With(log.Fields{
+			"organization": orgID,
+			"transferID":   xfer.TransferID,
+		}).LogErrorf("problem reading quota: %v", err)
+		return ""
+	}
+	return limiter.QuotaHeader(quota)
+}
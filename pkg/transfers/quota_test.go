@@ -0,0 +1,57 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package transfers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/transfers/files"
+	"github.com/moov-io/paygate/pkg/transfers/limiter"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRouter__GetQuota(t *testing.T) {
+	cfg := config.Empty()
+	cfg.ODFI.OriginationCaps = []config.OriginationCap{
+		{RoutingNumber: "987654320", Daily: 100000},
+	}
+	quotas := limiter.NewQuotas(cfg, nil, &files.MockRepository{Origination: 25000})
+
+	r := mux.NewRouter()
+	NewRouter(cfg, &MockRepository{}, nil, mockCustomersClient(), mockDecryptor, mockStrategy, fakePublisher, nil, nil, quotas, nil, nil).RegisterRoutes(r)
+
+	req := httptest.NewRequest("GET", "/quota", nil)
+	req.Header.Set("X-Organization", "moov")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var quota client.Quota
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&quota))
+	require.Equal(t, int64(75000), quota.Debits)
+	require.Equal(t, int64(75000), quota.Credits)
+	require.Len(t, quota.Limits, 1)
+	require.Equal(t, "odfi", quota.Limits[0].Scope)
+	require.Equal(t, int64(25000), quota.Limits[0].Used)
+
+	// without any limits nothing is limited
+	r = mux.NewRouter()
+	NewRouter(config.Empty(), &MockRepository{}, nil, mockCustomersClient(), mockDecryptor, mockStrategy, fakePublisher, nil, nil, nil, nil, nil).RegisterRoutes(r)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&quota))
+	require.Equal(t, int64(-1), quota.Debits)
+	require.Empty(t, quota.Limits)
+}
@@ -6,27 +6,103 @@ package transfers
 
 import (
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
 
 	"github.com/moov-io/ach"
 
+	"github.com/moov-io/paygate/pkg/admin"
 	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/transfers/fundflow"
+	"github.com/moov-io/paygate/pkg/transfers/history"
 )
 
 type Repository interface {
-	getTransfers(orgID string, params transferFilterParams) ([]*client.Transfer, error)
+	getTransfers(orgID string, params transferFilterParams) ([]*client.Transfer, []skippedRow, error)
+	streamTransfers(orgID string, params transferFilterParams, fn func(*client.Transfer) error) ([]skippedRow, error)
+	getRepresentment(orgID string, xfer *client.Transfer) (*client.Representment, error)
 	GetTransfer(id string) (*client.Transfer, error)
-	UpdateTransferStatus(transferID string, status client.TransferStatus) error
+	GetTransferOrganization(transferID string) (string, error)
+	UpdateTransferStatus(transferID string, status client.TransferStatus, actor history.Actor) error
 	WriteUserTransfer(orgID string, transfer *client.Transfer) error
-	deleteUserTransfer(orgID string, transferID string) error
+	deleteUserTransfer(orgID string, transferID string, cancel client.CancelTransfer) error
 
 	SaveReturnCode(transferID string, returnCode string) error
 	saveTraceNumbers(transferID string, traceNumbers []string) error
+	saveEntryTotals(transferID string, secCode string, debits, credits int64) error
 	getTraceNumbers(transferID string) ([]string, error)
+	saveTransferLegs(transferID string, legs []client.TransferLeg) error
+	getHeldCreditLegs() ([]heldLeg, error)
+	getHeldCreditLeg(transferID string) (*heldLeg, error)
+	updateTransferLeg(transferID string, leg string, from, to client.TransferLegStatus, actor history.Actor) error
+
+	getTransferHistory(orgID string, transferID string) ([]*client.TransferChange, error)
+
+	saveTransferTags(orgID string, transferID string, tags []string) error
+
+	getTransferViews(orgID string, userID string) ([]*client.TransferView, error)
+	getTransferView(orgID string, userID string, viewID string) (*client.TransferView, error)
+	createTransferView(orgID string, userID string, view *client.TransferView) error
+	deleteTransferView(orgID string, userID string, viewID string) error
+
+	getScheduledTransfers(orgID string) ([]*client.ScheduledTransfer, error)
+	getScheduledTransfer(orgID string, scheduleID string) (*client.ScheduledTransfer, error)
+	createScheduledTransfer(orgID string, userID string, schedule *client.ScheduledTransfer) error
+	updateScheduledTransferStatus(orgID string, scheduleID string, from client.ScheduleStatus, run scheduledRun) error
+	getDueScheduledTransfers(now time.Time) ([]dueSchedule, error)
+	claimScheduledRun(scheduleID string, runCount int, run scheduledRun) (bool, error)
+	recordScheduledRun(scheduleID string, transferID string, lastError string, when time.Time) error
 
 	LookupTransferFromReturn(amount client.Amount, traceNumber string, effectiveEntryDate time.Time) (*client.Transfer, error)
+	LookupTransferFromTraceNumber(traceNumber string) (*client.Transfer, error)
+	LookupReturnCandidates(routingNumber string, accountNumber string, start, end time.Time) ([]*ReturnCandidate, error)
+
+	SaveAmbiguousReturn(record *client.InboundRecord, candidates []admin.ReturnCandidate) error
+	ListAmbiguousReturns(status string) ([]*admin.AmbiguousReturn, error)
+	GetAmbiguousReturn(recordID string) (*admin.AmbiguousReturn, error)
+	ResolveAmbiguousReturn(recordID string, transferID string, when time.Time) error
+
+	SaveInboundRecord(record *client.InboundRecord) error
+	getInboundRecords(orgID string, transferID string) ([]*client.InboundRecord, error)
+	ListInboundRecords(filter InboundRecordFilter) ([]*admin.InboundRecord, error)
+
+	getTransferFiles(orgID string, transferID string) ([]*client.TransferFile, error)
+
+	saveReversal(transferID string, reversalID string) error
+	getReversal(transferID string) (reversalOf string, reversedBy string, err error)
+
+	writeTransferBatch(orgID string, batch []batchedTransfer) error
+	saveTransferBatch(orgID string, batchID string, idempotencyKey string, now time.Time) (bool, []byte, error)
+	saveTransferBatchResponse(batchID string, batch *client.TransferBatch) error
+	releaseTransferBatch(batchID string) error
+
+	claimIdempotencyKey(orgID, userID, key string, now time.Time) (bool, []byte, error)
+	saveIdempotentTransfer(orgID, userID, key string, transfer *client.Transfer) error
+	releaseIdempotencyKey(orgID, userID, key string) error
+	purgeIdempotencyKeys(before time.Time) (int64, error)
+
+	getDebitAuthorizations(orgID string, customerID string, accountID string) ([]*client.DebitAuthorization, error)
+	getDebitAuthorization(orgID string, authorizationID string) (*client.DebitAuthorization, error)
+	createDebitAuthorization(orgID string, auth *client.DebitAuthorization) error
+	revokeDebitAuthorization(orgID string, authorizationID string, reason string, when time.Time) ([]string, error)
+
+	SaveAccountCorrection(orgID string, correction *client.AccountCorrection) error
+	getAccountCorrections(orgID string, customerID string, accountID string) ([]*client.AccountCorrection, error)
+	getAccountCorrection(orgID string, correctionID string) (*client.AccountCorrection, error)
+	reviewAccountCorrection(orgID string, correctionID string, status client.AccountCorrectionStatus, userID string, when time.Time) error
+
+	customerSuspended(orgID string, customerID string) (bool, error)
+	customerDeceased(orgID string, customerID string) (bool, error)
+	emailVerified(orgID string, customerID string, email string) (bool, error)
+	sourceHolds(orgID string, customerID string, accountID string) (int64, error)
+
+	LookupEntryAccounts(routingNumber string, accountNumber string) ([]EntryAccount, error)
+	SaveDeathNotification(notification *DeathNotification) ([]string, error)
+
+	CheckIntegrity() (*admin.IntegrityReport, error)
 }
 
 func NewRepo(db *sql.DB) *sqlRepo {
@@ -44,42 +120,23 @@ func (r *sqlRepo) Close() error {
 	return r.db.Close()
 }
 
-func (r *sqlRepo) getTransfers(orgID string, params transferFilterParams) ([]*client.Transfer, error) {
+func (r *sqlRepo) getTransfers(orgID string, params transferFilterParams) ([]*client.Transfer, []skippedRow, error) {
 	var query strings.Builder
 	query.WriteString("select transfer_id from transfers where ")
-
-	var args []interface{}
-	query.WriteString("organization = ? and created_at >= ? and created_at <= ? and deleted_at is null ")
-	args = append(args, orgID, params.StartDate, params.EndDate)
-
-	if string(params.Status) != "" {
-		query.WriteString("and status = ? ")
-		args = append(args, params.Status)
-	}
-
-	if len(params.CustomerIDs) > 0 {
-		s := fmt.Sprintf(
-			"and ( source_customer_id in (?%[1]s) or destination_customer_id in (?%[1]s) ) ",
-			strings.Repeat(",?", len(params.CustomerIDs)-1),
-		)
-		query.WriteString(s)
-		for i := 0; i < len(params.CustomerIDs)*2; i++ {
-			args = append(args, params.CustomerIDs[i%len(params.CustomerIDs)])
-		}
-	}
+	args := writeTransferFilters(&query, orgID, params)
 
 	query.WriteString("order by created_at desc limit ? offset ?;")
 	args = append(args, params.Count, params.Skip)
 
 	stmt, err := r.db.Prepare(query.String())
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	defer stmt.Close()
 
 	rows, err := stmt.Query(args...)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	defer rows.Close()
 
@@ -89,28 +146,130 @@ func (r *sqlRepo) getTransfers(orgID string, params transferFilterParams) ([]*cl
 	for rows.Next() {
 		var row string
 		if err := rows.Scan(&row); err != nil {
-			return transfers, fmt.Errorf("getTransfers scan: %v", err)
+			return transfers, nil, fmt.Errorf("getTransfers scan: %v", err)
 		}
 		if row != "" {
 			transferIDs = append(transferIDs, row)
 		}
 	}
 	if err := rows.Err(); err != nil {
-		return transfers, fmt.Errorf("getTransfers: rows.Err=%v", err)
+		return transfers, nil, fmt.Errorf("getTransfers: rows.Err=%v", err)
 	}
 
-	// read each transferID
+	// read each transferID, rows which fail are returned so callers can surface them
+	var skipped []skippedRow
 	for i := range transferIDs {
 		t, err := r.getUserTransfer(transferIDs[i], orgID)
-		if err == nil && t.TransferID != "" {
-			transfers = append(transfers, t)
+		if err != nil || t == nil {
+			skipped = append(skipped, skipRow("transfers", transferIDs[i], err))
+			continue
+		}
+		transfers = append(transfers, t)
+	}
+	return transfers, skipped, rows.Err()
+}
+
+// streamTransfers calls fn with each Transfer matching params as rows are read from the database.
+// Only columns of the transfers table are read, so trace numbers, legs and tags are not included.
+func (r *sqlRepo) streamTransfers(orgID string, params transferFilterParams, fn func(*client.Transfer) error) ([]skippedRow, error) {
+	var query strings.Builder
+	query.WriteString(`select transfer_id, amount_currency, amount_value, source_customer_id, source_account_id, destination_customer_id, destination_account_id, description, status, same_day, effective_date, return_code, processed_at, created_at from transfers where `)
+	args := writeTransferFilters(&query, orgID, params)
+
+	query.WriteString("order by created_at desc limit ? offset ?;")
+	args = append(args, params.Count, params.Skip)
+
+	stmt, err := r.db.Prepare(query.String())
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.Query(args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var skipped []skippedRow
+	for rows.Next() {
+		var effectiveDate, returnCode *string
+		transfer := &client.Transfer{}
+		err := rows.Scan(
+			&transfer.TransferID,
+			&transfer.Amount.Currency,
+			&transfer.Amount.Value,
+			&transfer.Source.CustomerID,
+			&transfer.Source.AccountID,
+			&transfer.Destination.CustomerID,
+			&transfer.Destination.AccountID,
+			&transfer.Description,
+			&transfer.Status,
+			&transfer.SameDay,
+			&effectiveDate,
+			&returnCode,
+			&transfer.ProcessedAt,
+			&transfer.Created,
+		)
+		if err != nil {
+			// transfer_id is scanned first, so it's set unless that column failed
+			skipped = append(skipped, skipRow("transfers", transfer.TransferID, err))
+			continue
+		}
+		if effectiveDate != nil {
+			transfer.EffectiveDate = *effectiveDate
+		}
+		if returnCode != nil {
+			transfer.ReturnCode = &client.ReturnCode{Code: *returnCode}
+		}
+		if err := fn(transfer); err != nil {
+			return skipped, err
+		}
+	}
+	return skipped, rows.Err()
+}
+
+// writeTransferFilters appends the where clause for params onto query and returns its arguments.
+func writeTransferFilters(query *strings.Builder, orgID string, params transferFilterParams) []interface{} {
+	var args []interface{}
+	query.WriteString("organization = ? and created_at >= ? and created_at <= ? and deleted_at is null ")
+	args = append(args, orgID, params.StartDate, params.EndDate)
+
+	if string(params.Status) != "" {
+		query.WriteString("and status = ? ")
+		args = append(args, params.Status)
+	}
+
+	if len(params.Tags) > 0 {
+		query.WriteString(fmt.Sprintf("and transfer_id in (select transfer_id from transfer_tags where tag in (?%s)) ", strings.Repeat(",?", len(params.Tags)-1)))
+		for i := range params.Tags {
+			args = append(args, params.Tags[i])
+		}
+	}
+
+	if len(params.CustomerIDs) > 0 {
+		s := fmt.Sprintf(
+			"and ( source_customer_id in (?%[1]s) or destination_customer_id in (?%[1]s) ) ",
+			strings.Repeat(",?", len(params.CustomerIDs)-1),
+		)
+		query.WriteString(s)
+		for i := 0; i < len(params.CustomerIDs)*2; i++ {
+			args = append(args, params.CustomerIDs[i%len(params.CustomerIDs)])
 		}
 	}
-	return transfers, rows.Err()
+
+	if params.ReturnCode != "" {
+		query.WriteString("and return_code = ? ")
+		args = append(args, params.ReturnCode)
+	}
+	if params.Representable {
+		args = append(args, writeRepresentableFilter(query, time.Now())...)
+	}
+	return args
 }
 
 func (r *sqlRepo) getUserTransfer(transferID string, orgID string) (*client.Transfer, error) {
-	query := `select transfer_id, amount_currency, amount_value, source_customer_id, source_account_id, destination_customer_id, destination_account_id, description, status, same_day, return_code, processed_at, created_at
+	query := `select transfer_id, amount_currency, amount_value, source_customer_id, source_account_id, destination_customer_id, destination_account_id, description, status, same_day, same_day_preferred, same_day_reason, effective_date, return_code, processed_at, created_at, cancel_reason, cancel_note, canceled_at, correlation_id, iat_detail
 from transfers
 where transfer_id = ? and organization = ? and deleted_at is null
 limit 1`
@@ -120,7 +279,9 @@ limit 1`
 	}
 	defer stmt.Close()
 
-	var returnCode *string
+	var effectiveDate, returnCode, sameDayReason, cancelReason, cancelNote, iatDetail *string
+	var sameDayPreferred bool
+	var canceledAt *time.Time
 	transfer := &client.Transfer{}
 
 	err = stmt.QueryRow(transferID, orgID).Scan(
@@ -134,13 +295,27 @@ limit 1`
 		&transfer.Description,
 		&transfer.Status,
 		&transfer.SameDay,
+		&sameDayPreferred,
+		&sameDayReason,
+		&effectiveDate,
 		&returnCode,
 		&transfer.ProcessedAt,
 		&transfer.Created,
+		&cancelReason,
+		&cancelNote,
+		&canceledAt,
+		&transfer.CorrelationID,
+		&iatDetail,
 	)
 	if transfer.TransferID == "" || err != nil {
 		return nil, err
 	}
+	if iatDetail != nil && *iatDetail != "" {
+		transfer.IatDetail = &client.IatDetail{}
+		if err := json.Unmarshal([]byte(*iatDetail), transfer.IatDetail); err != nil {
+			return nil, fmt.Errorf("transferID=%s iat_detail: %v", transferID, err)
+		}
+	}
 
 	// query the trace table
 	// append the transfer if any tracenums
@@ -151,6 +326,38 @@ limit 1`
 	for i := range traceNumbers {
 		transfer.TraceNumbers = append(transfer.TraceNumbers, traceNumbers[i])
 	}
+	legs, err := r.getTransferLegs(transferID)
+	if err != nil {
+		return nil, err
+	}
+	transfer.Legs = legs
+	tags, err := r.getTransferTags(transferID)
+	if err != nil {
+		return nil, err
+	}
+	transfer.Tags = tags
+	transfer.ReversalOf, transfer.ReversedBy, err = r.getReversal(transferID)
+	if err != nil {
+		return nil, err
+	}
+	if effectiveDate != nil {
+		transfer.EffectiveDate = *effectiveDate
+	}
+	if sameDayPreferred {
+		transfer.SameDayDecision = &client.SameDayDecision{Selected: transfer.SameDay}
+		if sameDayReason != nil {
+			transfer.SameDayDecision.Reason = *sameDayReason
+		}
+	}
+	if cancelReason != nil && canceledAt != nil {
+		transfer.Cancellation = &client.Cancellation{
+			Reason:   client.CancellationReason(*cancelReason),
+			Canceled: *canceledAt,
+		}
+		if cancelNote != nil {
+			transfer.Cancellation.Note = *cancelNote
+		}
+	}
 	if returnCode != nil {
 		if rc := ach.LookupReturnCode(*returnCode); rc != nil {
 			transfer.ReturnCode = &client.ReturnCode{
@@ -178,26 +385,94 @@ func (r *sqlRepo) GetTransfer(transferID string) (*client.Transfer, error) {
 	return r.getUserTransfer(transferID, orgID)
 }
 
-func (r *sqlRepo) UpdateTransferStatus(transferID string, status client.TransferStatus) error {
-	query := `update transfers set status = ? where transfer_id = ? and deleted_at is null`
+// GetTransferOrganization returns the organization which created a Transfer.
+func (r *sqlRepo) GetTransferOrganization(transferID string) (string, error) {
+	query := `select organization from transfers where transfer_id = ? and deleted_at is null limit 1`
 	stmt, err := r.db.Prepare(query)
 	if err != nil {
+		return "", err
+	}
+	defer stmt.Close()
+
+	orgID := ""
+	if err := stmt.QueryRow(transferID).Scan(&orgID); err != nil {
+		return "", err
+	}
+	return orgID, nil
+}
+
+func (r *sqlRepo) UpdateTransferStatus(transferID string, status client.TransferStatus, actor history.Actor) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	existing, err := history.CurrentStatus(tx, transferID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	query := `update transfers set status = ? where transfer_id = ? and deleted_at is null`
+	stmt, err := tx.Prepare(query)
+	if err != nil {
+		tx.Rollback()
 		return err
 	}
 	defer stmt.Close()
 
-	_, err = stmt.Exec(status, transferID)
-	return err
+	if _, err = stmt.Exec(status, transferID); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if existing != "" && existing != string(status) {
+		change := history.Change{Field: "status", OldValue: existing, NewValue: string(status)}
+		if err := history.Record(tx, transferID, actor, change); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
 }
 
 func (r *sqlRepo) WriteUserTransfer(orgID string, transfer *client.Transfer) error {
-	query := `insert into transfers (transfer_id, organization, amount_currency, amount_value, source_customer_id, source_account_id, destination_customer_id, destination_account_id, description, status, same_day, created_at) values (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);`
-	stmt, err := r.db.Prepare(query)
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := insertTransfer(tx, orgID, transfer); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// insertTransfer writes a new Transfer, its tags and initial status inside of tx.
+func insertTransfer(tx *sql.Tx, orgID string, transfer *client.Transfer) error {
+	query := `insert into transfers (transfer_id, organization, amount_currency, amount_value, source_customer_id, source_account_id, destination_customer_id, destination_account_id, description, status, same_day, same_day_preferred, same_day_reason, effective_date, correlation_id, iat_detail, created_at) values (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);`
+	stmt, err := tx.Prepare(query)
 	if err != nil {
 		return err
 	}
 	defer stmt.Close()
 
+	var sameDayPreferred bool
+	var sameDayReason string
+	if transfer.SameDayDecision != nil {
+		sameDayPreferred = true
+		sameDayReason = transfer.SameDayDecision.Reason
+	}
+	var iatDetail string
+	if transfer.IatDetail != nil {
+		bs, err := json.Marshal(transfer.IatDetail)
+		if err != nil {
+			return fmt.Errorf("iat_detail: %v", err)
+		}
+		iatDetail = string(bs)
+	}
+
 	_, err = stmt.Exec(
 		transfer.TransferID,
 		orgID,
@@ -210,12 +485,29 @@ func (r *sqlRepo) WriteUserTransfer(orgID string, transfer *client.Transfer) err
 		transfer.Description,
 		transfer.Status,
 		transfer.SameDay,
+		sameDayPreferred,
+		sameDayReason,
+		transfer.EffectiveDate,
+		transfer.CorrelationID,
+		iatDetail,
 		time.Now(),
 	)
-	return err
+	if err != nil {
+		return err
+	}
+
+	if err := insertTransferTags(tx, transfer.TransferID, transfer.Tags); err != nil {
+		return err
+	}
+
+	change := history.Change{Field: "status", NewValue: string(transfer.Status)}
+	return history.Record(tx, transfer.TransferID, history.API, change)
 }
 
-func (r *sqlRepo) deleteUserTransfer(orgID string, transferID string) error {
+// deleteUserTransfer cancels a PENDING Transfer and records why. Canceled Transfers are kept
+// so their cancellation is included when they're read. Canceling a Transfer again is a no-op
+// so retried DELETE requests succeed and keep the original reason.
+func (r *sqlRepo) deleteUserTransfer(orgID string, transferID string, cancel client.CancelTransfer) error {
 	tx, err := r.db.Begin()
 	if err != nil {
 		return err
@@ -237,12 +529,16 @@ func (r *sqlRepo) deleteUserTransfer(orgID string, transferID string) error {
 		}
 		return err
 	}
+	if strings.EqualFold(status, string(client.CANCELED)) {
+		tx.Rollback()
+		return nil
+	}
 	if !strings.EqualFold(status, string(client.PENDING)) {
 		tx.Rollback()
 		return fmt.Errorf("transferID=%s is not in PENDING status", transferID)
 	}
 
-	query = `update transfers set deleted_at = ?
+	query = `update transfers set status = ?, cancel_reason = ?, cancel_note = ?, canceled_at = ?
 where transfer_id = ? and organization = ? and status = ? and deleted_at is null`
 	stmt, err = tx.Prepare(query)
 	if err != nil {
@@ -251,7 +547,7 @@ where transfer_id = ? and organization = ? and status = ? and deleted_at is null
 	}
 	defer stmt.Close()
 
-	_, err = stmt.Exec(time.Now(), transferID, orgID, client.PENDING)
+	_, err = stmt.Exec(client.CANCELED, cancel.Reason, cancel.Note, time.Now(), transferID, orgID, client.PENDING)
 	if err != nil {
 		tx.Rollback()
 		if err == sql.ErrNoRows {
@@ -260,73 +556,300 @@ where transfer_id = ? and organization = ? and status = ? and deleted_at is null
 		return err
 	}
 
+	changes := []history.Change{
+		{Field: "status", OldValue: string(client.PENDING), NewValue: string(client.CANCELED)},
+		{Field: "cancelReason", NewValue: string(cancel.Reason)},
+	}
+	if err := history.Record(tx, transferID, history.API, changes...); err != nil {
+		tx.Rollback()
+		return err
+	}
+
 	return tx.Commit()
 }
 
 func (r *sqlRepo) SaveReturnCode(transferID string, returnCode string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+
 	query := `update transfers set return_code = ? where transfer_id = ? and return_code is null and deleted_at is null`
-	stmt, err := r.db.Prepare(query)
+	stmt, err := tx.Prepare(query)
 	if err != nil {
+		tx.Rollback()
 		return err
 	}
 	defer stmt.Close()
 
-	_, err = stmt.Exec(returnCode, transferID)
-	if err == sql.ErrNoRows {
-		return nil
+	res, err := stmt.Exec(returnCode, transferID)
+	if err != nil {
+		tx.Rollback()
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		return err
 	}
-	return err
+
+	if n, _ := res.RowsAffected(); n > 0 {
+		change := history.Change{Field: "returnCode", NewValue: returnCode}
+		if err := history.Record(tx, transferID, history.Inbound, change); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
 }
 
 func (r *sqlRepo) saveTraceNumbers(transferID string, traceNumbers []string) error {
-	query := `insert into transfer_trace_numbers(transfer_id, trace_number) values (?, ?);`
 	tx, err := r.db.Begin()
 	if err != nil {
 		return err
 	}
+	if err := insertTraceNumbers(tx, transferID, traceNumbers); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func insertTraceNumbers(tx *sql.Tx, transferID string, traceNumbers []string) error {
+	query := `insert into transfer_trace_numbers(transfer_id, trace_number) values (?, ?);`
 	stmt, err := tx.Prepare(query)
 	if err != nil {
-		tx.Rollback()
 		return err
 	}
+	defer stmt.Close()
+
 	for i := range traceNumbers {
 		if _, err := stmt.Exec(transferID, traceNumbers[i]); err != nil {
-			tx.Rollback()
 			return err
 		}
 	}
+
+	change := history.Change{Field: "traceNumbers", NewValue: strings.Join(traceNumbers, ",")}
+	return history.Record(tx, transferID, history.API, change)
+}
+
+const saveEntryTotalsQuery = `update transfers set sec_code = ?, debit_amount = ?, credit_amount = ? where transfer_id = ? and deleted_at is null;`
+
+func (r *sqlRepo) saveEntryTotals(transferID string, secCode string, debits, credits int64) error {
+	_, err := r.db.Exec(saveEntryTotalsQuery, secCode, debits, credits, transferID)
+	return err
+}
+
+func (r *sqlRepo) saveTransferLegs(transferID string, legs []client.TransferLeg) error {
+	if len(legs) == 0 {
+		return nil
+	}
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := insertTransferLegs(tx, transferID, legs); err != nil {
+		tx.Rollback()
+		return err
+	}
 	return tx.Commit()
 }
 
-func (r *sqlRepo) LookupTransferFromReturn(amount client.Amount, traceNumber string, effectiveEntryDate time.Time) (*client.Transfer, error) {
-	// To match returned files we take a few values which are assumed to uniquely identify a Transfer.
-	// traceNumber, per NACHA guidelines, should be globally unique (routing number + random value),
-	// but we are going to filter to only select Transfers created within a few days of the EffectiveEntryDate
-	// to avoid updating really old (or future, I suppose) objects.
-	query := `select xf.transfer_id, xf.organization from transfers as xf
-inner join transfer_trace_numbers trace on xf.transfer_id = trace.transfer_id
-where xf.amount_value = ? and trace.trace_number = ? and xf.status = ? and (xf.created_at > ? and xf.created_at < ?) and xf.deleted_at is null limit 1`
+func insertTransferLegs(tx *sql.Tx, transferID string, legs []client.TransferLeg) error {
+	if len(legs) == 0 {
+		return nil
+	}
+
+	query := `insert into transfer_legs(transfer_id, leg, status, updated_at) values (?, ?, ?, ?);`
+	stmt, err := tx.Prepare(query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	var changes []history.Change
+	for i := range legs {
+		if _, err := stmt.Exec(transferID, legs[i].Leg, legs[i].Status, legs[i].Updated); err != nil {
+			return err
+		}
+		changes = append(changes, history.Change{Field: legs[i].Leg + "Leg", NewValue: string(legs[i].Status)})
+	}
+	return history.Record(tx, transferID, history.API, changes...)
+}
 
+func (r *sqlRepo) getTransferLegs(transferID string) ([]client.TransferLeg, error) {
+	query := `select leg, status, updated_at from transfer_legs where transfer_id = ? order by leg desc;`
 	stmt, err := r.db.Prepare(query)
 	if err != nil {
 		return nil, err
 	}
 	defer stmt.Close()
 
-	transferId, orgID := "", ""
-	min, max := startOfDayAndTomorrow(effectiveEntryDate)
-	// Only include Transfer objects within 5 calendar days of the EffectiveEntryDate
-	min = min.Add(-5 * 24 * time.Hour)
-	max = max.Add(5 * 24 * time.Hour)
-
-	row := stmt.QueryRow(amount.Value, traceNumber, client.PROCESSED, min, max)
-	if err := row.Scan(&transferId, &orgID); err != nil {
+	rows, err := stmt.Query(transferID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []client.TransferLeg
+	for rows.Next() {
+		var leg client.TransferLeg
+		if err := rows.Scan(&leg.Leg, &leg.Status, &leg.Updated); err != nil {
+			return nil, err
+		}
+		out = append(out, leg)
+	}
+	return out, rows.Err()
+}
+
+// heldLeg is the credit leg of a two-leg transfer waiting for release
+type heldLeg struct {
+	TransferID     string
+	OrganizationID string
+
+	// ReturnCode is set when the debit leg was returned
+	ReturnCode string
+
+	// DebitProcessed is when the debit leg was uploaded
+	DebitProcessed time.Time
+}
+
+var errLegNotUpdated = errors.New("transfer leg not found or changed")
+
+const heldCreditLegsQuery = `select xf.transfer_id, xf.organization, xf.return_code, debit.updated_at from transfer_legs as credit
+inner join transfer_legs as debit on credit.transfer_id = debit.transfer_id and debit.leg = ?
+inner join transfers as xf on credit.transfer_id = xf.transfer_id
+where credit.leg = ? and credit.status = ? and debit.status = ? and xf.deleted_at is null`
+
+func (r *sqlRepo) getHeldCreditLegs() ([]heldLeg, error) {
+	return r.queryHeldCreditLegs(heldCreditLegsQuery + ";")
+}
+
+func (r *sqlRepo) getHeldCreditLeg(transferID string) (*heldLeg, error) {
+	legs, err := r.queryHeldCreditLegs(heldCreditLegsQuery+" and xf.transfer_id = ? limit 1;", transferID)
+	if len(legs) == 0 || err != nil {
+		return nil, err
+	}
+	return &legs[0], nil
+}
+
+func (r *sqlRepo) queryHeldCreditLegs(query string, args ...interface{}) ([]heldLeg, error) {
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	args = append([]interface{}{
+		fundflow.DebitLeg, fundflow.CreditLeg, client.TRANSFERLEGSTATUS_HELD, client.TRANSFERLEGSTATUS_PROCESSED,
+	}, args...)
+	rows, err := stmt.Query(args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []heldLeg
+	for rows.Next() {
+		var leg heldLeg
+		var returnCode *string
+		if err := rows.Scan(&leg.TransferID, &leg.OrganizationID, &returnCode, &leg.DebitProcessed); err != nil {
+			return nil, err
+		}
+		if returnCode != nil {
+			leg.ReturnCode = *returnCode
+		}
+		out = append(out, leg)
+	}
+	return out, rows.Err()
+}
+
+// updateTransferLeg moves a leg between statuses. errLegNotUpdated is returned if the leg
+// wasn't in the from status, which lets callers claim a leg before acting on it.
+func (r *sqlRepo) updateTransferLeg(transferID string, leg string, from, to client.TransferLegStatus, actor history.Actor) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	query := `update transfer_legs set status = ?, updated_at = ? where transfer_id = ? and leg = ? and status = ?;`
+	stmt, err := tx.Prepare(query)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	res, err := stmt.Exec(to, time.Now(), transferID, leg, from)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		tx.Rollback()
+		return errLegNotUpdated
+	}
+
+	change := history.Change{Field: leg + "Leg", OldValue: string(from), NewValue: string(to)}
+	if err := history.Record(tx, transferID, actor, change); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *sqlRepo) LookupTransferFromReturn(amount client.Amount, traceNumber string, effectiveEntryDate time.Time) (*client.Transfer, error) {
+	// To match returned files we take a few values which are assumed to uniquely identify a Transfer.
+	// traceNumber, per NACHA guidelines, should be globally unique (routing number + random value),
+	// but we are going to filter to only select Transfers created within a few days of the EffectiveEntryDate
+	// to avoid updating really old (or future, I suppose) objects.
+	query := `select xf.transfer_id, xf.organization from transfers as xf
+inner join transfer_trace_numbers trace on xf.transfer_id = trace.transfer_id
+where xf.amount_value = ? and trace.trace_number = ? and xf.status = ? and (xf.created_at > ? and xf.created_at < ?) and xf.deleted_at is null limit 1`
+
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	transferId, orgID := "", ""
+	min, max := startOfDayAndTomorrow(effectiveEntryDate)
+	// Only include Transfer objects within 5 calendar days of the EffectiveEntryDate
+	min = min.Add(-5 * 24 * time.Hour)
+	max = max.Add(5 * 24 * time.Hour)
+
+	row := stmt.QueryRow(amount.Value, traceNumber, client.PROCESSED, min, max)
+	if err := row.Scan(&transferId, &orgID); err != nil {
 		return nil, err
 	}
 
 	return r.getUserTransfer(transferId, orgID)
 }
 
+// LookupTransferFromTraceNumber returns the processed Transfer which an entry with traceNumber
+// was uploaded for, or nil if there isn't one.
+func (r *sqlRepo) LookupTransferFromTraceNumber(traceNumber string) (*client.Transfer, error) {
+	query := `select xf.transfer_id, xf.organization from transfers as xf
+inner join transfer_trace_numbers trace on xf.transfer_id = trace.transfer_id
+where trace.trace_number = ? and xf.status = ? and xf.deleted_at is null limit 1`
+
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	transferID, orgID := "", ""
+	if err := stmt.QueryRow(traceNumber, client.PROCESSED).Scan(&transferID, &orgID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return r.getUserTransfer(transferID, orgID)
+}
+
 // startOfDayAndTomorrow returns two time.Time values from a given time.Time value.
 // The first is at the start of the same day as provided and the second is exactly 24 hours
 // after the first.
@@ -364,3 +887,434 @@ where transfer_id = ?`
 
 	return traceNumbers, nil
 }
+
+func (r *sqlRepo) getTransferHistory(orgID string, transferID string) ([]*client.TransferChange, error) {
+	query := `select h.field, h.old_value, h.new_value, h.actor, h.created_at from transfer_history as h
+inner join transfers as t on h.transfer_id = t.transfer_id
+where h.transfer_id = ? and t.organization = ? order by h.created_at asc;`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.Query(transferID, orgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []*client.TransferChange
+	for rows.Next() {
+		var change client.TransferChange
+		var oldValue, newValue *string
+		if err := rows.Scan(&change.Field, &oldValue, &newValue, &change.Actor, &change.Created); err != nil {
+			return nil, err
+		}
+		if oldValue != nil {
+			change.OldValue = *oldValue
+		}
+		if newValue != nil {
+			change.NewValue = *newValue
+		}
+		out = append(out, &change)
+	}
+	return out, rows.Err()
+}
+
+func (r *sqlRepo) getTransferTags(transferID string) ([]string, error) {
+	query := `select tag from transfer_tags where transfer_id = ? order by tag asc;`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.Query(transferID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tags []string
+	for rows.Next() {
+		var tag string
+		if err := rows.Scan(&tag); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
+
+func insertTransferTags(tx *sql.Tx, transferID string, tags []string) error {
+	if len(tags) == 0 {
+		return nil
+	}
+	query := `insert into transfer_tags (transfer_id, tag) values (?, ?);`
+	stmt, err := tx.Prepare(query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for i := range tags {
+		if _, err := stmt.Exec(transferID, tags[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// saveTransferTags replaces the tags of a Transfer and records the change in its history.
+func (r *sqlRepo) saveTransferTags(orgID string, transferID string, tags []string) error {
+	existing, err := r.getTransferTags(transferID)
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	query := `select transfer_id from transfers where transfer_id = ? and organization = ? and deleted_at is null limit 1;`
+	stmt, err := tx.Prepare(query)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	var id string
+	if err := stmt.QueryRow(transferID, orgID).Scan(&id); err != nil {
+		tx.Rollback()
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("transferID=%s not found", transferID)
+		}
+		return err
+	}
+
+	query = `delete from transfer_tags where transfer_id = ?;`
+	del, err := tx.Prepare(query)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	defer del.Close()
+
+	if _, err := del.Exec(transferID); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := insertTransferTags(tx, transferID, tags); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	change := history.Change{
+		Field:    "tags",
+		OldValue: strings.Join(existing, ","),
+		NewValue: strings.Join(tags, ","),
+	}
+	if err := history.Record(tx, transferID, history.API, change); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *sqlRepo) getTransferViews(orgID string, userID string) ([]*client.TransferView, error) {
+	query := `select view_id, name, status, start_date, end_date, tags, created_at from transfer_views
+where organization = ? and user_id = ? and deleted_at is null order by name asc;`
+	return r.queryTransferViews(query, orgID, userID)
+}
+
+func (r *sqlRepo) getTransferView(orgID string, userID string, viewID string) (*client.TransferView, error) {
+	query := `select view_id, name, status, start_date, end_date, tags, created_at from transfer_views
+where organization = ? and user_id = ? and view_id = ? and deleted_at is null limit 1;`
+	views, err := r.queryTransferViews(query, orgID, userID, viewID)
+	if err != nil || len(views) == 0 {
+		return nil, err
+	}
+	return views[0], nil
+}
+
+func (r *sqlRepo) queryTransferViews(query string, args ...interface{}) ([]*client.TransferView, error) {
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.Query(args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	views := make([]*client.TransferView, 0)
+	for rows.Next() {
+		var view client.TransferView
+		var startDate, endDate *time.Time
+		var tags string
+		if err := rows.Scan(&view.ViewID, &view.Name, &view.Status, &startDate, &endDate, &tags, &view.Created); err != nil {
+			return nil, err
+		}
+		if startDate != nil {
+			view.StartDate = *startDate
+		}
+		if endDate != nil {
+			view.EndDate = *endDate
+		}
+		if tags != "" {
+			view.Tags = strings.Split(tags, ",")
+		}
+		views = append(views, &view)
+	}
+	return views, rows.Err()
+}
+
+func (r *sqlRepo) createTransferView(orgID string, userID string, view *client.TransferView) error {
+	query := `insert into transfer_views (view_id, organization, user_id, name, status, start_date, end_date, tags, created_at) values (?, ?, ?, ?, ?, ?, ?, ?, ?);`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	var startDate, endDate *time.Time
+	if !view.StartDate.IsZero() {
+		startDate = &view.StartDate
+	}
+	if !view.EndDate.IsZero() {
+		endDate = &view.EndDate
+	}
+	_, err = stmt.Exec(view.ViewID, orgID, userID, view.Name, view.Status, startDate, endDate, strings.Join(view.Tags, ","), view.Created)
+	return err
+}
+
+func (r *sqlRepo) deleteTransferView(orgID string, userID string, viewID string) error {
+	query := `update transfer_views set deleted_at = ? where organization = ? and user_id = ? and view_id = ? and deleted_at is null;`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	res, err := stmt.Exec(time.Now(), orgID, userID, viewID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("viewID=%s not found", viewID)
+	}
+	return nil
+}
+
+const scheduledTransferColumns = `schedule_id, organization, user_id, run_count, template, frequency, status, start_date, end_date, next_run_at, last_run_at, last_transfer_id, last_error, created_at`
+
+func (r *sqlRepo) getScheduledTransfers(orgID string) ([]*client.ScheduledTransfer, error) {
+	query := `select ` + scheduledTransferColumns + ` from scheduled_transfers where organization = ? order by created_at desc;`
+	due, err := r.queryScheduledTransfers(query, orgID)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*client.ScheduledTransfer, 0, len(due))
+	for i := range due {
+		out = append(out, due[i].schedule)
+	}
+	return out, nil
+}
+
+func (r *sqlRepo) getScheduledTransfer(orgID string, scheduleID string) (*client.ScheduledTransfer, error) {
+	query := `select ` + scheduledTransferColumns + ` from scheduled_transfers where organization = ? and schedule_id = ? limit 1;`
+	due, err := r.queryScheduledTransfers(query, orgID, scheduleID)
+	if err != nil || len(due) == 0 {
+		return nil, err
+	}
+	return due[0].schedule, nil
+}
+
+func (r *sqlRepo) getDueScheduledTransfers(now time.Time) ([]dueSchedule, error) {
+	query := `select ` + scheduledTransferColumns + ` from scheduled_transfers where status = ? and next_run_at <= ? order by next_run_at asc;`
+	return r.queryScheduledTransfers(query, client.SCHEDULESTATUS_ACTIVE, now)
+}
+
+func (r *sqlRepo) queryScheduledTransfers(query string, args ...interface{}) ([]dueSchedule, error) {
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.Query(args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []dueSchedule
+	for rows.Next() {
+		var due dueSchedule
+		var schedule client.ScheduledTransfer
+		var template string
+		var endDate, nextRun, lastRun *time.Time
+		var lastTransferID, lastError *string
+		err := rows.Scan(&schedule.ScheduleID, &due.organization, &due.userID, &due.runCount, &template, &schedule.Frequency, &schedule.Status,
+			&schedule.StartDate, &endDate, &nextRun, &lastRun, &lastTransferID, &lastError, &schedule.Created)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(template), &schedule.Transfer); err != nil {
+			return nil, fmt.Errorf("scheduleID=%s template: %v", schedule.ScheduleID, err)
+		}
+		if endDate != nil {
+			schedule.EndDate = *endDate
+		}
+		if nextRun != nil {
+			schedule.NextRun = *nextRun
+		}
+		if lastRun != nil {
+			schedule.LastRun = *lastRun
+		}
+		if lastTransferID != nil {
+			schedule.LastTransferID = *lastTransferID
+		}
+		if lastError != nil {
+			schedule.LastError = *lastError
+		}
+		due.schedule = &schedule
+		out = append(out, due)
+	}
+	return out, rows.Err()
+}
+
+func (r *sqlRepo) createScheduledTransfer(orgID string, userID string, schedule *client.ScheduledTransfer) error {
+	template, err := json.Marshal(schedule.Transfer)
+	if err != nil {
+		return err
+	}
+
+	query := `insert into scheduled_transfers (schedule_id, organization, user_id, template, frequency, status, start_date, end_date, next_run_at, run_count, created_at)
+values (?, ?, ?, ?, ?, ?, ?, ?, ?, 0, ?);`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	var endDate *time.Time
+	if !schedule.EndDate.IsZero() {
+		endDate = &schedule.EndDate
+	}
+	_, err = stmt.Exec(schedule.ScheduleID, orgID, userID, string(template), schedule.Frequency, schedule.Status,
+		schedule.StartDate, endDate, schedule.NextRun, schedule.Created)
+	return err
+}
+
+// updateScheduledTransferStatus moves a schedule out of the from status. Only active schedules
+// keep a next run, so run is ignored when pausing or canceling.
+func (r *sqlRepo) updateScheduledTransferStatus(orgID string, scheduleID string, from client.ScheduleStatus, run scheduledRun) error {
+	query := `update scheduled_transfers set status = ?, next_run_at = null where organization = ? and schedule_id = ? and status = ?;`
+	args := []interface{}{run.status, orgID, scheduleID, from}
+	if !run.next.IsZero() {
+		query = `update scheduled_transfers set status = ?, run_count = ?, next_run_at = ? where organization = ? and schedule_id = ? and status = ?;`
+		args = []interface{}{run.status, run.count, run.next, orgID, scheduleID, from}
+	}
+
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	res, err := stmt.Exec(args...)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("scheduleID=%s is no longer %s", scheduleID, from)
+	}
+	return nil
+}
+
+// claimScheduledRun advances an active schedule past runCount. It returns false when another
+// instance has already claimed the run or the schedule was paused or canceled.
+func (r *sqlRepo) claimScheduledRun(scheduleID string, runCount int, run scheduledRun) (bool, error) {
+	query := `update scheduled_transfers set status = ?, run_count = ?, next_run_at = ? where schedule_id = ? and run_count = ? and status = ?;`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return false, err
+	}
+	defer stmt.Close()
+
+	var next *time.Time
+	if !run.next.IsZero() {
+		next = &run.next
+	}
+	res, err := stmt.Exec(run.status, run.count, next, scheduleID, runCount, client.SCHEDULESTATUS_ACTIVE)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+func (r *sqlRepo) recordScheduledRun(scheduleID string, transferID string, lastError string, when time.Time) error {
+	query := `update scheduled_transfers set last_run_at = ?, last_error = ? where schedule_id = ?;`
+	args := []interface{}{when, lastError, scheduleID}
+	if transferID != "" {
+		query = `update scheduled_transfers set last_run_at = ?, last_error = ?, last_transfer_id = ? where schedule_id = ?;`
+		args = []interface{}{when, lastError, transferID, scheduleID}
+	}
+
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	if len(lastError) > 200 {
+		args[1] = lastError[:200]
+	}
+	_, err = stmt.Exec(args...)
+	return err
+}
+
+// customerSuspended returns true when the Customer matched OFAC when screened again and
+// hasn't been cleared by an admin. See the pkg/customers/ofac package.
+func (r *sqlRepo) customerSuspended(orgID string, customerID string) (bool, error) {
+	query := `select count(*) from ofac_matches where organization = ? and customer_id = ? and status = 'suspended';`
+	var n int
+	if err := r.db.QueryRow(query, orgID, customerID).Scan(&n); err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// emailVerified returns true when the Customer verified the given email address. See the
+// pkg/validation/emails package.
+func (r *sqlRepo) emailVerified(orgID string, customerID string, email string) (bool, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if email == "" {
+		return false, nil
+	}
+	query := `select count(*) from email_verifications where organization = ? and customer_id = ? and email = ?;`
+	var n int
+	if err := r.db.QueryRow(query, orgID, customerID, email).Scan(&n); err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// sourceHolds returns the total of Transfers from an Account which are still pending or in
+// review. They haven't been sent yet so aren't reflected in the Account's balance.
+func (r *sqlRepo) sourceHolds(orgID string, customerID string, accountID string) (int64, error) {
+	query := `select coalesce(sum(amount_value), 0) from transfers
+where organization = ? and source_customer_id = ? and source_account_id = ? and status in (?, ?) and deleted_at is null;`
+	var total int64
+	err := r.db.QueryRow(query, orgID, customerID, accountID, client.PENDING, client.REVIEWABLE).Scan(&total)
+	return total, err
+}
@@ -5,6 +5,7 @@
 package transfers
 
 import (
+	"database/sql"
 	"net/http"
 	"strings"
 	"testing"
@@ -14,6 +15,9 @@ import (
 
 	"github.com/moov-io/paygate/pkg/client"
 	"github.com/moov-io/paygate/pkg/database"
+	"github.com/moov-io/paygate/pkg/transfers/history"
+
+	"github.com/stretchr/testify/require"
 )
 
 func TestRepository__getTransfers(t *testing.T) {
@@ -22,7 +26,7 @@ func TestRepository__getTransfers(t *testing.T) {
 	writeTransfer(t, orgID, repo)
 
 	params := readTransferFilterParams(&http.Request{})
-	xfers, err := repo.getTransfers(orgID, params)
+	xfers, _, err := repo.getTransfers(orgID, params)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -46,7 +50,7 @@ func TestRepository__getTransfersWithTraceNumbers(t *testing.T) {
 	saveTraceNumbers(t, transfer, traceNumbers, repo)
 
 	params := readTransferFilterParams(&http.Request{})
-	xfers, err := repo.getTransfers(orgID, params)
+	xfers, _, err := repo.getTransfers(orgID, params)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -74,7 +78,7 @@ func TestRepository__getTransfersByStatus(t *testing.T) {
 
 	wantStatus := client.TransferStatus("failed")
 	for id := range markedAsFailedIDs {
-		err := repo.UpdateTransferStatus(id, wantStatus)
+		err := repo.UpdateTransferStatus(id, wantStatus, history.Admin)
 		if err != nil {
 			t.Fatalf("updating transfer status: %v", err)
 		}
@@ -82,7 +86,7 @@ func TestRepository__getTransfersByStatus(t *testing.T) {
 
 	params := readTransferFilterParams(&http.Request{})
 	params.Status = wantStatus
-	xfers, err := repo.getTransfers(orgID, params)
+	xfers, _, err := repo.getTransfers(orgID, params)
 	if err != nil {
 		t.Fatalf("getting transfers: %v", err)
 	}
@@ -116,7 +120,7 @@ func TestRepository__getTransfersWithCustomerIDs(t *testing.T) {
 		xfers[len(xfers)-2].Source.CustomerID,
 		xfers[len(xfers)-1].Source.CustomerID,
 	}
-	got, err := repo.getTransfers(orgID, params)
+	got, _, err := repo.getTransfers(orgID, params)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -152,7 +156,7 @@ func TestRepository__UpdateTransferStatus(t *testing.T) {
 		t.Fatalf("unexpected status: %v", xfer.Status)
 	}
 
-	if err := repo.UpdateTransferStatus(xfer.TransferID, client.CANCELED); err != nil {
+	if err := repo.UpdateTransferStatus(xfer.TransferID, client.CANCELED, history.Admin); err != nil {
 		t.Fatal(err)
 	}
 
@@ -165,6 +169,90 @@ func TestRepository__UpdateTransferStatus(t *testing.T) {
 	}
 }
 
+func TestRepository__GetTransferOrganization(t *testing.T) {
+	t.Parallel()
+
+	check := func(t *testing.T, repo *sqlRepo) {
+		orgID := base.ID()
+		xfer := writeTransfer(t, orgID, repo)
+
+		org, err := repo.GetTransferOrganization(xfer.TransferID)
+		require.NoError(t, err)
+		require.Equal(t, orgID, org)
+
+		_, err = repo.GetTransferOrganization(base.ID())
+		require.Equal(t, sql.ErrNoRows, err)
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+}
+
+func TestRepository__saveEntryTotals(t *testing.T) {
+	t.Parallel()
+
+	check := func(t *testing.T, repo *sqlRepo) {
+		xfer := writeTransfer(t, base.ID(), repo)
+		require.NoError(t, repo.saveEntryTotals(xfer.TransferID, "PPD", 1250, 0))
+
+		var secCode string
+		var debits, credits int64
+		query := `select sec_code, debit_amount, credit_amount from transfers where transfer_id = ?;`
+		require.NoError(t, repo.db.QueryRow(query, xfer.TransferID).Scan(&secCode, &debits, &credits))
+		require.Equal(t, "PPD", secCode)
+		require.Equal(t, int64(1250), debits)
+		require.Equal(t, int64(0), credits)
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+}
+
+func TestRepository__getTransferHistory(t *testing.T) {
+	t.Parallel()
+
+	check := func(t *testing.T, repo *sqlRepo) {
+		orgID := base.ID()
+		xfer := writeTransfer(t, orgID, repo)
+
+		saveTraceNumbers(t, xfer, []string{"123", "456"}, repo)
+		require.NoError(t, repo.UpdateTransferStatus(xfer.TransferID, client.REVIEWABLE, history.Admin))
+		require.NoError(t, repo.UpdateTransferStatus(xfer.TransferID, client.REVIEWABLE, history.Admin)) // no change
+		require.NoError(t, repo.SaveReturnCode(xfer.TransferID, "R01"))
+
+		changes, err := repo.getTransferHistory(orgID, xfer.TransferID)
+		require.NoError(t, err)
+		require.Len(t, changes, 4)
+
+		require.Equal(t, "status", changes[0].Field)
+		require.Equal(t, string(client.PENDING), changes[0].NewValue)
+		require.Equal(t, string(history.API), changes[0].Actor)
+
+		require.Equal(t, "traceNumbers", changes[1].Field)
+		require.Equal(t, "123,456", changes[1].NewValue)
+
+		require.Equal(t, "status", changes[2].Field)
+		require.Equal(t, string(client.PENDING), changes[2].OldValue)
+		require.Equal(t, string(client.REVIEWABLE), changes[2].NewValue)
+		require.Equal(t, string(history.Admin), changes[2].Actor)
+
+		require.Equal(t, "returnCode", changes[3].Field)
+		require.Equal(t, string(history.Inbound), changes[3].Actor)
+
+		// other organizations can't read the history
+		changes, err = repo.getTransferHistory(base.ID(), xfer.TransferID)
+		require.NoError(t, err)
+		require.Empty(t, changes)
+	}
+
+	t.Run("SQLite", func(t *testing.T) {
+		check(t, setupSQLiteDB(t))
+	})
+	t.Run("MySQL", func(t *testing.T) {
+		check(t, setupMySQLeDB(t))
+	})
+}
+
 func TestRepository__WriteUserTransfer(t *testing.T) {
 	orgID := base.ID()
 	repo := setupSQLiteDB(t)
@@ -179,27 +267,156 @@ func TestRepository__WriteUserTransfer(t *testing.T) {
 	}
 }
 
+func TestRepository__WriteUserTransferCorrelationID(t *testing.T) {
+	t.Parallel()
+
+	check := func(t *testing.T, repo *sqlRepo) {
+		xfer := &client.Transfer{
+			TransferID:    base.ID(),
+			Amount:        client.Amount{Currency: "USD", Value: 1245},
+			Description:   "payroll",
+			Status:        client.PENDING,
+			Created:       time.Now(),
+			CorrelationID: base.ID(),
+		}
+		require.NoError(t, repo.WriteUserTransfer("moov", xfer))
+
+		found, err := repo.GetTransfer(xfer.TransferID)
+		require.NoError(t, err)
+		require.Equal(t, xfer.CorrelationID, found.CorrelationID)
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+}
+
+func TestRepository__WriteUserTransferIATDetail(t *testing.T) {
+	t.Parallel()
+
+	check := func(t *testing.T, repo *sqlRepo) {
+		xfer := &client.Transfer{
+			TransferID:  base.ID(),
+			Amount:      client.Amount{Currency: "USD", Value: 1245},
+			Description: "payroll",
+			Status:      client.PENDING,
+			Created:     time.Now(),
+			IatDetail:   iatDetail(),
+		}
+		require.NoError(t, repo.WriteUserTransfer("moov", xfer))
+
+		found, err := repo.GetTransfer(xfer.TransferID)
+		require.NoError(t, err)
+		require.Equal(t, xfer.IatDetail, found.IatDetail)
+
+		// domestic transfers have no IAT detail
+		xfer.TransferID = base.ID()
+		xfer.IatDetail = nil
+		require.NoError(t, repo.WriteUserTransfer("moov", xfer))
+
+		found, err = repo.GetTransfer(xfer.TransferID)
+		require.NoError(t, err)
+		require.Nil(t, found.IatDetail)
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+}
+
+func TestRepository__WriteUserTransferEffectiveDate(t *testing.T) {
+	orgID := base.ID()
+	repo := setupSQLiteDB(t)
+
+	xfer := &client.Transfer{
+		TransferID:    base.ID(),
+		Amount:        client.Amount{Currency: "USD", Value: 1245},
+		Description:   "payroll",
+		Status:        client.PENDING,
+		EffectiveDate: "2020-11-19",
+		Created:       time.Now(),
+	}
+	if err := repo.WriteUserTransfer(orgID, xfer); err != nil {
+		t.Fatal(err)
+	}
+
+	tt, err := repo.GetTransfer(xfer.TransferID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tt.EffectiveDate != "2020-11-19" {
+		t.Errorf("EffectiveDate=%q", tt.EffectiveDate)
+	}
+}
+
+func TestRepository__saveTransferLegs(t *testing.T) {
+	orgID := base.ID()
+	repo := setupSQLiteDB(t)
+
+	xfer := writeTransfer(t, orgID, repo)
+	legs := []client.TransferLeg{
+		{Leg: "debit", Status: client.TRANSFERLEGSTATUS_PENDING, Updated: time.Now()},
+		{Leg: "credit", Status: client.TRANSFERLEGSTATUS_HELD, Updated: time.Now()},
+	}
+	require.NoError(t, repo.saveTransferLegs(xfer.TransferID, legs))
+
+	tt, err := repo.GetTransfer(xfer.TransferID)
+	require.NoError(t, err)
+	require.Len(t, tt.Legs, 2)
+	require.Equal(t, "debit", tt.Legs[0].Leg)
+	require.Equal(t, client.TRANSFERLEGSTATUS_HELD, tt.Legs[1].Status)
+
+	changes, err := repo.getTransferHistory(orgID, xfer.TransferID)
+	require.NoError(t, err)
+	require.Equal(t, "creditLeg", changes[len(changes)-1].Field)
+}
+
 func TestRepository__deleteUserTransfer(t *testing.T) {
 	orgID := base.ID()
 	transferID := base.ID()
 	repo := setupSQLiteDB(t)
 
-	if err := repo.deleteUserTransfer(orgID, transferID); err != nil {
+	cancel := client.CancelTransfer{Reason: client.CANCELLATIONREASON_COMPLIANCE, Note: "OFAC match"}
+	if err := repo.deleteUserTransfer(orgID, transferID, cancel); err != nil {
 		t.Fatal(err)
 	}
 
 	// Write a PENDING transfer and delete it
 	xfer := writeTransfer(t, orgID, repo)
-	if err := repo.deleteUserTransfer(orgID, xfer.TransferID); err != nil {
+	if err := repo.deleteUserTransfer(orgID, xfer.TransferID, cancel); err != nil {
 		t.Fatal(err)
 	}
 
+	// The canceled transfer is still readable along with why it was canceled
+	found, err := repo.getUserTransfer(xfer.TransferID, orgID)
+	require.NoError(t, err)
+	require.Equal(t, client.CANCELED, found.Status)
+	require.NotNil(t, found.Cancellation)
+	require.Equal(t, client.CANCELLATIONREASON_COMPLIANCE, found.Cancellation.Reason)
+	require.Equal(t, "OFAC match", found.Cancellation.Note)
+	require.False(t, found.Cancellation.Canceled.IsZero())
+
+	changes, err := repo.getTransferHistory(orgID, xfer.TransferID)
+	require.NoError(t, err)
+	require.Equal(t, "cancelReason", changes[len(changes)-1].Field)
+	require.Equal(t, "compliance", changes[len(changes)-1].NewValue)
+
+	// Canceling again succeeds without changing the reason or recording history
+	retry := client.CancelTransfer{Reason: client.CANCELLATIONREASON_DUPLICATE}
+	require.NoError(t, repo.deleteUserTransfer(orgID, xfer.TransferID, retry))
+
+	found, err = repo.getUserTransfer(xfer.TransferID, orgID)
+	require.NoError(t, err)
+	require.Equal(t, client.CANCELLATIONREASON_COMPLIANCE, found.Cancellation.Reason)
+
+	after, err := repo.getTransferHistory(orgID, xfer.TransferID)
+	require.NoError(t, err)
+	require.Len(t, after, len(changes))
+
 	// Fail to delete a PROCESSED transfer
 	xfer = writeTransfer(t, orgID, repo)
-	if err := repo.UpdateTransferStatus(xfer.TransferID, client.PROCESSED); err != nil {
+	if err := repo.UpdateTransferStatus(xfer.TransferID, client.PROCESSED, history.Admin); err != nil {
 		t.Fatal(err)
 	}
-	if err := repo.deleteUserTransfer(orgID, xfer.TransferID); err != nil {
+	if err := repo.deleteUserTransfer(orgID, xfer.TransferID, cancel); err != nil {
 		if !strings.Contains(err.Error(), "is not in PENDING status") {
 			t.Fatal(err)
 		}
@@ -266,6 +483,102 @@ func saveTraceNumbers(t *testing.T, transfer *client.Transfer, traceNumbers []st
 	}
 }
 
+func TestRepository__customerSuspended(t *testing.T) {
+	t.Parallel()
+
+	check := func(t *testing.T, repo *sqlRepo) {
+		customerID := base.ID()
+		suspended, err := repo.customerSuspended("moov", customerID)
+		require.NoError(t, err)
+		require.False(t, suspended)
+
+		query := `insert into ofac_matches (organization, customer_id, entity_id, sdn_name, match_score, status, matched_at, screened_at) values (?, ?, '1231', 'Jane Doe', 0.99, ?, ?, ?);`
+		_, err = repo.db.Exec(query, "moov", customerID, "suspended", time.Now(), time.Now())
+		require.NoError(t, err)
+
+		suspended, err = repo.customerSuspended("moov", customerID)
+		require.NoError(t, err)
+		require.True(t, suspended)
+
+		// other organizations aren't affected
+		suspended, err = repo.customerSuspended("other", customerID)
+		require.NoError(t, err)
+		require.False(t, suspended)
+
+		_, err = repo.db.Exec(`update ofac_matches set status = 'cleared' where customer_id = ?;`, customerID)
+		require.NoError(t, err)
+
+		suspended, err = repo.customerSuspended("moov", customerID)
+		require.NoError(t, err)
+		require.False(t, suspended)
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+}
+
+func TestRepository__emailVerified(t *testing.T) {
+	t.Parallel()
+
+	check := func(t *testing.T, repo *sqlRepo) {
+		customerID := base.ID()
+		verified, err := repo.emailVerified("moov", customerID, "jane@example.com")
+		require.NoError(t, err)
+		require.False(t, verified)
+
+		query := `insert into email_verifications (organization, customer_id, email, verified_at) values (?, ?, ?, ?);`
+		_, err = repo.db.Exec(query, "moov", customerID, "jane@example.com", time.Now())
+		require.NoError(t, err)
+
+		verified, err = repo.emailVerified("moov", customerID, " Jane@Example.com")
+		require.NoError(t, err)
+		require.True(t, verified)
+
+		// a changed email needs verifying again
+		verified, err = repo.emailVerified("moov", customerID, "jane@moov.io")
+		require.NoError(t, err)
+		require.False(t, verified)
+
+		verified, err = repo.emailVerified("other", customerID, "jane@example.com")
+		require.NoError(t, err)
+		require.False(t, verified)
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+}
+
+func TestRepository__sourceHolds(t *testing.T) {
+	t.Parallel()
+
+	check := func(t *testing.T, repo *sqlRepo) {
+		xfer := writeTransfer(t, "moov", repo)
+		customerID, accountID := xfer.Source.CustomerID, xfer.Source.AccountID
+
+		holds, err := repo.sourceHolds("moov", customerID, accountID)
+		require.NoError(t, err)
+		require.Equal(t, int64(1245), holds)
+
+		// other organizations and accounts aren't held
+		holds, err = repo.sourceHolds("other", customerID, accountID)
+		require.NoError(t, err)
+		require.Equal(t, int64(0), holds)
+
+		holds, err = repo.sourceHolds("moov", customerID, base.ID())
+		require.NoError(t, err)
+		require.Equal(t, int64(0), holds)
+
+		// Transfers which were sent aren't held
+		require.NoError(t, repo.UpdateTransferStatus(xfer.TransferID, client.PROCESSED, history.API))
+		holds, err = repo.sourceHolds("moov", customerID, accountID)
+		require.NoError(t, err)
+		require.Equal(t, int64(0), holds)
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+}
+
 func TestTransfers__SaveReturnCode(t *testing.T) {
 	t.Parallel()
 
@@ -300,7 +613,7 @@ func TestTransfers__LookupTransferFromReturn(t *testing.T) {
 		xfer := writeTransfer(t, orgID, repo)
 
 		// mark transfer as PROCESSED (which is usually set after upload)
-		if err := repo.UpdateTransferStatus(xfer.TransferID, client.PROCESSED); err != nil {
+		if err := repo.UpdateTransferStatus(xfer.TransferID, client.PROCESSED, history.Admin); err != nil {
 			t.Fatal(err)
 		}
 
@@ -327,6 +640,32 @@ func TestTransfers__LookupTransferFromReturn(t *testing.T) {
 	check(t, setupMySQLeDB(t))
 }
 
+func TestTransfers__LookupTransferFromTraceNumber(t *testing.T) {
+	t.Parallel()
+
+	check := func(t *testing.T, repo *sqlRepo) {
+		orgID := base.ID()
+		xfer := writeTransfer(t, orgID, repo)
+		traceNumber := base.ID()[:15]
+		require.NoError(t, repo.saveTraceNumbers(xfer.TransferID, []string{traceNumber}))
+
+		// only processed transfers are found
+		found, err := repo.LookupTransferFromTraceNumber(traceNumber)
+		require.NoError(t, err)
+		require.Nil(t, found)
+
+		require.NoError(t, repo.UpdateTransferStatus(xfer.TransferID, client.PROCESSED, history.Admin))
+
+		found, err = repo.LookupTransferFromTraceNumber(traceNumber)
+		require.NoError(t, err)
+		require.NotNil(t, found)
+		require.Equal(t, xfer.TransferID, found.TransferID)
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+}
+
 func TestStartOfDayAndTomorrow(t *testing.T) {
 	now := time.Now()
 	min, max := startOfDayAndTomorrow(now)
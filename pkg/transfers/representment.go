@@ -0,0 +1,121 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package transfers
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/moov-io/paygate/pkg/client"
+)
+
+// NACHA allows an entry returned for insufficient (R01) or uncollected (R09) funds
+// to be reinitiated twice, within 180 days of the original entry's settlement.
+var representableReturnCodes = []string{"R01", "R09"}
+
+const (
+	maxRepresentments   = 2
+	representmentWindow = 180 * 24 * time.Hour
+)
+
+// representmentChain matches transfers from the same organization which move the same
+// amount between the same accounts as the outer transfers row. Those are treated as
+// attempts of the same entry.
+const representmentChain = `chain.organization = transfers.organization
+and chain.source_customer_id = transfers.source_customer_id and chain.source_account_id = transfers.source_account_id
+and chain.destination_customer_id = transfers.destination_customer_id and chain.destination_account_id = transfers.destination_account_id
+and chain.amount_currency = transfers.amount_currency and chain.amount_value = transfers.amount_value
+and chain.deleted_at is null`
+
+// writeRepresentableFilter appends conditions which only match the latest attempt of an entry
+// that's still eligible for re-presentment.
+func writeRepresentableFilter(query *strings.Builder, now time.Time) []interface{} {
+	codes := "?" + strings.Repeat(",?", len(representableReturnCodes)-1)
+
+	var args []interface{}
+	query.WriteString(fmt.Sprintf("and status = ? and return_code in (%s) ", codes))
+	args = append(args, client.FAILED)
+	for i := range representableReturnCodes {
+		args = append(args, representableReturnCodes[i])
+	}
+
+	// no later attempt has been made
+	query.WriteString(fmt.Sprintf("and not exists (select 1 from transfers chain where %s and chain.created_at > transfers.created_at and chain.status <> ?) ", representmentChain))
+	args = append(args, client.CANCELED)
+
+	// fewer than the allowed attempts have been returned
+	query.WriteString(fmt.Sprintf("and (select count(*) from transfers chain where %s and chain.return_code in (%s) and chain.created_at <= transfers.created_at) <= ? ", representmentChain, codes))
+	for i := range representableReturnCodes {
+		args = append(args, representableReturnCodes[i])
+	}
+	args = append(args, maxRepresentments)
+
+	// the original attempt is within the re-presentment window
+	query.WriteString(fmt.Sprintf("and not exists (select 1 from transfers chain where %s and chain.return_code in (%s) and coalesce(chain.processed_at, chain.created_at) < ?) ", representmentChain, codes))
+	for i := range representableReturnCodes {
+		args = append(args, representableReturnCodes[i])
+	}
+	args = append(args, now.Add(-representmentWindow))
+
+	return args
+}
+
+func (r *sqlRepo) getRepresentment(orgID string, xfer *client.Transfer) (*client.Representment, error) {
+	if xfer == nil {
+		return nil, errors.New("nil Transfer")
+	}
+	codes := "?" + strings.Repeat(",?", len(representableReturnCodes)-1)
+	query := fmt.Sprintf(`select processed_at, created_at from transfers
+where organization = ? and source_customer_id = ? and source_account_id = ? and destination_customer_id = ? and destination_account_id = ?
+and amount_currency = ? and amount_value = ? and return_code in (%s) and created_at <= ? and deleted_at is null
+order by created_at asc`, codes)
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	args := []interface{}{
+		orgID,
+		xfer.Source.CustomerID, xfer.Source.AccountID,
+		xfer.Destination.CustomerID, xfer.Destination.AccountID,
+		xfer.Amount.Currency, xfer.Amount.Value,
+	}
+	for i := range representableReturnCodes {
+		args = append(args, representableReturnCodes[i])
+	}
+	args = append(args, xfer.Created)
+
+	rows, err := stmt.Query(args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := &client.Representment{}
+	for rows.Next() {
+		var processedAt *time.Time
+		var createdAt time.Time
+		if err := rows.Scan(&processedAt, &createdAt); err != nil {
+			return nil, fmt.Errorf("getRepresentment scan: %v", err)
+		}
+		if out.Attempts == 0 {
+			if processedAt != nil {
+				createdAt = *processedAt
+			}
+			out.Deadline = createdAt.Add(representmentWindow)
+		}
+		out.Attempts++
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if remaining := maxRepresentments + 1 - out.Attempts; remaining > 0 {
+		out.Remaining = remaining
+	}
+	return out, nil
+}
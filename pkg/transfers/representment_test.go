@@ -0,0 +1,140 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package transfers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/moov-io/base"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/transfers/history"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepository__representable(t *testing.T) {
+	t.Parallel()
+
+	check := func(t *testing.T, repo *sqlRepo) {
+		orgID := base.ID()
+		now := time.Now().UTC().Truncate(time.Second)
+
+		newTemplate := func() client.Transfer {
+			return client.Transfer{
+				Amount:      client.Amount{Currency: "USD", Value: 1245},
+				Source:      client.Source{CustomerID: base.ID(), AccountID: base.ID()},
+				Destination: client.Destination{CustomerID: base.ID(), AccountID: base.ID()},
+				Description: "payroll",
+				Status:      client.PENDING,
+			}
+		}
+		template := newTemplate()
+		attempt := func(created time.Time, processed time.Time, returnCode string) *client.Transfer {
+			xfer := template
+			xfer.TransferID = base.ID()
+			xfer.Created = created
+			require.NoError(t, repo.WriteUserTransfer(orgID, &xfer))
+			_, err := repo.db.Exec(`update transfers set processed_at = ? where transfer_id = ?`, processed, xfer.TransferID)
+			require.NoError(t, err)
+			if returnCode != "" {
+				require.NoError(t, repo.UpdateTransferStatus(xfer.TransferID, client.FAILED, history.Inbound))
+				require.NoError(t, repo.SaveReturnCode(xfer.TransferID, returnCode))
+			}
+			return &xfer
+		}
+		representable := func() []*client.Transfer {
+			params := readTransferFilterParams(&http.Request{})
+			params.Representable = true
+			xfers, _, err := repo.getTransfers(orgID, params)
+			require.NoError(t, err)
+			return xfers
+		}
+
+		first := attempt(now.Add(-72*time.Hour), now.Add(-71*time.Hour), "R01")
+		xfers := representable()
+		require.Len(t, xfers, 1)
+		require.Equal(t, first.TransferID, xfers[0].TransferID)
+
+		rep, err := repo.getRepresentment(orgID, xfers[0])
+		require.NoError(t, err)
+		require.Equal(t, int32(1), rep.Attempts)
+		require.Equal(t, int32(2), rep.Remaining)
+		require.True(t, rep.Deadline.Equal(now.Add(-71*time.Hour).Add(representmentWindow)))
+
+		// a second attempt replaces the first, but only once it's returned
+		second := attempt(now.Add(-48*time.Hour), now.Add(-47*time.Hour), "")
+		require.Len(t, representable(), 0)
+		require.NoError(t, repo.UpdateTransferStatus(second.TransferID, client.FAILED, history.Inbound))
+		require.NoError(t, repo.SaveReturnCode(second.TransferID, "R09"))
+
+		xfers = representable()
+		require.Len(t, xfers, 1)
+		require.Equal(t, second.TransferID, xfers[0].TransferID)
+		rep, err = repo.getRepresentment(orgID, xfers[0])
+		require.NoError(t, err)
+		require.Equal(t, int32(2), rep.Attempts)
+		require.Equal(t, int32(1), rep.Remaining)
+
+		// filter by return code
+		params := readTransferFilterParams(&http.Request{})
+		params.ReturnCode = "R01"
+		xfers, _, err = repo.getTransfers(orgID, params)
+		require.NoError(t, err)
+		require.Len(t, xfers, 1)
+		require.Equal(t, first.TransferID, xfers[0].TransferID)
+
+		// a third returned attempt exhausts re-presentments
+		attempt(now.Add(-24*time.Hour), now.Add(-23*time.Hour), "R01")
+		require.Len(t, representable(), 0)
+
+		// entries older than 180 days can't be re-presented
+		template = newTemplate()
+		attempt(now.Add(-200*24*time.Hour), now.Add(-199*24*time.Hour), "R01")
+		require.Len(t, representable(), 0)
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+}
+
+func TestRouter__getRepresentableTransfers(t *testing.T) {
+	repo := &MockRepository{
+		Transfers: []*client.Transfer{
+			{
+				TransferID: base.ID(),
+				Status:     client.FAILED,
+			},
+		},
+		Representment: &client.Representment{
+			Attempts:  1,
+			Remaining: 2,
+		},
+	}
+
+	r := mux.NewRouter()
+	router := NewRouter(config.Empty(), repo, nil, mockCustomersClient(), mockDecryptor, mockStrategy, fakePublisher, nil, nil, nil, nil, nil)
+	router.RegisterRoutes(r)
+
+	req := httptest.NewRequest("GET", "/transfers?returnCode=r01&representable=true", nil)
+	req.Header.Set("X-Organization", "moov")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	w.Flush()
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var xfers []*client.Transfer
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&xfers))
+	require.Len(t, xfers, 1)
+	require.NotNil(t, xfers[0].Representment)
+	require.Equal(t, int32(1), xfers[0].Representment.Attempts)
+}
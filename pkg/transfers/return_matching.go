@@ -0,0 +1,224 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package transfers
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/moov-io/paygate/pkg/admin"
+	"github.com/moov-io/paygate/pkg/client"
+)
+
+const (
+	AmbiguousReturnPending   = "pending"
+	AmbiguousReturnResolved  = "resolved"
+	AmbiguousReturnDismissed = "dismissed"
+)
+
+// ReturnCandidate is a processed Transfer with an uploaded entry for the routing and account
+// number of a return whose trace number didn't match.
+type ReturnCandidate struct {
+	Transfer *client.Transfer
+
+	// EntryDetail is the NACHA formatted entry uploaded for the Transfer
+	EntryDetail string
+	Uploaded    time.Time
+}
+
+// LookupReturnCandidates returns processed Transfers with an entry for routingNumber (the first
+// eight digits) and accountNumber which was uploaded between start and end.
+func (r *sqlRepo) LookupReturnCandidates(routingNumber string, accountNumber string, start, end time.Time) ([]*ReturnCandidate, error) {
+	query := `select xf.transfer_id, xf.organization, e.entry_detail, f.uploaded_at from transfers as xf
+inner join transfer_trace_numbers as tn on xf.transfer_id = tn.transfer_id
+inner join uploaded_file_entries as e on tn.trace_number = e.trace_number
+inner join uploaded_files as f on e.file_id = f.file_id
+where substr(e.entry_detail, 4, 8) = ? and trim(substr(e.entry_detail, 13, 17)) = ?
+and f.uploaded_at > ? and f.uploaded_at < ? and xf.status = ? and xf.deleted_at is null
+order by f.uploaded_at asc;`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.Query(routingNumber, accountNumber, start, end, client.PROCESSED)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	type row struct {
+		transferID, orgID string
+		candidate         ReturnCandidate
+	}
+	var found []row
+	for rows.Next() {
+		var rr row
+		if err := rows.Scan(&rr.transferID, &rr.orgID, &rr.candidate.EntryDetail, &rr.candidate.Uploaded); err != nil {
+			return nil, err
+		}
+		found = append(found, rr)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	var out []*ReturnCandidate
+	for i := range found {
+		xfer, err := r.getUserTransfer(found[i].transferID, found[i].orgID)
+		if err != nil {
+			return nil, fmt.Errorf("reading transferID=%s: %v", found[i].transferID, err)
+		}
+		candidate := found[i].candidate
+		candidate.Transfer = xfer
+		out = append(out, &candidate)
+	}
+	return out, nil
+}
+
+// SaveAmbiguousReturn saves the inbound record of a return along with the Transfers it could be
+// for. The return is pending until an admin resolves or dismisses it.
+func (r *sqlRepo) SaveAmbiguousReturn(record *client.InboundRecord, candidates []admin.ReturnCandidate) error {
+	if err := r.SaveInboundRecord(record); err != nil {
+		return err
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	query := `insert into ambiguous_returns (record_id, status, created_at) values (?, ?, ?);`
+	if _, err := tx.Exec(query, record.RecordID, AmbiguousReturnPending, record.Created); err != nil {
+		tx.Rollback()
+		return err
+	}
+	query = `insert into ambiguous_return_candidates (record_id, transfer_id, confidence) values (?, ?, ?);`
+	for i := range candidates {
+		if _, err := tx.Exec(query, record.RecordID, candidates[i].TransferID, candidates[i].Confidence); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// ListAmbiguousReturns returns the newest ambiguous returns, optionally only those with status.
+func (r *sqlRepo) ListAmbiguousReturns(status string) ([]*admin.AmbiguousReturn, error) {
+	query := `select ` + inboundRecordColumns + `, a.status, a.transfer_id, a.resolved_at from ambiguous_returns as a
+inner join inbound_records as r on a.record_id = r.record_id`
+	var args []interface{}
+	if status != "" {
+		query += ` where a.status = ?`
+		args = append(args, status)
+	}
+	return r.queryAmbiguousReturns(query+` order by a.created_at desc limit 100;`, args...)
+}
+
+func (r *sqlRepo) GetAmbiguousReturn(recordID string) (*admin.AmbiguousReturn, error) {
+	query := `select ` + inboundRecordColumns + `, a.status, a.transfer_id, a.resolved_at from ambiguous_returns as a
+inner join inbound_records as r on a.record_id = r.record_id
+where a.record_id = ? limit 1;`
+	returns, err := r.queryAmbiguousReturns(query, recordID)
+	if err != nil || len(returns) == 0 {
+		return nil, err
+	}
+	return returns[0], nil
+}
+
+func (r *sqlRepo) queryAmbiguousReturns(query string, args ...interface{}) ([]*admin.AmbiguousReturn, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []*admin.AmbiguousReturn
+	for rows.Next() {
+		var ret admin.AmbiguousReturn
+		var recordTransferID, transferID *string
+		record := &ret.Record
+		if err := rows.Scan(
+			&record.RecordID, &recordTransferID, &record.Type, &record.Code, &record.TraceNumber, &record.OriginalTrace,
+			&record.ImmediateOrigin, &record.ImmediateDestination, &record.EntryDetail, &record.Addenda, &record.Created,
+			&ret.Status, &transferID, &ret.Resolved,
+		); err != nil {
+			return nil, err
+		}
+		if recordTransferID != nil {
+			record.TransferID = *recordTransferID
+		}
+		if transferID != nil {
+			ret.TransferID = *transferID
+		}
+		out = append(out, &ret)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	for i := range out {
+		candidates, err := r.getReturnCandidates(out[i].Record.RecordID)
+		if err != nil {
+			return nil, err
+		}
+		out[i].Candidates = candidates
+	}
+	return out, nil
+}
+
+func (r *sqlRepo) getReturnCandidates(recordID string) ([]admin.ReturnCandidate, error) {
+	query := `select c.transfer_id, xf.organization, c.confidence from ambiguous_return_candidates as c
+inner join transfers as xf on c.transfer_id = xf.transfer_id
+where c.record_id = ? order by c.confidence desc;`
+	rows, err := r.db.Query(query, recordID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]admin.ReturnCandidate, 0)
+	for rows.Next() {
+		var candidate admin.ReturnCandidate
+		if err := rows.Scan(&candidate.TransferID, &candidate.Organization, &candidate.Confidence); err != nil {
+			return nil, err
+		}
+		out = append(out, candidate)
+	}
+	return out, rows.Err()
+}
+
+// ResolveAmbiguousReturn records the Transfer a pending return was applied to and links its
+// inbound record to the Transfer. An empty transferID dismisses the return instead.
+func (r *sqlRepo) ResolveAmbiguousReturn(recordID string, transferID string, when time.Time) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	status, resolvedTo := AmbiguousReturnDismissed, (*string)(nil)
+	if transferID != "" {
+		status, resolvedTo = AmbiguousReturnResolved, &transferID
+	}
+	query := `update ambiguous_returns set status = ?, transfer_id = ?, resolved_at = ? where record_id = ? and status = ?;`
+	res, err := tx.Exec(query, status, resolvedTo, when, recordID, AmbiguousReturnPending)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		tx.Rollback()
+		return fmt.Errorf("recordID=%s is not a pending ambiguous return", recordID)
+	}
+	if resolvedTo != nil {
+		if _, err := tx.Exec(`update inbound_records set transfer_id = ? where record_id = ?;`, transferID, recordID); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,113 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package transfers
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/moov-io/ach"
+	"github.com/moov-io/base"
+	"github.com/moov-io/paygate/pkg/admin"
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/transfers/files"
+	"github.com/moov-io/paygate/pkg/transfers/history"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepository__LookupReturnCandidates(t *testing.T) {
+	t.Parallel()
+
+	file, err := ach.ReadFile(filepath.Join("..", "..", "testdata", "ppd-debit.ach"))
+	require.NoError(t, err)
+	entry := file.Batches[0].GetEntries()[0]
+	accountNumber := strings.TrimSpace(entry.DFIAccountNumber)
+
+	check := func(t *testing.T, repo *sqlRepo) {
+		xfer := writeTransfer(t, "moov", repo)
+		require.NoError(t, repo.saveTraceNumbers(xfer.TransferID, []string{entry.TraceNumber}))
+		require.NoError(t, repo.UpdateTransferStatus(xfer.TransferID, client.PROCESSED, history.API))
+
+		now := time.Now()
+		require.NoError(t, files.NewRepo(repo.db).RecordUpload(files.Upload{Filename: base.ID() + ".ach", RemoteServer: "sftp.bank.com:22", Uploaded: now}, file))
+
+		start, end := now.Add(-1*time.Hour), now.Add(time.Hour)
+		candidates, err := repo.LookupReturnCandidates(entry.RDFIIdentification, accountNumber, start, end)
+		require.NoError(t, err)
+		require.Len(t, candidates, 1)
+		require.Equal(t, xfer.TransferID, candidates[0].Transfer.TransferID)
+		require.Equal(t, entry.String(), candidates[0].EntryDetail)
+
+		// other accounts and uploads outside the window aren't candidates
+		candidates, err = repo.LookupReturnCandidates(entry.RDFIIdentification, "987654321", start, end)
+		require.NoError(t, err)
+		require.Empty(t, candidates)
+
+		candidates, err = repo.LookupReturnCandidates(entry.RDFIIdentification, accountNumber, end, end.Add(time.Hour))
+		require.NoError(t, err)
+		require.Empty(t, candidates)
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+}
+
+func TestRepository__AmbiguousReturns(t *testing.T) {
+	t.Parallel()
+
+	check := func(t *testing.T, repo *sqlRepo) {
+		first, second := writeTransfer(t, "moov", repo), writeTransfer(t, "moov", repo)
+
+		record := &client.InboundRecord{
+			RecordID:    base.ID(),
+			Type:        "return",
+			Code:        "R01",
+			TraceNumber: "076401250000009",
+			EntryDetail: "626",
+			Addenda:     "799",
+			Created:     time.Now(),
+		}
+		err := repo.SaveAmbiguousReturn(record, []admin.ReturnCandidate{
+			{TransferID: first.TransferID, Confidence: 0.6},
+			{TransferID: second.TransferID, Confidence: 0.95},
+		})
+		require.NoError(t, err)
+
+		returns, err := repo.ListAmbiguousReturns(AmbiguousReturnPending)
+		require.NoError(t, err)
+		require.Len(t, returns, 1)
+		require.Equal(t, record.RecordID, returns[0].Record.RecordID)
+		require.Equal(t, "R01", returns[0].Record.Code)
+		require.Len(t, returns[0].Candidates, 2)
+		require.Equal(t, second.TransferID, returns[0].Candidates[0].TransferID)
+		require.Equal(t, "moov", returns[0].Candidates[0].Organization)
+
+		require.NoError(t, repo.ResolveAmbiguousReturn(record.RecordID, second.TransferID, time.Now()))
+
+		ret, err := repo.GetAmbiguousReturn(record.RecordID)
+		require.NoError(t, err)
+		require.Equal(t, AmbiguousReturnResolved, ret.Status)
+		require.Equal(t, second.TransferID, ret.TransferID)
+		require.Equal(t, second.TransferID, ret.Record.TransferID)
+		require.NotNil(t, ret.Resolved)
+
+		// returns are only resolved once
+		require.Error(t, repo.ResolveAmbiguousReturn(record.RecordID, "", time.Now()))
+
+		returns, err = repo.ListAmbiguousReturns(AmbiguousReturnPending)
+		require.NoError(t, err)
+		require.Empty(t, returns)
+
+		ret, err = repo.GetAmbiguousReturn(base.ID())
+		require.NoError(t, err)
+		require.Nil(t, ret)
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+}
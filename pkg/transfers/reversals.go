@@ -0,0 +1,219 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package transfers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/moov-io/base"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/customers"
+	"github.com/moov-io/paygate/pkg/customers/accounts"
+	"github.com/moov-io/paygate/pkg/hooks"
+	"github.com/moov-io/paygate/pkg/organization"
+	"github.com/moov-io/paygate/pkg/transfers/fundflow"
+	"github.com/moov-io/paygate/pkg/transfers/killswitch"
+	"github.com/moov-io/paygate/pkg/transfers/limiter"
+	"github.com/moov-io/paygate/pkg/transfers/pipeline"
+	"github.com/moov-io/paygate/pkg/util"
+	"github.com/moov-io/paygate/pkg/webhooks"
+	"github.com/moov-io/paygate/x/route"
+
+	"github.com/moov-io/base/log"
+)
+
+const (
+	// reversalBankingDays is how long after settlement NACHA allows a reversing entry
+	// to be made available to the RDFI.
+	reversalBankingDays = 5
+
+	// reversalDescription is the Company Entry Description NACHA requires on reversals.
+	reversalDescription = "REVERSAL"
+)
+
+// reversalDeadline returns the last day a reversal of xfer can be originated, which is
+// five banking days after the Transfer settled.
+func reversalDeadline(xfer *client.Transfer, loc *time.Location) (time.Time, error) {
+	if loc == nil {
+		loc = time.UTC
+	}
+	var settled time.Time
+	switch {
+	case xfer.EffectiveDate != "":
+		when, err := time.ParseInLocation(util.YYMMDDTimeFormat, xfer.EffectiveDate, loc)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid effectiveDate=%q: %v", xfer.EffectiveDate, err)
+		}
+		settled = when
+	case xfer.ProcessedAt != nil:
+		settled = xfer.ProcessedAt.In(loc)
+	default:
+		return time.Time{}, errors.New("unknown settlement date")
+	}
+	return base.NewTime(settled).AddBankingDay(reversalBankingDays).Time, nil
+}
+
+// reversible returns an error when xfer can't be reversed at now.
+func reversible(xfer *client.Transfer, loc *time.Location, now time.Time) error {
+	if xfer.Status != client.PROCESSED {
+		return fmt.Errorf("transferID=%s is %s, only processed transfers can be reversed", xfer.TransferID, xfer.Status)
+	}
+	if xfer.ReversalOf != "" {
+		return fmt.Errorf("transferID=%s is a reversal", xfer.TransferID)
+	}
+	if xfer.ReversedBy != "" {
+		return fmt.Errorf("transferID=%s was already reversed by transferID=%s", xfer.TransferID, xfer.ReversedBy)
+	}
+	deadline, err := reversalDeadline(xfer, loc)
+	if err != nil {
+		return fmt.Errorf("transferID=%s: %v", xfer.TransferID, err)
+	}
+	if loc == nil {
+		loc = time.UTC
+	}
+	if now.In(loc).Format(util.YYMMDDTimeFormat) > deadline.Format(util.YYMMDDTimeFormat) {
+		return fmt.Errorf("transferID=%s can't be reversed after %s", xfer.TransferID, deadline.Format(util.YYMMDDTimeFormat))
+	}
+	return nil
+}
+
+// reversalRequest moves the funds of xfer back to where they came from.
+func reversalRequest(xfer *client.Transfer) client.CreateTransfer {
+	return client.CreateTransfer{
+		Amount: xfer.Amount,
+		Source: client.Source{
+			CustomerID: xfer.Destination.CustomerID,
+			AccountID:  xfer.Destination.AccountID,
+		},
+		Destination: client.Destination{
+			CustomerID: xfer.Source.CustomerID,
+			AccountID:  xfer.Source.AccountID,
+		},
+		Description: reversalDescription,
+		Tags:        xfer.Tags,
+	}
+}
+
+// reverse creates the reversal of a processed Transfer. It's originated like any other
+// Transfer so it's merged and uploaded at the next cutoff.
+func (c *creator) reverse(orgID, userID, requestID string, xfer *client.Transfer, now time.Time) (*client.Transfer, error) {
+	if err := reversible(xfer, c.cfg.ODFI.Cutoffs.Location(), now); err != nil {
+		return nil, fmt.Errorf("reversing transfer: %v", err)
+	}
+	return c.createTransfer(nil, orgID, userID, requestID, reversalRequest(xfer), xfer.TransferID)
+}
+
+func (r *sqlRepo) saveReversal(transferID string, reversalID string) error {
+	query := `insert into transfer_reversals (transfer_id, reversal_id, created_at) values (?, ?, ?);`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(transferID, reversalID, time.Now())
+	return err
+}
+
+// getReversal returns the Transfer reversed by transferID and the reversal of transferID.
+// At most one is non-empty.
+func (r *sqlRepo) getReversal(transferID string) (string, string, error) {
+	query := `select transfer_id, reversal_id from transfer_reversals where transfer_id = ? or reversal_id = ? limit 1;`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return "", "", err
+	}
+	defer stmt.Close()
+
+	var original, reversal string
+	if err := stmt.QueryRow(transferID, transferID).Scan(&original, &reversal); err != nil {
+		if err == sql.ErrNoRows {
+			return "", "", nil
+		}
+		return "", "", err
+	}
+	if original == transferID {
+		return "", reversal, nil
+	}
+	return original, "", nil
+}
+
+// CreateReversal originates a reversal of a processed Transfer within NACHA's five
+// banking day window and links the two Transfers.
+func CreateReversal(
+	cfg *config.Config,
+	repo Repository,
+	orgRepo organization.Repository,
+	customersClient customers.Client,
+	accountDecryptor accounts.Decryptor,
+	fundStrategy fundflow.Strategy,
+	pub pipeline.XferPublisher,
+	limitChecker limiter.Checker,
+	debits *killswitch.Checker,
+	exposure *limiter.Exposure,
+	hookRunner *hooks.Runner,
+	events webhooks.Sender,
+) http.HandlerFunc {
+	c := &creator{
+		cfg:              cfg,
+		repo:             repo,
+		orgRepo:          orgRepo,
+		customersClient:  customersClient,
+		accountDecryptor: accountDecryptor,
+		fundStrategy:     fundStrategy,
+		pub:              pub,
+		limitChecker:     limitChecker,
+		debits:           debits,
+		exposure:         exposure,
+		hookRunner:       hookRunner,
+		events:           events,
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(cfg, w, r)
+
+		transferID := getTransferID(r)
+		orgID, err := repo.GetTransferOrganization(transferID)
+		if err != nil {
+			responder.Problem(err)
+			return
+		}
+		if orgID == "" || orgID != responder.OrganizationID {
+			responder.Problem(fmt.Errorf("transferID=%s not found", transferID))
+			return
+		}
+		xfer, err := repo.GetTransfer(transferID)
+		if err != nil {
+			responder.Problem(err)
+			return
+		}
+		if xfer == nil {
+			responder.Problem(fmt.Errorf("transferID=%s not found", transferID))
+			return
+		}
+
+		reversal, err := c.reverse(responder.OrganizationID, getUserID(r), responder.XRequestID, xfer, time.Now())
+		if err != nil {
+			responder.Problem(err)
+			return
+		}
+
+		cfg.Logger.With(log.Fields{
+			"transferID":    transferID,
+			"correlationID": reversal.CorrelationID,
+		}).Logf("created reversal=%s", reversal.TransferID)
+
+		responder.Respond(func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(reversal)
+		})
+	}
+}
@@ -0,0 +1,126 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package transfers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/moov-io/base"
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReversals__reversible(t *testing.T) {
+	xfer := &client.Transfer{
+		TransferID:    base.ID(),
+		Status:        client.PROCESSED,
+		EffectiveDate: "2020-06-01", // Monday
+	}
+
+	deadline, err := reversalDeadline(xfer, time.UTC)
+	require.NoError(t, err)
+	require.Equal(t, "2020-06-08", deadline.Format("2006-01-02"))
+
+	require.NoError(t, reversible(xfer, time.UTC, time.Date(2020, time.June, 8, 12, 0, 0, 0, time.UTC)))
+	require.Error(t, reversible(xfer, time.UTC, time.Date(2020, time.June, 9, 12, 0, 0, 0, time.UTC)))
+
+	xfer.Status = client.PENDING
+	require.Error(t, reversible(xfer, time.UTC, time.Date(2020, time.June, 2, 12, 0, 0, 0, time.UTC)))
+
+	xfer.Status = client.PROCESSED
+	xfer.ReversedBy = base.ID()
+	require.Error(t, reversible(xfer, time.UTC, time.Date(2020, time.June, 2, 12, 0, 0, 0, time.UTC)))
+
+	// reversals can't be reversed
+	xfer.ReversedBy = ""
+	xfer.ReversalOf = base.ID()
+	require.Error(t, reversible(xfer, time.UTC, time.Date(2020, time.June, 2, 12, 0, 0, 0, time.UTC)))
+
+	// without an effective date settlement is when the Transfer was processed
+	processed := time.Date(2020, time.June, 3, 12, 0, 0, 0, time.UTC)
+	xfer = &client.Transfer{Status: client.PROCESSED, ProcessedAt: &processed}
+	deadline, err = reversalDeadline(xfer, time.UTC)
+	require.NoError(t, err)
+	require.Equal(t, "2020-06-10", deadline.Format("2006-01-02"))
+}
+
+func TestReversals__request(t *testing.T) {
+	xfer := &client.Transfer{
+		Amount:      client.Amount{Currency: "USD", Value: 1245},
+		Source:      client.Source{CustomerID: "src-customer", AccountID: "src-account"},
+		Destination: client.Destination{CustomerID: "dst-customer", AccountID: "dst-account"},
+		Description: "payroll",
+	}
+	req := reversalRequest(xfer)
+	require.Equal(t, xfer.Amount, req.Amount)
+	require.Equal(t, "dst-customer", req.Source.CustomerID)
+	require.Equal(t, "dst-account", req.Source.AccountID)
+	require.Equal(t, "src-customer", req.Destination.CustomerID)
+	require.Equal(t, "src-account", req.Destination.AccountID)
+	require.Equal(t, "REVERSAL", req.Description)
+}
+
+func TestRepository__reversals(t *testing.T) {
+	t.Parallel()
+
+	check := func(t *testing.T, repo *sqlRepo) {
+		xfer := writeTransfer(t, "moov", repo)
+		reversal := writeTransfer(t, "moov", repo)
+
+		require.NoError(t, repo.saveReversal(xfer.TransferID, reversal.TransferID))
+
+		found, err := repo.GetTransfer(xfer.TransferID)
+		require.NoError(t, err)
+		require.Equal(t, reversal.TransferID, found.ReversedBy)
+		require.Empty(t, found.ReversalOf)
+
+		found, err = repo.GetTransfer(reversal.TransferID)
+		require.NoError(t, err)
+		require.Equal(t, xfer.TransferID, found.ReversalOf)
+		require.Empty(t, found.ReversedBy)
+
+		// only one reversal per Transfer
+		require.Error(t, repo.saveReversal(xfer.TransferID, base.ID()))
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+}
+
+func TestRouter__CreateReversal(t *testing.T) {
+	repo := &MockRepository{
+		Organization: "moov",
+		Transfers: []*client.Transfer{
+			{
+				TransferID: base.ID(),
+				Status:     client.PENDING,
+			},
+		},
+	}
+
+	r := mux.NewRouter()
+	NewRouter(config.Empty(), repo, nil, mockCustomersClient(), mockDecryptor, mockStrategy, fakePublisher, nil, nil, nil, nil, nil).RegisterRoutes(r)
+
+	// only processed transfers
+	req := httptest.NewRequest("POST", "/transfers/"+repo.Transfers[0].TransferID+"/reversals", nil)
+	req.Header.Set("X-Organization", "moov")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+
+	// other organization
+	req = httptest.NewRequest("POST", "/transfers/"+repo.Transfers[0].TransferID+"/reversals", nil)
+	req.Header.Set("X-Organization", "other")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	require.Empty(t, repo.Reversals)
+}
@@ -0,0 +1,30 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package transfers
+
+import (
+	"github.com/moov-io/ach"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/transfers/pipeline"
+)
+
+// NewReviewHolder returns a pipeline.Holder which keeps REVIEWABLE Transfers out of merged
+// files until an admin moves them to PENDING or cancels them.
+func NewReviewHolder(repo Repository) pipeline.Holder {
+	return &reviewHolder{repo: repo}
+}
+
+type reviewHolder struct {
+	repo Repository
+}
+
+func (h *reviewHolder) HoldTransfer(transferID string, file *ach.File) (bool, error) {
+	xfer, err := h.repo.GetTransfer(transferID)
+	if err != nil || xfer == nil {
+		return false, err
+	}
+	return xfer.Status == client.REVIEWABLE, nil
+}
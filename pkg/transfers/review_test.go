@@ -0,0 +1,38 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package transfers
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/moov-io/paygate/pkg/client"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReviewHolder(t *testing.T) {
+	repo := &MockRepository{}
+	holder := NewReviewHolder(repo)
+
+	// unknown Transfers aren't held
+	held, err := holder.HoldTransfer("transferID", nil)
+	require.NoError(t, err)
+	require.False(t, held)
+
+	repo.Transfers = []*client.Transfer{{TransferID: "transferID", Status: client.REVIEWABLE}}
+	held, err = holder.HoldTransfer("transferID", nil)
+	require.NoError(t, err)
+	require.True(t, held)
+
+	repo.Transfers[0].Status = client.PENDING
+	held, err = holder.HoldTransfer("transferID", nil)
+	require.NoError(t, err)
+	require.False(t, held)
+
+	repo.Err = errors.New("bad error")
+	_, err = holder.HoldTransfer("transferID", nil)
+	require.Error(t, err)
+}
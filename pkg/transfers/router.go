@@ -8,23 +8,31 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/moov-io/ach"
 	"github.com/moov-io/base"
 	moovhttp "github.com/moov-io/base/http"
+	"github.com/moov-io/base/idempotent"
 
+	"github.com/moov-io/paygate/pkg/achx"
 	"github.com/moov-io/paygate/pkg/client"
 	"github.com/moov-io/paygate/pkg/config"
 	"github.com/moov-io/paygate/pkg/customers"
 	"github.com/moov-io/paygate/pkg/customers/accounts"
+	"github.com/moov-io/paygate/pkg/hooks"
 	"github.com/moov-io/paygate/pkg/organization"
+	"github.com/moov-io/paygate/pkg/transfers/balances"
 	"github.com/moov-io/paygate/pkg/transfers/fundflow"
+	"github.com/moov-io/paygate/pkg/transfers/killswitch"
 	"github.com/moov-io/paygate/pkg/transfers/limiter"
 	"github.com/moov-io/paygate/pkg/transfers/pipeline"
 	"github.com/moov-io/paygate/pkg/util"
+	"github.com/moov-io/paygate/pkg/webhooks"
 	"github.com/moov-io/paygate/x/route"
 
 	"github.com/gorilla/mux"
@@ -40,10 +48,41 @@ type Router struct {
 
 	LimitChecker limiter.Checker
 
-	GetTransfers       http.HandlerFunc
-	CreateTransfer     http.HandlerFunc
-	GetUserTransfer    http.HandlerFunc
-	DeleteUserTransfer http.HandlerFunc
+	GetTransfers        http.HandlerFunc
+	ExportTransfers     http.HandlerFunc
+	CreateTransfer      http.HandlerFunc
+	CreateTransfers     http.HandlerFunc
+	CreateReversal      http.HandlerFunc
+	GetUserTransfer     http.HandlerFunc
+	DeleteUserTransfer  http.HandlerFunc
+	GetTransferHistory  http.HandlerFunc
+	GetTransferReturns  http.HandlerFunc
+	GetTransferFiles    http.HandlerFunc
+	GetTransferTimeline http.HandlerFunc
+	UpdateTransferTags  http.HandlerFunc
+
+	GetQuota http.HandlerFunc
+
+	CreateStatusLink   http.HandlerFunc
+	GetTrackedTransfer http.HandlerFunc
+
+	GetTransferViews   http.HandlerFunc
+	CreateTransferView http.HandlerFunc
+	DeleteTransferView http.HandlerFunc
+
+	GetDebitAuthorizations   http.HandlerFunc
+	CreateDebitAuthorization http.HandlerFunc
+	GetDebitAuthorization    http.HandlerFunc
+	RevokeDebitAuthorization http.HandlerFunc
+
+	GetAccountCorrections   http.HandlerFunc
+	UpdateAccountCorrection http.HandlerFunc
+
+	GetScheduledTransfers   http.HandlerFunc
+	CreateScheduledTransfer http.HandlerFunc
+	GetScheduledTransfer    http.HandlerFunc
+	UpdateScheduledTransfer http.HandlerFunc
+	DeleteScheduledTransfer http.HandlerFunc
 }
 
 func NewRouter(
@@ -54,30 +93,116 @@ func NewRouter(
 	accountDecryptor accounts.Decryptor,
 	fundStrategy fundflow.Strategy,
 	pub pipeline.XferPublisher,
+	debits *killswitch.Checker,
+	exposure *limiter.Exposure,
+	quotas *limiter.Quotas,
+	overrides *limiter.Overrides,
+	events webhooks.Sender,
 ) *Router {
-	limitChecker, err := limiter.New(cfg.Transfers.Limits)
+	limitChecker, err := limiter.New(cfg.Transfers.Limits, overrides)
 	if err != nil {
 		err = cfg.Logger.LogErrorf("problem creating transfer limiter: %v", err).Err()
 		panic(err)
 	}
 	cfg.Logger.Logf("setup %T limit checker", limitChecker)
+
+	hookRunner, err := hooks.New(cfg)
+	if err != nil {
+		err = cfg.Logger.LogErrorf("problem creating lifecycle hooks: %v", err).Err()
+		panic(err)
+	}
+	balanceChecker, err := balances.NewChecker(cfg)
+	if err != nil {
+		err = cfg.Logger.LogErrorf("problem creating balance checker: %v", err).Err()
+		panic(err)
+	}
 	return &Router{
 		Logger:    cfg.Logger,
 		Repo:      repo,
 		Publisher: pub,
 
-		GetTransfers:       GetTransfers(cfg, repo),
-		CreateTransfer:     CreateTransfer(cfg, repo, orgRepo, customersClient, accountDecryptor, fundStrategy, pub, limitChecker),
-		GetUserTransfer:    GetUserTransfer(cfg, repo),
-		DeleteUserTransfer: DeleteUserTransfer(cfg, repo, pub),
+		GetTransfers:        GetTransfers(cfg, repo),
+		ExportTransfers:     ExportTransfers(cfg, repo),
+		CreateTransfer:      CreateTransfer(cfg, repo, orgRepo, customersClient, accountDecryptor, fundStrategy, pub, limitChecker, debits, exposure, balanceChecker, quotas, hookRunner, events),
+		CreateTransfers:     CreateTransferBatch(cfg, repo, orgRepo, customersClient, accountDecryptor, fundStrategy, pub, limitChecker, debits, exposure, balanceChecker, hookRunner, events),
+		CreateReversal:      CreateReversal(cfg, repo, orgRepo, customersClient, accountDecryptor, fundStrategy, pub, limitChecker, debits, exposure, hookRunner, events),
+		GetUserTransfer:     GetUserTransfer(cfg, repo),
+		DeleteUserTransfer:  DeleteUserTransfer(cfg, repo, pub),
+		GetTransferHistory:  GetTransferHistory(cfg, repo),
+		GetTransferReturns:  GetTransferReturns(cfg, repo),
+		GetTransferFiles:    GetTransferFiles(cfg, repo),
+		GetTransferTimeline: GetTransferTimeline(cfg, repo),
+		UpdateTransferTags:  UpdateTransferTags(cfg, repo, orgRepo),
+
+		GetQuota: GetQuota(cfg, quotas),
+
+		CreateStatusLink:   CreateStatusLink(cfg, repo),
+		GetTrackedTransfer: GetTrackedTransfer(cfg, repo),
+
+		GetTransferViews:   GetTransferViews(cfg, repo),
+		CreateTransferView: CreateTransferView(cfg, repo, orgRepo),
+		DeleteTransferView: DeleteTransferView(cfg, repo),
+
+		GetDebitAuthorizations:   GetDebitAuthorizations(cfg, repo),
+		CreateDebitAuthorization: CreateDebitAuthorization(cfg, repo, customersClient),
+		GetDebitAuthorization:    GetDebitAuthorization(cfg, repo),
+		RevokeDebitAuthorization: RevokeDebitAuthorization(cfg, repo),
+
+		GetAccountCorrections:   GetAccountCorrections(cfg, repo),
+		UpdateAccountCorrection: UpdateAccountCorrection(cfg, repo),
+
+		GetScheduledTransfers:   GetScheduledTransfers(cfg, repo),
+		CreateScheduledTransfer: CreateScheduledTransfer(cfg, repo, orgRepo),
+		GetScheduledTransfer:    GetScheduledTransfer(cfg, repo),
+		UpdateScheduledTransfer: UpdateScheduledTransfer(cfg, repo),
+		DeleteScheduledTransfer: DeleteScheduledTransfer(cfg, repo),
 	}
 }
 
 func (c *Router) RegisterRoutes(r *mux.Router) {
 	r.Methods("GET").Path("/transfers").HandlerFunc(c.GetTransfers)
+	r.Methods("GET").Path("/transfers.csv").HandlerFunc(c.ExportTransfers)
 	r.Methods("POST").Path("/transfers").HandlerFunc(c.CreateTransfer)
+
+	// Batches, saved views and schedules are registered before /transfers/{transferID} so
+	// "batch", "views" and "scheduled" aren't read as an ID
+	r.Methods("POST").Path("/transfers/batch").HandlerFunc(c.CreateTransfers)
+
+	r.Methods("GET").Path("/transfers/views").HandlerFunc(c.GetTransferViews)
+	r.Methods("POST").Path("/transfers/views").HandlerFunc(c.CreateTransferView)
+	r.Methods("DELETE").Path("/transfers/views/{viewID}").HandlerFunc(c.DeleteTransferView)
+
+	r.Methods("GET").Path("/transfers/scheduled").HandlerFunc(c.GetScheduledTransfers)
+	r.Methods("POST").Path("/transfers/scheduled").HandlerFunc(c.CreateScheduledTransfer)
+	r.Methods("GET").Path("/transfers/scheduled/{scheduleID}").HandlerFunc(c.GetScheduledTransfer)
+	r.Methods("PUT").Path("/transfers/scheduled/{scheduleID}").HandlerFunc(c.UpdateScheduledTransfer)
+	r.Methods("DELETE").Path("/transfers/scheduled/{scheduleID}").HandlerFunc(c.DeleteScheduledTransfer)
+
 	r.Methods("GET").Path("/transfers/{transferID}").HandlerFunc(c.GetUserTransfer)
 	r.Methods("DELETE").Path("/transfers/{transferID}").HandlerFunc(c.DeleteUserTransfer)
+	r.Methods("GET").Path("/transfers/{transferID}/history").HandlerFunc(c.GetTransferHistory)
+	r.Methods("GET").Path("/transfers/{transferID}/returns").HandlerFunc(c.GetTransferReturns)
+	r.Methods("GET").Path("/transfers/{transferID}/files").HandlerFunc(c.GetTransferFiles)
+	r.Methods("GET").Path("/transfers/{transferID}/timeline").HandlerFunc(c.GetTransferTimeline)
+	r.Methods("POST").Path("/transfers/{transferID}/reversals").HandlerFunc(c.CreateReversal)
+	r.Methods("PUT").Path("/transfers/{transferID}/tags").HandlerFunc(c.UpdateTransferTags)
+	r.Methods("POST").Path("/transfers/{transferID}/status-links").HandlerFunc(c.CreateStatusLink)
+
+	// Remaining capacity under exposure limits and the ODFI's origination caps
+	r.Methods("GET").Path("/quota").HandlerFunc(c.GetQuota)
+
+	// Debits from a Customer's Account are authorized by them
+	r.Methods("GET").Path("/customers/{customerID}/accounts/{accountID}/authorizations").HandlerFunc(c.GetDebitAuthorizations)
+	r.Methods("POST").Path("/customers/{customerID}/accounts/{accountID}/authorizations").HandlerFunc(c.CreateDebitAuthorization)
+	r.Methods("GET").Path("/authorizations/{authorizationID}").HandlerFunc(c.GetDebitAuthorization)
+	r.Methods("POST").Path("/authorizations/{authorizationID}/revoke").HandlerFunc(c.RevokeDebitAuthorization)
+
+	// Notifications of Change received for a Customer's Account
+	r.Methods("GET").Path("/customers/{customerID}/accounts/{accountID}/corrections").HandlerFunc(c.GetAccountCorrections)
+	r.Methods("PUT").Path("/customers/{customerID}/accounts/{accountID}/corrections/{correctionID}").HandlerFunc(c.UpdateAccountCorrection)
+
+	// Status links are signed and read without an organization
+	r.Methods("GET").Path("/track/{token}").HandlerFunc(c.GetTrackedTransfer)
 }
 
 func getTransferID(r *http.Request) string {
@@ -91,6 +216,10 @@ type transferFilterParams struct {
 	Count       int64
 	Skip        int64
 	CustomerIDs []string
+	Tags        []string
+
+	ReturnCode    string
+	Representable bool
 }
 
 func readTransferFilterParams(r *http.Request) transferFilterParams {
@@ -128,6 +257,15 @@ func readTransferFilterParams(r *http.Request) transferFilterParams {
 		if ids := q.Get("customerIDs"); ids != "" {
 			params.CustomerIDs = strings.Split(ids, ",")
 		}
+		if tags := q.Get("tags"); tags != "" {
+			params.Tags = strings.Split(tags, ",")
+		}
+		if code := strings.TrimSpace(q.Get("returnCode")); code != "" {
+			params.ReturnCode = strings.ToUpper(code)
+		}
+		if v, _ := strconv.ParseBool(q.Get("representable")); v {
+			params.Representable = true
+		}
 	}
 	return params
 }
@@ -143,14 +281,35 @@ func GetTransfers(cfg *config.Config, repo Repository) http.HandlerFunc {
 			responder.Problem(err)
 			return
 		}
-		xfers, err := repo.getTransfers(responder.OrganizationID, params)
+		params, err := readRequestedView(repo, responder.OrganizationID, r, params)
 		if err != nil {
 			responder.Problem(err)
 			return
 		}
+		xfers, skipped, err := repo.getTransfers(responder.OrganizationID, params)
+		if err != nil {
+			responder.Problem(err)
+			return
+		}
+		for i := range skipped {
+			cfg.Logger.With(log.Fields{
+				"organization": responder.OrganizationID,
+				"transferID":   skipped[i].ID,
+			}).LogErrorf("omitted unreadable transfer: %v", skipped[i].Err)
+		}
+		if params.Representable {
+			for i := range xfers {
+				xfers[i].Representment, err = repo.getRepresentment(responder.OrganizationID, xfers[i])
+				if err != nil {
+					responder.Problem(fmt.Errorf("transfer %s representment: %v", xfers[i].TransferID, err))
+					return
+				}
+			}
+		}
 
 		responder.Respond(
 			func(w http.ResponseWriter) {
+				omittedWarning(w, skipped)
 				w.WriteHeader(http.StatusOK)
 				json.NewEncoder(w).Encode(xfers)
 			},
@@ -167,112 +326,212 @@ func CreateTransfer(
 	fundStrategy fundflow.Strategy,
 	pub pipeline.XferPublisher,
 	limitChecker limiter.Checker,
+	debits *killswitch.Checker,
+	exposure *limiter.Exposure,
+	balanceChecker *balances.Checker,
+	quotas *limiter.Quotas,
+	hookRunner *hooks.Runner,
+	events webhooks.Sender,
 ) http.HandlerFunc {
+	c := &creator{
+		cfg:              cfg,
+		repo:             repo,
+		orgRepo:          orgRepo,
+		customersClient:  customersClient,
+		accountDecryptor: accountDecryptor,
+		fundStrategy:     fundStrategy,
+		pub:              pub,
+		limitChecker:     limitChecker,
+		debits:           debits,
+		exposure:         exposure,
+		balances:         balanceChecker,
+		hookRunner:       hookRunner,
+		events:           events,
+	}
 	return func(w http.ResponseWriter, r *http.Request) {
-		responder := route.NewResponder(cfg, w, r)
+		// Idempotency keys are kept in the database so a replay returns the original Transfer
+		responder := route.NewResponderWithoutIdempotency(cfg, w, r)
 
-		var req client.CreateTransfer
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			responder.Problem(fmt.Errorf("creating transfer: problem reading request body: %v", err))
-			return
-		}
-		if err := validateTransferRequest(req); err != nil {
-			responder.Problem(fmt.Errorf("creating transfer: invalid transfer request: %v", err))
+		wait, waiting, err := readWaitForMerge(cfg.Transfers.WaitForMerge, r)
+		if err != nil {
+			responder.Problem(err)
 			return
 		}
 
-		transfer := &client.Transfer{
-			TransferID:  base.ID(),
-			Amount:      req.Amount,
-			Source:      req.Source,
-			Destination: req.Destination,
-			Description: req.Description,
-			Status:      client.PENDING,
-			SameDay:     req.SameDay,
-			Created:     time.Now(),
-		}
-
-		// Check transfer limits
-		if limitChecker != nil {
-			if err := limitChecker.Accept(responder.OrganizationID, transfer); err != nil {
-				responder.Problem(err)
-				return
-			}
-		}
-
-		// Save our Transfer to the database
-		if err := repo.WriteUserTransfer(responder.OrganizationID, transfer); err != nil {
-			responder.Problem(fmt.Errorf("creating transfer: error writing user transfr: %v", err))
+		var req client.CreateTransfer
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			responder.Problem(fmt.Errorf("creating transfer: problem reading request body: %v", err))
 			return
 		}
 
-		// According to our strategy create (originate) ACH files to be published somewhere
-		if fundStrategy != nil {
-			source, err := GetFundflowSource(customersClient, accountDecryptor, req.Source, responder.OrganizationID)
-			if err != nil {
-				responder.Problem(fmt.Errorf("creating transfer: error getting fundflow source: %v", err))
-				return
-			}
-			destination, err := GetFundflowDestination(customersClient, accountDecryptor, req.Destination, responder.OrganizationID)
+		userID, key := getUserID(r), r.Header.Get("X-Idempotency-Key")
+		if key != "" {
+			claimed, response, err := repo.claimIdempotencyKey(responder.OrganizationID, userID, key, time.Now())
 			if err != nil {
-				responder.Problem(fmt.Errorf("creating transfer: error getting destination: %v", err))
+				responder.Problem(fmt.Errorf("creating transfer: %v", err))
 				return
 			}
-			if err := customers.AcceptableAccountStatus(&destination.Account); err != nil {
-				responder.Problem(fmt.Errorf("creating transfer: unaccepted account status: %v", err))
+			if !claimed {
+				replayIdempotentResponse(responder, response)
 				return
 			}
+		}
 
-			var companyID string
-			orgConfig, err := orgRepo.GetConfig(responder.OrganizationID)
-			if err != nil {
-				responder.Problem(fmt.Errorf("getting org config: error getting config: %v", err))
+		transfer, err := c.create(responder.Span(), responder.OrganizationID, userID, responder.XRequestID, req)
+		if err != nil {
+			if key != "" {
+				if err := repo.releaseIdempotencyKey(responder.OrganizationID, userID, key); err != nil {
+					cfg.Logger.LogErrorf("problem releasing idempotency key: %v", err)
+				}
+			}
+			var insufficient *balances.InsufficientFundsError
+			if errors.As(err, &insufficient) {
+				insufficientFundsProblem(responder, insufficient)
 				return
 			}
-			if orgConfig != nil {
-				companyID = orgConfig.CompanyIdentification
-			} else {
-				companyID = cfg.ODFI.FileConfig.BatchHeader.CompanyIdentification
+			responder.Problem(err)
+			return
+		}
+
+		cfg.Logger.With(log.Fields{
+			"transferID":    transfer.TransferID,
+			"correlationID": transfer.CorrelationID,
+		}).Log("successfully created transfer")
+
+		if key != "" {
+			if err := repo.saveIdempotentTransfer(responder.OrganizationID, userID, key, transfer); err != nil {
+				cfg.Logger.Set("transferID", transfer.TransferID).LogErrorf("problem saving idempotency key: %v", err)
 			}
+		}
 
-			files, err := fundStrategy.Originate(companyID, transfer, source, destination)
+		status := http.StatusOK
+		if waiting {
+			files, err := waitForMerge(r.Context(), cfg.Transfers.WaitForMerge, repo, responder.OrganizationID, transfer.TransferID, wait)
 			if err != nil {
-				responder.Problem(fmt.Errorf("creating transfer: error originating file: %v", err))
+				responder.Problem(err)
 				return
 			}
-			if err := SaveTraceNumbers(repo, transfer, files); err != nil {
-				responder.Problem(fmt.Errorf("creating transfer: error saving trace numbers: %v", err))
-				return
+			if len(files) == 0 {
+				status = http.StatusAccepted // created but not merged yet
 			}
-			if err := pipeline.PublishFiles(pub, transfer, files); err != nil {
-				responder.Problem(fmt.Errorf("creating transfer: error publishing files: %v", err))
-				return
+			for i := range files {
+				transfer.Files = append(transfer.Files, *files[i])
 			}
-		} else {
-			responder.Problem(errors.New("no fundflow strategy configured, unable to originate ACH files"))
-			return
 		}
 
-		cfg.Logger.Set("transferID", transfer.TransferID).Log("successfully created transfer=%s")
+		snapshot := quotaSnapshot(cfg, quotas, responder.OrganizationID, userID, transfer)
 
 		responder.Respond(func(w http.ResponseWriter) {
-			w.WriteHeader(http.StatusOK)
+			if remaining, ok := limitHeadroom(transfer.Warnings); ok {
+				w.Header().Set(limitRemainingHeader, fmt.Sprintf("%d", remaining))
+			}
+			if snapshot != "" {
+				w.Header().Set(quotaHeader, snapshot)
+			}
+			w.WriteHeader(status)
 			json.NewEncoder(w).Encode(transfer)
 		})
 	}
 }
 
+// replayIdempotentResponse responds with what was originally created with an idempotency key,
+// a Transfer or batch. Keys whose request is still being created are rejected as seen before.
+func replayIdempotentResponse(responder *route.Responder, response []byte) {
+	responder.Respond(func(w http.ResponseWriter) {
+		if len(response) == 0 {
+			idempotent.SeenBefore(w)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write(response)
+	})
+}
+
+// insufficientFundsProblem responds with a 422 Unprocessable Entity describing the source
+// Account's balance so callers can tell the shortfall apart from invalid requests.
+func insufficientFundsProblem(responder *route.Responder, err *balances.InsufficientFundsError) {
+	responder.Respond(func(w http.ResponseWriter) {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(err.Problem())
+	})
+}
+
+// limitRemainingHeader is set on created Transfers which came close to a limit. Its value
+// is the smallest headroom left under any limit.
+const limitRemainingHeader = "X-Limit-Remaining"
+
+func limitHeadroom(warnings []client.LimitWarning) (int64, bool) {
+	if len(warnings) == 0 {
+		return 0, false
+	}
+	remaining := warnings[0].Remaining
+	for i := range warnings {
+		if warnings[i].Remaining < remaining {
+			remaining = warnings[i].Remaining
+		}
+	}
+	return remaining, true
+}
+
+// selectStrategy returns the Strategy for an organization's Tenant and configured funding flow.
+// Strategies which don't support selecting another flow are used as-is.
+func selectStrategy(fundStrategy fundflow.Strategy, orgID string, flow string) (fundflow.Strategy, error) {
+	if tenants, ok := fundStrategy.(fundflow.Tenants); ok {
+		fundStrategy = tenants.ForOrganization(orgID)
+	}
+	selector, ok := fundStrategy.(fundflow.Selector)
+	if !ok {
+		return fundStrategy, nil
+	}
+	f, err := fundflow.ParseFlow(flow)
+	if err != nil {
+		return nil, err
+	}
+	return selector.Select(f)
+}
+
+// SaveTraceNumbers records the trace numbers of each entry in files and adds them to the Transfer.
 func SaveTraceNumbers(repo Repository, xfer *client.Transfer, files []*ach.File) error {
-	var traceNumbers []string
+	numbers := traceNumbers(files)
+	if err := repo.saveTraceNumbers(xfer.TransferID, numbers); err != nil {
+		return err
+	}
+	xfer.TraceNumbers = append(xfer.TraceNumbers, numbers...)
+	return nil
+}
+
+func traceNumbers(files []*ach.File) []string {
+	var out []string
 	for i := range files {
-		for j := range files[i].Batches {
-			entries := files[i].Batches[j].GetEntries()
-			for k := range entries {
-				traceNumbers = append(traceNumbers, entries[k].TraceNumber)
-			}
+		entries := achx.Entries(files[i])
+		for k := range entries {
+			out = append(out, entries[k].TraceNumber)
+		}
+	}
+	return out
+}
+
+// SaveEntryTotals records the SEC code and amounts debited and credited at other
+// financial institutions for a Transfer so accounting periods can be totaled.
+func SaveEntryTotals(repo Repository, odfiRoutingNumber string, xfer *client.Transfer, files []*ach.File) error {
+	secCode, debits, credits := entryTotals(odfiRoutingNumber, files)
+	return repo.saveEntryTotals(xfer.TransferID, secCode, debits, credits)
+}
+
+func entryTotals(odfiRoutingNumber string, files []*ach.File) (string, int64, int64) {
+	var secCode string
+	for i := range files {
+		if files[i] != nil && len(files[i].Batches) > 0 {
+			secCode = files[i].Batches[0].GetHeader().StandardEntryClassCode
+			break
+		}
+		if files[i] != nil && len(files[i].IATBatches) > 0 {
+			secCode = ach.IAT
+			break
 		}
 	}
-	return repo.saveTraceNumbers(xfer.TransferID, traceNumbers)
+	debits, credits := limiter.RemoteAmounts(odfiRoutingNumber, files)
+	return secCode, debits, credits
 }
 
 func validateTransferRequest(req client.CreateTransfer) error {
@@ -288,6 +547,9 @@ func validateTransferRequest(req client.CreateTransfer) error {
 	if req.Description == "" {
 		return errors.New("missing description")
 	}
+	if err := validateIATDetail(req.IatDetail, req.SameDay); err != nil {
+		return fmt.Errorf("iatDetail: %v", err)
+	}
 
 	return nil
 }
@@ -383,19 +645,56 @@ func GetUserTransfer(cfg *config.Config, repo Repository) http.HandlerFunc {
 	}
 }
 
+// GetTransferHistory returns every recorded change of a Transfer in chronological order.
+func GetTransferHistory(cfg *config.Config, repo Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(cfg, w, r)
+
+		transferID := getTransferID(r)
+		changes, err := repo.getTransferHistory(responder.OrganizationID, transferID)
+		if err != nil {
+			responder.Problem(err)
+			return
+		}
+		if len(changes) == 0 {
+			responder.Problem(fmt.Errorf("transferID=%s history not found", transferID))
+			return
+		}
+
+		responder.Respond(func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(changes)
+		})
+	}
+}
+
 func DeleteUserTransfer(cfg *config.Config, repo Repository, pub pipeline.XferPublisher) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		responder := route.NewResponder(cfg, w, r)
 
+		cancel, err := readCancelTransfer(r)
+		if err != nil {
+			responder.Problem(err)
+			return
+		}
+
 		transferID := getTransferID(r)
-		if err := repo.deleteUserTransfer(responder.OrganizationID, transferID); err != nil {
+		if err := repo.deleteUserTransfer(responder.OrganizationID, transferID, cancel); err != nil {
 			responder.Problem(err)
 			return
 		}
+		cfg.Logger.With(log.Fields{
+			"transferID": transferID,
+			"reason":     string(cancel.Reason),
+		}).Log("canceled transfer")
 
+		// The pipeline cancel is sent after the Transfer is canceled, and again when a DELETE is
+		// retried, so a failed publish is recovered by retrying the request.
 		if pub != nil {
 			msg := pipeline.CanceledTransfer{
 				TransferID: transferID,
+				Reason:     string(cancel.Reason),
+				Note:       cancel.Note,
 			}
 			if err := pub.Cancel(msg); err != nil {
 				responder.Problem(err)
@@ -408,3 +707,28 @@ func DeleteUserTransfer(cfg *config.Config, repo Repository, pub pipeline.XferPu
 		})
 	}
 }
+
+const maxCancelNoteLength = 200
+
+// readCancelTransfer reads the optional body of a DELETE request. Transfers canceled without
+// a reason are recorded as a customer request.
+func readCancelTransfer(r *http.Request) (client.CancelTransfer, error) {
+	var cancel client.CancelTransfer
+	if r.Body != nil {
+		if err := json.NewDecoder(r.Body).Decode(&cancel); err != nil && err != io.EOF {
+			return cancel, fmt.Errorf("reading cancellation: %v", err)
+		}
+	}
+	switch cancel.Reason {
+	case "":
+		cancel.Reason = client.CANCELLATIONREASON_CUSTOMER_REQUEST
+	case client.CANCELLATIONREASON_CUSTOMER_REQUEST, client.CANCELLATIONREASON_DUPLICATE, client.CANCELLATIONREASON_FRAUD,
+		client.CANCELLATIONREASON_COMPLIANCE, client.CANCELLATIONREASON_OTHER:
+	default:
+		return cancel, fmt.Errorf("unknown cancellation reason %q", cancel.Reason)
+	}
+	if len(cancel.Note) > maxCancelNoteLength {
+		return cancel, fmt.Errorf("cancellation note is longer than %d characters", maxCancelNoteLength)
+	}
+	return cancel, nil
+}
@@ -6,14 +6,19 @@ package transfers
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/moov-io/ach"
 	"github.com/moov-io/base"
 	moovcustomers "github.com/moov-io/customers/pkg/client"
 
@@ -21,13 +26,19 @@ import (
 	"github.com/moov-io/paygate/pkg/config"
 	"github.com/moov-io/paygate/pkg/customers"
 	"github.com/moov-io/paygate/pkg/customers/accounts"
+	"github.com/moov-io/paygate/pkg/hooks"
 	"github.com/moov-io/paygate/pkg/organization"
 	"github.com/moov-io/paygate/pkg/testclient"
 	"github.com/moov-io/paygate/pkg/transfers/fundflow"
+	"github.com/moov-io/paygate/pkg/transfers/killswitch"
+	"github.com/moov-io/paygate/pkg/transfers/limiter"
 	"github.com/moov-io/paygate/pkg/transfers/pipeline"
 	"github.com/moov-io/paygate/pkg/util"
+	"github.com/moov-io/paygate/pkg/webhooks"
 
+	"github.com/antihax/optional"
 	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
 )
 
 var (
@@ -136,7 +147,7 @@ func TestRouter__getUserTransfers(t *testing.T) {
 	customersClient := mockCustomersClient()
 
 	r := mux.NewRouter()
-	router := NewRouter(config.Empty(), repoWithTransfer, orgRepo, customersClient, mockDecryptor, mockStrategy, fakePublisher)
+	router := NewRouter(config.Empty(), repoWithTransfer, orgRepo, customersClient, mockDecryptor, mockStrategy, fakePublisher, nil, nil, nil, nil, nil)
 	router.RegisterRoutes(r)
 
 	c := testclient.New(t, r)
@@ -156,7 +167,7 @@ func TestRouter__createUserTransfer(t *testing.T) {
 	customersClient := mockCustomersClient()
 
 	r := mux.NewRouter()
-	router := NewRouter(config.Empty(), repoWithTransfer, orgRepo, customersClient, mockDecryptor, mockStrategy, fakePublisher)
+	router := NewRouter(config.Empty(), repoWithTransfer, orgRepo, customersClient, mockDecryptor, mockStrategy, fakePublisher, nil, nil, nil, nil, nil)
 	router.RegisterRoutes(r)
 
 	c := testclient.New(t, r)
@@ -187,13 +198,519 @@ func TestRouter__createUserTransfer(t *testing.T) {
 	if xfer.TransferID == "" {
 		t.Errorf("missing Transfer=%#v", xfer)
 	}
+	if xfer.CorrelationID != xfer.TransferID {
+		t.Errorf("unexpected correlationID=%q", xfer.CorrelationID)
+	}
+
+	// Transfers are correlated with the request which created them
+	xfer, resp, err = c.TransfersApi.AddTransfer(context.TODO(), "organization", opts, &client.AddTransferOpts{
+		XRequestID: optional.NewString("request-id"),
+	})
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, "request-id", xfer.CorrelationID)
+}
+
+func TestRouter__createUserTransferIdempotency(t *testing.T) {
+	repo := &MockRepository{}
+
+	r := mux.NewRouter()
+	router := NewRouter(config.Empty(), repo, orgRepo, mockCustomersClient(), mockDecryptor, mockStrategy, fakePublisher, nil, nil, nil, nil, nil)
+	router.RegisterRoutes(r)
+
+	c := testclient.New(t, r)
+
+	opts := client.CreateTransfer{
+		Amount: client.Amount{
+			Currency: "USD",
+			Value:    1244,
+		},
+		Source: client.Source{
+			CustomerID: sourceCustomerID,
+			AccountID:  sourceAccountID,
+		},
+		Destination: client.Destination{
+			CustomerID: destinationCustomerID,
+			AccountID:  destinationAccountID,
+		},
+		Description: "test transfer",
+	}
+	key := &client.AddTransferOpts{
+		XIdempotencyKey: optional.NewString(base.ID()),
+	}
+	xfer, resp, err := c.TransfersApi.AddTransfer(context.TODO(), "organization", opts, key)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	// sending the key again returns the same Transfer without creating another
+	replay, resp, err := c.TransfersApi.AddTransfer(context.TODO(), "organization", opts, key)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, xfer.TransferID, replay.TransferID)
+	require.Len(t, repo.TransferKeys, 1)
+
+	// keys of Transfers which weren't created can be sent again
+	key.XIdempotencyKey = optional.NewString(base.ID())
+	opts.Amount.Value = 0
+	_, resp, err = c.TransfersApi.AddTransfer(context.TODO(), "organization", opts, key)
+	require.Error(t, err)
+	resp.Body.Close()
+	_, exists := repo.TransferKeys[key.XIdempotencyKey.Value()]
+	require.False(t, exists)
+
+	opts.Amount.Value = 1244
+	retried, resp, err := c.TransfersApi.AddTransfer(context.TODO(), "organization", opts, key)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.NotEqual(t, xfer.TransferID, retried.TransferID)
+}
+
+func TestRouter__createUserTransferDebitsBlocked(t *testing.T) {
+	file, err := ach.ReadFile(filepath.Join("..", "..", "testdata", "ppd-debit.ach"))
+	require.NoError(t, err)
+
+	cfg := config.Empty()
+	cfg.ODFI.RoutingNumber = "076401251"
+	cfg.Transfers.KillSwitch.Organizations = []string{"organization"}
+	debits := killswitch.NewChecker(cfg, &killswitch.MockRepository{})
+
+	strategy := &fundflow.MockStrategy{Files: []*ach.File{file}}
+	repo := &MockRepository{}
+
+	r := mux.NewRouter()
+	router := NewRouter(cfg, repo, orgRepo, mockCustomersClient(), mockDecryptor, strategy, fakePublisher, debits, nil, nil, nil, nil)
+	router.RegisterRoutes(r)
+
+	c := testclient.New(t, r)
+
+	opts := client.CreateTransfer{
+		Amount: client.Amount{
+			Currency: "USD",
+			Value:    10500,
+		},
+		Source: client.Source{
+			CustomerID: sourceCustomerID,
+			AccountID:  sourceAccountID,
+		},
+		Destination: client.Destination{
+			CustomerID: destinationCustomerID,
+			AccountID:  destinationAccountID,
+		},
+		Description: "test transfer",
+	}
+	_, resp, err := c.TransfersApi.AddTransfer(context.TODO(), "organization", opts, nil)
+	require.Error(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	require.Contains(t, err.(client.GenericOpenAPIError).Model().(client.Error).Error, "debit transfers are blocked")
+
+	// other organizations aren't blocked
+	_, resp, err = c.TransfersApi.AddTransfer(context.TODO(), "other", opts, nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+}
+
+func TestRouter__createUserTransferCustomerSuspended(t *testing.T) {
+	repo := &MockRepository{
+		Suspended: []string{destinationCustomerID},
+	}
+
+	r := mux.NewRouter()
+	router := NewRouter(config.Empty(), repo, orgRepo, mockCustomersClient(), mockDecryptor, mockStrategy, fakePublisher, nil, nil, nil, nil, nil)
+	router.RegisterRoutes(r)
+
+	c := testclient.New(t, r)
+
+	opts := client.CreateTransfer{
+		Amount: client.Amount{
+			Currency: "USD",
+			Value:    1244,
+		},
+		Source: client.Source{
+			CustomerID: sourceCustomerID,
+			AccountID:  sourceAccountID,
+		},
+		Destination: client.Destination{
+			CustomerID: destinationCustomerID,
+			AccountID:  destinationAccountID,
+		},
+		Description: "test transfer",
+	}
+	_, resp, err := c.TransfersApi.AddTransfer(context.TODO(), "organization", opts, nil)
+	require.Error(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	require.Contains(t, err.(client.GenericOpenAPIError).Model().(client.Error).Error, "customer is suspended after an OFAC match")
+}
+
+func TestRouter__createUserTransferCustomerDeceased(t *testing.T) {
+	repo := &MockRepository{
+		Deceased: []string{sourceCustomerID},
+	}
+
+	r := mux.NewRouter()
+	router := NewRouter(config.Empty(), repo, orgRepo, mockCustomersClient(), mockDecryptor, mockStrategy, fakePublisher, nil, nil, nil, nil, nil)
+	router.RegisterRoutes(r)
+
+	c := testclient.New(t, r)
+
+	opts := client.CreateTransfer{
+		Amount: client.Amount{
+			Currency: "USD",
+			Value:    1244,
+		},
+		Source: client.Source{
+			CustomerID: sourceCustomerID,
+			AccountID:  sourceAccountID,
+		},
+		Destination: client.Destination{
+			CustomerID: destinationCustomerID,
+			AccountID:  destinationAccountID,
+		},
+		Description: "test transfer",
+	}
+	_, resp, err := c.TransfersApi.AddTransfer(context.TODO(), "organization", opts, nil)
+	require.Error(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	require.Contains(t, err.(client.GenericOpenAPIError).Model().(client.Error).Error, "customer is deceased after a death notification")
+}
+
+func TestCreator__checkVerifiedEmail(t *testing.T) {
+	file, err := ach.ReadFile(filepath.Join("..", "..", "testdata", "ppd-debit.ach"))
+	require.NoError(t, err)
+
+	cfg := config.Empty()
+	cfg.ODFI.RoutingNumber = "076401251"
+	repo := &MockRepository{}
+	c := &creator{cfg: cfg, repo: repo}
+
+	pending := &pendingTransfer{
+		transfer: &client.Transfer{
+			Source: client.Source{CustomerID: sourceCustomerID, AccountID: sourceAccountID},
+		},
+		source: fundflow.Source{
+			Customer: moovcustomers.Customer{CustomerID: sourceCustomerID, Email: "jane@example.com"},
+		},
+		files: []*ach.File{file},
+	}
+
+	// unverified emails are only rejected when required
+	require.NoError(t, c.checkVerifiedEmail("moov", pending))
+	cfg.Validation.Emails = &config.EmailVerification{RequiredForDebits: true}
+	err = c.checkVerifiedEmail("moov", pending)
+	require.True(t, errors.Is(err, ErrUnverifiedEmail))
+	require.True(t, blockedTransfer(err))
+
+	repo.Verified = []string{sourceCustomerID}
+	require.NoError(t, c.checkVerifiedEmail("moov", pending))
+
+	// Transfers without debits don't need a verified email
+	repo.Verified = nil
+	pending.files = nil
+	require.NoError(t, c.checkVerifiedEmail("moov", pending))
+}
+
+func TestRouter__createUserTransferOverExposure(t *testing.T) {
+	file, err := ach.ReadFile(filepath.Join("..", "..", "testdata", "ppd-debit.ach"))
+	require.NoError(t, err)
+
+	cfg := config.Empty()
+	cfg.ODFI.RoutingNumber = "076401251"
+	cfg.Transfers.Limits.Exposure = &config.ExposureLimits{
+		Debits: config.ExposureLimit{
+			Organization: 15000,
+		},
+	}
+	exposure, err := limiter.NewExposure(cfg, &limiter.MockRepository{}, nil)
+	require.NoError(t, err)
+
+	strategy := &fundflow.MockStrategy{Files: []*ach.File{file}}
+	repo := &MockRepository{}
+
+	r := mux.NewRouter()
+	quotas := limiter.NewQuotas(cfg, exposure, nil)
+	router := NewRouter(cfg, repo, orgRepo, mockCustomersClient(), mockDecryptor, strategy, fakePublisher, nil, exposure, quotas, nil, nil)
+	router.RegisterRoutes(r)
+
+	c := testclient.New(t, r)
+
+	opts := client.CreateTransfer{
+		Amount: client.Amount{
+			Currency: "USD",
+			Value:    10500,
+		},
+		Source: client.Source{
+			CustomerID: sourceCustomerID,
+			AccountID:  sourceAccountID,
+		},
+		Destination: client.Destination{
+			CustomerID: destinationCustomerID,
+			AccountID:  destinationAccountID,
+		},
+		Description: "test transfer",
+	}
+	_, resp, err := c.TransfersApi.AddTransfer(context.TODO(), "organization", opts, nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, "debit=4500", resp.Header.Get("X-Quota-Remaining"))
+
+	// the second debit puts the organization over its limit
+	_, resp, err = c.TransfersApi.AddTransfer(context.TODO(), "organization", opts, nil)
+	require.Error(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	require.Contains(t, err.(client.GenericOpenAPIError).Model().(client.Error).Error, limiter.ErrDebitExposure.Error())
+}
+
+func TestRouter__createUserTransferInsufficientFunds(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"available": 2000}`))
+	}))
+	defer server.Close()
+
+	cfg := config.Empty()
+	cfg.Transfers.Balances = &config.Balances{
+		Endpoint: server.URL,
+		Strict:   true,
+	}
+
+	// the pending transfer of $12.44 is held against the source account
+	repo := &MockRepository{
+		Transfers: []*client.Transfer{
+			{
+				TransferID: base.ID(),
+				Amount:     client.Amount{Currency: "USD", Value: 1244},
+				Source:     client.Source{CustomerID: sourceCustomerID, AccountID: sourceAccountID},
+				Status:     client.PENDING,
+			},
+		},
+	}
+
+	r := mux.NewRouter()
+	NewRouter(cfg, repo, orgRepo, mockCustomersClient(), mockDecryptor, mockStrategy, fakePublisher, nil, nil, nil, nil, nil).RegisterRoutes(r)
+	c := testclient.New(t, r)
+
+	opts := client.CreateTransfer{
+		Amount: client.Amount{
+			Currency: "USD",
+			Value:    1000,
+		},
+		Source: client.Source{
+			CustomerID: sourceCustomerID,
+			AccountID:  sourceAccountID,
+		},
+		Destination: client.Destination{
+			CustomerID: destinationCustomerID,
+			AccountID:  destinationAccountID,
+		},
+		Description: "test transfer",
+	}
+	_, resp, err := c.TransfersApi.AddTransfer(context.TODO(), "organization", opts, nil)
+	require.Error(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+
+	var problem client.InsufficientFunds
+	require.NoError(t, json.Unmarshal(err.(client.GenericOpenAPIError).Body(), &problem))
+	require.Equal(t, int64(2000), problem.Available)
+	require.Equal(t, int64(1244), problem.Holds)
+	require.Equal(t, int64(1000), problem.Required)
+	require.Contains(t, problem.Error, "insufficient funds")
+}
+
+func TestRouter__createUserTransferLimitWarnings(t *testing.T) {
+	customersClient := mockCustomersClient()
+
+	cfg := config.Empty()
+	cfg.Transfers.Limits.Fixed = &config.FixedLimits{
+		SoftLimit:   1300,
+		HardLimit:   5000,
+		WarnPercent: 90,
+	}
+
+	events := &webhooks.MockSender{}
+
+	r := mux.NewRouter()
+	router := NewRouter(cfg, repoWithTransfer, orgRepo, customersClient, mockDecryptor, mockStrategy, fakePublisher, nil, nil, nil, nil, events)
+	router.RegisterRoutes(r)
+
+	c := testclient.New(t, r)
+
+	opts := client.CreateTransfer{
+		Amount: client.Amount{
+			Currency: "USD",
+			Value:    1244,
+		},
+		Source: client.Source{
+			CustomerID: sourceCustomerID,
+			AccountID:  sourceAccountID,
+		},
+		Destination: client.Destination{
+			CustomerID: destinationCustomerID,
+			AccountID:  destinationAccountID,
+		},
+		Description: "test transfer",
+	}
+	xfer, resp, err := c.TransfersApi.AddTransfer(context.TODO(), "organization", opts, nil)
+	if err != nil {
+		bs, _ := ioutil.ReadAll(resp.Body)
+		t.Fatalf("error=%v \n body=%s", err, string(bs))
+	}
+	defer resp.Body.Close()
+
+	require.Len(t, xfer.Warnings, 1)
+	require.Equal(t, "soft", xfer.Warnings[0].Limit)
+	require.Equal(t, int64(56), xfer.Warnings[0].Remaining)
+	require.Equal(t, "56", resp.Header.Get("X-Limit-Remaining"))
+
+	require.Len(t, events.Events, 1)
+	require.Equal(t, webhooks.EventTransferLimitsNearing, events.Events[0].Type)
+	update, ok := events.Events[0].Data.(webhooks.TransferUpdate)
+	require.True(t, ok)
+	require.Equal(t, xfer.TransferID, update.TransferID)
+	require.Len(t, update.Warnings, 1)
+}
+
+func TestRouter__createUserTransferReviewable(t *testing.T) {
+	customersClient := mockCustomersClient()
+
+	cfg := config.Empty()
+	cfg.Transfers.Limits.Fixed = &config.FixedLimits{
+		SoftLimit: 1000,
+		HardLimit: 5000,
+	}
+
+	r := mux.NewRouter()
+	router := NewRouter(cfg, repoWithTransfer, orgRepo, customersClient, mockDecryptor, mockStrategy, fakePublisher, nil, nil, nil, nil, nil)
+	router.RegisterRoutes(r)
+
+	c := testclient.New(t, r)
+
+	opts := client.CreateTransfer{
+		Amount: client.Amount{
+			Currency: "USD",
+			Value:    1244,
+		},
+		Source: client.Source{
+			CustomerID: sourceCustomerID,
+			AccountID:  sourceAccountID,
+		},
+		Destination: client.Destination{
+			CustomerID: destinationCustomerID,
+			AccountID:  destinationAccountID,
+		},
+		Description: "test transfer",
+	}
+	xfer, resp, err := c.TransfersApi.AddTransfer(context.TODO(), "organization", opts, nil)
+	if err != nil {
+		bs, _ := ioutil.ReadAll(resp.Body)
+		t.Fatalf("error=%v \n body=%s", err, string(bs))
+	}
+	defer resp.Body.Close()
+
+	// Transfers over the soft limit are created for manual review
+	require.Equal(t, client.REVIEWABLE, xfer.Status)
+
+	// and rejected over the hard limit
+	opts.Amount.Value = 5001
+	_, resp, err = c.TransfersApi.AddTransfer(context.TODO(), "organization", opts, nil)
+	require.Error(t, err)
+	resp.Body.Close()
+}
+
+func TestRouter__createUserTransferHooks(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req hooks.Request
+		json.NewDecoder(r.Body).Decode(&req)
+
+		if req.Transfer.Amount.Value > 5000 {
+			json.NewEncoder(w).Encode(hooks.Response{Reject: true, Reason: "amount needs approval"})
+			return
+		}
+		json.NewEncoder(w).Encode(hooks.Response{Description: "enriched: " + req.Transfer.Description})
+	}))
+	defer server.Close()
+
+	cfg := config.Empty()
+	cfg.Hooks = []config.Hook{
+		{Name: "rules", Point: config.HookPreTransferCreate, Endpoint: server.URL},
+	}
+
+	r := mux.NewRouter()
+	router := NewRouter(cfg, repoWithTransfer, orgRepo, mockCustomersClient(), mockDecryptor, mockStrategy, fakePublisher, nil, nil, nil, nil, nil)
+	router.RegisterRoutes(r)
+
+	c := testclient.New(t, r)
+
+	opts := client.CreateTransfer{
+		Amount: client.Amount{
+			Currency: "USD",
+			Value:    1244,
+		},
+		Source: client.Source{
+			CustomerID: sourceCustomerID,
+			AccountID:  sourceAccountID,
+		},
+		Destination: client.Destination{
+			CustomerID: destinationCustomerID,
+			AccountID:  destinationAccountID,
+		},
+		Description: "test transfer",
+	}
+	xfer, resp, err := c.TransfersApi.AddTransfer(context.TODO(), "organization", opts, nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, "enriched: test transfer", xfer.Description)
+
+	opts.Amount.Value = 10000
+	_, resp, err = c.TransfersApi.AddTransfer(context.TODO(), "organization", opts, nil)
+	require.Error(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	require.Contains(t, err.(client.GenericOpenAPIError).Model().(client.Error).Error, "amount needs approval")
+}
+
+func TestRouter__selectStrategy(t *testing.T) {
+	strategy, err := selectStrategy(mockStrategy, "moov", "twoLeg")
+	require.NoError(t, err)
+	require.Equal(t, mockStrategy, strategy)
+
+	cfg := config.Empty()
+	cfg.ODFI.Tenants = []config.Tenant{
+		{
+			Name:          "acme",
+			Organizations: []string{"acme"},
+			Settlement: &config.Settlement{
+				AccountNumber: "123",
+				AccountType:   "checking",
+			},
+		},
+	}
+	strategies := fundflow.NewStrategies(cfg.Logger, cfg.ODFI, nil)
+
+	strategy, err = selectStrategy(strategies, "moov", "")
+	require.NoError(t, err)
+	require.IsType(t, &fundflow.FirstParty{}, strategy)
+
+	_, err = selectStrategy(strategies, "moov", "twoLeg")
+	require.Error(t, err)
+
+	_, err = selectStrategy(strategies, "moov", "other")
+	require.Error(t, err)
+
+	// the tenant has a settlement account
+	strategy, err = selectStrategy(strategies, "acme", "twoLeg")
+	require.NoError(t, err)
+	require.IsType(t, &fundflow.TwoLeg{}, strategy)
 }
 
 func TestRouter__createUserTransfersInvalidAmount(t *testing.T) {
 	customersClient := mockCustomersClient()
 
 	r := mux.NewRouter()
-	router := NewRouter(config.Empty(), repoWithTransfer, orgRepo, customersClient, mockDecryptor, mockStrategy, fakePublisher)
+	router := NewRouter(config.Empty(), repoWithTransfer, orgRepo, customersClient, mockDecryptor, mockStrategy, fakePublisher, nil, nil, nil, nil, nil)
 	router.RegisterRoutes(r)
 
 	c := testclient.New(t, r)
@@ -219,7 +736,7 @@ func TestRouter__createUserTransferMissingFundflowStrategy(t *testing.T) {
 	customersClient := mockCustomersClient()
 
 	r := mux.NewRouter()
-	router := NewRouter(config.Empty(), repoWithTransfer, orgRepo, customersClient, mockDecryptor, nil, fakePublisher)
+	router := NewRouter(config.Empty(), repoWithTransfer, orgRepo, customersClient, mockDecryptor, nil, fakePublisher, nil, nil, nil, nil, nil)
 	router.RegisterRoutes(r)
 
 	c := testclient.New(t, r)
@@ -259,7 +776,7 @@ func TestRouter__MissingSource(t *testing.T) {
 	customersClient := mockCustomersClient()
 
 	r := mux.NewRouter()
-	router := NewRouter(config.Empty(), repoWithTransfer, orgRepo, customersClient, mockDecryptor, mockStrategy, fakePublisher)
+	router := NewRouter(config.Empty(), repoWithTransfer, orgRepo, customersClient, mockDecryptor, mockStrategy, fakePublisher, nil, nil, nil, nil, nil)
 	router.RegisterRoutes(r)
 
 	c := testclient.New(t, r)
@@ -288,7 +805,7 @@ func TestRouter__MissingDestination(t *testing.T) {
 	customersClient := mockCustomersClient()
 
 	r := mux.NewRouter()
-	router := NewRouter(config.Empty(), repoWithTransfer, orgRepo, customersClient, mockDecryptor, mockStrategy, fakePublisher)
+	router := NewRouter(config.Empty(), repoWithTransfer, orgRepo, customersClient, mockDecryptor, mockStrategy, fakePublisher, nil, nil, nil, nil, nil)
 	router.RegisterRoutes(r)
 
 	c := testclient.New(t, r)
@@ -351,7 +868,7 @@ func TestRouter__getUserTransfer(t *testing.T) {
 	customersClient := mockCustomersClient()
 
 	r := mux.NewRouter()
-	router := NewRouter(config.Empty(), repoWithTransfer, orgRepo, customersClient, mockDecryptor, mockStrategy, fakePublisher)
+	router := NewRouter(config.Empty(), repoWithTransfer, orgRepo, customersClient, mockDecryptor, mockStrategy, fakePublisher, nil, nil, nil, nil, nil)
 	router.RegisterRoutes(r)
 
 	c := testclient.New(t, r)
@@ -371,7 +888,7 @@ func TestRouter__deleteUserTransfer(t *testing.T) {
 	customersClient := mockCustomersClient()
 
 	r := mux.NewRouter()
-	router := NewRouter(config.Empty(), repoWithTransfer, orgRepo, customersClient, mockDecryptor, mockStrategy, fakePublisher)
+	router := NewRouter(config.Empty(), repoWithTransfer, orgRepo, customersClient, mockDecryptor, mockStrategy, fakePublisher, nil, nil, nil, nil, nil)
 	router.RegisterRoutes(r)
 
 	c := testclient.New(t, r)
@@ -381,4 +898,87 @@ func TestRouter__deleteUserTransfer(t *testing.T) {
 		t.Fatal(err)
 	}
 	resp.Body.Close()
+
+	// cancel with a reason
+	opts := &client.DeleteTransferByIDOpts{
+		CancelTransfer: optional.NewInterface(client.CancelTransfer{
+			Reason: client.CANCELLATIONREASON_FRAUD,
+			Note:   "reported by customer",
+		}),
+	}
+	resp, err = c.TransfersApi.DeleteTransferByID(context.TODO(), "fraudulent", "organization", opts)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	msg := fakePublisher.Cancels["fraudulent"]
+	require.Equal(t, "fraud", msg.Reason)
+	require.Equal(t, "reported by customer", msg.Note)
+}
+
+func TestRouter__deleteUserTransferReason(t *testing.T) {
+	r := mux.NewRouter()
+	router := NewRouter(config.Empty(), repoWithTransfer, orgRepo, mockCustomersClient(), mockDecryptor, mockStrategy, fakePublisher, nil, nil, nil, nil, nil)
+	router.RegisterRoutes(r)
+
+	body := strings.NewReader(`{"reason": "bored"}`)
+	req := httptest.NewRequest("DELETE", "/transfers/transferID", body)
+	req.Header.Set("X-Organization", "moov")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	w.Flush()
+
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestReadCancelTransfer(t *testing.T) {
+	req := httptest.NewRequest("DELETE", "/transfers/transferID", nil)
+	cancel, err := readCancelTransfer(req)
+	require.NoError(t, err)
+	require.Equal(t, client.CANCELLATIONREASON_CUSTOMER_REQUEST, cancel.Reason)
+
+	req = httptest.NewRequest("DELETE", "/transfers/transferID", strings.NewReader(`{"reason": "duplicate"}`))
+	cancel, err = readCancelTransfer(req)
+	require.NoError(t, err)
+	require.Equal(t, client.CANCELLATIONREASON_DUPLICATE, cancel.Reason)
+
+	req = httptest.NewRequest("DELETE", "/transfers/transferID", strings.NewReader(`{"note": "`+strings.Repeat("a", 201)+`"}`))
+	_, err = readCancelTransfer(req)
+	require.Error(t, err)
+}
+
+func TestRouter__getTransferHistory(t *testing.T) {
+	repo := &MockRepository{
+		History: []*client.TransferChange{
+			{Field: "status", NewValue: "pending", Actor: "api", Created: time.Now()},
+			{Field: "status", OldValue: "pending", NewValue: "processed", Actor: "pipeline", Created: time.Now()},
+		},
+	}
+
+	r := mux.NewRouter()
+	router := NewRouter(config.Empty(), repo, orgRepo, mockCustomersClient(), mockDecryptor, mockStrategy, fakePublisher, nil, nil, nil, nil, nil)
+	router.RegisterRoutes(r)
+
+	req := httptest.NewRequest("GET", fmt.Sprintf("/transfers/%s/history", base.ID()), nil)
+	req.Header.Set("X-Organization", "moov")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	w.Flush()
+
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var changes []client.TransferChange
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&changes))
+	require.Len(t, changes, 2)
+	require.Equal(t, "pipeline", changes[1].Actor)
+
+	// unknown transfer
+	repo.History = nil
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	w.Flush()
+
+	require.Equal(t, http.StatusBadRequest, w.Code)
 }
@@ -0,0 +1,102 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package transfers
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/moov-io/base"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/util"
+)
+
+// decideSameDay chooses same-day processing for a Transfer from an organization which prefers it.
+// The Transfer is sent same-day when it's created on a banking day before the same-day cutoff for
+// its RDFI and its amount is within the same-day limit. Otherwise the decision records why it wasn't.
+func decideSameDay(cfg *config.SameDay, loc *time.Location, now time.Time, routingNumber string, req client.CreateTransfer) *client.SameDayDecision {
+	notSelected := func(reason string) *client.SameDayDecision {
+		return &client.SameDayDecision{Reason: reason}
+	}
+	if req.EffectiveDate != "" {
+		return notSelected("effectiveDate was requested")
+	}
+	if cfg == nil {
+		return notSelected("same-day processing is not enabled")
+	}
+	if loc == nil {
+		loc = time.UTC
+	}
+	now = now.In(loc)
+
+	if !base.NewTime(now).IsBankingDay() {
+		return notSelected("created on a non-banking day")
+	}
+	if err := checkSameDayCutoff(cfg, now, routingNumber); err != nil {
+		return notSelected(err.Error())
+	}
+	if err := checkSameDayLimit(cfg, req.Amount); err != nil {
+		return notSelected(err.Error())
+	}
+	return &client.SameDayDecision{Selected: true}
+}
+
+// checkSameDay rejects Transfers requested same-day which are over the same-day limit or
+// created on a banking day after the same-day cutoff for their RDFI. Nothing is checked
+// when same-day processing isn't configured.
+func checkSameDay(cfg *config.SameDay, loc *time.Location, now time.Time, routingNumber string, amount client.Amount) error {
+	if cfg == nil {
+		return nil
+	}
+	if loc == nil {
+		loc = time.UTC
+	}
+	now = now.In(loc)
+
+	if err := checkSameDayLimit(cfg, amount); err != nil {
+		return err
+	}
+	if base.NewTime(now).IsBankingDay() {
+		return checkSameDayCutoff(cfg, now, routingNumber)
+	}
+	return nil
+}
+
+func checkSameDayCutoff(cfg *config.SameDay, now time.Time, routingNumber string) error {
+	cutoff, err := cfg.CutoffFor(routingNumber, now)
+	if err != nil {
+		return err
+	}
+	if !now.Before(cutoff) {
+		return fmt.Errorf("created after the same-day cutoff of %s", cutoff.Format("15:04"))
+	}
+	return nil
+}
+
+func checkSameDayLimit(cfg *config.SameDay, amount client.Amount) error {
+	if limit := cfg.Limit(); int64(amount.Value) > limit {
+		return fmt.Errorf("amount exceeds the same-day limit of %d", limit)
+	}
+	return nil
+}
+
+// applySameDayPreference sets SameDay and today's EffectiveDate on transfer when its
+// organization prefers same-day processing and it's possible.
+func applySameDayPreference(cfg *config.Config, orgConfig *client.OrganizationConfiguration, now time.Time, routingNumber string, req client.CreateTransfer, transfer *client.Transfer) {
+	if orgConfig == nil || !orgConfig.PreferSameDay || req.SameDay || req.IatDetail != nil {
+		return // IAT entries can't be sent same-day
+	}
+	loc := cfg.ODFI.Cutoffs.Location()
+	transfer.SameDayDecision = decideSameDay(cfg.Transfers.SameDay, loc, now, routingNumber, req)
+	if transfer.SameDayDecision.Selected {
+		if loc == nil {
+			loc = time.UTC
+		}
+		transfer.SameDay = true
+		transfer.EffectiveDate = now.In(loc).Format(util.YYMMDDTimeFormat)
+	}
+}
@@ -0,0 +1,131 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package transfers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/moov-io/base"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransfers__decideSameDay(t *testing.T) {
+	cfg := &config.SameDay{Cutoff: "14:45", MaxAmount: 50000}
+	loc, _ := time.LoadLocation("America/New_York")
+
+	// Tuesday Nov 17th, 2020
+	now := time.Date(2020, time.November, 17, 10, 30, 0, 0, loc)
+	req := client.CreateTransfer{Amount: client.Amount{Currency: "USD", Value: 1245}}
+
+	decision := decideSameDay(cfg, loc, now, "987654320", req)
+	require.True(t, decision.Selected)
+	require.Empty(t, decision.Reason)
+
+	// after the cutoff
+	decision = decideSameDay(cfg, loc, now.Add(5*time.Hour), "987654320", req)
+	require.False(t, decision.Selected)
+	require.Contains(t, decision.Reason, "after the same-day cutoff of 14:45")
+
+	// weekend
+	decision = decideSameDay(cfg, loc, now.Add(4*24*time.Hour), "987654320", req)
+	require.False(t, decision.Selected)
+	require.Equal(t, "created on a non-banking day", decision.Reason)
+
+	// over the amount limit
+	decision = decideSameDay(cfg, loc, now, "987654320", client.CreateTransfer{Amount: client.Amount{Currency: "USD", Value: 50001}})
+	require.False(t, decision.Selected)
+	require.Equal(t, "amount exceeds the same-day limit of 50000", decision.Reason)
+
+	// caller picked a date
+	decision = decideSameDay(cfg, loc, now, "987654320", client.CreateTransfer{Amount: req.Amount, EffectiveDate: "2020-11-18"})
+	require.False(t, decision.Selected)
+	require.Equal(t, "effectiveDate was requested", decision.Reason)
+
+	// not configured
+	decision = decideSameDay(nil, loc, now, "987654320", req)
+	require.False(t, decision.Selected)
+	require.Equal(t, "same-day processing is not enabled", decision.Reason)
+
+	// RDFIs with an earlier cutoff
+	cfg.RoutingNumbers = []config.SameDayRoutingNumber{{RoutingNumber: "987654320", Cutoff: "10:00"}}
+	decision = decideSameDay(cfg, loc, now, "987654320", req)
+	require.False(t, decision.Selected)
+	require.Equal(t, "created after the same-day cutoff of 10:00", decision.Reason)
+	require.True(t, decideSameDay(cfg, loc, now, "123456780", req).Selected)
+}
+
+func TestTransfers__checkSameDay(t *testing.T) {
+	cfg := &config.SameDay{Cutoff: "14:45", MaxAmount: 50000}
+	loc, _ := time.LoadLocation("America/New_York")
+
+	// Tuesday Nov 17th, 2020
+	now := time.Date(2020, time.November, 17, 10, 30, 0, 0, loc)
+	amt := client.Amount{Currency: "USD", Value: 1245}
+
+	require.NoError(t, checkSameDay(cfg, loc, now, "987654320", amt))
+	require.NoError(t, checkSameDay(nil, loc, now.Add(5*time.Hour), "987654320", amt))
+
+	err := checkSameDay(cfg, loc, now.Add(5*time.Hour), "987654320", amt)
+	require.EqualError(t, err, "created after the same-day cutoff of 14:45")
+
+	err = checkSameDay(cfg, loc, now, "987654320", client.Amount{Currency: "USD", Value: 50001})
+	require.EqualError(t, err, "amount exceeds the same-day limit of 50000")
+
+	// weekends are sent on the next banking day
+	require.NoError(t, checkSameDay(cfg, loc, now.Add(4*24*time.Hour+5*time.Hour), "987654320", amt))
+}
+
+func TestTransfers__applySameDayPreference(t *testing.T) {
+	cfg := config.Empty()
+	cfg.ODFI.Cutoffs.Timezone = "America/New_York"
+	cfg.Transfers.SameDay = &config.SameDay{Cutoff: "14:45"}
+	loc, _ := time.LoadLocation("America/New_York")
+
+	now := time.Date(2020, time.November, 17, 10, 30, 0, 0, loc)
+	req := client.CreateTransfer{Amount: client.Amount{Currency: "USD", Value: 1245}}
+
+	// organizations without the preference are unchanged
+	xfer := &client.Transfer{}
+	applySameDayPreference(cfg, &client.OrganizationConfiguration{}, now, "987654320", req, xfer)
+	require.False(t, xfer.SameDay)
+	require.Nil(t, xfer.SameDayDecision)
+
+	orgConfig := &client.OrganizationConfiguration{PreferSameDay: true}
+	applySameDayPreference(cfg, orgConfig, now, "987654320", req, xfer)
+	require.True(t, xfer.SameDay)
+	require.Equal(t, "2020-11-17", xfer.EffectiveDate)
+	require.True(t, xfer.SameDayDecision.Selected)
+
+	// callers requesting same-day don't need a decision
+	xfer = &client.Transfer{SameDay: true}
+	req.SameDay = true
+	applySameDayPreference(cfg, orgConfig, now, "987654320", req, xfer)
+	require.Nil(t, xfer.SameDayDecision)
+}
+
+func TestRepository__SameDayDecision(t *testing.T) {
+	repo := setupSQLiteDB(t)
+	orgID := base.ID()
+
+	xfer := writeTransfer(t, orgID, repo)
+	found, err := repo.getUserTransfer(xfer.TransferID, orgID)
+	require.NoError(t, err)
+	require.Nil(t, found.SameDayDecision)
+
+	xfer.TransferID = base.ID()
+	xfer.SameDayDecision = &client.SameDayDecision{Reason: "created after the same-day cutoff of 14:45"}
+	require.NoError(t, repo.WriteUserTransfer(orgID, xfer))
+
+	found, err = repo.getUserTransfer(xfer.TransferID, orgID)
+	require.NoError(t, err)
+	require.NotNil(t, found.SameDayDecision)
+	require.False(t, found.SameDayDecision.Selected)
+	require.Equal(t, xfer.SameDayDecision.Reason, found.SameDayDecision.Reason)
+}
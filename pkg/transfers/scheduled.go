@@ -0,0 +1,436 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package transfers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/moov-io/base"
+	"github.com/moov-io/base/log"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/customers"
+	"github.com/moov-io/paygate/pkg/customers/accounts"
+	"github.com/moov-io/paygate/pkg/hooks"
+	"github.com/moov-io/paygate/pkg/organization"
+	"github.com/moov-io/paygate/pkg/transfers/balances"
+	"github.com/moov-io/paygate/pkg/transfers/fundflow"
+	"github.com/moov-io/paygate/pkg/transfers/killswitch"
+	"github.com/moov-io/paygate/pkg/transfers/limiter"
+	"github.com/moov-io/paygate/pkg/transfers/pipeline"
+	"github.com/moov-io/paygate/pkg/webhooks"
+	"github.com/moov-io/paygate/x/route"
+
+	"github.com/go-kit/kit/metrics/prometheus"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	scheduledRuns = prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+		Name: "scheduled_transfer_runs",
+		Help: "Counter of Transfers attempted from schedules",
+	}, []string{"outcome"})
+)
+
+// occurrence returns when the Nth Transfer of a schedule is created. Monthly schedules
+// starting late in a month run on the last day of shorter months.
+func occurrence(start time.Time, freq client.ScheduleFrequency, n int) time.Time {
+	switch freq {
+	case client.SCHEDULEFREQUENCY_DAILY:
+		return start.AddDate(0, 0, n)
+	case client.SCHEDULEFREQUENCY_WEEKLY:
+		return start.AddDate(0, 0, 7*n)
+	case client.SCHEDULEFREQUENCY_BIWEEKLY:
+		return start.AddDate(0, 0, 14*n)
+	case client.SCHEDULEFREQUENCY_MONTHLY:
+		first := time.Date(start.Year(), start.Month()+time.Month(n), 1, start.Hour(), start.Minute(), start.Second(), start.Nanosecond(), start.Location())
+		lastDay := first.AddDate(0, 1, -1).Day()
+		day := start.Day()
+		if day > lastDay {
+			day = lastDay
+		}
+		return first.AddDate(0, 0, day-1)
+	}
+	return time.Time{}
+}
+
+// nextOccurrence returns the first run at or after n which is after now. Runs missed while
+// PayGate was down or the schedule was paused are skipped rather than created all at once.
+func nextOccurrence(schedule *client.ScheduledTransfer, n int, now time.Time) (int, time.Time) {
+	when := occurrence(schedule.StartDate, schedule.Frequency, n)
+	for !when.After(now) {
+		n++
+		when = occurrence(schedule.StartDate, schedule.Frequency, n)
+	}
+	return n, when
+}
+
+// hasEnded returns true when a run at when is past the schedule's EndDate.
+func hasEnded(schedule *client.ScheduledTransfer, when time.Time) bool {
+	return !schedule.EndDate.IsZero() && when.After(schedule.EndDate)
+}
+
+func GetScheduledTransfers(cfg *config.Config, repo Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(cfg, w, r)
+
+		schedules, err := repo.getScheduledTransfers(responder.OrganizationID)
+		if err != nil {
+			responder.Problem(err)
+			return
+		}
+
+		responder.Respond(func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(schedules)
+		})
+	}
+}
+
+func CreateScheduledTransfer(cfg *config.Config, repo Repository, orgRepo organization.Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(cfg, w, r)
+
+		var req client.CreateScheduledTransfer
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			responder.Problem(fmt.Errorf("reading scheduled transfer: %v", err))
+			return
+		}
+		now := time.Now()
+		if req.StartDate.IsZero() {
+			req.StartDate = now
+		}
+		if err := validateScheduledTransfer(req, now); err != nil {
+			responder.Problem(err)
+			return
+		}
+		orgConfig, err := orgRepo.GetConfig(responder.OrganizationID)
+		if err != nil {
+			responder.Problem(fmt.Errorf("getting org config: error getting config: %v", err))
+			return
+		}
+		if err := validateTransferTags(orgConfig, req.Transfer.Tags); err != nil {
+			responder.Problem(err)
+			return
+		}
+
+		schedule := &client.ScheduledTransfer{
+			ScheduleID: base.ID(),
+			Transfer:   req.Transfer,
+			Frequency:  req.Frequency,
+			Status:     client.SCHEDULESTATUS_ACTIVE,
+			StartDate:  req.StartDate,
+			EndDate:    req.EndDate,
+			NextRun:    req.StartDate,
+			Created:    now,
+		}
+		if err := repo.createScheduledTransfer(responder.OrganizationID, getUserID(r), schedule); err != nil {
+			responder.Problem(err)
+			return
+		}
+		cfg.Logger.With(log.Fields{
+			"requestID":  responder.XRequestID,
+			"scheduleID": schedule.ScheduleID,
+		}).Log("created scheduled transfer")
+
+		responder.Respond(func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(schedule)
+		})
+	}
+}
+
+func validateScheduledTransfer(req client.CreateScheduledTransfer, now time.Time) error {
+	if err := validateTransferRequest(req.Transfer); err != nil {
+		return fmt.Errorf("invalid transfer template: %v", err)
+	}
+	if req.Transfer.EffectiveDate != "" {
+		return errors.New("effectiveDate is set for each Transfer created from a schedule")
+	}
+	switch req.Frequency {
+	case client.SCHEDULEFREQUENCY_DAILY, client.SCHEDULEFREQUENCY_WEEKLY, client.SCHEDULEFREQUENCY_BIWEEKLY, client.SCHEDULEFREQUENCY_MONTHLY:
+	default:
+		return fmt.Errorf("unknown frequency %q", req.Frequency)
+	}
+	if req.StartDate.Before(now.Add(-time.Minute)) {
+		return errors.New("startDate is in the past")
+	}
+	if !req.EndDate.IsZero() && req.EndDate.Before(req.StartDate) {
+		return errors.New("endDate is before startDate")
+	}
+	return nil
+}
+
+func getScheduleID(r *http.Request) string {
+	return route.ReadPathID("scheduleID", r)
+}
+
+func GetScheduledTransfer(cfg *config.Config, repo Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(cfg, w, r)
+
+		scheduleID := getScheduleID(r)
+		schedule, err := repo.getScheduledTransfer(responder.OrganizationID, scheduleID)
+		if err != nil {
+			responder.Problem(err)
+			return
+		}
+		if schedule == nil {
+			responder.Problem(fmt.Errorf("scheduleID=%s not found", scheduleID))
+			return
+		}
+
+		responder.Respond(func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(schedule)
+		})
+	}
+}
+
+// UpdateScheduledTransfer pauses or resumes a schedule. Runs missed while a schedule was
+// paused are skipped when it's resumed.
+func UpdateScheduledTransfer(cfg *config.Config, repo Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(cfg, w, r)
+
+		var req client.UpdateScheduledTransfer
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			responder.Problem(fmt.Errorf("reading scheduled transfer update: %v", err))
+			return
+		}
+		switch req.Status {
+		case client.SCHEDULESTATUS_ACTIVE, client.SCHEDULESTATUS_PAUSED:
+		default:
+			responder.Problem(fmt.Errorf("scheduled transfers can only be %s or %s", client.SCHEDULESTATUS_ACTIVE, client.SCHEDULESTATUS_PAUSED))
+			return
+		}
+
+		scheduleID := getScheduleID(r)
+		schedule, err := repo.getScheduledTransfer(responder.OrganizationID, scheduleID)
+		if err != nil {
+			responder.Problem(err)
+			return
+		}
+		if schedule == nil {
+			responder.Problem(fmt.Errorf("scheduleID=%s not found", scheduleID))
+			return
+		}
+		if schedule, err = changeScheduleStatus(repo, responder.OrganizationID, schedule, req.Status, time.Now()); err != nil {
+			responder.Problem(err)
+			return
+		}
+
+		responder.Respond(func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(schedule)
+		})
+	}
+}
+
+// DeleteScheduledTransfer cancels a schedule so no more Transfers are created from it.
+// Transfers already created are unaffected.
+func DeleteScheduledTransfer(cfg *config.Config, repo Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(cfg, w, r)
+
+		scheduleID := getScheduleID(r)
+		schedule, err := repo.getScheduledTransfer(responder.OrganizationID, scheduleID)
+		if err != nil {
+			responder.Problem(err)
+			return
+		}
+		if schedule != nil {
+			if _, err := changeScheduleStatus(repo, responder.OrganizationID, schedule, client.SCHEDULESTATUS_CANCELED, time.Now()); err != nil {
+				responder.Problem(err)
+				return
+			}
+			cfg.Logger.With(log.Fields{
+				"requestID":  responder.XRequestID,
+				"scheduleID": scheduleID,
+			}).Log("canceled scheduled transfer")
+		}
+
+		responder.Respond(func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusOK)
+		})
+	}
+}
+
+func changeScheduleStatus(repo Repository, orgID string, schedule *client.ScheduledTransfer, status client.ScheduleStatus, now time.Time) (*client.ScheduledTransfer, error) {
+	if schedule.Status == status {
+		return schedule, nil
+	}
+	switch schedule.Status {
+	case client.SCHEDULESTATUS_ACTIVE, client.SCHEDULESTATUS_PAUSED:
+	default:
+		return nil, fmt.Errorf("scheduleID=%s is %s", schedule.ScheduleID, schedule.Status)
+	}
+
+	run := scheduledRun{status: status}
+	if status == client.SCHEDULESTATUS_ACTIVE {
+		run.count, run.next = nextOccurrence(schedule, 0, now)
+		if hasEnded(schedule, run.next) {
+			run.status, run.next = client.SCHEDULESTATUS_COMPLETED, time.Time{}
+		}
+	}
+	if err := repo.updateScheduledTransferStatus(orgID, schedule.ScheduleID, schedule.Status, run); err != nil {
+		return nil, err
+	}
+	schedule.Status = run.status
+	schedule.NextRun = run.next
+	return schedule, nil
+}
+
+// scheduledRun is the position of a schedule after it changes status or a run is claimed.
+type scheduledRun struct {
+	status client.ScheduleStatus
+	count  int
+	next   time.Time
+}
+
+// dueSchedule is an active schedule whose next Transfer should be created.
+type dueSchedule struct {
+	organization string
+	userID       string
+	runCount     int
+	schedule     *client.ScheduledTransfer
+}
+
+// Scheduler creates Transfers from active schedules once their next run has passed.
+//
+// Each run is claimed by advancing the schedule's run count before its Transfer is created,
+// so only one Scheduler creates each Transfer even with several PayGate instances running.
+type Scheduler struct {
+	logger  log.Logger
+	repo    Repository
+	creator *creator
+
+	interval time.Duration
+}
+
+func NewScheduler(
+	cfg *config.Config,
+	repo Repository,
+	orgRepo organization.Repository,
+	customersClient customers.Client,
+	accountDecryptor accounts.Decryptor,
+	fundStrategy fundflow.Strategy,
+	pub pipeline.XferPublisher,
+	debits *killswitch.Checker,
+	exposure *limiter.Exposure,
+	overrides *limiter.Overrides,
+	events webhooks.Sender,
+) (*Scheduler, error) {
+	limitChecker, err := limiter.New(cfg.Transfers.Limits, overrides)
+	if err != nil {
+		return nil, fmt.Errorf("creating transfer limiter: %v", err)
+	}
+	hookRunner, err := hooks.New(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("creating lifecycle hooks: %v", err)
+	}
+	balanceChecker, err := balances.NewChecker(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("creating balance checker: %v", err)
+	}
+	return &Scheduler{
+		logger: cfg.Logger,
+		repo:   repo,
+		creator: &creator{
+			cfg:              cfg,
+			repo:             repo,
+			orgRepo:          orgRepo,
+			customersClient:  customersClient,
+			accountDecryptor: accountDecryptor,
+			fundStrategy:     fundStrategy,
+			pub:              pub,
+			limitChecker:     limitChecker,
+			debits:           debits,
+			exposure:         exposure,
+			balances:         balanceChecker,
+			hookRunner:       hookRunner,
+			events:           events,
+		},
+		interval: cfg.Transfers.Scheduled.CheckInterval(),
+	}, nil
+}
+
+func (s *Scheduler) Start(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case now := <-ticker.C:
+			if err := s.tick(now); err != nil {
+				s.logger.LogErrorf("ERROR creating scheduled transfers: %v", err)
+			}
+
+		case <-ctx.Done():
+			s.logger.Log("transfer scheduler shutdown")
+			return
+		}
+	}
+}
+
+func (s *Scheduler) tick(now time.Time) error {
+	due, err := s.repo.getDueScheduledTransfers(now)
+	if err != nil {
+		return err
+	}
+
+	var el base.ErrorList
+	for i := range due {
+		if err := s.run(due[i], now); err != nil {
+			el.Add(fmt.Errorf("scheduleID=%s: %v", due[i].schedule.ScheduleID, err))
+		}
+	}
+	if el.Empty() {
+		return nil
+	}
+	return el
+}
+
+func (s *Scheduler) run(due dueSchedule, now time.Time) error {
+	schedule := due.schedule
+
+	run := scheduledRun{status: client.SCHEDULESTATUS_ACTIVE}
+	run.count, run.next = nextOccurrence(schedule, due.runCount+1, now)
+	if hasEnded(schedule, run.next) {
+		run.status, run.next = client.SCHEDULESTATUS_COMPLETED, time.Time{}
+	}
+	claimed, err := s.repo.claimScheduledRun(schedule.ScheduleID, due.runCount, run)
+	if err != nil || !claimed {
+		return err // another instance created this run
+	}
+
+	logger := s.logger.With(log.Fields{
+		"organization": due.organization,
+		"scheduleID":   schedule.ScheduleID,
+	})
+
+	// Failed runs aren't retried, the error is kept on the schedule until the next run.
+	var transferID, lastError string
+	xfer, err := s.creator.create(nil, due.organization, due.userID, "", schedule.Transfer)
+	if err != nil {
+		scheduledRuns.With("outcome", "failed").Add(1)
+		logger.LogErrorf("problem creating scheduled transfer: %v", err)
+		lastError = err.Error()
+	} else {
+		scheduledRuns.With("outcome", "created").Add(1)
+		logger.With(log.Fields{
+			"transferID":    xfer.TransferID,
+			"correlationID": xfer.CorrelationID,
+		}).Log("created scheduled transfer")
+		transferID = xfer.TransferID
+	}
+	return s.repo.recordScheduledRun(schedule.ScheduleID, transferID, lastError, now)
+}
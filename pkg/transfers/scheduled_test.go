@@ -0,0 +1,295 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package transfers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/moov-io/base"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+)
+
+func scheduleTemplate() client.CreateTransfer {
+	return client.CreateTransfer{
+		Amount: client.Amount{
+			Currency: "USD",
+			Value:    125000,
+		},
+		Source: client.Source{
+			CustomerID: sourceCustomerID,
+			AccountID:  sourceAccountID,
+		},
+		Destination: client.Destination{
+			CustomerID: destinationCustomerID,
+			AccountID:  destinationAccountID,
+		},
+		Description: "payroll",
+	}
+}
+
+func TestScheduled__occurrence(t *testing.T) {
+	start := time.Date(2020, time.January, 31, 9, 0, 0, 0, time.UTC)
+
+	require.Equal(t, "2020-02-01", occurrence(start, client.SCHEDULEFREQUENCY_DAILY, 1).Format("2006-01-02"))
+	require.Equal(t, "2020-02-07", occurrence(start, client.SCHEDULEFREQUENCY_WEEKLY, 1).Format("2006-01-02"))
+	require.Equal(t, "2020-02-14", occurrence(start, client.SCHEDULEFREQUENCY_BIWEEKLY, 1).Format("2006-01-02"))
+
+	// monthly runs fall back to the end of shorter months
+	require.Equal(t, "2020-02-29", occurrence(start, client.SCHEDULEFREQUENCY_MONTHLY, 1).Format("2006-01-02"))
+	require.Equal(t, "2020-03-31", occurrence(start, client.SCHEDULEFREQUENCY_MONTHLY, 2).Format("2006-01-02"))
+	require.Equal(t, "2021-01-31", occurrence(start, client.SCHEDULEFREQUENCY_MONTHLY, 12).Format("2006-01-02"))
+	require.Equal(t, 9, occurrence(start, client.SCHEDULEFREQUENCY_MONTHLY, 1).Hour())
+}
+
+func TestScheduled__nextOccurrence(t *testing.T) {
+	schedule := &client.ScheduledTransfer{
+		Frequency: client.SCHEDULEFREQUENCY_WEEKLY,
+		StartDate: time.Date(2020, time.June, 1, 9, 0, 0, 0, time.UTC),
+	}
+
+	// missed runs are skipped
+	now := time.Date(2020, time.June, 20, 12, 0, 0, 0, time.UTC)
+	n, when := nextOccurrence(schedule, 1, now)
+	require.Equal(t, 3, n)
+	require.Equal(t, "2020-06-22", when.Format("2006-01-02"))
+
+	require.False(t, hasEnded(schedule, when))
+	schedule.EndDate = time.Date(2020, time.June, 21, 0, 0, 0, 0, time.UTC)
+	require.True(t, hasEnded(schedule, when))
+}
+
+func TestScheduled__validate(t *testing.T) {
+	now := time.Now()
+	req := client.CreateScheduledTransfer{
+		Transfer:  scheduleTemplate(),
+		Frequency: client.SCHEDULEFREQUENCY_MONTHLY,
+		StartDate: now.Add(time.Hour),
+	}
+	require.NoError(t, validateScheduledTransfer(req, now))
+
+	req.Frequency = "hourly"
+	require.Error(t, validateScheduledTransfer(req, now))
+	req.Frequency = client.SCHEDULEFREQUENCY_MONTHLY
+
+	req.StartDate = now.Add(-24 * time.Hour)
+	require.Error(t, validateScheduledTransfer(req, now))
+	req.StartDate = now.Add(time.Hour)
+
+	req.EndDate = now
+	require.Error(t, validateScheduledTransfer(req, now))
+	req.EndDate = time.Time{}
+
+	req.Transfer.EffectiveDate = "2020-06-01"
+	require.Error(t, validateScheduledTransfer(req, now))
+	req.Transfer.EffectiveDate = ""
+
+	req.Transfer.Description = ""
+	require.Error(t, validateScheduledTransfer(req, now))
+}
+
+func TestRepository__scheduledTransfers(t *testing.T) {
+	orgID := base.ID()
+	repo := setupSQLiteDB(t)
+
+	start := time.Now().Add(-time.Minute).Round(time.Second)
+	schedule := &client.ScheduledTransfer{
+		ScheduleID: base.ID(),
+		Transfer:   scheduleTemplate(),
+		Frequency:  client.SCHEDULEFREQUENCY_WEEKLY,
+		Status:     client.SCHEDULESTATUS_ACTIVE,
+		StartDate:  start,
+		NextRun:    start,
+		Created:    time.Now(),
+	}
+	require.NoError(t, repo.createScheduledTransfer(orgID, "jane", schedule))
+
+	found, err := repo.getScheduledTransfer(orgID, schedule.ScheduleID)
+	require.NoError(t, err)
+	require.Equal(t, schedule.Transfer, found.Transfer)
+	require.Equal(t, client.SCHEDULEFREQUENCY_WEEKLY, found.Frequency)
+
+	schedules, err := repo.getScheduledTransfers(orgID)
+	require.NoError(t, err)
+	require.Len(t, schedules, 1)
+
+	due, err := repo.getDueScheduledTransfers(time.Now())
+	require.NoError(t, err)
+	require.Len(t, due, 1)
+	require.Equal(t, orgID, due[0].organization)
+	require.Equal(t, "jane", due[0].userID)
+	require.Equal(t, 0, due[0].runCount)
+
+	// only the first claim of a run succeeds
+	run := scheduledRun{status: client.SCHEDULESTATUS_ACTIVE, count: 1, next: start.AddDate(0, 0, 7)}
+	claimed, err := repo.claimScheduledRun(schedule.ScheduleID, 0, run)
+	require.NoError(t, err)
+	require.True(t, claimed)
+
+	claimed, err = repo.claimScheduledRun(schedule.ScheduleID, 0, run)
+	require.NoError(t, err)
+	require.False(t, claimed)
+
+	require.NoError(t, repo.recordScheduledRun(schedule.ScheduleID, "xfer", "", time.Now()))
+
+	due, err = repo.getDueScheduledTransfers(time.Now())
+	require.NoError(t, err)
+	require.Len(t, due, 0)
+
+	found, err = repo.getScheduledTransfer(orgID, schedule.ScheduleID)
+	require.NoError(t, err)
+	require.Equal(t, "xfer", found.LastTransferID)
+	require.False(t, found.LastRun.IsZero())
+	require.Equal(t, run.next.Unix(), found.NextRun.Unix())
+
+	// pause and cancel
+	paused := scheduledRun{status: client.SCHEDULESTATUS_PAUSED}
+	require.NoError(t, repo.updateScheduledTransferStatus(orgID, schedule.ScheduleID, client.SCHEDULESTATUS_ACTIVE, paused))
+	require.Error(t, repo.updateScheduledTransferStatus(orgID, schedule.ScheduleID, client.SCHEDULESTATUS_ACTIVE, paused))
+
+	found, err = repo.getScheduledTransfer(orgID, schedule.ScheduleID)
+	require.NoError(t, err)
+	require.Equal(t, client.SCHEDULESTATUS_PAUSED, found.Status)
+	require.True(t, found.NextRun.IsZero())
+
+	// other organizations can't read the schedule
+	found, err = repo.getScheduledTransfer(base.ID(), schedule.ScheduleID)
+	require.NoError(t, err)
+	require.Nil(t, found)
+}
+
+func TestScheduler__run(t *testing.T) {
+	repo := setupSQLiteDB(t)
+	orgID := base.ID()
+
+	start := time.Now().Add(-time.Minute)
+	schedule := &client.ScheduledTransfer{
+		ScheduleID: base.ID(),
+		Transfer:   scheduleTemplate(),
+		Frequency:  client.SCHEDULEFREQUENCY_MONTHLY,
+		Status:     client.SCHEDULESTATUS_ACTIVE,
+		StartDate:  start,
+		EndDate:    start.Add(24 * time.Hour),
+		NextRun:    start,
+		Created:    time.Now(),
+	}
+	require.NoError(t, repo.createScheduledTransfer(orgID, "", schedule))
+
+	cfg := config.Empty()
+	scheduler, err := NewScheduler(cfg, repo, orgRepo, mockCustomersClient(), mockDecryptor, mockStrategy, fakePublisher, nil, nil, nil, nil)
+	require.NoError(t, err)
+	require.NoError(t, scheduler.tick(time.Now()))
+
+	// the Transfer was created and the schedule has ended
+	found, err := repo.getScheduledTransfer(orgID, schedule.ScheduleID)
+	require.NoError(t, err)
+	require.Equal(t, client.SCHEDULESTATUS_COMPLETED, found.Status)
+	require.Empty(t, found.LastError)
+	require.NotEmpty(t, found.LastTransferID)
+
+	xfer, err := repo.getUserTransfer(found.LastTransferID, orgID)
+	require.NoError(t, err)
+	require.Equal(t, "payroll", xfer.Description)
+	require.Equal(t, client.PENDING, xfer.Status)
+
+	// failed runs are recorded on the schedule
+	schedule.ScheduleID = base.ID()
+	schedule.Transfer.Source.AccountID = base.ID() // unknown account
+	schedule.EndDate = time.Time{}
+	require.NoError(t, repo.createScheduledTransfer(orgID, "", schedule))
+	require.NoError(t, scheduler.tick(time.Now()))
+
+	found, err = repo.getScheduledTransfer(orgID, schedule.ScheduleID)
+	require.NoError(t, err)
+	require.Equal(t, client.SCHEDULESTATUS_ACTIVE, found.Status)
+	require.NotEmpty(t, found.LastError)
+	require.Empty(t, found.LastTransferID)
+	require.True(t, found.NextRun.After(time.Now()))
+}
+
+func TestRouter__scheduledTransfers(t *testing.T) {
+	repo := &MockRepository{}
+
+	r := mux.NewRouter()
+	router := NewRouter(config.Empty(), repo, orgRepo, mockCustomersClient(), mockDecryptor, mockStrategy, fakePublisher, nil, nil, nil, nil, nil)
+	router.RegisterRoutes(r)
+
+	var body bytes.Buffer
+	json.NewEncoder(&body).Encode(client.CreateScheduledTransfer{
+		Transfer:  scheduleTemplate(),
+		Frequency: client.SCHEDULEFREQUENCY_BIWEEKLY,
+	})
+	req := httptest.NewRequest("POST", "/transfers/scheduled", &body)
+	req.Header.Set("X-Organization", "moov")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	w.Flush()
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var schedule client.ScheduledTransfer
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&schedule))
+	require.NotEmpty(t, schedule.ScheduleID)
+	require.Equal(t, client.SCHEDULESTATUS_ACTIVE, schedule.Status)
+	require.False(t, schedule.NextRun.IsZero())
+
+	// "scheduled" isn't read as a transferID
+	repo.Schedules = []*client.ScheduledTransfer{&schedule}
+	req = httptest.NewRequest("GET", "/transfers/scheduled", nil)
+	req.Header.Set("X-Organization", "moov")
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	w.Flush()
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var schedules []client.ScheduledTransfer
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&schedules))
+	require.Len(t, schedules, 1)
+
+	// pause
+	body.Reset()
+	json.NewEncoder(&body).Encode(client.UpdateScheduledTransfer{Status: client.SCHEDULESTATUS_PAUSED})
+	req = httptest.NewRequest("PUT", "/transfers/scheduled/"+schedule.ScheduleID, &body)
+	req.Header.Set("X-Organization", "moov")
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	w.Flush()
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, client.SCHEDULESTATUS_PAUSED, repo.Schedules[0].Status)
+
+	// schedules can't be completed by callers
+	body.Reset()
+	json.NewEncoder(&body).Encode(client.UpdateScheduledTransfer{Status: client.SCHEDULESTATUS_COMPLETED})
+	req = httptest.NewRequest("PUT", "/transfers/scheduled/"+schedule.ScheduleID, &body)
+	req.Header.Set("X-Organization", "moov")
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	w.Flush()
+	require.Equal(t, http.StatusBadRequest, w.Code)
+
+	// cancel, twice
+	for i := 0; i < 2; i++ {
+		req = httptest.NewRequest("DELETE", "/transfers/scheduled/"+schedule.ScheduleID, nil)
+		req.Header.Set("X-Organization", "moov")
+
+		w = httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		w.Flush()
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Equal(t, client.SCHEDULESTATUS_CANCELED, repo.Schedules[0].Status)
+	}
+}
@@ -0,0 +1,213 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package transfers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/x/route"
+)
+
+// Status links are tokens of the form transferID.expires.signature where expires is a unix
+// timestamp and signature is the hex encoded HMAC-SHA256 of the first two parts. Holding a
+// valid token only allows reading a subset of the Transfer's fields.
+
+var errInvalidStatusLink = errors.New("invalid or expired status link")
+
+func signStatusLink(secret string, transferID string, expires time.Time) string {
+	payload := fmt.Sprintf("%s.%d", transferID, expires.Unix())
+	return payload + "." + statusLinkSignature(secret, payload)
+}
+
+func statusLinkSignature(secret string, payload string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// parseStatusLink returns the transferID of a token which is correctly signed and unexpired.
+func parseStatusLink(secret string, token string, now time.Time) (string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 || parts[0] == "" {
+		return "", errInvalidStatusLink
+	}
+	expected := statusLinkSignature(secret, parts[0]+"."+parts[1])
+	if !hmac.Equal([]byte(expected), []byte(parts[2])) {
+		return "", errInvalidStatusLink
+	}
+	expires, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || now.After(time.Unix(expires, 0)) {
+		return "", errInvalidStatusLink
+	}
+	return parts[0], nil
+}
+
+func statusLinkURL(cfg *config.StatusLinks, token string) string {
+	if cfg.BaseURL == "" {
+		return ""
+	}
+	return strings.TrimSuffix(cfg.BaseURL, "/") + "/track/" + token
+}
+
+// trackedTransfer returns the fields of a Transfer which are safe to show receivers.
+func trackedTransfer(xfer *client.Transfer) client.TrackedTransfer {
+	tracked := client.TrackedTransfer{
+		TransferID:    xfer.TransferID,
+		Amount:        xfer.Amount,
+		Description:   xfer.Description,
+		Status:        xfer.Status,
+		EffectiveDate: xfer.EffectiveDate,
+		ProcessedAt:   xfer.ProcessedAt,
+		Created:       xfer.Created,
+	}
+	if xfer.ReturnCode != nil {
+		tracked.ReturnCode = &client.ReturnCode{
+			Code:   xfer.ReturnCode.Code,
+			Reason: xfer.ReturnCode.Reason,
+		}
+	}
+	return tracked
+}
+
+// CreateStatusLink signs a link to a Transfer's status which can be handed to its receiver.
+func CreateStatusLink(cfg *config.Config, repo Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(cfg, w, r)
+
+		conf := cfg.Transfers.StatusLinks
+		if conf == nil {
+			responder.Problem(errors.New("status links are disabled via config"))
+			return
+		}
+
+		transferID := getTransferID(r)
+		orgID, err := repo.GetTransferOrganization(transferID)
+		if err != nil {
+			responder.Problem(err)
+			return
+		}
+		if orgID == "" || orgID != responder.OrganizationID {
+			responder.Problem(fmt.Errorf("transferID=%s not found", transferID))
+			return
+		}
+
+		expires := time.Now().Add(conf.LinkExpiration()).Truncate(time.Second)
+		token := signStatusLink(conf.Secret, transferID, expires)
+
+		responder.Respond(func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(client.TransferStatusLink{
+				Token:     token,
+				Url:       statusLinkURL(conf, token),
+				ExpiresAt: expires,
+			})
+		})
+	}
+}
+
+// GetTrackedTransfer shows the status of the Transfer a link was signed for. It requires no
+// credentials and renders a page for browsers.
+func GetTrackedTransfer(cfg *config.Config, repo Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(cfg, w, r)
+
+		tracked, err := readTrackedTransfer(cfg.Transfers.StatusLinks, repo, route.ReadPathID("token", r))
+		if err != nil {
+			if err != errInvalidStatusLink {
+				cfg.Logger.LogErrorf("ERROR reading tracked transfer: %v", err)
+				err = errors.New("problem reading transfer")
+			}
+			if acceptsHTML(r) {
+				renderStatusPage(w, http.StatusBadRequest, statusPage{Error: err.Error()})
+				return
+			}
+			responder.Problem(err)
+			return
+		}
+
+		if acceptsHTML(r) {
+			renderStatusPage(w, http.StatusOK, statusPage{Transfer: tracked})
+			return
+		}
+		responder.Respond(func(w http.ResponseWriter) {
+			w.Header().Set("Cache-Control", "no-store")
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(tracked)
+		})
+	}
+}
+
+func readTrackedTransfer(conf *config.StatusLinks, repo Repository, token string) (*client.TrackedTransfer, error) {
+	if conf == nil {
+		return nil, errInvalidStatusLink
+	}
+	transferID, err := parseStatusLink(conf.Secret, token, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	xfer, err := repo.GetTransfer(transferID)
+	if err != nil {
+		return nil, err
+	}
+	if xfer == nil {
+		return nil, errInvalidStatusLink
+	}
+	tracked := trackedTransfer(xfer)
+	return &tracked, nil
+}
+
+func acceptsHTML(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "text/html")
+}
+
+type statusPage struct {
+	Transfer *client.TrackedTransfer
+	Error    string
+}
+
+func (p statusPage) Amount() string {
+	if p.Transfer == nil {
+		return ""
+	}
+	return fmt.Sprintf("$%d.%02d", p.Transfer.Amount.Value/100, p.Transfer.Amount.Value%100)
+}
+
+var statusTemplate = template.Must(template.New("track").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><meta name="viewport" content="width=device-width, initial-scale=1"><title>Payment status</title></head>
+<body>
+<h1>Payment status</h1>
+{{if .Error}}<p role="alert">{{.Error}}</p>{{end}}
+{{with .Transfer}}
+<dl>
+  <dt>Amount</dt><dd>{{$.Amount}}</dd>
+  <dt>Description</dt><dd>{{.Description}}</dd>
+  <dt>Status</dt><dd>{{.Status}}</dd>
+  {{if .EffectiveDate}}<dt>Expected on</dt><dd>{{.EffectiveDate}}</dd>{{end}}
+  {{with .ReturnCode}}<dt>Returned</dt><dd>{{.Code}} {{.Reason}}</dd>{{end}}
+</dl>
+{{end}}
+</body>
+</html>
+`))
+
+func renderStatusPage(w http.ResponseWriter, status int, page statusPage) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	statusTemplate.Execute(w, page)
+}
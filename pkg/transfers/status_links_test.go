@@ -0,0 +1,143 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package transfers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/moov-io/base"
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+)
+
+var statusLinkSecret = strings.Repeat("s", 32)
+
+func statusLinksRouter(repo Repository) *mux.Router {
+	cfg := config.Empty()
+	cfg.Transfers.StatusLinks = &config.StatusLinks{
+		Secret:  statusLinkSecret,
+		BaseURL: "https://pay.example.com/",
+	}
+	r := mux.NewRouter()
+	NewRouter(cfg, repo, nil, mockCustomersClient(), mockDecryptor, mockStrategy, fakePublisher, nil, nil, nil, nil, nil).RegisterRoutes(r)
+	return r
+}
+
+func TestStatusLinks__parseStatusLink(t *testing.T) {
+	now := time.Now()
+	token := signStatusLink(statusLinkSecret, "xfer", now.Add(time.Hour))
+
+	transferID, err := parseStatusLink(statusLinkSecret, token, now)
+	require.NoError(t, err)
+	require.Equal(t, "xfer", transferID)
+
+	// expired
+	_, err = parseStatusLink(statusLinkSecret, token, now.Add(2*time.Hour))
+	require.Equal(t, errInvalidStatusLink, err)
+
+	// other secret
+	_, err = parseStatusLink(strings.Repeat("x", 32), token, now)
+	require.Equal(t, errInvalidStatusLink, err)
+
+	// tampered
+	parts := strings.Split(token, ".")
+	_, err = parseStatusLink(statusLinkSecret, "other."+parts[1]+"."+parts[2], now)
+	require.Equal(t, errInvalidStatusLink, err)
+
+	_, err = parseStatusLink(statusLinkSecret, "", now)
+	require.Equal(t, errInvalidStatusLink, err)
+}
+
+func TestStatusLinks__roundTrip(t *testing.T) {
+	xfer := &client.Transfer{
+		TransferID:  base.ID(),
+		Amount:      client.Amount{Currency: "USD", Value: 1250},
+		Description: "invoice 42",
+		Status:      client.PROCESSED,
+		Source: client.Source{
+			CustomerID: base.ID(),
+			AccountID:  base.ID(),
+		},
+		ReturnCode: &client.ReturnCode{Code: "R01", Reason: "Insufficient Funds"},
+	}
+	repo := &MockRepository{
+		Transfers:    []*client.Transfer{xfer},
+		Organization: "moov",
+	}
+	r := statusLinksRouter(repo)
+
+	// other organizations can't sign links
+	req := httptest.NewRequest("POST", "/transfers/"+xfer.TransferID+"/status-links", nil)
+	req.Header.Set("X-Organization", "other")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+
+	req = httptest.NewRequest("POST", "/transfers/"+xfer.TransferID+"/status-links", nil)
+	req.Header.Set("X-Organization", "moov")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var link client.TransferStatusLink
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&link))
+	require.NotEmpty(t, link.Token)
+	require.Equal(t, "https://pay.example.com/track/"+link.Token, link.Url)
+	require.True(t, link.ExpiresAt.After(time.Now().Add(29*24*time.Hour)))
+
+	// read without an organization
+	req = httptest.NewRequest("GET", "/track/"+link.Token, nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+	require.NotContains(t, w.Body.String(), xfer.Source.AccountID)
+
+	var tracked client.TrackedTransfer
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&tracked))
+	require.Equal(t, xfer.TransferID, tracked.TransferID)
+	require.Equal(t, client.PROCESSED, tracked.Status)
+	require.Equal(t, "R01", tracked.ReturnCode.Code)
+
+	// browsers get a page
+	req = httptest.NewRequest("GET", "/track/"+link.Token, nil)
+	req.Header.Set("Accept", "text/html")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Contains(t, w.Body.String(), "$12.50")
+	require.Contains(t, w.Body.String(), "invoice 42")
+
+	// tampered tokens are rejected
+	req = httptest.NewRequest("GET", "/track/"+xfer.TransferID+".1.abc", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestStatusLinks__disabled(t *testing.T) {
+	repo := &MockRepository{Organization: "moov"}
+	r := mux.NewRouter()
+	NewRouter(config.Empty(), repo, nil, mockCustomersClient(), mockDecryptor, mockStrategy, fakePublisher, nil, nil, nil, nil, nil).RegisterRoutes(r)
+
+	req := httptest.NewRequest("POST", "/transfers/xfer/status-links", nil)
+	req.Header.Set("X-Organization", "moov")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+
+	req = httptest.NewRequest("GET", "/track/"+signStatusLink(statusLinkSecret, "xfer", time.Now().Add(time.Hour)), nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,76 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package transfers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/organization"
+	"github.com/moov-io/paygate/x/route"
+)
+
+// validateTransferTags checks each tag is one the organization has defined in its transferTags.
+func validateTransferTags(orgConfig *client.OrganizationConfiguration, tags []string) error {
+	if len(tags) == 0 {
+		return nil
+	}
+	if err := organization.ValidateTags(tags); err != nil {
+		return err
+	}
+	allowed := make(map[string]bool)
+	if orgConfig != nil {
+		for i := range orgConfig.TransferTags {
+			allowed[orgConfig.TransferTags[i]] = true
+		}
+	}
+	for i := range tags {
+		if !allowed[tags[i]] {
+			return fmt.Errorf("tag %q is not one of the organization's transferTags", tags[i])
+		}
+	}
+	return nil
+}
+
+// UpdateTransferTags replaces the tags of a Transfer.
+func UpdateTransferTags(cfg *config.Config, repo Repository, orgRepo organization.Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(cfg, w, r)
+
+		var req client.UpdateTransferTags
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			responder.Problem(fmt.Errorf("reading tags: %v", err))
+			return
+		}
+		orgConfig, err := orgRepo.GetConfig(responder.OrganizationID)
+		if err != nil {
+			responder.Problem(fmt.Errorf("getting org config: error getting config: %v", err))
+			return
+		}
+		if err := validateTransferTags(orgConfig, req.Tags); err != nil {
+			responder.Problem(err)
+			return
+		}
+
+		transferID := getTransferID(r)
+		if err := repo.saveTransferTags(responder.OrganizationID, transferID, req.Tags); err != nil {
+			responder.Problem(err)
+			return
+		}
+		xfer, err := repo.GetTransfer(transferID)
+		if err != nil {
+			responder.Problem(err)
+			return
+		}
+
+		responder.Respond(func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(xfer)
+		})
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package transfers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/moov-io/base"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/organization"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepository__transferTags(t *testing.T) {
+	t.Parallel()
+
+	check := func(t *testing.T, repo *sqlRepo) {
+		orgID := base.ID()
+
+		xfer := writeTransfer(t, orgID, repo)
+		other := &client.Transfer{
+			TransferID:  base.ID(),
+			Amount:      client.Amount{Currency: "USD", Value: 1245},
+			Description: "payroll",
+			Status:      client.PENDING,
+			Created:     time.Now(),
+			Tags:        []string{"payroll"},
+		}
+		require.NoError(t, repo.WriteUserTransfer(orgID, other))
+
+		require.NoError(t, repo.saveTransferTags(orgID, xfer.TransferID, []string{"retry", "chargeback"}))
+		require.Error(t, repo.saveTransferTags(base.ID(), xfer.TransferID, []string{"retry"}))
+
+		tt, err := repo.GetTransfer(xfer.TransferID)
+		require.NoError(t, err)
+		require.Equal(t, []string{"chargeback", "retry"}, tt.Tags)
+
+		// filter by tags
+		params := readTransferFilterParams(&http.Request{})
+		params.Tags = []string{"retry"}
+		xfers, _, err := repo.getTransfers(orgID, params)
+		require.NoError(t, err)
+		require.Len(t, xfers, 1)
+		require.Equal(t, xfer.TransferID, xfers[0].TransferID)
+
+		params.Tags = []string{"retry", "payroll"}
+		xfers, _, err = repo.getTransfers(orgID, params)
+		require.NoError(t, err)
+		require.Len(t, xfers, 2)
+
+		changes, err := repo.getTransferHistory(orgID, xfer.TransferID)
+		require.NoError(t, err)
+		require.Equal(t, "tags", changes[len(changes)-1].Field)
+		require.Equal(t, "retry,chargeback", changes[len(changes)-1].NewValue)
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+}
+
+func TestTransfers__validateTransferTags(t *testing.T) {
+	orgConfig := &client.OrganizationConfiguration{
+		TransferTags: []string{"June payroll", "chargeback-retry"},
+	}
+	require.NoError(t, validateTransferTags(nil, nil))
+	require.NoError(t, validateTransferTags(orgConfig, []string{"June payroll"}))
+
+	require.Error(t, validateTransferTags(nil, []string{"June payroll"}))
+	require.Error(t, validateTransferTags(orgConfig, []string{"other"}))
+	require.Error(t, validateTransferTags(orgConfig, []string{"June payroll", "June payroll"}))
+}
+
+func TestRouter__updateTransferTags(t *testing.T) {
+	orgRepo := &organization.MockRepository{
+		Config: &client.OrganizationConfiguration{
+			TransferTags: []string{"payroll"},
+		},
+	}
+
+	r := mux.NewRouter()
+	router := NewRouter(config.Empty(), repoWithTransfer, orgRepo, mockCustomersClient(), mockDecryptor, mockStrategy, fakePublisher, nil, nil, nil, nil, nil)
+	router.RegisterRoutes(r)
+
+	var body bytes.Buffer
+	json.NewEncoder(&body).Encode(client.UpdateTransferTags{Tags: []string{"payroll"}})
+	req := httptest.NewRequest("PUT", "/transfers/"+base.ID()+"/tags", &body)
+	req.Header.Set("X-Organization", "moov")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	w.Flush()
+	require.Equal(t, http.StatusOK, w.Code)
+
+	body.Reset()
+	json.NewEncoder(&body).Encode(client.UpdateTransferTags{Tags: []string{"other"}})
+	req = httptest.NewRequest("PUT", "/transfers/"+base.ID()+"/tags", &body)
+	req.Header.Set("X-Organization", "moov")
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	w.Flush()
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,130 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package transfers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/x/route"
+)
+
+// transferTimeline orders what happened to a Transfer from its creation, recorded changes,
+// the files it was uploaded in and the return or correction entries received for it.
+func transferTimeline(xfer *client.Transfer, changes []*client.TransferChange, files []*client.TransferFile, records []*client.InboundRecord) []client.TransferEvent {
+	events := []client.TransferEvent{
+		{
+			Type:        "created",
+			Description: fmt.Sprintf("created for %s %.2f", xfer.Amount.Currency, float64(xfer.Amount.Value)/100.0),
+			Created:     xfer.Created,
+		},
+	}
+	for i := range changes {
+		desc := fmt.Sprintf("%s changed to %s", changes[i].Field, changes[i].NewValue)
+		if changes[i].OldValue != "" {
+			desc = fmt.Sprintf("%s changed from %s to %s", changes[i].Field, changes[i].OldValue, changes[i].NewValue)
+		}
+		events = append(events, client.TransferEvent{
+			Type:        "changed",
+			Description: desc,
+			Actor:       changes[i].Actor,
+			Created:     changes[i].Created,
+		})
+	}
+
+	// files hold an entry for each trace number, so only describe each file once
+	seen := make(map[string]bool)
+	for i := range files {
+		if seen[files[i].Filename] {
+			continue
+		}
+		seen[files[i].Filename] = true
+
+		events = append(events, client.TransferEvent{
+			Type:          "uploaded",
+			Description:   fmt.Sprintf("uploaded in %s to %s", files[i].Filename, files[i].RoutingNumber),
+			Filename:      files[i].Filename,
+			RoutingNumber: files[i].RoutingNumber,
+			TraceNumber:   files[i].TraceNumber,
+			Created:       files[i].Uploaded,
+		})
+		if files[i].Acknowledged != nil {
+			desc := fmt.Sprintf("%s was %s by the ODFI", files[i].Filename, files[i].Status)
+			if files[i].Reason != "" {
+				desc += ": " + files[i].Reason
+			}
+			events = append(events, client.TransferEvent{
+				Type:          "acknowledged",
+				Description:   desc,
+				Filename:      files[i].Filename,
+				RoutingNumber: files[i].RoutingNumber,
+				Created:       *files[i].Acknowledged,
+			})
+		}
+	}
+
+	for i := range records {
+		events = append(events, client.TransferEvent{
+			Type:        records[i].Type,
+			Description: fmt.Sprintf("%s received with code %s", records[i].Type, records[i].Code),
+			TraceNumber: records[i].TraceNumber,
+			Code:        records[i].Code,
+			Created:     records[i].Created,
+		})
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Created.Before(events[j].Created)
+	})
+	return events
+}
+
+// GetTransferTimeline returns the lifecycle of a Transfer in chronological order.
+func GetTransferTimeline(cfg *config.Config, repo Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(cfg, w, r)
+
+		transferID := getTransferID(r)
+		orgID, err := repo.GetTransferOrganization(transferID)
+		if err != nil {
+			responder.Problem(err)
+			return
+		}
+		if orgID == "" || orgID != responder.OrganizationID {
+			responder.Problem(fmt.Errorf("transferID=%s not found", transferID))
+			return
+		}
+
+		xfer, err := repo.GetTransfer(transferID)
+		if err != nil || xfer == nil {
+			responder.Problem(fmt.Errorf("transferID=%s not found: %v", transferID, err))
+			return
+		}
+		changes, err := repo.getTransferHistory(orgID, transferID)
+		if err != nil {
+			responder.Problem(err)
+			return
+		}
+		files, err := repo.getTransferFiles(orgID, transferID)
+		if err != nil {
+			responder.Problem(err)
+			return
+		}
+		records, err := repo.getInboundRecords(orgID, transferID)
+		if err != nil {
+			responder.Problem(err)
+			return
+		}
+
+		responder.Respond(func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(transferTimeline(xfer, changes, files, records))
+		})
+	}
+}
@@ -0,0 +1,91 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package transfers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/moov-io/base"
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/transfers/files"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransfers__timeline(t *testing.T) {
+	created := time.Date(2020, time.June, 1, 10, 0, 0, 0, time.UTC)
+	acknowledged := created.Add(5 * time.Hour)
+
+	xfer := &client.Transfer{
+		TransferID: base.ID(),
+		Amount:     client.Amount{Currency: "USD", Value: 1204},
+		Created:    created,
+	}
+	changes := []*client.TransferChange{
+		{Field: "status", OldValue: "pending", NewValue: "processed", Actor: "pipeline", Created: created.Add(4 * time.Hour)},
+		{Field: "returnCode", NewValue: "R01", Actor: "inbound", Created: created.Add(48 * time.Hour)},
+	}
+	uploaded := []*client.TransferFile{
+		{Filename: "20200601-987654320.ach", RoutingNumber: "987654320", TraceNumber: "121042880000001", Status: files.StatusAccepted, Uploaded: created.Add(4 * time.Hour), Acknowledged: &acknowledged},
+		{Filename: "20200601-987654320.ach", RoutingNumber: "987654320", TraceNumber: "121042880000002", Status: files.StatusAccepted, Uploaded: created.Add(4 * time.Hour), Acknowledged: &acknowledged},
+	}
+	records := []*client.InboundRecord{
+		{Type: "return", Code: "R01", TraceNumber: "121042880000001", Created: created.Add(48 * time.Hour)},
+	}
+
+	events := transferTimeline(xfer, changes, uploaded, records)
+	require.Len(t, events, 6)
+
+	var types []string
+	for i := range events {
+		types = append(types, events[i].Type)
+	}
+	require.Equal(t, []string{"created", "changed", "uploaded", "acknowledged", "changed", "return"}, types)
+
+	require.Equal(t, "created for USD 12.04", events[0].Description)
+	require.Equal(t, "status changed from pending to processed", events[1].Description)
+	require.Equal(t, "uploaded in 20200601-987654320.ach to 987654320", events[2].Description)
+	require.Equal(t, "20200601-987654320.ach was accepted by the ODFI", events[3].Description)
+	require.Equal(t, "returnCode changed to R01", events[4].Description)
+	require.Equal(t, "return received with code R01", events[5].Description)
+	require.Equal(t, "R01", events[5].Code)
+}
+
+func TestRouter__GetTransferTimeline(t *testing.T) {
+	transferID := base.ID()
+	repo := &MockRepository{
+		Organization: "moov",
+		Transfers: []*client.Transfer{
+			{TransferID: transferID, Amount: client.Amount{Currency: "USD", Value: 100}, Created: time.Now()},
+		},
+	}
+
+	r := mux.NewRouter()
+	NewRouter(config.Empty(), repo, nil, mockCustomersClient(), mockDecryptor, mockStrategy, fakePublisher, nil, nil, nil, nil, nil).RegisterRoutes(r)
+
+	req := httptest.NewRequest("GET", "/transfers/"+transferID+"/timeline", nil)
+	req.Header.Set("X-Organization", "moov")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var events []client.TransferEvent
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&events))
+	require.Len(t, events, 1)
+	require.Equal(t, "created", events[0].Type)
+
+	// other organization
+	req = httptest.NewRequest("GET", "/transfers/"+transferID+"/timeline", nil)
+	req.Header.Set("X-Organization", "other")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
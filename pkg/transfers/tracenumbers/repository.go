@@ -0,0 +1,71 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+// Package tracenumbers hands out the trace numbers of originated entries from a sequence saved
+// in the database, so restarts and other instances never repeat a trace number.
+package tracenumbers
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/moov-io/paygate/pkg/achx"
+	"github.com/moov-io/paygate/pkg/database"
+)
+
+// maxSequence is the largest sequence which fits in the last 7 digits of a trace number
+const maxSequence = 9999999
+
+func NewRepo(db *sql.DB) *sqlRepo {
+	return &sqlRepo{db: db}
+}
+
+type sqlRepo struct {
+	db *sql.DB
+}
+
+// Next returns the ODFI's next trace number. Sequences increase for each routing number
+// and start over at 1 after 9999999.
+func (r *sqlRepo) Next(routingNumber string) (string, error) {
+	seq, err := r.nextSequence(routingNumber)
+	if err != nil && database.UniqueViolation(err) {
+		// another instance created the routing number's first sequence, so increment theirs
+		seq, err = r.nextSequence(routingNumber)
+	}
+	if err != nil {
+		return "", err
+	}
+	return achx.FormatTraceNumber(routingNumber, seq), nil
+}
+
+func (r *sqlRepo) nextSequence(routingNumber string) (int, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+
+	// The update locks the row until our transaction commits, so concurrent callers wait
+	// for us rather than reading the same sequence.
+	query := `update trace_number_sequences set sequence = (sequence % ?) + 1 where routing_number = ?;`
+	res, err := tx.Exec(query, maxSequence, routingNumber)
+	if err != nil {
+		tx.Rollback()
+		return 0, fmt.Errorf("incrementing sequence: %v", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		query = `insert into trace_number_sequences(routing_number, sequence) values (?, 1);`
+		if _, err := tx.Exec(query, routingNumber); err != nil {
+			tx.Rollback()
+			return 0, err
+		}
+	}
+
+	var seq int
+	query = `select sequence from trace_number_sequences where routing_number = ?;`
+	if err := tx.QueryRow(query, routingNumber).Scan(&seq); err != nil {
+		tx.Rollback()
+		return 0, fmt.Errorf("reading sequence: %v", err)
+	}
+	return seq, tx.Commit()
+}
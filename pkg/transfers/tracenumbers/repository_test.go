@@ -0,0 +1,62 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package tracenumbers
+
+import (
+	"testing"
+
+	"github.com/moov-io/paygate/pkg/database"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepository__Next(t *testing.T) {
+	t.Parallel()
+
+	check := func(t *testing.T, repo *sqlRepo) {
+		traceNumber, err := repo.Next("987654320")
+		require.NoError(t, err)
+		require.Equal(t, "987654320000001", traceNumber)
+
+		traceNumber, err = repo.Next("987654320")
+		require.NoError(t, err)
+		require.Equal(t, "987654320000002", traceNumber)
+
+		// each routing number has its own sequence
+		traceNumber, err = repo.Next("121042882")
+		require.NoError(t, err)
+		require.Equal(t, "121042880000001", traceNumber)
+
+		// a new repository (e.g. after a restart) continues the sequence
+		traceNumber, err = NewRepo(repo.db).Next("987654320")
+		require.NoError(t, err)
+		require.Equal(t, "987654320000003", traceNumber)
+
+		// sequences start over once they don't fit in a trace number
+		_, err = repo.db.Exec(`update trace_number_sequences set sequence = ? where routing_number = ?;`, maxSequence, "987654320")
+		require.NoError(t, err)
+
+		traceNumber, err = repo.Next("987654320")
+		require.NoError(t, err)
+		require.Equal(t, "987654320000001", traceNumber)
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+}
+
+func setupSQLiteDB(t *testing.T) *sqlRepo {
+	db := database.CreateTestSqliteDB(t)
+	t.Cleanup(func() { db.Close() })
+
+	return NewRepo(db.DB)
+}
+
+func setupMySQLeDB(t *testing.T) *sqlRepo {
+	db := database.CreateTestMySQLDB(t)
+	t.Cleanup(func() { db.Close() })
+
+	return NewRepo(db.DB)
+}
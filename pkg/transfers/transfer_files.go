@@ -0,0 +1,95 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package transfers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/x/route"
+)
+
+// getTransferFiles returns the entries of a Transfer from the upload history, matched on
+// the Transfer's trace numbers. Other entries of the merged files aren't included.
+func (r *sqlRepo) getTransferFiles(orgID string, transferID string) ([]*client.TransferFile, error) {
+	query := `select f.filename, f.remote_server, f.routing_number, e.batch_number, e.trace_number, e.entry_detail, b.status, b.reason, f.uploaded_at, f.acknowledged_at
+from transfer_trace_numbers as tn
+inner join transfers as t on tn.transfer_id = t.transfer_id
+inner join uploaded_file_entries as e on tn.trace_number = e.trace_number
+inner join uploaded_files as f on e.file_id = f.file_id
+left join uploaded_file_batches as b on e.file_id = b.file_id and e.batch_number = b.batch_number
+where tn.transfer_id = ? and t.organization = ? and t.deleted_at is null
+order by f.uploaded_at asc, e.trace_number asc;`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.Query(transferID, orgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []*client.TransferFile
+	for rows.Next() {
+		var file client.TransferFile
+		var remoteServer, status, reason *string
+		if err := rows.Scan(
+			&file.Filename, &remoteServer, &file.RoutingNumber, &file.BatchNumber, &file.TraceNumber, &file.EntryDetail,
+			&status, &reason, &file.Uploaded, &file.Acknowledged,
+		); err != nil {
+			return nil, err
+		}
+		if remoteServer != nil {
+			file.RemoteServer = *remoteServer
+		}
+		if status != nil {
+			file.Status = *status
+		}
+		if reason != nil {
+			file.Reason = *reason
+		}
+		out = append(out, &file)
+	}
+	return out, rows.Err()
+}
+
+// GetTransferFiles returns where and when a Transfer was uploaded to the ODFI along with
+// the EntryDetail records written for it.
+func GetTransferFiles(cfg *config.Config, repo Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(cfg, w, r)
+
+		transferID := getTransferID(r)
+		orgID, err := repo.GetTransferOrganization(transferID)
+		if err != nil {
+			responder.Problem(err)
+			return
+		}
+		if orgID == "" || orgID != responder.OrganizationID {
+			responder.Problem(fmt.Errorf("transferID=%s not found", transferID))
+			return
+		}
+
+		files, err := repo.getTransferFiles(responder.OrganizationID, transferID)
+		if err != nil {
+			responder.Problem(err)
+			return
+		}
+		if files == nil {
+			files = make([]*client.TransferFile, 0)
+		}
+
+		responder.Respond(func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(files)
+		})
+	}
+}
@@ -0,0 +1,104 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package transfers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/moov-io/ach"
+	"github.com/moov-io/base"
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/transfers/files"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepository__getTransferFiles(t *testing.T) {
+	t.Parallel()
+
+	file, err := ach.ReadFile(filepath.Join("..", "..", "testdata", "ppd-debit.ach"))
+	require.NoError(t, err)
+	entry := file.Batches[0].GetEntries()[0]
+
+	check := func(t *testing.T, repo *sqlRepo) {
+		xfer := writeTransfer(t, "moov", repo)
+
+		found, err := repo.getTransferFiles("moov", xfer.TransferID)
+		require.NoError(t, err)
+		require.Empty(t, found)
+
+		require.NoError(t, repo.saveTraceNumbers(xfer.TransferID, []string{entry.TraceNumber}))
+
+		filename := base.ID() + ".ach"
+		require.NoError(t, files.NewRepo(repo.db).RecordUpload(files.Upload{Filename: filename, RemoteServer: "sftp.bank.com:22", Uploaded: time.Now()}, file))
+
+		found, err = repo.getTransferFiles("moov", xfer.TransferID)
+		require.NoError(t, err)
+		require.Len(t, found, 1)
+		require.Equal(t, filename, found[0].Filename)
+		require.Equal(t, "sftp.bank.com:22", found[0].RemoteServer)
+		require.Equal(t, file.Header.ImmediateDestination, found[0].RoutingNumber)
+		require.Equal(t, int32(file.Batches[0].GetHeader().BatchNumber), found[0].BatchNumber)
+		require.Equal(t, entry.TraceNumber, found[0].TraceNumber)
+		require.Equal(t, entry.String(), found[0].EntryDetail)
+		require.Equal(t, files.StatusPending, found[0].Status)
+		require.Nil(t, found[0].Acknowledged)
+
+		// other organizations can't read them
+		found, err = repo.getTransferFiles("other", xfer.TransferID)
+		require.NoError(t, err)
+		require.Empty(t, found)
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+}
+
+func TestRouter__GetTransferFiles(t *testing.T) {
+	transferID := base.ID()
+	repo := &MockRepository{
+		Organization: "moov",
+		Files: []*client.TransferFile{
+			{
+				Filename:      "20200601-987654320.ach",
+				RemoteServer:  "sftp.bank.com:22",
+				RoutingNumber: "987654320",
+				BatchNumber:   1,
+				TraceNumber:   "121042880000001",
+				Status:        files.StatusAccepted,
+				Uploaded:      time.Now(),
+			},
+		},
+	}
+
+	r := mux.NewRouter()
+	NewRouter(config.Empty(), repo, nil, mockCustomersClient(), mockDecryptor, mockStrategy, fakePublisher, nil, nil, nil, nil, nil).RegisterRoutes(r)
+
+	req := httptest.NewRequest("GET", "/transfers/"+transferID+"/files", nil)
+	req.Header.Set("X-Organization", "moov")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var found []*client.TransferFile
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&found))
+	require.Len(t, found, 1)
+	require.Equal(t, "sftp.bank.com:22", found[0].RemoteServer)
+	require.Equal(t, files.StatusAccepted, found[0].Status)
+
+	// other organization
+	req = httptest.NewRequest("GET", "/transfers/"+transferID+"/files", nil)
+	req.Header.Set("X-Organization", "other")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
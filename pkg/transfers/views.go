@@ -0,0 +1,157 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package transfers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/moov-io/base"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/organization"
+	"github.com/moov-io/paygate/x/route"
+)
+
+const maxViewNameLength = 100
+
+// getUserID returns the user saved views belong to. Views are shared across an
+// organization when the header is missing.
+func getUserID(r *http.Request) string {
+	return route.GetHeaderValue("X-User-ID", r)
+}
+
+// readRequestedView applies the saved view named by the "view" query parameter onto params.
+func readRequestedView(repo Repository, orgID string, r *http.Request, params transferFilterParams) (transferFilterParams, error) {
+	viewID := r.URL.Query().Get("view")
+	if viewID == "" {
+		return params, nil
+	}
+	view, err := repo.getTransferView(orgID, getUserID(r), viewID)
+	if err != nil {
+		return params, err
+	}
+	if view == nil {
+		return params, fmt.Errorf("viewID=%s not found", viewID)
+	}
+	return applyTransferView(params, view, r.URL.Query()), nil
+}
+
+// applyTransferView fills in filters from a saved view which weren't set on the request.
+func applyTransferView(params transferFilterParams, view *client.TransferView, q url.Values) transferFilterParams {
+	if q.Get("status") == "" && view.Status != "" {
+		params.Status = view.Status
+	}
+	if q.Get("startDate") == "" && !view.StartDate.IsZero() {
+		params.StartDate = view.StartDate
+	}
+	if q.Get("endDate") == "" && !view.EndDate.IsZero() {
+		params.EndDate = view.EndDate
+	}
+	if q.Get("tags") == "" && len(view.Tags) > 0 {
+		params.Tags = view.Tags
+	}
+	return params
+}
+
+func GetTransferViews(cfg *config.Config, repo Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(cfg, w, r)
+
+		views, err := repo.getTransferViews(responder.OrganizationID, getUserID(r))
+		if err != nil {
+			responder.Problem(err)
+			return
+		}
+
+		responder.Respond(func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(views)
+		})
+	}
+}
+
+func CreateTransferView(cfg *config.Config, repo Repository, orgRepo organization.Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(cfg, w, r)
+
+		var req client.CreateTransferView
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			responder.Problem(fmt.Errorf("reading view: %v", err))
+			return
+		}
+		if err := validateTransferView(req); err != nil {
+			responder.Problem(err)
+			return
+		}
+		orgConfig, err := orgRepo.GetConfig(responder.OrganizationID)
+		if err != nil {
+			responder.Problem(fmt.Errorf("getting org config: error getting config: %v", err))
+			return
+		}
+		if err := validateTransferTags(orgConfig, req.Tags); err != nil {
+			responder.Problem(err)
+			return
+		}
+
+		view := &client.TransferView{
+			ViewID:    base.ID(),
+			Name:      req.Name,
+			Status:    req.Status,
+			StartDate: req.StartDate,
+			EndDate:   req.EndDate,
+			Tags:      req.Tags,
+			Created:   time.Now(),
+		}
+		if err := repo.createTransferView(responder.OrganizationID, getUserID(r), view); err != nil {
+			responder.Problem(err)
+			return
+		}
+
+		responder.Respond(func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(view)
+		})
+	}
+}
+
+func validateTransferView(req client.CreateTransferView) error {
+	if req.Name == "" {
+		return errors.New("missing view name")
+	}
+	if len(req.Name) > maxViewNameLength {
+		return fmt.Errorf("view name is longer than %d characters", maxViewNameLength)
+	}
+	switch req.Status {
+	case "", client.CANCELED, client.FAILED, client.REVIEWABLE, client.PENDING, client.PROCESSED:
+	default:
+		return fmt.Errorf("unknown status %q", req.Status)
+	}
+	if !req.StartDate.IsZero() && !req.EndDate.IsZero() && req.EndDate.Before(req.StartDate) {
+		return errors.New("endDate is before startDate")
+	}
+	return nil
+}
+
+func DeleteTransferView(cfg *config.Config, repo Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(cfg, w, r)
+
+		viewID := route.ReadPathID("viewID", r)
+		if err := repo.deleteTransferView(responder.OrganizationID, getUserID(r), viewID); err != nil {
+			responder.Problem(err)
+			return
+		}
+
+		responder.Respond(func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusOK)
+		})
+	}
+}
@@ -0,0 +1,176 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package transfers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/moov-io/base"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/organization"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepository__transferViews(t *testing.T) {
+	t.Parallel()
+
+	check := func(t *testing.T, repo *sqlRepo) {
+		orgID, userID := base.ID(), base.ID()
+
+		views, err := repo.getTransferViews(orgID, userID)
+		require.NoError(t, err)
+		require.Len(t, views, 0)
+
+		view := &client.TransferView{
+			ViewID:    base.ID(),
+			Name:      "June payroll",
+			Status:    client.PROCESSED,
+			StartDate: time.Date(2020, time.June, 1, 0, 0, 0, 0, time.UTC),
+			Tags:      []string{"payroll", "june"},
+			Created:   time.Now(),
+		}
+		require.NoError(t, repo.createTransferView(orgID, userID, view))
+
+		found, err := repo.getTransferView(orgID, userID, view.ViewID)
+		require.NoError(t, err)
+		require.Equal(t, "June payroll", found.Name)
+		require.Equal(t, client.PROCESSED, found.Status)
+		require.True(t, found.EndDate.IsZero())
+		require.Equal(t, []string{"payroll", "june"}, found.Tags)
+
+		// views belong to each user
+		views, err = repo.getTransferViews(orgID, base.ID())
+		require.NoError(t, err)
+		require.Len(t, views, 0)
+
+		require.NoError(t, repo.deleteTransferView(orgID, userID, view.ViewID))
+		require.Error(t, repo.deleteTransferView(orgID, userID, view.ViewID))
+
+		found, err = repo.getTransferView(orgID, userID, view.ViewID)
+		require.NoError(t, err)
+		require.Nil(t, found)
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+}
+
+func TestTransfers__applyTransferView(t *testing.T) {
+	view := &client.TransferView{
+		Status:    client.FAILED,
+		StartDate: time.Date(2020, time.June, 1, 0, 0, 0, 0, time.UTC),
+		Tags:      []string{"payroll"},
+	}
+	params := readTransferFilterParams(&http.Request{})
+	endDate := params.EndDate
+
+	out := applyTransferView(params, view, url.Values{})
+	require.Equal(t, client.FAILED, out.Status)
+	require.Equal(t, view.StartDate, out.StartDate)
+	require.Equal(t, endDate, out.EndDate)
+	require.Equal(t, []string{"payroll"}, out.Tags)
+
+	// request filters take precedence
+	params.Status = client.PENDING
+	out = applyTransferView(params, view, url.Values{"status": []string{"pending"}})
+	require.Equal(t, client.PENDING, out.Status)
+}
+
+func TestRouter__transferViews(t *testing.T) {
+	repo := &MockRepository{
+		Transfers: repoWithTransfer.Transfers,
+	}
+	orgRepo := &organization.MockRepository{
+		Config: &client.OrganizationConfiguration{
+			TransferTags: []string{"payroll"},
+		},
+	}
+
+	r := mux.NewRouter()
+	router := NewRouter(config.Empty(), repo, orgRepo, mockCustomersClient(), mockDecryptor, mockStrategy, fakePublisher, nil, nil, nil, nil, nil)
+	router.RegisterRoutes(r)
+
+	// create a view
+	var body bytes.Buffer
+	json.NewEncoder(&body).Encode(client.CreateTransferView{
+		Name: "June payroll",
+		Tags: []string{"payroll"},
+	})
+	req := httptest.NewRequest("POST", "/transfers/views", &body)
+	req.Header.Set("X-Organization", "moov")
+	req.Header.Set("X-User-ID", "jane")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	w.Flush()
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var view client.TransferView
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&view))
+	require.NotEmpty(t, view.ViewID)
+
+	// unknown tag
+	body.Reset()
+	json.NewEncoder(&body).Encode(client.CreateTransferView{
+		Name: "other",
+		Tags: []string{"other"},
+	})
+	req = httptest.NewRequest("POST", "/transfers/views", &body)
+	req.Header.Set("X-Organization", "moov")
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	w.Flush()
+	require.Equal(t, http.StatusBadRequest, w.Code)
+
+	// list transfers through the view
+	repo.Views = []*client.TransferView{&view}
+	req = httptest.NewRequest("GET", "/transfers?view="+view.ViewID, nil)
+	req.Header.Set("X-Organization", "moov")
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	w.Flush()
+	require.Equal(t, http.StatusOK, w.Code)
+
+	req = httptest.NewRequest("GET", "/transfers?view=missing", nil)
+	req.Header.Set("X-Organization", "moov")
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	w.Flush()
+	require.Equal(t, http.StatusBadRequest, w.Code)
+
+	// list and delete views
+	req = httptest.NewRequest("GET", "/transfers/views", nil)
+	req.Header.Set("X-Organization", "moov")
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	w.Flush()
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var views []client.TransferView
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&views))
+	require.Len(t, views, 1)
+
+	req = httptest.NewRequest("DELETE", "/transfers/views/"+view.ViewID, nil)
+	req.Header.Set("X-Organization", "moov")
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	w.Flush()
+	require.Equal(t, http.StatusOK, w.Code)
+}
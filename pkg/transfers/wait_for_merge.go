@@ -0,0 +1,61 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package transfers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+)
+
+// readWaitForMerge reads the waitForMerge query parameter, which is either "true" to wait
+// as long as allowed or a duration like 30s. It returns false when the request doesn't wait.
+func readWaitForMerge(cfg *config.WaitForMerge, r *http.Request) (time.Duration, bool, error) {
+	v := r.URL.Query().Get("waitForMerge")
+	if v == "" || v == "false" {
+		return 0, false, nil
+	}
+	if cfg == nil {
+		return 0, false, errors.New("waitForMerge is not enabled")
+	}
+	if v == "true" {
+		return cfg.Timeout(0), true, nil
+	}
+	dur, err := time.ParseDuration(v)
+	if err != nil || dur <= 0 {
+		return 0, false, fmt.Errorf("invalid waitForMerge=%q", v)
+	}
+	return cfg.Timeout(dur), true, nil
+}
+
+// waitForMerge polls the upload history until the Transfer's entries are found in an uploaded
+// file. Merging happens on workers at each cutoff, so no files are returned if the wait ends first.
+func waitForMerge(ctx context.Context, cfg *config.WaitForMerge, repo Repository, orgID, transferID string, wait time.Duration) ([]*client.TransferFile, error) {
+	ctx, cancel := context.WithTimeout(ctx, wait)
+	defer cancel()
+
+	ticker := time.NewTicker(cfg.Interval())
+	defer ticker.Stop()
+
+	for {
+		files, err := repo.getTransferFiles(orgID, transferID)
+		if err != nil {
+			return nil, fmt.Errorf("waiting for transferID=%s to be merged: %v", transferID, err)
+		}
+		if len(files) > 0 {
+			return files, nil
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return nil, nil
+		}
+	}
+}
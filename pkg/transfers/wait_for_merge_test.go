@@ -0,0 +1,67 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package transfers
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransfers__readWaitForMerge(t *testing.T) {
+	cfg := &config.WaitForMerge{MaxTimeout: 30 * time.Second}
+
+	read := func(cfg *config.WaitForMerge, query string) (time.Duration, bool, error) {
+		return readWaitForMerge(cfg, httptest.NewRequest("POST", "/transfers"+query, nil))
+	}
+
+	_, waiting, err := read(cfg, "")
+	require.NoError(t, err)
+	require.False(t, waiting)
+
+	wait, waiting, err := read(cfg, "?waitForMerge=true")
+	require.NoError(t, err)
+	require.True(t, waiting)
+	require.Equal(t, 30*time.Second, wait)
+
+	wait, _, err = read(cfg, "?waitForMerge=5s")
+	require.NoError(t, err)
+	require.Equal(t, 5*time.Second, wait)
+
+	wait, _, err = read(cfg, "?waitForMerge=1h")
+	require.NoError(t, err)
+	require.Equal(t, 30*time.Second, wait)
+
+	_, _, err = read(cfg, "?waitForMerge=soon")
+	require.Error(t, err)
+
+	// disabled
+	_, _, err = read(nil, "?waitForMerge=true")
+	require.Error(t, err)
+}
+
+func TestTransfers__waitForMerge(t *testing.T) {
+	cfg := &config.WaitForMerge{PollInterval: 10 * time.Millisecond}
+	repo := &MockRepository{}
+
+	// not merged before the wait ends
+	files, err := waitForMerge(context.Background(), cfg, repo, "moov", "transferID", 50*time.Millisecond)
+	require.NoError(t, err)
+	require.Empty(t, files)
+
+	repo.Files = []*client.TransferFile{
+		{Filename: "20200601-987654320-1.ach", RoutingNumber: "987654320", TraceNumber: "121042880000001"},
+	}
+	files, err = waitForMerge(context.Background(), cfg, repo, "moov", "transferID", time.Second)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	require.Equal(t, "121042880000001", files[0].TraceNumber)
+}
@@ -6,6 +6,7 @@ package upload
 
 import (
 	"errors"
+	"fmt"
 
 	"github.com/moov-io/paygate/pkg/config"
 
@@ -28,22 +29,28 @@ type Agent interface {
 	Close() error
 }
 
+// New returns the Agent for the ODFI's configured protocol.
 func New(logger log.Logger, cfg config.ODFI) (Agent, error) {
-	if cfg.FTP != nil {
+	switch protocol := cfg.UploadProtocol(); protocol {
+	case config.ProtocolFTP:
 		return newFTPTransferAgent(logger, cfg)
-	}
-	if cfg.SFTP != nil {
+	case config.ProtocolSFTP:
 		return newSFTPTransferAgent(logger, cfg)
+	case config.ProtocolAPI:
+		return newAPITransferAgent(logger, cfg)
+	case config.ProtocolBlob:
+		return newBlobTransferAgent(logger, cfg)
+	case "":
+		return nil, errors.New("upload: no protocol configured")
+	default:
+		return nil, fmt.Errorf("upload: unknown protocol=%q", protocol)
 	}
-	return nil, errors.New("upload: unknown Agent type")
 }
 
+// Type returns the ODFI's protocol, or "unknown" when none is configured.
 func Type(cfg config.ODFI) string {
-	if cfg.FTP != nil {
-		return "ftp"
-	}
-	if cfg.SFTP != nil {
-		return "sftp"
+	if protocol := cfg.UploadProtocol(); protocol != "" {
+		return protocol
 	}
 	return "unknown"
 }
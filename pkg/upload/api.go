@@ -0,0 +1,181 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package upload
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/moov-io/ach"
+	"github.com/moov-io/base/log"
+
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/x/httpclient"
+)
+
+// APITransferAgent sends merged files to an ODFI's REST API instead of uploading them.
+// Each batch is mapped by a BatchMapper into its own request. The ODFI acknowledges
+// batches later on a callback, see inbound.APICallbacks.
+type APITransferAgent struct {
+	cfg      config.ODFI
+	logger   log.Logger
+	endpoint *url.URL
+	mapper   BatchMapper
+	client   *http.Client
+}
+
+func newAPITransferAgent(logger log.Logger, cfg config.ODFI) (*APITransferAgent, error) {
+	if cfg.API == nil {
+		return nil, errors.New("nil API config")
+	}
+	endpoint, err := url.Parse(cfg.API.Endpoint)
+	if err != nil || endpoint.Host == "" {
+		return nil, fmt.Errorf("api: invalid endpoint %q: %v", cfg.API.Endpoint, err)
+	}
+	mapper := lookupBatchMapper(cfg.API.MappingName())
+	if mapper == nil {
+		return nil, fmt.Errorf("api: unknown mapping %q", cfg.API.MappingName())
+	}
+	if err := rejectOutboundIPRange(cfg.SplitAllowedIPs(), endpoint.Host); err != nil {
+		return nil, fmt.Errorf("api: %s is not whitelisted: %v", endpoint.Host, err)
+	}
+	client, err := httpclient.New(cfg.API.HTTPClient, cfg.API.Timeout())
+	if err != nil {
+		return nil, fmt.Errorf("api: %v", err)
+	}
+	return &APITransferAgent{
+		cfg:      cfg,
+		logger:   logger,
+		endpoint: endpoint,
+		mapper:   mapper,
+		client:   client,
+	}, nil
+}
+
+// UploadFile reads f as a NACHA formatted file and sends each of its batches to the ODFI.
+func (agent *APITransferAgent) UploadFile(f File) error {
+	defer f.Close()
+
+	file, err := ach.NewReader(f.Contents).Read()
+	if err != nil {
+		return fmt.Errorf("api: reading %s: %v", f.Filename, err)
+	}
+	batches, err := agent.mapper.Map(f.Filename, &file)
+	if err != nil {
+		return fmt.Errorf("api: mapping %s: %v", f.Filename, err)
+	}
+	for i := range batches {
+		// The ODFI can drop repeated batches if we retry a file
+		key := fmt.Sprintf("%s-%d", f.Filename, i+1)
+		if err := agent.post("batches", key, batches[i]); err != nil {
+			return fmt.Errorf("api: sending batch %d of %s: %v", i+1, f.Filename, err)
+		}
+	}
+	agent.logger.Set("filename", f.Filename).Logf("sent %d batches to ODFI API", len(batches))
+	return nil
+}
+
+func (agent *APITransferAgent) post(path string, idempotencyKey string, body interface{}) error {
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(body); err != nil {
+		return err
+	}
+	req, err := agent.newRequest("POST", path, &buf)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Idempotency-Key", idempotencyKey)
+
+	resp, err := agent.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		bs, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("unexpected %s: %s", resp.Status, strings.TrimSpace(string(bs)))
+	}
+	return nil
+}
+
+func (agent *APITransferAgent) newRequest(method, path string, body io.Reader) (*http.Request, error) {
+	u := *agent.endpoint
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + path
+
+	req, err := http.NewRequest(method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	if agent.cfg.API.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+agent.cfg.API.Token)
+	}
+	return req, nil
+}
+
+// GetInboundFiles returns nothing as acknowledgements arrive on callbacks instead of files.
+func (agent *APITransferAgent) GetInboundFiles() ([]File, error) {
+	return nil, nil
+}
+
+// GetReturnFiles returns nothing as the ODFI's API doesn't offer files.
+func (agent *APITransferAgent) GetReturnFiles() ([]File, error) {
+	return nil, nil
+}
+
+func (agent *APITransferAgent) Delete(path string) error {
+	return nil
+}
+
+func (agent *APITransferAgent) InboundPath() string {
+	return agent.cfg.InboundPath
+}
+
+func (agent *APITransferAgent) OutboundPath() string {
+	return agent.cfg.OutboundPath
+}
+
+func (agent *APITransferAgent) ReturnPath() string {
+	return agent.cfg.ReturnPath
+}
+
+func (agent *APITransferAgent) Hostname() string {
+	if agent.endpoint == nil {
+		return ""
+	}
+	return agent.endpoint.Host
+}
+
+// Ping checks the ODFI's API responds. Any status below 500 is considered up.
+func (agent *APITransferAgent) Ping() error {
+	if agent == nil {
+		return errors.New("nil APITransferAgent")
+	}
+	req, err := agent.newRequest("GET", "ping", nil)
+	if err != nil {
+		return err
+	}
+	resp, err := agent.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 500 {
+		return fmt.Errorf("api: unexpected %s", resp.Status)
+	}
+	return nil
+}
+
+func (agent *APITransferAgent) Close() error {
+	return nil
+}
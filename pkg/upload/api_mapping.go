@@ -0,0 +1,99 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package upload
+
+import (
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/moov-io/ach"
+)
+
+// BatchMapper converts a merged ACH file into the request bodies of an ODFI's batch API.
+// Each returned value is encoded as JSON and sent as its own request.
+type BatchMapper interface {
+	Map(filename string, file *ach.File) ([]interface{}, error)
+}
+
+var (
+	batchMappersMu sync.RWMutex
+	batchMappers   = map[string]BatchMapper{
+		"json-batches": &jsonBatchMapper{},
+	}
+)
+
+// RegisterBatchMapper makes a BatchMapper available to config under name. Registering
+// a name twice replaces the earlier mapper.
+func RegisterBatchMapper(name string, mapper BatchMapper) {
+	batchMappersMu.Lock()
+	defer batchMappersMu.Unlock()
+	batchMappers[name] = mapper
+}
+
+func lookupBatchMapper(name string) BatchMapper {
+	batchMappersMu.RLock()
+	defer batchMappersMu.RUnlock()
+	return batchMappers[name]
+}
+
+// jsonBatchMapper sends each batch with its entries as one JSON object.
+type jsonBatchMapper struct{}
+
+type jsonBatch struct {
+	Filename                string      `json:"fileName"`
+	BatchNumber             int         `json:"batchNumber"`
+	ServiceClassCode        int         `json:"serviceClassCode"`
+	CompanyName             string      `json:"companyName"`
+	CompanyIdentification   string      `json:"companyIdentification"`
+	StandardEntryClassCode  string      `json:"secCode"`
+	CompanyEntryDescription string      `json:"companyEntryDescription"`
+	EffectiveEntryDate      string      `json:"effectiveEntryDate"`
+	Entries                 []jsonEntry `json:"entries"`
+}
+
+type jsonEntry struct {
+	TraceNumber          string `json:"traceNumber"`
+	TransactionCode      int    `json:"transactionCode"`
+	RoutingNumber        string `json:"routingNumber"`
+	AccountNumber        string `json:"accountNumber"`
+	Amount               int    `json:"amount"`
+	IdentificationNumber string `json:"identificationNumber,omitempty"`
+	IndividualName       string `json:"individualName"`
+}
+
+func (*jsonBatchMapper) Map(filename string, file *ach.File) ([]interface{}, error) {
+	if file == nil {
+		return nil, errors.New("nil ach.File")
+	}
+	var out []interface{}
+	for i := range file.Batches {
+		bh := file.Batches[i].GetHeader()
+		batch := jsonBatch{
+			Filename:                filename,
+			BatchNumber:             bh.BatchNumber,
+			ServiceClassCode:        bh.ServiceClassCode,
+			CompanyName:             strings.TrimSpace(bh.CompanyName),
+			CompanyIdentification:   strings.TrimSpace(bh.CompanyIdentification),
+			StandardEntryClassCode:  bh.StandardEntryClassCode,
+			CompanyEntryDescription: strings.TrimSpace(bh.CompanyEntryDescription),
+			EffectiveEntryDate:      bh.EffectiveEntryDate,
+		}
+		entries := file.Batches[i].GetEntries()
+		for j := range entries {
+			batch.Entries = append(batch.Entries, jsonEntry{
+				TraceNumber:          entries[j].TraceNumber,
+				TransactionCode:      entries[j].TransactionCode,
+				RoutingNumber:        entries[j].RDFIIdentification + entries[j].CheckDigit,
+				AccountNumber:        strings.TrimSpace(entries[j].DFIAccountNumber),
+				Amount:               entries[j].Amount,
+				IdentificationNumber: strings.TrimSpace(entries[j].IdentificationNumber),
+				IndividualName:       strings.TrimSpace(entries[j].IndividualName),
+			})
+		}
+		out = append(out, batch)
+	}
+	return out, nil
+}
@@ -0,0 +1,136 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package upload
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/moov-io/ach"
+	"github.com/moov-io/base/log"
+
+	"github.com/moov-io/paygate/pkg/config"
+
+	"github.com/stretchr/testify/require"
+)
+
+type apiBatches struct {
+	mu      sync.Mutex
+	batches []jsonBatch
+	keys    []string
+	status  int
+}
+
+func (b *apiBatches) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if r.Header.Get("Authorization") != "Bearer token" {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	switch r.URL.Path {
+	case "/v1/ping":
+		w.WriteHeader(http.StatusOK)
+	case "/v1/batches":
+		if b.status != 0 {
+			w.WriteHeader(b.status)
+			return
+		}
+		var batch jsonBatch
+		if err := json.NewDecoder(r.Body).Decode(&batch); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		b.batches = append(b.batches, batch)
+		b.keys = append(b.keys, r.Header.Get("Idempotency-Key"))
+		w.WriteHeader(http.StatusAccepted)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func apiAgent(t *testing.T, handler http.Handler) *APITransferAgent {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	agent, err := newAPITransferAgent(log.NewNopLogger(), config.ODFI{
+		API: &config.API{
+			Endpoint:      server.URL + "/v1",
+			Token:         "token",
+			CallbackToken: "callback",
+		},
+	})
+	require.NoError(t, err)
+	return agent
+}
+
+func TestAPI__UploadFile(t *testing.T) {
+	handler := &apiBatches{}
+	agent := apiAgent(t, handler)
+
+	require.NoError(t, agent.Ping())
+	require.Equal(t, "api", Type(agent.cfg))
+
+	fd, err := os.Open(filepath.Join("..", "..", "testdata", "ppd-debit.ach"))
+	require.NoError(t, err)
+
+	err = agent.UploadFile(File{
+		Filename: "20200601-987654320-1.ach",
+		Contents: fd,
+	})
+	require.NoError(t, err)
+
+	require.Len(t, handler.batches, 1)
+	require.Equal(t, []string{"20200601-987654320-1.ach-1"}, handler.keys)
+
+	batch := handler.batches[0]
+	require.Equal(t, "20200601-987654320-1.ach", batch.Filename)
+	require.Equal(t, "PPD", batch.StandardEntryClassCode)
+	require.Len(t, batch.Entries, 1)
+	require.Equal(t, ach.CheckingDebit, batch.Entries[0].TransactionCode)
+	require.Len(t, batch.Entries[0].RoutingNumber, 9)
+
+	// rejected requests fail the upload
+	handler.status = http.StatusBadRequest
+	fd, err = os.Open(filepath.Join("..", "..", "testdata", "ppd-debit.ach"))
+	require.NoError(t, err)
+
+	err = agent.UploadFile(File{Filename: "20200601-987654320-2.ach", Contents: fd})
+	require.Error(t, err)
+}
+
+func TestAPI__UploadFileInvalid(t *testing.T) {
+	agent := apiAgent(t, &apiBatches{})
+
+	fd, err := os.Open(filepath.Join("..", "..", "testdata", "ppd-valid.json"))
+	require.NoError(t, err)
+
+	err = agent.UploadFile(File{Filename: "20200601-987654320-1.ach", Contents: fd})
+	require.Error(t, err)
+}
+
+func TestAPI__config(t *testing.T) {
+	_, err := newAPITransferAgent(log.NewNopLogger(), config.ODFI{})
+	require.Error(t, err)
+
+	_, err = newAPITransferAgent(log.NewNopLogger(), config.ODFI{
+		API: &config.API{Endpoint: "http://localhost", Mapping: "other"},
+	})
+	require.Error(t, err)
+
+	RegisterBatchMapper("other", &jsonBatchMapper{})
+	_, err = newAPITransferAgent(log.NewNopLogger(), config.ODFI{
+		API: &config.API{Endpoint: "http://localhost", Mapping: "other"},
+	})
+	require.NoError(t, err)
+}
@@ -0,0 +1,208 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package upload
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/moov-io/base/log"
+
+	"github.com/moov-io/paygate/pkg/config"
+
+	"gocloud.dev/blob"
+)
+
+// BlobTransferAgent uploads files into a bucket the ODFI polls. Buckets are any
+// gocloud.dev/blob URI, so S3, GCS and Azure are supported.
+//
+// Inbound and return files are read from prefixes of the ODFI's bucket. Files for
+// routing numbers listed in the config's Destinations are uploaded to their own bucket.
+type BlobTransferAgent struct {
+	cfg    config.ODFI
+	logger log.Logger
+
+	mu      sync.Mutex // protects buckets
+	buckets map[string]*blob.Bucket
+}
+
+func newBlobTransferAgent(logger log.Logger, cfg config.ODFI) (*BlobTransferAgent, error) {
+	if cfg.Blob == nil {
+		return nil, errors.New("nil Blob config")
+	}
+	agent := &BlobTransferAgent{
+		cfg:     cfg,
+		logger:  logger,
+		buckets: make(map[string]*blob.Bucket),
+	}
+	if _, err := agent.bucket(cfg.Blob.BucketURI); err != nil {
+		return nil, err
+	}
+	return agent, nil
+}
+
+// bucket opens uri once and keeps it for later calls.
+func (agent *BlobTransferAgent) bucket(uri string) (*blob.Bucket, error) {
+	agent.mu.Lock()
+	defer agent.mu.Unlock()
+
+	if b, exists := agent.buckets[uri]; exists {
+		return b, nil
+	}
+	b, err := blob.OpenBucket(context.Background(), uri)
+	if err != nil {
+		return nil, fmt.Errorf("blob: opening %s: %v", uri, err)
+	}
+	agent.buckets[uri] = b
+	return b, nil
+}
+
+func (agent *BlobTransferAgent) GetInboundFiles() ([]File, error) {
+	return agent.readFiles(agent.InboundPath())
+}
+
+func (agent *BlobTransferAgent) GetReturnFiles() ([]File, error) {
+	return agent.readFiles(agent.ReturnPath())
+}
+
+func (agent *BlobTransferAgent) readFiles(prefix string) ([]File, error) {
+	bucket, err := agent.bucket(agent.cfg.Blob.BucketURI)
+	if err != nil {
+		return nil, err
+	}
+	ctx := context.Background()
+
+	var files []File
+	iter := bucket.List(&blob.ListOptions{
+		Prefix:    prefix,
+		Delimiter: "/",
+	})
+	for {
+		obj, err := iter.Next(ctx)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return files, fmt.Errorf("blob: listing %s: %v", prefix, err)
+		}
+		if obj.IsDir {
+			continue
+		}
+		bs, err := bucket.ReadAll(ctx, obj.Key)
+		if err != nil {
+			return files, fmt.Errorf("blob: reading %s: %v", obj.Key, err)
+		}
+		files = append(files, File{
+			Filename: path.Base(obj.Key),
+			Contents: ioutil.NopCloser(bytes.NewReader(bs)),
+		})
+	}
+	return files, nil
+}
+
+func (agent *BlobTransferAgent) UploadFile(f File) error {
+	defer f.Close()
+
+	uri := agent.cfg.Blob.Bucket(f.RoutingNumber)
+	bucket, err := agent.bucket(uri)
+	if err != nil {
+		return err
+	}
+
+	key := agent.OutboundPath() + f.Filename
+	w, err := bucket.NewWriter(context.Background(), key, nil)
+	if err != nil {
+		return fmt.Errorf("blob: writing %s: %v", key, err)
+	}
+	if _, err := io.Copy(w, f.Contents); err != nil {
+		w.Close()
+		return fmt.Errorf("blob: writing %s: %v", key, err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("blob: writing %s: %v", key, err)
+	}
+
+	agent.logger.Set("bucket", uri).Logf("blob: uploaded %s", key)
+	return nil
+}
+
+// Delete removes path from the ODFI's bucket, such as "inbound/20200601.ach".
+func (agent *BlobTransferAgent) Delete(path string) error {
+	if path == "" || strings.HasSuffix(path, "/") {
+		return fmt.Errorf("blob: invalid path %v", path)
+	}
+	bucket, err := agent.bucket(agent.cfg.Blob.BucketURI)
+	if err != nil {
+		return err
+	}
+	return bucket.Delete(context.Background(), strings.TrimPrefix(path, "/"))
+}
+
+func (agent *BlobTransferAgent) InboundPath() string {
+	return blobPrefix(agent.cfg.InboundPath)
+}
+
+func (agent *BlobTransferAgent) OutboundPath() string {
+	return blobPrefix(agent.cfg.OutboundPath)
+}
+
+func (agent *BlobTransferAgent) ReturnPath() string {
+	return blobPrefix(agent.cfg.ReturnPath)
+}
+
+func (agent *BlobTransferAgent) Hostname() string {
+	if agent.cfg.Blob == nil {
+		return ""
+	}
+	return agent.cfg.Blob.BucketURI
+}
+
+func (agent *BlobTransferAgent) Ping() error {
+	bucket, err := agent.bucket(agent.cfg.Blob.BucketURI)
+	if err != nil {
+		return err
+	}
+	iter := bucket.List(&blob.ListOptions{
+		Prefix: agent.InboundPath(),
+	})
+	if _, err := iter.Next(context.Background()); err != nil && err != io.EOF {
+		return fmt.Errorf("blob: %v", err)
+	}
+	return nil
+}
+
+func (agent *BlobTransferAgent) Close() error {
+	if agent == nil {
+		return nil
+	}
+	agent.mu.Lock()
+	defer agent.mu.Unlock()
+
+	var firstErr error
+	for uri, b := range agent.buckets {
+		if err := b.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		delete(agent.buckets, uri)
+	}
+	return firstErr
+}
+
+// blobPrefix converts a config path into a key prefix, bucket keys don't start with "/"
+// and prefixes end with one.
+func blobPrefix(p string) string {
+	p = strings.TrimPrefix(p, "/")
+	if p == "" {
+		return ""
+	}
+	return withTrailingSlash(p)
+}
@@ -0,0 +1,91 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package upload
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/moov-io/paygate/pkg/config"
+
+	"github.com/moov-io/base/log"
+	"github.com/stretchr/testify/require"
+)
+
+func blobDir(t *testing.T) string {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "blob")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	for _, sub := range []string{"inbound", "outbound", "returned"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, sub), 0777))
+	}
+	return dir
+}
+
+func TestBlob(t *testing.T) {
+	odfi, other := blobDir(t), blobDir(t)
+
+	agent, err := New(log.NewNopLogger(), config.ODFI{
+		InboundPath:  "/inbound",
+		OutboundPath: "outbound/",
+		ReturnPath:   "returned",
+		Blob: &config.Blob{
+			BucketURI: "file://" + filepath.ToSlash(odfi),
+			Destinations: []config.BlobDestination{
+				{RoutingNumber: "231380104", BucketURI: "file://" + filepath.ToSlash(other)},
+			},
+		},
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { agent.Close() })
+
+	require.NoError(t, agent.Ping())
+	require.Equal(t, "inbound/", agent.InboundPath())
+	require.Equal(t, "blob", Type(agent.(*BlobTransferAgent).cfg))
+
+	// uploads
+	require.NoError(t, agent.UploadFile(File{
+		Filename:      "20200601-987654320-1.ach",
+		Contents:      ioutil.NopCloser(strings.NewReader("odfi")),
+		RoutingNumber: "987654320",
+	}))
+	require.NoError(t, agent.UploadFile(File{
+		Filename:      "20200601-231380104-1.ach",
+		Contents:      ioutil.NopCloser(strings.NewReader("other")),
+		RoutingNumber: "231380104",
+	}))
+
+	bs, err := ioutil.ReadFile(filepath.Join(odfi, "outbound", "20200601-987654320-1.ach"))
+	require.NoError(t, err)
+	require.Equal(t, "odfi", string(bs))
+
+	bs, err = ioutil.ReadFile(filepath.Join(other, "outbound", "20200601-231380104-1.ach"))
+	require.NoError(t, err)
+	require.Equal(t, "other", string(bs))
+
+	// inbound and returned files
+	require.NoError(t, ioutil.WriteFile(filepath.Join(odfi, "inbound", "cor.ach"), []byte("cor"), 0644))
+
+	files, err := agent.GetInboundFiles()
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	require.Equal(t, "cor.ach", files[0].Filename)
+
+	files, err = agent.GetReturnFiles()
+	require.NoError(t, err)
+	require.Len(t, files, 0)
+
+	require.NoError(t, agent.Delete(filepath.Join(agent.InboundPath(), "cor.ach")))
+	_, err = os.Stat(filepath.Join(odfi, "inbound", "cor.ach"))
+	require.True(t, os.IsNotExist(err))
+
+	require.Error(t, agent.Delete("inbound/"))
+}
@@ -11,6 +11,9 @@ import (
 type File struct {
 	Filename string
 	Contents io.ReadCloser
+
+	// RoutingNumber is the ImmediateDestination of outbound files
+	RoutingNumber string
 }
 
 func (f File) Close() error {
@@ -0,0 +1,102 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package upload
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/moov-io/paygate"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/x/httpclient"
+)
+
+// FilenameProvider names files before they're uploaded to the ODFI.
+type FilenameProvider interface {
+	Filename(data FilenameData) (string, error)
+}
+
+// NewFilenameProvider returns the provider picked by the ODFI's OutboundFilename config.
+func NewFilenameProvider(cfg config.ODFI) (FilenameProvider, error) {
+	switch name := cfg.OutboundFilename.ProviderName(); name {
+	case config.FilenameProviderTemplate:
+		return &templateFilenames{raw: cfg.FilenameTemplate()}, nil
+	case config.FilenameProviderHTTP:
+		client, err := httpclient.New(cfg.OutboundFilename.HTTPClient, cfg.OutboundFilename.RequestTimeout())
+		if err != nil {
+			return nil, fmt.Errorf("filename provider: %v", err)
+		}
+		return &httpFilenames{
+			endpoint: strings.TrimSpace(cfg.OutboundFilename.Endpoint),
+			client:   client,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown filename provider %q", name)
+	}
+}
+
+type templateFilenames struct {
+	raw string
+}
+
+func (t *templateFilenames) Filename(data FilenameData) (string, error) {
+	return RenderACHFilename(t.raw, data)
+}
+
+// httpFilenames POSTs FilenameData as JSON to an endpoint which responds with
+// the filename, for ODFIs which require names computed from data we don't template.
+type httpFilenames struct {
+	endpoint string
+	client   *http.Client
+}
+
+type filenameResponse struct {
+	Filename string `json:"filename"`
+}
+
+func (h *httpFilenames) Filename(data FilenameData) (string, error) {
+	var body bytes.Buffer
+	if err := json.NewEncoder(&body).Encode(data); err != nil {
+		return "", fmt.Errorf("filename provider: encode: %v", err)
+	}
+
+	req, err := http.NewRequest("POST", h.endpoint, &body)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", fmt.Sprintf("moov/paygate %v filenames", paygate.Version))
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("filename provider: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return "", fmt.Errorf("filename provider: unexpected status %s", resp.Status)
+	}
+
+	var out filenameResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1024*1024)).Decode(&out); err != nil {
+		return "", fmt.Errorf("filename provider: decode: %v", err)
+	}
+	return out.Filename, validateFilename(out.Filename)
+}
+
+func validateFilename(filename string) error {
+	if filename == "" {
+		return errors.New("filename provider: empty filename")
+	}
+	if strings.ContainsAny(filename, `/\`) || filename == "." || filename == ".." {
+		return fmt.Errorf("filename provider: invalid filename %q", filename)
+	}
+	return nil
+}
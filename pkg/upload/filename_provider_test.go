@@ -0,0 +1,72 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package upload
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/moov-io/paygate/pkg/config"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilenameProvider__template(t *testing.T) {
+	provider, err := NewFilenameProvider(config.ODFI{})
+	require.NoError(t, err)
+
+	filename, err := provider.Filename(FilenameData{
+		RoutingNumber: "987654320",
+		Sequence:      1,
+	})
+	require.NoError(t, err)
+	require.Equal(t, fmt.Sprintf("%s-987654320-1.ach", time.Now().Format("20060102")), filename)
+}
+
+func TestFilenameProvider__http(t *testing.T) {
+	var response string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var data FilenameData
+		if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if response == "" {
+			response = fmt.Sprintf("%s-%x.ach", data.RoutingNumber, sha256.Sum256(data.Contents))
+		}
+		json.NewEncoder(w).Encode(filenameResponse{Filename: response})
+	}))
+	t.Cleanup(server.Close)
+
+	provider, err := NewFilenameProvider(config.ODFI{
+		OutboundFilename: &config.OutboundFilename{
+			Provider: "http",
+			Endpoint: server.URL,
+		},
+	})
+	require.NoError(t, err)
+
+	contents := []byte("101 987654320 1234567890")
+	filename, err := provider.Filename(FilenameData{
+		RoutingNumber: "987654320",
+		Contents:      contents,
+	})
+	require.NoError(t, err)
+	require.Equal(t, fmt.Sprintf("987654320-%x.ach", sha256.Sum256(contents)), filename)
+
+	// filenames can't escape the outbound path
+	response = "../other.ach"
+	_, err = provider.Filename(FilenameData{RoutingNumber: "987654320"})
+	require.Error(t, err)
+
+	server.Close()
+	_, err = provider.Filename(FilenameData{RoutingNumber: "987654320"})
+	require.Error(t, err)
+}
@@ -12,13 +12,27 @@ import (
 	"strings"
 	"text/template"
 	"time"
+
+	"github.com/moov-io/ach"
 )
 
 type FilenameData struct {
-	RoutingNumber string
+	RoutingNumber string `json:"routingNumber"`
 
 	// GPG is true if the file has been encrypted with GPG
-	GPG bool
+	GPG bool `json:"gpg"`
+
+	// Sequence is the file's number (starting at 1) out of those sent to RoutingNumber today
+	Sequence int `json:"sequence"`
+
+	// IAT is true for files of international (IAT) batches, which are never merged with
+	// domestic batches
+	IAT bool `json:"iat"`
+
+	// File and Contents are the merged file and the bytes which are uploaded. They're
+	// only set for files about to be uploaded.
+	File     *ach.File `json:"file,omitempty"`
+	Contents []byte    `json:"contents,omitempty"`
 }
 
 var filenameFunctions template.FuncMap = map[string]interface{}{
@@ -52,6 +66,19 @@ func RoundSequenceNumber(seq int) string {
 	return string(rune(65 + seq - 10)) // A, B, ...
 }
 
+// FileIDModifier converts a file's sequence (starting at 1) into the FileIDModifier of its
+// FileHeader, which NACHA requires to be unique for each file sent to a destination in a day.
+// Modifiers are A-Z followed by 0-9, so at most 36 files can be sent per day.
+func FileIDModifier(seq int) (string, error) {
+	switch {
+	case seq >= 1 && seq <= 26:
+		return string(rune('A' + seq - 1)), nil
+	case seq > 26 && seq <= 36:
+		return fmt.Sprintf("%d", seq-27), nil
+	}
+	return "", fmt.Errorf("no FileIDModifier for sequence %d", seq)
+}
+
 // achFilenameSeq returns the sequence number from a given achFilename
 // A sequence number of 0 indicates an error
 func ACHFilenameSeq(filename string) int {
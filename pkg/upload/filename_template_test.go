@@ -32,12 +32,28 @@ func TestFilenameTemplate(t *testing.T) {
 	filename, err := RenderACHFilename(config.DefaultFilenameTemplate, FilenameData{
 		RoutingNumber: "987654320",
 		GPG:           true,
+		Sequence:      2,
 	})
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	expected := fmt.Sprintf("%s-987654320.ach.gpg", time.Now().Format("20060102"))
+	expected := fmt.Sprintf("%s-987654320-2.ach.gpg", time.Now().Format("20060102"))
+	if filename != expected {
+		t.Errorf("filename=%s", filename)
+	}
+
+	// IAT files
+	filename, err = RenderACHFilename(config.DefaultFilenameTemplate, FilenameData{
+		RoutingNumber: "987654320",
+		Sequence:      3,
+		IAT:           true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected = fmt.Sprintf("%s-987654320-3-IAT.ach", time.Now().Format("20060102"))
 	if filename != expected {
 		t.Errorf("filename=%s", filename)
 	}
@@ -97,6 +113,25 @@ func TestFilenameTemplate__RoundSequenceNumber(t *testing.T) {
 	}
 }
 
+func TestFilenameTemplate__FileIDModifier(t *testing.T) {
+	cases := map[int]string{1: "A", 2: "B", 26: "Z", 27: "0", 36: "9"}
+	for seq, expected := range cases {
+		modifier, err := FileIDModifier(seq)
+		if err != nil {
+			t.Fatalf("seq=%d: %v", seq, err)
+		}
+		if modifier != expected {
+			t.Errorf("seq=%d got %s", seq, modifier)
+		}
+	}
+	if _, err := FileIDModifier(0); err == nil {
+		t.Error("expected error")
+	}
+	if _, err := FileIDModifier(37); err == nil {
+		t.Error("expected error")
+	}
+}
+
 func TestFilenameTemplate__ACHFilenameSeq(t *testing.T) {
 	if n := ACHFilenameSeq("20060102-987654320-1.ach"); n != 1 {
 		t.Errorf("n=%d", n)
@@ -0,0 +1,213 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package upload
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/moov-io/base"
+	"github.com/moov-io/base/log"
+
+	"github.com/moov-io/paygate/pkg/config"
+
+	"gocloud.dev/blob"
+	_ "gocloud.dev/blob/azureblob"
+	_ "gocloud.dev/blob/fileblob"
+	_ "gocloud.dev/blob/gcsblob"
+	_ "gocloud.dev/blob/memblob"
+	_ "gocloud.dev/blob/s3blob"
+	"gocloud.dev/gcerrors"
+)
+
+// MailboxAgent reads files a bank pushes into a bucket or directory rather than
+// PayGate polling their FTP or SFTP server. Mailboxes are inbound only.
+//
+// Files are claimed by moving them under claimed/<instance>/ before they're read,
+// which keeps multiple PayGate instances from processing the same file. Delete
+// removes the claimed copy, so anything left under claimed/ was not fully processed.
+type MailboxAgent struct {
+	cfg    config.Mailbox
+	logger log.Logger
+	bucket *blob.Bucket
+
+	claimPrefix string
+}
+
+// NewMailboxes returns an Agent for each configured mailbox.
+func NewMailboxes(logger log.Logger, cfgs []config.Mailbox) ([]Agent, error) {
+	var agents []Agent
+	for i := range cfgs {
+		agent, err := newMailboxAgent(logger, cfgs[i])
+		if err != nil {
+			for j := range agents {
+				agents[j].Close()
+			}
+			return nil, fmt.Errorf("mailbox %s: %v", cfgs[i].RoutingNumber, err)
+		}
+		agents = append(agents, agent)
+	}
+	return agents, nil
+}
+
+func newMailboxAgent(logger log.Logger, cfg config.Mailbox) (*MailboxAgent, error) {
+	uri := cfg.BucketURI
+	if cfg.Directory != "" {
+		dir, err := filepath.Abs(cfg.Directory)
+		if err != nil {
+			return nil, err
+		}
+		uri = "file://" + filepath.ToSlash(dir)
+	}
+	bucket, err := blob.OpenBucket(context.Background(), uri)
+	if err != nil {
+		return nil, err
+	}
+
+	instance, err := os.Hostname()
+	if err != nil || instance == "" {
+		instance = base.ID()
+	}
+
+	return &MailboxAgent{
+		cfg:         cfg,
+		logger:      logger.Set("mailbox", cfg.RoutingNumber),
+		bucket:      bucket,
+		claimPrefix: fmt.Sprintf("claimed/%s/", instance),
+	}, nil
+}
+
+func (agent *MailboxAgent) GetInboundFiles() ([]File, error) {
+	return agent.readFiles(agent.InboundPath())
+}
+
+func (agent *MailboxAgent) GetReturnFiles() ([]File, error) {
+	return agent.readFiles(agent.ReturnPath())
+}
+
+func (agent *MailboxAgent) readFiles(prefix string) ([]File, error) {
+	ctx := context.Background()
+
+	var files []File
+	iter := agent.bucket.List(&blob.ListOptions{
+		Prefix:    prefix,
+		Delimiter: "/",
+	})
+	for {
+		obj, err := iter.Next(ctx)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return files, fmt.Errorf("mailbox: listing %s: %v", prefix, err)
+		}
+		if obj.IsDir {
+			continue
+		}
+
+		claimed, err := agent.claim(ctx, obj.Key)
+		if err != nil {
+			return files, fmt.Errorf("mailbox: claiming %s: %v", obj.Key, err)
+		}
+		if claimed == "" {
+			continue
+		}
+		bs, err := agent.bucket.ReadAll(ctx, claimed)
+		if err != nil {
+			return files, fmt.Errorf("mailbox: reading %s: %v", claimed, err)
+		}
+		files = append(files, File{
+			Filename: path.Base(obj.Key),
+			Contents: ioutil.NopCloser(bytes.NewReader(bs)),
+		})
+	}
+	return files, nil
+}
+
+// claim moves key under our claimPrefix and returns the new key. An empty key
+// is returned when another instance claimed the file first.
+func (agent *MailboxAgent) claim(ctx context.Context, key string) (string, error) {
+	claimed := agent.claimPrefix + key
+	if err := agent.bucket.Copy(ctx, claimed, key, nil); err != nil {
+		if gcerrors.Code(err) == gcerrors.NotFound {
+			return "", nil
+		}
+		return "", err
+	}
+	if err := agent.bucket.Delete(ctx, key); err != nil {
+		if gcerrors.Code(err) == gcerrors.NotFound {
+			// Another instance deleted the original first, so it owns the file
+			agent.logger.Logf("mailbox: %s was claimed by another instance", key)
+			return "", agent.bucket.Delete(ctx, claimed)
+		}
+		return "", err
+	}
+	return claimed, nil
+}
+
+func (agent *MailboxAgent) UploadFile(f File) error {
+	f.Close()
+	return errors.New("mailbox: uploads are not supported")
+}
+
+// Delete removes the claimed copy of path, which is relative to the mailbox
+// such as "inbound/20200601.ach".
+func (agent *MailboxAgent) Delete(path string) error {
+	if path == "" || strings.HasSuffix(path, "/") {
+		return fmt.Errorf("mailbox: invalid path %v", path)
+	}
+	return agent.bucket.Delete(context.Background(), agent.claimPrefix+filepath.ToSlash(path))
+}
+
+func (agent *MailboxAgent) InboundPath() string {
+	return withTrailingSlash(agent.cfg.Inbound())
+}
+
+func (agent *MailboxAgent) OutboundPath() string {
+	return ""
+}
+
+func (agent *MailboxAgent) ReturnPath() string {
+	return withTrailingSlash(agent.cfg.Return())
+}
+
+func (agent *MailboxAgent) Hostname() string {
+	if agent.cfg.Directory != "" {
+		return agent.cfg.Directory
+	}
+	return agent.cfg.BucketURI
+}
+
+func (agent *MailboxAgent) Ping() error {
+	iter := agent.bucket.List(&blob.ListOptions{
+		Prefix: agent.InboundPath(),
+	})
+	if _, err := iter.Next(context.Background()); err != nil && err != io.EOF {
+		return fmt.Errorf("mailbox: %v", err)
+	}
+	return nil
+}
+
+func (agent *MailboxAgent) Close() error {
+	if agent == nil || agent.bucket == nil {
+		return nil
+	}
+	return agent.bucket.Close()
+}
+
+func withTrailingSlash(path string) string {
+	if strings.HasSuffix(path, "/") {
+		return path
+	}
+	return path + "/"
+}
@@ -0,0 +1,103 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package upload
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/moov-io/paygate/pkg/config"
+
+	"github.com/moov-io/base/log"
+	"github.com/stretchr/testify/require"
+)
+
+func setupMailbox(t *testing.T) (string, *MailboxAgent) {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "mailbox")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "inbound"), 0777))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "returned"), 0777))
+
+	agent, err := newMailboxAgent(log.NewNopLogger(), config.Mailbox{
+		RoutingNumber: "987654320",
+		Directory:     dir,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { agent.Close() })
+
+	return dir, agent
+}
+
+func TestMailbox(t *testing.T) {
+	dir, agent := setupMailbox(t)
+	require.NoError(t, agent.Ping())
+
+	path := filepath.Join(dir, "inbound", "20200601.ach")
+	require.NoError(t, ioutil.WriteFile(path, []byte("contents"), 0644))
+
+	files, err := agent.GetInboundFiles()
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	require.Equal(t, "20200601.ach", files[0].Filename)
+
+	bs, err := ioutil.ReadAll(files[0].Contents)
+	require.NoError(t, err)
+	require.Equal(t, "contents", string(bs))
+
+	// the file was claimed so isn't read again
+	_, err = os.Stat(path)
+	require.True(t, os.IsNotExist(err))
+
+	files, err = agent.GetInboundFiles()
+	require.NoError(t, err)
+	require.Len(t, files, 0)
+
+	files, err = agent.GetReturnFiles()
+	require.NoError(t, err)
+	require.Len(t, files, 0)
+
+	// Delete removes the claimed copy
+	claimed := filepath.Join(dir, filepath.FromSlash(agent.claimPrefix), "inbound", "20200601.ach")
+	_, err = os.Stat(claimed)
+	require.NoError(t, err)
+
+	require.NoError(t, agent.Delete(filepath.Join(agent.InboundPath(), "20200601.ach")))
+	_, err = os.Stat(claimed)
+	require.True(t, os.IsNotExist(err))
+
+	require.Error(t, agent.Delete("inbound/"))
+	require.Error(t, agent.UploadFile(File{Filename: "out.ach"}))
+}
+
+func TestMailbox__claims(t *testing.T) {
+	dir, first := setupMailbox(t)
+
+	second, err := newMailboxAgent(log.NewNopLogger(), first.cfg)
+	require.NoError(t, err)
+	defer second.Close()
+	second.claimPrefix = "claimed/other/"
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "returned", "return.ach"), []byte("contents"), 0644))
+
+	files, err := first.GetReturnFiles()
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+
+	files, err = second.GetReturnFiles()
+	require.NoError(t, err)
+	require.Len(t, files, 0)
+
+	// Another instance removed the original after we listed it
+	claimed, err := second.claim(context.Background(), "returned/return.ach")
+	require.NoError(t, err)
+	require.Empty(t, claimed)
+}
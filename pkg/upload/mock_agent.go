@@ -38,6 +38,10 @@ func (a *MockAgent) UploadFile(f File) error {
 	a.mu.Lock()
 	defer a.mu.Unlock()
 
+	if a.Err != nil {
+		return a.Err
+	}
+
 	// read f.contents before callers close the underlying os.Open file descriptor
 	bs, _ := ioutil.ReadAll(f.Contents)
 	a.UploadedFile = &f
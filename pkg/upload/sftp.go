@@ -43,6 +43,9 @@ type SFTPTransferAgent struct {
 }
 
 func newSFTPTransferAgent(logger log.Logger, cfg config.ODFI) (*SFTPTransferAgent, error) {
+	if cfg.SFTP == nil {
+		return nil, errors.New("nil SFTP config")
+	}
 	agent := &SFTPTransferAgent{cfg: cfg, logger: logger}
 
 	if err := rejectOutboundIPRange(cfg.SplitAllowedIPs(), cfg.SFTP.Hostname); err != nil {
@@ -0,0 +1,17 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package emails
+
+import (
+	"github.com/go-kit/kit/metrics/prometheus"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	emailVerifications = prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+		Name: "email_verifications",
+		Help: "Counter of email verification links sent and confirmed by result",
+	}, []string{"result"})
+)
@@ -0,0 +1,36 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package emails
+
+import (
+	"time"
+)
+
+type MockRepository struct {
+	Verified map[string]string // customerID to verified email
+
+	Err error
+}
+
+func (r *MockRepository) getVerification(organization, customerID string) (*verification, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	if email, exists := r.Verified[customerID]; exists {
+		return &verification{email: email, verifiedAt: time.Now()}, nil
+	}
+	return nil, nil
+}
+
+func (r *MockRepository) saveVerification(organization, customerID, email string, when time.Time) error {
+	if r.Err != nil {
+		return r.Err
+	}
+	if r.Verified == nil {
+		r.Verified = make(map[string]string)
+	}
+	r.Verified[customerID] = normalize(email)
+	return nil
+}
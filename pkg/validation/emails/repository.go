@@ -0,0 +1,55 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package emails
+
+import (
+	"database/sql"
+	"time"
+)
+
+type Repository interface {
+	// getVerification returns the email address last verified by the Customer, or nil
+	// when they've never verified one.
+	getVerification(organization, customerID string) (*verification, error)
+
+	// saveVerification records the Customer verified their email, replacing any address
+	// they verified before.
+	saveVerification(organization, customerID, email string, when time.Time) error
+}
+
+type verification struct {
+	email      string
+	verifiedAt time.Time
+}
+
+func NewRepo(db *sql.DB) *sqlRepo {
+	return &sqlRepo{db: db}
+}
+
+type sqlRepo struct {
+	db *sql.DB
+}
+
+func (r *sqlRepo) Close() error {
+	return r.db.Close()
+}
+
+func (r *sqlRepo) getVerification(organization, customerID string) (*verification, error) {
+	query := `select email, verified_at from email_verifications where organization = ? and customer_id = ? limit 1;`
+	var out verification
+	if err := r.db.QueryRow(query, organization, customerID).Scan(&out.email, &out.verifiedAt); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (r *sqlRepo) saveVerification(organization, customerID, email string, when time.Time) error {
+	query := `replace into email_verifications (organization, customer_id, email, verified_at) values (?, ?, ?, ?);`
+	_, err := r.db.Exec(query, organization, customerID, normalize(email), when)
+	return err
+}
@@ -0,0 +1,63 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package emails
+
+import (
+	"testing"
+	"time"
+
+	"github.com/moov-io/base"
+
+	"github.com/moov-io/paygate/pkg/database"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepository__verifications(t *testing.T) {
+	t.Parallel()
+
+	check := func(t *testing.T, repo *sqlRepo) {
+		customerID := base.ID()
+		ver, err := repo.getVerification("moov", customerID)
+		require.NoError(t, err)
+		require.Nil(t, ver)
+
+		now := time.Now().Truncate(time.Second)
+		require.NoError(t, repo.saveVerification("moov", customerID, " Jane@Example.com", now))
+
+		ver, err = repo.getVerification("moov", customerID)
+		require.NoError(t, err)
+		require.Equal(t, "jane@example.com", ver.email)
+		require.True(t, now.Equal(ver.verifiedAt))
+
+		// other organizations aren't affected
+		ver, err = repo.getVerification("other", customerID)
+		require.NoError(t, err)
+		require.Nil(t, ver)
+
+		// verifying a new address replaces the old one
+		require.NoError(t, repo.saveVerification("moov", customerID, "jane@moov.io", now.Add(time.Hour)))
+		ver, err = repo.getVerification("moov", customerID)
+		require.NoError(t, err)
+		require.Equal(t, "jane@moov.io", ver.email)
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+}
+
+func setupSQLiteDB(t *testing.T) *sqlRepo {
+	db := database.CreateTestSqliteDB(t)
+	t.Cleanup(func() { db.Close() })
+
+	return NewRepo(db.DB)
+}
+
+func setupMySQLeDB(t *testing.T) *sqlRepo {
+	db := database.CreateTestMySQLDB(t)
+	t.Cleanup(func() { db.Close() })
+
+	return NewRepo(db.DB)
+}
@@ -0,0 +1,194 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package emails
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	moovcustomers "github.com/moov-io/customers/pkg/client"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/customers"
+	"github.com/moov-io/paygate/x/route"
+)
+
+type Router struct {
+	SendVerification http.HandlerFunc
+	GetVerification  http.HandlerFunc
+	VerifyEmail      http.HandlerFunc
+}
+
+func NewRouter(cfg *config.Config, repo Repository, customersClient customers.Client, sender Sender) *Router {
+	if cfg.Validation.Emails == nil || sender == nil {
+		return &Router{
+			SendVerification: NotImplemented(cfg),
+			GetVerification:  NotImplemented(cfg),
+			VerifyEmail:      NotImplemented(cfg),
+		}
+	}
+	return &Router{
+		SendVerification: SendVerification(cfg, repo, customersClient, sender),
+		GetVerification:  GetVerification(cfg, repo, customersClient),
+		VerifyEmail:      VerifyEmail(cfg, repo, customersClient),
+	}
+}
+
+func (c *Router) RegisterRoutes(r *mux.Router) {
+	r.Methods("POST").Path("/customers/{customerID}/email-verifications").HandlerFunc(c.SendVerification)
+	r.Methods("GET").Path("/customers/{customerID}/email-verification").HandlerFunc(c.GetVerification)
+	r.Methods("POST").Path("/customers/{customerID}/verify-email").HandlerFunc(c.VerifyEmail)
+}
+
+// lookupCustomer reads the Customer along with their current email address.
+func lookupCustomer(customersClient customers.Client, organization, customerID, requestID string) (*moovcustomers.Customer, error) {
+	if customerID == "" {
+		return nil, errors.New("missing customerID")
+	}
+	cust, err := customersClient.Lookup(organization, customerID, requestID)
+	if err != nil {
+		return nil, err
+	}
+	if cust == nil || cust.CustomerID == "" {
+		return nil, fmt.Errorf("customerID=%s is not found", customerID)
+	}
+	if cust.Email == "" {
+		return nil, fmt.Errorf("customerID=%s has no email", customerID)
+	}
+	return cust, nil
+}
+
+// currentVerification reports whether the Customer verified the email address they have now.
+func currentVerification(repo Repository, organization string, cust *moovcustomers.Customer) (*client.EmailVerification, error) {
+	ver, err := repo.getVerification(organization, cust.CustomerID)
+	if err != nil {
+		return nil, err
+	}
+	out := &client.EmailVerification{
+		CustomerID: cust.CustomerID,
+		Email:      cust.Email,
+	}
+	if ver != nil && ver.email == normalize(cust.Email) {
+		out.Verified = true
+		out.VerifiedAt = &ver.verifiedAt
+	}
+	return out, nil
+}
+
+func SendVerification(cfg *config.Config, repo Repository, customersClient customers.Client, sender Sender) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		conf := cfg.Validation.Emails
+		responder := route.NewResponder(cfg, w, r)
+
+		cust, err := lookupCustomer(customersClient, responder.OrganizationID, route.ReadPathID("customerID", r), responder.XRequestID)
+		if err != nil {
+			responder.Problem(err)
+			return
+		}
+		state, err := currentVerification(repo, responder.OrganizationID, cust)
+		if err != nil {
+			responder.Problem(err)
+			return
+		}
+		if state.Verified {
+			responder.Problem(fmt.Errorf("customerID=%s has already verified their email", cust.CustomerID))
+			return
+		}
+
+		expires := time.Now().Add(conf.LinkExpiration()).Truncate(time.Second)
+		token := signToken(conf.Secret, responder.OrganizationID, cust.CustomerID, cust.Email, expires)
+
+		subject := "Verify your email"
+		if conf.Email.CompanyName != "" {
+			subject = fmt.Sprintf("Verify your email for %s", conf.Email.CompanyName)
+		}
+		if err := sender.SendTo([]string{cust.Email}, subject, emailBody(linkURL(conf, cust.CustomerID, token), expires)); err != nil {
+			cfg.Logger.Set("customerID", cust.CustomerID).LogErrorf("problem sending email verification: %v", err)
+			responder.Problem(errors.New("problem sending email verification"))
+			return
+		}
+		emailVerifications.With("result", "sent").Add(1)
+
+		state.ExpiresAt = &expires
+		responder.Respond(func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(state)
+		})
+	}
+}
+
+func GetVerification(cfg *config.Config, repo Repository, customersClient customers.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(cfg, w, r)
+
+		cust, err := lookupCustomer(customersClient, responder.OrganizationID, route.ReadPathID("customerID", r), responder.XRequestID)
+		if err != nil {
+			responder.Problem(err)
+			return
+		}
+		state, err := currentVerification(repo, responder.OrganizationID, cust)
+		if err != nil {
+			responder.Problem(err)
+			return
+		}
+		responder.Respond(func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(state)
+		})
+	}
+}
+
+func VerifyEmail(cfg *config.Config, repo Repository, customersClient customers.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		conf := cfg.Validation.Emails
+		responder := route.NewResponder(cfg, w, r)
+
+		var req client.VerifyEmail
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			responder.Problem(err)
+			return
+		}
+		cust, err := lookupCustomer(customersClient, responder.OrganizationID, route.ReadPathID("customerID", r), responder.XRequestID)
+		if err != nil {
+			responder.Problem(err)
+			return
+		}
+		now := time.Now()
+		if err := checkToken(conf.Secret, responder.OrganizationID, cust.CustomerID, cust.Email, req.Token, now); err != nil {
+			emailVerifications.With("result", "invalid").Add(1)
+			responder.Problem(err)
+			return
+		}
+		if err := repo.saveVerification(responder.OrganizationID, cust.CustomerID, cust.Email, now); err != nil {
+			cfg.Logger.Set("customerID", cust.CustomerID).LogErrorf("problem saving email verification: %v", err)
+			responder.Problem(err)
+			return
+		}
+		emailVerifications.With("result", "verified").Add(1)
+
+		state := &client.EmailVerification{
+			CustomerID: cust.CustomerID,
+			Email:      cust.Email,
+			Verified:   true,
+			VerifiedAt: &now,
+		}
+		responder.Respond(func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(state)
+		})
+	}
+}
+
+func NotImplemented(cfg *config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(cfg, w, r)
+		responder.Problem(errors.New("email verification is disabled via config"))
+	}
+}
@@ -0,0 +1,225 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package microdeposits
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/moov-io/base/admin"
+	moovhttp "github.com/moov-io/base/http"
+	"github.com/moov-io/base/log"
+
+	paygateadmin "github.com/moov-io/paygate/pkg/admin"
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/webhooks"
+	"github.com/moov-io/paygate/x/adminauth"
+	"github.com/moov-io/paygate/x/route"
+)
+
+// RegisterAdminRoutes adds endpoints for operations to list pending verifications, expire them
+// (which sends the expired event), send their initiated event again and reset a lockout after too many incorrect confirmations.
+// Nothing is added when micro-deposits are disabled.
+func RegisterAdminRoutes(cfg *config.Config, svc *admin.Server, repo Repository, events webhooks.Sender) {
+	if cfg.Validation.MicroDeposits == nil {
+		return
+	}
+	svc.AddHandler("/micro-deposits/pending", listPendingVerifications(cfg, repo))
+	svc.AddHandler("/micro-deposits/{microDepositID}/expire", adminauth.Protect(cfg.Admin.Signing, expireVerification(cfg, repo, events)))
+	svc.AddHandler("/micro-deposits/{microDepositID}/resend", adminauth.Protect(cfg.Admin.Signing, resendVerification(cfg, repo, events)))
+	svc.AddHandler("/micro-deposits/{microDepositID}/lockout", adminauth.Protect(cfg.Admin.Signing, resetLockout(cfg, repo)))
+}
+
+func problem(w http.ResponseWriter, err error) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	moovhttp.Problem(w, err)
+}
+
+// pendingVerification returns micro-deposits the Customer can still confirm, or nil.
+func pendingVerification(cfg config.MicroDeposits, repo Repository, micro *client.MicroDeposits, now time.Time) *paygateadmin.PendingVerification {
+	state := verificationState(cfg, micro, now)
+	if state == nil || (state.Status != client.VERIFICATIONSTATUS_INITIATED && state.Status != client.VERIFICATIONSTATUS_PROCESSED) {
+		return nil
+	}
+	return &paygateadmin.PendingVerification{
+		MicroDepositID: micro.MicroDepositID,
+		Organization:   microDepositOrganization(repo, micro),
+		CustomerID:     micro.Destination.CustomerID,
+		AccountID:      micro.Destination.AccountID,
+		Status:         string(state.Status),
+		Attempt:        micro.Attempt,
+		Created:        micro.Created,
+		ExpiresAt:      state.ExpiresAt,
+		Age:            now.Sub(micro.Created).Truncate(time.Second).String(),
+	}
+}
+
+// microDepositOrganization reads the organization from the Transfers which sent micro.
+func microDepositOrganization(repo Repository, micro *client.MicroDeposits) string {
+	if len(micro.TransferIDs) == 0 {
+		return ""
+	}
+	_, organization, _ := repo.lookupMicroDepositFromTransfer(micro.TransferIDs[0])
+	return organization
+}
+
+func listPendingVerifications(cfg *config.Config, repo Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			problem(w, fmt.Errorf("invalid method %s", r.Method))
+			return
+		}
+		micros, err := repo.getUnverifiedMicroDeposits()
+		if err != nil {
+			problem(w, err)
+			return
+		}
+
+		now := time.Now()
+		pending := make([]*paygateadmin.PendingVerification, 0)
+		for i := range micros {
+			if p := pendingVerification(*cfg.Validation.MicroDeposits, repo, micros[i], now); p != nil {
+				pending = append(pending, p)
+			}
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(pending)
+	}
+}
+
+// readPendingVerification returns the micro-deposits from the request path if they can still be confirmed.
+func readPendingVerification(cfg *config.Config, repo Repository, r *http.Request) (*client.MicroDeposits, *paygateadmin.PendingVerification, error) {
+	microDepositID := route.ReadPathID("microDepositID", r)
+	if microDepositID == "" {
+		return nil, nil, errors.New("missing microDepositID")
+	}
+	micro, err := repo.getMicroDeposits(microDepositID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil, fmt.Errorf("microDepositID=%s not found", microDepositID)
+		}
+		return nil, nil, err
+	}
+	pending := pendingVerification(*cfg.Validation.MicroDeposits, repo, micro, time.Now())
+	if pending == nil {
+		return nil, nil, fmt.Errorf("microDepositID=%s is not pending verification", microDepositID)
+	}
+	return micro, pending, nil
+}
+
+func expireVerification(cfg *config.Config, repo Repository, events webhooks.Sender) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(cfg, w, r)
+
+		if r.Method != http.MethodPut {
+			responder.Problem(fmt.Errorf("unsupported HTTP verb %s", r.Method))
+			return
+		}
+		micro, pending, err := readPendingVerification(cfg, repo, r)
+		if err != nil {
+			responder.Problem(err)
+			return
+		}
+		now := time.Now()
+		if err := repo.expireMicroDeposits(micro.MicroDepositID, now); err != nil {
+			responder.Problem(err)
+			return
+		}
+		micro.ExpiresAt = &now
+
+		if sent, err := repo.markEventSent(micro.MicroDepositID, EventVerificationExpired, now); err != nil {
+			cfg.Logger.Set("microDepositID", micro.MicroDepositID).LogErrorf("ERROR recording %s event: %v", EventVerificationExpired, err)
+		} else if sent {
+			sendVerificationEvent(cfg.Logger, events, *cfg.Validation.MicroDeposits, EventVerificationExpired, pending.Organization, micro)
+		}
+
+		cfg.Logger.With(log.Fields{
+			"requestID":      responder.XRequestID,
+			"microDepositID": micro.MicroDepositID,
+			"organization":   pending.Organization,
+			"accountID":      micro.Destination.AccountID,
+			"age":            pending.Age,
+		}).Log("Expired micro-deposit verification")
+
+		responder.Respond(func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(verificationState(*cfg.Validation.MicroDeposits, micro, time.Now()))
+		})
+	}
+}
+
+func resendVerification(cfg *config.Config, repo Repository, events webhooks.Sender) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(cfg, w, r)
+
+		if r.Method != http.MethodPost {
+			responder.Problem(fmt.Errorf("unsupported HTTP verb %s", r.Method))
+			return
+		}
+		micro, pending, err := readPendingVerification(cfg, repo, r)
+		if err != nil {
+			responder.Problem(err)
+			return
+		}
+		sendVerificationEvent(cfg.Logger, events, *cfg.Validation.MicroDeposits, EventVerificationInitiated, pending.Organization, micro)
+
+		cfg.Logger.With(log.Fields{
+			"requestID":      responder.XRequestID,
+			"microDepositID": micro.MicroDepositID,
+			"organization":   pending.Organization,
+			"accountID":      micro.Destination.AccountID,
+			"age":            pending.Age,
+		}).Log("Re-sent micro-deposit verification")
+
+		responder.Respond(func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(pending)
+		})
+	}
+}
+
+func resetLockout(cfg *config.Config, repo Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(cfg, w, r)
+
+		if r.Method != http.MethodDelete {
+			responder.Problem(fmt.Errorf("unsupported HTTP verb %s", r.Method))
+			return
+		}
+		microDepositID := route.ReadPathID("microDepositID", r)
+		if microDepositID == "" {
+			responder.Problem(errors.New("missing microDepositID"))
+			return
+		}
+		conf := *cfg.Validation.MicroDeposits
+		if err := repo.resetLockout(microDepositID, conf.Links.Guesses()); err != nil {
+			responder.Problem(err)
+			return
+		}
+		micro, err := repo.getMicroDeposits(microDepositID)
+		if err != nil {
+			responder.Problem(err)
+			return
+		}
+
+		cfg.Logger.With(log.Fields{
+			"requestID":      responder.XRequestID,
+			"microDepositID": micro.MicroDepositID,
+			"organization":   microDepositOrganization(repo, micro),
+			"accountID":      micro.Destination.AccountID,
+		}).Log("Reset micro-deposit confirmation lockout")
+
+		responder.Respond(func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(verificationState(conf, micro, time.Now()))
+		})
+	}
+}
@@ -0,0 +1,115 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package microdeposits
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/moov-io/paygate/pkg/admin"
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/webhooks"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdmin__pendingVerifications(t *testing.T) {
+	micro := mockMicroDeposit()
+	micro.Created = time.Now().Add(-2 * time.Hour)
+
+	failed := mockMicroDeposit()
+	failed.Status = client.FAILED
+
+	repo := &mockRepository{
+		Micro:        micro,
+		Attempts:     []*client.MicroDeposits{micro, failed},
+		Organization: "moov",
+	}
+	events := &webhooks.MockSender{}
+	cfg := mockConfig()
+
+	router := mux.NewRouter()
+	router.Handle("/micro-deposits/pending", listPendingVerifications(cfg, repo))
+	router.Handle("/micro-deposits/{microDepositID}/expire", expireVerification(cfg, repo, events))
+	router.Handle("/micro-deposits/{microDepositID}/resend", resendVerification(cfg, repo, events))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/micro-deposits/pending", nil))
+	w.Flush()
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var pending []admin.PendingVerification
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&pending))
+	require.Len(t, pending, 1)
+	require.Equal(t, micro.MicroDepositID, pending[0].MicroDepositID)
+	require.Equal(t, "moov", pending[0].Organization)
+	require.Equal(t, string(client.VERIFICATIONSTATUS_INITIATED), pending[0].Status)
+	require.Equal(t, "2h0m0s", pending[0].Age)
+
+	// re-send the initiated event
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/micro-deposits/"+micro.MicroDepositID+"/resend", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Len(t, events.Events, 1)
+	require.Equal(t, EventVerificationInitiated, events.Events[0].Type)
+	require.Equal(t, "moov", events.Events[0].Organization)
+
+	// expire
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("PUT", "/micro-deposits/"+micro.MicroDepositID+"/expire", nil))
+	w.Flush()
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NotNil(t, micro.ExpiresAt)
+
+	var state client.AccountVerification
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&state))
+	require.Equal(t, client.VERIFICATIONSTATUS_EXPIRED, state.Status)
+	require.Len(t, events.Events, 2)
+	require.Equal(t, EventVerificationExpired, events.Events[1].Type)
+	require.True(t, repo.Sent[EventVerificationExpired+"/"+micro.MicroDepositID])
+
+	// expired verifications can't be expired or re-sent again
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("PUT", "/micro-deposits/"+micro.MicroDepositID+"/expire", nil))
+	require.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/micro-deposits/"+micro.MicroDepositID+"/resend", nil))
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	require.Len(t, events.Events, 2)
+}
+
+func TestAdmin__resetLockout(t *testing.T) {
+	micro := mockMicroDeposit()
+	micro.Status = client.FAILED
+	repo := &mockRepository{Micro: micro, Organization: "moov", Locked: true}
+	cfg := mockConfig()
+
+	router := mux.NewRouter()
+	router.Handle("/micro-deposits/{microDepositID}/lockout", resetLockout(cfg, repo))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/micro-deposits/"+micro.MicroDepositID+"/lockout", nil))
+	require.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("DELETE", "/micro-deposits/"+micro.MicroDepositID+"/lockout", nil))
+	w.Flush()
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.False(t, repo.Locked)
+
+	var state client.AccountVerification
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&state))
+	require.Equal(t, client.VERIFICATIONSTATUS_PROCESSED, state.Status)
+
+	// only locked out micro-deposits can be reset
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("DELETE", "/micro-deposits/"+micro.MicroDepositID+"/lockout", nil))
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
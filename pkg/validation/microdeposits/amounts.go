@@ -0,0 +1,62 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package microdeposits
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/moov-io/customers/pkg/secrets"
+	"github.com/moov-io/paygate/pkg/config"
+)
+
+// OpenAmountKeeper returns the keeper used to encrypt micro-deposit amounts stored in the database.
+// The micro-deposit keyURI is used when set, otherwise the key for decrypting account numbers.
+// A nil keeper is returned without either and amounts are stored in plaintext.
+func OpenAmountKeeper(cfg *config.Config) (*secrets.StringKeeper, error) {
+	var keyURI string
+	if cfg.Validation.MicroDeposits != nil {
+		keyURI = cfg.Validation.MicroDeposits.KeyURI
+	}
+	if keyURI == "" && cfg.Customers.Accounts.Decryptor.Symmetric != nil {
+		keyURI = cfg.Customers.Accounts.Decryptor.Symmetric.KeyURI
+	}
+	if keyURI == "" {
+		return nil, nil
+	}
+	keeper, err := secrets.OpenLocal(keyURI)
+	if err != nil {
+		return nil, fmt.Errorf("opening micro-deposit amount keeper: %v", err)
+	}
+	return secrets.NewStringKeeper(keeper, 5*time.Second), nil
+}
+
+func encryptAmount(keeper *secrets.StringKeeper, value int32) (string, error) {
+	return keeper.EncryptString(strconv.FormatInt(int64(value), 10))
+}
+
+// readAmount returns the value of a stored amount, decrypting it when it was stored encrypted.
+// Amounts written before encryption was enabled are only stored in plaintext.
+func readAmount(keeper *secrets.StringKeeper, plaintext *int32, encrypted *string) (int32, error) {
+	if encrypted == nil || *encrypted == "" {
+		if plaintext == nil {
+			return 0, fmt.Errorf("missing amount value")
+		}
+		return *plaintext, nil
+	}
+	if keeper == nil {
+		return 0, fmt.Errorf("encrypted amount found without a keeper")
+	}
+	value, err := keeper.DecryptString(*encrypted)
+	if err != nil {
+		return 0, fmt.Errorf("decrypting amount: %v", err)
+	}
+	n, err := strconv.ParseInt(value, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("parsing decrypted amount: %v", err)
+	}
+	return int32(n), nil
+}
@@ -0,0 +1,101 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package microdeposits
+
+import (
+	"testing"
+
+	"github.com/moov-io/customers/pkg/secrets"
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepository__encryptedAmounts(t *testing.T) {
+	t.Parallel()
+
+	check := func(t *testing.T, plain *sqlRepo) {
+		encrypted := NewRepo(plain.db, secrets.TestStringKeeper(t))
+
+		// amounts written before encryption was enabled are still read
+		old := writeMicroDeposits(t, plain)
+		micro, err := encrypted.getMicroDeposits(old.MicroDepositID)
+		require.NoError(t, err)
+		require.ElementsMatch(t, old.Amounts, micro.Amounts)
+
+		// new amounts aren't stored in plaintext
+		fresh := writeMicroDeposits(t, encrypted)
+		var plaintext int
+		require.NoError(t, plain.db.QueryRow(`select count(*) from micro_deposit_amounts where micro_deposit_id = ? and amount_value is not null`, fresh.MicroDepositID).Scan(&plaintext))
+		require.Equal(t, 0, plaintext)
+
+		micro, err = encrypted.getMicroDeposits(fresh.MicroDepositID)
+		require.NoError(t, err)
+		require.ElementsMatch(t, fresh.Amounts, micro.Amounts)
+
+		_, err = plain.getMicroDeposits(fresh.MicroDepositID)
+		require.Error(t, err)
+
+		// existing amounts are encrypted once
+		n, err := encrypted.EncryptAmounts()
+		require.NoError(t, err)
+		require.Equal(t, len(old.Amounts), n)
+
+		n, err = encrypted.EncryptAmounts()
+		require.NoError(t, err)
+		require.Equal(t, 0, n)
+
+		micro, err = encrypted.getMicroDeposits(old.MicroDepositID)
+		require.NoError(t, err)
+		require.ElementsMatch(t, old.Amounts, micro.Amounts)
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+}
+
+func TestRepository__encryptEqualAmounts(t *testing.T) {
+	plain := setupSQLiteDB(t)
+	encrypted := NewRepo(plain.db, secrets.TestStringKeeper(t))
+
+	micro := &client.MicroDeposits{
+		MicroDepositID: "equal",
+		Amounts: []client.Amount{
+			{Currency: "USD", Value: 7},
+			{Currency: "USD", Value: 7},
+		},
+		Status: client.PENDING,
+	}
+	require.NoError(t, plain.writeMicroDeposits(micro))
+
+	n, err := encrypted.EncryptAmounts()
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+
+	found, err := encrypted.getMicroDeposits(micro.MicroDepositID)
+	require.NoError(t, err)
+	require.Equal(t, micro.Amounts, found.Amounts)
+}
+
+func TestOpenAmountKeeper(t *testing.T) {
+	cfg := config.Empty()
+	keeper, err := OpenAmountKeeper(cfg)
+	require.NoError(t, err)
+	require.Nil(t, keeper)
+
+	cfg.Customers.Accounts.Decryptor.Symmetric = &config.Symmetric{
+		KeyURI: "MTIzNDU2Nzg5MDEyMzQ1Njc4OTAxMjM0NTY3ODkwMTI=",
+	}
+	keeper, err = OpenAmountKeeper(cfg)
+	require.NoError(t, err)
+	require.NotNil(t, keeper)
+
+	cfg.Validation.MicroDeposits = &config.MicroDeposits{
+		KeyURI: "invalid",
+	}
+	_, err = OpenAmountKeeper(cfg)
+	require.Error(t, err)
+}
@@ -0,0 +1,45 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package microdeposits
+
+import (
+	"time"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/webhooks"
+
+	"github.com/moov-io/base/log"
+)
+
+const (
+	EventVerificationInitiated = webhooks.EventVerificationInitiated
+	EventVerificationProcessed = webhooks.EventVerificationProcessed
+	EventVerificationFailed    = webhooks.EventVerificationFailed
+	EventVerificationCompleted = webhooks.EventVerificationCompleted
+	EventVerificationExpired   = webhooks.EventVerificationExpired
+)
+
+// sendVerificationEvent notifies external systems the verification state has changed.
+// Failures are logged as webhooks are not allowed to block micro-deposit processing.
+func sendVerificationEvent(logger log.Logger, events webhooks.Sender, cfg config.MicroDeposits, eventType string, organization string, micro *client.MicroDeposits) {
+	if events == nil || micro == nil {
+		return
+	}
+	event := webhooks.Event{
+		Type:         eventType,
+		Organization: organization,
+		Created:      time.Now(),
+		Data:         verificationState(cfg, micro, time.Now()),
+		Links: webhooks.Links(
+			webhooks.LinkMicroDeposit, micro.MicroDepositID,
+			webhooks.LinkCustomer, micro.Destination.CustomerID,
+			webhooks.LinkAccount, micro.Destination.AccountID,
+		),
+	}
+	if err := events.Send(event); err != nil {
+		logger.Set("microDepositID", micro.MicroDepositID).LogErrorf("problem sending %s webhook: %v", eventType, err)
+	}
+}
@@ -0,0 +1,416 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package microdeposits
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	moovcustomers "github.com/moov-io/customers/pkg/client"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/customers"
+	"github.com/moov-io/paygate/pkg/webhooks"
+	"github.com/moov-io/paygate/x/route"
+)
+
+// Verification links are tokens of the form microDepositID.expires.signature where expires
+// is a unix timestamp and signature is the hex encoded HMAC-SHA256 of the first two parts.
+// Holding a valid token is the only authentication needed to confirm the micro-deposits.
+
+var errInvalidLink = errors.New("invalid or expired verification link")
+
+func signLink(secret string, microDepositID string, expires time.Time) string {
+	payload := fmt.Sprintf("%s.%d", microDepositID, expires.Unix())
+	return payload + "." + linkSignature(secret, payload)
+}
+
+func linkSignature(secret string, payload string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// parseLink returns the microDepositID of a token which is correctly signed and unexpired.
+func parseLink(secret string, token string, now time.Time) (string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 || parts[0] == "" {
+		return "", errInvalidLink
+	}
+	expected := linkSignature(secret, parts[0]+"."+parts[1])
+	if !hmac.Equal([]byte(expected), []byte(parts[2])) {
+		return "", errInvalidLink
+	}
+	expires, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || now.After(time.Unix(expires, 0)) {
+		return "", errInvalidLink
+	}
+	return parts[0], nil
+}
+
+func linkURL(cfg *config.VerificationLinks, token string) string {
+	return strings.TrimSuffix(cfg.BaseURL, "/") + "/verify/" + token
+}
+
+// linkedMicroDeposits reads micro-deposits along with the organization which initiated them.
+func linkedMicroDeposits(repo Repository, microDepositID string) (*client.MicroDeposits, string, error) {
+	micro, err := repo.getMicroDeposits(microDepositID)
+	if err != nil {
+		return nil, "", err
+	}
+	if micro == nil || len(micro.TransferIDs) == 0 {
+		return nil, "", sql.ErrNoRows
+	}
+	_, organization, err := repo.lookupMicroDepositFromTransfer(micro.TransferIDs[0])
+	if err != nil {
+		return nil, "", err
+	}
+	return micro, organization, nil
+}
+
+// amountsMatch compares the Receiver's guesses against what was sent, in any order.
+func amountsMatch(sent []client.Amount, guesses []client.Amount) bool {
+	if len(sent) != len(guesses) {
+		return false
+	}
+	values := func(amounts []client.Amount) []int {
+		out := make([]int, len(amounts))
+		for i := range amounts {
+			out[i] = int(amounts[i].Value)
+		}
+		sort.Ints(out)
+		return out
+	}
+	want, got := values(sent), values(guesses)
+	for i := range want {
+		if want[i] != got[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func CreateVerificationLink(cfg *config.Config, repo Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		conf := *cfg.Validation.MicroDeposits
+
+		responder := route.NewResponder(cfg, w, r)
+		responder.Respond(func(w http.ResponseWriter) {
+			accountID := route.ReadPathID("accountID", r)
+			if accountID == "" {
+				responder.Problem(errors.New("missing accountID"))
+				return
+			}
+
+			latest, err := repo.getAccountMicroDeposits(accountID)
+			if err != nil && err != sql.ErrNoRows {
+				cfg.Logger.LogErrorf("ERROR reading accountID=%s micro-deposits: %v", accountID, err)
+				responder.Problem(err)
+				return
+			}
+			var organization string
+			if latest != nil {
+				latest, organization, err = linkedMicroDeposits(repo, latest.MicroDepositID)
+				if err != nil && err != sql.ErrNoRows {
+					cfg.Logger.LogErrorf("ERROR reading accountID=%s micro-deposits: %v", accountID, err)
+					responder.Problem(err)
+					return
+				}
+			}
+			if latest == nil || organization != responder.OrganizationID {
+				responder.Problem(fmt.Errorf("accountID=%s has no micro-deposits", accountID))
+				return
+			}
+
+			now := time.Now()
+			state := verificationState(conf, latest, now)
+			switch state.Status {
+			case client.VERIFICATIONSTATUS_INITIATED, client.VERIFICATIONSTATUS_PROCESSED:
+			default:
+				responder.Problem(fmt.Errorf("accountID=%s micro-deposits are %s", accountID, state.Status))
+				return
+			}
+
+			expires := now.Add(conf.Links.LinkExpiration())
+			if state.ExpiresAt != nil && state.ExpiresAt.Before(expires) {
+				expires = *state.ExpiresAt
+			}
+			token := signLink(conf.Links.Secret, latest.MicroDepositID, expires)
+
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(client.VerificationLink{
+				Token:     token,
+				Url:       linkURL(conf.Links, token),
+				ExpiresAt: expires.Truncate(time.Second),
+			})
+		})
+	}
+}
+
+func GetLinkedVerification(cfg *config.Config, repo Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		conf := *cfg.Validation.MicroDeposits
+		responder := route.NewResponder(cfg, w, r)
+
+		state, err := readLinkedVerification(conf, repo, route.ReadPathID("token", r))
+		if err != nil {
+			if err != errInvalidLink {
+				cfg.Logger.LogErrorf("ERROR reading linked verification: %v", err)
+			}
+			linkProblem(responder, w, r, err)
+			return
+		}
+
+		if wantsHTML(r) {
+			renderLinkPage(w, http.StatusOK, linkPage{State: state})
+			return
+		}
+		responder.Respond(func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(state)
+		})
+	}
+}
+
+func readLinkedVerification(conf config.MicroDeposits, repo Repository, token string) (*client.AccountVerification, error) {
+	microDepositID, err := parseLink(conf.Links.Secret, token, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	micro, err := repo.getMicroDeposits(microDepositID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errInvalidLink
+		}
+		return nil, err
+	}
+	if micro == nil {
+		return nil, errInvalidLink
+	}
+	return verificationState(conf, micro, time.Now()), nil
+}
+
+func ConfirmLinkedMicroDeposits(cfg *config.Config, repo Repository, customersClient customers.Client, events webhooks.Sender) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		conf := *cfg.Validation.MicroDeposits
+		responder := route.NewResponder(cfg, w, r)
+
+		microDepositID, err := parseLink(conf.Links.Secret, route.ReadPathID("token", r), time.Now())
+		if err != nil {
+			linkProblem(responder, w, r, err)
+			return
+		}
+		guesses, err := readConfirmation(r)
+		if err != nil {
+			linkProblem(responder, w, r, err)
+			return
+		}
+
+		micro, organization, err := linkedMicroDeposits(repo, microDepositID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				err = errInvalidLink
+			} else {
+				cfg.Logger.LogErrorf("ERROR reading microDepositID=%s: %v", microDepositID, err)
+			}
+			linkProblem(responder, w, r, err)
+			return
+		}
+		logger := cfg.Logger.Set("microDepositID", microDepositID)
+
+		state := verificationState(conf, micro, time.Now())
+		switch state.Status {
+		case client.VERIFICATIONSTATUS_PROCESSED:
+		case client.VERIFICATIONSTATUS_INITIATED:
+			linkProblem(responder, w, r, errors.New("micro-deposits have not been sent yet"))
+			return
+		case client.VERIFICATIONSTATUS_FAILED:
+			if locked, err := repo.lockedOut(microDepositID, conf.Links.Guesses()); err != nil {
+				logger.LogErrorf("ERROR reading lockout: %v", err)
+			} else if locked {
+				lockedOutProblem(responder, w, r, errors.New("too many incorrect confirmations, contact support to try again"))
+				return
+			}
+			linkProblem(responder, w, r, fmt.Errorf("micro-deposits are %s", state.Status))
+			return
+		default:
+			linkProblem(responder, w, r, fmt.Errorf("micro-deposits are %s", state.Status))
+			return
+		}
+
+		matched := amountsMatch(micro.Amounts, guesses)
+		if err := repo.recordConfirmation(microDepositID, matched, time.Now()); err != nil {
+			logger.LogErrorf("ERROR recording confirmation attempt: %v", err)
+		}
+		if !matched {
+			remaining, err := repo.failedConfirmation(microDepositID, conf.Links.Guesses())
+			if err != nil {
+				logger.LogErrorf("ERROR recording failed confirmation: %v", err)
+				linkProblem(responder, w, r, err)
+				return
+			}
+			micro.ConfirmationAttempts = int32(conf.Links.Guesses() - remaining)
+			if remaining == 0 {
+				micro.Status = client.FAILED
+				sendVerificationEvent(logger, events, conf, EventVerificationFailed, organization, micro)
+				linkProblem(responder, w, r, errors.New("incorrect amounts, no guesses remain"))
+				return
+			}
+			err = fmt.Errorf("incorrect amounts, %d guesses remain", remaining)
+			if wantsHTML(r) {
+				state = verificationState(conf, micro, time.Now())
+				renderLinkPage(w, http.StatusBadRequest, linkPage{State: state, Error: err.Error()})
+				return
+			}
+			responder.Problem(err)
+			return
+		}
+
+		dest := micro.Destination
+		if _, err := customersClient.UpdateAccountStatus(dest.CustomerID, dest.AccountID, moovcustomers.ACCOUNTSTATUS_VALIDATED); err != nil {
+			logger.LogErrorf("ERROR validating accountID=%s: %v", dest.AccountID, err)
+			linkProblem(responder, w, r, errors.New("problem validating account"))
+			return
+		}
+		now := time.Now()
+		if err := repo.verifyMicroDeposits(microDepositID, now); err != nil {
+			logger.LogErrorf("ERROR saving verification: %v", err)
+			linkProblem(responder, w, r, err)
+			return
+		}
+		micro.VerifiedAt = &now
+		sendVerificationEvent(logger, events, conf, EventVerificationCompleted, organization, micro)
+		logger.Log("micro-deposits confirmed from link")
+
+		state = verificationState(conf, micro, now)
+		if wantsHTML(r) {
+			renderLinkPage(w, http.StatusOK, linkPage{State: state})
+			return
+		}
+		responder.Respond(func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(state)
+		})
+	}
+}
+
+// readConfirmation reads guessed amounts from either a JSON body or the hosted form,
+// which submits dollar values like 0.07 as repeated amount fields.
+func readConfirmation(r *http.Request) ([]client.Amount, error) {
+	if !isFormPost(r) {
+		var req client.ConfirmMicroDeposits
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return nil, err
+		}
+		return req.Amounts, nil
+	}
+
+	if err := r.ParseForm(); err != nil {
+		return nil, err
+	}
+	var out []client.Amount
+	for _, v := range r.PostForm["amount"] {
+		dollars, err := strconv.ParseFloat(strings.TrimPrefix(strings.TrimSpace(v), "$"), 64)
+		if err != nil || dollars <= 0 {
+			return nil, fmt.Errorf("invalid amount %q", v)
+		}
+		out = append(out, client.Amount{
+			Currency: "USD",
+			Value:    int32(math.Round(dollars * 100)),
+		})
+	}
+	return out, nil
+}
+
+func isFormPost(r *http.Request) bool {
+	return strings.HasPrefix(r.Header.Get("Content-Type"), "application/x-www-form-urlencoded")
+}
+
+// wantsHTML returns true for requests from the hosted page rather than API clients.
+func wantsHTML(r *http.Request) bool {
+	return isFormPost(r) || strings.Contains(r.Header.Get("Accept"), "text/html")
+}
+
+func linkProblem(responder *route.Responder, w http.ResponseWriter, r *http.Request, err error) {
+	if wantsHTML(r) {
+		renderLinkPage(w, http.StatusBadRequest, linkPage{Error: err.Error()})
+		return
+	}
+	responder.Problem(err)
+}
+
+// lockedOutProblem responds with a 409 Conflict as the micro-deposits can't be confirmed
+// until an admin resets their lockout.
+func lockedOutProblem(responder *route.Responder, w http.ResponseWriter, r *http.Request, err error) {
+	if wantsHTML(r) {
+		renderLinkPage(w, http.StatusConflict, linkPage{Error: err.Error()})
+		return
+	}
+	responder.Respond(func(w http.ResponseWriter) {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]string{
+			"error": err.Error(),
+		})
+	})
+}
+
+type linkPage struct {
+	State *client.AccountVerification
+	Error string
+}
+
+func (p linkPage) Confirmable() bool {
+	return p.State != nil && p.State.Status == client.VERIFICATIONSTATUS_PROCESSED
+}
+
+var linkTemplate = template.Must(template.New("verify").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><meta name="viewport" content="width=device-width, initial-scale=1"><title>Verify your account</title></head>
+<body>
+<h1>Verify your account</h1>
+{{if .Error}}<p role="alert">{{.Error}}</p>{{end}}
+{{with .State}}
+  {{if eq .Status "verified"}}<p>Your account has been verified.</p>
+  {{else if eq .Status "initiated"}}<p>Two small deposits are on their way to your account. Return to this page once they appear.</p>
+  {{else if ne .Status "processed"}}<p>This verification is {{.Status}}.</p>{{end}}
+{{end}}
+{{if .Confirmable}}
+<p>Enter the two small deposit amounts that appeared in your account.</p>
+<form method="post">
+  <label>First amount <input name="amount" inputmode="decimal" placeholder="0.07" required></label>
+  <label>Second amount <input name="amount" inputmode="decimal" placeholder="0.12" required></label>
+  <button type="submit">Verify</button>
+</form>
+{{end}}
+</body>
+</html>
+`))
+
+func renderLinkPage(w http.ResponseWriter, status int, page linkPage) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	linkTemplate.Execute(w, page)
+}
+
+func LinksNotImplemented(cfg *config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(cfg, w, r)
+		responder.Problem(errors.New("verification links are disabled via config"))
+	}
+}
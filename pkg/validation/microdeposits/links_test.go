@@ -0,0 +1,265 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package microdeposits
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	moovcustomers "github.com/moov-io/customers/pkg/client"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/customers"
+	"github.com/moov-io/paygate/pkg/webhooks"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+)
+
+var linkSecret = strings.Repeat("s", 32)
+
+func linksConfig() *config.Config {
+	cfg := mockConfig()
+	cfg.Validation.MicroDeposits.Links = &config.VerificationLinks{
+		Secret:  linkSecret,
+		BaseURL: "https://pay.example.com/",
+	}
+	return cfg
+}
+
+func linksRouter(cfg *config.Config, repo Repository, customersClient customers.Client, events webhooks.Sender) *mux.Router {
+	r := mux.NewRouter()
+	NewRouter(cfg, repo, mockTransferRepo, customersClient, mockDecryptor, mockStrategy, fakePublisher, events).RegisterRoutes(r)
+	return r
+}
+
+func TestLinks__parseLink(t *testing.T) {
+	now := time.Now()
+	token := signLink(linkSecret, "micro", now.Add(time.Hour))
+
+	microDepositID, err := parseLink(linkSecret, token, now)
+	require.NoError(t, err)
+	require.Equal(t, "micro", microDepositID)
+
+	// expired
+	_, err = parseLink(linkSecret, token, now.Add(2*time.Hour))
+	require.Equal(t, errInvalidLink, err)
+
+	// other secret
+	_, err = parseLink(strings.Repeat("x", 32), token, now)
+	require.Equal(t, errInvalidLink, err)
+
+	// tampered
+	parts := strings.Split(token, ".")
+	_, err = parseLink(linkSecret, "other."+parts[1]+"."+parts[2], now)
+	require.Equal(t, errInvalidLink, err)
+
+	_, err = parseLink(linkSecret, "", now)
+	require.Equal(t, errInvalidLink, err)
+}
+
+func TestLinks__amountsMatch(t *testing.T) {
+	sent := []client.Amount{{Currency: "USD", Value: 2}, {Currency: "USD", Value: 5}}
+
+	require.True(t, amountsMatch(sent, []client.Amount{{Value: 5}, {Value: 2}}))
+	require.False(t, amountsMatch(sent, []client.Amount{{Value: 5}, {Value: 3}}))
+	require.False(t, amountsMatch(sent, []client.Amount{{Value: 5}}))
+}
+
+func TestLinks__CreateVerificationLink(t *testing.T) {
+	micro := mockMicroDeposit()
+	repo := &mockRepository{Micro: micro, Organization: "moov"}
+	router := linksRouter(linksConfig(), repo, mockCustomersClient(), nil)
+
+	create := func(organization string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/accounts/"+destinationAccountID+"/verification/links", nil)
+		req.Header.Set("X-Organization", organization)
+		router.ServeHTTP(w, req)
+		w.Flush()
+		return w
+	}
+
+	w := create("moov")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var link client.VerificationLink
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&link))
+	require.Equal(t, "https://pay.example.com/verify/"+link.Token, link.Url)
+	require.True(t, link.ExpiresAt.After(time.Now().Add(71*time.Hour)))
+
+	microDepositID, err := parseLink(linkSecret, link.Token, time.Now())
+	require.NoError(t, err)
+	require.Equal(t, micro.MicroDepositID, microDepositID)
+
+	// other organizations can't create links
+	w = create("other")
+	require.Equal(t, http.StatusBadRequest, w.Code)
+
+	// links don't outlive the micro-deposits
+	expires := time.Now().Add(time.Hour)
+	micro.ExpiresAt = &expires
+	w = create("moov")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&link))
+	require.True(t, link.ExpiresAt.Before(expires.Add(time.Second)))
+
+	// failed micro-deposits
+	micro.Status = client.FAILED
+	w = create("moov")
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestLinks__disabled(t *testing.T) {
+	repo := &mockRepository{Micro: mockMicroDeposit()}
+	router := linksRouter(mockConfig(), repo, mockCustomersClient(), nil)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/verify/token", nil))
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	require.Contains(t, w.Body.String(), "disabled")
+}
+
+func TestLinks__GetLinkedVerification(t *testing.T) {
+	micro := mockMicroDeposit()
+	micro.Status = client.PROCESSED
+	repo := &mockRepository{Micro: micro, Organization: "moov"}
+	router := linksRouter(linksConfig(), repo, mockCustomersClient(), nil)
+
+	token := signLink(linkSecret, micro.MicroDepositID, time.Now().Add(time.Hour))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/verify/"+token, nil))
+	w.Flush()
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var state client.AccountVerification
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&state))
+	require.Equal(t, client.VERIFICATIONSTATUS_PROCESSED, state.Status)
+
+	// browsers get the hosted form
+	w = httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/verify/"+token, nil)
+	req.Header.Set("Accept", "text/html")
+	router.ServeHTTP(w, req)
+	w.Flush()
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Contains(t, w.Header().Get("Content-Type"), "text/html")
+	require.Contains(t, w.Body.String(), `<form method="post">`)
+
+	// invalid token
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/verify/bogus", nil))
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestLinks__ConfirmLinkedMicroDeposits(t *testing.T) {
+	micro := mockMicroDeposit()
+	micro.Status = client.PROCESSED
+	repo := &mockRepository{Micro: micro, Organization: "moov", Remaining: 2}
+	customersClient := mockCustomersClient()
+	events := &webhooks.MockSender{}
+	router := linksRouter(linksConfig(), repo, customersClient, events)
+
+	token := signLink(linkSecret, micro.MicroDepositID, time.Now().Add(time.Hour))
+	confirm := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("POST", "/verify/"+token, strings.NewReader(body)))
+		w.Flush()
+		return w
+	}
+
+	// wrong guess
+	w := confirm(`{"amounts":[{"currency":"USD","value":3},{"currency":"USD","value":5}]}`)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	require.Contains(t, w.Body.String(), "2 guesses remain")
+	require.Nil(t, micro.VerifiedAt)
+	require.Equal(t, int32(1), micro.ConfirmationAttempts)
+
+	// correct guess
+	w = confirm(`{"amounts":[{"currency":"USD","value":5},{"currency":"USD","value":2}]}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var state client.AccountVerification
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&state))
+	require.Equal(t, client.VERIFICATIONSTATUS_VERIFIED, state.Status)
+	require.NotNil(t, micro.VerifiedAt)
+	require.Equal(t, moovcustomers.ACCOUNTSTATUS_VALIDATED, customersClient.Accounts[destinationAccountID].Status)
+	require.Equal(t, []bool{false, true}, repo.Confirmations)
+
+	require.Len(t, events.Events, 1)
+	require.Equal(t, EventVerificationCompleted, events.Events[0].Type)
+	require.Equal(t, "moov", events.Events[0].Organization)
+
+	// already verified
+	w = confirm(`{"amounts":[{"currency":"USD","value":5},{"currency":"USD","value":2}]}`)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestLinks__ConfirmLockedOut(t *testing.T) {
+	micro := mockMicroDeposit()
+	micro.Status = client.FAILED
+	repo := &mockRepository{Micro: micro, Organization: "moov", Locked: true}
+	router := linksRouter(linksConfig(), repo, mockCustomersClient(), &webhooks.MockSender{})
+
+	token := signLink(linkSecret, micro.MicroDepositID, time.Now().Add(time.Hour))
+	body := `{"amounts":[{"currency":"USD","value":5},{"currency":"USD","value":2}]}`
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/verify/"+token, strings.NewReader(body)))
+	w.Flush()
+	require.Equal(t, http.StatusConflict, w.Code)
+	require.Contains(t, w.Body.String(), "too many incorrect confirmations")
+	require.Nil(t, micro.VerifiedAt)
+	require.Empty(t, repo.Confirmations)
+
+	// micro-deposits failed for other reasons aren't a conflict
+	repo.Locked = false
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/verify/"+token, strings.NewReader(body)))
+	w.Flush()
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestLinks__ConfirmLinkedMicroDepositsForm(t *testing.T) {
+	micro := mockMicroDeposit()
+	micro.Status = client.PROCESSED
+	repo := &mockRepository{Micro: micro, Organization: "moov"}
+	events := &webhooks.MockSender{}
+	router := linksRouter(linksConfig(), repo, mockCustomersClient(), events)
+
+	token := signLink(linkSecret, micro.MicroDepositID, time.Now().Add(time.Hour))
+	confirm := func(amounts ...string) *httptest.ResponseRecorder {
+		form := url.Values{"amount": amounts}
+		req := httptest.NewRequest("POST", "/verify/"+token, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		w.Flush()
+		return w
+	}
+
+	// last wrong guess fails the micro-deposits
+	w := confirm("0.03", "0.05")
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	require.Contains(t, w.Body.String(), "no guesses remain")
+	require.Len(t, events.Events, 1)
+	require.Equal(t, EventVerificationFailed, events.Events[0].Type)
+
+	micro.Status = client.PROCESSED
+	w = confirm("0.02", "$0.05")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.Contains(t, w.Body.String(), "Your account has been verified.")
+
+	w = confirm("abc", "0.05")
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -22,6 +22,7 @@ func createMicroDeposits(
 	cfg config.MicroDeposits,
 	organization string,
 	companyIdentification string,
+	attempt int32,
 	src fundflow.Source,
 	dest fundflow.Destination,
 	repo transfers.Repository,
@@ -40,8 +41,13 @@ func createMicroDeposits(
 		},
 		Amounts: []client.Amount{amt1, amt2},
 		Status:  client.PENDING,
+		Attempt: attempt,
 		Created: time.Now(),
 	}
+	if cfg.Expiration > 0 {
+		expires := micro.Created.Add(cfg.Expiration)
+		micro.ExpiresAt = &expires
+	}
 
 	// originate two credits
 	if xfer, err := originate(cfg, organization, companyIdentification, amt1, src, dest, repo, strategy, pub); err != nil {
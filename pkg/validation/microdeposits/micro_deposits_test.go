@@ -78,7 +78,7 @@ func TestMicroDeposits__createMicroDeposits(t *testing.T) {
 	strategy := fundflow.NewFirstPerson(cfg.Logger, cfg.ODFI)
 
 	companyID := "MoovZZZZZZ"
-	micro, err := createMicroDeposits(*cfg.Validation.MicroDeposits, organization, companyID, src, dest, repo, decryptor, strategy, pub)
+	micro, err := createMicroDeposits(*cfg.Validation.MicroDeposits, organization, companyID, 1, src, dest, repo, decryptor, strategy, pub)
 	if err != nil {
 		t.Fatal(err)
 	}
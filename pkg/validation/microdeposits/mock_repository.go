@@ -5,12 +5,25 @@
 package microdeposits
 
 import (
+	"errors"
+	"time"
+
 	"github.com/moov-io/paygate/pkg/client"
 )
 
 type mockRepository struct {
-	Micro *client.MicroDeposits
-	Err   error
+	Micro        *client.MicroDeposits
+	Attempts     []*client.MicroDeposits
+	Organization string
+	Remaining    int
+	Locked       bool
+	Err          error
+
+	Confirmations []bool
+
+	UnsentProcessed []string
+	UnsentExpired   []string
+	Sent            map[string]bool // keyed by event type and microDepositID
 }
 
 func (r *mockRepository) getMicroDeposits(microDepositID string) (*client.MicroDeposits, error) {
@@ -27,6 +40,116 @@ func (r *mockRepository) getAccountMicroDeposits(accountID string) (*client.Micr
 	return r.Micro, nil
 }
 
+func (r *mockRepository) getAccountMicroDepositAttempts(accountID string) ([]*client.MicroDeposits, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	return r.Attempts, nil
+}
+
 func (r *mockRepository) writeMicroDeposits(micro *client.MicroDeposits) error {
 	return r.Err
 }
+
+func (r *mockRepository) lookupMicroDepositFromTransfer(transferID string) (*client.MicroDeposits, string, error) {
+	if r.Err != nil {
+		return nil, "", r.Err
+	}
+	return r.Micro, r.Organization, nil
+}
+
+func (r *mockRepository) saveReturnCode(microDepositID string, returnCode string) error {
+	return r.Err
+}
+
+func (r *mockRepository) verifyMicroDeposits(microDepositID string, when time.Time) error {
+	if r.Err != nil {
+		return r.Err
+	}
+	if r.Micro != nil {
+		r.Micro.VerifiedAt = &when
+	}
+	return nil
+}
+
+func (r *mockRepository) failedConfirmation(microDepositID string, maxGuesses int) (int, error) {
+	if r.Err != nil {
+		return 0, r.Err
+	}
+	return r.Remaining, nil
+}
+
+func (r *mockRepository) recordConfirmation(microDepositID string, matched bool, when time.Time) error {
+	if r.Err != nil {
+		return r.Err
+	}
+	r.Confirmations = append(r.Confirmations, matched)
+	return nil
+}
+
+func (r *mockRepository) lockedOut(microDepositID string, maxGuesses int) (bool, error) {
+	if r.Err != nil {
+		return false, r.Err
+	}
+	return r.Locked, nil
+}
+
+func (r *mockRepository) resetLockout(microDepositID string, maxGuesses int) error {
+	if r.Err != nil {
+		return r.Err
+	}
+	if !r.Locked {
+		return errors.New("not locked out")
+	}
+	r.Locked = false
+	if r.Micro != nil {
+		r.Micro.Status = client.PROCESSED
+	}
+	return nil
+}
+
+func (r *mockRepository) getUnverifiedMicroDeposits() ([]*client.MicroDeposits, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	return r.Attempts, nil
+}
+
+func (r *mockRepository) expireMicroDeposits(microDepositID string, when time.Time) error {
+	if r.Err != nil {
+		return r.Err
+	}
+	if r.Micro != nil {
+		r.Micro.ExpiresAt = &when
+	}
+	return nil
+}
+
+func (r *mockRepository) getUnsentProcessedEvents() ([]string, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	return r.UnsentProcessed, nil
+}
+
+func (r *mockRepository) getUnsentExpiredEvents(now time.Time) ([]string, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	return r.UnsentExpired, nil
+}
+
+func (r *mockRepository) markEventSent(microDepositID string, eventType string, when time.Time) (bool, error) {
+	if r.Err != nil {
+		return false, r.Err
+	}
+	if r.Sent == nil {
+		r.Sent = make(map[string]bool)
+	}
+	key := eventType + "/" + microDepositID
+	if r.Sent[key] {
+		return false, nil
+	}
+	r.Sent[key] = true
+	return true, nil
+}
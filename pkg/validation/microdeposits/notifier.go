@@ -0,0 +1,111 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package microdeposits
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/moov-io/base"
+	"github.com/moov-io/base/log"
+
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/webhooks"
+)
+
+// Notifier sends the verification events for state changes which don't happen during a request,
+// micro-deposits being uploaded by the pipeline and expiring. Each event is recorded before it's
+// sent so it's only sent once, even with multiple instances running.
+type Notifier struct {
+	cfg    config.MicroDeposits
+	logger log.Logger
+
+	repo   Repository
+	events webhooks.Sender
+
+	interval time.Duration
+}
+
+// NewNotifier returns a Notifier or nil if micro-deposits are disabled.
+func NewNotifier(cfg *config.Config, repo Repository, events webhooks.Sender) *Notifier {
+	if cfg.Validation.MicroDeposits == nil {
+		return nil
+	}
+	return &Notifier{
+		cfg:      *cfg.Validation.MicroDeposits,
+		logger:   cfg.Logger.Set("service", "micro-deposits"),
+		repo:     repo,
+		events:   events,
+		interval: time.Minute,
+	}
+}
+
+func (n *Notifier) Start(ctx context.Context) {
+	if n == nil {
+		return
+	}
+
+	ticker := time.NewTicker(n.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case now := <-ticker.C:
+			if err := n.tick(now); err != nil {
+				n.logger.LogErrorf("ERROR sending micro-deposit events: %v", err)
+			}
+
+		case <-ctx.Done():
+			n.logger.Log("micro-deposit notifier shutdown")
+			return
+		}
+	}
+}
+
+func (n *Notifier) tick(now time.Time) error {
+	var el base.ErrorList
+
+	processed, err := n.repo.getUnsentProcessedEvents()
+	if err != nil {
+		el.Add(fmt.Errorf("reading processed micro-deposits: %v", err))
+	}
+	for i := range processed {
+		if err := n.send(EventVerificationProcessed, processed[i], now); err != nil {
+			el.Add(fmt.Errorf("microDepositID=%s: %v", processed[i], err))
+		}
+	}
+
+	expired, err := n.repo.getUnsentExpiredEvents(now)
+	if err != nil {
+		el.Add(fmt.Errorf("reading expired micro-deposits: %v", err))
+	}
+	for i := range expired {
+		if err := n.send(EventVerificationExpired, expired[i], now); err != nil {
+			el.Add(fmt.Errorf("microDepositID=%s: %v", expired[i], err))
+		}
+	}
+
+	if el.Empty() {
+		return nil
+	}
+	return el
+}
+
+// send records eventType for the micro-deposits and sends it if no other instance already has.
+func (n *Notifier) send(eventType string, microDepositID string, now time.Time) error {
+	micro, organization, err := linkedMicroDeposits(n.repo, microDepositID)
+	if err != nil {
+		return err
+	}
+	sent, err := n.repo.markEventSent(microDepositID, eventType, now)
+	if err != nil {
+		return fmt.Errorf("problem recording %s event: %v", eventType, err)
+	}
+	if sent {
+		sendVerificationEvent(n.logger, n.events, n.cfg, eventType, organization, micro)
+	}
+	return nil
+}
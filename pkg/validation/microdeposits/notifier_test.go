@@ -0,0 +1,65 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package microdeposits
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/webhooks"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotifier__disabled(t *testing.T) {
+	require.Nil(t, NewNotifier(config.Empty(), &mockRepository{}, &webhooks.MockSender{}))
+}
+
+func TestNotifier__tick(t *testing.T) {
+	micro := mockMicroDeposit()
+	micro.Status = client.PROCESSED
+
+	repo := &mockRepository{
+		Micro:           micro,
+		Organization:    "moov",
+		UnsentProcessed: []string{micro.MicroDepositID},
+	}
+	events := &webhooks.MockSender{}
+	notifier := NewNotifier(mockConfig(), repo, events)
+
+	now := time.Now()
+	require.NoError(t, notifier.tick(now))
+	require.Len(t, events.Events, 1)
+	require.Equal(t, EventVerificationProcessed, events.Events[0].Type)
+	require.Equal(t, "moov", events.Events[0].Organization)
+
+	// events are only sent once
+	require.NoError(t, notifier.tick(now))
+	require.Len(t, events.Events, 1)
+
+	// expired micro-deposits
+	expires := now.Add(-time.Minute)
+	micro.ExpiresAt = &expires
+	repo.UnsentProcessed = nil
+	repo.UnsentExpired = []string{micro.MicroDepositID}
+
+	require.NoError(t, notifier.tick(now))
+	require.Len(t, events.Events, 2)
+	require.Equal(t, EventVerificationExpired, events.Events[1].Type)
+
+	state, ok := events.Events[1].Data.(*client.AccountVerification)
+	require.True(t, ok)
+	require.Equal(t, client.VERIFICATIONSTATUS_EXPIRED, state.Status)
+	require.Equal(t, int32(0), state.AttemptsRemaining)
+
+	// errors are returned and nothing is sent
+	repo.Sent = nil
+	repo.Err = errors.New("bad error")
+	require.Error(t, notifier.tick(now))
+	require.Len(t, events.Events, 2)
+}
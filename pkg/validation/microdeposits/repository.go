@@ -7,24 +7,64 @@ package microdeposits
 import (
 	"database/sql"
 	"fmt"
+	"time"
+
+	"github.com/moov-io/ach"
+	"github.com/moov-io/customers/pkg/secrets"
 
 	"github.com/moov-io/paygate/pkg/client"
 )
 
 type Repository interface {
-	// TODO(adam): lookup a micro-deposit from transferID, for return handling
-
 	getMicroDeposits(microDepositID string) (*client.MicroDeposits, error)
+	// getAccountMicroDeposits returns the latest micro-deposit attempt for accountID.
 	getAccountMicroDeposits(accountID string) (*client.MicroDeposits, error)
+	// getAccountMicroDepositAttempts returns every micro-deposit attempt for accountID, newest first.
+	getAccountMicroDepositAttempts(accountID string) ([]*client.MicroDeposits, error)
 	writeMicroDeposits(micro *client.MicroDeposits) error
+
+	// lookupMicroDepositFromTransfer returns the micro-deposits and organization which created transferID.
+	lookupMicroDepositFromTransfer(transferID string) (*client.MicroDeposits, string, error)
+	saveReturnCode(microDepositID string, returnCode string) error
+
+	// verifyMicroDeposits records the Receiver confirmed the amounts.
+	verifyMicroDeposits(microDepositID string, when time.Time) error
+	// failedConfirmation records an incorrect confirmation and returns how many guesses are left.
+	// The micro-deposits are failed once none remain.
+	failedConfirmation(microDepositID string, maxGuesses int) (int, error)
+	// recordConfirmation saves an attempt to confirm the amounts and if they matched.
+	recordConfirmation(microDepositID string, matched bool, when time.Time) error
+	// lockedOut returns true when the micro-deposits were failed after maxGuesses incorrect confirmations.
+	lockedOut(microDepositID string, maxGuesses int) (bool, error)
+	// resetLockout clears incorrect confirmations so locked out micro-deposits can be confirmed again.
+	resetLockout(microDepositID string, maxGuesses int) error
+
+	// getUnverifiedMicroDeposits returns micro-deposits which haven't been verified, failed or
+	// canceled, oldest first. Some may have expired.
+	getUnverifiedMicroDeposits() ([]*client.MicroDeposits, error)
+	// expireMicroDeposits stops unverified micro-deposits from being confirmed after when.
+	expireMicroDeposits(microDepositID string, when time.Time) error
+
+	// getUnsentProcessedEvents returns micro-deposits which were uploaded but haven't had
+	// their verification.processed event sent.
+	getUnsentProcessedEvents() ([]string, error)
+	// getUnsentExpiredEvents returns unverified micro-deposits which expired before now but
+	// haven't had their verification.expired event sent.
+	getUnsentExpiredEvents(now time.Time) ([]string, error)
+	// markEventSent records eventType was sent for the micro-deposits. False is returned
+	// when it was already recorded, so the event shouldn't be sent again.
+	markEventSent(microDepositID string, eventType string, when time.Time) (bool, error)
 }
 
-func NewRepo(db *sql.DB) *sqlRepo {
-	return &sqlRepo{db: db}
+// NewRepo returns a Repository which encrypts micro-deposit amounts with keeper.
+// Amounts are stored in plaintext when keeper is nil.
+func NewRepo(db *sql.DB, keeper *secrets.StringKeeper) *sqlRepo {
+	return &sqlRepo{db: db, keeper: keeper}
 }
 
 type sqlRepo struct {
-	db *sql.DB
+	db     *sql.DB
+	keeper *secrets.StringKeeper
 }
 
 func (r *sqlRepo) Close() error {
@@ -35,7 +75,7 @@ func (r *sqlRepo) Close() error {
 }
 
 func (r *sqlRepo) getMicroDeposits(microDepositID string) (*client.MicroDeposits, error) {
-	query := `select micro_deposit_id, destination_customer_id, destination_account_id, status, processed_at, created_at from micro_deposits
+	query := `select micro_deposit_id, destination_customer_id, destination_account_id, status, attempt, confirmation_attempts, return_code, expires_at, processed_at, verified_at, created_at from micro_deposits
 where micro_deposit_id = ? and deleted_at is null limit 1;`
 	stmt, err := r.db.Prepare(query)
 	if err != nil {
@@ -43,13 +83,19 @@ where micro_deposit_id = ? and deleted_at is null limit 1;`
 	}
 	defer stmt.Close()
 
+	var returnCode *string
 	var micro client.MicroDeposits
 	if err := stmt.QueryRow(microDepositID).Scan(
 		&micro.MicroDepositID,
 		&micro.Destination.CustomerID,
 		&micro.Destination.AccountID,
 		&micro.Status,
+		&micro.Attempt,
+		&micro.ConfirmationAttempts,
+		&returnCode,
+		&micro.ExpiresAt,
 		&micro.ProcessedAt,
+		&micro.VerifiedAt,
 		&micro.Created,
 	); err != nil {
 		if err == sql.ErrNoRows {
@@ -57,29 +103,54 @@ where micro_deposit_id = ? and deleted_at is null limit 1;`
 		}
 		return nil, fmt.Errorf("micro-deposit scan: %v", err)
 	}
+	if returnCode != nil {
+		if rc := ach.LookupReturnCode(*returnCode); rc != nil {
+			micro.ReturnCode = &client.ReturnCode{
+				Code:        rc.Code,
+				Reason:      rc.Reason,
+				Description: rc.Description,
+			}
+		}
+	}
 
 	micro.TransferIDs, err = r.getMicroDepositTransferIDs(microDepositID)
 	if err != nil {
 		return nil, err
 	}
+	if err := r.alignStatus(&micro); err != nil {
+		return nil, fmt.Errorf("micro-deposit transfers: %v", err)
+	}
 
 	// Read out the amounts
-	query = `select amount_currency, amount_value from micro_deposit_amounts where micro_deposit_id = ?;`
+	query = `select amount_currency, amount_value, amount_value_encrypted from micro_deposit_amounts where micro_deposit_id = ?;`
 	stmt, err = r.db.Prepare(query)
 	if err != nil {
 		return nil, fmt.Errorf("micro-deposit amounts prepare: %v", err)
 	}
+	defer stmt.Close()
+
 	rows, err := stmt.Query(microDepositID)
 	if err != nil {
 		return nil, fmt.Errorf("micro-deposit amounts query: %v", err)
 	}
+	defer rows.Close()
+
 	for rows.Next() {
 		var amt client.Amount
-		if err := rows.Scan(&amt.Currency, &amt.Value); err != nil {
+		var plaintext *int32
+		var encrypted *string
+		if err := rows.Scan(&amt.Currency, &plaintext, &encrypted); err != nil {
 			return nil, fmt.Errorf("micro-deposit amount scan: %v", err)
 		}
+		amt.Value, err = readAmount(r.keeper, plaintext, encrypted)
+		if err != nil {
+			return nil, fmt.Errorf("micro-deposit amount: %v", err)
+		}
 		micro.Amounts = append(micro.Amounts, amt)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("micro-deposit amounts: %v", err)
+	}
 
 	return &micro, nil
 }
@@ -109,8 +180,60 @@ func (r *sqlRepo) getMicroDepositTransferIDs(microDepositID string) ([]string, e
 	return transferIDs, nil
 }
 
+// alignStatus reports pending micro-deposits as PROCESSED once each of their Transfers
+// is. Micro-deposits are merged, uploaded and returned as ordinary Transfers, so their
+// status follows those Transfers rather than being tracked separately.
+func (r *sqlRepo) alignStatus(micro *client.MicroDeposits) error {
+	if micro.Status != client.PENDING || len(micro.TransferIDs) == 0 {
+		return nil
+	}
+
+	query := `select t.status, t.processed_at from micro_deposit_transfers as mdt
+inner join transfers as t on mdt.transfer_id = t.transfer_id
+where mdt.micro_deposit_id = ? and t.deleted_at is null;`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.Query(micro.MicroDepositID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	processed := 0
+	var processedAt *time.Time
+	for rows.Next() {
+		var status client.TransferStatus
+		var when *time.Time
+		if err := rows.Scan(&status, &when); err != nil {
+			return err
+		}
+		if status != client.PROCESSED {
+			return rows.Err()
+		}
+		processed++
+		if when != nil && (processedAt == nil || when.After(*processedAt)) {
+			processedAt = when
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if processed == len(micro.TransferIDs) {
+		micro.Status = client.PROCESSED
+		if micro.ProcessedAt == nil {
+			micro.ProcessedAt = processedAt
+		}
+	}
+	return nil
+}
+
 func (r *sqlRepo) getAccountMicroDeposits(accountID string) (*client.MicroDeposits, error) {
-	query := `select micro_deposit_id from micro_deposits where destination_account_id = ? and deleted_at is null limit 1;`
+	query := `select micro_deposit_id from micro_deposits where destination_account_id = ? and deleted_at is null
+order by created_at desc, attempt desc limit 1;`
 	stmt, err := r.db.Prepare(query)
 	if err != nil {
 		return nil, err
@@ -124,6 +247,44 @@ func (r *sqlRepo) getAccountMicroDeposits(accountID string) (*client.MicroDeposi
 	return r.getMicroDeposits(microDepositID)
 }
 
+func (r *sqlRepo) getAccountMicroDepositAttempts(accountID string) ([]*client.MicroDeposits, error) {
+	query := `select micro_deposit_id from micro_deposits where destination_account_id = ? and deleted_at is null
+order by created_at desc, attempt desc;`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.Query(accountID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var microDepositIDs []string
+	for rows.Next() {
+		var microDepositID string
+		if err := rows.Scan(&microDepositID); err != nil {
+			return nil, err
+		}
+		microDepositIDs = append(microDepositIDs, microDepositID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var out []*client.MicroDeposits
+	for i := range microDepositIDs {
+		micro, err := r.getMicroDeposits(microDepositIDs[i])
+		if err != nil {
+			return nil, fmt.Errorf("microDepositID=%s: %v", microDepositIDs[i], err)
+		}
+		out = append(out, micro)
+	}
+	return out, nil
+}
+
 func (r *sqlRepo) writeMicroDeposits(micro *client.MicroDeposits) error {
 	tx, err := r.db.Begin()
 	if err != nil {
@@ -149,14 +310,14 @@ func (r *sqlRepo) writeMicroDeposits(micro *client.MicroDeposits) error {
 }
 
 func (r *sqlRepo) writeMicroDeposit(tx *sql.Tx, micro *client.MicroDeposits) error {
-	query := `insert into micro_deposits (micro_deposit_id, destination_customer_id, destination_account_id, status, created_at) values (?, ?, ?, ?, ?);`
+	query := `insert into micro_deposits (micro_deposit_id, destination_customer_id, destination_account_id, status, attempt, expires_at, created_at) values (?, ?, ?, ?, ?, ?, ?);`
 	stmt, err := tx.Prepare(query)
 	if err != nil {
 		return err
 	}
 	defer stmt.Close()
 
-	_, err = stmt.Exec(micro.MicroDepositID, micro.Destination.CustomerID, micro.Destination.AccountID, micro.Status, micro.Created)
+	_, err = stmt.Exec(micro.MicroDepositID, micro.Destination.CustomerID, micro.Destination.AccountID, micro.Status, micro.Attempt, micro.ExpiresAt, micro.Created)
 	if err != nil {
 		return err
 	}
@@ -164,7 +325,7 @@ func (r *sqlRepo) writeMicroDeposit(tx *sql.Tx, micro *client.MicroDeposits) err
 }
 
 func (r *sqlRepo) writeMicroDepositAmounts(tx *sql.Tx, microDepositID string, amounts []client.Amount) error {
-	query := `insert into micro_deposit_amounts (micro_deposit_id, amount_currency, amount_value) values (?, ?, ?);`
+	query := `insert into micro_deposit_amounts (micro_deposit_id, amount_currency, amount_value, amount_value_encrypted) values (?, ?, ?, ?);`
 	stmt, err := tx.Prepare(query)
 	if err != nil {
 		return err
@@ -172,13 +333,79 @@ func (r *sqlRepo) writeMicroDepositAmounts(tx *sql.Tx, microDepositID string, am
 	defer stmt.Close()
 
 	for i := range amounts {
-		if _, err := stmt.Exec(microDepositID, amounts[i].Currency, amounts[i].Value); err != nil {
+		var plaintext *int32
+		var encrypted *string
+		if r.keeper == nil {
+			plaintext = &amounts[i].Value
+		} else {
+			value, err := encryptAmount(r.keeper, amounts[i].Value)
+			if err != nil {
+				return fmt.Errorf("encrypting amount: %v", err)
+			}
+			encrypted = &value
+		}
+		if _, err := stmt.Exec(microDepositID, amounts[i].Currency, plaintext, encrypted); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
+// EncryptAmounts encrypts micro-deposit amounts which were stored in plaintext and
+// returns how many were encrypted. Nothing is done without a keeper.
+func (r *sqlRepo) EncryptAmounts() (int, error) {
+	if r == nil || r.keeper == nil {
+		return 0, nil
+	}
+
+	query := `select micro_deposit_id, amount_value from micro_deposit_amounts where amount_value is not null and amount_value_encrypted is null;`
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return 0, err
+	}
+	type plaintextAmount struct {
+		microDepositID string
+		value          int32
+	}
+	var amounts []plaintextAmount
+	for rows.Next() {
+		var amt plaintextAmount
+		if err := rows.Scan(&amt.microDepositID, &amt.value); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("plaintext amount scan: %v", err)
+		}
+		amounts = append(amounts, amt)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	query = `update micro_deposit_amounts set amount_value = null, amount_value_encrypted = ?
+where micro_deposit_id = ? and amount_value = ? and amount_value_encrypted is null;`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	encrypted := 0
+	for i := range amounts {
+		value, err := encryptAmount(r.keeper, amounts[i].value)
+		if err != nil {
+			return encrypted, fmt.Errorf("encrypting microDepositID=%s amount: %v", amounts[i].microDepositID, err)
+		}
+		res, err := stmt.Exec(value, amounts[i].microDepositID, amounts[i].value)
+		if err != nil {
+			return encrypted, fmt.Errorf("updating microDepositID=%s amount: %v", amounts[i].microDepositID, err)
+		}
+		if n, _ := res.RowsAffected(); n > 0 {
+			encrypted += int(n)
+		}
+	}
+	return encrypted, nil
+}
+
 func (r *sqlRepo) writeMicroDepositTransferIDs(tx *sql.Tx, microDepositID string, transferIDs []string) error {
 	query := `insert into micro_deposit_transfers (micro_deposit_id, transfer_id) values (?, ?);`
 	stmt, err := tx.Prepare(query)
@@ -194,3 +421,189 @@ func (r *sqlRepo) writeMicroDepositTransferIDs(tx *sql.Tx, microDepositID string
 	}
 	return nil
 }
+
+func (r *sqlRepo) lookupMicroDepositFromTransfer(transferID string) (*client.MicroDeposits, string, error) {
+	query := `select mdt.micro_deposit_id, t.organization from micro_deposit_transfers as mdt
+inner join transfers as t on mdt.transfer_id = t.transfer_id
+where mdt.transfer_id = ? limit 1;`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return nil, "", err
+	}
+	defer stmt.Close()
+
+	var microDepositID, organization string
+	if err := stmt.QueryRow(transferID).Scan(&microDepositID, &organization); err != nil {
+		return nil, "", err
+	}
+	micro, err := r.getMicroDeposits(microDepositID)
+	if err != nil {
+		return nil, "", err
+	}
+	return micro, organization, nil
+}
+
+func (r *sqlRepo) saveReturnCode(microDepositID string, returnCode string) error {
+	query := `update micro_deposits set status = ?, return_code = ? where micro_deposit_id = ? and deleted_at is null;`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(client.FAILED, returnCode, microDepositID)
+	return err
+}
+
+func (r *sqlRepo) verifyMicroDeposits(microDepositID string, when time.Time) error {
+	query := `update micro_deposits set verified_at = ? where micro_deposit_id = ? and verified_at is null and deleted_at is null;`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(when, microDepositID)
+	return err
+}
+
+func (r *sqlRepo) failedConfirmation(microDepositID string, maxGuesses int) (int, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+
+	query := `update micro_deposits set confirmation_attempts = confirmation_attempts + 1 where micro_deposit_id = ? and deleted_at is null;`
+	if _, err := tx.Exec(query, microDepositID); err != nil {
+		tx.Rollback()
+		return 0, fmt.Errorf("incrementing confirmation attempts: %v", err)
+	}
+
+	var attempts int
+	query = `select confirmation_attempts from micro_deposits where micro_deposit_id = ? limit 1;`
+	if err := tx.QueryRow(query, microDepositID).Scan(&attempts); err != nil {
+		tx.Rollback()
+		return 0, fmt.Errorf("reading confirmation attempts: %v", err)
+	}
+
+	remaining := maxGuesses - attempts
+	if remaining <= 0 {
+		query = `update micro_deposits set status = ? where micro_deposit_id = ?;`
+		if _, err := tx.Exec(query, client.FAILED, microDepositID); err != nil {
+			tx.Rollback()
+			return 0, fmt.Errorf("failing micro-deposits: %v", err)
+		}
+		remaining = 0
+	}
+	return remaining, tx.Commit()
+}
+
+func (r *sqlRepo) recordConfirmation(microDepositID string, matched bool, when time.Time) error {
+	query := `insert into micro_deposit_confirmations (micro_deposit_id, matched, created_at) values (?, ?, ?);`
+	_, err := r.db.Exec(query, microDepositID, matched, when)
+	return err
+}
+
+func (r *sqlRepo) lockedOut(microDepositID string, maxGuesses int) (bool, error) {
+	query := `select count(*) from micro_deposits where micro_deposit_id = ? and status = ? and confirmation_attempts >= ?
+and return_code is null and verified_at is null and deleted_at is null;`
+	var n int
+	if err := r.db.QueryRow(query, microDepositID, client.FAILED, maxGuesses).Scan(&n); err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+func (r *sqlRepo) resetLockout(microDepositID string, maxGuesses int) error {
+	query := `update micro_deposits set status = ?, confirmation_attempts = 0 where micro_deposit_id = ? and status = ? and confirmation_attempts >= ?
+and return_code is null and verified_at is null and deleted_at is null;`
+	res, err := r.db.Exec(query, client.PROCESSED, microDepositID, client.FAILED, maxGuesses)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("microDepositID=%s not found or not locked out", microDepositID)
+	}
+	return nil
+}
+
+func (r *sqlRepo) getUnverifiedMicroDeposits() ([]*client.MicroDeposits, error) {
+	query := `select micro_deposit_id from micro_deposits where verified_at is null and status not in (?, ?) and deleted_at is null
+order by created_at asc;`
+	microDepositIDs, err := r.queryMicroDepositIDs(query, client.FAILED, client.CANCELED)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []*client.MicroDeposits
+	for i := range microDepositIDs {
+		micro, err := r.getMicroDeposits(microDepositIDs[i])
+		if err != nil {
+			return nil, fmt.Errorf("microDepositID=%s: %v", microDepositIDs[i], err)
+		}
+		out = append(out, micro)
+	}
+	return out, nil
+}
+
+func (r *sqlRepo) expireMicroDeposits(microDepositID string, when time.Time) error {
+	query := `update micro_deposits set expires_at = ? where micro_deposit_id = ? and verified_at is null and deleted_at is null;`
+	res, err := r.db.Exec(query, when, microDepositID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("microDepositID=%s not found or already verified", microDepositID)
+	}
+	return nil
+}
+
+// queryMicroDepositIDs returns the micro_deposit_id column from each row of query.
+func (r *sqlRepo) queryMicroDepositIDs(query string, args ...interface{}) ([]string, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var microDepositIDs []string
+	for rows.Next() {
+		var microDepositID string
+		if err := rows.Scan(&microDepositID); err != nil {
+			return nil, err
+		}
+		microDepositIDs = append(microDepositIDs, microDepositID)
+	}
+	return microDepositIDs, rows.Err()
+}
+
+func (r *sqlRepo) getUnsentProcessedEvents() ([]string, error) {
+	query := `select micro_deposit_id from micro_deposits where processed_at is not null and processed_event_at is null and deleted_at is null
+order by processed_at asc;`
+	return r.queryMicroDepositIDs(query)
+}
+
+func (r *sqlRepo) getUnsentExpiredEvents(now time.Time) ([]string, error) {
+	query := `select micro_deposit_id from micro_deposits where expires_at < ? and expired_event_at is null
+and verified_at is null and status not in (?, ?) and deleted_at is null order by expires_at asc;`
+	return r.queryMicroDepositIDs(query, now, client.FAILED, client.CANCELED)
+}
+
+func (r *sqlRepo) markEventSent(microDepositID string, eventType string, when time.Time) (bool, error) {
+	var column string
+	switch eventType {
+	case EventVerificationProcessed:
+		column = "processed_event_at"
+	case EventVerificationExpired:
+		column = "expired_event_at"
+	default:
+		return false, fmt.Errorf("unknown micro-deposit event %s", eventType)
+	}
+	query := fmt.Sprintf(`update micro_deposits set %s = ? where micro_deposit_id = ? and %s is null and deleted_at is null;`, column, column)
+	res, err := r.db.Exec(query, when, microDepositID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
@@ -12,6 +12,8 @@ import (
 	"github.com/moov-io/base"
 	"github.com/moov-io/paygate/pkg/client"
 	"github.com/moov-io/paygate/pkg/database"
+	"github.com/moov-io/paygate/pkg/transfers"
+	"github.com/moov-io/paygate/pkg/transfers/history"
 )
 
 func TestRepository__getMicroDeposits(t *testing.T) {
@@ -66,6 +68,66 @@ func TestRepository__getAccountMicroDeposits(t *testing.T) {
 	check(t, setupMySQLeDB(t))
 }
 
+func TestRepository__getAccountMicroDepositAttempts(t *testing.T) {
+	t.Parallel()
+
+	check := func(t *testing.T, repo *sqlRepo) {
+		first := writeMicroDeposits(t, repo)
+		if err := repo.saveReturnCode(first.MicroDepositID, "R03"); err != nil {
+			t.Fatal(err)
+		}
+
+		// a second attempt for the same account
+		expires := time.Now().Add(24 * time.Hour)
+		second := &client.MicroDeposits{
+			MicroDepositID: base.ID(),
+			TransferIDs:    []string{base.ID(), base.ID()},
+			Destination:    first.Destination,
+			Amounts: []client.Amount{
+				{Currency: "USD", Value: 3},
+				{Currency: "USD", Value: 7},
+			},
+			Status:    client.PENDING,
+			Attempt:   2,
+			ExpiresAt: &expires,
+			Created:   first.Created.Add(time.Second),
+		}
+		if err := repo.writeMicroDeposits(second); err != nil {
+			t.Fatal(err)
+		}
+
+		latest, err := repo.getAccountMicroDeposits(first.Destination.AccountID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if latest.MicroDepositID != second.MicroDepositID || latest.Attempt != 2 || latest.ExpiresAt == nil {
+			t.Errorf("unexpected latest attempt: %#v", latest)
+		}
+
+		attempts, err := repo.getAccountMicroDepositAttempts(first.Destination.AccountID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(attempts) != 2 {
+			t.Fatalf("got %d attempts", len(attempts))
+		}
+		if attempts[0].MicroDepositID != second.MicroDepositID || attempts[1].MicroDepositID != first.MicroDepositID {
+			t.Errorf("unexpected order: %s, %s", attempts[0].MicroDepositID, attempts[1].MicroDepositID)
+		}
+		if attempts[1].Status != client.FAILED || attempts[1].ReturnCode == nil {
+			t.Errorf("previous attempt: %#v", attempts[1])
+		}
+
+		attempts, err = repo.getAccountMicroDepositAttempts(base.ID())
+		if err != nil || len(attempts) != 0 {
+			t.Errorf("unexpected attempts=%#v error=%v", attempts, err)
+		}
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+}
+
 func setupSQLiteDB(t *testing.T) *sqlRepo {
 	db := database.CreateTestSqliteDB(t)
 	t.Cleanup(func() { db.Close() })
@@ -101,6 +163,7 @@ func writeMicroDeposits(t *testing.T, repo Repository) *client.MicroDeposits {
 			{Currency: "USD", Value: 5},
 		},
 		Status:  client.PENDING,
+		Attempt: 1,
 		Created: time.Now(),
 	}
 	if err := repo.writeMicroDeposits(micro); err != nil {
@@ -108,3 +171,339 @@ func writeMicroDeposits(t *testing.T, repo Repository) *client.MicroDeposits {
 	}
 	return micro
 }
+
+func TestRepository__lookupMicroDepositFromTransfer(t *testing.T) {
+	t.Parallel()
+
+	check := func(t *testing.T, repo *sqlRepo) {
+		micro := writeMicroDeposits(t, repo)
+
+		xfer := &client.Transfer{
+			TransferID:  micro.TransferIDs[0],
+			Amount:      micro.Amounts[0],
+			Destination: micro.Destination,
+			Description: "validation",
+			Status:      client.PROCESSED,
+			Created:     time.Now(),
+		}
+		if err := transfers.NewRepo(repo.db).WriteUserTransfer("moov", xfer); err != nil {
+			t.Fatal(err)
+		}
+
+		found, organization, err := repo.lookupMicroDepositFromTransfer(xfer.TransferID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if found.MicroDepositID != micro.MicroDepositID || organization != "moov" {
+			t.Errorf("microDepositID=%s organization=%s", found.MicroDepositID, organization)
+		}
+
+		// save a return code
+		if err := repo.saveReturnCode(micro.MicroDepositID, "R03"); err != nil {
+			t.Fatal(err)
+		}
+		found, err = repo.getMicroDeposits(micro.MicroDepositID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if found.Status != client.FAILED {
+			t.Errorf("unexpected status: %v", found.Status)
+		}
+		if found.ReturnCode == nil || found.ReturnCode.Code != "R03" {
+			t.Errorf("unexpected return code: %#v", found.ReturnCode)
+		}
+
+		// unknown transfer
+		if _, _, err := repo.lookupMicroDepositFromTransfer(base.ID()); err != sql.ErrNoRows {
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+}
+
+func TestRepository__alignStatus(t *testing.T) {
+	t.Parallel()
+
+	check := func(t *testing.T, repo *sqlRepo) {
+		micro := writeMicroDeposits(t, repo)
+
+		transferRepo := transfers.NewRepo(repo.db)
+		for i := range micro.TransferIDs {
+			xfer := &client.Transfer{
+				TransferID:  micro.TransferIDs[i],
+				Amount:      micro.Amounts[i],
+				Destination: micro.Destination,
+				Description: "validation",
+				Status:      client.PENDING,
+				Created:     time.Now(),
+			}
+			if err := transferRepo.WriteUserTransfer("moov", xfer); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		status := func() client.TransferStatus {
+			found, err := repo.getMicroDeposits(micro.MicroDepositID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return found.Status
+		}
+		if s := status(); s != client.PENDING {
+			t.Errorf("unexpected status: %v", s)
+		}
+
+		// micro-deposits are processed once each of their Transfers is
+		if err := transferRepo.UpdateTransferStatus(micro.TransferIDs[0], client.PROCESSED, history.Pipeline); err != nil {
+			t.Fatal(err)
+		}
+		if s := status(); s != client.PENDING {
+			t.Errorf("unexpected status: %v", s)
+		}
+		if err := transferRepo.UpdateTransferStatus(micro.TransferIDs[1], client.PROCESSED, history.Pipeline); err != nil {
+			t.Fatal(err)
+		}
+		if s := status(); s != client.PROCESSED {
+			t.Errorf("unexpected status: %v", s)
+		}
+
+		// returns still fail them
+		if err := repo.saveReturnCode(micro.MicroDepositID, "R03"); err != nil {
+			t.Fatal(err)
+		}
+		if s := status(); s != client.FAILED {
+			t.Errorf("unexpected status: %v", s)
+		}
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+}
+
+func TestRepository__confirmations(t *testing.T) {
+	t.Parallel()
+
+	check := func(t *testing.T, repo *sqlRepo) {
+		micro := writeMicroDeposits(t, repo)
+
+		remaining, err := repo.failedConfirmation(micro.MicroDepositID, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if remaining != 1 {
+			t.Errorf("unexpected remaining=%d", remaining)
+		}
+
+		now := time.Now()
+		if err := repo.verifyMicroDeposits(micro.MicroDepositID, now); err != nil {
+			t.Fatal(err)
+		}
+		found, err := repo.getMicroDeposits(micro.MicroDepositID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if found.VerifiedAt == nil || found.Status != client.PENDING {
+			t.Errorf("unexpected micro-deposits: %#v", found)
+		}
+
+		// running out of guesses fails the micro-deposits
+		micro = writeMicroDeposits(t, repo)
+		for i := 0; i < 2; i++ {
+			remaining, err = repo.failedConfirmation(micro.MicroDepositID, 2)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		if remaining != 0 {
+			t.Errorf("unexpected remaining=%d", remaining)
+		}
+		found, err = repo.getMicroDeposits(micro.MicroDepositID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if found.Status != client.FAILED || found.VerifiedAt != nil || found.ConfirmationAttempts != 2 {
+			t.Errorf("unexpected micro-deposits: %#v", found)
+		}
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+}
+
+func TestRepository__lockout(t *testing.T) {
+	t.Parallel()
+
+	check := func(t *testing.T, repo *sqlRepo) {
+		micro := writeMicroDeposits(t, repo)
+		if err := repo.recordConfirmation(micro.MicroDepositID, false, time.Now()); err != nil {
+			t.Fatal(err)
+		}
+		var n int
+		query := `select count(*) from micro_deposit_confirmations where micro_deposit_id = ? and matched = ?;`
+		if err := repo.db.QueryRow(query, micro.MicroDepositID, false).Scan(&n); err != nil {
+			t.Fatal(err)
+		}
+		if n != 1 {
+			t.Errorf("unexpected confirmations: %d", n)
+		}
+
+		if locked, err := repo.lockedOut(micro.MicroDepositID, 2); err != nil || locked {
+			t.Fatalf("locked=%v error=%v", locked, err)
+		}
+		if err := repo.resetLockout(micro.MicroDepositID, 2); err == nil {
+			t.Error("expected error")
+		}
+
+		for i := 0; i < 2; i++ {
+			if _, err := repo.failedConfirmation(micro.MicroDepositID, 2); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if locked, err := repo.lockedOut(micro.MicroDepositID, 2); err != nil || !locked {
+			t.Fatalf("locked=%v error=%v", locked, err)
+		}
+
+		// resetting allows guesses again
+		if err := repo.resetLockout(micro.MicroDepositID, 2); err != nil {
+			t.Fatal(err)
+		}
+		found, err := repo.getMicroDeposits(micro.MicroDepositID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if found.Status != client.PROCESSED {
+			t.Errorf("unexpected status=%s", found.Status)
+		}
+		remaining, err := repo.failedConfirmation(micro.MicroDepositID, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if remaining != 1 {
+			t.Errorf("unexpected remaining=%d", remaining)
+		}
+
+		// micro-deposits failed from a return aren't locked out
+		returned := writeMicroDeposits(t, repo)
+		for i := 0; i < 2; i++ {
+			if _, err := repo.failedConfirmation(returned.MicroDepositID, 2); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := repo.saveReturnCode(returned.MicroDepositID, "R03"); err != nil {
+			t.Fatal(err)
+		}
+		if locked, err := repo.lockedOut(returned.MicroDepositID, 2); err != nil || locked {
+			t.Fatalf("locked=%v error=%v", locked, err)
+		}
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+}
+
+func TestRepository__unverifiedMicroDeposits(t *testing.T) {
+	t.Parallel()
+
+	check := func(t *testing.T, repo *sqlRepo) {
+		pending := writeMicroDeposits(t, repo)
+
+		verified := writeMicroDeposits(t, repo)
+		if err := repo.verifyMicroDeposits(verified.MicroDepositID, time.Now()); err != nil {
+			t.Fatal(err)
+		}
+		failed := writeMicroDeposits(t, repo)
+		if err := repo.saveReturnCode(failed.MicroDepositID, "R03"); err != nil {
+			t.Fatal(err)
+		}
+
+		micros, err := repo.getUnverifiedMicroDeposits()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(micros) != 1 || micros[0].MicroDepositID != pending.MicroDepositID {
+			t.Errorf("unexpected micro-deposits: %#v", micros)
+		}
+
+		now := time.Now().Truncate(time.Second)
+		if err := repo.expireMicroDeposits(pending.MicroDepositID, now); err != nil {
+			t.Fatal(err)
+		}
+		found, err := repo.getMicroDeposits(pending.MicroDepositID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if found.ExpiresAt == nil || !found.ExpiresAt.Equal(now) {
+			t.Errorf("unexpected expiresAt=%v", found.ExpiresAt)
+		}
+
+		// verified micro-deposits can't be expired
+		if err := repo.expireMicroDeposits(verified.MicroDepositID, now); err == nil {
+			t.Error("expected error")
+		}
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+}
+
+func TestRepository__unsentEvents(t *testing.T) {
+	t.Parallel()
+
+	check := func(t *testing.T, repo *sqlRepo) {
+		now := time.Now()
+
+		processed := writeMicroDeposits(t, repo)
+		if _, err := repo.db.Exec(`update micro_deposits set processed_at = ? where micro_deposit_id = ?;`, now, processed.MicroDepositID); err != nil {
+			t.Fatal(err)
+		}
+		expired := writeMicroDeposits(t, repo)
+		if err := repo.expireMicroDeposits(expired.MicroDepositID, now.Add(-time.Minute)); err != nil {
+			t.Fatal(err)
+		}
+		writeMicroDeposits(t, repo) // neither
+
+		ids, err := repo.getUnsentProcessedEvents()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(ids) != 1 || ids[0] != processed.MicroDepositID {
+			t.Errorf("unexpected processed: %v", ids)
+		}
+		ids, err = repo.getUnsentExpiredEvents(now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(ids) != 1 || ids[0] != expired.MicroDepositID {
+			t.Errorf("unexpected expired: %v", ids)
+		}
+
+		// events are only marked sent once
+		for _, eventType := range []string{EventVerificationProcessed, EventVerificationExpired} {
+			microDepositID := processed.MicroDepositID
+			if eventType == EventVerificationExpired {
+				microDepositID = expired.MicroDepositID
+			}
+			if sent, err := repo.markEventSent(microDepositID, eventType, now); err != nil || !sent {
+				t.Fatalf("%s: sent=%v error=%v", eventType, sent, err)
+			}
+			if sent, err := repo.markEventSent(microDepositID, eventType, now); err != nil || sent {
+				t.Fatalf("%s: sent=%v error=%v", eventType, sent, err)
+			}
+		}
+		if ids, err := repo.getUnsentProcessedEvents(); err != nil || len(ids) != 0 {
+			t.Errorf("processed=%v error=%v", ids, err)
+		}
+		if ids, err := repo.getUnsentExpiredEvents(now); err != nil || len(ids) != 0 {
+			t.Errorf("expired=%v error=%v", ids, err)
+		}
+
+		if _, err := repo.markEventSent(processed.MicroDepositID, EventVerificationInitiated, now); err == nil {
+			t.Error("expected error")
+		}
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+}
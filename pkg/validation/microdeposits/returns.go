@@ -0,0 +1,96 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package microdeposits
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/moov-io/ach"
+	moovcustomers "github.com/moov-io/customers/pkg/client"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/customers"
+	"github.com/moov-io/paygate/pkg/webhooks"
+
+	"github.com/moov-io/base/log"
+)
+
+// ReturnHandler fails micro-deposits whose credits are returned by the RDFI. The account is
+// marked as unverified and a webhook is sent so the account details can be corrected.
+type ReturnHandler struct {
+	cfg    config.MicroDeposits
+	logger log.Logger
+
+	repo            Repository
+	customersClient customers.Client
+	events          webhooks.Sender
+}
+
+// NewReturnHandler returns a ReturnHandler or nil if micro-deposits are disabled.
+func NewReturnHandler(cfg *config.Config, repo Repository, customersClient customers.Client, events webhooks.Sender) *ReturnHandler {
+	if cfg.Validation.MicroDeposits == nil {
+		return nil
+	}
+	return &ReturnHandler{
+		cfg:             *cfg.Validation.MicroDeposits,
+		logger:          cfg.Logger.Set("service", "micro-deposits"),
+		repo:            repo,
+		customersClient: customersClient,
+		events:          events,
+	}
+}
+
+// HandleReturn updates the micro-deposits which created transferID, if any exist.
+func (h *ReturnHandler) HandleReturn(transferID string, returnCode *ach.ReturnCode) error {
+	if h == nil {
+		return nil
+	}
+	if returnCode == nil {
+		return errors.New("missing return code")
+	}
+
+	micro, organization, err := h.repo.lookupMicroDepositFromTransfer(transferID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		return fmt.Errorf("problem looking up micro-deposits for transferID=%s: %v", transferID, err)
+	}
+	if micro == nil || micro.Status == client.FAILED {
+		return nil // each credit can be returned, but we only need to fail the micro-deposits once
+	}
+
+	logger := h.logger.With(log.Fields{
+		"microDepositID": micro.MicroDepositID,
+		"transferID":     transferID,
+		"returnCode":     returnCode.Code,
+	})
+	logger.Log("handling return for micro-deposits")
+
+	if err := h.repo.saveReturnCode(micro.MicroDepositID, returnCode.Code); err != nil {
+		return fmt.Errorf("problem saving microDepositID=%s return code: %v", micro.MicroDepositID, err)
+	}
+	micro.Status = client.FAILED
+	micro.ReturnCode = &client.ReturnCode{
+		Code:        returnCode.Code,
+		Reason:      returnCode.Reason,
+		Description: returnCode.Description,
+	}
+
+	// Return the account to an unverified status so it can be corrected and validated again
+	if h.customersClient != nil {
+		dest := micro.Destination
+		if _, err := h.customersClient.UpdateAccountStatus(dest.CustomerID, dest.AccountID, moovcustomers.ACCOUNTSTATUS_NONE); err != nil {
+			logger.LogErrorf("problem updating accountID=%s status: %v", dest.AccountID, err)
+		}
+	}
+
+	sendVerificationEvent(logger, h.events, h.cfg, EventVerificationFailed, organization, micro)
+
+	return nil
+}
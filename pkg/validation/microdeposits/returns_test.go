@@ -0,0 +1,66 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package microdeposits
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/moov-io/ach"
+	moovcustomers "github.com/moov-io/customers/pkg/client"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/webhooks"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReturnHandler(t *testing.T) {
+	micro := mockMicroDeposit()
+	repo := &mockRepository{
+		Micro:        micro,
+		Organization: "moov",
+	}
+	customersClient := mockCustomersClient()
+	customersClient.Accounts[destinationAccountID].Status = moovcustomers.ACCOUNTSTATUS_VALIDATED
+	events := &webhooks.MockSender{}
+
+	handler := NewReturnHandler(mockConfig(), repo, customersClient, events)
+	require.NotNil(t, handler)
+
+	err := handler.HandleReturn(micro.TransferIDs[0], ach.LookupReturnCode("R03"))
+	require.NoError(t, err)
+
+	require.Equal(t, client.FAILED, micro.Status)
+	require.Equal(t, "R03", micro.ReturnCode.Code)
+	require.Equal(t, moovcustomers.ACCOUNTSTATUS_NONE, customersClient.Accounts[destinationAccountID].Status)
+
+	require.Len(t, events.Events, 1)
+	require.Equal(t, EventVerificationFailed, events.Events[0].Type)
+	require.Equal(t, "moov", events.Events[0].Organization)
+
+	// the second credit being returned is ignored
+	err = handler.HandleReturn(micro.TransferIDs[1], ach.LookupReturnCode("R03"))
+	require.NoError(t, err)
+	require.Len(t, events.Events, 1)
+}
+
+func TestReturnHandler__notFound(t *testing.T) {
+	repo := &mockRepository{Err: sql.ErrNoRows}
+	events := &webhooks.MockSender{}
+
+	handler := NewReturnHandler(mockConfig(), repo, nil, events)
+	require.NoError(t, handler.HandleReturn("transferID", ach.LookupReturnCode("R03")))
+	require.Len(t, events.Events, 0)
+}
+
+func TestReturnHandler__disabled(t *testing.T) {
+	cfg := config.Empty()
+
+	handler := NewReturnHandler(cfg, &mockRepository{}, nil, nil)
+	require.Nil(t, handler)
+	require.NoError(t, handler.HandleReturn("transferID", ach.LookupReturnCode("R03")))
+}
@@ -11,6 +11,7 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
 	moovcustomers "github.com/moov-io/customers/pkg/client"
@@ -22,6 +23,7 @@ import (
 	"github.com/moov-io/paygate/pkg/transfers"
 	"github.com/moov-io/paygate/pkg/transfers/fundflow"
 	"github.com/moov-io/paygate/pkg/transfers/pipeline"
+	"github.com/moov-io/paygate/pkg/webhooks"
 	"github.com/moov-io/paygate/x/route"
 )
 
@@ -29,6 +31,12 @@ type Router struct {
 	InitiateMicroDeposits   http.HandlerFunc
 	GetMicroDeposits        http.HandlerFunc
 	GetAccountMicroDeposits http.HandlerFunc
+	GetAccountAttempts      http.HandlerFunc
+	GetAccountVerification  http.HandlerFunc
+
+	CreateVerificationLink     http.HandlerFunc
+	GetLinkedVerification      http.HandlerFunc
+	ConfirmLinkedMicroDeposits http.HandlerFunc
 }
 
 func NewRouter(
@@ -39,12 +47,19 @@ func NewRouter(
 	accountDecryptor accounts.Decryptor,
 	fundStrategy fundflow.Strategy,
 	pub pipeline.XferPublisher,
+	events webhooks.Sender,
 ) *Router {
 	if cfg.Validation.MicroDeposits == nil {
 		return &Router{
 			InitiateMicroDeposits:   NotImplemented(cfg),
 			GetMicroDeposits:        NotImplemented(cfg),
 			GetAccountMicroDeposits: NotImplemented(cfg),
+			GetAccountAttempts:      NotImplemented(cfg),
+			GetAccountVerification:  NotImplemented(cfg),
+
+			CreateVerificationLink:     NotImplemented(cfg),
+			GetLinkedVerification:      NotImplemented(cfg),
+			ConfirmLinkedMicroDeposits: NotImplemented(cfg),
 		}
 	}
 
@@ -53,17 +68,36 @@ func NewRouter(
 	// TODO(adam): this will also be read from auth on the request
 	companyIdentification := cfg.ODFI.FileConfig.BatchHeader.CompanyIdentification
 
-	return &Router{
-		InitiateMicroDeposits:   InitiateMicroDeposits(cfg, companyIdentification, repo, transferRepo, customersClient, accountDecryptor, fundStrategy, pub),
+	router := &Router{
+		InitiateMicroDeposits:   InitiateMicroDeposits(cfg, companyIdentification, repo, transferRepo, customersClient, accountDecryptor, fundStrategy, pub, events),
 		GetMicroDeposits:        GetMicroDeposits(cfg, repo),
 		GetAccountMicroDeposits: GetAccountMicroDeposits(cfg, repo),
+		GetAccountAttempts:      GetAccountMicroDepositAttempts(cfg, repo),
+		GetAccountVerification:  GetAccountVerification(cfg, repo),
+
+		CreateVerificationLink:     LinksNotImplemented(cfg),
+		GetLinkedVerification:      LinksNotImplemented(cfg),
+		ConfirmLinkedMicroDeposits: LinksNotImplemented(cfg),
 	}
+	if cfg.Validation.MicroDeposits.Links != nil {
+		router.CreateVerificationLink = CreateVerificationLink(cfg, repo)
+		router.GetLinkedVerification = GetLinkedVerification(cfg, repo)
+		router.ConfirmLinkedMicroDeposits = ConfirmLinkedMicroDeposits(cfg, repo, customersClient, events)
+	}
+	return router
 }
 
 func (c *Router) RegisterRoutes(r *mux.Router) {
 	r.Methods("POST").Path("/micro-deposits").HandlerFunc(c.InitiateMicroDeposits)
 	r.Methods("GET").Path("/micro-deposits/{microDepositID}").HandlerFunc(c.GetMicroDeposits)
 	r.Methods("GET").Path("/accounts/{accountID}/micro-deposits").HandlerFunc(c.GetAccountMicroDeposits)
+	r.Methods("GET").Path("/accounts/{accountID}/micro-deposits/attempts").HandlerFunc(c.GetAccountAttempts)
+	r.Methods("GET").Path("/accounts/{accountID}/verification").HandlerFunc(c.GetAccountVerification)
+	r.Methods("POST").Path("/accounts/{accountID}/verification/links").HandlerFunc(c.CreateVerificationLink)
+
+	// Receivers open verification links directly, so these routes are authenticated by their token.
+	r.Methods("GET").Path("/verify/{token}").HandlerFunc(c.GetLinkedVerification)
+	r.Methods("POST").Path("/verify/{token}").HandlerFunc(c.ConfirmLinkedMicroDeposits)
 }
 
 func InitiateMicroDeposits(
@@ -75,6 +109,7 @@ func InitiateMicroDeposits(
 	accountDecryptor accounts.Decryptor,
 	fundStrategy fundflow.Strategy,
 	pub pipeline.XferPublisher,
+	events webhooks.Sender,
 ) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		conf := *cfg.Validation.MicroDeposits
@@ -112,7 +147,25 @@ func InitiateMicroDeposits(
 				return
 			}
 
-			micro, err := createMicroDeposits(conf, responder.OrganizationID, companyIdentification, src, dest, transferRepo, accountDecryptor, fundStrategy, pub)
+			// Only one attempt can be outstanding at a time, but once the latest has expired
+			// or failed (e.g. returned) a new attempt is made and prior ones are kept.
+			latest, err := repo.getAccountMicroDeposits(dest.Account.AccountID)
+			if err != nil && err != sql.ErrNoRows {
+				cfg.Logger.LogErrorf("ERROR reading latest micro-deposits: %v", err)
+				responder.Problem(err)
+				return
+			}
+			if err := activeAttempt(conf, latest, time.Now()); err != nil {
+				cfg.Logger.LogError(err)
+				responder.Problem(err)
+				return
+			}
+			attempt := int32(1)
+			if latest != nil {
+				attempt = latest.Attempt + 1
+			}
+
+			micro, err := createMicroDeposits(conf, responder.OrganizationID, companyIdentification, attempt, src, dest, transferRepo, accountDecryptor, fundStrategy, pub)
 			if err != nil {
 				cfg.Logger.LogErrorf("ERROR creating micro-deposits: %v", err)
 				responder.Problem(err)
@@ -123,6 +176,7 @@ func InitiateMicroDeposits(
 				responder.Problem(err)
 				return
 			}
+			sendVerificationEvent(cfg.Logger, events, conf, EventVerificationInitiated, responder.OrganizationID, micro)
 
 			w.WriteHeader(http.StatusOK)
 			json.NewEncoder(w).Encode(micro)
@@ -190,6 +244,32 @@ func GetAccountMicroDeposits(cfg *config.Config, repo Repository) http.HandlerFu
 	}
 }
 
+func GetAccountMicroDepositAttempts(cfg *config.Config, repo Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(cfg, w, r)
+		responder.Respond(func(w http.ResponseWriter) {
+			accountID := route.ReadPathID("accountID", r)
+			if accountID == "" {
+				responder.Problem(errors.New("missing accountID"))
+				return
+			}
+
+			attempts, err := repo.getAccountMicroDepositAttempts(accountID)
+			if err != nil {
+				cfg.Logger.LogErrorf("ERROR getting accountID=%s micro-deposit attempts: %v", accountID, err)
+				responder.Problem(err)
+				return
+			}
+			if attempts == nil {
+				attempts = []*client.MicroDeposits{}
+			}
+
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(attempts)
+		})
+	}
+}
+
 func NotImplemented(cfg *config.Config) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		responder := route.NewResponder(cfg, w, r)
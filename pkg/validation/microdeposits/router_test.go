@@ -6,6 +6,7 @@ package microdeposits
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
@@ -24,6 +25,7 @@ import (
 	"github.com/moov-io/paygate/pkg/transfers"
 	"github.com/moov-io/paygate/pkg/transfers/fundflow"
 	"github.com/moov-io/paygate/pkg/transfers/pipeline"
+	"github.com/moov-io/paygate/pkg/webhooks"
 
 	"github.com/gorilla/mux"
 )
@@ -138,7 +140,7 @@ func TestRouter__NotImplemented(t *testing.T) {
 	}
 
 	r := mux.NewRouter()
-	router := NewRouter(cfg, repo, mockTransferRepo, customersClient, mockDecryptor, mockStrategy, fakePublisher)
+	router := NewRouter(cfg, repo, mockTransferRepo, customersClient, mockDecryptor, mockStrategy, fakePublisher, nil)
 	router.RegisterRoutes(r)
 
 	req := httptest.NewRequest("GET", fmt.Sprintf("/micro-deposits/%s", base.ID()), nil)
@@ -160,12 +162,18 @@ func TestRouter__InitiateMicroDeposits(t *testing.T) {
 	cfg := mockConfig()
 	customersClient := mockCustomersClient()
 
+	// the previous attempt was returned, so a new one is allowed
+	previous := mockMicroDeposit()
+	previous.Status = client.FAILED
+	previous.Attempt = 1
 	repo := &mockRepository{
-		Micro: mockMicroDeposit(),
+		Micro: previous,
 	}
 
+	events := &webhooks.MockSender{}
+
 	r := mux.NewRouter()
-	router := NewRouter(cfg, repo, mockTransferRepo, customersClient, mockDecryptor, mockStrategy, fakePublisher)
+	router := NewRouter(cfg, repo, mockTransferRepo, customersClient, mockDecryptor, mockStrategy, fakePublisher, events)
 	router.RegisterRoutes(r)
 
 	c := testclient.New(t, r)
@@ -189,6 +197,72 @@ func TestRouter__InitiateMicroDeposits(t *testing.T) {
 	if micro.MicroDepositID == "" {
 		t.Error("missing MicroDeposit")
 	}
+	if micro.Attempt != 2 {
+		t.Errorf("unexpected attempt: %d", micro.Attempt)
+	}
+	if n := len(events.Events); n != 1 {
+		t.Fatalf("got %d webhook events", n)
+	}
+	if events.Events[0].Type != EventVerificationInitiated || events.Events[0].Organization != orgID {
+		t.Errorf("unexpected event: %#v", events.Events[0])
+	}
+	links := events.Events[0].Links
+	if len(links) != 3 || links[0].ID != micro.MicroDepositID || links[2].ID != destinationAccountID {
+		t.Errorf("unexpected links: %#v", links)
+	}
+}
+
+func TestRouter__InitiateMicroDepositsActiveAttempt(t *testing.T) {
+	cfg := mockConfig()
+	customersClient := mockCustomersClient()
+
+	repo := &mockRepository{
+		Micro: mockMicroDeposit(),
+	}
+	events := &webhooks.MockSender{}
+
+	r := mux.NewRouter()
+	router := NewRouter(cfg, repo, mockTransferRepo, customersClient, mockDecryptor, mockStrategy, fakePublisher, events)
+	router.RegisterRoutes(r)
+
+	c := testclient.New(t, r)
+
+	_, resp, err := c.ValidationApi.InitiateMicroDeposits(context.TODO(), base.ID(), client.CreateMicroDeposits{
+		Destination: client.Destination{
+			CustomerID: destinationCustomerID,
+			AccountID:  destinationAccountID,
+		},
+	})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("unexpected HTTP status: %s", resp.Status)
+	}
+	if n := len(events.Events); n != 0 {
+		t.Errorf("got %d webhook events", n)
+	}
+
+	// once expired a new attempt can be made
+	cfg.Validation.MicroDeposits.Expiration = time.Hour
+	repo.Micro.Created = time.Now().Add(-2 * time.Hour)
+
+	micro, resp, err := c.ValidationApi.InitiateMicroDeposits(context.TODO(), base.ID(), client.CreateMicroDeposits{
+		Destination: client.Destination{
+			CustomerID: destinationCustomerID,
+			AccountID:  destinationAccountID,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if micro.ExpiresAt == nil {
+		t.Error("expected ExpiresAt")
+	}
 }
 
 func TestRouter__InitiateMicroDepositsErr(t *testing.T) {
@@ -197,7 +271,7 @@ func TestRouter__InitiateMicroDepositsErr(t *testing.T) {
 	repo := &mockRepository{Err: errors.New("bad request")}
 
 	r := mux.NewRouter()
-	router := NewRouter(cfg, repo, mockTransferRepo, customersClient, mockDecryptor, mockStrategy, fakePublisher)
+	router := NewRouter(cfg, repo, mockTransferRepo, customersClient, mockDecryptor, mockStrategy, fakePublisher, nil)
 	router.RegisterRoutes(r)
 
 	c := testclient.New(t, r)
@@ -219,7 +293,7 @@ func TestRouter__GetMicroDeposits(t *testing.T) {
 	}
 
 	r := mux.NewRouter()
-	router := NewRouter(cfg, repo, mockTransferRepo, customersClient, mockDecryptor, mockStrategy, fakePublisher)
+	router := NewRouter(cfg, repo, mockTransferRepo, customersClient, mockDecryptor, mockStrategy, fakePublisher, nil)
 	router.RegisterRoutes(r)
 
 	c := testclient.New(t, r)
@@ -243,7 +317,7 @@ func TestRouter__GetMicroDepositsEmpty(t *testing.T) {
 	repo := &mockRepository{}
 
 	r := mux.NewRouter()
-	router := NewRouter(cfg, repo, mockTransferRepo, customersClient, mockDecryptor, mockStrategy, fakePublisher)
+	router := NewRouter(cfg, repo, mockTransferRepo, customersClient, mockDecryptor, mockStrategy, fakePublisher, nil)
 	router.RegisterRoutes(r)
 
 	c := testclient.New(t, r)
@@ -267,7 +341,7 @@ func TestRouter__GetMicroDepositsErr(t *testing.T) {
 	repo := &mockRepository{Err: errors.New("bad error")}
 
 	r := mux.NewRouter()
-	router := NewRouter(cfg, repo, mockTransferRepo, customersClient, mockDecryptor, mockStrategy, fakePublisher)
+	router := NewRouter(cfg, repo, mockTransferRepo, customersClient, mockDecryptor, mockStrategy, fakePublisher, nil)
 	router.RegisterRoutes(r)
 
 	c := testclient.New(t, r)
@@ -289,7 +363,7 @@ func TestRouter__GetAccountMicroDeposits(t *testing.T) {
 	}
 
 	r := mux.NewRouter()
-	router := NewRouter(cfg, repo, mockTransferRepo, customersClient, mockDecryptor, mockStrategy, fakePublisher)
+	router := NewRouter(cfg, repo, mockTransferRepo, customersClient, mockDecryptor, mockStrategy, fakePublisher, nil)
 	router.RegisterRoutes(r)
 
 	c := testclient.New(t, r)
@@ -313,7 +387,7 @@ func TestRouter__GetAccountMicroDepositsEmpty(t *testing.T) {
 	repo := &mockRepository{}
 
 	r := mux.NewRouter()
-	router := NewRouter(cfg, repo, mockTransferRepo, customersClient, mockDecryptor, mockStrategy, fakePublisher)
+	router := NewRouter(cfg, repo, mockTransferRepo, customersClient, mockDecryptor, mockStrategy, fakePublisher, nil)
 	router.RegisterRoutes(r)
 
 	c := testclient.New(t, r)
@@ -337,7 +411,7 @@ func TestRouter__GetAccountMicroDepositsErr(t *testing.T) {
 	repo := &mockRepository{Err: errors.New("bad error")}
 
 	r := mux.NewRouter()
-	router := NewRouter(cfg, repo, mockTransferRepo, customersClient, mockDecryptor, mockStrategy, fakePublisher)
+	router := NewRouter(cfg, repo, mockTransferRepo, customersClient, mockDecryptor, mockStrategy, fakePublisher, nil)
 	router.RegisterRoutes(r)
 
 	c := testclient.New(t, r)
@@ -349,3 +423,47 @@ func TestRouter__GetAccountMicroDepositsErr(t *testing.T) {
 	}
 	resp.Body.Close()
 }
+
+func TestRouter__GetAccountMicroDepositAttempts(t *testing.T) {
+	cfg := mockConfig()
+
+	first, second := mockMicroDeposit(), mockMicroDeposit()
+	first.Status, first.Attempt = client.FAILED, 1
+	second.Attempt = 2
+	repo := &mockRepository{
+		Attempts: []*client.MicroDeposits{second, first},
+	}
+
+	r := mux.NewRouter()
+	router := NewRouter(cfg, repo, mockTransferRepo, mockCustomersClient(), mockDecryptor, mockStrategy, fakePublisher, nil)
+	router.RegisterRoutes(r)
+
+	req := httptest.NewRequest("GET", fmt.Sprintf("/accounts/%s/micro-deposits/attempts", destinationAccountID), nil)
+	req.Header.Set("X-Organization", base.ID())
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	w.Flush()
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("bogus HTTP status %d: %v", w.Code, w.Body.String())
+	}
+
+	var attempts []*client.MicroDeposits
+	if err := json.NewDecoder(w.Body).Decode(&attempts); err != nil {
+		t.Fatal(err)
+	}
+	if len(attempts) != 2 || attempts[0].Attempt != 2 || attempts[1].Attempt != 1 {
+		t.Errorf("unexpected attempts: %#v", attempts)
+	}
+
+	// no attempts
+	repo.Attempts = nil
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	w.Flush()
+
+	if w.Code != http.StatusOK || strings.TrimSpace(w.Body.String()) != "[]" {
+		t.Errorf("bogus HTTP status %d: %v", w.Code, w.Body.String())
+	}
+}
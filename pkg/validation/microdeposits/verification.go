@@ -0,0 +1,131 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package microdeposits
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/x/route"
+)
+
+const verificationMethod = "micro-deposits"
+
+// verificationState returns the AccountVerification for a set of micro-deposits.
+// The status is derived from the micro-deposits status and when they expire.
+func verificationState(cfg config.MicroDeposits, micro *client.MicroDeposits, now time.Time) *client.AccountVerification {
+	if micro == nil {
+		return nil
+	}
+	out := &client.AccountVerification{
+		AccountID:      micro.Destination.AccountID,
+		Method:         verificationMethod,
+		MicroDepositID: micro.MicroDepositID,
+		Status:         client.VERIFICATIONSTATUS_INITIATED,
+		Initiated:      micro.Created,
+		ProcessedAt:    micro.ProcessedAt,
+		ReturnCode:     micro.ReturnCode,
+	}
+	switch micro.Status {
+	case client.PROCESSED:
+		out.Status = client.VERIFICATIONSTATUS_PROCESSED
+	case client.FAILED, client.CANCELED:
+		out.Status = client.VERIFICATIONSTATUS_FAILED
+	}
+	if micro.VerifiedAt != nil {
+		out.Status = client.VERIFICATIONSTATUS_VERIFIED
+		out.VerifiedAt = micro.VerifiedAt
+	}
+	if expires := expiresAt(cfg, micro); expires != nil {
+		out.ExpiresAt = expires
+
+		if !finished(out.Status) && now.After(*expires) {
+			out.Status = client.VERIFICATIONSTATUS_EXPIRED
+		}
+	}
+	out.AttemptsRemaining = attemptsRemaining(cfg, micro, out.Status)
+	return out
+}
+
+// attemptsRemaining returns how many more times the amounts can be confirmed. None remain
+// once the verification has finished or expired.
+func attemptsRemaining(cfg config.MicroDeposits, micro *client.MicroDeposits, status client.VerificationStatus) int32 {
+	if finished(status) || status == client.VERIFICATIONSTATUS_EXPIRED {
+		return 0
+	}
+	remaining := int32(cfg.Links.Guesses()) - micro.ConfirmationAttempts
+	if remaining < 0 {
+		return 0
+	}
+	return remaining
+}
+
+// expiresAt returns when micro-deposits are no longer valid for verification. The value
+// saved on the attempt is preferred over the current config so that changing
+// Expiration doesn't alter attempts already initiated.
+func expiresAt(cfg config.MicroDeposits, micro *client.MicroDeposits) *time.Time {
+	if micro.ExpiresAt != nil {
+		return micro.ExpiresAt
+	}
+	if cfg.Expiration > 0 {
+		expires := micro.Created.Add(cfg.Expiration)
+		return &expires
+	}
+	return nil
+}
+
+// finished returns true when a verification can no longer change status
+func finished(status client.VerificationStatus) bool {
+	return status == client.VERIFICATIONSTATUS_FAILED || status == client.VERIFICATIONSTATUS_VERIFIED
+}
+
+// activeAttempt returns an error if micro are still pending or awaiting confirmation
+// and a new attempt should not be initiated for the account.
+func activeAttempt(cfg config.MicroDeposits, micro *client.MicroDeposits, now time.Time) error {
+	state := verificationState(cfg, micro, now)
+	if state == nil {
+		return nil
+	}
+	switch state.Status {
+	case client.VERIFICATIONSTATUS_EXPIRED, client.VERIFICATIONSTATUS_FAILED:
+		return nil
+	case client.VERIFICATIONSTATUS_VERIFIED:
+		return fmt.Errorf("accountID=%s was already verified by microDepositID=%s", state.AccountID, state.MicroDepositID)
+	}
+	return fmt.Errorf("accountID=%s has an active micro-deposit attempt (microDepositID=%s status=%s)", state.AccountID, state.MicroDepositID, state.Status)
+}
+
+func GetAccountVerification(cfg *config.Config, repo Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(cfg, w, r)
+		responder.Respond(func(w http.ResponseWriter) {
+			accountID := route.ReadPathID("accountID", r)
+			if accountID == "" {
+				responder.Problem(errors.New("missing accountID"))
+				return
+			}
+
+			micro, err := repo.getAccountMicroDeposits(accountID)
+			if err != nil {
+				if err == sql.ErrNoRows {
+					responder.Problem(errors.New("no verification found"))
+					return
+				}
+				cfg.Logger.LogErrorf("ERROR getting accountID=%s verification: %v", accountID, err)
+				responder.Problem(err)
+				return
+			}
+
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(verificationState(*cfg.Validation.MicroDeposits, micro, time.Now()))
+		})
+	}
+}
@@ -0,0 +1,138 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package microdeposits
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/moov-io/base"
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerification__state(t *testing.T) {
+	cfg := config.MicroDeposits{}
+	micro := mockMicroDeposit()
+
+	state := verificationState(cfg, micro, time.Now())
+	require.Equal(t, client.VERIFICATIONSTATUS_INITIATED, state.Status)
+	require.Equal(t, micro.MicroDepositID, state.MicroDepositID)
+	require.Equal(t, destinationAccountID, state.AccountID)
+	require.Nil(t, state.ExpiresAt)
+
+	micro.Status = client.PROCESSED
+	state = verificationState(cfg, micro, time.Now())
+	require.Equal(t, client.VERIFICATIONSTATUS_PROCESSED, state.Status)
+
+	micro.Status = client.FAILED
+	state = verificationState(cfg, micro, time.Now())
+	require.Equal(t, client.VERIFICATIONSTATUS_FAILED, state.Status)
+
+	// expired micro-deposits
+	cfg.Expiration = 24 * time.Hour
+	micro.Status = client.PROCESSED
+	state = verificationState(cfg, micro, time.Now().Add(48*time.Hour))
+	require.Equal(t, client.VERIFICATIONSTATUS_EXPIRED, state.Status)
+	require.NotNil(t, state.ExpiresAt)
+
+	require.Nil(t, verificationState(cfg, nil, time.Now()))
+
+	// ExpiresAt saved on the attempt takes priority over config
+	expires := micro.Created.Add(time.Hour)
+	micro.ExpiresAt = &expires
+	state = verificationState(cfg, micro, time.Now().Add(2*time.Hour))
+	require.Equal(t, client.VERIFICATIONSTATUS_EXPIRED, state.Status)
+	require.Equal(t, expires, *state.ExpiresAt)
+
+	// verified micro-deposits don't expire
+	verified := micro.Created.Add(time.Minute)
+	micro.VerifiedAt = &verified
+	state = verificationState(cfg, micro, time.Now().Add(2*time.Hour))
+	require.Equal(t, client.VERIFICATIONSTATUS_VERIFIED, state.Status)
+	require.Equal(t, verified, *state.VerifiedAt)
+}
+
+func TestVerification__attemptsRemaining(t *testing.T) {
+	cfg := config.MicroDeposits{}
+	micro := mockMicroDeposit()
+	micro.Status = client.PROCESSED
+
+	state := verificationState(cfg, micro, time.Now())
+	require.Equal(t, int32(3), state.AttemptsRemaining)
+
+	micro.ConfirmationAttempts = 2
+	state = verificationState(cfg, micro, time.Now())
+	require.Equal(t, int32(1), state.AttemptsRemaining)
+
+	cfg.Links = &config.VerificationLinks{MaxGuesses: 5}
+	state = verificationState(cfg, micro, time.Now())
+	require.Equal(t, int32(3), state.AttemptsRemaining)
+
+	// none remain once the verification has finished or expired
+	micro.Status = client.FAILED
+	state = verificationState(cfg, micro, time.Now())
+	require.Equal(t, int32(0), state.AttemptsRemaining)
+
+	micro.Status = client.PROCESSED
+	expires := time.Now().Add(-time.Minute)
+	micro.ExpiresAt = &expires
+	state = verificationState(cfg, micro, time.Now())
+	require.Equal(t, client.VERIFICATIONSTATUS_EXPIRED, state.Status)
+	require.Equal(t, int32(0), state.AttemptsRemaining)
+}
+
+func TestVerification__activeAttempt(t *testing.T) {
+	cfg := config.MicroDeposits{Expiration: time.Hour}
+	micro := mockMicroDeposit()
+
+	require.NoError(t, activeAttempt(cfg, nil, time.Now()))
+	require.Error(t, activeAttempt(cfg, micro, time.Now()))
+
+	micro.Status = client.PROCESSED
+	require.Error(t, activeAttempt(cfg, micro, time.Now()))
+	require.NoError(t, activeAttempt(cfg, micro, time.Now().Add(2*time.Hour)))
+
+	micro.Status = client.FAILED
+	require.NoError(t, activeAttempt(cfg, micro, time.Now()))
+
+	verified := time.Now()
+	micro.Status = client.PROCESSED
+	micro.VerifiedAt = &verified
+	require.Error(t, activeAttempt(cfg, micro, time.Now().Add(2*time.Hour)))
+}
+
+func TestRouter__GetAccountVerification(t *testing.T) {
+	cfg := mockConfig()
+	repo := &mockRepository{
+		Micro: mockMicroDeposit(),
+	}
+
+	r := mux.NewRouter()
+	router := NewRouter(cfg, repo, mockTransferRepo, mockCustomersClient(), mockDecryptor, mockStrategy, fakePublisher, nil)
+	router.RegisterRoutes(r)
+
+	req := httptest.NewRequest("GET", fmt.Sprintf("/accounts/%s/verification", destinationAccountID), nil)
+	req.Header.Set("X-Organization", base.ID())
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	w.Flush()
+
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var resp client.AccountVerification
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	require.Equal(t, repo.Micro.MicroDepositID, resp.MicroDepositID)
+	require.Equal(t, "micro-deposits", resp.Method)
+	require.Equal(t, client.VERIFICATIONSTATUS_INITIATED, resp.Status)
+}
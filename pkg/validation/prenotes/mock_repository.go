@@ -0,0 +1,70 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package prenotes
+
+import (
+	"time"
+
+	"github.com/moov-io/paygate/pkg/client"
+)
+
+type mockRepository struct {
+	Prenote  *client.Prenote
+	Prenotes []*client.Prenote
+	Pending  []pendingPrenote
+	Err      error
+
+	// Verified and Failed record the prenoteIDs updated
+	Verified []string
+	Failed   []string
+}
+
+func (r *mockRepository) getPrenote(prenoteID string) (*client.Prenote, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	return r.Prenote, nil
+}
+
+func (r *mockRepository) getAccountPrenotes(accountID string) ([]*client.Prenote, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	return r.Prenotes, nil
+}
+
+func (r *mockRepository) writePrenote(organization string, prenote *client.Prenote) error {
+	return r.Err
+}
+
+func (r *mockRepository) getPendingPrenotes() ([]pendingPrenote, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	return r.Pending, nil
+}
+
+func (r *mockRepository) lookupPrenoteFromTransfer(transferID string) (*client.Prenote, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	return r.Prenote, nil
+}
+
+func (r *mockRepository) verifyPrenote(prenoteID string, when time.Time) error {
+	if r.Err != nil {
+		return r.Err
+	}
+	r.Verified = append(r.Verified, prenoteID)
+	return nil
+}
+
+func (r *mockRepository) failPrenote(prenoteID string, returnCode string, changeCode string) error {
+	if r.Err != nil {
+		return r.Err
+	}
+	r.Failed = append(r.Failed, prenoteID)
+	return nil
+}
@@ -0,0 +1,123 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package prenotes
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/moov-io/ach"
+	"github.com/moov-io/base"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/transfers"
+	"github.com/moov-io/paygate/pkg/transfers/fundflow"
+	"github.com/moov-io/paygate/pkg/transfers/pipeline"
+)
+
+func createPrenote(
+	cfg config.Prenotes,
+	organization string,
+	companyIdentification string,
+	src fundflow.Source,
+	dest fundflow.Destination,
+	repo transfers.Repository,
+	strategy fundflow.Strategy,
+	pub pipeline.XferPublisher,
+) (*client.Prenote, error) {
+	xfer := prenoteTransfer(src, dest, cfg.Description)
+
+	// Save our Transfer to the database
+	if err := repo.WriteUserTransfer(organization, xfer); err != nil {
+		return nil, err
+	}
+
+	// Originate the ACH file(s) as a credit and then mark each entry as a prenote
+	files, err := strategy.Originate(companyIdentification, xfer, src, dest)
+	if err != nil {
+		return nil, err
+	}
+	if err := convertToPrenotes(files); err != nil {
+		return nil, err
+	}
+	if err := pipeline.PublishFiles(pub, xfer, files); err != nil {
+		return nil, err
+	}
+
+	return &client.Prenote{
+		PrenoteID:  base.ID(),
+		TransferID: xfer.TransferID,
+		Destination: client.Destination{
+			CustomerID: dest.Customer.CustomerID,
+			AccountID:  dest.Account.AccountID,
+		},
+		Status:  client.VERIFICATIONSTATUS_INITIATED,
+		Created: time.Now(),
+	}, nil
+}
+
+func prenoteTransfer(src fundflow.Source, dest fundflow.Destination, description string) *client.Transfer {
+	if description == "" {
+		description = "prenote"
+	}
+	return &client.Transfer{
+		TransferID: base.ID(),
+		Amount: client.Amount{
+			Currency: "USD",
+			Value:    0,
+		},
+		Source: client.Source{
+			CustomerID: src.Customer.CustomerID,
+			AccountID:  src.Account.AccountID,
+		},
+		Destination: client.Destination{
+			CustomerID: dest.Customer.CustomerID,
+			AccountID:  dest.Account.AccountID,
+		},
+		Description: description,
+		Status:      client.PENDING,
+		Created:     time.Now(),
+	}
+}
+
+// convertToPrenotes rewrites each entry as a zero-dollar prenotification and
+// rebuilds the batch and file controls.
+func convertToPrenotes(files []*ach.File) error {
+	for i := range files {
+		for j := range files[i].Batches {
+			entries := files[i].Batches[j].GetEntries()
+			for k := range entries {
+				code, err := prenoteTransactionCode(entries[k].TransactionCode)
+				if err != nil {
+					return fmt.Errorf("traceNumber=%s: %v", entries[k].TraceNumber, err)
+				}
+				entries[k].TransactionCode = code
+				entries[k].Amount = 0
+			}
+			if err := files[i].Batches[j].Create(); err != nil {
+				return fmt.Errorf("problem creating prenote batch: %v", err)
+			}
+		}
+		if err := files[i].Create(); err != nil {
+			return fmt.Errorf("problem creating prenote file: %v", err)
+		}
+	}
+	return nil
+}
+
+func prenoteTransactionCode(code int) (int, error) {
+	switch code {
+	case ach.CheckingCredit:
+		return ach.CheckingPrenoteCredit, nil
+	case ach.CheckingDebit:
+		return ach.CheckingPrenoteDebit, nil
+	case ach.SavingsCredit:
+		return ach.SavingsPrenoteCredit, nil
+	case ach.SavingsDebit:
+		return ach.SavingsPrenoteDebit, nil
+	}
+	return 0, fmt.Errorf("no prenote for TransactionCode=%d", code)
+}
@@ -0,0 +1,106 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package prenotes
+
+import (
+	"testing"
+
+	"github.com/moov-io/ach"
+	"github.com/moov-io/base"
+	customers "github.com/moov-io/customers/pkg/client"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/database"
+	"github.com/moov-io/paygate/pkg/transfers"
+	"github.com/moov-io/paygate/pkg/transfers/fundflow"
+	"github.com/moov-io/paygate/pkg/transfers/pipeline"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrenotes__createPrenote(t *testing.T) {
+	cfg := mockConfig()
+	cfg.ODFI.RoutingNumber = "123456780"
+
+	db := database.CreateTestSqliteDB(t)
+	t.Cleanup(func() { db.Close() })
+
+	src, dest := createTestSource(cfg.ODFI), createTestDestination()
+
+	repo := transfers.NewRepo(db.DB)
+	pub := pipeline.NewMockPublisher()
+	strategy := fundflow.NewFirstPerson(cfg.Logger, cfg.ODFI)
+
+	prenote, err := createPrenote(*cfg.Validation.Prenotes, base.ID(), "MoovZZZZZZ", src, dest, repo, strategy, pub)
+	require.NoError(t, err)
+	require.Equal(t, client.VERIFICATIONSTATUS_INITIATED, prenote.Status)
+	require.Equal(t, "dest-account", prenote.Destination.AccountID)
+
+	xfer, err := repo.GetTransfer(prenote.TransferID)
+	require.NoError(t, err)
+	require.Equal(t, int32(0), xfer.Amount.Value)
+	require.Equal(t, "prenote", xfer.Description)
+
+	published, ok := pub.Xfers[prenote.TransferID]
+	require.True(t, ok)
+	require.Len(t, published.File.Batches, 1)
+
+	entries := published.File.Batches[0].GetEntries()
+	require.Len(t, entries, 1)
+	require.Equal(t, ach.SavingsPrenoteCredit, entries[0].TransactionCode) // destination is a savings account
+	require.Equal(t, 0, entries[0].Amount)
+	require.NoError(t, published.File.Validate())
+}
+
+func TestPrenotes__prenoteTransactionCode(t *testing.T) {
+	cases := map[int]int{
+		ach.CheckingCredit: ach.CheckingPrenoteCredit,
+		ach.CheckingDebit:  ach.CheckingPrenoteDebit,
+		ach.SavingsCredit:  ach.SavingsPrenoteCredit,
+		ach.SavingsDebit:   ach.SavingsPrenoteDebit,
+	}
+	for code, expected := range cases {
+		n, err := prenoteTransactionCode(code)
+		require.NoError(t, err)
+		require.Equal(t, expected, n)
+	}
+
+	_, err := prenoteTransactionCode(ach.GLCredit)
+	require.Error(t, err)
+}
+
+func createTestSource(odfi config.ODFI) fundflow.Source {
+	return fundflow.Source{
+		Customer: customers.Customer{
+			CustomerID: "src-customer",
+			FirstName:  "Jane",
+			LastName:   "Doe",
+			Status:     customers.CUSTOMERSTATUS_VERIFIED,
+		},
+		Account: customers.Account{
+			AccountID:     "src-account",
+			RoutingNumber: odfi.RoutingNumber,
+			Type:          customers.ACCOUNTTYPE_CHECKING,
+		},
+	}
+}
+
+func createTestDestination() fundflow.Destination {
+	return fundflow.Destination{
+		Customer: customers.Customer{
+			CustomerID: "dest-customer",
+			FirstName:  "Jon",
+			LastName:   "Doe",
+			Status:     customers.CUSTOMERSTATUS_VERIFIED,
+		},
+		Account: customers.Account{
+			AccountID:     "dest-account",
+			RoutingNumber: "987654320",
+			Type:          customers.ACCOUNTTYPE_SAVINGS,
+		},
+		AccountNumber: "12345",
+	}
+}
@@ -0,0 +1,220 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package prenotes
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/moov-io/paygate/pkg/client"
+)
+
+type Repository interface {
+	getPrenote(prenoteID string) (*client.Prenote, error)
+	// getAccountPrenotes returns every prenote for accountID, newest first.
+	getAccountPrenotes(accountID string) ([]*client.Prenote, error)
+	writePrenote(organization string, prenote *client.Prenote) error
+
+	// getPendingPrenotes returns each prenote which is waiting on its Transfer.
+	getPendingPrenotes() ([]pendingPrenote, error)
+	// lookupPrenoteFromTransfer returns the prenote which created transferID.
+	lookupPrenoteFromTransfer(transferID string) (*client.Prenote, error)
+
+	// verifyPrenote records the waiting period passed without a return or correction.
+	verifyPrenote(prenoteID string, when time.Time) error
+	// failPrenote records the return or change code received for a prenote.
+	failPrenote(prenoteID string, returnCode string, changeCode string) error
+}
+
+// pendingPrenote is an initiated prenote along with the state of its Transfer.
+type pendingPrenote struct {
+	PrenoteID   string
+	Destination client.Destination
+	TransferID  string
+
+	TransferStatus client.TransferStatus
+	ReturnCode     string
+	ProcessedAt    *time.Time
+}
+
+func NewRepo(db *sql.DB) *sqlRepo {
+	return &sqlRepo{db: db}
+}
+
+type sqlRepo struct {
+	db *sql.DB
+}
+
+func (r *sqlRepo) Close() error {
+	if r == nil || r.db == nil {
+		return nil
+	}
+	return r.db.Close()
+}
+
+func (r *sqlRepo) getPrenote(prenoteID string) (*client.Prenote, error) {
+	query := `select prenote_id, transfer_id, destination_customer_id, destination_account_id, status, return_code, change_code, verified_at, created_at from prenotes
+where prenote_id = ? and deleted_at is null limit 1;`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return nil, fmt.Errorf("prenote prepare: %v", err)
+	}
+	defer stmt.Close()
+
+	prenote, err := scanPrenote(stmt.QueryRow(prenoteID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+		return nil, fmt.Errorf("prenote scan: %v", err)
+	}
+	return prenote, nil
+}
+
+func (r *sqlRepo) getAccountPrenotes(accountID string) ([]*client.Prenote, error) {
+	query := `select prenote_id, transfer_id, destination_customer_id, destination_account_id, status, return_code, change_code, verified_at, created_at from prenotes
+where destination_account_id = ? and deleted_at is null order by created_at desc;`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return nil, fmt.Errorf("account prenotes prepare: %v", err)
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.Query(accountID)
+	if err != nil {
+		return nil, fmt.Errorf("account prenotes query: %v", err)
+	}
+	defer rows.Close()
+
+	var out []*client.Prenote
+	for rows.Next() {
+		prenote, err := scanPrenote(rows)
+		if err != nil {
+			return nil, fmt.Errorf("account prenotes scan: %v", err)
+		}
+		out = append(out, prenote)
+	}
+	return out, rows.Err()
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanPrenote(row scanner) (*client.Prenote, error) {
+	var returnCode, changeCode *string
+	var prenote client.Prenote
+	if err := row.Scan(
+		&prenote.PrenoteID,
+		&prenote.TransferID,
+		&prenote.Destination.CustomerID,
+		&prenote.Destination.AccountID,
+		&prenote.Status,
+		&returnCode,
+		&changeCode,
+		&prenote.VerifiedAt,
+		&prenote.Created,
+	); err != nil {
+		return nil, err
+	}
+	if returnCode != nil {
+		prenote.ReturnCode = *returnCode
+	}
+	if changeCode != nil {
+		prenote.ChangeCode = *changeCode
+	}
+	return &prenote, nil
+}
+
+func (r *sqlRepo) writePrenote(organization string, prenote *client.Prenote) error {
+	query := `insert into prenotes (prenote_id, organization, destination_customer_id, destination_account_id, transfer_id, status, created_at) values (?, ?, ?, ?, ?, ?, ?);`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	dest := prenote.Destination
+	_, err = stmt.Exec(prenote.PrenoteID, organization, dest.CustomerID, dest.AccountID, prenote.TransferID, prenote.Status, prenote.Created)
+	return err
+}
+
+func (r *sqlRepo) getPendingPrenotes() ([]pendingPrenote, error) {
+	query := `select p.prenote_id, p.destination_customer_id, p.destination_account_id, p.transfer_id, t.status, t.return_code, t.processed_at from prenotes as p
+inner join transfers as t on p.transfer_id = t.transfer_id
+where p.status = ? and p.deleted_at is null;`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return nil, fmt.Errorf("pending prenotes prepare: %v", err)
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.Query(client.VERIFICATIONSTATUS_INITIATED)
+	if err != nil {
+		return nil, fmt.Errorf("pending prenotes query: %v", err)
+	}
+	defer rows.Close()
+
+	var out []pendingPrenote
+	for rows.Next() {
+		var returnCode *string
+		var p pendingPrenote
+		if err := rows.Scan(&p.PrenoteID, &p.Destination.CustomerID, &p.Destination.AccountID, &p.TransferID, &p.TransferStatus, &returnCode, &p.ProcessedAt); err != nil {
+			return nil, fmt.Errorf("pending prenotes scan: %v", err)
+		}
+		if returnCode != nil {
+			p.ReturnCode = *returnCode
+		}
+		out = append(out, p)
+	}
+	return out, rows.Err()
+}
+
+func (r *sqlRepo) lookupPrenoteFromTransfer(transferID string) (*client.Prenote, error) {
+	query := `select prenote_id from prenotes where transfer_id = ? and deleted_at is null limit 1;`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	var prenoteID string
+	if err := stmt.QueryRow(transferID).Scan(&prenoteID); err != nil {
+		return nil, err
+	}
+	return r.getPrenote(prenoteID)
+}
+
+func (r *sqlRepo) verifyPrenote(prenoteID string, when time.Time) error {
+	query := `update prenotes set status = ?, verified_at = ? where prenote_id = ? and status = ? and deleted_at is null;`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(client.VERIFICATIONSTATUS_VERIFIED, when, prenoteID, client.VERIFICATIONSTATUS_INITIATED)
+	return err
+}
+
+func (r *sqlRepo) failPrenote(prenoteID string, returnCode string, changeCode string) error {
+	query := `update prenotes set status = ?, return_code = ?, change_code = ? where prenote_id = ? and status = ? and deleted_at is null;`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(client.VERIFICATIONSTATUS_FAILED, nullable(returnCode), nullable(changeCode), prenoteID, client.VERIFICATIONSTATUS_INITIATED)
+	return err
+}
+
+func nullable(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
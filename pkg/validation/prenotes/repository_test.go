@@ -0,0 +1,135 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package prenotes
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/moov-io/base"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/database"
+	"github.com/moov-io/paygate/pkg/transfers"
+	"github.com/moov-io/paygate/pkg/transfers/history"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepository__prenotes(t *testing.T) {
+	t.Parallel()
+
+	check := func(t *testing.T, repo *sqlRepo) {
+		prenote := writePrenote(t, repo)
+
+		found, err := repo.getPrenote(prenote.PrenoteID)
+		require.NoError(t, err)
+		require.Equal(t, prenote.TransferID, found.TransferID)
+		require.Equal(t, client.VERIFICATIONSTATUS_INITIATED, found.Status)
+
+		_, err = repo.getPrenote(base.ID())
+		require.Equal(t, sql.ErrNoRows, err)
+
+		prenotes, err := repo.getAccountPrenotes(prenote.Destination.AccountID)
+		require.NoError(t, err)
+		require.Len(t, prenotes, 1)
+
+		found, err = repo.lookupPrenoteFromTransfer(prenote.TransferID)
+		require.NoError(t, err)
+		require.Equal(t, prenote.PrenoteID, found.PrenoteID)
+
+		pending, err := repo.getPendingPrenotes()
+		require.NoError(t, err)
+		require.Len(t, pending, 1)
+		require.Equal(t, client.PENDING, pending[0].TransferStatus)
+		require.Nil(t, pending[0].ProcessedAt)
+
+		// verified prenotes are no longer pending and can't be failed
+		require.NoError(t, repo.verifyPrenote(prenote.PrenoteID, time.Now()))
+		require.NoError(t, repo.failPrenote(prenote.PrenoteID, "R03", ""))
+
+		found, err = repo.getPrenote(prenote.PrenoteID)
+		require.NoError(t, err)
+		require.Equal(t, client.VERIFICATIONSTATUS_VERIFIED, found.Status)
+		require.NotNil(t, found.VerifiedAt)
+		require.Empty(t, found.ReturnCode)
+
+		pending, err = repo.getPendingPrenotes()
+		require.NoError(t, err)
+		require.Len(t, pending, 0)
+
+		// a correction fails another prenote
+		prenote = writePrenote(t, repo)
+		require.NoError(t, repo.failPrenote(prenote.PrenoteID, "", "C01"))
+
+		found, err = repo.getPrenote(prenote.PrenoteID)
+		require.NoError(t, err)
+		require.Equal(t, client.VERIFICATIONSTATUS_FAILED, found.Status)
+		require.Equal(t, "C01", found.ChangeCode)
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+}
+
+func TestRepository__getPendingPrenotesReturned(t *testing.T) {
+	t.Parallel()
+
+	check := func(t *testing.T, repo *sqlRepo) {
+		prenote := writePrenote(t, repo)
+
+		transferRepo := transfers.NewRepo(repo.db)
+		require.NoError(t, transferRepo.SaveReturnCode(prenote.TransferID, "R03"))
+		require.NoError(t, transferRepo.UpdateTransferStatus(prenote.TransferID, client.FAILED, history.Inbound))
+
+		pending, err := repo.getPendingPrenotes()
+		require.NoError(t, err)
+		require.Len(t, pending, 1)
+		require.Equal(t, "R03", pending[0].ReturnCode)
+		require.Equal(t, client.FAILED, pending[0].TransferStatus)
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+}
+
+func setupSQLiteDB(t *testing.T) *sqlRepo {
+	db := database.CreateTestSqliteDB(t)
+	t.Cleanup(func() { db.Close() })
+
+	repo := &sqlRepo{db: db.DB}
+	t.Cleanup(func() { repo.Close() })
+
+	return repo
+}
+
+func setupMySQLeDB(t *testing.T) *sqlRepo {
+	db := database.CreateTestMySQLDB(t)
+	t.Cleanup(func() { db.Close() })
+
+	repo := &sqlRepo{db: db.DB}
+	t.Cleanup(func() { repo.Close() })
+
+	return repo
+}
+
+func writePrenote(t *testing.T, repo *sqlRepo) *client.Prenote {
+	t.Helper()
+
+	prenote := mockPrenote()
+	xfer := &client.Transfer{
+		TransferID:  prenote.TransferID,
+		Amount:      client.Amount{Currency: "USD"},
+		Source:      client.Source{CustomerID: sourceCustomerID, AccountID: sourceAccountID},
+		Destination: prenote.Destination,
+		Description: "prenote",
+		Status:      client.PENDING,
+		Created:     time.Now(),
+	}
+	require.NoError(t, transfers.NewRepo(repo.db).WriteUserTransfer("moov", xfer))
+	require.NoError(t, repo.writePrenote("moov", prenote))
+	return prenote
+}
@@ -0,0 +1,213 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package prenotes
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	moovcustomers "github.com/moov-io/customers/pkg/client"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/customers"
+	"github.com/moov-io/paygate/pkg/customers/accounts"
+	"github.com/moov-io/paygate/pkg/transfers"
+	"github.com/moov-io/paygate/pkg/transfers/fundflow"
+	"github.com/moov-io/paygate/pkg/transfers/pipeline"
+	"github.com/moov-io/paygate/x/route"
+)
+
+type Router struct {
+	CreatePrenote      http.HandlerFunc
+	GetPrenote         http.HandlerFunc
+	GetAccountPrenotes http.HandlerFunc
+}
+
+func NewRouter(
+	cfg *config.Config,
+	repo Repository,
+	transferRepo transfers.Repository,
+	customersClient customers.Client,
+	accountDecryptor accounts.Decryptor,
+	fundStrategy fundflow.Strategy,
+	pub pipeline.XferPublisher,
+) *Router {
+	if cfg.Validation.Prenotes == nil {
+		return &Router{
+			CreatePrenote:      NotImplemented(cfg),
+			GetPrenote:         NotImplemented(cfg),
+			GetAccountPrenotes: NotImplemented(cfg),
+		}
+	}
+
+	// companyIdentification is the similarly named Batch Header field.
+	companyIdentification := cfg.ODFI.FileConfig.BatchHeader.CompanyIdentification
+
+	return &Router{
+		CreatePrenote:      CreatePrenote(cfg, companyIdentification, repo, transferRepo, customersClient, accountDecryptor, fundStrategy, pub),
+		GetPrenote:         GetPrenote(cfg, repo),
+		GetAccountPrenotes: GetAccountPrenotes(cfg, repo),
+	}
+}
+
+func (c *Router) RegisterRoutes(r *mux.Router) {
+	r.Methods("POST").Path("/prenotes").HandlerFunc(c.CreatePrenote)
+	r.Methods("GET").Path("/prenotes/{prenoteID}").HandlerFunc(c.GetPrenote)
+	r.Methods("GET").Path("/accounts/{accountID}/prenotes").HandlerFunc(c.GetAccountPrenotes)
+}
+
+func CreatePrenote(
+	cfg *config.Config,
+	companyIdentification string,
+	repo Repository,
+	transferRepo transfers.Repository,
+	customersClient customers.Client,
+	accountDecryptor accounts.Decryptor,
+	fundStrategy fundflow.Strategy,
+	pub pipeline.XferPublisher,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		conf := *cfg.Validation.Prenotes
+		logger := cfg.Logger.Set("service", "prenotes")
+
+		responder := route.NewResponder(cfg, w, r)
+		responder.Respond(func(w http.ResponseWriter) {
+			var req client.CreatePrenote
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				responder.Problem(err)
+				return
+			}
+
+			src, err := transfers.GetFundflowSource(customersClient, accountDecryptor, client.Source{
+				CustomerID: conf.Source.CustomerID,
+				AccountID:  conf.Source.AccountID,
+			}, conf.Source.Organization)
+			if err != nil {
+				logger.LogErrorf("ERROR getting prenote source: %v", err)
+				responder.Problem(err)
+				return
+			}
+			dest, err := transfers.GetFundflowDestination(customersClient, accountDecryptor, req.Destination, responder.OrganizationID)
+			if err != nil {
+				logger.LogErrorf("ERROR getting prenote destination: %v", err)
+				responder.Problem(err)
+				return
+			}
+			if src.Account.RoutingNumber == dest.Account.RoutingNumber {
+				err = errors.New("not initiating prenote for account at ODFI")
+				logger.LogError(err)
+				responder.Problem(err)
+				return
+			}
+			if err := acceptableAccountStatus(dest.Account); err != nil {
+				logger.LogErrorf("destination account: %v", err)
+				responder.Problem(err)
+				return
+			}
+
+			// Only one prenote can be waiting on the RDFI for an account at a time
+			existing, err := repo.getAccountPrenotes(dest.Account.AccountID)
+			if err != nil {
+				logger.LogErrorf("ERROR reading account prenotes: %v", err)
+				responder.Problem(err)
+				return
+			}
+			if len(existing) > 0 && existing[0].Status == client.VERIFICATIONSTATUS_INITIATED {
+				err = fmt.Errorf("accountID=%s has an outstanding prenote", dest.Account.AccountID)
+				logger.LogError(err)
+				responder.Problem(err)
+				return
+			}
+
+			prenote, err := createPrenote(conf, responder.OrganizationID, companyIdentification, src, dest, transferRepo, fundStrategy, pub)
+			if err != nil {
+				logger.LogErrorf("ERROR creating prenote: %v", err)
+				responder.Problem(err)
+				return
+			}
+			if err := repo.writePrenote(responder.OrganizationID, prenote); err != nil {
+				logger.LogErrorf("ERROR writing prenote: %v", err)
+				responder.Problem(err)
+				return
+			}
+
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(prenote)
+		})
+	}
+}
+
+func acceptableAccountStatus(acct moovcustomers.Account) error {
+	if strings.EqualFold(string(acct.Status), string(moovcustomers.ACCOUNTSTATUS_NONE)) {
+		return nil
+	}
+	return fmt.Errorf("accountID=%s is in an unacceptable status: %v", acct.AccountID, acct.Status)
+}
+
+func GetPrenote(cfg *config.Config, repo Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(cfg, w, r)
+		responder.Respond(func(w http.ResponseWriter) {
+			prenoteID := route.ReadPathID("prenoteID", r)
+			if prenoteID == "" {
+				responder.Problem(errors.New("missing prenoteID"))
+				return
+			}
+
+			prenote, err := repo.getPrenote(prenoteID)
+			if err != nil {
+				if err == sql.ErrNoRows {
+					http.NotFound(w, r)
+					return
+				}
+				cfg.Logger.LogErrorf("ERROR getting prenote: %v", err)
+				responder.Problem(err)
+				return
+			}
+
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(prenote)
+		})
+	}
+}
+
+func GetAccountPrenotes(cfg *config.Config, repo Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(cfg, w, r)
+		responder.Respond(func(w http.ResponseWriter) {
+			accountID := route.ReadPathID("accountID", r)
+			if accountID == "" {
+				responder.Problem(errors.New("missing accountID"))
+				return
+			}
+
+			prenotes, err := repo.getAccountPrenotes(accountID)
+			if err != nil {
+				cfg.Logger.LogErrorf("ERROR getting accountID=%s prenotes: %v", accountID, err)
+				responder.Problem(err)
+				return
+			}
+			if prenotes == nil {
+				prenotes = []*client.Prenote{}
+			}
+
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(prenotes)
+		})
+	}
+}
+
+func NotImplemented(cfg *config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(cfg, w, r)
+		responder.Problem(errors.New("prenotes are disabled via config"))
+	}
+}
@@ -0,0 +1,194 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package prenotes
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/moov-io/base"
+	moovcustomers "github.com/moov-io/customers/pkg/client"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/customers"
+	"github.com/moov-io/paygate/pkg/customers/accounts"
+	"github.com/moov-io/paygate/pkg/transfers"
+	"github.com/moov-io/paygate/pkg/transfers/fundflow"
+	"github.com/moov-io/paygate/pkg/transfers/pipeline"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	sourceCustomerID, sourceAccountID           = base.ID(), base.ID()
+	destinationCustomerID, destinationAccountID = base.ID(), base.ID()
+)
+
+func mockCustomersClient() *customers.MockClient {
+	client := &customers.MockClient{
+		Accounts: make(map[string]*moovcustomers.Account),
+		Customers: []*moovcustomers.Customer{
+			{
+				CustomerID: sourceCustomerID,
+				FirstName:  "John",
+				LastName:   "Doe",
+				Status:     moovcustomers.CUSTOMERSTATUS_VERIFIED,
+			},
+			{
+				CustomerID: destinationCustomerID,
+				FirstName:  "John",
+				LastName:   "Doe",
+				Status:     moovcustomers.CUSTOMERSTATUS_RECEIVE_ONLY,
+			},
+		},
+	}
+	client.Accounts[sourceAccountID] = &moovcustomers.Account{
+		AccountID:     sourceAccountID,
+		RoutingNumber: "987654320",
+		Status:        moovcustomers.ACCOUNTSTATUS_VALIDATED,
+		Type:          moovcustomers.ACCOUNTTYPE_CHECKING,
+	}
+	client.Accounts[destinationAccountID] = &moovcustomers.Account{
+		AccountID:     destinationAccountID,
+		RoutingNumber: "123456780",
+		Status:        moovcustomers.ACCOUNTSTATUS_NONE,
+		Type:          moovcustomers.ACCOUNTTYPE_CHECKING,
+	}
+	return client
+}
+
+func mockPrenote() *client.Prenote {
+	return &client.Prenote{
+		PrenoteID:  base.ID(),
+		TransferID: base.ID(),
+		Destination: client.Destination{
+			CustomerID: destinationCustomerID,
+			AccountID:  destinationAccountID,
+		},
+		Status:  client.VERIFICATIONSTATUS_INITIATED,
+		Created: time.Now(),
+	}
+}
+
+func mockConfig() *config.Config {
+	cfg := config.Empty()
+	cfg.Validation = config.Validation{
+		Prenotes: &config.Prenotes{
+			Source: config.Source{
+				CustomerID: sourceCustomerID,
+				AccountID:  sourceAccountID,
+			},
+		},
+	}
+	return cfg
+}
+
+func setupRouter(cfg *config.Config, repo Repository) *mux.Router {
+	r := mux.NewRouter()
+	router := NewRouter(cfg, repo, &transfers.MockRepository{}, mockCustomersClient(), &accounts.MockDecryptor{Number: "12345"}, &fundflow.MockStrategy{}, pipeline.NewMockPublisher())
+	router.RegisterRoutes(r)
+	return r
+}
+
+func createPrenoteRequest(t *testing.T) *http.Request {
+	t.Helper()
+
+	var body bytes.Buffer
+	require.NoError(t, json.NewEncoder(&body).Encode(client.CreatePrenote{
+		Destination: client.Destination{
+			CustomerID: destinationCustomerID,
+			AccountID:  destinationAccountID,
+		},
+	}))
+	req := httptest.NewRequest("POST", "/prenotes", &body)
+	req.Header.Set("X-OrganizationID", base.ID())
+	return req
+}
+
+func TestRouter__NotImplemented(t *testing.T) {
+	r := setupRouter(config.Empty(), &mockRepository{})
+
+	req := httptest.NewRequest("GET", fmt.Sprintf("/prenotes/%s", base.ID()), nil)
+	req.Header.Set("X-OrganizationID", base.ID())
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	w.Flush()
+
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	require.Contains(t, w.Body.String(), "prenotes are disabled")
+}
+
+func TestRouter__CreatePrenote(t *testing.T) {
+	// a prior prenote failed so another is allowed
+	previous := mockPrenote()
+	previous.Status = client.VERIFICATIONSTATUS_FAILED
+	repo := &mockRepository{
+		Prenotes: []*client.Prenote{previous},
+	}
+	r := setupRouter(mockConfig(), repo)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, createPrenoteRequest(t))
+	w.Flush()
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var prenote client.Prenote
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&prenote))
+	require.NotEmpty(t, prenote.PrenoteID)
+	require.NotEmpty(t, prenote.TransferID)
+	require.Equal(t, client.VERIFICATIONSTATUS_INITIATED, prenote.Status)
+	require.Equal(t, destinationAccountID, prenote.Destination.AccountID)
+
+	// an outstanding prenote blocks another
+	repo.Prenotes = []*client.Prenote{mockPrenote()}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, createPrenoteRequest(t))
+	w.Flush()
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	require.Contains(t, w.Body.String(), "outstanding prenote")
+}
+
+func TestRouter__GetPrenote(t *testing.T) {
+	repo := &mockRepository{
+		Prenote: mockPrenote(),
+	}
+	r := setupRouter(mockConfig(), repo)
+
+	req := httptest.NewRequest("GET", fmt.Sprintf("/prenotes/%s", repo.Prenote.PrenoteID), nil)
+	req.Header.Set("X-OrganizationID", base.ID())
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	w.Flush()
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var prenote client.Prenote
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&prenote))
+	require.Equal(t, repo.Prenote.PrenoteID, prenote.PrenoteID)
+}
+
+func TestRouter__GetAccountPrenotes(t *testing.T) {
+	repo := &mockRepository{}
+	r := setupRouter(mockConfig(), repo)
+
+	req := httptest.NewRequest("GET", fmt.Sprintf("/accounts/%s/prenotes", destinationAccountID), nil)
+	req.Header.Set("X-OrganizationID", base.ID())
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	w.Flush()
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "[]", strings.TrimSpace(w.Body.String()))
+}
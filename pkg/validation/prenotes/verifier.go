@@ -0,0 +1,168 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package prenotes
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/moov-io/base"
+	"github.com/moov-io/base/log"
+	moovcustomers "github.com/moov-io/customers/pkg/client"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/customers"
+)
+
+// Verifier marks accounts as validated once their prenote has been uploaded for the
+// configured number of banking days without a return or correction from the RDFI.
+// Prenotes whose Transfer was returned or canceled are failed instead.
+type Verifier struct {
+	cfg    config.Prenotes
+	logger log.Logger
+
+	repo            Repository
+	customersClient customers.Client
+
+	interval time.Duration
+}
+
+// NewVerifier returns a Verifier or nil if prenotes are disabled.
+func NewVerifier(cfg *config.Config, repo Repository, customersClient customers.Client) *Verifier {
+	if cfg.Validation.Prenotes == nil {
+		return nil
+	}
+	return &Verifier{
+		cfg:             *cfg.Validation.Prenotes,
+		logger:          cfg.Logger.Set("service", "prenotes"),
+		repo:            repo,
+		customersClient: customersClient,
+		interval:        15 * time.Minute,
+	}
+}
+
+func (v *Verifier) Start(ctx context.Context) {
+	if v == nil {
+		return
+	}
+
+	ticker := time.NewTicker(v.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case now := <-ticker.C:
+			if err := v.tick(now); err != nil {
+				v.logger.LogErrorf("ERROR verifying prenotes: %v", err)
+			}
+
+		case <-ctx.Done():
+			v.logger.Log("prenote verifier shutdown")
+			return
+		}
+	}
+}
+
+func (v *Verifier) tick(now time.Time) error {
+	pending, err := v.repo.getPendingPrenotes()
+	if err != nil {
+		return err
+	}
+
+	var el base.ErrorList
+	for i := range pending {
+		if err := v.check(pending[i], now); err != nil {
+			el.Add(fmt.Errorf("prenoteID=%s: %v", pending[i].PrenoteID, err))
+		}
+	}
+	if el.Empty() {
+		return nil
+	}
+	return el
+}
+
+func (v *Verifier) check(p pendingPrenote, now time.Time) error {
+	switch {
+	case p.ReturnCode != "" || p.TransferStatus == client.FAILED:
+		v.logger.With(log.Fields{
+			"prenoteID":  p.PrenoteID,
+			"returnCode": p.ReturnCode,
+		}).Log("prenote was returned")
+		return v.repo.failPrenote(p.PrenoteID, p.ReturnCode, "")
+
+	case p.TransferStatus == client.CANCELED:
+		v.logger.Set("prenoteID", p.PrenoteID).Log("prenote transfer was canceled")
+		return v.repo.failPrenote(p.PrenoteID, "", "")
+
+	case p.ProcessedAt == nil || now.Before(v.verifyAt(*p.ProcessedAt)):
+		return nil // still waiting on the RDFI
+	}
+
+	// Validate the account before recording the prenote so a failure is retried on the next tick
+	dest := p.Destination
+	if _, err := v.customersClient.UpdateAccountStatus(dest.CustomerID, dest.AccountID, moovcustomers.ACCOUNTSTATUS_VALIDATED); err != nil {
+		return fmt.Errorf("problem updating accountID=%s status: %v", dest.AccountID, err)
+	}
+	if err := v.repo.verifyPrenote(p.PrenoteID, now); err != nil {
+		return err
+	}
+	v.logger.With(log.Fields{
+		"prenoteID": p.PrenoteID,
+		"accountID": dest.AccountID,
+	}).Log("verified account from prenote")
+	return nil
+}
+
+// verifyAt returns when the prenote's waiting period has passed
+func (v *Verifier) verifyAt(processedAt time.Time) time.Time {
+	return base.NewTime(processedAt).AddBankingDay(v.cfg.WaitBankingDays()).Time
+}
+
+// CorrectionHandler fails prenotes which the RDFI responded to with a Notification
+// of Change, as the account details need to be updated before it's validated.
+type CorrectionHandler struct {
+	logger log.Logger
+	repo   Repository
+}
+
+// NewCorrectionHandler returns a CorrectionHandler or nil if prenotes are disabled.
+func NewCorrectionHandler(cfg *config.Config, repo Repository) *CorrectionHandler {
+	if cfg.Validation.Prenotes == nil {
+		return nil
+	}
+	return &CorrectionHandler{
+		logger: cfg.Logger.Set("service", "prenotes"),
+		repo:   repo,
+	}
+}
+
+// HandleCorrection fails the prenote which created transferID, if one exists.
+func (h *CorrectionHandler) HandleCorrection(transferID string, changeCode string) error {
+	if h == nil {
+		return nil
+	}
+
+	prenote, err := h.repo.lookupPrenoteFromTransfer(transferID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		return fmt.Errorf("problem looking up prenote for transferID=%s: %v", transferID, err)
+	}
+	if prenote == nil || prenote.Status != client.VERIFICATIONSTATUS_INITIATED {
+		return nil
+	}
+
+	h.logger.With(log.Fields{
+		"prenoteID":  prenote.PrenoteID,
+		"transferID": transferID,
+		"changeCode": changeCode,
+	}).Log("handling correction for prenote")
+
+	return h.repo.failPrenote(prenote.PrenoteID, "", changeCode)
+}
@@ -0,0 +1,89 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package prenotes
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/moov-io/base"
+	moovcustomers "github.com/moov-io/customers/pkg/client"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifier__disabled(t *testing.T) {
+	require.Nil(t, NewVerifier(config.Empty(), &mockRepository{}, nil))
+	require.Nil(t, NewCorrectionHandler(config.Empty(), &mockRepository{}))
+
+	var h *CorrectionHandler
+	require.NoError(t, h.HandleCorrection(base.ID(), "C01"))
+}
+
+func TestVerifier__tick(t *testing.T) {
+	// Monday, so three banking days later is Thursday
+	processedAt := time.Date(2020, time.June, 1, 14, 0, 0, 0, time.UTC)
+
+	waiting := pendingPrenote{
+		PrenoteID:      base.ID(),
+		Destination:    client.Destination{CustomerID: destinationCustomerID, AccountID: destinationAccountID},
+		TransferID:     base.ID(),
+		TransferStatus: client.PROCESSED,
+		ProcessedAt:    &processedAt,
+	}
+	returned := pendingPrenote{
+		PrenoteID:      base.ID(),
+		TransferStatus: client.FAILED,
+		ReturnCode:     "R03",
+	}
+	unprocessed := pendingPrenote{
+		PrenoteID:      base.ID(),
+		TransferStatus: client.PENDING,
+	}
+	repo := &mockRepository{
+		Pending: []pendingPrenote{waiting, returned, unprocessed},
+	}
+	customersClient := mockCustomersClient()
+	verifier := NewVerifier(mockConfig(), repo, customersClient)
+
+	// still within the waiting period
+	require.NoError(t, verifier.tick(processedAt.Add(48*time.Hour)))
+	require.Empty(t, repo.Verified)
+	require.Equal(t, []string{returned.PrenoteID}, repo.Failed)
+
+	repo.Pending = []pendingPrenote{waiting}
+	require.NoError(t, verifier.tick(processedAt.Add(4*24*time.Hour)))
+	require.Equal(t, []string{waiting.PrenoteID}, repo.Verified)
+	require.Equal(t, moovcustomers.ACCOUNTSTATUS_VALIDATED, customersClient.Accounts[destinationAccountID].Status)
+
+	// errors from the Customers service leave the prenote pending
+	repo.Verified = nil
+	customersClient.Err = errors.New("bad error")
+	require.Error(t, verifier.tick(processedAt.Add(4*24*time.Hour)))
+	require.Empty(t, repo.Verified)
+}
+
+func TestCorrectionHandler(t *testing.T) {
+	prenote := mockPrenote()
+	repo := &mockRepository{
+		Prenote: prenote,
+	}
+	h := NewCorrectionHandler(mockConfig(), repo)
+
+	require.NoError(t, h.HandleCorrection(prenote.TransferID, "C01"))
+	require.Equal(t, []string{prenote.PrenoteID}, repo.Failed)
+
+	// prenotes which already finished are left alone
+	prenote.Status = client.VERIFICATIONSTATUS_VERIFIED
+	require.NoError(t, h.HandleCorrection(prenote.TransferID, "C01"))
+	require.Len(t, repo.Failed, 1)
+
+	repo.Err = errors.New("bad error")
+	require.Error(t, h.HandleCorrection(prenote.TransferID, "C01"))
+}
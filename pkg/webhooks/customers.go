@@ -0,0 +1,40 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package webhooks
+
+import (
+	"time"
+)
+
+const (
+	// EventCustomerDeceased is sent when a Death Notification Entry (DNE) is received for a Customer's Account.
+	EventCustomerDeceased = "customer.deceased"
+)
+
+// CustomerUpdate is the Data of customer Events.
+type CustomerUpdate struct {
+	CustomerID string `json:"customerID"`
+	AccountID  string `json:"accountID,omitempty"`
+
+	// DateOfDeath is set on customer.deceased events when the notification included it
+	DateOfDeath *time.Time `json:"dateOfDeath,omitempty"`
+
+	// CanceledTransferIDs are the pending Transfers canceled because of the change
+	CanceledTransferIDs []string `json:"canceledTransferIDs,omitempty"`
+}
+
+// CustomerEvent returns an Event of eventType about a Customer.
+func CustomerEvent(eventType string, organization string, update CustomerUpdate) Event {
+	return Event{
+		Type:         eventType,
+		Organization: organization,
+		Created:      time.Now(),
+		Data:         update,
+		Links: Links(
+			LinkCustomer, update.CustomerID,
+			LinkAccount, update.AccountID,
+		),
+	}
+}
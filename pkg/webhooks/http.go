@@ -0,0 +1,104 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package webhooks
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/moov-io/paygate"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/x/adminauth"
+	"github.com/moov-io/paygate/x/httpclient"
+)
+
+// maxBackoff caps how long is waited between attempts, as the backoff doubles after each one.
+const maxBackoff = time.Minute
+
+type httpSender struct {
+	client   *http.Client
+	endpoint string
+	secret   string
+
+	attempts int
+	backoff  time.Duration
+}
+
+func newHTTPSender(cfg *config.Webhooks) (*httpSender, error) {
+	client, err := httpclient.New(cfg.HTTPClient, cfg.RequestTimeout())
+	if err != nil {
+		return nil, err
+	}
+	return &httpSender{
+		client:   client,
+		endpoint: strings.TrimSpace(cfg.Endpoint),
+		secret:   cfg.Secret,
+		attempts: cfg.Attempts(),
+		backoff:  cfg.RetryBackoff(),
+	}, nil
+}
+
+func (s *httpSender) Send(event Event) error {
+	var body bytes.Buffer
+	if err := json.NewEncoder(&body).Encode(event); err != nil {
+		return fmt.Errorf("webhook %s encode: %v", event.Type, err)
+	}
+
+	wait := s.backoff
+	for attempt := 1; ; attempt++ {
+		retry, err := s.send(event, body.Bytes())
+		if err == nil {
+			webhookAttempts.With("type", event.Type, "outcome", "success").Add(1)
+			return nil
+		}
+		if !retry || attempt >= s.attempts {
+			webhookAttempts.With("type", event.Type, "outcome", "failure").Add(1)
+			return fmt.Errorf("webhook %s: %v (after %d attempts)", event.Type, err, attempt)
+		}
+		webhookAttempts.With("type", event.Type, "outcome", "retry").Add(1)
+		time.Sleep(wait)
+		wait = nextBackoff(wait)
+	}
+}
+
+func nextBackoff(wait time.Duration) time.Duration {
+	if wait *= 2; wait > maxBackoff {
+		return maxBackoff
+	}
+	return wait
+}
+
+// send makes one delivery attempt and returns if a failure should be retried.
+func (s *httpSender) send(event Event, body []byte) (bool, error) {
+	req, err := http.NewRequest("POST", s.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", fmt.Sprintf("moov/paygate %v webhooks", paygate.Version))
+
+	// Each attempt has a fresh timestamp and nonce so receivers can reject replays
+	if s.secret != "" {
+		if err := adminauth.SignRequest(s.secret, req); err != nil {
+			return false, err
+		}
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError
+		return retry, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return false, nil
+}
@@ -0,0 +1,22 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package webhooks
+
+import (
+	"github.com/go-kit/kit/metrics/prometheus"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	webhookAttempts = prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+		Name: "webhook_attempts",
+		Help: "Counter of webhook delivery attempts by their outcome",
+	}, []string{"type", "outcome"})
+
+	notificationsDispatched = prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+		Name: "notifications_dispatched",
+		Help: "Counter of events dispatched by the channel their organization prefers",
+	}, []string{"type", "channel"})
+)
@@ -0,0 +1,41 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package webhooks
+
+import (
+	"github.com/moov-io/paygate/pkg/client"
+)
+
+type MockRepository struct {
+	Preferences []client.NotificationPreference
+	Err         error
+}
+
+func (r *MockRepository) getPreferences(organization string) ([]client.NotificationPreference, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	return r.Preferences, nil
+}
+
+func (r *MockRepository) getPreference(organization string, eventType string) (*client.NotificationPreference, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	for i := range r.Preferences {
+		if r.Preferences[i].Event == eventType {
+			return &r.Preferences[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *MockRepository) updatePreferences(organization string, prefs []client.NotificationPreference) error {
+	if r.Err != nil {
+		return r.Err
+	}
+	r.Preferences = prefs
+	return nil
+}
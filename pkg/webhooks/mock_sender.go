@@ -0,0 +1,24 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package webhooks
+
+import (
+	"sync"
+)
+
+type MockSender struct {
+	Events []Event
+	Err    error
+
+	mu sync.Mutex
+}
+
+func (s *MockSender) Send(event Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.Events = append(s.Events, event)
+	return s.Err
+}
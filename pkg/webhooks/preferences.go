@@ -0,0 +1,217 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/mail"
+
+	"github.com/gorilla/mux"
+	"github.com/moov-io/base/log"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/transfers/pipeline/notify"
+	"github.com/moov-io/paygate/x/route"
+)
+
+// eventTypes lists each Event organizations can set a notification preference for.
+var eventTypes = []string{
+	EventTransferProcessed,
+	EventTransferReturned,
+	EventTransferCorrected,
+	EventTransferLimitsNearing,
+	EventVerificationInitiated,
+	EventVerificationProcessed,
+	EventVerificationFailed,
+	EventVerificationCompleted,
+	EventVerificationExpired,
+}
+
+func validatePreferences(prefs []client.NotificationPreference) error {
+	seen := make(map[string]bool)
+	for _, pref := range prefs {
+		if !knownEventType(pref.Event) {
+			return fmt.Errorf("unknown event %q", pref.Event)
+		}
+		if seen[pref.Event] {
+			return fmt.Errorf("duplicate preference for %s", pref.Event)
+		}
+		seen[pref.Event] = true
+
+		switch pref.Channel {
+		case client.NOTIFICATIONCHANNEL_WEBHOOK, client.NOTIFICATIONCHANNEL_NONE:
+			if pref.Email != "" {
+				return fmt.Errorf("%s: email is only allowed with the email channel", pref.Event)
+			}
+		case client.NOTIFICATIONCHANNEL_EMAIL:
+			if _, err := mail.ParseAddress(pref.Email); err != nil {
+				return fmt.Errorf("%s: invalid email: %v", pref.Event, err)
+			}
+		default:
+			return fmt.Errorf("%s: unknown channel %q", pref.Event, pref.Channel)
+		}
+	}
+	return nil
+}
+
+func knownEventType(eventType string) bool {
+	for i := range eventTypes {
+		if eventTypes[i] == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+type Router struct {
+	GetPreferences    http.HandlerFunc
+	UpdatePreferences http.HandlerFunc
+}
+
+func NewRouter(cfg *config.Config, repo Repository) *Router {
+	return &Router{
+		GetPreferences:    GetPreferences(cfg, repo),
+		UpdatePreferences: UpdatePreferences(cfg, repo),
+	}
+}
+
+func (c *Router) RegisterRoutes(r *mux.Router) {
+	r.Methods("GET").Path("/configuration/notifications").HandlerFunc(c.GetPreferences)
+	r.Methods("PUT").Path("/configuration/notifications").HandlerFunc(c.UpdatePreferences)
+}
+
+func GetPreferences(cfg *config.Config, repo Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(cfg, w, r)
+		responder.Respond(func(w http.ResponseWriter) {
+			prefs, err := repo.getPreferences(responder.OrganizationID)
+			if err != nil {
+				cfg.Logger.LogErrorf("ERROR reading notification preferences: %v", err)
+				responder.Problem(err)
+				return
+			}
+			if prefs == nil {
+				prefs = []client.NotificationPreference{}
+			}
+
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(client.NotificationPreferences{
+				Events: prefs,
+			})
+		})
+	}
+}
+
+func UpdatePreferences(cfg *config.Config, repo Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(cfg, w, r)
+		responder.Respond(func(w http.ResponseWriter) {
+			var req client.NotificationPreferences
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				responder.Problem(err)
+				return
+			}
+			if err := validatePreferences(req.Events); err != nil {
+				responder.Problem(err)
+				return
+			}
+			if err := repo.updatePreferences(responder.OrganizationID, req.Events); err != nil {
+				cfg.Logger.LogErrorf("ERROR updating notification preferences: %v", err)
+				responder.Problem(err)
+				return
+			}
+			if req.Events == nil {
+				req.Events = []client.NotificationPreference{}
+			}
+
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(req)
+		})
+	}
+}
+
+type emailer interface {
+	SendTo(to []string, subject, body string) error
+}
+
+// dispatcher delivers each Event over the channel its organization prefers. Events
+// without a preference are sent to the webhook endpoint.
+type dispatcher struct {
+	logger  log.Logger
+	repo    Repository
+	webhook Sender
+	email   emailer
+}
+
+// NewDispatcher returns a Sender which consults each organization's notification
+// preferences before delivering an Event as a webhook, an email or not at all.
+func NewDispatcher(logger log.Logger, cfg *config.Webhooks, repo Repository) (Sender, error) {
+	webhook, err := NewSender(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if cfg != nil {
+		webhook = newQueuedSender(logger, webhook, queueSize)
+	}
+	d := &dispatcher{
+		logger:  logger,
+		repo:    repo,
+		webhook: webhook,
+	}
+	if cfg != nil && cfg.Email != nil {
+		mailer, err := notify.NewEmail(cfg.Email)
+		if err != nil {
+			return nil, fmt.Errorf("webhooks email: %v", err)
+		}
+		d.email = mailer
+	}
+	return d, nil
+}
+
+func (d *dispatcher) Send(event Event) error {
+	pref, err := d.repo.getPreference(event.Organization, event.Type)
+	if err != nil {
+		// Fall back to the default channel rather than dropping the event
+		d.logger.Set("organization", event.Organization).LogErrorf("problem reading %s notification preference: %v", event.Type, err)
+	}
+	channel := client.NOTIFICATIONCHANNEL_WEBHOOK
+	if pref != nil {
+		channel = pref.Channel
+	}
+	notificationsDispatched.With("type", event.Type, "channel", string(channel)).Add(1)
+
+	switch channel {
+	case client.NOTIFICATIONCHANNEL_NONE:
+		return nil
+	case client.NOTIFICATIONCHANNEL_EMAIL:
+		return d.sendEmail(pref.Email, event)
+	}
+	return d.webhook.Send(event)
+}
+
+// Close stops accepting webhooks and waits for those already queued to be sent.
+func (d *dispatcher) Close(ctx context.Context) error {
+	return Close(ctx, d.webhook)
+}
+
+func (d *dispatcher) sendEmail(to string, event Event) error {
+	if d.email == nil {
+		return errors.New("email notifications are not configured")
+	}
+	body, err := json.MarshalIndent(event, "", "  ")
+	if err != nil {
+		return fmt.Errorf("email %s encode: %v", event.Type, err)
+	}
+	subject := fmt.Sprintf("PayGate %s event", event.Type)
+	if err := d.email.SendTo([]string{to}, subject, string(body)); err != nil {
+		return fmt.Errorf("email %s: %v", event.Type, err)
+	}
+	return nil
+}
@@ -0,0 +1,196 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package webhooks
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/moov-io/base"
+	"github.com/moov-io/base/log"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/database"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPreferences__validate(t *testing.T) {
+	require.NoError(t, validatePreferences(nil))
+	require.NoError(t, validatePreferences([]client.NotificationPreference{
+		{Event: EventTransferReturned, Channel: client.NOTIFICATIONCHANNEL_EMAIL, Email: "ops@example.com"},
+		{Event: EventTransferProcessed, Channel: client.NOTIFICATIONCHANNEL_NONE},
+		{Event: EventVerificationCompleted, Channel: client.NOTIFICATIONCHANNEL_WEBHOOK},
+	}))
+
+	cases := [][]client.NotificationPreference{
+		{{Event: "transfer.other", Channel: client.NOTIFICATIONCHANNEL_NONE}},
+		{{Event: EventTransferReturned, Channel: "sms"}},
+		{{Event: EventTransferReturned, Channel: client.NOTIFICATIONCHANNEL_EMAIL}},
+		{{Event: EventTransferReturned, Channel: client.NOTIFICATIONCHANNEL_WEBHOOK, Email: "ops@example.com"}},
+		{
+			{Event: EventTransferReturned, Channel: client.NOTIFICATIONCHANNEL_NONE},
+			{Event: EventTransferReturned, Channel: client.NOTIFICATIONCHANNEL_WEBHOOK},
+		},
+	}
+	for i := range cases {
+		require.Error(t, validatePreferences(cases[i]), "case %d", i)
+	}
+}
+
+func TestPreferences__router(t *testing.T) {
+	repo := &MockRepository{}
+	r := mux.NewRouter()
+	NewRouter(config.Empty(), repo).RegisterRoutes(r)
+
+	// empty preferences
+	req := httptest.NewRequest("GET", "/configuration/notifications", nil)
+	req.Header.Set("X-Organization", base.ID())
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	w.Flush()
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, `{"events":[]}`, strings.TrimSpace(w.Body.String()))
+
+	// update
+	var body bytes.Buffer
+	json.NewEncoder(&body).Encode(client.NotificationPreferences{
+		Events: []client.NotificationPreference{
+			{Event: EventTransferReturned, Channel: client.NOTIFICATIONCHANNEL_EMAIL, Email: "ops@example.com"},
+		},
+	})
+	req = httptest.NewRequest("PUT", "/configuration/notifications", &body)
+	req.Header.Set("X-Organization", base.ID())
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	w.Flush()
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.Len(t, repo.Preferences, 1)
+
+	// invalid preferences are rejected
+	body.Reset()
+	json.NewEncoder(&body).Encode(client.NotificationPreferences{
+		Events: []client.NotificationPreference{
+			{Event: "limits.nearing", Channel: client.NOTIFICATIONCHANNEL_NONE},
+		},
+	})
+	req = httptest.NewRequest("PUT", "/configuration/notifications", &body)
+	req.Header.Set("X-Organization", base.ID())
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	w.Flush()
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	require.Len(t, repo.Preferences, 1)
+}
+
+type mockEmailer struct {
+	to      []string
+	subject string
+}
+
+func (m *mockEmailer) SendTo(to []string, subject, body string) error {
+	m.to = append(m.to, to...)
+	m.subject = subject
+	return nil
+}
+
+func TestPreferences__dispatcher(t *testing.T) {
+	repo := &MockRepository{
+		Preferences: []client.NotificationPreference{
+			{Event: EventTransferProcessed, Channel: client.NOTIFICATIONCHANNEL_NONE},
+			{Event: EventTransferReturned, Channel: client.NOTIFICATIONCHANNEL_EMAIL, Email: "ops@example.com"},
+		},
+	}
+	webhook := &MockSender{}
+	d := &dispatcher{
+		logger:  log.NewNopLogger(),
+		repo:    repo,
+		webhook: webhook,
+	}
+
+	// email isn't configured
+	require.Error(t, d.Send(Event{Type: EventTransferReturned}))
+
+	mailer := &mockEmailer{}
+	d.email = mailer
+
+	require.NoError(t, d.Send(Event{Type: EventTransferProcessed}))
+	require.NoError(t, d.Send(Event{Type: EventTransferReturned}))
+	require.NoError(t, d.Send(Event{Type: EventTransferCorrected}))
+
+	require.Equal(t, []string{"ops@example.com"}, mailer.to)
+	require.Equal(t, "PayGate transfer.returned event", mailer.subject)
+	require.Len(t, webhook.Events, 1)
+	require.Equal(t, EventTransferCorrected, webhook.Events[0].Type)
+
+	// errors reading preferences fall back to webhooks
+	repo.Err = errors.New("bad error")
+	require.NoError(t, d.Send(Event{Type: EventTransferProcessed}))
+	require.Len(t, webhook.Events, 2)
+}
+
+func TestPreferences__NewDispatcher(t *testing.T) {
+	sender, err := NewDispatcher(log.NewNopLogger(), nil, &MockRepository{})
+	require.NoError(t, err)
+	require.NoError(t, sender.Send(Event{Type: EventTransferProcessed}))
+
+	_, err = NewDispatcher(log.NewNopLogger(), &config.Webhooks{}, &MockRepository{})
+	require.Error(t, err)
+}
+
+func TestRepository__preferences(t *testing.T) {
+	t.Parallel()
+
+	check := func(t *testing.T, repo *sqlRepo) {
+		organization := base.ID()
+
+		prefs, err := repo.getPreferences(organization)
+		require.NoError(t, err)
+		require.Empty(t, prefs)
+
+		pref, err := repo.getPreference(organization, EventTransferReturned)
+		require.NoError(t, err)
+		require.Nil(t, pref)
+
+		require.NoError(t, repo.updatePreferences(organization, []client.NotificationPreference{
+			{Event: EventTransferReturned, Channel: client.NOTIFICATIONCHANNEL_EMAIL, Email: "ops@example.com"},
+			{Event: EventTransferProcessed, Channel: client.NOTIFICATIONCHANNEL_NONE},
+		}))
+
+		pref, err = repo.getPreference(organization, EventTransferReturned)
+		require.NoError(t, err)
+		require.Equal(t, "ops@example.com", pref.Email)
+
+		// updates replace every preference
+		require.NoError(t, repo.updatePreferences(organization, []client.NotificationPreference{
+			{Event: EventTransferCorrected, Channel: client.NOTIFICATIONCHANNEL_NONE},
+		}))
+		prefs, err = repo.getPreferences(organization)
+		require.NoError(t, err)
+		require.Equal(t, []client.NotificationPreference{
+			{Event: EventTransferCorrected, Channel: client.NOTIFICATIONCHANNEL_NONE},
+		}, prefs)
+
+		// other organizations are separate
+		prefs, err = repo.getPreferences(base.ID())
+		require.NoError(t, err)
+		require.Empty(t, prefs)
+	}
+
+	sqliteDB := database.CreateTestSqliteDB(t)
+	t.Cleanup(func() { sqliteDB.Close() })
+	check(t, NewRepo(sqliteDB.DB))
+
+	mysqlDB := database.CreateTestMySQLDB(t)
+	t.Cleanup(func() { mysqlDB.Close() })
+	check(t, NewRepo(mysqlDB.DB))
+}
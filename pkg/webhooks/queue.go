@@ -0,0 +1,117 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/moov-io/base/log"
+)
+
+// queueSize is how many events can wait to be sent before new ones are dropped.
+const queueSize = 1000
+
+var errQueueClosed = errors.New("webhooks are shutting down")
+
+// closer is implemented by Senders which deliver Events in the background.
+type closer interface {
+	Close(ctx context.Context) error
+}
+
+// Close stops sender from accepting Events and waits for the Events it already accepted
+// to be sent, or until ctx is done. Senders which deliver Events as they're called have
+// nothing to close.
+func Close(ctx context.Context, sender Sender) error {
+	if c, ok := sender.(closer); ok {
+		return c.Close(ctx)
+	}
+	return nil
+}
+
+// queuedSender delivers Events from a background goroutine so callers, like the pipeline
+// and inbound file processing, aren't held up while a slow endpoint is retried. Events
+// are dropped rather than blocking the caller when the queue is full.
+type queuedSender struct {
+	logger log.Logger
+	sender Sender
+	queue  chan Event
+
+	mu     sync.RWMutex
+	closed bool
+
+	// abandon is closed when Close gives up on the queued Events, done once run returns
+	abandon     chan struct{}
+	abandonOnce sync.Once
+	done        chan struct{}
+}
+
+func newQueuedSender(logger log.Logger, sender Sender, size int) *queuedSender {
+	s := &queuedSender{
+		logger:  logger,
+		sender:  sender,
+		queue:   make(chan Event, size),
+		abandon: make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go s.run()
+	return s
+}
+
+func (s *queuedSender) Send(event Event) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		webhookAttempts.With("type", event.Type, "outcome", "dropped").Add(1)
+		return fmt.Errorf("webhook %s dropped: %v", event.Type, errQueueClosed)
+	}
+	select {
+	case s.queue <- event:
+		return nil
+	default:
+		webhookAttempts.With("type", event.Type, "outcome", "dropped").Add(1)
+		return fmt.Errorf("webhook %s dropped, %d events are already queued", event.Type, cap(s.queue))
+	}
+}
+
+// Close stops accepting Events and sends those already queued. Events which are still
+// queued once ctx is done are dropped and logged.
+func (s *queuedSender) Close(ctx context.Context) error {
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.queue)
+	}
+	s.mu.Unlock()
+
+	select {
+	case <-s.done:
+		return nil
+	case <-ctx.Done():
+		remaining := len(s.queue)
+		s.abandonOnce.Do(func() { close(s.abandon) })
+		return fmt.Errorf("%d queued webhooks were dropped: %v", remaining, ctx.Err())
+	}
+}
+
+func (s *queuedSender) run() {
+	defer close(s.done)
+
+	for event := range s.queue {
+		select {
+		case <-s.abandon:
+			webhookAttempts.With("type", event.Type, "outcome", "dropped").Add(1)
+			s.logger.Set("organization", event.Organization).LogErrorf("webhook %s dropped at shutdown", event.Type)
+			continue
+		default:
+		}
+		if err := s.sender.Send(event); err != nil {
+			s.logger.Set("organization", event.Organization).LogErrorf("problem sending webhook: %v", err)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package webhooks
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/moov-io/paygate/pkg/client"
+)
+
+type Repository interface {
+	getPreferences(organization string) ([]client.NotificationPreference, error)
+	// getPreference returns the preference an organization has for eventType, or nil if none is set.
+	getPreference(organization string, eventType string) (*client.NotificationPreference, error)
+	// updatePreferences replaces every preference for an organization.
+	updatePreferences(organization string, prefs []client.NotificationPreference) error
+}
+
+func NewRepo(db *sql.DB) *sqlRepo {
+	return &sqlRepo{db: db}
+}
+
+type sqlRepo struct {
+	db *sql.DB
+}
+
+func (r *sqlRepo) Close() error {
+	if r == nil || r.db == nil {
+		return nil
+	}
+	return r.db.Close()
+}
+
+func (r *sqlRepo) getPreferences(organization string) ([]client.NotificationPreference, error) {
+	query := `select event_type, channel, email from notification_preferences where organization = ? order by event_type;`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return nil, fmt.Errorf("notification preferences prepare: %v", err)
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.Query(organization)
+	if err != nil {
+		return nil, fmt.Errorf("notification preferences query: %v", err)
+	}
+	defer rows.Close()
+
+	var out []client.NotificationPreference
+	for rows.Next() {
+		var email *string
+		var pref client.NotificationPreference
+		if err := rows.Scan(&pref.Event, &pref.Channel, &email); err != nil {
+			return nil, fmt.Errorf("notification preferences scan: %v", err)
+		}
+		if email != nil {
+			pref.Email = *email
+		}
+		out = append(out, pref)
+	}
+	return out, rows.Err()
+}
+
+func (r *sqlRepo) getPreference(organization string, eventType string) (*client.NotificationPreference, error) {
+	query := `select event_type, channel, email from notification_preferences where organization = ? and event_type = ? limit 1;`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return nil, fmt.Errorf("notification preference prepare: %v", err)
+	}
+	defer stmt.Close()
+
+	var email *string
+	var pref client.NotificationPreference
+	if err := stmt.QueryRow(organization, eventType).Scan(&pref.Event, &pref.Channel, &email); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("notification preference scan: %v", err)
+	}
+	if email != nil {
+		pref.Email = *email
+	}
+	return &pref, nil
+}
+
+func (r *sqlRepo) updatePreferences(organization string, prefs []client.NotificationPreference) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	query := `delete from notification_preferences where organization = ?;`
+	if _, err := tx.Exec(query, organization); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("deleting notification preferences: %v", err)
+	}
+
+	query = `insert into notification_preferences (organization, event_type, channel, email, updated_at) values (?, ?, ?, ?, ?);`
+	now := time.Now()
+	for i := range prefs {
+		var email *string
+		if prefs[i].Email != "" {
+			email = &prefs[i].Email
+		}
+		if _, err := tx.Exec(query, organization, prefs[i].Event, prefs[i].Channel, email, now); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("writing %s notification preference: %v", prefs[i].Event, err)
+		}
+	}
+	return tx.Commit()
+}
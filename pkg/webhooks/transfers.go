@@ -0,0 +1,55 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package webhooks
+
+import (
+	"time"
+
+	"github.com/moov-io/paygate/pkg/client"
+)
+
+const (
+	// EventTransferProcessed is sent once a Transfer's file has been uploaded to the ODFI.
+	EventTransferProcessed = "transfer.processed"
+
+	// EventTransferReturned is sent when a return is received for a Transfer and it's marked FAILED.
+	EventTransferReturned = "transfer.returned"
+
+	// EventTransferCorrected is sent when a Notification of Change (NOC) is received for a Transfer.
+	EventTransferCorrected = "transfer.corrected"
+
+	// EventTransferLimitsNearing is sent when a Transfer is created close to one of its organization's limits.
+	EventTransferLimitsNearing = "transfer.limits_nearing"
+)
+
+// TransferUpdate is the Data of transfer Events.
+type TransferUpdate struct {
+	TransferID string                `json:"transferID"`
+	Status     client.TransferStatus `json:"status"`
+
+	// CorrelationID is copied from the Transfer when it's known
+	CorrelationID string `json:"correlationID,omitempty"`
+
+	// ReturnCode is set on transfer.returned events
+	ReturnCode string `json:"returnCode,omitempty"`
+
+	// ChangeCode and CorrectedData are set on transfer.corrected events
+	ChangeCode    string `json:"changeCode,omitempty"`
+	CorrectedData string `json:"correctedData,omitempty"`
+
+	// Warnings are set on transfer.limits_nearing events
+	Warnings []client.LimitWarning `json:"warnings,omitempty"`
+}
+
+// TransferEvent returns an Event of eventType about a Transfer.
+func TransferEvent(eventType string, organization string, update TransferUpdate) Event {
+	return Event{
+		Type:         eventType,
+		Organization: organization,
+		Created:      time.Now(),
+		Data:         update,
+		Links:        Links(LinkTransfer, update.TransferID),
+	}
+}
@@ -0,0 +1,22 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package webhooks
+
+const (
+	// EventVerificationInitiated is sent once micro-deposits are originated for an account.
+	EventVerificationInitiated = "verification.initiated"
+
+	// EventVerificationProcessed is sent once the micro-deposits have been uploaded to the ODFI.
+	EventVerificationProcessed = "verification.processed"
+
+	// EventVerificationFailed is sent when micro-deposits are returned or too many incorrect amounts are confirmed.
+	EventVerificationFailed = "verification.failed"
+
+	// EventVerificationCompleted is sent when the Receiver confirms their micro-deposit amounts.
+	EventVerificationCompleted = "verification.completed"
+
+	// EventVerificationExpired is sent when micro-deposits expire before the Receiver confirms them.
+	EventVerificationExpired = "verification.expired"
+)
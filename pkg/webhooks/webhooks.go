@@ -0,0 +1,72 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package webhooks
+
+import (
+	"time"
+
+	"github.com/moov-io/paygate/pkg/config"
+)
+
+// Event is a notification sent to external systems when an object inside
+// of PayGate changes state.
+type Event struct {
+	Type         string      `json:"type"`
+	Organization string      `json:"organization"`
+	Created      time.Time   `json:"created"`
+	Data         interface{} `json:"data"`
+
+	// Links reference each object the Event is about so receivers can find every
+	// Event for a Customer, Account or Transfer without reading Data.
+	Links []Link `json:"links,omitempty"`
+}
+
+// Link references an object inside of PayGate or the Customers service.
+type Link struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+}
+
+const (
+	LinkCustomer     = "customer"
+	LinkAccount      = "account"
+	LinkTransfer     = "transfer"
+	LinkMicroDeposit = "microDeposit"
+	LinkFile         = "file"
+)
+
+// Links returns a Link for each non-empty ID in pairs of type and ID.
+func Links(pairs ...string) []Link {
+	var out []Link
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if pairs[i+1] != "" {
+			out = append(out, Link{Type: pairs[i], ID: pairs[i+1]})
+		}
+	}
+	return out
+}
+
+// Sender is an interface for delivering Events to external systems.
+type Sender interface {
+	Send(event Event) error
+}
+
+// NewSender returns a Sender from the given config. When no webhooks
+// are configured a Sender which discards every Event is returned.
+func NewSender(cfg *config.Webhooks) (Sender, error) {
+	if cfg == nil {
+		return &discardSender{}, nil
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return newHTTPSender(cfg)
+}
+
+type discardSender struct{}
+
+func (*discardSender) Send(event Event) error {
+	return nil
+}
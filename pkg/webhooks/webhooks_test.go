@@ -0,0 +1,250 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/moov-io/base/log"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/x/adminauth"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhooks__NewSender(t *testing.T) {
+	sender, err := NewSender(nil)
+	require.NoError(t, err)
+	require.NoError(t, sender.Send(Event{Type: "test"}))
+
+	_, err = NewSender(&config.Webhooks{})
+	require.Error(t, err)
+}
+
+func TestWebhooks__Links(t *testing.T) {
+	links := Links(LinkTransfer, "xfer", LinkFile, "", LinkCustomer, "cust")
+	require.Equal(t, []Link{{Type: LinkTransfer, ID: "xfer"}, {Type: LinkCustomer, ID: "cust"}}, links)
+
+	require.Empty(t, Links())
+	require.Empty(t, Links(LinkTransfer))
+}
+
+func TestWebhooks__HTTP(t *testing.T) {
+	var received Event
+	handler := mux.NewRouter()
+	handler.Methods("POST").Path("/webhook").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	svc := httptest.NewServer(handler)
+	defer svc.Close()
+
+	sender, err := NewSender(&config.Webhooks{
+		Endpoint: svc.URL + "/webhook",
+	})
+	require.NoError(t, err)
+
+	err = sender.Send(Event{
+		Type:         "micro_deposits.initiated",
+		Organization: "moov",
+		Created:      time.Now(),
+	})
+	require.NoError(t, err)
+	require.Equal(t, "micro_deposits.initiated", received.Type)
+	require.Equal(t, "moov", received.Organization)
+
+	// unexpected HTTP status
+	sender, _ = NewSender(&config.Webhooks{
+		Endpoint: svc.URL + "/missing",
+	})
+	require.Error(t, sender.Send(Event{Type: "test"}))
+}
+
+func TestWebhooks__HTTPRetry(t *testing.T) {
+	var attempts int
+	handler := mux.NewRouter()
+	handler.Methods("POST").Path("/webhook").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	handler.Methods("POST").Path("/bad").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusBadRequest)
+	})
+	svc := httptest.NewServer(handler)
+	defer svc.Close()
+
+	sender, err := NewSender(&config.Webhooks{
+		Endpoint: svc.URL + "/webhook",
+		Backoff:  time.Millisecond,
+	})
+	require.NoError(t, err)
+	require.NoError(t, sender.Send(Event{Type: "test"}))
+	require.Equal(t, 3, attempts)
+
+	// give up after MaxAttempts
+	attempts = 0
+	sender, _ = NewSender(&config.Webhooks{
+		Endpoint:    svc.URL + "/webhook",
+		MaxAttempts: 2,
+		Backoff:     time.Millisecond,
+	})
+	require.Error(t, sender.Send(Event{Type: "test"}))
+	require.Equal(t, 2, attempts)
+
+	// client errors aren't retried
+	attempts = 0
+	sender, _ = NewSender(&config.Webhooks{
+		Endpoint: svc.URL + "/bad",
+		Backoff:  time.Millisecond,
+	})
+	require.Error(t, sender.Send(Event{Type: "test"}))
+	require.Equal(t, 1, attempts)
+}
+
+func TestWebhooks__nextBackoff(t *testing.T) {
+	require.Equal(t, 2*time.Second, nextBackoff(time.Second))
+	require.Equal(t, maxBackoff, nextBackoff(45*time.Second))
+	require.Equal(t, maxBackoff, nextBackoff(maxBackoff))
+}
+
+// blockingSender holds each Event until release is closed
+type blockingSender struct {
+	started chan Event
+	release chan struct{}
+	sent    chan Event
+}
+
+func (s *blockingSender) Send(event Event) error {
+	s.started <- event
+	<-s.release
+	s.sent <- event
+	return nil
+}
+
+func TestWebhooks__queued(t *testing.T) {
+	inner := &blockingSender{
+		started: make(chan Event, 3),
+		release: make(chan struct{}),
+		sent:    make(chan Event, 3),
+	}
+	sender := newQueuedSender(log.NewNopLogger(), inner, 1)
+
+	// Send returns while the first event is still being delivered
+	require.NoError(t, sender.Send(Event{Type: "first"}))
+	require.Equal(t, "first", (<-inner.started).Type)
+
+	require.NoError(t, sender.Send(Event{Type: "second"}))
+
+	// the queue is full
+	require.Error(t, sender.Send(Event{Type: "third"}))
+
+	close(inner.release)
+	require.Equal(t, "first", (<-inner.sent).Type)
+	require.Equal(t, "second", (<-inner.sent).Type)
+}
+
+func TestWebhooks__queuedClose(t *testing.T) {
+	inner := &blockingSender{
+		started: make(chan Event, 3),
+		release: make(chan struct{}),
+		sent:    make(chan Event, 3),
+	}
+	sender := newQueuedSender(log.NewNopLogger(), inner, 2)
+	require.NoError(t, sender.Send(Event{Type: "first"}))
+	require.NoError(t, sender.Send(Event{Type: "second"}))
+	<-inner.started
+
+	// queued events are sent before Close returns
+	close(inner.release)
+	require.NoError(t, Close(context.Background(), sender))
+	require.Len(t, inner.sent, 2)
+
+	// new events aren't accepted
+	require.Error(t, sender.Send(Event{Type: "third"}))
+	require.NoError(t, sender.Close(context.Background()))
+}
+
+func TestWebhooks__queuedCloseTimeout(t *testing.T) {
+	inner := &blockingSender{
+		started: make(chan Event, 3),
+		release: make(chan struct{}),
+		sent:    make(chan Event, 3),
+	}
+	sender := newQueuedSender(log.NewNopLogger(), inner, 2)
+	require.NoError(t, sender.Send(Event{Type: "first"}))
+	require.NoError(t, sender.Send(Event{Type: "second"}))
+	<-inner.started
+
+	// events still queued when ctx is done are dropped
+	ctx, cancelFunc := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancelFunc()
+	err := sender.Close(ctx)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "1 queued webhooks were dropped")
+
+	close(inner.release)
+	<-sender.done
+	require.Len(t, inner.sent, 1)
+}
+
+func TestWebhooks__HTTPSigning(t *testing.T) {
+	secret := strings.Repeat("s", 32)
+
+	var signature, expected string
+	handler := mux.NewRouter()
+	handler.Methods("POST").Path("/webhook").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		signature = r.Header.Get(adminauth.SignatureHeader)
+		expected = adminauth.Sign(secret, r.Method, r.URL.RequestURI(),
+			r.Header.Get(adminauth.TimestampHeader), r.Header.Get(adminauth.NonceHeader), body)
+		w.WriteHeader(http.StatusOK)
+	})
+	svc := httptest.NewServer(handler)
+	defer svc.Close()
+
+	sender, err := NewSender(&config.Webhooks{
+		Endpoint: svc.URL + "/webhook",
+		Secret:   secret,
+	})
+	require.NoError(t, err)
+	require.NoError(t, sender.Send(Event{Type: "test"}))
+	require.NotEmpty(t, signature)
+	require.Equal(t, expected, signature)
+
+	_, err = NewSender(&config.Webhooks{
+		Endpoint: svc.URL + "/webhook",
+		Secret:   "short",
+	})
+	require.Error(t, err)
+}
+
+func TestWebhooks__TransferEvent(t *testing.T) {
+	event := TransferEvent(EventTransferReturned, "moov", TransferUpdate{
+		TransferID: "xfer",
+		Status:     client.FAILED,
+		ReturnCode: "R01",
+	})
+	require.Equal(t, EventTransferReturned, event.Type)
+	require.Equal(t, "moov", event.Organization)
+	require.Equal(t, []Link{{Type: LinkTransfer, ID: "xfer"}}, event.Links)
+}
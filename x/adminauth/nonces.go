@@ -0,0 +1,42 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package adminauth
+
+import (
+	"sync"
+	"time"
+)
+
+// nonceCache holds nonces until they expire. Requests with expired nonces fail the
+// timestamp check, so older entries can be dropped.
+type nonceCache struct {
+	mu     sync.Mutex
+	nonces map[string]time.Time
+}
+
+func newNonceCache() *nonceCache {
+	return &nonceCache{
+		nonces: make(map[string]time.Time),
+	}
+}
+
+// add records nonce until expires and returns false if it was already present.
+func (c *nonceCache) add(nonce string, expires time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	for k, exp := range c.nonces {
+		if now.After(exp) {
+			delete(c.nonces, k)
+		}
+	}
+
+	if _, exists := c.nonces[nonce]; exists {
+		return false
+	}
+	c.nonces[nonce] = expires
+	return true
+}
@@ -0,0 +1,140 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package adminauth
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/moov-io/base"
+	"github.com/moov-io/paygate/pkg/config"
+
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	testSigning = &config.AdminSigning{
+		Secret: strings.Repeat("s", 32),
+	}
+)
+
+func protected(t *testing.T) http.HandlerFunc {
+	return Protect(testSigning, func(w http.ResponseWriter, r *http.Request) {
+		// the body must still be readable
+		bs, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		w.WriteHeader(http.StatusOK)
+		w.Write(bs)
+	})
+}
+
+func TestProtect(t *testing.T) {
+	handler := protected(t)
+
+	req := httptest.NewRequest("PUT", "/transfers/abc/status", strings.NewReader(`{"status":"canceled"}`))
+	require.NoError(t, SignRequest(testSigning.Secret, req))
+
+	w := httptest.NewRecorder()
+	handler(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.Equal(t, `{"status":"canceled"}`, w.Body.String())
+
+	// replay the same request
+	req2 := httptest.NewRequest("PUT", "/transfers/abc/status", strings.NewReader(`{"status":"canceled"}`))
+	req2.Header = req.Header.Clone()
+
+	w = httptest.NewRecorder()
+	handler(w, req2)
+	require.Equal(t, http.StatusUnauthorized, w.Code)
+	require.Contains(t, w.Body.String(), "already been seen")
+}
+
+func TestProtect__rejected(t *testing.T) {
+	handler := protected(t)
+
+	// unsigned
+	req := httptest.NewRequest("PUT", "/trigger-cutoff", nil)
+	w := httptest.NewRecorder()
+	handler(w, req)
+	require.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// wrong secret
+	req = httptest.NewRequest("PUT", "/trigger-cutoff", nil)
+	require.NoError(t, SignRequest(strings.Repeat("x", 32), req))
+	w = httptest.NewRecorder()
+	handler(w, req)
+	require.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// modified body
+	req = httptest.NewRequest("PUT", "/trigger-cutoff", strings.NewReader("a"))
+	require.NoError(t, SignRequest(testSigning.Secret, req))
+	req.Body = ioutil.NopCloser(strings.NewReader("b"))
+	w = httptest.NewRecorder()
+	handler(w, req)
+	require.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// stale timestamp
+	req = httptest.NewRequest("PUT", "/trigger-cutoff", nil)
+	timestamp := strconv.FormatInt(time.Now().Add(-1*time.Hour).Unix(), 10)
+	nonce := base.ID()
+	req.Header.Set(TimestampHeader, timestamp)
+	req.Header.Set(NonceHeader, nonce)
+	req.Header.Set(SignatureHeader, Sign(testSigning.Secret, "PUT", "/trigger-cutoff", timestamp, nonce, nil))
+	w = httptest.NewRecorder()
+	handler(w, req)
+	require.Equal(t, http.StatusUnauthorized, w.Code)
+	require.Contains(t, w.Body.String(), "skew")
+}
+
+func TestProtect__futureTimestamp(t *testing.T) {
+	now := time.Now()
+	timestamp := strconv.FormatInt(now.Add(testSigning.Skew()/2).Unix(), 10)
+	nonce := base.ID()
+
+	req := httptest.NewRequest("PUT", "/trigger-cutoff", nil)
+	req.Header.Set(TimestampHeader, timestamp)
+	req.Header.Set(NonceHeader, nonce)
+	req.Header.Set(SignatureHeader, Sign(testSigning.Secret, "PUT", "/trigger-cutoff", timestamp, nonce, nil))
+	require.NoError(t, verify(testSigning, req, now))
+
+	// the nonce is kept for as long as the timestamp is accepted
+	unix, _ := strconv.ParseInt(timestamp, 10, 64)
+	NonceRecorder.mu.Lock()
+	expires := NonceRecorder.nonces[nonce]
+	NonceRecorder.mu.Unlock()
+	require.Equal(t, time.Unix(unix, 0).Add(testSigning.Skew()), expires)
+}
+
+func TestProtect__bodyTooLarge(t *testing.T) {
+	body := strings.Repeat("a", maxBodySize+1)
+	req := httptest.NewRequest("PUT", "/trigger-cutoff", strings.NewReader(body))
+	require.NoError(t, SignRequest(testSigning.Secret, req))
+
+	w := httptest.NewRecorder()
+	protected(t)(w, req)
+	require.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+}
+
+func TestProtect__passthrough(t *testing.T) {
+	// GET requests don't need signatures
+	req := httptest.NewRequest("GET", "/inbound/quarantine", nil)
+	w := httptest.NewRecorder()
+	protected(t)(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	// Signing is disabled without config
+	handler := Protect(nil, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	req = httptest.NewRequest("PUT", "/trigger-cutoff", nil)
+	w = httptest.NewRecorder()
+	handler(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+}
@@ -0,0 +1,62 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+// Package errorlog keeps the most recent errors from a long running component in memory
+// so they can be inspected over an admin endpoint without searching logs.
+package errorlog
+
+import (
+	"sync"
+	"time"
+)
+
+type Entry struct {
+	Component string
+	Message   string
+	Created   time.Time
+}
+
+// Recent is a fixed size log of errors where the oldest entries are dropped first.
+// A nil Recent discards every error.
+type Recent struct {
+	mu      sync.Mutex
+	max     int
+	entries []Entry
+}
+
+func New(max int) *Recent {
+	return &Recent{max: max}
+}
+
+func (r *Recent) Add(component string, err error) {
+	if r == nil || err == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.entries = append(r.entries, Entry{
+		Component: component,
+		Message:   err.Error(),
+		Created:   time.Now(),
+	})
+	if n := len(r.entries); n > r.max {
+		r.entries = r.entries[n-r.max:]
+	}
+}
+
+// Entries returns a copy of the log with the newest entry first.
+func (r *Recent) Entries() []Entry {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	out := make([]Entry, len(r.entries))
+	for i := range r.entries {
+		out[len(r.entries)-1-i] = r.entries[i]
+	}
+	return out
+}
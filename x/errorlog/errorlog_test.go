@@ -0,0 +1,37 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package errorlog
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecent(t *testing.T) {
+	r := New(3)
+	require.Len(t, r.Entries(), 0)
+
+	r.Add("upload", nil)
+	require.Len(t, r.Entries(), 0)
+
+	for i := 0; i < 5; i++ {
+		r.Add("upload", fmt.Errorf("error %d", i))
+	}
+	entries := r.Entries()
+	require.Len(t, entries, 3)
+	require.Equal(t, "error 4", entries[0].Message)
+	require.Equal(t, "error 2", entries[2].Message)
+	require.Equal(t, "upload", entries[0].Component)
+	require.False(t, entries[0].Created.IsZero())
+}
+
+func TestRecent__nil(t *testing.T) {
+	var r *Recent
+	r.Add("upload", errors.New("bad error"))
+	require.Nil(t, r.Entries())
+}
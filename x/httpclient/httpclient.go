@@ -0,0 +1,80 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+// Package httpclient builds the clients PayGate uses to call other services.
+package httpclient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/x/trace"
+)
+
+// New returns an http.Client which gives up on requests after timeout. A nil cfg proxies
+// requests according to the environment and trusts the system's certificates. Requests are
+// traced, see trace.Transport.
+func New(cfg *config.HTTPClient, timeout time.Duration) (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if cfg != nil {
+		if cfg.TLS != nil {
+			tlsConfig, err := TLSConfig(cfg.TLS)
+			if err != nil {
+				return nil, err
+			}
+			transport.TLSClientConfig = tlsConfig
+		}
+		switch cfg.Proxy {
+		case "":
+			// keep http.ProxyFromEnvironment
+		case config.ProxyDirect:
+			transport.Proxy = nil
+		default:
+			proxy, err := url.Parse(cfg.Proxy)
+			if err != nil {
+				return nil, fmt.Errorf("invalid proxy: %v", err)
+			}
+			transport.Proxy = http.ProxyURL(proxy)
+		}
+	}
+	return &http.Client{
+		Transport: trace.NewTransport(transport),
+		Timeout:   timeout,
+	}, nil
+}
+
+// TLSConfig reads the CA and client certificates of cfg.
+func TLSConfig(cfg *config.ClientTLS) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: cfg.InsecureSkipVerify,
+	}
+	if cfg.CAFile != "" {
+		bs, err := ioutil.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %v", cfg.CAFile, err)
+		}
+		pool, err := x509.SystemCertPool()
+		if pool == nil || err != nil {
+			pool = x509.NewCertPool()
+		}
+		if ok := pool.AppendCertsFromPEM(bs); !ok {
+			return nil, fmt.Errorf("problem with AppendCertsFromPEM from %s", cfg.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if cfg.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("client certificate: %v", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
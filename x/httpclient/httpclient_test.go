@@ -0,0 +1,82 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package httpclient
+
+import (
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/x/trace"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	client, err := New(nil, 5*time.Second)
+	require.NoError(t, err)
+	require.Equal(t, 5*time.Second, client.Timeout)
+
+	transport := client.Transport.(*trace.Transport).Base.(*http.Transport)
+	require.NotNil(t, transport.Proxy)
+
+	client, err = New(&config.HTTPClient{Proxy: config.ProxyDirect}, time.Second)
+	require.NoError(t, err)
+	require.Nil(t, client.Transport.(*trace.Transport).Base.(*http.Transport).Proxy)
+}
+
+func TestNew__Proxy(t *testing.T) {
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r.URL.String()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer proxy.Close()
+
+	client, err := New(&config.HTTPClient{Proxy: proxy.URL}, time.Second)
+	require.NoError(t, err)
+
+	resp, err := client.Get("http://customers.example.com/ping")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, "http://customers.example.com/ping", proxied)
+}
+
+func TestNew__TLS(t *testing.T) {
+	svc := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer svc.Close()
+
+	// Trust the test server's certificate
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: svc.Certificate().Raw})
+	require.NoError(t, ioutil.WriteFile(caFile, ca, 0600))
+
+	client, err := New(&config.HTTPClient{
+		TLS:   &config.ClientTLS{CAFile: caFile},
+		Proxy: config.ProxyDirect,
+	}, time.Second)
+	require.NoError(t, err)
+
+	resp, err := client.Get(svc.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// missing client certificate
+	_, err = New(&config.HTTPClient{
+		TLS: &config.ClientTLS{
+			CertFile: filepath.Join("testdata", "missing.pem"),
+			KeyFile:  filepath.Join("testdata", "missing.key"),
+		},
+	}, time.Second)
+	require.Error(t, err)
+}
@@ -46,6 +46,16 @@ type Responder struct {
 }
 
 func NewResponder(cfg *config.Config, w http.ResponseWriter, r *http.Request) *Responder {
+	return newResponder(cfg, w, r, true)
+}
+
+// NewResponderWithoutIdempotency returns a Responder which doesn't check X-Idempotency-Key
+// against IdempotentRecorder, for handlers which store idempotency keys themselves.
+func NewResponderWithoutIdempotency(cfg *config.Config, w http.ResponseWriter, r *http.Request) *Responder {
+	return newResponder(cfg, w, r, false)
+}
+
+func newResponder(cfg *config.Config, w http.ResponseWriter, r *http.Request, idempotency bool) *Responder {
 	resp := &Responder{
 		OrganizationID: findOrg(cfg.Organization, r),
 		XRequestID:     moovhttp.GetRequestID(r),
@@ -53,7 +63,7 @@ func NewResponder(cfg *config.Config, w http.ResponseWriter, r *http.Request) *R
 		request:        r,
 	}
 	resp.setSpan()
-	writer, err := wrapResponseWriter(cfg.Logger, w, r)
+	writer, err := wrapResponseWriter(cfg.Logger, w, r, idempotency)
 	resp.writer = writer
 	if err != nil {
 		resp.Problem(err)
@@ -71,7 +81,7 @@ func (r *Responder) Respond(fn func(http.ResponseWriter)) {
 		return
 	}
 	// TODO(adam): we need to have a better framework for ensuring X-OrganizationID
-	r.finishSpan()
+	r.finishSpan(nil)
 	r.writer.Header().Set("Content-Type", "application/json; charset=utf-8")
 	fn(r.writer)
 }
@@ -80,15 +90,18 @@ func (r *Responder) Problem(err error) {
 	if r == nil {
 		return
 	}
-	r.finishSpan()
+	r.finishSpan(err)
 	r.writer.Header().Set("Content-Type", "application/json; charset=utf-8")
 	moovhttp.Problem(r.writer, err)
 }
 
-func wrapResponseWriter(logger log.Logger, w http.ResponseWriter, r *http.Request) (*moovhttp.ResponseWriter, error) {
+func wrapResponseWriter(logger log.Logger, w http.ResponseWriter, r *http.Request, idempotency bool) (*moovhttp.ResponseWriter, error) {
 	name := fmt.Sprintf("%s-%s", strings.ToLower(r.Method), CleanPath(r.URL.Path))
 
 	ww := moovhttp.Wrap(&loggerAdapter{inner: logger}, Histogram.With("route", name), w, r)
+	if !idempotency {
+		return ww, nil
+	}
 
 	if _, seen := idempotent.FromRequest(r, IdempotentRecorder); seen {
 		idempotent.SeenBefore(ww)
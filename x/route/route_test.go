@@ -122,6 +122,34 @@ func TestRoute__Idempotency(t *testing.T) {
 	}
 }
 
+func TestRoute__WithoutIdempotency(t *testing.T) {
+	cfg := config.Empty()
+
+	router := mux.NewRouter()
+	router.Methods("GET").Path("/test").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		responder := NewResponderWithoutIdempotency(cfg, w, r)
+		responder.Respond(func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte("PONG"))
+		})
+	})
+
+	key := base.ID()
+	IdempotentRecorder.SeenBefore(key)
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.Header.Set("x-idempotency-key", key)
+	req.Header.Set("X-Organization", base.ID())
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	w.Flush()
+
+	if w.Code != http.StatusOK {
+		t.Errorf("got %d", w.Code)
+	}
+}
+
 func TestRoute__CleanPath(t *testing.T) {
 	if v := CleanPath("/v1/paygate/ping"); v != "v1-paygate-ping" {
 		t.Errorf("got %q", v)
@@ -11,6 +11,7 @@ import (
 	"github.com/moov-io/paygate/x/trace"
 
 	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
 )
 
 func (r *Responder) Span() opentracing.Span {
@@ -24,11 +25,19 @@ func (r *Responder) setSpan() {
 	name := fmt.Sprintf("%s-%s", method, path)
 
 	r.span = trace.FromRequest(name, r.request)
+	ext.HTTPMethod.Set(r.span, r.request.Method)
+	ext.HTTPUrl.Set(r.span, r.request.URL.Path)
+	if r.OrganizationID != "" {
+		r.span.SetTag("organization", r.OrganizationID)
+	}
+	if r.XRequestID != "" {
+		r.span.SetTag("requestID", r.XRequestID)
+	}
 }
 
-func (r *Responder) finishSpan() {
+func (r *Responder) finishSpan(err error) {
 	if r == nil || r.span == nil {
 		return
 	}
-	r.span.Finish()
+	trace.Finish(r.span, err)
 }
@@ -0,0 +1,78 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package schedule
+
+import (
+	"sync"
+	"time"
+
+	"github.com/moov-io/base"
+)
+
+// Clock reports the current time and which days are banking days. Cutoffs, sequence
+// numbers and upload records read the time from a Clock so tests can control it.
+type Clock interface {
+	Now() time.Time
+	IsBankingDay(when time.Time) bool
+}
+
+// System is the Clock used in production. It reads the system time and follows the
+// Federal Reserve calendar of weekends and holidays.
+var System Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) IsBankingDay(when time.Time) bool {
+	return base.NewTime(when).IsBankingDay()
+}
+
+// MockClock is a Clock whose time only changes when Set or Advance are called.
+// Weekends and each of Holidays are not banking days, every other day is.
+type MockClock struct {
+	Holidays []time.Time
+
+	mu      sync.Mutex
+	current time.Time
+}
+
+func NewMockClock(now time.Time) *MockClock {
+	return &MockClock{current: now}
+}
+
+func (c *MockClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.current
+}
+
+func (c *MockClock) Set(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.current = now
+}
+
+func (c *MockClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.current = c.current.Add(d)
+}
+
+func (c *MockClock) IsBankingDay(when time.Time) bool {
+	if day := when.Weekday(); day == time.Saturday || day == time.Sunday {
+		return false
+	}
+	for i := range c.Holidays {
+		y1, m1, d1 := when.Date()
+		y2, m2, d2 := c.Holidays[i].In(when.Location()).Date()
+		if y1 == y2 && m1 == m2 && d1 == d2 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,48 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClock__System(t *testing.T) {
+	if now := System.Now(); time.Since(now) > time.Second {
+		t.Errorf("unexpected now=%v", now)
+	}
+	// Thanksgiving and a Saturday
+	if System.IsBankingDay(time.Date(2020, time.November, 26, 12, 0, 0, 0, time.UTC)) {
+		t.Error("expected holiday")
+	}
+	if System.IsBankingDay(time.Date(2020, time.November, 28, 12, 0, 0, 0, time.UTC)) {
+		t.Error("expected weekend")
+	}
+	if !System.IsBankingDay(time.Date(2020, time.November, 27, 12, 0, 0, 0, time.UTC)) {
+		t.Error("expected banking day")
+	}
+}
+
+func TestClock__Mock(t *testing.T) {
+	start := time.Date(2020, time.November, 25, 10, 0, 0, 0, time.UTC) // Wednesday
+	clock := NewMockClock(start)
+	clock.Holidays = []time.Time{time.Date(2020, time.November, 27, 0, 0, 0, 0, time.UTC)}
+
+	if !clock.Now().Equal(start) {
+		t.Errorf("unexpected now=%v", clock.Now())
+	}
+	clock.Advance(time.Hour)
+	if expected := start.Add(time.Hour); !clock.Now().Equal(expected) {
+		t.Errorf("now=%v expected=%v", clock.Now(), expected)
+	}
+
+	// only weekends and listed holidays are skipped
+	if !clock.IsBankingDay(time.Date(2020, time.November, 26, 12, 0, 0, 0, time.UTC)) {
+		t.Error("expected banking day")
+	}
+	if clock.IsBankingDay(time.Date(2020, time.November, 27, 12, 0, 0, 0, time.UTC)) {
+		t.Error("expected holiday")
+	}
+}
@@ -9,8 +9,6 @@ import (
 	"fmt"
 	"time"
 
-	"github.com/moov-io/base"
-
 	"github.com/robfig/cron/v3"
 )
 
@@ -19,13 +17,26 @@ import (
 type CutoffTimes struct {
 	C chan time.Time
 
-	sched *cron.Cron
+	clock     Clock
+	sched     *cron.Cron
+	windowsOn WindowsOn
+}
+
+// WindowsOn returns the cutoff timestamps in effect on a given day.
+type WindowsOn func(day time.Time) []string
+
+func ForCutoffTimes(clock Clock, tz string, timestamps []string) (*CutoffTimes, error) {
+	return ForDatedCutoffTimes(clock, tz, timestamps, nil)
 }
 
-func ForCutoffTimes(tz string, timestamps []string) (*CutoffTimes, error) {
+// ForDatedCutoffTimes returns CutoffTimes which fire at each of timestamps, but only on days where
+// windowsOn includes the timestamp. A nil windowsOn fires every timestamp on each banking day.
+func ForDatedCutoffTimes(clock Clock, tz string, timestamps []string, windowsOn WindowsOn) (*CutoffTimes, error) {
 	ct := &CutoffTimes{
-		C:     make(chan time.Time),
-		sched: cron.New(),
+		C:         make(chan time.Time),
+		clock:     clock,
+		sched:     cron.New(),
+		windowsOn: windowsOn,
 	}
 	if err := ct.registerCutoffs(tz, timestamps); err != nil {
 		return nil, err
@@ -46,13 +57,26 @@ func (ct *CutoffTimes) Stop() {
 	}
 }
 
-func (ct *CutoffTimes) maybeTick() {
-	now := base.Now()
-	if !now.IsWeekend() && now.IsBankingDay() {
-		ct.C <- now.Time.In(time.Local)
+func (ct *CutoffTimes) maybeTick(loc *time.Location, timestamp string) {
+	now := ct.clock.Now()
+	if ct.clock.IsBankingDay(now) && ct.activeOn(now.In(loc), timestamp) {
+		ct.C <- now.In(time.Local)
 	}
 }
 
+func (ct *CutoffTimes) activeOn(day time.Time, timestamp string) bool {
+	if ct.windowsOn == nil {
+		return true
+	}
+	windows := ct.windowsOn(day)
+	for i := range windows {
+		if windows[i] == timestamp {
+			return true
+		}
+	}
+	return false
+}
+
 func (ct *CutoffTimes) registerCutoffs(tz string, timestamps []string) error {
 	if len(timestamps) == 0 {
 		return errors.New("missing cutoff times")
@@ -71,14 +95,67 @@ func (ct *CutoffTimes) register(tz string, timestamp string) error {
 		return fmt.Errorf("failed to parse '%s' error=%v", timestamp, err)
 	}
 
+	loc, err := loadLocation(tz)
+	if err != nil {
+		return err
+	}
 	var zone string
 	if tz != "" {
 		zone = fmt.Sprintf("CRON_TZ=%s", tz)
 	}
 	schedule := fmt.Sprintf(`%s %d %d * * *`, zone, when.Minute(), when.Hour())
 	ct.sched.AddFunc(schedule, func() {
-		ct.maybeTick()
+		ct.maybeTick(loc, timestamp)
 	})
 
 	return nil
 }
+
+func loadLocation(tz string) (*time.Location, error) {
+	if tz == "" {
+		return time.Local, nil
+	}
+	return time.LoadLocation(tz)
+}
+
+// NextCutoff returns the earliest cutoff window after the clock's current time which
+// falls on a banking day.
+func NextCutoff(clock Clock, tz string, timestamps []string) (time.Time, error) {
+	return NextDatedCutoff(clock, tz, func(day time.Time) []string {
+		return timestamps
+	})
+}
+
+// NextDatedCutoff returns the earliest cutoff window after the clock's current time which
+// falls on a banking day, using the windows in effect on each day.
+func NextDatedCutoff(clock Clock, tz string, windowsOn WindowsOn) (time.Time, error) {
+	loc, err := loadLocation(tz)
+	if err != nil {
+		return time.Time{}, err
+	}
+	now := clock.Now().In(loc)
+
+	// Holidays and weekends can be skipped, so look at the next couple of weeks
+	for days := 0; days < 14; days++ {
+		day := now.AddDate(0, 0, days)
+		if !clock.IsBankingDay(day) {
+			continue
+		}
+		var next time.Time
+		timestamps := windowsOn(day)
+		for i := range timestamps {
+			when, err := time.Parse("15:04", timestamps[i])
+			if err != nil {
+				return time.Time{}, fmt.Errorf("failed to parse '%s' error=%v", timestamps[i], err)
+			}
+			cutoff := time.Date(day.Year(), day.Month(), day.Day(), when.Hour(), when.Minute(), 0, 0, loc)
+			if cutoff.After(now) && (next.IsZero() || cutoff.Before(next)) {
+				next = cutoff
+			}
+		}
+		if !next.IsZero() {
+			return next, nil
+		}
+	}
+	return time.Time{}, errors.New("no upcoming cutoff found")
+}
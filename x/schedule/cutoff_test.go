@@ -21,7 +21,7 @@ func TestCutoffTimes(t *testing.T) {
 
 	next := time.Now().Add(time.Minute).Format("15:04")
 
-	cutoffs, err := ForCutoffTimes("", []string{next})
+	cutoffs, err := ForCutoffTimes(System, "", []string{next})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -35,17 +35,131 @@ func TestCutoffTimes(t *testing.T) {
 	}
 }
 
+func TestCutoffTimes__maybeTick(t *testing.T) {
+	loc, _ := time.LoadLocation("America/New_York")
+	clock := NewMockClock(time.Date(2020, time.October, 17, 16, 20, 0, 0, loc)) // Saturday
+	ct := &CutoffTimes{
+		C:     make(chan time.Time, 1),
+		clock: clock,
+	}
+
+	// weekends don't tick
+	ct.maybeTick(loc, "16:20")
+	if len(ct.C) != 0 {
+		t.Fatalf("unexpected tick: %v", <-ct.C)
+	}
+
+	// neither do holidays
+	clock.Set(time.Date(2020, time.October, 19, 16, 20, 0, 0, loc))
+	clock.Holidays = []time.Time{clock.Now()}
+	ct.maybeTick(loc, "16:20")
+	if len(ct.C) != 0 {
+		t.Fatalf("unexpected tick: %v", <-ct.C)
+	}
+
+	clock.Advance(24 * time.Hour)
+	ct.maybeTick(loc, "16:20")
+	if tt := <-ct.C; !tt.Equal(clock.Now()) {
+		t.Errorf("unexpected tick: %v", tt)
+	}
+}
+
+func TestCutoffTimes__dated(t *testing.T) {
+	loc, _ := time.LoadLocation("America/New_York")
+	clock := NewMockClock(time.Date(2020, time.October, 19, 12, 30, 0, 0, loc)) // Monday
+	ct := &CutoffTimes{
+		C:     make(chan time.Time, 1),
+		clock: clock,
+		windowsOn: func(day time.Time) []string {
+			if day.Format("2006-01-02") < "2020-10-20" {
+				return []string{"16:20"}
+			}
+			return []string{"12:30", "16:20"}
+		},
+	}
+
+	// the 12:30 window isn't effective yet
+	ct.maybeTick(loc, "12:30")
+	if len(ct.C) != 0 {
+		t.Fatalf("unexpected tick: %v", <-ct.C)
+	}
+
+	clock.Advance(24 * time.Hour)
+	ct.maybeTick(loc, "12:30")
+	if tt := <-ct.C; !tt.Equal(clock.Now()) {
+		t.Errorf("unexpected tick: %v", tt)
+	}
+
+	// NextDatedCutoff also uses the windows in effect each day
+	clock.Set(time.Date(2020, time.October, 19, 17, 0, 0, 0, loc))
+	next, err := NextDatedCutoff(clock, "America/New_York", ct.windowsOn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := time.Date(2020, time.October, 20, 12, 30, 0, 0, loc); !next.Equal(expected) {
+		t.Errorf("next=%v expected=%v", next, expected)
+	}
+}
+
 func TestCutoffTimesErr(t *testing.T) {
-	_, err := ForCutoffTimes("bad_zone", nil)
+	_, err := ForCutoffTimes(System, "bad_zone", nil)
 	if err == nil {
 		t.Error("expected error")
 	}
-	_, err = ForCutoffTimes(time.Local.String(), nil)
+	_, err = ForCutoffTimes(System, time.Local.String(), nil)
 	if err == nil {
 		t.Error("expected error")
 	}
-	_, err = ForCutoffTimes(time.Local.String(), []string{"bad:time"})
+	_, err = ForCutoffTimes(System, time.Local.String(), []string{"bad:time"})
 	if err == nil {
 		t.Error("expected error")
 	}
 }
+
+func TestNextCutoff(t *testing.T) {
+	loc, _ := time.LoadLocation("America/New_York")
+	windows := []string{"16:20", "09:00"}
+
+	// Wednesday morning
+	clock := NewMockClock(time.Date(2020, time.October, 14, 10, 0, 0, 0, loc))
+	next, err := NextCutoff(clock, "America/New_York", windows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := time.Date(2020, time.October, 14, 16, 20, 0, 0, loc); !next.Equal(expected) {
+		t.Errorf("next=%v expected=%v", next, expected)
+	}
+
+	// after the last window moves to the next day
+	clock.Set(time.Date(2020, time.October, 14, 17, 0, 0, 0, loc))
+	next, _ = NextCutoff(clock, "America/New_York", windows)
+	if expected := time.Date(2020, time.October, 15, 9, 0, 0, 0, loc); !next.Equal(expected) {
+		t.Errorf("next=%v expected=%v", next, expected)
+	}
+
+	// Friday evening skips the weekend
+	clock.Set(time.Date(2020, time.October, 16, 17, 0, 0, 0, loc))
+	next, _ = NextCutoff(clock, "America/New_York", windows)
+	if expected := time.Date(2020, time.October, 19, 9, 0, 0, 0, loc); !next.Equal(expected) {
+		t.Errorf("next=%v expected=%v", next, expected)
+	}
+
+	// holidays are skipped like weekends
+	clock.Holidays = []time.Time{time.Date(2020, time.October, 19, 0, 0, 0, 0, loc)}
+	next, _ = NextCutoff(clock, "America/New_York", windows)
+	if expected := time.Date(2020, time.October, 20, 9, 0, 0, 0, loc); !next.Equal(expected) {
+		t.Errorf("next=%v expected=%v", next, expected)
+	}
+}
+
+func TestNextCutoffErr(t *testing.T) {
+	if _, err := NextCutoff(System, "bad_zone", []string{"16:20"}); err == nil {
+		t.Error("expected error")
+	}
+	if _, err := NextCutoff(System, "", []string{"bad:time"}); err == nil {
+		t.Error("expected error")
+	}
+	if _, err := NextCutoff(System, "", nil); err == nil {
+		t.Error("expected error")
+	}
+}
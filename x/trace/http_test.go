@@ -6,6 +6,7 @@ package trace
 
 import (
 	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/moov-io/base/log"
@@ -64,3 +65,33 @@ func TestFromRequest(t *testing.T) {
 		t.Errorf("expected trace header: %#v", req2.Header)
 	}
 }
+
+func TestTransport(t *testing.T) {
+	_, closer, err := NewConstantTracer(log.NewNopLogger(), "http-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { closer.Close() })
+
+	var header string
+	svc := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Get(jaeger.TraceContextHeaderName)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer svc.Close()
+
+	client := &http.Client{Transport: NewTransport(nil)}
+	req, _ := http.NewRequest("GET", svc.URL+"/ping", nil)
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if header == "" {
+		t.Error("expected trace header")
+	}
+	if v := req.Header.Get(jaeger.TraceContextHeaderName); v != "" {
+		t.Errorf("original request was modified: %#v", req.Header)
+	}
+}
@@ -0,0 +1,59 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package trace
+
+import (
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+)
+
+// StartSpan returns a span named name which is a child of parent, or starts a new trace
+// when parent is nil.
+func StartSpan(name string, parent opentracing.Span) opentracing.Span {
+	tracer := opentracing.GlobalTracer()
+	if parent == nil {
+		return tracer.StartSpan(name)
+	}
+	return tracer.StartSpan(name, opentracing.ChildOf(parent.Context()))
+}
+
+// Finish marks the span as failed when err is non-nil and then finishes it.
+func Finish(span opentracing.Span, err error) {
+	if span == nil {
+		return
+	}
+	if err != nil {
+		ext.Error.Set(span, true)
+		span.LogKV("event", "error", "message", err.Error())
+	}
+	span.Finish()
+}
+
+// Inject returns the context of span so it can be sent along with messages to other
+// processes, which continue the trace with FollowsFrom.
+func Inject(span opentracing.Span) map[string]string {
+	if span == nil {
+		return nil
+	}
+	carrier := make(map[string]string)
+	err := opentracing.GlobalTracer().Inject(span.Context(), opentracing.TextMap, opentracing.TextMapCarrier(carrier))
+	if err != nil || len(carrier) == 0 {
+		return nil
+	}
+	return carrier
+}
+
+// FollowsFrom returns a span named name which follows from the span context read from
+// carrier (see Inject), or starts a new trace when carrier doesn't contain one.
+func FollowsFrom(name string, carrier map[string]string) opentracing.Span {
+	tracer := opentracing.GlobalTracer()
+	if len(carrier) > 0 {
+		ctx, err := tracer.Extract(opentracing.TextMap, opentracing.TextMapCarrier(carrier))
+		if err == nil && ctx != nil {
+			return tracer.StartSpan(name, opentracing.FollowsFrom(ctx))
+		}
+	}
+	return tracer.StartSpan(name)
+}
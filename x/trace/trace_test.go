@@ -5,6 +5,7 @@
 package trace
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/moov-io/base/log"
@@ -41,3 +42,29 @@ func createParentWithChild(tracer opentracing.Tracer) {
 
 	parent.Finish()
 }
+
+func TestInjectFollowsFrom(t *testing.T) {
+	_, closer, err := NewConstantTracer(log.NewNopLogger(), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { closer.Close() })
+
+	parent := StartSpan("create-transfer", nil)
+	carrier := Inject(parent)
+	if len(carrier) == 0 {
+		t.Fatal("expected span context")
+	}
+	Finish(parent, nil)
+
+	child := FollowsFrom("merge-transfer", carrier)
+	if child == nil {
+		t.Fatal("nil Span")
+	}
+	Finish(child, errors.New("bad error"))
+
+	// spans are started without a carrier
+	if span := FollowsFrom("merge-transfer", nil); span == nil {
+		t.Fatal("nil Span")
+	}
+}
@@ -0,0 +1,44 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package trace
+
+import (
+	"fmt"
+	"net/http"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+)
+
+// Transport records a span for each request sent to another service and passes the trace
+// along in the request headers. Spans are children of the span in the request's context.
+type Transport struct {
+	Base http.RoundTripper
+}
+
+func NewTransport(base http.RoundTripper) *Transport {
+	return &Transport{Base: base}
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	span := StartSpan(fmt.Sprintf("%s %s", req.Method, req.URL.Host), opentracing.SpanFromContext(req.Context()))
+
+	// RoundTrippers shouldn't modify the request, so the headers are added to a copy
+	req = DecorateHttpRequest(req.Clone(req.Context()), span)
+
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	resp, err := base.RoundTrip(req)
+	if resp != nil {
+		ext.HTTPStatusCode.Set(span, uint16(resp.StatusCode))
+		if err == nil && resp.StatusCode >= http.StatusInternalServerError {
+			ext.Error.Set(span, true)
+		}
+	}
+	Finish(span, err)
+	return resp, err
+}